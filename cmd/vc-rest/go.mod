@@ -5,6 +5,7 @@
 module github.com/trustbloc/edge-service/cmd/vc-rest
 
 require (
+	github.com/btcsuite/btcutil v1.0.1
 	github.com/google/tink/go v0.0.0-20200403150819-3a14bf4b3380
 	github.com/gorilla/mux v1.7.4
 	github.com/hyperledger/aries-framework-go v0.1.4-0.20200528153636-1d4c39e41ae7
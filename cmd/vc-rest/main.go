@@ -41,6 +41,9 @@ func main() {
 	}
 
 	rootCmd.AddCommand(startcmd.GetStartCmd(&startcmd.HTTPServer{}))
+	rootCmd.AddCommand(startcmd.GetRotateKEKCmd())
+	rootCmd.AddCommand(startcmd.GetReplayEventsCmd())
+	rootCmd.AddCommand(startcmd.GetRebuildStateCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		logger.Fatalf("Failed to run vc-rest: %s", err.Error())
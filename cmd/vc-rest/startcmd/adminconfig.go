@@ -0,0 +1,290 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	issuerops "github.com/trustbloc/edge-service/pkg/restapi/issuer/operation"
+)
+
+const (
+	adminConfigEndpoint = "/admin/config"
+
+	// the value of the secrets reported by the admin API
+	redacted = "REDACTED"
+
+	kmsModeLocal  = "local"
+	kmsModeRemote = "remote"
+)
+
+// adminConfigResp is the effective configuration of the service, without its secrets
+type adminConfigResp struct {
+	EnabledModes      []mode                   `json:"enabledModes"`
+	ModePathPrefix    bool                     `json:"modePathPrefix"`
+	HostURL           string                   `json:"hostURL"`
+	HostURLExternal   string                   `json:"hostURLExternal,omitempty"`
+	BasePath          string                   `json:"basePath,omitempty"`
+	Storage           *storageConfig           `json:"storage"`
+	KMS               *kmsConfig               `json:"kms"`
+	DIDMethods        *didMethodsConfig        `json:"didMethods"`
+	CredentialStatus  *credentialStatusConfig  `json:"credentialStatus"`
+	Credentials       *credentialsConfig       `json:"credentials"`
+	Auth              *authConfig              `json:"auth"`
+	VerificationAlert *verificationAlertConfig `json:"verificationAlert,omitempty"`
+	Events            *eventsConfig            `json:"events,omitempty"`
+}
+
+type storageConfig struct {
+	DatabaseType             string `json:"databaseType"`
+	DatabaseURL              string `json:"databaseURL,omitempty"`
+	DatabasePrefix           string `json:"databasePrefix,omitempty"`
+	KMSSecretsDatabaseType   string `json:"kmsSecretsDatabaseType"`
+	KMSSecretsDatabaseURL    string `json:"kmsSecretsDatabaseURL,omitempty"`
+	KMSSecretsDatabasePrefix string `json:"kmsSecretsDatabasePrefix,omitempty"`
+	// EventLog is set if the mutations of the profiles and of the credential statuses are recorded in the event log
+	EventLog *eventLogConfig `json:"eventLog,omitempty"`
+}
+
+type eventLogConfig struct {
+	SnapshotInterval uint64 `json:"snapshotInterval"`
+}
+
+// kmsConfig is the KMS of the signing keys, the keys of the EDV always being in the local KMS
+type kmsConfig struct {
+	Mode      string `json:"mode"`
+	URL       string `json:"url,omitempty"`
+	KEKSource string `json:"kekSource,omitempty"`
+}
+
+// didMethodsConfig are the DID methods resolved locally and through the universal resolver, all the other DIDs
+// being resolved through the universal resolver if it's set without methods
+type didMethodsConfig struct {
+	Local                    []string `json:"local"`
+	UniversalResolverURL     string   `json:"universalResolverURL,omitempty"`
+	UniversalResolverMethods []string `json:"universalResolverMethods,omitempty"`
+}
+
+type credentialStatusConfig struct {
+	Type     string `json:"type"`
+	ListSize int    `json:"listSize"`
+	CacheTTL string `json:"cacheTTL"`
+}
+
+type credentialsConfig struct {
+	Storage              string   `json:"storage"`
+	DuplicateCredentials string   `json:"duplicateCredentials"`
+	EDVURL               string   `json:"edvURL,omitempty"`
+	ClaimsSourceURL      string   `json:"claimsSourceURL,omitempty"`
+	AnchorTSAURL         string   `json:"anchorTSAURL,omitempty"`
+	CredentialRefURLs    []string `json:"credentialRefAllowedURLs,omitempty"`
+	VerifyCredentialURLs []string `json:"verifyCredentialAllowedURLs,omitempty"`
+}
+
+type verificationAlertConfig struct {
+	WebhookURL       string  `json:"webhookURL"`
+	FailureRate      float64 `json:"failureRate"`
+	Window           string  `json:"window"`
+	MinVerifications int     `json:"minVerifications"`
+}
+
+// eventsConfig is the message broker the events are published to
+type eventsConfig struct {
+	Broker  string   `json:"broker"`
+	Servers []string `json:"servers"`
+	Topic   string   `json:"topic"`
+}
+
+// authConfig is the authentication of the requests, the secrets being redacted
+type authConfig struct {
+	Token            string            `json:"token,omitempty"`
+	APIKeyClients    []string          `json:"apiKeyClients,omitempty"`
+	RequestTokens    map[string]string `json:"requestTokens,omitempty"`
+	ClaimsSourceAuth string            `json:"claimsSourceAuth,omitempty"`
+	// ClientCertPermissions are the profiles of the clients authenticated by their certificate, by subject
+	ClientCertPermissions map[string][]string `json:"clientCertPermissions,omitempty"`
+	// HTTPSignatureKeys are the profiles of the keys of the clients authenticated by their HTTP signature, by key ID
+	HTTPSignatureKeys map[string][]string `json:"httpSignatureKeys,omitempty"`
+}
+
+// adminConfigHandler serves the effective configuration of the service, resolved once on startup
+func adminConfigHandler(parameters *vcRestParameters) http.HandlerFunc {
+	config := adminConfig(parameters)
+
+	return func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(rw).Encode(config); err != nil {
+			logger.Errorf("admin config response failure, %s", err)
+		}
+	}
+}
+
+func adminConfig(parameters *vcRestParameters) *adminConfigResp {
+	config := &adminConfigResp{
+		EnabledModes:    parameters.modes,
+		ModePathPrefix:  parameters.modePathPrefix,
+		HostURL:         parameters.hostURL,
+		HostURLExternal: parameters.hostURLExternal,
+		BasePath:        parameters.basePath,
+		Storage: &storageConfig{
+			DatabaseType:             parameters.dbParameters.databaseType,
+			DatabaseURL:              redactURL(parameters.dbParameters.databaseURL),
+			DatabasePrefix:           parameters.dbParameters.databasePrefix,
+			KMSSecretsDatabaseType:   parameters.dbParameters.kmsSecretsDatabaseType,
+			KMSSecretsDatabaseURL:    redactURL(parameters.dbParameters.kmsSecretsDatabaseURL),
+			KMSSecretsDatabasePrefix: parameters.dbParameters.kmsSecretsDatabasePrefix,
+		},
+		KMS:        kmsAdminConfig(parameters),
+		DIDMethods: didMethodsAdminConfig(parameters),
+		CredentialStatus: &credentialStatusConfig{
+			Type:     csl.CredentialStatusType,
+			ListSize: issuerops.CSLSize,
+			CacheTTL: parameters.cslCacheTTL.String(),
+		},
+		Credentials: &credentialsConfig{
+			Storage:              parameters.credentialStorage,
+			DuplicateCredentials: parameters.duplicateCredentials,
+			EDVURL:               redactURL(parameters.edvURL),
+			ClaimsSourceURL:      redactURL(parameters.claimsSourceURL),
+			AnchorTSAURL:         redactURL(parameters.anchorTSAURL),
+		},
+		Auth: authAdminConfig(parameters),
+	}
+
+	for _, u := range parameters.credentialRefURLs {
+		config.Credentials.CredentialRefURLs = append(config.Credentials.CredentialRefURLs, u.String())
+	}
+
+	for _, u := range parameters.verifyCredentialURLs {
+		config.Credentials.VerifyCredentialURLs = append(config.Credentials.VerifyCredentialURLs, u.String())
+	}
+
+	if alert := parameters.verificationAlert; alert != nil {
+		config.VerificationAlert = &verificationAlertConfig{WebhookURL: redactURL(alert.WebhookURL),
+			FailureRate: alert.FailureRate, Window: alert.Window.String(), MinVerifications: alert.MinVerifications}
+	}
+
+	if params := parameters.eventLogParams; params != nil {
+		config.Storage.EventLog = &eventLogConfig{SnapshotInterval: params.snapshotInterval}
+	}
+
+	if params := parameters.eventParams; params != nil {
+		config.Events = &eventsConfig{Broker: params.broker, Topic: params.topic}
+
+		for _, server := range params.urls {
+			config.Events.Servers = append(config.Events.Servers, redactURL(server))
+		}
+	}
+
+	return config
+}
+
+func kmsAdminConfig(parameters *vcRestParameters) *kmsConfig {
+	config := &kmsConfig{Mode: kmsModeLocal}
+
+	if parameters.kmsURL != "" {
+		config.Mode = kmsModeRemote
+		config.URL = redactURL(parameters.kmsURL)
+	}
+
+	// the master key of the local KMS is wrapped even with a remote KMS, the local KMS keeping the EDV keys
+	if kek := parameters.kekParameters; kek != nil {
+		switch {
+		case kek.passphrase != "":
+			config.KEKSource = "passphrase"
+		case kek.file != "":
+			config.KEKSource = "file"
+		case kek.url != "":
+			config.KEKSource = "url"
+		}
+	}
+
+	return config
+}
+
+func didMethodsAdminConfig(parameters *vcRestParameters) *didMethodsConfig {
+	config := &didMethodsConfig{Local: []string{didMethodKey, didMethodWeb, "trustbloc"}}
+
+	if parameters.universalResolverURL == "" {
+		return config
+	}
+
+	config.UniversalResolverURL = redactURL(parameters.universalResolverURL)
+
+	// each value of the flag is itself a comma-separated list, as is the value of the env variable
+	for _, value := range parameters.uniResolverMethods {
+		for _, method := range strings.Split(value, ",") {
+			if method = strings.TrimSpace(method); method != "" {
+				config.UniversalResolverMethods = append(config.UniversalResolverMethods, method)
+			}
+		}
+	}
+
+	return config
+}
+
+func authAdminConfig(parameters *vcRestParameters) *authConfig {
+	config := &authConfig{}
+
+	if parameters.token != "" {
+		config.Token = redacted
+	}
+
+	for client := range parameters.apiKeys {
+		config.APIKeyClients = append(config.APIKeyClients, client)
+	}
+
+	sort.Strings(config.APIKeyClients)
+
+	if len(parameters.requestTokens) > 0 {
+		config.RequestTokens = make(map[string]string, len(parameters.requestTokens))
+
+		for name := range parameters.requestTokens {
+			config.RequestTokens[name] = redacted
+		}
+	}
+
+	if parameters.claimsSourceAuth != "" {
+		config.ClaimsSourceAuth = redacted
+	}
+
+	if parameters.tlsServeParams != nil && len(parameters.tlsServeParams.clientPermissions) > 0 {
+		config.ClientCertPermissions = parameters.tlsServeParams.clientPermissions
+	}
+
+	if parameters.httpSignatureParams != nil {
+		config.HTTPSignatureKeys = parameters.httpSignatureParams.keys
+	}
+
+	return config
+}
+
+// redactURL redacts the password of the user info of the URL or of the data source name (user:password@host),
+// the URLs of the databases are not always valid URLs
+func redactURL(u string) string {
+	at := strings.LastIndex(u, "@")
+	if at < 0 {
+		return u
+	}
+
+	start := 0
+	if i := strings.Index(u[:at], "://"); i >= 0 {
+		start = i + len("://")
+	}
+
+	colon := strings.Index(u[start:at], ":")
+	if colon < 0 {
+		return u
+	}
+
+	return u[:start+colon+1] + redacted + u[at:]
+}
@@ -0,0 +1,272 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+
+	"github.com/trustbloc/edge-service/pkg/storage/eventlog"
+)
+
+const (
+	fromSequenceFlagName  = "from-sequence"
+	fromSequenceEnvKey    = "VC_REST_FROM_SEQUENCE"
+	fromSequenceFlagUsage = "The sequence of the first event replayed. Defaults to the first event of the log if not " +
+		"set. " + commonEnvVarUsageText + fromSequenceEnvKey
+
+	toSequenceFlagName  = "to-sequence"
+	toSequenceEnvKey    = "VC_REST_TO_SEQUENCE"
+	toSequenceFlagUsage = "The sequence of the last event replayed. " + commonEnvVarUsageText + toSequenceEnvKey
+
+	toTimeFlagName  = "to-time"
+	toTimeEnvKey    = "VC_REST_TO_TIME"
+	toTimeFlagUsage = "The point in time the events are replayed up to, in RFC3339 format " +
+		"(e.g. 2020-10-16T10:00:00Z). Defaults to the head of the log if neither the time nor the sequence is set. " +
+		commonEnvVarUsageText + toTimeEnvKey
+
+	targetDatabaseTypeFlagName  = "target-database-type"
+	targetDatabaseTypeEnvKey    = "VC_REST_TARGET_DATABASE_TYPE"
+	targetDatabaseTypeFlagUsage = "The type of the database the state is rebuilt in. Defaults to the database type " +
+		"if not set. " + commonEnvVarUsageText + targetDatabaseTypeEnvKey
+
+	targetDatabaseURLFlagName  = "target-database-url"
+	targetDatabaseURLEnvKey    = "VC_REST_TARGET_DATABASE_URL"
+	targetDatabaseURLFlagUsage = "The URL of the database the state is rebuilt in. Defaults to the database URL " +
+		"if not set. " + commonEnvVarUsageText + targetDatabaseURLEnvKey
+
+	targetDatabasePrefixFlagName  = "target-database-prefix"
+	targetDatabasePrefixEnvKey    = "VC_REST_TARGET_DATABASE_PREFIX"
+	targetDatabasePrefixFlagUsage = "The prefix of the databases the state is rebuilt in. " +
+		commonEnvVarUsageText + targetDatabasePrefixEnvKey
+)
+
+var errSameTargetDatabase = errors.New("the target database must differ from the database of the event log")
+
+// GetReplayEventsCmd returns the Cobra command writing the events of the event log.
+func GetReplayEventsCmd() *cobra.Command {
+	replayEventsCmd := &cobra.Command{
+		Use:   "replay-events",
+		Short: "Replay the events of the event log",
+		Long: "Writes the mutations of the profiles and of the credential statuses recorded in the event log, one " +
+			"JSON event per line in the order of the log, e.g. to audit the changes of a profile.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return replayEvents(cmd)
+		},
+	}
+
+	createEventLogFlags(replayEventsCmd)
+	replayEventsCmd.Flags().StringP(fromSequenceFlagName, "", "", fromSequenceFlagUsage)
+
+	return replayEventsCmd
+}
+
+// GetRebuildStateCmd returns the Cobra command rebuilding the state recorded in the event log at a point in time.
+func GetRebuildStateCmd() *cobra.Command {
+	rebuildStateCmd := &cobra.Command{
+		Use:   "rebuild-state",
+		Short: "Rebuild the profiles and credential statuses at a point in time",
+		Long: "Stores the profiles and the credential statuses recorded in the event log, as of the point in time, " +
+			"in the target database along with the events up to the point. vc-rest is then switched to the target " +
+			"database, as both its database and its status database.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return rebuildState(cmd)
+		},
+	}
+
+	createEventLogFlags(rebuildStateCmd)
+	rebuildStateCmd.Flags().StringP(targetDatabaseTypeFlagName, "", "", targetDatabaseTypeFlagUsage)
+	rebuildStateCmd.Flags().StringP(targetDatabaseURLFlagName, "", "", targetDatabaseURLFlagUsage)
+	rebuildStateCmd.Flags().StringP(targetDatabasePrefixFlagName, "", "", targetDatabasePrefixFlagUsage)
+
+	return rebuildStateCmd
+}
+
+func createEventLogFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP(databaseTypeFlagName, databaseTypeFlagShorthand, "", databaseTypeFlagUsage)
+	cmd.Flags().StringP(databaseURLFlagName, databaseURLFlagShorthand, "", databaseURLFlagUsage)
+	cmd.Flags().StringP(databasePrefixFlagName, "", "", databasePrefixFlagUsage)
+	cmd.Flags().StringP(toSequenceFlagName, "", "", toSequenceFlagUsage)
+	cmd.Flags().StringP(toTimeFlagName, "", "", toTimeFlagUsage)
+}
+
+func replayEvents(cmd *cobra.Command) error {
+	dbParams, err := getEventLogDBParameters(cmd)
+	if err != nil {
+		return err
+	}
+
+	from, err := getSequence(cmd, fromSequenceFlagName, fromSequenceEnvKey)
+	if err != nil {
+		return err
+	}
+
+	to, err := getPoint(cmd)
+	if err != nil {
+		return err
+	}
+
+	l, err := openEventLog(dbParams)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+
+	return l.Replay(from, to, func(event *eventlog.Event) error {
+		return encoder.Encode(event)
+	})
+}
+
+func rebuildState(cmd *cobra.Command) error {
+	dbParams, err := getEventLogDBParameters(cmd)
+	if err != nil {
+		return err
+	}
+
+	targetParams, err := getTargetDBParameters(cmd, dbParams)
+	if err != nil {
+		return err
+	}
+
+	to, err := getPoint(cmd)
+	if err != nil {
+		return err
+	}
+
+	l, err := openEventLog(dbParams)
+	if err != nil {
+		return err
+	}
+
+	target, err := createStoreProvider(targetParams)
+	if err != nil {
+		return fmt.Errorf("failed to create target store provider: %w", err)
+	}
+
+	state, err := eventlog.Rebuild(l, to, target)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("state rebuilt as of event %d (%s)", state.Sequence, state.Time.Format(time.RFC3339))
+
+	return nil
+}
+
+func openEventLog(dbParams *dbParameters) (*eventlog.Log, error) {
+	provider, err := createStoreProvider(dbParams)
+	if err != nil {
+		return nil, err
+	}
+
+	// the log is only read, no snapshot is taken
+	return eventlog.New(provider, eventlog.WithSnapshotInterval(0))
+}
+
+func getEventLogDBParameters(cmd *cobra.Command) (*dbParameters, error) {
+	databaseType, err := cmdutils.GetUserSetVarFromString(cmd, databaseTypeFlagName, databaseTypeEnvKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	databaseURL, err := cmdutils.GetUserSetVarFromString(cmd, databaseURLFlagName, databaseURLEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	databasePrefix, err := cmdutils.GetUserSetVarFromString(cmd, databasePrefixFlagName, databasePrefixEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dbParameters{databaseType: databaseType, databaseURL: databaseURL, databasePrefix: databasePrefix}, nil
+}
+
+func getTargetDBParameters(cmd *cobra.Command, dbParams *dbParameters) (*dbParameters, error) {
+	databaseType, err := cmdutils.GetUserSetVarFromString(cmd, targetDatabaseTypeFlagName,
+		targetDatabaseTypeEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	databaseURL, err := cmdutils.GetUserSetVarFromString(cmd, targetDatabaseURLFlagName,
+		targetDatabaseURLEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	databasePrefix, err := cmdutils.GetUserSetVarFromString(cmd, targetDatabasePrefixFlagName,
+		targetDatabasePrefixEnvKey, true)
+	if err != nil {
+		return nil, err
+	}
+
+	target := &dbParameters{databaseType: dbParams.databaseType, databaseURL: dbParams.databaseURL,
+		databasePrefix: databasePrefix}
+
+	if databaseType != "" {
+		target.databaseType = databaseType
+	}
+
+	if databaseURL != "" {
+		target.databaseURL = databaseURL
+	}
+
+	// the in-memory databases of the commands are separate
+	if !strings.EqualFold(target.databaseType, databaseTypeMemOption) &&
+		strings.EqualFold(target.databaseType, dbParams.databaseType) &&
+		strings.EqualFold(target.databaseURL, dbParams.databaseURL) && target.databasePrefix == dbParams.databasePrefix {
+		return nil, errSameTargetDatabase
+	}
+
+	return target, nil
+}
+
+func getPoint(cmd *cobra.Command) (eventlog.Point, error) {
+	sequence, err := getSequence(cmd, toSequenceFlagName, toSequenceEnvKey)
+	if err != nil {
+		return eventlog.Point{}, err
+	}
+
+	point := eventlog.Point{Sequence: sequence}
+
+	toTime, err := cmdutils.GetUserSetVarFromString(cmd, toTimeFlagName, toTimeEnvKey, true)
+	if err != nil {
+		return eventlog.Point{}, err
+	}
+
+	if toTime != "" {
+		point.Time, err = time.Parse(time.RFC3339, toTime)
+		if err != nil {
+			return eventlog.Point{}, fmt.Errorf("invalid value for %s: %w", toTimeFlagName, err)
+		}
+	}
+
+	return point, nil
+}
+
+func getSequence(cmd *cobra.Command, flagName, envKey string) (uint64, error) {
+	sequenceString, err := cmdutils.GetUserSetVarFromString(cmd, flagName, envKey, true)
+	if err != nil || sequenceString == "" {
+		return 0, err
+	}
+
+	sequence, err := strconv.ParseUint(sequenceString, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", flagName, err)
+	}
+
+	return sequence, nil
+}
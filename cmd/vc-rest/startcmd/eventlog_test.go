@@ -0,0 +1,92 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayEventsCmd(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		var out bytes.Buffer
+
+		replayEventsCmd := GetReplayEventsCmd()
+		replayEventsCmd.SetOut(&out)
+		replayEventsCmd.SetArgs([]string{"--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + fromSequenceFlagName, "10", "--" + toTimeFlagName, "2020-10-16T10:00:00Z"})
+
+		require.NoError(t, replayEventsCmd.Execute())
+		require.Empty(t, out.String())
+	})
+
+	t.Run("test error - missing database type", func(t *testing.T) {
+		replayEventsCmd := GetReplayEventsCmd()
+		replayEventsCmd.SetArgs([]string{})
+
+		err := replayEventsCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), databaseTypeFlagName)
+	})
+
+	t.Run("test error - invalid sequence", func(t *testing.T) {
+		replayEventsCmd := GetReplayEventsCmd()
+		replayEventsCmd.SetArgs([]string{"--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + fromSequenceFlagName, "first"})
+
+		err := replayEventsCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for from-sequence")
+	})
+
+	t.Run("test error - invalid time", func(t *testing.T) {
+		replayEventsCmd := GetReplayEventsCmd()
+		replayEventsCmd.SetArgs([]string{"--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + toTimeFlagName, "yesterday"})
+
+		err := replayEventsCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for to-time")
+	})
+
+	t.Run("test error - invalid database type", func(t *testing.T) {
+		replayEventsCmd := GetReplayEventsCmd()
+		replayEventsCmd.SetArgs([]string{"--" + databaseTypeFlagName, "invalid"})
+
+		require.Error(t, replayEventsCmd.Execute())
+	})
+}
+
+func TestRebuildStateCmd(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		rebuildStateCmd := GetRebuildStateCmd()
+		rebuildStateCmd.SetArgs([]string{"--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + toSequenceFlagName, "100"})
+
+		require.NoError(t, rebuildStateCmd.Execute())
+	})
+
+	t.Run("test error - same target database", func(t *testing.T) {
+		rebuildStateCmd := GetRebuildStateCmd()
+		rebuildStateCmd.SetArgs([]string{"--" + databaseTypeFlagName, databaseTypeCouchDBOption,
+			"--" + databaseURLFlagName, "localhost:5984", "--" + databasePrefixFlagName, "vcs",
+			"--" + targetDatabasePrefixFlagName, "vcs"})
+
+		require.Equal(t, errSameTargetDatabase, rebuildStateCmd.Execute())
+	})
+
+	t.Run("test error - invalid target database type", func(t *testing.T) {
+		rebuildStateCmd := GetRebuildStateCmd()
+		rebuildStateCmd.SetArgs([]string{"--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + targetDatabaseTypeFlagName, "invalid"})
+
+		err := rebuildStateCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create target store provider")
+	})
+}
@@ -0,0 +1,145 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"crypto/tls"
+	"errors"
+
+	"github.com/spf13/cobra"
+	cmdutils "github.com/trustbloc/edge-core/pkg/utils/cmd"
+	tlsutils "github.com/trustbloc/edge-core/pkg/utils/tls"
+
+	"github.com/trustbloc/edge-service/pkg/kms/masterkey"
+)
+
+const (
+	newKMSSecretsKEKPassphraseFlagName  = "new-kms-secrets-kek-passphrase"         //nolint: gosec
+	newKMSSecretsKEKPassphraseEnvKey    = "VC_REST_NEW_KMS_SECRETS_KEK_PASSPHRASE" //nolint: gosec
+	newKMSSecretsKEKPassphraseFlagUsage = "Passphrase the new key encryption key is derived from. " +
+		commonEnvVarUsageText + newKMSSecretsKEKPassphraseEnvKey
+
+	newKMSSecretsKEKFileFlagName  = "new-kms-secrets-kek-file"         //nolint: gosec
+	newKMSSecretsKEKFileEnvKey    = "VC_REST_NEW_KMS_SECRETS_KEK_FILE" //nolint: gosec
+	newKMSSecretsKEKFileFlagUsage = "Path of a file containing the passphrase the new key encryption key is " +
+		"derived from. " + commonEnvVarUsageText + newKMSSecretsKEKFileEnvKey
+
+	newKMSSecretsKEKURLFlagName  = "new-kms-secrets-kek-url"         //nolint: gosec
+	newKMSSecretsKEKURLEnvKey    = "VC_REST_NEW_KMS_SECRETS_KEK_URL" //nolint: gosec
+	newKMSSecretsKEKURLFlagUsage = "URL of the key in a remote (web) KMS used as new key encryption key. " +
+		commonEnvVarUsageText + newKMSSecretsKEKURLEnvKey
+)
+
+var errNewKEKNotSet = errors.New("new key encryption key not set")
+
+// GetRotateKEKCmd returns the Cobra command rotating the key encryption key of the KMS secrets.
+func GetRotateKEKCmd() *cobra.Command {
+	rotateKEKCmd := &cobra.Command{
+		Use:   "rotate-kek",
+		Short: "Rotate the KMS secrets key encryption key",
+		Long: "Replaces the KMS master key with a new one wrapped with the new key encryption key and re-wraps " +
+			"all the keys stored in the KMS secrets database. vc-rest must be stopped during the rotation. An " +
+			"interrupted rotation is resumed by running the command again with the same keys.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return rotateKEK(cmd)
+		},
+	}
+
+	rotateKEKCmd.Flags().StringP(kmsSecretsDatabaseTypeFlagName, kmsSecretsDatabaseTypeFlagShorthand, "",
+		kmsSecretsDatabaseTypeFlagUsage)
+	rotateKEKCmd.Flags().StringP(kmsSecretsDatabaseURLFlagName, kmsSecretsDatabaseURLFlagShorthand, "",
+		kmsSecretsDatabaseURLFlagUsage)
+	rotateKEKCmd.Flags().StringP(kmsSecretsDatabasePrefixFlagName, "", "", kmsSecretsDatabasePrefixFlagUsage)
+	rotateKEKCmd.Flags().StringP(kmsSecretsKEKPassphraseFlagName, "", "", kmsSecretsKEKPassphraseFlagUsage)
+	rotateKEKCmd.Flags().StringP(kmsSecretsKEKFileFlagName, "", "", kmsSecretsKEKFileFlagUsage)
+	rotateKEKCmd.Flags().StringP(kmsSecretsKEKURLFlagName, "", "", kmsSecretsKEKURLFlagUsage)
+	rotateKEKCmd.Flags().StringP(newKMSSecretsKEKPassphraseFlagName, "", "", newKMSSecretsKEKPassphraseFlagUsage)
+	rotateKEKCmd.Flags().StringP(newKMSSecretsKEKFileFlagName, "", "", newKMSSecretsKEKFileFlagUsage)
+	rotateKEKCmd.Flags().StringP(newKMSSecretsKEKURLFlagName, "", "", newKMSSecretsKEKURLFlagUsage)
+	rotateKEKCmd.Flags().StringP(tlsSystemCertPoolFlagName, "", "", tlsSystemCertPoolFlagUsage)
+	rotateKEKCmd.Flags().StringArrayP(tlsCACertsFlagName, "", []string{}, tlsCACertsFlagUsage)
+
+	return rotateKEKCmd
+}
+
+// nolint: funlen
+func rotateKEK(cmd *cobra.Command) error {
+	kmsSecretsDatabaseType, err := cmdutils.GetUserSetVarFromString(cmd, kmsSecretsDatabaseTypeFlagName,
+		kmsSecretsDatabaseTypeEnvKey, false)
+	if err != nil {
+		return err
+	}
+
+	kmsSecretsDatabaseURL, err := cmdutils.GetUserSetVarFromString(cmd, kmsSecretsDatabaseURLFlagName,
+		kmsSecretsDatabaseURLEnvKey, true)
+	if err != nil {
+		return err
+	}
+
+	kmsSecretsDatabasePrefix, err := cmdutils.GetUserSetVarFromString(cmd, kmsSecretsDatabasePrefixFlagName,
+		kmsSecretsDatabasePrefixEnvKey, true)
+	if err != nil {
+		return err
+	}
+
+	currentKEKParams, err := getKEKParameters(cmd, kmsSecretsKEKPassphraseFlagName, kmsSecretsKEKPassphraseEnvKey,
+		kmsSecretsKEKFileFlagName, kmsSecretsKEKFileEnvKey, kmsSecretsKEKURLFlagName, kmsSecretsKEKURLEnvKey)
+	if err != nil {
+		return err
+	}
+
+	newKEKParams, err := getKEKParameters(cmd, newKMSSecretsKEKPassphraseFlagName, newKMSSecretsKEKPassphraseEnvKey,
+		newKMSSecretsKEKFileFlagName, newKMSSecretsKEKFileEnvKey, newKMSSecretsKEKURLFlagName,
+		newKMSSecretsKEKURLEnvKey)
+	if err != nil {
+		return err
+	}
+
+	tlsSystemCertPool, tlsCACerts, err := getTLS(cmd)
+	if err != nil {
+		return err
+	}
+
+	rootCAs, err := tlsutils.GetCertPool(tlsSystemCertPool, tlsCACerts)
+	if err != nil {
+		return err
+	}
+
+	httpClient := createHTTPClients(nil, &tls.Config{RootCAs: rootCAs}).Client()
+
+	currentKEK, err := createKEK(currentKEKParams, httpClient)
+	if err != nil {
+		return err
+	}
+
+	newKEK, err := createKEK(newKEKParams, httpClient)
+	if err != nil {
+		return err
+	}
+
+	if newKEK == nil {
+		return errNewKEKNotSet
+	}
+
+	kmsSecretsProvider, err := createKMSSecretsProvider(&dbParameters{
+		kmsSecretsDatabaseType:   kmsSecretsDatabaseType,
+		kmsSecretsDatabaseURL:    kmsSecretsDatabaseURL,
+		kmsSecretsDatabasePrefix: kmsSecretsDatabasePrefix,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = masterkey.Rotate(kmsSecretsProvider, currentKEK, newKEK)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("KMS secrets key encryption key rotated")
+
+	return nil
+}
@@ -0,0 +1,59 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package startcmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateKEKCmd(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		rotateKEKCmd := GetRotateKEKCmd()
+		rotateKEKCmd.SetArgs([]string{"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsKEKPassphraseFlagName, "current",
+			"--" + newKMSSecretsKEKPassphraseFlagName, "new"})
+
+		require.NoError(t, rotateKEKCmd.Execute())
+	})
+
+	t.Run("test error - missing kms secrets database type", func(t *testing.T) {
+		rotateKEKCmd := GetRotateKEKCmd()
+		rotateKEKCmd.SetArgs([]string{"--" + newKMSSecretsKEKPassphraseFlagName, "new"})
+
+		err := rotateKEKCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), kmsSecretsDatabaseTypeFlagName)
+	})
+
+	t.Run("test error - new kek not set", func(t *testing.T) {
+		rotateKEKCmd := GetRotateKEKCmd()
+		rotateKEKCmd.SetArgs([]string{"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsKEKPassphraseFlagName, "current"})
+
+		require.Equal(t, errNewKEKNotSet, rotateKEKCmd.Execute())
+	})
+
+	t.Run("test error - multiple new kek sources", func(t *testing.T) {
+		rotateKEKCmd := GetRotateKEKCmd()
+		rotateKEKCmd.SetArgs([]string{"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+			"--" + newKMSSecretsKEKPassphraseFlagName, "new",
+			"--" + newKMSSecretsKEKFileFlagName, "/kek/file"})
+
+		err := rotateKEKCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "only one of new-kms-secrets-kek-passphrase")
+	})
+
+	t.Run("test error - invalid kms secrets database type", func(t *testing.T) {
+		rotateKEKCmd := GetRotateKEKCmd()
+		rotateKEKCmd.SetArgs([]string{"--" + kmsSecretsDatabaseTypeFlagName, "invalid",
+			"--" + newKMSSecretsKEKPassphraseFlagName, "new"})
+
+		require.Error(t, rotateKEKCmd.Execute())
+	})
+}
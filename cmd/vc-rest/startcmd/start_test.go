@@ -6,18 +6,47 @@ SPDX-License-Identifier: Apache-2.0
 package startcmd
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/btcsuite/btcutil/base58"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
 	ariesmockstorage "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
-	"github.com/hyperledger/aries-framework-go/pkg/storage"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/vdri/key"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/require"
 	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/httpclient"
+	"github.com/trustbloc/edge-service/pkg/httpsig"
+	"github.com/trustbloc/edge-service/pkg/restapi/openapi"
+	verifierops "github.com/trustbloc/edge-service/pkg/restapi/verifier/operation"
+	"github.com/trustbloc/edge-service/pkg/vdri/web"
 )
 
 type mockServer struct{}
@@ -26,6 +55,10 @@ func (s *mockServer) ListenAndServe(host string, handler http.Handler) error {
 	return nil
 }
 
+func (s *mockServer) ListenAndServeTLS(host string, tlsConfig *tls.Config, handler http.Handler) error {
+	return nil
+}
+
 func TestStartCmdContents(t *testing.T) {
 	startCmd := GetStartCmd(&mockServer{})
 
@@ -33,213 +66,1962 @@ func TestStartCmdContents(t *testing.T) {
 	require.Equal(t, "Start vc-rest", startCmd.Short)
 	require.Equal(t, "Start vc-rest inside the edge-service", startCmd.Long)
 
-	checkFlagPropertiesCorrect(t, startCmd, hostURLFlagName, hostURLFlagShorthand, hostURLFlagUsage)
-}
+	checkFlagPropertiesCorrect(t, startCmd, hostURLFlagName, hostURLFlagShorthand, hostURLFlagUsage)
+}
+
+func TestStartCmdWithBlankArg(t *testing.T) {
+	t.Run("test blank host url arg", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{"--" + hostURLFlagName, "", "--" + edvURLFlagName, ""}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Equal(t, "host-url value is empty", err.Error())
+	})
+
+	t.Run("test blank edv url arg", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{"--" + hostURLFlagName, "test", "--" + edvURLFlagName, ""}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Equal(t, "edv-url value is empty", err.Error())
+	})
+
+	t.Run("test blank bloc domain arg", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{"--" + hostURLFlagName, "test", "--" + edvURLFlagName, "test", "--" + blocDomainFlagName, ""}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Equal(t, "bloc-domain value is empty", err.Error())
+	})
+
+	t.Run("test blank database type arg", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{"--" + hostURLFlagName, "test", "--" + edvURLFlagName, "test",
+			"--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, ""}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Equal(t, "database-type value is empty", err.Error())
+	})
+
+	t.Run("test blank mode type arg", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{"--" + hostURLFlagName, "test", "--" + edvURLFlagName, "test",
+			"--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + modeFlagName, ""}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Equal(t, "mode value is empty", err.Error())
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{"--" + hostURLFlagName, "test", "--" + edvURLFlagName, "test",
+			"--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + modeFlagName, "invalid"}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported mode")
+	})
+}
+
+func TestStartCmdWithMissingArg(t *testing.T) {
+	t.Run("test missing host url arg", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Equal(t,
+			"Neither host-url (command line flag) nor VC_REST_HOST_URL (environment variable) have been set.",
+			err.Error())
+	})
+	t.Run("test missing edv url arg", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		args := []string{"--" + hostURLFlagName, "localhost:8080"}
+		startCmd.SetArgs(args)
+
+		err := startCmd.Execute()
+
+		require.Error(t, err)
+		require.Equal(t,
+			"Neither edv-url (command line flag) nor EDV_REST_HOST_URL (environment variable) have been set.",
+			err.Error())
+	})
+}
+
+func TestStartCmdWithBlankEnvVar(t *testing.T) {
+	t.Run("test blank host env var", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		err := os.Setenv(hostURLEnvKey, "")
+		require.NoError(t, err)
+
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Equal(t, "VC_REST_HOST_URL value is empty", err.Error())
+	})
+
+	t.Run("test blank edv url env var", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+
+		err := os.Setenv(hostURLEnvKey, "localhost:8080")
+		require.NoError(t, err)
+
+		err = os.Setenv(edvURLEnvKey, "")
+		require.NoError(t, err)
+
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Equal(t, "EDV_REST_HOST_URL value is empty", err.Error())
+	})
+}
+
+func TestStartCmdCreateKMSFailure(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeCouchDBOption, "--" + kmsSecretsDatabaseURLFlagName,
+		"badURL"}
+	startCmd.SetArgs(args)
+
+	err := startCmd.Execute()
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "failed to create db")
+}
+
+func TestStartCmdWithInvalidDatabaseMaxOpenConns(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMySQLOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + databaseMaxOpenConnsFlagName, "many"}
+	startCmd.SetArgs(args)
+
+	err := startCmd.Execute()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to parse database max open connections many")
+}
+
+func TestStartCmdWithInvalidDatabaseTTL(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "invalid duration", args: []string{"--" + databaseTTLFlagName, "long"},
+			err: "invalid value for database-ttl"},
+		{name: "too short", args: []string{"--" + databaseTTLFlagName, "1h"},
+			err: "invalid value for database-ttl: must be at least 24h0m0s"},
+		{name: "not longer than the exchanges", args: []string{"--" + databaseTTLFlagName, "48h",
+			"--" + exchangeTTLFlagName, "48h"}, err: "invalid value for database-ttl: must be longer than the exchange-ttl"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			startCmd := GetStartCmd(&mockServer{})
+
+			startCmd.SetArgs(append([]string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+				"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName,
+				databaseTypeMongoDBOption, "--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}, tc.args...))
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestMongoDBOptions(t *testing.T) {
+	require.Len(t, mongoDBOptions(&dbParameters{databasePrefix: "vcs"}), 2)
+	require.Len(t, mongoDBOptions(&dbParameters{databasePrefix: "vcs", databaseTTL: 48 * time.Hour}),
+		2+len(transientStores()))
+}
+
+func TestStartCmdWithNegativeMaxRetries(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + maxRetriesFlagName, "-5"}
+	startCmd.SetArgs(args)
+
+	err := startCmd.Execute()
+	require.EqualError(t, err, `the given max retries value "-5" is not a valid non-negative integer: `+
+		`strconv.ParseUint: parsing "-5": invalid syntax`)
+}
+
+func TestStartCmdWithNegativeInitialBackoff(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + initialBackoffMillisecFlagName, "-500"}
+	startCmd.SetArgs(args)
+
+	err := startCmd.Execute()
+	require.EqualError(t, err, `the given initial backoff value "-500" is not a valid non-negative integer: `+
+		`strconv.ParseUint: parsing "-500": invalid syntax`)
+}
+
+func TestStartCmdWithInvalidFloatingPointBackoffFactor(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + backoffFactorFlagName, "1.1.1"}
+	startCmd.SetArgs(args)
+
+	err := startCmd.Execute()
+	require.EqualError(t, err, `the given backoff factor "1.1.1" is not a valid floating point number: `+
+		`strconv.ParseFloat: parsing "1.1.1": invalid syntax`)
+}
+
+func TestStartCmdWithNegativeBackoffFactor(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + backoffFactorFlagName, "-2"}
+	startCmd.SetArgs(args)
+
+	err := startCmd.Execute()
+	require.Equal(t, errNegativeBackoffFactor, err)
+}
+
+func TestStartCmdValidArgs(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
+		"--" + requestTokensFlagName, "token1=tk1", "--" + requestTokensFlagName, "token2=tk2",
+		"--" + requestTokensFlagName, "token2=tk2=1"}
+	startCmd.SetArgs(args)
+
+	err := startCmd.Execute()
+
+	require.Nil(t, err)
+}
+
+func TestStartCmdWithHostURLExternal(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test absolute URL", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+hostURLExternalFlagName, "https://issuer.example.com"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - URL without scheme", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+hostURLExternalFlagName, "issuer.example.com:443"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err, "invalid host url external issuer.example.com:443: must be an absolute URL, "+
+			"e.g. https://issuer.example.com")
+	})
+}
+
+func TestStartCmdWithBasePath(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "token"}
+
+	t.Run("test base path", func(t *testing.T) {
+		srv := &handlerServer{}
+
+		startCmd := GetStartCmd(srv)
+		startCmd.SetArgs(append(args, "--"+basePathFlagName, "/vcs/",
+			"--"+hostURLExternalFlagName, "https://example.com/vcs/"))
+
+		require.NoError(t, startCmd.Execute())
+
+		// the health check isn't protected by the token under the base path either
+		rr := httptest.NewRecorder()
+		srv.handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/vcs"+healthCheckEndpoint, nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = httptest.NewRecorder()
+		srv.handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, healthCheckEndpoint, nil))
+		require.Equal(t, http.StatusNotFound, rr.Code)
+
+		req := httptest.NewRequest(http.MethodGet, "/vcs"+openapi.Endpoint, nil)
+		req.Header.Set("Authorization", "Bearer token")
+
+		rr = httptest.NewRecorder()
+		srv.handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		doc := &openapi.Document{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), doc))
+		require.Equal(t, []openapi.Server{{URL: "https://example.com/vcs"}}, doc.Servers)
+	})
+
+	t.Run("test error - relative base path", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+basePathFlagName, "vcs"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err, "invalid base path vcs: must be an absolute path, e.g. /vcs")
+	})
+}
+
+func TestStartCmdGovernanceMode(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + modeFlagName, string(governance)}
+	startCmd.SetArgs(args)
+
+	require.NoError(t, startCmd.Execute())
+}
+
+func TestStartCmdWithRemoteKMS(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsURLFlagName, "https://kms.example.com/kms/keystores/ks1"}
+	startCmd.SetArgs(args)
+
+	err := startCmd.Execute()
+
+	require.Nil(t, err)
+}
+
+func TestStartCmdWithKEK(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test passphrase", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+kmsSecretsKEKPassphraseFlagName, "passphrase"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test passphrase file", func(t *testing.T) {
+		file, err := ioutil.TempFile("", "kek")
+		require.NoError(t, err)
+
+		defer func() { require.NoError(t, os.Remove(file.Name())) }()
+
+		_, err = file.WriteString("passphrase\n")
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+kmsSecretsKEKFileFlagName, file.Name()))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test remote kms key", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+kmsSecretsKEKURLFlagName, "https://kms.example.com/keys/kek"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to encrypt")
+	})
+
+	t.Run("test error - passphrase file not found", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+kmsSecretsKEKFileFlagName, "/not/found"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read kek file")
+	})
+
+	t.Run("test error - multiple kek sources", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+kmsSecretsKEKPassphraseFlagName, "passphrase",
+			"--"+kmsSecretsKEKURLFlagName, "https://kms.example.com/keys/kek"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err, "only one of kms-secrets-kek-passphrase, kms-secrets-kek-file and "+
+			"kms-secrets-kek-url can be set")
+	})
+}
+
+func TestStartCmdWithProfileCache(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test mem cache", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+profileCacheTypeFlagName, profileCacheTypeMemOption,
+			"--"+profileCacheTTLFlagName, "1m", "--"+profileCacheSizeFlagName, "10"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - redis not reachable", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+profileCacheTypeFlagName, profileCacheTypeRedisOption,
+			"--"+profileCacheRedisURLFlagName, "redis://localhost:1"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to connect to redis")
+	})
+
+	t.Run("test error - invalid cache type", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+profileCacheTypeFlagName, "invalid"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "profile cache type not set to a valid type")
+	})
+
+	t.Run("test error - invalid ttl", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+profileCacheTTLFlagName, "1 minute"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse profile cache ttl")
+	})
+
+	t.Run("test error - invalid size", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+profileCacheSizeFlagName, "many"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse profile cache size")
+	})
+}
+
+func TestStartCmdWithDIDResolutionCache(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test cache disabled", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+didResolutionCacheTTLFlagName, "0s"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - invalid ttl", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+didResolutionCacheTTLFlagName, "1 minute"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse did resolution cache ttl")
+	})
+}
+
+func TestStartCmdWithExpiryCheckInterval(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test revocation on expiry disabled", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+expiryCheckIntervalFlagName, "0s"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - invalid interval", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+expiryCheckIntervalFlagName, "hourly"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse expiry check interval")
+	})
+}
+
+func TestStartCmdWithExpiryNoticeInterval(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test expiry notices disabled", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+expiryNoticeIntervalFlagName, "0s"))
+
+		require.NoError(t, startCmd.Execute())
+
+		interval, err := getExpiryNoticeInterval(startCmd)
+		require.NoError(t, err)
+		require.Zero(t, interval)
+	})
+
+	t.Run("test error - invalid interval", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+expiryNoticeIntervalFlagName, "daily"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse expiry notice interval")
+	})
+}
+
+func TestStartCmdWithCSLCache(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test cache enabled", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+cslCacheTTLFlagName, "30s"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - invalid ttl", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+cslCacheTTLFlagName, "30"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse csl cache ttl")
+	})
+}
+
+func TestStartCmdWithCSLShards(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test shards set", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+cslShardsFlagName, "4"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - invalid shards", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+cslShardsFlagName, "0"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+cslShardsFlagName)
+	})
+}
+
+func TestStartCmdWithExchangeTTL(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test ttl set", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+exchangeTTLFlagName, "1h"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - invalid ttl", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+exchangeTTLFlagName, "1"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+exchangeTTLFlagName)
+	})
+}
+
+func TestStartCmdWithDIDAnchoringTimeout(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test timeout set", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+didAnchoringTimeoutFlagName, "30s"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - invalid timeout", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+didAnchoringTimeoutFlagName, "30"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+didAnchoringTimeoutFlagName)
+	})
+}
+
+func TestStartCmdWithVerificationCache(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test cache enabled", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+verificationCacheTTLFlagName, "5m"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - invalid ttl", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+verificationCacheTTLFlagName, "5"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse verification cache ttl")
+	})
+}
+
+func TestStartCmdWithOpenAPIDocument(t *testing.T) {
+	srv := &handlerServer{}
+
+	startCmd := GetStartCmd(srv)
+	startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName, "localhost:8081",
+		"--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + modeFlagName, string(verifier),
+		"--" + tokenFlagName, "token"})
+
+	require.NoError(t, startCmd.Execute())
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	req.Header.Set("Authorization", "Bearer token")
+
+	rr := httptest.NewRecorder()
+	srv.handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	doc := &openapi.Document{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), doc))
+
+	// the handlers of the verifier mode only
+	require.Contains(t, doc.Paths, "/{id}/verifier/credentials")
+	require.NotContains(t, doc.Paths, "/{profileID}/credentials/issueCredential")
+	require.Contains(t, doc.Components.SecuritySchemes, "bearerAuth")
+}
+
+// handlerServer keeps the handler of the service, to send it requests
+type handlerServer struct {
+	handler   http.Handler
+	tlsConfig *tls.Config
+}
+
+func (s *handlerServer) ListenAndServe(host string, handler http.Handler) error {
+	s.handler = handler
+
+	return nil
+}
+
+func (s *handlerServer) ListenAndServeTLS(host string, tlsConfig *tls.Config, handler http.Handler) error {
+	s.handler = handler
+	s.tlsConfig = tlsConfig
+
+	return nil
+}
+
+func TestStartCmdWithModes(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName, "localhost:8081",
+		"--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test list of modes", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+modeFlagName, "issuer, verifier,holder"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test combined mode", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+modeFlagName, string(combined)))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test mode path prefix", func(t *testing.T) {
+		srv := &handlerServer{}
+
+		startCmd := GetStartCmd(srv)
+		startCmd.SetArgs(append(args, "--"+modeFlagName, "verifier,holder", "--"+modePathPrefixFlagName, "true"))
+
+		require.NoError(t, startCmd.Execute())
+
+		// the profile isn't found by the verifier under its prefix
+		rr := httptest.NewRecorder()
+		srv.handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/verifier/verifier/profile/p1", nil))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = httptest.NewRecorder()
+		srv.handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/verifier/profile/p1", nil))
+		require.Equal(t, http.StatusNotFound, rr.Code)
+
+		rr = httptest.NewRecorder()
+		srv.handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, openapi.Endpoint, nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		doc := &openapi.Document{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), doc))
+		require.Contains(t, doc.Paths, "/holder/{profileID}/prove/presentations")
+		require.NotContains(t, doc.Paths, "/{profileID}/prove/presentations")
+	})
+
+	t.Run("test error - invalid mode in list", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+modeFlagName, "issuer,invalid"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported mode: invalid")
+	})
+
+	t.Run("test error - invalid mode path prefix", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+modePathPrefixFlagName, "yes"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for mode-path-prefix")
+	})
+}
+
+func TestStartCmdWithHTTPClientParameters(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test timeouts and proxy", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+httpConnectTimeoutFlagName, "5s", "--"+httpReadTimeoutFlagName, "30s",
+			"--"+httpProxyURLFlagName, "http://proxy.example.com:3128"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - invalid connect timeout", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+httpConnectTimeoutFlagName, "5"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for http-connect-timeout")
+	})
+
+	t.Run("test error - invalid read timeout", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+httpReadTimeoutFlagName, "30"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for http-read-timeout")
+	})
+
+	t.Run("test error - invalid proxy URL", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+httpProxyURLFlagName, "://proxy"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for http-proxy-url")
+	})
+}
+
+func TestStartCmdWithDependencyTLS(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	certFile, keyFile := writeTLSFiles(t)
+
+	defer func() {
+		require.NoError(t, os.Remove(certFile))
+		require.NoError(t, os.Remove(keyFile))
+	}()
+
+	t.Run("test TLS of the dependencies", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--edv-tls-cacerts", certFile, "--edv-tls-client-cert", certFile,
+			"--edv-tls-client-key", keyFile, "--uni-registrar-tls-min-version", "1.2",
+			"--universal-resolver-tls-cacerts", certFile, "--"+universalResolverURLFlagName,
+			"https://uniresolver.example.com", "--webhook-tls-min-version", "1.3",
+			"--"+verificationAlertWebhookURLFlagName, "https://alerts.example.com"))
+
+		require.NoError(t, startCmd.Execute())
+
+		params, err := getDependencyTLSParameters(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, map[string]*dependencyTLSParameters{
+			edvDependency:               {caCerts: []string{certFile}, clientCert: certFile, clientKey: keyFile},
+			uniRegistrarDependency:      {caCerts: []string{}, minVersion: tls.VersionTLS12},
+			universalResolverDependency: {caCerts: []string{certFile}},
+			webhookDependency:           {caCerts: []string{}, minVersion: tls.VersionTLS13},
+		}, params)
+	})
+
+	t.Run("test TLS of the dependencies from the environment", func(t *testing.T) {
+		require.NoError(t, os.Setenv("VC_REST_UNI_REGISTRAR_TLS_MIN_VERSION", "1.2"))
+
+		defer func() {
+			require.NoError(t, os.Unsetenv("VC_REST_UNI_REGISTRAR_TLS_MIN_VERSION"))
+		}()
+
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(args)
+
+		require.NoError(t, startCmd.Execute())
+
+		params, err := getDependencyTLSParameters(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, uint16(tls.VersionTLS12), params[uniRegistrarDependency].minVersion)
+	})
+
+	t.Run("test error - client certificate without key", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--edv-tls-client-cert", certFile))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err,
+			"the TLS client certificate of edv requires the edv-tls-client-cert and edv-tls-client-key parameters")
+	})
+
+	t.Run("test error - invalid TLS version", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--webhook-tls-min-version", "1.4"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for webhook-tls-min-version")
+	})
+
+	t.Run("test error - invalid CA certs", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--uni-registrar-tls-cacerts", "/invalid/ca.pem"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create the TLS configuration of uni-registrar")
+	})
+
+	t.Run("test error - invalid client certificate", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--edv-tls-client-cert", keyFile, "--edv-tls-client-key", keyFile))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to load the TLS client certificate")
+	})
+}
+
+func TestCreateDependencyHTTPClients(t *testing.T) {
+	rootCAs := x509.NewCertPool()
+	httpClients := httpclient.New(httpclient.WithTLSConfig(&tls.Config{RootCAs: rootCAs}))
+
+	clients, err := createDependencyHTTPClients(&vcRestParameters{dependencyTLS: map[string]*dependencyTLSParameters{
+		uniRegistrarDependency: {minVersion: tls.VersionTLS12},
+	}}, httpClients, rootCAs)
+	require.NoError(t, err)
+
+	require.Equal(t, httpClients, clients.edv)
+	require.Equal(t, httpClients, clients.universalResolver)
+	require.Equal(t, httpClients, clients.webhook)
+
+	require.NotEqual(t, httpClients, clients.uniRegistrar)
+	require.Equal(t, rootCAs, clients.uniRegistrar.TLSConfig().RootCAs)
+	require.Equal(t, uint16(tls.VersionTLS12), clients.uniRegistrar.TLSConfig().MinVersion)
+}
+
+func TestStartCmdWithVerificationTimeout(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test timeout set", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+verificationTimeoutFlagName, "10s"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - invalid timeout", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+verificationTimeoutFlagName, "10"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse verification timeout")
+	})
+}
+
+func TestStartCmdWithVerificationBatchSize(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test batch size set", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+verificationBatchSizeFlagName, "20"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - invalid batch size", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+verificationBatchSizeFlagName, "0"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for verification-batch-size")
+	})
+}
+
+func TestStartCmdWithVerificationAlert(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test alert set", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+verificationAlertWebhookURLFlagName, "https://alerts.example.com",
+			"--"+verificationAlertFailureRateFlagName, "0.2", "--"+verificationAlertWindowFlagName, "10m",
+			"--"+verificationAlertMinVerificationsFlagName, "50"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test alert defaults", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+verificationAlertWebhookURLFlagName, "https://alerts.example.com"))
+
+		require.NoError(t, startCmd.Execute())
+
+		alert, err := getVerificationAlert(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, &verifierops.FailureAlertConfig{WebhookURL: "https://alerts.example.com",
+			FailureRate: verificationAlertFailureRateDefault, Window: verificationAlertWindowDefault,
+			MinVerifications: verificationAlertMinVerificationsDefault}, alert)
+	})
+
+	for flag, value := range map[string]string{
+		verificationAlertWebhookURLFlagName:       "alerts",
+		verificationAlertFailureRateFlagName:      "1.5",
+		verificationAlertWindowFlagName:           "48h",
+		verificationAlertMinVerificationsFlagName: "0",
+	} {
+		flag, value := flag, value
+
+		t.Run("test error - invalid "+flag, func(t *testing.T) {
+			startCmd := GetStartCmd(&mockServer{})
+			startCmd.SetArgs(append(args, "--"+verificationAlertWebhookURLFlagName, "https://alerts.example.com",
+				"--"+flag, value))
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid value for "+flag)
+		})
+	}
+}
+
+func TestStartCmdWithEventBroker(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test kafka broker", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+eventBrokerFlagName, "kafka", "--"+eventBrokerURLFlagName,
+			"kafka1:9092,kafka2:9092", "--"+eventBrokerDependency+dependencyTLSMinVersionFlagSuffix, "1.2"))
+
+		require.NoError(t, startCmd.Execute())
+
+		params, err := getEventParameters(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, &eventParameters{broker: "kafka", urls: []string{"kafka1:9092", "kafka2:9092"},
+			topic: eventTopicDefault}, params)
+	})
+
+	t.Run("test error - nats server unavailable", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+eventBrokerFlagName, "nats", "--"+eventBrokerURLFlagName,
+			"tls://localhost:1", "--"+eventTopicFlagName, "issuer.events"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create the nats event publisher")
+	})
+
+	t.Run("test error - unsupported broker", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+eventBrokerFlagName, "amqp", "--"+eventBrokerURLFlagName,
+			"amqp://localhost:5672"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err, "unsupported event broker: amqp")
+	})
+
+	t.Run("test error - missing broker servers", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+eventBrokerFlagName, "kafka"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err, "event broker kafka requires the servers of the broker (event-broker-url)")
+	})
+}
+
+func TestStartCmdWithEventLog(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test event log enabled", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+eventLogFlagName, "true", "--"+eventLogSnapshotIntervalFlagName, "100"))
+
+		require.NoError(t, startCmd.Execute())
+
+		params, err := getEventLogParameters(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, &eventLogParameters{snapshotInterval: 100}, params)
+	})
+
+	t.Run("test event log disabled", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+eventLogFlagName, "false"))
+
+		require.NoError(t, startCmd.Execute())
+
+		params, err := getEventLogParameters(startCmd)
+		require.NoError(t, err)
+		require.Nil(t, params)
+	})
+
+	t.Run("test error - invalid event log", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+eventLogFlagName, "yes"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for event-log")
+	})
+
+	t.Run("test error - invalid snapshot interval", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+eventLogFlagName, "true", "--"+eventLogSnapshotIntervalFlagName, "-1"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for event-log-snapshot-interval")
+	})
+}
+
+func TestStartCmdWithRevocationRegistryWebhooks(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test webhooks set", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+revocationRegistryWebhooksFlagName,
+			"ebsi=https://ebsi-adapter.example.com/status?token=a=b",
+			"--"+revocationRegistryWebhooksFlagName, "indy=http://localhost:9090"))
+
+		require.NoError(t, startCmd.Execute())
+
+		webhooks, err := getRevocationRegistryWebhooks(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, []*revocationRegistryWebhook{
+			{name: "ebsi", url: "https://ebsi-adapter.example.com/status?token=a=b"},
+			{name: "indy", url: "http://localhost:9090"},
+		}, webhooks)
+	})
+
+	for name, value := range map[string]string{
+		"format":    "https://ebsi-adapter.example.com/status",
+		"URL":       "ebsi=ebsi-adapter.example.com/status",
+		"duplicate": "ebsi=http://localhost:9090",
+	} {
+		value := value
+
+		t.Run("test error - invalid "+name, func(t *testing.T) {
+			startCmd := GetStartCmd(&mockServer{})
+			startCmd.SetArgs(append(args, "--"+revocationRegistryWebhooksFlagName, "ebsi=http://localhost:9091",
+				"--"+revocationRegistryWebhooksFlagName, value))
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid value for "+revocationRegistryWebhooksFlagName)
+		})
+	}
+}
+
+func TestStartCmdWithTrustedJWKS(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test trusted key sets set", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+trustedJWKSFlagName,
+			"https://issuer.example.com=https://issuer.example.com/jwks",
+			"--"+trustedJWKSFlagName, "did:web:example.com=http://localhost:9090/jwks?kid=a=b"))
+
+		require.NoError(t, startCmd.Execute())
+
+		trusted, err := getTrustedJWKS(startCmd)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{
+			"https://issuer.example.com": "https://issuer.example.com/jwks",
+			"did:web:example.com":        "http://localhost:9090/jwks?kid=a=b",
+		}, trusted)
+
+		require.Len(t, keyFetchers(&vcRestParameters{trustedJWKS: trusted}, httpclient.New(), nil), 1)
+		require.Empty(t, keyFetchers(&vcRestParameters{}, httpclient.New(), nil))
+	})
+
+	for name, value := range map[string]string{
+		"format":    "https://issuer.example.com/jwks",
+		"URL":       "https://issuer.example.com=issuer.example.com/jwks",
+		"duplicate": "https://issuer.example.com=http://localhost:9090/jwks",
+	} {
+		value := value
+
+		t.Run("test error - invalid "+name, func(t *testing.T) {
+			startCmd := GetStartCmd(&mockServer{})
+			startCmd.SetArgs(append(args, "--"+trustedJWKSFlagName,
+				"https://issuer.example.com=http://localhost:9091/jwks", "--"+trustedJWKSFlagName, value))
+
+			err := startCmd.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "invalid value for "+trustedJWKSFlagName)
+		})
+	}
+}
+
+func TestStartCmdWithCredentialRefURLs(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test allowed URLs set", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+credentialRefURLsFlagName,
+			"https://templates.example.com/credentials/, http://localhost:9090",
+			"--"+credentialRefURLsFlagName, "https://other.example.com"))
+
+		require.NoError(t, startCmd.Execute())
+
+		allowed, err := getAllowedURLs(startCmd, credentialRefURLsFlagName, credentialRefURLsEnvKey)
+		require.NoError(t, err)
+		require.Len(t, allowed, 3)
+		require.Equal(t, "https://templates.example.com/credentials/", allowed[0].String())
+		require.Equal(t, "http://localhost:9090", allowed[1].String())
+		require.Equal(t, "https://other.example.com", allowed[2].String())
+	})
+
+	t.Run("test error - invalid URL", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+credentialRefURLsFlagName, "templates.example.com/credentials"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+credentialRefURLsFlagName)
+	})
+
+	t.Run("test verify credential allowed URLs", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+verifyCredentialURLsFlagName, "https://issuer.example.com/credentials/"))
+
+		require.NoError(t, startCmd.Execute())
+
+		allowed, err := getAllowedURLs(startCmd, verifyCredentialURLsFlagName, verifyCredentialURLsEnvKey)
+		require.NoError(t, err)
+		require.Len(t, allowed, 1)
+		require.Equal(t, "https://issuer.example.com/credentials/", allowed[0].String())
+
+		startCmd = GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+verifyCredentialURLsFlagName, "issuer.example.com"))
+
+		err = startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+verifyCredentialURLsFlagName)
+	})
+}
+
+func TestStartCmdWithAnchorTSAURL(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+	startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+		"--" + anchorTSAURLFlagName, "https://tsa.example.com"})
+
+	require.NoError(t, startCmd.Execute())
+
+	parameters, err := getVCRestParameters(startCmd)
+	require.NoError(t, err)
+	require.Equal(t, "https://tsa.example.com", parameters.anchorTSAURL)
+}
+
+func TestStartCmdWithRateLimit(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test in-memory rate limit", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+rateLimitFlagName, "0.5", "--"+rateLimitBurstFlagName, "5",
+			"--"+rateLimitKeyFlagName, "api-key", "--"+apiKeysFlagName, "client1=key1",
+			"--"+apiKeysFlagName, "client2=key2"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - api-key rate limit without API keys", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+rateLimitFlagName, "1", "--"+rateLimitKeyFlagName, "api-key"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err, "rate limit key api-key requires the API keys of the clients (api-keys)")
+	})
+
+	t.Run("test error - invalid API keys", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+apiKeysFlagName, "key1"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err, "invalid API key: must be a client=key pair")
+
+		startCmd = GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+apiKeysFlagName, "client1=key1", "--"+apiKeysFlagName, "client1=key2"))
+
+		err = startCmd.Execute()
+		require.EqualError(t, err, "duplicate API key client: client1")
+
+		startCmd = GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+apiKeysFlagName, "client1=key1", "--"+apiKeysFlagName, "client2=key1"))
+
+		err = startCmd.Execute()
+		require.EqualError(t, err, "clients client1 and client2 have the same API key")
+	})
+
+	t.Run("test error - redis not reachable", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+rateLimitFlagName, "10",
+			"--"+rateLimitRedisURLFlagName, "redis://localhost:1"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to connect to redis")
+	})
+
+	t.Run("test error - invalid rate limit", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+rateLimitFlagName, "-1"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err, "failed to parse rate limit -1: must be a non-negative number")
+	})
+
+	t.Run("test error - invalid burst", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+rateLimitFlagName, "1", "--"+rateLimitBurstFlagName, "0"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err, "failed to parse rate limit burst 0: must be a positive integer")
+	})
+
+	t.Run("test error - invalid key", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+rateLimitFlagName, "1", "--"+rateLimitKeyFlagName, "ip"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err, "unsupported rate limit key: ip")
+	})
+}
+
+func TestStartCmdWithCORS(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test allowed origins with credentials", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+corsAllowedOriginsFlagName, "https://wallet.example.com",
+			"--"+corsAllowedMethodsFlagName, "GET", "--"+corsAllowedHeadersFlagName, "Content-Type",
+			"--"+corsAllowCredentialsFlagName, "true"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - credentials allowed for any origin", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+corsAllowCredentialsFlagName, "true"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err,
+			"cors-allow-credentials requires the cors-allowed-origins parameter to list the allowed origins")
+	})
+
+	t.Run("test error - invalid allow credentials", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+corsAllowCredentialsFlagName, "yes"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for cors-allow-credentials")
+	})
+}
+
+func TestConstructCORSHandler(t *testing.T) {
+	preflight := func(handler http.Handler, origin, method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/profile", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		req.Header.Set("Access-Control-Request-Headers", "X-API-Key")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	next := http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) { rw.WriteHeader(http.StatusOK) })
+
+	t.Run("test defaults", func(t *testing.T) {
+		params, err := getCORSParameters(GetStartCmd(&mockServer{}))
+		require.NoError(t, err)
+
+		rr := preflight(constructCORSHandler(next, params), "https://wallet.example.com", http.MethodPatch)
+		require.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, http.MethodPatch, rr.Header().Get("Access-Control-Allow-Methods"))
+		require.Empty(t, rr.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("test allowed origins", func(t *testing.T) {
+		handler := constructCORSHandler(next, &corsParameters{allowedOrigins: []string{"https://wallet.example.com"},
+			allowedMethods: []string{http.MethodPost}, allowedHeaders: []string{"X-API-Key"}, allowCredentials: true})
+
+		rr := preflight(handler, "https://wallet.example.com", http.MethodPost)
+		require.Equal(t, "https://wallet.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
+
+		rr = preflight(handler, "https://other.example.com", http.MethodPost)
+		require.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+
+		rr = preflight(handler, "https://wallet.example.com", http.MethodDelete)
+		require.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestStartCmdWithMaxRequestBodySize(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test max request body size", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+maxRequestBodySizeFlagName, "1024"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - invalid max request body size", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+maxRequestBodySizeFlagName, "0"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err, "failed to parse max request body size 0: must be a positive integer")
+	})
+}
+
+func TestMaxRequestBodySizeMiddleware(t *testing.T) {
+	handler := maxRequestBodySizeMiddleware(5)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			rw.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("12345")))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("123456")))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+}
+
+func TestStartCmdWithGRPC(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + blocDomainFlagName, "domain",
+		"--" + databaseTypeFlagName, databaseTypeMemOption, "--" + kmsSecretsDatabaseTypeFlagName,
+		databaseTypeMemOption, "--" + credentialStorageFlagName, "local"}
+
+	certFile, keyFile := writeTLSFiles(t)
+
+	defer func() {
+		require.NoError(t, os.Remove(certFile))
+		require.NoError(t, os.Remove(keyFile))
+	}()
+
+	tlsArgs := append(args, "--"+grpcTLSCertFileFlagName, certFile, "--"+grpcTLSKeyFileFlagName, keyFile)
+
+	t.Run("test grpc server", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(tlsArgs, "--"+grpcHostURLFlagName, "localhost:0"))
+
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - missing tls files", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+grpcHostURLFlagName, "localhost:0"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err,
+			"the gRPC API requires the grpc-tls-cert-file and grpc-tls-key-file parameters")
+	})
+
+	t.Run("test error - invalid tls certificate", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+grpcHostURLFlagName, "localhost:0",
+			"--"+grpcTLSCertFileFlagName, keyFile, "--"+grpcTLSKeyFileFlagName, keyFile))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to load the gRPC TLS certificate")
+	})
+
+	t.Run("test error - invalid grpc host", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(tlsArgs, "--"+grpcHostURLFlagName, "localhost"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to listen on gRPC host localhost")
+	})
+}
+
+func TestStartCmdWithTLSServe(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + blocDomainFlagName, "domain",
+		"--" + databaseTypeFlagName, databaseTypeMemOption, "--" + kmsSecretsDatabaseTypeFlagName,
+		databaseTypeMemOption, "--" + credentialStorageFlagName, "local"}
+
+	certFile, keyFile := writeTLSFiles(t)
+
+	defer func() {
+		require.NoError(t, os.Remove(certFile))
+		require.NoError(t, os.Remove(keyFile))
+	}()
+
+	tlsArgs := append(args, "--"+tlsServeCertFlagName, certFile, "--"+tlsServeKeyFlagName, keyFile)
+
+	t.Run("test REST API over TLS", func(t *testing.T) {
+		srv := &handlerServer{}
+
+		startCmd := GetStartCmd(srv)
+		startCmd.SetArgs(tlsArgs)
+
+		require.NoError(t, startCmd.Execute())
+		require.NotNil(t, srv.tlsConfig)
+		require.Len(t, srv.tlsConfig.Certificates, 1)
+		require.Equal(t, tls.NoClientCert, srv.tlsConfig.ClientAuth)
+	})
+
+	t.Run("test client certificates", func(t *testing.T) {
+		srv := &handlerServer{}
+
+		startCmd := GetStartCmd(srv)
+		startCmd.SetArgs(append(tlsArgs, "--"+tlsClientCACertsFlagName, certFile,
+			"--"+tlsClientPermissionsFlagName, "client1=issuer1", "--"+tlsClientPermissionsFlagName, "admin=*"))
+
+		require.NoError(t, startCmd.Execute())
+		require.Equal(t, tls.RequireAndVerifyClientCert, srv.tlsConfig.ClientAuth)
+		require.NotNil(t, srv.tlsConfig.ClientCAs)
+
+		serve := func(subject, path string) int {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if subject != "" {
+				req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+					{Subject: pkix.Name{CommonName: subject}},
+				}}}
+			}
+
+			rr := httptest.NewRecorder()
+			srv.handler.ServeHTTP(rr, req)
+
+			return rr.Code
+		}
+
+		// the profile isn't found, the request being authorized
+		require.Equal(t, http.StatusBadRequest, serve("client1", "/profile/issuer1"))
+		require.Equal(t, http.StatusForbidden, serve("client1", "/profile/issuer2"))
+		require.Equal(t, http.StatusForbidden, serve("client1", healthCheckEndpoint))
+		require.Equal(t, http.StatusOK, serve("admin", healthCheckEndpoint))
+		require.Equal(t, http.StatusUnauthorized, serve("", healthCheckEndpoint))
+	})
+
+	t.Run("test client certificates with mode path prefix", func(t *testing.T) {
+		srv := &handlerServer{}
+
+		startCmd := GetStartCmd(srv)
+		startCmd.SetArgs(append(tlsArgs, "--"+tlsClientCACertsFlagName, certFile,
+			"--"+tlsClientPermissionsFlagName, "client1=issuer1", "--"+modeFlagName, "issuer,holder",
+			"--"+modePathPrefixFlagName, "true"))
+
+		require.NoError(t, startCmd.Execute())
+
+		serve := func(method, path string) int {
+			req := httptest.NewRequest(method, path, nil)
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+				{Subject: pkix.Name{CommonName: "client1"}},
+			}}}
+
+			rr := httptest.NewRecorder()
+			srv.handler.ServeHTTP(rr, req)
+
+			return rr.Code
+		}
+
+		// the profile of the request is routed under the prefix of its mode
+		require.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/issuer/profile/issuer1"))
+		require.Equal(t, http.StatusForbidden, serve(http.MethodGet, "/issuer/profile/issuer2"))
+		require.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/holder/issuer1/prove/presentations"))
+		require.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/holder/issuer2/prove/presentations"))
+	})
+
+	t.Run("test error - missing tls files", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+tlsServeCertFlagName, certFile))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err,
+			"the TLS of the REST API requires the tls-serve-cert and tls-serve-key parameters")
+
+		startCmd = GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+tlsClientCACertsFlagName, certFile))
+
+		err = startCmd.Execute()
+		require.EqualError(t, err,
+			"the TLS of the REST API requires the tls-serve-cert and tls-serve-key parameters")
+	})
+
+	t.Run("test error - permissions without client CA certs", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(tlsArgs, "--"+tlsClientPermissionsFlagName, "client1=issuer1"))
+
+		err := startCmd.Execute()
+		require.EqualError(t, err, "the tls-client-permissions parameter requires the tls-client-cacerts parameter")
+	})
+
+	t.Run("test error - invalid permission", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(tlsArgs, "--"+tlsClientCACertsFlagName, certFile,
+			"--"+tlsClientPermissionsFlagName, "client1"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for tls-client-permissions")
+	})
+
+	t.Run("test error - invalid tls certificate", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+tlsServeCertFlagName, keyFile, "--"+tlsServeKeyFlagName, keyFile))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to load the TLS certificate of the REST API")
+	})
+
+	t.Run("test error - invalid client CA certs", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(tlsArgs, "--"+tlsClientCACertsFlagName, "/invalid/ca.pem"))
+
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to load the client CA certs of the REST API")
+	})
+}
+
+func TestStartCmdWithHTTPSignatures(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + blocDomainFlagName, "domain",
+		"--" + databaseTypeFlagName, databaseTypeMemOption, "--" + kmsSecretsDatabaseTypeFlagName,
+		databaseTypeMemOption, "--" + credentialStorageFlagName, "local", "--" + tokenFlagName, "token"}
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	doc, err := key.New().Build(&vdriapi.PubKey{Type: vccrypto.Ed25519VerificationKey2018,
+		Value: base58.Encode(pubKey)})
+	require.NoError(t, err)
+
+	keyID := doc.PublicKey[0].ID
+
+	t.Run("test signed requests", func(t *testing.T) {
+		srv := &handlerServer{}
+
+		startCmd := GetStartCmd(srv)
+		startCmd.SetArgs(append(args, "--"+httpSignatureKeysFlagName, keyID+"=issuer1",
+			"--"+httpSignatureMaxAgeFlagName, "1m"))
+
+		require.NoError(t, startCmd.Execute())
+
+		serve := func(path string, signed bool) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+
+			if signed {
+				input := fmt.Sprintf(`("@method" "@path");created=%d;keyid=%q`, time.Now().Unix(), keyID)
+				base := "\"@method\": GET\n\"@path\": " + path + "\n\"@signature-params\": " + input
+
+				req.Header.Set(httpsig.SignatureInputHeader, "sig1="+input)
+				req.Header.Set(httpsig.SignatureHeader,
+					"sig1=:"+base64.StdEncoding.EncodeToString(ed25519.Sign(privKey, []byte(base)))+":")
+			}
+
+			rr := httptest.NewRecorder()
+			srv.handler.ServeHTTP(rr, req)
+
+			return rr
+		}
+
+		// the profile isn't found, the signed request being authorized without the bearer token
+		require.Equal(t, http.StatusBadRequest, serve("/profile/issuer1", true).Code)
+		require.Equal(t, http.StatusForbidden, serve("/profile/issuer2", true).Code)
+		require.Equal(t, http.StatusUnauthorized, serve("/profile/issuer1", false).Code)
+
+		req := httptest.NewRequest(http.MethodGet, "/profile/issuer1", nil)
+		req.Header.Set(httpsig.SignatureInputHeader, `sig1=("@method" "@path");created=1;keyid="`+keyID+`"`)
+		req.Header.Set(httpsig.SignatureHeader, "sig1=:AA==:")
+
+		rr := httptest.NewRecorder()
+		srv.handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid HTTP signature")
+	})
+
+	t.Run("test signed requests with mode path prefix", func(t *testing.T) {
+		srv := &handlerServer{}
+
+		startCmd := GetStartCmd(srv)
+		startCmd.SetArgs(append(args, "--"+httpSignatureKeysFlagName, keyID+"=issuer1",
+			"--"+modeFlagName, "issuer,verifier", "--"+modePathPrefixFlagName, "true"))
+
+		require.NoError(t, startCmd.Execute())
+
+		serve := func(path string) int {
+			input := fmt.Sprintf(`("@method" "@path");created=%d;keyid=%q`, time.Now().Unix(), keyID)
+			base := "\"@method\": GET\n\"@path\": " + path + "\n\"@signature-params\": " + input
+
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set(httpsig.SignatureInputHeader, "sig1="+input)
+			req.Header.Set(httpsig.SignatureHeader,
+				"sig1=:"+base64.StdEncoding.EncodeToString(ed25519.Sign(privKey, []byte(base)))+":")
+
+			rr := httptest.NewRecorder()
+			srv.handler.ServeHTTP(rr, req)
 
-func TestStartCmdWithBlankArg(t *testing.T) {
-	t.Run("test blank host url arg", func(t *testing.T) {
-		startCmd := GetStartCmd(&mockServer{})
+			return rr.Code
+		}
 
-		args := []string{"--" + hostURLFlagName, "", "--" + edvURLFlagName, ""}
-		startCmd.SetArgs(args)
+		// the profile of the request is routed under the prefix of its mode
+		require.Equal(t, http.StatusBadRequest, serve("/issuer/profile/issuer1"))
+		require.Equal(t, http.StatusForbidden, serve("/issuer/profile/issuer2"))
+	})
+
+	t.Run("test error - invalid key", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+httpSignatureKeysFlagName, keyID))
 
 		err := startCmd.Execute()
 		require.Error(t, err)
-		require.Equal(t, "host-url value is empty", err.Error())
+		require.Contains(t, err.Error(), "invalid value for http-signature-keys")
 	})
 
-	t.Run("test blank edv url arg", func(t *testing.T) {
+	t.Run("test error - invalid max age", func(t *testing.T) {
 		startCmd := GetStartCmd(&mockServer{})
-
-		args := []string{"--" + hostURLFlagName, "test", "--" + edvURLFlagName, ""}
-		startCmd.SetArgs(args)
+		startCmd.SetArgs(append(args, "--"+httpSignatureKeysFlagName, keyID+"=issuer1",
+			"--"+httpSignatureMaxAgeFlagName, "-1m"))
 
 		err := startCmd.Execute()
-		require.Error(t, err)
-		require.Equal(t, "edv-url value is empty", err.Error())
+		require.EqualError(t, err, "failed to parse HTTP signature max age -1m: must be a positive duration")
+
+		startCmd = GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+httpSignatureMaxAgeFlagName, "1m"))
+
+		err = startCmd.Execute()
+		require.EqualError(t, err,
+			"the http-signature-max-age parameter requires the http-signature-keys parameter")
 	})
+}
 
-	t.Run("test blank bloc domain arg", func(t *testing.T) {
+func TestHTTPServer_ListenAndServeTLS(t *testing.T) {
+	err := (&HTTPServer{}).ListenAndServeTLS("invalid host", &tls.Config{}, http.NewServeMux()) // nolint: gosec
+	require.Error(t, err)
+}
+
+func writeTLSFiles(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, err := ioutil.TempFile("", "grpc-cert-*.pem")
+	require.NoError(t, err)
+
+	require.NoError(t, pem.Encode(certFile, &pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	require.NoError(t, certFile.Close())
+
+	keyFile, err := ioutil.TempFile("", "grpc-key-*.pem")
+	require.NoError(t, err)
+
+	require.NoError(t, pem.Encode(keyFile, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	require.NoError(t, keyFile.Close())
+
+	return certFile.Name(), keyFile.Name()
+}
+
+func TestStartCmdWithCredentialStorage(t *testing.T) {
+	t.Run("test local storage without edv url", func(t *testing.T) {
 		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + blocDomainFlagName, "domain",
+			"--" + databaseTypeFlagName, databaseTypeMemOption, "--" + kmsSecretsDatabaseTypeFlagName,
+			databaseTypeMemOption, "--" + credentialStorageFlagName, "local"})
 
-		args := []string{"--" + hostURLFlagName, "test", "--" + edvURLFlagName, "test", "--" + blocDomainFlagName, ""}
-		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - edv url required for edv storage", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + blocDomainFlagName, "domain",
+			"--" + credentialStorageFlagName, "edv"})
 
 		err := startCmd.Execute()
 		require.Error(t, err)
-		require.Equal(t, "bloc-domain value is empty", err.Error())
+		require.Contains(t, err.Error(), "value is empty")
 	})
 
-	t.Run("test blank database type arg", func(t *testing.T) {
+	t.Run("test duplicate credentials", func(t *testing.T) {
 		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + blocDomainFlagName, "domain",
+			"--" + databaseTypeFlagName, databaseTypeMemOption, "--" + kmsSecretsDatabaseTypeFlagName,
+			databaseTypeMemOption, "--" + credentialStorageFlagName, "local", "--" + duplicateCredentialsFlagName,
+			"version"})
 
-		args := []string{"--" + hostURLFlagName, "test", "--" + edvURLFlagName, "test",
-			"--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, ""}
-		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
+
+	t.Run("test error - unsupported duplicate credentials option", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + duplicateCredentialsFlagName,
+			"merge"})
 
 		err := startCmd.Execute()
-		require.Error(t, err)
-		require.Equal(t, "database-type value is empty", err.Error())
+		require.EqualError(t, err, "unsupported duplicate credentials option: merge")
 	})
 
-	t.Run("test blank mode type arg", func(t *testing.T) {
+	t.Run("test vault ID scheme", func(t *testing.T) {
 		startCmd := GetStartCmd(&mockServer{})
-
-		args := []string{"--" + hostURLFlagName, "test", "--" + edvURLFlagName, "test",
+		startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName, "localhost:8081",
 			"--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
-			"--" + modeFlagName, ""}
-		startCmd.SetArgs(args)
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + vaultIDSchemeFlagName, "prefix",
+			"--" + vaultIDPrefixFlagName, "instance1-"})
 
-		err := startCmd.Execute()
-		require.Error(t, err)
-		require.Equal(t, "mode value is empty", err.Error())
+		require.NoError(t, startCmd.Execute())
 	})
 
-	t.Run("invalid mode", func(t *testing.T) {
+	t.Run("test error - unsupported vault ID scheme", func(t *testing.T) {
 		startCmd := GetStartCmd(&mockServer{})
-
-		args := []string{"--" + hostURLFlagName, "test", "--" + edvURLFlagName, "test",
-			"--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
-			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + modeFlagName, "invalid"}
-		startCmd.SetArgs(args)
+		startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + vaultIDSchemeFlagName, "did"})
 
 		err := startCmd.Execute()
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "unsupported mode")
+		require.EqualError(t, err, "unsupported vault ID scheme: did")
 	})
-}
 
-func TestStartCmdWithMissingArg(t *testing.T) {
-	t.Run("test missing host url arg", func(t *testing.T) {
+	t.Run("test error - missing vault ID prefix", func(t *testing.T) {
 		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + vaultIDSchemeFlagName, "prefix"})
 
 		err := startCmd.Execute()
-
 		require.Error(t, err)
-		require.Equal(t,
-			"Neither host-url (command line flag) nor VC_REST_HOST_URL (environment variable) have been set.",
-			err.Error())
+		require.Contains(t, err.Error(), vaultIDPrefixFlagName)
 	})
-	t.Run("test missing edv url arg", func(t *testing.T) {
+
+	t.Run("test duplicate VCs cleanup dry run", func(t *testing.T) {
 		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName, "localhost:8081",
+			"--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+			"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + duplicateVCsCleanupDryRunFlagName,
+			"true"})
 
-		args := []string{"--" + hostURLFlagName, "localhost:8080"}
-		startCmd.SetArgs(args)
+		require.NoError(t, startCmd.Execute())
+	})
 
-		err := startCmd.Execute()
+	t.Run("test error - invalid duplicate VCs cleanup dry run", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + duplicateVCsCleanupDryRunFlagName,
+			"maybe"})
 
+		err := startCmd.Execute()
 		require.Error(t, err)
-		require.Equal(t,
-			"Neither edv-url (command line flag) nor EDV_REST_HOST_URL (environment variable) have been set.",
-			err.Error())
+		require.Contains(t, err.Error(), "invalid value for duplicate-vcs-cleanup-dry-run")
 	})
-}
 
-func TestStartCmdWithBlankEnvVar(t *testing.T) {
-	t.Run("test blank host env var", func(t *testing.T) {
+	t.Run("test error - unsupported storage", func(t *testing.T) {
 		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + credentialStorageFlagName, "s3"})
 
-		err := os.Setenv(hostURLEnvKey, "")
-		require.NoError(t, err)
-
-		err = startCmd.Execute()
-		require.Error(t, err)
-		require.Equal(t, "VC_REST_HOST_URL value is empty", err.Error())
+		err := startCmd.Execute()
+		require.EqualError(t, err, "unsupported credential storage: s3")
 	})
+}
 
-	t.Run("test blank edv url env var", func(t *testing.T) {
+func TestStartCmdWithEDVParameters(t *testing.T) {
+	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption}
+
+	t.Run("test success", func(t *testing.T) {
 		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+edvMaxRetriesFlagName, "2",
+			"--"+edvCircuitBreakerThresholdFlagName, "3", "--"+edvCircuitBreakerTimeoutFlagName, "10s"))
 
-		err := os.Setenv(hostURLEnvKey, "localhost:8080")
-		require.NoError(t, err)
+		require.NoError(t, startCmd.Execute())
+	})
 
-		err = os.Setenv(edvURLEnvKey, "")
-		require.NoError(t, err)
+	t.Run("test error - invalid max retries", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+edvMaxRetriesFlagName, "-1"))
 
-		err = startCmd.Execute()
+		err := startCmd.Execute()
 		require.Error(t, err)
-		require.Equal(t, "EDV_REST_HOST_URL value is empty", err.Error())
+		require.Contains(t, err.Error(), "failed to parse EDV max retries")
 	})
-}
 
-func TestStartCmdCreateKMSFailure(t *testing.T) {
-	startCmd := GetStartCmd(&mockServer{})
+	t.Run("test error - invalid circuit breaker threshold", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+edvCircuitBreakerThresholdFlagName, "many"))
 
-	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
-		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
-		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeCouchDBOption, "--" + kmsSecretsDatabaseURLFlagName,
-		"badURL"}
-	startCmd.SetArgs(args)
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse EDV circuit breaker threshold")
+	})
 
-	err := startCmd.Execute()
-	require.NotNil(t, err)
-	require.Contains(t, err.Error(), "failed to create db")
-}
+	t.Run("test error - invalid circuit breaker timeout", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+edvCircuitBreakerTimeoutFlagName, "10"))
 
-func TestStartCmdWithNegativeMaxRetries(t *testing.T) {
-	startCmd := GetStartCmd(&mockServer{})
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse EDV circuit breaker timeout")
+	})
 
-	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
-		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
-		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + maxRetriesFlagName, "-5"}
-	startCmd.SetArgs(args)
+	t.Run("test JWE algorithm", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+edvJWEEncAlgFlagName, "A256GCM",
+			"--"+edvJWEKeyWrapAlgFlagName, "ECDH-ES+A256KW"))
 
-	err := startCmd.Execute()
-	require.EqualError(t, err, `the given max retries value "-5" is not a valid non-negative integer: `+
-		`strconv.ParseUint: parsing "-5": invalid syntax`)
-}
+		require.NoError(t, startCmd.Execute())
+	})
 
-func TestStartCmdWithNegativeInitialBackoff(t *testing.T) {
-	startCmd := GetStartCmd(&mockServer{})
+	t.Run("test error - unsupported JWE algorithm", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+edvJWEKeyWrapAlgFlagName, "ECDH-1PU+A256KW"))
 
-	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
-		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
-		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + initialBackoffMillisecFlagName, "-500"}
-	startCmd.SetArgs(args)
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported JWE algorithm ECDH-1PU+A256KW/A256GCM")
+	})
 
-	err := startCmd.Execute()
-	require.EqualError(t, err, `the given initial backoff value "-500" is not a valid non-negative integer: `+
-		`strconv.ParseUint: parsing "-500": invalid syntax`)
-}
+	t.Run("test capability invocation", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+edvCapabilityInvocationFlagName, "true"))
 
-func TestStartCmdWithInvalidFloatingPointBackoffFactor(t *testing.T) {
-	startCmd := GetStartCmd(&mockServer{})
+		require.NoError(t, startCmd.Execute())
+	})
 
-	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
-		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
-		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + backoffFactorFlagName, "1.1.1"}
-	startCmd.SetArgs(args)
+	t.Run("test error - invalid capability invocation", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		startCmd.SetArgs(append(args, "--"+edvCapabilityInvocationFlagName, "yes"))
 
-	err := startCmd.Execute()
-	require.EqualError(t, err, `the given backoff factor "1.1.1" is not a valid floating point number: `+
-		`strconv.ParseFloat: parsing "1.1.1": invalid syntax`)
+		err := startCmd.Execute()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid value for "+edvCapabilityInvocationFlagName)
+	})
 }
 
-func TestStartCmdWithNegativeBackoffFactor(t *testing.T) {
-	startCmd := GetStartCmd(&mockServer{})
+func TestEDVProfileInvoker(t *testing.T) {
+	provider := mockstore.NewMockStoreProvider()
 
-	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
-		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
-		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + backoffFactorFlagName, "-2"}
-	startCmd.SetArgs(args)
+	invoker, err := newEDVProfileInvoker(provider, nil,
+		vccrypto.New(&mockkms.KeyManager{}, &cryptomock.Crypto{SignValue: []byte("signature")},
+			&vdrimock.MockVDRIRegistry{}))
+	require.NoError(t, err)
 
-	err := startCmd.Execute()
-	require.Equal(t, errNegativeBackoffFactor, err)
-}
+	profileStore, err := vcprofile.New(provider)
+	require.NoError(t, err)
 
-func TestStartCmdValidArgs(t *testing.T) {
-	startCmd := GetStartCmd(&mockServer{})
+	require.NoError(t, profileStore.SaveProfile(&vcprofile.DataProfile{Name: "issuer", DID: "did:example:issuer",
+		Creator: "did:example:issuer#key1"}))
 
-	args := []string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
-		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
-		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption, "--" + tokenFlagName, "tk1",
-		"--" + requestTokensFlagName, "token1=tk1", "--" + requestTokensFlagName, "token2=tk2",
-		"--" + requestTokensFlagName, "token2=tk2=1"}
-	startCmd.SetArgs(args)
+	t.Run("test success", func(t *testing.T) {
+		invocation, err := invoker.Invocation("issuer")
+		require.NoError(t, err)
+		require.Equal(t, "did:example:issuer", invocation.Controller)
+		require.Equal(t, "did:example:issuer#key1", invocation.VerificationMethod)
 
-	err := startCmd.Execute()
+		signature, err := invocation.Sign([]byte("data"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("signature"), signature)
+	})
 
-	require.Nil(t, err)
+	t.Run("test error - profile not found", func(t *testing.T) {
+		_, err := invoker.Invocation("other")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get profile other")
+	})
+
+	t.Run("test error - profile store", func(t *testing.T) {
+		_, err := newEDVProfileInvoker(&mockstore.Provider{ErrCreateStore: errors.New("create error")}, nil, nil)
+		require.EqualError(t, err, "create error")
+	})
 }
 
 func TestStartCmdLogLevels(t *testing.T) {
@@ -367,6 +2149,16 @@ func TestCreateProviders(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "hostURL for new CouchDB provider can't be blank")
 	})
+	t.Run("test error from create new mysql", func(t *testing.T) {
+		err := startEdgeService(&vcRestParameters{dbParameters: &dbParameters{databaseType: databaseTypeMySQLOption}}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "DB URL for new MySQL provider can't be blank")
+	})
+	t.Run("test error from create new mongodb", func(t *testing.T) {
+		err := startEdgeService(&vcRestParameters{dbParameters: &dbParameters{databaseType: databaseTypeMongoDBOption}}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "connection string for new MongoDB provider can't be blank")
+	})
 	t.Run("test error from create new kms secrets couchdb", func(t *testing.T) {
 		err := startEdgeService(&vcRestParameters{
 			dbParameters: &dbParameters{databaseType: databaseTypeMemOption,
@@ -386,13 +2178,50 @@ func TestCreateProviders(t *testing.T) {
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "database type not set to a valid type")
 	})
+	t.Run("test error from create new status couchdb", func(t *testing.T) {
+		err := startEdgeService(&vcRestParameters{
+			dbParameters: &dbParameters{databaseType: databaseTypeMemOption,
+				kmsSecretsDatabaseType: databaseTypeMemOption, statusDatabaseType: databaseTypeCouchDBOption}}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create status store provider")
+		require.Contains(t, err.Error(), "hostURL for new CouchDB provider can't be blank")
+	})
+}
+
+func TestCreateStoreProviders(t *testing.T) {
+	t.Run("test status provider defaults to the provider", func(t *testing.T) {
+		providers, err := createStoreProviders(&vcRestParameters{dbParameters: &dbParameters{
+			databaseType: databaseTypeMemOption, kmsSecretsDatabaseType: databaseTypeMemOption}})
+		require.NoError(t, err)
+		require.True(t, providers.statusProvider == providers.provider)
+	})
+
+	t.Run("test separate status provider", func(t *testing.T) {
+		providers, err := createStoreProviders(&vcRestParameters{dbParameters: &dbParameters{
+			databaseType: databaseTypeMemOption, kmsSecretsDatabaseType: databaseTypeMemOption,
+			statusDatabaseType: databaseTypeMemOption}})
+		require.NoError(t, err)
+		require.NotNil(t, providers.statusProvider)
+		require.False(t, providers.statusProvider == providers.provider)
+	})
+}
+
+func TestStartCmdWithStatusDatabase(t *testing.T) {
+	startCmd := GetStartCmd(&mockServer{})
+	startCmd.SetArgs([]string{"--" + hostURLFlagName, "localhost:8080", "--" + edvURLFlagName,
+		"localhost:8081", "--" + blocDomainFlagName, "domain", "--" + databaseTypeFlagName, databaseTypeMemOption,
+		"--" + kmsSecretsDatabaseTypeFlagName, databaseTypeMemOption,
+		"--" + statusDatabaseTypeFlagName, databaseTypeMemOption,
+		"--" + statusDatabasePrefixFlagName, "status"})
+
+	require.NoError(t, startCmd.Execute())
 }
 
 func TestCreateKMS(t *testing.T) {
 	t.Run("fail to open master key store", func(t *testing.T) {
 		localKMS, err := createKMS(&edgeServiceProviders{
 			kmsSecretsProvider: &ariesmockstorage.MockStoreProvider{FailNamespace: "masterkey"},
-		})
+		}, nil)
 
 		require.Nil(t, localKMS)
 		require.EqualError(t, err, "failed to open store for name space masterkey")
@@ -411,7 +2240,7 @@ func TestCreateKMS(t *testing.T) {
 
 		localKMS, err := createKMS(&edgeServiceProviders{
 			kmsSecretsProvider: &ariesmockstorage.MockStoreProvider{Store: &masterKeyStore},
-		})
+		}, nil)
 		require.EqualError(t, err, "masterKeyReader is empty")
 		require.Nil(t, localKMS)
 	})
@@ -419,7 +2248,7 @@ func TestCreateKMS(t *testing.T) {
 
 func TestCreateVDRI(t *testing.T) {
 	t.Run("test error from create new universal resolver vdri", func(t *testing.T) {
-		v, err := createVDRI("wrong", &tls.Config{})
+		v, err := createVDRI("wrong", nil, &web.VDRI{}, httpclient.New())
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "failed to create new universal resolver vdri")
 		require.Nil(t, v)
@@ -433,7 +2262,8 @@ func TestCreateVDRI(t *testing.T) {
 	})
 
 	t.Run("test success", func(t *testing.T) {
-		v, err := createVDRI("localhost:8083", &tls.Config{})
+		v, err := createVDRI("localhost:8083", []string{"ion"}, &web.VDRI{},
+			httpclient.New(httpclient.WithConnectTimeout(time.Second), httpclient.WithReadTimeout(time.Second)))
 		require.NoError(t, err)
 		require.NotNil(t, v)
 	})
@@ -486,6 +2316,44 @@ func TestAcceptedDIDs(t *testing.T) {
 	})
 }
 
+func TestUniversalResolverAccept(t *testing.T) {
+	t.Run("default methods", func(t *testing.T) {
+		accept := universalResolverAccept(nil)
+		require.True(t, accept(didMethodSov))
+		require.False(t, accept("ion"))
+	})
+
+	t.Run("allow-listed methods", func(t *testing.T) {
+		accept := universalResolverAccept([]string{"ion", " btcr"})
+		require.True(t, accept("ion"))
+		require.True(t, accept("btcr"))
+		require.False(t, accept(didMethodSov))
+	})
+
+	t.Run("any method", func(t *testing.T) {
+		require.True(t, universalResolverAccept([]string{anyDIDMethod})("ion"))
+	})
+
+	t.Run("comma-separated flag values", func(t *testing.T) {
+		startCmd := GetStartCmd(&mockServer{})
+		require.NoError(t, startCmd.ParseFlags([]string{"--" + hostURLFlagName, "localhost:8080",
+			"--" + edvURLFlagName, "localhost:8081", "--" + blocDomainFlagName, "domain",
+			"--" + databaseTypeFlagName, databaseTypeMemOption, "--" + kmsSecretsDatabaseTypeFlagName,
+			databaseTypeMemOption, "--" + universalResolverMethodsFlagName, "ion, btcr",
+			"--" + universalResolverMethodsFlagName, "orb,"}))
+
+		parameters, err := getVCRestParameters(startCmd)
+		require.NoError(t, err)
+
+		accept := universalResolverAccept(parameters.uniResolverMethods)
+		require.True(t, accept("ion"))
+		require.True(t, accept("btcr"))
+		require.True(t, accept("orb"))
+		require.False(t, accept(didMethodSov))
+		require.False(t, accept(""))
+	})
+}
+
 func TestTLSSystemCertPoolInvalidArgsEnvVar(t *testing.T) {
 	startCmd := GetStartCmd(&mockServer{})
 
@@ -499,39 +2367,45 @@ func TestTLSSystemCertPoolInvalidArgsEnvVar(t *testing.T) {
 	require.Contains(t, err.Error(), "invalid syntax")
 }
 
-func TestPrepareMasterKeyReader(t *testing.T) {
-	t.Run("Unexpected error when trying to retrieve master key from store", func(t *testing.T) {
-		reader, err := prepareMasterKeyReader(
-			&ariesmockstorage.MockStoreProvider{
-				Store: &ariesmockstorage.MockStore{
-					ErrGet: errors.New("testError")}})
-		require.Equal(t, errors.New("testError"), err)
-		require.Nil(t, reader)
-	})
-	t.Run("Error when putting newly generated master key into store", func(t *testing.T) {
-		reader, err := prepareMasterKeyReader(
-			&ariesmockstorage.MockStoreProvider{
-				Store: &ariesmockstorage.MockStore{
-					ErrGet: storage.ErrDataNotFound,
-					ErrPut: errors.New("testError")}})
-		require.Equal(t, errors.New("testError"), err)
-		require.Nil(t, reader)
-	})
-}
-
 func TestValidateAuthorizationBearerToken(t *testing.T) {
 	t.Run("test invalid token", func(t *testing.T) {
 		header := make(map[string][]string)
 		header["Authorization"] = []string{"Bearer tk1"}
 		require.False(t, validateAuthorizationBearerToken(&httptest.ResponseRecorder{},
-			&http.Request{Header: header}, "tk2"))
+			&http.Request{URL: &url.URL{Path: "/profile"}, Header: header}, "tk2"))
 	})
 
 	t.Run("test valid token", func(t *testing.T) {
 		header := make(map[string][]string)
 		header["Authorization"] = []string{"Bearer tk1"}
 		require.True(t, validateAuthorizationBearerToken(&httptest.ResponseRecorder{},
-			&http.Request{Header: header}, "tk1"))
+			&http.Request{URL: &url.URL{Path: "/profile"}, Header: header}, "tk1"))
+	})
+
+	t.Run("test request without URL", func(t *testing.T) {
+		require.False(t, validateAuthorizationBearerToken(&httptest.ResponseRecorder{},
+			&http.Request{Header: make(map[string][]string)}, "tk1"))
+	})
+
+	t.Run("test health check without token", func(t *testing.T) {
+		require.True(t, validateAuthorizationBearerToken(&httptest.ResponseRecorder{},
+			&http.Request{URL: &url.URL{Path: healthCheckEndpoint}, Header: make(map[string][]string)}, "tk1"))
+	})
+
+	t.Run("test health check under the base path without token", func(t *testing.T) {
+		handler := http.StripPrefix("/vcs", authorizationMiddleware("tk1")(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/vcs"+healthCheckEndpoint, nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		// the other endpoints under the base path still require the token
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/vcs/profile", nil))
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
 	})
 }
 
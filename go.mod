@@ -7,16 +7,34 @@ module github.com/trustbloc/edge-service
 go 1.13
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.4.1
+	github.com/alicebob/miniredis/v2 v2.11.4
+	github.com/btcsuite/btcd v0.20.1-beta
 	github.com/btcsuite/btcutil v1.0.1
+	github.com/go-kivik/couchdb v2.0.0+incompatible
+	github.com/go-kivik/kivik v2.0.0+incompatible
+	github.com/go-kivik/couchdb v2.0.0+incompatible
+	github.com/go-kivik/kivik v2.0.0+incompatible
+	github.com/go-redis/redis/v7 v7.4.0
+	github.com/go-sql-driver/mysql v1.5.0
+	github.com/golang/protobuf v1.4.2
 	github.com/google/tink/go v0.0.0-20200403150819-3a14bf4b3380
 	github.com/google/uuid v1.1.1
 	github.com/gorilla/mux v1.7.4
 	github.com/hyperledger/aries-framework-go v0.1.4-0.20200528153636-1d4c39e41ae7
+	github.com/nats-io/nats.go v1.9.1
+	github.com/piprate/json-gold v0.3.0
+	github.com/prometheus/client_golang v1.7.1
+	github.com/segmentio/kafka-go v0.3.10
 	github.com/sirupsen/logrus v1.4.2
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/stretchr/testify v1.5.1
 	github.com/trustbloc/edge-core v0.1.4-0.20200603140750-8d89a0084be7
 	github.com/trustbloc/edv v0.1.4-0.20200612202422-540ab6ea9def
 	github.com/trustbloc/trustbloc-did-method v0.1.4-0.20200525135153-c9d911ac1bb7
+	go.mongodb.org/mongo-driver v1.3.4
+	google.golang.org/grpc v1.29.1
+	google.golang.org/protobuf v1.23.0
 )
 
 replace github.com/piprate/json-gold => github.com/trustbloc/json-gold v0.3.1-0.20200414173446-30d742ee949e
@@ -0,0 +1,81 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"net/http"
+)
+
+// Header is the header of the API key authenticating the client
+const Header = "X-API-Key"
+
+type clientContextKey struct{}
+
+// Authenticator authenticates the clients of the requests by their API key.
+type Authenticator struct {
+	// the clients by the SHA-256 hash of their API key
+	clients map[[sha256.Size]byte]string
+}
+
+// New returns an authenticator of the given API keys, keyed by client name.
+func New(keys map[string]string) *Authenticator {
+	clients := make(map[[sha256.Size]byte]string, len(keys))
+
+	for client, key := range keys {
+		clients[sha256.Sum256([]byte(key))] = client
+	}
+
+	return &Authenticator{clients: clients}
+}
+
+// Middleware rejects the requests with an unknown API key with status 401, and sets the client of the requests
+// with a known API key (see Client). The requests without API key are passed on as anonymous.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(Header)
+		if key == "" {
+			next.ServeHTTP(rw, req)
+
+			return
+		}
+
+		client, ok := a.Authenticate(key)
+		if !ok {
+			http.Error(rw, "invalid API key", http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(rw, req.WithContext(NewContext(req.Context(), client)))
+	})
+}
+
+// Authenticate returns the client of the API key, false if the API key is unknown.
+func (a *Authenticator) Authenticate(key string) (string, bool) {
+	client, ok := a.clients[sha256.Sum256([]byte(key))]
+
+	return client, ok
+}
+
+// NewContext returns the context of a request made by the authenticated client.
+func NewContext(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, client)
+}
+
+// FromContext returns the client authenticated by the API key of the request context, empty if the request is
+// anonymous.
+func FromContext(ctx context.Context) string {
+	client, _ := ctx.Value(clientContextKey{}).(string)
+
+	return client
+}
+
+// Client returns the client authenticated by the API key of the request, empty if the request is anonymous.
+func Client(req *http.Request) string {
+	return FromContext(req.Context())
+}
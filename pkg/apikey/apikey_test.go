@@ -0,0 +1,69 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package apikey
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator(t *testing.T) {
+	var client string
+
+	handler := New(map[string]string{"client1": "key1"}).Middleware(
+		http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			client = Client(req)
+		}))
+
+	serve := func(key string) *httptest.ResponseRecorder {
+		client = "not called"
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if key != "" {
+			req.Header.Set(Header, key)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	t.Run("known API key", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve("key1").Code)
+		require.Equal(t, "client1", client)
+	})
+
+	t.Run("anonymous", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve("").Code)
+		require.Empty(t, client)
+	})
+
+	t.Run("unknown API key", func(t *testing.T) {
+		rr := serve("key2")
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid API key")
+		require.Equal(t, "not called", client)
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	authenticator := New(map[string]string{"client1": "key1"})
+
+	client, ok := authenticator.Authenticate("key1")
+	require.True(t, ok)
+	require.Equal(t, "client1", client)
+
+	_, ok = authenticator.Authenticate("key2")
+	require.False(t, ok)
+
+	require.Equal(t, "client1", FromContext(NewContext(context.Background(), "client1")))
+	require.Empty(t, FromContext(context.Background()))
+}
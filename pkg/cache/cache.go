@@ -0,0 +1,69 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cache
+
+import (
+	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/edge-core/pkg/storage"
+)
+
+var logger = log.New("edge-service-cache")
+
+// Cache is a key-value cache. Caching is best effort: a failing cache is treated as a cache miss.
+type Cache interface {
+	// Get returns the cached value of the key, false if the key is not cached.
+	Get(key string) ([]byte, bool)
+
+	// Set caches the value of the key.
+	Set(key string, value []byte)
+
+	// Delete removes the key from the cache.
+	Delete(key string)
+}
+
+// Store is a storage.Store caching the values read from the underlying store.
+type Store struct {
+	storage.Store
+	cache Cache
+}
+
+// NewStore returns a store caching the values of the given store.
+func NewStore(store storage.Store, cache Cache) *Store {
+	return &Store{Store: store, cache: cache}
+}
+
+// Put stores the key-value pair in the underlying store and invalidates the cached value.
+func (s *Store) Put(k string, v []byte) error {
+	// invalidated before the update as well, so that a failed update doesn't leave a stale value
+	s.cache.Delete(k)
+
+	err := s.Store.Put(k, v)
+	if err != nil {
+		return err
+	}
+
+	s.cache.Delete(k)
+
+	return nil
+}
+
+// Get returns the cached value of the key, or the value from the underlying store which is then cached.
+func (s *Store) Get(k string) ([]byte, error) {
+	if v, ok := s.cache.Get(k); ok {
+		return v, nil
+	}
+
+	v, err := s.Store.Get(k)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.Set(k, v)
+
+	logger.Debugf("cached value of key %s", k)
+
+	return v, nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+)
+
+func TestStore(t *testing.T) {
+	t.Run("test get cached", func(t *testing.T) {
+		store := &mockstore.MockStore{Store: map[string][]byte{"k1": []byte("v1")}}
+		cache := &mockCache{values: map[string][]byte{}}
+
+		s := NewStore(store, cache)
+
+		v, err := s.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+		require.Equal(t, []byte("v1"), cache.values["k1"])
+
+		delete(store.Store, "k1")
+
+		v, err = s.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+	})
+
+	t.Run("test put invalidates", func(t *testing.T) {
+		store := &mockstore.MockStore{Store: map[string][]byte{}}
+		cache := &mockCache{values: map[string][]byte{"k1": []byte("v1")}}
+
+		s := NewStore(store, cache)
+
+		require.NoError(t, s.Put("k1", []byte("v2")))
+		require.NotContains(t, cache.values, "k1")
+
+		v, err := s.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v2"), v)
+	})
+
+	t.Run("test put error", func(t *testing.T) {
+		cache := &mockCache{values: map[string][]byte{"k1": []byte("v1")}}
+
+		s := NewStore(&mockstore.MockStore{Store: map[string][]byte{}, ErrPut: errors.New("put error")}, cache)
+
+		require.EqualError(t, s.Put("k1", []byte("v2")), "put error")
+		require.NotContains(t, cache.values, "k1")
+	})
+
+	t.Run("test get error", func(t *testing.T) {
+		cache := &mockCache{values: map[string][]byte{}}
+
+		s := NewStore(&mockstore.MockStore{Store: map[string][]byte{}}, cache)
+
+		_, err := s.Get("k1")
+		require.Equal(t, storage.ErrValueNotFound, err)
+		require.Empty(t, cache.values)
+	})
+}
+
+type mockCache struct {
+	values map[string][]byte
+}
+
+func (m *mockCache) Get(key string) ([]byte, bool) {
+	v, ok := m.values[key]
+
+	return v, ok
+}
+
+func (m *mockCache) Set(key string, value []byte) {
+	m.values[key] = value
+}
+
+func (m *mockCache) Delete(key string) {
+	delete(m.values, key)
+}
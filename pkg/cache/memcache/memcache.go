@@ -0,0 +1,94 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package memcache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache is an in-memory LRU cache whose entries expire after a TTL.
+type Cache struct {
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	lru     *list.List
+	now     func() time.Time
+	mux     sync.Mutex
+}
+
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// New returns a cache holding at most size entries (the least recently used entry is evicted first)
+// for at most ttl.
+func New(size int, ttl time.Duration) *Cache {
+	return &Cache{
+		size:    size,
+		ttl:     ttl,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+// Get returns the cached value of the key, false if the key is not cached or expired.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := elem.Value.(*entry)
+
+	if !c.now().Before(e.expires) {
+		c.remove(elem)
+
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+
+	return e.value, true
+}
+
+// Set caches the value of the key.
+func (c *Cache) Set(key string, value []byte) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+
+	c.entries[key] = c.lru.PushFront(&entry{key: key, value: value, expires: c.now().Add(c.ttl)})
+
+	if c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Delete removes the key from the cache.
+func (c *Cache) Delete(key string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+func (c *Cache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*entry).key)
+}
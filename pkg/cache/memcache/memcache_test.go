@@ -0,0 +1,76 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package memcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	t.Run("test get set delete", func(t *testing.T) {
+		c := New(10, time.Minute)
+
+		_, ok := c.Get("k1")
+		require.False(t, ok)
+
+		c.Set("k1", []byte("v1"))
+		c.Set("k1", []byte("v2"))
+
+		v, ok := c.Get("k1")
+		require.True(t, ok)
+		require.Equal(t, []byte("v2"), v)
+
+		c.Delete("k1")
+		c.Delete("k2")
+
+		_, ok = c.Get("k1")
+		require.False(t, ok)
+	})
+
+	t.Run("test lru eviction", func(t *testing.T) {
+		c := New(2, time.Minute)
+
+		c.Set("k1", []byte("v1"))
+		c.Set("k2", []byte("v2"))
+
+		_, ok := c.Get("k1")
+		require.True(t, ok)
+
+		c.Set("k3", []byte("v3"))
+
+		_, ok = c.Get("k2")
+		require.False(t, ok)
+
+		_, ok = c.Get("k1")
+		require.True(t, ok)
+
+		_, ok = c.Get("k3")
+		require.True(t, ok)
+	})
+
+	t.Run("test expiry", func(t *testing.T) {
+		now := time.Now()
+
+		c := New(10, time.Minute)
+		c.now = func() time.Time { return now }
+
+		c.Set("k1", []byte("v1"))
+
+		now = now.Add(30 * time.Second)
+
+		_, ok := c.Get("k1")
+		require.True(t, ok)
+
+		now = now.Add(30 * time.Second)
+
+		_, ok = c.Get("k1")
+		require.False(t, ok)
+		require.Empty(t, c.entries)
+	})
+}
@@ -0,0 +1,92 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rediscache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/trustbloc/edge-core/pkg/log"
+)
+
+var logger = log.New("edge-service-rediscache")
+
+// Option configures the Redis cache
+type Option func(c *Cache)
+
+// WithKeyPrefix option is for adding prefix to the cached keys, for instances sharing a Redis server
+// while using different databases
+func WithKeyPrefix(keyPrefix string) Option {
+	return func(c *Cache) {
+		c.keyPrefix = keyPrefix
+	}
+}
+
+// Cache is a Redis backed cache whose entries expire after a TTL, to be shared by multiple instances.
+type Cache struct {
+	client    *redis.Client
+	ttl       time.Duration
+	keyPrefix string
+}
+
+// New returns a cache backed by the Redis server of the given URL (e.g. redis://:password@redis:6379/0)
+// holding the entries for at most ttl.
+func New(url string, ttl time.Duration, opts ...Option) (*Cache, error) {
+	redisOpts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+
+	c := &Cache{client: redis.NewClient(redisOpts), ttl: ttl}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	err = c.client.Ping().Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return c, nil
+}
+
+// Get returns the cached value of the key, false if the key is not cached or Redis fails.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	value, err := c.client.Get(c.keyPrefix + key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			logger.Warnf("failed to get key %s from redis: %s", key, err)
+		}
+
+		return nil, false
+	}
+
+	return value, true
+}
+
+// Set caches the value of the key.
+func (c *Cache) Set(key string, value []byte) {
+	err := c.client.Set(c.keyPrefix+key, value, c.ttl).Err()
+	if err != nil {
+		logger.Warnf("failed to set key %s in redis: %s", key, err)
+	}
+}
+
+// Delete removes the key from the cache.
+func (c *Cache) Delete(key string) {
+	err := c.client.Del(c.keyPrefix + key).Err()
+	if err != nil {
+		logger.Warnf("failed to delete key %s from redis: %s", key, err)
+	}
+}
+
+// Close closes the connections to Redis.
+func (c *Cache) Close() error {
+	return c.client.Close()
+}
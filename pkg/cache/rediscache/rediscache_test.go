@@ -0,0 +1,88 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package rediscache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+
+	defer s.Close()
+
+	t.Run("test get set delete", func(t *testing.T) {
+		c, err := New("redis://"+s.Addr(), time.Minute, WithKeyPrefix("vcs_"))
+		require.NoError(t, err)
+
+		defer func() { require.NoError(t, c.Close()) }()
+
+		_, ok := c.Get("k1")
+		require.False(t, ok)
+
+		c.Set("k1", []byte("v1"))
+
+		v, ok := c.Get("k1")
+		require.True(t, ok)
+		require.Equal(t, []byte("v1"), v)
+		require.True(t, s.Exists("vcs_k1"))
+		require.Equal(t, time.Minute, s.TTL("vcs_k1"))
+
+		c.Delete("k1")
+
+		_, ok = c.Get("k1")
+		require.False(t, ok)
+	})
+
+	t.Run("test expiry", func(t *testing.T) {
+		c, err := New("redis://"+s.Addr(), time.Minute)
+		require.NoError(t, err)
+
+		c.Set("k1", []byte("v1"))
+
+		s.FastForward(time.Minute)
+
+		_, ok := c.Get("k1")
+		require.False(t, ok)
+	})
+
+	t.Run("test redis failure", func(t *testing.T) {
+		failing, err := miniredis.Run()
+		require.NoError(t, err)
+
+		c, err := New("redis://"+failing.Addr(), time.Minute)
+		require.NoError(t, err)
+
+		c.Set("k1", []byte("v1"))
+
+		failing.Close()
+
+		_, ok := c.Get("k1")
+		require.False(t, ok)
+
+		c.Set("k1", []byte("v1"))
+		c.Delete("k1")
+	})
+
+	t.Run("test error - invalid url", func(t *testing.T) {
+		c, err := New("http://localhost", time.Minute)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid redis url")
+		require.Nil(t, c)
+	})
+
+	t.Run("test error - redis not reachable", func(t *testing.T) {
+		c, err := New("redis://localhost:1", time.Minute)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to connect to redis")
+		require.Nil(t, c)
+	})
+}
@@ -0,0 +1,142 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package claimsource
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/log"
+)
+
+const (
+	// SubjectIDPlaceholder is replaced in the URL template with the (path escaped) subject ID
+	SubjectIDPlaceholder = "{subjectID}"
+
+	authorizationHeader = "Authorization"
+
+	// defaultTimeout of the requests to the claims API, so that a hanging claims API doesn't block the issuance
+	defaultTimeout = 10 * time.Second
+)
+
+var logger = log.New("claimsource-client")
+
+// Client for an external claims API
+type Client struct {
+	urlTemplate string
+	authHeader  string
+	httpClient  *http.Client
+}
+
+// New return new instance of claims source client. The given URL template must contain
+// the {subjectID} placeholder, e.g. https://idp.example.com/users/{subjectID}/claims
+func New(urlTemplate string, opts ...Option) (*Client, error) {
+	if !strings.Contains(urlTemplate, SubjectIDPlaceholder) {
+		return nil, fmt.Errorf("claims source url template must contain %s placeholder", SubjectIDPlaceholder)
+	}
+
+	c := &Client{urlTemplate: urlTemplate, httpClient: &http.Client{Timeout: defaultTimeout}}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// FetchClaims fetches the claims of the given subject from the claims API
+func (c *Client) FetchClaims(subjectID string) (map[string]interface{}, error) {
+	if subjectID == "" {
+		return nil, errors.New("missing subject ID")
+	}
+
+	req, err := http.NewRequest(http.MethodGet,
+		strings.ReplaceAll(c.urlTemplate, SubjectIDPlaceholder, url.PathEscape(subjectID)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.authHeader != "" {
+		req.Header.Set(authorizationHeader, c.authHeader)
+	}
+
+	resp, err := c.sendHTTPRequest(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]interface{})
+	if err := json.Unmarshal(resp, &claims); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal resp to claims: %w", err)
+	}
+
+	return claims, nil
+}
+
+func (c *Client) sendHTTPRequest(req *http.Request, status int) ([]byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		err = resp.Body.Close()
+		if err != nil {
+			logger.Warnf("failed to close response body")
+		}
+	}()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		logger.Warnf("failed to read response body for status %d: %s", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode != status {
+		return nil, fmt.Errorf("failed to read response body for status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
+// Option is a claims source client instance option
+type Option func(opts *Client)
+
+// WithTLSConfig option is for definition of secured HTTP transport using a tls.Config instance
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(opts *Client) {
+		opts.httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+}
+
+// WithHTTPClient option sets the transport of the requests to that of the HTTP client, e.g. with the proxy and the
+// TLS configuration of the service, the requests still timing out after the timeout of the claims source
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(opts *Client) {
+		opts.httpClient.Transport = httpClient.Transport
+	}
+}
+
+// WithAuthHeader option sets the value of the Authorization header sent to the claims API
+// (e.g. "Bearer <token>")
+func WithAuthHeader(authHeader string) Option {
+	return func(opts *Client) {
+		opts.authHeader = authHeader
+	}
+}
+
+// WithTimeout option sets the timeout of the requests to the claims API (10 seconds by default)
+func WithTimeout(timeout time.Duration) Option {
+	return func(opts *Client) {
+		opts.httpClient.Timeout = timeout
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package claimsource
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		c, err := New("https://example.com/users/"+SubjectIDPlaceholder, WithAuthHeader("Bearer abc"),
+			WithTLSConfig(&tls.Config{ServerName: "name"}))
+		require.NoError(t, err)
+		require.NotNil(t, c)
+		require.Equal(t, "Bearer abc", c.authHeader)
+	})
+
+	t.Run("test timeout", func(t *testing.T) {
+		c, err := New("https://example.com/users/" + SubjectIDPlaceholder)
+		require.NoError(t, err)
+		require.Equal(t, defaultTimeout, c.httpClient.Timeout)
+
+		c, err = New("https://example.com/users/"+SubjectIDPlaceholder, WithTimeout(time.Second))
+		require.NoError(t, err)
+		require.Equal(t, time.Second, c.httpClient.Timeout)
+	})
+
+	t.Run("test missing placeholder", func(t *testing.T) {
+		c, err := New("https://example.com/users")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "must contain {subjectID} placeholder")
+		require.Nil(t, c)
+	})
+}
+
+func TestClient_FetchClaims(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/users/did:example:123/claims", r.URL.Path)
+			require.Equal(t, "Bearer abc", r.Header.Get(authorizationHeader))
+
+			w.WriteHeader(http.StatusOK)
+			_, err := fmt.Fprint(w, `{"name":"John Doe","age":30}`)
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		c, err := New(serv.URL+"/users/"+SubjectIDPlaceholder+"/claims", WithAuthHeader("Bearer abc"))
+		require.NoError(t, err)
+
+		claims, err := c.FetchClaims("did:example:123")
+		require.NoError(t, err)
+		require.Equal(t, "John Doe", claims["name"])
+		require.Equal(t, float64(30), claims["age"])
+	})
+
+	t.Run("test http client", func(t *testing.T) {
+		serv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, err := fmt.Fprint(w, `{"name":"John Doe"}`)
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		// the client of the server trusts its certificate
+		c, err := New(serv.URL+"/users/"+SubjectIDPlaceholder+"/claims", WithHTTPClient(serv.Client()))
+		require.NoError(t, err)
+		require.Equal(t, defaultTimeout, c.httpClient.Timeout)
+
+		claims, err := c.FetchClaims("did:example:123")
+		require.NoError(t, err)
+		require.Equal(t, "John Doe", claims["name"])
+	})
+
+	t.Run("test missing subject ID", func(t *testing.T) {
+		c, err := New("https://example.com/" + SubjectIDPlaceholder)
+		require.NoError(t, err)
+
+		claims, err := c.FetchClaims("")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing subject ID")
+		require.Nil(t, claims)
+	})
+
+	t.Run("test error from http get", func(t *testing.T) {
+		c, err := New(SubjectIDPlaceholder)
+		require.NoError(t, err)
+
+		claims, err := c.FetchClaims("did:example:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported protocol scheme")
+		require.Nil(t, claims)
+	})
+
+	t.Run("test http get return 404 status", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer serv.Close()
+
+		c, err := New(serv.URL + "/" + SubjectIDPlaceholder)
+		require.NoError(t, err)
+
+		claims, err := c.FetchClaims("did:example:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read response body for status 404")
+		require.Nil(t, claims)
+	})
+
+	t.Run("test request timeout", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+		}))
+		defer serv.Close()
+
+		c, err := New(serv.URL+"/"+SubjectIDPlaceholder, WithTimeout(10*time.Millisecond))
+		require.NoError(t, err)
+
+		claims, err := c.FetchClaims("did:example:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Client.Timeout exceeded")
+		require.Nil(t, claims)
+	})
+
+	t.Run("test error from unmarshal resp to claims", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, err := fmt.Fprint(w, "wrongValue")
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		c, err := New(serv.URL + "/" + SubjectIDPlaceholder)
+		require.NoError(t, err)
+
+		claims, err := c.FetchClaims("did:example:123")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal resp to claims")
+		require.Nil(t, claims)
+	})
+}
@@ -0,0 +1,477 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package edv
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/edge-core/pkg/utils/retry"
+	"github.com/trustbloc/edv/pkg/restapi/messages"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+
+	"github.com/trustbloc/edge-service/pkg/metrics"
+)
+
+const (
+	defaultMaxRetries       = 3
+	defaultInitialBackoff   = 250 * time.Millisecond
+	defaultBackoffFactor    = 2
+	defaultFailureThreshold = 5
+	defaultResetTimeout     = 30 * time.Second
+	defaultBatchConcurrency = 10
+)
+
+var logger = log.New("edv-client")
+
+// ErrCircuitOpen is returned without calling the EDV server while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("EDV server unavailable: circuit breaker is open")
+
+// ErrDeleteNotSupported is returned by DeleteDocument if neither the wrapped client nor the EDV server URL
+// supports the deletion of documents.
+var ErrDeleteNotSupported = errors.New("deletion of EDV documents not supported")
+
+// the EDV client reports unexpected status codes only through the error message
+var statusCodeRegex = regexp.MustCompile(`returned status code (\d{3})`)
+
+type edvClient interface {
+	CreateDataVault(config *models.DataVaultConfiguration) (string, error)
+	CreateDocument(vaultID string, document *models.EncryptedDocument) (string, error)
+	ReadDocument(vaultID, docID string) (*models.EncryptedDocument, error)
+	QueryVault(vaultID string, query *models.Query) ([]string, error)
+}
+
+type documentDeleter interface {
+	DeleteDocument(vaultID, docID string) error
+}
+
+// Option configures the client
+type Option func(c *Client)
+
+// WithRetryParams option sets how the requests failing with a transient error are retried
+func WithRetryParams(retryParams *retry.Params) Option {
+	return func(c *Client) {
+		c.retryParams = retryParams
+	}
+}
+
+// WithCircuitBreaker option sets the number of consecutive failed requests (after retries) opening the circuit
+// breaker, and the time the circuit breaker stays open before a request is sent to the EDV server again
+func WithCircuitBreaker(failureThreshold int, resetTimeout time.Duration) Option {
+	return func(c *Client) {
+		c.breaker.failureThreshold = failureThreshold
+		c.breaker.resetTimeout = resetTimeout
+	}
+}
+
+// WithBatchConcurrency option sets the maximum number of requests sent concurrently to the EDV server
+// for a batch operation
+func WithBatchConcurrency(batchConcurrency int) Option {
+	return func(c *Client) {
+		c.batchConcurrency = batchConcurrency
+	}
+}
+
+// WithServerURL option sets the URL of the EDV server (e.g. https://edv.example.com/encrypted-data-vaults)
+// the documents are deleted from, when the wrapped client doesn't support the deletion of documents
+func WithServerURL(edvServerURL string, tlsConfig *tls.Config) Option {
+	return func(c *Client) {
+		c.edvServerURL = edvServerURL
+		c.httpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	}
+}
+
+// Client is an EDV client retrying the requests failing with a transient error (network error or 5xx status)
+// with exponential backoff, and failing fast while the EDV server is unavailable. The wrapped client (and so its
+// connections) is shared by all the requests.
+type Client struct {
+	client           edvClient
+	retryParams      *retry.Params
+	breaker          *circuitBreaker
+	batchConcurrency int
+	edvServerURL     string
+	httpClient       *http.Client
+}
+
+// New returns a resilient client wrapping the given EDV client
+func New(client edvClient, opts ...Option) *Client {
+	c := &Client{
+		client: client,
+		retryParams: &retry.Params{
+			MaxRetries:     defaultMaxRetries,
+			InitialBackoff: defaultInitialBackoff,
+			BackoffFactor:  defaultBackoffFactor,
+		},
+		breaker: &circuitBreaker{
+			failureThreshold: defaultFailureThreshold,
+			resetTimeout:     defaultResetTimeout,
+			now:              time.Now,
+		},
+		batchConcurrency: defaultBatchConcurrency,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// CreateDataVault creates a new data vault.
+func (c *Client) CreateDataVault(config *models.DataVaultConfiguration) (string, error) {
+	var location string
+
+	err := c.do("createVault", func() error {
+		var err error
+
+		location, err = c.client.CreateDataVault(config)
+
+		return err
+	})
+
+	return location, err
+}
+
+// CreateDocument stores the document in the vault. A retried request rejected as the document already exists
+// succeeds if the stored document is the same: the previous request stored it but its response was lost.
+func (c *Client) CreateDocument(vaultID string, document *models.EncryptedDocument) (string, error) {
+	var (
+		location string
+		attempts int
+	)
+
+	err := c.do("createDocument", func() error {
+		var err error
+
+		attempts++
+
+		location, err = c.client.CreateDocument(vaultID, document)
+		if err != nil && attempts > 1 && strings.Contains(err.Error(), messages.ErrDuplicateDocument.Error()) {
+			return c.checkStoredDocument(vaultID, document, &location, err)
+		}
+
+		return err
+	})
+
+	return location, err
+}
+
+// checkStoredDocument sets the location of the document if the same document is stored in the vault, and
+// returns the duplicate document error otherwise
+func (c *Client) checkStoredDocument(vaultID string, document *models.EncryptedDocument, location *string,
+	duplicateErr error) error {
+	stored, err := c.client.ReadDocument(vaultID, document.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read document %s stored by a previous attempt: %w", document.ID, err)
+	}
+
+	if stored.Sequence != document.Sequence || !sameJSON(stored.JWE, document.JWE) {
+		return duplicateErr
+	}
+
+	logger.Debugf("document %s was stored by a previous attempt", document.ID)
+
+	*location = fmt.Sprintf("%s/%s/documents/%s", c.edvServerURL, url.PathEscape(vaultID),
+		url.PathEscape(document.ID))
+
+	return nil
+}
+
+// ReadDocument retrieves the document from the vault.
+func (c *Client) ReadDocument(vaultID, docID string) (*models.EncryptedDocument, error) {
+	var document *models.EncryptedDocument
+
+	err := c.do("readDocument", func() error {
+		var err error
+
+		document, err = c.client.ReadDocument(vaultID, docID)
+
+		return err
+	})
+
+	return document, err
+}
+
+// QueryVault queries the vault and returns the URLs of the matching documents.
+func (c *Client) QueryVault(vaultID string, query *models.Query) ([]string, error) {
+	var docURLs []string
+
+	err := c.do("queryVault", func() error {
+		var err error
+
+		docURLs, err = c.client.QueryVault(vaultID, query)
+
+		return err
+	})
+
+	return docURLs, err
+}
+
+// DeleteDocument deletes the document from the vault.
+func (c *Client) DeleteDocument(vaultID, docID string) error {
+	if deleter, ok := c.client.(documentDeleter); ok {
+		return c.do("deleteDocument", func() error {
+			return deleter.DeleteDocument(vaultID, docID)
+		})
+	}
+
+	if c.edvServerURL == "" {
+		return ErrDeleteNotSupported
+	}
+
+	return c.do("deleteDocument", func() error {
+		return c.sendDeleteRequest(vaultID, docID)
+	})
+}
+
+// CreateDocuments stores the documents in the vault and returns their locations, in the same order.
+// The EDV server has no batch endpoint, the documents are sent concurrently instead.
+func (c *Client) CreateDocuments(vaultID string, documents []*models.EncryptedDocument) ([]string, error) {
+	locations := make([]string, len(documents))
+
+	err := c.batch(len(documents), func(i int) error {
+		var err error
+
+		locations[i], err = c.CreateDocument(vaultID, documents[i])
+		if err != nil {
+			return fmt.Errorf("failed to create document %s: %w", documents[i].ID, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return locations, nil
+}
+
+// ReadDocuments retrieves the documents from the vault concurrently, in the same order as the IDs.
+func (c *Client) ReadDocuments(vaultID string, docIDs []string) ([]*models.EncryptedDocument, error) {
+	documents := make([]*models.EncryptedDocument, len(docIDs))
+
+	err := c.batch(len(docIDs), func(i int) error {
+		var err error
+
+		documents[i], err = c.ReadDocument(vaultID, docIDs[i])
+		if err != nil {
+			return fmt.Errorf("failed to read document %s: %w", docIDs[i], err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+// batch runs the n requests with at most batchConcurrency of them in flight, and returns the error
+// of the first failed request.
+func (c *Client) batch(n int, request func(i int) error) error {
+	concurrency := c.batchConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	errs := make([]error, n)
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+
+		wg.Add(1)
+
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			errs[i] = request(i)
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// do sends the request, the operation names the request in the metrics
+func (c *Client) do(operation string, request func() error) error {
+	allowed, probe := c.breaker.allow()
+	if !allowed {
+		return ErrCircuitOpen
+	}
+
+	var permanentErr error
+
+	err := retry.Retry(func() error {
+		err := request()
+		if err != nil && !isTransient(err) {
+			// stops the retries, the error is returned below
+			permanentErr = err
+
+			return nil
+		}
+
+		if err != nil {
+			logger.Debugf("EDV request failed with transient error: %s", err)
+		}
+
+		return err
+	}, c.retryParams)
+
+	// only the transient errors are failures of the EDV server
+	c.breaker.done(probe, err == nil)
+
+	if permanentErr != nil {
+		err = permanentErr
+	}
+
+	if err != nil {
+		metrics.EDVError(operation)
+	}
+
+	return err
+}
+
+func (c *Client) sendDeleteRequest(vaultID, docID string) error {
+	endpoint := fmt.Sprintf("%s/%s/documents/%s", c.edvServerURL, url.PathEscape(vaultID), url.PathEscape(docID))
+
+	req, err := http.NewRequest(http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create DELETE request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send DELETE message: %w", err)
+	}
+
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			logger.Warnf("failed to close response body: %s", errClose)
+		}
+	}()
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response message while deleting document: %w", err)
+	}
+
+	// same format as the errors of the EDV client, for the transient errors to be retried
+	return fmt.Errorf("the EDV server returned status code %d along with the following message: %s",
+		resp.StatusCode, respBytes)
+}
+
+func sameJSON(a, b []byte) bool {
+	var compactA, compactB bytes.Buffer
+
+	if json.Compact(&compactA, a) != nil || json.Compact(&compactB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+
+	return bytes.Equal(compactA.Bytes(), compactB.Bytes())
+}
+
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	matches := statusCodeRegex.FindStringSubmatch(err.Error())
+	if len(matches) == 0 {
+		return false
+	}
+
+	statusCode, err := strconv.Atoi(matches[1])
+
+	return err == nil && statusCode >= 500
+}
+
+// circuitBreaker opens after failureThreshold consecutive failures. Once resetTimeout has elapsed a single
+// request is let through (half-open), its success closes the circuit breaker and its failure opens it again.
+// The requests sent before the circuit breaker opened don't end the half-open state, only the probe does.
+type circuitBreaker struct {
+	failureThreshold int
+	resetTimeout     time.Duration
+	failures         int
+	openedAt         time.Time
+	halfOpen         bool
+	now              func() time.Time
+	mux              sync.Mutex
+}
+
+// allow returns if the request can be sent, and if it is the probe of the half-open circuit breaker
+func (b *circuitBreaker) allow() (bool, bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if b.failures < b.failureThreshold {
+		return true, false
+	}
+
+	if b.halfOpen || b.now().Sub(b.openedAt) < b.resetTimeout {
+		return false, false
+	}
+
+	b.halfOpen = true
+
+	return true, true
+}
+
+func (b *circuitBreaker) done(probe, success bool) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	if probe {
+		b.halfOpen = false
+	}
+
+	if success {
+		b.failures = 0
+
+		return
+	}
+
+	b.failures++
+
+	if b.failures >= b.failureThreshold {
+		if b.failures == b.failureThreshold {
+			logger.Warnf("EDV server unavailable, circuit breaker opened for %s", b.resetTimeout)
+		}
+
+		b.openedAt = b.now()
+	}
+}
@@ -0,0 +1,363 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package edv
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/utils/retry"
+	"github.com/trustbloc/edv/pkg/client"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+)
+
+func TestClient(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		m := &mockEDVClient{}
+		c := New(m)
+
+		location, err := c.CreateDataVault(&models.DataVaultConfiguration{})
+		require.NoError(t, err)
+		require.Equal(t, "vault1", location)
+
+		location, err = c.CreateDocument("vault1", &models.EncryptedDocument{})
+		require.NoError(t, err)
+		require.Equal(t, "doc1", location)
+
+		document, err := c.ReadDocument("vault1", "doc1")
+		require.NoError(t, err)
+		require.Equal(t, "doc1", document.ID)
+
+		docURLs, err := c.QueryVault("vault1", &models.Query{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"doc1"}, docURLs)
+
+		require.Equal(t, 4, m.calls)
+	})
+
+	t.Run("test transient error retried", func(t *testing.T) {
+		m := &mockEDVClient{errs: []error{
+			errors.New("the EDV server returned status code 503 along with the following message: unavailable"),
+			errors.New("the EDV server returned status code 502 along with the following message: bad gateway"),
+		}}
+		c := New(m, WithRetryParams(&retry.Params{MaxRetries: 2}))
+
+		document, err := c.ReadDocument("vault1", "doc1")
+		require.NoError(t, err)
+		require.Equal(t, "doc1", document.ID)
+		require.Equal(t, 3, m.calls)
+	})
+
+	t.Run("test network error retried", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		srv.Close()
+
+		c := New(client.New(srv.URL), WithRetryParams(&retry.Params{MaxRetries: 1}))
+
+		_, err := c.ReadDocument("vault1", "doc1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send GET message")
+	})
+
+	t.Run("test permanent error not retried", func(t *testing.T) {
+		m := &mockEDVClient{errs: []error{
+			errors.New("the EDV server returned status code 404 along with the following message: not found"),
+		}}
+		c := New(m, WithRetryParams(&retry.Params{MaxRetries: 2}))
+
+		_, err := c.ReadDocument("vault1", "doc1")
+		require.EqualError(t, err, "the EDV server returned status code 404 along with the following message: "+
+			"not found")
+		require.Equal(t, 1, m.calls)
+
+		m = &mockEDVClient{errs: []error{errors.New("marshal error")}}
+		c = New(m, WithRetryParams(&retry.Params{MaxRetries: 2}))
+
+		_, err = c.QueryVault("vault1", &models.Query{})
+		require.EqualError(t, err, "marshal error")
+		require.Equal(t, 1, m.calls)
+	})
+}
+
+func TestClient_Batch(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		m := &mockEDVClient{}
+		c := New(m, WithBatchConcurrency(2))
+
+		locations, err := c.CreateDocuments("vault1", []*models.EncryptedDocument{{ID: "doc1"}, {ID: "doc2"},
+			{ID: "doc3"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"doc1", "doc2", "doc3"}, locations)
+
+		documents, err := c.ReadDocuments("vault1", []string{"doc1", "doc2", "doc3"})
+		require.NoError(t, err)
+		require.Len(t, documents, 3)
+		require.Equal(t, "doc2", documents[1].ID)
+
+		require.Equal(t, 6, m.calls)
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		m := &mockEDVClient{failingID: "doc2"}
+		c := New(m, WithBatchConcurrency(0))
+
+		_, err := c.CreateDocuments("vault1", []*models.EncryptedDocument{{ID: "doc1"}, {ID: "doc2"}})
+		require.EqualError(t, err, "failed to create document doc2: vault not found")
+
+		_, err = c.ReadDocuments("vault1", []string{"doc1", "doc2"})
+		require.EqualError(t, err, "failed to read document doc2: vault not found")
+	})
+}
+
+func TestClient_DeleteDocument(t *testing.T) {
+	t.Run("test wrapped client deletion", func(t *testing.T) {
+		m := &mockEDVClientWithDelete{}
+
+		require.NoError(t, New(m).DeleteDocument("vault1", "doc1"))
+		require.Equal(t, []string{"doc1"}, m.deleted)
+	})
+
+	t.Run("test server deletion", func(t *testing.T) {
+		var path string
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodDelete, r.Method)
+
+			path = r.URL.Path
+
+			if r.URL.Path == "/encrypted-data-vaults/vault1/documents/doc2" {
+				w.WriteHeader(http.StatusNotFound)
+				_, err := w.Write([]byte("not found"))
+				require.NoError(t, err)
+
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer srv.Close()
+
+		c := New(&mockEDVClient{}, WithServerURL(srv.URL+"/encrypted-data-vaults", nil))
+
+		require.NoError(t, c.DeleteDocument("vault1", "doc1"))
+		require.Equal(t, "/encrypted-data-vaults/vault1/documents/doc1", path)
+
+		err := c.DeleteDocument("vault1", "doc2")
+		require.EqualError(t, err, "the EDV server returned status code 404 along with the following message: "+
+			"not found")
+	})
+
+	t.Run("test error - deletion not supported", func(t *testing.T) {
+		require.Equal(t, ErrDeleteNotSupported, New(&mockEDVClient{}).DeleteDocument("vault1", "doc1"))
+	})
+
+	t.Run("test error - server not reachable", func(t *testing.T) {
+		c := New(&mockEDVClient{}, WithServerURL("http://localhost:1", nil),
+			WithRetryParams(&retry.Params{}))
+
+		err := c.DeleteDocument("vault1", "doc1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send DELETE message")
+	})
+}
+
+func TestClient_CircuitBreaker(t *testing.T) {
+	unavailableErr := errors.New("the EDV server returned status code 503 along with the following message: " +
+		"unavailable")
+
+	now := time.Now()
+
+	m := &mockEDVClient{errs: []error{unavailableErr, unavailableErr, unavailableErr, unavailableErr}}
+	c := New(m, WithRetryParams(&retry.Params{}), WithCircuitBreaker(2, time.Minute))
+	c.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		_, err := c.CreateDocument("vault1", &models.EncryptedDocument{})
+		require.Equal(t, unavailableErr, err)
+	}
+
+	_, err := c.CreateDocument("vault1", &models.EncryptedDocument{})
+	require.Equal(t, ErrCircuitOpen, err)
+	require.Equal(t, 2, m.calls)
+
+	// half-open: a single request is sent, its failure opens the circuit breaker again
+	now = now.Add(time.Minute)
+
+	_, err = c.CreateDocument("vault1", &models.EncryptedDocument{})
+	require.Equal(t, unavailableErr, err)
+
+	_, err = c.CreateDocument("vault1", &models.EncryptedDocument{})
+	require.Equal(t, ErrCircuitOpen, err)
+	require.Equal(t, 3, m.calls)
+
+	// half-open: the success of the request closes the circuit breaker
+	now = now.Add(time.Minute)
+	m.errs = nil
+
+	for i := 0; i < 2; i++ {
+		_, err = c.CreateDocument("vault1", &models.EncryptedDocument{})
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, 5, m.calls)
+}
+
+func TestClient_CreateDocumentRetried(t *testing.T) {
+	unavailableErr := errors.New("the EDV server returned status code 503 along with the following message: " +
+		"unavailable")
+	duplicateErr := errors.New("the EDV server returned status code 409 along with the following message: " +
+		"a document with the given ID already exists")
+
+	t.Run("test success - document stored by the previous attempt", func(t *testing.T) {
+		m := &mockEDVClient{errs: []error{unavailableErr, duplicateErr}}
+		c := New(m, WithRetryParams(&retry.Params{MaxRetries: 1}), WithServerURL("https://edv.example.com", nil))
+
+		location, err := c.CreateDocument("vault1", &models.EncryptedDocument{ID: "doc2"})
+		require.NoError(t, err)
+		require.Equal(t, "https://edv.example.com/vault1/documents/doc2", location)
+		require.Equal(t, 3, m.calls)
+	})
+
+	t.Run("test error - other document stored", func(t *testing.T) {
+		m := &mockEDVClient{errs: []error{unavailableErr, duplicateErr}}
+		c := New(m, WithRetryParams(&retry.Params{MaxRetries: 1}))
+
+		_, err := c.CreateDocument("vault1", &models.EncryptedDocument{ID: "doc2", JWE: []byte(`{"a":"b"}`)})
+		require.Equal(t, duplicateErr, err)
+	})
+
+	t.Run("test error - document already stored", func(t *testing.T) {
+		m := &mockEDVClient{errs: []error{duplicateErr}}
+		c := New(m, WithRetryParams(&retry.Params{MaxRetries: 1}))
+
+		_, err := c.CreateDocument("vault1", &models.EncryptedDocument{ID: "doc2"})
+		require.Equal(t, duplicateErr, err)
+		require.Equal(t, 1, m.calls)
+	})
+
+	t.Run("test error - failed to read the stored document", func(t *testing.T) {
+		m := &mockEDVClient{errs: []error{unavailableErr, duplicateErr, errors.New("read error")}}
+		c := New(m, WithRetryParams(&retry.Params{MaxRetries: 1}))
+
+		_, err := c.CreateDocument("vault1", &models.EncryptedDocument{ID: "doc2"})
+		require.EqualError(t, err, "failed to read document doc2 stored by a previous attempt: read error")
+	})
+}
+
+func TestCircuitBreaker_HalfOpen(t *testing.T) {
+	now := time.Now()
+	b := &circuitBreaker{failureThreshold: 1, resetTimeout: time.Minute, now: func() time.Time { return now }}
+
+	// requests sent while the circuit breaker is closed
+	for i := 0; i < 2; i++ {
+		allowed, probe := b.allow()
+		require.True(t, allowed)
+		require.False(t, probe)
+	}
+
+	b.done(false, false)
+
+	allowed, _ := b.allow()
+	require.False(t, allowed)
+
+	now = now.Add(time.Minute)
+
+	allowed, probe := b.allow()
+	require.True(t, allowed)
+	require.True(t, probe)
+
+	// the second request sent before the circuit breaker opened doesn't let another probe through
+	b.done(false, false)
+
+	allowed, _ = b.allow()
+	require.False(t, allowed)
+
+	b.done(true, true)
+
+	allowed, probe = b.allow()
+	require.True(t, allowed)
+	require.False(t, probe)
+}
+
+func TestIsTransient(t *testing.T) {
+	require.True(t, isTransient(fmt.Errorf("the EDV server returned status code 500 along with the "+
+		"following message: %s", "error")))
+	require.False(t, isTransient(errors.New("the EDV server returned status code 409 along with the "+
+		"following message: conflict")))
+	require.False(t, isTransient(errors.New("unexpected error")))
+}
+
+type mockEDVClient struct {
+	errs      []error
+	calls     int
+	failingID string
+	mux       sync.Mutex
+}
+
+func (m *mockEDVClient) nextErr() error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	m.calls++
+
+	if len(m.errs) == 0 {
+		return nil
+	}
+
+	err := m.errs[0]
+	m.errs = m.errs[1:]
+
+	return err
+}
+
+func (m *mockEDVClient) CreateDataVault(*models.DataVaultConfiguration) (string, error) {
+	return "vault1", m.nextErr()
+}
+
+func (m *mockEDVClient) CreateDocument(_ string, document *models.EncryptedDocument) (string, error) {
+	if m.failingID != "" && document.ID == m.failingID {
+		return "", errors.New("vault not found")
+	}
+
+	if document.ID != "" {
+		return document.ID, m.nextErr()
+	}
+
+	return "doc1", m.nextErr()
+}
+
+func (m *mockEDVClient) ReadDocument(_, docID string) (*models.EncryptedDocument, error) {
+	if m.failingID != "" && docID == m.failingID {
+		return nil, errors.New("vault not found")
+	}
+
+	if err := m.nextErr(); err != nil {
+		return nil, err
+	}
+
+	return &models.EncryptedDocument{ID: docID}, nil
+}
+
+func (m *mockEDVClient) QueryVault(string, *models.Query) ([]string, error) {
+	return []string{"doc1"}, m.nextErr()
+}
+
+type mockEDVClientWithDelete struct {
+	mockEDVClient
+	deleted []string
+}
+
+func (m *mockEDVClientWithDelete) DeleteDocument(_, docID string) error {
+	m.deleted = append(m.deleted, docID)
+
+	return nil
+}
@@ -0,0 +1,309 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package edv
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/trustbloc/edv/pkg/restapi/models"
+)
+
+const (
+	actionRead  = "read"
+	actionWrite = "write"
+
+	capabilityInvocationHeader = "Capability-Invocation"
+	signatureHeader            = "Signature"
+)
+
+// Invocation is the capability invocation signing the requests on a vault
+type Invocation struct {
+	// Capability is the ID of the invoked capability, the root capability of the vault (the vault URL) if empty
+	Capability string
+	// Controller is the DID of the invoker, set as the controller of the vaults created without controller
+	Controller string
+	// VerificationMethod is the ID of the key of the invoker signing the requests (didID#keyID)
+	VerificationMethod string
+	// Sign signs the data with the key of the verification method
+	Sign func(data []byte) ([]byte, error)
+}
+
+// Invoker returns the capability invocation of the requests on the vault
+type Invoker interface {
+	Invocation(vaultID string) (*Invocation, error)
+}
+
+// RESTClient sends the requests of the EDV REST API signed with the capability invocation of their vault, for the
+// EDV servers enforcing authorization. Each request has a Capability-Invocation header naming the invoked capability
+// and the action (read or write), and is signed with HTTP Signatures over the request target, the host, the date,
+// the capability invocation and the digest of the body. Without invoker, the requests are not signed.
+type RESTClient struct {
+	edvServerURL string
+	httpClient   *http.Client
+	invoker      Invoker
+	now          func() time.Time
+}
+
+// NewRESTClient returns a client of the EDV server (e.g. https://edv.example.com/encrypted-data-vaults) sending
+// the requests with the HTTP client (the default client if nil), signed with the capability invocations of the
+// invoker if not nil
+func NewRESTClient(edvServerURL string, httpClient *http.Client, invoker Invoker) *RESTClient {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	return &RESTClient{
+		edvServerURL: edvServerURL,
+		httpClient:   httpClient,
+		invoker:      invoker,
+		now:          time.Now,
+	}
+}
+
+// CreateDataVault creates the vault of the configuration reference ID, controlled by the invoker if the
+// configuration has no controller, and returns its location.
+func (c *RESTClient) CreateDataVault(config *models.DataVaultConfiguration) (string, error) {
+	invocation, err := c.invocation(config.ReferenceID)
+	if err != nil {
+		return "", err
+	}
+
+	if config.Controller == "" && invocation != nil {
+		controlled := *config
+		controlled.Controller = invocation.Controller
+		config = &controlled
+	}
+
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal data vault configuration: %w", err)
+	}
+
+	resp, err := c.send(http.MethodPost, c.edvServerURL, config.ReferenceID, actionWrite, invocation, configBytes)
+	if err != nil {
+		return "", err
+	}
+
+	if err = resp.expect(http.StatusCreated); err != nil {
+		return "", err
+	}
+
+	return resp.header.Get("Location"), nil
+}
+
+// CreateDocument stores the document in the vault and returns its location.
+func (c *RESTClient) CreateDocument(vaultID string, document *models.EncryptedDocument) (string, error) {
+	documentBytes, err := json.Marshal(document)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal document: %w", err)
+	}
+
+	resp, err := c.sendToVault(http.MethodPost, vaultID, "/documents", actionWrite, documentBytes)
+	if err != nil {
+		return "", err
+	}
+
+	if err = resp.expect(http.StatusCreated); err != nil {
+		return "", err
+	}
+
+	return resp.header.Get("Location"), nil
+}
+
+// ReadDocument retrieves the document from the vault.
+func (c *RESTClient) ReadDocument(vaultID, docID string) (*models.EncryptedDocument, error) {
+	resp, err := c.sendToVault(http.MethodGet, vaultID, "/documents/"+url.PathEscape(docID), actionRead, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = resp.expect(http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	document := &models.EncryptedDocument{}
+
+	if err = json.Unmarshal(resp.body, document); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal document: %w", err)
+	}
+
+	return document, nil
+}
+
+// QueryVault queries the vault and returns the URLs of the matching documents.
+func (c *RESTClient) QueryVault(vaultID string, query *models.Query) ([]string, error) {
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	resp, err := c.sendToVault(http.MethodPost, vaultID, "/queries", actionRead, queryBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = resp.expect(http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	var docURLs []string
+
+	if err = json.Unmarshal(resp.body, &docURLs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal query results: %w", err)
+	}
+
+	return docURLs, nil
+}
+
+// DeleteDocument deletes the document from the vault.
+func (c *RESTClient) DeleteDocument(vaultID, docID string) error {
+	resp, err := c.sendToVault(http.MethodDelete, vaultID, "/documents/"+url.PathEscape(docID), actionWrite, nil)
+	if err != nil {
+		return err
+	}
+
+	return resp.expect(http.StatusOK, http.StatusNoContent)
+}
+
+// invocation returns the capability invocation of the requests on the vault, nil if the requests are not signed
+func (c *RESTClient) invocation(vaultID string) (*Invocation, error) {
+	if c.invoker == nil {
+		return nil, nil
+	}
+
+	invocation, err := c.invoker.Invocation(vaultID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the capability invocation of vault %s: %w", vaultID, err)
+	}
+
+	return invocation, nil
+}
+
+// sendToVault sends the request to the path of the vault, signed with the capability invocation of the vault
+func (c *RESTClient) sendToVault(method, vaultID, path, action string, body []byte) (*restResponse, error) {
+	invocation, err := c.invocation(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.send(method, c.vaultURL(vaultID)+path, vaultID, action, invocation, body)
+}
+
+func (c *RESTClient) send(method, endpoint, vaultID, action string, invocation *Invocation,
+	body []byte) (*restResponse, error) {
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s request: %w", method, err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if invocation != nil {
+		if err = c.sign(req, vaultID, action, invocation, body); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send %s request: %w", method, err)
+	}
+
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			logger.Warnf("failed to close response body: %s", errClose)
+		}
+	}()
+
+	respBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return &restResponse{statusCode: resp.StatusCode, header: resp.Header, body: respBytes}, nil
+}
+
+// sign adds the capability invocation of the action on the vault to the request and signs it
+func (c *RESTClient) sign(req *http.Request, vaultID, action string, invocation *Invocation, body []byte) error {
+	capability := invocation.Capability
+	if capability == "" {
+		capability = c.vaultURL(vaultID)
+	}
+
+	req.Header.Set("Date", c.now().UTC().Format(http.TimeFormat))
+	req.Header.Set(capabilityInvocationHeader, fmt.Sprintf(`zcap capability="%s",action="%s"`, capability, action))
+
+	headers := []string{"(request-target)", "host", "date", "capability-invocation"}
+
+	if body != nil {
+		digest := sha256.Sum256(body)
+		req.Header.Set("Digest", "SHA-256="+base64.StdEncoding.EncodeToString(digest[:]))
+
+		headers = append(headers, "digest")
+	}
+
+	signature, err := invocation.Sign([]byte(signingString(req, headers)))
+	if err != nil {
+		return fmt.Errorf("failed to sign the capability invocation of vault %s: %w", vaultID, err)
+	}
+
+	req.Header.Set(signatureHeader, fmt.Sprintf(`keyId="%s",algorithm="hs2019",headers="%s",signature="%s"`,
+		invocation.VerificationMethod, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature)))
+
+	return nil
+}
+
+func (c *RESTClient) vaultURL(vaultID string) string {
+	return c.edvServerURL + "/" + url.PathEscape(vaultID)
+}
+
+// signingString returns the HTTP Signatures signing string of the headers of the request
+func signingString(req *http.Request, headers []string) string {
+	lines := make([]string, len(headers))
+
+	for i, header := range headers {
+		switch header {
+		case "(request-target)":
+			lines[i] = header + ": " + strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			lines[i] = header + ": " + req.URL.Host
+		default:
+			lines[i] = header + ": " + req.Header.Get(header)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+type restResponse struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+// expect returns an error if the status code isn't one of the expected ones, in the format of the errors of the EDV
+// client for the transient errors to be retried
+func (r *restResponse) expect(statusCodes ...int) error {
+	for _, statusCode := range statusCodes {
+		if r.statusCode == statusCode {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the EDV server returned status code %d along with the following message: %s",
+		r.statusCode, r.body)
+}
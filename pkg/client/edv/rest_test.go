@@ -0,0 +1,258 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package edv
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+)
+
+func TestRESTClient(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	invoker := &mockInvoker{invocation: &Invocation{
+		Controller:         "did:example:issuer",
+		VerificationMethod: "did:example:issuer#key1",
+		Sign: func(data []byte) ([]byte, error) {
+			return ed25519.Sign(privKey, data), nil
+		},
+	}}
+
+	var (
+		requests   []*http.Request
+		controller string
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, errRead := ioutil.ReadAll(req.Body)
+		require.NoError(t, errRead)
+
+		require.NoError(t, verifyRequest(req, body, pubKey))
+
+		requests = append(requests, req)
+
+		switch {
+		case req.Method == http.MethodPost && req.URL.Path == "/edv":
+			config := &models.DataVaultConfiguration{}
+			require.NoError(t, json.Unmarshal(body, config))
+
+			controller = config.Controller
+
+			rw.Header().Set("Location", "/edv/"+config.ReferenceID)
+			rw.WriteHeader(http.StatusCreated)
+		case req.Method == http.MethodPost && req.URL.Path == "/edv/vault1/documents":
+			rw.Header().Set("Location", "/edv/vault1/documents/doc1")
+			rw.WriteHeader(http.StatusCreated)
+		case req.Method == http.MethodGet && req.URL.Path == "/edv/vault1/documents/doc1":
+			_, errWrite := rw.Write([]byte(`{"id":"doc1"}`))
+			require.NoError(t, errWrite)
+		case req.Method == http.MethodPost && req.URL.Path == "/edv/vault1/queries":
+			_, errWrite := rw.Write([]byte(`["/edv/vault1/documents/doc1"]`))
+			require.NoError(t, errWrite)
+		case req.Method == http.MethodDelete && req.URL.Path == "/edv/vault1/documents/doc1":
+			rw.WriteHeader(http.StatusNoContent)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+			_, errWrite := rw.Write([]byte("not found"))
+			require.NoError(t, errWrite)
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("test success", func(t *testing.T) {
+		requests = nil
+		c := NewRESTClient(srv.URL+"/edv", nil, invoker)
+
+		location, err := c.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: "vault1"})
+		require.NoError(t, err)
+		require.Equal(t, "/edv/vault1", location)
+		require.Equal(t, "did:example:issuer", controller)
+
+		location, err = c.CreateDocument("vault1", &models.EncryptedDocument{ID: "doc1"})
+		require.NoError(t, err)
+		require.Equal(t, "/edv/vault1/documents/doc1", location)
+
+		document, err := c.ReadDocument("vault1", "doc1")
+		require.NoError(t, err)
+		require.Equal(t, "doc1", document.ID)
+
+		docURLs, err := c.QueryVault("vault1", &models.Query{Name: "name", Value: "value"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"/edv/vault1/documents/doc1"}, docURLs)
+
+		require.NoError(t, c.DeleteDocument("vault1", "doc1"))
+
+		require.Len(t, requests, 5)
+
+		for i, action := range []string{"write", "write", "read", "read", "write"} {
+			require.Equal(t, fmt.Sprintf(`zcap capability="%s/edv/vault1",action="%s"`, srv.URL, action),
+				requests[i].Header.Get("Capability-Invocation"))
+		}
+	})
+
+	t.Run("test delegated capability", func(t *testing.T) {
+		requests = nil
+		delegated := *invoker.invocation
+		delegated.Capability = "urn:zcap:delegated"
+
+		c := NewRESTClient(srv.URL+"/edv", nil, &mockInvoker{invocation: &delegated})
+
+		_, err := c.ReadDocument("vault1", "doc1")
+		require.NoError(t, err)
+		require.Equal(t, `zcap capability="urn:zcap:delegated",action="read"`,
+			requests[0].Header.Get("Capability-Invocation"))
+	})
+
+	t.Run("test error status", func(t *testing.T) {
+		c := NewRESTClient(srv.URL+"/edv", nil, invoker)
+
+		_, err := c.ReadDocument("vault1", "doc2")
+		require.EqualError(t, err, "the EDV server returned status code 404 along with the following message: "+
+			"not found")
+
+		_, err = c.CreateDocument("vault2", &models.EncryptedDocument{})
+		require.Contains(t, err.Error(), "status code 404")
+
+		_, err = c.QueryVault("vault2", &models.Query{})
+		require.Contains(t, err.Error(), "status code 404")
+
+		_, err = c.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: "vault1", Controller: "other"})
+		require.NoError(t, err)
+		require.Equal(t, "other", controller)
+
+		require.Contains(t, c.DeleteDocument("vault2", "doc1").Error(), "status code 404")
+	})
+
+	t.Run("test error - no invocation", func(t *testing.T) {
+		c := NewRESTClient(srv.URL+"/edv", nil, &mockInvoker{err: errors.New("profile not found")})
+
+		_, err := c.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: "vault1"})
+		require.EqualError(t, err, "failed to get the capability invocation of vault vault1: profile not found")
+
+		_, err = c.ReadDocument("vault1", "doc1")
+		require.EqualError(t, err, "failed to get the capability invocation of vault vault1: profile not found")
+	})
+
+	t.Run("test error - signing failed", func(t *testing.T) {
+		failing := *invoker.invocation
+		failing.Sign = func([]byte) ([]byte, error) {
+			return nil, errors.New("sign error")
+		}
+
+		c := NewRESTClient(srv.URL+"/edv", nil, &mockInvoker{invocation: &failing})
+
+		_, err := c.QueryVault("vault1", &models.Query{})
+		require.EqualError(t, err, "failed to sign the capability invocation of vault vault1: sign error")
+	})
+
+	t.Run("test error - server unreachable is transient", func(t *testing.T) {
+		c := NewRESTClient("http://localhost:0/edv", nil, invoker)
+
+		_, err := c.ReadDocument("vault1", "doc1")
+		require.Error(t, err)
+		require.True(t, isTransient(err))
+	})
+
+	t.Run("test unsigned requests", func(t *testing.T) {
+		unsigned := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Empty(t, req.Header.Get("Signature"))
+			require.Empty(t, req.Header.Get("Capability-Invocation"))
+
+			_, errWrite := rw.Write([]byte(`{"id":"doc1"}`))
+			require.NoError(t, errWrite)
+		}))
+		defer unsigned.Close()
+
+		c := NewRESTClient(unsigned.URL, unsigned.Client(), nil)
+
+		document, err := c.ReadDocument("vault1", "doc1")
+		require.NoError(t, err)
+		require.Equal(t, "doc1", document.ID)
+	})
+
+	t.Run("test error - invalid response", func(t *testing.T) {
+		invalid := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, errWrite := rw.Write([]byte("{"))
+			require.NoError(t, errWrite)
+		}))
+		defer invalid.Close()
+
+		c := NewRESTClient(invalid.URL, nil, invoker)
+
+		_, err := c.ReadDocument("vault1", "doc1")
+		require.Contains(t, err.Error(), "failed to unmarshal document")
+
+		_, err = c.QueryVault("vault1", &models.Query{})
+		require.Contains(t, err.Error(), "failed to unmarshal query results")
+	})
+}
+
+type mockInvoker struct {
+	invocation *Invocation
+	err        error
+}
+
+func (m *mockInvoker) Invocation(string) (*Invocation, error) {
+	return m.invocation, m.err
+}
+
+var signatureRegex = regexp.MustCompile(`^keyId="([^"]+)",algorithm="hs2019",headers="([^"]+)",signature="([^"]+)"$`)
+
+// verifyRequest verifies the HTTP signature and the digest of the request, as an EDV server enforcing
+// authorization would
+func verifyRequest(req *http.Request, body []byte, pubKey ed25519.PublicKey) error {
+	matches := signatureRegex.FindStringSubmatch(req.Header.Get("Signature"))
+	if len(matches) == 0 {
+		return fmt.Errorf("invalid signature header: %s", req.Header.Get("Signature"))
+	}
+
+	if matches[1] != "did:example:issuer#key1" {
+		return fmt.Errorf("unexpected key ID: %s", matches[1])
+	}
+
+	if _, err := time.Parse(http.TimeFormat, req.Header.Get("Date")); err != nil {
+		return err
+	}
+
+	headers := strings.Split(matches[2], " ")
+
+	if len(body) > 0 {
+		digest := sha256.Sum256(body)
+		if req.Header.Get("Digest") != "SHA-256="+base64.StdEncoding.EncodeToString(digest[:]) {
+			return errors.New("invalid digest")
+		}
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(matches[3])
+	if err != nil {
+		return err
+	}
+
+	// the request URL of the server has no host
+	req.URL.Host = req.Host
+
+	if !ed25519.Verify(pubKey, []byte(signingString(req, headers)), signature) {
+		return errors.New("invalid signature")
+	}
+
+	return nil
+}
@@ -0,0 +1,349 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package localedv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edv/pkg/restapi/messages"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+)
+
+const (
+	storeName = "localedv"
+
+	// the components of the keys are path escaped, so they never contain the separator
+	vaultKeyPrefix    = "vault/"
+	documentKeyPrefix = "doc/"
+	indexKeyPrefix    = "index/"
+	keySeparator      = "/"
+
+	documentURLPattern = "/encrypted-data-vaults/%s/documents/%s"
+)
+
+// Client stores the encrypted documents in the local store provider instead of an EDV server. The documents
+// are indexed by their indexed attributes, so they can be queried the same way as in an EDV. As the store
+// doesn't support deletion, a deleted document is replaced by an empty value.
+//
+// Each index entry is stored under its own key, the index key holding the number of entries. The document is
+// stored before its index entries, and the entries of the deleted or replaced documents are skipped when queried,
+// so a document is never found through an index before being stored. The store having no atomic increment,
+// the entries are numbered under a mutex of the client: the store must not be shared by several instances.
+type Client struct {
+	store storage.Store
+	// serializes the document creations and deletions, and the numbering of the index entries
+	mux sync.Mutex
+}
+
+// New returns a client storing the documents of all the vaults in a single store of the given provider
+func New(provider storage.Provider) (*Client, error) {
+	err := provider.CreateStore(storeName)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, fmt.Errorf("failed to create local EDV store: %w", err)
+	}
+
+	store, err := provider.OpenStore(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local EDV store: %w", err)
+	}
+
+	return &Client{store: store}, nil
+}
+
+// CreateDataVault creates a new data vault, nothing is done if the vault already exists.
+func (c *Client) CreateDataVault(config *models.DataVaultConfiguration) (string, error) {
+	err := c.store.Put(vaultKey(config.ReferenceID), []byte(config.ReferenceID))
+	if err != nil {
+		return "", fmt.Errorf("failed to create vault %s: %w", config.ReferenceID, err)
+	}
+
+	return "/encrypted-data-vaults/" + config.ReferenceID, nil
+}
+
+// CreateDocument stores the document in the vault.
+func (c *Client) CreateDocument(vaultID string, document *models.EncryptedDocument) (string, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if err := c.checkVault(vaultID); err != nil {
+		return "", err
+	}
+
+	docKey := documentKey(vaultID, document.ID)
+
+	existing, err := c.store.Get(docKey)
+	if err == nil && len(existing) > 0 {
+		return "", messages.ErrDuplicateDocument
+	}
+
+	if err != nil && !errors.Is(err, storage.ErrValueNotFound) {
+		return "", fmt.Errorf("failed to get document %s: %w", document.ID, err)
+	}
+
+	documentBytes, err := json.Marshal(document)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal document %s: %w", document.ID, err)
+	}
+
+	err = c.store.Put(docKey, documentBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to store document %s: %w", document.ID, err)
+	}
+
+	if err = c.indexDocument(vaultID, document); err != nil {
+		// the document not fully indexed is removed, its entries already stored are skipped
+		if errDelete := c.store.Put(docKey, []byte{}); errDelete != nil {
+			return "", fmt.Errorf("%w (and failed to remove document %s: %s)", err, document.ID, errDelete)
+		}
+
+		return "", err
+	}
+
+	return fmt.Sprintf(documentURLPattern, vaultID, document.ID), nil
+}
+
+// ReadDocument retrieves the document from the vault.
+func (c *Client) ReadDocument(vaultID, docID string) (*models.EncryptedDocument, error) {
+	if err := c.checkVault(vaultID); err != nil {
+		return nil, err
+	}
+
+	return c.readDocument(vaultID, docID)
+}
+
+// DeleteDocument deletes the document from the vault. Its index entries are skipped once it is deleted.
+func (c *Client) DeleteDocument(vaultID, docID string) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if _, err := c.ReadDocument(vaultID, docID); err != nil {
+		return err
+	}
+
+	err := c.store.Put(documentKey(vaultID, docID), []byte{})
+	if err != nil {
+		return fmt.Errorf("failed to delete document %s: %w", docID, err)
+	}
+
+	return nil
+}
+
+// QueryVault returns the URLs of the documents of the vault having the queried indexed attribute.
+func (c *Client) QueryVault(vaultID string, query *models.Query) ([]string, error) {
+	if err := c.checkVault(vaultID); err != nil {
+		return nil, err
+	}
+
+	indexKey := attributeKey(vaultID, query.Name, query.Value)
+
+	count, err := c.getEntryCount(indexKey)
+	if err != nil {
+		return nil, err
+	}
+
+	docURLs := []string{}
+	seen := make(map[string]bool)
+
+	for i := 0; i < count; i++ {
+		docIDBytes, errGet := c.store.Get(entryKey(indexKey, i))
+		if errGet != nil {
+			return nil, fmt.Errorf("failed to get index entry: %w", errGet)
+		}
+
+		docID := string(docIDBytes)
+		if seen[docID] {
+			continue
+		}
+
+		seen[docID] = true
+
+		indexed, errIndexed := c.hasIndexedAttribute(vaultID, docID, query)
+		if errIndexed != nil {
+			return nil, errIndexed
+		}
+
+		if indexed {
+			docURLs = append(docURLs, fmt.Sprintf(documentURLPattern, vaultID, docID))
+		}
+	}
+
+	return docURLs, nil
+}
+
+// CreateDocuments stores the documents in the vault and returns their locations, in the same order.
+func (c *Client) CreateDocuments(vaultID string, documents []*models.EncryptedDocument) ([]string, error) {
+	locations := make([]string, len(documents))
+
+	for i, document := range documents {
+		location, err := c.CreateDocument(vaultID, document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create document %s: %w", document.ID, err)
+		}
+
+		locations[i] = location
+	}
+
+	return locations, nil
+}
+
+// ReadDocuments retrieves the documents from the vault, in the same order as the IDs.
+func (c *Client) ReadDocuments(vaultID string, docIDs []string) ([]*models.EncryptedDocument, error) {
+	documents := make([]*models.EncryptedDocument, len(docIDs))
+
+	for i, docID := range docIDs {
+		document, err := c.ReadDocument(vaultID, docID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document %s: %w", docID, err)
+		}
+
+		documents[i] = document
+	}
+
+	return documents, nil
+}
+
+func (c *Client) checkVault(vaultID string) error {
+	_, err := c.store.Get(vaultKey(vaultID))
+	if err != nil {
+		if errors.Is(err, storage.ErrValueNotFound) {
+			return messages.ErrVaultNotFound
+		}
+
+		return fmt.Errorf("failed to get vault %s: %w", vaultID, err)
+	}
+
+	return nil
+}
+
+func (c *Client) readDocument(vaultID, docID string) (*models.EncryptedDocument, error) {
+	documentBytes, err := c.store.Get(documentKey(vaultID, docID))
+	if err != nil {
+		if errors.Is(err, storage.ErrValueNotFound) {
+			return nil, messages.ErrDocumentNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get document %s: %w", docID, err)
+	}
+
+	if len(documentBytes) == 0 {
+		return nil, messages.ErrDocumentNotFound
+	}
+
+	document := &models.EncryptedDocument{}
+
+	err = json.Unmarshal(documentBytes, document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal document %s: %w", docID, err)
+	}
+
+	return document, nil
+}
+
+// hasIndexedAttribute returns if the document is stored with the queried attribute: the entries of the deleted
+// documents, or of the documents since replaced under the same ID, are skipped
+func (c *Client) hasIndexedAttribute(vaultID, docID string, query *models.Query) (bool, error) {
+	document, err := c.readDocument(vaultID, docID)
+	if err != nil {
+		if errors.Is(err, messages.ErrDocumentNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	for _, collection := range document.IndexedAttributeCollections {
+		for _, attribute := range collection.IndexedAttributes {
+			if attribute.Name == query.Name && attribute.Value == query.Value {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (c *Client) indexDocument(vaultID string, document *models.EncryptedDocument) error {
+	// a document can have several attributes with the same name and value
+	indexed := make(map[string]bool)
+
+	for _, collection := range document.IndexedAttributeCollections {
+		for _, attribute := range collection.IndexedAttributes {
+			indexKey := attributeKey(vaultID, attribute.Name, attribute.Value)
+			if indexed[indexKey] {
+				continue
+			}
+
+			indexed[indexKey] = true
+
+			if err := c.addEntry(indexKey, document.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// addEntry stores the entry before the incremented count, a failure leaves at most an entry overwritten later
+func (c *Client) addEntry(indexKey, docID string) error {
+	count, err := c.getEntryCount(indexKey)
+	if err != nil {
+		return err
+	}
+
+	err = c.store.Put(entryKey(indexKey, count), []byte(docID))
+	if err != nil {
+		return fmt.Errorf("failed to store index entry: %w", err)
+	}
+
+	err = c.store.Put(indexKey, []byte(strconv.Itoa(count+1)))
+	if err != nil {
+		return fmt.Errorf("failed to store index: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) getEntryCount(indexKey string) (int, error) {
+	countBytes, err := c.store.Get(indexKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrValueNotFound) {
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("failed to get index: %w", err)
+	}
+
+	count, err := strconv.Atoi(string(countBytes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse index: %w", err)
+	}
+
+	return count, nil
+}
+
+func vaultKey(vaultID string) string {
+	return vaultKeyPrefix + url.PathEscape(vaultID)
+}
+
+func documentKey(vaultID, docID string) string {
+	return documentKeyPrefix + url.PathEscape(vaultID) + keySeparator + url.PathEscape(docID)
+}
+
+func attributeKey(vaultID, name, value string) string {
+	return indexKeyPrefix + url.PathEscape(vaultID) + keySeparator + url.PathEscape(name) + keySeparator +
+		url.PathEscape(value)
+}
+
+func entryKey(indexKey string, i int) string {
+	return indexKey + keySeparator + strconv.Itoa(i)
+}
@@ -0,0 +1,272 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package localedv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+	"github.com/trustbloc/edv/pkg/restapi/messages"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+)
+
+func TestClient(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		c, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		_, err = c.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: "vault1"})
+		require.NoError(t, err)
+
+		locations, err := c.CreateDocuments("vault1", []*models.EncryptedDocument{
+			newDocument("doc1", "vc1"), newDocument("doc2", "vc1"), newDocument("doc3", "vc2"),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "/encrypted-data-vaults/vault1/documents/doc1", locations[0])
+
+		docURLs, err := c.QueryVault("vault1", &models.Query{Name: "vcID", Value: "vc1"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"/encrypted-data-vaults/vault1/documents/doc1",
+			"/encrypted-data-vaults/vault1/documents/doc2"}, docURLs)
+
+		docURLs, err = c.QueryVault("vault1", &models.Query{Name: "vcID", Value: "vc3"})
+		require.NoError(t, err)
+		require.Empty(t, docURLs)
+
+		documents, err := c.ReadDocuments("vault1", []string{"doc3", "doc1"})
+		require.NoError(t, err)
+		require.Equal(t, "doc3", documents[0].ID)
+		require.Equal(t, []byte(`"doc1"`), []byte(documents[1].JWE))
+
+		_, err = c.CreateDocument("vault1", newDocument("doc1", "vc1"))
+		require.Equal(t, messages.ErrDuplicateDocument, err)
+
+		// the vault is kept when created again
+		_, err = c.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: "vault1"})
+		require.NoError(t, err)
+
+		_, err = c.ReadDocument("vault1", "doc2")
+		require.NoError(t, err)
+
+		require.NoError(t, c.DeleteDocument("vault1", "doc1"))
+
+		_, err = c.ReadDocument("vault1", "doc1")
+		require.Equal(t, messages.ErrDocumentNotFound, err)
+
+		docURLs, err = c.QueryVault("vault1", &models.Query{Name: "vcID", Value: "vc1"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"/encrypted-data-vaults/vault1/documents/doc2"}, docURLs)
+
+		require.Equal(t, messages.ErrDocumentNotFound, c.DeleteDocument("vault1", "doc1"))
+
+		// the ID of a deleted document can be reused
+		_, err = c.CreateDocument("vault1", newDocument("doc1", "vc1"))
+		require.NoError(t, err)
+
+		// the document is indexed once for the same attribute
+		document := newDocument("doc4", "vc4")
+		document.IndexedAttributeCollections[0].IndexedAttributes = append(
+			document.IndexedAttributeCollections[0].IndexedAttributes, models.IndexedAttribute{Name: "vcID", Value: "vc4"})
+
+		_, err = c.CreateDocument("vault1", document)
+		require.NoError(t, err)
+
+		docURLs, err = c.QueryVault("vault1", &models.Query{Name: "vcID", Value: "vc4"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"/encrypted-data-vaults/vault1/documents/doc4"}, docURLs)
+
+		// the entries of the deleted document are skipped once its ID is reused
+		require.NoError(t, c.DeleteDocument("vault1", "doc4"))
+
+		_, err = c.CreateDocument("vault1", newDocument("doc4", "vc5"))
+		require.NoError(t, err)
+
+		docURLs, err = c.QueryVault("vault1", &models.Query{Name: "vcID", Value: "vc4"})
+		require.NoError(t, err)
+		require.Empty(t, docURLs)
+
+		docURLs, err = c.QueryVault("vault1", &models.Query{Name: "vcID", Value: "vc5"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"/encrypted-data-vaults/vault1/documents/doc4"}, docURLs)
+	})
+
+	t.Run("test success - names with separators", func(t *testing.T) {
+		c, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		for _, vaultID := range []string{"a", "a_b", "a/b"} {
+			_, err = c.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: vaultID})
+			require.NoError(t, err)
+		}
+
+		document := newDocument("b_c", "b_c")
+		document.IndexedAttributeCollections[0].IndexedAttributes[0].Name = "a"
+
+		_, err = c.CreateDocument("a", document)
+		require.NoError(t, err)
+
+		document = newDocument("c", "c")
+		document.IndexedAttributeCollections[0].IndexedAttributes[0].Name = "a_b"
+
+		_, err = c.CreateDocument("a_b", document)
+		require.NoError(t, err)
+
+		_, err = c.CreateDocument("a/b", newDocument("c", "c"))
+		require.NoError(t, err)
+
+		_, err = c.ReadDocument("a", "b_c")
+		require.NoError(t, err)
+
+		_, err = c.ReadDocument("a_b", "c")
+		require.NoError(t, err)
+
+		_, err = c.ReadDocument("a", "b_c/c")
+		require.Equal(t, messages.ErrDocumentNotFound, err)
+
+		docURLs, err := c.QueryVault("a_b", &models.Query{Name: "a_b", Value: "c"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"/encrypted-data-vaults/a_b/documents/c"}, docURLs)
+
+		docURLs, err = c.QueryVault("a_b", &models.Query{Name: "a", Value: "b_c"})
+		require.NoError(t, err)
+		require.Empty(t, docURLs)
+	})
+
+	t.Run("test error - vault or document not found", func(t *testing.T) {
+		c, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		_, err = c.CreateDocument("vault1", newDocument("doc1", "vc1"))
+		require.Equal(t, messages.ErrVaultNotFound, err)
+
+		_, err = c.CreateDocuments("vault1", []*models.EncryptedDocument{newDocument("doc1", "vc1")})
+		require.EqualError(t, err, "failed to create document doc1: "+messages.ErrVaultNotFound.Error())
+
+		_, err = c.QueryVault("vault1", &models.Query{})
+		require.Equal(t, messages.ErrVaultNotFound, err)
+
+		_, err = c.ReadDocument("vault1", "doc1")
+		require.Equal(t, messages.ErrVaultNotFound, err)
+
+		_, err = c.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: "vault1"})
+		require.NoError(t, err)
+
+		_, err = c.ReadDocument("vault1", "doc1")
+		require.Equal(t, messages.ErrDocumentNotFound, err)
+
+		_, err = c.ReadDocuments("vault1", []string{"doc1"})
+		require.EqualError(t, err, "failed to read document doc1: "+messages.ErrDocumentNotFound.Error())
+	})
+
+	t.Run("test error - store failures", func(t *testing.T) {
+		_, err := New(&mockstore.Provider{ErrCreateStore: errors.New("create error")})
+		require.EqualError(t, err, "failed to create local EDV store: create error")
+
+		_, err = New(&mockstore.Provider{ErrOpenStoreHandle: errors.New("open error"),
+			Store: &mockstore.MockStore{}})
+		require.EqualError(t, err, "failed to open local EDV store: open error")
+
+		store := &mockstore.MockStore{Store: map[string][]byte{}}
+
+		c, err := New(&mockstore.Provider{Store: store})
+		require.NoError(t, err)
+
+		_, err = c.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: "vault1"})
+		require.NoError(t, err)
+
+		store.ErrPut = errors.New("put error")
+
+		_, err = c.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: "vault2"})
+		require.EqualError(t, err, "failed to create vault vault2: put error")
+
+		_, err = c.CreateDocument("vault1", newDocument("doc1", "vc1"))
+		require.EqualError(t, err, "failed to store document doc1: put error")
+
+		store.ErrPut = nil
+
+		// the mock store keeps the values failed to be stored
+		_, err = c.CreateDocument("vault1", newDocument("doc4", "vc1"))
+		require.NoError(t, err)
+
+		store.ErrPut = errors.New("put error")
+
+		err = c.DeleteDocument("vault1", "doc4")
+		require.EqualError(t, err, "failed to delete document doc4: put error")
+
+		store.ErrPut = nil
+		store.Store["index/vault1/vcID/vc2"] = []byte("{")
+
+		_, err = c.CreateDocument("vault1", newDocument("doc2", "vc2"))
+		require.Contains(t, err.Error(), "failed to parse index")
+
+		// the document not indexed is removed
+		_, err = c.ReadDocument("vault1", "doc2")
+		require.Equal(t, messages.ErrDocumentNotFound, err)
+
+		_, err = c.QueryVault("vault1", &models.Query{Name: "vcID", Value: "vc2"})
+		require.Contains(t, err.Error(), "failed to parse index")
+
+		store.Store["index/vault1/vcID/vc3"] = []byte("1")
+
+		_, err = c.QueryVault("vault1", &models.Query{Name: "vcID", Value: "vc3"})
+		require.Contains(t, err.Error(), "failed to get index entry")
+
+		store.Store["doc/vault1/doc3"] = []byte("{")
+
+		_, err = c.ReadDocument("vault1", "doc3")
+		require.Contains(t, err.Error(), "failed to unmarshal document doc3")
+
+		store.ErrGet = errors.New("get error")
+
+		_, err = c.ReadDocument("vault1", "doc3")
+		require.EqualError(t, err, "failed to get vault vault1: get error")
+	})
+
+	t.Run("test error - failed to store index entry", func(t *testing.T) {
+		store := &failingPutStore{MockStore: mockstore.MockStore{Store: map[string][]byte{}}, keyPrefix: "index/"}
+
+		c, err := New(&mockstore.Provider{Store: &store.MockStore})
+		require.NoError(t, err)
+
+		c.store = store
+
+		_, err = c.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: "vault1"})
+		require.NoError(t, err)
+
+		_, err = c.CreateDocument("vault1", newDocument("doc1", "vc1"))
+		require.EqualError(t, err, "failed to store index entry: put error")
+
+		_, err = c.ReadDocument("vault1", "doc1")
+		require.Equal(t, messages.ErrDocumentNotFound, err)
+	})
+}
+
+type failingPutStore struct {
+	mockstore.MockStore
+	keyPrefix string
+}
+
+func (s *failingPutStore) Put(k string, v []byte) error {
+	if strings.HasPrefix(k, s.keyPrefix) {
+		return errors.New("put error")
+	}
+
+	return s.MockStore.Put(k, v)
+}
+
+func newDocument(id, vcID string) *models.EncryptedDocument {
+	return &models.EncryptedDocument{
+		ID:  id,
+		JWE: []byte(`"` + id + `"`),
+		IndexedAttributeCollections: []models.IndexedAttributeCollection{{
+			IndexedAttributes: []models.IndexedAttribute{{Name: "vcID", Value: vcID, Unique: true}},
+		}},
+	}
+}
@@ -0,0 +1,282 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/log"
+)
+
+const (
+	queryContentType = "application/timestamp-query"
+	replyContentType = "application/timestamp-reply"
+
+	// maxResponseSize is the size limit of the responses of the TSA
+	maxResponseSize = 1 << 20
+
+	nonceBits = 64
+
+	// the PKIStatus values of a granted request
+	statusGranted         = 0
+	statusGrantedWithMods = 1
+)
+
+// nolint: gochecknoglobals
+var (
+	oidSHA256     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+)
+
+var logger = log.New("tsa-client")
+
+// Token is a time-stamp token of the TSA
+type Token struct {
+	// Authority is the URL of the TSA
+	Authority string
+	// Token is the DER encoded TimeStampToken, a CMS SignedData signed by the TSA
+	Token []byte
+	// Time is the time the TSA time-stamped the digest at
+	Time time.Time
+	// SerialNumber of the token, unique for the TSA
+	SerialNumber *big.Int
+}
+
+// Client of a RFC 3161 time-stamping authority
+type Client struct {
+	url        string
+	httpClient *http.Client
+}
+
+// New returns a client of the TSA at the URL
+func New(tsaURL string, opts ...Option) *Client {
+	c := &Client{url: tsaURL, httpClient: &http.Client{}}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Option is a TSA client option
+type Option func(c *Client)
+
+// WithHTTPClient option sets the HTTP client of the requests, e.g. with the timeouts and the proxy of the service
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	Nonce          *big.Int `asn1:"optional"`
+	CertReq        bool     `asn1:"optional,default:false"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     []byte `asn1:"explicit,optional,tag:0"`
+}
+
+// signedData has the fields of the CMS SignedData up to the encapsulated content, the certificates and the signer
+// infos following it aren't parsed
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+	Accuracy       accuracy  `asn1:"optional"`
+	Ordering       bool      `asn1:"optional,default:false"`
+	Nonce          *big.Int  `asn1:"optional"`
+}
+
+// Timestamp time-stamps the SHA-256 digest, the returned token is checked to be the token of the request (same
+// digest and nonce) but its signature isn't verified: it is verified by the relying parties with the certificate of
+// the TSA.
+func (c *Client) Timestamp(digest []byte) (*Token, error) {
+	if len(digest) != sha256.Size {
+		return nil, fmt.Errorf("invalid SHA-256 digest length %d", len(digest))
+	}
+
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), nonceBits))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	reqBytes, err := asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue},
+			HashedMessage: digest,
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	respBytes, err := c.send(reqBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	token, info, err := parseResponse(respBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return nil, errors.New("time-stamp token of another digest")
+	}
+
+	if info.Nonce == nil || info.Nonce.Cmp(nonce) != 0 {
+		return nil, errors.New("time-stamp token of another request, nonce mismatch")
+	}
+
+	return &Token{Authority: c.url, Token: token, Time: info.GenTime.UTC(), SerialNumber: info.SerialNumber}, nil
+}
+
+func (c *Client) send(reqBytes []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(reqBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", queryContentType)
+	req.Header.Set("Accept", replyContentType)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send time-stamp request: %w", err)
+	}
+
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			logger.Warnf("failed to close response body")
+		}
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read time-stamp response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("time-stamp request failed with status %d", resp.StatusCode)
+	}
+
+	if len(body) > maxResponseSize {
+		return nil, fmt.Errorf("time-stamp response exceeds %d bytes", maxResponseSize)
+	}
+
+	return body, nil
+}
+
+// parseResponse returns the token of the TSA response and its TSTInfo
+func parseResponse(respBytes []byte) ([]byte, *tstInfo, error) {
+	resp := timeStampResp{}
+
+	if rest, err := asn1.Unmarshal(respBytes, &resp); err != nil || len(rest) > 0 {
+		return nil, nil, fmt.Errorf("invalid time-stamp response: %v", err)
+	}
+
+	if resp.Status.Status != statusGranted && resp.Status.Status != statusGrantedWithMods {
+		return nil, nil, fmt.Errorf("time-stamp request rejected with status %d: %v", resp.Status.Status,
+			resp.Status.StatusString)
+	}
+
+	if len(resp.TimeStampToken.FullBytes) == 0 {
+		return nil, nil, errors.New("time-stamp response without token")
+	}
+
+	info, err := parseToken(resp.TimeStampToken.FullBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid time-stamp token: %w", err)
+	}
+
+	return resp.TimeStampToken.FullBytes, info, nil
+}
+
+func parseToken(token []byte) (*tstInfo, error) {
+	content := contentInfo{}
+
+	if _, err := asn1.Unmarshal(token, &content); err != nil {
+		return nil, err
+	}
+
+	if !content.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("unexpected content type %s", content.ContentType)
+	}
+
+	sd := signedData{}
+
+	if _, err := asn1.Unmarshal(content.Content.Bytes, &sd); err != nil {
+		return nil, err
+	}
+
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, fmt.Errorf("unexpected encapsulated content type %s", sd.EncapContentInfo.EContentType)
+	}
+
+	info := &tstInfo{}
+
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent, info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
@@ -0,0 +1,222 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tsa
+
+import (
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// marshalToken returns a token with the TSTInfo and no signer info
+func marshalToken(t *testing.T, info *tstInfo) []byte {
+	t.Helper()
+
+	infoBytes, err := asn1.Marshal(*info)
+	require.NoError(t, err)
+
+	sdBytes, err := asn1.Marshal(struct {
+		Version          int
+		DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+		EncapContentInfo encapsulatedContentInfo
+		SignerInfos      []asn1.RawValue `asn1:"set"`
+	}{
+		Version:          3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}},
+		EncapContentInfo: encapsulatedContentInfo{EContentType: oidTSTInfo, EContent: infoBytes},
+	})
+	require.NoError(t, err)
+
+	token, err := asn1.Marshal(contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sdBytes},
+	})
+	require.NoError(t, err)
+
+	return token
+}
+
+// tsaServer returns a TSA answering with the response built by the function from the request
+func tsaServer(t *testing.T, respond func(req *timeStampReq) *timeStampResp) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		require.Equal(t, queryContentType, r.Header.Get("Content-Type"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		req := &timeStampReq{}
+		_, err = asn1.Unmarshal(body, req)
+		require.NoError(t, err)
+
+		respBytes, err := asn1.Marshal(*respond(req))
+		require.NoError(t, err)
+
+		rw.Header().Set("Content-Type", replyContentType)
+		_, err = rw.Write(respBytes)
+		require.NoError(t, err)
+	}))
+}
+
+func grant(t *testing.T, info *tstInfo) *timeStampResp {
+	t.Helper()
+
+	return &timeStampResp{
+		Status:         pkiStatusInfo{Status: statusGranted},
+		TimeStampToken: asn1.RawValue{FullBytes: marshalToken(t, info)},
+	}
+}
+
+func TestClient_Timestamp(t *testing.T) {
+	digest := sha256.Sum256([]byte("credential"))
+	genTime := time.Date(2020, 9, 1, 10, 20, 30, 0, time.UTC)
+
+	t.Run("test success", func(t *testing.T) {
+		server := tsaServer(t, func(req *timeStampReq) *timeStampResp {
+			require.Equal(t, 1, req.Version)
+			require.True(t, req.CertReq)
+			require.True(t, req.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA256))
+
+			return grant(t, &tstInfo{
+				Version:        1,
+				Policy:         asn1.ObjectIdentifier{1, 2, 3},
+				MessageImprint: req.MessageImprint,
+				SerialNumber:   big.NewInt(42),
+				GenTime:        genTime,
+				Nonce:          req.Nonce,
+			})
+		})
+		defer server.Close()
+
+		token, err := New(server.URL, WithHTTPClient(server.Client())).Timestamp(digest[:])
+		require.NoError(t, err)
+		require.Equal(t, server.URL, token.Authority)
+		require.True(t, genTime.Equal(token.Time))
+		require.Equal(t, int64(42), token.SerialNumber.Int64())
+
+		info, err := parseToken(token.Token)
+		require.NoError(t, err)
+		require.Equal(t, digest[:], info.MessageImprint.HashedMessage)
+	})
+
+	t.Run("test invalid digest", func(t *testing.T) {
+		_, err := New("http://tsa.example.com").Timestamp([]byte("digest"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid SHA-256 digest length 6")
+	})
+
+	t.Run("test rejected request", func(t *testing.T) {
+		server := tsaServer(t, func(req *timeStampReq) *timeStampResp {
+			return &timeStampResp{Status: pkiStatusInfo{Status: 2, StatusString: []string{"bad alg"}}}
+		})
+		defer server.Close()
+
+		_, err := New(server.URL).Timestamp(digest[:])
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "time-stamp request rejected with status 2: [bad alg]")
+	})
+
+	t.Run("test token of another digest", func(t *testing.T) {
+		server := tsaServer(t, func(req *timeStampReq) *timeStampResp {
+			other := sha256.Sum256([]byte("other"))
+
+			return grant(t, &tstInfo{
+				Version:        1,
+				Policy:         asn1.ObjectIdentifier{1, 2, 3},
+				MessageImprint: messageImprint{HashAlgorithm: req.MessageImprint.HashAlgorithm, HashedMessage: other[:]},
+				SerialNumber:   big.NewInt(1),
+				GenTime:        genTime,
+				Nonce:          req.Nonce,
+			})
+		})
+		defer server.Close()
+
+		_, err := New(server.URL).Timestamp(digest[:])
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "time-stamp token of another digest")
+	})
+
+	t.Run("test nonce mismatch", func(t *testing.T) {
+		server := tsaServer(t, func(req *timeStampReq) *timeStampResp {
+			return grant(t, &tstInfo{
+				Version:        1,
+				Policy:         asn1.ObjectIdentifier{1, 2, 3},
+				MessageImprint: req.MessageImprint,
+				SerialNumber:   big.NewInt(1),
+				GenTime:        genTime,
+				Nonce:          new(big.Int).Add(req.Nonce, big.NewInt(1)),
+			})
+		})
+		defer server.Close()
+
+		_, err := New(server.URL).Timestamp(digest[:])
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "nonce mismatch")
+	})
+
+	t.Run("test granted without token", func(t *testing.T) {
+		server := tsaServer(t, func(req *timeStampReq) *timeStampResp {
+			return &timeStampResp{Status: pkiStatusInfo{Status: statusGranted}}
+		})
+		defer server.Close()
+
+		_, err := New(server.URL).Timestamp(digest[:])
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "time-stamp response without token")
+	})
+
+	t.Run("test http error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		_, err := New(server.URL).Timestamp(digest[:])
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "time-stamp request failed with status 503")
+
+		_, err = New("http://[::1]:0").Timestamp(digest[:])
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to send time-stamp request")
+	})
+
+	t.Run("test invalid response", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_, err := rw.Write([]byte("not DER"))
+			require.NoError(t, err)
+		}))
+		defer server.Close()
+
+		_, err := New(server.URL).Timestamp(digest[:])
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid time-stamp response")
+	})
+}
+
+func TestParseToken(t *testing.T) {
+	_, err := parseToken([]byte("not DER"))
+	require.Error(t, err)
+
+	token, err := asn1.Marshal(contentInfo{
+		ContentType: asn1.ObjectIdentifier{1, 2, 3},
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: []byte{5, 0}},
+	})
+	require.NoError(t, err)
+
+	_, err = parseToken(token)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unexpected content type 1.2.3")
+}
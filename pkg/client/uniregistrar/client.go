@@ -21,6 +21,14 @@ import (
 
 var logger = log.New("uniregistrar-client")
 
+// operations of the did document updates
+const (
+	// AddToDIDDocument adds the public keys and services to the did document, the default operation
+	AddToDIDDocument = "addToDidDocument"
+	// RemoveFromDIDDocument removes the public keys and services, identified by their ID, from the did document
+	RemoveFromDIDDocument = "removeFromDidDocument"
+)
+
 // Client for uni-registrar
 type Client struct {
 	httpClient *http.Client
@@ -55,34 +63,76 @@ func (c *Client) CreateDID(driverURL string, opts ...CreateDIDOption) (string, [
 		return "", nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, driverURL, bytes.NewBuffer(reqBytes))
+	registerResponse, err := c.sendRegistrarRequest(driverURL, jobID, reqBytes)
 	if err != nil {
 		return "", nil, err
 	}
 
+	return registerResponse.DIDState.Identifier, registerResponse.DIDState.Secret.Keys, nil
+}
+
+// UpdateDID adds the public keys and services passed in the options to the document of an existing did, or removes
+// them with the RemoveFromDIDDocument operation
+func (c *Client) UpdateDID(driverURL, did string, opts ...CreateDIDOption) ([]didmethodoperation.Key, error) {
+	updateDIDOpts := &CreateDIDOpts{}
+
+	// Apply options
+	for _, opt := range opts {
+		opt(updateDIDOpts)
+	}
+
+	jobID := uuid.New().String()
+
+	var operation []string
+	if updateDIDOpts.operation != "" {
+		operation = []string{updateDIDOpts.operation}
+	}
+
+	reqBytes, err := json.Marshal(UpdateDIDRequest{JobID: jobID, Identifier: did,
+		DIDDocument: didmethodoperation.DIDDocument{PublicKey: updateDIDOpts.publicKeys,
+			Service: updateDIDOpts.services}, Options: updateDIDOpts.options, DIDDocumentOperation: operation})
+	if err != nil {
+		return nil, err
+	}
+
+	registerResponse, err := c.sendRegistrarRequest(driverURL, jobID, reqBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return registerResponse.DIDState.Secret.Keys, nil
+}
+
+func (c *Client) sendRegistrarRequest(driverURL, jobID string,
+	reqBytes []byte) (*didmethodoperation.RegisterResponse, error) {
+	req, err := http.NewRequest(http.MethodPost, driverURL, bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, err
+	}
+
 	resp, err := c.sendHTTPRequest(req, http.StatusOK)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 
 	var registerResponse didmethodoperation.RegisterResponse
 	if err := json.Unmarshal(resp, &registerResponse); err != nil {
-		return "", nil, fmt.Errorf("failed to unmarshal resp to register response: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal resp to register response: %w", err)
 	}
 
 	if registerResponse.JobID != "" && jobID != registerResponse.JobID {
-		return "", nil, fmt.Errorf("register response jobID=%s not equal %s", registerResponse.JobID, jobID)
+		return nil, fmt.Errorf("register response jobID=%s not equal %s", registerResponse.JobID, jobID)
 	}
 
 	if registerResponse.DIDState.State == didmethodoperation.RegistrationStateFailure {
-		return "", nil, fmt.Errorf("failure from uniregistrar %s", registerResponse.DIDState.Reason)
+		return nil, fmt.Errorf("failure from uniregistrar %s", registerResponse.DIDState.Reason)
 	}
 
 	if registerResponse.DIDState.State != didmethodoperation.RegistrationStateFinished {
-		return "", nil, fmt.Errorf("uniregistrar return unknown state %s", registerResponse.DIDState.State)
+		return nil, fmt.Errorf("uniregistrar return unknown state %s", registerResponse.DIDState.State)
 	}
 
-	return registerResponse.DIDState.Identifier, registerResponse.DIDState.Secret.Keys, nil
+	return &registerResponse, nil
 }
 
 func (c *Client) sendHTTPRequest(req *http.Request, status int) ([]byte, error) {
@@ -120,11 +170,28 @@ func WithTLSConfig(tlsConfig *tls.Config) Option {
 	}
 }
 
+// WithHTTPClient option sets the HTTP client of the requests, e.g. with the timeouts and the proxy of the service
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(opts *Client) {
+		opts.httpClient = httpClient
+	}
+}
+
+// UpdateDIDRequest uni-registrar update did request
+type UpdateDIDRequest struct {
+	JobID                string                         `json:"jobId,omitempty"`
+	Identifier           string                         `json:"identifier"`
+	Options              map[string]string              `json:"options,omitempty"`
+	DIDDocumentOperation []string                       `json:"didDocumentOperation,omitempty"`
+	DIDDocument          didmethodoperation.DIDDocument `json:"didDocument,omitempty"`
+}
+
 // CreateDIDOpts create did opts
 type CreateDIDOpts struct {
 	publicKeys []*didmethodoperation.PublicKey
 	services   []*didmethodoperation.Service
 	options    map[string]string
+	operation  string
 }
 
 // CreateDIDOption is a create DID option
@@ -150,3 +217,10 @@ func WithOptions(options map[string]string) CreateDIDOption {
 		opts.options = options
 	}
 }
+
+// WithDIDDocumentOperation sets the operation of a did document update
+func WithDIDDocumentOperation(operation string) CreateDIDOption {
+	return func(opts *CreateDIDOpts) {
+		opts.operation = operation
+	}
+}
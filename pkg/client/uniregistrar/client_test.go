@@ -149,5 +149,97 @@ func TestClient_CreateDID(t *testing.T) {
 			WithService(&didmethodoperation.Service{ID: "service"}))
 		require.NoError(t, err)
 		require.Equal(t, "did1", didID)
+
+		v = New(WithHTTPClient(serv.Client()))
+
+		didID, _, err = v.CreateDID(serv.URL, WithOptions(opts), WithPublicKey(
+			&didmethodoperation.PublicKey{ID: "key1", Type: "type1", Value: "value1"}),
+			WithService(&didmethodoperation.Service{ID: "service"}))
+		require.NoError(t, err)
+		require.Equal(t, "did1", didID)
+	})
+}
+
+func TestClient_UpdateDID(t *testing.T) {
+	t.Run("test error from http post", func(t *testing.T) {
+		v := New()
+
+		keys, err := v.UpdateDID("", "did1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unsupported protocol scheme")
+		require.Nil(t, keys)
+	})
+
+	t.Run("test server return state failure", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			bytes, err := json.Marshal(didmethodoperation.RegisterResponse{
+				DIDState: didmethodoperation.DIDState{State: didmethodoperation.RegistrationStateFailure,
+					Reason: "did not found"}})
+			require.NoError(t, err)
+			_, err = fmt.Fprint(w, string(bytes))
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		v := New()
+
+		keys, err := v.UpdateDID(serv.URL, "did1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failure from uniregistrar did not found")
+		require.Nil(t, keys)
+	})
+
+	t.Run("test success", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req UpdateDIDRequest
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, "did1", req.Identifier)
+			require.Equal(t, "v1", req.Options["k1"])
+
+			require.Equal(t, 1, len(req.DIDDocument.PublicKey))
+			require.Equal(t, "key2", req.DIDDocument.PublicKey[0].ID)
+
+			w.WriteHeader(http.StatusOK)
+			bytes, err := json.Marshal(didmethodoperation.RegisterResponse{JobID: req.JobID,
+				DIDState: didmethodoperation.DIDState{State: didmethodoperation.RegistrationStateFinished,
+					Identifier: "did1", Secret: didmethodoperation.Secret{
+						Keys: []didmethodoperation.Key{{ID: "did1#key2"}}}}})
+			require.NoError(t, err)
+			_, err = fmt.Fprint(w, string(bytes))
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		v := New()
+
+		keys, err := v.UpdateDID(serv.URL, "did1", WithOptions(map[string]string{"k1": "v1"}),
+			WithPublicKey(&didmethodoperation.PublicKey{ID: "key2", Type: "type1", Value: "value1"}))
+		require.NoError(t, err)
+		require.Equal(t, 1, len(keys))
+		require.Equal(t, "did1#key2", keys[0].ID)
+	})
+
+	t.Run("test success - remove from did document", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req UpdateDIDRequest
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, []string{RemoveFromDIDDocument}, req.DIDDocumentOperation)
+			require.Equal(t, "service1", req.DIDDocument.Service[0].ID)
+
+			bytes, err := json.Marshal(didmethodoperation.RegisterResponse{JobID: req.JobID,
+				DIDState: didmethodoperation.DIDState{State: didmethodoperation.RegistrationStateFinished,
+					Identifier: "did1"}})
+			require.NoError(t, err)
+			_, err = w.Write(bytes)
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		_, err := New().UpdateDID(serv.URL, "did1", WithDIDDocumentOperation(RemoveFromDIDDocument),
+			WithService(&didmethodoperation.Service{ID: "service1"}))
+		require.NoError(t, err)
 	})
 }
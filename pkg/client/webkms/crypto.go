@@ -0,0 +1,211 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webkms
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+)
+
+type signReq struct {
+	Message string `json:"message"`
+}
+
+type signResp struct {
+	Signature string `json:"signature"`
+}
+
+type verifyReq struct {
+	Signature string `json:"signature"`
+	Message   string `json:"message"`
+}
+
+type encryptReq struct {
+	Message string `json:"message"`
+	AAD     string `json:"aad,omitempty"`
+}
+
+type encryptResp struct {
+	CipherText string `json:"cipherText"`
+	Nonce      string `json:"nonce"`
+}
+
+type decryptReq struct {
+	CipherText string `json:"cipherText"`
+	AAD        string `json:"aad,omitempty"`
+	Nonce      string `json:"nonce"`
+}
+
+type decryptResp struct {
+	PlainText string `json:"plainText"`
+}
+
+type computeMACReq struct {
+	Data string `json:"data"`
+}
+
+type computeMACResp struct {
+	MAC string `json:"mac"`
+}
+
+type verifyMACReq struct {
+	MAC  string `json:"mac"`
+	Data string `json:"data"`
+}
+
+// Crypto is a crypto.Crypto executing the operations in a web kms. The key handles must be key URLs as returned
+// by the web kms KeyManager.
+type Crypto struct {
+	httpClient *http.Client
+}
+
+// NewCrypto return new instance of web kms crypto
+func NewCrypto(opts ...Option) *Crypto {
+	c := &Crypto{httpClient: &http.Client{}}
+
+	for _, opt := range opts {
+		opt(c.httpClient)
+	}
+
+	return c
+}
+
+// Encrypt encrypts msg and aad with the key of the given key handle
+func (c *Crypto) Encrypt(msg, aad []byte, kh interface{}) ([]byte, []byte, error) {
+	keyURL, err := getKeyURL(kh)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp := &encryptResp{}
+
+	_, err = doHTTPRequest(c.httpClient, http.MethodPost, keyURL+"/encrypt",
+		&encryptReq{Message: encode(msg), AAD: encode(aad)}, http.StatusOK, resp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+
+	cipherText, err := decode(resp.CipherText)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce, err := decode(resp.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cipherText, nonce, nil
+}
+
+// Decrypt decrypts cipher with aad and nonce with the key of the given key handle
+func (c *Crypto) Decrypt(cipher, aad, nonce []byte, kh interface{}) ([]byte, error) {
+	keyURL, err := getKeyURL(kh)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &decryptResp{}
+
+	_, err = doHTTPRequest(c.httpClient, http.MethodPost, keyURL+"/decrypt",
+		&decryptReq{CipherText: encode(cipher), AAD: encode(aad), Nonce: encode(nonce)}, http.StatusOK, resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	return decode(resp.PlainText)
+}
+
+// Sign signs msg with the key of the given key handle
+func (c *Crypto) Sign(msg []byte, kh interface{}) ([]byte, error) {
+	keyURL, err := getKeyURL(kh)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &signResp{}
+
+	_, err = doHTTPRequest(c.httpClient, http.MethodPost, keyURL+"/sign",
+		&signReq{Message: encode(msg)}, http.StatusOK, resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	return decode(resp.Signature)
+}
+
+// Verify verifies the signature of msg with the key of the given key handle
+func (c *Crypto) Verify(signature, msg []byte, kh interface{}) error {
+	keyURL, err := getKeyURL(kh)
+	if err != nil {
+		return err
+	}
+
+	_, err = doHTTPRequest(c.httpClient, http.MethodPost, keyURL+"/verify",
+		&verifyReq{Signature: encode(signature), Message: encode(msg)}, http.StatusOK, nil)
+	if err != nil {
+		return fmt.Errorf("failed to verify: %w", err)
+	}
+
+	return nil
+}
+
+// ComputeMAC computes the MAC of data with the key of the given key handle
+func (c *Crypto) ComputeMAC(data []byte, kh interface{}) ([]byte, error) {
+	keyURL, err := getKeyURL(kh)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &computeMACResp{}
+
+	_, err = doHTTPRequest(c.httpClient, http.MethodPost, keyURL+"/computemac",
+		&computeMACReq{Data: encode(data)}, http.StatusOK, resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute mac: %w", err)
+	}
+
+	return decode(resp.MAC)
+}
+
+// VerifyMAC verifies the MAC of data with the key of the given key handle
+func (c *Crypto) VerifyMAC(mac, data []byte, kh interface{}) error {
+	keyURL, err := getKeyURL(kh)
+	if err != nil {
+		return err
+	}
+
+	_, err = doHTTPRequest(c.httpClient, http.MethodPost, keyURL+"/verifymac",
+		&verifyMACReq{MAC: encode(mac), Data: encode(data)}, http.StatusOK, nil)
+	if err != nil {
+		return fmt.Errorf("failed to verify mac: %w", err)
+	}
+
+	return nil
+}
+
+func getKeyURL(kh interface{}) (string, error) {
+	keyURL, ok := kh.(string)
+	if !ok || keyURL == "" {
+		return "", fmt.Errorf("invalid key handle %T, web kms key url expected", kh)
+	}
+
+	return keyURL, nil
+}
+
+func encode(b []byte) string {
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	b, err := base64.URLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webkms
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrypto(t *testing.T) {
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp interface{}
+
+		switch r.URL.Path {
+		case "/keys/key1/sign":
+			var req signReq
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, encode([]byte("msg")), req.Message)
+
+			resp = &signResp{Signature: encode([]byte("signature"))}
+		case "/keys/key1/verify":
+			var req verifyReq
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			if req.Signature != encode([]byte("signature")) {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+		case "/keys/key1/encrypt":
+			resp = &encryptResp{CipherText: encode([]byte("cipher")), Nonce: encode([]byte("nonce"))}
+		case "/keys/key1/decrypt":
+			var req decryptReq
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, encode([]byte("nonce")), req.Nonce)
+
+			resp = &decryptResp{PlainText: encode([]byte("msg"))}
+		case "/keys/key1/computemac":
+			resp = &computeMACResp{MAC: encode([]byte("mac"))}
+		case "/keys/key1/verifymac":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		if resp != nil {
+			require.NoError(t, json.NewEncoder(w).Encode(resp))
+		}
+	}))
+	defer serv.Close()
+
+	c := NewCrypto()
+	kh := serv.URL + "/keys/key1"
+
+	t.Run("test sign and verify", func(t *testing.T) {
+		signature, err := c.Sign([]byte("msg"), kh)
+		require.NoError(t, err)
+		require.Equal(t, []byte("signature"), signature)
+
+		require.NoError(t, c.Verify(signature, []byte("msg"), kh))
+
+		err = c.Verify([]byte("wrong"), []byte("msg"), kh)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to verify")
+	})
+
+	t.Run("test encrypt and decrypt", func(t *testing.T) {
+		cipher, nonce, err := c.Encrypt([]byte("msg"), []byte("aad"), kh)
+		require.NoError(t, err)
+		require.Equal(t, []byte("cipher"), cipher)
+		require.Equal(t, []byte("nonce"), nonce)
+
+		msg, err := c.Decrypt(cipher, []byte("aad"), nonce, kh)
+		require.NoError(t, err)
+		require.Equal(t, []byte("msg"), msg)
+	})
+
+	t.Run("test compute and verify mac", func(t *testing.T) {
+		mac, err := c.ComputeMAC([]byte("data"), kh)
+		require.NoError(t, err)
+		require.Equal(t, []byte("mac"), mac)
+
+		require.NoError(t, c.VerifyMAC(mac, []byte("data"), kh))
+	})
+
+	t.Run("test error - invalid key handle", func(t *testing.T) {
+		_, err := c.Sign([]byte("msg"), []byte("kh"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid key handle []uint8, web kms key url expected")
+
+		_, _, err = c.Encrypt([]byte("msg"), nil, nil)
+		require.Error(t, err)
+
+		_, err = c.Decrypt([]byte("msg"), nil, nil, "")
+		require.Error(t, err)
+
+		_, err = c.ComputeMAC([]byte("data"), nil)
+		require.Error(t, err)
+
+		require.Error(t, c.Verify(nil, nil, nil))
+		require.Error(t, c.VerifyMAC(nil, nil, nil))
+	})
+
+	t.Run("test error - key not found", func(t *testing.T) {
+		kh := serv.URL + "/keys/key2"
+
+		_, err := c.Sign([]byte("msg"), kh)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to sign: failed to read response body for status 404")
+
+		_, _, err = c.Encrypt([]byte("msg"), nil, kh)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to encrypt")
+
+		_, err = c.Decrypt([]byte("msg"), nil, nil, kh)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to decrypt")
+
+		_, err = c.ComputeMAC([]byte("data"), kh)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to compute mac")
+
+		err = c.VerifyMAC([]byte("mac"), []byte("data"), kh)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to verify mac")
+	})
+}
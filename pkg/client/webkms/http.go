@@ -0,0 +1,70 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webkms
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// doHTTPRequest sends the request (body is marshalled to JSON if not nil) and unmarshals the response
+// body into resp (if not nil). The response headers are returned.
+func doHTTPRequest(httpClient *http.Client, method, url string, body interface{}, status int,
+	resp interface{}) (http.Header, error) {
+	var reqBody io.Reader
+
+	if body != nil {
+		reqBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		reqBody = bytes.NewBuffer(reqBytes)
+	}
+
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpResp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		err = httpResp.Body.Close()
+		if err != nil {
+			logger.Warnf("failed to close response body")
+		}
+	}()
+
+	respBody, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		logger.Warnf("failed to read response body for status %d: %s", httpResp.StatusCode, err)
+	}
+
+	if httpResp.StatusCode != status {
+		return nil, fmt.Errorf("failed to read response body for status %d: %s", httpResp.StatusCode,
+			string(respBody))
+	}
+
+	if resp != nil {
+		if err := json.Unmarshal(respBody, resp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+	}
+
+	return httpResp.Header, nil
+}
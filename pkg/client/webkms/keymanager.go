@@ -0,0 +1,181 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webkms
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/trustbloc/edge-core/pkg/log"
+)
+
+const (
+	keysPath = "/keys"
+
+	locationHeader = "Location"
+)
+
+var logger = log.New("webkms-client")
+
+var errNotSupported = errors.New("not supported by web kms")
+
+type createKeyReq struct {
+	KeyType string `json:"keyType"`
+}
+
+type importKeyReq struct {
+	KeyBytes string `json:"keyBytes"`
+	KeyType  string `json:"keyType"`
+	KeyID    string `json:"keyID,omitempty"`
+}
+
+type exportKeyResp struct {
+	PublicKey string `json:"publicKey"`
+}
+
+// KeyManager is a kms.KeyManager backed by a remote keystore of a web kms. The private keys never leave the
+// remote kms, the key handles returned are the URLs of the keys in the keystore.
+type KeyManager struct {
+	keystoreURL string
+	httpClient  *http.Client
+}
+
+// New return new instance of web kms key manager for the given keystore URL
+// (e.g. https://kms.example.com/kms/keystores/{keystoreID})
+func New(keystoreURL string, opts ...Option) *KeyManager {
+	km := &KeyManager{keystoreURL: strings.TrimSuffix(keystoreURL, "/"), httpClient: &http.Client{}}
+
+	for _, opt := range opts {
+		opt(km.httpClient)
+	}
+
+	return km
+}
+
+// Create a new key of the given type in the remote keystore. The key handle returned is the key URL.
+func (k *KeyManager) Create(kt kms.KeyType) (string, interface{}, error) {
+	header, err := doHTTPRequest(k.httpClient, http.MethodPost, k.keystoreURL+keysPath,
+		&createKeyReq{KeyType: string(kt)}, http.StatusCreated, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create key: %w", err)
+	}
+
+	keyURL := header.Get(locationHeader)
+	if keyURL == "" {
+		return "", nil, errors.New("failed to create key: missing location of the key")
+	}
+
+	return keyURL[strings.LastIndex(keyURL, "/")+1:], keyURL, nil
+}
+
+// Get returns the key handle (key URL) for the given keyID
+func (k *KeyManager) Get(keyID string) (interface{}, error) {
+	return k.keyURL(keyID), nil
+}
+
+// Rotate is not supported by the web kms
+func (k *KeyManager) Rotate(kt kms.KeyType, keyID string) (string, interface{}, error) {
+	return "", nil, fmt.Errorf("rotate key: %w", errNotSupported)
+}
+
+// ExportPubKeyBytes fetches the public key bytes of the given keyID from the remote keystore
+func (k *KeyManager) ExportPubKeyBytes(keyID string) ([]byte, error) {
+	resp := &exportKeyResp{}
+
+	_, err := doHTTPRequest(k.httpClient, http.MethodGet, k.keyURL(keyID)+"/export", nil, http.StatusOK, resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export public key: %w", err)
+	}
+
+	return base64.URLEncoding.DecodeString(resp.PublicKey)
+}
+
+// Delete removes the key of the given keyID from the remote keystore
+func (k *KeyManager) Delete(keyID string) error {
+	_, err := doHTTPRequest(k.httpClient, http.MethodDelete, k.keyURL(keyID), nil, http.StatusNoContent, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete key: %w", err)
+	}
+
+	return nil
+}
+
+// PubKeyBytesToHandle is not supported by the web kms
+func (k *KeyManager) PubKeyBytesToHandle(pubKey []byte, kt kms.KeyType) (interface{}, error) {
+	return nil, fmt.Errorf("public key bytes to handle: %w", errNotSupported)
+}
+
+// ImportPrivateKey imports the private key into the remote keystore. Supported private key types are
+// ed25519.PrivateKey and *ecdsa.PrivateKey.
+func (k *KeyManager) ImportPrivateKey(privKey interface{}, kt kms.KeyType,
+	opts ...kms.PrivateKeyOpts) (string, interface{}, error) {
+	var keyBytes []byte
+
+	switch key := privKey.(type) {
+	case ed25519.PrivateKey:
+		keyBytes = key
+	case *ecdsa.PrivateKey:
+		var err error
+
+		keyBytes, err = x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to marshal private key: %w", err)
+		}
+	default:
+		return "", nil, fmt.Errorf("import private key type not supported %T", privKey)
+	}
+
+	pkOpts := kms.NewOpt()
+
+	for _, opt := range opts {
+		opt(pkOpts)
+	}
+
+	header, err := doHTTPRequest(k.httpClient, http.MethodPut, k.keystoreURL+keysPath,
+		&importKeyReq{KeyBytes: base64.URLEncoding.EncodeToString(keyBytes), KeyType: string(kt),
+			KeyID: pkOpts.KsID()}, http.StatusCreated, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to import private key: %w", err)
+	}
+
+	keyURL := header.Get(locationHeader)
+	if keyURL == "" {
+		keyURL = k.keyURL(pkOpts.KsID())
+	}
+
+	return keyURL[strings.LastIndex(keyURL, "/")+1:], keyURL, nil
+}
+
+func (k *KeyManager) keyURL(keyID string) string {
+	return k.keystoreURL + keysPath + "/" + keyID
+}
+
+// Option is a web kms client option
+type Option func(httpClient *http.Client)
+
+// WithTLSConfig option is for definition of secured HTTP transport using a tls.Config instance
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(httpClient *http.Client) {
+		httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+}
+
+// WithHTTPClient option sets the transport and the timeout of the requests to those of the HTTP client, e.g. with the
+// timeouts and the proxy of the service
+func WithHTTPClient(client *http.Client) Option {
+	return func(httpClient *http.Client) {
+		httpClient.Transport = client.Transport
+		httpClient.Timeout = client.Timeout
+	}
+}
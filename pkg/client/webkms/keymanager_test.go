@@ -0,0 +1,217 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webkms
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyManager_Create(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "/kms/keystores/ks1/keys", r.URL.Path)
+
+			var req createKeyReq
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, "ED25519", req.KeyType)
+
+			w.Header().Set(locationHeader, "https://kms.example.com/kms/keystores/ks1/keys/key1")
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer serv.Close()
+
+		km := New(serv.URL+"/kms/keystores/ks1/", WithTLSConfig(&tls.Config{}))
+
+		keyID, kh, err := km.Create(kms.ED25519Type)
+		require.NoError(t, err)
+		require.Equal(t, "key1", keyID)
+		require.Equal(t, "https://kms.example.com/kms/keystores/ks1/keys/key1", kh)
+	})
+
+	t.Run("test http client", func(t *testing.T) {
+		serv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(locationHeader, "https://kms.example.com/kms/keystores/ks1/keys/key1")
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer serv.Close()
+
+		// the client of the server trusts its certificate
+		keyID, _, err := New(serv.URL+"/kms/keystores/ks1", WithHTTPClient(serv.Client())).Create(kms.ED25519Type)
+		require.NoError(t, err)
+		require.Equal(t, "key1", keyID)
+	})
+
+	t.Run("test error - missing location", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer serv.Close()
+
+		keyID, kh, err := New(serv.URL).Create(kms.ED25519Type)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing location of the key")
+		require.Empty(t, keyID)
+		require.Nil(t, kh)
+	})
+
+	t.Run("test error - http status 500", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer serv.Close()
+
+		keyID, kh, err := New(serv.URL).Create(kms.ED25519Type)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create key: failed to read response body for status 500")
+		require.Empty(t, keyID)
+		require.Nil(t, kh)
+	})
+}
+
+func TestKeyManager_Get(t *testing.T) {
+	kh, err := New("https://kms.example.com/kms/keystores/ks1").Get("key1")
+	require.NoError(t, err)
+	require.Equal(t, "https://kms.example.com/kms/keystores/ks1/keys/key1", kh)
+}
+
+func TestKeyManager_NotSupported(t *testing.T) {
+	km := New("https://kms.example.com/kms/keystores/ks1")
+
+	_, _, err := km.Rotate(kms.ED25519Type, "key1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not supported by web kms")
+
+	_, err = km.PubKeyBytesToHandle([]byte("key"), kms.ED25519Type)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not supported by web kms")
+}
+
+func TestKeyManager_ExportPubKeyBytes(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodGet, r.Method)
+			require.Equal(t, "/keys/key1/export", r.URL.Path)
+
+			_, err := fmt.Fprintf(w, `{"publicKey":"%s"}`, base64.URLEncoding.EncodeToString([]byte("public key")))
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		pubKey, err := New(serv.URL).ExportPubKeyBytes("key1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("public key"), pubKey)
+	})
+
+	t.Run("test error - unmarshal response", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := fmt.Fprint(w, "wrongValue")
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		pubKey, err := New(serv.URL).ExportPubKeyBytes("key1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal response")
+		require.Nil(t, pubKey)
+	})
+}
+
+func TestKeyManager_Delete(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodDelete, r.Method)
+			require.Equal(t, "/keys/key1", r.URL.Path)
+
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer serv.Close()
+
+		require.NoError(t, New(serv.URL).Delete("key1"))
+	})
+
+	t.Run("test error - key not found", func(t *testing.T) {
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer serv.Close()
+
+		err := New(serv.URL).Delete("key1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to delete key: failed to read response body for status 404")
+	})
+}
+
+func TestKeyManager_ImportPrivateKey(t *testing.T) {
+	t.Run("test success - ed25519 key", func(t *testing.T) {
+		_, privKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPut, r.Method)
+
+			var req importKeyReq
+
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, base64.URLEncoding.EncodeToString(privKey), req.KeyBytes)
+			require.Equal(t, "key1", req.KeyID)
+
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer serv.Close()
+
+		keyID, kh, err := New(serv.URL).ImportPrivateKey(privKey, kms.ED25519Type, kms.WithKeyID("key1"))
+		require.NoError(t, err)
+		require.Equal(t, "key1", keyID)
+		require.Equal(t, serv.URL+"/keys/key1", kh)
+	})
+
+	t.Run("test success - ecdsa key", func(t *testing.T) {
+		privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(locationHeader, "https://kms.example.com/keys/key2")
+			w.WriteHeader(http.StatusCreated)
+		}))
+		defer serv.Close()
+
+		keyID, kh, err := New(serv.URL).ImportPrivateKey(privKey, kms.ECDSAP256TypeIEEEP1363)
+		require.NoError(t, err)
+		require.Equal(t, "key2", keyID)
+		require.Equal(t, "https://kms.example.com/keys/key2", kh)
+	})
+
+	t.Run("test error - key type not supported", func(t *testing.T) {
+		keyID, kh, err := New("https://kms.example.com").ImportPrivateKey("key", kms.ED25519Type)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "import private key type not supported string")
+		require.Empty(t, keyID)
+		require.Nil(t, kh)
+	})
+
+	t.Run("test error - http post", func(t *testing.T) {
+		keyID, kh, err := New("").ImportPrivateKey(ed25519.PrivateKey("key"), kms.ED25519Type)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to import private key")
+		require.Empty(t, keyID)
+		require.Nil(t, kh)
+	})
+}
@@ -0,0 +1,62 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webkms
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+)
+
+const nonceSeparator = "."
+
+// SecretLock is a secretlock.Service encrypting the secrets with a key of the web kms, e.g. to wrap the
+// local kms master key with a key encryption key that never leaves the remote kms.
+type SecretLock struct {
+	keyURL string
+	crypto *Crypto
+}
+
+// NewSecretLock return new instance of web kms secret lock using the key with the given key URL
+func NewSecretLock(keyURL string, opts ...Option) *SecretLock {
+	return &SecretLock{keyURL: keyURL, crypto: NewCrypto(opts...)}
+}
+
+// Encrypt encrypts the plaintext of req with the web kms key (keyURI is ignored)
+func (s *SecretLock) Encrypt(keyURI string, req *secretlock.EncryptRequest) (*secretlock.EncryptResponse, error) {
+	cipher, nonce, err := s.crypto.Encrypt([]byte(req.Plaintext), []byte(req.AdditionalAuthenticatedData), s.keyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secretlock.EncryptResponse{Ciphertext: encode(nonce) + nonceSeparator + encode(cipher)}, nil
+}
+
+// Decrypt decrypts the ciphertext of req with the web kms key (keyURI is ignored)
+func (s *SecretLock) Decrypt(keyURI string, req *secretlock.DecryptRequest) (*secretlock.DecryptResponse, error) {
+	parts := strings.Split(req.Ciphertext, nonceSeparator)
+	if len(parts) != 2 { // nolint: gomnd
+		return nil, errors.New("invalid ciphertext")
+	}
+
+	nonce, err := decode(parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	cipher, err := decode(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := s.crypto.Decrypt(cipher, []byte(req.AdditionalAuthenticatedData), nonce, s.keyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &secretlock.DecryptResponse{Plaintext: string(plaintext)}, nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webkms
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretLock(t *testing.T) {
+	serv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp interface{}
+
+		switch r.URL.Path {
+		case "/keys/kek/encrypt":
+			var req encryptReq
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, encode([]byte("master key")), req.Message)
+
+			resp = &encryptResp{CipherText: encode([]byte("cipher")), Nonce: encode([]byte("nonce"))}
+		case "/keys/kek/decrypt":
+			var req decryptReq
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Equal(t, encode([]byte("cipher")), req.CipherText)
+			require.Equal(t, encode([]byte("nonce")), req.Nonce)
+
+			resp = &decryptResp{PlainText: encode([]byte("master key"))}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	defer serv.Close()
+
+	t.Run("test encrypt and decrypt", func(t *testing.T) {
+		lock := NewSecretLock(serv.URL + "/keys/kek")
+
+		encResp, err := lock.Encrypt("", &secretlock.EncryptRequest{Plaintext: "master key"})
+		require.NoError(t, err)
+
+		decResp, err := lock.Decrypt("", &secretlock.DecryptRequest{Ciphertext: encResp.Ciphertext})
+		require.NoError(t, err)
+		require.Equal(t, "master key", decResp.Plaintext)
+	})
+
+	t.Run("test error - invalid ciphertext", func(t *testing.T) {
+		lock := NewSecretLock(serv.URL + "/keys/kek")
+
+		_, err := lock.Decrypt("", &secretlock.DecryptRequest{Ciphertext: "invalid"})
+		require.EqualError(t, err, "invalid ciphertext")
+
+		_, err = lock.Decrypt("", &secretlock.DecryptRequest{Ciphertext: "!.Y2lwaGVy"})
+		require.Error(t, err)
+
+		_, err = lock.Decrypt("", &secretlock.DecryptRequest{Ciphertext: "bm9uY2U=.!"})
+		require.Error(t, err)
+	})
+
+	t.Run("test error - key not found", func(t *testing.T) {
+		lock := NewSecretLock(serv.URL + "/keys/other")
+
+		_, err := lock.Encrypt("", &secretlock.EncryptRequest{Plaintext: "master key"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to encrypt")
+
+		_, err = lock.Decrypt("", &secretlock.DecryptRequest{Ciphertext: "bm9uY2U=.Y2lwaGVy"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to decrypt")
+	})
+}
@@ -0,0 +1,123 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clientcert
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/trustbloc/edge-service/pkg/apikey"
+)
+
+const (
+	// AllProfiles is the permission of a client on all the profiles, and on the requests not scoped to a profile
+	AllProfiles = "*"
+
+	// the path parameter of the profile in the paths of the operations
+	profileIDPathParam = "profileID"
+	// the profile endpoints of the issuer and the verifier name the profile id
+	profileEndpointPattern = "/profile/{id}"
+	profileEndpointParam   = "id"
+)
+
+// Authorizer authorizes the requests of the clients authenticated by their TLS client certificate (mTLS), the
+// client being the subject common name of its certificate.
+type Authorizer struct {
+	// the profiles of the clients by subject, nil if all the clients may access all the profiles
+	permissions map[string]map[string]bool
+}
+
+// New returns an authorizer of the given profiles, keyed by client subject. The clients may access all the profiles
+// if permissions is empty.
+func New(permissions map[string][]string) *Authorizer {
+	a := &Authorizer{}
+
+	if len(permissions) == 0 {
+		return a
+	}
+
+	a.permissions = make(map[string]map[string]bool, len(permissions))
+
+	for subject, profiles := range permissions {
+		a.permissions[subject] = make(map[string]bool, len(profiles))
+
+		for _, profile := range profiles {
+			a.permissions[subject][profile] = true
+		}
+	}
+
+	return a
+}
+
+// Middleware rejects the requests without verified client certificate with status 401, and the requests of a client
+// not allowed to access the profile of the request with status 403. The profile of a request is the profile of its
+// path, the requests not scoped to a profile requiring the AllProfiles permission. The subject is set as the client
+// of the allowed requests (see apikey.Client), unless an API key authenticates the request later on.
+//
+// The middleware must be used by the router, the profile being a route variable.
+func (a *Authorizer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		subject, ok := Subject(req)
+		if !ok {
+			http.Error(rw, "client certificate required", http.StatusUnauthorized)
+
+			return
+		}
+
+		if !a.Allowed(subject, RequestProfile(req)) {
+			http.Error(rw, "client not allowed to access the profile", http.StatusForbidden)
+
+			return
+		}
+
+		next.ServeHTTP(rw, req.WithContext(apikey.NewContext(req.Context(), subject)))
+	})
+}
+
+// Allowed returns whether the client may access the profile, the profile being empty for a request not scoped to a
+// profile.
+func (a *Authorizer) Allowed(subject, profile string) bool {
+	if a.permissions == nil {
+		return true
+	}
+
+	profiles := a.permissions[subject]
+
+	return profiles[AllProfiles] || (profile != "" && profiles[profile])
+}
+
+// Subject returns the subject common name of the verified client certificate of the request, false if the request
+// has no verified client certificate.
+func Subject(req *http.Request) (string, bool) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+
+	return req.TLS.VerifiedChains[0][0].Subject.CommonName, true
+}
+
+// RequestProfile returns the profile the request is scoped to by its path, empty if none. The request must be routed
+// by the router, the profile being a route variable.
+func RequestProfile(req *http.Request) string {
+	vars := mux.Vars(req)
+
+	if profile := vars[profileIDPathParam]; profile != "" {
+		return profile
+	}
+
+	route := mux.CurrentRoute(req)
+	if route == nil {
+		return ""
+	}
+
+	if template, err := route.GetPathTemplate(); err == nil && strings.Contains(template, profileEndpointPattern) {
+		return vars[profileEndpointParam]
+	}
+
+	return ""
+}
@@ -0,0 +1,119 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clientcert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/apikey"
+)
+
+func TestAuthorizer(t *testing.T) {
+	var client string
+
+	router := mux.NewRouter()
+	router.Use(New(map[string][]string{"issuer-client": {"issuer1"}, "admin": {AllProfiles}}).Middleware)
+
+	handle := func(rw http.ResponseWriter, req *http.Request) {
+		client = apikey.Client(req)
+	}
+
+	router.HandleFunc("/{profileID}/credentials/issueCredential", handle)
+	router.HandleFunc("/profile/{id}", handle)
+	router.HandleFunc("/verifier/profile/{id}", handle)
+	router.HandleFunc("/status/{id}", handle)
+	router.HandleFunc("/profile", handle)
+
+	serve := func(subject, path string) int {
+		client = "not called"
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if subject != "" {
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+				{Subject: pkix.Name{CommonName: subject}},
+			}}}
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	t.Run("test profile of the client", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, serve("issuer-client", "/issuer1/credentials/issueCredential"))
+		require.Equal(t, "issuer-client", client)
+
+		require.Equal(t, http.StatusOK, serve("issuer-client", "/profile/issuer1"))
+		require.Equal(t, http.StatusOK, serve("issuer-client", "/verifier/profile/issuer1"))
+	})
+
+	t.Run("test other profile", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, serve("issuer-client", "/issuer2/credentials/issueCredential"))
+		require.Equal(t, "not called", client)
+
+		require.Equal(t, http.StatusForbidden, serve("issuer-client", "/profile/issuer2"))
+		require.Equal(t, http.StatusForbidden, serve("other-client", "/profile/issuer1"))
+	})
+
+	t.Run("test requests not scoped to a profile", func(t *testing.T) {
+		require.Equal(t, http.StatusForbidden, serve("issuer-client", "/profile"))
+		require.Equal(t, http.StatusForbidden, serve("issuer-client", "/status/1"))
+
+		require.Equal(t, http.StatusOK, serve("admin", "/profile"))
+		require.Equal(t, http.StatusOK, serve("admin", "/status/1"))
+		require.Equal(t, http.StatusOK, serve("admin", "/issuer2/credentials/issueCredential"))
+		require.Equal(t, "admin", client)
+	})
+
+	t.Run("test no client certificate", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, serve("", "/profile/issuer1"))
+		require.Equal(t, "not called", client)
+	})
+}
+
+func TestAuthorizer_Allowed(t *testing.T) {
+	t.Run("test without permissions", func(t *testing.T) {
+		require.True(t, New(nil).Allowed("client", "profile"))
+		require.True(t, New(nil).Allowed("client", ""))
+	})
+
+	t.Run("test permissions", func(t *testing.T) {
+		a := New(map[string][]string{"client": {"profile1", "profile2"}})
+
+		require.True(t, a.Allowed("client", "profile1"))
+		require.True(t, a.Allowed("client", "profile2"))
+		require.False(t, a.Allowed("client", "profile3"))
+		require.False(t, a.Allowed("client", ""))
+		require.False(t, a.Allowed("other", "profile1"))
+	})
+}
+
+func TestSubject(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	_, ok := Subject(req)
+	require.False(t, ok)
+
+	req.TLS = &tls.ConnectionState{}
+
+	_, ok = Subject(req)
+	require.False(t, ok)
+
+	req.TLS.VerifiedChains = [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "client"}}}}
+
+	subject, ok := Subject(req)
+	require.True(t, ok)
+	require.Equal(t, "client", subject)
+}
@@ -0,0 +1,655 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anoncreds
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// the predicate types of the requested predicates
+const (
+	predicateGreaterOrEqual = ">="
+	predicateGreater        = ">"
+	predicateLessOrEqual    = "<="
+	predicateLess           = "<"
+)
+
+// the restrictions of the requested attributes and predicates translated to constraints of the W3C credentials
+const (
+	restrictionIssuerDID  = "issuer_did"
+	restrictionCredDefID  = "cred_def_id"
+	restrictionSchemaID   = "schema_id"
+	restrictionSchemaName = "schema_name"
+
+	// the restrictions on the value of an attribute (attr::<name>::value) or on its presence (attr::<name>::marker)
+	attrRestrictionPrefix = "attr::"
+	attrValueSuffix       = "::value"
+	attrMarkerSuffix      = "::marker"
+)
+
+const (
+	// the unqualified DIDs of the AnonCreds issuers are Indy DIDs
+	indyDIDPrefix = "did:sov:"
+
+	limitDisclosureRequired = "required"
+	predicateRequired       = "required"
+	statusRequired          = "required"
+
+	// the nonces of the proof requests are 80-bit numbers
+	nonceBits = 80
+)
+
+// the paths of the fields of the credentials constrained by the restrictions
+// nolint: gochecknoglobals
+var (
+	issuerPaths     = []string{"$.issuer", "$.issuer.id", "$.vc.iss"}
+	schemaIDPaths   = []string{"$.credentialSchema.id", "$.vc.credentialSchema.id"}
+	schemaNamePaths = []string{"$.type", "$.vc.type"}
+)
+
+// plainName matches the attribute names usable in the dot notation of a JSONPath
+var plainName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ProofRequest is an AnonCreds (Hyperledger Indy) proof request, its requested attributes and predicates being keyed
+// by their referents
+type ProofRequest struct {
+	Name                string                    `json:"name,omitempty"`
+	Version             string                    `json:"version,omitempty"`
+	Nonce               string                    `json:"nonce"`
+	RequestedAttributes map[string]*AttributeInfo `json:"requested_attributes,omitempty"`
+	RequestedPredicates map[string]*PredicateInfo `json:"requested_predicates,omitempty"`
+	// NonRevoked requires the credentials not to be revoked, unless overridden by an attribute or a predicate.
+	NonRevoked *NonRevokedInterval `json:"non_revoked,omitempty"`
+}
+
+// AttributeInfo is a requested attribute, or a group of attributes of the same credential
+type AttributeInfo struct {
+	Name         string              `json:"name,omitempty"`
+	Names        []string            `json:"names,omitempty"`
+	Restrictions []map[string]string `json:"restrictions,omitempty"`
+	NonRevoked   *NonRevokedInterval `json:"non_revoked,omitempty"`
+}
+
+// PredicateInfo is a requested predicate, an attribute compared to an integer without revealing it
+type PredicateInfo struct {
+	Name         string              `json:"name"`
+	PType        string              `json:"p_type"`
+	PValue       int64               `json:"p_value"`
+	Restrictions []map[string]string `json:"restrictions,omitempty"`
+	NonRevoked   *NonRevokedInterval `json:"non_revoked,omitempty"`
+}
+
+// NonRevokedInterval is the interval the credentials mustn't be revoked in, as Unix times
+type NonRevokedInterval struct {
+	From *int64 `json:"from,omitempty"`
+	To   *int64 `json:"to,omitempty"`
+}
+
+// PresentationDefinition is a DIF presentation definition translated from a proof request
+type PresentationDefinition struct {
+	ID               string             `json:"id"`
+	Name             string             `json:"name,omitempty"`
+	InputDescriptors []*InputDescriptor `json:"input_descriptors"`
+}
+
+// InputDescriptor is the credential of a requested attribute or predicate, identified by its referent
+type InputDescriptor struct {
+	ID          string       `json:"id"`
+	Constraints *Constraints `json:"constraints"`
+}
+
+// Constraints are the fields the credential of an input descriptor must have, which are the only ones disclosed
+type Constraints struct {
+	LimitDisclosure string    `json:"limit_disclosure"`
+	Statuses        *Statuses `json:"statuses,omitempty"`
+	Fields          []*Field  `json:"fields"`
+}
+
+// Statuses requires the credential to be active, i.e. not revoked
+type Statuses struct {
+	Active *StatusDirective `json:"active"`
+}
+
+// StatusDirective is the directive of a status of the credential
+type StatusDirective struct {
+	Directive string `json:"directive"`
+}
+
+// Field is a field of the credential at the first of its paths, matching its filter (a JSON schema) if set
+type Field struct {
+	Path      []string               `json:"path"`
+	Filter    map[string]interface{} `json:"filter,omitempty"`
+	Predicate string                 `json:"predicate,omitempty"`
+}
+
+// ParsePresentationDefinition parses the presentation definition to translate to a proof request
+func ParsePresentationDefinition(raw json.RawMessage) (*PresentationDefinition, error) {
+	definition := &PresentationDefinition{}
+
+	if err := json.Unmarshal(raw, definition); err != nil {
+		return nil, fmt.Errorf("invalid presentation definition: %w", err)
+	}
+
+	return definition, nil
+}
+
+// ParseProofRequest parses the AnonCreds proof request
+func ParseProofRequest(raw json.RawMessage) (*ProofRequest, error) {
+	request := &ProofRequest{}
+
+	if err := json.Unmarshal(raw, request); err != nil {
+		return nil, fmt.Errorf("invalid proof request: %w", err)
+	}
+
+	return request, nil
+}
+
+// ToPresentationDefinition translates the proof request to a presentation definition, the nonce of the request being
+// its ID: each requested attribute or predicate is an input descriptor of the credential subject fields it requests,
+// with the constraints of its restrictions. The W3C credentials have no credential definitions nor CL signatures,
+// so the restrictions of a credential definition are translated to its issuer only, and the predicates are required
+// to be proven rather than disclosed.
+func ToPresentationDefinition(request *ProofRequest) (*PresentationDefinition, error) {
+	if request.Nonce == "" {
+		return nil, errors.New("the proof request must have a nonce")
+	}
+
+	if len(request.RequestedAttributes) == 0 && len(request.RequestedPredicates) == 0 {
+		return nil, errors.New("the proof request has no requested attributes nor predicates")
+	}
+
+	definition := &PresentationDefinition{ID: request.Nonce, Name: request.Name}
+
+	for _, referent := range sortedKeys(request.RequestedAttributes) {
+		descriptor, err := attributeDescriptor(referent, request.RequestedAttributes[referent], request.NonRevoked)
+		if err != nil {
+			return nil, fmt.Errorf("requested attribute %s: %w", referent, err)
+		}
+
+		definition.InputDescriptors = append(definition.InputDescriptors, descriptor)
+	}
+
+	for _, referent := range sortedKeys(request.RequestedPredicates) {
+		if _, ok := request.RequestedAttributes[referent]; ok {
+			return nil, fmt.Errorf("requested predicate %s: the referent is also a requested attribute", referent)
+		}
+
+		descriptor, err := predicateDescriptor(referent, request.RequestedPredicates[referent], request.NonRevoked)
+		if err != nil {
+			return nil, fmt.Errorf("requested predicate %s: %w", referent, err)
+		}
+
+		definition.InputDescriptors = append(definition.InputDescriptors, descriptor)
+	}
+
+	return definition, nil
+}
+
+func attributeDescriptor(referent string, attribute *AttributeInfo,
+	nonRevoked *NonRevokedInterval) (*InputDescriptor, error) {
+	if attribute == nil || (attribute.Name == "") == (len(attribute.Names) == 0) {
+		return nil, errors.New("exactly one of name and names must be set")
+	}
+
+	names := attribute.Names
+	if attribute.Name != "" {
+		names = []string{attribute.Name}
+	}
+
+	var fields []*Field
+
+	for _, name := range names {
+		if name == "" {
+			return nil, errors.New("the names must not be empty")
+		}
+
+		fields = append(fields, &Field{Path: subjectPaths(name)})
+	}
+
+	return descriptor(referent, fields, attribute.Restrictions, nonRevokedOf(attribute.NonRevoked, nonRevoked))
+}
+
+func predicateDescriptor(referent string, predicate *PredicateInfo,
+	nonRevoked *NonRevokedInterval) (*InputDescriptor, error) {
+	if predicate == nil || predicate.Name == "" {
+		return nil, errors.New("the name must be set")
+	}
+
+	filter := map[string]interface{}{"type": "number"}
+
+	switch predicate.PType {
+	case predicateGreaterOrEqual:
+		filter["minimum"] = predicate.PValue
+	case predicateGreater:
+		filter["exclusiveMinimum"] = predicate.PValue
+	case predicateLessOrEqual:
+		filter["maximum"] = predicate.PValue
+	case predicateLess:
+		filter["exclusiveMaximum"] = predicate.PValue
+	default:
+		return nil, fmt.Errorf("unsupported predicate type %s", predicate.PType)
+	}
+
+	fields := []*Field{{Path: subjectPaths(predicate.Name), Filter: filter, Predicate: predicateRequired}}
+
+	return descriptor(referent, fields, predicate.Restrictions, nonRevokedOf(predicate.NonRevoked, nonRevoked))
+}
+
+// descriptor returns the input descriptor of the fields, constrained by the restriction
+func descriptor(referent string, fields []*Field, restrictions []map[string]string,
+	nonRevoked *NonRevokedInterval) (*InputDescriptor, error) {
+	// the restrictions are alternatives, the input descriptors have no alternative constraints
+	if len(restrictions) > 1 {
+		return nil, errors.New("alternative restrictions are not supported")
+	}
+
+	constraints := &Constraints{LimitDisclosure: limitDisclosureRequired, Fields: fields}
+
+	if len(restrictions) == 1 {
+		restrictionFields, err := restrictionFields(restrictions[0])
+		if err != nil {
+			return nil, err
+		}
+
+		constraints.Fields = append(constraints.Fields, restrictionFields...)
+	}
+
+	if nonRevoked != nil {
+		constraints.Statuses = &Statuses{Active: &StatusDirective{Directive: statusRequired}}
+	}
+
+	return &InputDescriptor{ID: referent, Constraints: constraints}, nil
+}
+
+// restrictionFields returns the fields of the credential constrained by the restriction
+func restrictionFields(restriction map[string]string) ([]*Field, error) {
+	var fields []*Field
+
+	for _, key := range sortedKeys(restriction) {
+		value := restriction[key]
+
+		switch {
+		case key == restrictionIssuerDID:
+			fields = append(fields, issuerField(value))
+		case key == restrictionCredDefID:
+			// the credential definition IDs start with the DID of their issuer: <issuer DID>:3:CL:<schema>:<tag>
+			issuer := strings.SplitN(value, ":3:", 2)[0]
+			if issuer == value || issuer == "" {
+				return nil, fmt.Errorf("invalid credential definition ID %s", value)
+			}
+
+			fields = append(fields, issuerField(issuer))
+		case key == restrictionSchemaID:
+			fields = append(fields, &Field{Path: schemaIDPaths,
+				Filter: map[string]interface{}{"type": "string", "const": value}})
+		case key == restrictionSchemaName:
+			fields = append(fields, &Field{Path: schemaNamePaths,
+				Filter: map[string]interface{}{"type": "array", "contains": map[string]interface{}{"const": value}}})
+		case strings.HasPrefix(key, attrRestrictionPrefix) && strings.HasSuffix(key, attrValueSuffix):
+			name := strings.TrimSuffix(strings.TrimPrefix(key, attrRestrictionPrefix), attrValueSuffix)
+
+			fields = append(fields, &Field{Path: subjectPaths(name),
+				Filter: map[string]interface{}{"type": "string", "const": value}})
+		case strings.HasPrefix(key, attrRestrictionPrefix) && strings.HasSuffix(key, attrMarkerSuffix):
+			name := strings.TrimSuffix(strings.TrimPrefix(key, attrRestrictionPrefix), attrMarkerSuffix)
+
+			fields = append(fields, &Field{Path: subjectPaths(name)})
+		default:
+			return nil, fmt.Errorf("unsupported restriction %s", key)
+		}
+	}
+
+	return fields, nil
+}
+
+// issuerField returns the field of the issuer of the credential, the unqualified DIDs being Indy DIDs
+func issuerField(did string) *Field {
+	if !strings.HasPrefix(did, "did:") {
+		did = indyDIDPrefix + did
+	}
+
+	return &Field{Path: issuerPaths, Filter: map[string]interface{}{"type": "string", "const": did}}
+}
+
+// subjectPaths returns the paths of the attribute in the subject of the credential, in the JSON-LD and the JWT
+// credentials
+func subjectPaths(name string) []string {
+	path := "." + name
+	if !plainName.MatchString(name) {
+		path = "['" + strings.ReplaceAll(name, "'", `\'`) + "']"
+	}
+
+	return []string{"$.credentialSubject" + path, "$.vc.credentialSubject" + path}
+}
+
+// subjectName returns the name of the attribute of the credential subject at the path, false if the path isn't an
+// attribute of the subject
+func subjectName(path string) (string, bool) {
+	for _, prefix := range []string{"$.credentialSubject", "$.vc.credentialSubject"} {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+
+		name := strings.TrimPrefix(path, prefix)
+
+		switch {
+		case strings.HasPrefix(name, ".") && plainName.MatchString(name[1:]):
+			return name[1:], true
+		case strings.HasPrefix(name, "['") && strings.HasSuffix(name, "']") && len(name) > 4:
+			return strings.ReplaceAll(name[2:len(name)-2], `\'`, "'"), true
+		}
+	}
+
+	return "", false
+}
+
+// nonRevokedOf returns the interval of an attribute or a predicate, the interval of the request if not set
+func nonRevokedOf(interval, requestInterval *NonRevokedInterval) *NonRevokedInterval {
+	if interval != nil {
+		return interval
+	}
+
+	return requestInterval
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+
+	switch values := m.(type) {
+	case map[string]*AttributeInfo:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	case map[string]*PredicateInfo:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	case map[string]string:
+		for key := range values {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// NewNonce returns a new random nonce of a proof request, a decimal 80-bit number
+func NewNonce() (string, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), nonceBits))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return n.String(), nil
+}
+
+// ToProofRequest translates the presentation definition to a proof request with the nonce, reversing
+// ToPresentationDefinition: the subject fields of each input descriptor are the attributes requested by the referent
+// of its ID, its number filters the predicates on them, and its other constraints the restrictions of the credential.
+// The predicates of an input descriptor also requesting attributes, or requesting several predicates, are requested
+// by the referents <ID>_predicate<N>. An active status required is the non_revoked interval up to the translation.
+// The fields which have no AnonCreds equivalent are rejected.
+func ToProofRequest(definition *PresentationDefinition, nonce string) (*ProofRequest, error) {
+	if nonce == "" {
+		return nil, errors.New("the proof request must have a nonce")
+	}
+
+	if len(definition.InputDescriptors) == 0 {
+		return nil, errors.New("the presentation definition has no input descriptors")
+	}
+
+	request := &ProofRequest{Name: definition.Name, Version: "1.0", Nonce: nonce,
+		RequestedAttributes: map[string]*AttributeInfo{}, RequestedPredicates: map[string]*PredicateInfo{}}
+
+	for _, descriptor := range definition.InputDescriptors {
+		if descriptor == nil || descriptor.ID == "" {
+			return nil, errors.New("the input descriptors must have an id")
+		}
+
+		if err := addDescriptor(request, descriptor); err != nil {
+			return nil, fmt.Errorf("input descriptor %s: %w", descriptor.ID, err)
+		}
+	}
+
+	return request, nil
+}
+
+// addDescriptor adds the requested attributes and predicates of the input descriptor to the request
+func addDescriptor(request *ProofRequest, descriptor *InputDescriptor) error {
+	names, predicates, restrictions, err := descriptorFields(descriptor)
+	if err != nil {
+		return err
+	}
+
+	var restriction []map[string]string
+	if len(restrictions) > 0 {
+		restriction = []map[string]string{restrictions}
+	}
+
+	nonRevoked := statusNonRevoked(descriptor.Constraints.Statuses)
+	id := descriptor.ID
+
+	if _, ok := request.RequestedAttributes[id]; ok {
+		return errors.New("duplicate input descriptor id")
+	}
+
+	switch len(names) {
+	case 0:
+	case 1:
+		request.RequestedAttributes[id] = &AttributeInfo{Name: names[0], Restrictions: restriction,
+			NonRevoked: nonRevoked}
+	default:
+		request.RequestedAttributes[id] = &AttributeInfo{Names: names, Restrictions: restriction,
+			NonRevoked: nonRevoked}
+	}
+
+	for i, predicate := range predicates {
+		referent := id
+		if len(names) > 0 || len(predicates) > 1 {
+			referent = fmt.Sprintf("%s_predicate%d", id, i+1)
+		}
+
+		if _, ok := request.RequestedPredicates[referent]; ok {
+			return fmt.Errorf("duplicate referent %s", referent)
+		}
+
+		predicate.Restrictions = restriction
+		predicate.NonRevoked = nonRevoked
+		request.RequestedPredicates[referent] = predicate
+	}
+
+	return nil
+}
+
+// statusNonRevoked returns the non_revoked interval up to now if an active status is required, else nil
+func statusNonRevoked(statuses *Statuses) *NonRevokedInterval {
+	if statuses == nil || statuses.Active == nil || statuses.Active.Directive != statusRequired {
+		return nil
+	}
+
+	now := time.Now().Unix()
+
+	return &NonRevokedInterval{To: &now}
+}
+
+// descriptorFields returns the attributes, the predicates and the restrictions requested by the fields of the input
+// descriptor
+func descriptorFields(descriptor *InputDescriptor) ([]string, []*PredicateInfo, map[string]string, error) {
+	if descriptor.Constraints == nil {
+		return nil, nil, nil, errors.New("no fields requested")
+	}
+
+	var (
+		names        []string
+		predicates   []*PredicateInfo
+		restrictions = map[string]string{}
+	)
+
+	for _, field := range descriptor.Constraints.Fields {
+		fieldPredicates, err := addField(field, &names, restrictions)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		predicates = append(predicates, fieldPredicates...)
+	}
+
+	// the fields of the attributes of the predicates are the markers of their attributes
+	for _, predicate := range predicates {
+		for i, name := range names {
+			if name == predicate.Name {
+				restrictions[attrRestrictionPrefix+name+attrMarkerSuffix] = "1"
+				names = append(names[:i], names[i+1:]...)
+
+				break
+			}
+		}
+	}
+
+	if len(names) == 0 && len(predicates) == 0 {
+		return nil, nil, nil, errors.New("no attribute of the credential subject requested")
+	}
+
+	return names, predicates, restrictions, nil
+}
+
+// addField adds the requested attribute or the restriction of the field, and returns the predicates of its filter
+func addField(field *Field, names *[]string, restrictions map[string]string) ([]*PredicateInfo, error) {
+	if field == nil || len(field.Path) == 0 {
+		return nil, errors.New("the fields must have a path")
+	}
+
+	path := field.Path[0]
+
+	if name, ok := subjectName(path); ok {
+		return addSubjectField(field, name, names, restrictions)
+	}
+
+	switch {
+	case samePaths(field.Path, issuerPaths):
+		did, ok := field.Filter["const"].(string)
+		if !ok {
+			return nil, fmt.Errorf("unsupported filter of field %s", path)
+		}
+
+		restrictions[restrictionIssuerDID] = strings.TrimPrefix(did, indyDIDPrefix)
+	case samePaths(field.Path, schemaIDPaths):
+		schemaID, ok := field.Filter["const"].(string)
+		if !ok {
+			return nil, fmt.Errorf("unsupported filter of field %s", path)
+		}
+
+		restrictions[restrictionSchemaID] = schemaID
+	case samePaths(field.Path, schemaNamePaths):
+		contains, _ := field.Filter["contains"].(map[string]interface{}) // nolint: errcheck
+
+		schemaName, ok := contains["const"].(string)
+		if !ok {
+			return nil, fmt.Errorf("unsupported filter of field %s", path)
+		}
+
+		restrictions[restrictionSchemaName] = schemaName
+	default:
+		return nil, fmt.Errorf("unsupported field %s", path)
+	}
+
+	return nil, nil
+}
+
+// addSubjectField adds the attribute of the credential subject requested by the field without filter, or the value
+// restriction of its string const, and returns the predicates of its number filter
+func addSubjectField(field *Field, name string, names *[]string,
+	restrictions map[string]string) ([]*PredicateInfo, error) {
+	switch {
+	case field.Filter == nil:
+		*names = append(*names, name)
+
+		return nil, nil
+	case field.Filter["type"] == "number":
+		return filterPredicates(name, field.Filter)
+	}
+
+	value, ok := field.Filter["const"].(string)
+	if !ok || field.Filter["type"] != "string" {
+		return nil, fmt.Errorf("unsupported filter of field %s", field.Path[0])
+	}
+
+	restrictions[attrRestrictionPrefix+name+attrValueSuffix] = value
+
+	return nil, nil
+}
+
+// filterPredicates returns the predicates of the bounds of the number filter of the attribute
+func filterPredicates(name string, filter map[string]interface{}) ([]*PredicateInfo, error) {
+	var predicates []*PredicateInfo
+
+	for _, bound := range []struct{ keyword, pType string }{{"minimum", predicateGreaterOrEqual},
+		{"exclusiveMinimum", predicateGreater}, {"maximum", predicateLessOrEqual},
+		{"exclusiveMaximum", predicateLess}} {
+		value, ok := filter[bound.keyword]
+		if !ok {
+			continue
+		}
+
+		pValue, ok := integer(value)
+		if !ok {
+			return nil, fmt.Errorf("the %s of attribute %s must be an integer", bound.keyword, name)
+		}
+
+		predicates = append(predicates, &PredicateInfo{Name: name, PType: bound.pType, PValue: pValue})
+	}
+
+	if len(predicates) == 0 {
+		return nil, fmt.Errorf("the number filter of attribute %s has no bound", name)
+	}
+
+	return predicates, nil
+}
+
+// integer returns the value as an integer, the numbers of the parsed JSON documents being float64
+func integer(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > math.MaxInt32 {
+			return 0, false
+		}
+
+		return int64(v), true
+	}
+
+	return 0, false
+}
+
+// samePaths returns true if the paths of the field start with the paths
+func samePaths(fieldPaths, paths []string) bool {
+	if len(fieldPaths) > len(paths) {
+		return false
+	}
+
+	for i, path := range fieldPaths {
+		if path != paths[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,294 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anoncreds
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/presentationrequest"
+)
+
+const proofRequest = `{
+	"name": "proof of education",
+	"version": "1.0",
+	"nonce": "1234567890",
+	"requested_attributes": {
+		"attr1_referent": {
+			"name": "degree",
+			"restrictions": [{"cred_def_id": "Th7MpTaRZVRYnPiabds81Y:3:CL:12:tag", "attr::status::value": "graduated"}]
+		},
+		"attr2_referent": {
+			"names": ["first name", "last_name"],
+			"restrictions": [{"issuer_did": "did:example:76e12ec712ebc6f1c221ebfeb1f", "schema_name": "Passport"}]
+		}
+	},
+	"requested_predicates": {
+		"predicate1_referent": {
+			"name": "age",
+			"p_type": ">=",
+			"p_value": 18,
+			"restrictions": [{"schema_id": "https://example.com/schemas/passport", "attr::age::marker": "1"}]
+		}
+	},
+	"non_revoked": {"to": 1591000000}
+}`
+
+func TestToPresentationDefinition(t *testing.T) {
+	t.Run("test proof request", func(t *testing.T) {
+		request, err := ParseProofRequest(json.RawMessage(proofRequest))
+		require.NoError(t, err)
+
+		definition, err := ToPresentationDefinition(request)
+		require.NoError(t, err)
+
+		definitionBytes, err := json.Marshal(definition)
+		require.NoError(t, err)
+
+		require.JSONEq(t, `{
+			"id": "1234567890",
+			"name": "proof of education",
+			"input_descriptors": [
+				{"id": "attr1_referent", "constraints": {"limit_disclosure": "required",
+					"statuses": {"active": {"directive": "required"}}, "fields": [
+					{"path": ["$.credentialSubject.degree", "$.vc.credentialSubject.degree"]},
+					{"path": ["$.credentialSubject.status", "$.vc.credentialSubject.status"],
+						"filter": {"type": "string", "const": "graduated"}},
+					{"path": ["$.issuer", "$.issuer.id", "$.vc.iss"],
+						"filter": {"type": "string", "const": "did:sov:Th7MpTaRZVRYnPiabds81Y"}}
+				]}},
+				{"id": "attr2_referent", "constraints": {"limit_disclosure": "required",
+					"statuses": {"active": {"directive": "required"}}, "fields": [
+					{"path": ["$.credentialSubject['first name']", "$.vc.credentialSubject['first name']"]},
+					{"path": ["$.credentialSubject.last_name", "$.vc.credentialSubject.last_name"]},
+					{"path": ["$.issuer", "$.issuer.id", "$.vc.iss"],
+						"filter": {"type": "string", "const": "did:example:76e12ec712ebc6f1c221ebfeb1f"}},
+					{"path": ["$.type", "$.vc.type"], "filter": {"type": "array", "contains": {"const": "Passport"}}}
+				]}},
+				{"id": "predicate1_referent", "constraints": {"limit_disclosure": "required",
+					"statuses": {"active": {"directive": "required"}}, "fields": [
+					{"path": ["$.credentialSubject.age", "$.vc.credentialSubject.age"],
+						"filter": {"type": "number", "minimum": 18}, "predicate": "required"},
+					{"path": ["$.credentialSubject.age", "$.vc.credentialSubject.age"]},
+					{"path": ["$.credentialSchema.id", "$.vc.credentialSchema.id"],
+						"filter": {"type": "string", "const": "https://example.com/schemas/passport"}}
+				]}}
+			]
+		}`, string(definitionBytes))
+
+		// the presentation definitions are accepted by the presentation requests of the verifiers
+		parsed, err := presentationrequest.ParseDefinition(definitionBytes)
+		require.NoError(t, err)
+		require.Len(t, parsed.InputDescriptors, 3)
+	})
+
+	t.Run("test predicate types", func(t *testing.T) {
+		for pType, keyword := range map[string]string{">=": "minimum", ">": "exclusiveMinimum", "<=": "maximum",
+			"<": "exclusiveMaximum"} {
+			definition, err := ToPresentationDefinition(&ProofRequest{Nonce: "1",
+				RequestedPredicates: map[string]*PredicateInfo{"p": {Name: "age", PType: pType, PValue: 65}}})
+			require.NoError(t, err)
+
+			field := definition.InputDescriptors[0].Constraints.Fields[0]
+			require.Equal(t, map[string]interface{}{"type": "number", keyword: int64(65)}, field.Filter)
+			require.Equal(t, "required", field.Predicate)
+			require.Nil(t, definition.InputDescriptors[0].Constraints.Statuses)
+		}
+	})
+
+	t.Run("test non revoked attribute", func(t *testing.T) {
+		from := int64(1591000000)
+
+		definition, err := ToPresentationDefinition(&ProofRequest{Nonce: "1",
+			RequestedAttributes: map[string]*AttributeInfo{
+				"a": {Name: "degree", NonRevoked: &NonRevokedInterval{From: &from}},
+				"b": {Name: "name"},
+			}})
+		require.NoError(t, err)
+		require.NotNil(t, definition.InputDescriptors[0].Constraints.Statuses)
+		require.Nil(t, definition.InputDescriptors[1].Constraints.Statuses)
+	})
+
+	for name, test := range map[string]struct {
+		request string
+		err     string
+	}{
+		"missing nonce": {`{"requested_attributes":{"a":{"name":"degree"}}}`,
+			"the proof request must have a nonce"},
+		"nothing requested": {`{"nonce":"1"}`, "the proof request has no requested attributes nor predicates"},
+		"name and names": {`{"nonce":"1","requested_attributes":{"a":{"name":"degree","names":["name"]}}}`,
+			"requested attribute a: exactly one of name and names must be set"},
+		"no name": {`{"nonce":"1","requested_attributes":{"a":{}}}`,
+			"requested attribute a: exactly one of name and names must be set"},
+		"empty names": {`{"nonce":"1","requested_attributes":{"a":{"names":["degree",""]}}}`,
+			"requested attribute a: the names must not be empty"},
+		"predicate without name": {`{"nonce":"1","requested_predicates":{"p":{"p_type":">=","p_value":18}}}`,
+			"requested predicate p: the name must be set"},
+		"unsupported predicate": {`{"nonce":"1","requested_predicates":{"p":{"name":"age","p_type":"==","p_value":1}}}`,
+			"requested predicate p: unsupported predicate type =="},
+		"duplicate referent": {`{"nonce":"1","requested_attributes":{"a":{"name":"degree"}},
+			"requested_predicates":{"a":{"name":"age","p_type":">=","p_value":18}}}`,
+			"requested predicate a: the referent is also a requested attribute"},
+		"alternative restrictions": {`{"nonce":"1","requested_attributes":{"a":{"name":"degree",
+			"restrictions":[{"issuer_did":"did:example:1"},{"issuer_did":"did:example:2"}]}}}`,
+			"requested attribute a: alternative restrictions are not supported"},
+		"unsupported restriction": {`{"nonce":"1","requested_attributes":{"a":{"name":"degree",
+			"restrictions":[{"schema_version":"1.0"}]}}}`,
+			"requested attribute a: unsupported restriction schema_version"},
+		"invalid credential definition": {`{"nonce":"1","requested_attributes":{"a":{"name":"degree",
+			"restrictions":[{"cred_def_id":"12"}]}}}`,
+			"requested attribute a: invalid credential definition ID 12"},
+	} {
+		test := test
+
+		t.Run("test "+name, func(t *testing.T) {
+			request, err := ParseProofRequest(json.RawMessage(test.request))
+			require.NoError(t, err)
+
+			_, err = ToPresentationDefinition(request)
+			require.EqualError(t, err, test.err)
+		})
+	}
+
+	t.Run("test invalid proof request", func(t *testing.T) {
+		_, err := ParseProofRequest(json.RawMessage(`{"nonce":1}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid proof request")
+	})
+}
+
+func TestToProofRequest(t *testing.T) {
+	t.Run("test presentation definition of a proof request", func(t *testing.T) {
+		request, err := ParseProofRequest(json.RawMessage(proofRequest))
+		require.NoError(t, err)
+
+		definition, err := ToPresentationDefinition(request)
+		require.NoError(t, err)
+
+		definitionBytes, err := json.Marshal(definition)
+		require.NoError(t, err)
+
+		parsed, err := ParsePresentationDefinition(definitionBytes)
+		require.NoError(t, err)
+
+		translated, err := ToProofRequest(parsed, "987")
+		require.NoError(t, err)
+
+		// the non_revoked intervals end with the translation
+		for _, attribute := range translated.RequestedAttributes {
+			require.NotNil(t, attribute.NonRevoked.To)
+			attribute.NonRevoked = nil
+		}
+
+		for _, predicate := range translated.RequestedPredicates {
+			require.NotNil(t, predicate.NonRevoked.To)
+			predicate.NonRevoked = nil
+		}
+
+		translatedBytes, err := json.Marshal(translated)
+		require.NoError(t, err)
+
+		// the credential definition is translated to its issuer
+		require.JSONEq(t, `{
+			"name": "proof of education",
+			"version": "1.0",
+			"nonce": "987",
+			"requested_attributes": {
+				"attr1_referent": {"name": "degree",
+					"restrictions": [{"issuer_did": "Th7MpTaRZVRYnPiabds81Y", "attr::status::value": "graduated"}]},
+				"attr2_referent": {"names": ["first name", "last_name"],
+					"restrictions": [{"issuer_did": "did:example:76e12ec712ebc6f1c221ebfeb1f", "schema_name": "Passport"}]}
+			},
+			"requested_predicates": {
+				"predicate1_referent": {"name": "age", "p_type": ">=", "p_value": 18,
+					"restrictions": [{"schema_id": "https://example.com/schemas/passport", "attr::age::marker": "1"}]}
+			}
+		}`, string(translatedBytes))
+	})
+
+	t.Run("test predicates of an input descriptor", func(t *testing.T) {
+		definition, err := ParsePresentationDefinition(json.RawMessage(`{"id":"pd1","input_descriptors":[
+			{"id":"d1","constraints":{"fields":[
+				{"path":["$.credentialSubject.name"]},
+				{"path":["$.credentialSubject.age"],"filter":{"type":"number","exclusiveMinimum":17,"maximum":65}}
+			]}}]}`))
+		require.NoError(t, err)
+
+		request, err := ToProofRequest(definition, "1")
+		require.NoError(t, err)
+		require.Equal(t, map[string]*AttributeInfo{"d1": {Name: "name"}}, request.RequestedAttributes)
+		require.Equal(t, map[string]*PredicateInfo{
+			"d1_predicate1": {Name: "age", PType: ">", PValue: 17},
+			"d1_predicate2": {Name: "age", PType: "<=", PValue: 65},
+		}, request.RequestedPredicates)
+	})
+
+	for name, test := range map[string]struct {
+		definition string
+		err        string
+	}{
+		"no input descriptors": {`{"id":"pd1"}`, "the presentation definition has no input descriptors"},
+		"input descriptor without id": {`{"id":"pd1","input_descriptors":[{}]}`,
+			"the input descriptors must have an id"},
+		"duplicate input descriptors": {`{"id":"pd1","input_descriptors":[
+			{"id":"d1","constraints":{"fields":[{"path":["$.credentialSubject.name"]}]}},
+			{"id":"d1","constraints":{"fields":[{"path":["$.credentialSubject.name"]}]}}]}`,
+			"input descriptor d1: duplicate input descriptor id"},
+		"no constraints": {`{"id":"pd1","input_descriptors":[{"id":"d1"}]}`,
+			"input descriptor d1: no fields requested"},
+		"no subject field": {`{"id":"pd1","input_descriptors":[{"id":"d1","constraints":{"fields":[
+			{"path":["$.issuer"],"filter":{"type":"string","const":"did:example:1"}}]}}]}`,
+			"input descriptor d1: no attribute of the credential subject requested"},
+		"unsupported field": {`{"id":"pd1","input_descriptors":[{"id":"d1","constraints":{"fields":[
+			{"path":["$.expirationDate"]}]}}]}`, "input descriptor d1: unsupported field $.expirationDate"},
+		"unsupported filter": {`{"id":"pd1","input_descriptors":[{"id":"d1","constraints":{"fields":[
+			{"path":["$.credentialSubject.degree"],"filter":{"type":"string","pattern":"^B"}}]}}]}`,
+			"input descriptor d1: unsupported filter of field $.credentialSubject.degree"},
+		"unsupported issuer filter": {`{"id":"pd1","input_descriptors":[{"id":"d1","constraints":{"fields":[
+			{"path":["$.credentialSubject.degree"]},{"path":["$.issuer"],"filter":{"type":"string"}}]}}]}`,
+			"input descriptor d1: unsupported filter of field $.issuer"},
+		"number filter without bound": {`{"id":"pd1","input_descriptors":[{"id":"d1","constraints":{"fields":[
+			{"path":["$.credentialSubject.age"],"filter":{"type":"number"}}]}}]}`,
+			"input descriptor d1: the number filter of attribute age has no bound"},
+		"bound not an integer": {`{"id":"pd1","input_descriptors":[{"id":"d1","constraints":{"fields":[
+			{"path":["$.credentialSubject.age"],"filter":{"type":"number","minimum":17.5}}]}}]}`,
+			"input descriptor d1: the minimum of attribute age must be an integer"},
+	} {
+		test := test
+
+		t.Run("test "+name, func(t *testing.T) {
+			definition, err := ParsePresentationDefinition(json.RawMessage(test.definition))
+			require.NoError(t, err)
+
+			_, err = ToProofRequest(definition, "1")
+			require.EqualError(t, err, test.err)
+		})
+	}
+
+	t.Run("test missing nonce", func(t *testing.T) {
+		_, err := ToProofRequest(&PresentationDefinition{}, "")
+		require.EqualError(t, err, "the proof request must have a nonce")
+	})
+
+	t.Run("test invalid presentation definition", func(t *testing.T) {
+		_, err := ParsePresentationDefinition(json.RawMessage(`{"id":1}`))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid presentation definition")
+	})
+}
+
+func TestNewNonce(t *testing.T) {
+	nonce, err := NewNonce()
+	require.NoError(t, err)
+	require.Regexp(t, `^[0-9]+$`, nonce)
+
+	other, err := NewNonce()
+	require.NoError(t, err)
+	require.NotEqual(t, nonce, other)
+}
@@ -0,0 +1,329 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anoncreds
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// largeE is the bit size of the start of the range of the primes e of the CL signatures
+	largeE = 596
+	// deltaKey is the key of the values of the predicate proofs for delta, the difference of the attribute with the
+	// predicate value
+	deltaKey = "DELTA"
+)
+
+// squareKeys are the keys of the values of the predicate proofs for the four squares whose sum is delta
+var squareKeys = []string{"0", "1", "2", "3"} // nolint: gochecknoglobals
+
+// verifyProof verifies the CL proof of the credentials with the nonce of the proof request: the tau values of the
+// equality proofs and of the predicate proofs are recomputed from the challenge, and must hash to the challenge with
+// the commitments of the proofs and the nonce
+func verifyProof(proof *Proof, keys []*PrimaryPublicKey, nonce *big.Int) error {
+	aggregated := proof.AggregatedProof
+	if aggregated.CHash == nil {
+		return errors.New("invalid proof: missing challenge hash")
+	}
+
+	c := &aggregated.CHash.Int
+
+	var taus, commitments []*big.Int
+
+	for i, subProof := range proof.Proofs {
+		subTaus, subCommitments, err := verifySubProof(keys[i], subProof, c)
+		if err != nil {
+			return fmt.Errorf("invalid proof of credential %d: %w", i, err)
+		}
+
+		taus = append(taus, subTaus...)
+		commitments = append(commitments, subCommitments...)
+	}
+
+	if len(commitments) != len(aggregated.CList) {
+		return errors.New("invalid proof: the commitments don't match the aggregated proof")
+	}
+
+	for i, commitment := range commitments {
+		if new(big.Int).SetBytes(aggregated.CList[i]).Cmp(commitment) != 0 {
+			return errors.New("invalid proof: the commitments don't match the aggregated proof")
+		}
+	}
+
+	if challenge(taus, aggregated.CList, nonce).Cmp(c) != 0 {
+		return errors.New("invalid proof: the challenge doesn't match the proof")
+	}
+
+	return nil
+}
+
+// verifySubProof returns the tau values of the proof of a credential, and its commitments
+func verifySubProof(key *PrimaryPublicKey, subProof *SubProof, c *big.Int) ([]*big.Int, []*big.Int, error) {
+	if !key.complete() {
+		return nil, nil, errors.New("invalid public key")
+	}
+
+	if subProof.PrimaryProof == nil || subProof.PrimaryProof.EqProof == nil {
+		return nil, nil, errors.New("incomplete proof")
+	}
+
+	eqProof := subProof.PrimaryProof.EqProof
+
+	tau, err := verifyEquality(key, eqProof, c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	taus := []*big.Int{tau}
+	commitments := []*big.Int{&eqProof.APrime.Int}
+
+	for _, geProof := range subProof.PrimaryProof.GEProofs {
+		var predicateTaus []*big.Int
+
+		if predicateTaus, err = verifyPredicate(key, eqProof, geProof, c); err != nil {
+			return nil, nil, err
+		}
+
+		taus = append(taus, predicateTaus...)
+
+		for _, k := range append(squareKeys, deltaKey) {
+			commitments = append(commitments, &geProof.T[k].Int)
+		}
+	}
+
+	return taus, commitments, nil
+}
+
+// challenge returns the SHA-256 hash of the tau values, the commitments and the nonce, as big-endian bytes
+func challenge(taus []*big.Int, cList []ByteArray, nonce *big.Int) *big.Int {
+	hash := sha256.New()
+
+	for _, tau := range taus {
+		hash.Write(tau.Bytes()) // nolint: errcheck,gosec
+	}
+
+	for _, commitment := range cList {
+		hash.Write(commitment) // nolint: errcheck,gosec
+	}
+
+	hash.Write(nonce.Bytes()) // nolint: errcheck,gosec
+
+	return new(big.Int).SetBytes(hash.Sum(nil))
+}
+
+// verifyEquality returns the tau value of the equality proof, the proof of knowledge of the signature (A, e, v) of
+// the attributes: T = A'^e * S^v * Rctxt^m2 * Prod(R_i^m_i) * (Z / (A'^2^596 * Prod(R_j^r_j)))^-c, the attributes m_i
+// being unrevealed and r_j revealed
+func verifyEquality(key *PrimaryPublicKey, proof *EqProof, c *big.Int) (*big.Int, error) {
+	if proof.APrime == nil || proof.E == nil || proof.V == nil || proof.M2 == nil {
+		return nil, errors.New("incomplete equality proof")
+	}
+
+	n := &key.N.Int
+
+	rar, err := revealedProduct(key, proof)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := multiExp(n, &proof.APrime.Int, &proof.E.Int, &key.S.Int, &proof.V.Int, &key.Rctxt.Int, &proof.M2.Int)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, r := range key.R {
+		if _, ok := proof.RevealedAttrs[name]; ok {
+			continue
+		}
+
+		m := proof.M[name]
+		if m == nil {
+			return nil, fmt.Errorf("missing proof of attribute %s", name)
+		}
+
+		if t, err = mulExp(n, t, &r.Int, &m.Int); err != nil {
+			return nil, err
+		}
+	}
+
+	rarInverse := new(big.Int).ModInverse(rar, n)
+	if rarInverse == nil {
+		return nil, errors.New("invalid equality proof")
+	}
+
+	return mulExp(n, t, new(big.Int).Mul(&key.Z.Int, rarInverse), new(big.Int).Neg(c))
+}
+
+// revealedProduct returns A'^2^596 * Prod(R_j^r_j), the revealed attributes r_j being attributes of the key
+func revealedProduct(key *PrimaryPublicKey, proof *EqProof) (*big.Int, error) {
+	n := &key.N.Int
+
+	rar := new(big.Int).Exp(&proof.APrime.Int, new(big.Int).Lsh(big.NewInt(1), largeE), n)
+
+	for name, revealed := range proof.RevealedAttrs {
+		r := key.R[name]
+		if r == nil || revealed == nil {
+			return nil, fmt.Errorf("unknown revealed attribute %s", name)
+		}
+
+		rar.Mul(rar, new(big.Int).Exp(&r.Int, &revealed.Int, n)).Mod(rar, n)
+	}
+
+	return rar, nil
+}
+
+// verifyPredicate returns the tau values of the predicate proof of an unrevealed attribute m_j, the proof of
+// knowledge of the four squares u_i whose sum is delta, the difference of m_j with the predicate value, and of their
+// commitments T_i = Z^u_i * S^r_i and T_delta = Z^delta * S^r_delta
+func verifyPredicate(key *PrimaryPublicKey, eqProof *EqProof, proof *GEProof, c *big.Int) ([]*big.Int, error) {
+	if !proof.complete() {
+		return nil, errors.New("incomplete predicate proof")
+	}
+
+	// the predicate is proven on the attribute of the equality proof
+	if m := eqProof.M[proof.Predicate.AttrName]; m == nil || m.Cmp(&proof.MJ.Int) != 0 {
+		return nil, fmt.Errorf("the predicate of attribute %s isn't bound to the equality proof",
+			proof.Predicate.AttrName)
+	}
+
+	value, less, err := deltaPrime(proof.Predicate)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &key.N.Int
+	minusC := new(big.Int).Neg(c)
+
+	taus := make([]*big.Int, len(squareKeys))
+
+	for i, k := range squareKeys {
+		taus[i], err = multiExp(n, &key.Z.Int, &proof.U[k].Int, &key.S.Int, &proof.R[k].Int, &proof.T[k].Int, minusC)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	deltaTau, err := verifyDelta(key, proof, value, less, minusC)
+	if err != nil {
+		return nil, err
+	}
+
+	// S^alpha * Prod(T_i^u_i) is T_delta up to the challenge
+	q := new(big.Int).Exp(&key.S.Int, &proof.Alpha.Int, n)
+
+	for _, k := range squareKeys {
+		if q, err = mulExp(n, q, &proof.T[k].Int, &proof.U[k].Int); err != nil {
+			return nil, err
+		}
+	}
+
+	productTau, err := mulExp(n, q, &proof.T[deltaKey].Int, minusC)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(taus, deltaTau, productTau), nil
+}
+
+// deltaPrime returns the value m_j is compared to, delta being m_j - value for GE and GT, value - m_j for LE and LT
+func deltaPrime(predicate *Predicate) (*big.Int, bool, error) {
+	value := big.NewInt(predicate.Value)
+
+	switch predicate.PType {
+	case proofPredicateGE:
+		return value, false, nil
+	case proofPredicateGT:
+		return value.Add(value, big.NewInt(1)), false, nil
+	case proofPredicateLE:
+		return value, true, nil
+	case proofPredicateLT:
+		return value.Sub(value, big.NewInt(1)), true, nil
+	}
+
+	return nil, false, fmt.Errorf("unsupported predicate type %s", predicate.PType)
+}
+
+// verifyDelta returns the tau value of m_j, Z^m_j being Z^value * T_delta (Z^value / T_delta for LE and LT) up to
+// S^r_delta
+func verifyDelta(key *PrimaryPublicKey, proof *GEProof, value *big.Int, less bool, minusC *big.Int) (*big.Int,
+	error) {
+	n := &key.N.Int
+	tDelta := &proof.T[deltaKey].Int
+	rDelta := &proof.R[deltaKey].Int
+
+	if less {
+		tDelta = new(big.Int).ModInverse(tDelta, n)
+		if tDelta == nil {
+			return nil, errors.New("invalid predicate proof")
+		}
+
+		rDelta = new(big.Int).Neg(rDelta)
+	}
+
+	delta := new(big.Int).Exp(&key.Z.Int, value, n)
+	delta.Mul(delta, tDelta).Mod(delta, n)
+
+	return multiExp(n, &key.Z.Int, &proof.MJ.Int, &key.S.Int, rDelta, delta, minusC)
+}
+
+// complete returns true if the key has all its values
+func (k *PrimaryPublicKey) complete() bool {
+	if k.N == nil || k.S == nil || k.Z == nil || k.Rctxt == nil || len(k.R) == 0 {
+		return false
+	}
+
+	for _, r := range k.R {
+		if r == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// complete returns true if the proof has all its values
+func (p *GEProof) complete() bool {
+	if p.Predicate == nil || p.MJ == nil || p.Alpha == nil || p.R[deltaKey] == nil || p.T[deltaKey] == nil {
+		return false
+	}
+
+	for _, k := range squareKeys {
+		if p.U[k] == nil || p.R[k] == nil || p.T[k] == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// multiExp returns the product of the bases raised to their exponents modulo n, given as base, exponent pairs
+func multiExp(n *big.Int, pairs ...*big.Int) (*big.Int, error) {
+	result := big.NewInt(1)
+
+	for i := 0; i+1 < len(pairs); i += 2 {
+		var err error
+
+		if result, err = mulExp(n, result, pairs[i], pairs[i+1]); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// mulExp returns value * base^exp modulo n, the negative exponents being those of the inverse of the base
+func mulExp(n, value, base, exp *big.Int) (*big.Int, error) {
+	power := new(big.Int).Exp(base, exp, n)
+	if power == nil {
+		return nil, errors.New("invalid proof: value not invertible")
+	}
+
+	return new(big.Int).Mod(new(big.Int).Mul(value, power), n), nil
+}
@@ -0,0 +1,623 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anoncreds
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// the DIDComm present-proof presentation messages, and the formats of their AnonCreds attachments
+const (
+	presentationV1Type = "https://didcomm.org/present-proof/1.0/presentation"
+	presentationV2Type = "https://didcomm.org/present-proof/2.0/presentation"
+	// the legacy prefix of the message types sent by the Aries agents
+	legacyTypePrefix = "did:sov:BzCbsNYhMrjHiqZDTUASHg;spec/"
+	didCommPrefix    = "https://didcomm.org/"
+
+	indyProofFormat      = "hlindy/proof@v2.0"
+	anonCredsProofFormat = "anoncreds/proof@v1.0"
+)
+
+// the predicate types of the proofs
+const (
+	proofPredicateGE = "GE"
+	proofPredicateGT = "GT"
+	proofPredicateLE = "LE"
+	proofPredicateLT = "LT"
+)
+
+const (
+	restrictionSchemaIssuerDID = "schema_issuer_did"
+	restrictionSchemaVersion   = "schema_version"
+
+	// the IDs of the schemas are <issuer DID>:2:<name>:<version>, of the credential definitions
+	// <issuer DID>:3:CL:<schema>:<tag>
+	schemaIDMarker  = ":2:"
+	credDefIDMarker = ":3:"
+	schemaIDParts   = 4
+)
+
+// BigNumber is an integer of the CL signatures and proofs, a decimal string in JSON
+type BigNumber struct {
+	big.Int
+}
+
+// UnmarshalJSON parses the decimal string of the number
+func (n *BigNumber) UnmarshalJSON(b []byte) error {
+	var s string
+
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("the number must be a decimal string: %w", err)
+	}
+
+	if _, ok := n.SetString(s, 10); !ok {
+		return fmt.Errorf("invalid number %s", s)
+	}
+
+	return nil
+}
+
+// MarshalJSON returns the decimal string of the number
+func (n *BigNumber) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.String())
+}
+
+// ByteArray is a byte array of the proofs, an array of numbers in JSON
+type ByteArray []byte
+
+// UnmarshalJSON parses the numbers of the bytes
+func (a *ByteArray) UnmarshalJSON(b []byte) error {
+	// unmarshalled as a byte slice, the array would be a base64 string
+	var numbers []uint16
+
+	if err := json.Unmarshal(b, &numbers); err != nil {
+		return fmt.Errorf("the byte array must be an array of numbers: %w", err)
+	}
+
+	values := make([]byte, 0, len(numbers))
+
+	for _, number := range numbers {
+		if number > 0xff {
+			return fmt.Errorf("invalid byte %d", number)
+		}
+
+		values = append(values, uint8(number))
+	}
+
+	*a = values
+
+	return nil
+}
+
+// MarshalJSON returns the numbers of the bytes
+func (a ByteArray) MarshalJSON() ([]byte, error) {
+	numbers := make([]uint16, len(a))
+
+	for i, b := range a {
+		numbers[i] = uint16(b)
+	}
+
+	return json.Marshal(numbers)
+}
+
+// CredentialDefinition is the CL credential definition of an AnonCreds issuer, its primary public key verifying the
+// proofs of its credentials
+type CredentialDefinition struct {
+	ID       string                     `json:"id"`
+	SchemaID string                     `json:"schemaId,omitempty"`
+	Type     string                     `json:"type"`
+	Tag      string                     `json:"tag,omitempty"`
+	Value    *CredentialDefinitionValue `json:"value"`
+}
+
+// CredentialDefinitionValue is the public keys of a credential definition, the revocation key being set for the
+// revocable credentials
+type CredentialDefinitionValue struct {
+	Primary    *PrimaryPublicKey `json:"primary"`
+	Revocation json.RawMessage   `json:"revocation,omitempty"`
+}
+
+// PrimaryPublicKey is the CL public key of a credential definition, with a base for each attribute of the credentials
+// (keyed by their canonical names) and for their master secret
+type PrimaryPublicKey struct {
+	N     *BigNumber            `json:"n"`
+	S     *BigNumber            `json:"s"`
+	R     map[string]*BigNumber `json:"r"`
+	Rctxt *BigNumber            `json:"rctxt"`
+	Z     *BigNumber            `json:"z"`
+}
+
+// ParseCredentialDefinition parses the credential definition, which must have a primary public key
+func ParseCredentialDefinition(raw json.RawMessage) (*CredentialDefinition, error) {
+	credDef := &CredentialDefinition{}
+
+	if err := json.Unmarshal(raw, credDef); err != nil {
+		return nil, fmt.Errorf("invalid credential definition: %w", err)
+	}
+
+	if credDef.ID == "" {
+		return nil, errors.New("invalid credential definition: missing id")
+	}
+
+	if credDef.Value == nil || credDef.Value.Primary == nil || !credDef.Value.Primary.complete() {
+		return nil, fmt.Errorf("invalid credential definition %s: missing primary public key", credDef.ID)
+	}
+
+	return credDef, nil
+}
+
+// Presentation is an AnonCreds presentation answering a proof request: the proof of the credentials, the attributes
+// revealed and the predicates proven for the referents of the request, and the identifiers of the credentials
+type Presentation struct {
+	Proof          *Proof          `json:"proof"`
+	RequestedProof *RequestedProof `json:"requested_proof"`
+	Identifiers    []*Identifier   `json:"identifiers"`
+}
+
+// Proof is the sub proofs of the credentials of a presentation, and their aggregated proof
+type Proof struct {
+	Proofs          []*SubProof      `json:"proofs"`
+	AggregatedProof *AggregatedProof `json:"aggregated_proof"`
+}
+
+// SubProof is the proof of a credential, its non-revocation proof being set for the revocable credentials
+type SubProof struct {
+	PrimaryProof  *PrimaryProof   `json:"primary_proof"`
+	NonRevocProof json.RawMessage `json:"non_revoc_proof,omitempty"`
+}
+
+// PrimaryProof is the proof of the CL signature of a credential, revealing some attributes, and the proofs of its
+// predicates
+type PrimaryProof struct {
+	EqProof  *EqProof   `json:"eq_proof"`
+	GEProofs []*GEProof `json:"ge_proofs"`
+}
+
+// EqProof proves the CL signature of the revealed attributes and of the unrevealed ones, by their canonical names
+type EqProof struct {
+	RevealedAttrs map[string]*BigNumber `json:"revealed_attrs"`
+	APrime        *BigNumber            `json:"a_prime"`
+	E             *BigNumber            `json:"e"`
+	V             *BigNumber            `json:"v"`
+	M             map[string]*BigNumber `json:"m"`
+	M2            *BigNumber            `json:"m2"`
+}
+
+// GEProof proves the predicate of an unrevealed attribute
+type GEProof struct {
+	U         map[string]*BigNumber `json:"u"`
+	R         map[string]*BigNumber `json:"r"`
+	MJ        *BigNumber            `json:"mj"`
+	Alpha     *BigNumber            `json:"alpha"`
+	T         map[string]*BigNumber `json:"t"`
+	Predicate *Predicate            `json:"predicate"`
+}
+
+// Predicate is the predicate of a proof: GE, GT, LE or LT
+type Predicate struct {
+	AttrName string `json:"attr_name"`
+	PType    string `json:"p_type"`
+	Value    int64  `json:"value"`
+}
+
+// AggregatedProof is the challenge hash of the sub proofs, and the commitments of their proofs
+type AggregatedProof struct {
+	CHash *BigNumber  `json:"c_hash"`
+	CList []ByteArray `json:"c_list"`
+}
+
+// RequestedProof maps the referents of the proof request to the sub proofs answering them
+type RequestedProof struct {
+	RevealedAttrs      map[string]*RevealedAttr      `json:"revealed_attrs"`
+	RevealedAttrGroups map[string]*RevealedAttrGroup `json:"revealed_attr_groups,omitempty"`
+	SelfAttestedAttrs  map[string]string             `json:"self_attested_attrs"`
+	UnrevealedAttrs    map[string]*SubProofReferent  `json:"unrevealed_attrs"`
+	Predicates         map[string]*SubProofReferent  `json:"predicates"`
+}
+
+// RevealedAttr is the value of a requested attribute revealed by a sub proof, raw and encoded
+type RevealedAttr struct {
+	SubProofIndex int    `json:"sub_proof_index"`
+	Raw           string `json:"raw"`
+	Encoded       string `json:"encoded"`
+}
+
+// RevealedAttrGroup is the values of a group of requested attributes revealed by a sub proof, by their names
+type RevealedAttrGroup struct {
+	SubProofIndex int                   `json:"sub_proof_index"`
+	Values        map[string]*AttrValue `json:"values"`
+}
+
+// AttrValue is the value of an attribute, raw and encoded
+type AttrValue struct {
+	Raw     string `json:"raw"`
+	Encoded string `json:"encoded"`
+}
+
+// SubProofReferent is the sub proof answering a referent of the proof request
+type SubProofReferent struct {
+	SubProofIndex int `json:"sub_proof_index"`
+}
+
+// Identifier identifies the schema and the credential definition of the credential of a sub proof
+type Identifier struct {
+	SchemaID  string  `json:"schema_id"`
+	CredDefID string  `json:"cred_def_id"`
+	RevRegID  *string `json:"rev_reg_id,omitempty"`
+	Timestamp *int64  `json:"timestamp,omitempty"`
+}
+
+// presentationMessage is a DIDComm present-proof presentation message, 1.0 or 2.0
+type presentationMessage struct {
+	Type    string `json:"@type"`
+	Formats []struct {
+		AttachID string `json:"attach_id"`
+		Format   string `json:"format"`
+	} `json:"formats,omitempty"`
+	Attachments []*attachment `json:"presentations~attach"`
+}
+
+type attachment struct {
+	ID   string `json:"@id"`
+	Data struct {
+		Base64 string          `json:"base64,omitempty"`
+		JSON   json.RawMessage `json:"json,omitempty"`
+	} `json:"data"`
+}
+
+// ParsePresentation parses the AnonCreds presentation, either as is or attached to the DIDComm present-proof
+// presentation message (1.0, or 2.0 with the hlindy/proof@v2.0 or anoncreds/proof@v1.0 format) received by the
+// agent of the verifier
+func ParsePresentation(raw json.RawMessage) (*Presentation, error) {
+	message := &presentationMessage{}
+
+	if err := json.Unmarshal(raw, message); err != nil {
+		return nil, fmt.Errorf("invalid presentation: %w", err)
+	}
+
+	if message.Type != "" {
+		var err error
+
+		if raw, err = message.presentation(); err != nil {
+			return nil, err
+		}
+	}
+
+	presentation := &Presentation{}
+
+	if err := json.Unmarshal(raw, presentation); err != nil {
+		return nil, fmt.Errorf("invalid presentation: %w", err)
+	}
+
+	if presentation.Proof == nil || presentation.Proof.AggregatedProof == nil || presentation.RequestedProof == nil {
+		return nil, errors.New("invalid presentation: missing proof or requested proof")
+	}
+
+	if len(presentation.Proof.Proofs) == 0 || len(presentation.Proof.Proofs) != len(presentation.Identifiers) {
+		return nil, errors.New("invalid presentation: the proofs don't match the identifiers of the credentials")
+	}
+
+	return presentation, nil
+}
+
+// presentation returns the AnonCreds presentation attached to the message
+func (m *presentationMessage) presentation() (json.RawMessage, error) {
+	attachmentID, err := m.attachmentID()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range m.Attachments {
+		if a.ID == attachmentID {
+			return a.data()
+		}
+	}
+
+	return nil, fmt.Errorf("invalid presentation message: missing attachment %s", attachmentID)
+}
+
+// attachmentID returns the ID of the attachment of the AnonCreds presentation: the attached presentation of a 1.0
+// message, the attachment of the AnonCreds format of a 2.0 message
+func (m *presentationMessage) attachmentID() (string, error) {
+	switch strings.Replace(m.Type, legacyTypePrefix, didCommPrefix, 1) {
+	case presentationV1Type:
+		if len(m.Attachments) == 0 {
+			return "", errors.New("invalid presentation message: no attached presentation")
+		}
+
+		return m.Attachments[0].ID, nil
+	case presentationV2Type:
+		for _, format := range m.Formats {
+			if format.Format == indyProofFormat || format.Format == anonCredsProofFormat {
+				return format.AttachID, nil
+			}
+		}
+
+		return "", errors.New("invalid presentation message: no attached AnonCreds presentation")
+	}
+
+	return "", fmt.Errorf("unsupported message type %s", m.Type)
+}
+
+// data returns the JSON data of the attachment, or its base64 data decoded
+func (a *attachment) data() (json.RawMessage, error) {
+	if len(a.Data.JSON) > 0 {
+		return a.Data.JSON, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(a.Data.Base64)
+	if err != nil {
+		data, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(a.Data.Base64, "="))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid presentation message: invalid attachment %s: %w", a.ID, err)
+	}
+
+	return data, nil
+}
+
+// Verify verifies the presentation answers the proof request with credentials of the credential definitions, keyed
+// by their IDs: the attributes requested are revealed, or self-attested if unrestricted, the predicates are proven,
+// the credentials match the restrictions, and the CL proof of the credentials is verified with the nonce of the
+// request. The non-revocation proofs of the revocable credentials aren't supported.
+func Verify(request *ProofRequest, presentation *Presentation, credDefs map[string]*CredentialDefinition) error {
+	keys, err := publicKeys(presentation, credDefs)
+	if err != nil {
+		return err
+	}
+
+	v := &verification{presentation: presentation, keys: keys}
+
+	for _, referent := range sortedKeys(request.RequestedAttributes) {
+		if err = v.checkAttribute(referent, request.RequestedAttributes[referent]); err != nil {
+			return fmt.Errorf("requested attribute %s: %w", referent, err)
+		}
+	}
+
+	for _, referent := range sortedKeys(request.RequestedPredicates) {
+		if err = v.checkPredicate(referent, request.RequestedPredicates[referent]); err != nil {
+			return fmt.Errorf("requested predicate %s: %w", referent, err)
+		}
+	}
+
+	nonce, ok := new(big.Int).SetString(request.Nonce, 10)
+	if !ok {
+		return fmt.Errorf("invalid nonce %s", request.Nonce)
+	}
+
+	return verifyProof(presentation.Proof, keys, nonce)
+}
+
+// publicKeys returns the public keys of the credentials of the presentation, which mustn't have non-revocation proofs
+func publicKeys(presentation *Presentation, credDefs map[string]*CredentialDefinition) ([]*PrimaryPublicKey, error) {
+	keys := make([]*PrimaryPublicKey, len(presentation.Identifiers))
+
+	for i, identifier := range presentation.Identifiers {
+		credDef, ok := credDefs[identifier.CredDefID]
+		if !ok || credDef.Value == nil || credDef.Value.Primary == nil {
+			return nil, fmt.Errorf("unknown credential definition %s", identifier.CredDefID)
+		}
+
+		nonRevocProof := presentation.Proof.Proofs[i].NonRevocProof
+		if len(nonRevocProof) > 0 && string(nonRevocProof) != "null" {
+			return nil, fmt.Errorf("credential %d: non-revocation proofs are not supported", i)
+		}
+
+		keys[i] = credDef.Value.Primary
+	}
+
+	return keys, nil
+}
+
+// verification checks the requested proof of a presentation against the proof request
+type verification struct {
+	presentation *Presentation
+	keys         []*PrimaryPublicKey
+}
+
+func (v *verification) checkAttribute(referent string, attribute *AttributeInfo) error {
+	requested := v.presentation.RequestedProof
+
+	if attribute.Name != "" {
+		if revealed, ok := requested.RevealedAttrs[referent]; ok {
+			return v.checkRevealed(revealed.SubProofIndex, attribute.Name,
+				&AttrValue{Raw: revealed.Raw, Encoded: revealed.Encoded}, attribute.Restrictions)
+		}
+
+		if _, ok := requested.SelfAttestedAttrs[referent]; ok && len(attribute.Restrictions) == 0 {
+			return nil
+		}
+
+		return errors.New("not revealed")
+	}
+
+	group, ok := requested.RevealedAttrGroups[referent]
+	if !ok {
+		return errors.New("not revealed")
+	}
+
+	for _, name := range attribute.Names {
+		value, ok := group.Values[name]
+		if !ok {
+			return fmt.Errorf("attribute %s not revealed", name)
+		}
+
+		if err := v.checkRevealed(group.SubProofIndex, name, value, attribute.Restrictions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkRevealed checks the revealed value is the raw value of the attribute, as signed in the credential of the
+// sub proof
+func (v *verification) checkRevealed(index int, name string, value *AttrValue,
+	restrictions []map[string]string) error {
+	eqProof, err := v.eqProof(index)
+	if err != nil {
+		return err
+	}
+
+	if err = v.checkRestrictions(index, restrictions); err != nil {
+		return err
+	}
+
+	encoded, ok := new(big.Int).SetString(value.Encoded, 10)
+	if !ok || encoded.Cmp(encode(value.Raw)) != 0 {
+		return fmt.Errorf("the encoded value of attribute %s isn't the encoding of its raw value", name)
+	}
+
+	proven, ok := eqProof.RevealedAttrs[canonicalName(name)]
+	if !ok || proven == nil || proven.Cmp(encoded) != 0 {
+		return fmt.Errorf("the value of attribute %s isn't revealed by the proof", name)
+	}
+
+	return nil
+}
+
+func (v *verification) checkPredicate(referent string, predicate *PredicateInfo) error {
+	proven, ok := v.presentation.RequestedProof.Predicates[referent]
+	if !ok {
+		return errors.New("not proven")
+	}
+
+	if _, err := v.eqProof(proven.SubProofIndex); err != nil {
+		return err
+	}
+
+	if err := v.checkRestrictions(proven.SubProofIndex, predicate.Restrictions); err != nil {
+		return err
+	}
+
+	pType, ok := map[string]string{predicateGreaterOrEqual: proofPredicateGE, predicateGreater: proofPredicateGT,
+		predicateLessOrEqual: proofPredicateLE, predicateLess: proofPredicateLT}[predicate.PType]
+	if !ok {
+		return fmt.Errorf("unsupported predicate type %s", predicate.PType)
+	}
+
+	for _, geProof := range v.presentation.Proof.Proofs[proven.SubProofIndex].PrimaryProof.GEProofs {
+		p := geProof.Predicate
+
+		if p != nil && p.AttrName == canonicalName(predicate.Name) && p.PType == pType && p.Value == predicate.PValue {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("the predicate %s %s %d isn't proven", predicate.Name, predicate.PType, predicate.PValue)
+}
+
+// checkRestrictions checks the credential of the sub proof matches one of the restrictions
+func (v *verification) checkRestrictions(index int, restrictions []map[string]string) error {
+	if len(restrictions) == 0 {
+		return nil
+	}
+
+	for _, restriction := range restrictions {
+		matches, err := v.matches(index, restriction)
+		if err != nil {
+			return err
+		}
+
+		if matches {
+			return nil
+		}
+	}
+
+	return errors.New("the credential doesn't match the restrictions")
+}
+
+// nolint: gocyclo
+func (v *verification) matches(index int, restriction map[string]string) (bool, error) {
+	identifier := v.presentation.Identifiers[index]
+	eqProof := v.presentation.Proof.Proofs[index].PrimaryProof.EqProof
+
+	schemaParts := strings.Split(identifier.SchemaID, ":")
+	if len(schemaParts) != schemaIDParts || !strings.Contains(identifier.SchemaID, schemaIDMarker) {
+		schemaParts = make([]string, schemaIDParts)
+	}
+
+	for _, key := range sortedKeys(restriction) {
+		value := restriction[key]
+
+		var matches bool
+
+		switch {
+		case key == restrictionSchemaID:
+			matches = identifier.SchemaID == value
+		case key == restrictionSchemaIssuerDID:
+			matches = sameDID(schemaParts[0], value)
+		case key == restrictionSchemaName:
+			matches = schemaParts[2] == value
+		case key == restrictionSchemaVersion:
+			matches = schemaParts[3] == value
+		case key == restrictionCredDefID:
+			matches = identifier.CredDefID == value
+		case key == restrictionIssuerDID:
+			matches = sameDID(strings.SplitN(identifier.CredDefID, credDefIDMarker, 2)[0], value)
+		case strings.HasPrefix(key, attrRestrictionPrefix) && strings.HasSuffix(key, attrValueSuffix):
+			name := strings.TrimSuffix(strings.TrimPrefix(key, attrRestrictionPrefix), attrValueSuffix)
+			revealed := eqProof.RevealedAttrs[canonicalName(name)]
+
+			// the value of the attribute must be revealed to be checked
+			matches = revealed != nil && revealed.Cmp(encode(value)) == 0
+		case strings.HasPrefix(key, attrRestrictionPrefix) && strings.HasSuffix(key, attrMarkerSuffix):
+			name := strings.TrimSuffix(strings.TrimPrefix(key, attrRestrictionPrefix), attrMarkerSuffix)
+			_, matches = v.keys[index].R[canonicalName(name)]
+		default:
+			return false, fmt.Errorf("unsupported restriction %s", key)
+		}
+
+		if !matches {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// eqProof returns the equality proof of the sub proof
+func (v *verification) eqProof(index int) (*EqProof, error) {
+	proofs := v.presentation.Proof.Proofs
+
+	if index < 0 || index >= len(proofs) || proofs[index].PrimaryProof == nil ||
+		proofs[index].PrimaryProof.EqProof == nil {
+		return nil, fmt.Errorf("invalid sub proof %d", index)
+	}
+
+	return proofs[index].PrimaryProof.EqProof, nil
+}
+
+// encode returns the AnonCreds encoding of the raw value of an attribute: the 32-bit integers are encoded as is, the
+// other values as the SHA-256 hash of their UTF-8 bytes
+func encode(raw string) *big.Int {
+	if i, err := strconv.ParseInt(raw, 10, 32); err == nil {
+		return big.NewInt(i)
+	}
+
+	hash := sha256.Sum256([]byte(raw))
+
+	return new(big.Int).SetBytes(hash[:])
+}
+
+// canonicalName returns the name of the attribute in the credentials and the proofs, lowercase without spaces
+func canonicalName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", ""))
+}
+
+// sameDID returns true if the DIDs are the same, the unqualified DIDs being Indy DIDs
+func sameDID(did, other string) bool {
+	return did != "" && strings.TrimPrefix(did, indyDIDPrefix) == strings.TrimPrefix(other, indyDIDPrefix)
+}
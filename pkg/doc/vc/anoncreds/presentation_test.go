@@ -0,0 +1,643 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package anoncreds
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuerDID = "Th7MpTaRZVRYnPiabds81Y"
+	testSchemaID  = testIssuerDID + ":2:Passport:1.0"
+	testCredDefID = testIssuerDID + ":3:CL:12:tag"
+	testNonce     = "1234567890"
+
+	// the bit sizes of the test keys, signatures and proofs, smaller than the AnonCreds ones for speed
+	testPrimeBits    = 256
+	testRandomBits   = 1024
+	testExponentBits = 2048
+)
+
+const verifiedProofRequest = `{
+	"nonce": "1234567890",
+	"requested_attributes": {
+		"attr1_referent": {
+			"name": "name",
+			"restrictions": [{"issuer_did": "did:sov:Th7MpTaRZVRYnPiabds81Y", "schema_name": "Passport"}]
+		},
+		"attr2_referent": {
+			"names": ["degree", "status"],
+			"restrictions": [{"cred_def_id": "Th7MpTaRZVRYnPiabds81Y:3:CL:12:tag", "attr::status::value": "graduated"}]
+		},
+		"attr3_referent": {"name": "phone"}
+	},
+	"requested_predicates": {
+		"predicate1_referent": {
+			"name": "age",
+			"p_type": ">=",
+			"p_value": 18,
+			"restrictions": [{"schema_id": "Th7MpTaRZVRYnPiabds81Y:2:Passport:1.0", "attr::age::marker": "1"}]
+		}
+	}
+}`
+
+func TestVerify(t *testing.T) {
+	issuer := newTestIssuer(t, "name", "degree", "status", "age")
+	credential := issuer.issue(t, map[string]string{"name": "Alice", "degree": "Bachelor of Science",
+		"status": "graduated", "age": "25"})
+
+	request, err := ParseProofRequest([]byte(verifiedProofRequest))
+	require.NoError(t, err)
+
+	credDefs := map[string]*CredentialDefinition{testCredDefID: issuer.credDef()}
+
+	newPresentation := func(nonce string) *Presentation {
+		return newTestPresentation(t, credential, nonce, []string{"name", "degree", "status"},
+			[]*Predicate{{AttrName: "age", PType: proofPredicateGE, Value: 18}})
+	}
+
+	t.Run("test verify", func(t *testing.T) {
+		require.NoError(t, Verify(request, newPresentation(testNonce), credDefs))
+	})
+
+	t.Run("test verify parsed presentation", func(t *testing.T) {
+		raw, err := json.Marshal(newPresentation(testNonce))
+		require.NoError(t, err)
+
+		presentation, err := ParsePresentation(raw)
+		require.NoError(t, err)
+
+		require.NoError(t, Verify(request, presentation, credDefs))
+	})
+
+	t.Run("test predicates", func(t *testing.T) {
+		for _, predicate := range []*PredicateInfo{
+			{Name: "age", PType: predicateGreater, PValue: 24},
+			{Name: "Age", PType: predicateLessOrEqual, PValue: 25},
+			{Name: "age", PType: predicateLess, PValue: 65},
+		} {
+			pType := map[string]string{predicateGreater: proofPredicateGT, predicateLessOrEqual: proofPredicateLE,
+				predicateLess: proofPredicateLT}[predicate.PType]
+
+			presentation := newTestPresentation(t, credential, testNonce, nil,
+				[]*Predicate{{AttrName: "age", PType: pType, Value: predicate.PValue}})
+
+			require.NoError(t, Verify(&ProofRequest{Nonce: testNonce,
+				RequestedPredicates: map[string]*PredicateInfo{"predicate1_referent": predicate}},
+				presentation, credDefs), predicate.PType)
+		}
+	})
+
+	t.Run("test invalid presentations", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			tamper func(p *Presentation)
+			err    string
+		}{{
+			name:   "other nonce",
+			tamper: func(p *Presentation) { *p = *newPresentation("987654321") },
+			err:    "invalid proof: the challenge doesn't match the proof",
+		}, {
+			name:   "tampered raw value",
+			tamper: func(p *Presentation) { p.RequestedProof.RevealedAttrs["attr1_referent"].Raw = "Bob" },
+			err: "requested attribute attr1_referent: the encoded value of attribute name isn't the encoding of " +
+				"its raw value",
+		}, {
+			name: "tampered revealed value",
+			tamper: func(p *Presentation) {
+				p.RequestedProof.RevealedAttrs["attr1_referent"].Raw = "Bob"
+				p.RequestedProof.RevealedAttrs["attr1_referent"].Encoded = encode("Bob").String()
+				p.Proof.Proofs[0].PrimaryProof.EqProof.RevealedAttrs["name"] = &BigNumber{*encode("Bob")}
+			},
+			err: "invalid proof: the challenge doesn't match the proof",
+		}, {
+			name: "value not revealed by the proof",
+			tamper: func(p *Presentation) {
+				p.RequestedProof.RevealedAttrs["attr1_referent"].Raw = "Bob"
+				p.RequestedProof.RevealedAttrs["attr1_referent"].Encoded = encode("Bob").String()
+			},
+			err: "requested attribute attr1_referent: the value of attribute name isn't revealed by the proof",
+		}, {
+			name:   "missing attribute",
+			tamper: func(p *Presentation) { delete(p.RequestedProof.RevealedAttrs, "attr1_referent") },
+			err:    "requested attribute attr1_referent: not revealed",
+		}, {
+			name: "self-attested restricted attribute",
+			tamper: func(p *Presentation) {
+				delete(p.RequestedProof.RevealedAttrs, "attr1_referent")
+				p.RequestedProof.SelfAttestedAttrs["attr1_referent"] = "Alice"
+			},
+			err: "requested attribute attr1_referent: not revealed",
+		}, {
+			name:   "missing attribute of a group",
+			tamper: func(p *Presentation) { delete(p.RequestedProof.RevealedAttrGroups["attr2_referent"].Values, "status") },
+			err:    "requested attribute attr2_referent: attribute status not revealed",
+		}, {
+			name:   "missing predicate",
+			tamper: func(p *Presentation) { delete(p.RequestedProof.Predicates, "predicate1_referent") },
+			err:    "requested predicate predicate1_referent: not proven",
+		}, {
+			name:   "other predicate",
+			tamper: func(p *Presentation) { p.Proof.Proofs[0].PrimaryProof.GEProofs[0].Predicate.Value = 17 },
+			err:    "requested predicate predicate1_referent: the predicate age >= 18 isn't proven",
+		}, {
+			name: "tampered predicate",
+			tamper: func(p *Presentation) {
+				*p = *newTestPresentation(t, credential, testNonce, []string{"name", "degree", "status"},
+					[]*Predicate{{AttrName: "age", PType: proofPredicateGE, Value: 10}})
+				p.Proof.Proofs[0].PrimaryProof.GEProofs[0].Predicate.Value = 18
+			},
+			err: "invalid proof: the challenge doesn't match the proof",
+		}, {
+			name: "predicate of another attribute",
+			tamper: func(p *Presentation) {
+				p.Proof.Proofs[0].PrimaryProof.GEProofs[0].MJ = p.Proof.Proofs[0].PrimaryProof.EqProof.M["master_secret"]
+			},
+			err: "invalid proof of credential 0: the predicate of attribute age isn't bound to the equality proof",
+		}, {
+			name:   "other schema",
+			tamper: func(p *Presentation) { p.Identifiers[0].SchemaID = testIssuerDID + ":2:Diploma:1.0" },
+			err:    "requested attribute attr1_referent: the credential doesn't match the restrictions",
+		}, {
+			name:   "unknown credential definition",
+			tamper: func(p *Presentation) { p.Identifiers[0].CredDefID = testIssuerDID + ":3:CL:13:tag" },
+			err:    "unknown credential definition Th7MpTaRZVRYnPiabds81Y:3:CL:13:tag",
+		}, {
+			name:   "non-revocation proof",
+			tamper: func(p *Presentation) { p.Proof.Proofs[0].NonRevocProof = json.RawMessage(`{"x_list":{}}`) },
+			err:    "credential 0: non-revocation proofs are not supported",
+		}, {
+			name:   "tampered commitments",
+			tamper: func(p *Presentation) { p.Proof.AggregatedProof.CList[0] = ByteArray{1} },
+			err:    "invalid proof: the commitments don't match the aggregated proof",
+		}, {
+			name:   "invalid sub proof index",
+			tamper: func(p *Presentation) { p.RequestedProof.Predicates["predicate1_referent"].SubProofIndex = 1 },
+			err:    "requested predicate predicate1_referent: invalid sub proof 1",
+		}, {
+			name:   "incomplete proof",
+			tamper: func(p *Presentation) { p.Proof.Proofs[0].PrimaryProof.EqProof.M2 = nil },
+			err:    "invalid proof of credential 0: incomplete equality proof",
+		}, {
+			name:   "missing proof of an attribute",
+			tamper: func(p *Presentation) { delete(p.Proof.Proofs[0].PrimaryProof.EqProof.M, "master_secret") },
+			err:    "invalid proof of credential 0: missing proof of attribute master_secret",
+		}, {
+			name:   "incomplete predicate proof",
+			tamper: func(p *Presentation) { delete(p.Proof.Proofs[0].PrimaryProof.GEProofs[0].T, deltaKey) },
+			err:    "invalid proof of credential 0: incomplete predicate proof",
+		}}
+
+		for _, tc := range tests {
+			presentation := newPresentation(testNonce)
+			tc.tamper(presentation)
+
+			require.EqualError(t, Verify(request, presentation, credDefs), tc.err, tc.name)
+		}
+	})
+
+	t.Run("test unsupported restriction", func(t *testing.T) {
+		err := Verify(&ProofRequest{Nonce: testNonce, RequestedAttributes: map[string]*AttributeInfo{
+			"attr1_referent": {Name: "name", Restrictions: []map[string]string{{"rev_reg_id": "1"}}},
+		}}, newPresentation(testNonce), credDefs)
+		require.EqualError(t, err, "requested attribute attr1_referent: unsupported restriction rev_reg_id")
+	})
+
+	t.Run("test invalid nonce", func(t *testing.T) {
+		require.EqualError(t, Verify(&ProofRequest{Nonce: "nonce"}, newPresentation(testNonce), credDefs),
+			"invalid nonce nonce")
+	})
+}
+
+func TestParsePresentation(t *testing.T) {
+	issuer := newTestIssuer(t, "name")
+	credential := issuer.issue(t, map[string]string{"name": "Alice"})
+
+	raw, err := json.Marshal(newTestPresentation(t, credential, testNonce, []string{"name"}, nil))
+	require.NoError(t, err)
+
+	encoded := base64.StdEncoding.EncodeToString(raw)
+
+	t.Run("test present-proof 1.0 message", func(t *testing.T) {
+		presentation, err := ParsePresentation([]byte(fmt.Sprintf(`{
+			"@type": "did:sov:BzCbsNYhMrjHiqZDTUASHg;spec/present-proof/1.0/presentation",
+			"@id": "f1ca8245-ab2d-4d9c-8d7d-94bf310314ef",
+			"presentations~attach": [{
+				"@id": "libindy-presentation-0",
+				"mime-type": "application/json",
+				"data": {"base64": "%s"}
+			}]
+		}`, encoded)))
+		require.NoError(t, err)
+		require.Equal(t, "Alice", presentation.RequestedProof.RevealedAttrs["attr1_referent"].Raw)
+	})
+
+	t.Run("test present-proof 2.0 message", func(t *testing.T) {
+		presentation, err := ParsePresentation([]byte(fmt.Sprintf(`{
+			"@type": "https://didcomm.org/present-proof/2.0/presentation",
+			"@id": "f1ca8245-ab2d-4d9c-8d7d-94bf310314ef",
+			"formats": [
+				{"attach_id": "dif", "format": "dif/presentation-exchange/submission@v1.0"},
+				{"attach_id": "indy", "format": "hlindy/proof@v2.0"}
+			],
+			"presentations~attach": [
+				{"@id": "dif", "data": {"json": {}}},
+				{"@id": "indy", "data": {"json": %s}}
+			]
+		}`, raw)))
+		require.NoError(t, err)
+		require.Equal(t, "Alice", presentation.RequestedProof.RevealedAttrs["attr1_referent"].Raw)
+	})
+
+	t.Run("test invalid presentations", func(t *testing.T) {
+		tests := []struct {
+			presentation string
+			err          string
+		}{{
+			presentation: `[]`,
+			err:          "invalid presentation",
+		}, {
+			presentation: `{"proof": {}}`,
+			err:          "invalid presentation: missing proof or requested proof",
+		}, {
+			presentation: `{"proof": {"aggregated_proof": {}, "proofs": [{}]}, "requested_proof": {}}`,
+			err:          "invalid presentation: the proofs don't match the identifiers of the credentials",
+		}, {
+			presentation: `{"proof": {"aggregated_proof": {"c_hash": 1}}}`,
+			err:          "the number must be a decimal string",
+		}, {
+			presentation: `{"proof": {"aggregated_proof": {"c_hash": "a"}}}`,
+			err:          "invalid number a",
+		}, {
+			presentation: `{"proof": {"aggregated_proof": {"c_list": [[256]]}}}`,
+			err:          "invalid byte 256",
+		}, {
+			presentation: `{"proof": {"aggregated_proof": {"c_list": ["AQ=="]}}}`,
+			err:          "the byte array must be an array of numbers",
+		}, {
+			presentation: `{"@type": "https://didcomm.org/present-proof/3.0/presentation"}`,
+			err:          "unsupported message type https://didcomm.org/present-proof/3.0/presentation",
+		}, {
+			presentation: `{"@type": "https://didcomm.org/present-proof/1.0/presentation"}`,
+			err:          "invalid presentation message: no attached presentation",
+		}, {
+			presentation: `{"@type": "https://didcomm.org/present-proof/2.0/presentation",
+				"formats": [{"attach_id": "dif", "format": "dif/presentation-exchange/submission@v1.0"}]}`,
+			err: "invalid presentation message: no attached AnonCreds presentation",
+		}, {
+			presentation: `{"@type": "https://didcomm.org/present-proof/2.0/presentation",
+				"formats": [{"attach_id": "indy", "format": "anoncreds/proof@v1.0"}]}`,
+			err: "invalid presentation message: missing attachment indy",
+		}, {
+			presentation: `{"@type": "https://didcomm.org/present-proof/1.0/presentation",
+				"presentations~attach": [{"@id": "libindy-presentation-0", "data": {"base64": "!"}}]}`,
+			err: "invalid presentation message: invalid attachment libindy-presentation-0",
+		}}
+
+		for _, tc := range tests {
+			_, err := ParsePresentation([]byte(tc.presentation))
+			require.Error(t, err, tc.presentation)
+			require.Contains(t, err.Error(), tc.err, tc.presentation)
+		}
+	})
+}
+
+func TestParseCredentialDefinition(t *testing.T) {
+	raw, err := json.Marshal(newTestIssuer(t, "name").credDef())
+	require.NoError(t, err)
+
+	credDef, err := ParseCredentialDefinition(raw)
+	require.NoError(t, err)
+	require.Equal(t, testCredDefID, credDef.ID)
+	require.Contains(t, credDef.Value.Primary.R, "master_secret")
+
+	for _, tc := range []struct {
+		credDef string
+		err     string
+	}{
+		{credDef: `[]`, err: "invalid credential definition: json"},
+		{credDef: `{"value":{}}`, err: "invalid credential definition: missing id"},
+		{credDef: `{"id":"1","value":{}}`, err: "invalid credential definition 1: missing primary public key"},
+		{credDef: `{"id":"1","value":{"primary":{"n":"1","s":"1","z":"1","rctxt":"1"}}}`,
+			err: "invalid credential definition 1: missing primary public key"},
+	} {
+		_, err := ParseCredentialDefinition([]byte(tc.credDef))
+		require.Error(t, err, tc.credDef)
+		require.Contains(t, err.Error(), tc.err, tc.credDef)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	require.Equal(t, "25", encode("25").String())
+	require.Equal(t, "-3", encode("-3").String())
+
+	hash := sha256.Sum256([]byte("4294967296"))
+	require.Equal(t, new(big.Int).SetBytes(hash[:]), encode("4294967296"))
+}
+
+// testIssuer issues CL credentials, with a key of small safe primes
+type testIssuer struct {
+	key *PrimaryPublicKey
+	// the order of the quadratic residues of the modulus
+	order *big.Int
+}
+
+type testCredential struct {
+	issuer       *testIssuer
+	raw          map[string]string
+	m            map[string]*big.Int
+	a, e, v, m2  *big.Int
+	masterSecret *big.Int
+}
+
+func newTestIssuer(t *testing.T, attributes ...string) *testIssuer {
+	p, pPrime := safePrime(t)
+	q, qPrime := safePrime(t)
+
+	n := new(big.Int).Mul(p, q)
+	order := new(big.Int).Mul(pPrime, qPrime)
+
+	s := new(big.Int).Exp(random(t, n.BitLen()), big.NewInt(2), n)
+	power := func() *BigNumber {
+		return &BigNumber{*new(big.Int).Exp(s, new(big.Int).Mod(random(t, testRandomBits), order), n)}
+	}
+
+	key := &PrimaryPublicKey{N: &BigNumber{*n}, S: &BigNumber{*s}, R: map[string]*BigNumber{}, Rctxt: power(),
+		Z: power()}
+
+	for _, attribute := range append(attributes, "master_secret") {
+		key.R[attribute] = power()
+	}
+
+	return &testIssuer{key: key, order: order}
+}
+
+func (i *testIssuer) credDef() *CredentialDefinition {
+	return &CredentialDefinition{ID: testCredDefID, SchemaID: "12", Type: "CL", Tag: "tag",
+		Value: &CredentialDefinitionValue{Primary: i.key}}
+}
+
+// issue signs the attributes: A = (Z / (S^v * Rctxt^m2 * Prod(R_i^m_i)))^(1/e)
+func (i *testIssuer) issue(t *testing.T, raw map[string]string) *testCredential {
+	n := &i.key.N.Int
+
+	credential := &testCredential{issuer: i, raw: raw, m: map[string]*big.Int{}, v: random(t, testExponentBits),
+		m2: random(t, testRandomBits), masterSecret: random(t, testRandomBits)}
+
+	for name, value := range raw {
+		credential.m[name] = encode(value)
+	}
+
+	credential.m["master_secret"] = credential.masterSecret
+
+	for {
+		e := new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), largeE), random(t, 119))
+		if e.ProbablyPrime(20) {
+			credential.e = e
+
+			break
+		}
+	}
+
+	signed := new(big.Int).Exp(&i.key.S.Int, credential.v, n)
+	signed.Mul(signed, new(big.Int).Exp(&i.key.Rctxt.Int, credential.m2, n))
+
+	for name, m := range credential.m {
+		signed.Mul(signed, new(big.Int).Exp(&i.key.R[name].Int, m, n)).Mod(signed, n)
+	}
+
+	base := new(big.Int).Mul(&i.key.Z.Int, new(big.Int).ModInverse(signed, n))
+	credential.a = new(big.Int).Exp(base, new(big.Int).ModInverse(credential.e, i.order), n)
+
+	return credential
+}
+
+// newTestPresentation proves the credential with the attributes revealed and the predicates proven, answering the
+// referents of the verified proof request
+func newTestPresentation(t *testing.T, credential *testCredential, nonce string, revealed []string,
+	predicates []*Predicate) *Presentation {
+	key := credential.issuer.key
+	n := &key.N.Int
+	s := &key.S.Int
+	z := &key.Z.Int
+
+	// the equality proof of the randomized signature A' = A * S^r
+	r := random(t, testRandomBits)
+	aPrime := new(big.Int).Mod(new(big.Int).Mul(credential.a, new(big.Int).Exp(s, r, n)), n)
+	ePrime := new(big.Int).Sub(credential.e, new(big.Int).Lsh(big.NewInt(1), largeE))
+	vPrime := new(big.Int).Sub(credential.v, new(big.Int).Mul(credential.e, r))
+
+	eTilde, vTilde, m2Tilde := random(t, testRandomBits), random(t, testExponentBits), random(t, testRandomBits)
+	mTilde := map[string]*big.Int{}
+
+	eqProof := &EqProof{RevealedAttrs: map[string]*BigNumber{}, M: map[string]*BigNumber{}}
+
+	tau := mustMultiExp(t, n, aPrime, eTilde, s, vTilde, &key.Rctxt.Int, m2Tilde)
+
+	for name, m := range credential.m {
+		if contains(revealed, name) {
+			eqProof.RevealedAttrs[name] = &BigNumber{*m}
+
+			continue
+		}
+
+		mTilde[name] = random(t, testRandomBits)
+		tau = mustMultiExp(t, n, tau, big.NewInt(1), &key.R[name].Int, mTilde[name])
+	}
+
+	taus := []*big.Int{tau}
+	commitments := []*big.Int{aPrime}
+
+	// the predicate proofs, of the four squares of delta
+	type predicateProof struct {
+		predicate                              *Predicate
+		u, r, uTilde, rTilde                   []*big.Int
+		t                                      []*big.Int
+		alpha, alphaTilde, rDelta, rDeltaTilde *big.Int
+	}
+
+	var predicateProofs []*predicateProof
+
+	for _, predicate := range predicates {
+		value := big.NewInt(predicate.Value)
+		mj := credential.m[predicate.AttrName]
+		less := predicate.PType == proofPredicateLE || predicate.PType == proofPredicateLT
+
+		switch predicate.PType {
+		case proofPredicateGT:
+			value.Add(value, big.NewInt(1))
+		case proofPredicateLT:
+			value.Sub(value, big.NewInt(1))
+		}
+
+		delta := new(big.Int).Sub(mj, value)
+		if less {
+			delta.Neg(delta)
+		}
+
+		pp := &predicateProof{predicate: predicate, u: fourSquares(t, delta.Int64()), rDelta: random(t, testRandomBits),
+			rDeltaTilde: random(t, testRandomBits), alphaTilde: random(t, testExponentBits)}
+		pp.alpha = new(big.Int).Set(pp.rDelta)
+
+		q := new(big.Int).Exp(s, pp.alphaTilde, n)
+
+		for i := range squareKeys {
+			pp.r = append(pp.r, random(t, testRandomBits))
+			pp.uTilde = append(pp.uTilde, random(t, testRandomBits))
+			pp.rTilde = append(pp.rTilde, random(t, testRandomBits))
+			pp.t = append(pp.t, mustMultiExp(t, n, z, pp.u[i], s, pp.r[i]))
+			pp.alpha.Sub(pp.alpha, new(big.Int).Mul(pp.u[i], pp.r[i]))
+
+			taus = append(taus, mustMultiExp(t, n, z, pp.uTilde[i], s, pp.rTilde[i]))
+			q = mustMultiExp(t, n, q, big.NewInt(1), pp.t[i], pp.uTilde[i])
+		}
+
+		pp.t = append(pp.t, mustMultiExp(t, n, z, delta, s, pp.rDelta))
+
+		rDeltaTilde := pp.rDeltaTilde
+		if less {
+			rDeltaTilde = new(big.Int).Neg(rDeltaTilde)
+		}
+
+		taus = append(taus, mustMultiExp(t, n, z, mTilde[predicate.AttrName], s, rDeltaTilde), q)
+		commitments = append(commitments, pp.t...)
+		predicateProofs = append(predicateProofs, pp)
+	}
+
+	cList := make([]ByteArray, len(commitments))
+	for i, commitment := range commitments {
+		cList[i] = commitment.Bytes()
+	}
+
+	nonceInt, ok := new(big.Int).SetString(nonce, 10)
+	require.True(t, ok)
+
+	c := challenge(taus, cList, nonceInt)
+
+	response := func(tilde, secret *big.Int) *BigNumber {
+		return &BigNumber{*new(big.Int).Add(tilde, new(big.Int).Mul(c, secret))}
+	}
+
+	eqProof.APrime = &BigNumber{*aPrime}
+	eqProof.E = response(eTilde, ePrime)
+	eqProof.V = response(vTilde, vPrime)
+	eqProof.M2 = response(m2Tilde, credential.m2)
+
+	for name, tilde := range mTilde {
+		eqProof.M[name] = response(tilde, credential.m[name])
+	}
+
+	primaryProof := &PrimaryProof{EqProof: eqProof}
+
+	for _, pp := range predicateProofs {
+		geProof := &GEProof{U: map[string]*BigNumber{}, R: map[string]*BigNumber{}, T: map[string]*BigNumber{},
+			MJ: eqProof.M[pp.predicate.AttrName], Alpha: response(pp.alphaTilde, pp.alpha), Predicate: pp.predicate}
+
+		for i, k := range squareKeys {
+			geProof.U[k] = response(pp.uTilde[i], pp.u[i])
+			geProof.R[k] = response(pp.rTilde[i], pp.r[i])
+			geProof.T[k] = &BigNumber{*pp.t[i]}
+		}
+
+		geProof.R[deltaKey] = response(pp.rDeltaTilde, pp.rDelta)
+		geProof.T[deltaKey] = &BigNumber{*pp.t[len(squareKeys)]}
+
+		primaryProof.GEProofs = append(primaryProof.GEProofs, geProof)
+	}
+
+	requestedProof := &RequestedProof{RevealedAttrs: map[string]*RevealedAttr{},
+		RevealedAttrGroups: map[string]*RevealedAttrGroup{}, SelfAttestedAttrs: map[string]string{"attr3_referent": "555"},
+		UnrevealedAttrs: map[string]*SubProofReferent{}, Predicates: map[string]*SubProofReferent{}}
+
+	if contains(revealed, "name") {
+		requestedProof.RevealedAttrs["attr1_referent"] = &RevealedAttr{Raw: credential.raw["name"],
+			Encoded: credential.m["name"].String()}
+	}
+
+	if contains(revealed, "degree") {
+		requestedProof.RevealedAttrGroups["attr2_referent"] = &RevealedAttrGroup{Values: map[string]*AttrValue{
+			"degree": {Raw: credential.raw["degree"], Encoded: credential.m["degree"].String()},
+			"status": {Raw: credential.raw["status"], Encoded: credential.m["status"].String()},
+		}}
+	}
+
+	if len(predicates) > 0 {
+		requestedProof.Predicates["predicate1_referent"] = &SubProofReferent{}
+	}
+
+	return &Presentation{
+		Proof: &Proof{Proofs: []*SubProof{{PrimaryProof: primaryProof}},
+			AggregatedProof: &AggregatedProof{CHash: &BigNumber{*c}, CList: cList}},
+		RequestedProof: requestedProof,
+		Identifiers:    []*Identifier{{SchemaID: testSchemaID, CredDefID: testCredDefID}},
+	}
+}
+
+func safePrime(t *testing.T) (*big.Int, *big.Int) {
+	for {
+		pPrime, err := rand.Prime(rand.Reader, testPrimeBits-1)
+		require.NoError(t, err)
+
+		p := new(big.Int).Add(new(big.Int).Lsh(pPrime, 1), big.NewInt(1))
+		if p.ProbablyPrime(20) {
+			return p, pPrime
+		}
+	}
+}
+
+func random(t *testing.T, bits int) *big.Int {
+	n, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+	require.NoError(t, err)
+
+	return n
+}
+
+// fourSquares decomposes the small non-negative integer as a sum of four squares
+func fourSquares(t *testing.T, delta int64) []*big.Int {
+	for a := int64(0); a*a <= delta; a++ {
+		for b := int64(0); a*a+b*b <= delta; b++ {
+			for c := int64(0); a*a+b*b+c*c <= delta; c++ {
+				d := delta - a*a - b*b - c*c
+
+				for root := int64(0); root*root <= d; root++ {
+					if root*root == d {
+						return []*big.Int{big.NewInt(a), big.NewInt(b), big.NewInt(c), big.NewInt(root)}
+					}
+				}
+			}
+		}
+	}
+
+	require.Fail(t, "negative delta", delta)
+
+	return nil
+}
+
+func mustMultiExp(t *testing.T, n *big.Int, pairs ...*big.Int) *big.Int {
+	result, err := multiExp(n, pairs...)
+	require.NoError(t, err)
+
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
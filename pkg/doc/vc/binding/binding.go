@@ -0,0 +1,88 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package binding
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+)
+
+const (
+	holderBindingStore = "holderbinding"
+
+	evidenceKeyPrefix = "evidence_"
+)
+
+// Evidence is the proof of possession of the subject DID of an issued credential
+type Evidence struct {
+	Subject            string          `json:"subject"`
+	VerificationMethod string          `json:"verificationMethod"`
+	Challenge          string          `json:"challenge"`
+	Domain             string          `json:"domain,omitempty"`
+	Presentation       json.RawMessage `json:"presentation"`
+	Time               time.Time       `json:"time"`
+}
+
+// Store keeps the binding evidence of the credentials issued under the profiles. The challenges of the holder
+// bindings are kept by the challenge store.
+type Store struct {
+	store storage.Store
+}
+
+// New returns a new holder binding store
+func New(provider storage.Provider) (*Store, error) {
+	err := provider.CreateStore(holderBindingStore)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := provider.OpenStore(holderBindingStore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{store: store}, nil
+}
+
+// Record records the binding evidence of the credential issued under the profile
+func (s *Store) Record(profile, vcID string, evidence *Evidence) error {
+	evidenceBytes, err := json.Marshal(evidence)
+	if err != nil {
+		return fmt.Errorf("failed to marshal binding evidence: %w", err)
+	}
+
+	if err := s.store.Put(evidenceKey(profile, vcID), evidenceBytes); err != nil {
+		return fmt.Errorf("failed to store binding evidence: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the binding evidence of the credential issued under the profile. The error wraps
+// storage.ErrValueNotFound if the credential was issued without holder binding.
+func (s *Store) Get(profile, vcID string) (*Evidence, error) {
+	evidenceBytes, err := s.store.Get(evidenceKey(profile, vcID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get binding evidence: %w", err)
+	}
+
+	evidence := &Evidence{}
+
+	if err := json.Unmarshal(evidenceBytes, evidence); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal binding evidence: %w", err)
+	}
+
+	return evidence, nil
+}
+
+func evidenceKey(profile, vcID string) string {
+	return evidenceKeyPrefix + profile + "_" + vcID
+}
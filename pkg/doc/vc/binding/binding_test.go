@@ -0,0 +1,75 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		store, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+		require.NotNil(t, store)
+	})
+
+	t.Run("test error from create store", func(t *testing.T) {
+		store, err := New(&mockstore.Provider{ErrCreateStore: fmt.Errorf("error create")})
+		require.EqualError(t, err, "error create")
+		require.Nil(t, store)
+	})
+
+	t.Run("test error from open store", func(t *testing.T) {
+		store, err := New(&mockstore.Provider{ErrOpenStoreHandle: fmt.Errorf("error open")})
+		require.EqualError(t, err, "error open")
+		require.Nil(t, store)
+	})
+}
+
+func TestStore_Evidence(t *testing.T) {
+	store, err := New(memstore.NewProvider())
+	require.NoError(t, err)
+
+	_, err = store.Get("issuer", "http://example.edu/credentials/1872")
+	require.True(t, errors.Is(err, storage.ErrValueNotFound))
+
+	evidence := &Evidence{
+		Subject:            "did:example:holder",
+		VerificationMethod: "did:example:holder#key-1",
+		Challenge:          "challenge",
+		Presentation:       []byte(`{"type":"VerifiablePresentation"}`),
+		Time:               time.Date(2020, time.October, 16, 10, 0, 0, 0, time.UTC),
+	}
+
+	require.NoError(t, store.Record("issuer", "http://example.edu/credentials/1872", evidence))
+
+	recorded, err := store.Get("issuer", "http://example.edu/credentials/1872")
+	require.NoError(t, err)
+	require.Equal(t, evidence, recorded)
+
+	_, err = store.Get("other", "http://example.edu/credentials/1872")
+	require.True(t, errors.Is(err, storage.ErrValueNotFound))
+
+	t.Run("test store errors", func(t *testing.T) {
+		store.store = &mockstore.MockStore{Store: map[string][]byte{}, ErrPut: fmt.Errorf("error put")}
+		require.EqualError(t, store.Record("issuer", "vc", evidence), "failed to store binding evidence: error put")
+
+		store.store = &mockstore.MockStore{Store: map[string][]byte{evidenceKey("issuer", "vc"): []byte("{")}}
+
+		_, err := store.Get("issuer", "vc")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal binding evidence")
+	})
+}
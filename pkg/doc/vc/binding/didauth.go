@@ -0,0 +1,68 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package binding
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/internal/common/diddoc"
+)
+
+// DIDAuth is the proof of control of a DID by the signer of a DIDAuth presentation
+type DIDAuth struct {
+	DID                string `json:"did"`
+	VerificationMethod string `json:"verificationMethod"`
+	Challenge          string `json:"challenge"`
+	Domain             string `json:"domain,omitempty"`
+}
+
+// VerifyDIDAuth verifies the DIDAuth presentation has a single proof, signed with an authentication method of the
+// DID of its verification method for the authentication proof purpose. The challenge of the proof is returned to be
+// used by the caller, and its domain to be checked if needed.
+func VerifyDIDAuth(vpBytes []byte, vdri vdriapi.Registry, opts ...verifiable.PresentationOpt) (*DIDAuth, error) {
+	opts = append([]verifiable.PresentationOpt{
+		verifiable.WithPresPublicKeyFetcher(verifiable.NewDIDKeyResolver(vdri).PublicKeyFetcher()),
+		verifiable.WithPresEmbeddedSignatureSuites(crypto.VerifierSuites()...),
+	}, opts...)
+
+	vp, err := verifiable.ParsePresentation(vpBytes, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify presentation: %w", err)
+	}
+
+	if len(vp.Proofs) != 1 {
+		return nil, errors.New("the presentation must have a single proof")
+	}
+
+	proof := vp.Proofs[0]
+	auth := &DIDAuth{}
+	auth.VerificationMethod, _ = proof["verificationMethod"].(string)
+	auth.Challenge, _ = proof["challenge"].(string)
+	auth.Domain, _ = proof["domain"].(string)
+
+	auth.DID, err = diddoc.GetDIDFromVerificationMethod(auth.VerificationMethod)
+	if err != nil {
+		return nil, fmt.Errorf("invalid verification method of the presentation: %w", err)
+	}
+
+	didDoc, err := vdri.Resolve(auth.DID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve DID %s: %w", auth.DID, err)
+	}
+
+	if err = crypto.ValidateProofPurpose(crypto.Authentication, auth.VerificationMethod, didDoc); err != nil ||
+		proof["proofPurpose"] != crypto.Authentication {
+		return nil, fmt.Errorf("the presentation isn't signed by an authentication method of %s", auth.DID)
+	}
+
+	return auth, nil
+}
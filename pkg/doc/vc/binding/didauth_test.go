@@ -0,0 +1,100 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package binding
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+)
+
+func TestVerifyDIDAuth(t *testing.T) {
+	const didID = "did:example:holder"
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signingKey := did.PublicKey{ID: didID + "#key-1", Type: "Ed25519VerificationKey2018", Controller: didID,
+		Value: pubKey}
+
+	vdri := &vdrimock.MockVDRIRegistry{
+		ResolveFunc: func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+			return &did.Doc{Context: []string{"https://w3id.org/did/v1"}, ID: didID,
+				PublicKey:       []did.PublicKey{signingKey},
+				Authentication:  []did.VerificationMethod{{PublicKey: signingKey}},
+				AssertionMethod: []did.VerificationMethod{{PublicKey: signingKey}},
+			}, nil
+		}}
+
+	signedPresentation := func(t *testing.T, purpose string) []byte {
+		vp := &verifiable.Presentation{Context: []string{"https://www.w3.org/2018/credentials/v1"},
+			Type: []string{"VerifiablePresentation"}, Holder: didID}
+
+		err := vp.AddLinkedDataProof(&verifiable.LinkedDataProofContext{
+			SignatureType: crypto.Ed25519Signature2018,
+			Suite: ed25519signature2018.New(
+				suite.WithSigner(signature.GetEd25519Signer(privKey, pubKey))),
+			SignatureRepresentation: verifiable.SignatureJWS,
+			VerificationMethod:      didID + "#key-1",
+			Purpose:                 purpose,
+			Challenge:               "challenge",
+			Domain:                  "example.com",
+		})
+		require.NoError(t, err)
+
+		vpBytes, err := vp.MarshalJSON()
+		require.NoError(t, err)
+
+		return vpBytes
+	}
+
+	t.Run("test success", func(t *testing.T) {
+		auth, err := VerifyDIDAuth(signedPresentation(t, crypto.Authentication), vdri)
+		require.NoError(t, err)
+		require.Equal(t, &DIDAuth{DID: didID, VerificationMethod: didID + "#key-1", Challenge: "challenge",
+			Domain: "example.com"}, auth)
+	})
+
+	t.Run("test invalid proof purpose", func(t *testing.T) {
+		_, err := VerifyDIDAuth(signedPresentation(t, crypto.AssertionMethod), vdri)
+		require.EqualError(t, err, "the presentation isn't signed by an authentication method of did:example:holder")
+	})
+
+	t.Run("test DID resolution error", func(t *testing.T) {
+		vpBytes := signedPresentation(t, crypto.Authentication)
+
+		_, err := VerifyDIDAuth(vpBytes, &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+				return nil, errors.New("resolve error")
+			}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "resolve error")
+	})
+
+	t.Run("test invalid presentation", func(t *testing.T) {
+		_, err := VerifyDIDAuth([]byte(`{`), vdri)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to verify presentation")
+
+		_, err = VerifyDIDAuth([]byte(`{"@context":["https://www.w3.org/2018/credentials/v1"],`+
+			`"type":"VerifiablePresentation"}`), vdri)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "embedded proof is missing")
+	})
+}
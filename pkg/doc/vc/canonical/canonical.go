@@ -0,0 +1,226 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package canonical diagnoses the canonicalization of JSON-LD credentials, the URDNA2015 normalization the linked
+// data proofs are signed over. The terms a credential's contexts don't define are silently dropped from the
+// normalized form, so a verifier that resolves the contexts differently fails to verify the signature. It also
+// compares credentials, to tell tampered credentials from credentials differing only in their proofs.
+package canonical
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/piprate/json-gold/ld"
+)
+
+const (
+	algorithm = "URDNA2015"
+	format    = "application/n-quads"
+
+	contextKey = "@context"
+	// the proof isn't part of the normalized credential the proof is signed over
+	proofKey = "proof"
+)
+
+// DroppedTerm is a term of the credential which isn't in its normalized form
+type DroppedTerm struct {
+	// JSON pointer of the term in the credential, e.g. /credentialSubject/degree/name
+	Path string `json:"path"`
+	Term string `json:"term"`
+}
+
+// Diagnosis is the canonicalization of a credential
+type Diagnosis struct {
+	// the N-Quads of the URDNA2015 normalized credential, without its proof
+	Document string `json:"canonicalDocument"`
+	// the terms not defined by the contexts of the credential
+	DroppedTerms []*DroppedTerm `json:"droppedTerms,omitempty"`
+	// the statements which aren't valid RDF, e.g. of relative IRIs, rejected by the signers and removed by some
+	// verifiers
+	InvalidStatements []string `json:"invalidStatements,omitempty"`
+}
+
+// Diagnose normalizes the credential as its linked data proofs are signed over, loading the contexts with the loader
+// (the default loader if nil), and flags the terms dropped by the normalization.
+func Diagnose(credential []byte, loader ld.DocumentLoader) (*Diagnosis, error) {
+	// the JSON-LD processor may modify the documents it processes, so each step gets its own copy
+	document, err := normalize(credential, loader)
+	if err != nil {
+		return nil, err
+	}
+
+	proc := ld.NewJsonLdProcessor()
+	opts := ldOptions(loader)
+
+	original, err := toMap(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(original, proofKey)
+
+	input, err := toMap(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(input, proofKey)
+
+	// the terms not defined by the contexts don't survive the expansion, so they're missing once compacted back
+	compacted, err := proc.Compact(input, map[string]interface{}{contextKey: original[contextKey]}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compact credential: %w", err)
+	}
+
+	return &Diagnosis{
+		Document:          document,
+		DroppedTerms:      droppedTerms("", original, compacted),
+		InvalidStatements: invalidStatements(document),
+	}, nil
+}
+
+// normalize returns the N-Quads of the URDNA2015 normalized credential, without its proof
+func normalize(credential []byte, loader ld.DocumentLoader) (string, error) {
+	doc, err := toMap(credential)
+	if err != nil {
+		return "", err
+	}
+
+	delete(doc, proofKey)
+
+	view, err := ld.NewJsonLdProcessor().Normalize(doc, ldOptions(loader))
+	if err != nil {
+		return "", fmt.Errorf("failed to normalize credential: %w", err)
+	}
+
+	document, ok := view.(string)
+	if !ok {
+		return "", fmt.Errorf("failed to normalize credential: invalid view")
+	}
+
+	return document, nil
+}
+
+func ldOptions(loader ld.DocumentLoader) *ld.JsonLdOptions {
+	opts := ld.NewJsonLdOptions("")
+	opts.ProcessingMode = ld.JsonLd_1_1
+	opts.Algorithm = algorithm
+	opts.Format = format
+	opts.ProduceGeneralizedRdf = true
+
+	if loader != nil {
+		opts.DocumentLoader = loader
+	}
+
+	return opts
+}
+
+func toMap(credential []byte) (map[string]interface{}, error) {
+	doc := make(map[string]interface{})
+
+	if err := json.Unmarshal(credential, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal credential: %w", err)
+	}
+
+	return doc, nil
+}
+
+// droppedTerms returns the terms of the original value missing from the compacted value, in the order of their paths
+func droppedTerms(path string, original, compacted interface{}) []*DroppedTerm {
+	switch o := original.(type) {
+	case map[string]interface{}:
+		c, ok := single(compacted).(map[string]interface{})
+		if !ok {
+			// the value is no longer an object, its terms can't be matched
+			return nil
+		}
+
+		var dropped []*DroppedTerm
+
+		for _, k := range sortedKeys(o) {
+			// the keywords aren't terms
+			if strings.HasPrefix(k, "@") {
+				continue
+			}
+
+			termPath := path + "/" + escape(k)
+
+			v, ok := c[k]
+			if !ok {
+				dropped = append(dropped, &DroppedTerm{Path: termPath, Term: k})
+
+				continue
+			}
+
+			dropped = append(dropped, droppedTerms(termPath, o[k], v)...)
+		}
+
+		return dropped
+	case []interface{}:
+		c, ok := compacted.([]interface{})
+		if !ok {
+			// a single value is compacted from an array of one value
+			c = []interface{}{compacted}
+		}
+
+		var dropped []*DroppedTerm
+
+		for i := 0; i < len(o) && i < len(c); i++ {
+			dropped = append(dropped, droppedTerms(fmt.Sprintf("%s/%d", path, i), o[i], c[i])...)
+		}
+
+		return dropped
+	default:
+		return nil
+	}
+}
+
+// single returns the value of an array of one value, as an object may be compacted into a set
+func single(v interface{}) interface{} {
+	if a, ok := v.([]interface{}); ok && len(a) == 1 {
+		return a[0]
+	}
+
+	return v
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// escape escapes the term as a reference token of a JSON pointer (RFC 6901)
+func escape(term string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(term)
+}
+
+// invalidStatements returns the statements of the normalized document which aren't valid N-Quads, as the generalized
+// RDF the signers normalize to keeps the statements of relative IRIs
+func invalidStatements(document string) []string {
+	var invalid []string
+
+	for _, statement := range strings.Split(document, "\n") {
+		if statement == "" {
+			continue
+		}
+
+		if _, err := ld.ParseNQuads(statement); err != nil {
+			invalid = append(invalid, statement)
+		}
+	}
+
+	return invalid
+}
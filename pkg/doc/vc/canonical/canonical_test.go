@@ -0,0 +1,114 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package canonical
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+)
+
+const validCredential = `{
+  "@context": [
+    "https://www.w3.org/2018/credentials/v1",
+    {"name": "http://schema.org/name", "alumniOf": {"@id": "http://schema.org/alumniOf", "@container": "@set"}}
+  ],
+  "id": "http://example.edu/credentials/1872",
+  "type": "VerifiableCredential",
+  "issuer": "did:example:76e12ec712ebc6f1c221ebfeb1f",
+  "issuanceDate": "2010-01-01T19:23:24Z",
+  "credentialSubject": {
+    "id": "did:example:ebfeb1f712ebc6f1c276e12ec21",
+    "name": "Jayden Doe",
+    "alumniOf": [{"name": "Example University"}]
+  },
+  "proof": {
+    "type": "Ed25519Signature2018",
+    "unknownProofTerm": "not normalized"
+  }
+}`
+
+const droppedTermsCredential = `{
+  "@context": [
+    "https://www.w3.org/2018/credentials/v1",
+    {"name": "http://schema.org/name"}
+  ],
+  "id": "http://example.edu/credentials/1872",
+  "type": "VerifiableCredential",
+  "issuer": "did:example:76e12ec712ebc6f1c221ebfeb1f",
+  "issuanceDate": "2010-01-01T19:23:24Z",
+  "credentialSubject": {
+    "id": "did:example:ebfeb1f712ebc6f1c276e12ec21",
+    "name": "Jayden Doe",
+    "degree": {"type": "BachelorDegree", "name": "Bachelor of Science"},
+    "a/b~c": "value",
+    "alumniOf": [{"name": "Example University"}, {"school": "Example School"}]
+  }
+}`
+
+func TestDiagnose(t *testing.T) {
+	loader := verifiable.CachingJSONLDLoader()
+
+	t.Run("test credential of defined terms", func(t *testing.T) {
+		diagnosis, err := Diagnose([]byte(validCredential), loader)
+		require.NoError(t, err)
+		require.Contains(t, diagnosis.Document,
+			`<did:example:ebfeb1f712ebc6f1c276e12ec21> <http://schema.org/name> "Jayden Doe" .`)
+		require.NotContains(t, diagnosis.Document, "Ed25519Signature2018")
+		require.Empty(t, diagnosis.DroppedTerms)
+		require.Empty(t, diagnosis.InvalidStatements)
+	})
+
+	t.Run("test dropped terms", func(t *testing.T) {
+		diagnosis, err := Diagnose([]byte(droppedTermsCredential), loader)
+		require.NoError(t, err)
+		require.NotContains(t, diagnosis.Document, "Bachelor of Science")
+		require.Equal(t, []*DroppedTerm{
+			{Path: "/credentialSubject/a~1b~0c", Term: "a/b~c"},
+			{Path: "/credentialSubject/alumniOf", Term: "alumniOf"},
+			{Path: "/credentialSubject/degree", Term: "degree"},
+		}, diagnosis.DroppedTerms)
+	})
+
+	t.Run("test invalid statements", func(t *testing.T) {
+		credential := strings.Replace(validCredential, `"type": "VerifiableCredential"`,
+			`"type": ["VerifiableCredential", "UniversityDegreeCredential"]`, 1)
+
+		diagnosis, err := Diagnose([]byte(credential), loader)
+		require.NoError(t, err)
+		require.Empty(t, diagnosis.DroppedTerms)
+		require.Len(t, diagnosis.InvalidStatements, 1)
+		require.Contains(t, diagnosis.InvalidStatements[0], "<UniversityDegreeCredential>")
+	})
+
+	t.Run("test invalid credential", func(t *testing.T) {
+		diagnosis, err := Diagnose([]byte("[]"), loader)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal credential")
+		require.Nil(t, diagnosis)
+	})
+
+	t.Run("test error from normalize", func(t *testing.T) {
+		diagnosis, err := Diagnose([]byte(`{"@context": {"@version": 2}}`), loader)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to normalize credential")
+		require.Nil(t, diagnosis)
+	})
+}
+
+func TestDroppedTerms(t *testing.T) {
+	t.Run("test object no longer an object", func(t *testing.T) {
+		require.Empty(t, droppedTerms("", map[string]interface{}{"name": "value"}, "value"))
+	})
+
+	t.Run("test object of a set", func(t *testing.T) {
+		require.Equal(t, []*DroppedTerm{{Path: "/name", Term: "name"}},
+			droppedTerms("", map[string]interface{}{"name": "value"}, []interface{}{map[string]interface{}{}}))
+	})
+}
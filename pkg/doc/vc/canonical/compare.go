@@ -0,0 +1,146 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package canonical
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/piprate/json-gold/ld"
+)
+
+// the kinds of the differences between two credentials
+const (
+	// Added is a field of the right credential only
+	Added = "added"
+	// Removed is a field of the left credential only
+	Removed = "removed"
+	// Changed is a field whose value differs between the credentials
+	Changed = "changed"
+)
+
+// Difference is a field whose value differs between two credentials
+type Difference struct {
+	// JSON pointer of the field, e.g. /credentialSubject/degree/name
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	// the value of the field in the left credential, if any
+	Left interface{} `json:"left,omitempty"`
+	// the value of the field in the right credential, if any
+	Right interface{} `json:"right,omitempty"`
+}
+
+// Comparison is the comparison of two credentials
+type Comparison struct {
+	// Equivalent is true if the credentials have the same normalized form, i.e. the same proofs are valid for both
+	Equivalent bool `json:"equivalent"`
+	// the fields which differ between the credentials as JSON, in the order of their paths
+	Differences []*Difference `json:"differences,omitempty"`
+}
+
+// Compare compares the credentials without their proofs: their URDNA2015 normalized forms, loading the contexts
+// with the loader (the default loader if nil), and their fields. Credentials differing in their fields are still
+// equivalent if the differences are lost in the normalization, e.g. their key order or undefined terms.
+func Compare(left, right []byte, loader ld.DocumentLoader) (*Comparison, error) {
+	differences, err := Diff(left, right)
+	if err != nil {
+		return nil, err
+	}
+
+	leftDocument, err := normalize(left, loader)
+	if err != nil {
+		return nil, fmt.Errorf("left credential: %w", err)
+	}
+
+	rightDocument, err := normalize(right, loader)
+	if err != nil {
+		return nil, fmt.Errorf("right credential: %w", err)
+	}
+
+	return &Comparison{Equivalent: leftDocument == rightDocument, Differences: differences}, nil
+}
+
+// Diff returns the fields which differ between the credentials, their proofs being ignored
+func Diff(left, right []byte) ([]*Difference, error) {
+	leftDoc, err := toMap(left)
+	if err != nil {
+		return nil, fmt.Errorf("left credential: %w", err)
+	}
+
+	rightDoc, err := toMap(right)
+	if err != nil {
+		return nil, fmt.Errorf("right credential: %w", err)
+	}
+
+	delete(leftDoc, proofKey)
+	delete(rightDoc, proofKey)
+
+	return differences("", leftDoc, rightDoc), nil
+}
+
+// differences returns the differences between the values at the path, the fields of objects and the items of arrays
+// being compared one by one
+func differences(path string, left, right interface{}) []*Difference {
+	switch l := left.(type) {
+	case map[string]interface{}:
+		r, ok := right.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		var diffs []*Difference
+
+		for _, k := range sortedKeys(l) {
+			fieldPath := path + "/" + escape(k)
+
+			v, ok := r[k]
+			if !ok {
+				diffs = append(diffs, &Difference{Path: fieldPath, Kind: Removed, Left: l[k]})
+
+				continue
+			}
+
+			diffs = append(diffs, differences(fieldPath, l[k], v)...)
+		}
+
+		for _, k := range sortedKeys(r) {
+			if _, ok := l[k]; !ok {
+				diffs = append(diffs, &Difference{Path: path + "/" + escape(k), Kind: Added, Right: r[k]})
+			}
+		}
+
+		return diffs
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok {
+			break
+		}
+
+		var diffs []*Difference
+
+		for i := 0; i < len(l) || i < len(r); i++ {
+			itemPath := fmt.Sprintf("%s/%d", path, i)
+
+			switch {
+			case i >= len(r):
+				diffs = append(diffs, &Difference{Path: itemPath, Kind: Removed, Left: l[i]})
+			case i >= len(l):
+				diffs = append(diffs, &Difference{Path: itemPath, Kind: Added, Right: r[i]})
+			default:
+				diffs = append(diffs, differences(itemPath, l[i], r[i])...)
+			}
+		}
+
+		return diffs
+	}
+
+	if reflect.DeepEqual(left, right) {
+		return nil
+	}
+
+	return []*Difference{{Path: path, Kind: Changed, Left: left, Right: right}}
+}
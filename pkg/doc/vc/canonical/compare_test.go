@@ -0,0 +1,97 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package canonical
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	loader := verifiable.CachingJSONLDLoader()
+
+	t.Run("test same credential with other proof", func(t *testing.T) {
+		other := strings.Replace(validCredential, `"unknownProofTerm": "not normalized"`, `"jws": "abc"`, 1)
+
+		comparison, err := Compare([]byte(validCredential), []byte(other), loader)
+		require.NoError(t, err)
+		require.True(t, comparison.Equivalent)
+		require.Empty(t, comparison.Differences)
+	})
+
+	t.Run("test tampered credential", func(t *testing.T) {
+		tampered := strings.Replace(validCredential, `"name": "Jayden Doe"`, `"name": "John Doe"`, 1)
+
+		comparison, err := Compare([]byte(validCredential), []byte(tampered), loader)
+		require.NoError(t, err)
+		require.False(t, comparison.Equivalent)
+		require.Equal(t, []*Difference{{Path: "/credentialSubject/name", Kind: Changed, Left: "Jayden Doe",
+			Right: "John Doe"}}, comparison.Differences)
+	})
+
+	t.Run("test differences lost in the normalization", func(t *testing.T) {
+		undefined := strings.Replace(validCredential, `"name": "Jayden Doe",`,
+			`"name": "Jayden Doe", "degree": "Bachelor of Science",`, 1)
+
+		comparison, err := Compare([]byte(validCredential), []byte(undefined), loader)
+		require.NoError(t, err)
+		require.True(t, comparison.Equivalent)
+		require.Equal(t, []*Difference{{Path: "/credentialSubject/degree", Kind: Added,
+			Right: "Bachelor of Science"}}, comparison.Differences)
+	})
+
+	t.Run("test invalid credentials", func(t *testing.T) {
+		_, err := Compare([]byte("[]"), []byte(validCredential), loader)
+		require.EqualError(t, err, "left credential: failed to unmarshal credential: "+
+			"json: cannot unmarshal array into Go value of type map[string]interface {}")
+
+		_, err = Compare([]byte(validCredential), []byte("[]"), loader)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "right credential")
+
+		_, err = Compare([]byte(`{"@context": {"@version": 2}}`), []byte(validCredential), loader)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "left credential: failed to normalize credential")
+
+		_, err = Compare([]byte(validCredential), []byte(`{"@context": {"@version": 2}}`), loader)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "right credential: failed to normalize credential")
+	})
+}
+
+func TestDiff(t *testing.T) {
+	diffs, err := Diff([]byte(`{
+		"type": ["VerifiableCredential", "UniversityDegreeCredential"],
+		"credentialSubject": {"name": "Jayden Doe", "a/b": 1, "degree": {"name": "BSc"}},
+		"issuer": "did:example:abc",
+		"proof": {"jws": "abc"}
+	}`), []byte(`{
+		"type": ["VerifiableCredential"],
+		"credentialSubject": {"name": "Jayden Doe", "degree": "BSc", "spouse": "did:example:def"},
+		"issuer": {"id": "did:example:abc"},
+		"evidence": [{"id": "1"}]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, []*Difference{
+		{Path: "/credentialSubject/a~1b", Kind: Removed, Left: float64(1)},
+		{Path: "/credentialSubject/degree", Kind: Changed, Left: map[string]interface{}{"name": "BSc"},
+			Right: "BSc"},
+		{Path: "/credentialSubject/spouse", Kind: Added, Right: "did:example:def"},
+		{Path: "/issuer", Kind: Changed, Left: "did:example:abc",
+			Right: map[string]interface{}{"id": "did:example:abc"}},
+		{Path: "/type/1", Kind: Removed, Left: "UniversityDegreeCredential"},
+		{Path: "/evidence", Kind: Added, Right: []interface{}{map[string]interface{}{"id": "1"}}},
+	}, diffs)
+
+	diffs, err = Diff([]byte(`{"type": ["VerifiableCredential"]}`),
+		[]byte(`{"type": ["VerifiableCredential", "UniversityDegreeCredential"]}`))
+	require.NoError(t, err)
+	require.Equal(t, []*Difference{{Path: "/type/1", Kind: Added, Right: "UniversityDegreeCredential"}}, diffs)
+}
@@ -0,0 +1,187 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package challenge
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+const (
+	challengeStore = "challenge"
+
+	// DefaultTTL is how long the challenges can be consumed
+	DefaultTTL = 5 * time.Minute
+
+	challengeLength = 32
+)
+
+// ErrInvalidChallenge is returned when the challenge wasn't created for the scope, has expired or was already
+// consumed
+var ErrInvalidChallenge = errors.New("invalid, expired or already used challenge")
+
+// Challenge is a challenge signed by a presentation to prevent its replay, e.g. by a DIDAuth presentation to prove
+// the control of a DID
+type Challenge struct {
+	Challenge string    `json:"challenge"`
+	Expires   time.Time `json:"expires"`
+}
+
+// record is a stored challenge
+type record struct {
+	Scope    string    `json:"scope"`
+	Expires  time.Time `json:"expires"`
+	Consumed bool      `json:"consumed,omitempty"`
+}
+
+// Store keeps the challenges created for each scope, e.g. an issuer profile for the holder binding of its
+// credentials, a verifier profile for its presentations or the DIDAuth of the verifier.
+//
+// A challenge is consumed once: it is marked as consumed, the store having no delete, the stored challenge being
+// replaced only if it is unchanged since it was read. The replacement is atomic in the store, so a challenge is
+// consumed once across the instances sharing the storage provider.
+type Store struct {
+	store conditional.Store
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// New returns a new challenge store, the challenges expiring after the ttl
+func New(provider storage.Provider, ttl time.Duration) (*Store, error) {
+	err := provider.CreateStore(challengeStore)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := provider.OpenStore(challengeStore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{store: conditional.New(store), ttl: ttl, now: time.Now}, nil
+}
+
+// Create returns a new random challenge for the scope
+func (s *Store) Create(scope string) (*Challenge, error) {
+	nonce, err := randomString(challengeLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+
+	challenge := &Challenge{
+		Challenge: nonce,
+		Expires:   s.now().Add(s.ttl).UTC(),
+	}
+
+	if err := s.put(challenge.Challenge, &record{Scope: scope, Expires: challenge.Expires}); err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// Consume marks the challenge created for the scope as consumed. The error is ErrInvalidChallenge if the challenge
+// wasn't created for the scope, has expired or was already consumed, e.g. concurrently by another instance.
+func (s *Store) Consume(scope, challenge string) error {
+	rec, recordBytes, err := s.get(challenge)
+	if err != nil {
+		return err
+	}
+
+	if !s.valid(rec, scope) {
+		return ErrInvalidChallenge
+	}
+
+	rec.Consumed = true
+
+	consumedBytes, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal challenge: %w", err)
+	}
+
+	err = s.store.Replace(challenge, recordBytes, consumedBytes)
+	if errors.Is(err, conditional.ErrConflict) {
+		return ErrInvalidChallenge
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to store challenge: %w", err)
+	}
+
+	return nil
+}
+
+// Check returns ErrInvalidChallenge if the challenge wasn't created for the scope, has expired or was already
+// consumed, without consuming it.
+func (s *Store) Check(scope, challenge string) error {
+	rec, _, err := s.get(challenge)
+	if err != nil {
+		return err
+	}
+
+	if !s.valid(rec, scope) {
+		return ErrInvalidChallenge
+	}
+
+	return nil
+}
+
+// valid checks the challenge was created for the scope, isn't consumed and hasn't expired
+func (s *Store) valid(rec *record, scope string) bool {
+	return rec.Scope == scope && !rec.Consumed && s.now().Before(rec.Expires)
+}
+
+// get returns the record of the challenge and its stored bytes
+func (s *Store) get(challenge string) (*record, []byte, error) {
+	recordBytes, err := s.store.Get(challenge)
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return nil, nil, ErrInvalidChallenge
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get challenge: %w", err)
+	}
+
+	rec := &record{}
+
+	if err = json.Unmarshal(recordBytes, rec); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal challenge: %w", err)
+	}
+
+	return rec, recordBytes, nil
+}
+
+func (s *Store) put(challenge string, rec *record) error {
+	recordBytes, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal challenge: %w", err)
+	}
+
+	if err := s.store.Put(challenge, recordBytes); err != nil {
+		return fmt.Errorf("failed to store challenge: %w", err)
+	}
+
+	return nil
+}
+
+func randomString(length int) (string, error) {
+	b := make([]byte, length)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,164 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package challenge
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		store, err := New(memstore.NewProvider(), DefaultTTL)
+		require.NoError(t, err)
+		require.NotNil(t, store)
+	})
+
+	t.Run("test error from create store", func(t *testing.T) {
+		store, err := New(&mockstore.Provider{ErrCreateStore: fmt.Errorf("error create")}, DefaultTTL)
+		require.EqualError(t, err, "error create")
+		require.Nil(t, store)
+	})
+
+	t.Run("test error from open store", func(t *testing.T) {
+		store, err := New(&mockstore.Provider{ErrOpenStoreHandle: fmt.Errorf("error open")}, DefaultTTL)
+		require.EqualError(t, err, "error open")
+		require.Nil(t, store)
+	})
+}
+
+func TestStore(t *testing.T) {
+	now := time.Date(2020, time.October, 16, 10, 0, 0, 0, time.UTC)
+
+	newStore := func(t *testing.T, provider storage.Provider) *Store {
+		store, err := New(provider, time.Minute)
+		require.NoError(t, err)
+
+		store.now = func() time.Time { return now }
+
+		return store
+	}
+
+	t.Run("test challenge consumed once", func(t *testing.T) {
+		store := newStore(t, memstore.NewProvider())
+
+		challenge, err := store.Create("issuer")
+		require.NoError(t, err)
+		require.Len(t, challenge.Challenge, 43)
+		require.Equal(t, now.Add(time.Minute), challenge.Expires)
+
+		other, err := store.Create("issuer")
+		require.NoError(t, err)
+		require.NotEqual(t, challenge.Challenge, other.Challenge)
+
+		require.NoError(t, store.Consume("issuer", challenge.Challenge))
+		require.True(t, errors.Is(store.Consume("issuer", challenge.Challenge), ErrInvalidChallenge))
+	})
+
+	t.Run("test challenge consumed once across instances", func(t *testing.T) {
+		provider := memstore.NewProvider()
+		store := newStore(t, provider)
+		otherInstance := newStore(t, provider)
+
+		challenge, err := store.Create("verifier")
+		require.NoError(t, err)
+
+		require.NoError(t, otherInstance.Consume("verifier", challenge.Challenge))
+		require.True(t, errors.Is(store.Consume("verifier", challenge.Challenge), ErrInvalidChallenge))
+	})
+
+	t.Run("test challenge consumed concurrently by another instance", func(t *testing.T) {
+		store := newStore(t, memstore.NewProvider())
+
+		challenge, err := store.Create("verifier")
+		require.NoError(t, err)
+
+		store.store = &concurrentConsumerStore{Store: store.store}
+
+		require.True(t, errors.Is(store.Consume("verifier", challenge.Challenge), ErrInvalidChallenge))
+		require.True(t, errors.Is(store.Check("verifier", challenge.Challenge), ErrInvalidChallenge))
+	})
+
+	t.Run("test challenge checked without being consumed", func(t *testing.T) {
+		store := newStore(t, memstore.NewProvider())
+
+		challenge, err := store.Create("issuer")
+		require.NoError(t, err)
+
+		require.NoError(t, store.Check("issuer", challenge.Challenge))
+		require.NoError(t, store.Check("issuer", challenge.Challenge))
+		require.True(t, errors.Is(store.Check("other", challenge.Challenge), ErrInvalidChallenge))
+
+		require.NoError(t, store.Consume("issuer", challenge.Challenge))
+		require.True(t, errors.Is(store.Check("issuer", challenge.Challenge), ErrInvalidChallenge))
+		require.True(t, errors.Is(store.Check("issuer", "unknown"), ErrInvalidChallenge))
+	})
+
+	t.Run("test invalid challenge", func(t *testing.T) {
+		store := newStore(t, memstore.NewProvider())
+
+		challenge, err := store.Create("issuer")
+		require.NoError(t, err)
+
+		require.True(t, errors.Is(store.Consume("other", challenge.Challenge), ErrInvalidChallenge))
+		require.True(t, errors.Is(store.Consume("issuer", "unknown"), ErrInvalidChallenge))
+
+		store.now = func() time.Time { return now.Add(time.Minute) }
+
+		require.True(t, errors.Is(store.Consume("issuer", challenge.Challenge), ErrInvalidChallenge))
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		store := newStore(t, memstore.NewProvider())
+
+		store.store = conditional.New(&mockstore.MockStore{Store: map[string][]byte{}, ErrPut: fmt.Errorf("error put")})
+
+		_, err := store.Create("issuer")
+		require.EqualError(t, err, "failed to store challenge: error put")
+
+		store.store = conditional.New(&mockstore.MockStore{Store: map[string][]byte{
+			"c": []byte(`{"scope":"issuer","expires":"2020-10-16T11:00:00Z"}`),
+		}, ErrPut: fmt.Errorf("error put")})
+
+		err = store.Consume("issuer", "c")
+		require.EqualError(t, err, "failed to store challenge: error put")
+
+		store.store = conditional.New(&mockstore.MockStore{Store: map[string][]byte{"c": []byte("{")}})
+
+		err = store.Consume("issuer", "c")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal challenge")
+
+		store.store = conditional.New(&mockstore.MockStore{Store: map[string][]byte{"c": []byte("{}")},
+			ErrGet: fmt.Errorf("error get")})
+
+		err = store.Consume("issuer", "c")
+		require.EqualError(t, err, "failed to get challenge: error get")
+	})
+}
+
+// concurrentConsumerStore simulates another instance consuming the challenges right before each replacement
+type concurrentConsumerStore struct {
+	conditional.Store
+}
+
+func (s *concurrentConsumerStore) Replace(k string, old, v []byte) error {
+	if err := s.Store.Put(k, v); err != nil {
+		return err
+	}
+
+	return s.Store.Replace(k, old, v)
+}
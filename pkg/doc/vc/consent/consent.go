@@ -0,0 +1,299 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package consent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/sdjwt"
+)
+
+const (
+	consentStore   = "consentlog"
+	countKeyPrefix = "count_"
+	entryKeyPrefix = "entry_"
+
+	subjectKey = "credentialSubject"
+)
+
+// Entry is a presentation created by a holder profile: to whom, with which credentials, revealing which claims.
+type Entry struct {
+	PresentationID string `json:"presentationID,omitempty"`
+	// Domain is the domain of the proof of the presentation, i.e. the verifier it is presented to
+	Domain      string              `json:"domain,omitempty"`
+	Challenge   string              `json:"challenge,omitempty"`
+	Credentials []*SharedCredential `json:"credentials"`
+	Created     time.Time           `json:"created"`
+}
+
+// SharedCredential is a credential of a presentation
+type SharedCredential struct {
+	ID     string   `json:"id,omitempty"`
+	Types  []string `json:"types,omitempty"`
+	Issuer string   `json:"issuer,omitempty"`
+	// Claims are the JSON pointers of the claims of the subject revealed by the credential, e.g.
+	// /credentialSubject/degree/type
+	Claims []string `json:"claims,omitempty"`
+}
+
+// Query filters the entries of the log of a profile, the zero values matching all the entries
+type Query struct {
+	Domain       string
+	CredentialID string
+	// Since and Until bound the creation time of the presentations, inclusively
+	Since time.Time
+	Until time.Time
+	// Limit is the maximum number of entries returned
+	Limit int
+}
+
+// Log keeps the presentations created by each holder profile, for the holders to know what they shared with whom.
+//
+// Each entry is stored under its own key, numbered in the log of its profile, before the count of the entries of
+// the profile. The log mutex serializes the numbering, so the entries recorded by an instance can't overwrite each
+// other.
+type Log struct {
+	store storage.Store
+	mutex sync.Mutex
+}
+
+// New returns a new consent log
+func New(provider storage.Provider) (*Log, error) {
+	err := provider.CreateStore(consentStore)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := provider.OpenStore(consentStore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Log{store: store}, nil
+}
+
+// NewEntry returns the entry of the presentation, with the domain and the challenge of its proof
+func NewEntry(vp *verifiable.Presentation, domain, challenge string) (*Entry, error) {
+	entry := &Entry{PresentationID: vp.ID, Domain: domain, Challenge: challenge,
+		Credentials: []*SharedCredential{}, Created: time.Now().UTC()}
+
+	for _, credential := range vp.Credentials() {
+		shared, err := newSharedCredential(credential)
+		if err != nil {
+			return nil, err
+		}
+
+		entry.Credentials = append(entry.Credentials, shared)
+	}
+
+	return entry, nil
+}
+
+// NewSDJWTEntry returns the entry of the presentation of a SD-JWT credential, its claims being the claims it discloses,
+// with the audience and the nonce of its key binding JWT
+func NewSDJWTEntry(credential *sdjwt.Credential, audience, nonce string) (*Entry, error) {
+	shared, err := newSharedCredential(credential.VC)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Entry{Domain: audience, Challenge: nonce, Credentials: []*SharedCredential{shared},
+		Created: time.Now().UTC()}, nil
+}
+
+// Record appends the entry to the log of the profile
+func (l *Log) Record(profile string, entry *Entry) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal consent log entry: %w", err)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	count, err := l.getCount(profile)
+	if err != nil {
+		return err
+	}
+
+	if err := l.store.Put(entryKey(profile, count), entryBytes); err != nil {
+		return fmt.Errorf("failed to store consent log entry: %w", err)
+	}
+
+	if err := l.store.Put(countKeyPrefix+profile, []byte(strconv.Itoa(count+1))); err != nil {
+		return fmt.Errorf("failed to store consent log: %w", err)
+	}
+
+	return nil
+}
+
+// Query returns the entries of the log of the profile matching the query, latest first
+func (l *Log) Query(profile string, query *Query) ([]*Entry, error) {
+	count, err := l.getCount(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*Entry{}
+
+	for i := count - 1; i >= 0; i-- {
+		if query.Limit > 0 && len(entries) == query.Limit {
+			break
+		}
+
+		entryBytes, err := l.store.Get(entryKey(profile, i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get consent log entry: %w", err)
+		}
+
+		entry := &Entry{}
+
+		if err := json.Unmarshal(entryBytes, entry); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal consent log entry: %w", err)
+		}
+
+		if query.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// getCount returns the number of entries of the log of the profile
+func (l *Log) getCount(profile string) (int, error) {
+	countBytes, err := l.store.Get(countKeyPrefix + profile)
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to get consent log: %w", err)
+	}
+
+	count, err := strconv.Atoi(string(countBytes))
+	if err != nil {
+		return 0, fmt.Errorf("invalid consent log: %w", err)
+	}
+
+	return count, nil
+}
+
+func (q *Query) matches(entry *Entry) bool {
+	if q.Domain != "" && q.Domain != entry.Domain {
+		return false
+	}
+
+	if !q.Since.IsZero() && entry.Created.Before(q.Since) {
+		return false
+	}
+
+	if !q.Until.IsZero() && entry.Created.After(q.Until) {
+		return false
+	}
+
+	if q.CredentialID == "" {
+		return true
+	}
+
+	for _, credential := range entry.Credentials {
+		if credential.ID == q.CredentialID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newSharedCredential returns the shared credential of a credential of a presentation, a parsed credential, a JWT
+// or a JSON object
+func newSharedCredential(credential interface{}) (*SharedCredential, error) {
+	vc, ok := credential.(*verifiable.Credential)
+	if !ok {
+		var credentialBytes []byte
+
+		if jwt, isJWT := credential.(string); isJWT {
+			credentialBytes = []byte(jwt)
+		} else {
+			var err error
+
+			credentialBytes, err = json.Marshal(credential)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal presented credential: %w", err)
+			}
+		}
+
+		var err error
+
+		vc, err = verifiable.ParseUnverifiedCredential(credentialBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse presented credential: %w", err)
+		}
+	}
+
+	vcBytes, err := vc.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal presented credential: %w", err)
+	}
+
+	doc := make(map[string]interface{})
+
+	if err := json.Unmarshal(vcBytes, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal presented credential: %w", err)
+	}
+
+	return &SharedCredential{ID: vc.ID, Types: vc.Types, Issuer: vc.Issuer.ID,
+		Claims: claims("/"+subjectKey, doc[subjectKey])}, nil
+}
+
+// claims returns the JSON pointers of the values of the claim, the claims of its objects and arrays
+func claims(path string, claim interface{}) []string {
+	switch c := claim.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(c))
+
+		for k := range c {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		var paths []string
+
+		for _, k := range keys {
+			paths = append(paths, claims(path+"/"+strings.NewReplacer("~", "~0", "/", "~1").Replace(k), c[k])...)
+		}
+
+		return paths
+	case []interface{}:
+		var paths []string
+
+		for i, v := range c {
+			paths = append(paths, claims(fmt.Sprintf("%s/%d", path, i), v)...)
+		}
+
+		return paths
+	case nil:
+		return nil
+	default:
+		return []string{path}
+	}
+}
+
+func entryKey(profile string, i int) string {
+	return entryKeyPrefix + profile + "/" + strconv.Itoa(i)
+}
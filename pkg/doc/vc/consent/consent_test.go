@@ -0,0 +1,248 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package consent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/sdjwt"
+)
+
+const testCredential = `{
+  "@context": ["https://www.w3.org/2018/credentials/v1", "https://www.w3.org/2018/credentials/examples/v1"],
+  "id": "http://example.edu/credentials/1872",
+  "type": ["VerifiableCredential", "UniversityDegreeCredential"],
+  "issuer": "did:example:76e12ec712ebc6f1c221ebfeb1f",
+  "issuanceDate": "2010-01-01T19:23:24Z",
+  "credentialSubject": {
+    "id": "did:example:ebfeb1f712ebc6f1c276e12ec21",
+    "degree": {"type": "BachelorDegree", "name": "Bachelor of Science"},
+    "alumniOf": [{"name": "Example University"}],
+    "a/b~c": "value"
+  }
+}`
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		l, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+		require.NotNil(t, l)
+	})
+
+	t.Run("test error from create store", func(t *testing.T) {
+		l, err := New(&mockstore.Provider{ErrCreateStore: fmt.Errorf("error create")})
+		require.EqualError(t, err, "error create")
+		require.Nil(t, l)
+	})
+
+	t.Run("test error from open store", func(t *testing.T) {
+		l, err := New(&mockstore.Provider{ErrOpenStoreHandle: fmt.Errorf("error open")})
+		require.EqualError(t, err, "error open")
+		require.Nil(t, l)
+	})
+}
+
+func TestNewEntry(t *testing.T) {
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(testCredential))
+	require.NoError(t, err)
+
+	vp, err := vc.Presentation()
+	require.NoError(t, err)
+
+	vp.ID = "urn:uuid:presentation1"
+
+	t.Run("test entry of the presentation", func(t *testing.T) {
+		entry, err := NewEntry(vp, "verifier.example.com", "challenge1")
+		require.NoError(t, err)
+		require.Equal(t, "urn:uuid:presentation1", entry.PresentationID)
+		require.Equal(t, "verifier.example.com", entry.Domain)
+		require.Equal(t, "challenge1", entry.Challenge)
+		require.False(t, entry.Created.IsZero())
+		require.Equal(t, []*SharedCredential{{
+			ID:     "http://example.edu/credentials/1872",
+			Types:  []string{"VerifiableCredential", "UniversityDegreeCredential"},
+			Issuer: "did:example:76e12ec712ebc6f1c221ebfeb1f",
+			Claims: []string{
+				"/credentialSubject/a~1b~0c",
+				"/credentialSubject/alumniOf/0/name",
+				"/credentialSubject/degree/name",
+				"/credentialSubject/degree/type",
+				"/credentialSubject/id",
+			},
+		}}, entry.Credentials)
+	})
+
+	t.Run("test credential as JSON", func(t *testing.T) {
+		doc := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal([]byte(testCredential), &doc))
+
+		shared, err := newSharedCredential(doc)
+		require.NoError(t, err)
+		require.Equal(t, "http://example.edu/credentials/1872", shared.ID)
+		require.Len(t, shared.Claims, 5)
+	})
+
+	t.Run("test presentation without credentials", func(t *testing.T) {
+		entry, err := NewEntry(&verifiable.Presentation{}, "", "")
+		require.NoError(t, err)
+		require.Empty(t, entry.Credentials)
+	})
+
+	t.Run("test invalid credential", func(t *testing.T) {
+		_, err := newSharedCredential(map[string]interface{}{"id": 1})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse presented credential")
+
+		_, err = newSharedCredential("not a JWT")
+		require.Error(t, err)
+
+		_, err = newSharedCredential(func() {})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to marshal presented credential")
+	})
+}
+
+func TestNewSDJWTEntry(t *testing.T) {
+	vc := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal([]byte(testCredential), &vc))
+
+	// the claims of the subject not disclosed by the SD-JWT
+	subject := vc["credentialSubject"].(map[string]interface{})
+	delete(subject, "alumniOf")
+	delete(subject, "a/b~c")
+
+	t.Run("test entry of the presentation", func(t *testing.T) {
+		entry, err := NewSDJWTEntry(&sdjwt.Credential{VC: vc, Disclosed: []string{"degree"}},
+			"verifier.example.com", "nonce1")
+		require.NoError(t, err)
+		require.Empty(t, entry.PresentationID)
+		require.Equal(t, "verifier.example.com", entry.Domain)
+		require.Equal(t, "nonce1", entry.Challenge)
+		require.False(t, entry.Created.IsZero())
+		require.Equal(t, []*SharedCredential{{
+			ID:     "http://example.edu/credentials/1872",
+			Types:  []string{"VerifiableCredential", "UniversityDegreeCredential"},
+			Issuer: "did:example:76e12ec712ebc6f1c221ebfeb1f",
+			Claims: []string{
+				"/credentialSubject/degree/name",
+				"/credentialSubject/degree/type",
+				"/credentialSubject/id",
+			},
+		}}, entry.Credentials)
+	})
+
+	t.Run("test invalid credential", func(t *testing.T) {
+		_, err := NewSDJWTEntry(&sdjwt.Credential{VC: map[string]interface{}{"id": 1}}, "", "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse presented credential")
+	})
+}
+
+func TestLog(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+
+	entries := []*Entry{
+		{Domain: "a.example.com", Credentials: []*SharedCredential{{ID: "vc1"}}, Created: now.Add(-2 * time.Hour)},
+		{Domain: "b.example.com", Credentials: []*SharedCredential{{ID: "vc2"}}, Created: now.Add(-time.Hour)},
+		{Domain: "a.example.com", Credentials: []*SharedCredential{{ID: "vc1"}, {ID: "vc2"}}, Created: now},
+	}
+
+	l, err := New(memstore.NewProvider())
+	require.NoError(t, err)
+
+	for _, entry := range entries {
+		require.NoError(t, l.Record("holder", entry))
+	}
+
+	require.NoError(t, l.Record("other", entries[0]))
+
+	for _, test := range []struct {
+		name     string
+		query    *Query
+		expected []*Entry
+	}{
+		{name: "all", query: &Query{}, expected: []*Entry{entries[2], entries[1], entries[0]}},
+		{name: "domain", query: &Query{Domain: "a.example.com"}, expected: []*Entry{entries[2], entries[0]}},
+		{name: "credential", query: &Query{CredentialID: "vc2"}, expected: []*Entry{entries[2], entries[1]}},
+		{name: "since", query: &Query{Since: now.Add(-time.Hour)}, expected: []*Entry{entries[2], entries[1]}},
+		{name: "until", query: &Query{Until: now.Add(-time.Hour)}, expected: []*Entry{entries[1], entries[0]}},
+		{name: "limit", query: &Query{Limit: 1}, expected: []*Entry{entries[2]}},
+		{name: "no match", query: &Query{CredentialID: "vc3"}, expected: []*Entry{}},
+	} {
+		t.Run("test query by "+test.name, func(t *testing.T) {
+			result, err := l.Query("holder", test.query)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, result)
+		})
+	}
+
+	t.Run("test profile without log", func(t *testing.T) {
+		result, err := l.Query("unknown", &Query{})
+		require.NoError(t, err)
+		require.Empty(t, result)
+	})
+
+	t.Run("test concurrent records", func(t *testing.T) {
+		l, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				require.NoError(t, l.Record("holder", &Entry{Domain: fmt.Sprintf("verifier%d", i)}))
+			}(i)
+		}
+
+		wg.Wait()
+
+		result, err := l.Query("holder", &Query{})
+		require.NoError(t, err)
+		require.Len(t, result, 10)
+	})
+
+	t.Run("test error from store", func(t *testing.T) {
+		store := &mockstore.MockStore{Store: map[string][]byte{}}
+		l, err := New(&mockstore.Provider{Store: store})
+		require.NoError(t, err)
+
+		store.ErrPut = fmt.Errorf("error put")
+		err = l.Record("holder", &Entry{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store consent log entry: error put")
+
+		store.ErrPut = nil
+		store.Store[countKeyPrefix+"holder"] = []byte("x")
+		err = l.Record("holder", &Entry{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid consent log")
+
+		store.Store[countKeyPrefix+"holder"] = []byte("1")
+		store.Store[entryKey("holder", 0)] = []byte("[")
+		_, err = l.Query("holder", &Query{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal consent log entry")
+
+		store.ErrGet = fmt.Errorf("error get")
+		_, err = l.Query("holder", &Query{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get consent log: error get")
+	})
+}
@@ -7,22 +7,23 @@ SPDX-License-Identifier: Apache-2.0
 package crypto
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	ariescrypto "github.com/hyperledger/aries-framework-go/pkg/crypto"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/proof"
 	ariessigner "github.com/hyperledger/aries-framework-go/pkg/doc/signature/signer"
-	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
-	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
-	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/jsonwebsignature2020"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
 
 	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
 	"github.com/trustbloc/edge-service/pkg/internal/common/diddoc"
+	"github.com/trustbloc/edge-service/pkg/metrics"
 )
 
 const (
@@ -30,11 +31,15 @@ const (
 	Ed25519Signature2018 = "Ed25519Signature2018"
 	// JSONWebSignature2020 json web signature suite
 	JSONWebSignature2020 = "JsonWebSignature2020"
+	// EcdsaSecp256k1Signature2019 ecdsa secp256k1 signature suite
+	EcdsaSecp256k1Signature2019 = "EcdsaSecp256k1Signature2019"
 
 	// Ed25519VerificationKey2018 ed25119 verification key
 	Ed25519VerificationKey2018 = "Ed25519VerificationKey2018"
 	// JwsVerificationKey2020 jws verification key
 	JwsVerificationKey2020 = "JwsVerificationKey2020"
+	// EcdsaSecp256k1VerificationKey2019 ecdsa secp256k1 verification key
+	EcdsaSecp256k1VerificationKey2019 = "EcdsaSecp256k1VerificationKey2019"
 )
 
 const (
@@ -43,6 +48,18 @@ const (
 
 	// P256KeyType EC P-256 key type
 	P256KeyType = "P256"
+
+	// P384KeyType EC P-384 key type
+	P384KeyType = "P384"
+
+	// P521KeyType EC P-521 key type
+	P521KeyType = "P521"
+
+	// Secp256k1KeyType EC secp256k1 key type
+	Secp256k1KeyType = "secp256k1"
+
+	// RSAKeyType RSA key type
+	RSAKeyType = "RSA"
 )
 
 const (
@@ -61,10 +78,9 @@ const (
 	CapabilityInvocation = "capabilityInvocation"
 )
 
-type signer interface {
-	// Sign will sign document and return signature
-	Sign(data []byte) ([]byte, error)
-}
+// ErrInvalidProofPurpose is returned when the verification method isn't listed under the proof purpose in the DID
+// document of its DID
+var ErrInvalidProofPurpose = errors.New("the verification method isn't listed under the proof purpose")
 
 type kmsSigner struct {
 	keyHandle interface{}
@@ -78,20 +94,28 @@ func newKMSSigner(keyManager kms.KeyManager, c ariescrypto.Crypto, creator strin
 		return nil, err
 	}
 
+	start := time.Now()
+
 	keyHandler, err := keyManager.Get(keyID)
 	if err != nil {
 		return nil, err
 	}
 
+	metrics.ObserveKMSOperation("getKey", start)
+
 	return &kmsSigner{keyHandle: keyHandler, crypto: c}, nil
 }
 
 func (s *kmsSigner) Sign(data []byte) ([]byte, error) {
+	start := time.Now()
+
 	v, err := s.crypto.Sign(data, s.keyHandle)
 	if err != nil {
 		return nil, err
 	}
 
+	metrics.ObserveKMSOperation("sign", start)
+
 	return v, nil
 }
 
@@ -109,6 +133,8 @@ type signingOpts struct {
 	Created            *time.Time
 	Challenge          string
 	Domain             string
+	Nonce              []byte
+	ProofProperties    map[string]interface{}
 }
 
 // SigningOpts is signing credential option
@@ -163,6 +189,38 @@ func WithDomain(domain string) SigningOpts {
 	}
 }
 
+// WithNonce proof nonce, base64url encoded in the proof and signed with the other proof options
+func WithNonce(nonce []byte) SigningOpts {
+	return func(opts *signingOpts) {
+		opts.Nonce = nonce
+	}
+}
+
+// WithProofProperties is an option to add custom properties (e.g. capabilityChain) to the proof. The properties
+// are added once the proof is created, they are not part of the signed proof options.
+func WithProofProperties(properties map[string]interface{}) SigningOpts {
+	return func(opts *signingOpts) {
+		opts.ProofProperties = properties
+	}
+}
+
+// reservedProofProperties are the properties set by the signer, which can't be custom proof properties
+var reservedProofProperties = map[string]bool{ // nolint: gochecknoglobals
+	"type": true, "created": true, "creator": true, "verificationMethod": true, "proofPurpose": true,
+	"challenge": true, "domain": true, "nonce": true, "jws": true, "proofValue": true, "@context": true,
+}
+
+// ValidateProofProperties checks the custom proof properties don't override the properties set by the signer
+func ValidateProofProperties(properties map[string]interface{}) error {
+	for name := range properties {
+		if reservedProofProperties[name] {
+			return fmt.Errorf("proof property %s is reserved", name)
+		}
+	}
+
+	return nil
+}
+
 // Crypto to sign credential
 type Crypto struct {
 	keyManager kms.KeyManager
@@ -170,7 +228,8 @@ type Crypto struct {
 	vdri       vdriapi.Registry
 }
 
-// SignCredential sign vc
+// SignCredential sign vc. The proof is added to the proofs of the vc, signing an already signed vc again (e.g. with
+// another signature type or key) makes a proof set, each proof being verified independently.
 func (c *Crypto) SignCredential(dataProfile *vcprofile.DataProfile, vc *verifiable.Credential, opts ...SigningOpts) (*verifiable.Credential, error) { // nolint:lll,dupl
 	signOpts := &signingOpts{}
 	// apply opts
@@ -183,17 +242,28 @@ func (c *Crypto) SignCredential(dataProfile *vcprofile.DataProfile, vc *verifiab
 		signatureType = signOpts.SignatureType
 	}
 
+	if err := ValidateProofProperties(signOpts.ProofProperties); err != nil {
+		return nil, err
+	}
+
 	signingCtx, err := c.getLinkedDataProofContext(dataProfile.Creator, signatureType, AssertionMethod,
 		dataProfile.SignatureRepresentation, signOpts)
 	if err != nil {
 		return nil, err
 	}
 
-	err = vc.AddLinkedDataProof(signingCtx)
+	if len(signOpts.Nonce) == 0 {
+		err = vc.AddLinkedDataProof(signingCtx)
+	} else {
+		vc.Proofs, err = addLinkedDataProofWithNonce(signingCtx, signOpts.Nonce, vc)
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign vc: %w", err)
 	}
 
+	addProofProperties(vc.Proofs, signOpts.ProofProperties)
+
 	return vc, nil
 }
 
@@ -222,48 +292,155 @@ func (c *Crypto) SignPresentation(profile *vcprofile.HolderProfile, vp *verifiab
 		signingCtx.Purpose = Authentication
 	}
 
-	err = vp.AddLinkedDataProof(signingCtx)
+	if err = ValidateProofProperties(signOpts.ProofProperties); err != nil {
+		return nil, err
+	}
+
+	if len(signOpts.Nonce) == 0 {
+		err = vp.AddLinkedDataProof(signingCtx)
+	} else {
+		vp.Proofs, err = addLinkedDataProofWithNonce(signingCtx, signOpts.Nonce, vp)
+	}
+
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign vc: %w", err)
 	}
 
+	addProofProperties(vp.Proofs, signOpts.ProofProperties)
+
 	return vp, nil
 }
 
-func (c *Crypto) getLinkedDataProofContext(creator, signatureType, proofPurpose string, // nolint: lll,gocyclo
-	signRep verifiable.SignatureRepresentation, opts *signingOpts) (*verifiable.LinkedDataProofContext, error) {
-	s, method, err := c.getSigner(creator, opts)
+// addLinkedDataProofWithNonce adds the proof of the signing context with the nonce to the proofs of the credential
+// or presentation, and returns its proofs. The linked data proof context of the verifiable package has no nonce, the
+// document is signed with the context of the signer.
+func addLinkedDataProofWithNonce(signingCtx *verifiable.LinkedDataProofContext, nonce []byte,
+	doc json.Marshaler) ([]verifiable.Proof, error) {
+	docBytes, err := doc.MarshalJSON()
 	if err != nil {
 		return nil, err
 	}
 
-	if opts.Purpose != "" {
-		proofPurpose = opts.Purpose
+	signedBytes, err := ariessigner.New(signingCtx.Suite).Sign(&ariessigner.Context{
+		SignatureType:           signingCtx.SignatureType,
+		SignatureRepresentation: proof.SignatureRepresentation(signingCtx.SignatureRepresentation),
+		Created:                 signingCtx.Created,
+		Domain:                  signingCtx.Domain,
+		Nonce:                   nonce,
+		VerificationMethod:      signingCtx.VerificationMethod,
+		Challenge:               signingCtx.Challenge,
+		Purpose:                 signingCtx.Purpose,
+	}, docBytes)
+	if err != nil {
+		return nil, fmt.Errorf("add linked data proof: %w", err)
+	}
+
+	var signed struct {
+		Proof json.RawMessage `json:"proof,omitempty"`
+	}
+
+	if err = json.Unmarshal(signedBytes, &signed); err != nil {
+		return nil, err
 	}
 
+	// the proof is a single proof, or a list of proofs if the document was already signed
+	var singleProof verifiable.Proof
+	if err = json.Unmarshal(signed.Proof, &singleProof); err == nil {
+		return []verifiable.Proof{singleProof}, nil
+	}
+
+	var proofs []verifiable.Proof
+	if err = json.Unmarshal(signed.Proof, &proofs); err != nil {
+		return nil, fmt.Errorf("invalid proof of the signed document: %w", err)
+	}
+
+	return proofs, nil
+}
+
+// addProofProperties adds the custom properties to the last proof, the one just created
+func addProofProperties(proofs []verifiable.Proof, properties map[string]interface{}) {
+	if len(proofs) == 0 {
+		return
+	}
+
+	for name, value := range properties {
+		proofs[len(proofs)-1][name] = value
+	}
+}
+
+// ValidateSigningKey validates the verification method a credential is signed with by the profile and the options
+// is listed under the proof purpose (assertionMethod by default) in the resolved DID document of its DID, so that a
+// request failing to sign can be rejected before the credential is issued. The error wraps ErrInvalidProofPurpose
+// if the verification method isn't listed under the proof purpose.
+func (c *Crypto) ValidateSigningKey(dataProfile *vcprofile.DataProfile, opts ...SigningOpts) error {
+	signOpts := &signingOpts{}
+	// apply opts
+	for _, opt := range opts {
+		opt(signOpts)
+	}
+
+	method := dataProfile.Creator
+	if signOpts.VerificationMethod != "" {
+		method = signOpts.VerificationMethod
+	}
+
+	proofPurpose := AssertionMethod
+	if signOpts.Purpose != "" {
+		proofPurpose = signOpts.Purpose
+	}
+
+	return c.validateProofPurpose(proofPurpose, method)
+}
+
+// validateProofPurpose resolves the DID of the verification method to validate the method is listed under the
+// proof purpose
+func (c *Crypto) validateProofPurpose(proofPurpose, method string) error {
 	didID, err := diddoc.GetDIDFromVerificationMethod(method)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	didDoc, err := c.vdri.Resolve(didID)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to resolve DID %s: %w", didID, err)
+	}
+
+	if err = ValidateProofPurpose(proofPurpose, method, didDoc); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidProofPurpose, err.Error())
 	}
 
-	err = ValidateProofPurpose(proofPurpose, method, didDoc)
+	return nil
+}
+
+// Sign signs the data with the key of the verification method (didID#keyID), e.g. to sign the capability
+// invocations of the EDV requests of a profile
+func (c *Crypto) Sign(verificationMethod string, data []byte) ([]byte, error) {
+	s, err := newKMSSigner(c.keyManager, c.crypto, verificationMethod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the signer of %s: %w", verificationMethod, err)
+	}
+
+	return s.Sign(data)
+}
+
+func (c *Crypto) getLinkedDataProofContext(creator, signatureType, proofPurpose string, // nolint: lll,gocyclo
+	signRep verifiable.SignatureRepresentation, opts *signingOpts) (*verifiable.LinkedDataProofContext, error) {
+	s, method, err := c.getSigner(creator, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	var signatureSuite ariessigner.SignatureSuite
+	if opts.Purpose != "" {
+		proofPurpose = opts.Purpose
+	}
 
-	switch signatureType {
-	case Ed25519Signature2018:
-		signatureSuite = ed25519signature2018.New(suite.WithSigner(s))
-	case JSONWebSignature2020:
-		signatureSuite = jsonwebsignature2020.New(suite.WithSigner(s))
-	default:
+	err = c.validateProofPurpose(proofPurpose, method)
+	if err != nil {
+		return nil, err
+	}
+
+	signatureSuite, ok := GetSignatureSuite(signatureType)
+	if !ok {
 		return nil, fmt.Errorf("signature type unsupported %s", signatureType)
 	}
 
@@ -278,7 +455,7 @@ func (c *Crypto) getLinkedDataProofContext(creator, signatureType, proofPurpose
 		VerificationMethod:      method,
 		SignatureRepresentation: signRep,
 		SignatureType:           signatureType,
-		Suite:                   signatureSuite,
+		Suite:                   signatureSuite.Signer(s),
 		Purpose:                 opts.Purpose,
 		Created:                 opts.Created,
 		Challenge:               opts.Challenge,
@@ -290,7 +467,7 @@ func (c *Crypto) getLinkedDataProofContext(creator, signatureType, proofPurpose
 
 // getSigner returns signer and verification method based on profile and signing opts
 // verificationMethod from opts takes priority to create signer and verification method
-func (c *Crypto) getSigner(creator string, opts *signingOpts) (signer, string, error) { // nolint: lll
+func (c *Crypto) getSigner(creator string, opts *signingOpts) (Signer, string, error) { // nolint: lll
 	verificationMethod := creator
 	if opts.VerificationMethod != "" {
 		verificationMethod = opts.VerificationMethod
@@ -9,6 +9,8 @@ package crypto
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -35,6 +37,74 @@ func TestCrypto_SignCredential(t *testing.T) {
 		require.Equal(t, 1, len(signedVC.Proofs))
 	})
 
+	t.Run("test proof set", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")},
+		)
+
+		signedVC, err := c.SignCredential(
+			getTestIssuerProfile(), &verifiable.Credential{ID: "http://example.edu/credentials/1872"})
+		require.NoError(t, err)
+
+		signedVC, err = c.SignCredential(getTestIssuerProfile(), signedVC,
+			WithSignatureType(JSONWebSignature2020), WithVerificationMethod("did:trustbloc:abc#key1"))
+		require.NoError(t, err)
+		require.Equal(t, 2, len(signedVC.Proofs))
+		require.Equal(t, Ed25519Signature2018, signedVC.Proofs[0]["type"])
+		require.Equal(t, JSONWebSignature2020, signedVC.Proofs[1]["type"])
+		require.Equal(t, "did:trustbloc:abc#key1", signedVC.Proofs[1]["verificationMethod"])
+	})
+
+	t.Run("test nonce and proof properties", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")},
+		)
+
+		capabilityChain := []interface{}{"https://example.com/zcaps/1"}
+
+		signedVC, err := c.SignCredential(getTestIssuerProfile(),
+			&verifiable.Credential{ID: "http://example.edu/credentials/1872"}, WithNonce([]byte("nonce")),
+			WithProofProperties(map[string]interface{}{"capabilityChain": capabilityChain}))
+		require.NoError(t, err)
+		require.Len(t, signedVC.Proofs, 1)
+		require.Equal(t, base64.RawURLEncoding.EncodeToString([]byte("nonce")), signedVC.Proofs[0]["nonce"])
+		require.Equal(t, capabilityChain, signedVC.Proofs[0]["capabilityChain"])
+		require.Equal(t, AssertionMethod, signedVC.Proofs[0]["proofPurpose"])
+
+		// the nonce proof is added to the proof set
+		signedVC, err = c.SignCredential(getTestIssuerProfile(), signedVC, WithNonce([]byte("nonce2")),
+			WithSignatureType(JSONWebSignature2020), WithChallenge("challenge"))
+		require.NoError(t, err)
+		require.Len(t, signedVC.Proofs, 2)
+		require.Equal(t, capabilityChain, signedVC.Proofs[0]["capabilityChain"])
+		require.Equal(t, base64.RawURLEncoding.EncodeToString([]byte("nonce2")), signedVC.Proofs[1]["nonce"])
+		require.Equal(t, "challenge", signedVC.Proofs[1]["challenge"])
+		require.NotContains(t, signedVC.Proofs[1], "capabilityChain")
+	})
+
+	t.Run("test reserved proof property", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")},
+		)
+
+		signedVC, err := c.SignCredential(getTestIssuerProfile(),
+			&verifiable.Credential{ID: "http://example.edu/credentials/1872"},
+			WithProofProperties(map[string]interface{}{"jws": "forged"}))
+		require.EqualError(t, err, "proof property jws is reserved")
+		require.Nil(t, signedVC)
+	})
+
+	t.Run("test nonce signing error", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{SignErr: errors.New("sign error")},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")},
+		)
+
+		_, err := c.SignCredential(getTestIssuerProfile(),
+			&verifiable.Credential{ID: "http://example.edu/credentials/1872"}, WithNonce([]byte("nonce")))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "sign error")
+	})
+
 	t.Run("test successful sign credential using opts", func(t *testing.T) {
 		prepareTestCreated := func(y, m, d int) *time.Time {
 			c := time.Now().AddDate(y, m, d)
@@ -283,6 +353,22 @@ func TestSignPresentation(t *testing.T) {
 		require.Equal(t, 1, len(signedVP.Proofs))
 	})
 
+	t.Run("sign presentation - nonce", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")},
+		)
+
+		signedVP, err := c.SignPresentation(getTestHolderProfile(),
+			&verifiable.Presentation{ID: "http://example.edu/presentation/1872"},
+			WithNonce([]byte("nonce")), WithProofProperties(map[string]interface{}{"capability": "urn:zcap:1"}),
+		)
+		require.NoError(t, err)
+		require.Len(t, signedVP.Proofs, 1)
+		require.Equal(t, base64.RawURLEncoding.EncodeToString([]byte("nonce")), signedVP.Proofs[0]["nonce"])
+		require.Equal(t, Authentication, signedVP.Proofs[0]["proofPurpose"])
+		require.Equal(t, "urn:zcap:1", signedVP.Proofs[0]["capability"])
+	})
+
 	t.Run("sign presentation - fail", func(t *testing.T) {
 		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
 			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")},
@@ -298,6 +384,82 @@ func TestSignPresentation(t *testing.T) {
 	})
 }
 
+func TestCrypto_Sign(t *testing.T) {
+	t.Run("sign - success", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{SignValue: []byte("signature")}, &vdrimock.MockVDRIRegistry{})
+
+		signature, err := c.Sign("did:trustbloc:abc#key1", []byte("data"))
+		require.NoError(t, err)
+		require.Equal(t, []byte("signature"), signature)
+	})
+
+	t.Run("sign - invalid verification method", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{}, &vdrimock.MockVDRIRegistry{})
+
+		signature, err := c.Sign("did:trustbloc:abc", []byte("data"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get the signer of did:trustbloc:abc")
+		require.Nil(t, signature)
+	})
+
+	t.Run("sign - key not found", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{GetKeyErr: errors.New("key not found")}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{})
+
+		_, err := c.Sign("did:trustbloc:abc#key1", []byte("data"))
+		require.EqualError(t, err, "failed to get the signer of did:trustbloc:abc#key1: key not found")
+	})
+
+	t.Run("sign - signing error", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{SignErr: errors.New("sign error")},
+			&vdrimock.MockVDRIRegistry{})
+
+		_, err := c.Sign("did:trustbloc:abc#key1", []byte("data"))
+		require.EqualError(t, err, "sign error")
+	})
+}
+
+func TestCrypto_ValidateSigningKey(t *testing.T) {
+	t.Run("validate signing key - success", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")})
+
+		require.NoError(t, c.ValidateSigningKey(getTestIssuerProfile()))
+		require.NoError(t, c.ValidateSigningKey(getTestIssuerProfile(), WithPurpose(Authentication)))
+	})
+
+	t.Run("validate signing key - not listed under the proof purpose", func(t *testing.T) {
+		didDoc := createDIDDoc("did:trustbloc:abc")
+		didDoc.Authentication = nil
+
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{}, &vdrimock.MockVDRIRegistry{ResolveValue: didDoc})
+
+		err := c.ValidateSigningKey(getTestIssuerProfile(), WithPurpose(Authentication))
+		require.True(t, errors.Is(err, ErrInvalidProofPurpose))
+		require.Contains(t, err.Error(), "authentication")
+
+		err = c.ValidateSigningKey(getTestIssuerProfile(), WithVerificationMethod("did:trustbloc:abc#key2"))
+		require.True(t, errors.Is(err, ErrInvalidProofPurpose))
+		require.Contains(t, err.Error(), "did:trustbloc:abc#key2")
+	})
+
+	t.Run("validate signing key - invalid verification method", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{}, &vdrimock.MockVDRIRegistry{})
+
+		err := c.ValidateSigningKey(getTestIssuerProfile(), WithVerificationMethod("did:trustbloc:abc"))
+		require.Error(t, err)
+		require.False(t, errors.Is(err, ErrInvalidProofPurpose))
+	})
+
+	t.Run("validate signing key - DID resolution error", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveErr: errors.New("resolve error")})
+
+		err := c.ValidateSigningKey(getTestIssuerProfile())
+		require.EqualError(t, err, "failed to resolve DID did:trustbloc:abc: resolve error")
+	})
+}
+
 func getTestIssuerProfile() *vcprofile.DataProfile {
 	return &vcprofile.DataProfile{
 		Name:          "test",
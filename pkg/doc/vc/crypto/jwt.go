@@ -0,0 +1,166 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jwt"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/sdjwt"
+	"github.com/trustbloc/edge-service/pkg/internal/common/diddoc"
+)
+
+// PS256 is the JWS algorithm of the JWT credentials: RSASSA-PSS with SHA-256, signed by the RSA keys of the profiles
+const PS256 = "PS256"
+
+// SignCredentialJWT signs the vc as a JWT credential (the vc being its "vc" claim) with the PS256 algorithm, the key
+// ID of the JWT header being the verification method. Only the verification method and purpose signing options
+// apply, the verification method must be a RSA key.
+func (c *Crypto) SignCredentialJWT(dataProfile *vcprofile.DataProfile, vc *verifiable.Credential,
+	opts ...SigningOpts) (string, error) {
+	signOpts := &signingOpts{}
+	// apply opts
+	for _, opt := range opts {
+		opt(signOpts)
+	}
+
+	s, method, err := c.getSigner(dataProfile.Creator, signOpts)
+	if err != nil {
+		return "", err
+	}
+
+	proofPurpose := AssertionMethod
+	if signOpts.Purpose != "" {
+		proofPurpose = signOpts.Purpose
+	}
+
+	if err = c.validateProofPurpose(proofPurpose, method); err != nil {
+		return "", err
+	}
+
+	// the JWT claims of a vc are created from its issuance date
+	if vc.Issued == nil {
+		return "", errors.New("failed to create JWT claims: missing issuance date")
+	}
+
+	claims, err := vc.JWTClaims(false)
+	if err != nil {
+		return "", fmt.Errorf("failed to create JWT claims: %w", err)
+	}
+
+	token, err := jwt.NewSigned(claims, jose.Headers{jose.HeaderKeyID: method}, &jwtSigner{Signer: s, alg: PS256})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+
+	return token.Serialize(false)
+}
+
+// SignCredentialSDJWT signs the vc as a SD-JWT credential, the claims of its subject being selectively disclosable,
+// bound to the holder key of the confirmation if set. The JWS algorithm is the algorithm of the key of the
+// verification method, see sdjwt.Algorithm. Only the verification method and purpose signing options apply.
+func (c *Crypto) SignCredentialSDJWT(dataProfile *vcprofile.DataProfile, vc *verifiable.Credential,
+	confirmation *sdjwt.Confirmation, opts ...SigningOpts) (string, error) {
+	signOpts := &signingOpts{}
+	// apply opts
+	for _, opt := range opts {
+		opt(signOpts)
+	}
+
+	s, method, err := c.getSigner(dataProfile.Creator, signOpts)
+	if err != nil {
+		return "", err
+	}
+
+	proofPurpose := AssertionMethod
+	if signOpts.Purpose != "" {
+		proofPurpose = signOpts.Purpose
+	}
+
+	if err = c.validateProofPurpose(proofPurpose, method); err != nil {
+		return "", err
+	}
+
+	alg, err := c.sdJWTAlgorithm(method)
+	if err != nil {
+		return "", err
+	}
+
+	return sdjwt.Issue(vc, &sdjwt.Issuance{VerificationMethod: method, Algorithm: alg, Signer: s,
+		Confirmation: confirmation})
+}
+
+// PresentSDJWT returns the presentation of the SD-JWT credential disclosing the claims of the subject with the names.
+// The presentation of a SD-JWT bound to a holder key ends with a key binding JWT for the audience and the nonce,
+// signed by the key of the holder profile with the authentication purpose. Only the verification method signing
+// option applies.
+func (c *Crypto) PresentSDJWT(profile *vcprofile.HolderProfile, sdJWT string, names []string,
+	audience, nonce string, opts ...SigningOpts) (string, error) {
+	credential, err := sdjwt.Parse(sdJWT)
+	if err != nil {
+		return "", err
+	}
+
+	if credential.Confirmation == nil {
+		return sdjwt.Present(sdJWT, names, nil)
+	}
+
+	signOpts := &signingOpts{}
+	// apply opts
+	for _, opt := range opts {
+		opt(signOpts)
+	}
+
+	s, method, err := c.getSigner(profile.Creator, signOpts)
+	if err != nil {
+		return "", err
+	}
+
+	if err = c.validateProofPurpose(Authentication, method); err != nil {
+		return "", err
+	}
+
+	alg, err := c.sdJWTAlgorithm(method)
+	if err != nil {
+		return "", err
+	}
+
+	return sdjwt.Present(sdJWT, names, &sdjwt.KeyBinding{Audience: audience, Nonce: nonce, Algorithm: alg,
+		Signer: s})
+}
+
+// sdJWTAlgorithm returns the JWS algorithm of the key of the verification method
+func (c *Crypto) sdJWTAlgorithm(verificationMethod string) (string, error) {
+	didID, err := diddoc.GetDIDFromVerificationMethod(verificationMethod)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := verifiable.NewDIDKeyResolver(c.vdri).PublicKeyFetcher()(didID,
+		strings.TrimPrefix(verificationMethod, didID))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the key of %s: %w", verificationMethod, err)
+	}
+
+	return sdjwt.Algorithm(key)
+}
+
+// jwtSigner signs the JWT with the algorithm of its header
+type jwtSigner struct {
+	Signer
+	alg string
+}
+
+func (s *jwtSigner) Headers() jose.Headers {
+	return jose.Headers{jose.HeaderAlgorithm: s.alg}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/sdjwt"
+	"github.com/trustbloc/edge-service/pkg/kms/signingkms"
+)
+
+func TestCrypto_SignCredentialJWT(t *testing.T) {
+	newCredential := func() *verifiable.Credential {
+		return &verifiable.Credential{
+			Context: []string{"https://www.w3.org/2018/credentials/v1"},
+			ID:      "http://example.edu/credentials/1872",
+			Types:   []string{"VerifiableCredential"},
+			Issuer:  verifiable.Issuer{ID: "did:trustbloc:abc"},
+			Issued:  &util.TimeWithTrailingZeroMsec{Time: time.Now().UTC()},
+			Subject: map[string]interface{}{"id": "did:example:holder"},
+		}
+	}
+
+	t.Run("test success", func(t *testing.T) {
+		wrappingKey, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		km, err := signingkms.New(&mockkms.KeyManager{CreateKeyValue: wrappingKey}, mem.NewProvider())
+		require.NoError(t, err)
+
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		_, _, err = km.ImportPrivateKey(rsaKey, signingkms.RSAKeyType, kms.WithKeyID("key1"))
+		require.NoError(t, err)
+
+		c := New(km, signingkms.NewCrypto(&cryptomock.Crypto{}),
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")})
+
+		jwtVC, err := c.SignCredentialJWT(getTestIssuerProfile(), newCredential())
+		require.NoError(t, err)
+
+		parts := strings.Split(jwtVC, ".")
+		require.Len(t, parts, 3)
+
+		headerBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+		require.NoError(t, err)
+
+		var header map[string]interface{}
+
+		require.NoError(t, json.Unmarshal(headerBytes, &header))
+		require.Equal(t, PS256, header["alg"])
+		require.Equal(t, "did:trustbloc:abc#key1", header["kid"])
+
+		claimsBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(t, err)
+
+		var claims map[string]interface{}
+
+		require.NoError(t, json.Unmarshal(claimsBytes, &claims))
+		require.Equal(t, "did:trustbloc:abc", claims["iss"])
+		require.Equal(t, "did:example:holder", claims["sub"])
+		require.NotNil(t, claims["vc"])
+
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		require.NoError(t, rsa.VerifyPSS(&rsaKey.PublicKey, crypto.SHA256, digest[:], signature, nil))
+	})
+
+	t.Run("test error - invalid proof purpose", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")})
+
+		_, err := c.SignCredentialJWT(getTestIssuerProfile(), newCredential(), WithPurpose("invalid"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "proof purpose invalid not supported")
+	})
+
+	t.Run("test error - key not found", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{GetKeyErr: errors.New("key not found")}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")})
+
+		_, err := c.SignCredentialJWT(getTestIssuerProfile(), newCredential())
+		require.EqualError(t, err, "key not found")
+	})
+
+	t.Run("test error - invalid credential", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")})
+
+		vc := newCredential()
+		vc.Issued = nil
+
+		_, err := c.SignCredentialJWT(getTestIssuerProfile(), vc)
+		require.EqualError(t, err, "failed to create JWT claims: missing issuance date")
+
+		vc = newCredential()
+		vc.Subject = nil
+
+		_, err = c.SignCredentialJWT(getTestIssuerProfile(), vc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create JWT claims")
+	})
+
+	t.Run("test error - signing error", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{SignErr: errors.New("sign error")},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")})
+
+		_, err := c.SignCredentialJWT(getTestIssuerProfile(), newCredential())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "sign error")
+	})
+}
+
+func TestCrypto_SignCredentialSDJWT(t *testing.T) {
+	newCredential := func() *verifiable.Credential {
+		return &verifiable.Credential{
+			Context: []string{"https://www.w3.org/2018/credentials/v1"},
+			ID:      "http://example.edu/credentials/1872",
+			Types:   []string{"VerifiableCredential"},
+			Issuer:  verifiable.Issuer{ID: "did:trustbloc:abc"},
+			Issued:  &util.TimeWithTrailingZeroMsec{Time: time.Now().UTC()},
+			Subject: map[string]interface{}{"id": "did:example:holder", "name": "Jayden Doe"},
+		}
+	}
+
+	t.Run("test success", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{SignValue: []byte("signature")},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")})
+
+		sdJWT, err := c.SignCredentialSDJWT(getTestIssuerProfile(), newCredential(),
+			&sdjwt.Confirmation{KeyID: "did:example:holder#key-1"})
+		require.NoError(t, err)
+
+		headerBytes, err := base64.RawURLEncoding.DecodeString(strings.Split(sdJWT, ".")[0])
+		require.NoError(t, err)
+
+		var header map[string]interface{}
+
+		require.NoError(t, json.Unmarshal(headerBytes, &header))
+		require.Equal(t, sdjwt.AlgorithmEdDSA, header["alg"])
+		require.Equal(t, "did:trustbloc:abc#key1", header["kid"])
+
+		credential, err := sdjwt.Parse(sdJWT)
+		require.NoError(t, err)
+		require.Equal(t, "did:trustbloc:abc", credential.Issuer)
+		require.Equal(t, []string{"name"}, credential.Disclosed)
+	})
+
+	t.Run("test error - invalid proof purpose", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")})
+
+		_, err := c.SignCredentialSDJWT(getTestIssuerProfile(), newCredential(), nil, WithPurpose("invalid"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "proof purpose invalid not supported")
+	})
+
+	t.Run("test error - key not found", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{GetKeyErr: errors.New("key not found")}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")})
+
+		_, err := c.SignCredentialSDJWT(getTestIssuerProfile(), newCredential(), nil)
+		require.EqualError(t, err, "key not found")
+	})
+
+	t.Run("test error - signing error", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{SignErr: errors.New("sign error")},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")})
+
+		_, err := c.SignCredentialSDJWT(getTestIssuerProfile(), newCredential(), nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "sign error")
+	})
+}
+
+func TestCrypto_PresentSDJWT(t *testing.T) {
+	issuer := New(&mockkms.KeyManager{}, &cryptomock.Crypto{SignValue: []byte("signature")},
+		&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")})
+
+	credential := &verifiable.Credential{
+		Context: []string{"https://www.w3.org/2018/credentials/v1"},
+		ID:      "http://example.edu/credentials/1872",
+		Types:   []string{"VerifiableCredential"},
+		Issuer:  verifiable.Issuer{ID: "did:trustbloc:abc"},
+		Issued:  &util.TimeWithTrailingZeroMsec{Time: time.Now().UTC()},
+		Subject: map[string]interface{}{"id": "did:trustbloc:abc", "name": "Jayden Doe", "age": 21},
+	}
+
+	bound, err := issuer.SignCredentialSDJWT(getTestIssuerProfile(), credential,
+		&sdjwt.Confirmation{KeyID: "did:trustbloc:abc#key1"})
+	require.NoError(t, err)
+
+	unbound, err := issuer.SignCredentialSDJWT(getTestIssuerProfile(), credential, nil)
+	require.NoError(t, err)
+
+	t.Run("test bound SD-JWT", func(t *testing.T) {
+		presentation, err := issuer.PresentSDJWT(getTestHolderProfile(), bound, []string{"name"},
+			"https://verifier.example.com", "nonce")
+		require.NoError(t, err)
+
+		parts := strings.Split(presentation, "~")
+		require.Len(t, parts, 3)
+
+		headerBytes, err := base64.RawURLEncoding.DecodeString(strings.Split(parts[2], ".")[0])
+		require.NoError(t, err)
+		require.JSONEq(t, `{"alg":"EdDSA","typ":"kb+jwt"}`, string(headerBytes))
+
+		parsed, err := sdjwt.Parse(presentation)
+		require.NoError(t, err)
+		require.True(t, parsed.KeyBound)
+		require.Equal(t, []string{"name"}, parsed.Disclosed)
+	})
+
+	t.Run("test unbound SD-JWT", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{GetKeyErr: errors.New("key not found")}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{})
+
+		presentation, err := c.PresentSDJWT(getTestHolderProfile(), unbound, []string{"age"}, "", "")
+		require.NoError(t, err)
+		require.True(t, strings.HasSuffix(presentation, "~"))
+
+		parsed, err := sdjwt.Parse(presentation)
+		require.NoError(t, err)
+		require.False(t, parsed.KeyBound)
+		require.Equal(t, []string{"age"}, parsed.Disclosed)
+	})
+
+	t.Run("test error - not a SD-JWT", func(t *testing.T) {
+		_, err := issuer.PresentSDJWT(getTestHolderProfile(), "abc", nil, "", "")
+		require.EqualError(t, err, "not a SD-JWT: missing ~ separator")
+	})
+
+	t.Run("test error - key not found", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{GetKeyErr: errors.New("key not found")}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")})
+
+		_, err := c.PresentSDJWT(getTestHolderProfile(), bound, nil, "", "")
+		require.EqualError(t, err, "key not found")
+	})
+
+	t.Run("test error - invalid proof purpose", func(t *testing.T) {
+		_, err := issuer.PresentSDJWT(getTestHolderProfile(), bound, nil, "", "",
+			WithVerificationMethod("did:trustbloc:abc#key2"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "unable to find matching authentication key IDs")
+	})
+
+	t.Run("test error - signing error", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{SignErr: errors.New("sign error")},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")})
+
+		_, err := c.PresentSDJWT(getTestHolderProfile(), bound, nil, "", "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "sign error")
+	})
+}
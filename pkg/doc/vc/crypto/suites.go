@@ -0,0 +1,186 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	ariessigner "github.com/hyperledger/aries-framework-go/pkg/doc/signature/signer"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ecdsasecp256k1signature2019"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/jsonwebsignature2020"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+)
+
+// JSONWebSignature2020Context is the JSON-LD context of the JsonWebSignature2020 proofs
+const JSONWebSignature2020Context = "https://trustbloc.github.io/context/vc/credentials-v1.jsonld"
+
+// Signer signs the documents with the key of a verification method
+type Signer interface {
+	// Sign will sign document and return signature
+	Sign(data []byte) ([]byte, error)
+}
+
+// SignatureSuite is a linked data signature suite the credentials and presentations are signed and verified with.
+// The Ed25519Signature2018, JsonWebSignature2020 and EcdsaSecp256k1Signature2019 suites are built in, other suites
+// are plugged in with RegisterSignatureSuite.
+type SignatureSuite interface {
+	// Type is the signature type of the proofs of the suite, e.g. Ed25519Signature2018.
+	Type() string
+	// KeyType is the type of the verification methods of the suite in the DID documents, e.g.
+	// Ed25519VerificationKey2018.
+	KeyType() string
+	// Context is the JSON-LD context the signed documents must have for the proofs of the suite, empty if none.
+	Context() string
+	// Signer returns the suite signing the proofs with the signer.
+	Signer(s Signer) ariessigner.SignatureSuite
+	// Verifier returns the suite verifying the proofs with the public keys of their verification methods.
+	Verifier() verifier.SignatureSuite
+}
+
+// suiteRegistry is the registry of the signature suites, by signature type
+type suiteRegistry struct {
+	mutex  sync.RWMutex
+	suites map[string]SignatureSuite
+}
+
+// nolint: gochecknoglobals
+var signatureSuites = &suiteRegistry{suites: map[string]SignatureSuite{
+	Ed25519Signature2018: &builtinSuite{
+		signatureType: Ed25519Signature2018,
+		keyType:       Ed25519VerificationKey2018,
+		signer: func(s Signer) ariessigner.SignatureSuite {
+			return ed25519signature2018.New(suite.WithSigner(s))
+		},
+		verifier: func() verifier.SignatureSuite {
+			return ed25519signature2018.New(suite.WithVerifier(ed25519signature2018.NewPublicKeyVerifier()))
+		},
+	},
+	JSONWebSignature2020: &builtinSuite{
+		signatureType: JSONWebSignature2020,
+		keyType:       JwsVerificationKey2020,
+		context:       JSONWebSignature2020Context,
+		signer: func(s Signer) ariessigner.SignatureSuite {
+			return jsonwebsignature2020.New(suite.WithSigner(s))
+		},
+		verifier: func() verifier.SignatureSuite {
+			return jsonwebsignature2020.New(suite.WithVerifier(jsonwebsignature2020.NewPublicKeyVerifier()))
+		},
+	},
+	EcdsaSecp256k1Signature2019: &builtinSuite{
+		signatureType: EcdsaSecp256k1Signature2019,
+		keyType:       EcdsaSecp256k1VerificationKey2019,
+		signer: func(s Signer) ariessigner.SignatureSuite {
+			return ecdsasecp256k1signature2019.New(suite.WithSigner(s))
+		},
+		verifier: func() verifier.SignatureSuite {
+			return ecdsasecp256k1signature2019.New(
+				suite.WithVerifier(ecdsasecp256k1signature2019.NewPublicKeyVerifier()))
+		},
+	},
+}}
+
+// RegisterSignatureSuite registers a signature suite, which the profiles can then use as signature type. The suites
+// are registered once, before the service starts; a signature type can't be registered twice.
+func RegisterSignatureSuite(s SignatureSuite) error {
+	if s.Type() == "" {
+		return errors.New("missing signature type of the signature suite")
+	}
+
+	signatureSuites.mutex.Lock()
+	defer signatureSuites.mutex.Unlock()
+
+	if _, ok := signatureSuites.suites[s.Type()]; ok {
+		return fmt.Errorf("signature suite %s already registered", s.Type())
+	}
+
+	signatureSuites.suites[s.Type()] = s
+
+	return nil
+}
+
+// GetSignatureSuite returns the registered signature suite of the signature type
+func GetSignatureSuite(signatureType string) (SignatureSuite, bool) {
+	signatureSuites.mutex.RLock()
+	defer signatureSuites.mutex.RUnlock()
+
+	s, ok := signatureSuites.suites[signatureType]
+
+	return s, ok
+}
+
+// SignatureTypes returns the signature types of the registered signature suites, sorted
+func SignatureTypes() []string {
+	signatureSuites.mutex.RLock()
+	defer signatureSuites.mutex.RUnlock()
+
+	types := make([]string, 0, len(signatureSuites.suites))
+
+	for signatureType := range signatureSuites.suites {
+		types = append(types, signatureType)
+	}
+
+	sort.Strings(types)
+
+	return types
+}
+
+// SignatureContext returns the JSON-LD context required by the signature type, empty if none or if the signature
+// type isn't registered
+func SignatureContext(signatureType string) string {
+	if s, ok := GetSignatureSuite(signatureType); ok {
+		return s.Context()
+	}
+
+	return ""
+}
+
+// VerifierSuites returns the suites verifying the proofs of the registered signature suites
+func VerifierSuites() []verifier.SignatureSuite {
+	var suites []verifier.SignatureSuite
+
+	for _, signatureType := range SignatureTypes() {
+		if s, ok := GetSignatureSuite(signatureType); ok {
+			suites = append(suites, s.Verifier())
+		}
+	}
+
+	return suites
+}
+
+// builtinSuite is a signature suite of the aries framework
+type builtinSuite struct {
+	signatureType string
+	keyType       string
+	context       string
+	signer        func(s Signer) ariessigner.SignatureSuite
+	verifier      func() verifier.SignatureSuite
+}
+
+func (s *builtinSuite) Type() string {
+	return s.signatureType
+}
+
+func (s *builtinSuite) KeyType() string {
+	return s.keyType
+}
+
+func (s *builtinSuite) Context() string {
+	return s.context
+}
+
+func (s *builtinSuite) Signer(signer Signer) ariessigner.SignatureSuite {
+	return s.signer(signer)
+}
+
+func (s *builtinSuite) Verifier() verifier.SignatureSuite {
+	return s.verifier()
+}
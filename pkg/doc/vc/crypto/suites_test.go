@@ -0,0 +1,120 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crypto
+
+import (
+	"testing"
+
+	ariessigner "github.com/hyperledger/aries-framework-go/pkg/doc/signature/signer"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterSignatureSuite(t *testing.T) {
+	t.Run("test built-in suites", func(t *testing.T) {
+		require.Equal(t, []string{EcdsaSecp256k1Signature2019, Ed25519Signature2018, JSONWebSignature2020},
+			SignatureTypes())
+
+		s, ok := GetSignatureSuite(JSONWebSignature2020)
+		require.True(t, ok)
+		require.Equal(t, JwsVerificationKey2020, s.KeyType())
+		require.Equal(t, JSONWebSignature2020Context, SignatureContext(JSONWebSignature2020))
+		require.Empty(t, SignatureContext(Ed25519Signature2018))
+
+		_, ok = GetSignatureSuite("unknown")
+		require.False(t, ok)
+		require.Empty(t, SignatureContext("unknown"))
+		require.Len(t, VerifierSuites(), 3)
+
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")},
+		)
+
+		signedVC, err := c.SignCredential(getTestIssuerProfile(),
+			&verifiable.Credential{ID: "http://example.edu/credentials/1872"},
+			WithSignatureType(EcdsaSecp256k1Signature2019))
+		require.NoError(t, err)
+		require.Len(t, signedVC.Proofs, 1)
+		require.Equal(t, EcdsaSecp256k1Signature2019, signedVC.Proofs[0]["type"])
+	})
+
+	t.Run("test register suite", func(t *testing.T) {
+		defer unregisterSignatureSuite(testSignature)
+
+		require.NoError(t, RegisterSignatureSuite(newTestSuite()))
+		require.Contains(t, SignatureTypes(), testSignature)
+		require.Len(t, VerifierSuites(), 4)
+
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")},
+		)
+
+		signedVC, err := c.SignCredential(getTestIssuerProfile(),
+			&verifiable.Credential{ID: "http://example.edu/credentials/1872"},
+			WithSignatureType(testSignature))
+		require.NoError(t, err)
+		require.Len(t, signedVC.Proofs, 1)
+		require.Equal(t, testSignature, signedVC.Proofs[0]["type"])
+	})
+
+	t.Run("test register errors", func(t *testing.T) {
+		err := RegisterSignatureSuite(&builtinSuite{})
+		require.EqualError(t, err, "missing signature type of the signature suite")
+
+		err = RegisterSignatureSuite(&builtinSuite{signatureType: Ed25519Signature2018})
+		require.EqualError(t, err, "signature suite Ed25519Signature2018 already registered")
+	})
+
+	t.Run("test unsupported signature type", func(t *testing.T) {
+		c := New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
+			&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:trustbloc:abc")},
+		)
+
+		_, err := c.SignCredential(getTestIssuerProfile(),
+			&verifiable.Credential{ID: "http://example.edu/credentials/1872"},
+			WithSignatureType(testSignature))
+		require.EqualError(t, err, "signature type unsupported TestSignature2020")
+	})
+}
+
+const testSignature = "TestSignature2020"
+
+func newTestSuite() SignatureSuite {
+	return &builtinSuite{
+		signatureType: testSignature,
+		keyType:       Ed25519VerificationKey2018,
+		signer: func(s Signer) ariessigner.SignatureSuite {
+			return &testSuite{Suite: ed25519signature2018.New(suite.WithSigner(s))}
+		},
+		verifier: func() verifier.SignatureSuite {
+			return &testSuite{
+				Suite: ed25519signature2018.New(suite.WithVerifier(ed25519signature2018.NewPublicKeyVerifier()))}
+		},
+	}
+}
+
+// testSuite is an experimental suite signing as Ed25519Signature2018
+type testSuite struct {
+	*ed25519signature2018.Suite
+}
+
+func (s *testSuite) Accept(signatureType string) bool {
+	return signatureType == testSignature
+}
+
+func unregisterSignatureSuite(signatureType string) {
+	signatureSuites.mutex.Lock()
+	defer signatureSuites.mutex.Unlock()
+
+	delete(signatureSuites.suites, signatureType)
+}
@@ -0,0 +1,191 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/trustbloc/edge-core/pkg/storage"
+)
+
+const (
+	exchangeStore = "issuanceexchange"
+
+	// DefaultTTL is how long the exchanges can be completed by the holder
+	DefaultTTL = 15 * time.Minute
+)
+
+// The states of an exchange
+const (
+	// StatePending is the state of a new exchange, the holder hasn't interacted yet
+	StatePending = "pending"
+	// StateActive is the state of an exchange whose presentation request was returned to the holder
+	StateActive = "active"
+	// StateComplete is the state of an exchange whose credential was issued
+	StateComplete = "complete"
+	// StateExpired is the state of an exchange not completed before its expiry
+	StateExpired = "expired"
+)
+
+var (
+	// ErrNotFound is returned when the profile has no exchange with the ID
+	ErrNotFound = errors.New("exchange not found")
+	// ErrClosed is returned when the exchange can't be continued, being complete or expired
+	ErrClosed = errors.New("exchange complete or expired")
+)
+
+// Exchange is a multi-step issuance of a credential: the holder interacts with the exchange to get the presentation
+// request of the issuer, then continues it with its presentation to get the credential
+type Exchange struct {
+	ID      string `json:"exchangeId"`
+	Profile string `json:"profile"`
+	State   string `json:"state"`
+	// Credential is the credential to issue, its subject ID being set to the DID of the holder if missing
+	Credential json.RawMessage `json:"credential"`
+	// Options are the issuance options of the credential
+	Options json.RawMessage `json:"options,omitempty"`
+	// ManifestID is the ID of the credential manifest of the profile describing the credential offered
+	ManifestID string `json:"manifestID,omitempty"`
+	// Challenge is the challenge of the last presentation request returned to the holder
+	Challenge string `json:"challenge,omitempty"`
+	// Holder is the DID authenticated by the holder on completion
+	Holder string `json:"holder,omitempty"`
+	// IssuedCredential is the credential issued on completion
+	IssuedCredential json.RawMessage `json:"issuedCredential,omitempty"`
+	Created          time.Time       `json:"created"`
+	Expires          time.Time       `json:"expires"`
+}
+
+// Store keeps the exchanges of the profiles, which expire after the TTL unless complete.
+//
+// The store mutex serializes the updates of the exchanges, so an instance can't complete an exchange twice.
+type Store struct {
+	store storage.Store
+	ttl   time.Duration
+	mutex sync.Mutex
+	now   func() time.Time
+}
+
+// New returns a new exchange store, the exchanges expiring after the ttl
+func New(provider storage.Provider, ttl time.Duration) (*Store, error) {
+	err := provider.CreateStore(exchangeStore)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := provider.OpenStore(exchangeStore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{store: store, ttl: ttl, now: time.Now}, nil
+}
+
+// Create creates a pending exchange issuing the credential under the profile, the credential being described by the
+// manifest of the profile if the manifest ID is set
+func (s *Store) Create(profile, manifestID string, credential, options json.RawMessage) (*Exchange, error) {
+	now := s.now().UTC()
+
+	exchange := &Exchange{
+		ID:         uuid.New().String(),
+		Profile:    profile,
+		State:      StatePending,
+		Credential: credential,
+		Options:    options,
+		ManifestID: manifestID,
+		Created:    now,
+		Expires:    now.Add(s.ttl),
+	}
+
+	if err := s.put(exchange); err != nil {
+		return nil, err
+	}
+
+	return exchange, nil
+}
+
+// Get returns the exchange of the profile, in the expired state once expired. The error is ErrNotFound if the
+// profile has no exchange with the ID.
+func (s *Store) Get(profile, id string) (*Exchange, error) {
+	exchange, err := s.get(profile, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if exchange.State != StateComplete && !s.now().Before(exchange.Expires) {
+		exchange.State = StateExpired
+	}
+
+	return exchange, nil
+}
+
+// Update updates the exchange of the profile with the function, unless complete or expired. The exchange is saved
+// if the function succeeds, the error being ErrNotFound or ErrClosed if the exchange can't be updated.
+func (s *Store) Update(profile, id string, update func(exchange *Exchange) error) (*Exchange, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	exchange, err := s.Get(profile, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if exchange.State == StateComplete || exchange.State == StateExpired {
+		return nil, ErrClosed
+	}
+
+	if err = update(exchange); err != nil {
+		return nil, err
+	}
+
+	if err = s.put(exchange); err != nil {
+		return nil, err
+	}
+
+	return exchange, nil
+}
+
+func (s *Store) get(profile, id string) (*Exchange, error) {
+	exchangeBytes, err := s.store.Get(id)
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange: %w", err)
+	}
+
+	exchange := &Exchange{}
+
+	if err = json.Unmarshal(exchangeBytes, exchange); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal exchange: %w", err)
+	}
+
+	if exchange.Profile != profile {
+		return nil, ErrNotFound
+	}
+
+	return exchange, nil
+}
+
+func (s *Store) put(exchange *Exchange) error {
+	exchangeBytes, err := json.Marshal(exchange)
+	if err != nil {
+		return fmt.Errorf("failed to marshal exchange: %w", err)
+	}
+
+	if err := s.store.Put(exchange.ID, exchangeBytes); err != nil {
+		return fmt.Errorf("failed to store exchange: %w", err)
+	}
+
+	return nil
+}
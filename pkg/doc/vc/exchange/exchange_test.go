@@ -0,0 +1,164 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		store, err := New(memstore.NewProvider(), DefaultTTL)
+		require.NoError(t, err)
+		require.NotNil(t, store)
+	})
+
+	t.Run("test error from create store", func(t *testing.T) {
+		store, err := New(&mockstore.Provider{ErrCreateStore: fmt.Errorf("error create")}, DefaultTTL)
+		require.EqualError(t, err, "error create")
+		require.Nil(t, store)
+	})
+
+	t.Run("test error from open store", func(t *testing.T) {
+		store, err := New(&mockstore.Provider{ErrOpenStoreHandle: fmt.Errorf("error open")}, DefaultTTL)
+		require.EqualError(t, err, "error open")
+		require.Nil(t, store)
+	})
+}
+
+func TestStore(t *testing.T) {
+	now := time.Date(2020, time.October, 16, 10, 0, 0, 0, time.UTC)
+	credential := json.RawMessage(`{"id":"http://example.edu/credentials/1872"}`)
+
+	newStore := func(t *testing.T) *Store {
+		store, err := New(memstore.NewProvider(), time.Minute)
+		require.NoError(t, err)
+
+		store.now = func() time.Time { return now }
+
+		return store
+	}
+
+	t.Run("test exchange completed once", func(t *testing.T) {
+		store := newStore(t)
+
+		exchange, err := store.Create("issuer", "degree", credential, nil)
+		require.NoError(t, err)
+		require.NotEmpty(t, exchange.ID)
+		require.Equal(t, StatePending, exchange.State)
+		require.Equal(t, "degree", exchange.ManifestID)
+		require.Equal(t, now.Add(time.Minute), exchange.Expires)
+
+		exchange, err = store.Update("issuer", exchange.ID, func(exchange *Exchange) error {
+			exchange.State = StateActive
+			exchange.Challenge = "challenge"
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		exchange, err = store.Update("issuer", exchange.ID, func(exchange *Exchange) error {
+			require.Equal(t, "challenge", exchange.Challenge)
+			exchange.State = StateComplete
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		_, err = store.Update("issuer", exchange.ID, func(exchange *Exchange) error {
+			require.Fail(t, "complete exchange updated")
+
+			return nil
+		})
+		require.True(t, errors.Is(err, ErrClosed))
+
+		// a complete exchange doesn't expire
+		store.now = func() time.Time { return now.Add(time.Hour) }
+
+		exchange, err = store.Get("issuer", exchange.ID)
+		require.NoError(t, err)
+		require.Equal(t, StateComplete, exchange.State)
+		require.JSONEq(t, string(credential), string(exchange.Credential))
+	})
+
+	t.Run("test expired exchange", func(t *testing.T) {
+		store := newStore(t)
+
+		exchange, err := store.Create("issuer", "", credential, nil)
+		require.NoError(t, err)
+
+		store.now = func() time.Time { return now.Add(time.Minute) }
+
+		exchange, err = store.Get("issuer", exchange.ID)
+		require.NoError(t, err)
+		require.Equal(t, StateExpired, exchange.State)
+
+		_, err = store.Update("issuer", exchange.ID, func(exchange *Exchange) error { return nil })
+		require.True(t, errors.Is(err, ErrClosed))
+	})
+
+	t.Run("test exchange not found", func(t *testing.T) {
+		store := newStore(t)
+
+		exchange, err := store.Create("issuer", "", credential, nil)
+		require.NoError(t, err)
+
+		_, err = store.Get("other", exchange.ID)
+		require.True(t, errors.Is(err, ErrNotFound))
+
+		_, err = store.Get("issuer", "unknown")
+		require.True(t, errors.Is(err, ErrNotFound))
+
+		_, err = store.Update("issuer", "unknown", func(exchange *Exchange) error { return nil })
+		require.True(t, errors.Is(err, ErrNotFound))
+	})
+
+	t.Run("test update error", func(t *testing.T) {
+		store := newStore(t)
+
+		exchange, err := store.Create("issuer", "", credential, nil)
+		require.NoError(t, err)
+
+		_, err = store.Update("issuer", exchange.ID, func(exchange *Exchange) error {
+			exchange.State = StateComplete
+
+			return errors.New("update error")
+		})
+		require.EqualError(t, err, "update error")
+
+		// the exchange isn't saved
+		exchange, err = store.Get("issuer", exchange.ID)
+		require.NoError(t, err)
+		require.Equal(t, StatePending, exchange.State)
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		store := newStore(t)
+		store.store = &mockstore.MockStore{Store: map[string][]byte{"id": []byte("{")},
+			ErrPut: errors.New("put error")}
+
+		_, err := store.Create("issuer", "", credential, nil)
+		require.EqualError(t, err, "failed to store exchange: put error")
+
+		_, err = store.Get("issuer", "id")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal exchange")
+
+		store.store = &mockstore.MockStore{Store: map[string][]byte{"id": nil}, ErrGet: errors.New("get error")}
+
+		_, err = store.Get("issuer", "id")
+		require.EqualError(t, err, "failed to get exchange: get error")
+	})
+}
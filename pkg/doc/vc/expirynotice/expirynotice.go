@@ -0,0 +1,359 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package expirynotice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/edge-core/pkg/storage"
+)
+
+const (
+	noticeStore      = "expirynotices"
+	lastScannedKey   = "lastScannedDay"
+	bucketKeyPrefix  = "notice_"
+	trackedKeyPrefix = "tracked_"
+	entryKeySep      = "/"
+	dayLayout        = "2006-01-02"
+)
+
+var logger = log.New("edge-service-vc-expiry-notice")
+
+// Notice is the notice of a credential of a holder profile expiring soon.
+type Notice struct {
+	Profile      string    `json:"profile"`
+	CredentialID string    `json:"credentialID"`
+	Types        []string  `json:"types,omitempty"`
+	Expires      time.Time `json:"expires"`
+}
+
+// Notify sends the notice, the notice being sent again by the next scans if it fails.
+type Notify func(notice *Notice) error
+
+// Scheduler notifies the holders of their stored credentials expiring soon, so the wallets can prompt the users to
+// refresh them.
+//
+// The notices are bucketed by the day they are due, the days before the expiration of the credential of its profile
+// at the time it is stored, and a scan notifies the notices of the days since the last scanned day. Each notice is
+// stored under its own key, numbered in the bucket of its day, and is replaced by an empty value once notified or
+// once its credential is removed. A notice failing to be notified stays pending and is retried by the next scans, the
+// last scanned day doesn't pass its day until then.
+type Scheduler struct {
+	store  storage.Store
+	notify Notify
+	now    func() time.Time
+
+	mutex sync.Mutex
+	stop  chan struct{}
+}
+
+// New returns a new expiry notice scheduler sending the notices with notify
+func New(provider storage.Provider, notify Notify) (*Scheduler, error) {
+	err := provider.CreateStore(noticeStore)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := provider.OpenStore(noticeStore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scheduler{store: store, notify: notify, now: time.Now}, nil
+}
+
+// Track schedules the notice of the credential stored by the holder profile, noticeDays days before its expiration,
+// and returns the day the notice is due. The credentials without an expiration date or ID are ignored, as are all the
+// credentials if noticeDays isn't positive, the day returned being zero. A credential stored again replaces its
+// previous notice.
+func (s *Scheduler) Track(profile string, vc *verifiable.Credential, noticeDays int) (time.Time, error) {
+	if vc.Expired == nil || vc.ID == "" || noticeDays <= 0 {
+		return time.Time{}, nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.untrack(profile, vc.ID); err != nil {
+		return time.Time{}, err
+	}
+
+	today := truncateDay(s.now().UTC())
+
+	lastScanned, err := s.lastScannedDay()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if lastScanned.IsZero() {
+		if err := s.store.Put(lastScannedKey, []byte(today.AddDate(0, 0, -1).Format(dayLayout))); err != nil {
+			return time.Time{}, fmt.Errorf("failed to store last scanned day: %w", err)
+		}
+	}
+
+	expires := vc.Expired.Time.UTC()
+
+	// the credentials expiring within the notice period are notified with the ones due today
+	day := truncateDay(expires).AddDate(0, 0, -noticeDays)
+	if day.Before(today) {
+		day = today
+	}
+
+	err = s.addNotice(day, &Notice{Profile: profile, CredentialID: vc.ID, Types: vc.Types, Expires: expires})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return day, nil
+}
+
+// Untrack cancels the notice of the credential removed by the holder profile.
+func (s *Scheduler) Untrack(profile, vcID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.untrack(profile, vcID)
+}
+
+// NotifyExpiring sends the notices due at the current time
+func (s *Scheduler) NotifyExpiring() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	today := truncateDay(s.now().UTC())
+
+	lastScanned, err := s.lastScannedDay()
+	if err != nil || lastScanned.IsZero() {
+		return err
+	}
+
+	// the days are scanned again until all their notices are sent, today until it has passed
+	advance := true
+
+	for day := lastScanned.AddDate(0, 0, 1); !day.After(today); day = day.AddDate(0, 0, 1) {
+		pending, err := s.notifyBucket(day)
+		if err != nil {
+			return err
+		}
+
+		advance = advance && !pending && day.Before(today)
+
+		if advance {
+			if err := s.store.Put(lastScannedKey, []byte(day.Format(dayLayout))); err != nil {
+				return fmt.Errorf("failed to store last scanned day: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Start sends the notices due at the given interval until the scheduler is stopped
+func (s *Scheduler) Start(interval time.Duration) {
+	stop := make(chan struct{})
+	s.stop = stop
+
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.NotifyExpiring(); err != nil {
+					logger.Errorf("failed to notify expiring credentials: %s", err.Error())
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler started with Start
+func (s *Scheduler) Stop() {
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// notifyBucket sends the notices of the day, and returns if notices of the day failed to be sent
+func (s *Scheduler) notifyBucket(day time.Time) (bool, error) {
+	count, err := s.getNoticeCount(day)
+	if err != nil {
+		return false, err
+	}
+
+	pending := false
+
+	for i := 0; i < count; i++ {
+		notice, err := s.getNotice(entryKey(day, i))
+		if err != nil {
+			return false, err
+		}
+
+		if notice == nil {
+			// already notified or removed
+			continue
+		}
+
+		if errNotify := s.notify(notice); errNotify != nil {
+			logger.Warnf("keeping the expiry notice of credential %s of profile %s for the next scan: %s",
+				notice.CredentialID, notice.Profile, errNotify.Error())
+
+			pending = true
+
+			continue
+		}
+
+		if err := s.removeNotice(notice.Profile, notice.CredentialID, entryKey(day, i)); err != nil {
+			return false, err
+		}
+	}
+
+	return pending, nil
+}
+
+// addNotice stores the notice before the incremented count of the bucket, then indexes it by credential, the
+// scheduler mutex serializing the numbering of the notices
+func (s *Scheduler) addNotice(day time.Time, notice *Notice) error {
+	count, err := s.getNoticeCount(day)
+	if err != nil {
+		return err
+	}
+
+	noticeBytes, err := json.Marshal(notice)
+	if err != nil {
+		return fmt.Errorf("failed to marshal expiry notice: %w", err)
+	}
+
+	key := entryKey(day, count)
+
+	if err := s.store.Put(key, noticeBytes); err != nil {
+		return fmt.Errorf("failed to store expiry notice: %w", err)
+	}
+
+	if err := s.store.Put(bucketKey(day), []byte(strconv.Itoa(count+1))); err != nil {
+		return fmt.Errorf("failed to store expiry notices count: %w", err)
+	}
+
+	if err := s.store.Put(trackedKey(notice.Profile, notice.CredentialID), []byte(key)); err != nil {
+		return fmt.Errorf("failed to store expiry notice index: %w", err)
+	}
+
+	return nil
+}
+
+// untrack empties the notice of the credential, if any
+func (s *Scheduler) untrack(profile, vcID string) error {
+	keyBytes, err := s.store.Get(trackedKey(profile, vcID))
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to get expiry notice index: %w", err)
+	}
+
+	if len(keyBytes) == 0 {
+		return nil
+	}
+
+	return s.removeNotice(profile, vcID, string(keyBytes))
+}
+
+func (s *Scheduler) removeNotice(profile, vcID, key string) error {
+	if err := s.store.Put(key, []byte{}); err != nil {
+		return fmt.Errorf("failed to remove expiry notice: %w", err)
+	}
+
+	if err := s.store.Put(trackedKey(profile, vcID), []byte{}); err != nil {
+		return fmt.Errorf("failed to remove expiry notice index: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Scheduler) lastScannedDay() (time.Time, error) {
+	dayBytes, err := s.store.Get(lastScannedKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrValueNotFound) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, fmt.Errorf("failed to get last scanned day: %w", err)
+	}
+
+	day, err := time.Parse(dayLayout, string(dayBytes))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid last scanned day: %w", err)
+	}
+
+	return day, nil
+}
+
+func (s *Scheduler) getNoticeCount(day time.Time) (int, error) {
+	countBytes, err := s.store.Get(bucketKey(day))
+	if err != nil {
+		if errors.Is(err, storage.ErrValueNotFound) {
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("failed to get expiry notices: %w", err)
+	}
+
+	count, err := strconv.Atoi(string(countBytes))
+	if err != nil {
+		return 0, fmt.Errorf("invalid expiry notices count: %w", err)
+	}
+
+	return count, nil
+}
+
+// getNotice returns the notice stored under the key, nil once notified or removed
+func (s *Scheduler) getNotice(key string) (*Notice, error) {
+	noticeBytes, err := s.store.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiry notice: %w", err)
+	}
+
+	if len(noticeBytes) == 0 {
+		return nil, nil
+	}
+
+	notice := &Notice{}
+	if err := json.Unmarshal(noticeBytes, notice); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal expiry notice: %w", err)
+	}
+
+	return notice, nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func bucketKey(day time.Time) string {
+	return bucketKeyPrefix + day.Format(dayLayout)
+}
+
+func entryKey(day time.Time, i int) string {
+	return bucketKey(day) + entryKeySep + strconv.Itoa(i)
+}
+
+func trackedKey(profile, vcID string) string {
+	return trackedKeyPrefix + profile + entryKeySep + vcID
+}
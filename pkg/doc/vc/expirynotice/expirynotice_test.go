@@ -0,0 +1,226 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package expirynotice
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+)
+
+const profileName = "holder"
+
+type mockNotifier struct {
+	notified []string
+	err      map[string]error
+}
+
+func (n *mockNotifier) notify(notice *Notice) error {
+	if err := n.err[notice.CredentialID]; err != nil {
+		return err
+	}
+
+	n.notified = append(n.notified, notice.CredentialID)
+
+	return nil
+}
+
+func newScheduler(t *testing.T, n *mockNotifier) *Scheduler {
+	s, err := New(memstore.NewProvider(), n.notify)
+	require.NoError(t, err)
+
+	return s
+}
+
+func newVC(id string, expires time.Time) *verifiable.Credential {
+	return &verifiable.Credential{ID: id, Types: []string{"VerifiableCredential"}, Expired: util.NewTime(expires)}
+}
+
+func TestNew(t *testing.T) {
+	t.Run("test error from create store", func(t *testing.T) {
+		s, err := New(&mockstore.Provider{ErrCreateStore: fmt.Errorf("error create")}, nil)
+		require.EqualError(t, err, "error create")
+		require.Nil(t, s)
+	})
+
+	t.Run("test error from open store", func(t *testing.T) {
+		s, err := New(&mockstore.Provider{ErrOpenStoreHandle: fmt.Errorf("error open")}, nil)
+		require.EqualError(t, err, "error open")
+		require.Nil(t, s)
+	})
+}
+
+func TestScheduler_NotifyExpiring(t *testing.T) {
+	day := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("test credentials notified before their expiration", func(t *testing.T) {
+		n := &mockNotifier{}
+		s := newScheduler(t, n)
+		s.now = func() time.Time { return day.Add(12 * time.Hour) }
+
+		noticeDay, err := s.Track(profileName, newVC("vc1", day.AddDate(0, 0, 30)), 7)
+		require.NoError(t, err)
+		require.Equal(t, day.AddDate(0, 0, 23), noticeDay)
+
+		// a credential expiring within the notice period is notified today
+		noticeDay, err = s.Track(profileName, newVC("vc2", day.AddDate(0, 0, 3)), 7)
+		require.NoError(t, err)
+		require.Equal(t, day, noticeDay)
+
+		_, err = s.Track(profileName, newVC("vc3", day.AddDate(0, 0, 10)), 7)
+		require.NoError(t, err)
+
+		require.NoError(t, s.NotifyExpiring())
+		require.Equal(t, []string{"vc2"}, n.notified)
+
+		s.now = func() time.Time { return day.AddDate(0, 0, 3) }
+		require.NoError(t, s.NotifyExpiring())
+		require.Equal(t, []string{"vc2", "vc3"}, n.notified)
+
+		s.now = func() time.Time { return day.AddDate(0, 0, 40) }
+		require.NoError(t, s.NotifyExpiring())
+		require.NoError(t, s.NotifyExpiring())
+		require.Equal(t, []string{"vc2", "vc3", "vc1"}, n.notified)
+	})
+
+	t.Run("test credentials not tracked", func(t *testing.T) {
+		n := &mockNotifier{}
+		s := newScheduler(t, n)
+		s.now = func() time.Time { return day }
+
+		vc := newVC("vc1", day)
+		vc.Expired = nil
+
+		for _, tracked := range []struct {
+			vc   *verifiable.Credential
+			days int
+		}{{vc, 7}, {newVC("", day), 7}, {newVC("vc2", day), 0}} {
+			noticeDay, err := s.Track(profileName, tracked.vc, tracked.days)
+			require.NoError(t, err)
+			require.True(t, noticeDay.IsZero())
+		}
+
+		// nothing tracked yet
+		require.NoError(t, s.NotifyExpiring())
+		require.Empty(t, n.notified)
+	})
+
+	t.Run("test credentials removed or stored again", func(t *testing.T) {
+		n := &mockNotifier{}
+		s := newScheduler(t, n)
+		s.now = func() time.Time { return day }
+
+		_, err := s.Track(profileName, newVC("vc1", day.AddDate(0, 0, 5)), 7)
+		require.NoError(t, err)
+		_, err = s.Track(profileName, newVC("vc2", day.AddDate(0, 0, 5)), 7)
+		require.NoError(t, err)
+		_, err = s.Track("other", newVC("vc1", day.AddDate(0, 0, 5)), 7)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Untrack(profileName, "vc1"))
+		require.NoError(t, s.Untrack(profileName, "unknown"))
+
+		// the credential renewed is notified before its new expiration only
+		_, err = s.Track(profileName, newVC("vc2", day.AddDate(0, 0, 60)), 7)
+		require.NoError(t, err)
+
+		require.NoError(t, s.NotifyExpiring())
+		require.Equal(t, []string{"vc1"}, n.notified)
+
+		s.now = func() time.Time { return day.AddDate(0, 0, 53) }
+		require.NoError(t, s.NotifyExpiring())
+		require.Equal(t, []string{"vc1", "vc2"}, n.notified)
+	})
+
+	t.Run("test notices failing are retried", func(t *testing.T) {
+		n := &mockNotifier{err: map[string]error{"failed": errors.New("webhook unavailable")}}
+		s := newScheduler(t, n)
+		s.now = func() time.Time { return day }
+
+		_, err := s.Track(profileName, newVC("failed", day.AddDate(0, 0, 1)), 7)
+		require.NoError(t, err)
+		_, err = s.Track(profileName, newVC("vc1", day.AddDate(0, 0, 9)), 7)
+		require.NoError(t, err)
+
+		s.now = func() time.Time { return day.AddDate(0, 0, 3) }
+		require.NoError(t, s.NotifyExpiring())
+		require.Equal(t, []string{"vc1"}, n.notified)
+
+		lastScanned, err := s.lastScannedDay()
+		require.NoError(t, err)
+		require.Equal(t, day.AddDate(0, 0, -1), lastScanned)
+
+		n.err = nil
+
+		require.NoError(t, s.NotifyExpiring())
+		require.Equal(t, []string{"vc1", "failed"}, n.notified)
+
+		lastScanned, err = s.lastScannedDay()
+		require.NoError(t, err)
+		require.Equal(t, day.AddDate(0, 0, 2), lastScanned)
+	})
+
+	t.Run("test error from store", func(t *testing.T) {
+		store := &mockstore.MockStore{Store: map[string][]byte{}}
+
+		s, err := New(&mockstore.Provider{Store: store}, (&mockNotifier{}).notify)
+		require.NoError(t, err)
+
+		s.now = func() time.Time { return day }
+
+		store.Store[lastScannedKey] = []byte("invalid")
+
+		_, err = s.Track(profileName, newVC("vc1", day), 7)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid last scanned day")
+
+		require.Error(t, s.NotifyExpiring())
+
+		store.Store[lastScannedKey] = []byte(day.Format(dayLayout))
+		store.Store[bucketKey(day)] = []byte("invalid")
+
+		_, err = s.Track(profileName, newVC("vc1", day), 7)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid expiry notices count")
+
+		store.ErrPut = errors.New("put error")
+
+		_, err = s.Track(profileName, newVC("vc1", day.AddDate(0, 0, 30)), 7)
+		require.EqualError(t, err, "failed to store expiry notice: put error")
+	})
+}
+
+func TestScheduler_Start(t *testing.T) {
+	notified := make(chan string, 1)
+
+	s, err := New(memstore.NewProvider(), func(notice *Notice) error {
+		notified <- notice.CredentialID
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	_, err = s.Track(profileName, newVC("vc1", time.Now().Add(time.Hour)), 1)
+	require.NoError(t, err)
+
+	s.Start(time.Millisecond)
+	defer s.Stop()
+
+	select {
+	case vcID := <-notified:
+		require.Equal(t, "vc1", vcID)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "credential not notified")
+	}
+}
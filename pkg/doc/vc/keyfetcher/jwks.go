@@ -0,0 +1,254 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyfetcher
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+)
+
+const (
+	// DefaultJWKSCacheTTL is how long the key sets are cached by default
+	DefaultJWKSCacheTTL = 5 * time.Minute
+
+	// jwksRefreshInterval is the minimum time between two fetches of a key set, the key set being fetched again
+	// before its TTL for an unknown key ID (e.g. a rotated key)
+	jwksRefreshInterval = time.Minute
+
+	// maxJWKSSize is the size limit of the key sets
+	maxJWKSSize = 1 << 20
+
+	// jwkKeyType is the type of the keys of the key sets
+	jwkKeyType = "JwsVerificationKey2020"
+)
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// JWKSOption configures the JWKS fetcher
+type JWKSOption func(f *JWKSFetcher)
+
+// WithCacheTTL sets how long the key sets are cached, DefaultJWKSCacheTTL by default
+func WithCacheTTL(ttl time.Duration) JWKSOption {
+	return func(f *JWKSFetcher) {
+		f.ttl = ttl
+	}
+}
+
+// WithRootCAs sets the CAs the certificate chains (x5c) of the keys must chain to, the system CAs by default
+func WithRootCAs(roots *x509.CertPool) JWKSOption {
+	return func(f *JWKSFetcher) {
+		f.roots = roots
+	}
+}
+
+// JWKSFetcher fetches the keys of the trusted issuers from their JSON Web Key Sets, e.g. the issuers without DIDs.
+// The key of a proof is the key of the set with the key ID of the proof, or the only key of the set if the proof has
+// no key ID. A key with a certificate chain (x5c) must chain to the root CAs.
+type JWKSFetcher struct {
+	urls   map[string]string
+	client httpClient
+	ttl    time.Duration
+	roots  *x509.CertPool
+
+	mutex   sync.Mutex
+	keySets map[string]*keySet
+	now     func() time.Time
+}
+
+type keySet struct {
+	keys    []*jose.JWK
+	fetched time.Time
+}
+
+// NewJWKSFetcher returns the fetcher of the keys of the trusted issuers, keyed by issuer ID with the URLs of their key
+// sets, fetched with the client
+func NewJWKSFetcher(trusted map[string]string, client httpClient, opts ...JWKSOption) *JWKSFetcher {
+	f := &JWKSFetcher{urls: trusted, client: client, ttl: DefaultJWKSCacheTTL, keySets: make(map[string]*keySet),
+		now: time.Now}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	return f
+}
+
+// Fetch fetches the key of the trusted issuer, the other issuers not being supported
+func (f *JWKSFetcher) Fetch(issuerID, keyID string) (*verifier.PublicKey, error) {
+	jwksURL, ok := f.urls[issuerID]
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	set, err := f.keySet(jwksURL, false)
+	if err != nil {
+		return nil, err
+	}
+
+	key := set.key(keyID)
+	if key == nil && f.now().Sub(set.fetched) >= jwksRefreshInterval {
+		if set, err = f.keySet(jwksURL, true); err != nil {
+			return nil, err
+		}
+
+		key = set.key(keyID)
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("public key with KID %s is not found for issuer %s", keyID, issuerID)
+	}
+
+	if err := f.checkCertificates(key); err != nil {
+		return nil, fmt.Errorf("untrusted key %s of issuer %s: %w", keyID, issuerID, err)
+	}
+
+	value, err := key.PublicKeyBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return &verifier.PublicKey{Type: jwkKeyType, Value: value, JWK: key}, nil
+}
+
+// keySet returns the cached key set of the URL, fetched if expired or if refresh is set
+func (f *JWKSFetcher) keySet(jwksURL string, refresh bool) (*keySet, error) {
+	if set, ok := f.keySets[jwksURL]; ok && !refresh && f.now().Sub(set.fetched) < f.ttl {
+		return set, nil
+	}
+
+	keys, err := f.fetchKeys(jwksURL)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &keySet{keys: keys, fetched: f.now()}
+	f.keySets[jwksURL] = set
+
+	return set, nil
+}
+
+func (f *JWKSFetcher) fetchKeys(jwksURL string) ([]*jose.JWK, error) {
+	req, err := http.NewRequest(http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the key set request: %w", err)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the key set %s: %w", jwksURL, err)
+	}
+
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch the key set %s: status %d", jwksURL, resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxJWKSSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the key set %s: %w", jwksURL, err)
+	}
+
+	if len(body) > maxJWKSSize {
+		return nil, fmt.Errorf("the key set %s exceeds %d bytes", jwksURL, maxJWKSSize)
+	}
+
+	jwks := struct {
+		Keys []json.RawMessage `json:"keys"`
+	}{}
+
+	if err := json.Unmarshal(body, &jwks); err != nil {
+		return nil, fmt.Errorf("invalid key set %s: %w", jwksURL, err)
+	}
+
+	keys := make([]*jose.JWK, 0, len(jwks.Keys))
+
+	// the keys which can't be parsed are skipped, e.g. keys of types not supported
+	for _, raw := range jwks.Keys {
+		key := &jose.JWK{}
+		if err := key.UnmarshalJSON(raw); err == nil && key.Valid() && key.IsPublic() {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// checkCertificates checks the certificate chain of the key, if any, chains to the root CAs and certifies the key
+func (f *JWKSFetcher) checkCertificates(key *jose.JWK) error {
+	if len(key.Certificates) == 0 {
+		return nil
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range key.Certificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	leaf := key.Certificates[0]
+
+	_, err := leaf.Verify(x509.VerifyOptions{Roots: f.roots, Intermediates: intermediates,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+	if err != nil {
+		return err
+	}
+
+	leafKey, err := x509.MarshalPKIXPublicKey(leaf.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	jwkKey, err := x509.MarshalPKIXPublicKey(key.Key)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(leafKey, jwkKey) {
+		return errors.New("the certificate doesn't certify the key")
+	}
+
+	return nil
+}
+
+// key returns the key with the key ID, or the only key of the set if no key ID. The key IDs of the linked data
+// proofs are the fragments of their verification methods, e.g. #key-1 for https://issuer.example.com#key-1.
+func (s *keySet) key(keyID string) *jose.JWK {
+	keyID = strings.TrimPrefix(keyID, "#")
+
+	if keyID == "" {
+		if len(s.keys) == 1 {
+			return s.keys[0]
+		}
+
+		return nil
+	}
+
+	for _, key := range s.keys {
+		if key.KeyID == keyID {
+			return key
+		}
+	}
+
+	return nil
+}
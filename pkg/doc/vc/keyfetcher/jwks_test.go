@@ -0,0 +1,285 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyfetcher
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha1" // nolint: gosec
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+)
+
+const testIssuer = "https://issuer.example.com"
+
+// jwksServer serves the key set of its keys, counting the requests
+type jwksServer struct {
+	mutex    sync.Mutex
+	keys     []*jose.JWK
+	status   int
+	body     string
+	requests int
+}
+
+func (s *jwksServer) ServeHTTP(rw http.ResponseWriter, _ *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.requests++
+
+	if s.status != 0 {
+		rw.WriteHeader(s.status)
+
+		return
+	}
+
+	if s.body != "" {
+		fmt.Fprint(rw, s.body)
+
+		return
+	}
+
+	jwks := struct {
+		Keys []*jose.JWK `json:"keys"`
+	}{Keys: s.keys}
+
+	json.NewEncoder(rw).Encode(jwks) // nolint: errcheck,gosec
+}
+
+func newJWK(t *testing.T, pubKey ed25519.PublicKey, keyID string, certs ...*x509.Certificate) *jose.JWK {
+	key, err := jose.JWKFromPublicKey(pubKey)
+	require.NoError(t, err)
+
+	key.KeyID = keyID
+	key.Certificates = certs
+
+	if len(certs) > 0 {
+		sha1Thumbprint := sha1.Sum(certs[0].Raw) // nolint: gosec
+		sha256Thumbprint := sha256.Sum256(certs[0].Raw)
+
+		key.CertificateThumbprintSHA1 = sha1Thumbprint[:]
+		key.CertificateThumbprintSHA256 = sha256Thumbprint[:]
+	}
+
+	return key
+}
+
+func TestJWKSFetcher(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	jwks := &jwksServer{keys: []*jose.JWK{newJWK(t, otherKey, "key-0"), newJWK(t, pubKey, "key-1")}}
+
+	server := httptest.NewServer(jwks)
+	defer server.Close()
+
+	t.Run("test credential of an issuer without DID", func(t *testing.T) {
+		f := NewJWKSFetcher(map[string]string{testIssuer: server.URL}, server.Client())
+
+		vc, err := verifiable.ParseUnverifiedCredential([]byte(`{
+			"@context": ["https://www.w3.org/2018/credentials/v1"],
+			"type": ["VerifiableCredential"],
+			"issuer": "` + testIssuer + `",
+			"issuanceDate": "2010-01-01T19:23:24Z",
+			"credentialSubject": {"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"}
+		}`))
+		require.NoError(t, err)
+
+		claims, err := vc.JWTClaims(false)
+		require.NoError(t, err)
+
+		jwtVC, err := claims.MarshalJWS(verifiable.EdDSA, signature.GetEd25519Signer(privKey, pubKey), "key-1")
+		require.NoError(t, err)
+
+		_, err = verifiable.ParseCredential([]byte(jwtVC), verifiable.WithPublicKeyFetcher(Chain(f)))
+		require.NoError(t, err)
+
+		// signed with another key of the issuer
+		jwtVC, err = claims.MarshalJWS(verifiable.EdDSA, signature.GetEd25519Signer(privKey, pubKey), "key-0")
+		require.NoError(t, err)
+
+		_, err = verifiable.ParseCredential([]byte(jwtVC), verifiable.WithPublicKeyFetcher(Chain(f)))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "signature doesn't match")
+	})
+
+	t.Run("test issuer not trusted", func(t *testing.T) {
+		f := NewJWKSFetcher(map[string]string{testIssuer: server.URL}, server.Client())
+
+		_, err := f.Fetch("https://other.example.com", "key-1")
+		require.True(t, errors.Is(err, ErrNotSupported))
+	})
+
+	t.Run("test cached key set", func(t *testing.T) {
+		now := time.Now()
+
+		f := NewJWKSFetcher(map[string]string{testIssuer: server.URL}, server.Client(), WithCacheTTL(time.Hour))
+		f.now = func() time.Time { return now }
+
+		jwks.requests = 0
+
+		for i := 0; i < 3; i++ {
+			key, err := f.Fetch(testIssuer, "#key-1")
+			require.NoError(t, err)
+			require.Equal(t, []byte(pubKey), key.Value)
+			require.Equal(t, "key-1", key.JWK.KeyID)
+		}
+
+		require.Equal(t, 1, jwks.requests)
+
+		// an unknown key is fetched again once per refresh interval
+		_, err := f.Fetch(testIssuer, "key-2")
+		require.EqualError(t, err, "public key with KID key-2 is not found for issuer "+testIssuer)
+		require.Equal(t, 1, jwks.requests)
+
+		now = now.Add(jwksRefreshInterval)
+
+		jwks.keys = append(jwks.keys, newJWK(t, otherKey, "key-2"))
+		defer func() { jwks.keys = jwks.keys[:2] }()
+
+		key, err := f.Fetch(testIssuer, "key-2")
+		require.NoError(t, err)
+		require.Equal(t, []byte(otherKey), key.Value)
+		require.Equal(t, 2, jwks.requests)
+
+		// the key set is fetched again once expired
+		now = now.Add(time.Hour)
+
+		_, err = f.Fetch(testIssuer, "key-1")
+		require.NoError(t, err)
+		require.Equal(t, 3, jwks.requests)
+	})
+
+	t.Run("test key without key ID", func(t *testing.T) {
+		single := httptest.NewServer(&jwksServer{keys: []*jose.JWK{newJWK(t, pubKey, "")}})
+		defer single.Close()
+
+		f := NewJWKSFetcher(map[string]string{testIssuer: single.URL, "https://other.example.com": server.URL},
+			server.Client())
+
+		key, err := f.Fetch(testIssuer, "")
+		require.NoError(t, err)
+		require.Equal(t, []byte(pubKey), key.Value)
+
+		// the key ID is required to pick a key of a set of several keys
+		_, err = f.Fetch("https://other.example.com", "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is not found")
+	})
+
+	t.Run("test invalid key sets", func(t *testing.T) {
+		for _, test := range []struct {
+			server *jwksServer
+			err    string
+		}{
+			{server: &jwksServer{status: http.StatusNotFound}, err: "status 404"},
+			{server: &jwksServer{body: "{"}, err: "invalid key set"},
+			{server: &jwksServer{body: `{"keys":[{"kty":"unknown"}]}`}, err: "not found"},
+			{server: &jwksServer{body: `{"keys":["` + strings.Repeat("a", maxJWKSSize) + `"]}`}, err: "exceeds"},
+		} {
+			s := httptest.NewServer(test.server)
+
+			_, err := NewJWKSFetcher(map[string]string{testIssuer: s.URL}, s.Client()).Fetch(testIssuer, "key-1")
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.err)
+
+			s.Close()
+		}
+
+		_, err := NewJWKSFetcher(map[string]string{testIssuer: "http://[::1]:namedport"},
+			server.Client()).Fetch(testIssuer, "key-1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create the key set request")
+
+		_, err = NewJWKSFetcher(map[string]string{testIssuer: "http://localhost:1"},
+			server.Client()).Fetch(testIssuer, "key-1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to fetch the key set")
+	})
+}
+
+func TestJWKSFetcher_Certificates(t *testing.T) {
+	caPub, caPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "Trust Service CA"},
+		NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour), IsCA: true,
+		BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caPub, caPriv)
+	require.NoError(t, err)
+
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{SerialNumber: big.NewInt(2),
+		Subject:   pkix.Name{CommonName: "Issuer"},
+		NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour),
+		KeyUsage: x509.KeyUsageDigitalSignature}, ca, pubKey, caPriv)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(leafDER)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	server := httptest.NewServer(&jwksServer{keys: []*jose.JWK{newJWK(t, pubKey, "key-1", leaf, ca)}})
+	defer server.Close()
+
+	t.Run("test key certified by a trusted CA", func(t *testing.T) {
+		f := NewJWKSFetcher(map[string]string{testIssuer: server.URL}, server.Client(), WithRootCAs(roots))
+
+		key, err := f.Fetch(testIssuer, "key-1")
+		require.NoError(t, err)
+		require.Equal(t, []byte(pubKey), key.Value)
+	})
+
+	t.Run("test key certified by an untrusted CA", func(t *testing.T) {
+		f := NewJWKSFetcher(map[string]string{testIssuer: server.URL}, server.Client(),
+			WithRootCAs(x509.NewCertPool()))
+
+		_, err := f.Fetch(testIssuer, "key-1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "untrusted key key-1 of issuer "+testIssuer)
+	})
+
+	t.Run("test certificate of another key", func(t *testing.T) {
+		key := newJWK(t, pubKey, "key-1", leaf)
+		key.Key = otherKey
+
+		f := NewJWKSFetcher(nil, nil, WithRootCAs(roots))
+
+		err := f.checkCertificates(key)
+		require.EqualError(t, err, "the certificate doesn't certify the key")
+	})
+}
@@ -0,0 +1,63 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyfetcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+)
+
+const didPrefix = "did:"
+
+// ErrNotSupported is returned by the fetchers not resolving the keys of an issuer, the next fetcher being tried
+var ErrNotSupported = errors.New("issuer not supported by the key fetcher")
+
+// Fetcher fetches the public keys verifying the proofs of the issuers it supports, ErrNotSupported otherwise
+type Fetcher interface {
+	Fetch(issuerID, keyID string) (*verifier.PublicKey, error)
+}
+
+// Chain returns the public key fetcher of the credentials, the key of an issuer being fetched by the first fetcher
+// supporting the issuer
+func Chain(fetchers ...Fetcher) verifiable.PublicKeyFetcher {
+	return func(issuerID, keyID string) (*verifier.PublicKey, error) {
+		for _, fetcher := range fetchers {
+			key, err := fetcher.Fetch(issuerID, keyID)
+			if errors.Is(err, ErrNotSupported) {
+				continue
+			}
+
+			return key, err
+		}
+
+		return nil, fmt.Errorf("no key fetcher for issuer %s", issuerID)
+	}
+}
+
+// DIDFetcher resolves the keys of the DIDs
+type DIDFetcher struct {
+	resolver verifiable.PublicKeyFetcher
+}
+
+// NewDIDFetcher returns the fetcher resolving the keys of the DIDs with the registry
+func NewDIDFetcher(registry vdriapi.Registry) *DIDFetcher {
+	return &DIDFetcher{resolver: verifiable.NewDIDKeyResolver(registry).PublicKeyFetcher()}
+}
+
+// Fetch resolves the key of the DID, the other issuers not being supported
+func (f *DIDFetcher) Fetch(issuerID, keyID string) (*verifier.PublicKey, error) {
+	if !strings.HasPrefix(issuerID, didPrefix) {
+		return nil, ErrNotSupported
+	}
+
+	return f.resolver(issuerID, keyID)
+}
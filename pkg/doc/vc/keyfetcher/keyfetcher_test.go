@@ -0,0 +1,71 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyfetcher
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+)
+
+type mockFetcher struct {
+	key *verifier.PublicKey
+	err error
+}
+
+func (f *mockFetcher) Fetch(string, string) (*verifier.PublicKey, error) {
+	return f.key, f.err
+}
+
+func TestChain(t *testing.T) {
+	key := &verifier.PublicKey{Type: "Ed25519VerificationKey2018", Value: []byte("key")}
+
+	t.Run("test first fetcher supporting the issuer", func(t *testing.T) {
+		fetched, err := Chain(&mockFetcher{err: ErrNotSupported}, &mockFetcher{key: key},
+			&mockFetcher{err: errors.New("not called")})("https://issuer.example.com", "key-1")
+		require.NoError(t, err)
+		require.Equal(t, key, fetched)
+	})
+
+	t.Run("test error of the fetcher supporting the issuer", func(t *testing.T) {
+		_, err := Chain(&mockFetcher{err: errors.New("fetch error")}, &mockFetcher{key: key})("did:example:1", "")
+		require.EqualError(t, err, "fetch error")
+	})
+
+	t.Run("test no fetcher supporting the issuer", func(t *testing.T) {
+		_, err := Chain(&mockFetcher{err: ErrNotSupported})("https://issuer.example.com", "key-1")
+		require.EqualError(t, err, "no key fetcher for issuer https://issuer.example.com")
+	})
+}
+
+func TestDIDFetcher(t *testing.T) {
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	f := NewDIDFetcher(&vdrimock.MockVDRIRegistry{
+		ResolveFunc: func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+			key := did.PublicKey{ID: didID + "#key-1", Type: "Ed25519VerificationKey2018", Controller: didID,
+				Value: pubKey}
+
+			return &did.Doc{ID: didID, PublicKey: []did.PublicKey{key},
+				AssertionMethod: []did.VerificationMethod{{PublicKey: key}}}, nil
+		}})
+
+	key, err := f.Fetch("did:example:1", "#key-1")
+	require.NoError(t, err)
+	require.Equal(t, []byte(pubKey), key.Value)
+
+	_, err = f.Fetch("https://issuer.example.com", "key-1")
+	require.True(t, errors.Is(err, ErrNotSupported))
+}
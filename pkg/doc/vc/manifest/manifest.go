@@ -0,0 +1,246 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+)
+
+const manifestStore = "credentialmanifest"
+
+// CredentialManifest is a DIF credential manifest, describing the credentials an issuer issues and the inputs
+// required to issue them (https://identity.foundation/credential-manifest/).
+type CredentialManifest struct {
+	ID                string             `json:"id"`
+	Issuer            Issuer             `json:"issuer"`
+	OutputDescriptors []OutputDescriptor `json:"output_descriptors"`
+	// PresentationDefinition describes the inputs required by the issuer, as a DIF presentation definition
+	PresentationDefinition json.RawMessage `json:"presentation_definition,omitempty"`
+}
+
+// Issuer is the issuer of the credentials described by a manifest
+type Issuer struct {
+	ID     string          `json:"id"`
+	Name   string          `json:"name,omitempty"`
+	Styles json.RawMessage `json:"styles,omitempty"`
+}
+
+// OutputDescriptor describes a credential issued by the issuer of a manifest
+type OutputDescriptor struct {
+	ID          string          `json:"id"`
+	Schema      json.RawMessage `json:"schema"`
+	Name        string          `json:"name,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Display     json.RawMessage `json:"display,omitempty"`
+	Styles      json.RawMessage `json:"styles,omitempty"`
+}
+
+// Validate checks the manifest has an ID, an issuer, and output descriptors with IDs and schemas
+func (m *CredentialManifest) Validate() error {
+	if m.ID == "" {
+		return errors.New("missing manifest id")
+	}
+
+	if m.Issuer.ID == "" {
+		return errors.New("missing manifest issuer id")
+	}
+
+	if len(m.OutputDescriptors) == 0 {
+		return errors.New("missing manifest output descriptors")
+	}
+
+	for i, descriptor := range m.OutputDescriptors {
+		if descriptor.ID == "" {
+			return fmt.Errorf("missing id of output descriptor %d", i)
+		}
+
+		if len(descriptor.Schema) == 0 {
+			return fmt.Errorf("missing schema of output descriptor %s", descriptor.ID)
+		}
+	}
+
+	return nil
+}
+
+// record is a stored manifest, kept once deleted as the store can't delete values
+type record struct {
+	Manifest *CredentialManifest `json:"manifest,omitempty"`
+	Deleted  bool                `json:"deleted,omitempty"`
+}
+
+// Store keeps the credential manifests of each profile.
+//
+// Each manifest is stored under its own key, and the IDs of the manifests of a profile under the profile key. The
+// store mutex serializes the updates of the IDs, so the manifests saved by an instance can't drop each other.
+type Store struct {
+	store storage.Store
+	mutex sync.Mutex
+}
+
+// New returns a new credential manifest store
+func New(provider storage.Provider) (*Store, error) {
+	err := provider.CreateStore(manifestStore)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := provider.OpenStore(manifestStore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{store: store}, nil
+}
+
+// Put saves the manifest of the profile, replacing the manifest with the same ID if any
+func (s *Store) Put(profile string, manifest *CredentialManifest) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.putRecord(profile, manifest.ID, &record{Manifest: manifest}); err != nil {
+		return err
+	}
+
+	ids, err := s.getIDs(profile)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if id == manifest.ID {
+			return nil
+		}
+	}
+
+	return s.putIDs(profile, append(ids, manifest.ID))
+}
+
+// Get returns the manifest of the profile, the error wraps storage.ErrValueNotFound if the manifest doesn't exist
+func (s *Store) Get(profile, id string) (*CredentialManifest, error) {
+	recordBytes, err := s.store.Get(key(profile, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest: %w", err)
+	}
+
+	r := &record{}
+
+	if err := json.Unmarshal(recordBytes, r); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal manifest: %w", err)
+	}
+
+	if r.Deleted {
+		return nil, fmt.Errorf("manifest %s deleted: %w", id, storage.ErrValueNotFound)
+	}
+
+	return r.Manifest, nil
+}
+
+// List returns the manifests of the profile, in the order they were created
+func (s *Store) List(profile string) ([]CredentialManifest, error) {
+	ids, err := s.getIDs(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := make([]CredentialManifest, len(ids))
+
+	for i, id := range ids {
+		manifest, errGet := s.Get(profile, id)
+		if errGet != nil {
+			return nil, errGet
+		}
+
+		manifests[i] = *manifest
+	}
+
+	return manifests, nil
+}
+
+// Delete deletes the manifest of the profile, the error wraps storage.ErrValueNotFound if the manifest doesn't
+// exist
+func (s *Store) Delete(profile, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := s.Get(profile, id); err != nil {
+		return err
+	}
+
+	if err := s.putRecord(profile, id, &record{Deleted: true}); err != nil {
+		return err
+	}
+
+	ids, err := s.getIDs(profile)
+	if err != nil {
+		return err
+	}
+
+	for i := range ids {
+		if ids[i] == id {
+			ids = append(ids[:i], ids[i+1:]...)
+
+			break
+		}
+	}
+
+	return s.putIDs(profile, ids)
+}
+
+func (s *Store) putRecord(profile, id string, r *record) error {
+	recordBytes, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if err := s.store.Put(key(profile, id), recordBytes); err != nil {
+		return fmt.Errorf("failed to store manifest: %w", err)
+	}
+
+	return nil
+}
+
+// getIDs returns the IDs of the manifests of the profile, none if the profile has no manifests
+func (s *Store) getIDs(profile string) ([]string, error) {
+	idsBytes, err := s.store.Get(profile)
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest ids: %w", err)
+	}
+
+	var ids []string
+
+	if err := json.Unmarshal(idsBytes, &ids); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal manifest ids: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (s *Store) putIDs(profile string, ids []string) error {
+	idsBytes, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest ids: %w", err)
+	}
+
+	if err := s.store.Put(profile, idsBytes); err != nil {
+		return fmt.Errorf("failed to store manifest ids: %w", err)
+	}
+
+	return nil
+}
+
+func key(profile, id string) string {
+	return profile + "/" + id
+}
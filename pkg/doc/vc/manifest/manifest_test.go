@@ -0,0 +1,141 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		s, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+		require.NotNil(t, s)
+	})
+
+	t.Run("test error from create store", func(t *testing.T) {
+		s, err := New(&mockstore.Provider{ErrCreateStore: fmt.Errorf("error create")})
+		require.EqualError(t, err, "error create")
+		require.Nil(t, s)
+	})
+
+	t.Run("test error from open store", func(t *testing.T) {
+		s, err := New(&mockstore.Provider{ErrOpenStoreHandle: fmt.Errorf("error open")})
+		require.EqualError(t, err, "error open")
+		require.Nil(t, s)
+	})
+}
+
+func TestCredentialManifest_Validate(t *testing.T) {
+	require.NoError(t, newManifest("m1").Validate())
+
+	m := newManifest("")
+	require.EqualError(t, m.Validate(), "missing manifest id")
+
+	m = newManifest("m1")
+	m.Issuer.ID = ""
+	require.EqualError(t, m.Validate(), "missing manifest issuer id")
+
+	m.Issuer.ID = "did:example:issuer"
+	m.OutputDescriptors = nil
+	require.EqualError(t, m.Validate(), "missing manifest output descriptors")
+
+	m.OutputDescriptors = []OutputDescriptor{{Schema: json.RawMessage(`"https://example.com/schema"`)}}
+	require.EqualError(t, m.Validate(), "missing id of output descriptor 0")
+
+	m.OutputDescriptors = []OutputDescriptor{{ID: "d1"}}
+	require.EqualError(t, m.Validate(), "missing schema of output descriptor d1")
+}
+
+func TestStore(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		s, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		manifests, err := s.List("issuer")
+		require.NoError(t, err)
+		require.Empty(t, manifests)
+
+		_, err = s.Get("issuer", "m1")
+		require.True(t, errors.Is(err, storage.ErrValueNotFound))
+
+		require.NoError(t, s.Put("issuer", newManifest("m1")))
+		require.NoError(t, s.Put("issuer", newManifest("m2")))
+		require.NoError(t, s.Put("other", newManifest("m1")))
+
+		updated := newManifest("m1")
+		updated.Issuer.Name = "Example University"
+		require.NoError(t, s.Put("issuer", updated))
+
+		manifest, err := s.Get("issuer", "m1")
+		require.NoError(t, err)
+		require.Equal(t, updated, manifest)
+
+		manifests, err = s.List("issuer")
+		require.NoError(t, err)
+		require.Equal(t, []CredentialManifest{*updated, *newManifest("m2")}, manifests)
+
+		require.NoError(t, s.Delete("issuer", "m1"))
+
+		_, err = s.Get("issuer", "m1")
+		require.True(t, errors.Is(err, storage.ErrValueNotFound))
+
+		err = s.Delete("issuer", "m1")
+		require.True(t, errors.Is(err, storage.ErrValueNotFound))
+
+		manifests, err = s.List("issuer")
+		require.NoError(t, err)
+		require.Equal(t, []CredentialManifest{*newManifest("m2")}, manifests)
+
+		manifests, err = s.List("other")
+		require.NoError(t, err)
+		require.Len(t, manifests, 1)
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		s, err := New(&mockstore.Provider{Store: &mockstore.MockStore{
+			Store: map[string][]byte{"issuer": []byte("invalid"), "issuer/m1": []byte("invalid")},
+		}})
+		require.NoError(t, err)
+
+		_, err = s.List("issuer")
+		require.Contains(t, err.Error(), "failed to unmarshal manifest ids")
+
+		_, err = s.Get("issuer", "m1")
+		require.Contains(t, err.Error(), "failed to unmarshal manifest")
+
+		err = s.Put("issuer", newManifest("m2"))
+		require.Contains(t, err.Error(), "failed to unmarshal manifest ids")
+
+		s, err = New(&mockstore.Provider{Store: &mockstore.MockStore{
+			Store: map[string][]byte{}, ErrPut: errors.New("put error"),
+		}})
+		require.NoError(t, err)
+
+		err = s.Put("issuer", newManifest("m1"))
+		require.EqualError(t, err, "failed to store manifest: put error")
+	})
+}
+
+func newManifest(id string) *CredentialManifest {
+	return &CredentialManifest{
+		ID:     id,
+		Issuer: Issuer{ID: "did:example:issuer"},
+		OutputDescriptors: []OutputDescriptor{{
+			ID:     "degree",
+			Schema: json.RawMessage(`"https://schema.org/EducationalOccupationalCredential"`),
+		}},
+	}
+}
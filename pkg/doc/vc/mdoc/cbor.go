@@ -0,0 +1,430 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mdoc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// the major types of the CBOR data items (RFC 8949)
+const (
+	majorUint = iota
+	majorNegInt
+	majorBytes
+	majorText
+	majorArray
+	majorMap
+	majorTag
+	majorSimple
+)
+
+const (
+	simpleFalse   = 20
+	simpleTrue    = 21
+	simpleNull    = 22
+	simpleFloat16 = 25
+	simpleFloat32 = 26
+	simpleFloat64 = 27
+
+	// maxDepth bounds the nesting of the decoded data items
+	maxDepth = 32
+)
+
+// the tags of the mdoc data items
+const (
+	tagDateTime    = 0
+	tagEncodedCBOR = 24
+	tagFullDate    = 1004
+)
+
+// tagged is a tagged data item
+type tagged struct {
+	number  uint64
+	content interface{}
+}
+
+// encodedCBOR returns the data item embedding the encoding of the value, i.e. #6.24(bstr .cbor value)
+func encodedCBOR(v interface{}) (*tagged, error) {
+	encoded, err := marshalCBOR(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tagged{number: tagEncodedCBOR, content: encoded}, nil
+}
+
+// marshalCBOR encodes the value with the core deterministic encoding: the shortest heads and the map keys sorted by
+// their encoding. The values are nil, bool, the integers, float64, string, []byte, []interface{}, the maps keyed by
+// string or int and *tagged.
+func marshalCBOR(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	if err := encodeCBOR(buf, v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeCBOR(buf *bytes.Buffer, v interface{}) error { // nolint: gocyclo
+	switch value := v.(type) {
+	case nil:
+		buf.WriteByte(majorSimple<<5 | simpleNull)
+	case bool:
+		if value {
+			buf.WriteByte(majorSimple<<5 | simpleTrue)
+		} else {
+			buf.WriteByte(majorSimple<<5 | simpleFalse)
+		}
+	case int:
+		encodeInt(buf, int64(value))
+	case int64:
+		encodeInt(buf, value)
+	case uint:
+		writeHead(buf, majorUint, uint64(value))
+	case uint64:
+		writeHead(buf, majorUint, value)
+	case float64:
+		encodeFloat(buf, value)
+	case string:
+		writeHead(buf, majorText, uint64(len(value)))
+		buf.WriteString(value)
+	case []byte:
+		writeHead(buf, majorBytes, uint64(len(value)))
+		buf.Write(value)
+	case []interface{}:
+		writeHead(buf, majorArray, uint64(len(value)))
+
+		for _, item := range value {
+			if err := encodeCBOR(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		m := make(map[interface{}]interface{}, len(value))
+		for k, item := range value {
+			m[k] = item
+		}
+
+		return encodeMap(buf, m)
+	case map[int]interface{}:
+		m := make(map[interface{}]interface{}, len(value))
+		for k, item := range value {
+			m[k] = item
+		}
+
+		return encodeMap(buf, m)
+	case map[interface{}]interface{}:
+		return encodeMap(buf, value)
+	case *tagged:
+		writeHead(buf, majorTag, value.number)
+
+		return encodeCBOR(buf, value.content)
+	default:
+		return fmt.Errorf("unsupported CBOR value of type %T", v)
+	}
+
+	return nil
+}
+
+func encodeInt(buf *bytes.Buffer, value int64) {
+	if value < 0 {
+		writeHead(buf, majorNegInt, uint64(-(value + 1)))
+
+		return
+	}
+
+	writeHead(buf, majorUint, uint64(value))
+}
+
+// encodeFloat encodes the integral floats as integers, e.g. the numbers of the JSON credentials
+func encodeFloat(buf *bytes.Buffer, value float64) {
+	if value == math.Trunc(value) && math.Abs(value) < 1<<63 {
+		encodeInt(buf, int64(value))
+
+		return
+	}
+
+	buf.WriteByte(majorSimple<<5 | simpleFloat64)
+
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, math.Float64bits(value))
+	buf.Write(b)
+}
+
+func encodeMap(buf *bytes.Buffer, m map[interface{}]interface{}) error {
+	type entry struct {
+		key   []byte
+		value interface{}
+	}
+
+	entries := make([]entry, 0, len(m))
+
+	for k, v := range m {
+		key, err := marshalCBOR(k)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, entry{key: key, value: v})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+
+	writeHead(buf, majorMap, uint64(len(entries)))
+
+	for _, e := range entries {
+		buf.Write(e.key)
+
+		if err := encodeCBOR(buf, e.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeHead writes the shortest head of the major type with the argument
+func writeHead(buf *bytes.Buffer, major byte, arg uint64) {
+	switch {
+	case arg < 24:
+		buf.WriteByte(major<<5 | byte(arg))
+	case arg <= math.MaxUint8:
+		buf.Write([]byte{major<<5 | 24, byte(arg)})
+	case arg <= math.MaxUint16:
+		b := []byte{major<<5 | 25, 0, 0}
+		binary.BigEndian.PutUint16(b[1:], uint16(arg))
+		buf.Write(b)
+	case arg <= math.MaxUint32:
+		b := []byte{major<<5 | 26, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(b[1:], uint32(arg))
+		buf.Write(b)
+	default:
+		b := []byte{major<<5 | 27, 0, 0, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint64(b[1:], arg)
+		buf.Write(b)
+	}
+}
+
+var errTruncated = errors.New("truncated CBOR data")
+
+// unmarshalCBOR decodes the data item, the integers as int64 (uint64 if greater), the floats as float64, the maps as
+// map[interface{}]interface{} and the tags as *tagged. The indefinite lengths aren't supported.
+func unmarshalCBOR(data []byte) (interface{}, error) {
+	d := &decoder{data: data}
+
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.offset != len(data) {
+		return nil, errors.New("trailing CBOR data")
+	}
+
+	return v, nil
+}
+
+type decoder struct {
+	data   []byte
+	offset int
+}
+
+func (d *decoder) decode(depth int) (interface{}, error) { // nolint: gocyclo
+	if depth > maxDepth {
+		return nil, errors.New("CBOR data nested too deeply")
+	}
+
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case majorUint:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+
+		return int64(arg), nil
+	case majorNegInt:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("CBOR negative integer overflows int64")
+		}
+
+		return -int64(arg) - 1, nil
+	case majorBytes, majorText:
+		b, err := d.bytes(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		if major == majorText {
+			return string(b), nil
+		}
+
+		return append([]byte(nil), b...), nil
+	case majorArray:
+		return d.array(arg, depth)
+	case majorMap:
+		return d.mapItems(arg, depth)
+	case majorTag:
+		content, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		return &tagged{number: arg, content: content}, nil
+	default:
+		return simpleValue(info, arg)
+	}
+}
+
+func (d *decoder) array(length uint64, depth int) ([]interface{}, error) {
+	// each item takes a byte at least
+	if length > uint64(len(d.data)-d.offset) {
+		return nil, errTruncated
+	}
+
+	items := make([]interface{}, length)
+
+	for i := range items {
+		item, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		items[i] = item
+	}
+
+	return items, nil
+}
+
+func (d *decoder) mapItems(length uint64, depth int) (map[interface{}]interface{}, error) {
+	if length > uint64(len(d.data)-d.offset)/2 {
+		return nil, errTruncated
+	}
+
+	m := make(map[interface{}]interface{}, length)
+
+	for i := uint64(0); i < length; i++ {
+		key, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key.(type) {
+		case string, int64, uint64:
+		default:
+			return nil, fmt.Errorf("unsupported CBOR map key of type %T", key)
+		}
+
+		if _, ok := m[key]; ok {
+			return nil, fmt.Errorf("duplicate CBOR map key %v", key)
+		}
+
+		if m[key], err = d.decode(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func simpleValue(info byte, arg uint64) (interface{}, error) {
+	switch info {
+	case simpleFalse:
+		return false, nil
+	case simpleTrue:
+		return true, nil
+	case simpleNull:
+		return nil, nil
+	case simpleFloat16:
+		return float16(uint16(arg)), nil
+	case simpleFloat32:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case simpleFloat64:
+		return math.Float64frombits(arg), nil
+	default:
+		return nil, fmt.Errorf("unsupported CBOR simple value %d", info)
+	}
+}
+
+// float16 converts the IEEE 754 half-precision float
+func float16(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+
+	var v float64
+
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+
+	if h&0x8000 != 0 {
+		return -v
+	}
+
+	return v
+}
+
+// head reads the head of the next data item: its major type, additional information and argument
+func (d *decoder) head() (byte, byte, uint64, error) {
+	if d.offset >= len(d.data) {
+		return 0, 0, 0, errTruncated
+	}
+
+	initial := d.data[d.offset]
+	d.offset++
+
+	major, info := initial>>5, initial&0x1f
+
+	if info < 24 {
+		return major, info, uint64(info), nil
+	}
+
+	if info > 27 {
+		return 0, 0, 0, fmt.Errorf("unsupported CBOR additional information %d", info)
+	}
+
+	b, err := d.bytes(1 << (info - 24))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var arg uint64
+	for _, x := range b {
+		arg = arg<<8 | uint64(x)
+	}
+
+	return major, info, arg, nil
+}
+
+func (d *decoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.offset) {
+		return nil, errTruncated
+	}
+
+	b := d.data[d.offset : d.offset+int(n)]
+	d.offset += int(n)
+
+	return b, nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mdoc
+
+import (
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalCBOR(t *testing.T) {
+	// the examples of RFC 8949, appendix A
+	for _, test := range []struct {
+		value   interface{}
+		encoded string
+	}{
+		{value: 0, encoded: "00"},
+		{value: 23, encoded: "17"},
+		{value: 24, encoded: "1818"},
+		{value: int64(1000), encoded: "1903e8"},
+		{value: uint64(1000000), encoded: "1a000f4240"},
+		{value: uint(1000000000000), encoded: "1b000000e8d4a51000"},
+		{value: -1, encoded: "20"},
+		{value: -1000, encoded: "3903e7"},
+		{value: float64(100), encoded: "1864"},
+		{value: 1.1, encoded: "fb3ff199999999999a"},
+		{value: false, encoded: "f4"},
+		{value: true, encoded: "f5"},
+		{value: nil, encoded: "f6"},
+		{value: "IETF", encoded: "6449455446"},
+		{value: []byte{1, 2, 3, 4}, encoded: "4401020304"},
+		{value: []interface{}{1, []interface{}{2, 3}}, encoded: "8201820203"},
+		{value: map[string]interface{}{"b": []interface{}{2, 3}, "a": 1}, encoded: "a26161016162820203"},
+		{value: map[int]interface{}{-1: 2, 1: 1}, encoded: "a201012002"},
+		{value: &tagged{number: tagFullDate, content: "2013-03-21"}, encoded: "d903ec6a323031332d30332d3231"},
+	} {
+		encoded, err := marshalCBOR(test.value)
+		require.NoError(t, err)
+		require.Equal(t, test.encoded, hex.EncodeToString(encoded), "%v", test.value)
+	}
+
+	_, err := marshalCBOR(struct{}{})
+	require.EqualError(t, err, "unsupported CBOR value of type struct {}")
+
+	_, err = marshalCBOR([]interface{}{map[string]interface{}{"a": struct{}{}}})
+	require.Error(t, err)
+}
+
+func TestUnmarshalCBOR(t *testing.T) {
+	for _, test := range []struct {
+		encoded string
+		value   interface{}
+	}{
+		{encoded: "1b000000e8d4a51000", value: int64(1000000000000)},
+		{encoded: "1bffffffffffffffff", value: uint64(math.MaxUint64)},
+		{encoded: "3903e7", value: int64(-1000)},
+		{encoded: "f93c00", value: 1.0},
+		{encoded: "f97bff", value: 65504.0},
+		{encoded: "f9c400", value: -4.0},
+		{encoded: "f90001", value: 5.960464477539063e-8},
+		{encoded: "f97c00", value: math.Inf(1)},
+		{encoded: "fa47c35000", value: 100000.0},
+		{encoded: "fb3ff199999999999a", value: 1.1},
+		{encoded: "f4", value: false},
+		{encoded: "f5", value: true},
+		{encoded: "f6", value: nil},
+		{encoded: "6449455446", value: "IETF"},
+		{encoded: "4401020304", value: []byte{1, 2, 3, 4}},
+		{encoded: "8201820203", value: []interface{}{int64(1), []interface{}{int64(2), int64(3)}}},
+		{encoded: "a201022003", value: map[interface{}]interface{}{int64(1): int64(2), int64(-1): int64(3)}},
+		{encoded: "d818430a0b0c", value: &tagged{number: tagEncodedCBOR, content: []byte{10, 11, 12}}},
+	} {
+		encoded, err := hex.DecodeString(test.encoded)
+		require.NoError(t, err)
+
+		value, err := unmarshalCBOR(encoded)
+		require.NoError(t, err)
+		require.Equal(t, test.value, value, test.encoded)
+	}
+
+	value, err := unmarshalCBOR([]byte{0xf9, 0x7e, 0x00})
+	require.NoError(t, err)
+	require.True(t, math.IsNaN(value.(float64)))
+
+	for encoded, msg := range map[string]string{
+		"":                                      "truncated CBOR data",
+		"19":                                    "truncated CBOR data",
+		"6449":                                  "truncated CBOR data",
+		"9a0fffffff":                            "truncated CBOR data",
+		"ba0fffffff":                            "truncated CBOR data",
+		"0000":                                  "trailing CBOR data",
+		"9f":                                    "unsupported CBOR additional information 31",
+		"3bffffffffffffffff":                    "CBOR negative integer overflows int64",
+		"a2616101616102":                        "duplicate CBOR map key a",
+		"a14001":                                "unsupported CBOR map key of type []uint8",
+		"f7":                                    "unsupported CBOR simple value 23",
+		strings.Repeat("81", maxDepth+1) + "00": "CBOR data nested too deeply",
+	} {
+		data, err := hex.DecodeString(encoded)
+		require.NoError(t, err)
+
+		_, err = unmarshalCBOR(data)
+		require.EqualError(t, err, msg, encoded)
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mdoc
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+
+	"github.com/trustbloc/edge-service/pkg/internal/common/pubkey"
+)
+
+// the COSE algorithms of the keys of the profiles (RFC 8152, RFC 8812)
+const (
+	AlgorithmEdDSA  = -8
+	AlgorithmES256  = -7
+	AlgorithmES384  = -35
+	AlgorithmES512  = -36
+	AlgorithmES256K = -47
+	AlgorithmPS256  = -37
+)
+
+// the COSE header and key parameters
+const (
+	headerAlgorithm = 1
+	headerKeyID     = 4
+
+	keyType  = 1
+	keyCurve = -1
+	keyX     = -2
+	keyY     = -3
+
+	keyTypeOKP = 1
+	keyTypeEC2 = 2
+
+	curveP256    = 1
+	curveP384    = 2
+	curveP521    = 3
+	curveEd25519 = 6
+
+	sign1Context = "Signature1"
+)
+
+// coseAlgorithms are the COSE algorithms of the signature algorithms of the keys
+// nolint: gochecknoglobals
+var coseAlgorithms = map[string]int{
+	pubkey.AlgorithmEdDSA:  AlgorithmEdDSA,
+	pubkey.AlgorithmES256:  AlgorithmES256,
+	pubkey.AlgorithmES384:  AlgorithmES384,
+	pubkey.AlgorithmES512:  AlgorithmES512,
+	pubkey.AlgorithmES256K: AlgorithmES256K,
+	pubkey.AlgorithmPS256:  AlgorithmPS256,
+}
+
+// Signer signs the mobile security objects with the key of the issuer
+type Signer interface {
+	Sign(data []byte) ([]byte, error)
+}
+
+// Algorithm returns the COSE algorithm of the public key of a verification method: EdDSA for the Ed25519 keys, ES256,
+// ES384, ES512 or ES256K for the EC keys and PS256 for the RSA keys, the signatures of the keys of the profiles
+func Algorithm(key *verifier.PublicKey) (int, error) {
+	alg, err := pubkey.Algorithm(key)
+	if err != nil {
+		return 0, err
+	}
+
+	return coseAlgorithms[alg], nil
+}
+
+// sign1 returns the COSE_Sign1 (untagged) of the payload signed by the signer with the algorithm, its unprotected
+// header having the key ID
+func sign1(payload []byte, alg int, keyID string, signer Signer) ([]interface{}, error) {
+	protected, err := marshalCBOR(map[int]interface{}{headerAlgorithm: alg})
+	if err != nil {
+		return nil, err
+	}
+
+	toBeSigned, err := sigStructure(protected, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signer.Sign(toBeSigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the mobile security object: %w", err)
+	}
+
+	return []interface{}{protected, map[int]interface{}{headerKeyID: []byte(keyID)}, payload, signature}, nil
+}
+
+// sign1Message is a decoded COSE_Sign1
+type sign1Message struct {
+	protected []byte
+	alg       int64
+	keyID     string
+	payload   []byte
+	signature []byte
+}
+
+// parseSign1 parses the COSE_Sign1, the algorithm being in its protected header and the key ID in either header
+func parseSign1(v interface{}) (*sign1Message, error) {
+	if t, ok := v.(*tagged); ok && t.number == 18 {
+		v = t.content
+	}
+
+	items, ok := v.([]interface{})
+	if !ok || len(items) != 4 {
+		return nil, errors.New("the issuer authentication is not a COSE_Sign1")
+	}
+
+	msg := &sign1Message{}
+
+	msg.protected, ok = items[0].([]byte)
+	if !ok {
+		return nil, errors.New("invalid protected header")
+	}
+
+	protected, err := unmarshalCBOR(msg.protected)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protected header: %w", err)
+	}
+
+	protectedHeader, _ := protected.(map[interface{}]interface{})  // nolint: errcheck
+	unprotectedHeader, _ := items[1].(map[interface{}]interface{}) // nolint: errcheck
+
+	if msg.alg, ok = protectedHeader[int64(headerAlgorithm)].(int64); !ok {
+		return nil, errors.New("missing algorithm in the protected header")
+	}
+
+	keyID, ok := protectedHeader[int64(headerKeyID)].([]byte)
+	if !ok {
+		keyID, ok = unprotectedHeader[int64(headerKeyID)].([]byte)
+	}
+
+	if !ok {
+		return nil, errors.New("missing key ID")
+	}
+
+	msg.keyID = string(keyID)
+
+	if msg.payload, ok = items[2].([]byte); !ok {
+		return nil, errors.New("missing payload")
+	}
+
+	if msg.signature, ok = items[3].([]byte); !ok {
+		return nil, errors.New("missing signature")
+	}
+
+	return msg, nil
+}
+
+// verify verifies the signature of the message with the public key
+func (msg *sign1Message) verify(key *verifier.PublicKey) error {
+	alg, err := Algorithm(key)
+	if err != nil {
+		return err
+	}
+
+	if int64(alg) != msg.alg {
+		return fmt.Errorf("algorithm %d doesn't match the algorithm %d of the key", msg.alg, alg)
+	}
+
+	toBeSigned, err := sigStructure(msg.protected, msg.payload)
+	if err != nil {
+		return err
+	}
+
+	if !pubkey.Verify(key, toBeSigned, msg.signature) {
+		return errors.New("invalid signature")
+	}
+
+	return nil
+}
+
+// sigStructure returns the data signed by a COSE_Sign1, without external data
+func sigStructure(protected, payload []byte) ([]byte, error) {
+	return marshalCBOR([]interface{}{sign1Context, protected, []byte{}, payload})
+}
+
+// coseKey returns the COSE_Key of the Ed25519 or EC public key
+func coseKey(key *jose.JWK) (map[int]interface{}, error) {
+	switch pubKey := key.Key.(type) {
+	case ed25519.PublicKey:
+		return map[int]interface{}{keyType: keyTypeOKP, keyCurve: curveEd25519, keyX: []byte(pubKey)}, nil
+	case *ecdsa.PublicKey:
+		curves := map[string]int{"P-256": curveP256, "P-384": curveP384, "P-521": curveP521}
+
+		curve, ok := curves[pubKey.Curve.Params().Name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported device key curve %s", pubKey.Curve.Params().Name)
+		}
+
+		size := (pubKey.Curve.Params().BitSize + 7) / 8
+
+		return map[int]interface{}{keyType: keyTypeEC2, keyCurve: curve,
+			keyX: padded(pubKey.X.Bytes(), size), keyY: padded(pubKey.Y.Bytes(), size)}, nil
+	default:
+		return nil, errors.New("the device key must be a public Ed25519 or EC key")
+	}
+}
+
+// padded left pads the big-endian integer to the size
+func padded(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+
+	return append(make([]byte, size-len(b)), b...)
+}
+
+// ed25519PublicKey returns the Ed25519 public key, nil if invalid
+func ed25519PublicKey(x []byte) interface{} {
+	if len(x) != ed25519.PublicKeySize {
+		return nil
+	}
+
+	return ed25519.PublicKey(x)
+}
+
+// ecPublicKey returns the EC public key of the COSE curve, nil if invalid
+func ecPublicKey(curve interface{}, x, y []byte) interface{} {
+	curves := map[int64]elliptic.Curve{curveP256: elliptic.P256(), curveP384: elliptic.P384(),
+		curveP521: elliptic.P521()}
+
+	id, _ := curve.(int64) // nolint: errcheck
+
+	c, ok := curves[id]
+	if !ok {
+		return nil
+	}
+
+	pubKey := &ecdsa.PublicKey{Curve: c, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	if !c.IsOnCurve(pubKey.X, pubKey.Y) {
+		return nil
+	}
+
+	return pubKey
+}
@@ -0,0 +1,460 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mdoc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+)
+
+const (
+	// DocTypeMDL is the document type of the mobile driving licences (ISO/IEC 18013-5)
+	DocTypeMDL = "org.iso.18013.5.1.mDL"
+	// NamespaceMDL is the namespace of the data elements of the mobile driving licences
+	NamespaceMDL = "org.iso.18013.5.1"
+
+	msoVersion      = "1.0"
+	digestAlgorithm = "SHA-256"
+	randomSize      = 16
+)
+
+// fullDate matches the dates of the credential subjects, encoded as the full-date data elements, e.g. birth_date
+var fullDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`) // nolint: gochecknoglobals
+
+// Issuance is the mdoc issued from a credential: its data elements are the claims of the credential subject (except
+// its id) in the namespace, signed by the issuer with the key of the verification method for the device key of the
+// holder
+type Issuance struct {
+	// DocType is the document type, DocTypeMDL by default.
+	DocType string
+	// Namespace is the namespace of the data elements, NamespaceMDL by default.
+	Namespace string
+	// DeviceKey is the public key of the holder, an Ed25519 or EC key.
+	DeviceKey *jose.JWK
+	// VerificationMethod is the verification method of the signing key, the key ID of the issuer authentication.
+	VerificationMethod string
+	// Algorithm is the COSE algorithm of the signing key, see Algorithm.
+	Algorithm int
+	Signer    Signer
+}
+
+// Document is a verified mdoc
+type Document struct {
+	DocType string `json:"docType"`
+	// Issuer is the DID of the verification method signing the mdoc.
+	Issuer     string    `json:"issuer"`
+	Signed     time.Time `json:"signed"`
+	ValidFrom  time.Time `json:"validFrom"`
+	ValidUntil time.Time `json:"validUntil"`
+	// Claims are the data elements, by namespace and element identifier.
+	Claims    map[string]map[string]interface{} `json:"claims"`
+	DeviceKey *jose.JWK                         `json:"deviceKey"`
+}
+
+// Issue issues the credential as the IssuerSigned structure of a mdoc, CBOR encoded. The credential must expire: the
+// mdoc is valid from its issuance date until its expiration date.
+func Issue(vc *verifiable.Credential, issuance *Issuance) ([]byte, error) { // nolint: funlen
+	if vc.Issued == nil || vc.Expired == nil {
+		return nil, errors.New("mdocs require the issuance and expiration dates of the credential")
+	}
+
+	if issuance.DeviceKey == nil {
+		return nil, errors.New("missing device key")
+	}
+
+	deviceKey, err := coseKey(issuance.DeviceKey)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := subjectClaims(vc)
+	if err != nil {
+		return nil, err
+	}
+
+	docType, namespace := issuance.DocType, issuance.Namespace
+	if docType == "" {
+		docType = DocTypeMDL
+	}
+
+	if namespace == "" {
+		namespace = NamespaceMDL
+	}
+
+	items := make([]interface{}, 0, len(claims))
+	digests := make(map[int]interface{}, len(claims))
+
+	for i, element := range sortedElements(claims) {
+		item, digest, err := issuerSignedItem(i, element, claims[element])
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, item)
+		digests[i] = digest
+	}
+
+	mso := map[string]interface{}{
+		"version":         msoVersion,
+		"digestAlgorithm": digestAlgorithm,
+		"valueDigests":    map[string]interface{}{namespace: digests},
+		"deviceKeyInfo":   map[string]interface{}{"deviceKey": deviceKey},
+		"docType":         docType,
+		"validityInfo": map[string]interface{}{
+			"signed":     tdate(time.Now()),
+			"validFrom":  tdate(vc.Issued.Time),
+			"validUntil": tdate(vc.Expired.Time),
+		},
+	}
+
+	msoBytes, err := encodedCBOR(mso)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := marshalCBOR(msoBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	issuerAuth, err := sign1(payload, issuance.Algorithm, issuance.VerificationMethod, issuance.Signer)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalCBOR(map[string]interface{}{
+		"nameSpaces": map[string]interface{}{namespace: items},
+		"issuerAuth": issuerAuth,
+	})
+}
+
+// subjectClaims returns the claims of the single subject of the credential, except its id
+func subjectClaims(vc *verifiable.Credential) (map[string]interface{}, error) {
+	vcBytes, err := vc.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := struct {
+		Subject interface{} `json:"credentialSubject"`
+	}{}
+
+	if err = json.Unmarshal(vcBytes, &doc); err != nil {
+		return nil, err
+	}
+
+	if subjects, ok := doc.Subject.([]interface{}); ok && len(subjects) == 1 {
+		doc.Subject = subjects[0]
+	}
+
+	claims, ok := doc.Subject.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("mdocs require a single credential subject")
+	}
+
+	delete(claims, "id")
+
+	if len(claims) == 0 {
+		return nil, errors.New("no claims in the credential subject")
+	}
+
+	return claims, nil
+}
+
+func sortedElements(claims map[string]interface{}) []string {
+	elements := make([]string, 0, len(claims))
+	for element := range claims {
+		elements = append(elements, element)
+	}
+
+	sort.Strings(elements)
+
+	return elements
+}
+
+// issuerSignedItem returns the salted data element and its digest, the digest of its tagged encoding
+func issuerSignedItem(digestID int, element string, value interface{}) (*tagged, []byte, error) {
+	random := make([]byte, randomSize)
+
+	if _, err := rand.Read(random); err != nil {
+		return nil, nil, err
+	}
+
+	if s, ok := value.(string); ok && fullDate.MatchString(s) {
+		value = &tagged{number: tagFullDate, content: s}
+	}
+
+	item, err := encodedCBOR(map[string]interface{}{
+		"digestID":          digestID,
+		"random":            random,
+		"elementIdentifier": element,
+		"elementValue":      value,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode the data element %s: %w", element, err)
+	}
+
+	itemBytes, err := marshalCBOR(item)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	digest := sha256.Sum256(itemBytes)
+
+	return item, digest[:], nil
+}
+
+func tdate(t time.Time) *tagged {
+	return &tagged{number: tagDateTime, content: t.UTC().Truncate(time.Second).Format(time.RFC3339)}
+}
+
+// Verify verifies the IssuerSigned structure of a mdoc: the signature of its mobile security object with the key of
+// its verification method, fetched by the fetcher as a linked data proof key, its validity at the time and the
+// digests of its data elements
+func Verify(issuerSigned []byte, fetcher verifiable.PublicKeyFetcher, now time.Time) (*Document, error) {
+	decoded, err := unmarshalCBOR(issuerSigned)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mdoc: %w", err)
+	}
+
+	root, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("invalid mdoc: not a map")
+	}
+
+	msg, err := parseSign1(root["issuerAuth"])
+	if err != nil {
+		return nil, err
+	}
+
+	split := strings.SplitN(msg.keyID, "#", 2)
+	if len(split) != 2 {
+		return nil, fmt.Errorf("the key ID %s is not a verification method", msg.keyID)
+	}
+
+	key, err := fetcher(split[0], "#"+split[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the key %s: %w", msg.keyID, err)
+	}
+
+	if err = msg.verify(key); err != nil {
+		return nil, fmt.Errorf("invalid issuer authentication: %w", err)
+	}
+
+	doc, digests, err := parseMSO(msg.payload)
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Issuer = split[0]
+
+	if now.Before(doc.ValidFrom) || !now.Before(doc.ValidUntil) {
+		return nil, fmt.Errorf("the mdoc is only valid from %s until %s", doc.ValidFrom.Format(time.RFC3339),
+			doc.ValidUntil.Format(time.RFC3339))
+	}
+
+	namespaces, ok := root["nameSpaces"].(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("invalid mdoc: missing name spaces")
+	}
+
+	doc.Claims = make(map[string]map[string]interface{}, len(namespaces))
+
+	for ns, items := range namespaces {
+		namespace, _ := ns.(string) // nolint: errcheck
+
+		claims, err := verifyItems(items, digests[namespace])
+		if err != nil {
+			return nil, fmt.Errorf("invalid data elements of %s: %w", namespace, err)
+		}
+
+		doc.Claims[namespace] = claims
+	}
+
+	return doc, nil
+}
+
+// parseMSO parses the mobile security object of the payload, returned with its value digests by namespace
+func parseMSO(payload []byte) (*Document, map[string]map[interface{}]interface{}, error) { // nolint: gocyclo
+	embedded, err := unmarshalCBOR(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid mobile security object: %w", err)
+	}
+
+	msoBytes, ok := embeddedCBOR(embedded)
+	if !ok {
+		return nil, nil, errors.New("invalid mobile security object: not an encoded CBOR data item")
+	}
+
+	decoded, err := unmarshalCBOR(msoBytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid mobile security object: %w", err)
+	}
+
+	mso, ok := decoded.(map[interface{}]interface{})
+	if !ok || mso["version"] != msoVersion || mso["digestAlgorithm"] != digestAlgorithm {
+		return nil, nil, fmt.Errorf("unsupported mobile security object: version %v, digest algorithm %v",
+			mso["version"], mso["digestAlgorithm"])
+	}
+
+	doc := &Document{}
+
+	if doc.DocType, ok = mso["docType"].(string); !ok {
+		return nil, nil, errors.New("invalid mobile security object: missing doc type")
+	}
+
+	validity, _ := mso["validityInfo"].(map[interface{}]interface{}) // nolint: errcheck
+
+	for name, t := range map[string]*time.Time{"signed": &doc.Signed, "validFrom": &doc.ValidFrom,
+		"validUntil": &doc.ValidUntil} {
+		if *t, err = parseTDate(validity[name]); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s of the mobile security object: %w", name, err)
+		}
+	}
+
+	deviceKeyInfo, _ := mso["deviceKeyInfo"].(map[interface{}]interface{}) // nolint: errcheck
+
+	if doc.DeviceKey, err = parseCOSEKey(deviceKeyInfo["deviceKey"]); err != nil {
+		return nil, nil, fmt.Errorf("invalid device key: %w", err)
+	}
+
+	valueDigests, _ := mso["valueDigests"].(map[interface{}]interface{}) // nolint: errcheck
+	digests := make(map[string]map[interface{}]interface{}, len(valueDigests))
+
+	for ns, nsDigests := range valueDigests {
+		namespace, _ := ns.(string)                                     // nolint: errcheck
+		digests[namespace], _ = nsDigests.(map[interface{}]interface{}) // nolint: errcheck
+	}
+
+	return doc, digests, nil
+}
+
+// verifyItems returns the data elements of the namespace, their digests being the value digests of the namespace
+func verifyItems(v interface{}, digests map[interface{}]interface{}) (map[string]interface{}, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("not an array")
+	}
+
+	claims := make(map[string]interface{}, len(items))
+
+	for _, item := range items {
+		itemBytes, err := marshalCBOR(item)
+		if err != nil {
+			return nil, err
+		}
+
+		encoded, ok := embeddedCBOR(item)
+		if !ok {
+			return nil, errors.New("data element not an encoded CBOR data item")
+		}
+
+		decoded, err := unmarshalCBOR(encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		signedItem, _ := decoded.(map[interface{}]interface{}) // nolint: errcheck
+
+		element, ok := signedItem["elementIdentifier"].(string)
+		if !ok {
+			return nil, errors.New("missing element identifier")
+		}
+
+		digest, ok := digests[signedItem["digestID"]].([]byte)
+		if actual := sha256.Sum256(itemBytes); !ok || string(digest) != string(actual[:]) {
+			return nil, fmt.Errorf("the digest of %s doesn't match the mobile security object", element)
+		}
+
+		claims[element] = jsonValue(signedItem["elementValue"])
+	}
+
+	return claims, nil
+}
+
+// embeddedCBOR returns the encoding embedded by the #6.24(bstr) data item
+func embeddedCBOR(v interface{}) ([]byte, bool) {
+	t, ok := v.(*tagged)
+	if !ok || t.number != tagEncodedCBOR {
+		return nil, false
+	}
+
+	b, ok := t.content.([]byte)
+
+	return b, ok
+}
+
+func parseTDate(v interface{}) (time.Time, error) {
+	t, ok := v.(*tagged)
+	if !ok || t.number != tagDateTime {
+		return time.Time{}, errors.New("not a date-time")
+	}
+
+	s, _ := t.content.(string) // nolint: errcheck
+
+	return time.Parse(time.RFC3339, s)
+}
+
+// parseCOSEKey returns the JWK of the Ed25519 or EC COSE_Key
+func parseCOSEKey(v interface{}) (*jose.JWK, error) {
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("not a COSE_Key")
+	}
+
+	x, _ := m[int64(keyX)].([]byte) // nolint: errcheck
+	y, _ := m[int64(keyY)].([]byte) // nolint: errcheck
+
+	var pubKey interface{}
+
+	switch {
+	case m[int64(keyType)] == int64(keyTypeOKP) && m[int64(keyCurve)] == int64(curveEd25519):
+		pubKey = ed25519PublicKey(x)
+	case m[int64(keyType)] == int64(keyTypeEC2):
+		pubKey = ecPublicKey(m[int64(keyCurve)], x, y)
+	}
+
+	if pubKey == nil {
+		return nil, errors.New("unsupported COSE_Key")
+	}
+
+	return jose.JWKFromPublicKey(pubKey)
+}
+
+// jsonValue returns the value of the data element as JSON, e.g. the dates as strings
+func jsonValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case *tagged:
+		return jsonValue(value.content)
+	case []interface{}:
+		values := make([]interface{}, len(value))
+		for i, item := range value {
+			values[i] = jsonValue(item)
+		}
+
+		return values
+	case map[interface{}]interface{}:
+		values := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			values[fmt.Sprint(k)] = jsonValue(item)
+		}
+
+		return values
+	default:
+		return v
+	}
+}
@@ -0,0 +1,351 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mdoc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/internal/common/pubkey"
+)
+
+const (
+	issuerDID          = "did:example:76e12ec712ebc6f1c221ebfeb1f"
+	verificationMethod = issuerDID + "#key-1"
+
+	licence = `{
+		"@context": ["https://www.w3.org/2018/credentials/v1"],
+		"type": ["VerifiableCredential"],
+		"issuer": "` + issuerDID + `",
+		"issuanceDate": "2020-01-01T00:00:00Z",
+		"expirationDate": "2030-01-01T00:00:00Z",
+		"credentialSubject": {
+			"id": "did:example:ebfeb1f712ebc6f1c276e12ec21",
+			"family_name": "Doe",
+			"given_name": "Jayden",
+			"birth_date": "1958-07-17",
+			"document_number": "542426814",
+			"driving_privileges": [{"vehicle_category_code": "A", "issue_date": "2018-08-09"}],
+			"age_over_18": true,
+			"height": 175
+		}
+	}`
+)
+
+// ecdsaSigner signs with IEEE P1363 (r || s) signatures, as the kms
+type ecdsaSigner struct {
+	key  *ecdsa.PrivateKey
+	hash crypto.Hash
+}
+
+func (s *ecdsaSigner) Sign(data []byte) ([]byte, error) {
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, pubkey.Digest(s.hash, data))
+	if err != nil {
+		return nil, err
+	}
+
+	size := (s.key.Curve.Params().BitSize + 7) / 8
+
+	return append(padded(r.Bytes(), size), padded(sig.Bytes(), size)...), nil
+}
+
+type rsaSigner struct {
+	key *rsa.PrivateKey
+}
+
+func (s *rsaSigner) Sign(data []byte) ([]byte, error) {
+	return rsa.SignPSS(rand.Reader, s.key, crypto.SHA256, pubkey.Digest(crypto.SHA256, data), nil)
+}
+
+type failingSigner struct{}
+
+func (s *failingSigner) Sign([]byte) ([]byte, error) {
+	return nil, errors.New("sign error")
+}
+
+func jwkKey(t *testing.T, pubKey interface{}) *verifier.PublicKey {
+	key, err := jose.JWKFromPublicKey(pubKey)
+	require.NoError(t, err)
+
+	return &verifier.PublicKey{Type: "JwsVerificationKey2020", JWK: key}
+}
+
+func keyFetcher(key *verifier.PublicKey) verifiable.PublicKeyFetcher {
+	return func(issuerID, keyID string) (*verifier.PublicKey, error) {
+		if issuerID+keyID != verificationMethod {
+			return nil, errors.New("key not found")
+		}
+
+		return key, nil
+	}
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(licence))
+	require.NoError(t, err)
+
+	devicePub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	deviceKey, err := jose.JWKFromPublicKey(devicePub)
+	require.NoError(t, err)
+
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, test := range map[string]struct {
+		signer Signer
+		key    *verifier.PublicKey
+		alg    int
+	}{
+		"EdDSA": {signer: signature.GetEd25519Signer(edPriv, edPub),
+			key: &verifier.PublicKey{Type: "Ed25519VerificationKey2018", Value: edPub}, alg: AlgorithmEdDSA},
+		"EdDSA JWK": {signer: signature.GetEd25519Signer(edPriv, edPub), key: jwkKey(t, edPub), alg: AlgorithmEdDSA},
+		"ES256": {signer: &ecdsaSigner{key: p256, hash: crypto.SHA256}, key: jwkKey(t, &p256.PublicKey),
+			alg: AlgorithmES256},
+		"ES384": {signer: &ecdsaSigner{key: p384, hash: crypto.SHA384}, key: jwkKey(t, &p384.PublicKey),
+			alg: AlgorithmES384},
+		"PS256": {signer: &rsaSigner{key: rsaKey}, key: jwkKey(t, &rsaKey.PublicKey), alg: AlgorithmPS256},
+	} {
+		test := test
+
+		t.Run("test "+name, func(t *testing.T) {
+			alg, err := Algorithm(test.key)
+			require.NoError(t, err)
+			require.Equal(t, test.alg, alg)
+
+			issuerSigned, err := Issue(vc, &Issuance{DeviceKey: deviceKey, VerificationMethod: verificationMethod,
+				Algorithm: alg, Signer: test.signer})
+			require.NoError(t, err)
+
+			doc, err := Verify(issuerSigned, keyFetcher(test.key), now)
+			require.NoError(t, err)
+			require.Equal(t, DocTypeMDL, doc.DocType)
+			require.Equal(t, issuerDID, doc.Issuer)
+			require.Equal(t, vc.Issued.Time, doc.ValidFrom)
+			require.Equal(t, vc.Expired.Time, doc.ValidUntil)
+			require.Equal(t, []byte(devicePub), []byte(doc.DeviceKey.Key.(ed25519.PublicKey)))
+			require.Equal(t, map[string]map[string]interface{}{NamespaceMDL: {
+				"family_name":     "Doe",
+				"given_name":      "Jayden",
+				"birth_date":      "1958-07-17",
+				"document_number": "542426814",
+				"driving_privileges": []interface{}{
+					map[string]interface{}{"vehicle_category_code": "A", "issue_date": "2018-08-09"}},
+				"age_over_18": true,
+				"height":      int64(175),
+			}}, doc.Claims)
+		})
+	}
+
+	t.Run("test document type and namespace", func(t *testing.T) {
+		deviceKey, err := jose.JWKFromPublicKey(&p256.PublicKey)
+		require.NoError(t, err)
+
+		issuerSigned, err := Issue(vc, &Issuance{DocType: "org.example.licence", Namespace: "org.example",
+			DeviceKey: deviceKey, VerificationMethod: verificationMethod, Algorithm: AlgorithmEdDSA,
+			Signer: signature.GetEd25519Signer(edPriv, edPub)})
+		require.NoError(t, err)
+
+		doc, err := Verify(issuerSigned, keyFetcher(jwkKey(t, edPub)), now)
+		require.NoError(t, err)
+		require.Equal(t, "org.example.licence", doc.DocType)
+		require.Contains(t, doc.Claims, "org.example")
+		require.Equal(t, &p256.PublicKey, doc.DeviceKey.Key)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(licence))
+	require.NoError(t, err)
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	deviceKey, err := jose.JWKFromPublicKey(pubKey)
+	require.NoError(t, err)
+
+	key := &verifier.PublicKey{Type: "Ed25519VerificationKey2018", Value: pubKey}
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	issue := func(t *testing.T, method string) map[interface{}]interface{} {
+		issuerSigned, err := Issue(vc, &Issuance{DeviceKey: deviceKey, VerificationMethod: method,
+			Algorithm: AlgorithmEdDSA, Signer: signature.GetEd25519Signer(privKey, pubKey)})
+		require.NoError(t, err)
+
+		decoded, err := unmarshalCBOR(issuerSigned)
+		require.NoError(t, err)
+
+		return decoded.(map[interface{}]interface{})
+	}
+
+	verify := func(t *testing.T, root interface{}, key *verifier.PublicKey, now time.Time) error {
+		issuerSigned, err := marshalCBOR(root)
+		require.NoError(t, err)
+
+		_, err = Verify(issuerSigned, keyFetcher(key), now)
+
+		return err
+	}
+
+	t.Run("test tampered data element", func(t *testing.T) {
+		root := issue(t, verificationMethod)
+
+		items := root["nameSpaces"].(map[interface{}]interface{})[NamespaceMDL].([]interface{})
+
+		item, err := encodedCBOR(map[string]interface{}{"digestID": 3, "random": []byte("random"),
+			"elementIdentifier": "family_name", "elementValue": "Smith"})
+		require.NoError(t, err)
+
+		items[3] = item
+
+		err = verify(t, root, key, now)
+		require.EqualError(t, err, "invalid data elements of "+NamespaceMDL+
+			": the digest of family_name doesn't match the mobile security object")
+	})
+
+	t.Run("test validity", func(t *testing.T) {
+		root := issue(t, verificationMethod)
+
+		require.NoError(t, verify(t, root, key, vc.Issued.Time))
+
+		err := verify(t, root, key, vc.Expired.Time)
+		require.EqualError(t, err, "the mdoc is only valid from 2020-01-01T00:00:00Z until 2030-01-01T00:00:00Z")
+
+		err = verify(t, root, key, vc.Issued.Time.Add(-time.Second))
+		require.Error(t, err)
+	})
+
+	t.Run("test signature", func(t *testing.T) {
+		root := issue(t, verificationMethod)
+
+		otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		err = verify(t, root, &verifier.PublicKey{Type: "Ed25519VerificationKey2018", Value: otherKey}, now)
+		require.EqualError(t, err, "invalid issuer authentication: invalid signature")
+
+		p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		err = verify(t, root, jwkKey(t, &p256.PublicKey), now)
+		require.EqualError(t, err, "invalid issuer authentication: algorithm -8 doesn't match the algorithm -7 "+
+			"of the key")
+
+		err = verify(t, root, &verifier.PublicKey{Type: "EcdsaSecp256k1VerificationKey2019"}, now)
+		require.EqualError(t, err, "invalid issuer authentication: unsupported key type "+
+			"EcdsaSecp256k1VerificationKey2019")
+	})
+
+	t.Run("test key ID", func(t *testing.T) {
+		err := verify(t, issue(t, issuerDID), key, now)
+		require.EqualError(t, err, "the key ID "+issuerDID+" is not a verification method")
+
+		err = verify(t, issue(t, issuerDID+"#key-2"), key, now)
+		require.EqualError(t, err, "failed to fetch the key "+issuerDID+"#key-2: key not found")
+	})
+
+	t.Run("test invalid mdoc", func(t *testing.T) {
+		_, err := Verify([]byte{0x9f}, keyFetcher(key), now)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid mdoc")
+
+		err = verify(t, []interface{}{}, key, now)
+		require.EqualError(t, err, "invalid mdoc: not a map")
+
+		root := issue(t, verificationMethod)
+		root["issuerAuth"] = []interface{}{}
+
+		err = verify(t, root, key, now)
+		require.EqualError(t, err, "the issuer authentication is not a COSE_Sign1")
+
+		root = issue(t, verificationMethod)
+		delete(root, "nameSpaces")
+
+		err = verify(t, root, key, now)
+		require.EqualError(t, err, "invalid mdoc: missing name spaces")
+	})
+}
+
+func TestIssue_Errors(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	deviceKey, err := jose.JWKFromPublicKey(pubKey)
+	require.NoError(t, err)
+
+	issuance := &Issuance{DeviceKey: deviceKey, VerificationMethod: verificationMethod,
+		Algorithm: AlgorithmEdDSA, Signer: signature.GetEd25519Signer(privKey, pubKey)}
+
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(licence))
+	require.NoError(t, err)
+
+	t.Run("test credential without expiration", func(t *testing.T) {
+		noExpiry := *vc
+		noExpiry.Expired = nil
+
+		_, err := Issue(&noExpiry, issuance)
+		require.EqualError(t, err, "mdocs require the issuance and expiration dates of the credential")
+	})
+
+	t.Run("test credential without claims", func(t *testing.T) {
+		noClaims := *vc
+		noClaims.Subject = map[string]interface{}{"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"}
+
+		_, err := Issue(&noClaims, issuance)
+		require.EqualError(t, err, "no claims in the credential subject")
+
+		noClaims.Subject = []string{"did:example:1", "did:example:2"}
+
+		_, err = Issue(&noClaims, issuance)
+		require.EqualError(t, err, "mdocs require a single credential subject")
+	})
+
+	t.Run("test device key", func(t *testing.T) {
+		_, err := Issue(vc, &Issuance{})
+		require.EqualError(t, err, "missing device key")
+
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		rsaDeviceKey := &jose.JWK{}
+		rsaDeviceKey.Key = &rsaKey.PublicKey
+
+		_, err = Issue(vc, &Issuance{DeviceKey: rsaDeviceKey})
+		require.EqualError(t, err, "the device key must be a public Ed25519 or EC key")
+	})
+
+	t.Run("test signing error", func(t *testing.T) {
+		_, err := Issue(vc, &Issuance{DeviceKey: deviceKey, VerificationMethod: verificationMethod,
+			Algorithm: AlgorithmEdDSA, Signer: &failingSigner{}})
+		require.EqualError(t, err, "failed to sign the mobile security object: sign error")
+	})
+}
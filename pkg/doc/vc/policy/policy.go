@@ -0,0 +1,360 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+)
+
+// the built-in rule types
+const (
+	// SubjectDID requires the IDs of the credential subjects to be DIDs
+	SubjectDID = "subjectDID"
+	// RequiredTypes requires the credential to have all the types of the rule values
+	RequiredTypes = "requiredTypes"
+	// AllowedTypes requires the types of the credential to be VerifiableCredential or one of the rule values
+	AllowedTypes = "allowedTypes"
+	// RequiredClaims requires the credential subjects to have all the claims of the rule values
+	RequiredClaims = "requiredClaims"
+	// DisallowedTypes requires the credential to have none of the types of the rule values
+	DisallowedTypes = "disallowedTypes"
+	// ClaimValues requires the credential subjects to have the claims of the rule values, given as claim=value
+	ClaimValues = "claimValues"
+	// NotExpired requires the credential to have no expiration date or one in the future
+	NotExpired = "notExpired"
+	// MaxAge requires the credential to be issued within the duration of the rule value (e.g. 720h) of now
+	MaxAge = "maxAge"
+
+	vcType = "VerifiableCredential"
+)
+
+// Rule is a rule of a policy, evaluated by the evaluator registered under its type
+type Rule struct {
+	Type string `json:"type"`
+	// Values are the parameters of the rule, e.g. the required types
+	Values []string `json:"values,omitempty"`
+}
+
+// Violation is the failure of a credential to comply with a rule
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Evaluator evaluates a rule on a credential, the returned error is the violation of the rule
+type Evaluator func(rule *Rule, vc *verifiable.Credential) error
+
+// Option configures the policy engine
+type Option func(e *Engine)
+
+// WithEvaluator registers the evaluator of the rules of the type, replacing the built-in evaluator if any
+func WithEvaluator(ruleType string, evaluator Evaluator) Option {
+	return func(e *Engine) {
+		e.evaluators[ruleType] = evaluator
+	}
+}
+
+// Engine evaluates the rules of the policies with the evaluators registered under their types
+type Engine struct {
+	evaluators map[string]Evaluator
+}
+
+// New returns a new policy engine with the built-in evaluators
+func New(opts ...Option) *Engine {
+	e := &Engine{evaluators: map[string]Evaluator{
+		SubjectDID:      evaluateSubjectDID,
+		RequiredTypes:   evaluateRequiredTypes,
+		AllowedTypes:    evaluateAllowedTypes,
+		RequiredClaims:  evaluateRequiredClaims,
+		DisallowedTypes: evaluateDisallowedTypes,
+		ClaimValues:     evaluateClaimValues,
+		NotExpired:      evaluateNotExpired,
+		MaxAge:          evaluateMaxAge,
+	}}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// ValidateRule checks that the rule can be evaluated: its type is registered, and the built-in rules taking
+// values have some.
+func (e *Engine) ValidateRule(rule *Rule) error {
+	if _, ok := e.evaluators[rule.Type]; !ok {
+		return fmt.Errorf("unsupported rule type: %s", rule.Type)
+	}
+
+	switch rule.Type {
+	case RequiredTypes, AllowedTypes, RequiredClaims, DisallowedTypes:
+		if len(rule.Values) == 0 {
+			return fmt.Errorf("rule %s requires values", rule.Type)
+		}
+	case ClaimValues:
+		if len(rule.Values) == 0 {
+			return fmt.Errorf("rule %s requires values", rule.Type)
+		}
+
+		for _, v := range rule.Values {
+			if _, _, err := parseClaimValue(v); err != nil {
+				return fmt.Errorf("rule %s: %w", rule.Type, err)
+			}
+		}
+	case MaxAge:
+		if _, err := parseMaxAge(rule); err != nil {
+			return fmt.Errorf("rule %s: %w", rule.Type, err)
+		}
+	}
+
+	return nil
+}
+
+// Evaluate evaluates the rules on the credential and returns the violations of the rules, none if the
+// credential complies with all of them. A rule whose type is not registered is violated.
+func (e *Engine) Evaluate(rules []Rule, vc *verifiable.Credential) []Violation {
+	var violations []Violation
+
+	for i := range rules {
+		rule := &rules[i]
+
+		evaluator, ok := e.evaluators[rule.Type]
+		if !ok {
+			violations = append(violations, Violation{Rule: rule.Type, Message: "unsupported rule type"})
+
+			continue
+		}
+
+		if err := evaluator(rule, vc); err != nil {
+			violations = append(violations, Violation{Rule: rule.Type, Message: err.Error()})
+		}
+	}
+
+	return violations
+}
+
+func evaluateSubjectDID(_ *Rule, vc *verifiable.Credential) error {
+	subjects := credentialSubjects(vc)
+	if len(subjects) == 0 {
+		return errors.New("credential has no subject")
+	}
+
+	for _, subject := range subjects {
+		id, ok := subject["id"].(string)
+		if !ok || !strings.HasPrefix(id, "did:") {
+			return fmt.Errorf("subject id is not a did: %v", subject["id"])
+		}
+	}
+
+	return nil
+}
+
+func evaluateRequiredTypes(rule *Rule, vc *verifiable.Credential) error {
+	var missing []string
+
+	for _, required := range rule.Values {
+		if !contains(vc.Types, required) {
+			missing = append(missing, required)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing credential types: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+func evaluateAllowedTypes(rule *Rule, vc *verifiable.Credential) error {
+	var disallowed []string
+
+	for _, t := range vc.Types {
+		if t != vcType && !contains(rule.Values, t) {
+			disallowed = append(disallowed, t)
+		}
+	}
+
+	if len(disallowed) > 0 {
+		return fmt.Errorf("credential types not allowed: %s", strings.Join(disallowed, ", "))
+	}
+
+	return nil
+}
+
+func evaluateRequiredClaims(rule *Rule, vc *verifiable.Credential) error {
+	subjects := credentialSubjects(vc)
+	if len(subjects) == 0 {
+		return errors.New("credential has no subject")
+	}
+
+	for _, subject := range subjects {
+		var missing []string
+
+		for _, claim := range rule.Values {
+			if _, ok := subject[claim]; !ok {
+				missing = append(missing, claim)
+			}
+		}
+
+		if len(missing) > 0 {
+			return fmt.Errorf("missing subject claims: %s", strings.Join(missing, ", "))
+		}
+	}
+
+	return nil
+}
+
+func evaluateDisallowedTypes(rule *Rule, vc *verifiable.Credential) error {
+	var disallowed []string
+
+	for _, t := range vc.Types {
+		if contains(rule.Values, t) {
+			disallowed = append(disallowed, t)
+		}
+	}
+
+	if len(disallowed) > 0 {
+		return fmt.Errorf("credential types not allowed: %s", strings.Join(disallowed, ", "))
+	}
+
+	return nil
+}
+
+func evaluateClaimValues(rule *Rule, vc *verifiable.Credential) error {
+	subjects := credentialSubjects(vc)
+	if len(subjects) == 0 {
+		return errors.New("credential has no subject")
+	}
+
+	for _, v := range rule.Values {
+		claim, value, err := parseClaimValue(v)
+		if err != nil {
+			return err
+		}
+
+		for _, subject := range subjects {
+			if actual, ok := subject[claim]; !ok || fmt.Sprint(actual) != value {
+				return fmt.Errorf("subject claim %s is not %s: %v", claim, value, actual)
+			}
+		}
+	}
+
+	return nil
+}
+
+func evaluateNotExpired(_ *Rule, vc *verifiable.Credential) error {
+	if vc.Expired != nil && !time.Now().Before(vc.Expired.Time) {
+		return fmt.Errorf("credential expired at %s", vc.Expired.Time.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func evaluateMaxAge(rule *Rule, vc *verifiable.Credential) error {
+	maxAge, err := parseMaxAge(rule)
+	if err != nil {
+		return err
+	}
+
+	if vc.Issued == nil {
+		return errors.New("credential has no issuance date")
+	}
+
+	if age := time.Since(vc.Issued.Time); age > maxAge {
+		return fmt.Errorf("credential issued at %s is older than %s", vc.Issued.Time.Format(time.RFC3339), maxAge)
+	}
+
+	return nil
+}
+
+// parseClaimValue returns the claim and the value of a claim=value rule value
+func parseClaimValue(v string) (string, string, error) {
+	i := strings.Index(v, "=")
+	if i <= 0 {
+		return "", "", fmt.Errorf("invalid claim value %q, expected claim=value", v)
+	}
+
+	return v[:i], v[i+1:], nil
+}
+
+// parseMaxAge returns the maximum age given by the single value of a maxAge rule
+func parseMaxAge(rule *Rule) (time.Duration, error) {
+	if len(rule.Values) != 1 {
+		return 0, errors.New("requires a single duration value")
+	}
+
+	maxAge, err := time.ParseDuration(rule.Values[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %w", err)
+	}
+
+	if maxAge <= 0 {
+		return 0, fmt.Errorf("invalid duration: %s is not positive", rule.Values[0])
+	}
+
+	return maxAge, nil
+}
+
+// Describe returns the violations as a single message, each violation given as rule: message
+func Describe(violations []Violation) string {
+	details := make([]string, len(violations))
+
+	for i, violation := range violations {
+		details[i] = violation.Rule + ": " + violation.Message
+	}
+
+	return strings.Join(details, "; ")
+}
+
+// credentialSubjects returns the subjects of the credential as JSON objects, a subject given by its ID only
+// being an object with the id claim.
+func credentialSubjects(vc *verifiable.Credential) []map[string]interface{} {
+	subjectBytes, err := json.Marshal(vc.Subject)
+	if err != nil {
+		return nil
+	}
+
+	var subject interface{}
+
+	if err = json.Unmarshal(subjectBytes, &subject); err != nil {
+		return nil
+	}
+
+	list, ok := subject.([]interface{})
+	if !ok {
+		list = []interface{}{subject}
+	}
+
+	var subjects []map[string]interface{}
+
+	for _, s := range list {
+		switch s := s.(type) {
+		case string:
+			subjects = append(subjects, map[string]interface{}{"id": s})
+		case map[string]interface{}:
+			subjects = append(subjects, s)
+		}
+	}
+
+	return subjects
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
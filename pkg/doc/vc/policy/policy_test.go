@@ -0,0 +1,170 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_ValidateRule(t *testing.T) {
+	e := New()
+
+	t.Run("success", func(t *testing.T) {
+		require.NoError(t, e.ValidateRule(&Rule{Type: SubjectDID}))
+		require.NoError(t, e.ValidateRule(&Rule{Type: RequiredTypes, Values: []string{"a"}}))
+		require.NoError(t, e.ValidateRule(&Rule{Type: AllowedTypes, Values: []string{"a"}}))
+		require.NoError(t, e.ValidateRule(&Rule{Type: RequiredClaims, Values: []string{"a"}}))
+	})
+
+	t.Run("verifier rules", func(t *testing.T) {
+		require.NoError(t, e.ValidateRule(&Rule{Type: NotExpired}))
+		require.NoError(t, e.ValidateRule(&Rule{Type: DisallowedTypes, Values: []string{"a"}}))
+		require.NoError(t, e.ValidateRule(&Rule{Type: ClaimValues, Values: []string{"a=b", "c="}}))
+		require.NoError(t, e.ValidateRule(&Rule{Type: MaxAge, Values: []string{"720h"}}))
+
+		require.EqualError(t, e.ValidateRule(&Rule{Type: ClaimValues}), "rule claimValues requires values")
+		require.EqualError(t, e.ValidateRule(&Rule{Type: ClaimValues, Values: []string{"=b"}}),
+			`rule claimValues: invalid claim value "=b", expected claim=value`)
+		require.EqualError(t, e.ValidateRule(&Rule{Type: MaxAge}), "rule maxAge: requires a single duration value")
+		require.Contains(t, e.ValidateRule(&Rule{Type: MaxAge, Values: []string{"1y"}}).Error(),
+			"rule maxAge: invalid duration")
+		require.EqualError(t, e.ValidateRule(&Rule{Type: MaxAge, Values: []string{"-1h"}}),
+			"rule maxAge: invalid duration: -1h is not positive")
+	})
+
+	t.Run("unsupported rule type", func(t *testing.T) {
+		err := e.ValidateRule(&Rule{Type: "other"})
+		require.EqualError(t, err, "unsupported rule type: other")
+	})
+
+	t.Run("missing values", func(t *testing.T) {
+		err := e.ValidateRule(&Rule{Type: RequiredTypes})
+		require.EqualError(t, err, "rule requiredTypes requires values")
+	})
+
+	t.Run("custom evaluator", func(t *testing.T) {
+		custom := New(WithEvaluator("other", func(*Rule, *verifiable.Credential) error { return nil }))
+		require.NoError(t, custom.ValidateRule(&Rule{Type: "other"}))
+	})
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	e := New()
+
+	vc := &verifiable.Credential{
+		Types:   []string{"VerifiableCredential", "UniversityDegreeCredential"},
+		Subject: map[string]interface{}{"id": "did:example:123", "name": "Jayden Doe"},
+	}
+
+	t.Run("success", func(t *testing.T) {
+		violations := e.Evaluate([]Rule{
+			{Type: SubjectDID},
+			{Type: RequiredTypes, Values: []string{"UniversityDegreeCredential"}},
+			{Type: AllowedTypes, Values: []string{"UniversityDegreeCredential"}},
+			{Type: RequiredClaims, Values: []string{"name"}},
+		}, vc)
+		require.Empty(t, violations)
+	})
+
+	t.Run("violations", func(t *testing.T) {
+		violations := e.Evaluate([]Rule{
+			{Type: RequiredTypes, Values: []string{"PermanentResidentCard", "UniversityDegreeCredential"}},
+			{Type: AllowedTypes, Values: []string{"PermanentResidentCard"}},
+			{Type: RequiredClaims, Values: []string{"name", "degree"}},
+			{Type: "other"},
+		}, vc)
+		require.Equal(t, []Violation{
+			{Rule: RequiredTypes, Message: "missing credential types: PermanentResidentCard"},
+			{Rule: AllowedTypes, Message: "credential types not allowed: UniversityDegreeCredential"},
+			{Rule: RequiredClaims, Message: "missing subject claims: degree"},
+			{Rule: "other", Message: "unsupported rule type"},
+		}, violations)
+	})
+
+	t.Run("subject given by its id", func(t *testing.T) {
+		violations := e.Evaluate([]Rule{{Type: SubjectDID}, {Type: RequiredClaims, Values: []string{"name"}}},
+			&verifiable.Credential{Subject: "urn:uuid:1"})
+		require.Equal(t, []Violation{
+			{Rule: SubjectDID, Message: "subject id is not a did: urn:uuid:1"},
+			{Rule: RequiredClaims, Message: "missing subject claims: name"},
+		}, violations)
+	})
+
+	t.Run("multiple subjects", func(t *testing.T) {
+		violations := e.Evaluate([]Rule{{Type: SubjectDID}}, &verifiable.Credential{
+			Subject: []interface{}{"did:example:1", map[string]interface{}{"name": "Jayden Doe"}},
+		})
+		require.Equal(t, []Violation{{Rule: SubjectDID, Message: "subject id is not a did: <nil>"}}, violations)
+	})
+
+	t.Run("no subject", func(t *testing.T) {
+		violations := e.Evaluate([]Rule{{Type: SubjectDID}, {Type: RequiredClaims, Values: []string{"name"}}},
+			&verifiable.Credential{})
+		require.Equal(t, []Violation{
+			{Rule: SubjectDID, Message: "credential has no subject"},
+			{Rule: RequiredClaims, Message: "credential has no subject"},
+		}, violations)
+	})
+
+	t.Run("verifier rules", func(t *testing.T) {
+		issued := time.Now().Add(-48 * time.Hour)
+		expired := time.Now().Add(-time.Hour)
+
+		rules := []Rule{
+			{Type: NotExpired},
+			{Type: MaxAge, Values: []string{"72h"}},
+			{Type: DisallowedTypes, Values: []string{"PermanentResidentCard"}},
+			{Type: ClaimValues, Values: []string{"name=Jayden Doe"}},
+		}
+
+		require.Empty(t, e.Evaluate(rules, &verifiable.Credential{
+			Types:   vc.Types,
+			Subject: vc.Subject,
+			Issued:  &util.TimeWithTrailingZeroMsec{Time: issued},
+		}))
+
+		violations := e.Evaluate(append(rules, Rule{Type: MaxAge, Values: []string{"24h"}}), &verifiable.Credential{
+			Types:   []string{"VerifiableCredential", "PermanentResidentCard"},
+			Subject: map[string]interface{}{"id": "did:example:123", "name": "John Smith"},
+			Issued:  &util.TimeWithTrailingZeroMsec{Time: issued},
+			Expired: &util.TimeWithTrailingZeroMsec{Time: expired},
+		})
+		require.Equal(t, []Violation{
+			{Rule: NotExpired, Message: "credential expired at " + expired.Format(time.RFC3339)},
+			{Rule: DisallowedTypes, Message: "credential types not allowed: PermanentResidentCard"},
+			{Rule: ClaimValues, Message: "subject claim name is not Jayden Doe: John Smith"},
+			{Rule: MaxAge, Message: "credential issued at " + issued.Format(time.RFC3339) +
+				" is older than 24h0m0s"},
+		}, violations)
+
+		violations = e.Evaluate([]Rule{{Type: MaxAge, Values: []string{"24h"}}, {Type: ClaimValues,
+			Values: []string{"name"}}}, &verifiable.Credential{Subject: vc.Subject})
+		require.Equal(t, []Violation{
+			{Rule: MaxAge, Message: "credential has no issuance date"},
+			{Rule: ClaimValues, Message: `invalid claim value "name", expected claim=value`},
+		}, violations)
+	})
+
+	t.Run("describe violations", func(t *testing.T) {
+		require.Equal(t, "a: b; c: d", Describe([]Violation{{Rule: "a", Message: "b"}, {Rule: "c", Message: "d"}}))
+	})
+
+	t.Run("custom evaluator", func(t *testing.T) {
+		custom := New(WithEvaluator(SubjectDID, func(*Rule, *verifiable.Credential) error {
+			return errors.New("custom violation")
+		}))
+
+		violations := custom.Evaluate([]Rule{{Type: SubjectDID}}, vc)
+		require.Equal(t, []Violation{{Rule: SubjectDID, Message: "custom violation"}}, violations)
+	})
+}
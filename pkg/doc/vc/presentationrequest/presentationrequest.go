@@ -0,0 +1,252 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package presentationrequest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+const (
+	requestStore = "presentationrequest"
+
+	// DefaultTTL is how long the holders can respond to the requests
+	DefaultTTL = 15 * time.Minute
+	// DefaultMaxTTL is the longest TTL of a request, at most the time the transient records are kept in the database
+	DefaultMaxTTL = 24 * time.Hour
+
+	challengeLength = 32
+)
+
+// The formats of the requests
+const (
+	// FormatVPR is a verifiable presentation request (W3C CCG), the credentials being queried by its queries
+	FormatVPR = "vpr"
+	// FormatPE is a presentation definition (DIF presentation exchange), the credentials being described by its
+	// input descriptors
+	FormatPE = "pe"
+)
+
+// The states of a request
+const (
+	// StatePending is the state of a request the holder hasn't responded to
+	StatePending = "pending"
+	// StateVerified is the state of a request whose response was verified
+	StateVerified = "verified"
+	// StateFailed is the state of a request whose response failed to be verified
+	StateFailed = "failed"
+	// StateExpired is the state of a request not responded to before its expiry
+	StateExpired = "expired"
+)
+
+var (
+	// ErrNotFound is returned when the profile has no request with the ID
+	ErrNotFound = errors.New("presentation request not found")
+	// ErrClosed is returned when the request can't be responded to, being responded to or expired
+	ErrClosed = errors.New("presentation request responded to or expired")
+)
+
+// Request is a presentation request of a verifier profile, shared with the holder as its request URI. The holder
+// responds once, with a presentation signed with the challenge and the domain of the request.
+type Request struct {
+	ID      string `json:"id"`
+	Profile string `json:"profile"`
+	Format  string `json:"format"`
+	// Query is the query of the request of the vpr format, e.g. a QueryByExample.
+	Query json.RawMessage `json:"query,omitempty"`
+	// PresentationDefinition is the presentation definition of the request of the pe format.
+	PresentationDefinition json.RawMessage `json:"presentationDefinition,omitempty"`
+	// AnonCredsProofRequest is the AnonCreds proof request of the request of the pe format, if it can be answered
+	// with an AnonCreds presentation, and AnonCredsCredentialDefinitions the credential definitions of the
+	// credentials the presentation is verified with.
+	AnonCredsProofRequest          json.RawMessage   `json:"anonCredsProofRequest,omitempty"`
+	AnonCredsCredentialDefinitions []json.RawMessage `json:"anonCredsCredentialDefinitions,omitempty"`
+	Challenge                      string            `json:"challenge"`
+	Domain                         string            `json:"domain,omitempty"`
+	State                          string            `json:"state"`
+	// Response is the response of the holder, once responded to.
+	Response *Response `json:"response,omitempty"`
+	Created  time.Time `json:"created"`
+	Expires  time.Time `json:"expires"`
+}
+
+// Response is the presentation of the holder responding to a request, with the outcome of its verification
+type Response struct {
+	Presentation json.RawMessage `json:"presentation"`
+	Holder       string          `json:"holder,omitempty"`
+	// Errors are the failed checks of the presentation, empty if verified.
+	Errors   []string  `json:"errors,omitempty"`
+	Received time.Time `json:"received"`
+}
+
+// Store keeps the presentation requests of the profiles, which expire after their TTL unless responded to.
+//
+// A response is recorded in place of the pending request only if the stored request is unchanged since it was read.
+// The replacement is atomic in the store, so the instances sharing the storage provider can't accept two responses to
+// a request.
+type Store struct {
+	store conditional.Store
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// New returns a new presentation request store, the requests expiring after the ttl by default
+func New(provider storage.Provider, ttl time.Duration) (*Store, error) {
+	err := provider.CreateStore(requestStore)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := provider.OpenStore(requestStore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{store: conditional.New(store), ttl: ttl, now: time.Now}, nil
+}
+
+// Create creates a pending request of the profile with a new random challenge, the format, the query or the
+// presentation definition and the domain being set by the caller. The request expires after the ttl, or the TTL of
+// the store if not set.
+func (s *Store) Create(request *Request, ttl time.Duration) (*Request, error) {
+	challenge, err := randomString(challengeLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+
+	if ttl <= 0 {
+		ttl = s.ttl
+	}
+
+	now := s.now().UTC()
+
+	created := *request
+	created.ID = uuid.New().String()
+	created.Challenge = challenge
+	created.State = StatePending
+	created.Response = nil
+	created.Created = now
+	created.Expires = now.Add(ttl)
+
+	if err := s.put(&created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// Get returns the request of the profile, in the expired state once expired. The error is ErrNotFound if the
+// profile has no request with the ID.
+func (s *Store) Get(profile, id string) (*Request, error) {
+	request, _, err := s.get(profile, id)
+
+	return request, err
+}
+
+// Respond records the response to the pending request of the profile, verified by the function: the request is
+// verified if the function returns no errors, failed otherwise. The error is ErrNotFound or ErrClosed if the request
+// can't be responded to, e.g. responded to concurrently by another instance, or the error of the function.
+func (s *Store) Respond(profile, id string, presentation json.RawMessage,
+	verify func(request *Request) (holder string, errs []string, err error)) (*Request, error) {
+	request, requestBytes, err := s.get(profile, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.State != StatePending {
+		return nil, ErrClosed
+	}
+
+	holder, errs, err := verify(request)
+	if err != nil {
+		return nil, err
+	}
+
+	request.State = StateVerified
+	if len(errs) > 0 {
+		request.State = StateFailed
+	}
+
+	request.Response = &Response{Presentation: presentation, Holder: holder, Errors: errs,
+		Received: s.now().UTC()}
+
+	respondedBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal presentation request: %w", err)
+	}
+
+	err = s.store.Replace(request.ID, requestBytes, respondedBytes)
+	if errors.Is(err, conditional.ErrConflict) {
+		return nil, ErrClosed
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to store presentation request: %w", err)
+	}
+
+	return request, nil
+}
+
+// get returns the request of the profile, in the expired state once expired, and its stored bytes
+func (s *Store) get(profile, id string) (*Request, []byte, error) {
+	requestBytes, err := s.store.Get(id)
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return nil, nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get presentation request: %w", err)
+	}
+
+	request := &Request{}
+
+	if err = json.Unmarshal(requestBytes, request); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal presentation request: %w", err)
+	}
+
+	if request.Profile != profile {
+		return nil, nil, ErrNotFound
+	}
+
+	if request.State == StatePending && !s.now().Before(request.Expires) {
+		request.State = StateExpired
+	}
+
+	return request, requestBytes, nil
+}
+
+func (s *Store) put(request *Request) error {
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal presentation request: %w", err)
+	}
+
+	if err := s.store.Put(request.ID, requestBytes); err != nil {
+		return fmt.Errorf("failed to store presentation request: %w", err)
+	}
+
+	return nil
+}
+
+func randomString(length int) (string, error) {
+	b := make([]byte, length)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
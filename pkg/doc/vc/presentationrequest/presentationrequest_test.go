@@ -0,0 +1,220 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package presentationrequest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		store, err := New(memstore.NewProvider(), DefaultTTL)
+		require.NoError(t, err)
+		require.NotNil(t, store)
+	})
+
+	t.Run("test error from create store", func(t *testing.T) {
+		store, err := New(&mockstore.Provider{ErrCreateStore: fmt.Errorf("error create")}, DefaultTTL)
+		require.EqualError(t, err, "error create")
+		require.Nil(t, store)
+	})
+
+	t.Run("test error from open store", func(t *testing.T) {
+		store, err := New(&mockstore.Provider{ErrOpenStoreHandle: fmt.Errorf("error open")}, DefaultTTL)
+		require.EqualError(t, err, "error open")
+		require.Nil(t, store)
+	})
+}
+
+func TestStore(t *testing.T) {
+	now := time.Date(2020, time.October, 16, 10, 0, 0, 0, time.UTC)
+	presentation := json.RawMessage(`{"type":"VerifiablePresentation"}`)
+
+	newStore := func(t *testing.T) *Store {
+		store, err := New(memstore.NewProvider(), time.Minute)
+		require.NoError(t, err)
+
+		store.now = func() time.Time { return now }
+
+		return store
+	}
+
+	newRequest := func() *Request {
+		return &Request{Profile: "verifier", Format: FormatVPR, Query: json.RawMessage(`[{"type":"DIDAuthentication"}]`),
+			Domain: "verifier.example.com"}
+	}
+
+	t.Run("test request responded to once", func(t *testing.T) {
+		store := newStore(t)
+
+		request, err := store.Create(newRequest(), 0)
+		require.NoError(t, err)
+		require.NotEmpty(t, request.ID)
+		require.Len(t, request.Challenge, 43)
+		require.Equal(t, StatePending, request.State)
+		require.Equal(t, "verifier.example.com", request.Domain)
+		require.Equal(t, now.Add(time.Minute), request.Expires)
+
+		request, err = store.Respond("verifier", request.ID, presentation,
+			func(pending *Request) (string, []string, error) {
+				require.Equal(t, request.Challenge, pending.Challenge)
+
+				return "did:example:holder", nil, nil
+			})
+		require.NoError(t, err)
+		require.Equal(t, StateVerified, request.State)
+		require.Equal(t, &Response{Presentation: presentation, Holder: "did:example:holder", Received: now},
+			request.Response)
+
+		_, err = store.Respond("verifier", request.ID, presentation, func(*Request) (string, []string, error) {
+			require.Fail(t, "request responded to twice")
+
+			return "", nil, nil
+		})
+		require.True(t, errors.Is(err, ErrClosed))
+
+		request, err = store.Get("verifier", request.ID)
+		require.NoError(t, err)
+		require.Equal(t, StateVerified, request.State)
+	})
+
+	t.Run("test request responded to concurrently by another instance", func(t *testing.T) {
+		provider := memstore.NewProvider()
+
+		store, err := New(provider, time.Minute)
+		require.NoError(t, err)
+
+		otherInstance, err := New(provider, time.Minute)
+		require.NoError(t, err)
+
+		request, err := store.Create(newRequest(), 0)
+		require.NoError(t, err)
+
+		_, err = store.Respond("verifier", request.ID, presentation, func(*Request) (string, []string, error) {
+			// the other instance accepts a response while this response is verified
+			_, errRespond := otherInstance.Respond("verifier", request.ID, presentation,
+				func(*Request) (string, []string, error) {
+					return "did:example:other", nil, nil
+				})
+			require.NoError(t, errRespond)
+
+			return "did:example:holder", nil, nil
+		})
+		require.True(t, errors.Is(err, ErrClosed))
+
+		request, err = store.Get("verifier", request.ID)
+		require.NoError(t, err)
+		require.Equal(t, StateVerified, request.State)
+		require.Equal(t, "did:example:other", request.Response.Holder)
+	})
+
+	t.Run("test response failed", func(t *testing.T) {
+		store := newStore(t)
+
+		request, err := store.Create(newRequest(), 0)
+		require.NoError(t, err)
+
+		request, err = store.Respond("verifier", request.ID, presentation, func(*Request) (string, []string, error) {
+			return "", []string{"proof: invalid signature"}, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, StateFailed, request.State)
+		require.Equal(t, []string{"proof: invalid signature"}, request.Response.Errors)
+	})
+
+	t.Run("test verification error", func(t *testing.T) {
+		store := newStore(t)
+
+		request, err := store.Create(newRequest(), 0)
+		require.NoError(t, err)
+
+		_, err = store.Respond("verifier", request.ID, presentation, func(*Request) (string, []string, error) {
+			return "", nil, errors.New("verification error")
+		})
+		require.EqualError(t, err, "verification error")
+
+		// the request can still be responded to
+		request, err = store.Get("verifier", request.ID)
+		require.NoError(t, err)
+		require.Equal(t, StatePending, request.State)
+		require.Nil(t, request.Response)
+	})
+
+	t.Run("test request expired", func(t *testing.T) {
+		store := newStore(t)
+
+		request, err := store.Create(newRequest(), time.Hour)
+		require.NoError(t, err)
+		require.Equal(t, now.Add(time.Hour), request.Expires)
+
+		store.now = func() time.Time { return now.Add(time.Hour) }
+
+		request, err = store.Get("verifier", request.ID)
+		require.NoError(t, err)
+		require.Equal(t, StateExpired, request.State)
+
+		_, err = store.Respond("verifier", request.ID, presentation, func(*Request) (string, []string, error) {
+			require.Fail(t, "expired request responded to")
+
+			return "", nil, nil
+		})
+		require.True(t, errors.Is(err, ErrClosed))
+	})
+
+	t.Run("test request of another profile", func(t *testing.T) {
+		store := newStore(t)
+
+		request, err := store.Create(newRequest(), 0)
+		require.NoError(t, err)
+
+		_, err = store.Get("other", request.ID)
+		require.True(t, errors.Is(err, ErrNotFound))
+
+		_, err = store.Get("verifier", "unknown")
+		require.True(t, errors.Is(err, ErrNotFound))
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		store := newStore(t)
+		request, err := store.Create(newRequest(), 0)
+		require.NoError(t, err)
+
+		requestBytes, err := store.store.Get(request.ID)
+		require.NoError(t, err)
+
+		store.store = conditional.New(&mockstore.MockStore{Store: map[string][]byte{"invalid": []byte("{"),
+			request.ID: requestBytes}, ErrPut: errors.New("put error")})
+
+		_, err = store.Respond("verifier", request.ID, presentation, func(*Request) (string, []string, error) {
+			return "", nil, nil
+		})
+		require.EqualError(t, err, "failed to store presentation request: put error")
+
+		_, err = store.Create(newRequest(), 0)
+		require.EqualError(t, err, "failed to store presentation request: put error")
+
+		_, err = store.Get("verifier", "invalid")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal presentation request")
+
+		store.store = conditional.New(&mockstore.MockStore{Store: map[string][]byte{"id": nil},
+			ErrGet: errors.New("get error")})
+
+		_, err = store.Get("verifier", "id")
+		require.EqualError(t, err, "failed to get presentation request: get error")
+	})
+}
@@ -0,0 +1,264 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package presentationrequest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// The query types of the requests of the vpr format
+const (
+	// QueryDIDAuthentication requests a presentation proving the control of the DID of its holder
+	QueryDIDAuthentication = "DIDAuthentication"
+	// QueryByExample requests the credentials matching the types of its example
+	QueryByExample = "QueryByExample"
+)
+
+// Query is a query of a request of the vpr format
+type Query struct {
+	Type string `json:"type"`
+	// CredentialQuery is a credential query of a QueryByExample, or an array of them.
+	CredentialQuery json.RawMessage `json:"credentialQuery,omitempty"`
+}
+
+// credentialQuery queries a credential by example, matched by its types only
+type credentialQuery struct {
+	Reason  string `json:"reason,omitempty"`
+	Example struct {
+		Type stringOrArray `json:"type"`
+	} `json:"example"`
+}
+
+// Definition is a presentation definition of a request of the pe format, the constraints of its input descriptors
+// being left to the policy of the verifier
+type Definition struct {
+	ID               string `json:"id"`
+	InputDescriptors []struct {
+		ID string `json:"id"`
+	} `json:"input_descriptors"`
+}
+
+// Submission is the presentation submission of the presentation responding to a request of the pe format, mapping
+// the input descriptors of the definition to the credentials of the presentation
+type Submission struct {
+	ID            string `json:"id"`
+	DefinitionID  string `json:"definition_id"`
+	DescriptorMap []struct {
+		ID     string `json:"id"`
+		Format string `json:"format,omitempty"`
+		Path   string `json:"path,omitempty"`
+	} `json:"descriptor_map"`
+}
+
+// Answer is the presentation responding to a request: its holder and the types of its credentials, with its
+// presentation submission for the pe format
+type Answer struct {
+	Holder          string
+	CredentialTypes [][]string
+	Submission      *Submission
+}
+
+// ParseQuery parses the queries of a request of the vpr format, a DIDAuthentication or a QueryByExample with the
+// types of its examples
+func ParseQuery(query json.RawMessage) ([]*Query, error) {
+	var queries []*Query
+
+	if err := json.Unmarshal(query, &queries); err != nil {
+		return nil, fmt.Errorf("the query must be an array of queries: %w", err)
+	}
+
+	if len(queries) == 0 {
+		return nil, errors.New("the query has no queries")
+	}
+
+	for i, q := range queries {
+		switch q.Type {
+		case QueryDIDAuthentication:
+		case QueryByExample:
+			credentialQueries, err := parseCredentialQueries(q.CredentialQuery)
+			if err != nil {
+				return nil, fmt.Errorf("query %d: %w", i, err)
+			}
+
+			for _, cq := range credentialQueries {
+				if len(cq.Example.Type) == 0 {
+					return nil, fmt.Errorf("query %d: the example of a credential query must have a type", i)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("query %d: unsupported query type %s", i, q.Type)
+		}
+	}
+
+	return queries, nil
+}
+
+// ParseDefinition parses the presentation definition of a request of the pe format, with the IDs of the definition
+// and of its input descriptors
+func ParseDefinition(definition json.RawMessage) (*Definition, error) {
+	parsed := &Definition{}
+
+	if err := json.Unmarshal(definition, parsed); err != nil {
+		return nil, fmt.Errorf("invalid presentation definition: %w", err)
+	}
+
+	if parsed.ID == "" {
+		return nil, errors.New("the presentation definition must have an id")
+	}
+
+	if len(parsed.InputDescriptors) == 0 {
+		return nil, errors.New("the presentation definition must have input descriptors")
+	}
+
+	for i, descriptor := range parsed.InputDescriptors {
+		if descriptor.ID == "" {
+			return nil, fmt.Errorf("input descriptor %d must have an id", i)
+		}
+	}
+
+	return parsed, nil
+}
+
+// Check checks the answer responds to the request: a holder for a DIDAuthentication, a credential with the types of
+// the example of each credential query, or a submission of the presentation definition mapping each of its input
+// descriptors
+func (r *Request) Check(answer *Answer) error {
+	switch r.Format {
+	case FormatVPR:
+		return r.checkQuery(answer)
+	case FormatPE:
+		return r.checkSubmission(answer)
+	default:
+		return fmt.Errorf("unsupported format %s", r.Format)
+	}
+}
+
+func (r *Request) checkQuery(answer *Answer) error {
+	queries, err := ParseQuery(r.Query)
+	if err != nil {
+		return err
+	}
+
+	for _, q := range queries {
+		if q.Type == QueryDIDAuthentication {
+			if answer.Holder == "" {
+				return errors.New("the presentation has no holder")
+			}
+
+			continue
+		}
+
+		credentialQueries, err := parseCredentialQueries(q.CredentialQuery)
+		if err != nil {
+			return err
+		}
+
+		for _, cq := range credentialQueries {
+			if !hasCredential(answer.CredentialTypes, cq.Example.Type) {
+				return fmt.Errorf("the presentation has no credential of the types %v", []string(cq.Example.Type))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *Request) checkSubmission(answer *Answer) error {
+	definition, err := ParseDefinition(r.PresentationDefinition)
+	if err != nil {
+		return err
+	}
+
+	if answer.Submission == nil {
+		return errors.New("missing presentation submission")
+	}
+
+	if answer.Submission.DefinitionID != definition.ID {
+		return fmt.Errorf("the presentation submission isn't for the presentation definition %s", definition.ID)
+	}
+
+	mapped := make(map[string]bool, len(answer.Submission.DescriptorMap))
+	for _, descriptor := range answer.Submission.DescriptorMap {
+		mapped[descriptor.ID] = true
+	}
+
+	for _, descriptor := range definition.InputDescriptors {
+		if !mapped[descriptor.ID] {
+			return fmt.Errorf("the presentation submission doesn't map the input descriptor %s", descriptor.ID)
+		}
+	}
+
+	return nil
+}
+
+// parseCredentialQueries parses the credential query of a QueryByExample, a credential query or an array of them
+func parseCredentialQueries(raw json.RawMessage) ([]*credentialQuery, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("a QueryByExample must have a credential query")
+	}
+
+	var queries []*credentialQuery
+
+	if err := json.Unmarshal(raw, &queries); err == nil {
+		return queries, nil
+	}
+
+	query := &credentialQuery{}
+
+	if err := json.Unmarshal(raw, query); err != nil {
+		return nil, fmt.Errorf("invalid credential query: %w", err)
+	}
+
+	return []*credentialQuery{query}, nil
+}
+
+// hasCredential returns true if a credential has all the types
+func hasCredential(credentialTypes [][]string, types []string) bool {
+	for _, credential := range credentialTypes {
+		has := make(map[string]bool, len(credential))
+		for _, t := range credential {
+			has[t] = true
+		}
+
+		matches := true
+
+		for _, t := range types {
+			matches = matches && has[t]
+		}
+
+		if matches {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stringOrArray is a JSON-LD value which is a string or an array of strings
+type stringOrArray []string
+
+func (s *stringOrArray) UnmarshalJSON(b []byte) error {
+	var value string
+
+	if err := json.Unmarshal(b, &value); err == nil {
+		*s = []string{value}
+
+		return nil
+	}
+
+	var values []string
+
+	if err := json.Unmarshal(b, &values); err != nil {
+		return err
+	}
+
+	*s = values
+
+	return nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package presentationrequest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	t.Run("test queries", func(t *testing.T) {
+		queries, err := ParseQuery(json.RawMessage(`[{"type":"DIDAuthentication"},{"type":"QueryByExample",
+			"credentialQuery":{"reason":"degree","example":{"type":"UniversityDegreeCredential"}}}]`))
+		require.NoError(t, err)
+		require.Len(t, queries, 2)
+		require.Equal(t, QueryByExample, queries[1].Type)
+	})
+
+	for name, test := range map[string]struct {
+		query string
+		err   string
+	}{
+		"not an array":     {`{"type":"DIDAuthentication"}`, "the query must be an array of queries"},
+		"no queries":       {`[]`, "the query has no queries"},
+		"unsupported type": {`[{"type":"APS"}]`, "query 0: unsupported query type APS"},
+		"missing credential query": {`[{"type":"QueryByExample"}]`,
+			"query 0: a QueryByExample must have a credential query"},
+		"invalid credential query": {`[{"type":"QueryByExample","credentialQuery":1}]`,
+			"query 0: invalid credential query"},
+		"example without type": {`[{"type":"QueryByExample","credentialQuery":[{"example":{}}]}]`,
+			"query 0: the example of a credential query must have a type"},
+	} {
+		test := test
+
+		t.Run("test "+name, func(t *testing.T) {
+			_, err := ParseQuery(json.RawMessage(test.query))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestParseDefinition(t *testing.T) {
+	t.Run("test definition", func(t *testing.T) {
+		definition, err := ParseDefinition(json.RawMessage(`{"id":"degree",
+			"input_descriptors":[{"id":"degree_input","constraints":{"fields":[{"path":["$.type"]}]}}]}`))
+		require.NoError(t, err)
+		require.Equal(t, "degree", definition.ID)
+		require.Equal(t, "degree_input", definition.InputDescriptors[0].ID)
+	})
+
+	for name, test := range map[string]struct {
+		definition string
+		err        string
+	}{
+		"invalid":                     {`[]`, "invalid presentation definition"},
+		"missing id":                  {`{"input_descriptors":[{"id":"a"}]}`, "the presentation definition must have an id"},
+		"no input descriptors":        {`{"id":"a"}`, "the presentation definition must have input descriptors"},
+		"input descriptor without id": {`{"id":"a","input_descriptors":[{}]}`, "input descriptor 0 must have an id"},
+	} {
+		test := test
+
+		t.Run("test "+name, func(t *testing.T) {
+			_, err := ParseDefinition(json.RawMessage(test.definition))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestRequest_Check(t *testing.T) {
+	t.Run("test vpr format", func(t *testing.T) {
+		request := &Request{Format: FormatVPR, Query: json.RawMessage(`[{"type":"DIDAuthentication"},
+			{"type":"QueryByExample","credentialQuery":[{"example":{"type":["VerifiableCredential",
+			"UniversityDegreeCredential"]}}]}]`)}
+
+		require.NoError(t, request.Check(&Answer{Holder: "did:example:holder", CredentialTypes: [][]string{
+			{"VerifiableCredential", "PermanentResidentCard"},
+			{"VerifiableCredential", "UniversityDegreeCredential"},
+		}}))
+
+		require.EqualError(t, request.Check(&Answer{CredentialTypes: [][]string{
+			{"VerifiableCredential", "UniversityDegreeCredential"},
+		}}), "the presentation has no holder")
+
+		require.EqualError(t, request.Check(&Answer{Holder: "did:example:holder", CredentialTypes: [][]string{
+			{"VerifiableCredential", "PermanentResidentCard"},
+		}}), "the presentation has no credential of the types [VerifiableCredential UniversityDegreeCredential]")
+	})
+
+	t.Run("test pe format", func(t *testing.T) {
+		request := &Request{Format: FormatPE, PresentationDefinition: json.RawMessage(`{"id":"degree",
+			"input_descriptors":[{"id":"degree_input"},{"id":"residence_input"}]}`)}
+
+		submission := &Submission{}
+		require.NoError(t, json.Unmarshal([]byte(`{"id":"s1","definition_id":"degree","descriptor_map":[
+			{"id":"degree_input","format":"ldp_vc","path":"$.verifiableCredential[0]"},
+			{"id":"residence_input","format":"ldp_vc","path":"$.verifiableCredential[1]"}]}`), submission))
+
+		require.NoError(t, request.Check(&Answer{Submission: submission}))
+
+		require.EqualError(t, request.Check(&Answer{}), "missing presentation submission")
+
+		submission.DescriptorMap = submission.DescriptorMap[:1]
+		require.EqualError(t, request.Check(&Answer{Submission: submission}),
+			"the presentation submission doesn't map the input descriptor residence_input")
+
+		submission.DefinitionID = "other"
+		require.EqualError(t, request.Check(&Answer{Submission: submission}),
+			"the presentation submission isn't for the presentation definition degree")
+	})
+
+	t.Run("test unsupported format", func(t *testing.T) {
+		require.EqualError(t, (&Request{Format: "other"}).Check(&Answer{}), "unsupported format other")
+	})
+}
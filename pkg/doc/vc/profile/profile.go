@@ -7,26 +7,46 @@ package profile
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 
 	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/cache"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/quota"
 )
 
 const (
 	keyPattern       = "%s_%s_%s"
 	profileKeyPrefix = "profile"
+	// the kms keys used by the profiles are recorded under the key ID, the prefix doesn't collide with the profiles
+	keyRefPrefix = "keyref/"
 
 	credentialStoreName = "credential"
 
 	issuerMode = "issuer"
 	holderMode = "holder"
+
+	governanceMode = "governance"
 )
 
+// Option configures the profile store
+type Option func(p *Profile)
+
+// WithCache option caches the profiles read from the store, the cached profiles are invalidated when saved
+func WithCache(c cache.Cache) Option {
+	return func(p *Profile) {
+		p.cache = c
+	}
+}
+
 // New returns new credential recorder instance
-func New(provider storage.Provider) (*Profile, error) {
+func New(provider storage.Provider, opts ...Option) (*Profile, error) {
 	err := provider.CreateStore(credentialStoreName)
 	if err != nil {
 		if err != storage.ErrDuplicateStore {
@@ -39,12 +59,23 @@ func New(provider storage.Provider) (*Profile, error) {
 		return nil, err
 	}
 
-	return &Profile{store: store}, nil
+	p := &Profile{store: store}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.cache != nil {
+		p.store = cache.NewStore(store, p.cache)
+	}
+
+	return p, nil
 }
 
 // Profile takes care of features to be persisted for credentials
 type Profile struct {
 	store storage.Store
+	cache cache.Cache
 }
 
 // DataProfile struct for profile
@@ -58,6 +89,24 @@ type DataProfile struct {
 	Created                 *time.Time                         `json:"created"`
 	DisableVCStatus         bool                               `json:"disableVCStatus"`
 	OverwriteIssuer         bool                               `json:"overwriteIssuer"`
+	PreviousCreators        []string                           `json:"previousCreators,omitempty"`
+	SigningKeys             []SigningKey                       `json:"signingKeys,omitempty"`
+	CredentialStorage       string                             `json:"credentialStorage,omitempty"`
+	RevokeOnExpiry          bool                               `json:"revokeOnExpiry,omitempty"`
+	Policy                  []policy.Rule                      `json:"policy,omitempty"`
+	Quota                   *quota.Quota                       `json:"quota,omitempty"`
+	RequireHolderBinding    bool                               `json:"requireHolderBinding,omitempty"`
+	CredentialFormat        string                             `json:"credentialFormat,omitempty"`
+	AnchorCredentials       bool                               `json:"anchorCredentials,omitempty"`
+	StrictJSONLD            bool                               `json:"strictJSONLD,omitempty"`
+	DIDPending              bool                               `json:"didPending,omitempty"`
+	VaultID                 string                             `json:"vaultID,omitempty"`
+}
+
+// SigningKey is an additional key of the profile DID which can be selected for signing credentials
+type SigningKey struct {
+	ID            string `json:"id"`
+	SignatureType string `json:"signatureType"`
 }
 
 // HolderProfile struct for holder profile
@@ -69,6 +118,11 @@ type HolderProfile struct {
 	Creator                 string                             `json:"creator"`
 	OverwriteHolder         bool                               `json:"overwriteHolder,omitempty"`
 	Created                 *time.Time                         `json:"created"`
+	// ExpiryNoticeDays is how many days before their expiration the holder is notified of its stored credentials
+	// expiring, the holder isn't notified if not set.
+	ExpiryNoticeDays int `json:"expiryNoticeDays,omitempty"`
+	// ExpiryWebhookURL receives the expiry notices of the holder (POST), in addition to the expiry events.
+	ExpiryWebhookURL string `json:"expiryWebhookURL,omitempty"`
 }
 
 // SaveProfile saves issuer profile to underlying store
@@ -78,6 +132,10 @@ func (c *Profile) SaveProfile(data *DataProfile) error {
 		return fmt.Errorf("save profile marshalling error: %s", err.Error())
 	}
 
+	if err := c.saveKeyRefs(issuerMode, data.Name, profileKeys(data)...); err != nil {
+		return err
+	}
+
 	return c.store.Put(getDBKey(issuerMode, data.Name), bytes)
 }
 
@@ -88,6 +146,11 @@ func (c *Profile) GetProfile(name string) (*DataProfile, error) {
 		return nil, err
 	}
 
+	// the deleted profiles are stored empty, the store doesn't support deletion
+	if len(bytes) == 0 {
+		return nil, storage.ErrValueNotFound
+	}
+
 	response := &DataProfile{}
 
 	err = json.Unmarshal(bytes, response)
@@ -98,6 +161,12 @@ func (c *Profile) GetProfile(name string) (*DataProfile, error) {
 	return response, nil
 }
 
+// DeleteProfile deletes the issuer profile from the underlying store, e.g. when the profile can't be set up. The
+// keys used by the profile stay recorded.
+func (c *Profile) DeleteProfile(name string) error {
+	return c.store.Put(getDBKey(issuerMode, name), []byte{})
+}
+
 // SaveHolderProfile saves holder profile to the underlying store.
 func (c *Profile) SaveHolderProfile(data *HolderProfile) error {
 	bytes, err := json.Marshal(data)
@@ -105,6 +174,10 @@ func (c *Profile) SaveHolderProfile(data *HolderProfile) error {
 		return fmt.Errorf("save holder profile : %s", err.Error())
 	}
 
+	if err := c.saveKeyRefs(holderMode, data.Name, data.Creator); err != nil {
+		return err
+	}
+
 	return c.store.Put(getDBKey(holderMode, data.Name), bytes)
 }
 
@@ -125,6 +198,79 @@ func (c *Profile) GetHolderProfile(name string) (*HolderProfile, error) {
 	return response, nil
 }
 
+// SaveGovernanceProfile saves governance profile to the underlying store.
+func (c *Profile) SaveGovernanceProfile(data *DataProfile) error {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("save governance profile : %s", err.Error())
+	}
+
+	if err := c.saveKeyRefs(governanceMode, data.Name, profileKeys(data)...); err != nil {
+		return err
+	}
+
+	return c.store.Put(getDBKey(governanceMode, data.Name), bytes)
+}
+
+// GetGovernanceProfile retrieves the governance profile based on name.
+func (c *Profile) GetGovernanceProfile(name string) (*DataProfile, error) {
+	bytes, err := c.store.Get(getDBKey(governanceMode, name))
+	if err != nil {
+		return nil, err
+	}
+
+	response := &DataProfile{}
+
+	err = json.Unmarshal(bytes, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// GetKeyReference returns the profile using the kms key, as "<mode>/<name>", an empty string if no profile uses the
+// key. The keys are recorded when the profiles are saved, the keys of a profile stay recorded once replaced.
+func (c *Profile) GetKeyReference(keyID string) (string, error) {
+	ref, err := c.store.Get(keyRefPrefix + keyID)
+	if err != nil {
+		if errors.Is(err, storage.ErrValueNotFound) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return string(ref), nil
+}
+
+func (c *Profile) saveKeyRefs(mode, name string, verificationMethods ...string) error {
+	for _, verificationMethod := range verificationMethods {
+		// the kms key ID is the fragment of the verification method
+		i := strings.LastIndex(verificationMethod, "#")
+		if i < 0 || i == len(verificationMethod)-1 {
+			continue
+		}
+
+		err := c.store.Put(keyRefPrefix+verificationMethod[i+1:], []byte(mode+"/"+name))
+		if err != nil {
+			return fmt.Errorf("failed to record profile key: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func profileKeys(data *DataProfile) []string {
+	keys := append([]string{data.Creator}, data.PreviousCreators...)
+
+	for _, signingKey := range data.SigningKeys {
+		keys = append(keys, signingKey.ID)
+	}
+
+	return keys
+}
+
 func getDBKey(mode, name string) string {
 	return fmt.Sprintf(keyPattern, profileKeyPrefix, mode, name)
 }
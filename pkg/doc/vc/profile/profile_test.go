@@ -7,13 +7,17 @@ package profile
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
 	mockstorage "github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edge-service/pkg/cache/memcache"
 )
 
 func TestCredentialRecord_SaveProfile(t *testing.T) {
@@ -40,6 +44,41 @@ func TestCredentialRecord_SaveProfile(t *testing.T) {
 	})
 }
 
+func TestProfile_GetKeyReference(t *testing.T) {
+	record, err := New(mockstorage.NewMockStoreProvider())
+	require.NoError(t, err)
+
+	require.NoError(t, record.SaveProfile(&DataProfile{
+		Name:             "issuer",
+		Creator:          "did:example:123#key2",
+		PreviousCreators: []string{"did:example:123#key1"},
+		SigningKeys:      []SigningKey{{ID: "did:example:123#key3"}},
+	}))
+	require.NoError(t, record.SaveHolderProfile(&HolderProfile{Name: "holder", Creator: "did:example:456#key4"}))
+	require.NoError(t, record.SaveGovernanceProfile(&DataProfile{Name: "governance", Creator: "did:example:789#key5"}))
+
+	for keyID, ref := range map[string]string{
+		"key1": "issuer/issuer", "key2": "issuer/issuer", "key3": "issuer/issuer",
+		"key4": "holder/holder", "key5": "governance/governance", "key6": "",
+	} {
+		profileRef, err := record.GetKeyReference(keyID)
+		require.NoError(t, err)
+		require.Equal(t, ref, profileRef, keyID)
+	}
+
+	t.Run("test error from store", func(t *testing.T) {
+		record, err := New(&mockstorage.Provider{Store: &mockstorage.MockStore{
+			Store: make(map[string][]byte), ErrGet: fmt.Errorf("get error"), ErrPut: fmt.Errorf("put error")}})
+		require.NoError(t, err)
+
+		err = record.SaveProfile(&DataProfile{Name: "issuer", Creator: "did:example:123#key1"})
+		require.EqualError(t, err, "failed to record profile key: put error")
+
+		_, err = record.GetKeyReference("key1")
+		require.EqualError(t, err, "get error")
+	})
+}
+
 func TestCredentialRecord_GetProfile(t *testing.T) {
 	t.Run("test get profile success", func(t *testing.T) {
 		record, err := New(mockstorage.NewMockStoreProvider())
@@ -64,6 +103,35 @@ func TestCredentialRecord_GetProfile(t *testing.T) {
 		require.Equal(t, valueStored, valueFound)
 	})
 
+	t.Run("test get profile cached", func(t *testing.T) {
+		provider := mockstorage.NewMockStoreProvider()
+
+		record, err := New(provider, WithCache(memcache.New(10, time.Minute)))
+		require.NoError(t, err)
+
+		err = record.SaveProfile(&DataProfile{Name: "issuer", DID: "did1"})
+		require.NoError(t, err)
+
+		valueFound, err := record.GetProfile("issuer")
+		require.NoError(t, err)
+		require.Equal(t, "did1", valueFound.DID)
+
+		// the cached profile is returned when the store is updated behind the cache
+		provider.Store.Store[getDBKey(issuerMode, "issuer")] = []byte(`{"name":"issuer","did":"did2"}`)
+
+		valueFound, err = record.GetProfile("issuer")
+		require.NoError(t, err)
+		require.Equal(t, "did1", valueFound.DID)
+
+		// the cached profile is invalidated when the profile is saved
+		err = record.SaveProfile(&DataProfile{Name: "issuer", DID: "did3"})
+		require.NoError(t, err)
+
+		valueFound, err = record.GetProfile("issuer")
+		require.NoError(t, err)
+		require.Equal(t, "did3", valueFound.DID)
+	})
+
 	t.Run("test get profile failure due to invalid id", func(t *testing.T) {
 		record, err := New(mockstorage.NewMockStoreProvider())
 		require.NoError(t, err)
@@ -76,6 +144,30 @@ func TestCredentialRecord_GetProfile(t *testing.T) {
 	})
 }
 
+func TestCredentialRecord_DeleteProfile(t *testing.T) {
+	record, err := New(mockstorage.NewMockStoreProvider(), WithCache(memcache.New(10, time.Minute)))
+	require.NoError(t, err)
+
+	err = record.SaveProfile(&DataProfile{Name: "issuer", DID: "did1", Creator: "did1#key1"})
+	require.NoError(t, err)
+
+	_, err = record.GetProfile("issuer")
+	require.NoError(t, err)
+
+	require.NoError(t, record.DeleteProfile("issuer"))
+
+	_, err = record.GetProfile("issuer")
+	require.True(t, errors.Is(err, storage.ErrValueNotFound))
+
+	// the profile can be saved again
+	err = record.SaveProfile(&DataProfile{Name: "issuer", DID: "did2"})
+	require.NoError(t, err)
+
+	valueFound, err := record.GetProfile("issuer")
+	require.NoError(t, err)
+	require.Equal(t, "did2", valueFound.DID)
+}
+
 func TestSaveHolder(t *testing.T) {
 	t.Run("test save holder - success", func(t *testing.T) {
 		s := make(map[string][]byte)
@@ -176,3 +268,54 @@ func TestGetHolder(t *testing.T) {
 		require.Nil(t, resp)
 	})
 }
+
+func TestGovernanceProfile(t *testing.T) {
+	t.Run("test save and get governance - success", func(t *testing.T) {
+		s := make(map[string][]byte)
+
+		profileStore, err := New(&mockstorage.Provider{Store: &mockstorage.MockStore{Store: s}})
+		require.NoError(t, err)
+
+		governanceProfile := &DataProfile{
+			Name:          "governance-1",
+			DID:           "did",
+			SignatureType: "SignatureType",
+		}
+
+		err = profileStore.SaveGovernanceProfile(governanceProfile)
+		require.NoError(t, err)
+		require.NotNil(t, s[getDBKey(governanceMode, governanceProfile.Name)])
+
+		resp, err := profileStore.GetGovernanceProfile(governanceProfile.Name)
+		require.NoError(t, err)
+		require.Equal(t, governanceProfile, resp)
+
+		// the issuer profiles don't share the names of the governance profiles
+		_, err = profileStore.GetProfile(governanceProfile.Name)
+		require.Error(t, err)
+	})
+
+	t.Run("test save governance - fail", func(t *testing.T) {
+		profileStore, err := New(&mockstorage.Provider{
+			Store: &mockstorage.MockStore{Store: make(map[string][]byte), ErrPut: fmt.Errorf("put error")}})
+		require.NoError(t, err)
+
+		err = profileStore.SaveGovernanceProfile(&DataProfile{Name: "governance-1"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "put error")
+	})
+
+	t.Run("test get governance - invalid json", func(t *testing.T) {
+		s := make(map[string][]byte)
+
+		profileStore, err := New(&mockstorage.Provider{Store: &mockstorage.MockStore{Store: s}})
+		require.NoError(t, err)
+
+		s[getDBKey(governanceMode, "governance-1")] = []byte("invalid-data")
+
+		resp, err := profileStore.GetGovernanceProfile("governance-1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid character")
+		require.Nil(t, resp)
+	})
+}
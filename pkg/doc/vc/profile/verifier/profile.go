@@ -11,6 +11,8 @@ import (
 	"fmt"
 
 	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
 )
 
 const (
@@ -31,6 +33,27 @@ type ProfileData struct {
 	Name               string   `json:"name"`
 	CredentialChecks   []string `json:"credentialChecks,omitempty"`
 	PresentationChecks []string `json:"presentationChecks,omitempty"`
+	// GovernanceVC is the URL of the governance credential listing the trusted issuers, used by the governance check
+	GovernanceVC string `json:"governanceVC,omitempty"`
+	// GovernanceAuthority is the DID of the issuer the governance credential must be issued by
+	GovernanceAuthority string `json:"governanceAuthority,omitempty"`
+	// Policy is the rules the credentials must comply with, evaluated by the policy check
+	Policy []policy.Rule `json:"policy,omitempty"`
+	// RequireChallenge requires the presentations to be signed with a challenge created for the profile, consumed
+	// by the proof check so the presentation can't be replayed
+	RequireChallenge bool `json:"requireChallenge,omitempty"`
+	// ReceiptSignatureType is the signature type of the verification receipts of the profile, a DID signing them
+	// being created with the profile when set. The profile returns no receipts if not set.
+	ReceiptSignatureType string `json:"receiptSignatureType,omitempty"`
+	// ReceiptKeyType is the type of the key of the DID signing the receipts (Ed25519 by default)
+	ReceiptKeyType string `json:"receiptKeyType,omitempty"`
+	// ReceiptDIDMethod is the method of the DID signing the receipts, "key" for a did:key, a DID of the domain of
+	// the service by default
+	ReceiptDIDMethod string `json:"receiptDIDMethod,omitempty"`
+	// ReceiptDID is the DID signing the receipts, created by the service
+	ReceiptDID string `json:"receiptDID,omitempty"`
+	// ReceiptCreator is the verification method of the key signing the receipts, created by the service
+	ReceiptCreator string `json:"receiptCreator,omitempty"`
 }
 
 // New returns new credential recorder instance
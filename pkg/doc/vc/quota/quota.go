@@ -0,0 +1,264 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+const (
+	issuanceQuotaStore = "issuancequota"
+
+	// Daily is the period of the daily cap, a UTC day
+	Daily = "daily"
+	// Monthly is the period of the monthly cap, a UTC month
+	Monthly = "monthly"
+
+	dayLayout   = "2006-01-02"
+	monthLayout = "2006-01"
+
+	// maxUpdateAttempts is how many times a count is incremented when it changed since it was read
+	maxUpdateAttempts = 10
+)
+
+var logger = log.New("edge-service-quota")
+
+// ErrConcurrentUpdate is returned when a count couldn't be updated, the stored count having changed since it was read
+// on each attempt
+var ErrConcurrentUpdate = errors.New("issuance count updated concurrently")
+
+// Quota caps the credentials issued under a profile per period, zero for no cap
+type Quota struct {
+	Daily   int `json:"daily,omitempty"`
+	Monthly int `json:"monthly,omitempty"`
+}
+
+// Usage is the credentials issued under a profile during the current periods
+type Usage struct {
+	Daily   PeriodUsage `json:"daily"`
+	Monthly PeriodUsage `json:"monthly"`
+}
+
+// PeriodUsage is the credentials issued during a period (e.g. 2020-10-16 or 2020-10) and its cap, if any
+type PeriodUsage struct {
+	Period string    `json:"period"`
+	Issued int       `json:"issued"`
+	Limit  int       `json:"limit,omitempty"`
+	Reset  time.Time `json:"reset"`
+}
+
+// ExceededError is returned when the cap of a period is reached, until the reset of the period
+type ExceededError struct {
+	Period string
+	Limit  int
+	Reset  time.Time
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s issuance quota of %d credentials exceeded until %s", e.Period, e.Limit,
+		e.Reset.Format(time.RFC3339))
+}
+
+// Tracker counts the credentials issued under each profile per UTC day and month, and enforces their quota.
+//
+// The counts are stored under the profile and the period. A count is checked against the quota and incremented with
+// a conditional write of the store, which fails if the count changed since it was read, e.g. incremented by another
+// instance, the increment being checked again then. The credentials issued by the instances sharing the database
+// can't exceed the quota. The tracker mutex serializes the reservations of an instance.
+type Tracker struct {
+	store conditional.Store
+	mutex sync.Mutex
+	now   func() time.Time
+}
+
+// New returns a new issuance quota tracker
+func New(provider storage.Provider) (*Tracker, error) {
+	err := provider.CreateStore(issuanceQuotaStore)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := provider.OpenStore(issuanceQuotaStore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tracker{store: conditional.New(store), now: time.Now}, nil
+}
+
+// Reserve counts a credential to be issued under the profile, or returns an ExceededError if the quota of the
+// profile is reached. The returned release function uncounts the credential if it can't be issued.
+func (t *Tracker) Reserve(profile string, quota *Quota) (func(), error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	usage, err := t.usage(profile, quota)
+	if err != nil {
+		return nil, err
+	}
+
+	if usage.Daily.exceeded() {
+		return nil, usage.Daily.exceededError(Daily)
+	}
+
+	if usage.Monthly.exceeded() {
+		return nil, usage.Monthly.exceededError(Monthly)
+	}
+
+	dayKey, monthKey := key(profile, usage.Daily.Period), key(profile, usage.Monthly.Period)
+
+	if err = t.add(dayKey, 1, &usage.Daily, Daily); err != nil {
+		return nil, err
+	}
+
+	if err = t.add(monthKey, 1, &usage.Monthly, Monthly); err != nil {
+		t.release(profile, dayKey)
+
+		return nil, err
+	}
+
+	return func() {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+
+		t.release(profile, dayKey, monthKey)
+	}, nil
+}
+
+// Usage returns the credentials issued under the profile during the current day and month, with its quota
+func (t *Tracker) Usage(profile string, quota *Quota) (*Usage, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.usage(profile, quota)
+}
+
+func (t *Tracker) usage(profile string, quota *Quota) (*Usage, error) {
+	now := t.now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	usage := &Usage{
+		Daily:   PeriodUsage{Period: day.Format(dayLayout), Reset: day.AddDate(0, 0, 1)},
+		Monthly: PeriodUsage{Period: month.Format(monthLayout), Reset: month.AddDate(0, 1, 0)},
+	}
+
+	if quota != nil {
+		usage.Daily.Limit = quota.Daily
+		usage.Monthly.Limit = quota.Monthly
+	}
+
+	var err error
+
+	usage.Daily.Issued, err = t.getCount(key(profile, usage.Daily.Period))
+	if err != nil {
+		return nil, err
+	}
+
+	usage.Monthly.Issued, err = t.getCount(key(profile, usage.Monthly.Period))
+	if err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+// release uncounts a credential from the counts of the keys
+func (t *Tracker) release(profile string, keys ...string) {
+	for _, k := range keys {
+		if err := t.add(k, -1, nil, ""); err != nil {
+			logger.Warnf("failed to release issuance quota of profile %s: %s", profile, err)
+		}
+	}
+}
+
+// add adds the delta to the count of the key, the count being replaced only if it is unchanged since it was read.
+// An increment beyond the limit of the period usage, if any, returns an ExceededError.
+func (t *Tracker) add(k string, delta int, usage *PeriodUsage, period string) error {
+	for attempt := 1; attempt <= maxUpdateAttempts; attempt++ {
+		countBytes, err := t.store.Get(k)
+		if err != nil && !errors.Is(err, storage.ErrValueNotFound) {
+			return fmt.Errorf("failed to get issuance count: %w", err)
+		}
+
+		count, err := parseCount(countBytes)
+		if err != nil {
+			return err
+		}
+
+		if usage != nil {
+			usage.Issued = count
+
+			if usage.exceeded() {
+				return usage.exceededError(period)
+			}
+		}
+
+		newCount := []byte(strconv.Itoa(count + delta))
+
+		if countBytes == nil {
+			err = t.store.PutIfAbsent(k, newCount)
+		} else {
+			err = t.store.Replace(k, countBytes, newCount)
+		}
+
+		if !errors.Is(err, conditional.ErrConflict) {
+			if err != nil {
+				return fmt.Errorf("failed to store issuance count: %w", err)
+			}
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrConcurrentUpdate, k)
+}
+
+// getCount returns the count of the key, zero if no credential was issued during the period
+func (t *Tracker) getCount(k string) (int, error) {
+	countBytes, err := t.store.Get(k)
+	if err != nil && !errors.Is(err, storage.ErrValueNotFound) {
+		return 0, fmt.Errorf("failed to get issuance count: %w", err)
+	}
+
+	return parseCount(countBytes)
+}
+
+// parseCount parses the stored count, zero if not stored
+func parseCount(countBytes []byte) (int, error) {
+	if countBytes == nil {
+		return 0, nil
+	}
+
+	count, err := strconv.Atoi(string(countBytes))
+	if err != nil {
+		return 0, fmt.Errorf("invalid issuance count: %w", err)
+	}
+
+	return count, nil
+}
+
+func (u *PeriodUsage) exceeded() bool {
+	return u.Limit > 0 && u.Issued >= u.Limit
+}
+
+func (u *PeriodUsage) exceededError(period string) error {
+	return &ExceededError{Period: period, Limit: u.Limit, Reset: u.Reset}
+}
+
+func key(profile, period string) string {
+	return profile + "/" + period
+}
@@ -0,0 +1,270 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		tracker, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+		require.NotNil(t, tracker)
+	})
+
+	t.Run("test error from create store", func(t *testing.T) {
+		tracker, err := New(&mockstore.Provider{ErrCreateStore: fmt.Errorf("error create")})
+		require.EqualError(t, err, "error create")
+		require.Nil(t, tracker)
+	})
+
+	t.Run("test error from open store", func(t *testing.T) {
+		tracker, err := New(&mockstore.Provider{ErrOpenStoreHandle: fmt.Errorf("error open")})
+		require.EqualError(t, err, "error open")
+		require.Nil(t, tracker)
+	})
+}
+
+func TestTracker_Reserve(t *testing.T) {
+	now := time.Date(2020, time.October, 16, 10, 0, 0, 0, time.UTC)
+
+	t.Run("test daily quota", func(t *testing.T) {
+		tracker, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		tracker.now = func() time.Time { return now }
+
+		quota := &Quota{Daily: 2, Monthly: 10}
+
+		for i := 0; i < 2; i++ {
+			_, err = tracker.Reserve("issuer", quota)
+			require.NoError(t, err)
+		}
+
+		_, err = tracker.Reserve("issuer", quota)
+		require.EqualError(t, err, "daily issuance quota of 2 credentials exceeded until 2020-10-17T00:00:00Z")
+
+		var exceeded *ExceededError
+		require.True(t, errors.As(err, &exceeded))
+		require.Equal(t, Daily, exceeded.Period)
+
+		// the quota is per profile
+		_, err = tracker.Reserve("other", quota)
+		require.NoError(t, err)
+
+		// the next day
+		tracker.now = func() time.Time { return now.Add(24 * time.Hour) }
+
+		_, err = tracker.Reserve("issuer", quota)
+		require.NoError(t, err)
+
+		usage, err := tracker.Usage("issuer", quota)
+		require.NoError(t, err)
+		require.Equal(t, PeriodUsage{Period: "2020-10-17", Issued: 1, Limit: 2,
+			Reset: time.Date(2020, time.October, 18, 0, 0, 0, 0, time.UTC)}, usage.Daily)
+		require.Equal(t, PeriodUsage{Period: "2020-10", Issued: 3, Limit: 10,
+			Reset: time.Date(2020, time.November, 1, 0, 0, 0, 0, time.UTC)}, usage.Monthly)
+	})
+
+	t.Run("test monthly quota", func(t *testing.T) {
+		tracker, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		tracker.now = func() time.Time { return now }
+
+		_, err = tracker.Reserve("issuer", &Quota{Monthly: 1})
+		require.NoError(t, err)
+
+		_, err = tracker.Reserve("issuer", &Quota{Monthly: 1})
+		require.EqualError(t, err, "monthly issuance quota of 1 credentials exceeded until 2020-11-01T00:00:00Z")
+	})
+
+	t.Run("test release", func(t *testing.T) {
+		tracker, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		release, err := tracker.Reserve("issuer", &Quota{Daily: 1})
+		require.NoError(t, err)
+
+		release()
+
+		_, err = tracker.Reserve("issuer", &Quota{Daily: 1})
+		require.NoError(t, err)
+	})
+
+	t.Run("test no quota", func(t *testing.T) {
+		tracker, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			_, err = tracker.Reserve("issuer", nil)
+			require.NoError(t, err)
+		}
+
+		// the credentials are still counted
+		usage, err := tracker.Usage("issuer", nil)
+		require.NoError(t, err)
+		require.Equal(t, 3, usage.Daily.Issued)
+		require.Zero(t, usage.Daily.Limit)
+	})
+
+	t.Run("test concurrent reservations", func(t *testing.T) {
+		tracker, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+
+		var mutex sync.Mutex
+
+		reserved := 0
+
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				if _, errReserve := tracker.Reserve("issuer", &Quota{Daily: 5}); errReserve == nil {
+					mutex.Lock()
+					reserved++
+					mutex.Unlock()
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		require.Equal(t, 5, reserved)
+	})
+
+	t.Run("test count incremented by another instance", func(t *testing.T) {
+		provider := memstore.NewProvider()
+
+		tracker, err := New(provider)
+		require.NoError(t, err)
+
+		otherInstance, err := New(provider)
+		require.NoError(t, err)
+
+		tracker.now = func() time.Time { return now }
+		otherInstance.now = tracker.now
+
+		quota := &Quota{Daily: 2}
+
+		_, err = tracker.Reserve("issuer", quota)
+		require.NoError(t, err)
+
+		// the other instance reserves the last credential of the day between the read and the write of the count
+		tracker.store = &concurrentStore{Store: tracker.store, beforeReplace: func() {
+			_, errReserve := otherInstance.Reserve("issuer", quota)
+			require.NoError(t, errReserve)
+		}}
+
+		_, err = tracker.Reserve("issuer", quota)
+		require.EqualError(t, err, "daily issuance quota of 2 credentials exceeded until 2020-10-17T00:00:00Z")
+
+		usage, err := tracker.Usage("issuer", quota)
+		require.NoError(t, err)
+		require.Equal(t, 2, usage.Daily.Issued)
+		require.Equal(t, 2, usage.Monthly.Issued)
+	})
+
+	t.Run("test count always incremented by another instance", func(t *testing.T) {
+		provider := memstore.NewProvider()
+
+		tracker, err := New(provider)
+		require.NoError(t, err)
+
+		otherInstance, err := New(provider)
+		require.NoError(t, err)
+
+		_, err = tracker.Reserve("issuer", nil)
+		require.NoError(t, err)
+
+		tracker.store = &concurrentStore{Store: tracker.store, beforeReplace: func() {
+			_, errReserve := otherInstance.Reserve("issuer", nil)
+			require.NoError(t, errReserve)
+		}}
+
+		_, err = tracker.Reserve("issuer", nil)
+		require.True(t, errors.Is(err, ErrConcurrentUpdate))
+	})
+
+	t.Run("test day count released if the month count fails", func(t *testing.T) {
+		tracker, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		tracker.now = func() time.Time { return now }
+
+		require.NoError(t, tracker.store.Put("issuer/2020-10", []byte("1")))
+
+		tracker.store = &concurrentStore{Store: tracker.store, beforeReplace: func() {
+			// the month reaches its quota concurrently
+			require.NoError(t, tracker.store.Put("issuer/2020-10", []byte("2")))
+		}}
+
+		_, err = tracker.Reserve("issuer", &Quota{Monthly: 2})
+		require.EqualError(t, err, "monthly issuance quota of 2 credentials exceeded until 2020-11-01T00:00:00Z")
+
+		usage, err := tracker.Usage("issuer", nil)
+		require.NoError(t, err)
+		require.Equal(t, 0, usage.Daily.Issued)
+	})
+
+	t.Run("test error from store", func(t *testing.T) {
+		tracker, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		tracker.now = func() time.Time { return now }
+		tracker.store = conditional.New(&mockstore.MockStore{Store: map[string][]byte{"issuer/2020-10-16": []byte("1")},
+			ErrGet: fmt.Errorf("error get")})
+
+		_, err = tracker.Reserve("issuer", &Quota{Daily: 1})
+		require.EqualError(t, err, "failed to get issuance count: error get")
+
+		tracker.store = conditional.New(&mockstore.MockStore{Store: map[string][]byte{}, ErrPut: fmt.Errorf("error put")})
+
+		_, err = tracker.Reserve("issuer", &Quota{Daily: 1})
+		require.EqualError(t, err, "failed to store issuance count: error put")
+	})
+
+	t.Run("test invalid count", func(t *testing.T) {
+		tracker, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		tracker.now = func() time.Time { return now }
+
+		require.NoError(t, tracker.store.Put("issuer/2020-10-16", []byte("invalid")))
+
+		_, err = tracker.Usage("issuer", nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid issuance count")
+	})
+}
+
+// concurrentStore simulates another instance writing right before each replacement
+type concurrentStore struct {
+	conditional.Store
+	beforeReplace func()
+}
+
+func (s *concurrentStore) Replace(k string, old, v []byte) error {
+	s.beforeReplace()
+
+	return s.Store.Replace(k, old, v)
+}
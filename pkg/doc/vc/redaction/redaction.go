@@ -0,0 +1,290 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package redaction
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+)
+
+const (
+	// Vocabulary defines the terms of the committed subjects, which would otherwise be left out of their signature
+	Vocabulary = "https://trustbloc.github.io/context/vc/redaction#"
+
+	subjectKey      = "credentialSubject"
+	idKey           = "id"
+	claimsDigestKey = "claimsDigest"
+
+	saltLength = 16
+)
+
+// Claim is a claim of the subject of a redactable credential, with the salt of its digest
+type Claim struct {
+	// Path is the JSON pointer of the claim in the credential, e.g. /credentialSubject/degree/type
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+	// Salt is the random salt of the digest of the claim, base64url encoded without padding
+	Salt string `json:"salt"`
+}
+
+// Disclosure is a claim of a redacted credential: either the claim, disclosed, or the digest of the redacted claim
+type Disclosure struct {
+	Claim  *Claim `json:"claim,omitempty"`
+	Digest string `json:"digest,omitempty"`
+}
+
+// Derived is a redacted credential: the signed credential committing to its claims, and the disclosures of the claims
+// in the order of the commitment
+type Derived struct {
+	Credential  json.RawMessage `json:"credential"`
+	Disclosures []*Disclosure   `json:"disclosures"`
+}
+
+// Commit replaces the claims of the subject of the credential with the digest of their salted digests, so the claims
+// can be disclosed one by one once the credential is signed. The claims are returned with their salts, in the order
+// of the commitment: the ID of the subject is kept, and the claims are the values of the subject, its objects and
+// arrays.
+func Commit(vc *verifiable.Credential) ([]*Claim, error) {
+	subject, err := credentialSubject(vc)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims []*Claim
+
+	for _, k := range sortedKeys(subject) {
+		if k != idKey {
+			claims = leaves("/"+subjectKey+"/"+escape(k), subject[k], claims)
+		}
+	}
+
+	if len(claims) == 0 {
+		return nil, errors.New("no claims to commit to")
+	}
+
+	digests := make([]string, len(claims))
+
+	for i, claim := range claims {
+		salt := make([]byte, saltLength)
+
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+
+		claim.Salt = base64.RawURLEncoding.EncodeToString(salt)
+
+		if digests[i], err = Digest(claim); err != nil {
+			return nil, err
+		}
+	}
+
+	committed := map[string]interface{}{claimsDigestKey: claimsDigest(digests)}
+	if id, ok := subject[idKey]; ok {
+		committed[idKey] = id
+	}
+
+	vc.Subject = committed
+	vc.CustomContext = append(vc.CustomContext, map[string]interface{}{"@vocab": Vocabulary})
+
+	return claims, nil
+}
+
+// Derive returns the credential with the claims under the revealed JSON pointers disclosed, e.g.
+// /credentialSubject/degree for all the claims of the degree, and the other claims redacted. The claims are the
+// claims committed to by the credential, as returned by Commit.
+func Derive(credential json.RawMessage, claims []*Claim, reveal []string) (*Derived, error) {
+	vc, err := parseCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	derived := &Derived{Credential: credential, Disclosures: make([]*Disclosure, len(claims))}
+	digests := make([]string, len(claims))
+	revealed := make(map[string]bool)
+
+	for i, claim := range claims {
+		if digests[i], err = Digest(claim); err != nil {
+			return nil, err
+		}
+
+		derived.Disclosures[i] = &Disclosure{Digest: digests[i]}
+
+		for _, path := range reveal {
+			if claim.Path == path || strings.HasPrefix(claim.Path, path+"/") {
+				derived.Disclosures[i] = &Disclosure{Claim: claim}
+				revealed[path] = true
+			}
+		}
+	}
+
+	for _, path := range reveal {
+		if !revealed[path] {
+			return nil, fmt.Errorf("no claim to reveal at %s", path)
+		}
+	}
+
+	if err := checkDigest(vc, digests); err != nil {
+		return nil, err
+	}
+
+	return derived, nil
+}
+
+// Verify checks the disclosures are the claims committed to by the credential, and returns the disclosed claims. The
+// proof of the credential isn't verified.
+func Verify(vc *verifiable.Credential, disclosures []*Disclosure) ([]*Claim, error) {
+	digests := make([]string, len(disclosures))
+
+	var claims []*Claim
+
+	for i, disclosure := range disclosures {
+		switch {
+		case disclosure.Claim != nil && disclosure.Digest == "":
+			digest, err := Digest(disclosure.Claim)
+			if err != nil {
+				return nil, err
+			}
+
+			digests[i] = digest
+			claims = append(claims, disclosure.Claim)
+		case disclosure.Claim == nil && disclosure.Digest != "":
+			digests[i] = disclosure.Digest
+		default:
+			return nil, fmt.Errorf("disclosure %d must have either a claim or a digest", i)
+		}
+	}
+
+	if err := checkDigest(vc, digests); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// Digest returns the digest of the salted claim, the SHA-256 hash of the JSON array of its salt, path and value,
+// base64url encoded without padding
+func Digest(claim *Claim) (string, error) {
+	claimBytes, err := json.Marshal([]interface{}{claim.Salt, claim.Path, claim.Value})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claim %s: %w", claim.Path, err)
+	}
+
+	hash := sha256.Sum256(claimBytes)
+
+	return base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
+
+// checkDigest checks the digests of the claims are the digests committed to by the credential
+func checkDigest(vc *verifiable.Credential, digests []string) error {
+	subject, err := credentialSubject(vc)
+	if err != nil {
+		return err
+	}
+
+	committed, ok := subject[claimsDigestKey].(string)
+	if !ok {
+		return errors.New("credential not redactable: no claims digest")
+	}
+
+	if claimsDigest(digests) != committed {
+		return errors.New("the claims don't match the claims digest of the credential")
+	}
+
+	return nil
+}
+
+// claimsDigest returns the SHA-256 hash of the concatenated digests of the claims, base64url encoded without padding
+func claimsDigest(digests []string) string {
+	hash := sha256.Sum256([]byte(strings.Join(digests, ".")))
+
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// credentialSubject returns the subject of the credential, which must be a single subject
+func credentialSubject(vc *verifiable.Credential) (map[string]interface{}, error) {
+	vcBytes, err := vc.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal credential: %w", err)
+	}
+
+	doc := make(map[string]interface{})
+
+	if err := json.Unmarshal(vcBytes, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal credential: %w", err)
+	}
+
+	subject, ok := doc[subjectKey].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("redaction requires a single credential subject")
+	}
+
+	return subject, nil
+}
+
+// parseCredential parses the credential, in JSON-LD or JWT, without checking its proof
+func parseCredential(credential json.RawMessage) (*verifiable.Credential, error) {
+	var jwt string
+	if err := json.Unmarshal(credential, &jwt); err == nil {
+		credential = []byte(jwt)
+	}
+
+	vc, err := verifiable.ParseUnverifiedCredential(credential)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse credential: %w", err)
+	}
+
+	return vc, nil
+}
+
+// leaves appends the claims of the value at the path, the values of its objects and arrays, to the claims
+func leaves(path string, value interface{}, claims []*Claim) []*Claim {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) > 0 {
+			for _, k := range sortedKeys(v) {
+				claims = leaves(path+"/"+escape(k), v[k], claims)
+			}
+
+			return claims
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			for i, item := range v {
+				claims = leaves(fmt.Sprintf("%s/%d", path, i), item, claims)
+			}
+
+			return claims
+		}
+	}
+
+	return append(claims, &Claim{Path: path, Value: value})
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// escape escapes the key as a JSON pointer reference token
+func escape(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
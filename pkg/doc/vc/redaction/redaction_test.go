@@ -0,0 +1,179 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package redaction
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+)
+
+const testCredential = `{
+  "@context": ["https://www.w3.org/2018/credentials/v1"],
+  "id": "http://example.edu/credentials/1872",
+  "type": ["VerifiableCredential", "UniversityDegreeCredential"],
+  "issuer": "did:example:76e12ec712ebc6f1c221ebfeb1f",
+  "issuanceDate": "2010-01-01T19:23:24Z",
+  "credentialSubject": {
+    "id": "did:example:ebfeb1f712ebc6f1c276e12ec21",
+    "name": "Jayden Doe",
+    "birthDate": "1958-07-17",
+    "degree": {"type": "BachelorDegree", "name": "Bachelor of Science"},
+    "alumniOf": [],
+    "a/b~c": null
+  }
+}`
+
+func TestRedaction(t *testing.T) {
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(testCredential))
+	require.NoError(t, err)
+
+	claims, err := Commit(vc)
+	require.NoError(t, err)
+
+	var paths []string
+	for _, claim := range claims {
+		require.Len(t, claim.Salt, 22)
+
+		paths = append(paths, claim.Path)
+	}
+
+	require.Equal(t, []string{"/credentialSubject/a~1b~0c", "/credentialSubject/alumniOf",
+		"/credentialSubject/birthDate", "/credentialSubject/degree/name", "/credentialSubject/degree/type",
+		"/credentialSubject/name"}, paths)
+
+	subject, err := credentialSubject(vc)
+	require.NoError(t, err)
+	require.Len(t, subject, 2)
+	require.Equal(t, "did:example:ebfeb1f712ebc6f1c276e12ec21", subject[idKey])
+	require.NotEmpty(t, subject[claimsDigestKey])
+	require.Equal(t, []interface{}{map[string]interface{}{"@vocab": Vocabulary}}, vc.CustomContext)
+
+	// the committed credential is signed as a JWT, the proof covering the claims digest
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	jwtClaims, err := vc.JWTClaims(false)
+	require.NoError(t, err)
+
+	jwtVC, err := jwtClaims.MarshalJWS(verifiable.EdDSA, signature.GetEd25519Signer(privKey, pubKey), "#key-1")
+	require.NoError(t, err)
+
+	credential, err := json.Marshal(jwtVC)
+	require.NoError(t, err)
+
+	t.Run("test derive and verify", func(t *testing.T) {
+		derived, err := Derive(credential, claims, []string{"/credentialSubject/degree", "/credentialSubject/name"})
+		require.NoError(t, err)
+		require.Equal(t, json.RawMessage(credential), derived.Credential)
+		require.Len(t, derived.Disclosures, len(claims))
+
+		for i, disclosure := range derived.Disclosures {
+			if i < 3 {
+				require.Nil(t, disclosure.Claim)
+				require.NotEmpty(t, disclosure.Digest)
+
+				continue
+			}
+
+			require.Equal(t, claims[i], disclosure.Claim)
+			require.Empty(t, disclosure.Digest)
+		}
+
+		derivedBytes, err := json.Marshal(derived)
+		require.NoError(t, err)
+		require.NotContains(t, string(derivedBytes), "1958-07-17")
+
+		// the verifier checks the proof of the credential, then the disclosures
+		received := &Derived{}
+		require.NoError(t, json.Unmarshal(derivedBytes, received))
+
+		signedVC, err := verifiable.ParseCredential([]byte(jwtVC),
+			verifiable.WithPublicKeyFetcher(verifiable.SingleKey(pubKey, "Ed25519Signature2018")))
+		require.NoError(t, err)
+
+		disclosed, err := Verify(signedVC, received.Disclosures)
+		require.NoError(t, err)
+		require.Equal(t, claims[3:], disclosed)
+	})
+
+	t.Run("test tampered disclosures", func(t *testing.T) {
+		derived, err := Derive(credential, claims, []string{"/credentialSubject/name"})
+		require.NoError(t, err)
+
+		derived.Disclosures[5].Claim = &Claim{Path: "/credentialSubject/name", Value: "John Doe",
+			Salt: claims[5].Salt}
+
+		_, err = Verify(vc, derived.Disclosures)
+		require.EqualError(t, err, "the claims don't match the claims digest of the credential")
+
+		_, err = Verify(vc, derived.Disclosures[1:])
+		require.EqualError(t, err, "the claims don't match the claims digest of the credential")
+
+		_, err = Verify(vc, []*Disclosure{{}})
+		require.EqualError(t, err, "disclosure 0 must have either a claim or a digest")
+
+		_, err = Verify(vc, []*Disclosure{{Claim: &Claim{Value: func() {}}}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to marshal claim")
+	})
+
+	t.Run("test invalid derivation", func(t *testing.T) {
+		_, err := Derive(credential, claims, []string{"/credentialSubject/degree/level"})
+		require.EqualError(t, err, "no claim to reveal at /credentialSubject/degree/level")
+
+		_, err = Derive(credential, claims[1:], nil)
+		require.EqualError(t, err, "the claims don't match the claims digest of the credential")
+
+		_, err = Derive(credential, []*Claim{{Value: func() {}}}, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to marshal claim")
+
+		_, err = Derive([]byte(testCredential), claims, nil)
+		require.EqualError(t, err, "credential not redactable: no claims digest")
+
+		_, err = Derive([]byte("{}"), claims, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse credential")
+	})
+}
+
+func TestCommit_Errors(t *testing.T) {
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(testCredential))
+	require.NoError(t, err)
+
+	vc.Subject = map[string]interface{}{"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"}
+
+	_, err = Commit(vc)
+	require.EqualError(t, err, "no claims to commit to")
+
+	vc.Subject = []map[string]interface{}{{"name": "Jayden Doe"}, {"name": "John Doe"}}
+
+	_, err = Commit(vc)
+	require.EqualError(t, err, "redaction requires a single credential subject")
+
+	vc.Subject = map[string]interface{}{"name": func() {}}
+
+	_, err = Commit(vc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to marshal credential")
+}
+
+func TestDigest(t *testing.T) {
+	digest, err := Digest(&Claim{Path: "/credentialSubject/name", Value: "Jayden Doe", Salt: "c2FsdA"})
+	require.NoError(t, err)
+	require.Len(t, digest, 43)
+
+	other, err := Digest(&Claim{Path: "/credentialSubject/name", Value: "Jayden Doe", Salt: "b3RoZXI"})
+	require.NoError(t, err)
+	require.NotEqual(t, digest, other)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sdjwt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+
+	"github.com/trustbloc/edge-service/pkg/internal/common/pubkey"
+)
+
+// the JWS algorithms of the keys of the profiles and of the holders (RFC 7518, RFC 8037, RFC 8812)
+const (
+	AlgorithmEdDSA  = pubkey.AlgorithmEdDSA
+	AlgorithmES256  = pubkey.AlgorithmES256
+	AlgorithmES384  = pubkey.AlgorithmES384
+	AlgorithmES512  = pubkey.AlgorithmES512
+	AlgorithmES256K = pubkey.AlgorithmES256K
+	AlgorithmPS256  = pubkey.AlgorithmPS256
+)
+
+// Signer signs the JWTs with the private key of their key ID, the ECDSA signatures being the concatenation of r and s
+type Signer interface {
+	Sign(data []byte) ([]byte, error)
+}
+
+// Algorithm returns the JWS algorithm of a public key: EdDSA for the Ed25519 keys, ES256, ES384, ES512 or ES256K for
+// the EC keys depending on their curve, and PS256 for the RSA keys
+func Algorithm(key *verifier.PublicKey) (string, error) {
+	return pubkey.Algorithm(key)
+}
+
+// signJWT returns the compact serialization of the JWT of the claims signed by the signer, the algorithm being set
+// in the header
+func signJWT(header map[string]interface{}, claims interface{}, alg string, signer Signer) (string, error) {
+	header["alg"] = alg
+
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	claimsBytes, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encode(headerBytes) + "." + encode(claimsBytes)
+
+	signature, err := signer.Sign([]byte(signingInput))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+
+	return signingInput + "." + encode(signature), nil
+}
+
+// jwt is a parsed JWT, not verified
+type jwt struct {
+	header       map[string]interface{}
+	claims       map[string]interface{}
+	signingInput string
+	signature    []byte
+}
+
+func parseJWT(token string) (*jwt, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 { // nolint: gomnd
+		return nil, errors.New("not a JWS compact serialization")
+	}
+
+	parsed := &jwt{signingInput: parts[0] + "." + parts[1]}
+
+	if err := decodeJSON(parts[0], &parsed.header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+
+	if err := decodeJSON(parts[1], &parsed.claims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	parsed.signature = signature
+
+	return parsed, nil
+}
+
+// headerString returns the string parameter of the header, empty if not set
+func (t *jwt) headerString(name string) string {
+	s, _ := t.header[name].(string) // nolint: errcheck
+
+	return s
+}
+
+// verify verifies the signature of the JWT with the public key, the algorithm of its header being the algorithm of
+// the key
+func (t *jwt) verify(key *verifier.PublicKey) error {
+	alg, err := Algorithm(key)
+	if err != nil {
+		return err
+	}
+
+	if t.headerString("alg") != alg {
+		return fmt.Errorf("algorithm %s doesn't match the algorithm %s of the key", t.headerString("alg"), alg)
+	}
+
+	if !pubkey.Verify(key, []byte(t.signingInput), t.signature) {
+		return errors.New("invalid signature")
+	}
+
+	return nil
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeJSON(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,590 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sdjwt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+)
+
+const (
+	// DigestAlgorithm is the hash algorithm of the digests of the disclosures (_sd_alg)
+	DigestAlgorithm = "sha-256"
+	// DefaultKeyBindingMaxAge is how long after its issuance a key binding JWT is accepted if not set
+	DefaultKeyBindingMaxAge = 5 * time.Minute
+
+	// keyBindingClockSkew tolerates the key binding JWTs issued ahead of the clock of the verifier
+	keyBindingClockSkew = time.Minute
+
+	typeSDJWT      = "vc+sd-jwt"
+	typeKeyBinding = "kb+jwt"
+
+	// separator separates the issuer JWT, the disclosures and the key binding JWT of a SD-JWT
+	separator = "~"
+
+	saltSize = 16
+
+	sdClaim    = "_sd"
+	sdAlgClaim = "_sd_alg"
+	cnfClaim   = "cnf"
+)
+
+// Confirmation is the key of the holder a SD-JWT is bound to (cnf claim): a JWK, or the ID of a verification method
+// of the holder DID
+type Confirmation struct {
+	JWK   *jose.JWK `json:"jwk,omitempty"`
+	KeyID string    `json:"kid,omitempty"`
+}
+
+// Issuance is the SD-JWT issued from a credential: the claims of the credential subject (except its id) are
+// selectively disclosable, the other claims of the credential are always disclosed
+type Issuance struct {
+	// VerificationMethod is the verification method of the signing key, the key ID of the issuer JWT.
+	VerificationMethod string
+	// Algorithm is the JWS algorithm of the signing key, see Algorithm.
+	Algorithm string
+	Signer    Signer
+	// Confirmation binds the SD-JWT to the key of the holder (optional), which then signs its presentations.
+	Confirmation *Confirmation
+}
+
+// KeyBinding is the key binding JWT of a presentation, signed by the key of the holder
+type KeyBinding struct {
+	// Audience is the verifier the SD-JWT is presented to.
+	Audience string
+	Nonce    string
+	// Algorithm is the JWS algorithm of the holder key, see Algorithm.
+	Algorithm string
+	Signer    Signer
+	// IssuedAt is the issuance time of the key binding JWT (iat), the current time if not set.
+	IssuedAt time.Time
+}
+
+// Verification are the checks of a presented SD-JWT, in addition to the signature of its issuer and its digests
+type Verification struct {
+	// Audience and Nonce the key binding JWT must have, if set.
+	Audience string
+	Nonce    string
+	// RequireKeyBinding fails the presentations without key binding JWT, also required if Audience or Nonce is set.
+	RequireKeyBinding bool
+	// Now is the time the expiration of the SD-JWT and the age of the key binding JWT are checked at.
+	Now time.Time
+	// KeyBindingMaxAge is how long after its issuance the key binding JWT is accepted, DefaultKeyBindingMaxAge if
+	// not set, so a captured presentation can't be replayed later.
+	KeyBindingMaxAge time.Duration
+}
+
+// Credential is the credential of a SD-JWT with its disclosed claims
+type Credential struct {
+	// VC is the "vc" claim of the issuer JWT, the disclosed claims being set in its subject and the digests of the
+	// undisclosed claims removed.
+	VC map[string]interface{} `json:"vc"`
+	// Issuer is the DID of the verification method signing the SD-JWT.
+	Issuer string `json:"issuer"`
+	// Disclosed are the names of the disclosed claims of the subject.
+	Disclosed []string `json:"disclosed"`
+	// Confirmation is the holder key the SD-JWT is bound to, nil if not bound.
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+	// KeyBound is set when the presentation is signed by the holder key the SD-JWT is bound to.
+	KeyBound bool `json:"keyBound"`
+}
+
+// disclosure is a selectively disclosable claim of the subject: its salted name and value, and the digest of its
+// encoding in the issuer JWT
+type disclosure struct {
+	encoded string
+	name    string
+	value   interface{}
+}
+
+// Issue issues the credential as a SD-JWT: the issuer JWT, with the digests of the claims of the subject instead of
+// the claims, followed by the disclosures of all the claims, each ending with a ~
+func Issue(vc *verifiable.Credential, issuance *Issuance) (string, error) {
+	// the JWT claims of a vc are created from its issuance date
+	if vc.Issued == nil {
+		return "", errors.New("failed to create JWT claims: missing issuance date")
+	}
+
+	jwtClaims, err := vc.JWTClaims(false)
+	if err != nil {
+		return "", fmt.Errorf("failed to create JWT claims: %w", err)
+	}
+
+	claims := map[string]interface{}{}
+
+	if err = remarshal(jwtClaims, &claims); err != nil {
+		return "", fmt.Errorf("failed to create JWT claims: %w", err)
+	}
+
+	subject, err := singleSubject(claims)
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(subject))
+
+	for name := range subject {
+		if name != "id" {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	disclosures := make([]*disclosure, 0, len(names))
+	digests := make([]string, 0, len(names))
+
+	for _, name := range names {
+		d, err := newDisclosure(name, subject[name])
+		if err != nil {
+			return "", err
+		}
+
+		delete(subject, name)
+
+		disclosures = append(disclosures, d)
+		digests = append(digests, d.digest())
+	}
+
+	// sorted, so the digests don't reveal the order of the claims
+	sort.Strings(digests)
+
+	subject[sdClaim] = digests
+	claims[sdAlgClaim] = DigestAlgorithm
+
+	if issuance.Confirmation != nil {
+		claims[cnfClaim] = issuance.Confirmation
+	}
+
+	token, err := signJWT(map[string]interface{}{"typ": typeSDJWT, "kid": issuance.VerificationMethod}, claims,
+		issuance.Algorithm, issuance.Signer)
+	if err != nil {
+		return "", err
+	}
+
+	return serialize(token, disclosures), nil
+}
+
+// Present returns the presentation of the SD-JWT disclosing the claims of the subject with the names, followed by the
+// key binding JWT if set
+func Present(sdJWT string, names []string, binding *KeyBinding) (string, error) {
+	token, disclosures, _, err := split(sdJWT)
+	if err != nil {
+		return "", err
+	}
+
+	byName := make(map[string]*disclosure, len(disclosures))
+	for _, d := range disclosures {
+		byName[d.name] = d
+	}
+
+	presented := make([]*disclosure, 0, len(names))
+
+	for _, name := range names {
+		d, ok := byName[name]
+		if !ok {
+			return "", fmt.Errorf("no disclosure of the claim %s", name)
+		}
+
+		presented = append(presented, d)
+	}
+
+	presentation := serialize(token, presented)
+
+	if binding == nil {
+		return presentation, nil
+	}
+
+	issuedAt := binding.IssuedAt
+	if issuedAt.IsZero() {
+		issuedAt = time.Now()
+	}
+
+	kbJWT, err := signJWT(map[string]interface{}{"typ": typeKeyBinding}, map[string]interface{}{
+		"iat":     issuedAt.Unix(),
+		"aud":     binding.Audience,
+		"nonce":   binding.Nonce,
+		"sd_hash": sdHash(presentation),
+	}, binding.Algorithm, binding.Signer)
+	if err != nil {
+		return "", err
+	}
+
+	return presentation + kbJWT, nil
+}
+
+// Parse returns the credential of the SD-JWT with its disclosed claims, without verifying it
+func Parse(sdJWT string) (*Credential, error) {
+	token, disclosures, kbJWT, err := split(sdJWT)
+	if err != nil {
+		return nil, err
+	}
+
+	issuerJWT, err := parseJWT(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issuer JWT: %w", err)
+	}
+
+	credential, err := disclose(issuerJWT, disclosures)
+	if err != nil {
+		return nil, err
+	}
+
+	credential.Issuer = strings.SplitN(issuerJWT.headerString("kid"), "#", 2)[0]
+	credential.KeyBound = kbJWT != ""
+
+	return credential, nil
+}
+
+// Verify verifies the presentation of a SD-JWT: the signature of its issuer JWT with the key of its verification
+// method, fetched by the fetcher as a linked data proof key, its expiration, the digests of its disclosures, and its
+// key binding JWT signed by the holder key, if the SD-JWT is presented with one or the verification requires it.
+func Verify(presentation string, fetcher verifiable.PublicKeyFetcher, // nolint: funlen,gocyclo
+	verification *Verification) (*Credential, error) {
+	token, disclosures, kbJWT, err := split(presentation)
+	if err != nil {
+		return nil, err
+	}
+
+	issuerJWT, err := parseJWT(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issuer JWT: %w", err)
+	}
+
+	kid := issuerJWT.headerString("kid")
+
+	key, err := fetchKey(fetcher, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = issuerJWT.verify(key); err != nil {
+		return nil, fmt.Errorf("invalid issuer JWT: %w", err)
+	}
+
+	issuer := strings.SplitN(kid, "#", 2)[0]
+
+	if iss, ok := issuerJWT.claims["iss"].(string); ok && iss != issuer {
+		return nil, fmt.Errorf("the issuer %s isn't the DID of the key %s", iss, kid)
+	}
+
+	if err = checkExpiration(issuerJWT.claims, verification.Now); err != nil {
+		return nil, err
+	}
+
+	credential, err := disclose(issuerJWT, disclosures)
+	if err != nil {
+		return nil, err
+	}
+
+	credential.Issuer = issuer
+
+	if kbJWT == "" {
+		if verification.RequireKeyBinding || verification.Audience != "" || verification.Nonce != "" {
+			return nil, errors.New("missing key binding JWT")
+		}
+
+		return credential, nil
+	}
+
+	if err = verifyKeyBinding(kbJWT, credential.Confirmation, strings.TrimSuffix(presentation, kbJWT), fetcher,
+		verification); err != nil {
+		return nil, fmt.Errorf("invalid key binding JWT: %w", err)
+	}
+
+	credential.KeyBound = true
+
+	return credential, nil
+}
+
+// verifyKeyBinding verifies the key binding JWT is signed by the holder key of the confirmation, for the presentation
+// it ends, was issued recently and has the audience and nonce of the verification
+func verifyKeyBinding(kbJWT string, confirmation *Confirmation, presentation string,
+	fetcher verifiable.PublicKeyFetcher, verification *Verification) error {
+	if confirmation == nil {
+		return errors.New("the SD-JWT isn't bound to a holder key")
+	}
+
+	var (
+		key *verifier.PublicKey
+		err error
+	)
+
+	switch {
+	case confirmation.JWK != nil:
+		key = &verifier.PublicKey{Type: "JsonWebKey2020", JWK: confirmation.JWK}
+	case confirmation.KeyID != "":
+		if key, err = fetchKey(fetcher, confirmation.KeyID); err != nil {
+			return err
+		}
+	default:
+		return errors.New("the SD-JWT isn't bound to a holder key")
+	}
+
+	parsed, err := parseJWT(kbJWT)
+	if err != nil {
+		return err
+	}
+
+	if parsed.headerString("typ") != typeKeyBinding {
+		return fmt.Errorf("the type isn't %s", typeKeyBinding)
+	}
+
+	if err = parsed.verify(key); err != nil {
+		return err
+	}
+
+	if parsed.claims["sd_hash"] != sdHash(presentation) {
+		return errors.New("the SD-JWT hash doesn't match the presentation")
+	}
+
+	if err = checkKeyBindingAge(parsed.claims, verification); err != nil {
+		return err
+	}
+
+	if verification.Audience != "" && parsed.claims["aud"] != verification.Audience {
+		return fmt.Errorf("the audience isn't %s", verification.Audience)
+	}
+
+	if verification.Nonce != "" && parsed.claims["nonce"] != verification.Nonce {
+		return errors.New("the nonce doesn't match")
+	}
+
+	return nil
+}
+
+// disclose returns the credential of the issuer JWT with the claims of the disclosures set in its subject, their
+// digests being in the digests of the subject
+func disclose(issuerJWT *jwt, disclosures []*disclosure) (*Credential, error) {
+	if alg, ok := issuerJWT.claims[sdAlgClaim]; ok && alg != DigestAlgorithm {
+		return nil, fmt.Errorf("unsupported digest algorithm %v", alg)
+	}
+
+	subject, err := singleSubject(issuerJWT.claims)
+	if err != nil {
+		return nil, err
+	}
+
+	digests := map[string]bool{}
+
+	sd, _ := subject[sdClaim].([]interface{}) // nolint: errcheck
+	for _, digest := range sd {
+		if s, ok := digest.(string); ok {
+			digests[s] = true
+		}
+	}
+
+	delete(subject, sdClaim)
+
+	disclosed := make([]string, 0, len(disclosures))
+
+	for _, d := range disclosures {
+		digest := d.digest()
+
+		if !digests[digest] {
+			return nil, fmt.Errorf("the digest of the disclosure of %s isn't in the SD-JWT", d.name)
+		}
+
+		// each digest is disclosed once
+		delete(digests, digest)
+
+		if _, ok := subject[d.name]; ok || d.name == sdClaim {
+			return nil, fmt.Errorf("the disclosure of %s overwrites a claim", d.name)
+		}
+
+		subject[d.name] = d.value
+		disclosed = append(disclosed, d.name)
+	}
+
+	vc, _ := issuerJWT.claims["vc"].(map[string]interface{}) // nolint: errcheck
+
+	credential := &Credential{VC: vc, Disclosed: disclosed}
+
+	if cnf, ok := issuerJWT.claims[cnfClaim]; ok {
+		credential.Confirmation = &Confirmation{}
+
+		if err := remarshal(cnf, credential.Confirmation); err != nil {
+			return nil, fmt.Errorf("invalid holder key: %w", err)
+		}
+	}
+
+	return credential, nil
+}
+
+// split splits the SD-JWT into its issuer JWT, its disclosures and its key binding JWT, empty if not set
+func split(sdJWT string) (string, []*disclosure, string, error) {
+	parts := strings.Split(sdJWT, separator)
+	if len(parts) < 2 { // nolint: gomnd
+		return "", nil, "", errors.New("not a SD-JWT: missing ~ separator")
+	}
+
+	disclosures := make([]*disclosure, 0, len(parts)-2)
+
+	for _, encoded := range parts[1 : len(parts)-1] {
+		d, err := parseDisclosure(encoded)
+		if err != nil {
+			return "", nil, "", err
+		}
+
+		disclosures = append(disclosures, d)
+	}
+
+	return parts[0], disclosures, parts[len(parts)-1], nil
+}
+
+func serialize(token string, disclosures []*disclosure) string {
+	var sb strings.Builder
+
+	sb.WriteString(token)
+	sb.WriteString(separator)
+
+	for _, d := range disclosures {
+		sb.WriteString(d.encoded)
+		sb.WriteString(separator)
+	}
+
+	return sb.String()
+}
+
+func newDisclosure(name string, value interface{}) (*disclosure, error) {
+	salt := make([]byte, saltSize)
+
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal([]interface{}{encode(salt), name, value})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the disclosure of %s: %w", name, err)
+	}
+
+	return &disclosure{encoded: encode(b), name: name, value: value}, nil
+}
+
+func parseDisclosure(encoded string) (*disclosure, error) {
+	var items []interface{}
+
+	if err := decodeJSON(encoded, &items); err != nil {
+		return nil, fmt.Errorf("invalid disclosure: %w", err)
+	}
+
+	if len(items) != 3 { // nolint: gomnd
+		return nil, errors.New("invalid disclosure: not a salt, a claim name and a claim value")
+	}
+
+	name, ok := items[1].(string)
+	if !ok {
+		return nil, errors.New("invalid disclosure: the claim name isn't a string")
+	}
+
+	return &disclosure{encoded: encoded, name: name, value: items[2]}, nil
+}
+
+// digest returns the digest of the disclosure in the issuer JWT, the base64url SHA-256 of its encoding
+func (d *disclosure) digest() string {
+	sum := sha256.Sum256([]byte(d.encoded))
+
+	return encode(sum[:])
+}
+
+// sdHash returns the hash of the presentation in its key binding JWT, the base64url SHA-256 of the presentation
+func sdHash(presentation string) string {
+	sum := sha256.Sum256([]byte(presentation))
+
+	return encode(sum[:])
+}
+
+// singleSubject returns the single subject of the vc claim of the JWT claims
+func singleSubject(claims map[string]interface{}) (map[string]interface{}, error) {
+	vc, ok := claims["vc"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("missing vc claim")
+	}
+
+	if subjects, ok := vc["credentialSubject"].([]interface{}); ok && len(subjects) == 1 {
+		vc["credentialSubject"] = subjects[0]
+	}
+
+	subject, ok := vc["credentialSubject"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("SD-JWTs require a single credential subject")
+	}
+
+	return subject, nil
+}
+
+// fetchKey fetches the key of the verification method
+func fetchKey(fetcher verifiable.PublicKeyFetcher, verificationMethod string) (*verifier.PublicKey, error) {
+	split := strings.SplitN(verificationMethod, "#", 2)
+	if len(split) != 2 { // nolint: gomnd
+		return nil, fmt.Errorf("the key ID %s is not a verification method", verificationMethod)
+	}
+
+	key, err := fetcher(split[0], "#"+split[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the key %s: %w", verificationMethod, err)
+	}
+
+	return key, nil
+}
+
+// checkExpiration checks the JWT is valid at the time, by its expiration and not before claims
+// checkKeyBindingAge checks the key binding JWT was issued (iat) within the max age of the verification
+func checkKeyBindingAge(claims map[string]interface{}, verification *Verification) error {
+	iat, ok := claims["iat"].(float64)
+	if !ok {
+		return errors.New("missing issuance time (iat)")
+	}
+
+	maxAge := verification.KeyBindingMaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultKeyBindingMaxAge
+	}
+
+	issuedAt := time.Unix(int64(iat), 0)
+
+	if issuedAt.After(verification.Now.Add(keyBindingClockSkew)) {
+		return fmt.Errorf("issued in the future at %s", issuedAt.UTC().Format(time.RFC3339))
+	}
+
+	if verification.Now.Sub(issuedAt) > maxAge {
+		return fmt.Errorf("issued at %s, more than %s ago", issuedAt.UTC().Format(time.RFC3339), maxAge)
+	}
+
+	return nil
+}
+
+func checkExpiration(claims map[string]interface{}, now time.Time) error {
+	if exp, ok := claims["exp"].(float64); ok && !now.Before(time.Unix(int64(exp), 0)) {
+		return fmt.Errorf("the SD-JWT expired at %s", time.Unix(int64(exp), 0).UTC().Format(time.RFC3339))
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("the SD-JWT isn't valid before %s", time.Unix(int64(nbf), 0).UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func remarshal(from, to interface{}) error {
+	b, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, to)
+}
@@ -0,0 +1,425 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sdjwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/internal/common/pubkey"
+)
+
+const (
+	issuerDID          = "did:example:76e12ec712ebc6f1c221ebfeb1f"
+	verificationMethod = issuerDID + "#key-1"
+	holderDID          = "did:example:ebfeb1f712ebc6f1c276e12ec21"
+	holderKeyID        = holderDID + "#key-1"
+
+	licence = `{
+		"@context": ["https://www.w3.org/2018/credentials/v1"],
+		"id": "http://example.gov/credentials/3732",
+		"type": ["VerifiableCredential"],
+		"issuer": "` + issuerDID + `",
+		"issuanceDate": "2020-01-01T00:00:00Z",
+		"expirationDate": "2030-01-01T00:00:00Z",
+		"credentialSubject": {
+			"id": "` + holderDID + `",
+			"family_name": "Doe",
+			"given_name": "Jayden",
+			"birth_date": "1958-07-17",
+			"age_over_18": true
+		}
+	}`
+)
+
+// ecdsaSigner signs with IEEE P1363 (r || s) signatures, as the kms
+type ecdsaSigner struct {
+	key  *ecdsa.PrivateKey
+	hash crypto.Hash
+}
+
+func (s *ecdsaSigner) Sign(data []byte) ([]byte, error) {
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, pubkey.Digest(s.hash, data))
+	if err != nil {
+		return nil, err
+	}
+
+	size := (s.key.Curve.Params().BitSize + 7) / 8
+
+	return append(padded(r.Bytes(), size), padded(sig.Bytes(), size)...), nil
+}
+
+func padded(b []byte, size int) []byte {
+	return append(make([]byte, size-len(b)), b...)
+}
+
+type rsaSigner struct {
+	key *rsa.PrivateKey
+}
+
+func (s *rsaSigner) Sign(data []byte) ([]byte, error) {
+	return rsa.SignPSS(rand.Reader, s.key, crypto.SHA256, pubkey.Digest(crypto.SHA256, data), nil)
+}
+
+type failingSigner struct{}
+
+func (s *failingSigner) Sign([]byte) ([]byte, error) {
+	return nil, errors.New("sign error")
+}
+
+func jwkKey(t *testing.T, pubKey interface{}) *verifier.PublicKey {
+	key, err := jose.JWKFromPublicKey(pubKey)
+	require.NoError(t, err)
+
+	return &verifier.PublicKey{Type: "JwsVerificationKey2020", JWK: key}
+}
+
+func keyFetcher(keys map[string]*verifier.PublicKey) verifiable.PublicKeyFetcher {
+	return func(issuerID, keyID string) (*verifier.PublicKey, error) {
+		key, ok := keys[issuerID+keyID]
+		if !ok {
+			return nil, errors.New("key not found")
+		}
+
+		return key, nil
+	}
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(licence))
+	require.NoError(t, err)
+
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	holderPub, holderPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	holderKey, err := jose.JWKFromPublicKey(holderPub)
+	require.NoError(t, err)
+
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, test := range map[string]struct {
+		signer Signer
+		key    *verifier.PublicKey
+		alg    string
+	}{
+		"EdDSA": {signer: signature.GetEd25519Signer(edPriv, edPub),
+			key: &verifier.PublicKey{Type: "Ed25519VerificationKey2018", Value: edPub}, alg: AlgorithmEdDSA},
+		"EdDSA JWK": {signer: signature.GetEd25519Signer(edPriv, edPub), key: jwkKey(t, edPub), alg: AlgorithmEdDSA},
+		"ES256": {signer: &ecdsaSigner{key: p256, hash: crypto.SHA256}, key: jwkKey(t, &p256.PublicKey),
+			alg: AlgorithmES256},
+		"ES384": {signer: &ecdsaSigner{key: p384, hash: crypto.SHA384}, key: jwkKey(t, &p384.PublicKey),
+			alg: AlgorithmES384},
+		"PS256": {signer: &rsaSigner{key: rsaKey}, key: jwkKey(t, &rsaKey.PublicKey), alg: AlgorithmPS256},
+	} {
+		test := test
+
+		t.Run("test "+name, func(t *testing.T) {
+			alg, err := Algorithm(test.key)
+			require.NoError(t, err)
+			require.Equal(t, test.alg, alg)
+
+			sdJWT, err := Issue(vc, &Issuance{VerificationMethod: verificationMethod, Algorithm: alg,
+				Signer: test.signer, Confirmation: &Confirmation{JWK: holderKey}})
+			require.NoError(t, err)
+			require.True(t, strings.HasSuffix(sdJWT, "~"))
+			require.Len(t, strings.Split(sdJWT, "~"), 6)
+			require.NotContains(t, sdJWT, "Jayden")
+
+			presentation, err := Present(sdJWT, []string{"given_name", "age_over_18"}, &KeyBinding{
+				Audience: "https://verifier.example.com", Nonce: "n-0S6_WzA2Mj", Algorithm: AlgorithmEdDSA,
+				Signer: signature.GetEd25519Signer(holderPriv, holderPub), IssuedAt: now})
+			require.NoError(t, err)
+
+			credential, err := Verify(presentation, keyFetcher(map[string]*verifier.PublicKey{
+				verificationMethod: test.key}), &Verification{Audience: "https://verifier.example.com",
+				Nonce: "n-0S6_WzA2Mj", Now: now})
+			require.NoError(t, err)
+			require.Equal(t, issuerDID, credential.Issuer)
+			require.True(t, credential.KeyBound)
+			require.Equal(t, holderKey.Key, credential.Confirmation.JWK.Key)
+			require.Equal(t, []string{"given_name", "age_over_18"}, credential.Disclosed)
+			require.Equal(t, "http://example.gov/credentials/3732", credential.VC["id"])
+			require.Equal(t, map[string]interface{}{
+				"id":          holderDID,
+				"given_name":  "Jayden",
+				"age_over_18": true,
+			}, credential.VC["credentialSubject"])
+		})
+	}
+
+	t.Run("test holder key ID", func(t *testing.T) {
+		sdJWT, err := Issue(vc, &Issuance{VerificationMethod: verificationMethod, Algorithm: AlgorithmEdDSA,
+			Signer: signature.GetEd25519Signer(edPriv, edPub), Confirmation: &Confirmation{KeyID: holderKeyID}})
+		require.NoError(t, err)
+
+		presentation, err := Present(sdJWT, []string{"family_name"}, &KeyBinding{Nonce: "nonce",
+			Algorithm: AlgorithmEdDSA, Signer: signature.GetEd25519Signer(holderPriv, holderPub), IssuedAt: now})
+		require.NoError(t, err)
+
+		credential, err := Verify(presentation, keyFetcher(map[string]*verifier.PublicKey{
+			verificationMethod: jwkKey(t, edPub), holderKeyID: jwkKey(t, holderPub)}),
+			&Verification{Nonce: "nonce", Now: now})
+		require.NoError(t, err)
+		require.True(t, credential.KeyBound)
+		require.Equal(t, &Confirmation{KeyID: holderKeyID}, credential.Confirmation)
+		require.Equal(t, []string{"family_name"}, credential.Disclosed)
+	})
+
+	t.Run("test without key binding", func(t *testing.T) {
+		sdJWT, err := Issue(vc, &Issuance{VerificationMethod: verificationMethod, Algorithm: AlgorithmEdDSA,
+			Signer: signature.GetEd25519Signer(edPriv, edPub)})
+		require.NoError(t, err)
+
+		presentation, err := Present(sdJWT, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, strings.SplitN(sdJWT, "~", 2)[0]+"~", presentation)
+
+		credential, err := Verify(presentation, keyFetcher(map[string]*verifier.PublicKey{
+			verificationMethod: jwkKey(t, edPub)}), &Verification{Now: now})
+		require.NoError(t, err)
+		require.False(t, credential.KeyBound)
+		require.Empty(t, credential.Disclosed)
+		require.Equal(t, map[string]interface{}{"id": holderDID}, credential.VC["credentialSubject"])
+
+		parsed, err := Parse(sdJWT)
+		require.NoError(t, err)
+		require.Equal(t, issuerDID, parsed.Issuer)
+		require.Len(t, parsed.Disclosed, 4)
+		require.Nil(t, parsed.Confirmation)
+
+		_, err = Verify(presentation, keyFetcher(map[string]*verifier.PublicKey{
+			verificationMethod: jwkKey(t, edPub)}), &Verification{RequireKeyBinding: true, Now: now})
+		require.EqualError(t, err, "missing key binding JWT")
+	})
+}
+
+func TestIssue(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	signer := signature.GetEd25519Signer(privKey, pubKey)
+
+	t.Run("test missing issuance date", func(t *testing.T) {
+		vc, err := verifiable.ParseUnverifiedCredential([]byte(licence))
+		require.NoError(t, err)
+
+		vc.Issued = nil
+
+		_, err = Issue(vc, &Issuance{VerificationMethod: verificationMethod, Algorithm: AlgorithmEdDSA,
+			Signer: signer})
+		require.EqualError(t, err, "failed to create JWT claims: missing issuance date")
+	})
+
+	t.Run("test several subjects", func(t *testing.T) {
+		vc, err := verifiable.ParseUnverifiedCredential([]byte(licence))
+		require.NoError(t, err)
+
+		vc.Subject = []map[string]interface{}{{"id": "did:example:1"}, {"id": "did:example:2"}}
+
+		_, err = Issue(vc, &Issuance{VerificationMethod: verificationMethod, Algorithm: AlgorithmEdDSA,
+			Signer: signer})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "more than one subject")
+	})
+
+	t.Run("test signing error", func(t *testing.T) {
+		vc, err := verifiable.ParseUnverifiedCredential([]byte(licence))
+		require.NoError(t, err)
+
+		_, err = Issue(vc, &Issuance{VerificationMethod: verificationMethod, Algorithm: AlgorithmEdDSA,
+			Signer: &failingSigner{}})
+		require.EqualError(t, err, "failed to sign JWT: sign error")
+	})
+}
+
+func TestVerify(t *testing.T) {
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(licence))
+	require.NoError(t, err)
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	holderPub, holderPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	holderKey, err := jose.JWKFromPublicKey(holderPub)
+	require.NoError(t, err)
+
+	fetcher := keyFetcher(map[string]*verifier.PublicKey{verificationMethod: jwkKey(t, pubKey)})
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	sdJWT, err := Issue(vc, &Issuance{VerificationMethod: verificationMethod, Algorithm: AlgorithmEdDSA,
+		Signer: signature.GetEd25519Signer(privKey, pubKey), Confirmation: &Confirmation{JWK: holderKey}})
+	require.NoError(t, err)
+
+	presentAt := func(t *testing.T, issuedAt time.Time, names ...string) string {
+		presentation, err := Present(sdJWT, names, &KeyBinding{Audience: "verifier", Nonce: "nonce",
+			Algorithm: AlgorithmEdDSA, Signer: signature.GetEd25519Signer(holderPriv, holderPub),
+			IssuedAt: issuedAt})
+		require.NoError(t, err)
+
+		return presentation
+	}
+
+	present := func(t *testing.T, names ...string) string {
+		return presentAt(t, now, names...)
+	}
+
+	t.Run("test expired", func(t *testing.T) {
+		_, err := Verify(present(t), fetcher, &Verification{Now: time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)})
+		require.EqualError(t, err, "the SD-JWT expired at 2030-01-01T00:00:00Z")
+	})
+
+	t.Run("test not valid yet", func(t *testing.T) {
+		_, err := Verify(present(t), fetcher, &Verification{Now: time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)})
+		require.EqualError(t, err, "the SD-JWT isn't valid before 2020-01-01T00:00:00Z")
+	})
+
+	t.Run("test invalid issuer signature", func(t *testing.T) {
+		otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		_, err = Verify(present(t), keyFetcher(map[string]*verifier.PublicKey{verificationMethod: jwkKey(t, otherPub)}),
+			&Verification{Now: now})
+		require.EqualError(t, err, "invalid issuer JWT: invalid signature")
+	})
+
+	t.Run("test key not found", func(t *testing.T) {
+		_, err := Verify(present(t), keyFetcher(nil), &Verification{Now: now})
+		require.EqualError(t, err, "failed to fetch the key "+verificationMethod+": key not found")
+	})
+
+	t.Run("test disclosure not in the SD-JWT", func(t *testing.T) {
+		d, err := newDisclosure("given_name", "Jane")
+		require.NoError(t, err)
+
+		parts := strings.Split(sdJWT, "~")
+
+		_, err = Verify(parts[0]+"~"+d.encoded+"~", fetcher, &Verification{Now: now})
+		require.EqualError(t, err, "the digest of the disclosure of given_name isn't in the SD-JWT")
+	})
+
+	t.Run("test disclosure presented twice", func(t *testing.T) {
+		parts := strings.Split(sdJWT, "~")
+
+		_, err := Verify(parts[0]+"~"+parts[1]+"~"+parts[1]+"~", fetcher, &Verification{Now: now})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "isn't in the SD-JWT")
+	})
+
+	t.Run("test invalid disclosure", func(t *testing.T) {
+		parts := strings.Split(sdJWT, "~")
+
+		_, err := Verify(parts[0]+"~"+encode([]byte(`["salt", "name"]`))+"~", fetcher, &Verification{Now: now})
+		require.EqualError(t, err, "invalid disclosure: not a salt, a claim name and a claim value")
+	})
+
+	t.Run("test key binding - wrong audience", func(t *testing.T) {
+		_, err := Verify(present(t, "given_name"), fetcher, &Verification{Audience: "other", Now: now})
+		require.EqualError(t, err, "invalid key binding JWT: the audience isn't other")
+	})
+
+	t.Run("test key binding - wrong nonce", func(t *testing.T) {
+		_, err := Verify(present(t, "given_name"), fetcher, &Verification{Nonce: "other", Now: now})
+		require.EqualError(t, err, "invalid key binding JWT: the nonce doesn't match")
+	})
+
+	t.Run("test key binding - age", func(t *testing.T) {
+		_, err := Verify(presentAt(t, now.Add(-4*time.Minute)), fetcher, &Verification{Now: now})
+		require.NoError(t, err)
+
+		_, err = Verify(presentAt(t, now.Add(30*time.Second)), fetcher, &Verification{Now: now})
+		require.NoError(t, err)
+
+		_, err = Verify(presentAt(t, now.Add(-6*time.Minute)), fetcher, &Verification{Now: now})
+		require.EqualError(t, err, "invalid key binding JWT: issued at 2020-05-31T23:54:00Z, more than 5m0s ago")
+
+		_, err = Verify(presentAt(t, now.Add(-6*time.Minute)), fetcher,
+			&Verification{Now: now, KeyBindingMaxAge: 10 * time.Minute})
+		require.NoError(t, err)
+
+		_, err = Verify(presentAt(t, now.Add(2*time.Minute)), fetcher, &Verification{Now: now})
+		require.EqualError(t, err, "invalid key binding JWT: issued in the future at 2020-06-01T00:02:00Z")
+	})
+
+	t.Run("test key binding - missing issuance time", func(t *testing.T) {
+		presentation := strings.SplitN(sdJWT, "~", 2)[0] + "~"
+
+		kbJWT, err := signJWT(map[string]interface{}{"typ": typeKeyBinding}, map[string]interface{}{
+			"sd_hash": sdHash(presentation)}, AlgorithmEdDSA, signature.GetEd25519Signer(holderPriv, holderPub))
+		require.NoError(t, err)
+
+		_, err = Verify(presentation+kbJWT, fetcher, &Verification{Now: now})
+		require.EqualError(t, err, "invalid key binding JWT: missing issuance time (iat)")
+	})
+
+	t.Run("test key binding - other presentation", func(t *testing.T) {
+		presentation := present(t, "given_name")
+		kbJWT := presentation[strings.LastIndex(presentation, "~")+1:]
+
+		_, err := Verify(strings.SplitN(sdJWT, "~", 2)[0]+"~"+kbJWT, fetcher, &Verification{Now: now})
+		require.EqualError(t, err, "invalid key binding JWT: the SD-JWT hash doesn't match the presentation")
+	})
+
+	t.Run("test key binding - not signed by the holder key", func(t *testing.T) {
+		presentation, err := Present(sdJWT, nil, &KeyBinding{Algorithm: AlgorithmEdDSA,
+			Signer: signature.GetEd25519Signer(privKey, pubKey), IssuedAt: now})
+		require.NoError(t, err)
+
+		_, err = Verify(presentation, fetcher, &Verification{Now: now})
+		require.EqualError(t, err, "invalid key binding JWT: invalid signature")
+	})
+
+	t.Run("test key binding - SD-JWT not bound", func(t *testing.T) {
+		unbound, err := Issue(vc, &Issuance{VerificationMethod: verificationMethod, Algorithm: AlgorithmEdDSA,
+			Signer: signature.GetEd25519Signer(privKey, pubKey)})
+		require.NoError(t, err)
+
+		presentation, err := Present(unbound, nil, &KeyBinding{Algorithm: AlgorithmEdDSA,
+			Signer: signature.GetEd25519Signer(holderPriv, holderPub), IssuedAt: now})
+		require.NoError(t, err)
+
+		_, err = Verify(presentation, fetcher, &Verification{Now: now})
+		require.EqualError(t, err, "invalid key binding JWT: the SD-JWT isn't bound to a holder key")
+	})
+
+	t.Run("test not a SD-JWT", func(t *testing.T) {
+		_, err := Verify("abc", fetcher, &Verification{Now: now})
+		require.EqualError(t, err, "not a SD-JWT: missing ~ separator")
+	})
+
+	t.Run("test present undisclosable claim", func(t *testing.T) {
+		_, err := Present(sdJWT, []string{"id"}, nil)
+		require.EqualError(t, err, "no disclosure of the claim id")
+	})
+}
@@ -10,15 +10,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
 
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 	"github.com/trustbloc/edge-core/pkg/storage"
 
+	"github.com/trustbloc/edge-service/pkg/cache"
 	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/metrics"
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
 )
 
 const (
@@ -28,7 +35,20 @@ const (
 	CredentialStatusType  = "CredentialStatusList2017"
 	credentialStatusStore = "credentialstatus"
 	latestListID          = "latestListID"
+	statusIndex           = "statusIndex"
 	defaultRepresentation = "jws"
+	// maxUpdateAttempts is how many times an update of a csl is applied when the stored csl changed since it was
+	// read
+	maxUpdateAttempts = 5
+
+	// StatusRevoked is the status of a revoked credential, a revoked credential can't be reinstated
+	StatusRevoked = "Revoked"
+	// StatusSuspended is the status of a temporarily suspended credential
+	StatusSuspended = "Suspended"
+	// StatusActive is the status of a credential not in its status list, e.g. once reinstated
+	StatusActive = "Active"
+
+	credentialsContext = "https://www.w3.org/2018/credentials/v1"
 
 	// proof json keys
 	jsonKeyProofValue         = "proofValue"
@@ -37,17 +57,58 @@ const (
 	jsonKeySignaturefType     = "type"
 )
 
+// ErrInvalidStatusTransition is returned when the status change isn't allowed from the current status of the
+// credential
+var ErrInvalidStatusTransition = errors.New("invalid status transition")
+
+// ErrConcurrentUpdate is returned when a csl couldn't be updated, the stored csl having changed since it was read on
+// each attempt
+var ErrConcurrentUpdate = errors.New("csl updated concurrently")
+
 type crypto interface {
 	SignCredential(dataProfile *vcprofile.DataProfile, vc *verifiable.Credential,
 		opts ...vccrypto.SigningOpts) (*verifiable.Credential, error)
 }
 
+// Option configures the credential status manager
+type Option func(c *CredentialStatusManager)
+
+// WithShards option sets the number of active status lists of each profile the status IDs are created in, in turn,
+// so the status updates of the credentials are spread over the lists. A list filled is rolled over to a new list.
+func WithShards(shards int) Option {
+	return func(m *CredentialStatusManager) {
+		if shards > 0 {
+			m.shards = shards
+		}
+	}
+}
+
+// WithCache option caches the signed status lists served to the verifiers, the cached lists are invalidated when
+// updated by this instance and expire with the cache otherwise. The status updates always read the lists from the
+// store, as a stale list written back would lose the updates of other instances.
+func WithCache(c cache.Cache) Option {
+	return func(m *CredentialStatusManager) {
+		m.cache = c
+	}
+}
+
 // CredentialStatusManager implement spec https://w3c-ccg.github.io/vc-csl2017/
+//
+// The updates of a csl are serialized by the manager, so the updates of an instance are never lost. A csl is only
+// stored if the stored csl is unchanged since it was read, the update being applied again to the stored csl
+// otherwise. The stored csl is compared and replaced atomically by the store, so the updates of other instances
+// sharing the store aren't lost either.
 type CredentialStatusManager struct {
-	store    storage.Store
+	store    conditional.Store
+	cache    cache.Cache
 	url      string
 	listSize int
 	crypto   crypto
+	shards   int
+	mutex    sync.Mutex
+	locks    map[string]*sync.Mutex
+	// next is the shard of the next status ID of each profile
+	next map[string]int
 }
 
 // CSL struct
@@ -62,6 +123,46 @@ type cslWrapper struct {
 	CSL  *CSL   `json:"csl"`
 	Size int    `json:"size"`
 	ID   string `json:"id"`
+	// Profile is the issuer profile the csl belongs to, empty for the lists shared by all the profiles before the
+	// lists were scoped per profile
+	Profile string `json:"profile,omitempty"`
+	// SignedCSL is the csl signed as a status list credential, re-signed when the csl is updated
+	SignedCSL json.RawMessage `json:"signedCSL,omitempty"`
+	// Sequence is incremented on each update of the csl, to detect the concurrent updates
+	Sequence int64 `json:"sequence,omitempty"`
+}
+
+// profileLists are the status lists of a profile: the active lists the status IDs are created in, one per shard, and
+// the number of the last list created. The profiles created before the lists were sharded have their latest list
+// number stored instead.
+type profileLists struct {
+	Active []string `json:"active"`
+	Last   int      `json:"last"`
+}
+
+// StatusEntry is the status list of a credential, with the position of its status ID in the order the status IDs of
+// the list were created
+type StatusEntry struct {
+	ListID   string `json:"listId"`
+	Position int    `json:"position"`
+}
+
+// ListUtilization is the number of status IDs created in a status list over its size
+type ListUtilization struct {
+	ID       string `json:"id"`
+	Used     int    `json:"used"`
+	Capacity int    `json:"capacity"`
+	// Active is set for the lists the status IDs are created in
+	Active bool `json:"active"`
+}
+
+// Utilization is the utilization of the status lists of a profile
+type Utilization struct {
+	Profile string             `json:"profile"`
+	Lists   []*ListUtilization `json:"lists"`
+	Used    int                `json:"used"`
+	// Available is the number of status IDs the active lists can still hold before they roll over
+	Available int `json:"available"`
 }
 
 // VCStatus vc status
@@ -71,7 +172,8 @@ type VCStatus struct {
 }
 
 // New returns new Credential Status List
-func New(provider storage.Provider, url string, listSize int, c crypto) (*CredentialStatusManager, error) {
+func New(provider storage.Provider, url string, listSize int, c crypto,
+	opts ...Option) (*CredentialStatusManager, error) {
 	err := provider.CreateStore(credentialStatusStore)
 	if err != nil {
 		if err != storage.ErrDuplicateStore {
@@ -84,46 +186,166 @@ func New(provider storage.Provider, url string, listSize int, c crypto) (*Creden
 		return nil, err
 	}
 
-	return &CredentialStatusManager{store: store, url: url, listSize: listSize, crypto: c}, nil
+	m := &CredentialStatusManager{store: conditional.New(store), url: url, listSize: listSize, crypto: c, shards: 1,
+		locks: make(map[string]*sync.Mutex), next: make(map[string]int)}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m, nil
+}
+
+// CreateStatusID create status id in an active status list of the profile, the status lists are scoped per profile
+// and signed with the profile. The status list of the credential is indexed by the credential ID, if set.
+func (c *CredentialStatusManager) CreateStatusID(profile *vcprofile.DataProfile,
+	vcID string) (*verifiable.TypedID, error) {
+	defer c.lock(latestListIDKey(profile.Name))()
+
+	for attempt := 1; ; attempt++ {
+		status, err := c.createStatusID(profile, vcID)
+		if !errors.Is(err, ErrConcurrentUpdate) || attempt == maxUpdateAttempts {
+			return status, err
+		}
+	}
 }
 
-// CreateStatusID create status id
-func (c *CredentialStatusManager) CreateStatusID() (*verifiable.TypedID, error) {
-	cslWrapper, err := c.getLatestCSL()
+func (c *CredentialStatusManager) createStatusID(profile *vcprofile.DataProfile,
+	vcID string) (*verifiable.TypedID, error) {
+	lists, err := c.getProfileLists(profile.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	shard := c.nextShard(profile.Name, len(lists.Active))
+
+	cslWrapper, err := c.getProfileCSL(profile.Name, lists.Active[shard])
 	if err != nil {
 		return nil, err
 	}
 
+	if cslWrapper.Size == 0 {
+		if err := c.signCSL(cslWrapper, profile); err != nil {
+			return nil, err
+		}
+	}
+
+	position := cslWrapper.Size
 	cslWrapper.Size++
 
-	if err := c.storeCSL(cslWrapper); err != nil {
+	unlock := c.lock(cslWrapper.CSL.ID)
+	err = c.putCSL(cslWrapper)
+	unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.SetCSLUtilization(cslWrapper.Size, c.listSize)
+
+	if cslWrapper.Size >= c.listSize {
+		// the list is filled, the shard rolls over to a new list
+		lists.Last++
+		lists.Active[shard] = strconv.Itoa(lists.Last)
+
+		if err := c.putProfileLists(profile.Name, lists); err != nil {
+			return nil, err
+		}
+	}
+
+	if vcID != "" {
+		if err := c.putStatusEntry(vcID, &StatusEntry{ListID: cslWrapper.CSL.ID, Position: position}); err != nil {
+			return nil, err
+		}
+	}
+
+	return &verifiable.TypedID{ID: cslWrapper.CSL.ID, Type: CredentialStatusType}, nil
+}
+
+// GetStatusEntry returns the status list of the credential and the position of its status ID in the list, the error
+// is storage.ErrValueNotFound for the credentials without status or issued before the status lists were indexed
+func (c *CredentialStatusManager) GetStatusEntry(vcID string) (*StatusEntry, error) {
+	entryBytes, err := c.store.Get(statusIndexKey(vcID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status entry from store: %w", err)
+	}
+
+	var entry StatusEntry
+	if err := json.Unmarshal(entryBytes, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal status entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// Utilization returns the number of status IDs created in each status list of the profile, the lists filled being
+// rolled over to new lists
+func (c *CredentialStatusManager) Utilization(profileName string) (*Utilization, error) {
+	lists, err := c.getProfileLists(profileName)
+	if err != nil {
 		return nil, err
 	}
 
-	if cslWrapper.Size == c.listSize {
-		id, err := strconv.Atoi(cslWrapper.ID)
+	active := make(map[string]bool)
+	for _, id := range lists.Active {
+		active[id] = true
+	}
+
+	utilization := &Utilization{Profile: profileName, Lists: []*ListUtilization{}}
+
+	for i := 1; i <= lists.Last; i++ {
+		id := strconv.Itoa(i)
+
+		w, err := c.getProfileCSL(profileName, id)
 		if err != nil {
 			return nil, err
 		}
 
-		id++
+		list := &ListUtilization{ID: w.CSL.ID, Used: w.Size, Capacity: c.listSize, Active: active[id]}
 
-		if err := c.store.Put(latestListID, []byte(strconv.FormatInt(int64(id), 10))); err != nil {
-			return nil, fmt.Errorf("failed to store latest list ID in store: %w", err)
+		utilization.Lists = append(utilization.Lists, list)
+		utilization.Used += list.Used
+
+		if list.Active && list.Used < list.Capacity {
+			utilization.Available += list.Capacity - list.Used
 		}
 	}
 
-	return &verifiable.TypedID{ID: cslWrapper.CSL.ID, Type: CredentialStatusType}, nil
+	return utilization, nil
 }
 
-// UpdateVCStatus update vc status
+// UpdateVCStatus update vc status, the status of a revoked credential can't be changed
 func (c *CredentialStatusManager) UpdateVCStatus(v *verifiable.Credential, profile *vcprofile.DataProfile,
 	status, statusReason string) error {
-	cslWrapper, err := c.getCSLWrapper(v.Status.ID)
+	return c.updateVCStatus(v, profile, status, statusReason, func(vcStatus *VCStatus) error {
+		if vcStatus != nil && vcStatus.CurrentStatus == StatusRevoked {
+			return fmt.Errorf("%w: cannot change the status of revoked credential %s", ErrInvalidStatusTransition,
+				v.ID)
+		}
+
+		return nil
+	})
+}
+
+// updateVCStatus adds the status credential of the credential to its list, replacing its current status credential,
+// if the current status of the credential passes the check. The check is applied again to the list being updated,
+// the status of the credential having possibly changed since it was checked.
+func (c *CredentialStatusManager) updateVCStatus(v *verifiable.Credential, profile *vcprofile.DataProfile,
+	status, statusReason string, check func(vcStatus *VCStatus) error) error {
+	current, err := c.getCSLWrapper(v.Status.ID)
+	if err != nil {
+		return err
+	}
+
+	vcStatus, err := findStatusCredential(current.CSL, v.ID)
 	if err != nil {
 		return err
 	}
 
+	if err = check(vcStatus); err != nil {
+		return err
+	}
+
 	signOpts, err := prepareSigningOpts(profile, v.Proofs)
 	if err != nil {
 		return err
@@ -139,21 +361,107 @@ func (c *CredentialStatusManager) UpdateVCStatus(v *verifiable.Credential, profi
 		return err
 	}
 
-	for i, vc := range cslWrapper.CSL.VC {
-		if strings.Contains(vc, v.ID) {
-			cslWrapper.CSL.VC = append(cslWrapper.CSL.VC[:i], cslWrapper.CSL.VC[i+1:]...)
-			break
+	signedStatusCredentialBytes, err := signedStatusCredential.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	return c.updateCSL(v.Status.ID, func(w *cslWrapper) error {
+		vcStatus, err := findStatusCredential(w.CSL, v.ID)
+		if err != nil {
+			return err
+		}
+
+		if err = check(vcStatus); err != nil {
+			return err
 		}
+
+		if err = removeStatusCredential(w.CSL, v.ID); err != nil {
+			return err
+		}
+
+		w.CSL.VC = append(w.CSL.VC, string(signedStatusCredentialBytes))
+
+		return c.signCSL(w, profile)
+	})
+}
+
+// GetVCStatus returns the status of the credential in its status list, nil if the credential is active
+func (c *CredentialStatusManager) GetVCStatus(v *verifiable.Credential) (*VCStatus, error) {
+	if v.Status == nil {
+		return nil, fmt.Errorf("credential %s has no status", v.ID)
 	}
 
-	signedStatusCredentialBytes, err := signedStatusCredential.MarshalJSON()
+	cslWrapper, err := c.getCSLWrapper(v.Status.ID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	vcStatus, err := findStatusCredential(cslWrapper.CSL, v.ID)
+
+	return vcStatus, err
+}
+
+// RevokeVC revokes the active or suspended credential
+func (c *CredentialStatusManager) RevokeVC(v *verifiable.Credential, profile *vcprofile.DataProfile,
+	statusReason string) error {
+	if v.Status == nil {
+		return fmt.Errorf("credential %s has no status", v.ID)
+	}
+
+	return c.updateVCStatus(v, profile, StatusRevoked, statusReason, func(vcStatus *VCStatus) error {
+		if vcStatus != nil && vcStatus.CurrentStatus == StatusRevoked {
+			return fmt.Errorf("%w: credential %s is already revoked", ErrInvalidStatusTransition, v.ID)
+		}
+
+		return nil
+	})
+}
+
+// SuspendVC suspends the active credential until it is reinstated
+func (c *CredentialStatusManager) SuspendVC(v *verifiable.Credential, profile *vcprofile.DataProfile,
+	statusReason string) error {
+	if v.Status == nil {
+		return fmt.Errorf("credential %s has no status", v.ID)
+	}
+
+	return c.updateVCStatus(v, profile, StatusSuspended, statusReason, func(vcStatus *VCStatus) error {
+		if vcStatus != nil {
+			return fmt.Errorf("%w: credential %s is %s", ErrInvalidStatusTransition, v.ID,
+				strings.ToLower(vcStatus.CurrentStatus))
+		}
+
+		return nil
+	})
+}
+
+// ReinstateVC reinstates the suspended credential, its status credential is removed from the status list
+func (c *CredentialStatusManager) ReinstateVC(v *verifiable.Credential, profile *vcprofile.DataProfile) error {
+	if v.Status == nil {
+		return fmt.Errorf("credential %s has no status", v.ID)
 	}
 
-	cslWrapper.CSL.VC = append(cslWrapper.CSL.VC, string(signedStatusCredentialBytes))
+	return c.updateCSL(v.Status.ID, func(w *cslWrapper) error {
+		vcStatus, err := findStatusCredential(w.CSL, v.ID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case vcStatus == nil:
+			return fmt.Errorf("%w: credential %s is not suspended", ErrInvalidStatusTransition, v.ID)
+		case vcStatus.CurrentStatus == StatusRevoked:
+			return fmt.Errorf("%w: cannot reinstate revoked credential %s", ErrInvalidStatusTransition, v.ID)
+		case vcStatus.CurrentStatus != StatusSuspended:
+			return fmt.Errorf("%w: credential %s is not suspended", ErrInvalidStatusTransition, v.ID)
+		}
+
+		if err := removeStatusCredential(w.CSL, v.ID); err != nil {
+			return err
+		}
 
-	return c.storeCSL(cslWrapper)
+		return c.signCSL(w, profile)
+	})
 }
 
 // GetCSL get csl
@@ -166,6 +474,36 @@ func (c *CredentialStatusManager) GetCSL(id string) (*CSL, error) {
 	return cslWrapper.CSL, nil
 }
 
+// GetSignedCSL returns the csl signed as a status list credential, lists stored before the lists were signed are
+// returned unsigned
+func (c *CredentialStatusManager) GetSignedCSL(id string) ([]byte, error) {
+	if c.cache != nil {
+		if cslBytes, ok := c.cache.Get(id); ok {
+			return cslBytes, nil
+		}
+	}
+
+	cslWrapper, err := c.getCSLWrapper(id)
+	if err != nil {
+		return nil, err
+	}
+
+	cslBytes := []byte(cslWrapper.SignedCSL)
+
+	if len(cslBytes) == 0 {
+		cslBytes, err = json.Marshal(cslWrapper.CSL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.cache != nil {
+		c.cache.Set(id, cslBytes)
+	}
+
+	return cslBytes, nil
+}
+
 func (c *CredentialStatusManager) getCSLWrapper(id string) (*cslWrapper, error) {
 	cslWrapperBytes, err := c.store.Get(id)
 	if err != nil {
@@ -198,48 +536,307 @@ func (c *CredentialStatusManager) createStatusCredential(v *verifiable.Credentia
 	return validatedStatusCred, nil
 }
 
-func (c *CredentialStatusManager) getLatestCSL() (*cslWrapper, error) {
-	// get latest id
-	id, err := c.store.Get(latestListID)
+// signCSL signs the csl as a status list credential issued by the profile the csl belongs to, the verifiers reading
+// the csl find its fields at the top level of the credential. The lists shared by all the profiles are not signed, as
+// their issuer would change with the profile updating them.
+func (c *CredentialStatusManager) signCSL(w *cslWrapper, profile *vcprofile.DataProfile) error {
+	if w.Profile == "" {
+		w.SignedCSL = nil
+
+		return nil
+	}
+
+	if w.Profile != profile.Name {
+		return fmt.Errorf("csl %s belongs to profile %s, not %s", w.CSL.ID, w.Profile, profile.Name)
+	}
+
+	context := []string{credentialsContext, Context}
+	if signatureContext := vccrypto.SignatureContext(profile.SignatureType); signatureContext != "" {
+		context = append(context, signatureContext)
+	}
+
+	vcs := w.CSL.VC
+	if vcs == nil {
+		vcs = []string{}
+	}
+
+	listCredential := &verifiable.Credential{
+		Context: context,
+		ID:      w.CSL.ID,
+		Types:   []string{"VerifiableCredential", CredentialStatusType},
+		Issuer:  verifiable.Issuer{ID: profile.DID},
+		Issued:  util.NewTime(time.Now().UTC()),
+		Subject: map[string]interface{}{"id": w.CSL.ID},
+		CustomFields: verifiable.CustomFields{
+			"description":          w.CSL.Description,
+			"verifiableCredential": vcs,
+		},
+	}
+
+	signedListCredential, err := c.crypto.SignCredential(profile, listCredential)
 	if err != nil {
-		if errors.Is(err, storage.ErrValueNotFound) {
-			if errPut := c.store.Put(latestListID, []byte("1")); errPut != nil {
-				return nil, fmt.Errorf("failed to store latest list ID in store: %w", errPut)
-			}
+		return fmt.Errorf("failed to sign csl: %w", err)
+	}
 
-			return &cslWrapper{&CSL{ID: c.url + "/1"}, 0, "1"}, nil
-		}
+	w.SignedCSL, err = signedListCredential.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal signed csl: %w", err)
+	}
+
+	return nil
+}
+
+// getProfileLists returns the status lists of the profile, new lists being created for the shards without active list
+func (c *CredentialStatusManager) getProfileLists(profileName string) (*profileLists, error) {
+	lists := &profileLists{}
 
+	listsBytes, err := c.store.Get(latestListIDKey(profileName))
+
+	switch {
+	case errors.Is(err, storage.ErrValueNotFound):
+	case err != nil:
 		return nil, fmt.Errorf("failed to get latestListID from store: %w", err)
+	default:
+		if id, errAtoi := strconv.Atoi(string(listsBytes)); errAtoi == nil {
+			// the latest list of a profile created before the lists were sharded
+			lists = &profileLists{Active: []string{string(listsBytes)}, Last: id}
+		} else if err := json.Unmarshal(listsBytes, lists); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal status lists of profile %s: %w", profileName, err)
+		}
 	}
 
-	statusID := c.url + "/" + string(id)
-	w, err := c.getCSLWrapper(statusID)
+	if len(lists.Active) == c.shards {
+		return lists, nil
+	}
+
+	for len(lists.Active) < c.shards {
+		lists.Last++
+		lists.Active = append(lists.Active, strconv.Itoa(lists.Last))
+	}
+
+	// the lists of the shards removed are left partially filled
+	lists.Active = lists.Active[:c.shards]
+
+	if err := c.putProfileLists(profileName, lists); err != nil {
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+func (c *CredentialStatusManager) putProfileLists(profileName string, lists *profileLists) error {
+	listsBytes, err := json.Marshal(lists)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status lists: %w", err)
+	}
+
+	if err := c.store.Put(latestListIDKey(profileName), listsBytes); err != nil {
+		return fmt.Errorf("failed to store latest list ID in store: %w", err)
+	}
+
+	return nil
+}
 
+// getProfileCSL returns the status list of the profile with the number, empty if not created yet
+func (c *CredentialStatusManager) getProfileCSL(profileName, id string) (*cslWrapper, error) {
+	statusID := c.url + "/" + url.PathEscape(profileName) + "/" + id
+
+	w, err := c.getCSLWrapper(statusID)
 	if err != nil {
 		if errors.Is(err, storage.ErrValueNotFound) {
-			return &cslWrapper{&CSL{ID: statusID}, 0, string(id)}, nil
+			return &cslWrapper{CSL: &CSL{ID: statusID}, ID: id, Profile: profileName}, nil
 		}
 
-		return nil, fmt.Errorf("failed to get csl from store: %w", err)
+		return nil, err
 	}
 
 	return w, nil
 }
 
-func (c *CredentialStatusManager) storeCSL(cslWrapper *cslWrapper) error {
+// nextShard returns the shard of the next status ID of the profile, the shards being used in turn
+func (c *CredentialStatusManager) nextShard(profileName string, shards int) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.next == nil {
+		c.next = make(map[string]int)
+	}
+
+	shard := c.next[profileName] % shards
+	c.next[profileName] = shard + 1
+
+	return shard
+}
+
+func (c *CredentialStatusManager) putStatusEntry(vcID string, entry *StatusEntry) error {
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status entry: %w", err)
+	}
+
+	if err := c.store.Put(statusIndexKey(vcID), entryBytes); err != nil {
+		return fmt.Errorf("failed to store status entry in store: %w", err)
+	}
+
+	return nil
+}
+
+// latestListIDKey returns the key of the status lists of the profile, named after the ID of the latest list stored
+// before the lists were sharded. The csl are stored by URL so the keys don't collide.
+func latestListIDKey(profileName string) string {
+	return latestListID + "/" + profileName
+}
+
+// statusIndexKey returns the key of the status entry of the credential
+func statusIndexKey(vcID string) string {
+	return statusIndex + "/" + vcID
+}
+
+// updateCSL applies the update to the csl read from the store and stores it, the update being applied again if the
+// stored csl changed in the meantime
+func (c *CredentialStatusManager) updateCSL(id string, update func(w *cslWrapper) error) error {
+	defer c.lock(id)()
+
+	for attempt := 1; ; attempt++ {
+		w, err := c.getCSLWrapper(id)
+		if err != nil {
+			return err
+		}
+
+		if err = update(w); err != nil {
+			return err
+		}
+
+		err = c.putCSL(w)
+		if !errors.Is(err, ErrConcurrentUpdate) || attempt == maxUpdateAttempts {
+			return err
+		}
+	}
+}
+
+// putCSL stores the csl with the next sequence, unless the stored csl was updated since the csl was read. The caller
+// holds the lock of the csl.
+func (c *CredentialStatusManager) putCSL(w *cslWrapper) error {
+	stored, err := c.store.Get(w.CSL.ID)
+
+	switch {
+	case errors.Is(err, storage.ErrValueNotFound):
+		// new csl
+		stored = nil
+	case err != nil:
+		return fmt.Errorf("failed to get csl from store: %w", err)
+	default:
+		var storedWrapper cslWrapper
+		if err := json.Unmarshal(stored, &storedWrapper); err != nil {
+			return fmt.Errorf("failed to unmarshal csl bytes: %w", err)
+		}
+
+		if storedWrapper.Sequence != w.Sequence {
+			return fmt.Errorf("%w: csl %s", ErrConcurrentUpdate, w.CSL.ID)
+		}
+	}
+
+	w.Sequence++
+
+	return c.storeCSL(w, stored)
+}
+
+// lock locks the key for this instance, returning the function unlocking it
+func (c *CredentialStatusManager) lock(key string) func() {
+	c.mutex.Lock()
+
+	if c.locks == nil {
+		c.locks = make(map[string]*sync.Mutex)
+	}
+
+	l, ok := c.locks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		c.locks[key] = l
+	}
+
+	c.mutex.Unlock()
+
+	l.Lock()
+
+	return l.Unlock
+}
+
+// storeCSL stores the csl in place of the stored csl, nil for a new csl. The csl isn't stored if the stored csl
+// changed in the meantime, e.g. updated by another instance.
+func (c *CredentialStatusManager) storeCSL(cslWrapper *cslWrapper, stored []byte) error {
 	cslWrapperBytes, err := json.Marshal(cslWrapper)
 	if err != nil {
 		return fmt.Errorf("failed to marshal csl struct: %w", err)
 	}
 
-	if err := c.store.Put(cslWrapper.CSL.ID, cslWrapperBytes); err != nil {
+	if stored == nil {
+		err = c.store.PutIfAbsent(cslWrapper.CSL.ID, cslWrapperBytes)
+	} else {
+		err = c.store.Replace(cslWrapper.CSL.ID, stored, cslWrapperBytes)
+	}
+
+	if errors.Is(err, conditional.ErrConflict) {
+		return fmt.Errorf("%w: csl %s", ErrConcurrentUpdate, cslWrapper.CSL.ID)
+	}
+
+	if err != nil {
 		return fmt.Errorf("failed to store csl in store: %w", err)
 	}
 
+	if c.cache != nil {
+		c.cache.Delete(cslWrapper.CSL.ID)
+	}
+
+	return nil
+}
+
+// findStatusCredential returns the status of the status credential of the credential in the list, nil if the list
+// has no status credential for the credential
+func findStatusCredential(csl *CSL, vcID string) (*VCStatus, error) {
+	i, statusCredential, err := indexOfStatusCredential(csl, vcID)
+	if err != nil || i < 0 {
+		return nil, err
+	}
+
+	return &statusCredential.Subject, nil
+}
+
+// removeStatusCredential removes the status credential of the credential from the list, if any
+func removeStatusCredential(csl *CSL, vcID string) error {
+	i, _, err := indexOfStatusCredential(csl, vcID)
+	if err != nil || i < 0 {
+		return err
+	}
+
+	csl.VC = append(csl.VC[:i], csl.VC[i+1:]...)
+
 	return nil
 }
 
+type statusCredential struct {
+	ID      string   `json:"id"`
+	Subject VCStatus `json:"credentialSubject"`
+}
+
+// indexOfStatusCredential returns the index of the status credential of the credential in the list, matched on the
+// exact credential ID, -1 if the list has no status credential for the credential
+func indexOfStatusCredential(csl *CSL, vcID string) (int, *statusCredential, error) {
+	for i, vc := range csl.VC {
+		var sc statusCredential
+
+		if err := json.Unmarshal([]byte(vc), &sc); err != nil {
+			return -1, nil, fmt.Errorf("failed to unmarshal status credential: %w", err)
+		}
+
+		if sc.ID == vcID {
+			return i, &sc, nil
+		}
+	}
+
+	return -1, nil, nil
+}
+
 // prepareSigningOpts prepares signing opts from recently issued proof of given credential
 func prepareSigningOpts(profile *vcprofile.DataProfile, proofs []verifiable.Proof) ([]vccrypto.SigningOpts, error) {
 	var signingOpts []vccrypto.SigningOpts
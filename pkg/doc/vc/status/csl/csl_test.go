@@ -10,7 +10,10 @@ import (
 	"crypto/ed25519"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -23,8 +26,10 @@ import (
 	"github.com/trustbloc/edge-core/pkg/storage"
 	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
 
+	"github.com/trustbloc/edge-service/pkg/cache/memcache"
 	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
 	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
 )
 
 const (
@@ -54,6 +59,17 @@ const (
     "spouse": "did:example:c276e12ec21ebfeb1f712ebc6f1"
   }
 }`
+
+	statusTestCred = `{
+  "@context": "https://www.w3.org/2018/credentials/v1",
+  "type": "VerifiableCredential",
+  "id": "http://example.edu/credentials/%d",
+  "issuanceDate": "2020-03-16T22:37:26.544Z",
+  "issuer": "did:example:oakek12as93mas91220dapop092",
+  "credentialSubject": {
+    "id": "did:example:ebfeb1f712ebc6f1c276e12ec21"
+  }
+}`
 )
 
 func TestCredentialStatusList_New(t *testing.T) {
@@ -68,32 +84,48 @@ func TestCredentialStatusList_New(t *testing.T) {
 func TestCredentialStatusList_CreateStatusID(t *testing.T) {
 	t.Run("test success", func(t *testing.T) {
 		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 2,
-			vccrypto.New(&mockkms.KeyManager{}, &cryptomock.Crypto{}, &vdrimock.MockVDRIRegistry{}))
+			&mockCrypto{})
 		require.NoError(t, err)
 
-		status, err := s.CreateStatusID()
+		status, err := s.CreateStatusID(getTestProfile(), "")
 		require.NoError(t, err)
 		require.Equal(t, CredentialStatusType, status.Type)
-		require.Equal(t, "localhost:8080/status/1", status.ID)
-		csl, err := s.GetCSL("localhost:8080/status/1")
+		require.Equal(t, "localhost:8080/status/test/1", status.ID)
+		csl, err := s.GetCSL("localhost:8080/status/test/1")
 		require.NoError(t, err)
 		require.Equal(t, len(csl.VC), 0)
 
-		status, err = s.CreateStatusID()
+		status, err = s.CreateStatusID(getTestProfile(), "")
 		require.NoError(t, err)
 		require.Equal(t, CredentialStatusType, status.Type)
-		require.Equal(t, "localhost:8080/status/1", status.ID)
-		csl, err = s.GetCSL("localhost:8080/status/1")
+		require.Equal(t, "localhost:8080/status/test/1", status.ID)
+		csl, err = s.GetCSL("localhost:8080/status/test/1")
 		require.NoError(t, err)
 		require.Equal(t, len(csl.VC), 0)
 
-		status, err = s.CreateStatusID()
+		status, err = s.CreateStatusID(getTestProfile(), "")
 		require.NoError(t, err)
 		require.Equal(t, CredentialStatusType, status.Type)
-		require.Equal(t, "localhost:8080/status/2", status.ID)
-		csl, err = s.GetCSL("localhost:8080/status/2")
+		require.Equal(t, "localhost:8080/status/test/2", status.ID)
+		csl, err = s.GetCSL("localhost:8080/status/test/2")
 		require.NoError(t, err)
 		require.Equal(t, len(csl.VC), 0)
+
+		// the lists are scoped per profile
+		otherProfile := getTestProfile()
+		otherProfile.Name = "other profile"
+		otherProfile.DID = "did:test:other"
+
+		status, err = s.CreateStatusID(otherProfile, "")
+		require.NoError(t, err)
+		require.Equal(t, "localhost:8080/status/other%20profile/1", status.ID)
+
+		signedCSLBytes, err := s.GetSignedCSL(status.ID)
+		require.NoError(t, err)
+
+		var signedCSL map[string]interface{}
+		require.NoError(t, json.Unmarshal(signedCSLBytes, &signedCSL))
+		require.Equal(t, otherProfile.DID, signedCSL["issuer"])
 	})
 
 	t.Run("test error from get latest id from store", func(t *testing.T) {
@@ -101,10 +133,10 @@ func TestCredentialStatusList_CreateStatusID(t *testing.T) {
 			return nil, fmt.Errorf("get error")
 		},
 		}}, "localhost:8080/status", 1,
-			vccrypto.New(&mockkms.KeyManager{}, &cryptomock.Crypto{}, &vdrimock.MockVDRIRegistry{}))
+			&mockCrypto{})
 		require.NoError(t, err)
 
-		status, err := s.CreateStatusID()
+		status, err := s.CreateStatusID(getTestProfile(), "")
 		require.Error(t, err)
 		require.Nil(t, status)
 		require.Contains(t, err.Error(), "failed to get latestListID from store")
@@ -118,10 +150,10 @@ func TestCredentialStatusList_CreateStatusID(t *testing.T) {
 				return fmt.Errorf("put error")
 			},
 		}}, "localhost:8080/status", 1,
-			vccrypto.New(&mockkms.KeyManager{}, &cryptomock.Crypto{}, &vdrimock.MockVDRIRegistry{}))
+			&mockCrypto{})
 		require.NoError(t, err)
 
-		status, err := s.CreateStatusID()
+		status, err := s.CreateStatusID(getTestProfile(), "")
 		require.Error(t, err)
 		require.Nil(t, status)
 		require.Contains(t, err.Error(), "failed to store latest list ID in store")
@@ -132,16 +164,16 @@ func TestCredentialStatusList_CreateStatusID(t *testing.T) {
 			return nil, storage.ErrValueNotFound
 		},
 			putFunc: func(k string, v []byte) error {
-				if k == "localhost:8080/status/1" {
+				if k == "localhost:8080/status/test/1" {
 					return fmt.Errorf("put error")
 				}
 				return nil
 			},
 		}}, "localhost:8080/status", 1,
-			vccrypto.New(&mockkms.KeyManager{}, &cryptomock.Crypto{}, &vdrimock.MockVDRIRegistry{}))
+			&mockCrypto{})
 		require.NoError(t, err)
 
-		status, err := s.CreateStatusID()
+		status, err := s.CreateStatusID(getTestProfile(), "")
 		require.Error(t, err)
 		require.Nil(t, status)
 		require.Contains(t, err.Error(), "failed to store csl in store")
@@ -152,28 +184,270 @@ func TestCredentialStatusList_CreateStatusID(t *testing.T) {
 			return nil, storage.ErrValueNotFound
 		},
 			putFunc: func(k string, v []byte) error {
-				if k == latestListID && string(v) == "2" {
+				if k == latestListIDKey("test") && strings.Contains(string(v), `"last":2`) {
 					return fmt.Errorf("put error")
 				}
 				return nil
 			},
 		}}, "localhost:8080/status", 1,
-			vccrypto.New(&mockkms.KeyManager{}, &cryptomock.Crypto{}, &vdrimock.MockVDRIRegistry{}))
+			&mockCrypto{})
 		require.NoError(t, err)
 
-		status, err := s.CreateStatusID()
+		status, err := s.CreateStatusID(getTestProfile(), "")
 		require.Error(t, err)
 		require.Nil(t, status)
 		require.Contains(t, err.Error(), "failed to store latest list ID in store")
 	})
 }
 
+func TestCredentialStatusList_Shards(t *testing.T) {
+	t.Run("test status IDs created in the lists of the shards in turn", func(t *testing.T) {
+		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 2, &mockCrypto{}, WithShards(2))
+		require.NoError(t, err)
+
+		var ids []string
+
+		for i := 0; i < 6; i++ {
+			status, err := s.CreateStatusID(getTestProfile(), fmt.Sprintf("http://example.edu/credentials/%d", i))
+			require.NoError(t, err)
+
+			ids = append(ids, status.ID)
+		}
+
+		require.Equal(t, []string{
+			"localhost:8080/status/test/1", "localhost:8080/status/test/2",
+			"localhost:8080/status/test/1", "localhost:8080/status/test/2",
+			"localhost:8080/status/test/3", "localhost:8080/status/test/4",
+		}, ids)
+
+		entry, err := s.GetStatusEntry("http://example.edu/credentials/3")
+		require.NoError(t, err)
+		require.Equal(t, &StatusEntry{ListID: "localhost:8080/status/test/2", Position: 1}, entry)
+
+		_, err = s.GetStatusEntry("http://example.edu/credentials/10")
+		require.True(t, errors.Is(err, storage.ErrValueNotFound))
+
+		utilization, err := s.Utilization("test")
+		require.NoError(t, err)
+		require.Equal(t, &Utilization{Profile: "test", Lists: []*ListUtilization{
+			{ID: "localhost:8080/status/test/1", Used: 2, Capacity: 2},
+			{ID: "localhost:8080/status/test/2", Used: 2, Capacity: 2},
+			{ID: "localhost:8080/status/test/3", Used: 1, Capacity: 2, Active: true},
+			{ID: "localhost:8080/status/test/4", Used: 1, Capacity: 2, Active: true},
+		}, Used: 6, Available: 2}, utilization)
+	})
+
+	t.Run("test latest list of a profile created before the lists were sharded", func(t *testing.T) {
+		provider := mockstore.NewMockStoreProvider()
+
+		s, err := New(provider, "localhost:8080/status", 10, &mockCrypto{})
+		require.NoError(t, err)
+
+		require.NoError(t, provider.Store.Put(latestListIDKey("test"), []byte("3")))
+		require.NoError(t, storeTestCSL(s, &cslWrapper{CSL: &CSL{ID: "localhost:8080/status/test/3"}, Size: 4,
+			ID: "3", Profile: "test"}))
+
+		s, err = New(provider, "localhost:8080/status", 10, &mockCrypto{}, WithShards(2))
+		require.NoError(t, err)
+
+		status, err := s.CreateStatusID(getTestProfile(), "")
+		require.NoError(t, err)
+		require.Equal(t, "localhost:8080/status/test/3", status.ID)
+
+		status, err = s.CreateStatusID(getTestProfile(), "")
+		require.NoError(t, err)
+		require.Equal(t, "localhost:8080/status/test/4", status.ID)
+
+		utilization, err := s.Utilization("test")
+		require.NoError(t, err)
+		require.Len(t, utilization.Lists, 4)
+		require.Equal(t, 6, utilization.Used)
+		require.Equal(t, 14, utilization.Available)
+
+		// the lists of the shards removed are no longer active
+		s, err = New(provider, "localhost:8080/status", 10, &mockCrypto{})
+		require.NoError(t, err)
+
+		utilization, err = s.Utilization("test")
+		require.NoError(t, err)
+		require.True(t, utilization.Lists[2].Active)
+		require.False(t, utilization.Lists[3].Active)
+		require.Equal(t, 5, utilization.Available)
+	})
+
+	t.Run("test profile without status list", func(t *testing.T) {
+		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 10, &mockCrypto{})
+		require.NoError(t, err)
+
+		utilization, err := s.Utilization("test")
+		require.NoError(t, err)
+		require.Equal(t, 1, len(utilization.Lists))
+		require.Equal(t, 10, utilization.Available)
+	})
+
+	t.Run("test errors", func(t *testing.T) {
+		provider := mockstore.NewMockStoreProvider()
+
+		s, err := New(provider, "localhost:8080/status", 10, &mockCrypto{})
+		require.NoError(t, err)
+
+		require.NoError(t, provider.Store.Put(latestListIDKey("test"), []byte("{")))
+
+		_, err = s.Utilization("test")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal status lists of profile test")
+
+		require.NoError(t, provider.Store.Put(statusIndexKey("http://example.edu/credentials/1"), []byte("{")))
+
+		_, err = s.GetStatusEntry("http://example.edu/credentials/1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal status entry")
+
+		s, err = New(&storeProvider{store: &mockStore{getFunc: func(k string) ([]byte, error) {
+			return nil, storage.ErrValueNotFound
+		}, putFunc: func(k string, v []byte) error {
+			if strings.HasPrefix(k, statusIndex) {
+				return errors.New("put error")
+			}
+
+			return nil
+		}}}, "localhost:8080/status", 10, &mockCrypto{})
+		require.NoError(t, err)
+
+		_, err = s.CreateStatusID(getTestProfile(), "http://example.edu/credentials/1")
+		require.EqualError(t, err, "failed to store status entry in store: put error")
+	})
+}
+
+func TestCredentialStatusList_GetSignedCSL(t *testing.T) {
+	t.Run("test list signed on creation", func(t *testing.T) {
+		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 2, &mockCrypto{})
+		require.NoError(t, err)
+
+		status, err := s.CreateStatusID(getTestProfile(), "")
+		require.NoError(t, err)
+
+		signedCSLBytes, err := s.GetSignedCSL(status.ID)
+		require.NoError(t, err)
+
+		var signedCSL map[string]interface{}
+		require.NoError(t, json.Unmarshal(signedCSLBytes, &signedCSL))
+		require.Equal(t, []interface{}{"VerifiableCredential", CredentialStatusType}, signedCSL["type"])
+		require.Equal(t, getTestProfile().DID, signedCSL["issuer"])
+		require.NotEmpty(t, signedCSL["proof"])
+
+		// the verifiers read the list from the fields of the status list credential
+		var csl CSL
+		require.NoError(t, json.Unmarshal(signedCSLBytes, &csl))
+		require.Equal(t, status.ID, csl.ID)
+		require.Empty(t, csl.VC)
+	})
+
+	t.Run("test list stored unsigned", func(t *testing.T) {
+		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 2, &mockCrypto{})
+		require.NoError(t, err)
+
+		require.NoError(t, storeTestCSL(s, &cslWrapper{CSL: &CSL{ID: "localhost:8080/status/1", VC: []string{"vc"}},
+			ID: "1"}))
+
+		cslBytes, err := s.GetSignedCSL("localhost:8080/status/1")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"id":"localhost:8080/status/1","description":"","verifiableCredential":["vc"]}`,
+			string(cslBytes))
+	})
+
+	t.Run("test cached list", func(t *testing.T) {
+		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 2, &mockCrypto{},
+			WithCache(memcache.New(10, time.Minute)))
+		require.NoError(t, err)
+
+		status, err := s.CreateStatusID(getTestProfile(), "")
+		require.NoError(t, err)
+
+		signedCSLBytes, err := s.GetSignedCSL(status.ID)
+		require.NoError(t, err)
+
+		w, err := s.getCSLWrapper(status.ID)
+		require.NoError(t, err)
+
+		w.CSL, w.SignedCSL = &CSL{ID: status.ID}, nil
+
+		// the cached list is invalidated on update
+		require.NoError(t, s.putCSL(w))
+
+		cslBytes, err := s.GetSignedCSL(status.ID)
+		require.NoError(t, err)
+		require.NotEqual(t, signedCSLBytes, cslBytes)
+	})
+
+	t.Run("test status updates read the list from the store", func(t *testing.T) {
+		provider := mockstore.NewMockStoreProvider()
+
+		s, err := New(provider, "localhost:8080/status", 2, &mockCrypto{},
+			WithCache(memcache.New(10, time.Minute)))
+		require.NoError(t, err)
+
+		// another instance sharing the store
+		other, err := New(provider, "localhost:8080/status", 2, &mockCrypto{})
+		require.NoError(t, err)
+
+		status, err := s.CreateStatusID(getTestProfile(), "")
+		require.NoError(t, err)
+
+		signedCSLBytes, err := s.GetSignedCSL(status.ID)
+		require.NoError(t, err)
+
+		cred, err := verifiable.ParseCredential([]byte(universityDegreeCred))
+		require.NoError(t, err)
+		cred.Status = status
+
+		require.NoError(t, other.RevokeVC(cred, getTestProfile(), "Disciplinary action"))
+
+		// the served list is cached
+		cslBytes, err := s.GetSignedCSL(status.ID)
+		require.NoError(t, err)
+		require.Equal(t, signedCSLBytes, cslBytes)
+
+		// the revocation of the other instance isn't lost
+		status, err = s.CreateStatusID(getTestProfile(), "")
+		require.NoError(t, err)
+
+		csl, err := s.GetCSL(status.ID)
+		require.NoError(t, err)
+		require.Len(t, csl.VC, 1)
+
+		err = s.RevokeVC(cred, getTestProfile(), "")
+		require.True(t, errors.Is(err, ErrInvalidStatusTransition))
+	})
+
+	t.Run("test error from sign list", func(t *testing.T) {
+		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 2,
+			&mockCrypto{signErr: fmt.Errorf("sign error")})
+		require.NoError(t, err)
+
+		status, err := s.CreateStatusID(getTestProfile(), "")
+		require.EqualError(t, err, "failed to sign csl: sign error")
+		require.Nil(t, status)
+	})
+
+	t.Run("test error getting csl from store", func(t *testing.T) {
+		s, err := New(&storeProvider{store: &mockStore{getFunc: func(k string) (bytes []byte, err error) {
+			return nil, fmt.Errorf("get error")
+		}}}, "localhost:8080/status", 2, &mockCrypto{})
+		require.NoError(t, err)
+
+		cslBytes, err := s.GetSignedCSL("1")
+		require.Error(t, err)
+		require.Nil(t, cslBytes)
+	})
+}
+
 func TestCredentialStatusList_GetCSL(t *testing.T) {
 	t.Run("test error getting csl from store", func(t *testing.T) {
 		s, err := New(&storeProvider{store: &mockStore{getFunc: func(k string) (bytes []byte, err error) {
 			return nil, fmt.Errorf("get error")
 		}}}, "localhost:8080/status", 2,
-			vccrypto.New(&mockkms.KeyManager{}, &cryptomock.Crypto{}, &vdrimock.MockVDRIRegistry{}))
+			&mockCrypto{})
 		require.NoError(t, err)
 		csl, err := s.GetCSL("1")
 		require.Error(t, err)
@@ -189,10 +463,10 @@ func TestCredentialStatusList_UpdateVCStatus(t *testing.T) {
 				&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:test:abc")}))
 		require.NoError(t, err)
 
-		status, err := s.CreateStatusID()
+		status, err := s.CreateStatusID(getTestProfile(), "")
 		require.NoError(t, err)
 
-		statusValue := []string{"Revoked", "Revoked1"}
+		statusValue := []string{"Suspended", "Revoked"}
 
 		cred, err := verifiable.ParseCredential([]byte(universityDegreeCred))
 		require.NoError(t, err)
@@ -209,6 +483,11 @@ func TestCredentialStatusList_UpdateVCStatus(t *testing.T) {
 			require.Contains(t, csl.VC[0], v)
 			require.Contains(t, csl.VC[0], "Disciplinary action")
 		}
+
+		// the status of a revoked credential can't be changed
+		err = s.UpdateVCStatus(cred, getTestProfile(), "Suspended", "")
+		require.True(t, errors.Is(err, ErrInvalidStatusTransition))
+		require.Contains(t, err.Error(), "cannot change the status of revoked credential")
 	})
 
 	t.Run("test error get csl from store", func(t *testing.T) {
@@ -227,12 +506,10 @@ func TestCredentialStatusList_UpdateVCStatus(t *testing.T) {
 	})
 
 	t.Run("test error from creating new status credential", func(t *testing.T) {
-		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 2,
-			vccrypto.New(&mockkms.KeyManager{}, &cryptomock.Crypto{},
-				&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:test:abc")}))
+		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 2, &mockCrypto{})
 		require.NoError(t, err)
 
-		status, err := s.CreateStatusID()
+		status, err := s.CreateStatusID(getTestProfile(), "")
 		require.NoError(t, err)
 
 		err = s.UpdateVCStatus(&verifiable.Credential{ID: "1872",
@@ -242,14 +519,56 @@ func TestCredentialStatusList_UpdateVCStatus(t *testing.T) {
 		require.Contains(t, err.Error(), "failed to parse credential")
 	})
 
+	t.Run("test list of another profile", func(t *testing.T) {
+		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 2, &mockCrypto{})
+		require.NoError(t, err)
+
+		status, err := s.CreateStatusID(getTestProfile(), "")
+		require.NoError(t, err)
+
+		cred, err := verifiable.ParseCredential([]byte(universityDegreeCred))
+		require.NoError(t, err)
+		cred.Status = status
+
+		otherProfile := getTestProfile()
+		otherProfile.Name = "other"
+
+		err = s.UpdateVCStatus(cred, otherProfile, "Revoked", "Disciplinary action")
+		require.EqualError(t, err, "csl localhost:8080/status/test/1 belongs to profile test, not other")
+	})
+
+	t.Run("test list shared by all the profiles", func(t *testing.T) {
+		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 2, &mockCrypto{})
+		require.NoError(t, err)
+
+		status := &verifiable.TypedID{ID: "localhost:8080/status/1", Type: CredentialStatusType}
+		require.NoError(t, storeTestCSL(s, &cslWrapper{CSL: &CSL{ID: status.ID}, ID: "1",
+			SignedCSL: json.RawMessage(`{"id":"localhost:8080/status/1"}`)}))
+
+		cred, err := verifiable.ParseCredential([]byte(universityDegreeCred))
+		require.NoError(t, err)
+		cred.Status = status
+
+		require.NoError(t, s.UpdateVCStatus(cred, getTestProfile(), "Revoked", "Disciplinary action"))
+
+		// the list is served unsigned
+		cslBytes, err := s.GetSignedCSL(status.ID)
+		require.NoError(t, err)
+
+		var csl map[string]interface{}
+		require.NoError(t, json.Unmarshal(cslBytes, &csl))
+		require.Empty(t, csl["proof"])
+		require.Len(t, csl["verifiableCredential"], 1)
+	})
+
 	t.Run("test error from sign status credential", func(t *testing.T) {
 		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 2,
 			vccrypto.New(&mockkms.KeyManager{}, &cryptomock.Crypto{SignErr: fmt.Errorf("failed to sign")},
 				&vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:test:abc")}))
 		require.NoError(t, err)
 
-		status, err := s.CreateStatusID()
-		require.NoError(t, err)
+		status := &verifiable.TypedID{ID: "localhost:8080/status/1", Type: CredentialStatusType}
+		require.NoError(t, storeTestCSL(s, &cslWrapper{CSL: &CSL{ID: status.ID}, ID: "1"}))
 
 		cred, err := verifiable.ParseCredential([]byte(universityDegreeCred))
 		require.NoError(t, err)
@@ -263,6 +582,330 @@ func TestCredentialStatusList_UpdateVCStatus(t *testing.T) {
 	})
 }
 
+func TestCredentialStatusList_StatusTransitions(t *testing.T) {
+	s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 2,
+		&mockCrypto{})
+	require.NoError(t, err)
+
+	status, err := s.CreateStatusID(getTestProfile(), "")
+	require.NoError(t, err)
+
+	require.NoError(t, storeTestCSL(s, &cslWrapper{CSL: &CSL{ID: status.ID, VC: []string{
+		`{"id":"http://example.edu/credentials/1","credentialSubject":{"currentStatus":"Suspended"}}`,
+		`{"id":"http://example.edu/credentials/2","credentialSubject":{"currentStatus":"Revoked"}}`,
+	}}, Size: 3, ID: "1", Profile: getTestProfile().Name}))
+
+	suspendedVC := &verifiable.Credential{ID: "http://example.edu/credentials/1", Status: status}
+	revokedVC := &verifiable.Credential{ID: "http://example.edu/credentials/2", Status: status}
+	activeVC := &verifiable.Credential{ID: "http://example.edu/credentials/3", Status: status}
+
+	t.Run("test get vc status", func(t *testing.T) {
+		vcStatus, err := s.GetVCStatus(suspendedVC)
+		require.NoError(t, err)
+		require.Equal(t, StatusSuspended, vcStatus.CurrentStatus)
+
+		vcStatus, err = s.GetVCStatus(activeVC)
+		require.NoError(t, err)
+		require.Nil(t, vcStatus)
+
+		_, err = s.GetVCStatus(&verifiable.Credential{ID: "http://example.edu/credentials/4"})
+		require.EqualError(t, err, "credential http://example.edu/credentials/4 has no status")
+	})
+
+	t.Run("test invalid transitions", func(t *testing.T) {
+		err := s.SuspendVC(suspendedVC, getTestProfile(), "")
+		require.True(t, errors.Is(err, ErrInvalidStatusTransition))
+		require.Contains(t, err.Error(), "credential http://example.edu/credentials/1 is suspended")
+
+		err = s.SuspendVC(revokedVC, getTestProfile(), "")
+		require.True(t, errors.Is(err, ErrInvalidStatusTransition))
+		require.Contains(t, err.Error(), "credential http://example.edu/credentials/2 is revoked")
+
+		err = s.RevokeVC(revokedVC, getTestProfile(), "")
+		require.True(t, errors.Is(err, ErrInvalidStatusTransition))
+		require.Contains(t, err.Error(), "is already revoked")
+
+		err = s.ReinstateVC(revokedVC, getTestProfile())
+		require.True(t, errors.Is(err, ErrInvalidStatusTransition))
+		require.Contains(t, err.Error(), "cannot reinstate revoked credential")
+
+		err = s.ReinstateVC(activeVC, getTestProfile())
+		require.True(t, errors.Is(err, ErrInvalidStatusTransition))
+		require.Contains(t, err.Error(), "is not suspended")
+
+		err = s.ReinstateVC(&verifiable.Credential{ID: "http://example.edu/credentials/4"}, getTestProfile())
+		require.EqualError(t, err, "credential http://example.edu/credentials/4 has no status")
+	})
+
+	t.Run("test reinstate suspended vc", func(t *testing.T) {
+		require.NoError(t, s.ReinstateVC(suspendedVC, getTestProfile()))
+
+		vcStatus, err := s.GetVCStatus(suspendedVC)
+		require.NoError(t, err)
+		require.Nil(t, vcStatus)
+
+		csl, err := s.GetCSL(status.ID)
+		require.NoError(t, err)
+		require.Len(t, csl.VC, 1)
+		require.Contains(t, csl.VC[0], revokedVC.ID)
+
+		// the list is re-signed without the status credential of the reinstated credential
+		signedCSLBytes, err := s.GetSignedCSL(status.ID)
+		require.NoError(t, err)
+
+		var signedCSL CSL
+		require.NoError(t, json.Unmarshal(signedCSLBytes, &signedCSL))
+		require.Equal(t, csl.VC, signedCSL.VC)
+	})
+
+	t.Run("test status credentials matched on the exact credential ID", func(t *testing.T) {
+		require.NoError(t, storeTestCSL(s, &cslWrapper{CSL: &CSL{ID: status.ID, VC: []string{
+			`{"id":"http://example.edu/credentials/10","credentialSubject":{"currentStatus":"Revoked"}}`,
+			`{"id":"http://example.edu/credentials/1","credentialSubject":{"currentStatus":"Suspended"}}`,
+		}}, Size: 3, ID: "1", Profile: getTestProfile().Name}))
+
+		require.NoError(t, s.ReinstateVC(suspendedVC, getTestProfile()))
+
+		csl, err := s.GetCSL(status.ID)
+		require.NoError(t, err)
+		require.Len(t, csl.VC, 1)
+		require.Contains(t, csl.VC[0], "http://example.edu/credentials/10")
+		require.Contains(t, csl.VC[0], StatusRevoked)
+	})
+
+	t.Run("test error invalid status credential", func(t *testing.T) {
+		require.NoError(t, storeTestCSL(s, &cslWrapper{CSL: &CSL{ID: status.ID, VC: []string{"invalid"}}, ID: "1"}))
+
+		_, err := s.GetVCStatus(activeVC)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal status credential")
+
+		require.Error(t, s.RevokeVC(activeVC, getTestProfile(), ""))
+		require.Error(t, s.SuspendVC(activeVC, getTestProfile(), ""))
+		require.Error(t, s.ReinstateVC(activeVC, getTestProfile()))
+	})
+}
+
+func TestCredentialStatusList_ConcurrentUpdates(t *testing.T) {
+	credential := func(t *testing.T, i int, status *verifiable.TypedID) *verifiable.Credential {
+		t.Helper()
+
+		vc, err := verifiable.ParseCredential([]byte(fmt.Sprintf(statusTestCred, i)))
+		require.NoError(t, err)
+
+		vc.Status = status
+
+		return vc
+	}
+
+	t.Run("test concurrent revocations", func(t *testing.T) {
+		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 100, &mockCrypto{})
+		require.NoError(t, err)
+
+		status, err := s.CreateStatusID(getTestProfile(), "")
+		require.NoError(t, err)
+
+		const count = 20
+
+		vcs := make([]*verifiable.Credential, count)
+		for i := range vcs {
+			vcs[i] = credential(t, i, status)
+		}
+
+		errs := make(chan error, count)
+
+		var wg sync.WaitGroup
+
+		for _, vc := range vcs {
+			wg.Add(1)
+
+			go func(vc *verifiable.Credential) {
+				defer wg.Done()
+
+				errs <- s.RevokeVC(vc, getTestProfile(), "")
+			}(vc)
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		csl, err := s.GetCSL(status.ID)
+		require.NoError(t, err)
+		require.Len(t, csl.VC, count)
+
+		for i := 0; i < count; i++ {
+			vcStatus, err := s.GetVCStatus(credential(t, i, status))
+			require.NoError(t, err)
+			require.Equal(t, StatusRevoked, vcStatus.CurrentStatus)
+		}
+	})
+
+	t.Run("test concurrent status IDs", func(t *testing.T) {
+		s, err := New(mockstore.NewMockStoreProvider(), "localhost:8080/status", 5, &mockCrypto{})
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				_, err := s.CreateStatusID(getTestProfile(), "")
+				require.NoError(t, err)
+			}()
+		}
+
+		wg.Wait()
+
+		for i := 1; i <= 4; i++ {
+			w, err := s.getCSLWrapper(fmt.Sprintf("localhost:8080/status/test/%d", i))
+			require.NoError(t, err)
+			require.Equal(t, 5, w.Size)
+		}
+	})
+
+	t.Run("test csl updated by another instance", func(t *testing.T) {
+		provider := mockstore.NewMockStoreProvider()
+
+		s, err := New(provider, "localhost:8080/status", 100, &mockCrypto{})
+		require.NoError(t, err)
+
+		other, err := New(provider, "localhost:8080/status", 100, &mockCrypto{})
+		require.NoError(t, err)
+
+		status, err := s.CreateStatusID(getTestProfile(), "")
+		require.NoError(t, err)
+
+		w, err := s.getCSLWrapper(status.ID)
+		require.NoError(t, err)
+
+		require.NoError(t, other.RevokeVC(credential(t, 1, status), getTestProfile(), ""))
+
+		// the csl read before the update of the other instance isn't stored
+		err = s.putCSL(w)
+		require.True(t, errors.Is(err, ErrConcurrentUpdate))
+
+		require.NoError(t, s.SuspendVC(credential(t, 2, status), getTestProfile(), ""))
+
+		csl, err := other.GetCSL(status.ID)
+		require.NoError(t, err)
+		require.Len(t, csl.VC, 2)
+	})
+
+	t.Run("test update applied again", func(t *testing.T) {
+		var gets int
+
+		s, err := New(&storeProvider{store: &mockStore{getFunc: func(k string) ([]byte, error) {
+			gets++
+
+			// the csl is updated by another instance between the first read and the write
+			sequence := int64(2)
+			if gets == 1 {
+				sequence = 1
+			}
+
+			return json.Marshal(&cslWrapper{CSL: &CSL{ID: k}, ID: "1", Sequence: sequence})
+		}}}, "localhost:8080/status", 100, &mockCrypto{})
+		require.NoError(t, err)
+
+		var updates int
+
+		require.NoError(t, s.updateCSL("localhost:8080/status/1", func(w *cslWrapper) error {
+			updates++
+
+			return nil
+		}))
+		require.Equal(t, 2, updates)
+	})
+
+	t.Run("test csl replaced by another instance", func(t *testing.T) {
+		p := mockstore.NewMockStoreProvider()
+		require.NoError(t, p.CreateStore(credentialStatusStore))
+
+		store, err := p.OpenStore(credentialStatusStore)
+		require.NoError(t, err)
+
+		var replaces int
+
+		s, err := New(&storeProvider{store: &replacingStore{Store: conditional.New(store),
+			replaceFunc: func(k string, old, v []byte) error {
+				replaces++
+
+				// the csl is replaced by another instance between the read and the write
+				if replaces == 1 {
+					return conditional.ErrConflict
+				}
+
+				return conditional.New(store).Replace(k, old, v)
+			}}}, "localhost:8080/status", 100, &mockCrypto{})
+		require.NoError(t, err)
+
+		status, err := s.CreateStatusID(getTestProfile(), "")
+		require.NoError(t, err)
+
+		var updates int
+
+		require.NoError(t, s.updateCSL(status.ID, func(w *cslWrapper) error {
+			updates++
+
+			return nil
+		}))
+		require.Equal(t, 2, updates)
+
+		w, err := s.getCSLWrapper(status.ID)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), w.Sequence)
+	})
+
+	t.Run("test csl replace error", func(t *testing.T) {
+		p := mockstore.NewMockStoreProvider()
+		require.NoError(t, p.CreateStore(credentialStatusStore))
+
+		store, err := p.OpenStore(credentialStatusStore)
+		require.NoError(t, err)
+
+		s, err := New(&storeProvider{store: &replacingStore{Store: conditional.New(store),
+			replaceFunc: func(k string, old, v []byte) error {
+				return errors.New("replace error")
+			}}}, "localhost:8080/status", 100, &mockCrypto{})
+		require.NoError(t, err)
+
+		status, err := s.CreateStatusID(getTestProfile(), "")
+		require.NoError(t, err)
+
+		err = s.RevokeVC(credential(t, 1, status), getTestProfile(), "")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store csl in store: replace error")
+	})
+
+	t.Run("test csl always updated by another instance", func(t *testing.T) {
+		var gets int
+
+		s, err := New(&storeProvider{store: &mockStore{getFunc: func(k string) ([]byte, error) {
+			gets++
+
+			return json.Marshal(&cslWrapper{CSL: &CSL{ID: k}, ID: "1", Sequence: int64(gets)})
+		}}}, "localhost:8080/status", 100, &mockCrypto{})
+		require.NoError(t, err)
+
+		var updates int
+
+		err = s.updateCSL("localhost:8080/status/1", func(w *cslWrapper) error {
+			updates++
+
+			return nil
+		})
+		require.True(t, errors.Is(err, ErrConcurrentUpdate))
+		require.Equal(t, maxUpdateAttempts, updates)
+	})
+}
+
 func TestPrepareSigningOpts(t *testing.T) {
 	t.Run("prepare signing opts", func(t *testing.T) {
 		profile := vcprofile.DataProfile{
@@ -382,6 +1025,21 @@ func TestPrepareSigningOpts(t *testing.T) {
 	})
 }
 
+type mockCrypto struct {
+	signErr error
+}
+
+func (m *mockCrypto) SignCredential(profile *vcprofile.DataProfile, vc *verifiable.Credential,
+	opts ...vccrypto.SigningOpts) (*verifiable.Credential, error) {
+	if m.signErr != nil {
+		return nil, m.signErr
+	}
+
+	vc.Proofs = append(vc.Proofs, verifiable.Proof{"type": profile.SignatureType, "creator": profile.Creator})
+
+	return vc, nil
+}
+
 func getTestProfile() *vcprofile.DataProfile {
 	return &vcprofile.DataProfile{
 		Name:          "test",
@@ -394,7 +1052,7 @@ func getTestProfile() *vcprofile.DataProfile {
 
 // storeProvider mock store provider.
 type storeProvider struct {
-	store *mockStore
+	store storage.Store
 }
 
 func (p *storeProvider) CreateStore(name string) error {
@@ -494,3 +1152,24 @@ func createDIDDoc(didID string) *did.Doc {
 		CapabilityDelegation: []did.VerificationMethod{{PublicKey: signingKey}},
 	}
 }
+
+// replacingStore is a store with conditional writes whose replacements are mocked
+type replacingStore struct {
+	conditional.Store
+	replaceFunc func(k string, old, v []byte) error
+}
+
+// Replace replaces the value of the key if it is still the old value
+func (s *replacingStore) Replace(k string, old, v []byte) error {
+	return s.replaceFunc(k, old, v)
+}
+
+// storeTestCSL stores the csl as is
+func storeTestCSL(s *CredentialStatusManager, w *cslWrapper) error {
+	wBytes, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Put(w.CSL.ID, wBytes)
+}
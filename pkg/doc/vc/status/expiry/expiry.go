@@ -0,0 +1,375 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package expiry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/history"
+)
+
+const (
+	issuanceLogStore = "issuancelog"
+	lastScannedKey   = "lastScannedDay"
+	bucketKeyPrefix  = "expiry_"
+	entryKeySep      = "/"
+	dayLayout        = "2006-01-02"
+
+	// StatusReasonExpired is the status reason of the credentials revoked on expiry
+	StatusReasonExpired = "Expired"
+	// Actor is the actor of the revocations on expiry in the status history of the credentials
+	Actor = "expiry-scheduler"
+)
+
+var logger = log.New("edge-service-vc-expiry")
+
+type vcStatusManager interface {
+	RevokeVC(v *verifiable.Credential, profile *vcprofile.DataProfile, statusReason string) error
+}
+
+type profileStore interface {
+	GetProfile(name string) (*vcprofile.DataProfile, error)
+}
+
+type statusHistory interface {
+	Record(profile, vcID string, change *history.StatusChange) error
+}
+
+// Option configures the scheduler
+type Option func(s *Scheduler)
+
+// WithStatusHistory records the revocations in the status history of the credentials
+func WithStatusHistory(h statusHistory) Option {
+	return func(s *Scheduler) {
+		s.history = h
+	}
+}
+
+// Scheduler logs the issued credentials expiring under profiles opted in to revocation on expiry, and revokes
+// them in their status list once their expiration date has passed.
+//
+// The log is bucketed by expiration day, a scan revokes the credentials of the days since the last scanned day.
+// Each logged credential is stored under its own key, numbered in the bucket of its day, and is replaced by an empty
+// value once revoked. A credential failing revocation stays logged and is retried by the next scans, the last scanned
+// day doesn't pass its day until then.
+type Scheduler struct {
+	store         storage.Store
+	statusManager vcStatusManager
+	profiles      profileStore
+	history       statusHistory
+	now           func() time.Time
+
+	mutex sync.Mutex
+	stop  chan struct{}
+}
+
+// entry is a logged credential
+type entry struct {
+	Profile    string          `json:"profile"`
+	Credential json.RawMessage `json:"credential"`
+}
+
+// New returns a new expiry scheduler
+func New(provider storage.Provider, statusManager vcStatusManager, profiles profileStore,
+	opts ...Option) (*Scheduler, error) {
+	err := provider.CreateStore(issuanceLogStore)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := provider.OpenStore(issuanceLogStore)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Scheduler{store: store, statusManager: statusManager, profiles: profiles, now: time.Now}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// Record logs the issued credential for revocation on expiry, credentials without an expiration date or a status
+// are ignored
+func (s *Scheduler) Record(vc *verifiable.Credential, profile string) error {
+	if vc.Expired == nil || vc.Status == nil {
+		return nil
+	}
+
+	vcBytes, err := vc.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal credential: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now().UTC()
+
+	lastScanned, err := s.lastScannedDay()
+	if err != nil {
+		return err
+	}
+
+	if lastScanned.IsZero() {
+		lastScanned = truncateDay(now).AddDate(0, 0, -1)
+
+		if err := s.store.Put(lastScannedKey, []byte(lastScanned.Format(dayLayout))); err != nil {
+			return fmt.Errorf("failed to store last scanned day: %w", err)
+		}
+	}
+
+	// credentials already expired are revoked with the ones expiring today
+	day := truncateDay(vc.Expired.Time.UTC())
+	if day.Before(truncateDay(now)) {
+		day = truncateDay(now)
+	}
+
+	return s.addEntry(day, &entry{Profile: profile, Credential: vcBytes})
+}
+
+// RevokeExpired revokes the logged credentials expired at the current time
+func (s *Scheduler) RevokeExpired() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now().UTC()
+	today := truncateDay(now)
+
+	lastScanned, err := s.lastScannedDay()
+	if err != nil || lastScanned.IsZero() {
+		return err
+	}
+
+	// the days are scanned again until all their credentials are revoked, today until it has passed
+	advance := true
+
+	for day := lastScanned.AddDate(0, 0, 1); !day.After(today); day = day.AddDate(0, 0, 1) {
+		pending, err := s.revokeBucket(day, now)
+		if err != nil {
+			return err
+		}
+
+		advance = advance && !pending && day.Before(today)
+
+		if advance {
+			if err := s.store.Put(lastScannedKey, []byte(day.Format(dayLayout))); err != nil {
+				return fmt.Errorf("failed to store last scanned day: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Start revokes the expired credentials at the given interval until the scheduler is stopped
+func (s *Scheduler) Start(interval time.Duration) {
+	stop := make(chan struct{})
+	s.stop = stop
+
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.RevokeExpired(); err != nil {
+					logger.Errorf("failed to revoke expired credentials: %s", err.Error())
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the scheduler started with Start
+func (s *Scheduler) Stop() {
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// revokeBucket revokes the credentials of the day expired at now, and returns if credentials of the day are still
+// logged: not expired yet or failed to be revoked
+func (s *Scheduler) revokeBucket(day, now time.Time) (bool, error) {
+	count, err := s.getEntryCount(day)
+	if err != nil {
+		return false, err
+	}
+
+	pending := false
+
+	for i := 0; i < count; i++ {
+		e, errGet := s.getEntry(day, i)
+		if errGet != nil {
+			return false, errGet
+		}
+
+		if e == nil {
+			// already revoked
+			continue
+		}
+
+		vc, errParse := verifiable.ParseCredential(e.Credential, verifiable.WithDisabledProofCheck())
+		if errParse != nil {
+			// the credential can't be revoked in any later scan
+			logger.Errorf("dropping logged credential of profile %s: failed to parse: %s", e.Profile, errParse.Error())
+		} else if vc.Expired.Time.After(now) {
+			pending = true
+
+			continue
+		} else if errRevoke := s.revoke(vc, e.Profile); errRevoke != nil {
+			logger.Errorf("keeping logged credential %s for the next scan: %s", vc.ID, errRevoke.Error())
+
+			pending = true
+
+			continue
+		}
+
+		if err := s.store.Put(entryKey(day, i), []byte{}); err != nil {
+			return false, fmt.Errorf("failed to remove revoked credential from issuance log: %w", err)
+		}
+	}
+
+	return pending, nil
+}
+
+func (s *Scheduler) revoke(vc *verifiable.Credential, profileName string) error {
+	profile, err := s.profiles.GetProfile(profileName)
+	if err != nil {
+		return fmt.Errorf("failed to get profile %s: %w", profileName, err)
+	}
+
+	err = s.statusManager.RevokeVC(vc, profile, StatusReasonExpired)
+	if err != nil {
+		if errors.Is(err, cslstatus.ErrInvalidStatusTransition) {
+			// a credential already revoked stays revoked
+			return nil
+		}
+
+		return fmt.Errorf("failed to revoke credential: %w", err)
+	}
+
+	if s.history != nil {
+		err = s.history.Record(profileName, vc.ID, &history.StatusChange{
+			Status: cslstatus.StatusRevoked, StatusReason: StatusReasonExpired, Actor: Actor, Time: s.now().UTC(),
+		})
+		if err != nil {
+			logger.Errorf("failed to record revocation of credential %s in status history: %s", vc.ID, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (s *Scheduler) lastScannedDay() (time.Time, error) {
+	dayBytes, err := s.store.Get(lastScannedKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrValueNotFound) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, fmt.Errorf("failed to get last scanned day: %w", err)
+	}
+
+	day, err := time.Parse(dayLayout, string(dayBytes))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid last scanned day: %w", err)
+	}
+
+	return day, nil
+}
+
+func (s *Scheduler) getEntryCount(day time.Time) (int, error) {
+	countBytes, err := s.store.Get(bucketKey(day))
+	if err != nil {
+		if errors.Is(err, storage.ErrValueNotFound) {
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("failed to get issuance log: %w", err)
+	}
+
+	count, err := strconv.Atoi(string(countBytes))
+	if err != nil {
+		return 0, fmt.Errorf("invalid issuance log: %w", err)
+	}
+
+	return count, nil
+}
+
+// getEntry returns the i-th credential logged for the day, nil once revoked
+func (s *Scheduler) getEntry(day time.Time, i int) (*entry, error) {
+	entryBytes, err := s.store.Get(entryKey(day, i))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issuance log entry: %w", err)
+	}
+
+	if len(entryBytes) == 0 {
+		return nil, nil
+	}
+
+	e := &entry{}
+	if err := json.Unmarshal(entryBytes, e); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal issuance log entry: %w", err)
+	}
+
+	return e, nil
+}
+
+// addEntry stores the entry before the incremented count of the bucket, the scheduler mutex serializes the
+// numbering of the entries
+func (s *Scheduler) addEntry(day time.Time, e *entry) error {
+	count, err := s.getEntryCount(day)
+	if err != nil {
+		return err
+	}
+
+	entryBytes, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal issuance log entry: %w", err)
+	}
+
+	if err := s.store.Put(entryKey(day, count), entryBytes); err != nil {
+		return fmt.Errorf("failed to store issuance log entry: %w", err)
+	}
+
+	if err := s.store.Put(bucketKey(day), []byte(strconv.Itoa(count+1))); err != nil {
+		return fmt.Errorf("failed to store issuance log: %w", err)
+	}
+
+	return nil
+}
+
+func bucketKey(day time.Time) string {
+	return bucketKeyPrefix + day.Format(dayLayout)
+}
+
+func entryKey(day time.Time, i int) string {
+	return bucketKey(day) + entryKeySep + strconv.Itoa(i)
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
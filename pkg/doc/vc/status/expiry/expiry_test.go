@@ -0,0 +1,306 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package expiry
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/history"
+)
+
+const profileName = "issuer"
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		s, err := New(memstore.NewProvider(), &mockStatusManager{}, &mockProfiles{})
+		require.NoError(t, err)
+		require.NotNil(t, s)
+	})
+
+	t.Run("test error from create store", func(t *testing.T) {
+		s, err := New(&mockstore.Provider{ErrCreateStore: fmt.Errorf("error create")}, nil, nil)
+		require.EqualError(t, err, "error create")
+		require.Nil(t, s)
+	})
+
+	t.Run("test error from open store", func(t *testing.T) {
+		s, err := New(&mockstore.Provider{ErrOpenStoreHandle: fmt.Errorf("error open")}, nil, nil)
+		require.EqualError(t, err, "error open")
+		require.Nil(t, s)
+	})
+}
+
+func TestScheduler_RevokeExpired(t *testing.T) {
+	day := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("test credentials revoked once expired", func(t *testing.T) {
+		statusManager := &mockStatusManager{}
+		s := newScheduler(t, statusManager)
+
+		s.now = func() time.Time { return day.Add(12 * time.Hour) }
+
+		require.NoError(t, s.Record(newVC(t, "vc1", day.Add(10*time.Hour)), profileName))
+		require.NoError(t, s.Record(newVC(t, "vc2", day.Add(18*time.Hour)), profileName))
+		require.NoError(t, s.Record(newVC(t, "vc3", day.AddDate(0, 0, 2)), profileName))
+		require.NoError(t, s.Record(newVC(t, "expired", day.AddDate(0, 0, -3)), profileName))
+
+		require.NoError(t, s.RevokeExpired())
+		require.Equal(t, []string{"vc1", "expired"}, statusManager.revoked)
+
+		s.now = func() time.Time { return day.AddDate(0, 0, 1) }
+		require.NoError(t, s.RevokeExpired())
+		require.Equal(t, []string{"vc1", "expired", "vc2"}, statusManager.revoked)
+
+		s.now = func() time.Time { return day.AddDate(0, 0, 5) }
+		require.NoError(t, s.RevokeExpired())
+		require.NoError(t, s.RevokeExpired())
+		require.Equal(t, []string{"vc1", "expired", "vc2", "vc3"}, statusManager.revoked)
+	})
+
+	t.Run("test credentials without expiration date or status are not logged", func(t *testing.T) {
+		statusManager := &mockStatusManager{}
+		s := newScheduler(t, statusManager)
+
+		vc := newVC(t, "vc1", day)
+		vc.Expired = nil
+		require.NoError(t, s.Record(vc, profileName))
+
+		vc = newVC(t, "vc2", day)
+		vc.Status = nil
+		require.NoError(t, s.Record(vc, profileName))
+
+		s.now = func() time.Time { return day.AddDate(0, 0, 1) }
+		require.NoError(t, s.RevokeExpired())
+		require.Empty(t, statusManager.revoked)
+	})
+
+	t.Run("test credentials failing revocation are kept", func(t *testing.T) {
+		statusManager := &mockStatusManager{revokeErr: map[string]error{
+			"revoked": fmt.Errorf("%w: already revoked", cslstatus.ErrInvalidStatusTransition),
+			"failed":  fmt.Errorf("error revoke"),
+		}}
+		s := newScheduler(t, statusManager)
+		s.now = func() time.Time { return day }
+
+		require.NoError(t, s.Record(newVC(t, "revoked", day), profileName))
+		require.NoError(t, s.Record(newVC(t, "failed", day), profileName))
+		require.NoError(t, s.Record(newVC(t, "noprofile", day), "unknown"))
+		require.NoError(t, s.Record(newVC(t, "vc1", day.AddDate(0, 0, 1)), profileName))
+
+		require.NoError(t, s.RevokeExpired())
+		require.Empty(t, statusManager.revoked)
+
+		// the day of the failed revocations is scanned again once passed
+		s.now = func() time.Time { return day.AddDate(0, 0, 2) }
+		require.NoError(t, s.RevokeExpired())
+		require.Equal(t, []string{"vc1"}, statusManager.revoked)
+
+		lastScanned, err := s.lastScannedDay()
+		require.NoError(t, err)
+		require.Equal(t, day.AddDate(0, 0, -1), lastScanned)
+
+		statusManager.revokeErr = nil
+		s.profiles = &mockProfiles{anyName: true}
+
+		require.NoError(t, s.RevokeExpired())
+		require.Equal(t, []string{"vc1", "failed", "noprofile"}, statusManager.revoked)
+
+		lastScanned, err = s.lastScannedDay()
+		require.NoError(t, err)
+		require.Equal(t, day.AddDate(0, 0, 1), lastScanned)
+
+		for i := 0; i < 3; i++ {
+			e, err := s.getEntry(day, i)
+			require.NoError(t, err)
+			require.Nil(t, e)
+		}
+	})
+
+	t.Run("test credentials failing to be parsed are dropped", func(t *testing.T) {
+		statusManager := &mockStatusManager{}
+		s := newScheduler(t, statusManager)
+		s.now = func() time.Time { return day }
+
+		require.NoError(t, s.Record(newVC(t, "vc1", day), profileName))
+		require.NoError(t, s.store.Put(entryKey(day, 0), []byte(`{"profile":"issuer","credential":{}}`)))
+
+		require.NoError(t, s.RevokeExpired())
+		require.Empty(t, statusManager.revoked)
+
+		e, err := s.getEntry(day, 0)
+		require.NoError(t, err)
+		require.Nil(t, e)
+	})
+
+	t.Run("test revocations recorded in status history", func(t *testing.T) {
+		statusManager := &mockStatusManager{revokeErr: map[string]error{
+			"revoked": fmt.Errorf("%w: already revoked", cslstatus.ErrInvalidStatusTransition),
+		}}
+
+		h, err := history.New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		s, err := New(memstore.NewProvider(), statusManager, &mockProfiles{}, WithStatusHistory(h))
+		require.NoError(t, err)
+
+		s.now = func() time.Time { return day }
+
+		require.NoError(t, s.Record(newVC(t, "vc1", day), profileName))
+		require.NoError(t, s.Record(newVC(t, "revoked", day), profileName))
+		require.NoError(t, s.RevokeExpired())
+
+		changes, err := h.Get(profileName, "urn:vc1")
+		require.NoError(t, err)
+		require.Equal(t, []history.StatusChange{{
+			Status: cslstatus.StatusRevoked, StatusReason: StatusReasonExpired, Actor: Actor, Time: day,
+		}}, changes)
+
+		_, err = h.Get(profileName, "urn:revoked")
+		require.Error(t, err)
+	})
+
+	t.Run("test nothing logged", func(t *testing.T) {
+		s := newScheduler(t, &mockStatusManager{})
+		require.NoError(t, s.RevokeExpired())
+	})
+
+	t.Run("test error from store", func(t *testing.T) {
+		store := &mockstore.MockStore{Store: map[string][]byte{}}
+		s, err := New(&mockstore.Provider{Store: store}, &mockStatusManager{}, &mockProfiles{})
+		require.NoError(t, err)
+
+		store.ErrPut = fmt.Errorf("error put")
+		err = s.Record(newVC(t, "vc1", day), profileName)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store last scanned day: error put")
+
+		store.Store[lastScannedKey] = []byte("yesterday")
+		err = s.RevokeExpired()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid last scanned day")
+
+		store.Store[lastScannedKey] = []byte(day.AddDate(0, 0, -1).Format(dayLayout))
+		store.Store[bucketKey(day)] = []byte("[")
+		s.now = func() time.Time { return day }
+		err = s.RevokeExpired()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid issuance log")
+
+		store.Store[bucketKey(day)] = []byte("2")
+		store.Store[entryKey(day, 0)] = []byte("[")
+		err = s.RevokeExpired()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal issuance log entry")
+
+		store.Store[entryKey(day, 0)] = []byte{}
+		err = s.RevokeExpired()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get issuance log entry")
+
+		store.ErrPut = fmt.Errorf("error put")
+		err = s.Record(newVC(t, "vc1", day), profileName)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store issuance log entry: error put")
+		store.ErrPut = nil
+
+		store.ErrGet = fmt.Errorf("error get")
+		err = s.RevokeExpired()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get last scanned day: error get")
+	})
+}
+
+func TestScheduler_Start(t *testing.T) {
+	statusManager := &mockStatusManager{}
+	s := newScheduler(t, statusManager)
+
+	require.NoError(t, s.Record(newVC(t, "vc1", time.Now().Add(-time.Minute)), profileName))
+
+	s.Start(time.Millisecond)
+	defer s.Stop()
+
+	require.Eventually(t, func() bool {
+		return len(statusManager.revokedIDs()) == 1
+	}, time.Second, time.Millisecond)
+}
+
+func newScheduler(t *testing.T, statusManager *mockStatusManager) *Scheduler {
+	t.Helper()
+
+	s, err := New(memstore.NewProvider(), statusManager, &mockProfiles{})
+	require.NoError(t, err)
+
+	return s
+}
+
+func newVC(t *testing.T, id string, expired time.Time) *verifiable.Credential {
+	t.Helper()
+
+	vc, err := verifiable.ParseCredential([]byte(fmt.Sprintf(`{
+		"@context": ["https://www.w3.org/2018/credentials/v1"],
+		"id": "urn:%s",
+		"type": "VerifiableCredential",
+		"issuer": "did:example:issuer",
+		"issuanceDate": "2020-01-01T00:00:00Z",
+		"expirationDate": %q,
+		"credentialStatus": {"id": "http://example.com/status/1", "type": "CredentialStatusList2017"},
+		"credentialSubject": {"id": "did:example:subject"}
+	}`, id, expired.Format(time.RFC3339))))
+	require.NoError(t, err)
+
+	return vc
+}
+
+type mockStatusManager struct {
+	mutex     sync.Mutex
+	revoked   []string
+	revokeErr map[string]error
+}
+
+func (m *mockStatusManager) RevokeVC(v *verifiable.Credential, profile *vcprofile.DataProfile,
+	statusReason string) error {
+	if err := m.revokeErr[strings.TrimPrefix(v.ID, "urn:")]; err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.revoked = append(m.revoked, strings.TrimPrefix(v.ID, "urn:"))
+
+	return nil
+}
+
+func (m *mockStatusManager) revokedIDs() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.revoked
+}
+
+type mockProfiles struct {
+	anyName bool
+}
+
+func (m *mockProfiles) GetProfile(name string) (*vcprofile.DataProfile, error) {
+	if name != profileName && !m.anyName {
+		return nil, fmt.Errorf("profile %s not found", name)
+	}
+
+	return &vcprofile.DataProfile{Name: name}, nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+)
+
+const statusHistoryStore = "statushistory"
+
+// StatusChange is a status transition of a credential
+type StatusChange struct {
+	Status        string    `json:"status"`
+	StatusReason  string    `json:"statusReason,omitempty"`
+	Actor         string    `json:"actor"`
+	CorrelationID string    `json:"correlationID,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// Store keeps the status transitions of the credentials of each profile. The credential status lists only hold
+// the current status of the credentials.
+//
+// Each status change is stored under its own key, numbered in the history of its credential, before the count of
+// the changes of the credential. The store mutex serializes the numbering, so the changes recorded by an instance
+// can't overwrite each other.
+type Store struct {
+	store storage.Store
+	mutex sync.Mutex
+}
+
+// New returns a new status history store
+func New(provider storage.Provider) (*Store, error) {
+	err := provider.CreateStore(statusHistoryStore)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := provider.OpenStore(statusHistoryStore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{store: store}, nil
+}
+
+// Record appends the status change to the history of the credential issued under the profile
+func (s *Store) Record(profile, vcID string, change *StatusChange) error {
+	changeBytes, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status change: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count, err := s.getCount(profile, vcID)
+	if err != nil && !errors.Is(err, storage.ErrValueNotFound) {
+		return err
+	}
+
+	if err := s.store.Put(entryKey(profile, vcID, count), changeBytes); err != nil {
+		return fmt.Errorf("failed to store status change: %w", err)
+	}
+
+	if err := s.store.Put(key(profile, vcID), []byte(strconv.Itoa(count+1))); err != nil {
+		return fmt.Errorf("failed to store status history: %w", err)
+	}
+
+	return nil
+}
+
+// Get returns the status changes of the credential issued under the profile, oldest first. The error wraps
+// storage.ErrValueNotFound if the status of the credential never changed.
+func (s *Store) Get(profile, vcID string) ([]StatusChange, error) {
+	count, err := s.getCount(profile, vcID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]StatusChange, count)
+
+	for i := range changes {
+		changeBytes, errGet := s.store.Get(entryKey(profile, vcID, i))
+		if errGet != nil {
+			return nil, fmt.Errorf("failed to get status change: %w", errGet)
+		}
+
+		if errUnmarshal := json.Unmarshal(changeBytes, &changes[i]); errUnmarshal != nil {
+			return nil, fmt.Errorf("failed to unmarshal status change: %w", errUnmarshal)
+		}
+	}
+
+	return changes, nil
+}
+
+// getCount returns the number of status changes of the credential, the error wraps storage.ErrValueNotFound if
+// the status of the credential never changed
+func (s *Store) getCount(profile, vcID string) (int, error) {
+	countBytes, err := s.store.Get(key(profile, vcID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get status history: %w", err)
+	}
+
+	count, err := strconv.Atoi(string(countBytes))
+	if err != nil {
+		return 0, fmt.Errorf("invalid status history: %w", err)
+	}
+
+	return count, nil
+}
+
+func key(profile, vcID string) string {
+	return profile + "_" + vcID
+}
+
+func entryKey(profile, vcID string, i int) string {
+	return key(profile, vcID) + "/" + strconv.Itoa(i)
+}
@@ -0,0 +1,122 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package history
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		s, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+		require.NotNil(t, s)
+	})
+
+	t.Run("test error from create store", func(t *testing.T) {
+		s, err := New(&mockstore.Provider{ErrCreateStore: fmt.Errorf("error create")})
+		require.EqualError(t, err, "error create")
+		require.Nil(t, s)
+	})
+
+	t.Run("test error from open store", func(t *testing.T) {
+		s, err := New(&mockstore.Provider{ErrOpenStoreHandle: fmt.Errorf("error open")})
+		require.EqualError(t, err, "error open")
+		require.Nil(t, s)
+	})
+}
+
+func TestStore_Record(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		s, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		_, err = s.Get("issuer", "http://example.edu/credentials/1")
+		require.True(t, errors.Is(err, storage.ErrValueNotFound))
+
+		now := time.Now().UTC().Truncate(time.Second)
+
+		require.NoError(t, s.Record("issuer", "http://example.edu/credentials/1",
+			&StatusChange{Status: "Suspended", StatusReason: "lost", Actor: "api", Time: now}))
+		require.NoError(t, s.Record("issuer", "http://example.edu/credentials/1",
+			&StatusChange{Status: "Revoked", Actor: "api", CorrelationID: "c1", Time: now.Add(time.Hour)}))
+		require.NoError(t, s.Record("other", "http://example.edu/credentials/1",
+			&StatusChange{Status: "Revoked", Actor: "api", Time: now}))
+
+		changes, err := s.Get("issuer", "http://example.edu/credentials/1")
+		require.NoError(t, err)
+		require.Equal(t, []StatusChange{
+			{Status: "Suspended", StatusReason: "lost", Actor: "api", Time: now},
+			{Status: "Revoked", Actor: "api", CorrelationID: "c1", Time: now.Add(time.Hour)},
+		}, changes)
+
+		changes, err = s.Get("other", "http://example.edu/credentials/1")
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+	})
+
+	t.Run("test concurrent records", func(t *testing.T) {
+		s, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+
+				require.NoError(t, s.Record("issuer", "vc1",
+					&StatusChange{Status: "Suspended", Actor: fmt.Sprintf("client%d", i)}))
+			}(i)
+		}
+
+		wg.Wait()
+
+		changes, err := s.Get("issuer", "vc1")
+		require.NoError(t, err)
+		require.Len(t, changes, 10)
+	})
+
+	t.Run("test error from store", func(t *testing.T) {
+		store := &mockstore.MockStore{Store: map[string][]byte{}}
+		s, err := New(&mockstore.Provider{Store: store})
+		require.NoError(t, err)
+
+		store.ErrPut = fmt.Errorf("error put")
+		err = s.Record("issuer", "vc1", &StatusChange{Status: "Revoked"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store status change: error put")
+
+		store.ErrPut = nil
+		store.Store[key("issuer", "vc1")] = []byte("x")
+		err = s.Record("issuer", "vc1", &StatusChange{Status: "Revoked"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid status history")
+
+		store.Store[key("issuer", "vc1")] = []byte("1")
+		store.Store[entryKey("issuer", "vc1", 0)] = []byte("[")
+		_, err = s.Get("issuer", "vc1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal status change")
+
+		store.ErrGet = fmt.Errorf("error get")
+		_, err = s.Get("issuer", "vc1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get status history: error get")
+	})
+}
@@ -0,0 +1,493 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/edge-core/pkg/storage"
+)
+
+const (
+	publicationsStore = "statuspublications"
+
+	// DefaultQueueSize is the number of publications waiting to be published, the publications of the status
+	// changes made while the queue is full stay pending until reconciled.
+	DefaultQueueSize = 1000
+	// DefaultAttempts is how many times a publication is attempted before it fails.
+	DefaultAttempts = 5
+	// DefaultBackoff is the time between the first two attempts of a publication, doubled after each attempt.
+	DefaultBackoff = time.Second
+
+	publishTimeout = 10 * time.Second
+)
+
+// The states of the publications.
+const (
+	// StatePending is the state of a publication queued or being attempted
+	StatePending = "pending"
+	// StatePublished is the state of a publication accepted by its registry
+	StatePublished = "published"
+	// StateFailed is the state of a publication which failed all its attempts, published again when reconciled
+	StateFailed = "failed"
+)
+
+var logger = log.New("edge-service-status-registry")
+
+// Registry is an external revocation registry the status changes of the credentials are pushed to, in addition to
+// their status list (e.g. an adapter of an EBSI or Indy registry).
+type Registry interface {
+	// Name identifies the registry in the publications, unique among the registries of the publisher.
+	Name() string
+	// Publish pushes the status change to the registry. A change may be published more than once, e.g. when an
+	// attempt timed out after the registry accepted it, so the registry must ignore the changes already published.
+	Publish(ctx context.Context, change *StatusChange) error
+}
+
+// StatusChange is a status change of a credential published to the registries.
+type StatusChange struct {
+	Profile      string `json:"profile"`
+	CredentialID string `json:"credentialID"`
+	// StatusListID is the ID of the status list of the credential, if known.
+	StatusListID string    `json:"statusListID,omitempty"`
+	Status       string    `json:"status"`
+	StatusReason string    `json:"statusReason,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// Publication is the publication of a status change to a registry.
+type Publication struct {
+	Registry  string        `json:"registry"`
+	Change    *StatusChange `json:"change"`
+	State     string        `json:"state"`
+	Attempts  int           `json:"attempts"`
+	LastError string        `json:"lastError,omitempty"`
+	Updated   time.Time     `json:"updated"`
+}
+
+// Report is the state of the publications of the status changes of a profile to each registry.
+type Report struct {
+	Profile    string            `json:"profile"`
+	Registries []*RegistryReport `json:"registries"`
+}
+
+// RegistryReport is the number of publications of the status changes of a profile to a registry in each state,
+// with the failed publications to reconcile.
+type RegistryReport struct {
+	Registry           string         `json:"registry"`
+	Published          int            `json:"published"`
+	Pending            int            `json:"pending"`
+	Failed             int            `json:"failed"`
+	FailedPublications []*Publication `json:"failedPublications,omitempty"`
+}
+
+// Option configures the publisher
+type Option func(p *Publisher)
+
+// WithRetry sets how many times a publication is attempted, and the time between its first two attempts, doubled
+// after each attempt.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(p *Publisher) {
+		p.attempts = attempts
+		p.backoff = backoff
+	}
+}
+
+// WithQueueSize sets the number of publications waiting to be published.
+func WithQueueSize(size int) Option {
+	return func(p *Publisher) {
+		p.queueSize = size
+	}
+}
+
+// Publisher publishes the status changes of the credentials to the registries in the background, so the status
+// changes neither wait for the registries nor fail with them. A publication is retried with backoff, and fails once
+// all its attempts failed. The publications of a profile are stored, so their states are reported and the failed
+// ones, or the ones left pending by an instance stopped, are published again when the profile is reconciled.
+//
+// Each publication is stored under its own key, numbered in the publications of its profile, before the count of the
+// publications of the profile. The publications are attempted one at a time in the order of the status changes,
+// the status changes of a credential reaching the registries in order.
+type Publisher struct {
+	store      storage.Store
+	registries []Registry
+	attempts   int
+	backoff    time.Duration
+	queueSize  int
+	now        func() time.Time
+	sleep      func(d time.Duration)
+
+	queue chan *publicationRef
+	done  chan struct{}
+
+	mutex  sync.Mutex
+	queued map[publicationRef]struct{}
+	closed bool
+}
+
+// publicationRef is the profile and the number of a stored publication
+type publicationRef struct {
+	profile string
+	index   int
+}
+
+// New returns a publisher of the status changes to the registries, which must have distinct names.
+func New(provider storage.Provider, registries []Registry, opts ...Option) (*Publisher, error) {
+	names := make(map[string]struct{})
+
+	for _, r := range registries {
+		if _, ok := names[r.Name()]; ok {
+			return nil, fmt.Errorf("duplicate registry %s", r.Name())
+		}
+
+		names[r.Name()] = struct{}{}
+	}
+
+	err := provider.CreateStore(publicationsStore)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := provider.OpenStore(publicationsStore)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Publisher{
+		store:      store,
+		registries: registries,
+		attempts:   DefaultAttempts,
+		backoff:    DefaultBackoff,
+		queueSize:  DefaultQueueSize,
+		now:        time.Now,
+		sleep:      time.Sleep,
+		queued:     make(map[publicationRef]struct{}),
+		done:       make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.attempts <= 0 {
+		p.attempts = 1
+	}
+
+	if p.queueSize <= 0 {
+		p.queueSize = DefaultQueueSize
+	}
+
+	p.queue = make(chan *publicationRef, p.queueSize)
+
+	go p.run()
+
+	return p, nil
+}
+
+// Publish stores the publications of the status change to each registry and queues them. A nil publisher publishes
+// nothing, so the status changes may be published whether registries are configured or not.
+func (p *Publisher) Publish(change *StatusChange) error {
+	if p == nil {
+		return nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	count, err := p.getCount(change.Profile)
+	if err != nil {
+		return err
+	}
+
+	refs := make([]publicationRef, len(p.registries))
+
+	for i, r := range p.registries {
+		refs[i] = publicationRef{profile: change.Profile, index: count + i}
+
+		err = p.putPublication(refs[i], &Publication{Registry: r.Name(), Change: change, State: StatePending,
+			Updated: p.now().UTC()})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := p.store.Put(countKey(change.Profile), []byte(strconv.Itoa(count+len(refs)))); err != nil {
+		return fmt.Errorf("failed to store status publications count: %w", err)
+	}
+
+	for i := range refs {
+		p.enqueue(refs[i])
+	}
+
+	return nil
+}
+
+// Report returns the states of the publications of the status changes of the profile to each registry.
+func (p *Publisher) Report(profile string) (*Report, error) {
+	report := &Report{Profile: profile, Registries: []*RegistryReport{}}
+	byName := make(map[string]*RegistryReport)
+
+	for _, r := range p.registries {
+		registryReport := &RegistryReport{Registry: r.Name()}
+		report.Registries = append(report.Registries, registryReport)
+		byName[r.Name()] = registryReport
+	}
+
+	err := p.forEach(profile, func(_ publicationRef, pub *Publication) error {
+		registryReport, ok := byName[pub.Registry]
+		if !ok {
+			// a registry removed from the configuration
+			return nil
+		}
+
+		switch pub.State {
+		case StatePublished:
+			registryReport.Published++
+		case StateFailed:
+			registryReport.Failed++
+			registryReport.FailedPublications = append(registryReport.FailedPublications, pub)
+		default:
+			registryReport.Pending++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Reconcile queues the failed publications of the status changes of the profile, and its pending publications not
+// queued by this instance (e.g. left pending by an instance stopped), with all their attempts. It returns the number
+// of publications queued.
+func (p *Publisher) Reconcile(profile string) (int, error) {
+	var refs []publicationRef
+
+	p.mutex.Lock()
+
+	err := p.forEach(profile, func(ref publicationRef, pub *Publication) error {
+		if _, queued := p.queued[ref]; queued || pub.State == StatePublished || p.registry(pub.Registry) == nil {
+			return nil
+		}
+
+		pub.State = StatePending
+		pub.Attempts = 0
+		pub.Updated = p.now().UTC()
+
+		if err := p.putPublication(ref, pub); err != nil {
+			return err
+		}
+
+		refs = append(refs, ref)
+
+		return nil
+	})
+
+	for _, ref := range refs {
+		p.enqueue(ref)
+	}
+
+	p.mutex.Unlock()
+
+	return len(refs), err
+}
+
+// Close publishes the queued publications, then stops the publisher.
+func (p *Publisher) Close() {
+	p.mutex.Lock()
+
+	if p.closed {
+		p.mutex.Unlock()
+
+		return
+	}
+
+	p.closed = true
+	close(p.queue)
+	p.mutex.Unlock()
+
+	<-p.done
+}
+
+// enqueue queues the publication unless the queue is full, the publication staying pending until reconciled. The
+// mutex must be held.
+func (p *Publisher) enqueue(ref publicationRef) {
+	if p.closed {
+		return
+	}
+
+	select {
+	case p.queue <- &ref:
+		p.queued[ref] = struct{}{}
+	default:
+		logger.Warnf("status publication queue full, publication %d of profile %s stays pending", ref.index,
+			ref.profile)
+	}
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+
+	for ref := range p.queue {
+		if err := p.publish(*ref); err != nil {
+			logger.Errorf("failed to publish status publication %d of profile %s: %s", ref.index, ref.profile, err)
+		}
+
+		p.mutex.Lock()
+		delete(p.queued, *ref)
+		p.mutex.Unlock()
+	}
+}
+
+// publish attempts the publication until it is published or all its attempts failed, storing its state after each
+// attempt
+func (p *Publisher) publish(ref publicationRef) error {
+	pub, err := p.getPublication(ref)
+	if err != nil {
+		return err
+	}
+
+	r := p.registry(pub.Registry)
+	if r == nil || pub.State != StatePending {
+		return nil
+	}
+
+	backoff := p.backoff
+
+	for pub.Attempts < p.attempts {
+		if pub.Attempts > 0 {
+			p.sleep(backoff)
+			backoff *= 2
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		publishErr := r.Publish(ctx, pub.Change)
+
+		cancel()
+
+		pub.Attempts++
+		pub.Updated = p.now().UTC()
+
+		if publishErr == nil {
+			pub.State = StatePublished
+			pub.LastError = ""
+
+			return p.putPublication(ref, pub)
+		}
+
+		pub.LastError = publishErr.Error()
+
+		if pub.Attempts >= p.attempts {
+			pub.State = StateFailed
+
+			logger.Warnf("failed to publish the status change of credential %s to registry %s: %s",
+				pub.Change.CredentialID, pub.Registry, publishErr)
+		}
+
+		if err := p.putPublication(ref, pub); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Publisher) registry(name string) Registry {
+	for _, r := range p.registries {
+		if r.Name() == name {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// forEach calls f with the publications of the profile, in order
+func (p *Publisher) forEach(profile string, f func(ref publicationRef, pub *Publication) error) error {
+	count, err := p.getCount(profile)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		ref := publicationRef{profile: profile, index: i}
+
+		pub, err := p.getPublication(ref)
+		if err != nil {
+			return err
+		}
+
+		if err := f(ref, pub); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getCount returns the number of publications of the profile
+func (p *Publisher) getCount(profile string) (int, error) {
+	countBytes, err := p.store.Get(countKey(profile))
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to get status publications count: %w", err)
+	}
+
+	count, err := strconv.Atoi(string(countBytes))
+	if err != nil {
+		return 0, fmt.Errorf("invalid status publications count: %w", err)
+	}
+
+	return count, nil
+}
+
+func (p *Publisher) getPublication(ref publicationRef) (*Publication, error) {
+	pubBytes, err := p.store.Get(entryKey(ref))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status publication: %w", err)
+	}
+
+	pub := &Publication{}
+	if err := json.Unmarshal(pubBytes, pub); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal status publication: %w", err)
+	}
+
+	return pub, nil
+}
+
+func (p *Publisher) putPublication(ref publicationRef, pub *Publication) error {
+	pubBytes, err := json.Marshal(pub)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status publication: %w", err)
+	}
+
+	if err := p.store.Put(entryKey(ref), pubBytes); err != nil {
+		return fmt.Errorf("failed to store status publication: %w", err)
+	}
+
+	return nil
+}
+
+func countKey(profile string) string {
+	return "publications_" + profile
+}
+
+func entryKey(ref publicationRef) string {
+	return countKey(ref.profile) + "/" + strconv.Itoa(ref.index)
+}
@@ -0,0 +1,257 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+)
+
+type mockRegistry struct {
+	name string
+
+	mutex     sync.Mutex
+	failures  int
+	published []*StatusChange
+}
+
+func (r *mockRegistry) Name() string {
+	return r.name
+}
+
+func (r *mockRegistry) Publish(_ context.Context, change *StatusChange) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.failures != 0 {
+		r.failures--
+
+		return errors.New("registry unavailable")
+	}
+
+	r.published = append(r.published, change)
+
+	return nil
+}
+
+func newPublisher(t *testing.T, provider storage.Provider, registries ...Registry) (*Publisher, *[]time.Duration) {
+	p, err := New(provider, registries, WithRetry(3, time.Second))
+	require.NoError(t, err)
+
+	var sleeps []time.Duration
+
+	p.sleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+	}
+
+	return p, &sleeps
+}
+
+func statusChange(profile, vcID, status string) *StatusChange {
+	return &StatusChange{Profile: profile, CredentialID: vcID, Status: status,
+		Time: time.Now().UTC().Truncate(time.Second)}
+}
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		p, err := New(memstore.NewProvider(), []Registry{&mockRegistry{name: "ebsi"}, &mockRegistry{name: "indy"}},
+			WithQueueSize(10))
+		require.NoError(t, err)
+		require.NotNil(t, p)
+		require.Equal(t, 10, cap(p.queue))
+
+		p.Close()
+		p.Close()
+	})
+
+	t.Run("test error from duplicate registry", func(t *testing.T) {
+		p, err := New(memstore.NewProvider(), []Registry{&mockRegistry{name: "ebsi"}, &mockRegistry{name: "ebsi"}})
+		require.EqualError(t, err, "duplicate registry ebsi")
+		require.Nil(t, p)
+	})
+
+	t.Run("test error from create store", func(t *testing.T) {
+		p, err := New(&mockstore.Provider{ErrCreateStore: fmt.Errorf("error create")}, nil)
+		require.EqualError(t, err, "error create")
+		require.Nil(t, p)
+	})
+
+	t.Run("test error from open store", func(t *testing.T) {
+		p, err := New(&mockstore.Provider{ErrOpenStoreHandle: fmt.Errorf("error open")}, nil)
+		require.EqualError(t, err, "error open")
+		require.Nil(t, p)
+	})
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	t.Run("test published to each registry", func(t *testing.T) {
+		ebsi, indy := &mockRegistry{name: "ebsi"}, &mockRegistry{name: "indy"}
+		p, sleeps := newPublisher(t, memstore.NewProvider(), ebsi, indy)
+
+		suspended := statusChange("issuer", "http://example.edu/credentials/1", "Suspended")
+		revoked := statusChange("issuer", "http://example.edu/credentials/1", "Revoked")
+
+		require.NoError(t, p.Publish(suspended))
+		require.NoError(t, p.Publish(revoked))
+		p.Close()
+
+		// the status changes reach the registries in order
+		require.Equal(t, []*StatusChange{suspended, revoked}, ebsi.published)
+		require.Equal(t, []*StatusChange{suspended, revoked}, indy.published)
+		require.Empty(t, *sleeps)
+
+		report, err := p.Report("issuer")
+		require.NoError(t, err)
+		require.Equal(t, &Report{Profile: "issuer", Registries: []*RegistryReport{
+			{Registry: "ebsi", Published: 2}, {Registry: "indy", Published: 2},
+		}}, report)
+
+		// the publications of the other profiles are kept apart
+		report, err = p.Report("other")
+		require.NoError(t, err)
+		require.Equal(t, &Report{Profile: "other", Registries: []*RegistryReport{
+			{Registry: "ebsi"}, {Registry: "indy"},
+		}}, report)
+	})
+
+	t.Run("test published after retries", func(t *testing.T) {
+		ebsi := &mockRegistry{name: "ebsi", failures: 2}
+		p, sleeps := newPublisher(t, memstore.NewProvider(), ebsi)
+
+		require.NoError(t, p.Publish(statusChange("issuer", "http://example.edu/credentials/1", "Revoked")))
+		p.Close()
+
+		require.Len(t, ebsi.published, 1)
+		require.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *sleeps)
+
+		pub, err := p.getPublication(publicationRef{profile: "issuer"})
+		require.NoError(t, err)
+		require.Equal(t, StatePublished, pub.State)
+		require.Equal(t, 3, pub.Attempts)
+		require.Empty(t, pub.LastError)
+	})
+
+	t.Run("test failed after all attempts", func(t *testing.T) {
+		ebsi, indy := &mockRegistry{name: "ebsi", failures: 3}, &mockRegistry{name: "indy"}
+		p, _ := newPublisher(t, memstore.NewProvider(), ebsi, indy)
+
+		change := statusChange("issuer", "http://example.edu/credentials/1", "Revoked")
+
+		require.NoError(t, p.Publish(change))
+		p.Close()
+
+		require.Empty(t, ebsi.published)
+		require.Len(t, indy.published, 1)
+
+		report, err := p.Report("issuer")
+		require.NoError(t, err)
+		require.Len(t, report.Registries, 2)
+		require.Equal(t, 1, report.Registries[0].Failed)
+		require.Len(t, report.Registries[0].FailedPublications, 1)
+		require.Equal(t, change, report.Registries[0].FailedPublications[0].Change)
+		require.Equal(t, 3, report.Registries[0].FailedPublications[0].Attempts)
+		require.Equal(t, "registry unavailable", report.Registries[0].FailedPublications[0].LastError)
+		require.Equal(t, 1, report.Registries[1].Published)
+	})
+
+	t.Run("test pending when the queue is full", func(t *testing.T) {
+		p, err := New(memstore.NewProvider(), []Registry{&mockRegistry{name: "ebsi"}}, WithQueueSize(1))
+		require.NoError(t, err)
+
+		// the worker is stopped, so the queue is never read
+		p.mutex.Lock()
+		p.closed = true
+		close(p.queue)
+		p.mutex.Unlock()
+		<-p.done
+
+		p.closed = false
+		p.queue = make(chan *publicationRef, 1)
+
+		require.NoError(t, p.Publish(statusChange("issuer", "http://example.edu/credentials/1", "Revoked")))
+		require.NoError(t, p.Publish(statusChange("issuer", "http://example.edu/credentials/2", "Revoked")))
+		require.Len(t, p.queue, 1)
+
+		report, err := p.Report("issuer")
+		require.NoError(t, err)
+		require.Equal(t, 2, report.Registries[0].Pending)
+	})
+
+	t.Run("test nil publisher", func(t *testing.T) {
+		var p *Publisher
+
+		require.NoError(t, p.Publish(statusChange("issuer", "http://example.edu/credentials/1", "Revoked")))
+	})
+
+	t.Run("test error from store", func(t *testing.T) {
+		store := &mockstore.MockStore{Store: map[string][]byte{}, ErrPut: errors.New("put error")}
+
+		p, err := New(&mockstore.Provider{Store: store}, []Registry{&mockRegistry{name: "ebsi"}})
+		require.NoError(t, err)
+
+		defer p.Close()
+
+		err = p.Publish(statusChange("issuer", "http://example.edu/credentials/1", "Revoked"))
+		require.EqualError(t, err, "failed to store status publication: put error")
+
+		store.ErrPut = nil
+		store.Store[countKey("issuer")] = []byte("invalid")
+
+		err = p.Publish(statusChange("issuer", "http://example.edu/credentials/1", "Revoked"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid status publications count")
+
+		_, err = p.Report("issuer")
+		require.Error(t, err)
+	})
+}
+
+func TestPublisher_Reconcile(t *testing.T) {
+	provider := memstore.NewProvider()
+
+	// an instance stopped, or a registry unavailable
+	p, _ := newPublisher(t, provider, &mockRegistry{name: "ebsi", failures: 3}, &mockRegistry{name: "indy"})
+
+	require.NoError(t, p.Publish(statusChange("issuer", "http://example.edu/credentials/1", "Revoked")))
+	p.Close()
+
+	require.NoError(t, p.putPublication(publicationRef{profile: "issuer", index: 2}, &Publication{Registry: "ebsi",
+		Change: statusChange("issuer", "http://example.edu/credentials/2", "Revoked"), State: StatePending}))
+	require.NoError(t, p.putPublication(publicationRef{profile: "issuer", index: 3}, &Publication{
+		Registry: "removed", Change: statusChange("issuer", "http://example.edu/credentials/2", "Revoked"),
+		State: StateFailed}))
+	require.NoError(t, p.store.Put(countKey("issuer"), []byte("4")))
+
+	ebsi := &mockRegistry{name: "ebsi"}
+	p, _ = newPublisher(t, provider, ebsi, &mockRegistry{name: "indy"})
+
+	queued, err := p.Reconcile("issuer")
+	require.NoError(t, err)
+	require.Equal(t, 2, queued)
+	p.Close()
+
+	require.Len(t, ebsi.published, 2)
+
+	report, err := p.Report("issuer")
+	require.NoError(t, err)
+	require.Equal(t, &Report{Profile: "issuer", Registries: []*RegistryReport{
+		{Registry: "ebsi", Published: 2}, {Registry: "indy", Published: 1},
+	}}, report)
+
+	queued, err = p.Reconcile("issuer")
+	require.NoError(t, err)
+	require.Zero(t, queued)
+}
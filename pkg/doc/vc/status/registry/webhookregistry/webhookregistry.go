@@ -0,0 +1,73 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webhookregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/registry"
+)
+
+const maxErrorBodySize = 1024
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Registry publishes the status changes to a revocation registry adapter listening on a webhook, which POSTs them
+// to the adapter as JSON. The adapter accepts a status change with a 2xx response.
+type Registry struct {
+	name       string
+	url        string
+	httpClient httpClient
+}
+
+// New returns the registry of the webhook.
+func New(name, url string, httpClient httpClient) *Registry {
+	return &Registry{name: name, url: url, httpClient: httpClient}
+}
+
+// Name returns the name of the registry.
+func (r *Registry) Name() string {
+	return r.name
+}
+
+// Publish POSTs the status change to the webhook.
+func (r *Registry) Publish(ctx context.Context, change *registry.StatusChange) error {
+	changeBytes, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status change: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(changeBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post status change to webhook: %w", err)
+	}
+
+	defer resp.Body.Close() // nolint: errcheck
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize)) // nolint: errcheck
+
+		return fmt.Errorf("webhook responded with status %d: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webhookregistry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/registry"
+)
+
+func TestRegistry_Publish(t *testing.T) {
+	change := &registry.StatusChange{Profile: "issuer", CredentialID: "http://example.edu/credentials/1",
+		StatusListID: "https://example.com/status/issuer/1", Status: "Revoked",
+		Time: time.Now().UTC().Truncate(time.Second)}
+
+	t.Run("test success", func(t *testing.T) {
+		var received *registry.StatusChange
+
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			require.Equal(t, http.MethodPost, req.Method)
+			require.Equal(t, "application/json", req.Header.Get("Content-Type"))
+
+			received = &registry.StatusChange{}
+			require.NoError(t, json.NewDecoder(req.Body).Decode(received))
+
+			rw.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		r := New("ebsi", server.URL, server.Client())
+		require.Equal(t, "ebsi", r.Name())
+		require.NoError(t, r.Publish(context.Background(), change))
+		require.Equal(t, change, received)
+	})
+
+	t.Run("test error status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = rw.Write([]byte("ledger unavailable")) // nolint: errcheck
+		}))
+		defer server.Close()
+
+		err := New("ebsi", server.URL, server.Client()).Publish(context.Background(), change)
+		require.EqualError(t, err, "webhook responded with status 503: ledger unavailable")
+	})
+
+	t.Run("test error from request", func(t *testing.T) {
+		err := New("ebsi", "http://localhost:0", &http.Client{}).Publish(context.Background(), change)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to post status change to webhook")
+
+		err = New("ebsi", "://invalid", &http.Client{}).Publish(context.Background(), change)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create webhook request")
+	})
+}
@@ -0,0 +1,193 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/trustbloc/edge-core/pkg/log"
+)
+
+var logger = log.New("edge-service-events")
+
+// SchemaVersion is the version of the schema of the event payloads, a breaking change of the payloads (of the event
+// or of its data) bumps the major version.
+const SchemaVersion = "1.0"
+
+// The types of the events.
+const (
+	// CredentialIssued is published when a credential is issued, the data being IssuanceData.
+	CredentialIssued = "credential.issued"
+	// CredentialStatusChanged is published when the status of a credential changes (e.g. revoked), the data being
+	// StatusChangeData.
+	CredentialStatusChanged = "credential.status.changed"
+	// CredentialVerified is published when a credential is verified, successfully or not, the data being
+	// VerificationData.
+	CredentialVerified = "credential.verified"
+	// PresentationVerified is published when a presentation is verified, successfully or not, the data being
+	// VerificationData.
+	PresentationVerified = "presentation.verified"
+	// CredentialExpiring is published when a credential stored by a holder profile expires within the notice period
+	// of the profile, the data being ExpiryData.
+	CredentialExpiring = "credential.expiring"
+)
+
+const (
+	// DefaultQueueSize is the number of events waiting to be published, the events emitted when the queue is full
+	// are dropped.
+	DefaultQueueSize = 1000
+
+	publishTimeout = 10 * time.Second
+)
+
+// Event is the JSON payload of an event published to the message broker.
+type Event struct {
+	SchemaVersion string      `json:"schemaVersion"`
+	ID            string      `json:"id"`
+	Type          string      `json:"type"`
+	Time          time.Time   `json:"time"`
+	Profile       string      `json:"profile"`
+	Data          interface{} `json:"data"`
+}
+
+// IssuanceData is the data of the CredentialIssued events.
+type IssuanceData struct {
+	CredentialID string   `json:"credentialID,omitempty"`
+	Types        []string `json:"types,omitempty"`
+	Issuer       string   `json:"issuer,omitempty"`
+	// Format is the format of the issued credential: ldp or jwt.
+	Format string `json:"format"`
+}
+
+// StatusChangeData is the data of the CredentialStatusChanged events.
+type StatusChangeData struct {
+	CredentialID string `json:"credentialID"`
+	Status       string `json:"status"`
+	StatusReason string `json:"statusReason,omitempty"`
+	// Actor is who changed the status: the client authenticated by its API key, anonymous, or the system (e.g. the
+	// revocation on expiry).
+	Actor string `json:"actor,omitempty"`
+}
+
+// VerificationData is the data of the CredentialVerified and PresentationVerified events.
+type VerificationData struct {
+	CredentialID string   `json:"credentialID,omitempty"`
+	Verified     bool     `json:"verified"`
+	Checks       []string `json:"checks"`
+	// FailureReasons are the reasons of the failed checks, see the verification metrics.
+	FailureReasons []string `json:"failureReasons,omitempty"`
+}
+
+// ExpiryData is the data of the CredentialExpiring events.
+type ExpiryData struct {
+	CredentialID string    `json:"credentialID"`
+	Types        []string  `json:"types,omitempty"`
+	Expires      time.Time `json:"expires"`
+}
+
+// Publisher publishes the events to a message broker.
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+	Close() error
+}
+
+// Emitter emits the events of the service to the publisher in the background, so the requests neither wait for
+// the message broker nor fail with it. The events are delivered at most once: an event failing to be published is
+// logged and dropped, as are the events emitted while the queue is full.
+type Emitter struct {
+	publisher Publisher
+	queue     chan *Event
+	done      chan struct{}
+	now       func() time.Time
+
+	mutex  sync.RWMutex
+	closed bool
+}
+
+// NewEmitter returns an emitter publishing the events with the publisher, queueing at most queueSize events
+// (DefaultQueueSize if not positive).
+func NewEmitter(publisher Publisher, queueSize int) *Emitter {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+
+	e := &Emitter{
+		publisher: publisher,
+		queue:     make(chan *Event, queueSize),
+		done:      make(chan struct{}),
+		now:       time.Now,
+	}
+
+	go e.run()
+
+	return e
+}
+
+// Emit queues the event of the profile for publication. A nil emitter emits nothing, so the services may emit their
+// events whether a message broker is configured or not.
+func (e *Emitter) Emit(eventType, profile string, data interface{}) {
+	if e == nil {
+		return
+	}
+
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if e.closed {
+		return
+	}
+
+	event := &Event{
+		SchemaVersion: SchemaVersion,
+		ID:            uuid.New().String(),
+		Type:          eventType,
+		Time:          e.now().UTC(),
+		Profile:       profile,
+		Data:          data,
+	}
+
+	select {
+	case e.queue <- event:
+	default:
+		logger.Warnf("event queue full, dropped %s event %s of profile %s", eventType, event.ID, profile)
+	}
+}
+
+// Close publishes the queued events, then closes the publisher.
+func (e *Emitter) Close() error {
+	e.mutex.Lock()
+
+	if e.closed {
+		e.mutex.Unlock()
+
+		return nil
+	}
+
+	e.closed = true
+	close(e.queue)
+	e.mutex.Unlock()
+
+	<-e.done
+
+	return e.publisher.Close()
+}
+
+func (e *Emitter) run() {
+	defer close(e.done)
+
+	for event := range e.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+
+		if err := e.publisher.Publish(ctx, event); err != nil {
+			logger.Warnf("failed to publish %s event %s of profile %s: %s", event.Type, event.ID, event.Profile, err)
+		}
+
+		cancel()
+	}
+}
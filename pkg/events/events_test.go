@@ -0,0 +1,137 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockPublisher struct {
+	mutex     sync.Mutex
+	events    []*Event
+	publishFn func(event *Event) error
+	closed    bool
+}
+
+func (p *mockPublisher) Publish(_ context.Context, event *Event) error {
+	if p.publishFn != nil {
+		if err := p.publishFn(event); err != nil {
+			return err
+		}
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.events = append(p.events, event)
+
+	return nil
+}
+
+func (p *mockPublisher) Close() error {
+	p.closed = true
+
+	return nil
+}
+
+func TestEmitter(t *testing.T) {
+	now := time.Date(2020, time.October, 16, 10, 0, 0, 0, time.UTC)
+
+	t.Run("test events published", func(t *testing.T) {
+		publisher := &mockPublisher{}
+
+		emitter := NewEmitter(publisher, 0)
+		emitter.now = func() time.Time { return now }
+
+		emitter.Emit(CredentialIssued, "issuer", &IssuanceData{CredentialID: "vc1", Format: "ldp"})
+		emitter.Emit(CredentialStatusChanged, "issuer", &StatusChangeData{CredentialID: "vc1", Status: "revoked"})
+
+		require.NoError(t, emitter.Close())
+		require.True(t, publisher.closed)
+		require.Len(t, publisher.events, 2)
+
+		event := publisher.events[0]
+		require.Equal(t, SchemaVersion, event.SchemaVersion)
+		require.Equal(t, CredentialIssued, event.Type)
+		require.Equal(t, "issuer", event.Profile)
+		require.Equal(t, now, event.Time)
+		require.NotEmpty(t, event.ID)
+		require.NotEqual(t, event.ID, publisher.events[1].ID)
+
+		payload, err := json.Marshal(event)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"schemaVersion":"1.0","id":"`+event.ID+`","type":"credential.issued",`+
+			`"time":"2020-10-16T10:00:00Z","profile":"issuer","data":{"credentialID":"vc1","format":"ldp"}}`,
+			string(payload))
+
+		// the events emitted once closed are dropped
+		emitter.Emit(CredentialIssued, "issuer", &IssuanceData{})
+		require.NoError(t, emitter.Close())
+		require.Len(t, publisher.events, 2)
+	})
+
+	t.Run("test event failing to be published", func(t *testing.T) {
+		publisher := &mockPublisher{publishFn: func(event *Event) error {
+			if event.Profile == "failing" {
+				return errors.New("broker unavailable")
+			}
+
+			return nil
+		}}
+
+		emitter := NewEmitter(publisher, 10)
+
+		emitter.Emit(CredentialVerified, "failing", &VerificationData{})
+		emitter.Emit(CredentialVerified, "verifier", &VerificationData{Verified: true})
+
+		require.NoError(t, emitter.Close())
+		require.Len(t, publisher.events, 1)
+		require.Equal(t, "verifier", publisher.events[0].Profile)
+	})
+
+	t.Run("test events dropped when the queue is full", func(t *testing.T) {
+		release := make(chan struct{})
+		publishing := make(chan struct{})
+
+		publisher := &mockPublisher{publishFn: func(event *Event) error {
+			if event.Profile == "first" {
+				close(publishing)
+				<-release
+			}
+
+			return nil
+		}}
+
+		emitter := NewEmitter(publisher, 1)
+
+		emitter.Emit(PresentationVerified, "first", &VerificationData{})
+		<-publishing
+
+		emitter.Emit(PresentationVerified, "queued", &VerificationData{})
+		emitter.Emit(PresentationVerified, "dropped", &VerificationData{})
+
+		close(release)
+		require.NoError(t, emitter.Close())
+
+		require.Len(t, publisher.events, 2)
+		require.Equal(t, "queued", publisher.events[1].Profile)
+	})
+
+	t.Run("test nil emitter", func(t *testing.T) {
+		var emitter *Emitter
+
+		require.NotPanics(t, func() {
+			emitter.Emit(CredentialIssued, "issuer", &IssuanceData{})
+		})
+	})
+}
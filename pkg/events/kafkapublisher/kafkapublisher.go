@@ -0,0 +1,90 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kafkapublisher
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+
+	"github.com/trustbloc/edge-service/pkg/events"
+)
+
+const (
+	// the headers of the messages, so the consumers may route the events without parsing them
+	typeHeader          = "type"
+	schemaVersionHeader = "schemaVersion"
+)
+
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Option configures the Kafka publisher
+type Option func(c *kafka.WriterConfig)
+
+// WithTLS option is for connecting to the Kafka brokers over TLS
+func WithTLS(tlsConfig *tls.Config) Option {
+	return func(c *kafka.WriterConfig) {
+		c.Dialer = &kafka.Dialer{TLS: tlsConfig, DualStack: true}
+	}
+}
+
+// Publisher publishes the events to a Kafka topic. The events are keyed by profile, so the events of a profile are
+// written to the same partition in the order they are published.
+type Publisher struct {
+	writer messageWriter
+}
+
+// New returns a publisher of the events to the topic of the Kafka cluster of the brokers (host:port).
+func New(brokers []string, topic string, opts ...Option) (*Publisher, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("no kafka broker")
+	}
+
+	if topic == "" {
+		return nil, fmt.Errorf("no kafka topic")
+	}
+
+	config := kafka.WriterConfig{Brokers: brokers, Topic: topic, Balancer: &kafka.Hash{}}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return &Publisher{writer: kafka.NewWriter(config)}, nil
+}
+
+// Publish writes the event to the topic.
+func (p *Publisher) Publish(ctx context.Context, event *events.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	err = p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(event.Profile),
+		Value: payload,
+		Headers: []kafka.Header{
+			{Key: typeHeader, Value: []byte(event.Type)},
+			{Key: schemaVersionHeader, Value: []byte(event.SchemaVersion)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write kafka message: %w", err)
+	}
+
+	return nil
+}
+
+// Close flushes the pending messages and closes the connections to the brokers.
+func (p *Publisher) Close() error {
+	return p.writer.Close()
+}
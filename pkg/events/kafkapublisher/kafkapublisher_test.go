@@ -0,0 +1,105 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kafkapublisher
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/events"
+)
+
+type mockWriter struct {
+	messages []kafka.Message
+	writeErr error
+	closed   bool
+}
+
+func (w *mockWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	if w.writeErr != nil {
+		return w.writeErr
+	}
+
+	w.messages = append(w.messages, msgs...)
+
+	return nil
+}
+
+func (w *mockWriter) Close() error {
+	w.closed = true
+
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		p, err := New([]string{"kafka:9092"}, "vcs.events", WithTLS(&tls.Config{MinVersion: tls.VersionTLS12}))
+		require.NoError(t, err)
+		require.NotNil(t, p)
+		require.NoError(t, p.Close())
+	})
+
+	t.Run("test missing broker", func(t *testing.T) {
+		p, err := New(nil, "vcs.events")
+		require.EqualError(t, err, "no kafka broker")
+		require.Nil(t, p)
+	})
+
+	t.Run("test missing topic", func(t *testing.T) {
+		p, err := New([]string{"kafka:9092"}, "")
+		require.EqualError(t, err, "no kafka topic")
+		require.Nil(t, p)
+	})
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	event := &events.Event{SchemaVersion: events.SchemaVersion, ID: "id", Type: events.CredentialIssued,
+		Profile: "issuer", Data: &events.IssuanceData{CredentialID: "vc1"}}
+
+	t.Run("test success", func(t *testing.T) {
+		writer := &mockWriter{}
+		p := &Publisher{writer: writer}
+
+		require.NoError(t, p.Publish(context.Background(), event))
+		require.Len(t, writer.messages, 1)
+
+		message := writer.messages[0]
+		require.Equal(t, "issuer", string(message.Key))
+		require.Equal(t, []kafka.Header{
+			{Key: "type", Value: []byte(events.CredentialIssued)},
+			{Key: "schemaVersion", Value: []byte(events.SchemaVersion)},
+		}, message.Headers)
+
+		published := &events.Event{}
+		require.NoError(t, json.Unmarshal(message.Value, published))
+		require.Equal(t, "id", published.ID)
+		require.Equal(t, map[string]interface{}{"credentialID": "vc1", "format": ""}, published.Data)
+
+		require.NoError(t, p.Close())
+		require.True(t, writer.closed)
+	})
+
+	t.Run("test write error", func(t *testing.T) {
+		p := &Publisher{writer: &mockWriter{writeErr: errors.New("leader not available")}}
+
+		err := p.Publish(context.Background(), event)
+		require.EqualError(t, err, "failed to write kafka message: leader not available")
+	})
+
+	t.Run("test marshal error", func(t *testing.T) {
+		p := &Publisher{writer: &mockWriter{}}
+
+		err := p.Publish(context.Background(), &events.Event{Data: make(chan int)})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to marshal event")
+	})
+}
@@ -0,0 +1,84 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package natspublisher
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/trustbloc/edge-service/pkg/events"
+)
+
+const clientName = "vc-rest"
+
+type conn interface {
+	Publish(subject string, data []byte) error
+	Close()
+}
+
+// Option configures the NATS publisher
+type Option func(opts *[]nats.Option)
+
+// WithTLS option is for connecting to the NATS servers over TLS
+func WithTLS(tlsConfig *tls.Config) Option {
+	return func(opts *[]nats.Option) {
+		*opts = append(*opts, nats.Secure(tlsConfig))
+	}
+}
+
+// Publisher publishes the events to NATS, under the subject of their type prefixed with the subject prefix
+// (e.g. vcs.events.credential.issued), so the consumers may subscribe to some of the types with wildcards.
+type Publisher struct {
+	conn          conn
+	subjectPrefix string
+}
+
+// New returns a publisher of the events to the NATS servers of the URL (comma-separated for a cluster, e.g.
+// nats://nats:4222), the connection reconnecting on failure.
+func New(url, subjectPrefix string, opts ...Option) (*Publisher, error) {
+	if subjectPrefix == "" {
+		return nil, fmt.Errorf("no nats subject")
+	}
+
+	natsOpts := []nats.Option{nats.Name(clientName)}
+
+	for _, opt := range opts {
+		opt(&natsOpts)
+	}
+
+	nc, err := nats.Connect(url, natsOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	return &Publisher{conn: nc, subjectPrefix: subjectPrefix}, nil
+}
+
+// Publish publishes the event under the subject of its type. The event is buffered by the connection, which
+// flushes it asynchronously.
+func (p *Publisher) Publish(_ context.Context, event *events.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	if err := p.conn.Publish(p.subjectPrefix+"."+event.Type, payload); err != nil {
+		return fmt.Errorf("failed to publish nats message: %w", err)
+	}
+
+	return nil
+}
+
+// Close flushes the buffered events and closes the connection.
+func (p *Publisher) Close() error {
+	p.conn.Close()
+
+	return nil
+}
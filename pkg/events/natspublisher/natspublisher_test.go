@@ -0,0 +1,162 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package natspublisher
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/events"
+)
+
+type message struct {
+	subject string
+	payload []byte
+}
+
+// startServer starts a server speaking enough of the NATS protocol to accept a connection and its messages
+func startServer(t *testing.T) (net.Listener, <-chan *message) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	messages := make(chan *message, 10)
+
+	go func() {
+		c, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		defer c.Close() // nolint: errcheck
+
+		serveConn(c, messages)
+	}()
+
+	return listener, messages
+}
+
+func serveConn(c net.Conn, messages chan<- *message) {
+	if _, err := fmt.Fprint(c, `INFO {"server_id":"test","version":"2.1.0","max_payload":1048576}`+"\r\n"); err != nil {
+		return
+	}
+
+	r := bufio.NewReader(c)
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "PING":
+			if _, err := fmt.Fprint(c, "PONG\r\n"); err != nil {
+				return
+			}
+		case fields[0] == "PUB" && len(fields) == 3:
+			size, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return
+			}
+
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+
+			messages <- &message{subject: fields[1], payload: payload[:size]}
+		}
+	}
+}
+
+type mockConn struct {
+	publishErr error
+}
+
+func (c *mockConn) Publish(string, []byte) error {
+	return c.publishErr
+}
+
+func (c *mockConn) Close() {}
+
+func TestNew(t *testing.T) {
+	t.Run("test missing subject", func(t *testing.T) {
+		p, err := New("nats://localhost:4222", "")
+		require.EqualError(t, err, "no nats subject")
+		require.Nil(t, p)
+	})
+
+	t.Run("test connection error", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		url := "nats://" + listener.Addr().String()
+		require.NoError(t, listener.Close())
+
+		p, err := New(url, "vcs.events", WithTLS(&tls.Config{MinVersion: tls.VersionTLS12}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to connect to nats")
+		require.Nil(t, p)
+	})
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	event := &events.Event{SchemaVersion: events.SchemaVersion, ID: "id", Type: events.CredentialStatusChanged,
+		Profile: "issuer", Data: &events.StatusChangeData{CredentialID: "vc1", Status: "revoked"}}
+
+	t.Run("test success", func(t *testing.T) {
+		listener, messages := startServer(t)
+		defer listener.Close() // nolint: errcheck
+
+		p, err := New("nats://"+listener.Addr().String(), "vcs.events")
+		require.NoError(t, err)
+
+		require.NoError(t, p.Publish(context.Background(), event))
+		require.NoError(t, p.Close())
+
+		select {
+		case msg := <-messages:
+			require.Equal(t, "vcs.events.credential.status.changed", msg.subject)
+
+			published := &events.Event{}
+			require.NoError(t, json.Unmarshal(msg.payload, published))
+			require.Equal(t, "id", published.ID)
+			require.Equal(t, "issuer", published.Profile)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "event not published")
+		}
+	})
+
+	t.Run("test publish error", func(t *testing.T) {
+		p := &Publisher{conn: &mockConn{publishErr: errors.New("connection closed")}, subjectPrefix: "vcs.events"}
+
+		err := p.Publish(context.Background(), event)
+		require.EqualError(t, err, "failed to publish nats message: connection closed")
+	})
+
+	t.Run("test marshal error", func(t *testing.T) {
+		p := &Publisher{conn: &mockConn{}, subjectPrefix: "vcs.events"}
+
+		err := p.Publish(context.Background(), &events.Event{Data: make(chan int)})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to marshal event")
+	})
+}
@@ -0,0 +1,96 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpcapi
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// nolint: gochecknoglobals
+var (
+	// the gRPC status codes of the error codes of the REST API
+	errorCodes = map[string]codes.Code{
+		"INVALID_REQUEST":    codes.InvalidArgument,
+		"INVALID_CREDENTIAL": codes.InvalidArgument,
+		"PROFILE_NOT_FOUND":  codes.NotFound,
+		"NOT_FOUND":          codes.NotFound,
+		"ALREADY_EXISTS":     codes.AlreadyExists,
+		"CONFLICT":           codes.FailedPrecondition,
+		"RATE_LIMITED":       codes.ResourceExhausted,
+		"QUOTA_EXCEEDED":     codes.ResourceExhausted,
+		"DID_NOT_ANCHORED":   codes.FailedPrecondition,
+	}
+
+	// the gRPC status codes of the HTTP status codes, for the errors without a mapped error code
+	httpStatusCodes = map[int]codes.Code{
+		http.StatusBadRequest:      codes.InvalidArgument,
+		http.StatusUnauthorized:    codes.Unauthenticated,
+		http.StatusForbidden:       codes.PermissionDenied,
+		http.StatusNotFound:        codes.NotFound,
+		http.StatusConflict:        codes.FailedPrecondition,
+		http.StatusTooManyRequests: codes.ResourceExhausted,
+	}
+
+	// the HTTP status codes of the gRPC status codes, recorded in the metrics and request logs of the calls
+	statusHTTPCodes = map[codes.Code]int{
+		codes.OK:                 http.StatusOK,
+		codes.InvalidArgument:    http.StatusBadRequest,
+		codes.Unauthenticated:    http.StatusUnauthorized,
+		codes.PermissionDenied:   http.StatusForbidden,
+		codes.NotFound:           http.StatusNotFound,
+		codes.AlreadyExists:      http.StatusConflict,
+		codes.FailedPrecondition: http.StatusConflict,
+		codes.ResourceExhausted:  http.StatusTooManyRequests,
+		codes.Unimplemented:      http.StatusNotImplemented,
+	}
+)
+
+// operationError is the failure of an operation of the REST API, with its HTTP status and error code
+type operationError interface {
+	error
+	StatusCode() int
+	ErrorCode() string
+}
+
+// statusError converts the failure of an operation into a gRPC status error
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var opErr operationError
+	if !errors.As(err, &opErr) {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	code, ok := errorCodes[opErr.ErrorCode()]
+	if !ok {
+		code, ok = httpStatusCodes[opErr.StatusCode()]
+	}
+
+	if !ok {
+		code = codes.Internal
+	}
+
+	return status.Error(code, err.Error())
+}
+
+// httpStatus returns the HTTP status of the gRPC status error
+func httpStatus(err error) int {
+	if statusCode, ok := statusHTTPCodes[status.Code(err)]; ok {
+		return statusCode
+	}
+
+	return http.StatusInternalServerError
+}
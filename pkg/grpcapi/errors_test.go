@@ -0,0 +1,59 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpcapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{name: "error code", err: &testOperationError{status: http.StatusBadRequest, code: "PROFILE_NOT_FOUND",
+			message: "profile not found"}, code: codes.NotFound, message: "profile not found"},
+		{name: "wrapped error", err: fmt.Errorf("issue: %w", &testOperationError{status: http.StatusBadRequest,
+			code: "INVALID_REQUEST", message: "Invalid request: EOF"}),
+			code: codes.InvalidArgument, message: "issue: Invalid request: EOF"},
+		{name: "conflict", err: &testOperationError{status: http.StatusConflict, code: "CONFLICT",
+			message: "invalid status transition"}, code: codes.FailedPrecondition, message: "invalid status transition"},
+		{name: "HTTP status", err: &testOperationError{status: http.StatusBadRequest, code: "DID_ERROR",
+			message: "did error"}, code: codes.InvalidArgument, message: "did error"},
+		{name: "backend error", err: &testOperationError{status: http.StatusInternalServerError, code: "EDV_ERROR",
+			message: "edv down"}, code: codes.Internal, message: "edv down"},
+		{name: "status error", err: status.Error(codes.Unauthenticated, "invalid API key"),
+			code: codes.Unauthenticated, message: "invalid API key"},
+		{name: "other error", err: errors.New("unexpected"), code: codes.Internal, message: "unexpected"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := statusError(tc.err)
+			require.Equal(t, tc.code, status.Code(err))
+			require.Equal(t, tc.message, status.Convert(err).Message())
+		})
+	}
+
+	require.NoError(t, statusError(nil))
+}
+
+func TestHTTPStatus(t *testing.T) {
+	require.Equal(t, http.StatusOK, httpStatus(nil))
+	require.Equal(t, http.StatusTooManyRequests, httpStatus(status.Error(codes.ResourceExhausted, "")))
+	require.Equal(t, http.StatusInternalServerError, httpStatus(status.Error(codes.Internal, "")))
+}
@@ -0,0 +1,130 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/trustbloc/edge-service/pkg/apikey"
+	"github.com/trustbloc/edge-service/pkg/internal/common/support"
+)
+
+const grpcMethod = "GRPC"
+
+type correlationIDContextKey struct{}
+
+// profileRequest is a request of an issuance or verification call, rate limited per profile
+type profileRequest interface {
+	GetProfileId() string // nolint: golint,stylecheck
+}
+
+// interceptor applies to the gRPC calls what the router and the handlers apply to the REST requests: the
+// correlation ID, the bearer token and API key authentication, the rate limits, the metrics and the request logs.
+type interceptor struct {
+	config *Config
+}
+
+func (i *interceptor) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	correlationID := firstValue(md, support.CorrelationIDHeader)
+	if correlationID == "" {
+		correlationID = uuid.New().String()
+	}
+
+	// the correlation ID is only informative, the call isn't failed if its header can't be sent
+	_ = grpc.SetHeader(ctx, metadata.Pairs(support.CorrelationIDHeader, correlationID))
+
+	ctx = context.WithValue(ctx, correlationIDContextKey{}, correlationID)
+
+	resp, err := i.authorize(ctx, md, req, handler)
+	err = statusError(err)
+
+	var profileID string
+	if profileReq, ok := req.(profileRequest); ok {
+		profileID = profileReq.GetProfileId()
+	}
+
+	support.ObserveRequest(&support.HandledRequest{Endpoint: info.FullMethod, Method: grpcMethod,
+		Path: info.FullMethod, Profile: profileID, CorrelationID: correlationID, Status: httpStatus(err),
+		Duration: time.Since(start)})
+
+	return resp, err
+}
+
+// authorize authenticates the call by its bearer token and API key, then rate limits it
+func (i *interceptor) authorize(ctx context.Context, md metadata.MD, req interface{},
+	handler grpc.UnaryHandler) (interface{}, error) {
+	if i.config.Token != "" {
+		authorization := firstValue(md, "Authorization")
+		if subtle.ConstantTimeCompare([]byte(authorization), []byte("Bearer "+i.config.Token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid authorization token")
+		}
+	}
+
+	if key := firstValue(md, apikey.Header); key != "" && i.config.APIKeys != nil {
+		client, ok := i.config.APIKeys.Authenticate(key)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "invalid API key")
+		}
+
+		ctx = apikey.NewContext(ctx, client)
+	}
+
+	if err := i.rateLimit(ctx, req); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// rateLimit takes a token of the bucket of the issuance and verification calls, as the REST rate limit
+func (i *interceptor) rateLimit(ctx context.Context, req interface{}) error {
+	profileReq, ok := req.(profileRequest)
+	if !ok || i.config.RateLimit == nil || i.config.RateLimit.Limiter == nil {
+		return nil
+	}
+
+	allowed, retryAfter := i.config.RateLimit.Limiter.Allow(
+		i.config.RateLimit.BucketKey(profileReq.GetProfileId(), apikey.FromContext(ctx)))
+	if allowed {
+		return nil
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))))
+
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %s", retryAfter)
+}
+
+// correlationID returns the correlation ID of the call
+func correlationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDContextKey{}).(string)
+
+	return id
+}
+
+func firstValue(md metadata.MD, key string) string {
+	values := md.Get(strings.ToLower(key))
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
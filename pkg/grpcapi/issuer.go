@@ -0,0 +1,178 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/trustbloc/edge-service/pkg/apikey"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	"github.com/trustbloc/edge-service/pkg/grpcapi/vcspb"
+	issuerops "github.com/trustbloc/edge-service/pkg/restapi/issuer/operation"
+)
+
+// IssuerService is the issuer logic shared with the REST API (see the issuer operation).
+type IssuerService interface {
+	CreateProfile(data *issuerops.ProfileRequest) (*profile.DataProfile, error)
+	GetProfile(id string) (*profile.DataProfile, error)
+	IssueCredential(profileID string, cred *issuerops.IssueCredentialRequest) (*verifiable.Credential, error)
+	UpdateCredentialStatus(data *issuerops.UpdateCredentialStatusRequest, client, correlationID string) error
+	GetCredentialStatus(id string) ([]byte, error)
+}
+
+type issuerServer struct {
+	service IssuerService
+}
+
+// NewIssuerServer returns the issuer gRPC service calling the issuer logic
+func NewIssuerServer(service IssuerService) vcspb.IssuerServer {
+	return &issuerServer{service: service}
+}
+
+func (s *issuerServer) CreateProfile(_ context.Context,
+	req *vcspb.CreateIssuerProfileRequest) (*vcspb.IssuerProfile, error) {
+	dataProfile, err := s.service.CreateProfile(&issuerops.ProfileRequest{
+		Name:                    req.Name,
+		URI:                     req.Uri,
+		SignatureType:           req.SignatureType,
+		SignatureRepresentation: verifiable.SignatureRepresentation(req.SignatureRepresentation),
+		DID:                     req.Did,
+		DIDPrivateKey:           req.DidPrivateKey,
+		DIDKeyType:              req.DidKeyType,
+		DIDKeyID:                req.DidKeyId,
+		DisableVCStatus:         req.DisableVcStatus,
+		OverwriteIssuer:         req.OverwriteIssuer,
+		CredentialStorage:       req.CredentialStorage,
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return toIssuerProfile(dataProfile), nil
+}
+
+func (s *issuerServer) GetProfile(_ context.Context, req *vcspb.GetProfileRequest) (*vcspb.IssuerProfile, error) {
+	dataProfile, err := s.service.GetProfile(req.Id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return toIssuerProfile(dataProfile), nil
+}
+
+func (s *issuerServer) IssueCredential(_ context.Context,
+	req *vcspb.IssueCredentialRequest) (*vcspb.CredentialResponse, error) {
+	issueReq := &issuerops.IssueCredentialRequest{Credential: rawJSON(req.Credential)}
+
+	if req.Options != nil {
+		issueReq.Opts = &issuerops.IssueCredentialOptions{
+			VerificationMethod: req.Options.VerificationMethod,
+			AssertionMethod:    req.Options.AssertionMethod,
+			ProofPurpose:       req.Options.ProofPurpose,
+			Challenge:          req.Options.Challenge,
+			Domain:             req.Options.Domain,
+		}
+
+		if req.Options.Created != "" {
+			created, err := time.Parse(time.RFC3339, req.Options.Created)
+			if err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid created date: %s", err.Error())
+			}
+
+			issueReq.Opts.Created = &created
+		}
+	}
+
+	credential, err := s.service.IssueCredential(req.ProfileId, issueReq)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	credentialBytes, err := credential.MarshalJSON()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to marshal credential: %s", err.Error())
+	}
+
+	return &vcspb.CredentialResponse{Credential: credentialBytes}, nil
+}
+
+func (s *issuerServer) UpdateCredentialStatus(ctx context.Context,
+	req *vcspb.UpdateCredentialStatusRequest) (*vcspb.UpdateCredentialStatusResponse, error) {
+	err := s.service.UpdateCredentialStatus(&issuerops.UpdateCredentialStatusRequest{
+		Credential:   string(req.Credential),
+		Status:       req.Status,
+		StatusReason: req.StatusReason,
+	}, apikey.FromContext(ctx), correlationID(ctx))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &vcspb.UpdateCredentialStatusResponse{}, nil
+}
+
+func (s *issuerServer) GetCredentialStatus(_ context.Context,
+	req *vcspb.GetCredentialStatusRequest) (*vcspb.CredentialStatusList, error) {
+	cslBytes, err := s.service.GetCredentialStatus(req.Id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	// the list is stored as its status list credential, or as is for the lists created before the signed lists
+	statusList := &csl.CSL{}
+
+	if err := json.Unmarshal(cslBytes, statusList); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to unmarshal credential status list: %s", err.Error())
+	}
+
+	resp := &vcspb.CredentialStatusList{Id: statusList.ID, Description: statusList.Description}
+
+	for _, vc := range statusList.VC {
+		resp.VerifiableCredentials = append(resp.VerifiableCredentials, []byte(vc))
+	}
+
+	return resp, nil
+}
+
+func toIssuerProfile(dataProfile *profile.DataProfile) *vcspb.IssuerProfile {
+	issuerProfile := &vcspb.IssuerProfile{
+		Name:                    dataProfile.Name,
+		Did:                     dataProfile.DID,
+		Uri:                     dataProfile.URI,
+		SignatureType:           dataProfile.SignatureType,
+		SignatureRepresentation: int32(dataProfile.SignatureRepresentation),
+		Creator:                 dataProfile.Creator,
+		DisableVcStatus:         dataProfile.DisableVCStatus,
+		OverwriteIssuer:         dataProfile.OverwriteIssuer,
+		CredentialStorage:       dataProfile.CredentialStorage,
+	}
+
+	if dataProfile.Created != nil {
+		issuerProfile.Created = dataProfile.Created.Format(time.RFC3339Nano)
+	}
+
+	for _, key := range dataProfile.SigningKeys {
+		issuerProfile.SigningKeys = append(issuerProfile.SigningKeys,
+			&vcspb.SigningKey{Id: key.ID, SignatureType: key.SignatureType})
+	}
+
+	return issuerProfile
+}
+
+// rawJSON keeps the missing documents of the requests missing, the operations report them as such
+func rawJSON(document []byte) json.RawMessage {
+	if len(document) == 0 {
+		return nil
+	}
+
+	return document
+}
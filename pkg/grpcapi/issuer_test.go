@@ -0,0 +1,173 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/trustbloc/edge-service/pkg/apikey"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/grpcapi/vcspb"
+	issuerops "github.com/trustbloc/edge-service/pkg/restapi/issuer/operation"
+)
+
+const testCredential = `{"@context":["https://www.w3.org/2018/credentials/v1"],` +
+	`"id":"http://example.edu/credentials/1872","type":["VerifiableCredential","UniversityDegreeCredential"],` +
+	`"credentialSubject":{"id":"did:example:ebfeb1f712ebc6f1c276e12ec21"},` +
+	`"issuer":"did:example:76e12ec712ebc6f1c221ebfeb1f","issuanceDate":"2010-01-01T19:23:24Z"}`
+
+func TestIssuerServer(t *testing.T) {
+	service := &mockIssuerService{}
+	server := NewIssuerServer(service)
+	ctx := context.Background()
+
+	t.Run("test create and get profile", func(t *testing.T) {
+		issuerProfile, err := server.CreateProfile(ctx, &vcspb.CreateIssuerProfileRequest{
+			Name: "issuer", DidKeyId: "did:example:123#key1", DisableVcStatus: true})
+		require.NoError(t, err)
+		require.Equal(t, "did:example:123", issuerProfile.Did)
+		require.Equal(t, "2020-04-01T10:00:00Z", issuerProfile.Created)
+		require.Equal(t, "did:example:123#key2", issuerProfile.SigningKeys[0].Id)
+		require.Equal(t, "did:example:123#key1", service.profileReq.DIDKeyID)
+		require.True(t, service.profileReq.DisableVCStatus)
+
+		issuerProfile, err = server.GetProfile(ctx, &vcspb.GetProfileRequest{Id: "issuer"})
+		require.NoError(t, err)
+		require.Equal(t, "issuer", issuerProfile.Name)
+
+		_, err = server.GetProfile(ctx, &vcspb.GetProfileRequest{Id: "unknown"})
+		require.Equal(t, codes.NotFound, status.Code(err))
+		require.Equal(t, "Failed to find the profile", status.Convert(err).Message())
+	})
+
+	t.Run("test issue credential", func(t *testing.T) {
+		resp, err := server.IssueCredential(ctx, &vcspb.IssueCredentialRequest{ProfileId: "issuer",
+			Credential: []byte(testCredential),
+			Options:    &vcspb.IssueCredentialOptions{ProofPurpose: "assertionMethod", Created: "2020-04-01T10:00:00Z"}})
+		require.NoError(t, err)
+		require.JSONEq(t, testCredential, string(resp.Credential))
+		require.Equal(t, "assertionMethod", service.issueReq.Opts.ProofPurpose)
+		require.Equal(t, "2020-04-01T10:00:00Z", service.issueReq.Opts.Created.Format(time.RFC3339))
+
+		_, err = server.IssueCredential(ctx, &vcspb.IssueCredentialRequest{ProfileId: "issuer",
+			Credential: []byte(testCredential), Options: &vcspb.IssueCredentialOptions{Created: "yesterday"}})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.Contains(t, err.Error(), "invalid created date")
+
+		_, err = server.IssueCredential(ctx, &vcspb.IssueCredentialRequest{ProfileId: "issuer",
+			Credential: []byte("{")})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.Contains(t, err.Error(), "failed to validate credential")
+	})
+
+	t.Run("test credential status", func(t *testing.T) {
+		_, err := server.UpdateCredentialStatus(apikey.NewContext(
+			context.WithValue(ctx, correlationIDContextKey{}, "correlation-1"), "client1"),
+			&vcspb.UpdateCredentialStatusRequest{Credential: []byte(testCredential), Status: "Revoked"})
+		require.NoError(t, err)
+		require.Equal(t, "Revoked", service.statusReq.Status)
+		require.Equal(t, "client1", service.client)
+		require.Equal(t, "correlation-1", service.correlationID)
+
+		statusList, err := server.GetCredentialStatus(ctx, &vcspb.GetCredentialStatusRequest{Id: "issuer/1"})
+		require.NoError(t, err)
+		require.Equal(t, "https://vcs.example.com/status/issuer/1", statusList.Id)
+		require.Equal(t, [][]byte{[]byte("{}")}, statusList.VerifiableCredentials)
+
+		_, err = server.GetCredentialStatus(ctx, &vcspb.GetCredentialStatusRequest{Id: "invalid"})
+		require.Equal(t, codes.Internal, status.Code(err))
+		require.Contains(t, err.Error(), "failed to unmarshal credential status list")
+
+		_, err = server.GetCredentialStatus(ctx, &vcspb.GetCredentialStatusRequest{Id: "unknown"})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+// mockIssuerService records the requests of the issuer server
+type mockIssuerService struct {
+	profileReq    *issuerops.ProfileRequest
+	issueReq      *issuerops.IssueCredentialRequest
+	statusReq     *issuerops.UpdateCredentialStatusRequest
+	client        string
+	correlationID string
+}
+
+func (m *mockIssuerService) CreateProfile(data *issuerops.ProfileRequest) (*profile.DataProfile, error) {
+	m.profileReq = data
+
+	created := time.Date(2020, 4, 1, 10, 0, 0, 0, time.UTC)
+
+	return &profile.DataProfile{Name: data.Name, DID: "did:example:123", Created: &created,
+		SigningKeys: []profile.SigningKey{{ID: "did:example:123#key2", SignatureType: "Ed25519Signature2018"}}}, nil
+}
+
+func (m *mockIssuerService) GetProfile(id string) (*profile.DataProfile, error) {
+	if id != "issuer" {
+		return nil, &testOperationError{status: http.StatusNotFound, code: "PROFILE_NOT_FOUND",
+			message: "Failed to find the profile"}
+	}
+
+	return &profile.DataProfile{Name: id}, nil
+}
+
+func (m *mockIssuerService) IssueCredential(_ string,
+	cred *issuerops.IssueCredentialRequest) (*verifiable.Credential, error) {
+	m.issueReq = cred
+
+	credential, err := verifiable.ParseCredential(cred.Credential, verifiable.WithDisabledProofCheck())
+	if err != nil {
+		return nil, &testOperationError{status: http.StatusBadRequest, code: "INVALID_CREDENTIAL",
+			message: "failed to validate credential: " + err.Error()}
+	}
+
+	return credential, nil
+}
+
+func (m *mockIssuerService) UpdateCredentialStatus(data *issuerops.UpdateCredentialStatusRequest,
+	client, correlationID string) error {
+	m.statusReq, m.client, m.correlationID = data, client, correlationID
+
+	return nil
+}
+
+func (m *mockIssuerService) GetCredentialStatus(id string) ([]byte, error) {
+	switch id {
+	case "invalid":
+		return []byte("{"), nil
+	case "unknown":
+		return nil, &testOperationError{status: http.StatusBadRequest, code: "NOT_FOUND",
+			message: "failed to get credential status list"}
+	default:
+		return []byte(`{"id":"https://vcs.example.com/status/` + id + `","verifiableCredential":["{}"]}`), nil
+	}
+}
+
+// testOperationError is an operation error of the REST API
+type testOperationError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *testOperationError) Error() string {
+	return e.message
+}
+
+func (e *testOperationError) StatusCode() int {
+	return e.status
+}
+
+func (e *testOperationError) ErrorCode() string {
+	return e.code
+}
@@ -0,0 +1,54 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpcapi
+
+import (
+	"crypto/tls"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/trustbloc/edge-service/pkg/apikey"
+	"github.com/trustbloc/edge-service/pkg/grpcapi/vcspb"
+	"github.com/trustbloc/edge-service/pkg/ratelimit"
+)
+
+// Config is the configuration of the gRPC server.
+type Config struct {
+	// Issuer and Verifier are the services of the mode, nil if not served
+	Issuer   IssuerService
+	Verifier VerifierService
+	// Token is the bearer token of the calls (authorization metadata), if set
+	Token string
+	// APIKeys authenticates the clients by their API key (x-api-key metadata), if set
+	APIKeys *apikey.Authenticator
+	// RateLimit limits the issuance and verification calls, if set
+	RateLimit *ratelimit.Config
+	// TLSConfig is the TLS configuration of the server, the calls are served in plaintext if nil
+	TLSConfig *tls.Config
+}
+
+// NewServer returns a gRPC server with the issuer and/or verifier services. The calls are authenticated, rate
+// limited, logged and recorded in the metrics as the REST requests.
+func NewServer(config *Config, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.UnaryInterceptor((&interceptor{config: config}).intercept))
+
+	if config.TLSConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config.TLSConfig)))
+	}
+
+	grpcServer := grpc.NewServer(opts...)
+
+	if config.Issuer != nil {
+		vcspb.RegisterIssuerServer(grpcServer, NewIssuerServer(config.Issuer))
+	}
+
+	if config.Verifier != nil {
+		vcspb.RegisterVerifierServer(grpcServer, NewVerifierServer(config.Verifier))
+	}
+
+	return grpcServer
+}
@@ -0,0 +1,183 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpcapi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/trustbloc/edge-service/pkg/apikey"
+	"github.com/trustbloc/edge-service/pkg/grpcapi/vcspb"
+	"github.com/trustbloc/edge-service/pkg/ratelimit"
+)
+
+func TestNewServer(t *testing.T) {
+	limiter := &mockLimiter{}
+
+	conn := serve(t, &Config{Issuer: &mockIssuerService{}, Token: "token",
+		APIKeys:   apikey.New(map[string]string{"client1": "key1"}),
+		RateLimit: &ratelimit.Config{Limiter: limiter, Key: ratelimit.KeyAPIKey}}, grpc.WithInsecure())
+
+	issuer := vcspb.NewIssuerClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer token")
+
+	t.Run("test authorization", func(t *testing.T) {
+		var header metadata.MD
+
+		issuerProfile, err := issuer.GetProfile(metadata.AppendToOutgoingContext(ctx, "x-correlation-id", "c1"),
+			&vcspb.GetProfileRequest{Id: "issuer"}, grpc.Header(&header))
+		require.NoError(t, err)
+		require.Equal(t, "issuer", issuerProfile.Name)
+		require.Equal(t, []string{"c1"}, header.Get("x-correlation-id"))
+
+		_, err = issuer.GetProfile(context.Background(), &vcspb.GetProfileRequest{Id: "issuer"})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+		require.Equal(t, "invalid authorization token", status.Convert(err).Message())
+
+		_, err = issuer.GetProfile(metadata.AppendToOutgoingContext(ctx, "x-api-key", "key2"),
+			&vcspb.GetProfileRequest{Id: "issuer"})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+		require.Equal(t, "invalid API key", status.Convert(err).Message())
+	})
+
+	t.Run("test rate limit", func(t *testing.T) {
+		issueReq := &vcspb.IssueCredentialRequest{ProfileId: "issuer1", Credential: []byte(testCredential)}
+
+		_, err := issuer.IssueCredential(ctx, issueReq)
+		require.NoError(t, err)
+		require.Equal(t, "profile:issuer1", limiter.key)
+
+		_, err = issuer.IssueCredential(metadata.AppendToOutgoingContext(ctx, "x-api-key", "key1"), issueReq)
+		require.NoError(t, err)
+		require.Equal(t, "client:client1", limiter.key)
+
+		limiter.denied = true
+
+		var header metadata.MD
+
+		_, err = issuer.IssueCredential(ctx, issueReq, grpc.Header(&header))
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		require.Equal(t, "rate limit exceeded, retry after 1.5s", status.Convert(err).Message())
+		require.Equal(t, []string{"2"}, header.Get("retry-after"))
+
+		// the profile calls are not rate limited
+		_, err = issuer.GetProfile(ctx, &vcspb.GetProfileRequest{Id: "issuer"})
+		require.NoError(t, err)
+	})
+
+	t.Run("test service not registered", func(t *testing.T) {
+		_, err := vcspb.NewVerifierClient(conn).GetProfile(ctx, &vcspb.GetProfileRequest{Id: "verifier"})
+		require.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+}
+
+func TestNewServer_TLS(t *testing.T) {
+	cert, rootCAs := selfSignedCertificate(t)
+
+	conn := serve(t, &Config{Verifier: &mockVerifierService{},
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}}},
+		grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{RootCAs: rootCAs, ServerName: "localhost"})))
+
+	_, err := vcspb.NewVerifierClient(conn).CreateProfile(context.Background(), &vcspb.VerifierProfile{Id: "verifier"})
+	require.NoError(t, err)
+
+	// the plaintext calls are rejected
+	insecureConn := serve(t, &Config{Verifier: &mockVerifierService{},
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}}}, grpc.WithInsecure())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err = vcspb.NewVerifierClient(insecureConn).CreateProfile(ctx, &vcspb.VerifierProfile{Id: "verifier"})
+	require.Error(t, err)
+}
+
+// serve serves the gRPC server of the config over an in-memory listener and returns the client connection
+func serve(t *testing.T, config *Config, dialOpts ...grpc.DialOption) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+
+	grpcServer := NewServer(config)
+	t.Cleanup(grpcServer.Stop)
+
+	go func() {
+		require.NoError(t, grpcServer.Serve(listener))
+	}()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet", append(dialOpts,
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}))...)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		require.NoError(t, conn.Close())
+	})
+
+	return conn
+}
+
+func selfSignedCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(leaf)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, rootCAs
+}
+
+// mockLimiter records the key of the last call, and denies the calls if set
+type mockLimiter struct {
+	key    string
+	denied bool
+}
+
+func (m *mockLimiter) Allow(key string) (bool, time.Duration) {
+	m.key = key
+
+	if m.denied {
+		return false, 1500 * time.Millisecond
+	}
+
+	return true, 0
+}
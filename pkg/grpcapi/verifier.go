@@ -0,0 +1,135 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpcapi
+
+import (
+	"context"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	"github.com/trustbloc/edge-service/pkg/grpcapi/vcspb"
+	verifierops "github.com/trustbloc/edge-service/pkg/restapi/verifier/operation"
+)
+
+// VerifierService is the verifier logic shared with the REST API (see the verifier operation).
+type VerifierService interface {
+	CreateProfile(request *verifier.ProfileData) error
+	GetProfile(id string) (*verifier.ProfileData, error)
+	VerifyCredential(profileID string, verificationReq *verifierops.CredentialsVerificationRequest) ([]string,
+		[]verifierops.CredentialsVerificationCheckResult, error)
+	VerifyPresentation(profileID string, verificationReq *verifierops.VerifyPresentationRequest) ([]string,
+		[]verifierops.VerifyPresentationCheckResult, error)
+}
+
+type verifierServer struct {
+	service VerifierService
+}
+
+// NewVerifierServer returns the verifier gRPC service calling the verifier logic
+func NewVerifierServer(service VerifierService) vcspb.VerifierServer {
+	return &verifierServer{service: service}
+}
+
+func (s *verifierServer) CreateProfile(_ context.Context,
+	req *vcspb.VerifierProfile) (*vcspb.VerifierProfile, error) {
+	profileData := &verifier.ProfileData{
+		ID:                 req.Id,
+		Name:               req.Name,
+		CredentialChecks:   req.CredentialChecks,
+		PresentationChecks: req.PresentationChecks,
+	}
+
+	if err := s.service.CreateProfile(profileData); err != nil {
+		return nil, statusError(err)
+	}
+
+	return toVerifierProfile(profileData), nil
+}
+
+func (s *verifierServer) GetProfile(_ context.Context, req *vcspb.GetProfileRequest) (*vcspb.VerifierProfile, error) {
+	profileData, err := s.service.GetProfile(req.Id)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return toVerifierProfile(profileData), nil
+}
+
+// VerifyCredential verifies the credential, the failed checks are part of the response and not an error of the call
+func (s *verifierServer) VerifyCredential(_ context.Context,
+	req *vcspb.VerifyRequest) (*vcspb.VerifyResponse, error) {
+	verifyReq := &verifierops.CredentialsVerificationRequest{Credential: rawJSON(req.Document)}
+
+	if req.Options != nil {
+		verifyReq.Opts = &verifierops.CredentialsVerificationOptions{
+			Domain:    req.Options.Domain,
+			Challenge: req.Options.Challenge,
+			Checks:    req.Options.Checks,
+		}
+	}
+
+	checks, failedChecks, err := s.service.VerifyCredential(req.ProfileId, verifyReq)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return toVerifyResponse(checks, failedChecks), nil
+}
+
+// VerifyPresentation verifies the presentation, the failed checks are part of the response and not an error of
+// the call
+func (s *verifierServer) VerifyPresentation(_ context.Context,
+	req *vcspb.VerifyRequest) (*vcspb.VerifyResponse, error) {
+	verifyReq := &verifierops.VerifyPresentationRequest{Presentation: rawJSON(req.Document)}
+
+	if req.Options != nil {
+		verifyReq.Opts = &verifierops.VerifyPresentationOptions{
+			Domain:    req.Options.Domain,
+			Challenge: req.Options.Challenge,
+			Checks:    req.Options.Checks,
+		}
+	}
+
+	checks, failedChecks, err := s.service.VerifyPresentation(req.ProfileId, verifyReq)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	results := make([]verifierops.CredentialsVerificationCheckResult, len(failedChecks))
+	for i, check := range failedChecks {
+		results[i] = verifierops.CredentialsVerificationCheckResult(check)
+	}
+
+	return toVerifyResponse(checks, results), nil
+}
+
+// toVerifyResponse returns the verified response of the checks, or the response of the failed checks
+func toVerifyResponse(checks []string,
+	failedChecks []verifierops.CredentialsVerificationCheckResult) *vcspb.VerifyResponse {
+	if len(failedChecks) == 0 {
+		return &vcspb.VerifyResponse{Verified: true, Checks: checks}
+	}
+
+	resp := &vcspb.VerifyResponse{}
+
+	for _, check := range failedChecks {
+		resp.FailedChecks = append(resp.FailedChecks, &vcspb.CheckResult{
+			Check:              check.Check,
+			Error:              check.Error,
+			VerificationMethod: check.VerificationMethod,
+		})
+	}
+
+	return resp
+}
+
+func toVerifierProfile(profileData *verifier.ProfileData) *vcspb.VerifierProfile {
+	return &vcspb.VerifierProfile{
+		Id:                 profileData.ID,
+		Name:               profileData.Name,
+		CredentialChecks:   profileData.CredentialChecks,
+		PresentationChecks: profileData.PresentationChecks,
+	}
+}
@@ -0,0 +1,143 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	"github.com/trustbloc/edge-service/pkg/grpcapi/vcspb"
+	verifierops "github.com/trustbloc/edge-service/pkg/restapi/verifier/operation"
+)
+
+func TestVerifierServer(t *testing.T) {
+	service := &mockVerifierService{}
+	server := NewVerifierServer(service)
+	ctx := context.Background()
+
+	t.Run("test create and get profile", func(t *testing.T) {
+		verifierProfile, err := server.CreateProfile(ctx, &vcspb.VerifierProfile{Id: "verifier", Name: "verifier",
+			CredentialChecks: []string{"proof"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"proof"}, verifierProfile.CredentialChecks)
+		require.Equal(t, "verifier", service.profile.Name)
+
+		_, err = server.CreateProfile(ctx, &vcspb.VerifierProfile{Id: "verifier"})
+		require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+		verifierProfile, err = server.GetProfile(ctx, &vcspb.GetProfileRequest{Id: "verifier"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"proof"}, verifierProfile.CredentialChecks)
+
+		_, err = server.GetProfile(ctx, &vcspb.GetProfileRequest{Id: "unknown"})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("test verify credential", func(t *testing.T) {
+		resp, err := server.VerifyCredential(ctx, &vcspb.VerifyRequest{ProfileId: "verifier",
+			Document: []byte(testCredential), Options: &vcspb.VerifyOptions{Checks: []string{"proof"}}})
+		require.NoError(t, err)
+		require.True(t, resp.Verified)
+		require.Equal(t, []string{"proof"}, resp.Checks)
+		require.Equal(t, []string{"proof"}, service.credentialReq.Opts.Checks)
+
+		resp, err = server.VerifyCredential(ctx, &vcspb.VerifyRequest{ProfileId: "revoked",
+			Document: []byte(testCredential)})
+		require.NoError(t, err)
+		require.False(t, resp.Verified)
+		require.Equal(t, "Revoked", resp.FailedChecks[0].Error)
+
+		_, err = server.VerifyCredential(ctx, &vcspb.VerifyRequest{ProfileId: "unknown",
+			Document: []byte(testCredential)})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("test verify presentation", func(t *testing.T) {
+		resp, err := server.VerifyPresentation(ctx, &vcspb.VerifyRequest{ProfileId: "verifier",
+			Document: []byte(`{}`), Options: &vcspb.VerifyOptions{Challenge: "c1", Domain: "d1"}})
+		require.NoError(t, err)
+		require.True(t, resp.Verified)
+		require.Equal(t, "c1", service.presentationReq.Opts.Challenge)
+		require.Equal(t, "d1", service.presentationReq.Opts.Domain)
+
+		resp, err = server.VerifyPresentation(ctx, &vcspb.VerifyRequest{ProfileId: "revoked",
+			Document: []byte(`{}`)})
+		require.NoError(t, err)
+		require.False(t, resp.Verified)
+		require.Equal(t, "proof", resp.FailedChecks[0].Check)
+
+		_, err = server.VerifyPresentation(ctx, &vcspb.VerifyRequest{ProfileId: "unknown", Document: []byte(`{}`)})
+		require.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+// mockVerifierService records the requests of the verifier server
+type mockVerifierService struct {
+	profile         *verifier.ProfileData
+	credentialReq   *verifierops.CredentialsVerificationRequest
+	presentationReq *verifierops.VerifyPresentationRequest
+}
+
+func profileNotFoundError() error {
+	return &testOperationError{status: http.StatusBadRequest, code: "PROFILE_NOT_FOUND", message: "profile not found"}
+}
+
+func (m *mockVerifierService) CreateProfile(request *verifier.ProfileData) error {
+	if m.profile != nil {
+		return &testOperationError{status: http.StatusBadRequest, code: "ALREADY_EXISTS",
+			message: "profile verifier already exists"}
+	}
+
+	m.profile = request
+
+	return nil
+}
+
+func (m *mockVerifierService) GetProfile(id string) (*verifier.ProfileData, error) {
+	if m.profile == nil || id != m.profile.ID {
+		return nil, profileNotFoundError()
+	}
+
+	return m.profile, nil
+}
+
+func (m *mockVerifierService) VerifyCredential(profileID string,
+	verificationReq *verifierops.CredentialsVerificationRequest) ([]string,
+	[]verifierops.CredentialsVerificationCheckResult, error) {
+	m.credentialReq = verificationReq
+
+	switch profileID {
+	case "verifier":
+		return []string{"proof"}, nil, nil
+	case "revoked":
+		return []string{"status"}, []verifierops.CredentialsVerificationCheckResult{
+			{Check: "status", Error: "Revoked"}}, nil
+	default:
+		return nil, nil, profileNotFoundError()
+	}
+}
+
+func (m *mockVerifierService) VerifyPresentation(profileID string,
+	verificationReq *verifierops.VerifyPresentationRequest) ([]string,
+	[]verifierops.VerifyPresentationCheckResult, error) {
+	m.presentationReq = verificationReq
+
+	switch profileID {
+	case "verifier":
+		return []string{"proof"}, nil, nil
+	case "revoked":
+		return []string{"proof"}, []verifierops.VerifyPresentationCheckResult{
+			{Check: "proof", Error: "invalid proof"}}, nil
+	default:
+		return nil, nil, profileNotFoundError()
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpclient
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/piprate/json-gold/ld"
+	"github.com/trustbloc/edge-core/pkg/log"
+)
+
+const (
+	keepAlive           = 30 * time.Second
+	idleConnTimeout     = 90 * time.Second
+	maxIdleConns        = 100
+	maxIdleConnsPerHost = 10
+)
+
+var logger = log.New("edge-service-httpclient")
+
+// the JSON-LD contexts preloaded by the document loaders, not fetched
+var preloadedContexts = []string{"https://www.w3.org/2018/credentials/v1"}
+
+// Option configures the HTTP clients of the factory
+type Option func(f *Factory)
+
+// WithConnectTimeout option sets the time to establish the connection to the server, including the TLS handshake
+func WithConnectTimeout(timeout time.Duration) Option {
+	return func(f *Factory) {
+		f.connectTimeout = timeout
+	}
+}
+
+// WithReadTimeout option sets the time to wait for the response headers of the server once the request is sent
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(f *Factory) {
+		f.readTimeout = timeout
+	}
+}
+
+// WithProxyURL option sets the proxy of the requests, instead of the proxy of the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables
+func WithProxyURL(proxyURL *url.URL) Option {
+	return func(f *Factory) {
+		f.proxyURL = proxyURL
+	}
+}
+
+// WithTLSConfig option sets the TLS configuration of the requests, e.g. the CA certificates of the servers
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(f *Factory) {
+		f.tlsConfig = tlsConfig
+	}
+}
+
+// Factory creates the HTTP clients of the outbound calls of the service, sharing the timeouts, the proxy and the
+// TLS configuration of the service. The clients share the same transport, and so its connections.
+type Factory struct {
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+	proxyURL       *url.URL
+	tlsConfig      *tls.Config
+	transport      *http.Transport
+}
+
+// New returns the factory of the HTTP clients, without timeouts and proxied as set by the environment by default
+func New(opts ...Option) *Factory {
+	f := &Factory{}
+
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	f.transport = f.newTransport()
+
+	return f
+}
+
+// WithTLS returns a factory of the timeouts and the proxy of f with another TLS configuration, for the outbound
+// dependencies in another trust domain than the service. The clients of the factories don't share their connections.
+func (f *Factory) WithTLS(tlsConfig *tls.Config) *Factory {
+	tf := &Factory{
+		connectTimeout: f.connectTimeout,
+		readTimeout:    f.readTimeout,
+		proxyURL:       f.proxyURL,
+		tlsConfig:      tlsConfig,
+	}
+
+	tf.transport = tf.newTransport()
+
+	return tf
+}
+
+func (f *Factory) newTransport() *http.Transport {
+	proxy := http.ProxyFromEnvironment
+	if f.proxyURL != nil {
+		proxy = http.ProxyURL(f.proxyURL)
+	}
+
+	dialer := &net.Dialer{Timeout: f.connectTimeout, KeepAlive: keepAlive}
+
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       f.tlsConfig,
+		TLSHandshakeTimeout:   f.connectTimeout,
+		ResponseHeaderTimeout: f.readTimeout,
+		IdleConnTimeout:       idleConnTimeout,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+	}
+}
+
+// Client returns an HTTP client of the factory
+func (f *Factory) Client() *http.Client {
+	return &http.Client{Transport: f.transport}
+}
+
+// TLSConfig returns the TLS configuration of the requests, for the clients only configurable with a TLS
+// configuration
+func (f *Factory) TLSConfig() *tls.Config {
+	return f.tlsConfig
+}
+
+// Timeout returns the time a request may take until its response headers are received, for the clients only
+// configurable with a timeout (0 if not bounded)
+func (f *Factory) Timeout() time.Duration {
+	if f.connectTimeout <= 0 || f.readTimeout <= 0 {
+		return 0
+	}
+
+	return f.connectTimeout + f.readTimeout
+}
+
+// DocumentLoader returns a caching JSON-LD document loader fetching the contexts with a client of the factory, the
+// base context of the credentials being preloaded
+func (f *Factory) DocumentLoader() ld.DocumentLoader {
+	loader := ld.NewCachingDocumentLoader(ld.NewRFC7324CachingDocumentLoader(f.Client()))
+
+	preloaded := verifiable.CachingJSONLDLoader()
+
+	for _, u := range preloadedContexts {
+		doc, err := preloaded.LoadDocument(u)
+		if err != nil {
+			logger.Warnf("failed to preload JSON-LD context %s: %s", u, err)
+
+			continue
+		}
+
+		loader.AddDocument(u, doc.Document)
+	}
+
+	return loader
+}
@@ -0,0 +1,148 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFactory_Client(t *testing.T) {
+	t.Run("test CA certificates", func(t *testing.T) {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}))
+		defer srv.Close()
+
+		resp, err := New().Client().Get(srv.URL) // nolint: bodyclose
+		require.Error(t, err)
+		require.Nil(t, resp)
+
+		rootCAs := x509.NewCertPool()
+		rootCAs.AddCert(srv.Certificate())
+
+		f := New(WithTLSConfig(&tls.Config{RootCAs: rootCAs}))
+
+		resp, err = f.Client().Get(srv.URL)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NoError(t, resp.Body.Close())
+
+		require.Equal(t, rootCAs, f.TLSConfig().RootCAs)
+	})
+
+	t.Run("test read timeout", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer srv.Close()
+
+		resp, err := New(WithReadTimeout(50 * time.Millisecond)).Client().Get(srv.URL) // nolint: bodyclose
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "timeout awaiting response headers")
+		require.Nil(t, resp)
+	})
+
+	t.Run("test proxy", func(t *testing.T) {
+		var proxied *http.Request
+
+		proxy := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			proxied = req
+		}))
+		defer proxy.Close()
+
+		proxyURL, err := url.Parse(proxy.URL)
+		require.NoError(t, err)
+
+		resp, err := New(WithProxyURL(proxyURL)).Client().Get("http://edv.example.com/encrypted-data-vaults")
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+
+		require.NotNil(t, proxied)
+		require.Equal(t, "edv.example.com", proxied.Host)
+		require.Equal(t, "/encrypted-data-vaults", proxied.URL.Path)
+	})
+
+	t.Run("test shared transport", func(t *testing.T) {
+		f := New()
+
+		require.Equal(t, f.Client().Transport, f.Client().Transport)
+	})
+}
+
+func TestFactory_WithTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(srv.Certificate())
+
+	f := New(WithConnectTimeout(time.Second), WithReadTimeout(2*time.Second))
+	tf := f.WithTLS(&tls.Config{RootCAs: rootCAs})
+
+	resp, err := f.Client().Get(srv.URL) // nolint: bodyclose
+	require.Error(t, err)
+	require.Nil(t, resp)
+
+	resp, err = tf.Client().Get(srv.URL)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, resp.Body.Close())
+
+	require.Equal(t, rootCAs, tf.TLSConfig().RootCAs)
+	require.Nil(t, f.TLSConfig())
+	require.Equal(t, f.Timeout(), tf.Timeout())
+	require.NotEqual(t, f.Client().Transport, tf.Client().Transport)
+}
+
+func TestFactory_Timeout(t *testing.T) {
+	require.Zero(t, New().Timeout())
+	require.Zero(t, New(WithConnectTimeout(time.Second)).Timeout())
+	require.Equal(t, 3*time.Second, New(WithConnectTimeout(time.Second), WithReadTimeout(2*time.Second)).Timeout())
+}
+
+func TestFactory_DocumentLoader(t *testing.T) {
+	var requests int
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests++
+
+		rw.Header().Set("Content-Type", "application/ld+json")
+
+		_, err := rw.Write([]byte(`{"@context":{"name":"http://schema.org/name"}}`))
+		require.NoError(t, err)
+	}))
+	defer srv.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(srv.Certificate())
+
+	loader := New(WithTLSConfig(&tls.Config{RootCAs: rootCAs})).DocumentLoader()
+
+	t.Run("test preloaded context", func(t *testing.T) {
+		doc, err := loader.LoadDocument("https://www.w3.org/2018/credentials/v1")
+		require.NoError(t, err)
+		require.NotNil(t, doc.Document)
+		require.Zero(t, requests)
+	})
+
+	t.Run("test context fetched with the client of the factory", func(t *testing.T) {
+		doc, err := loader.LoadDocument(srv.URL + "/contexts/v1")
+		require.NoError(t, err)
+		require.NotNil(t, doc.Document)
+		require.Equal(t, 1, requests)
+	})
+}
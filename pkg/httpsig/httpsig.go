@@ -0,0 +1,286 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsig
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+
+	"github.com/trustbloc/edge-service/pkg/apikey"
+	"github.com/trustbloc/edge-service/pkg/clientcert"
+)
+
+const (
+	// SignatureInputHeader is the header of the covered components and parameters of the signature
+	SignatureInputHeader = "Signature-Input"
+	// SignatureHeader is the header of the signature
+	SignatureHeader = "Signature"
+	// ContentDigestHeader is the header of the digest of the body, covered by the signature of a request with a body
+	ContentDigestHeader = "Content-Digest"
+
+	// DefaultMaxAge is how long after its creation a signature is accepted
+	DefaultMaxAge = 5 * time.Minute
+
+	algEd25519               = "ed25519"
+	ed25519VerificationKey   = "Ed25519VerificationKey2018"
+	keyCacheTTL              = 5 * time.Minute
+	componentMethod          = "@method"
+	componentPath            = "@path"
+	componentTargetURI       = "@target-uri"
+	componentRequestTarget   = "@request-target"
+	componentContentDigest   = "content-digest"
+	signatureParamsComponent = "@signature-params"
+)
+
+type keyIDContextKey struct{}
+
+// Verifier authenticates the clients of the requests signed with HTTP Message Signatures (RFC 9421), the client
+// being the key ID of the signature: a verification method of the authentication of a DID, resolved with the VDRI.
+//
+// Only the Ed25519 signatures of Ed25519VerificationKey2018 keys are supported. The signature must cover the method
+// and the path of the request, and the content digest of its body if any, and be created within the max age.
+type Verifier struct {
+	// the profiles of the registered keys by key ID
+	permissions map[string]map[string]bool
+	vdri        vdriapi.Registry
+	maxAge      time.Duration
+	now         func() time.Time
+
+	keys  map[string]*cachedKey
+	mutex sync.Mutex
+}
+
+// cachedKey is a resolved public key, resolved again after expiresAt to follow the key rotations
+type cachedKey struct {
+	key       ed25519.PublicKey
+	expiresAt time.Time
+}
+
+// New returns a verifier of the signatures of the given keys, the profiles they may access being keyed by key ID.
+func New(permissions map[string][]string, vdri vdriapi.Registry, maxAge time.Duration) *Verifier {
+	v := &Verifier{
+		permissions: make(map[string]map[string]bool, len(permissions)),
+		vdri:        vdri,
+		maxAge:      maxAge,
+		now:         time.Now,
+		keys:        make(map[string]*cachedKey),
+	}
+
+	for keyID, profiles := range permissions {
+		v.permissions[keyID] = make(map[string]bool, len(profiles))
+
+		for _, profile := range profiles {
+			v.permissions[keyID][profile] = true
+		}
+	}
+
+	return v
+}
+
+// Middleware rejects the signed requests with an invalid signature with status 401, and the signed requests of a key
+// not allowed to access the profile of the request with status 403. The profile of a request is the profile of its
+// path, the requests not scoped to a profile requiring the clientcert.AllProfiles permission. The key ID is set as the
+// client of the allowed requests (see apikey.Client and KeyID). The requests without signature are passed on.
+//
+// The middleware must be used by the router, the profile being a route variable.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get(SignatureInputHeader) == "" && req.Header.Get(SignatureHeader) == "" {
+			next.ServeHTTP(rw, req)
+
+			return
+		}
+
+		keyID, err := v.Verify(req)
+		if err != nil {
+			http.Error(rw, fmt.Sprintf("invalid HTTP signature: %s", err.Error()), http.StatusUnauthorized)
+
+			return
+		}
+
+		if !v.Allowed(keyID, clientcert.RequestProfile(req)) {
+			http.Error(rw, "key not allowed to access the profile", http.StatusForbidden)
+
+			return
+		}
+
+		ctx := context.WithValue(apikey.NewContext(req.Context(), keyID), keyIDContextKey{}, keyID)
+
+		next.ServeHTTP(rw, req.WithContext(ctx))
+	})
+}
+
+// Allowed returns whether the key may access the profile, the profile being empty for a request not scoped to a
+// profile.
+func (v *Verifier) Allowed(keyID, profile string) bool {
+	profiles := v.permissions[keyID]
+
+	return profiles[clientcert.AllProfiles] || (profile != "" && profiles[profile])
+}
+
+// KeyID returns the key ID of the verified signature of the request, empty if the request isn't signed.
+func KeyID(req *http.Request) string {
+	keyID, _ := req.Context().Value(keyIDContextKey{}).(string) // nolint: errcheck
+
+	return keyID
+}
+
+// Verify verifies the signature of the request and returns its key ID. The body of the request is read to verify
+// its content digest, and replaced.
+func (v *Verifier) Verify(req *http.Request) (string, error) {
+	label, input, err := parseSignatureInput(req.Header.Get(SignatureInputHeader))
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := parseSignature(req.Header.Get(SignatureHeader), label)
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := v.permissions[input.keyID]; !ok {
+		return "", fmt.Errorf("unknown key %s", input.keyID)
+	}
+
+	if err = v.checkInput(input); err != nil {
+		return "", err
+	}
+
+	if err = checkContentDigest(req, input.covers(componentContentDigest)); err != nil {
+		return "", err
+	}
+
+	base, err := signatureBase(req, input)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := v.publicKey(input.keyID)
+	if err != nil {
+		return "", err
+	}
+
+	if !ed25519.Verify(key, base, signature) {
+		return "", errors.New("signature verification failed")
+	}
+
+	return input.keyID, nil
+}
+
+// checkInput checks the signature covers the method and path of the request, and is neither too old nor expired
+func (v *Verifier) checkInput(input *signatureInput) error {
+	if !input.covers(componentMethod) ||
+		!(input.covers(componentPath) || input.covers(componentTargetURI) || input.covers(componentRequestTarget)) {
+		return fmt.Errorf("the signature must cover %s and %s", componentMethod, componentPath)
+	}
+
+	if input.alg != "" && input.alg != algEd25519 {
+		return fmt.Errorf("unsupported algorithm %s", input.alg)
+	}
+
+	if input.created == nil {
+		return errors.New("missing created parameter")
+	}
+
+	now := v.now()
+	created := time.Unix(*input.created, 0)
+
+	if now.Sub(created) > v.maxAge || created.Sub(now) > v.maxAge {
+		return fmt.Errorf("the signature must be created within %s", v.maxAge)
+	}
+
+	if input.expires != nil && !now.Before(time.Unix(*input.expires, 0)) {
+		return errors.New("the signature has expired")
+	}
+
+	return nil
+}
+
+// publicKey returns the Ed25519 public key of the key ID, a verification method of the authentication of its DID
+func (v *Verifier) publicKey(keyID string) (ed25519.PublicKey, error) {
+	v.mutex.Lock()
+	cached, ok := v.keys[keyID]
+	v.mutex.Unlock()
+
+	if ok && v.now().Before(cached.expiresAt) {
+		return cached.key, nil
+	}
+
+	key, err := v.resolveKey(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mutex.Lock()
+	v.keys[keyID] = &cachedKey{key: key, expiresAt: v.now().Add(keyCacheTTL)}
+	v.mutex.Unlock()
+
+	return key, nil
+}
+
+func (v *Verifier) resolveKey(keyID string) (ed25519.PublicKey, error) {
+	i := strings.Index(keyID, "#")
+	if i <= 0 {
+		return nil, fmt.Errorf("key %s isn't a DID URL with a fragment", keyID)
+	}
+
+	didID := keyID[:i]
+
+	doc, err := v.vdri.Resolve(didID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve DID %s: %w", didID, err)
+	}
+
+	for _, vm := range doc.VerificationMethods(did.Authentication)[did.Authentication] {
+		if vm.PublicKey.ID != keyID && didID+vm.PublicKey.ID != keyID {
+			continue
+		}
+
+		if vm.PublicKey.Type != ed25519VerificationKey || len(vm.PublicKey.Value) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("key %s isn't an %s", keyID, ed25519VerificationKey)
+		}
+
+		return vm.PublicKey.Value, nil
+	}
+
+	return nil, fmt.Errorf("key %s isn't an authentication method of %s", keyID, didID)
+}
+
+// parseSignature returns the signature of the label in the Signature header, a byte sequence
+func parseSignature(header, label string) ([]byte, error) {
+	for _, member := range strings.Split(header, ",") {
+		member = strings.TrimSpace(member)
+
+		if !strings.HasPrefix(member, label+"=") {
+			continue
+		}
+
+		value := strings.TrimPrefix(member, label+"=")
+
+		if len(value) < 2 || value[0] != ':' || value[len(value)-1] != ':' {
+			return nil, fmt.Errorf("signature %s isn't a byte sequence", label)
+		}
+
+		signature, err := base64.StdEncoding.DecodeString(value[1 : len(value)-1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode signature %s: %w", label, err)
+		}
+
+		return signature, nil
+	}
+
+	return nil, fmt.Errorf("missing signature %s", label)
+}
@@ -0,0 +1,372 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsig
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/apikey"
+	"github.com/trustbloc/edge-service/pkg/clientcert"
+)
+
+const (
+	pipelineDID = "did:example:pipeline"
+	keyID       = pipelineDID + "#key-1"
+)
+
+func TestVerifier(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	resolutions := 0
+
+	vdri := &vdrimock.MockVDRIRegistry{ResolveFunc: func(didID string, _ ...vdriapi.ResolveOpts) (*did.Doc, error) {
+		resolutions++
+
+		return createDIDDoc(didID, pubKey), nil
+	}}
+
+	verifier := New(map[string][]string{keyID: {"issuer1"}, pipelineDID + "#admin": {clientcert.AllProfiles}},
+		vdri, DefaultMaxAge)
+
+	var client, signer string
+
+	router := mux.NewRouter()
+	router.Use(verifier.Middleware)
+
+	handle := func(rw http.ResponseWriter, req *http.Request) {
+		client = apikey.Client(req)
+		signer = KeyID(req)
+
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+
+		rw.Write(body) // nolint: errcheck,gosec
+	}
+
+	router.HandleFunc("/{profileID}/credentials/issueCredential", handle)
+	router.HandleFunc("/profile", handle)
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		client, signer = "not called", "not called"
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	body := []byte(`{"credential":{}}`)
+
+	t.Run("test signed request", func(t *testing.T) {
+		req := signedRequest(t, privKey, keyID, "/issuer1/credentials/issueCredential", body, time.Now())
+
+		rr := serve(req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, keyID, client)
+		require.Equal(t, keyID, signer)
+		require.Equal(t, body, rr.Body.Bytes())
+
+		// the key is resolved once
+		rr = serve(signedRequest(t, privKey, keyID, "/issuer1/credentials/issueCredential", body, time.Now()))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, 1, resolutions)
+	})
+
+	t.Run("test request without signature", func(t *testing.T) {
+		rr := serve(httptest.NewRequest(http.MethodPost, "/issuer1/credentials/issueCredential",
+			bytes.NewReader(body)))
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Empty(t, client)
+		require.Empty(t, signer)
+	})
+
+	t.Run("test key not allowed to access the profile", func(t *testing.T) {
+		rr := serve(signedRequest(t, privKey, keyID, "/issuer2/credentials/issueCredential", body, time.Now()))
+		require.Equal(t, http.StatusForbidden, rr.Code)
+		require.Equal(t, "not called", client)
+
+		rr = serve(signedRequest(t, privKey, keyID, "/profile", nil, time.Now()))
+		require.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("test key allowed all the profiles", func(t *testing.T) {
+		// the key ID of the DID document is relative
+		rr := serve(signedRequest(t, privKey, pipelineDID+"#admin", "/profile", nil, time.Now()))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, pipelineDID+"#admin", client)
+
+		rr = serve(signedRequest(t, privKey, pipelineDID+"#admin", "/issuer2/credentials/issueCredential", body,
+			time.Now()))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	})
+
+	t.Run("test invalid signatures", func(t *testing.T) {
+		otherPubKey, otherPrivKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		require.NotEqual(t, pubKey, otherPubKey)
+
+		path := "/issuer1/credentials/issueCredential"
+
+		tests := []struct {
+			name   string
+			req    func() *http.Request
+			errMsg string
+		}{
+			{
+				name:   "other key",
+				req:    func() *http.Request { return signedRequest(t, otherPrivKey, keyID, path, body, time.Now()) },
+				errMsg: "signature verification failed",
+			},
+			{
+				name: "unknown key",
+				req: func() *http.Request {
+					return signedRequest(t, privKey, pipelineDID+"#key-2", path, body, time.Now())
+				},
+				errMsg: "unknown key " + pipelineDID + "#key-2",
+			},
+			{
+				name: "old signature",
+				req: func() *http.Request {
+					return signedRequest(t, privKey, keyID, path, body, time.Now().Add(-time.Hour))
+				},
+				errMsg: "the signature must be created within 5m0s",
+			},
+			{
+				name: "tampered body",
+				req: func() *http.Request {
+					req := signedRequest(t, privKey, keyID, path, body, time.Now())
+					req.Body = ioutil.NopCloser(bytes.NewReader([]byte(`{"credential":{"id":"other"}}`)))
+
+					return req
+				},
+				errMsg: "the sha-256 digest of the body doesn't match",
+			},
+			{
+				name: "tampered path",
+				req: func() *http.Request {
+					signed := signedRequest(t, privKey, keyID, "/issuer1/credentials/other", body, time.Now())
+					req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+					req.Header = signed.Header
+
+					return req
+				},
+				errMsg: "signature verification failed",
+			},
+			{
+				name: "missing signature",
+				req: func() *http.Request {
+					req := signedRequest(t, privKey, keyID, path, body, time.Now())
+					req.Header.Del(SignatureHeader)
+
+					return req
+				},
+				errMsg: "missing signature sig1",
+			},
+			{
+				name: "body digest not covered",
+				req: func() *http.Request {
+					req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+					sign(req, privKey, `("@method" "@path");created=`+
+						strconv.FormatInt(time.Now().Unix(), 10)+`;keyid="`+keyID+`"`)
+
+					return req
+				},
+				errMsg: "the signature must cover content-digest",
+			},
+			{
+				name: "path not covered",
+				req: func() *http.Request {
+					req := httptest.NewRequest(http.MethodGet, path, nil)
+					sign(req, privKey, `("@method");created=`+strconv.FormatInt(time.Now().Unix(), 10)+
+						`;keyid="`+keyID+`"`)
+
+					return req
+				},
+				errMsg: "the signature must cover @method and @path",
+			},
+		}
+
+		for _, tc := range tests {
+			tc := tc
+
+			t.Run(tc.name, func(t *testing.T) {
+				rr := serve(tc.req())
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+				require.Contains(t, rr.Body.String(), tc.errMsg)
+				require.Equal(t, "not called", client)
+			})
+		}
+	})
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Now()
+	created := strconv.FormatInt(now.Unix(), 10)
+
+	verifier := New(map[string][]string{keyID: {"issuer1"}}, &vdrimock.MockVDRIRegistry{
+		ResolveFunc: func(didID string, _ ...vdriapi.ResolveOpts) (*did.Doc, error) {
+			return createDIDDoc(didID, pubKey), nil
+		}}, DefaultMaxAge)
+
+	verify := func(input string) error {
+		req := httptest.NewRequest(http.MethodGet, "/issuer1/credentials", nil)
+		req.Header.Set("X-Custom", " value ")
+		sign(req, privKey, input)
+
+		_, err := verifier.Verify(req)
+
+		return err
+	}
+
+	t.Run("test covered components", func(t *testing.T) {
+		require.NoError(t, verify(`("@method" "@target-uri" "@authority" "@scheme" "@query" "@request-target" `+
+			`"x-custom" "host");created=`+created+`;keyid="`+keyID+`";alg="ed25519";nonce="n";expires=`+
+			strconv.FormatInt(now.Add(time.Minute).Unix(), 10)))
+
+		require.EqualError(t, verify(`("@method" "@path" "x-missing");created=`+created+`;keyid="`+keyID+`"`),
+			"missing header x-missing")
+		require.EqualError(t, verify(`("@method" "@path" "@status");created=`+created+`;keyid="`+keyID+`"`),
+			"unsupported component @status")
+	})
+
+	t.Run("test invalid parameters", func(t *testing.T) {
+		require.EqualError(t, verify(`("@method" "@path");created=`+created+`;keyid="`+keyID+`";alg="rsa-v1_5-sha256"`),
+			"unsupported algorithm rsa-v1_5-sha256")
+		require.EqualError(t, verify(`("@method" "@path");keyid="`+keyID+`"`), "missing created parameter")
+		require.EqualError(t, verify(`("@method" "@path");created=`+created+`;keyid="`+keyID+`";expires=`+created),
+			"the signature has expired")
+		require.EqualError(t, verify(`("@method" "@path");created=`+created), "missing keyid parameter")
+		require.EqualError(t, verify(`("@method" "@path");created=x;keyid="`+keyID+`"`),
+			"invalid created parameter x")
+	})
+
+	t.Run("test invalid headers", func(t *testing.T) {
+		for _, input := range []string{`invalid`, `"@method" "@path"`, `("@method" "@path"`, `("@Method")`,
+			`("@method" "@path");created=1 x`, `("@method" "\x")`, `("@method");=1`} {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(SignatureInputHeader, "sig1="+input)
+			req.Header.Set(SignatureHeader, "sig1=:AA==:")
+
+			_, err := verifier.Verify(req)
+			require.Error(t, err, input)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(SignatureInputHeader, "sig1=();created="+created+`;keyid="`+keyID+`"`)
+
+		for _, signature := range []string{"sig1=AA==", "sig1=:!:"} {
+			req.Header.Set(SignatureHeader, signature)
+
+			_, err := verifier.Verify(req)
+			require.Error(t, err, signature)
+		}
+	})
+
+	t.Run("test key resolution errors", func(t *testing.T) {
+		tests := map[string]struct {
+			keyID  string
+			doc    *did.Doc
+			err    error
+			errMsg string
+		}{
+			"not a DID URL":    {keyID: "key-1", errMsg: "key key-1 isn't a DID URL with a fragment"},
+			"resolution error": {keyID: keyID, err: errors.New("not found"), errMsg: "failed to resolve DID"},
+			"not authentication": {keyID: pipelineDID + "#key-2", doc: createDIDDoc(pipelineDID, pubKey),
+				errMsg: "isn't an authentication method of " + pipelineDID},
+			"not an Ed25519 key": {keyID: keyID, doc: &did.Doc{ID: pipelineDID, Authentication: []did.VerificationMethod{
+				{PublicKey: did.PublicKey{ID: "#key-1", Type: "JwsVerificationKey2020", Value: pubKey}},
+			}}, errMsg: "isn't an Ed25519VerificationKey2018"},
+		}
+
+		for name, tc := range tests {
+			tc := tc
+
+			t.Run(name, func(t *testing.T) {
+				v := New(nil, &vdrimock.MockVDRIRegistry{
+					ResolveFunc: func(string, ...vdriapi.ResolveOpts) (*did.Doc, error) {
+						return tc.doc, tc.err
+					}}, DefaultMaxAge)
+
+				_, err := v.publicKey(tc.keyID)
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.errMsg)
+			})
+		}
+	})
+}
+
+// signedRequest returns a POST request (GET without body) signed with the key for the given time, covering the
+// method, path and content digest
+func signedRequest(t *testing.T, privKey ed25519.PrivateKey, keyID, path string, body []byte,
+	created time.Time) *http.Request {
+	method := http.MethodGet
+	if body != nil {
+		method = http.MethodPost
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+
+	digest := sha256.Sum256(body)
+	req.Header.Set(ContentDigestHeader, "sha-256=:"+base64.StdEncoding.EncodeToString(digest[:])+":")
+
+	sign(req, privKey, fmt.Sprintf(`("@method" "@path" "content-digest");created=%d;keyid=%q;alg="ed25519"`,
+		created.Unix(), keyID))
+
+	return req
+}
+
+// sign signs the request with the signature input, the signature being invalid if the request has no signature base
+func sign(req *http.Request, privKey ed25519.PrivateKey, input string) {
+	req.Header.Set(SignatureInputHeader, "sig1="+input)
+	req.Header.Set(SignatureHeader, "sig1=:AA==:")
+
+	_, parsed, err := parseSignatureInput(req.Header.Get(SignatureInputHeader))
+	if err != nil {
+		return
+	}
+
+	base, err := signatureBase(req, parsed)
+	if err != nil {
+		return
+	}
+
+	req.Header.Set(SignatureHeader, "sig1=:"+base64.StdEncoding.EncodeToString(ed25519.Sign(privKey, base))+":")
+}
+
+func createDIDDoc(didID string, pubKey []byte) *did.Doc {
+	key := did.PublicKey{ID: didID + "#key-1", Type: ed25519VerificationKey, Controller: didID, Value: pubKey}
+	admin := did.PublicKey{ID: "#admin", Type: ed25519VerificationKey, Controller: didID, Value: pubKey}
+	other := did.PublicKey{ID: didID + "#key-2", Type: ed25519VerificationKey, Controller: didID, Value: pubKey}
+
+	return &did.Doc{
+		ID:              didID,
+		PublicKey:       []did.PublicKey{key, admin, other},
+		Authentication:  []did.VerificationMethod{{PublicKey: key}, {PublicKey: admin}},
+		AssertionMethod: []did.VerificationMethod{{PublicKey: other}},
+	}
+}
@@ -0,0 +1,366 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// signatureInput is the member of the Signature-Input header of a signature: its covered components and parameters
+type signatureInput struct {
+	components []string
+	// the parameters in their order, serialized as in the signature base
+	params  []string
+	keyID   string
+	alg     string
+	created *int64
+	expires *int64
+}
+
+func (i *signatureInput) covers(component string) bool {
+	for _, c := range i.components {
+		if c == component {
+			return true
+		}
+	}
+
+	return false
+}
+
+// serialize serializes the input as the value of the @signature-params line of the signature base
+func (i *signatureInput) serialize() string {
+	quoted := make([]string, len(i.components))
+
+	for j, c := range i.components {
+		quoted[j] = strconv.Quote(c)
+	}
+
+	return "(" + strings.Join(quoted, " ") + ")" + strings.Join(i.params, "")
+}
+
+// parseSignatureInput returns the label and the input of the first signature of the Signature-Input header, a
+// dictionary of inner lists of component names with parameters (RFC 8941)
+func parseSignatureInput(header string) (string, *signatureInput, error) {
+	eq := strings.Index(header, "=")
+	if eq <= 0 {
+		return "", nil, fmt.Errorf("invalid %s header", SignatureInputHeader)
+	}
+
+	label := strings.TrimSpace(header[:eq])
+	p := &parser{s: header[eq+1:]}
+	input := &signatureInput{}
+
+	if err := p.expect('('); err != nil {
+		return "", nil, err
+	}
+
+	for {
+		p.skipSpaces()
+
+		if p.peek() == ')' {
+			p.pos++
+
+			break
+		}
+
+		component, err := p.parseString()
+		if err != nil {
+			return "", nil, err
+		}
+
+		if component != strings.ToLower(component) {
+			return "", nil, fmt.Errorf("component %s must be lowercase", component)
+		}
+
+		input.components = append(input.components, component)
+	}
+
+	for p.peek() == ';' {
+		p.pos++
+
+		if err := p.parseParam(input); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if p.peek() != 0 && p.peek() != ',' {
+		return "", nil, fmt.Errorf("invalid %s header", SignatureInputHeader)
+	}
+
+	if input.keyID == "" {
+		return "", nil, errors.New("missing keyid parameter")
+	}
+
+	return label, input, nil
+}
+
+// parser parses the structured field values of the Signature-Input header
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) peek() byte {
+	if p.pos >= len(p.s) {
+		return 0
+	}
+
+	return p.s[p.pos]
+}
+
+func (p *parser) skipSpaces() {
+	for p.peek() == ' ' {
+		p.pos++
+	}
+}
+
+func (p *parser) expect(c byte) error {
+	if p.peek() != c {
+		return fmt.Errorf("invalid %s header: expected %q at %d", SignatureInputHeader, c, p.pos)
+	}
+
+	p.pos++
+
+	return nil
+}
+
+func (p *parser) parseString() (string, error) {
+	if err := p.expect('"'); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	for {
+		c := p.peek()
+
+		switch c {
+		case 0:
+			return "", fmt.Errorf("invalid %s header: unterminated string", SignatureInputHeader)
+		case '"':
+			p.pos++
+
+			return b.String(), nil
+		case '\\':
+			p.pos++
+			c = p.peek()
+
+			if c != '"' && c != '\\' {
+				return "", fmt.Errorf("invalid %s header: invalid escape", SignatureInputHeader)
+			}
+		}
+
+		b.WriteByte(c)
+		p.pos++
+	}
+}
+
+func (p *parser) parseToken() string {
+	start := p.pos
+
+	for c := p.peek(); c != 0 && c != ';' && c != ',' && c != ' ' && c != '=' && c != ')'; c = p.peek() {
+		p.pos++
+	}
+
+	return p.s[start:p.pos]
+}
+
+// parseParam parses a parameter of the signature, keeping its serialization
+func (p *parser) parseParam(input *signatureInput) error {
+	name := p.parseToken()
+	if name == "" {
+		return fmt.Errorf("invalid %s header: missing parameter name", SignatureInputHeader)
+	}
+
+	if p.peek() != '=' {
+		input.params = append(input.params, ";"+name)
+
+		return nil
+	}
+
+	p.pos++
+
+	if p.peek() == '"' {
+		value, err := p.parseString()
+		if err != nil {
+			return err
+		}
+
+		input.params = append(input.params, ";"+name+"="+strconv.Quote(value))
+
+		switch name {
+		case "keyid":
+			input.keyID = value
+		case "alg":
+			input.alg = value
+		}
+
+		return nil
+	}
+
+	value := p.parseToken()
+	input.params = append(input.params, ";"+name+"="+value)
+
+	if name == "created" || name == "expires" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s parameter %s", name, value)
+		}
+
+		if name == "created" {
+			input.created = &n
+		} else {
+			input.expires = &n
+		}
+	}
+
+	return nil
+}
+
+// signatureBase returns the signature base of the request: the values of the covered components, then the
+// signature parameters
+func signatureBase(req *http.Request, input *signatureInput) ([]byte, error) {
+	var b bytes.Buffer
+
+	for _, component := range input.components {
+		value, err := componentValue(req, component)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&b, "%q: %s\n", component, value)
+	}
+
+	fmt.Fprintf(&b, "%q: %s", signatureParamsComponent, input.serialize())
+
+	return b.Bytes(), nil
+}
+
+func componentValue(req *http.Request, component string) (string, error) {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+
+	// the request URI as sent by the client, before any prefix is stripped
+	requestURI := req.RequestURI
+	if requestURI == "" {
+		requestURI = req.URL.RequestURI()
+	}
+
+	path := strings.SplitN(requestURI, "?", 2)[0]
+	if path == "" {
+		path = "/"
+	}
+
+	switch component {
+	case componentMethod:
+		return req.Method, nil
+	case "@authority":
+		return strings.ToLower(req.Host), nil
+	case "@scheme":
+		return scheme, nil
+	case componentPath:
+		return path, nil
+	case "@query":
+		return "?" + req.URL.RawQuery, nil
+	case componentTargetURI:
+		return scheme + "://" + req.Host + requestURI, nil
+	case componentRequestTarget:
+		return requestURI, nil
+	case "host":
+		return req.Host, nil
+	}
+
+	if strings.HasPrefix(component, "@") {
+		return "", fmt.Errorf("unsupported component %s", component)
+	}
+
+	values := req.Header[textproto.CanonicalMIMEHeaderKey(component)]
+	if len(values) == 0 {
+		return "", fmt.Errorf("missing header %s", component)
+	}
+
+	trimmed := make([]string, len(values))
+
+	for i, value := range values {
+		trimmed[i] = strings.TrimSpace(value)
+	}
+
+	return strings.Join(trimmed, ", "), nil
+}
+
+// checkContentDigest verifies the Content-Digest header matches the body of the request, which must be covered by
+// the signature if the body isn't empty. The body is replaced.
+func checkContentDigest(req *http.Request, covered bool) error {
+	var body []byte
+
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+
+		body, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read body: %w", err)
+		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	header := req.Header.Get(ContentDigestHeader)
+
+	if len(body) > 0 && !covered {
+		return fmt.Errorf("the signature must cover %s", componentContentDigest)
+	}
+
+	if header == "" {
+		return nil
+	}
+
+	verified := false
+
+	for _, member := range strings.Split(header, ",") {
+		split := strings.SplitN(strings.TrimSpace(member), "=", 2)
+		if len(split) != 2 {
+			return fmt.Errorf("invalid %s header", ContentDigestHeader)
+		}
+
+		var h hash.Hash
+
+		switch split[0] {
+		case "sha-256":
+			h = sha256.New()
+		case "sha-512":
+			h = sha512.New()
+		default:
+			continue
+		}
+
+		h.Write(body) // nolint: errcheck,gosec
+
+		if split[1] != ":"+base64.StdEncoding.EncodeToString(h.Sum(nil))+":" {
+			return fmt.Errorf("the %s digest of the body doesn't match", split[0])
+		}
+
+		verified = true
+	}
+
+	if !verified {
+		return fmt.Errorf("%s header without sha-256 or sha-512 digest", ContentDigestHeader)
+	}
+
+	return nil
+}
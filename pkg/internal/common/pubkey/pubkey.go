@@ -0,0 +1,119 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pubkey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+)
+
+// the signature algorithms of the keys of the profiles and of the holders, by their JWS names (RFC 7518, RFC 8037,
+// RFC 8812)
+const (
+	AlgorithmEdDSA  = "EdDSA"
+	AlgorithmES256  = "ES256"
+	AlgorithmES384  = "ES384"
+	AlgorithmES512  = "ES512"
+	AlgorithmES256K = "ES256K"
+	AlgorithmPS256  = "PS256"
+)
+
+const ed25519VerificationKey2018 = "Ed25519VerificationKey2018"
+
+// ecAlgorithms are the ECDSA algorithms and their digests, by curve
+// nolint: gochecknoglobals
+var ecAlgorithms = map[string]struct {
+	alg  string
+	hash crypto.Hash
+}{
+	"P-256":     {alg: AlgorithmES256, hash: crypto.SHA256},
+	"P-384":     {alg: AlgorithmES384, hash: crypto.SHA384},
+	"P-521":     {alg: AlgorithmES512, hash: crypto.SHA512},
+	"secp256k1": {alg: AlgorithmES256K, hash: crypto.SHA256},
+}
+
+// Algorithm returns the signature algorithm of a public key: EdDSA for the Ed25519 keys, ES256, ES384, ES512 or
+// ES256K for the EC keys depending on their curve, and PS256 for the RSA keys
+func Algorithm(key *verifier.PublicKey) (string, error) {
+	if key.JWK == nil {
+		if key.Type == ed25519VerificationKey2018 {
+			return AlgorithmEdDSA, nil
+		}
+
+		return "", fmt.Errorf("unsupported key type %s", key.Type)
+	}
+
+	switch pubKey := key.JWK.Key.(type) {
+	case ed25519.PublicKey:
+		return AlgorithmEdDSA, nil
+	case *ecdsa.PublicKey:
+		if ec, ok := ecAlgorithms[curveName(key.JWK, pubKey)]; ok {
+			return ec.alg, nil
+		}
+
+		return "", fmt.Errorf("unsupported curve %s", curveName(key.JWK, pubKey))
+	case *rsa.PublicKey:
+		return AlgorithmPS256, nil
+	default:
+		return "", fmt.Errorf("unsupported key %T", key.JWK.Key)
+	}
+}
+
+// Verify verifies the signature of the data with the public key, the ECDSA signatures being the concatenation of r
+// and s (IEEE P1363) of the digest of the curve, and the RSA signatures RSASSA-PSS of the SHA-256 digest
+func Verify(key *verifier.PublicKey, data, signature []byte) bool {
+	if key.JWK == nil {
+		return len(key.Value) == ed25519.PublicKeySize && ed25519.Verify(key.Value, data, signature)
+	}
+
+	switch pubKey := key.JWK.Key.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(pubKey, data, signature)
+	case *ecdsa.PublicKey:
+		ec, ok := ecAlgorithms[curveName(key.JWK, pubKey)]
+		if !ok {
+			return false
+		}
+
+		size := (pubKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return false
+		}
+
+		r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+
+		return ecdsa.Verify(pubKey, Digest(ec.hash, data), r, s)
+	case *rsa.PublicKey:
+		return rsa.VerifyPSS(pubKey, crypto.SHA256, Digest(crypto.SHA256, data), signature, nil) == nil
+	default:
+		return false
+	}
+}
+
+// Digest returns the digest of the data
+func Digest(hash crypto.Hash, data []byte) []byte {
+	h := hash.New()
+	h.Write(data) // nolint: errcheck,gosec
+
+	return h.Sum(nil)
+}
+
+// curveName returns the curve of the JWK, the curve of the secp256k1 keys having no name
+func curveName(key *jose.JWK, pubKey *ecdsa.PublicKey) string {
+	if key.Crv != "" {
+		return key.Crv
+	}
+
+	return pubKey.Curve.Params().Name
+}
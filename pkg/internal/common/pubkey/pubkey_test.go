@@ -0,0 +1,130 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pubkey
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlgorithmAndVerify(t *testing.T) {
+	data := []byte("data")
+
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	rsaSignature, err := rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, Digest(crypto.SHA256, data), nil)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		key       *verifier.PublicKey
+		alg       string
+		signature []byte
+	}{
+		"Ed25519 verification key": {key: &verifier.PublicKey{Type: "Ed25519VerificationKey2018", Value: edPub},
+			alg: AlgorithmEdDSA, signature: ed25519.Sign(edPriv, data)},
+		"Ed25519 JWK": {key: jwkKey(t, edPub), alg: AlgorithmEdDSA, signature: ed25519.Sign(edPriv, data)},
+		"RSA JWK":     {key: jwkKey(t, &rsaKey.PublicKey), alg: AlgorithmPS256, signature: rsaSignature},
+	}
+
+	for _, ec := range []struct {
+		curve elliptic.Curve
+		hash  crypto.Hash
+		alg   string
+	}{
+		{curve: elliptic.P256(), hash: crypto.SHA256, alg: AlgorithmES256},
+		{curve: elliptic.P384(), hash: crypto.SHA384, alg: AlgorithmES384},
+		{curve: elliptic.P521(), hash: crypto.SHA512, alg: AlgorithmES512},
+		{curve: btcec.S256(), hash: crypto.SHA256, alg: AlgorithmES256K},
+	} {
+		privKey, err := ecdsa.GenerateKey(ec.curve, rand.Reader)
+		require.NoError(t, err)
+
+		key := jwkKey(t, &privKey.PublicKey)
+		if ec.alg == AlgorithmES256K {
+			// the JWKs of the secp256k1 keys of the DID documents have their curve set
+			key.JWK.Crv = "secp256k1"
+		}
+
+		tests[ec.alg+" JWK"] = struct {
+			key       *verifier.PublicKey
+			alg       string
+			signature []byte
+		}{key: key, alg: ec.alg, signature: signECDSA(t, privKey, Digest(ec.hash, data))}
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			alg, err := Algorithm(tc.key)
+			require.NoError(t, err)
+			require.Equal(t, tc.alg, alg)
+
+			require.True(t, Verify(tc.key, data, tc.signature))
+			require.False(t, Verify(tc.key, []byte("other data"), tc.signature))
+			require.False(t, Verify(tc.key, data, tc.signature[1:]))
+		})
+	}
+}
+
+func TestAlgorithm_Errors(t *testing.T) {
+	_, err := Algorithm(&verifier.PublicKey{Type: "JsonWebKey2020"})
+	require.EqualError(t, err, "unsupported key type JsonWebKey2020")
+
+	privKey, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	require.NoError(t, err)
+
+	key := &verifier.PublicKey{JWK: &jose.JWK{}}
+	key.JWK.Key = &privKey.PublicKey
+
+	_, err = Algorithm(key)
+	require.EqualError(t, err, "unsupported curve P-224")
+	require.False(t, Verify(key, []byte("data"), make([]byte, 56)))
+
+	key.JWK.Key = []byte("key")
+
+	_, err = Algorithm(key)
+	require.EqualError(t, err, "unsupported key []uint8")
+	require.False(t, Verify(key, []byte("data"), []byte("signature")))
+}
+
+func jwkKey(t *testing.T, pubKey interface{}) *verifier.PublicKey {
+	t.Helper()
+
+	key, err := jose.JWKFromPublicKey(pubKey)
+	require.NoError(t, err)
+
+	return &verifier.PublicKey{Type: "JsonWebKey2020", JWK: key}
+}
+
+// signECDSA returns the IEEE P1363 (r || s) signature of the digest
+func signECDSA(t *testing.T, privKey *ecdsa.PrivateKey, digest []byte) []byte {
+	t.Helper()
+
+	r, s, err := ecdsa.Sign(rand.Reader, privKey, digest)
+	require.NoError(t, err)
+
+	size := (privKey.Curve.Params().BitSize + 7) / 8
+
+	signature := make([]byte, 2*size)
+	r.FillBytes(signature[:size])
+	s.FillBytes(signature[size:])
+
+	return signature
+}
@@ -8,6 +8,31 @@ package support
 
 import (
 	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/trustbloc/edge-service/pkg/metrics"
+)
+
+// CorrelationIDHeader is the header of the request and response correlation ID
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// nolint: gochecknoglobals
+var (
+	// the path parameters holding the profile ID, depending on the mode
+	profilePathParams = []string{"profileID", "id"}
+
+	// requestLogger logs a JSON entry per handled request, separately from the text logs of the modules
+	requestLogger = &logrus.Logger{
+		Out:       os.Stderr,
+		Formatter: &logrus.JSONFormatter{},
+		Hooks:     make(logrus.LevelHooks),
+		Level:     logrus.InfoLevel,
+	}
 )
 
 // NewHTTPHandler returns instance of HTTPHandler which can be used to handle http requests
@@ -33,7 +58,74 @@ func (h *HTTPHandler) Method() string {
 	return h.method
 }
 
-// Handle returns http request handle func
+// Handle returns http request handle func. The correlation ID of the request (a new one if not set) is set
+// on the response before it is handled. Once handled, the request is logged and its count and latency
+// are recorded.
 func (h *HTTPHandler) Handle() http.HandlerFunc {
-	return h.handle
+	return func(rw http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		correlationID := req.Header.Get(CorrelationIDHeader)
+		if correlationID == "" {
+			correlationID = uuid.New().String()
+		}
+
+		rw.Header().Set(CorrelationIDHeader, correlationID)
+
+		srw := &statusResponseWriter{ResponseWriter: rw, status: http.StatusOK}
+
+		h.handle(srw, req)
+
+		ObserveRequest(&HandledRequest{Endpoint: h.path, Method: h.method, Path: req.URL.Path,
+			Profile: profileID(req), CorrelationID: correlationID, Status: srw.status, Duration: time.Since(start)})
+	}
+}
+
+// HandledRequest is a request handled by the REST or gRPC API
+type HandledRequest struct {
+	// Endpoint is the path template of the REST request, or the full method of the gRPC call
+	Endpoint      string
+	Method        string
+	Path          string
+	Profile       string
+	CorrelationID string
+	Status        int
+	Duration      time.Duration
+}
+
+// ObserveRequest records the count and latency of the handled request, and logs it.
+func ObserveRequest(r *HandledRequest) {
+	metrics.ObserveHTTPRequest(r.Endpoint, r.Method, r.Status, r.Duration)
+
+	requestLogger.WithFields(logrus.Fields{
+		"correlationID": r.CorrelationID,
+		"method":        r.Method,
+		"path":          r.Path,
+		"profile":       r.Profile,
+		"status":        r.Status,
+		"durationMs":    r.Duration.Milliseconds(),
+	}).Info("request handled")
+}
+
+func profileID(req *http.Request) string {
+	vars := mux.Vars(req)
+
+	for _, param := range profilePathParams {
+		if id, ok := vars[param]; ok {
+			return id
+		}
+	}
+
+	return ""
+}
+
+// statusResponseWriter keeps the status code written by the handler
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
 }
@@ -7,11 +7,18 @@ SPDX-License-Identifier: Apache-2.0
 package support
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/metrics"
 )
 
 func TestNewHTTPHandler(t *testing.T) {
@@ -28,7 +35,7 @@ func TestNewHTTPHandler(t *testing.T) {
 	require.Equal(t, method, handler.Method())
 	require.NotNil(t, handler.Handle())
 
-	go handler.Handle()(nil, nil)
+	go handler.Handle()(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
 
 	select {
 	case res := <-handled:
@@ -37,3 +44,66 @@ func TestNewHTTPHandler(t *testing.T) {
 		t.Fatal("handler function didn't get executed")
 	}
 }
+
+func TestHTTPHandler_CorrelationID(t *testing.T) {
+	handler := NewHTTPHandler("/sample-path", http.MethodGet, func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/sample-path", nil)
+	req.Header.Set(CorrelationIDHeader, "correlation-1")
+
+	rr := httptest.NewRecorder()
+	handler.Handle()(rr, req)
+	require.Equal(t, "correlation-1", rr.Header().Get(CorrelationIDHeader))
+
+	rr = httptest.NewRecorder()
+	handler.Handle()(rr, httptest.NewRequest(http.MethodGet, "/sample-path", nil))
+	require.NotEmpty(t, rr.Header().Get(CorrelationIDHeader))
+}
+
+func TestHTTPHandler_Metrics(t *testing.T) {
+	handler := NewHTTPHandler("/metrics-path/{id}", http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	rr := httptest.NewRecorder()
+	handler.Handle()(rr, httptest.NewRequest(http.MethodPost, "/metrics-path/1", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Contains(t, rr.Body.String(),
+		`vcs_http_requests_total{endpoint="/metrics-path/{id}",method="POST",status="404"} 1`)
+	require.Contains(t, rr.Body.String(),
+		`vcs_http_request_duration_seconds_count{endpoint="/metrics-path/{id}",method="POST"} 1`)
+}
+
+func TestHTTPHandler_RequestLog(t *testing.T) {
+	var logContents bytes.Buffer
+
+	requestLogger.SetOutput(&logContents)
+
+	defer requestLogger.SetOutput(os.Stderr)
+
+	handler := NewHTTPHandler("/{profileID}/credentials/issueCredential", http.MethodPost,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+
+	router := mux.NewRouter()
+	router.HandleFunc(handler.Path(), handler.Handle()).Methods(handler.Method())
+
+	req := httptest.NewRequest(http.MethodPost, "/issuer1/credentials/issueCredential", nil)
+	req.Header.Set(CorrelationIDHeader, "correlation-1")
+
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	entry := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(logContents.Bytes(), &entry))
+	require.Equal(t, "correlation-1", entry["correlationID"])
+	require.Equal(t, http.MethodPost, entry["method"])
+	require.Equal(t, "/issuer1/credentials/issueCredential", entry["path"])
+	require.Equal(t, "issuer1", entry["profile"])
+	require.Equal(t, float64(http.StatusCreated), entry["status"])
+	require.Contains(t, entry, "durationMs")
+	require.Equal(t, "request handled", entry["msg"])
+}
@@ -11,6 +11,10 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/google/tink/go/keyset"
 	ariescrypto "github.com/hyperledger/aries-framework-go/pkg/crypto"
@@ -26,29 +30,436 @@ const (
 	keyIDStoreName       = "keyid"
 	hmacKeyIDDBKeyName   = "hmackeyid"
 	ecdhesKeyIDDBKeyName = "ecdheskeyid"
+	// the name of the profile of each profile key is kept under this prefix and the key ID
+	jweKeyProfileDBKeyPrefix = "jwekeyprofile/"
 )
 
 var errKeySetHandleAssertionFailure = errors.New("unable to assert key handle as a key set handle pointer")
 
+// ECDHESA256KW is the ECDH-ES key agreement with AES256 key wrapping JWE algorithm
+const ECDHESA256KW = "ECDH-ES+A256KW"
+
+// JWEAlgorithm is the content encryption and key wrapping algorithms of the JWE of the EDV documents
+type JWEAlgorithm struct {
+	Enc     jose.EncAlg
+	KeyWrap string
+}
+
+// DefaultJWEAlgorithm is the JWE algorithm of the EDV documents when not configured, and of the documents that
+// don't record their algorithm
+var DefaultJWEAlgorithm = JWEAlgorithm{Enc: jose.A256GCM, KeyWrap: ECDHESA256KW} //nolint:gochecknoglobals
+
+// jweKeyTypes are the kms key types of the recipient keys of the supported JWE algorithms. The algorithms of the
+// JWE primitives of aries-framework-go are added here as they become available (e.g. XC20P, ECDH-1PU).
+var jweKeyTypes = map[JWEAlgorithm]kms.KeyType{ //nolint:gochecknoglobals
+	DefaultJWEAlgorithm: kms.ECDHES256AES256GCMType,
+}
+
+func (a JWEAlgorithm) String() string {
+	return a.KeyWrap + "/" + string(a.Enc)
+}
+
+// keyIDDBKeyName returns the key of the current recipient key ID of the algorithm in the key ID store, the default
+// algorithm keeping the key it had before the algorithm was configurable
+func (a JWEAlgorithm) keyIDDBKeyName() string {
+	if a == DefaultJWEAlgorithm {
+		return ecdhesKeyIDDBKeyName
+	}
+
+	return ecdhesKeyIDDBKeyName + "/" + a.String()
+}
+
+// profileKeyIDDBKeyName returns the key of the current recipient key ID of the algorithm of the profile in the key
+// ID store, the service-wide key for an empty profile name
+func (a JWEAlgorithm) profileKeyIDDBKeyName(profile string) string {
+	if profile == "" {
+		return a.keyIDDBKeyName()
+	}
+
+	return a.keyIDDBKeyName() + "/profile/" + profile
+}
+
+// jweKeyIDs returns the IDs of the keys of the algorithm of the profile in the order they were created, the key
+// created before the keys could be rotated being the only key if the IDs aren't stored
+func jweKeyIDs(keyIDStore storage.Store, algorithm JWEAlgorithm, profile string) ([]string, error) {
+	keyIDsBytes, err := keyIDStore.Get(keyIDsDBKeyName(algorithm.profileKeyIDDBKeyName(profile)))
+	if err == nil {
+		var keyIDs []string
+
+		if errUnmarshal := json.Unmarshal(keyIDsBytes, &keyIDs); errUnmarshal != nil {
+			return nil, fmt.Errorf("failed to unmarshal the JWE key IDs: %w", errUnmarshal)
+		}
+
+		return keyIDs, nil
+	}
+
+	if !errors.Is(err, storage.ErrValueNotFound) {
+		return nil, err
+	}
+
+	keyIDBytes, err := keyIDStore.Get(algorithm.profileKeyIDDBKeyName(profile))
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{string(keyIDBytes)}, nil
+}
+
+// keyIDsDBKeyName returns the key of the IDs of all the recipient keys having the current key ID under the key
+func keyIDsDBKeyName(keyIDDBKeyName string) string {
+	return keyIDDBKeyName + "/keys"
+}
+
 type unmarshalFunc func([]byte, interface{}) error
 type newJWEEncryptFunc func(jose.EncAlg, []subtle.PublicKey) (*jose.JWEEncrypt, error)
 
-// PrepareJWECrypto prepares necessary JWE crypto data for edge-service operations
+// JWECrypto encrypts the EDV documents with the current key of the configured JWE algorithm, the algorithm and the
+// key ID being recorded in the unprotected header of the JWE. It decrypts the documents encrypted with any of the
+// keys of the algorithms used before, so the documents stay readable once the key is rotated or the algorithm
+// changed. The JWEs without key ID were encrypted with the first key of their algorithm.
+//
+// The IDs of the keys of each algorithm are kept in the key ID store, the last one being the current key. The
+// keys created by another instance are loaded when a JWE encrypted with one of them is decrypted.
+//
+// The JWECrypto returned by PrepareJWECrypto has the service-wide keys, which encrypted the documents of all the
+// profiles before each profile had its own keys (see ProfileCrypto).
+type JWECrypto struct {
+	algorithm  JWEAlgorithm
+	keyManager kms.KeyManager
+	keyIDStore storage.Store
+	mutex      sync.RWMutex
+	keyID      string
+	encrypter  jose.Encrypter
+	decrypters map[string]jose.Decrypter
+	// the decrypters of the first key of each algorithm, for the JWEs without key ID
+	firstKeyDecrypters map[JWEAlgorithm]jose.Decrypter
+	// the profile of the keys, empty for the service-wide keys
+	profile string
+	// the service-wide keys, decrypting the documents of the profile encrypted before it had its own keys
+	parent        *JWECrypto
+	profilesMutex sync.Mutex
+	profiles      map[string]*JWECrypto
+}
+
+// PrepareJWECrypto prepares necessary JWE crypto data for edge-service operations, the recipient key of the
+// algorithm being created if it isn't used yet
 func PrepareJWECrypto(keyManager kms.KeyManager, storeProvider storage.Provider,
-	encAlg jose.EncAlg, keyType kms.KeyType) (*jose.JWEEncrypt, *jose.JWEDecrypt, error) {
-	keyHandle, err := prepareKeyHandle(storeProvider, keyManager, ecdhesKeyIDDBKeyName, keyType)
+	algorithm JWEAlgorithm) (*JWECrypto, error) {
+	if _, ok := jweKeyTypes[algorithm]; !ok {
+		return nil, fmt.Errorf("unsupported JWE algorithm %s, supported algorithms: %s", algorithm,
+			supportedJWEAlgorithms())
+	}
+
+	keyIDStore, err := prepareKeyIDStore(storeProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &JWECrypto{algorithm: algorithm, keyManager: keyManager, keyIDStore: keyIDStore,
+		profiles: map[string]*JWECrypto{}}
+
+	if err = c.prepare(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// ProfileCrypto returns the JWECrypto of the keys of the profile, the first key of the profile being created if it
+// has none yet. Each profile having its own keys, a compromised key only exposes the documents of one profile and
+// the keys of an offboarded profile can be destroyed without affecting the other profiles. The documents of the
+// profile encrypted with the service-wide keys are still decrypted, until they are re-encrypted.
+func (c *JWECrypto) ProfileCrypto(profile string) (*JWECrypto, error) {
+	if c.parent != nil {
+		return c.parent.ProfileCrypto(profile)
+	}
+
+	c.profilesMutex.Lock()
+	defer c.profilesMutex.Unlock()
+
+	if profileCrypto, ok := c.profiles[profile]; ok {
+		return profileCrypto, nil
+	}
+
+	profileCrypto := &JWECrypto{algorithm: c.algorithm, keyManager: c.keyManager, keyIDStore: c.keyIDStore,
+		profile: profile, parent: c}
+
+	if err := profileCrypto.prepare(); err != nil {
+		return nil, fmt.Errorf("failed to prepare the JWE keys of profile %s: %w", profile, err)
+	}
+
+	c.profiles[profile] = profileCrypto
+
+	return profileCrypto, nil
+}
+
+// prepare loads the keys, the first key being created if there is none
+func (c *JWECrypto) prepare() error {
+	if err := c.load(); err != nil {
+		return err
+	}
+
+	if c.keyID == "" {
+		if _, err := c.createKey(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// KeyID returns the ID of the current key, encrypting the documents
+func (c *JWECrypto) KeyID() string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.keyID
+}
+
+// RotateKey creates a new key of the configured algorithm, which becomes the current key, and returns its ID. The
+// previous keys are kept to decrypt the documents they encrypted.
+func (c *JWECrypto) RotateKey() (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.createKey()
+}
+
+// Encrypt encrypts the plaintext with the current key
+func (c *JWECrypto) Encrypt(plaintext, aad []byte) (*jose.JSONWebEncryption, error) {
+	c.mutex.RLock()
+	encrypter, keyID := c.encrypter, c.keyID
+	c.mutex.RUnlock()
+
+	jwe, err := encrypter.Encrypt(plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	jwe.UnprotectedHeaders = jose.Headers{jose.HeaderAlgorithm: c.algorithm.KeyWrap, jose.HeaderKeyID: keyID}
+
+	return jwe, nil
+}
+
+// Decrypt decrypts the JWE with the key it was encrypted with
+func (c *JWECrypto) Decrypt(jwe *jose.JSONWebEncryption) ([]byte, error) {
+	decrypter, err := c.decrypter(jwe)
+	if err != nil {
+		return nil, err
+	}
+
+	return decrypter.Decrypt(jwe)
+}
+
+// decrypter returns the decrypter of the key of the JWE, the keys of the profile being looked up before the
+// service-wide keys
+func (c *JWECrypto) decrypter(jwe *jose.JSONWebEncryption) (jose.Decrypter, error) {
+	if decrypter, ok := c.loadedDecrypter(jwe); ok {
+		return decrypter, nil
+	}
+
+	if c.parent != nil {
+		if decrypter, ok := c.parent.loadedDecrypter(jwe); ok {
+			return decrypter, nil
+		}
+	}
+
+	keyID := JWEKeyID(jwe)
+
+	if keyID == "" && c.parent != nil {
+		return c.parent.decrypter(jwe)
+	}
+
+	if keyID == "" {
+		return nil, fmt.Errorf("no key to decrypt the JWE algorithm %s", jweAlgorithm(jwe))
+	}
+
+	decrypter, err := c.reloadedDecrypter(keyID)
+	if err == nil || c.parent == nil {
+		return decrypter, err
+	}
+
+	return c.parent.decrypter(jwe)
+}
+
+// loadedDecrypter returns the decrypter of the key of the JWE if it is loaded
+func (c *JWECrypto) loadedDecrypter(jwe *jose.JSONWebEncryption) (jose.Decrypter, bool) {
+	keyID := JWEKeyID(jwe)
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	// the JWEs without key ID were encrypted with the service-wide keys
+	if keyID == "" && c.parent == nil {
+		decrypter, ok := c.firstKeyDecrypters[jweAlgorithm(jwe)]
+
+		return decrypter, ok
+	}
+
+	decrypter, ok := c.decrypters[keyID]
+
+	return decrypter, ok
+}
+
+// reloadedDecrypter reloads the keys to return the decrypter of a key created by another instance
+func (c *JWECrypto) reloadedDecrypter(keyID string) (jose.Decrypter, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.load(); err != nil {
+		return nil, fmt.Errorf("failed to load the JWE keys: %w", err)
+	}
+
+	decrypter, ok := c.decrypters[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown JWE key %s", keyID)
+	}
+
+	return decrypter, nil
+}
+
+// load loads the keys of all the algorithms, the current key of the configured algorithm being the last one
+func (c *JWECrypto) load() error {
+	decrypters := map[string]jose.Decrypter{}
+	firstKeyDecrypters := map[JWEAlgorithm]jose.Decrypter{}
+
+	for algorithm := range jweKeyTypes {
+		keyIDs, err := jweKeyIDs(c.keyIDStore, algorithm, c.profile)
+		if err != nil {
+			return err
+		}
+
+		for i, keyID := range keyIDs {
+			keyHandle, errGet := getKeyHandle(c.keyManager, keyID)
+			if errGet != nil {
+				return errGet
+			}
+
+			decrypters[keyID] = jose.NewJWEDecrypt(keyHandle)
+
+			if i == 0 {
+				firstKeyDecrypters[algorithm] = decrypters[keyID]
+			}
+
+			if algorithm == c.algorithm && i == len(keyIDs)-1 && keyID != c.keyID {
+				c.encrypter, err = createJWEEncrypter(keyHandle, algorithm.Enc, json.Unmarshal, jose.NewJWEEncrypt)
+				if err != nil {
+					return err
+				}
+
+				c.keyID = keyID
+			}
+		}
+	}
+
+	c.decrypters, c.firstKeyDecrypters = decrypters, firstKeyDecrypters
+
+	return nil
+}
+
+// createKey creates a new key of the configured algorithm and makes it the current key
+func (c *JWECrypto) createKey() (string, error) {
+	keyID, keyHandleUntyped, err := c.keyManager.Create(jweKeyTypes[c.algorithm])
 	if err != nil {
-		return nil, nil, err
+		return "", err
 	}
 
-	jweEncrypter, err := createJWEEncrypter(keyHandle, encAlg, json.Unmarshal, jose.NewJWEEncrypt)
+	keyHandle, ok := keyHandleUntyped.(*keyset.Handle)
+	if !ok {
+		return "", errKeySetHandleAssertionFailure
+	}
+
+	encrypter, err := createJWEEncrypter(keyHandle, c.algorithm.Enc, json.Unmarshal, jose.NewJWEEncrypt)
 	if err != nil {
-		return nil, nil, err
+		return "", err
+	}
+
+	if c.profile != "" {
+		if err = c.keyIDStore.Put(jweKeyProfileDBKeyPrefix+keyID, []byte(c.profile)); err != nil {
+			return "", err
+		}
+	}
+
+	// the keys created by other instances are kept
+	keyIDs, err := jweKeyIDs(c.keyIDStore, c.algorithm, c.profile)
+	if err != nil {
+		return "", err
+	}
+
+	keyIDsBytes, err := json.Marshal(append(keyIDs, keyID))
+	if err != nil {
+		return "", err
+	}
+
+	keyIDDBKeyName := c.algorithm.profileKeyIDDBKeyName(c.profile)
+
+	if err = c.keyIDStore.Put(keyIDsDBKeyName(keyIDDBKeyName), keyIDsBytes); err != nil {
+		return "", err
+	}
+
+	// the current key is also kept as it was before the keys could be rotated
+	if err = c.keyIDStore.Put(keyIDDBKeyName, []byte(keyID)); err != nil {
+		return "", err
+	}
+
+	c.decrypters[keyID] = jose.NewJWEDecrypt(keyHandle)
+
+	if _, ok = c.firstKeyDecrypters[c.algorithm]; !ok {
+		c.firstKeyDecrypters[c.algorithm] = c.decrypters[keyID]
 	}
 
-	jweDecrypter := jose.NewJWEDecrypt(keyHandle)
+	c.keyID, c.encrypter = keyID, encrypter
 
-	return jweEncrypter, jweDecrypter, nil
+	return keyID, nil
+}
+
+// JWEKeyID returns the ID of the key recorded in the JWE, an empty string if it isn't recorded
+func JWEKeyID(jwe *jose.JSONWebEncryption) string {
+	if jwe == nil {
+		return ""
+	}
+
+	keyID, _ := jwe.UnprotectedHeaders.KeyID()
+
+	return keyID
+}
+
+// jweAlgorithm returns the algorithm recorded in the JWE (the key wrapping algorithm of the documents stored before
+// it was recorded being the one of their recipient), the default one for the parts not recorded
+func jweAlgorithm(jwe *jose.JSONWebEncryption) JWEAlgorithm {
+	algorithm := DefaultJWEAlgorithm
+
+	if jwe == nil {
+		return algorithm
+	}
+
+	if enc, ok := jwe.ProtectedHeaders.Encryption(); ok {
+		algorithm.Enc = jose.EncAlg(enc)
+	}
+
+	if keyWrap, ok := jwe.UnprotectedHeaders.Algorithm(); ok {
+		algorithm.KeyWrap = keyWrap
+	} else if len(jwe.Recipients) > 0 && jwe.Recipients[0].Header != nil && jwe.Recipients[0].Header.Alg != "" {
+		algorithm.KeyWrap = jwe.Recipients[0].Header.Alg
+	}
+
+	return algorithm
+}
+
+func supportedJWEAlgorithms() string {
+	algorithms := make([]string, 0, len(jweKeyTypes))
+
+	for algorithm := range jweKeyTypes {
+		algorithms = append(algorithms, algorithm.String())
+	}
+
+	sort.Strings(algorithms)
+
+	return strings.Join(algorithms, ", ")
 }
 
 func createJWEEncrypter(keyHandle *keyset.Handle, encAlg jose.EncAlg, unmarshal unmarshalFunc,
@@ -145,6 +556,63 @@ func prepareKeyHandle(storeProvider storage.Provider, keyManager kms.KeyManager,
 	return kh, nil
 }
 
+func getKeyHandle(keyManager kms.KeyManager, keyID string) (*keyset.Handle, error) {
+	keyHandleUntyped, err := keyManager.Get(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	kh, ok := keyHandleUntyped.(*keyset.Handle)
+	if !ok {
+		return nil, errKeySetHandleAssertionFailure
+	}
+
+	return kh, nil
+}
+
+// IsCryptoKey returns if the kms key is one of the JWE (service-wide or profile) or MAC keys prepared for
+// edge-service operations
+func IsCryptoKey(storeProvider storage.Provider, keyID string) (bool, error) {
+	keyIDStore, err := prepareKeyIDStore(storeProvider)
+	if err != nil {
+		return false, err
+	}
+
+	keyIDBytes, err := keyIDStore.Get(hmacKeyIDDBKeyName)
+	if err != nil && !errors.Is(err, storage.ErrValueNotFound) {
+		return false, err
+	}
+
+	if err == nil && string(keyIDBytes) == keyID {
+		return true, nil
+	}
+
+	_, err = keyIDStore.Get(jweKeyProfileDBKeyPrefix + keyID)
+	if err == nil {
+		return true, nil
+	}
+
+	if !errors.Is(err, storage.ErrValueNotFound) {
+		return false, err
+	}
+
+	// all the JWE keys are still used to decrypt the documents they encrypted
+	for algorithm := range jweKeyTypes {
+		keyIDs, errGet := jweKeyIDs(keyIDStore, algorithm, "")
+		if errGet != nil {
+			return false, errGet
+		}
+
+		for _, jweKeyID := range keyIDs {
+			if jweKeyID == keyID {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
 func prepareKeyIDStore(storeProvider storage.Provider) (storage.Store, error) {
 	err := storeProvider.CreateStore(keyIDStoreName)
 	if err != nil {
@@ -9,6 +9,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"strconv"
 	"testing"
 
 	"github.com/google/tink/go/keyset"
@@ -26,16 +27,307 @@ import (
 var errTest = errors.New("testError")
 
 func TestPrepareJWECrypto(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		jweCrypto, err := PrepareJWECrypto(newJWEKeyManager(), mockstore.NewMockStoreProvider(), DefaultJWEAlgorithm)
+		require.NoError(t, err)
+		require.Equal(t, "key1", jweCrypto.KeyID())
+
+		jwe, err := jweCrypto.Encrypt([]byte("plaintext"), nil)
+		require.NoError(t, err)
+		require.Equal(t, jose.Headers{jose.HeaderAlgorithm: ECDHESA256KW, jose.HeaderKeyID: "key1"},
+			jwe.UnprotectedHeaders)
+
+		jwe = roundTripJWE(t, jwe)
+		require.Equal(t, "key1", JWEKeyID(jwe))
+
+		plaintext, err := jweCrypto.Decrypt(jwe)
+		require.NoError(t, err)
+		require.Equal(t, "plaintext", string(plaintext))
+
+		// the documents stored before the algorithm and the key were recorded
+		jwe.UnprotectedHeaders = nil
+
+		plaintext, err = jweCrypto.Decrypt(jwe)
+		require.NoError(t, err)
+		require.Equal(t, "plaintext", string(plaintext))
+	})
+	t.Run("Success: key ID already in store", func(t *testing.T) {
+		keyManager := newJWEKeyManager()
+		mockStoreProvider := mockstore.NewMockStoreProvider()
+
+		_, _, err := keyManager.Create(kmsservice.ECDHES256AES256GCMType)
+		require.NoError(t, err)
+		require.NoError(t, mockStoreProvider.Store.Put(ecdhesKeyIDDBKeyName, []byte("key1")))
+
+		jweCrypto, err := PrepareJWECrypto(keyManager, mockStoreProvider, DefaultJWEAlgorithm)
+		require.NoError(t, err)
+		require.Equal(t, "key1", jweCrypto.KeyID())
+	})
+	t.Run("Success: key rotated", func(t *testing.T) {
+		keyManager := newJWEKeyManager()
+		mockStoreProvider := mockstore.NewMockStoreProvider()
+
+		jweCrypto, err := PrepareJWECrypto(keyManager, mockStoreProvider, DefaultJWEAlgorithm)
+		require.NoError(t, err)
+
+		// the instances loading the keys before the rotation
+		otherCrypto, err := PrepareJWECrypto(keyManager, mockStoreProvider, DefaultJWEAlgorithm)
+		require.NoError(t, err)
+
+		jwe, err := jweCrypto.Encrypt([]byte("key1"), nil)
+		require.NoError(t, err)
+
+		firstKeyJWE := roundTripJWE(t, jwe)
+		firstKeyJWE.UnprotectedHeaders = nil
+
+		keyID, err := jweCrypto.RotateKey()
+		require.NoError(t, err)
+		require.Equal(t, "key2", keyID)
+		require.Equal(t, "key2", jweCrypto.KeyID())
+
+		jwe2, err := jweCrypto.Encrypt([]byte("key2"), nil)
+		require.NoError(t, err)
+		require.Equal(t, "key2", JWEKeyID(jwe2))
+
+		for _, c := range []*JWECrypto{jweCrypto, otherCrypto} {
+			for plaintext, jwe := range map[string]*jose.JSONWebEncryption{"key1": roundTripJWE(t, jwe),
+				"key2": roundTripJWE(t, jwe2)} {
+				decrypted, errDecrypt := c.Decrypt(jwe)
+				require.NoError(t, errDecrypt)
+				require.Equal(t, plaintext, string(decrypted))
+			}
+
+			decrypted, errDecrypt := c.Decrypt(firstKeyJWE)
+			require.NoError(t, errDecrypt)
+			require.Equal(t, "key1", string(decrypted))
+		}
+
+		// the other instance encrypts with the new key once loaded
+		require.Equal(t, "key2", otherCrypto.KeyID())
+
+		keyIDsBytes, err := mockStoreProvider.Store.Get(ecdhesKeyIDDBKeyName + "/keys")
+		require.NoError(t, err)
+		require.Equal(t, `["key1","key2"]`, string(keyIDsBytes))
+
+		for _, keyID := range []string{"key1", "key2"} {
+			isCryptoKey, errCheck := IsCryptoKey(mockStoreProvider, keyID)
+			require.NoError(t, errCheck)
+			require.True(t, isCryptoKey)
+		}
+	})
+	t.Run("Success: documents of another algorithm", func(t *testing.T) {
+		other := JWEAlgorithm{Enc: jose.A256GCM, KeyWrap: "test"}
+
+		jweKeyTypes[other] = kmsservice.ECDHES256AES256GCMType
+		defer delete(jweKeyTypes, other)
+
+		keyManager := newJWEKeyManager()
+		mockStoreProvider := mockstore.NewMockStoreProvider()
+
+		otherCrypto, err := PrepareJWECrypto(keyManager, mockStoreProvider, other)
+		require.NoError(t, err)
+
+		jwe, err := otherCrypto.Encrypt([]byte("plaintext"), nil)
+		require.NoError(t, err)
+
+		jwe = roundTripJWE(t, jwe)
+		jwe.UnprotectedHeaders = jose.Headers{jose.HeaderAlgorithm: "test"}
+
+		// the key of the other algorithm is still used to decrypt its documents
+		jweCrypto, err := PrepareJWECrypto(keyManager, mockStoreProvider, DefaultJWEAlgorithm)
+		require.NoError(t, err)
+		require.Equal(t, "key2", jweCrypto.KeyID())
+
+		plaintext, err := jweCrypto.Decrypt(jwe)
+		require.NoError(t, err)
+		require.Equal(t, "plaintext", string(plaintext))
+
+		_, err = otherCrypto.Decrypt(&jose.JSONWebEncryption{ProtectedHeaders: jose.Headers{
+			jose.HeaderEncryption: "XC20P"}})
+		require.EqualError(t, err, "no key to decrypt the JWE algorithm ECDH-ES+A256KW/XC20P")
+	})
+	t.Run("Unsupported algorithm", func(t *testing.T) {
+		jweCrypto, err := PrepareJWECrypto(&mockkms.KeyManager{}, mockstore.NewMockStoreProvider(),
+			JWEAlgorithm{Enc: "XC20P", KeyWrap: "ECDH-1PU+A256KW"})
+		require.EqualError(t, err, "unsupported JWE algorithm ECDH-1PU+A256KW/XC20P, "+
+			"supported algorithms: ECDH-ES+A256KW/A256GCM")
+		require.Nil(t, jweCrypto)
+	})
+	t.Run("Unknown key", func(t *testing.T) {
+		jweCrypto, err := PrepareJWECrypto(newJWEKeyManager(), mockstore.NewMockStoreProvider(), DefaultJWEAlgorithm)
+		require.NoError(t, err)
+
+		_, err = jweCrypto.Decrypt(&jose.JSONWebEncryption{UnprotectedHeaders: jose.Headers{
+			jose.HeaderKeyID: "other"}})
+		require.EqualError(t, err, "unknown JWE key other")
+	})
+	t.Run("Fail to load the keys of another instance", func(t *testing.T) {
+		mockStoreProvider := mockstore.NewMockStoreProvider()
+
+		jweCrypto, err := PrepareJWECrypto(newJWEKeyManager(), mockStoreProvider, DefaultJWEAlgorithm)
+		require.NoError(t, err)
+
+		mockStoreProvider.Store.ErrGet = errTest
+
+		_, err = jweCrypto.Decrypt(&jose.JSONWebEncryption{UnprotectedHeaders: jose.Headers{
+			jose.HeaderKeyID: "other"}})
+		require.EqualError(t, err, "failed to load the JWE keys: testError")
+	})
 	t.Run("Fail to create JWE Encrypter", func(t *testing.T) {
 		// Calling keyHandle.Public() on an HMAC key set isn't valid, which will cause PrepareJWECrypto to fail
 		keyHandleToBeCreated, err := keyset.NewHandle(mac.HMACSHA256Tag256KeyTemplate())
 		require.NoError(t, err)
 
-		jweEncrypter, jweDecrypter, err := PrepareJWECrypto(&mockkms.KeyManager{CreateKeyValue: keyHandleToBeCreated},
-			mockstore.NewMockStoreProvider(), jose.A256GCM, kmsservice.ECDHES256AES256GCMType)
+		jweCrypto, err := PrepareJWECrypto(&mockkms.KeyManager{CreateKeyValue: keyHandleToBeCreated},
+			mockstore.NewMockStoreProvider(), DefaultJWEAlgorithm)
 		require.EqualError(t, err, "keyset.Handle: keyset.Handle: keyset contains a non-private key")
-		require.Nil(t, jweEncrypter)
-		require.Nil(t, jweDecrypter)
+		require.Nil(t, jweCrypto)
+	})
+	t.Run("Fail to create the key", func(t *testing.T) {
+		jweCrypto, err := PrepareJWECrypto(&mockkms.KeyManager{CreateKeyErr: errTest},
+			mockstore.NewMockStoreProvider(), DefaultJWEAlgorithm)
+		require.Equal(t, errTest, err)
+		require.Nil(t, jweCrypto)
+
+		jweCrypto, err = PrepareJWECrypto(&mockKeyManager{}, mockstore.NewMockStoreProvider(), DefaultJWEAlgorithm)
+		require.Equal(t, errKeySetHandleAssertionFailure, err)
+		require.Nil(t, jweCrypto)
+	})
+	t.Run("Fail to store the key ID", func(t *testing.T) {
+		mockStoreProvider := mockstore.NewMockStoreProvider()
+		mockStoreProvider.Store.ErrPut = errTest
+
+		jweCrypto, err := PrepareJWECrypto(newJWEKeyManager(), mockStoreProvider, DefaultJWEAlgorithm)
+		require.Equal(t, errTest, err)
+		require.Nil(t, jweCrypto)
+	})
+	t.Run("Fail to get the key", func(t *testing.T) {
+		mockStoreProvider := mockstore.NewMockStoreProvider()
+		require.NoError(t, mockStoreProvider.Store.Put(ecdhesKeyIDDBKeyName, []byte("key1")))
+
+		jweCrypto, err := PrepareJWECrypto(&mockkms.KeyManager{GetKeyErr: errTest}, mockStoreProvider,
+			DefaultJWEAlgorithm)
+		require.Equal(t, errTest, err)
+		require.Nil(t, jweCrypto)
+
+		jweCrypto, err = PrepareJWECrypto(&mockKeyManager{}, mockStoreProvider, DefaultJWEAlgorithm)
+		require.Equal(t, errKeySetHandleAssertionFailure, err)
+		require.Nil(t, jweCrypto)
+	})
+	t.Run("Invalid key IDs", func(t *testing.T) {
+		mockStoreProvider := mockstore.NewMockStoreProvider()
+		require.NoError(t, mockStoreProvider.Store.Put(ecdhesKeyIDDBKeyName+"/keys", []byte("{")))
+
+		jweCrypto, err := PrepareJWECrypto(newJWEKeyManager(), mockStoreProvider, DefaultJWEAlgorithm)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unmarshal the JWE key IDs")
+		require.Nil(t, jweCrypto)
+	})
+}
+
+func TestJWECrypto_ProfileCrypto(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		keyManager := newJWEKeyManager()
+		mockStoreProvider := mockstore.NewMockStoreProvider()
+
+		jweCrypto, err := PrepareJWECrypto(keyManager, mockStoreProvider, DefaultJWEAlgorithm)
+		require.NoError(t, err)
+
+		serviceJWE, err := jweCrypto.Encrypt([]byte("service"), nil)
+		require.NoError(t, err)
+
+		serviceJWE = roundTripJWE(t, serviceJWE)
+
+		profileCrypto, err := jweCrypto.ProfileCrypto("profile1")
+		require.NoError(t, err)
+		require.Equal(t, "key2", profileCrypto.KeyID())
+
+		cached, err := profileCrypto.ProfileCrypto("profile1")
+		require.NoError(t, err)
+		require.True(t, cached == profileCrypto)
+
+		otherCrypto, err := jweCrypto.ProfileCrypto("profile2")
+		require.NoError(t, err)
+		require.Equal(t, "key3", otherCrypto.KeyID())
+
+		profileJWE, err := profileCrypto.Encrypt([]byte("profile1"), nil)
+		require.NoError(t, err)
+		require.Equal(t, "key2", JWEKeyID(profileJWE))
+
+		profileJWE = roundTripJWE(t, profileJWE)
+
+		plaintext, err := profileCrypto.Decrypt(profileJWE)
+		require.NoError(t, err)
+		require.Equal(t, "profile1", string(plaintext))
+
+		// the documents encrypted before the profile had its keys
+		plaintext, err = profileCrypto.Decrypt(serviceJWE)
+		require.NoError(t, err)
+		require.Equal(t, "service", string(plaintext))
+
+		serviceJWE.UnprotectedHeaders = nil
+
+		plaintext, err = profileCrypto.Decrypt(serviceJWE)
+		require.NoError(t, err)
+		require.Equal(t, "service", string(plaintext))
+
+		// the keys of a profile don't decrypt the documents of the other profiles
+		_, err = otherCrypto.Decrypt(profileJWE)
+		require.EqualError(t, err, "unknown JWE key key2")
+
+		keyIDsBytes, err := mockStoreProvider.Store.Get(ecdhesKeyIDDBKeyName + "/profile/profile1/keys")
+		require.NoError(t, err)
+		require.Equal(t, `["key2"]`, string(keyIDsBytes))
+
+		for _, keyID := range []string{"key1", "key2", "key3"} {
+			isCryptoKey, errCheck := IsCryptoKey(mockStoreProvider, keyID)
+			require.NoError(t, errCheck)
+			require.True(t, isCryptoKey)
+		}
+	})
+	t.Run("Success: key rotated by another instance", func(t *testing.T) {
+		keyManager := newJWEKeyManager()
+		mockStoreProvider := mockstore.NewMockStoreProvider()
+
+		jweCrypto, err := PrepareJWECrypto(keyManager, mockStoreProvider, DefaultJWEAlgorithm)
+		require.NoError(t, err)
+
+		profileCrypto, err := jweCrypto.ProfileCrypto("profile1")
+		require.NoError(t, err)
+
+		otherInstance, err := PrepareJWECrypto(keyManager, mockStoreProvider, DefaultJWEAlgorithm)
+		require.NoError(t, err)
+
+		otherProfileCrypto, err := otherInstance.ProfileCrypto("profile1")
+		require.NoError(t, err)
+		require.Equal(t, "key2", otherProfileCrypto.KeyID())
+
+		keyID, err := otherProfileCrypto.RotateKey()
+		require.NoError(t, err)
+		require.Equal(t, "key3", keyID)
+
+		jwe, err := otherProfileCrypto.Encrypt([]byte("plaintext"), nil)
+		require.NoError(t, err)
+
+		plaintext, err := profileCrypto.Decrypt(roundTripJWE(t, jwe))
+		require.NoError(t, err)
+		require.Equal(t, "plaintext", string(plaintext))
+		require.Equal(t, "key3", profileCrypto.KeyID())
+
+		// the service-wide key is unchanged
+		require.Equal(t, "key1", jweCrypto.KeyID())
+	})
+	t.Run("Fail to prepare the keys of the profile", func(t *testing.T) {
+		mockStoreProvider := mockstore.NewMockStoreProvider()
+
+		jweCrypto, err := PrepareJWECrypto(newJWEKeyManager(), mockStoreProvider, DefaultJWEAlgorithm)
+		require.NoError(t, err)
+
+		mockStoreProvider.Store.ErrPut = errTest
+
+		profileCrypto, err := jweCrypto.ProfileCrypto("profile1")
+		require.EqualError(t, err, "failed to prepare the JWE keys of profile profile1: testError")
+		require.Nil(t, profileCrypto)
 	})
 }
 
@@ -173,6 +465,45 @@ func TestPrepareMACCrypto(t *testing.T) {
 	})
 }
 
+func TestIsCryptoKey(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mockStoreProvider := mockstore.NewMockStoreProvider()
+		require.NoError(t, mockStoreProvider.Store.Put(ecdhesKeyIDDBKeyName, []byte("jweKeyID")))
+		require.NoError(t, mockStoreProvider.Store.Put(hmacKeyIDDBKeyName, []byte("macKeyID")))
+
+		for keyID, cryptoKey := range map[string]bool{"jweKeyID": true, "macKeyID": true, "otherKeyID": false} {
+			isCryptoKey, err := IsCryptoKey(mockStoreProvider, keyID)
+			require.NoError(t, err)
+			require.Equal(t, cryptoKey, isCryptoKey, keyID)
+		}
+	})
+	t.Run("Success: no key prepared", func(t *testing.T) {
+		isCryptoKey, err := IsCryptoKey(mockstore.NewMockStoreProvider(), "keyID")
+		require.NoError(t, err)
+		require.False(t, isCryptoKey)
+	})
+	t.Run("Unexpected failure while getting key ID from store", func(t *testing.T) {
+		mockStoreProvider := mockstore.NewMockStoreProvider()
+		require.NoError(t, mockStoreProvider.Store.Put(ecdhesKeyIDDBKeyName, []byte("jweKeyID")))
+		mockStoreProvider.Store.ErrGet = errTest
+
+		isCryptoKey, err := IsCryptoKey(mockStoreProvider, "jweKeyID")
+		require.Equal(t, errTest, err)
+		require.False(t, isCryptoKey)
+	})
+}
+
+// roundTripJWE serializes and deserializes the JWE, as the JWEs are stored
+func roundTripJWE(t *testing.T, jwe *jose.JSONWebEncryption) *jose.JSONWebEncryption {
+	serializedJWE, err := jwe.FullSerialize(json.Marshal)
+	require.NoError(t, err)
+
+	jwe, err = jose.Deserialize(serializedJWE)
+	require.NoError(t, err)
+
+	return jwe
+}
+
 type mockKeyManager struct {
 }
 
@@ -200,3 +531,34 @@ func (m mockKeyManager) ImportPrivateKey(
 	privKey interface{}, kt kmsservice.KeyType, opts ...kmsservice.PrivateKeyOpts) (string, interface{}, error) {
 	return "", nil, nil
 }
+
+// jweKeyManager creates the JWE keys and returns them by ID
+type jweKeyManager struct {
+	mockKeyManager
+	keys map[string]*keyset.Handle
+}
+
+func newJWEKeyManager() *jweKeyManager {
+	return &jweKeyManager{keys: map[string]*keyset.Handle{}}
+}
+
+func (m *jweKeyManager) Create(kmsservice.KeyType) (string, interface{}, error) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	if err != nil {
+		return "", nil, err
+	}
+
+	keyID := "key" + strconv.Itoa(len(m.keys)+1)
+	m.keys[keyID] = kh
+
+	return keyID, kh, nil
+}
+
+func (m *jweKeyManager) Get(keyID string) (interface{}, error) {
+	kh, ok := m.keys[keyID]
+	if !ok {
+		return nil, errTest
+	}
+
+	return kh, nil
+}
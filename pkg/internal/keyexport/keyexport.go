@@ -0,0 +1,248 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyexport
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/tink/go/insecurecleartextkeyset"
+	"github.com/google/tink/go/keyset"
+	commonpb "github.com/google/tink/go/proto/common_go_proto"
+	ecdsapb "github.com/google/tink/go/proto/ecdsa_go_proto"
+	ed25519pb "github.com/google/tink/go/proto/ed25519_go_proto"
+	"github.com/google/tink/go/subtle/aead"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+
+	"github.com/trustbloc/edge-service/pkg/kms/signingkms"
+)
+
+const (
+	ecdsaPrivateKeyTypeURL   = "type.googleapis.com/google.crypto.tink.EcdsaPrivateKey"
+	ed25519PrivateKeyTypeURL = "type.googleapis.com/google.crypto.tink.Ed25519PrivateKey"
+)
+
+var errKeySetHandleAssertionFailure = errors.New("unable to assert key handle as a key set handle pointer")
+
+// privateKeyExporter exports the keys which aren't Tink key sets, e.g. the keys of signingkms
+type privateKeyExporter interface {
+	ExportPrivateKey(keyID string) (kms.KeyType, []byte, error)
+}
+
+// wrappedPrivateKey is an exported key which isn't a Tink key set, its private key bytes being encrypted with the
+// key encryption key
+type wrappedPrivateKey struct {
+	KeyType      kms.KeyType `json:"keyType"`
+	EncryptedKey []byte      `json:"encryptedKey"`
+}
+
+// ExportKey exports the key set of the given keyID encrypted with the key encryption key (AES-GCM, 16 or 32 bytes).
+// The keys of signingkms (secp256k1, P-384 SHA-384 and RSA) are exported as their encrypted private key bytes. The
+// wrapped key is returned base64url encoded.
+func ExportKey(keyManager kms.KeyManager, keyID string, kek []byte) (string, error) {
+	keyHandle, err := keyManager.Get(keyID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get key %s: %w", keyID, err)
+	}
+
+	kh, ok := keyHandle.(*keyset.Handle)
+	if !ok {
+		exporter, ok := keyManager.(privateKeyExporter)
+		if !ok {
+			return "", errKeySetHandleAssertionFailure
+		}
+
+		return exportPrivateKey(exporter, keyID, kek)
+	}
+
+	kekAEAD, err := aead.NewAESGCM(kek)
+	if err != nil {
+		return "", fmt.Errorf("invalid key encryption key: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+
+	err = kh.Write(keyset.NewJSONWriter(buf), kekAEAD)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap key %s: %w", keyID, err)
+	}
+
+	return base64.URLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func exportPrivateKey(exporter privateKeyExporter, keyID string, kek []byte) (string, error) {
+	keyType, keyBytes, err := exporter.ExportPrivateKey(keyID)
+	if err != nil {
+		return "", fmt.Errorf("failed to export key %s: %w", keyID, err)
+	}
+
+	kekAEAD, err := aead.NewAESGCM(kek)
+	if err != nil {
+		return "", fmt.Errorf("invalid key encryption key: %w", err)
+	}
+
+	// the key ID and type are authenticated with the key, an exported key can't be imported as another one
+	encryptedKey, err := kekAEAD.Encrypt(keyBytes, []byte(keyID+string(keyType)))
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap key %s: %w", keyID, err)
+	}
+
+	wrappedKeyBytes, err := json.Marshal(&wrappedPrivateKey{KeyType: keyType, EncryptedKey: encryptedKey})
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap key %s: %w", keyID, err)
+	}
+
+	return base64.URLEncoding.EncodeToString(wrappedKeyBytes), nil
+}
+
+// ImportKey unwraps the key exported by ExportKey and imports its primary private key into the key manager
+// under the given keyID. Ed25519 and ECDSA keys, and the keys of signingkms, are supported.
+func ImportKey(keyManager kms.KeyManager, keyID, wrappedKey string, kek []byte) error {
+	wrappedKeyBytes, err := base64.URLEncoding.DecodeString(wrappedKey)
+	if err != nil {
+		return fmt.Errorf("failed to decode wrapped key %s: %w", keyID, err)
+	}
+
+	kekAEAD, err := aead.NewAESGCM(kek)
+	if err != nil {
+		return fmt.Errorf("invalid key encryption key: %w", err)
+	}
+
+	// the wrapped key sets have no key type
+	wrapped := &wrappedPrivateKey{}
+	if json.Unmarshal(wrappedKeyBytes, wrapped) == nil && wrapped.KeyType != "" {
+		return importPrivateKey(keyManager, keyID, wrapped, kekAEAD)
+	}
+
+	kh, err := keyset.Read(keyset.NewJSONReader(bytes.NewReader(wrappedKeyBytes)), kekAEAD)
+	if err != nil {
+		return fmt.Errorf("failed to unwrap key %s: %w", keyID, err)
+	}
+
+	privKey, keyType, err := primaryPrivateKey(kh)
+	if err != nil {
+		return fmt.Errorf("failed to read key %s: %w", keyID, err)
+	}
+
+	_, _, err = keyManager.ImportPrivateKey(privKey, keyType, kms.WithKeyID(keyID))
+	if err != nil {
+		return fmt.Errorf("failed to import key %s: %w", keyID, err)
+	}
+
+	return nil
+}
+
+func importPrivateKey(keyManager kms.KeyManager, keyID string, wrapped *wrappedPrivateKey,
+	kekAEAD *aead.AESGCM) error {
+	keyBytes, err := kekAEAD.Decrypt(wrapped.EncryptedKey, []byte(keyID+string(wrapped.KeyType)))
+	if err != nil {
+		return fmt.Errorf("failed to unwrap key %s: %w", keyID, err)
+	}
+
+	privKey, err := signingkms.ParsePrivateKey(wrapped.KeyType, keyBytes)
+	if err != nil {
+		return fmt.Errorf("failed to read key %s: %w", keyID, err)
+	}
+
+	_, _, err = keyManager.ImportPrivateKey(privKey, wrapped.KeyType, kms.WithKeyID(keyID))
+	if err != nil {
+		return fmt.Errorf("failed to import key %s: %w", keyID, err)
+	}
+
+	return nil
+}
+
+func primaryPrivateKey(kh *keyset.Handle) (interface{}, kms.KeyType, error) {
+	mem := &keyset.MemReaderWriter{}
+
+	err := insecurecleartextkeyset.Write(kh, mem)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, key := range mem.Keyset.Key {
+		if key.KeyId != mem.Keyset.PrimaryKeyId {
+			continue
+		}
+
+		switch key.KeyData.TypeUrl {
+		case ed25519PrivateKeyTypeURL:
+			return ed25519PrivateKey(key.KeyData.Value)
+		case ecdsaPrivateKeyTypeURL:
+			return ecdsaPrivateKey(key.KeyData.Value)
+		default:
+			return nil, "", fmt.Errorf("key type not supported %s", key.KeyData.TypeUrl)
+		}
+	}
+
+	return nil, "", errors.New("primary key not found")
+}
+
+func ed25519PrivateKey(value []byte) (interface{}, kms.KeyType, error) {
+	pbKey := &ed25519pb.Ed25519PrivateKey{}
+
+	err := proto.Unmarshal(value, pbKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(pbKey.KeyValue) != ed25519.SeedSize {
+		return nil, "", errors.New("invalid ed25519 private key")
+	}
+
+	return ed25519.NewKeyFromSeed(pbKey.KeyValue), kms.ED25519Type, nil
+}
+
+func ecdsaPrivateKey(value []byte) (interface{}, kms.KeyType, error) {
+	pbKey := &ecdsapb.EcdsaPrivateKey{}
+
+	err := proto.Unmarshal(value, pbKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if pbKey.PublicKey == nil || pbKey.PublicKey.Params == nil {
+		return nil, "", errors.New("invalid ecdsa private key")
+	}
+
+	var (
+		curve   elliptic.Curve
+		keyType kms.KeyType
+		der     = pbKey.PublicKey.Params.Encoding == ecdsapb.EcdsaSignatureEncoding_DER
+	)
+
+	switch pbKey.PublicKey.Params.Curve {
+	case commonpb.EllipticCurveType_NIST_P256:
+		curve, keyType = elliptic.P256(), kms.ECDSAP256TypeIEEEP1363
+		if der {
+			keyType = kms.ECDSAP256TypeDER
+		}
+	case commonpb.EllipticCurveType_NIST_P384:
+		curve, keyType = elliptic.P384(), kms.ECDSAP384TypeIEEEP1363
+		if der {
+			keyType = kms.ECDSAP384TypeDER
+		}
+	default:
+		return nil, "", fmt.Errorf("ecdsa curve not supported %s", pbKey.PublicKey.Params.Curve)
+	}
+
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(pbKey.PublicKey.X),
+			Y:     new(big.Int).SetBytes(pbKey.PublicKey.Y),
+		},
+		D: new(big.Int).SetBytes(pbKey.KeyValue),
+	}, keyType, nil
+}
@@ -0,0 +1,256 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keyexport
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/subtle/aead"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	ariesstorage "github.com/hyperledger/aries-framework-go/pkg/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/kms/signingkms"
+)
+
+func TestExportImportKey(t *testing.T) {
+	kek := []byte("0123456789abcdef0123456789abcdef")
+
+	for _, keyType := range []kms.KeyType{kms.ED25519Type, kms.ECDSAP256TypeIEEEP1363, kms.ECDSAP384TypeDER} {
+		source := newLocalKMS(t)
+		target := newLocalKMS(t)
+
+		keyID, _, err := source.Create(keyType)
+		require.NoError(t, err)
+
+		wrappedKey, err := ExportKey(source, keyID, kek)
+		require.NoError(t, err)
+		require.NotEmpty(t, wrappedKey)
+
+		require.NoError(t, ImportKey(target, keyID, wrappedKey, kek))
+
+		sourcePubKey, err := source.ExportPubKeyBytes(keyID)
+		require.NoError(t, err)
+
+		targetPubKey, err := target.ExportPubKeyBytes(keyID)
+		require.NoError(t, err)
+		require.Equal(t, sourcePubKey, targetPubKey)
+	}
+}
+
+func TestExportImportSigningKMSKey(t *testing.T) {
+	kek := []byte("0123456789abcdef0123456789abcdef")
+
+	for _, keyType := range []kms.KeyType{signingkms.Secp256k1KeyType, signingkms.P384KeyType,
+		signingkms.RSAKeyType} {
+		source := newSigningKMS(t)
+		target := newSigningKMS(t)
+
+		keyID, _, err := source.Create(keyType)
+		require.NoError(t, err)
+
+		wrappedKey, err := ExportKey(source, keyID, kek)
+		require.NoError(t, err)
+
+		require.NoError(t, ImportKey(target, keyID, wrappedKey, kek))
+
+		sourcePubKey, err := source.ExportPubKeyBytes(keyID)
+		require.NoError(t, err)
+
+		targetPubKey, err := target.ExportPubKeyBytes(keyID)
+		require.NoError(t, err)
+		require.Equal(t, sourcePubKey, targetPubKey, keyType)
+	}
+
+	// the keys of the wrapped key manager are still exported as key sets
+	source := newSigningKMS(t)
+
+	keyID, _, err := source.Create(kms.ED25519Type)
+	require.NoError(t, err)
+
+	wrappedKey, err := ExportKey(source, keyID, kek)
+	require.NoError(t, err)
+
+	require.NoError(t, ImportKey(newSigningKMS(t), keyID, wrappedKey, kek))
+
+	t.Run("test error - wrong kek", func(t *testing.T) {
+		keyID, _, err := source.Create(signingkms.Secp256k1KeyType)
+		require.NoError(t, err)
+
+		wrappedKey, err := ExportKey(source, keyID, kek)
+		require.NoError(t, err)
+
+		err = ImportKey(newSigningKMS(t), keyID, wrappedKey, []byte("fedcba9876543210"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unwrap key")
+
+		// the wrapped key is bound to its key ID
+		err = ImportKey(newSigningKMS(t), "other", wrappedKey, kek)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unwrap key other")
+
+		_, err = ExportKey(source, keyID, []byte("kek"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid key encryption key")
+	})
+
+	t.Run("test error - key manager without the key type", func(t *testing.T) {
+		keyID, _, err := source.Create(signingkms.RSAKeyType)
+		require.NoError(t, err)
+
+		wrappedKey, err := ExportKey(source, keyID, kek)
+		require.NoError(t, err)
+
+		err = ImportKey(newLocalKMS(t), keyID, wrappedKey, kek)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to import key "+keyID)
+	})
+
+	t.Run("test error - invalid private key", func(t *testing.T) {
+		kekAEAD, err := aead.NewAESGCM(kek)
+		require.NoError(t, err)
+
+		encryptedKey, err := kekAEAD.Encrypt([]byte("key"), []byte("key1"+string(signingkms.RSAKeyType)))
+		require.NoError(t, err)
+
+		wrappedKeyBytes, err := json.Marshal(&wrappedPrivateKey{KeyType: signingkms.RSAKeyType,
+			EncryptedKey: encryptedKey})
+		require.NoError(t, err)
+
+		err = ImportKey(newSigningKMS(t), "key1", base64.URLEncoding.EncodeToString(wrappedKeyBytes), kek)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read key key1")
+	})
+}
+
+func TestExportKey(t *testing.T) {
+	t.Run("test error - get key", func(t *testing.T) {
+		_, err := ExportKey(&mockkms.KeyManager{GetKeyErr: errors.New("get error")}, "key1", nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get key key1: get error")
+	})
+
+	t.Run("test error - key handle not a key set", func(t *testing.T) {
+		_, err := ExportKey(&stringHandleKeyManager{}, "key1", nil)
+		require.Equal(t, errKeySetHandleAssertionFailure, err)
+	})
+
+	t.Run("test error - invalid kek", func(t *testing.T) {
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		_, err = ExportKey(&mockkms.KeyManager{GetKeyValue: kh}, "key1", []byte("kek"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid key encryption key")
+	})
+}
+
+func TestImportKey(t *testing.T) {
+	kek := []byte("0123456789abcdef")
+
+	t.Run("test error - invalid wrapped key", func(t *testing.T) {
+		err := ImportKey(&mockkms.KeyManager{}, "key1", "!invalid", kek)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to decode wrapped key key1")
+
+		err = ImportKey(&mockkms.KeyManager{}, "key1", "", []byte("kek"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid key encryption key")
+
+		err = ImportKey(&mockkms.KeyManager{}, "key1", "e30=", kek)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unwrap key key1")
+	})
+
+	t.Run("test error - wrong kek", func(t *testing.T) {
+		source := newLocalKMS(t)
+
+		keyID, _, err := source.Create(kms.ED25519Type)
+		require.NoError(t, err)
+
+		wrappedKey, err := ExportKey(source, keyID, kek)
+		require.NoError(t, err)
+
+		err = ImportKey(newLocalKMS(t), keyID, wrappedKey, []byte("fedcba9876543210"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unwrap key")
+	})
+
+	t.Run("test error - key type not supported", func(t *testing.T) {
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		wrappedKey, err := ExportKey(&mockkms.KeyManager{GetKeyValue: kh}, "key1", kek)
+		require.NoError(t, err)
+
+		err = ImportKey(&mockkms.KeyManager{}, "key1", wrappedKey, kek)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to read key key1: key type not supported")
+	})
+
+	t.Run("test error - import private key", func(t *testing.T) {
+		source := newLocalKMS(t)
+
+		keyID, _, err := source.Create(kms.ED25519Type)
+		require.NoError(t, err)
+
+		wrappedKey, err := ExportKey(source, keyID, kek)
+		require.NoError(t, err)
+
+		err = ImportKey(&mockkms.KeyManager{ImportPrivateKeyErr: errors.New("import error")}, keyID, wrappedKey, kek)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "import error")
+	})
+}
+
+type stringHandleKeyManager struct {
+	mockkms.KeyManager
+}
+
+func (k *stringHandleKeyManager) Get(keyID string) (interface{}, error) {
+	return keyID, nil
+}
+
+type kmsProvider struct {
+	storageProvider ariesstorage.Provider
+}
+
+func (k kmsProvider) StorageProvider() ariesstorage.Provider {
+	return k.storageProvider
+}
+
+func (k kmsProvider) SecretLock() secretlock.Service {
+	return &noop.NoLock{}
+}
+
+func newSigningKMS(t *testing.T) *signingkms.KeyManager {
+	provider := mem.NewProvider()
+
+	localKMS, err := localkms.New("local-lock://test/key-uri/", kmsProvider{storageProvider: provider})
+	require.NoError(t, err)
+
+	km, err := signingkms.New(localKMS, provider)
+	require.NoError(t, err)
+
+	return km
+}
+
+func newLocalKMS(t *testing.T) *localkms.LocalKMS {
+	km, err := localkms.New("local-lock://test/key-uri/", kmsProvider{storageProvider: mem.NewProvider()})
+	require.NoError(t, err)
+
+	return km
+}
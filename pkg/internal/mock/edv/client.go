@@ -16,6 +16,8 @@ type Client struct {
 	ReadDocumentSubsequentReturnValue *models.EncryptedDocument
 	readDocumentCalledAtLeastOnce     bool
 	QueryVaultReturnValue             []string
+	DeletedDocIDs                     []string
+	DeleteDocumentErr                 error
 }
 
 // NewMockEDVClient is the mock version of edv client
@@ -51,3 +53,30 @@ func (c *Client) ReadDocument(vaultID, docID string) (*models.EncryptedDocument,
 func (c *Client) QueryVault(vaultID string, query *models.Query) ([]string, error) {
 	return c.QueryVaultReturnValue, nil
 }
+
+// CreateDocuments stores the specified documents.
+func (c *Client) CreateDocuments(vaultID string, documents []*models.EncryptedDocument) ([]string, error) {
+	return make([]string, len(documents)), nil
+}
+
+// ReadDocuments mocks a ReadDocuments call, reading the documents one after the other. It never returns an error.
+func (c *Client) ReadDocuments(vaultID string, docIDs []string) ([]*models.EncryptedDocument, error) {
+	documents := make([]*models.EncryptedDocument, len(docIDs))
+
+	for i, docID := range docIDs {
+		documents[i], _ = c.ReadDocument(vaultID, docID)
+	}
+
+	return documents, nil
+}
+
+// DeleteDocument records the ID of the deleted document.
+func (c *Client) DeleteDocument(vaultID, docID string) error {
+	if c.DeleteDocumentErr != nil {
+		return c.DeleteDocumentErr
+	}
+
+	c.DeletedDocIDs = append(c.DeletedDocIDs, docID)
+
+	return nil
+}
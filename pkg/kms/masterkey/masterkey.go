@@ -0,0 +1,263 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package masterkey manages the master key protecting the Tink keysets stored by localkms in the kms secrets store.
+//
+// The keysets are encrypted with data keys wrapped by the master key (Tink KMS envelope encryption). The master key
+// itself is stored in the kms secrets store as well, wrapped with a key encryption key (KEK) sourced from outside of
+// the store (e.g. a passphrase or a remote KMS key). Without a KEK the master key is stored unencrypted.
+package masterkey
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/subtle/random"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/local"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/local/masterlock/hkdf"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+const (
+	// URI is the master key URI to be passed to localkms
+	URI = "local-lock://custom/master/key/"
+
+	storeName = "masterkey"
+	dbKeyName = storeName
+	// pendingDBKeyName stores the new master key while a rotation is in progress
+	pendingDBKeyName = storeName + "_pending"
+
+	masterKeySize = 32
+)
+
+// NewPassphraseKEK returns a key encryption key lock deriving the key from the given passphrase (HKDF SHA-256)
+func NewPassphraseKEK(passphrase string) (secretlock.Service, error) {
+	return hkdf.NewMasterLock(passphrase, sha256.New, nil)
+}
+
+// NewLock returns the secret lock service to be used by localkms. It is backed by the master key stored in the
+// kms secrets store, the master key is created on first use and stored wrapped with the kek (optional).
+func NewLock(kmsSecretsProvider storage.Provider, kek secretlock.Service) (secretlock.Service, error) {
+	masterKeyStore, err := kmsSecretsProvider.OpenStore(storeName)
+	if err != nil {
+		return nil, err
+	}
+
+	storedMasterKey, err := masterKeyStore.Get(dbKeyName)
+	if err != nil {
+		if !errors.Is(err, storage.ErrDataNotFound) {
+			return nil, err
+		}
+
+		storedMasterKey, err = wrapMasterKey(random.GetRandomBytes(masterKeySize), kek)
+		if err != nil {
+			return nil, err
+		}
+
+		err = masterKeyStore.Put(dbKeyName, storedMasterKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return local.NewService(bytes.NewReader(storedMasterKey), kek)
+}
+
+// Rotate replaces the master key with a new one wrapped with the new kek. All the keysets stored by localkms
+// are re-wrapped with the new master key. The service using the store must be stopped during the rotation.
+//
+// The new master key is stored under a staging key before any keyset is re-wrapped, and replaces the current master
+// key only once all the keysets are re-wrapped. An interrupted rotation is resumed by running it again with the same
+// keks: the staged master key is reused and the keysets already re-wrapped are skipped.
+func Rotate(kmsSecretsProvider storage.Provider, currentKEK, newKEK secretlock.Service) error {
+	masterKeyStore, err := kmsSecretsProvider.OpenStore(storeName)
+	if err != nil {
+		return err
+	}
+
+	stagedMasterKey, err := masterKeyStore.Get(pendingDBKeyName)
+	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		return err
+	}
+
+	storedMasterKey, err := masterKeyStore.Get(dbKeyName)
+	if err != nil && !errors.Is(err, storage.ErrDataNotFound) {
+		return err
+	}
+
+	// interrupted after the master key was replaced, only the staging key is left to remove
+	if len(stagedMasterKey) != 0 && bytes.Equal(storedMasterKey, stagedMasterKey) {
+		return masterKeyStore.Delete(pendingDBKeyName)
+	}
+
+	currentLock, err := NewLock(kmsSecretsProvider, currentKEK)
+	if err != nil {
+		return fmt.Errorf("failed to unwrap current master key: %w", err)
+	}
+
+	newStoredMasterKey, err := stageMasterKey(masterKeyStore, stagedMasterKey, newKEK)
+	if err != nil {
+		return err
+	}
+
+	newLock, err := local.NewService(bytes.NewReader(newStoredMasterKey), newKEK)
+	if err != nil {
+		return fmt.Errorf("failed to unwrap staged master key: %w", err)
+	}
+
+	kmsStore, err := kmsSecretsProvider.OpenStore(localkms.Namespace)
+	if err != nil {
+		return err
+	}
+
+	err = rewrapKeysets(kmsStore, envelopeAEAD(currentLock), envelopeAEAD(newLock))
+	if err != nil {
+		return err
+	}
+
+	err = masterKeyStore.Put(dbKeyName, newStoredMasterKey)
+	if err != nil {
+		return err
+	}
+
+	return masterKeyStore.Delete(pendingDBKeyName)
+}
+
+// stageMasterKey returns the new master key of the rotation in progress, or stores a new one under the staging key
+func stageMasterKey(masterKeyStore storage.Store, stagedMasterKey []byte, newKEK secretlock.Service) ([]byte, error) {
+	if len(stagedMasterKey) != 0 {
+		return stagedMasterKey, nil
+	}
+
+	stagedMasterKey, err := wrapMasterKey(random.GetRandomBytes(masterKeySize), newKEK)
+	if err != nil {
+		return nil, err
+	}
+
+	err = masterKeyStore.Put(pendingDBKeyName, stagedMasterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage new master key: %w", err)
+	}
+
+	return stagedMasterKey, nil
+}
+
+// rewrapKeysets re-wraps and stores the keysets one by one, the keysets already wrapped with the new master key
+// (by an interrupted rotation) are skipped.
+func rewrapKeysets(kmsStore storage.Store, currentAEAD, newAEAD *aead.KMSEnvelopeAEAD) error {
+	keysets, err := readKeysets(kmsStore)
+	if err != nil {
+		return err
+	}
+
+	// all the keysets are unwrapped before any is stored, an invalid keyset fails the rotation without changes
+	rewrapped := make(map[string][]byte)
+
+	for keysetID, keysetBytes := range keysets {
+		if _, err := keyset.Read(keyset.NewJSONReader(bytes.NewReader(keysetBytes)), newAEAD); err == nil {
+			continue
+		}
+
+		kh, err := keyset.Read(keyset.NewJSONReader(bytes.NewReader(keysetBytes)), currentAEAD)
+		if err != nil {
+			return fmt.Errorf("failed to unwrap keyset %s: %w", keysetID, err)
+		}
+
+		buf := new(bytes.Buffer)
+
+		err = kh.Write(keyset.NewJSONWriter(buf), newAEAD)
+		if err != nil {
+			return fmt.Errorf("failed to wrap keyset %s: %w", keysetID, err)
+		}
+
+		rewrapped[keysetID] = buf.Bytes()
+	}
+
+	for keysetID, keysetBytes := range rewrapped {
+		if err := kmsStore.Put(keysetID, keysetBytes); err != nil {
+			return fmt.Errorf("failed to store keyset %s: %w", keysetID, err)
+		}
+	}
+
+	return nil
+}
+
+func readKeysets(kmsStore storage.Store) (map[string][]byte, error) {
+	keysets := make(map[string][]byte)
+
+	itr := kmsStore.Iterator("", storage.EndKeySuffix)
+	defer itr.Release()
+
+	for itr.Next() {
+		keysets[string(itr.Key())] = append([]byte(nil), itr.Value()...)
+	}
+
+	if err := itr.Error(); err != nil {
+		return nil, err
+	}
+
+	return keysets, nil
+}
+
+// wrapMasterKey returns the master key as stored in the kms secrets store: wrapped with the kek or, without kek,
+// base64url encoded (as expected by the local secret lock service).
+func wrapMasterKey(masterKey []byte, kek secretlock.Service) ([]byte, error) {
+	if kek == nil {
+		return []byte(base64.URLEncoding.EncodeToString(masterKey)), nil
+	}
+
+	resp, err := kek.Encrypt("", &secretlock.EncryptRequest{Plaintext: string(masterKey)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap master key: %w", err)
+	}
+
+	return []byte(resp.Ciphertext), nil
+}
+
+// envelopeAEAD returns the envelope AEAD used by localkms to wrap the keysets with the given lock.
+func envelopeAEAD(lock secretlock.Service) *aead.KMSEnvelopeAEAD {
+	return aead.NewKMSEnvelopeAEAD(*aead.AES256GCMKeyTemplate(), &lockAEAD{
+		keyURI: strings.TrimPrefix(URI, "local-lock://"),
+		lock:   lock,
+	})
+}
+
+// lockAEAD is a tink.AEAD backed by a secret lock, compatible with the key wrapper used internally by localkms.
+type lockAEAD struct {
+	keyURI string
+	lock   secretlock.Service
+}
+
+func (a *lockAEAD) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	resp, err := a.lock.Encrypt(a.keyURI, &secretlock.EncryptRequest{
+		Plaintext:                   base64.URLEncoding.EncodeToString(plaintext),
+		AdditionalAuthenticatedData: base64.URLEncoding.EncodeToString(additionalData),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.URLEncoding.DecodeString(resp.Ciphertext)
+}
+
+func (a *lockAEAD) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	resp, err := a.lock.Decrypt(a.keyURI, &secretlock.DecryptRequest{
+		Ciphertext:                  base64.URLEncoding.EncodeToString(ciphertext),
+		AdditionalAuthenticatedData: base64.URLEncoding.EncodeToString(additionalData),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.URLEncoding.DecodeString(resp.Plaintext)
+}
@@ -0,0 +1,291 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package masterkey
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLock(t *testing.T) {
+	t.Run("test success - without kek", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		keyID, pubKey := createKey(t, provider, nil)
+
+		require.Equal(t, pubKey, exportPubKey(t, provider, nil, keyID))
+	})
+
+	t.Run("test success - with kek", func(t *testing.T) {
+		provider := mem.NewProvider()
+		kek := newPassphraseKEK(t, "passphrase")
+
+		keyID, pubKey := createKey(t, provider, kek)
+
+		require.Equal(t, pubKey, exportPubKey(t, provider, newPassphraseKEK(t, "passphrase"), keyID))
+
+		_, err := NewLock(provider, newPassphraseKEK(t, "other"))
+		require.Error(t, err)
+	})
+
+	t.Run("test error - open store", func(t *testing.T) {
+		_, err := NewLock(&mockstorage.MockStoreProvider{FailNamespace: storeName}, nil)
+		require.EqualError(t, err, "failed to open store for name space masterkey")
+	})
+
+	t.Run("test error - get master key", func(t *testing.T) {
+		_, err := NewLock(&mockstorage.MockStoreProvider{Store: &mockstorage.MockStore{
+			ErrGet: errors.New("get error")}}, nil)
+		require.EqualError(t, err, "get error")
+	})
+
+	t.Run("test error - put master key", func(t *testing.T) {
+		_, err := NewLock(&mockstorage.MockStoreProvider{Store: &mockstorage.MockStore{
+			ErrGet: storage.ErrDataNotFound, ErrPut: errors.New("put error")}}, nil)
+		require.EqualError(t, err, "put error")
+	})
+
+	t.Run("test error - empty master key", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		masterKeyStore, err := provider.OpenStore(storeName)
+		require.NoError(t, err)
+		require.NoError(t, masterKeyStore.Put(dbKeyName, []byte("")))
+
+		_, err = NewLock(provider, nil)
+		require.EqualError(t, err, "masterKeyReader is empty")
+	})
+
+	t.Run("test error - wrap master key", func(t *testing.T) {
+		_, err := NewLock(mem.NewProvider(), &mockLock{encryptErr: errors.New("encrypt error")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to wrap master key: encrypt error")
+	})
+}
+
+func TestRotate(t *testing.T) {
+	t.Run("test success - add kek", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		keyID, pubKey := createKey(t, provider, nil)
+
+		require.NoError(t, Rotate(provider, nil, newPassphraseKEK(t, "passphrase")))
+
+		require.Equal(t, pubKey, exportPubKey(t, provider, newPassphraseKEK(t, "passphrase"), keyID))
+	})
+
+	t.Run("test success - rotate kek", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		keyID1, pubKey1 := createKey(t, provider, newPassphraseKEK(t, "old"))
+		keyID2, pubKey2 := createKey(t, provider, newPassphraseKEK(t, "old"))
+
+		require.NoError(t, Rotate(provider, newPassphraseKEK(t, "old"), newPassphraseKEK(t, "new")))
+
+		require.Equal(t, pubKey1, exportPubKey(t, provider, newPassphraseKEK(t, "new"), keyID1))
+		require.Equal(t, pubKey2, exportPubKey(t, provider, newPassphraseKEK(t, "new"), keyID2))
+
+		_, err := NewLock(provider, newPassphraseKEK(t, "old"))
+		require.Error(t, err)
+	})
+
+	t.Run("test error - wrong current kek", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		keyID, pubKey := createKey(t, provider, newPassphraseKEK(t, "old"))
+
+		err := Rotate(provider, newPassphraseKEK(t, "wrong"), newPassphraseKEK(t, "new"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unwrap current master key")
+
+		require.Equal(t, pubKey, exportPubKey(t, provider, newPassphraseKEK(t, "old"), keyID))
+	})
+
+	t.Run("test error - invalid keyset", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		keyID, pubKey := createKey(t, provider, nil)
+
+		kmsStore, err := provider.OpenStore(localkms.Namespace)
+		require.NoError(t, err)
+		require.NoError(t, kmsStore.Put("invalid", []byte("{}")))
+
+		err = Rotate(provider, nil, newPassphraseKEK(t, "new"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unwrap keyset invalid")
+
+		require.Equal(t, pubKey, exportPubKey(t, provider, nil, keyID))
+	})
+
+	t.Run("test success - resume interrupted rotation", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		keyID1, pubKey1 := createKey(t, provider, newPassphraseKEK(t, "old"))
+		keyID2, pubKey2 := createKey(t, provider, newPassphraseKEK(t, "old"))
+
+		// the second keyset fails to be stored
+		failingProvider := &failingKMSStoreProvider{Provider: provider, putsBeforeErr: 1}
+
+		err := Rotate(failingProvider, newPassphraseKEK(t, "old"), newPassphraseKEK(t, "new"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store keyset")
+
+		masterKeyStore, err := provider.OpenStore(storeName)
+		require.NoError(t, err)
+
+		_, err = masterKeyStore.Get(pendingDBKeyName)
+		require.NoError(t, err)
+
+		// the current master key is still stored
+		_, err = NewLock(provider, newPassphraseKEK(t, "old"))
+		require.NoError(t, err)
+
+		require.NoError(t, Rotate(provider, newPassphraseKEK(t, "old"), newPassphraseKEK(t, "new")))
+
+		require.Equal(t, pubKey1, exportPubKey(t, provider, newPassphraseKEK(t, "new"), keyID1))
+		require.Equal(t, pubKey2, exportPubKey(t, provider, newPassphraseKEK(t, "new"), keyID2))
+
+		_, err = masterKeyStore.Get(pendingDBKeyName)
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+	})
+
+	t.Run("test success - resume rotation interrupted after the master key was replaced", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		keyID, pubKey := createKey(t, provider, newPassphraseKEK(t, "old"))
+
+		require.NoError(t, Rotate(provider, newPassphraseKEK(t, "old"), newPassphraseKEK(t, "new")))
+
+		masterKeyStore, err := provider.OpenStore(storeName)
+		require.NoError(t, err)
+
+		storedMasterKey, err := masterKeyStore.Get(dbKeyName)
+		require.NoError(t, err)
+		require.NoError(t, masterKeyStore.Put(pendingDBKeyName, storedMasterKey))
+
+		require.NoError(t, Rotate(provider, newPassphraseKEK(t, "old"), newPassphraseKEK(t, "new")))
+
+		_, err = masterKeyStore.Get(pendingDBKeyName)
+		require.True(t, errors.Is(err, storage.ErrDataNotFound))
+
+		require.Equal(t, pubKey, exportPubKey(t, provider, newPassphraseKEK(t, "new"), keyID))
+	})
+
+	t.Run("test error - resume with another new kek", func(t *testing.T) {
+		provider := mem.NewProvider()
+
+		createKey(t, provider, nil)
+
+		err := Rotate(&failingKMSStoreProvider{Provider: provider}, nil, newPassphraseKEK(t, "new"))
+		require.Error(t, err)
+
+		err = Rotate(provider, nil, newPassphraseKEK(t, "other"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to unwrap staged master key")
+	})
+
+	t.Run("test error - wrap new master key", func(t *testing.T) {
+		err := Rotate(mem.NewProvider(), nil, &mockLock{encryptErr: errors.New("encrypt error")})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to wrap master key: encrypt error")
+	})
+}
+
+func createKey(t *testing.T, provider storage.Provider, kek secretlock.Service) (string, []byte) {
+	km := newLocalKMS(t, provider, kek)
+
+	keyID, _, err := km.Create(kms.ED25519Type)
+	require.NoError(t, err)
+
+	pubKey, err := km.ExportPubKeyBytes(keyID)
+	require.NoError(t, err)
+
+	return keyID, pubKey
+}
+
+func exportPubKey(t *testing.T, provider storage.Provider, kek secretlock.Service, keyID string) []byte {
+	pubKey, err := newLocalKMS(t, provider, kek).ExportPubKeyBytes(keyID)
+	require.NoError(t, err)
+
+	return pubKey
+}
+
+func newLocalKMS(t *testing.T, provider storage.Provider, kek secretlock.Service) *localkms.LocalKMS {
+	lock, err := NewLock(provider, kek)
+	require.NoError(t, err)
+
+	km, err := localkms.New(URI, &kmsProvider{storageProvider: provider, secretLock: lock})
+	require.NoError(t, err)
+
+	return km
+}
+
+func newPassphraseKEK(t *testing.T, passphrase string) secretlock.Service {
+	kek, err := NewPassphraseKEK(passphrase)
+	require.NoError(t, err)
+
+	return kek
+}
+
+type kmsProvider struct {
+	storageProvider storage.Provider
+	secretLock      secretlock.Service
+}
+
+func (k *kmsProvider) StorageProvider() storage.Provider {
+	return k.storageProvider
+}
+
+func (k *kmsProvider) SecretLock() secretlock.Service {
+	return k.secretLock
+}
+
+// failingKMSStoreProvider fails to store the keysets after the given number of keysets were stored
+type failingKMSStoreProvider struct {
+	storage.Provider
+	putsBeforeErr int
+}
+
+func (p *failingKMSStoreProvider) OpenStore(name string) (storage.Store, error) {
+	store, err := p.Provider.OpenStore(name)
+	if err != nil || name != localkms.Namespace {
+		return store, err
+	}
+
+	return &failingStore{Store: store, putsBeforeErr: p.putsBeforeErr}, nil
+}
+
+type failingStore struct {
+	storage.Store
+	putsBeforeErr int
+}
+
+func (s *failingStore) Put(k string, v []byte) error {
+	if s.putsBeforeErr == 0 {
+		return errors.New("put error")
+	}
+
+	s.putsBeforeErr--
+
+	return s.Store.Put(k, v)
+}
+
+type mockLock struct {
+	secretlock.Service
+	encryptErr error
+}
+
+func (m *mockLock) Encrypt(keyURI string, req *secretlock.EncryptRequest) (*secretlock.EncryptResponse, error) {
+	return nil, m.encryptErr
+}
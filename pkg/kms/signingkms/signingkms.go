@@ -0,0 +1,418 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package signingkms adds the signing keys not supported by the Tink keysets of localkms to a kms key manager: the
+// secp256k1 keys, the P-384 keys signing the SHA-384 digest, as required by ES384 (the P-384 keys of localkms sign
+// the SHA-512 digest), and the RSA keys signing with RSASSA-PSS (PS256).
+//
+// The private keys are stored in the kms secrets store, encrypted with an AES-256-GCM key of the wrapped key manager.
+// The keys of the other types are managed by the wrapped key manager, the key handles of the package are signed with
+// by the Crypto of the package.
+package signingkms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/google/tink/go/aead"
+	"github.com/google/tink/go/keyset"
+	"github.com/google/uuid"
+	ariescrypto "github.com/hyperledger/aries-framework-go/pkg/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+)
+
+const (
+	// Secp256k1KeyType is the kms key type of the secp256k1 keys, signing the SHA-256 digest with IEEE P1363 (r || s)
+	// signatures
+	Secp256k1KeyType = kms.KeyType("ECDSASecp256k1IEEEP1363")
+	// P384KeyType is the kms key type of the P-384 keys signing the SHA-384 digest with IEEE P1363 (r || s)
+	// signatures, i.e. the ES384 JWS algorithm
+	P384KeyType = kms.KeyType("ECDSAP384SHA384IEEEP1363")
+	// RSAKeyType is the kms key type of the 2048 bits RSA keys signing the SHA-256 digest with RSASSA-PSS, i.e. the
+	// PS256 JWS algorithm
+	RSAKeyType = kms.KeyType("RSAPS256")
+
+	rsaKeySize = 2048
+
+	storeName = "signingkms"
+	// wrappingKeyIDDBKeyName stores the ID of the key encrypting the private keys, the IDs of the signing keys are
+	// UUIDs so they can't collide with it
+	wrappingKeyIDDBKeyName = "wrappingkeyid"
+)
+
+var errNotSupported = errors.New("not supported for the signingkms keys")
+
+// keyTypes are the curves (none for RSA) and digests of the key types of the package
+// nolint: gochecknoglobals
+var keyTypes = map[kms.KeyType]struct {
+	curve elliptic.Curve
+	hash  crypto.Hash
+}{
+	Secp256k1KeyType: {curve: btcec.S256(), hash: crypto.SHA256},
+	P384KeyType:      {curve: elliptic.P384(), hash: crypto.SHA384},
+	RSAKeyType:       {hash: crypto.SHA256},
+}
+
+// KeyManager is a kms.KeyManager creating and importing the secp256k1, P-384 (SHA-384) and RSA keys, the keys of
+// the other types being managed by the wrapped key manager.
+type KeyManager struct {
+	kms.KeyManager
+	store       storage.Store
+	wrappingKey tinkAEAD
+}
+
+type tinkAEAD interface {
+	Encrypt(plaintext, additionalData []byte) ([]byte, error)
+	Decrypt(ciphertext, additionalData []byte) ([]byte, error)
+}
+
+// keyHandle is the handle of a key of the package, signed with by Crypto
+type keyHandle struct {
+	keyType    kms.KeyType
+	privateKey crypto.Signer
+}
+
+// storedKey is an encrypted private key in the store
+type storedKey struct {
+	KeyType kms.KeyType `json:"keyType"`
+	Key     []byte      `json:"key"`
+}
+
+// New returns the key manager adding the secp256k1, P-384 (SHA-384) and RSA keys to the key manager, the private
+// keys being stored in the kms secrets store. The key encrypting them is created in the key manager on first use.
+func New(keyManager kms.KeyManager, kmsSecretsProvider storage.Provider) (*KeyManager, error) {
+	store, err := kmsSecretsProvider.OpenStore(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open signingkms key store: %w", err)
+	}
+
+	wrappingKey, err := getWrappingKey(keyManager, store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signingkms wrapping key: %w", err)
+	}
+
+	return &KeyManager{KeyManager: keyManager, store: store, wrappingKey: wrappingKey}, nil
+}
+
+// Create a new key of the given type, the keys of the package are stored with a new UUID as key ID
+func (k *KeyManager) Create(kt kms.KeyType) (string, interface{}, error) {
+	params, ok := keyTypes[kt]
+	if !ok {
+		return k.KeyManager.Create(kt)
+	}
+
+	var privateKey crypto.Signer
+
+	var err error
+
+	if params.curve == nil {
+		privateKey, err = rsa.GenerateKey(rand.Reader, rsaKeySize)
+	} else {
+		privateKey, err = ecdsa.GenerateKey(params.curve, rand.Reader)
+	}
+
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate %s key: %w", kt, err)
+	}
+
+	keyID := uuid.New().String()
+
+	if err := k.putKey(keyID, kt, privateKey); err != nil {
+		return "", nil, err
+	}
+
+	return keyID, &keyHandle{keyType: kt, privateKey: privateKey}, nil
+}
+
+// Get returns the key handle of the given keyID
+func (k *KeyManager) Get(keyID string) (interface{}, error) {
+	kh, err := k.getKey(keyID)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return k.KeyManager.Get(keyID)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return kh, nil
+}
+
+// Rotate is not supported for the keys of the package
+func (k *KeyManager) Rotate(kt kms.KeyType, keyID string) (string, interface{}, error) {
+	if _, ok := keyTypes[kt]; ok {
+		return "", nil, fmt.Errorf("rotate key: %w", errNotSupported)
+	}
+
+	return k.KeyManager.Rotate(kt, keyID)
+}
+
+// ExportPubKeyBytes returns the public key bytes of the given keyID: the uncompressed point for the ECDSA keys and
+// the PKCS #1 public key for the RSA keys of the package
+func (k *KeyManager) ExportPubKeyBytes(keyID string) ([]byte, error) {
+	kh, err := k.getKey(keyID)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return k.KeyManager.ExportPubKeyBytes(keyID)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch privateKey := kh.privateKey.(type) {
+	case *rsa.PrivateKey:
+		return x509.MarshalPKCS1PublicKey(&privateKey.PublicKey), nil
+	case *ecdsa.PrivateKey:
+		return elliptic.Marshal(privateKey.Curve, privateKey.X, privateKey.Y), nil
+	default:
+		return nil, fmt.Errorf("export public key: unsupported key type %s", kh.keyType)
+	}
+}
+
+// PubKeyBytesToHandle is not supported for the keys of the package
+func (k *KeyManager) PubKeyBytesToHandle(pubKey []byte, kt kms.KeyType) (interface{}, error) {
+	if _, ok := keyTypes[kt]; ok {
+		return nil, fmt.Errorf("public key bytes to handle: %w", errNotSupported)
+	}
+
+	return k.KeyManager.PubKeyBytesToHandle(pubKey, kt)
+}
+
+// ImportPrivateKey imports the private key, a key of the package must be an *ecdsa.PrivateKey on the curve of the
+// key type or an *rsa.PrivateKey
+func (k *KeyManager) ImportPrivateKey(privKey interface{}, kt kms.KeyType,
+	opts ...kms.PrivateKeyOpts) (string, interface{}, error) {
+	params, ok := keyTypes[kt]
+	if !ok {
+		return k.KeyManager.ImportPrivateKey(privKey, kt, opts...)
+	}
+
+	var privateKey crypto.Signer
+
+	switch key := privKey.(type) {
+	case *rsa.PrivateKey:
+		if params.curve == nil {
+			privateKey = key
+		}
+	case *ecdsa.PrivateKey:
+		if params.curve != nil && key.Curve == params.curve {
+			privateKey = key
+		}
+	}
+
+	if privateKey == nil {
+		return "", nil, fmt.Errorf("import private key: not a %s private key", kt)
+	}
+
+	keyOpts := kms.NewOpt()
+
+	for _, opt := range opts {
+		opt(keyOpts)
+	}
+
+	keyID := keyOpts.KsID()
+	if keyID == "" {
+		keyID = uuid.New().String()
+	}
+
+	if err := k.putKey(keyID, kt, privateKey); err != nil {
+		return "", nil, err
+	}
+
+	return keyID, &keyHandle{keyType: kt, privateKey: privateKey}, nil
+}
+
+// ExportPrivateKey returns the key type and the private key bytes of a key of the package: the PKCS #1 private key
+// for the RSA keys and the private scalar for the ECDSA keys. The keys of the wrapped key manager aren't exported.
+func (k *KeyManager) ExportPrivateKey(keyID string) (kms.KeyType, []byte, error) {
+	kh, err := k.getKey(keyID)
+	if errors.Is(err, storage.ErrDataNotFound) {
+		return "", nil, fmt.Errorf("export private key: %s isn't a signingkms key", keyID)
+	}
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	return kh.keyType, marshalPrivateKey(kh.privateKey), nil
+}
+
+// ParsePrivateKey parses the private key bytes of the key type exported by ExportPrivateKey, to be imported with
+// ImportPrivateKey
+func ParsePrivateKey(kt kms.KeyType, keyBytes []byte) (crypto.Signer, error) {
+	params, ok := keyTypes[kt]
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %s", kt)
+	}
+
+	if params.curve == nil {
+		return x509.ParsePKCS1PrivateKey(keyBytes)
+	}
+
+	if len(keyBytes) == 0 || len(keyBytes) > (params.curve.Params().BitSize+7)/8 {
+		return nil, fmt.Errorf("invalid %s private key", kt)
+	}
+
+	privateKey := &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: params.curve},
+		D: new(big.Int).SetBytes(keyBytes)}
+	privateKey.X, privateKey.Y = privateKey.Curve.ScalarBaseMult(keyBytes)
+
+	return privateKey, nil
+}
+
+func marshalPrivateKey(privateKey crypto.Signer) []byte {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return x509.MarshalPKCS1PrivateKey(key)
+	case *ecdsa.PrivateKey:
+		return key.D.Bytes()
+	default:
+		return nil
+	}
+}
+
+func (k *KeyManager) putKey(keyID string, kt kms.KeyType, privateKey crypto.Signer) error {
+	keyBytes := marshalPrivateKey(privateKey)
+
+	// the key ID and type are authenticated with the key, a stored key can't be swapped for another one
+	encrypted, err := k.wrappingKey.Encrypt(keyBytes, []byte(keyID+string(kt)))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s key: %w", kt, err)
+	}
+
+	storedBytes, err := json.Marshal(&storedKey{KeyType: kt, Key: encrypted})
+	if err != nil {
+		return err
+	}
+
+	return k.store.Put(keyID, storedBytes)
+}
+
+func (k *KeyManager) getKey(keyID string) (*keyHandle, error) {
+	if keyID == wrappingKeyIDDBKeyName {
+		return nil, storage.ErrDataNotFound
+	}
+
+	storedBytes, err := k.store.Get(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored storedKey
+
+	if err = json.Unmarshal(storedBytes, &stored); err != nil {
+		return nil, fmt.Errorf("invalid signingkms key %s: %w", keyID, err)
+	}
+
+	if _, ok := keyTypes[stored.KeyType]; !ok {
+		return nil, fmt.Errorf("invalid signingkms key %s: unsupported key type %s", keyID, stored.KeyType)
+	}
+
+	keyBytes, err := k.wrappingKey.Decrypt(stored.Key, []byte(keyID+string(stored.KeyType)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt signingkms key %s: %w", keyID, err)
+	}
+
+	privateKey, err := ParsePrivateKey(stored.KeyType, keyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signingkms key %s: %w", keyID, err)
+	}
+
+	return &keyHandle{keyType: stored.KeyType, privateKey: privateKey}, nil
+}
+
+func getWrappingKey(keyManager kms.KeyManager, store storage.Store) (tinkAEAD, error) {
+	var kh interface{}
+
+	keyIDBytes, err := store.Get(wrappingKeyIDDBKeyName)
+
+	switch {
+	case errors.Is(err, storage.ErrDataNotFound):
+		var keyID string
+
+		keyID, kh, err = keyManager.Create(kms.AES256GCMType)
+		if err != nil {
+			return nil, err
+		}
+
+		err = store.Put(wrappingKeyIDDBKeyName, []byte(keyID))
+	case err == nil:
+		kh, err = keyManager.Get(string(keyIDBytes))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	keyHandle, ok := kh.(*keyset.Handle)
+	if !ok || keyHandle == nil {
+		return nil, errors.New("the wrapping key isn't a keyset handle")
+	}
+
+	return aead.New(keyHandle)
+}
+
+// Crypto signs with the key handles of KeyManager, the other key handles being used by the wrapped crypto
+type Crypto struct {
+	ariescrypto.Crypto
+}
+
+// NewCrypto returns the crypto signing with the keys of the package and the key handles of the wrapped crypto
+func NewCrypto(c ariescrypto.Crypto) *Crypto {
+	return &Crypto{Crypto: c}
+}
+
+// Sign signs the message with the key handle. The keys of the package sign the digest of their key type, with an
+// IEEE P1363 (r || s) signature for the ECDSA keys and a RSASSA-PSS signature salted with the digest length for the
+// RSA keys.
+func (c *Crypto) Sign(msg []byte, kh interface{}) ([]byte, error) {
+	h, ok := kh.(*keyHandle)
+	if !ok {
+		return c.Crypto.Sign(msg, kh)
+	}
+
+	hash := keyTypes[h.keyType].hash
+	hasher := hash.New()
+
+	if _, err := hasher.Write(msg); err != nil {
+		return nil, err
+	}
+
+	switch privateKey := h.privateKey.(type) {
+	case *rsa.PrivateKey:
+		return rsa.SignPSS(rand.Reader, privateKey, hash, hasher.Sum(nil),
+			&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case *ecdsa.PrivateKey:
+		return signECDSA(privateKey, hasher.Sum(nil))
+	default:
+		return nil, fmt.Errorf("sign: unsupported key type %s", h.keyType)
+	}
+}
+
+// signECDSA returns the IEEE P1363 (r || s) signature of the digest
+func signECDSA(privateKey *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	keySize := (privateKey.Curve.Params().BitSize + 7) / 8
+
+	signature := make([]byte, 2*keySize)
+	copy(signature[keySize-len(r.Bytes()):keySize], r.Bytes())
+	copy(signature[2*keySize-len(s.Bytes()):], s.Bytes())
+
+	return signature, nil
+}
@@ -0,0 +1,217 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package signingkms
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	mockstorage "github.com/hyperledger/aries-framework-go/pkg/mock/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	"github.com/hyperledger/aries-framework-go/pkg/storage"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyManager(t *testing.T) {
+	provider := mem.NewProvider()
+	localKMS := newLocalKMS(t, provider)
+
+	km, err := New(localKMS, provider)
+	require.NoError(t, err)
+
+	c, err := tinkcrypto.New()
+	require.NoError(t, err)
+
+	signingCrypto := NewCrypto(c)
+
+	t.Run("test create and sign", func(t *testing.T) {
+		tests := []struct {
+			keyType  kms.KeyType
+			verifier verifier.SignatureVerifier
+		}{
+			{keyType: Secp256k1KeyType, verifier: verifier.NewECDSASecp256k1SignatureVerifier()},
+			{keyType: P384KeyType, verifier: verifier.NewECDSAES384SignatureVerifier()},
+			{keyType: RSAKeyType, verifier: verifier.NewRSAPS256SignatureVerifier()},
+		}
+
+		for _, tc := range tests {
+			keyID, _, err := km.Create(tc.keyType)
+			require.NoError(t, err)
+
+			pubKey, err := km.ExportPubKeyBytes(keyID)
+			require.NoError(t, err)
+
+			// the keys are read back from the store by a new key manager
+			reloaded, err := New(localKMS, provider)
+			require.NoError(t, err)
+
+			kh, err := reloaded.Get(keyID)
+			require.NoError(t, err)
+
+			signature, err := signingCrypto.Sign([]byte("message"), kh)
+			require.NoError(t, err)
+
+			err = tc.verifier.Verify(&verifier.PublicKey{Value: pubKey}, []byte("message"), signature)
+			require.NoError(t, err, tc.keyType)
+
+			_, _, err = km.Rotate(tc.keyType, keyID)
+			require.Error(t, err)
+
+			_, err = km.PubKeyBytesToHandle(pubKey, tc.keyType)
+			require.Error(t, err)
+		}
+	})
+
+	t.Run("test import", func(t *testing.T) {
+		privateKey, err := ecdsa.GenerateKey(btcec.S256(), rand.Reader)
+		require.NoError(t, err)
+
+		keyID, _, err := km.ImportPrivateKey(privateKey, Secp256k1KeyType, kms.WithKeyID("imported"))
+		require.NoError(t, err)
+		require.Equal(t, "imported", keyID)
+
+		pubKey, err := km.ExportPubKeyBytes(keyID)
+		require.NoError(t, err)
+		require.Equal(t, elliptic.Marshal(btcec.S256(), privateKey.X, privateKey.Y), pubKey)
+
+		_, _, err = km.ImportPrivateKey(privateKey, P384KeyType)
+		require.EqualError(t, err, "import private key: not a ECDSAP384SHA384IEEEP1363 private key")
+
+		_, _, err = km.ImportPrivateKey(privateKey, RSAKeyType)
+		require.EqualError(t, err, "import private key: not a RSAPS256 private key")
+
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		keyID, _, err = km.ImportPrivateKey(rsaKey, RSAKeyType)
+		require.NoError(t, err)
+
+		pubKey, err = km.ExportPubKeyBytes(keyID)
+		require.NoError(t, err)
+		require.Equal(t, x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey), pubKey)
+	})
+
+	t.Run("test export private key", func(t *testing.T) {
+		for _, keyType := range []kms.KeyType{Secp256k1KeyType, P384KeyType, RSAKeyType} {
+			keyID, _, err := km.Create(keyType)
+			require.NoError(t, err)
+
+			exportedType, keyBytes, err := km.ExportPrivateKey(keyID)
+			require.NoError(t, err)
+			require.Equal(t, keyType, exportedType)
+
+			privateKey, err := ParsePrivateKey(exportedType, keyBytes)
+			require.NoError(t, err)
+
+			importedID, _, err := km.ImportPrivateKey(privateKey, keyType)
+			require.NoError(t, err)
+
+			pubKey, err := km.ExportPubKeyBytes(keyID)
+			require.NoError(t, err)
+
+			importedPubKey, err := km.ExportPubKeyBytes(importedID)
+			require.NoError(t, err)
+			require.Equal(t, pubKey, importedPubKey, keyType)
+		}
+
+		keyID, _, err := km.Create(kms.ED25519Type)
+		require.NoError(t, err)
+
+		_, _, err = km.ExportPrivateKey(keyID)
+		require.EqualError(t, err, "export private key: "+keyID+" isn't a signingkms key")
+	})
+
+	t.Run("test parse private key - errors", func(t *testing.T) {
+		_, err := ParsePrivateKey(kms.ED25519Type, []byte("key"))
+		require.EqualError(t, err, "unsupported key type ED25519")
+
+		_, err = ParsePrivateKey(P384KeyType, make([]byte, 49))
+		require.EqualError(t, err, "invalid ECDSAP384SHA384IEEEP1363 private key")
+
+		_, err = ParsePrivateKey(Secp256k1KeyType, nil)
+		require.Error(t, err)
+
+		_, err = ParsePrivateKey(RSAKeyType, []byte("key"))
+		require.Error(t, err)
+	})
+
+	t.Run("test other key types", func(t *testing.T) {
+		keyID, _, err := km.Create(kms.ED25519Type)
+		require.NoError(t, err)
+
+		pubKey, err := km.ExportPubKeyBytes(keyID)
+		require.NoError(t, err)
+
+		kh, err := km.Get(keyID)
+		require.NoError(t, err)
+
+		signature, err := signingCrypto.Sign([]byte("message"), kh)
+		require.NoError(t, err)
+		require.True(t, ed25519.Verify(pubKey, []byte("message"), signature))
+
+		_, err = km.Get(wrappingKeyIDDBKeyName)
+		require.Error(t, err)
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("test error - open store", func(t *testing.T) {
+		_, err := New(&mockkms.KeyManager{}, &mockstorage.MockStoreProvider{FailNamespace: storeName})
+		require.EqualError(t, err,
+			"failed to open signingkms key store: failed to open store for name space signingkms")
+	})
+
+	t.Run("test error - wrapping key", func(t *testing.T) {
+		_, err := New(&mockkms.KeyManager{CreateKeyErr: errors.New("create error")}, mem.NewProvider())
+		require.EqualError(t, err, "failed to get signingkms wrapping key: create error")
+
+		_, err = New(&mockkms.KeyManager{CreateKeyValue: nil}, mem.NewProvider())
+		require.EqualError(t, err, "failed to get signingkms wrapping key: the wrapping key isn't a keyset handle")
+
+		_, err = New(&mockkms.KeyManager{}, &mockstorage.MockStoreProvider{Store: &mockstorage.MockStore{
+			ErrGet: errors.New("get error")}})
+		require.EqualError(t, err, "failed to get signingkms wrapping key: get error")
+
+		_, err = New(&mockkms.KeyManager{}, &mockstorage.MockStoreProvider{Store: &mockstorage.MockStore{
+			ErrGet: storage.ErrDataNotFound, ErrPut: errors.New("put error")}})
+		require.EqualError(t, err, "failed to get signingkms wrapping key: put error")
+	})
+}
+
+func newLocalKMS(t *testing.T, provider storage.Provider) *localkms.LocalKMS {
+	km, err := localkms.New("local-lock://test/key/uri", &kmsProvider{storageProvider: provider,
+		secretLock: &noop.NoLock{}})
+	require.NoError(t, err)
+
+	return km
+}
+
+type kmsProvider struct {
+	storageProvider storage.Provider
+	secretLock      secretlock.Service
+}
+
+func (k *kmsProvider) StorageProvider() storage.Provider {
+	return k.storageProvider
+}
+
+func (k *kmsProvider) SecretLock() secretlock.Service {
+	return k.secretLock
+}
@@ -0,0 +1,132 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "vcs"
+
+// the metrics are registered in a registry of their own, the default one is free for the embedding applications
+var (
+	registry = newRegistry()
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by endpoint, method and status code.",
+	}, []string{"endpoint", "method", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the HTTP requests by endpoint and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "method"})
+
+	cslUtilization = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "csl",
+		Name:      "utilization_ratio",
+		Help:      "Ratio of the status IDs of the latest credential status list already assigned.",
+	})
+
+	edvErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "edv",
+		Name:      "errors_total",
+		Help:      "Number of failed EDV requests (after retries) by operation.",
+	}, []string{"operation"})
+
+	kmsOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "kms",
+		Name:      "operation_duration_seconds",
+		Help:      "Latency of the KMS operations by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	verifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "verifier",
+		Name:      "verifications_total",
+		Help:      "Number of credential and presentation verifications by verifier profile and result.",
+	}, []string{"profile", "result"})
+
+	verificationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "verifier",
+		Name:      "failures_total",
+		Help:      "Number of failed verifications by verifier profile and failure reason.",
+	}, []string{"profile", "reason"})
+)
+
+func newRegistry() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+
+	r.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		httpRequests, httpRequestDuration, cslUtilization, edvErrors, kmsOperationDuration, verifications,
+		verificationFailures,
+	)
+
+	return r
+}
+
+// Handler returns the handler exposing the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTPRequest records a handled HTTP request. The endpoint is the path template of the handler
+// (e.g. /{id}/credentials/issueCredential), so the number of label values stays bounded.
+func ObserveHTTPRequest(endpoint, method string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(endpoint, method, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(endpoint, method).Observe(duration.Seconds())
+}
+
+// SetCSLUtilization sets the number of status IDs assigned in the latest credential status list over its size
+func SetCSLUtilization(assigned, listSize int) {
+	if listSize <= 0 {
+		return
+	}
+
+	cslUtilization.Set(float64(assigned) / float64(listSize))
+}
+
+// EDVError records a failed EDV request
+func EDVError(operation string) {
+	edvErrors.WithLabelValues(operation).Inc()
+}
+
+// ObserveKMSOperation records the latency of a KMS operation started at the given time
+func ObserveKMSOperation(operation string, start time.Time) {
+	kmsOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// ObserveVerification records a verification of the verifier profile, failed for the given reasons if any
+func ObserveVerification(profile string, failureReasons []string) {
+	if len(failureReasons) == 0 {
+		verifications.WithLabelValues(profile, "verified").Inc()
+
+		return
+	}
+
+	verifications.WithLabelValues(profile, "failed").Inc()
+
+	for _, reason := range failureReasons {
+		verificationFailures.WithLabelValues(profile, reason).Inc()
+	}
+}
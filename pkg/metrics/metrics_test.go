@@ -0,0 +1,42 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	ObserveHTTPRequest("/{id}/credentials/issueCredential", http.MethodPost, http.StatusCreated, time.Second)
+	SetCSLUtilization(25, 100)
+	SetCSLUtilization(1, 0)
+	EDVError("readDocument")
+	ObserveKMSOperation("sign", time.Now())
+	ObserveVerification("verifier", nil)
+	ObserveVerification("verifier", []string{"badProof", "revoked"})
+
+	rr := httptest.NewRecorder()
+	Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	body := rr.Body.String()
+	require.Contains(t, body,
+		`vcs_http_requests_total{endpoint="/{id}/credentials/issueCredential",method="POST",status="201"} 1`)
+	require.Contains(t, body,
+		`vcs_http_request_duration_seconds_sum{endpoint="/{id}/credentials/issueCredential",method="POST"} 1`)
+	require.Contains(t, body, "vcs_csl_utilization_ratio 0.25")
+	require.Contains(t, body, `vcs_edv_errors_total{operation="readDocument"} 1`)
+	require.Contains(t, body, `vcs_kms_operation_duration_seconds_count{operation="sign"} 1`)
+	require.Contains(t, body, `vcs_verifier_verifications_total{profile="verifier",result="verified"} 1`)
+	require.Contains(t, body, `vcs_verifier_verifications_total{profile="verifier",result="failed"} 1`)
+	require.Contains(t, body, `vcs_verifier_failures_total{profile="verifier",reason="revoked"} 1`)
+	require.Contains(t, body, "go_goroutines")
+}
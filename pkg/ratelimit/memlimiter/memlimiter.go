@@ -0,0 +1,84 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package memlimiter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter is an in-memory token bucket rate limiter, limiting the requests of a single instance.
+// The buckets idle long enough to be full again are evicted, as they are the same as new buckets.
+type Limiter struct {
+	rate     float64
+	burst    float64
+	fillTime time.Duration
+	buckets  map[string]*bucket
+	swept    time.Time
+	now      func() time.Time
+	mux      sync.Mutex
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// New returns a limiter allowing rate requests per second for each key, with bursts of at most burst requests.
+func New(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:     rate,
+		burst:    float64(burst),
+		fillTime: time.Duration(float64(burst) / rate * float64(time.Second)),
+		buckets:  map[string]*bucket{},
+		swept:    time.Now(),
+		now:      time.Now,
+	}
+}
+
+// Allow takes a token of the key bucket, or returns the time until a token is available.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	now := l.now()
+
+	l.evictFullBuckets(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// evictFullBuckets removes the buckets refilled to the burst size, at most once per fill time: a bucket idle for
+// the fill time is full, so only the buckets used during the last two fill times are kept.
+func (l *Limiter) evictFullBuckets(now time.Time) {
+	if now.Sub(l.swept) < l.fillTime {
+		return
+	}
+
+	l.swept = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
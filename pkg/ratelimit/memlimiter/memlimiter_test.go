@@ -0,0 +1,91 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package memlimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+
+	l := New(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		ok, _ := l.Allow("profile1")
+		require.True(t, ok)
+	}
+
+	ok, retryAfter := l.Allow("profile1")
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, retryAfter)
+
+	// the buckets of the keys are independent
+	ok, _ = l.Allow("profile2")
+	require.True(t, ok)
+
+	// a token is added every 500ms
+	now = now.Add(250 * time.Millisecond)
+
+	ok, retryAfter = l.Allow("profile1")
+	require.False(t, ok)
+	require.Equal(t, 250*time.Millisecond, retryAfter)
+
+	now = now.Add(250 * time.Millisecond)
+
+	ok, _ = l.Allow("profile1")
+	require.True(t, ok)
+
+	// the bucket is refilled up to the burst size
+	now = now.Add(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		ok, _ = l.Allow("profile1")
+		require.True(t, ok)
+	}
+
+	ok, _ = l.Allow("profile1")
+	require.False(t, ok)
+}
+
+func TestLimiterEviction(t *testing.T) {
+	now := time.Now()
+
+	l := New(2, 3)
+	l.now = func() time.Time { return now }
+	l.swept = now
+
+	ok, _ := l.Allow("profile1")
+	require.True(t, ok)
+
+	for i := 0; i < 3; i++ {
+		ok, _ = l.Allow("profile2")
+		require.True(t, ok)
+	}
+
+	require.Len(t, l.buckets, 2)
+
+	// the buckets are refilled at 2 tokens per second, profile1 is full after 500ms and profile2 after 1.5s
+	now = now.Add(1500 * time.Millisecond)
+
+	ok, _ = l.Allow("profile3")
+	require.True(t, ok)
+	require.Len(t, l.buckets, 1)
+	require.Contains(t, l.buckets, "profile3")
+
+	// the eviction doesn't reset the limits of the evicted keys
+	for i := 0; i < 3; i++ {
+		ok, _ = l.Allow("profile2")
+		require.True(t, ok)
+	}
+
+	ok, _ = l.Allow("profile2")
+	require.False(t, ok)
+}
@@ -0,0 +1,41 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ratelimit
+
+import "time"
+
+const (
+	// KeyProfile limits the requests of each profile
+	KeyProfile = "profile"
+	// KeyAPIKey limits the requests of each client authenticated by its API key (see package apikey), the
+	// anonymous requests are limited per profile
+	KeyAPIKey = "api-key"
+)
+
+// Limiter limits the rate of the requests of each key with a token bucket: a request takes a token, and the
+// bucket is refilled at the configured rate up to the burst size.
+type Limiter interface {
+	// Allow takes a token of the key bucket. If the bucket is empty, the request is not allowed and
+	// the time until a token is available is returned.
+	Allow(key string) (bool, time.Duration)
+}
+
+// Config is the rate limit of a service.
+type Config struct {
+	Limiter Limiter
+	// Key is what the requests are limited by: KeyProfile (default) or KeyAPIKey.
+	Key string
+}
+
+// BucketKey returns the key of the bucket of a request of the profile, made by the client authenticated by its
+// API key (empty if the request is anonymous).
+func (c *Config) BucketKey(profileID, client string) string {
+	if c.Key == KeyAPIKey && client != "" {
+		return "client:" + client
+	}
+
+	return "profile:" + profileID
+}
@@ -0,0 +1,138 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package redislimiter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/trustbloc/edge-core/pkg/log"
+)
+
+var logger = log.New("edge-service-redislimiter")
+
+// tokenBucketScript takes a token of the bucket (a hash of its tokens and update time in ms) after refilling it,
+// and returns whether the request is allowed and otherwise the time in ms until a token is available. The bucket
+// expires once it would be full again.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+
+if tokens == nil then
+	tokens = burst
+	updated = now
+end
+
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate / 1000)
+
+local allowed = 0
+local wait = 0
+
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) * 1000 / rate)
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+
+return {allowed, wait}
+`
+
+// Option configures the Redis limiter
+type Option func(l *Limiter)
+
+// WithKeyPrefix option is for adding prefix to the bucket keys, for instances sharing a Redis server
+// while using different databases
+func WithKeyPrefix(keyPrefix string) Option {
+	return func(l *Limiter) {
+		l.keyPrefix = keyPrefix
+	}
+}
+
+// Limiter is a Redis backed token bucket rate limiter, limiting the requests of all the instances sharing
+// the Redis server.
+type Limiter struct {
+	client    *redis.Client
+	script    *redis.Script
+	rate      float64
+	burst     int
+	keyPrefix string
+	now       func() time.Time
+}
+
+// New returns a limiter allowing rate requests per second for each key, with bursts of at most burst requests,
+// whose buckets are kept in the Redis server of the given URL (e.g. redis://:password@redis:6379/0).
+func New(url string, rate float64, burst int, opts ...Option) (*Limiter, error) {
+	redisOpts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+
+	l := &Limiter{
+		client: redis.NewClient(redisOpts),
+		script: redis.NewScript(tokenBucketScript),
+		rate:   rate,
+		burst:  burst,
+		now:    time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	err = l.client.Ping().Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	return l, nil
+}
+
+// Allow takes a token of the key bucket, or returns the time until a token is available. The requests are
+// allowed while Redis fails.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	nowMs := l.now().UnixNano() / int64(time.Millisecond)
+
+	result, err := l.script.Run(l.client, []string{l.keyPrefix + "ratelimit_" + key}, l.rate, l.burst,
+		nowMs).Result()
+	if err != nil {
+		logger.Warnf("failed to take token of key %s from redis: %s", key, err)
+
+		return true, 0
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		logger.Warnf("unexpected rate limit result from redis: %v", result)
+
+		return true, 0
+	}
+
+	if values[0] == int64(1) {
+		return true, 0
+	}
+
+	wait, ok := values[1].(int64)
+	if !ok {
+		return false, time.Second
+	}
+
+	return false, time.Duration(wait) * time.Millisecond
+}
+
+// Close closes the connections to Redis.
+func (l *Limiter) Close() error {
+	return l.client.Close()
+}
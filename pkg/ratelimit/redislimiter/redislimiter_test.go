@@ -0,0 +1,86 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package redislimiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	s, err := miniredis.Run()
+	require.NoError(t, err)
+
+	defer s.Close()
+
+	t.Run("test token bucket", func(t *testing.T) {
+		now := time.Now()
+
+		l, err := New("redis://"+s.Addr(), 2, 3, WithKeyPrefix("vcs_"))
+		require.NoError(t, err)
+
+		defer func() { require.NoError(t, l.Close()) }()
+
+		l.now = func() time.Time { return now }
+
+		for i := 0; i < 3; i++ {
+			ok, _ := l.Allow("profile1")
+			require.True(t, ok)
+		}
+
+		ok, retryAfter := l.Allow("profile1")
+		require.False(t, ok)
+		require.Equal(t, 500*time.Millisecond, retryAfter)
+		require.True(t, s.Exists("vcs_ratelimit_profile1"))
+
+		// another instance shares the buckets
+		other, err := New("redis://"+s.Addr(), 2, 3, WithKeyPrefix("vcs_"))
+		require.NoError(t, err)
+
+		other.now = l.now
+
+		ok, _ = other.Allow("profile1")
+		require.False(t, ok)
+
+		ok, _ = other.Allow("profile2")
+		require.True(t, ok)
+
+		now = now.Add(500 * time.Millisecond)
+
+		ok, _ = l.Allow("profile1")
+		require.True(t, ok)
+	})
+
+	t.Run("test redis failure allows requests", func(t *testing.T) {
+		failing, err := miniredis.Run()
+		require.NoError(t, err)
+
+		l, err := New("redis://"+failing.Addr(), 1, 1)
+		require.NoError(t, err)
+
+		failing.Close()
+
+		ok, _ := l.Allow("profile1")
+		require.True(t, ok)
+	})
+
+	t.Run("test error - invalid url", func(t *testing.T) {
+		l, err := New("http://localhost", 1, 1)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid redis url")
+		require.Nil(t, l)
+	})
+
+	t.Run("test error - redis not reachable", func(t *testing.T) {
+		l, err := New("redis://localhost:1", 1, 1)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to connect to redis")
+		require.Nil(t, l)
+	})
+}
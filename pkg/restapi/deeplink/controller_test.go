@@ -0,0 +1,20 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package deeplink
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestController_GetOperations(t *testing.T) {
+	controller := New()
+	require.NotNil(t, controller)
+
+	require.Equal(t, 1, len(controller.GetOperations()))
+}
@@ -0,0 +1,34 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import "encoding/json"
+
+// DeepLinkRequest is the credential offer, the invitation or the presentation request to link to, exactly one of
+// them being set.
+type DeepLinkRequest struct {
+	// CredentialOffer is the OpenID for Verifiable Credential Issuance credential offer, passed by value.
+	CredentialOffer json.RawMessage `json:"credentialOffer,omitempty"`
+	// CredentialOfferURI is the URL of the credential offer, passed by reference.
+	CredentialOfferURI string `json:"credentialOfferURI,omitempty"`
+	// ManifestURI is the URL of the credential manifest of the credential offered, e.g. the manifest URL of an
+	// issuer exchange.
+	ManifestURI string `json:"manifestURI,omitempty"`
+	// Invitation is the DIDComm out-of-band invitation, e.g. with an attached presentation request.
+	Invitation json.RawMessage `json:"invitation,omitempty"`
+	// PresentationRequestURI is the request URI of a presentation request of a verifier.
+	PresentationRequestURI string `json:"presentationRequestURI,omitempty"`
+	// Format is link to return the deep link (default), png or svg to return its QR code.
+	Format string `json:"format,omitempty"`
+	// Size is the width and height of the QR code in pixels, 256 if not set.
+	Size int `json:"size,omitempty"`
+}
+
+// DeepLinkResponse is the deep link the wallets open.
+type DeepLinkResponse struct {
+	Link string `json:"link"`
+}
@@ -0,0 +1,35 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"github.com/trustbloc/edge-service/pkg/restapi/model"
+)
+
+// genericError model
+//
+// swagger:response genericError
+type genericError struct { // nolint: unused,deadcode
+	// in: body
+	model.ErrorResponse
+}
+
+// createDeepLinkReq model
+//
+// swagger:parameters createDeepLinkReq
+type createDeepLinkReq struct { // nolint: unused,deadcode
+	// in: body
+	Params DeepLinkRequest
+}
+
+// deepLinkRes model, the deep link or its QR code image
+//
+// swagger:response deepLinkRes
+type deepLinkRes struct { // nolint: unused,deadcode
+	// in: body
+	DeepLinkResponse
+}
@@ -0,0 +1,285 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	"github.com/trustbloc/edge-service/pkg/internal/common/support"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	deepLinksEndpoint = "/deepLinks"
+
+	credentialOfferScheme = "openid-credential-offer://"
+	didCommScheme         = "didcomm://"
+
+	// the formats of the deep links
+	linkFormat = "link"
+	pngFormat  = "png"
+	svgFormat  = "svg"
+
+	pngContentType = "image/png"
+	svgContentType = "image/svg+xml"
+
+	// DefaultSize is the size of the QR codes in pixels if not requested
+	DefaultSize = 256
+	// MaxSize is the maximum size of the QR codes in pixels
+	MaxSize = 1024
+)
+
+var logger = log.New("edge-service-deeplink-restapi")
+
+// Handler represents an HTTP handler for each controller API endpoint
+type Handler interface {
+	Path() string
+	Method() string
+	Handle() http.HandlerFunc
+}
+
+// Operation defines handlers for the deep links
+type Operation struct{}
+
+// New returns the deep link operations
+func New() *Operation {
+	return &Operation{}
+}
+
+// GetRESTHandlers get all controller API handler available for this service
+func (o *Operation) GetRESTHandlers() []Handler {
+	return []Handler{
+		support.NewHTTPHandler(deepLinksEndpoint, http.MethodPost, o.createDeepLinkHandler),
+	}
+}
+
+// CreateDeepLink swagger:route POST /deepLinks deeplink createDeepLinkReq
+//
+// Returns the wallet deep link of a credential offer, a DIDComm out-of-band invitation or a presentation request, or
+// its QR code as a PNG or SVG image:
+// openid-credential-offer://?credential_offer=... for a credential offer passed by value,
+// openid-credential-offer://?credential_offer_uri=... for a credential offer passed by reference, followed by
+// &manifest_uri=... for the credential manifest of the offer,
+// didcomm://?_oob=... for an invitation, and the request URI of a presentation request.
+//
+// Produces:
+// - application/json
+// - image/png
+// - image/svg+xml
+//
+// Responses:
+//    default: genericError
+//        200: deepLinkRes
+func (o *Operation) createDeepLinkHandler(rw http.ResponseWriter, req *http.Request) {
+	request := &DeepLinkRequest{}
+
+	if err := commhttp.DecodeJSON(req, request); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if request.Format == "" {
+		request.Format = linkFormat
+	}
+
+	if request.Size == 0 {
+		request.Size = DefaultSize
+	}
+
+	if err := validateDeepLinkRequest(request); err != nil {
+		commhttp.WriteError(rw, commhttp.NewValidationError(err))
+
+		return
+	}
+
+	link, err := deepLink(request)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+
+		return
+	}
+
+	if request.Format == linkFormat {
+		commhttp.WriteResponse(rw, &DeepLinkResponse{Link: link})
+
+		return
+	}
+
+	// the QR codes are encoded with the medium recovery level, restoring 15% of the code
+	code, err := qrcode.New(link, qrcode.Medium)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("failed to encode the QR code of the link: %s", err.Error()))
+
+		return
+	}
+
+	image, contentType := svg(code, request.Size), svgContentType
+
+	if request.Format == pngFormat {
+		image, err = code.PNG(request.Size)
+		if err != nil {
+			commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.InternalError,
+				fmt.Sprintf("failed to render the QR code: %s", err.Error()))
+
+			return
+		}
+
+		contentType = pngContentType
+	}
+
+	rw.Header().Set("Content-Type", contentType)
+
+	if _, err = rw.Write(image); err != nil {
+		logger.Errorf("Failed to write QR code: %s", err.Error())
+	}
+}
+
+func validateDeepLinkRequest(request *DeepLinkRequest) error {
+	validationErr := &commhttp.ValidationError{}
+
+	sources := 0
+
+	for _, set := range []bool{len(request.CredentialOffer) > 0, request.CredentialOfferURI != "",
+		len(request.Invitation) > 0, request.PresentationRequestURI != ""} {
+		if set {
+			sources++
+		}
+	}
+
+	if sources != 1 {
+		validationErr.Add("/", "exactly one of credentialOffer, credentialOfferURI, invitation and "+
+			"presentationRequestURI must be set")
+	}
+
+	if request.CredentialOfferURI != "" && !isHTTPURL(request.CredentialOfferURI) {
+		validationErr.Add("/credentialOfferURI", "credentialOfferURI must be an absolute http(s) URL")
+	}
+
+	if request.ManifestURI != "" && len(request.CredentialOffer) == 0 && request.CredentialOfferURI == "" {
+		validationErr.Add("/manifestURI", "manifestURI must be set with a credential offer")
+	}
+
+	if request.ManifestURI != "" && !isHTTPURL(request.ManifestURI) {
+		validationErr.Add("/manifestURI", "manifestURI must be an absolute http(s) URL")
+	}
+
+	if request.PresentationRequestURI != "" && !isHTTPURL(request.PresentationRequestURI) {
+		validationErr.Add("/presentationRequestURI", "presentationRequestURI must be an absolute http(s) URL")
+	}
+
+	switch request.Format {
+	case linkFormat, pngFormat, svgFormat:
+	default:
+		validationErr.Add("/format", fmt.Sprintf("invalid format: %s", request.Format))
+	}
+
+	if request.Size < 0 || request.Size > MaxSize {
+		validationErr.Add("/size", fmt.Sprintf("size must be between 1 and %d pixels", MaxSize))
+	}
+
+	return validationErr.ErrorOrNil()
+}
+
+// deepLink returns the deep link of the request, the JSON objects passed by value being compacted
+func deepLink(request *DeepLinkRequest) (string, error) {
+	switch {
+	case len(request.CredentialOffer) > 0:
+		offer, err := compactObject(request.CredentialOffer)
+		if err != nil {
+			return "", fmt.Errorf("invalid credential offer: %w", err)
+		}
+
+		return credentialOfferScheme + "?credential_offer=" + url.QueryEscape(offer) + manifestParam(request), nil
+	case request.CredentialOfferURI != "":
+		return credentialOfferScheme + "?credential_offer_uri=" + url.QueryEscape(request.CredentialOfferURI) +
+			manifestParam(request), nil
+	case len(request.Invitation) > 0:
+		invitation, err := compactObject(request.Invitation)
+		if err != nil {
+			return "", fmt.Errorf("invalid invitation: %w", err)
+		}
+
+		// the out-of-band invitations are encoded base64url, without padding
+		return didCommScheme + "?_oob=" + base64.RawURLEncoding.EncodeToString([]byte(invitation)), nil
+	default:
+		// the holders get the presentation requests from their request URIs
+		return request.PresentationRequestURI, nil
+	}
+}
+
+// manifestParam returns the query parameter of the manifest of the credential offer, if set
+func manifestParam(request *DeepLinkRequest) string {
+	if request.ManifestURI == "" {
+		return ""
+	}
+
+	return "&manifest_uri=" + url.QueryEscape(request.ManifestURI)
+}
+
+// compactObject returns the JSON object without insignificant space
+func compactObject(raw json.RawMessage) (string, error) {
+	var object map[string]interface{}
+
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return "", fmt.Errorf("must be a JSON object: %w", err)
+	}
+
+	compacted := &bytes.Buffer{}
+
+	if err := json.Compact(compacted, raw); err != nil {
+		return "", err
+	}
+
+	return compacted.String(), nil
+}
+
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+// svg renders the QR code as a SVG image of the size, a module being a unit of its view box
+func svg(code *qrcode.QRCode, size int) []byte {
+	bitmap := code.Bitmap()
+
+	path := &strings.Builder{}
+
+	// the consecutive dark modules of a row are drawn as a rectangle
+	for y, row := range bitmap {
+		for x := 0; x < len(row); {
+			if !row[x] {
+				x++
+
+				continue
+			}
+
+			start := x
+
+			for x < len(row) && row[x] {
+				x++
+			}
+
+			fmt.Fprintf(path, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" `+
+		`shape-rendering="crispEdges"><rect width="100%%" height="100%%" fill="#fff"/>`+
+		`<path fill="#000" d="%s"/></svg>`, size, size, len(bitmap), len(bitmap), path.String()))
+}
@@ -0,0 +1,171 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateDeepLink(t *testing.T) {
+	handler := New().GetRESTHandlers()[0]
+	require.Equal(t, deepLinksEndpoint, handler.Path())
+	require.Equal(t, http.MethodPost, handler.Method())
+
+	post := func(t *testing.T, request string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, deepLinksEndpoint, strings.NewReader(request))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+
+		handler.Handle().ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	link := func(t *testing.T, request string) string {
+		rr := post(t, request)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		response := &DeepLinkResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), response))
+
+		return response.Link
+	}
+
+	t.Run("credential offer", func(t *testing.T) {
+		offer := `{"credential_issuer": "https://issuer.example.com", "credentials": ["UniversityDegree"]}`
+
+		deepLink := link(t, `{"credentialOffer":`+offer+`}`)
+		require.True(t, strings.HasPrefix(deepLink, "openid-credential-offer://?credential_offer="))
+
+		u, err := url.Parse(deepLink)
+		require.NoError(t, err)
+		require.Equal(t, `{"credential_issuer":"https://issuer.example.com","credentials":["UniversityDegree"]}`,
+			u.Query().Get("credential_offer"))
+	})
+
+	t.Run("credential offer URI", func(t *testing.T) {
+		require.Equal(t, "openid-credential-offer://?credential_offer_uri="+
+			"https%3A%2F%2Fissuer.example.com%2Foffers%2F1%3Fa%3Db",
+			link(t, `{"credentialOfferURI":"https://issuer.example.com/offers/1?a=b"}`))
+	})
+
+	t.Run("credential offer with manifest", func(t *testing.T) {
+		manifestURI := "https://issuer.example.com/issuer1/manifests/degree"
+
+		deepLink := link(t, `{"credentialOffer":{"credential_issuer":"https://issuer.example.com"},
+			"manifestURI":"`+manifestURI+`"}`)
+
+		u, err := url.Parse(deepLink)
+		require.NoError(t, err)
+		require.Equal(t, `{"credential_issuer":"https://issuer.example.com"}`, u.Query().Get("credential_offer"))
+		require.Equal(t, manifestURI, u.Query().Get("manifest_uri"))
+
+		require.Equal(t, "openid-credential-offer://?credential_offer_uri=https%3A%2F%2Fissuer.example.com%2Foffers%2F1"+
+			"&manifest_uri=https%3A%2F%2Fissuer.example.com%2Fissuer1%2Fmanifests%2Fdegree",
+			link(t, `{"credentialOfferURI":"https://issuer.example.com/offers/1","manifestURI":"`+manifestURI+`"}`))
+	})
+
+	t.Run("invitation", func(t *testing.T) {
+		deepLink := link(t, `{"invitation":{"@type": "https://didcomm.org/out-of-band/1.0/invitation",
+			"@id": "1234"}}`)
+		require.True(t, strings.HasPrefix(deepLink, "didcomm://?_oob="))
+
+		invitation, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(deepLink, "didcomm://?_oob="))
+		require.NoError(t, err)
+		require.Equal(t, `{"@type":"https://didcomm.org/out-of-band/1.0/invitation","@id":"1234"}`, string(invitation))
+	})
+
+	t.Run("presentation request", func(t *testing.T) {
+		requestURI := "https://verifier.example.com/verifier1/verifier/presentationRequests/8e5f2a3c"
+
+		require.Equal(t, requestURI, link(t, `{"presentationRequestURI":"`+requestURI+`"}`))
+	})
+
+	t.Run("png QR code", func(t *testing.T) {
+		rr := post(t, `{"credentialOfferURI":"https://issuer.example.com/offers/1","format":"png","size":300}`)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, pngContentType, rr.Header().Get("Content-Type"))
+
+		img, err := png.Decode(bytes.NewReader(rr.Body.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, 300, img.Bounds().Dx())
+		require.Equal(t, 300, img.Bounds().Dy())
+
+		rr = post(t, `{"credentialOfferURI":"https://issuer.example.com/offers/1","format":"png"}`)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		img, err = png.Decode(bytes.NewReader(rr.Body.Bytes()))
+		require.NoError(t, err)
+		require.Equal(t, DefaultSize, img.Bounds().Dx())
+	})
+
+	t.Run("svg QR code", func(t *testing.T) {
+		rr := post(t, `{"credentialOfferURI":"https://issuer.example.com/offers/1","format":"svg","size":200}`)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, svgContentType, rr.Header().Get("Content-Type"))
+
+		image := rr.Body.String()
+		require.True(t, strings.HasPrefix(image, `<svg xmlns="http://www.w3.org/2000/svg" width="200" height="200"`))
+		require.Contains(t, image, `<path fill="#000" d="M`)
+		require.True(t, strings.HasSuffix(image, "</svg>"))
+	})
+
+	t.Run("content too long", func(t *testing.T) {
+		rr := post(t, `{"presentationRequestURI":"https://verifier.example.com/`+strings.Repeat("a", 4000)+
+			`","format":"svg"}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to encode the QR code of the link")
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		for _, tc := range []struct {
+			name    string
+			request string
+			err     string
+		}{
+			{name: "nothing to link", request: `{}`, err: "exactly one of credentialOffer"},
+			{name: "two things to link", request: `{"credentialOfferURI":"https://issuer.example.com/offers/1",
+				"presentationRequestURI":"https://verifier.example.com/requests/1"}`, err: "exactly one of"},
+			{name: "invalid offer URI", request: `{"credentialOfferURI":"issuer.example.com/offers/1"}`,
+				err: "credentialOfferURI must be an absolute http(s) URL"},
+			{name: "manifest without offer", request: `{"presentationRequestURI":"https://verifier.example.com",
+				"manifestURI":"https://issuer.example.com/issuer1/manifests/degree"}`,
+				err: "manifestURI must be set with a credential offer"},
+			{name: "invalid manifest URI", request: `{"credentialOfferURI":"https://issuer.example.com",
+				"manifestURI":"issuer.example.com/issuer1/manifests/degree"}`,
+				err: "manifestURI must be an absolute http(s) URL"},
+			{name: "invalid request URI", request: `{"presentationRequestURI":"ftp://verifier.example.com"}`,
+				err: "presentationRequestURI must be an absolute http(s) URL"},
+			{name: "invalid format", request: `{"credentialOfferURI":"https://issuer.example.com","format":"gif"}`,
+				err: "invalid format: gif"},
+			{name: "invalid size", request: `{"credentialOfferURI":"https://issuer.example.com","size":2048}`,
+				err: "size must be between 1 and 1024 pixels"},
+			{name: "offer not an object", request: `{"credentialOffer":["offer"]}`,
+				err: "invalid credential offer: must be a JSON object"},
+			{name: "invitation not an object", request: `{"invitation":"invitation"}`,
+				err: "invalid invitation: must be a JSON object"},
+			{name: "invalid json", request: `{`, err: "Invalid request"},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				rr := post(t, tc.request)
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+				require.Contains(t, rr.Body.String(), tc.err)
+			})
+		}
+	})
+}
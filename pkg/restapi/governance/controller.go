@@ -0,0 +1,31 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package governance
+
+import (
+	"github.com/trustbloc/edge-service/pkg/restapi/governance/operation"
+)
+
+// New returns new controller instance.
+func New(config *operation.Config) (*Controller, error) {
+	governanceService, err := operation.New(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Controller{handlers: governanceService.GetRESTHandlers()}, nil
+}
+
+// Controller contains handlers for controller
+type Controller struct {
+	handlers []operation.Handler
+}
+
+// GetOperations returns all controller endpoints
+func (c *Controller) GetOperations() []operation.Handler {
+	return c.handlers
+}
@@ -0,0 +1,35 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/edge-service/pkg/restapi/model"
+)
+
+// GovernanceProfileRequest governance mode profile request
+type GovernanceProfileRequest struct {
+	Name                    string                             `json:"name"`
+	URI                     string                             `json:"uri"`
+	SignatureType           string                             `json:"signatureType"`
+	SignatureRepresentation verifiable.SignatureRepresentation `json:"signatureRepresentation"`
+	DID                     string                             `json:"did"`
+	DIDPrivateKey           string                             `json:"didPrivateKey"`
+	DIDKeyType              string                             `json:"didKeyType"`
+	DIDKeyID                string                             `json:"didKeyID"`
+	UNIRegistrar            model.UNIRegistrar                 `json:"uniRegistrar,omitempty"`
+}
+
+// IssueCredentialRequest request for issuing a governance credential. The name identifies the published
+// credential of the profile (e.g. trusted-issuers).
+type IssueCredentialRequest struct {
+	Name       string          `json:"name"`
+	Credential json.RawMessage `json:"credential"`
+}
@@ -0,0 +1,371 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	ariescrypto "github.com/hyperledger/aries-framework-go/pkg/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/cache"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/httpclient"
+	"github.com/trustbloc/edge-service/pkg/internal/common/support"
+	commondid "github.com/trustbloc/edge-service/pkg/restapi/internal/common/did"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+	"github.com/trustbloc/edge-service/pkg/restapi/internal/common/vcutil"
+	"github.com/trustbloc/edge-service/pkg/restapi/model"
+)
+
+const (
+	profileIDPathParam = "profileID"
+	namePathParam      = "name"
+
+	// governance endpoints
+	governanceProfileEndpoint    = "/governance/profile"
+	getGovernanceProfileEndpoint = governanceProfileEndpoint + "/" + "{" + profileIDPathParam + "}"
+	issueCredentialEndpoint      = "/" + "{" + profileIDPathParam + "}" + "/governance/issueCredential"
+	wellKnownCredentialPath      = "/.well-known/governance/"
+	wellKnownCredentialEndpoint  = "/" + "{" + profileIDPathParam + "}" + wellKnownCredentialPath +
+		"{" + namePathParam + "}.json"
+
+	storeName  = "governance"
+	keyPattern = "%s_%s"
+)
+
+var logger = log.New("edge-service-governance-restapi")
+
+// Handler http handler for each controller API endpoint
+type Handler interface {
+	Path() string
+	Method() string
+	Handle() http.HandlerFunc
+}
+
+type commonDID interface {
+	CreateDID(keyType, signatureType, did, privateKey, keyID, purpose string,
+		registrar model.UNIRegistrar) (string, string, error)
+}
+
+// New returns governance operation instance
+func New(config *Config) (*Operation, error) {
+	p, err := vcprofile.New(config.StoreProvider, vcprofile.WithCache(config.ProfileCache))
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.StoreProvider.CreateStore(storeName)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := config.StoreProvider.OpenStore(storeName)
+	if err != nil {
+		return nil, err
+	}
+
+	svc := &Operation{
+		profileStore: p,
+		store:        store,
+		commonDID: commondid.New(&commondid.Config{VDRI: config.VDRI, KeyManager: config.KeyManager,
+			Domain: config.Domain, TLSConfig: config.TLSConfig, HTTPClients: config.HTTPClients,
+			UniRegistrarHTTPClients: config.UniRegistrarHTTPClients}),
+		crypto:  crypto.New(config.KeyManager, config.Crypto, config.VDRI),
+		hostURL: config.HostURL,
+	}
+
+	return svc, nil
+}
+
+// Config defines configuration for governance operations
+type Config struct {
+	StoreProvider storage.Provider
+	KeyManager    keyManager
+	VDRI          vdriapi.Registry
+	Domain        string
+	TLSConfig     *tls.Config
+	Crypto        ariescrypto.Crypto
+	ProfileCache  cache.Cache
+	// HostURL is the external URL of the service, the default IDs of the governance credentials are their
+	// well-known URLs.
+	HostURL string
+	// HTTPClients creates the clients of the outbound calls (optional).
+	HTTPClients *httpclient.Factory
+	// UniRegistrarHTTPClients creates the clients of the uni-registrar requests, in their own trust domain
+	// (optional, HTTPClients by default).
+	UniRegistrarHTTPClients *httpclient.Factory
+}
+
+type keyManager interface {
+	kms.KeyManager
+}
+
+// Operation defines handlers for the governance mode
+type Operation struct {
+	commonDID    commonDID
+	profileStore *vcprofile.Profile
+	store        storage.Store
+	crypto       *crypto.Crypto
+	hostURL      string
+}
+
+// GetRESTHandlers get all controller API handler available for this service
+func (o *Operation) GetRESTHandlers() []Handler {
+	return []Handler{
+		// governance profile
+		support.NewHTTPHandler(governanceProfileEndpoint, http.MethodPost, o.createGovernanceProfileHandler),
+		support.NewHTTPHandler(getGovernanceProfileEndpoint, http.MethodGet, o.getGovernanceProfileHandler),
+
+		// governance credentials
+		support.NewHTTPHandler(issueCredentialEndpoint, http.MethodPost, o.issueCredentialHandler),
+		support.NewHTTPHandler(wellKnownCredentialEndpoint, http.MethodGet, o.getCredentialHandler),
+	}
+}
+
+// CreateGovernanceProfile swagger:route POST /governance/profile governance governanceProfileReq
+//
+// Creates governance profile.
+//
+// Responses:
+//    default: genericError
+//        201: governanceProfileRes
+func (o *Operation) createGovernanceProfileHandler(rw http.ResponseWriter, req *http.Request) {
+	request := &GovernanceProfileRequest{}
+
+	if err := commhttp.DecodeJSON(req, request); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if err := validateGovernanceProfileRequest(request); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+
+		return
+	}
+
+	profile, err := o.profileStore.GetGovernanceProfile(request.Name)
+	if err != nil && !errors.Is(err, storage.ErrValueNotFound) {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.StorageError, err.Error())
+
+		return
+	}
+
+	if profile != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.AlreadyExists,
+			fmt.Sprintf("profile %s already exists", profile.Name))
+
+		return
+	}
+
+	profile, err = o.createGovernanceProfile(request)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.DIDError, err.Error())
+
+		return
+	}
+
+	err = o.profileStore.SaveGovernanceProfile(profile)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.StorageError, err.Error())
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, profile)
+}
+
+// RetrieveGovernanceProfile swagger:route GET /governance/profile/{id} governance retrieveGovernanceProfileReq
+//
+// Retrieves governance profile.
+//
+// Responses:
+//    default: genericError
+//        200: governanceProfileRes
+func (o *Operation) getGovernanceProfileHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.profileStore.GetGovernanceProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err), err.Error())
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, profile)
+}
+
+// IssueGovernanceCredential swagger:route POST /{id}/governance/issueCredential governance issueGovernanceCredentialReq
+//
+// Issues a governance credential (e.g. a trusted issuer list or a rules document) and publishes it at
+// /{id}/.well-known/governance/{name}.json, replacing the credential previously published under the name.
+//
+// Responses:
+//    default: genericError
+//        201: verifiableCredentialRes
+func (o *Operation) issueCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	profileID := mux.Vars(req)[profileIDPathParam]
+
+	profile, err := o.profileStore.GetGovernanceProfile(profileID)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid governance profile - id=%s: err=%s", profileID, err.Error()))
+
+		return
+	}
+
+	request := &IssueCredentialRequest{}
+
+	if err = commhttp.DecodeJSON(req, request); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if err = validateIssueCredentialRequest(request); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+
+		return
+	}
+
+	credential, err := verifiable.ParseCredential(request.Credential, verifiable.WithDisabledProofCheck())
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("failed to validate credential: %s", err.Error()))
+
+		return
+	}
+
+	if credential.ID == "" {
+		credential.ID = o.hostURL + "/" + profileID + wellKnownCredentialPath + request.Name + ".json"
+	}
+
+	vcutil.UpdateIssuer(credential, profile)
+	vcutil.UpdateSignatureTypeContext(credential, profile)
+
+	signedVC, err := o.crypto.SignCredential(profile, credential)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.SigningError,
+			fmt.Sprintf("failed to sign credential: %s", err.Error()))
+
+		return
+	}
+
+	vcBytes, err := signedVC.MarshalJSON()
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to marshal credential: %s", err.Error()))
+
+		return
+	}
+
+	err = o.store.Put(getDBKey(profileID, request.Name), vcBytes)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to store credential: %s", err.Error()))
+
+		return
+	}
+
+	logger.Infof("published governance credential %s of profile %s", request.Name, profileID)
+
+	rw.WriteHeader(http.StatusCreated)
+
+	if _, err = rw.Write(vcBytes); err != nil {
+		logger.Errorf("failed to write response: %s", err.Error())
+	}
+}
+
+// GetGovernanceCredential swagger:route GET /{id}/.well-known/governance/{name}.json governance governanceCredentialReq
+//
+// Retrieves the published governance credential.
+//
+// Responses:
+//    default: genericError
+//        200: verifiableCredentialRes
+func (o *Operation) getCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	profileID := mux.Vars(req)[profileIDPathParam]
+	name := mux.Vars(req)[namePathParam]
+
+	vcBytes, err := o.store.Get(getDBKey(profileID, name))
+	if errors.Is(err, storage.ErrValueNotFound) {
+		commhttp.WriteErrorResponse(rw, http.StatusNotFound, commhttp.NotFound,
+			fmt.Sprintf("governance credential %s of profile %s not found", name, profileID))
+
+		return
+	}
+
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to get governance credential: %s", err.Error()))
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	if _, err = rw.Write(vcBytes); err != nil {
+		logger.Errorf("failed to write response: %s", err.Error())
+	}
+}
+
+func (o *Operation) createGovernanceProfile(pr *GovernanceProfileRequest) (*vcprofile.DataProfile, error) {
+	didID, publicKeyID, err := o.commonDID.CreateDID(pr.DIDKeyType, pr.SignatureType, pr.DID,
+		pr.DIDPrivateKey, pr.DIDKeyID, crypto.AssertionMethod, pr.UNIRegistrar)
+	if err != nil {
+		return nil, err
+	}
+
+	created := time.Now().UTC()
+
+	// the governance credentials are always issued by the governance authority
+	return &vcprofile.DataProfile{
+		Name:                    pr.Name,
+		URI:                     pr.URI,
+		Created:                 &created,
+		DID:                     didID,
+		SignatureType:           pr.SignatureType,
+		SignatureRepresentation: pr.SignatureRepresentation,
+		Creator:                 publicKeyID,
+		OverwriteIssuer:         true,
+		DisableVCStatus:         true,
+	}, nil
+}
+
+func validateGovernanceProfileRequest(pr *GovernanceProfileRequest) error {
+	if pr.Name == "" {
+		return fmt.Errorf("missing profile name")
+	}
+
+	return nil
+}
+
+func validateIssueCredentialRequest(request *IssueCredentialRequest) error {
+	switch {
+	case request.Name == "" || strings.ContainsAny(request.Name, "/?#"):
+		return fmt.Errorf("invalid governance credential name: %s", request.Name)
+	case len(request.Credential) == 0:
+		return fmt.Errorf("missing credential")
+	}
+
+	return nil
+}
+
+func getDBKey(profileID, name string) string {
+	return fmt.Sprintf(keyPattern, profileID, name)
+}
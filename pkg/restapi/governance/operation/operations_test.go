@@ -0,0 +1,326 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/restapi/model"
+)
+
+const (
+	keyID = "key-1"
+
+	trustedIssuersVC = `{
+		"@context": [
+			"https://www.w3.org/2018/credentials/v1"
+		],
+		"type": ["VerifiableCredential", "TrustedIssuerListCredential"],
+		"credentialSubject": {
+			"id": "https://example.com/governance/v1",
+			"trustedIssuers": [{"id": "did:example:issuer", "credentialTypes": ["UniversityDegreeCredential"]}]
+		},
+		"issuer": "did:example:governance",
+		"issuanceDate": "2020-06-01T00:00:00Z"
+	}`
+)
+
+func TestNew(t *testing.T) {
+	t.Run("create store error", func(t *testing.T) {
+		op, err := New(&Config{StoreProvider: &failingCreateStoreProvider{
+			Provider: memstore.NewProvider(), name: storeName}})
+		require.EqualError(t, err, "create store error")
+		require.Nil(t, op)
+	})
+
+	t.Run("store exists already", func(t *testing.T) {
+		provider := memstore.NewProvider()
+		require.NoError(t, provider.CreateStore(storeName))
+
+		op, err := New(&Config{StoreProvider: provider})
+		require.NoError(t, err)
+		require.NotNil(t, op)
+	})
+}
+
+func TestCreateGovernanceProfile(t *testing.T) {
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(), VDRI: &vdrimock.MockVDRIRegistry{}})
+	require.NoError(t, err)
+
+	op.commonDID = &mockCommonDID{createDIDValue: "did:test:abc", createDIDKeyID: "did:test:abc#" + keyID}
+
+	handler := getHandler(t, op, governanceProfileEndpoint)
+
+	t.Run("create profile - success", func(t *testing.T) {
+		reqBytes, err := json.Marshal(&GovernanceProfileRequest{Name: "governance",
+			SignatureType: vccrypto.Ed25519Signature2018})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, governanceProfileEndpoint, reqBytes, nil)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		profile := &vcprofile.DataProfile{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), profile))
+		require.Equal(t, "governance", profile.Name)
+		require.Equal(t, "did:test:abc", profile.DID)
+		require.Equal(t, "did:test:abc#"+keyID, profile.Creator)
+		require.True(t, profile.OverwriteIssuer)
+
+		rr = serveHTTPMux(t, handler, governanceProfileEndpoint, reqBytes, nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "profile governance already exists")
+	})
+
+	t.Run("create profile - invalid request", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, governanceProfileEndpoint, []byte("invalid-json"), nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "Invalid request")
+
+		rr = serveHTTPMux(t, handler, governanceProfileEndpoint, []byte("{}"), nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "missing profile name")
+	})
+
+	t.Run("create profile - failed to create DID", func(t *testing.T) {
+		op.commonDID = &mockCommonDID{createDIDErr: errors.New("create did error")}
+
+		rr := serveHTTPMux(t, handler, governanceProfileEndpoint, []byte(`{"name":"governance-2"}`), nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "create did error")
+	})
+}
+
+func TestGetGovernanceProfile(t *testing.T) {
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(), VDRI: &vdrimock.MockVDRIRegistry{}})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveGovernanceProfile(&vcprofile.DataProfile{Name: "governance"}))
+
+	handler := getHandler(t, op, getGovernanceProfileEndpoint)
+
+	t.Run("get profile - success", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, "/governance/profile/governance", nil,
+			map[string]string{profileIDPathParam: "governance"})
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), `"name":"governance"`)
+	})
+
+	t.Run("get profile - not found", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, "/governance/profile/unknown", nil,
+			map[string]string{profileIDPathParam: "unknown"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "PROFILE_NOT_FOUND")
+	})
+}
+
+func TestIssueAndGetCredential(t *testing.T) {
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		StoreProvider: memstore.NewProvider(),
+		KeyManager:    &mockkms.KeyManager{CreateKeyID: keyID, CreateKeyValue: kh},
+		Crypto:        &cryptomock.Crypto{},
+		VDRI: &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, opts ...vdri.ResolveOpts) (*did.Doc, error) {
+				return createDIDDoc(didID, pubKey), nil
+			},
+		},
+		HostURL: "https://example.com",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveGovernanceProfile(&vcprofile.DataProfile{Name: "governance",
+		DID: "did:test:abc", Creator: "did:test:abc#" + keyID, SignatureType: vccrypto.Ed25519Signature2018,
+		OverwriteIssuer: true}))
+
+	issueHandler := getHandler(t, op, issueCredentialEndpoint)
+	getCredentialHandler := getHandler(t, op, wellKnownCredentialEndpoint)
+
+	urlVars := map[string]string{profileIDPathParam: "governance", namePathParam: "trusted-issuers"}
+
+	t.Run("issue and get credential - success", func(t *testing.T) {
+		reqBytes, err := json.Marshal(&IssueCredentialRequest{Name: "trusted-issuers",
+			Credential: []byte(trustedIssuersVC)})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, issueHandler, "/governance/governance/issueCredential", reqBytes, urlVars)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		signedVC := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &signedVC))
+		require.NotEmpty(t, signedVC["proof"])
+		require.Equal(t, "https://example.com/governance/.well-known/governance/trusted-issuers.json", signedVC["id"])
+		require.Equal(t, "did:test:abc", signedVC["issuer"].(map[string]interface{})["id"])
+
+		rr = serveHTTPMux(t, getCredentialHandler, "/governance/.well-known/governance/trusted-issuers.json",
+			nil, urlVars)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		publishedVC := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &publishedVC))
+		require.Equal(t, signedVC, publishedVC)
+	})
+
+	t.Run("issue credential - invalid profile", func(t *testing.T) {
+		rr := serveHTTPMux(t, issueHandler, "/unknown/governance/issueCredential", nil,
+			map[string]string{profileIDPathParam: "unknown"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid governance profile - id=unknown")
+	})
+
+	t.Run("issue credential - invalid request", func(t *testing.T) {
+		rr := serveHTTPMux(t, issueHandler, "/governance/governance/issueCredential", []byte("invalid-json"),
+			urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "Invalid request")
+
+		rr = serveHTTPMux(t, issueHandler, "/governance/governance/issueCredential",
+			[]byte(`{"name":"a/b","credential":{}}`), urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid governance credential name: a/b")
+
+		rr = serveHTTPMux(t, issueHandler, "/governance/governance/issueCredential",
+			[]byte(`{"name":"rules"}`), urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "missing credential")
+
+		rr = serveHTTPMux(t, issueHandler, "/governance/governance/issueCredential",
+			[]byte(`{"name":"rules","credential":{"type":"VerifiableCredential"}}`), urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to validate credential")
+	})
+
+	t.Run("issue credential - signing error", func(t *testing.T) {
+		require.NoError(t, op.profileStore.SaveGovernanceProfile(&vcprofile.DataProfile{Name: "invalid-creator",
+			DID: "did:test:abc", Creator: "invalid", SignatureType: vccrypto.Ed25519Signature2018}))
+
+		reqBytes, err := json.Marshal(&IssueCredentialRequest{Name: "rules", Credential: []byte(trustedIssuersVC)})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, issueHandler, "/invalid-creator/governance/issueCredential", reqBytes,
+			map[string]string{profileIDPathParam: "invalid-creator"})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to sign credential")
+	})
+
+	t.Run("get credential - not found", func(t *testing.T) {
+		rr := serveHTTPMux(t, getCredentialHandler, "/governance/.well-known/governance/rules.json", nil,
+			map[string]string{profileIDPathParam: "governance", namePathParam: "rules"})
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), "governance credential rules of profile governance not found")
+	})
+}
+
+func TestCredentialStoreErrors(t *testing.T) {
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(), VDRI: &vdrimock.MockVDRIRegistry{}})
+	require.NoError(t, err)
+
+	op.store = &mockstore.MockStore{Store: map[string][]byte{getDBKey("governance", "rules"): nil},
+		ErrGet: errors.New("get error")}
+
+	rr := serveHTTPMux(t, getHandler(t, op, wellKnownCredentialEndpoint),
+		"/governance/.well-known/governance/rules.json", nil,
+		map[string]string{profileIDPathParam: "governance", namePathParam: "rules"})
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+	require.Contains(t, rr.Body.String(), "failed to get governance credential: get error")
+}
+
+type mockCommonDID struct {
+	createDIDValue string
+	createDIDKeyID string
+	createDIDErr   error
+}
+
+func (m *mockCommonDID) CreateDID(keyType, signatureType, didID, privateKey, keyID, purpose string,
+	registrar model.UNIRegistrar) (string, string, error) {
+	return m.createDIDValue, m.createDIDKeyID, m.createDIDErr
+}
+
+type failingCreateStoreProvider struct {
+	storage.Provider
+	name string
+}
+
+func (p *failingCreateStoreProvider) CreateStore(name string) error {
+	if name == p.name {
+		return errors.New("create store error")
+	}
+
+	return p.Provider.CreateStore(name)
+}
+
+func getHandler(t *testing.T, op *Operation, lookup string) Handler {
+	for _, h := range op.GetRESTHandlers() {
+		if h.Path() == lookup {
+			return h
+		}
+	}
+
+	require.Fail(t, "unable to find handler")
+
+	return nil
+}
+
+func serveHTTPMux(t *testing.T, handler Handler, endpoint string, reqBytes []byte,
+	urlVars map[string]string) *httptest.ResponseRecorder {
+	r, err := http.NewRequest(handler.Method(), endpoint, bytes.NewBuffer(reqBytes))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+
+	handler.Handle().ServeHTTP(rr, mux.SetURLVars(r, urlVars))
+
+	return rr
+}
+
+func createDIDDoc(didID string, pubKey []byte) *did.Doc {
+	signingKey := did.PublicKey{
+		ID:         didID + "#" + keyID,
+		Type:       "Ed25519VerificationKey2018",
+		Controller: didID,
+		Value:      pubKey,
+	}
+
+	createdTime := time.Now()
+
+	return &did.Doc{
+		Context:         []string{"https://w3id.org/did/v1"},
+		ID:              didID,
+		PublicKey:       []did.PublicKey{signingKey},
+		Created:         &createdTime,
+		AssertionMethod: []did.VerificationMethod{{PublicKey: signingKey}},
+		Authentication:  []did.VerificationMethod{{PublicKey: signingKey}},
+	}
+}
@@ -46,5 +46,5 @@ func TestController_GetOperations(t *testing.T) {
 
 	ops := controller.GetOperations()
 
-	require.Equal(t, 3, len(ops))
+	require.Equal(t, 8, len(ops))
 }
@@ -0,0 +1,111 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/consent"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	domainQueryParam       = "domain"
+	credentialIDQueryParam = "credentialID"
+	sinceQueryParam        = "since"
+	untilQueryParam        = "until"
+	limitQueryParam        = "limit"
+)
+
+// recordPresentation records the signed presentation in the consent log of the profile
+func (o *Operation) recordPresentation(profile string, vp *verifiable.Presentation,
+	opts *SignPresentationOptions) error {
+	if opts == nil {
+		opts = &SignPresentationOptions{}
+	}
+
+	entry, err := consent.NewEntry(vp, opts.Domain, opts.Challenge)
+	if err != nil {
+		return err
+	}
+
+	return o.consentLog.Record(profile, entry)
+}
+
+// ConsentLog swagger:route GET /{profileID}/holder/consentLog holder consentLogReq
+//
+// Returns the presentations signed by the holder profile, latest first: the domain they are presented to, and the
+// credentials and claims they reveal. The presentations are filtered by the domain, a credential they reveal, and
+// their creation time.
+//
+// Responses:
+//    default: genericError
+//        200: consentLogRes
+func (o *Operation) consentLogHandler(rw http.ResponseWriter, req *http.Request) {
+	profileID := mux.Vars(req)[profileIDPathParam]
+
+	profile, err := o.profileStore.GetHolderProfile(profileID)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid holder profile - id=%s: err=%s", profileID, err.Error()))
+
+		return
+	}
+
+	query, err := consentQuery(req.URL.Query())
+	if err != nil {
+		commhttp.WriteError(rw, commhttp.NewValidationError(err))
+
+		return
+	}
+
+	entries, err := o.consentLog.Query(profile.Name, query)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to query consent log: %s", err.Error()))
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, &ConsentLogResponse{Entries: entries})
+}
+
+// consentQuery returns the query of the query parameters, the times being RFC 3339 timestamps
+func consentQuery(values url.Values) (*consent.Query, error) {
+	query := &consent.Query{Domain: values.Get(domainQueryParam), CredentialID: values.Get(credentialIDQueryParam)}
+
+	validationErr := &commhttp.ValidationError{}
+
+	var err error
+
+	if since := values.Get(sinceQueryParam); since != "" {
+		if query.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			validationErr.Add(sinceQueryParam, "since must be an RFC 3339 timestamp")
+		}
+	}
+
+	if until := values.Get(untilQueryParam); until != "" {
+		if query.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			validationErr.Add(untilQueryParam, "until must be an RFC 3339 timestamp")
+		}
+	}
+
+	if limit := values.Get(limitQueryParam); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 1 {
+			validationErr.Add(limitQueryParam, "limit must be a positive integer")
+		}
+	}
+
+	return query, validationErr.ErrorOrNil()
+}
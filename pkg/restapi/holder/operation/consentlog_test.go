@@ -0,0 +1,128 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+)
+
+func TestConsentLog(t *testing.T) {
+	op, err := New(&Config{Crypto: &cryptomock.Crypto{}, StoreProvider: memstore.NewProvider(),
+		VDRI: &vdrimock.MockVDRIRegistry{}})
+	require.NoError(t, err)
+
+	profile := &vcprofile.HolderProfile{Name: "test", SignatureType: vccrypto.Ed25519Signature2018}
+	require.NoError(t, op.profileStore.SaveHolderProfile(profile))
+
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(expiringVC("http://example.edu/credentials/1",
+		time.Now().Add(time.Hour))))
+	require.NoError(t, err)
+
+	vp, err := vc.Presentation()
+	require.NoError(t, err)
+
+	require.NoError(t, op.recordPresentation(profile.Name, vp, &SignPresentationOptions{Domain: "a.example.com",
+		Challenge: "challenge1"}))
+	require.NoError(t, op.recordPresentation(profile.Name, vp, nil))
+
+	handler := getHandler(t, op, consentLogEndpoint)
+	urlVars := map[string]string{profileIDPathParam: profile.Name}
+
+	t.Run("test consent log", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, consentLogEndpoint, nil, urlVars)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		res := &ConsentLogResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), res))
+		require.Len(t, res.Entries, 2)
+		require.Empty(t, res.Entries[0].Domain)
+		require.Equal(t, "a.example.com", res.Entries[1].Domain)
+		require.Equal(t, "challenge1", res.Entries[1].Challenge)
+		require.Len(t, res.Entries[1].Credentials, 1)
+		require.Equal(t, "http://example.edu/credentials/1", res.Entries[1].Credentials[0].ID)
+		require.Equal(t, []string{"/credentialSubject/id"}, res.Entries[1].Credentials[0].Claims)
+	})
+
+	t.Run("test consent log query", func(t *testing.T) {
+		since := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+
+		rr := serveHTTPMux(t, handler, consentLogEndpoint+"?domain=a.example.com&since="+since+
+			"&credentialID=http://example.edu/credentials/1&limit=1", nil, urlVars)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		res := &ConsentLogResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), res))
+		require.Len(t, res.Entries, 1)
+		require.Equal(t, "a.example.com", res.Entries[0].Domain)
+
+		rr = serveHTTPMux(t, handler, consentLogEndpoint+"?until=2000-01-01T00:00:00Z", nil, urlVars)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		res = &ConsentLogResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), res))
+		require.Empty(t, res.Entries)
+	})
+
+	t.Run("test invalid query", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, consentLogEndpoint+"?since=yesterday&until=1&limit=0", nil, urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "since must be an RFC 3339 timestamp")
+		require.Contains(t, rr.Body.String(), "until must be an RFC 3339 timestamp")
+		require.Contains(t, rr.Body.String(), "limit must be a positive integer")
+	})
+
+	t.Run("test invalid profile", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, consentLogEndpoint, nil, map[string]string{profileIDPathParam: "invalid"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid holder profile")
+	})
+
+	t.Run("test error from store", func(t *testing.T) {
+		store := &mockstore.MockStore{Store: map[string][]byte{}}
+
+		op, err := New(&Config{Crypto: &cryptomock.Crypto{}, StoreProvider: &mockstore.Provider{Store: store},
+			VDRI: &vdrimock.MockVDRIRegistry{}})
+		require.NoError(t, err)
+
+		require.NoError(t, op.profileStore.SaveHolderProfile(profile))
+
+		store.ErrPut = fmt.Errorf("error put")
+		err = op.recordPresentation(profile.Name, vp, nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "error put")
+
+		store.ErrPut = nil
+		store.Store["count_"+profile.Name] = []byte("x")
+
+		rr := serveHTTPMux(t, getHandler(t, op, consentLogEndpoint), consentLogEndpoint, nil, urlVars)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to query consent log")
+	})
+}
+
+func TestNew_ConsentLogError(t *testing.T) {
+	op, err := New(&Config{Crypto: &cryptomock.Crypto{}, VDRI: &vdrimock.MockVDRIRegistry{},
+		StoreProvider: &mockstore.Provider{Store: &mockstore.MockStore{Store: map[string][]byte{}},
+			FailNameSpace: "consentlog"}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to instantiate consent log")
+	require.Nil(t, op)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/redaction"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+// DeriveCredential swagger:route POST /{profileID}/holder/credentials/derive holder deriveCredentialReq
+//
+// Derives a redacted credential from a credential issued with the redactable option: the claims under the revealed
+// JSON pointers are disclosed, and the other claims are replaced by their salted digests. The signature of the
+// credential, which covers the digest of the claims, is kept, so the selective disclosure works with any signature
+// suite, e.g. Ed25519.
+//
+// Responses:
+//    default: genericError
+//        200: deriveCredentialRes
+func (o *Operation) deriveCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	profileID := mux.Vars(req)[profileIDPathParam]
+
+	if _, err := o.profileStore.GetHolderProfile(profileID); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid holder profile - id=%s: err=%s", profileID, err.Error()))
+
+		return
+	}
+
+	request := &DeriveCredentialRequest{}
+
+	if err := commhttp.DecodeJSON(req, request); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if err := validateDeriveCredentialRequest(request); err != nil {
+		commhttp.WriteError(rw, commhttp.NewValidationError(err))
+
+		return
+	}
+
+	derived, err := redaction.Derive(request.Credential, request.Claims, request.Reveal)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("failed to derive credential: %s", err.Error()))
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, derived)
+}
+
+func validateDeriveCredentialRequest(request *DeriveCredentialRequest) error {
+	validationErr := &commhttp.ValidationError{}
+
+	if len(request.Credential) == 0 {
+		validationErr.Add("/credential", "credential is required")
+	}
+
+	if len(request.Claims) == 0 {
+		validationErr.Add("/claims", "claims are required")
+	}
+
+	for i, claim := range request.Claims {
+		if claim == nil {
+			validationErr.Add(fmt.Sprintf("/claims/%d", i), "claim must be an object")
+		}
+	}
+
+	return validationErr.ErrorOrNil()
+}
@@ -0,0 +1,113 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/redaction"
+)
+
+func TestDeriveCredential(t *testing.T) {
+	op, err := New(&Config{Crypto: &cryptomock.Crypto{}, StoreProvider: memstore.NewProvider(),
+		VDRI: &vdrimock.MockVDRIRegistry{}})
+	require.NoError(t, err)
+
+	profile := &vcprofile.HolderProfile{Name: "test", SignatureType: vccrypto.Ed25519Signature2018}
+	require.NoError(t, op.profileStore.SaveHolderProfile(profile))
+
+	// the credential committing to its claims, signed by the issuer as a JWT
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(`{` + validContext + `,
+		"id": "http://example.edu/credentials/1872",
+		"type": ["VerifiableCredential", "UniversityDegreeCredential"],
+		"issuer": "did:example:76e12ec712ebc6f1c221ebfeb1f",
+		"issuanceDate": "2010-01-01T19:23:24Z",
+		"credentialSubject": {"id": "did:example:ebfeb1f712ebc6f1c276e12ec21", "name": "Jayden Doe",
+			"degree": {"type": "BachelorDegree", "name": "Bachelor of Science"}}}`))
+	require.NoError(t, err)
+
+	claims, err := redaction.Commit(vc)
+	require.NoError(t, err)
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	jwtClaims, err := vc.JWTClaims(false)
+	require.NoError(t, err)
+
+	jwtVC, err := jwtClaims.MarshalJWS(verifiable.EdDSA, signature.GetEd25519Signer(privKey, pubKey), "#key-1")
+	require.NoError(t, err)
+
+	credential, err := json.Marshal(jwtVC)
+	require.NoError(t, err)
+
+	handler := getHandler(t, op, deriveCredentialEndpoint)
+	urlVars := map[string]string{profileIDPathParam: profile.Name}
+
+	derive := func(t *testing.T, req *DeriveCredentialRequest) (int, []byte) {
+		reqBytes, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, deriveCredentialEndpoint, reqBytes, urlVars)
+
+		return rr.Code, rr.Body.Bytes()
+	}
+
+	t.Run("test derived credential", func(t *testing.T) {
+		code, body := derive(t, &DeriveCredentialRequest{Credential: credential, Claims: claims,
+			Reveal: []string{"/credentialSubject/degree"}})
+		require.Equal(t, http.StatusOK, code, string(body))
+		require.NotContains(t, string(body), "Jayden Doe")
+
+		derived := &redaction.Derived{}
+		require.NoError(t, json.Unmarshal(body, derived))
+		require.JSONEq(t, string(credential), string(derived.Credential))
+
+		disclosed, err := redaction.Verify(vc, derived.Disclosures)
+		require.NoError(t, err)
+		require.Equal(t, claims[:2], disclosed)
+	})
+
+	t.Run("test claims not committed to", func(t *testing.T) {
+		code, body := derive(t, &DeriveCredentialRequest{Credential: credential, Claims: claims[1:]})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "failed to derive credential: the claims don't match the claims digest")
+	})
+
+	t.Run("test invalid request", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, deriveCredentialEndpoint, []byte(`{"claims":[null]}`), urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "credential is required")
+		require.Contains(t, rr.Body.String(), "claim must be an object")
+
+		code, body := derive(t, &DeriveCredentialRequest{Credential: credential})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "claims are required")
+
+		rr = serveHTTPMux(t, handler, deriveCredentialEndpoint, []byte("{"), urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("test invalid profile", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, deriveCredentialEndpoint, nil, map[string]string{profileIDPathParam: "invalid"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid holder profile")
+	})
+}
@@ -0,0 +1,199 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/expirynotice"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/events"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const noticeDayLayout = "2006-01-02"
+
+// StoreCredential swagger:route POST /{profileID}/holder/credentials holder storeCredentialReq
+//
+// Stores a credential of the holder, so the holder is notified of its expiry the expiryNoticeDays of the profile
+// before its expiration date. Only the ID, the types and the expiration date of the credential are kept.
+//
+// Responses:
+//    default: genericError
+//        201: storeCredentialRes
+func (o *Operation) storeCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	profileID := mux.Vars(req)[profileIDPathParam]
+
+	profile, err := o.profileStore.GetHolderProfile(profileID)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid holder profile - id=%s: err=%s", profileID, err.Error()))
+
+		return
+	}
+
+	request := &StoreCredentialRequest{}
+
+	if err := commhttp.DecodeJSON(req, request); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	vc, err := verifiable.ParseCredential(request.Credential, verifiable.WithDisabledProofCheck())
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("failed to parse credential: %s", err.Error()))
+
+		return
+	}
+
+	if vc.ID == "" {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidCredential,
+			"the credential has no ID")
+
+		return
+	}
+
+	day, err := o.expiryNotices.Track(profile.Name, vc, profile.ExpiryNoticeDays)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to store credential: %s", err.Error()))
+
+		return
+	}
+
+	resp := &StoreCredentialResponse{CredentialID: vc.ID}
+
+	if !day.IsZero() {
+		resp.ExpiryNotice = day.Format(noticeDayLayout)
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, resp)
+}
+
+// RemoveCredential swagger:route DELETE /{profileID}/holder/credentials/{credentialID} holder removeCredentialReq
+//
+// Removes a stored credential of the holder, cancelling its expiry notice. The credential ID is base64url encoded
+// (without padding) in the path.
+//
+// Responses:
+//    default: genericError
+//        200: emptyRes
+func (o *Operation) removeCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	profileID := mux.Vars(req)[profileIDPathParam]
+
+	vcID, err := base64.RawURLEncoding.DecodeString(mux.Vars(req)[credentialIDPathParam])
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("invalid credential ID, expected base64url encoding: %s", err.Error()))
+
+		return
+	}
+
+	profile, err := o.profileStore.GetHolderProfile(profileID)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid holder profile - id=%s: err=%s", profileID, err.Error()))
+
+		return
+	}
+
+	if err := o.expiryNotices.Untrack(profile.Name, string(vcID)); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to remove credential: %s", err.Error()))
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+}
+
+// notifyExpiry sends the expiry notice to the webhook of the profile, if any, then emits it. The event is emitted
+// once the webhook accepted the notice, so a notice retried by the next scans isn't emitted twice.
+func (o *Operation) notifyExpiry(notice *expirynotice.Notice) error {
+	profile, err := o.profileStore.GetHolderProfile(notice.Profile)
+	if err != nil {
+		return fmt.Errorf("failed to get holder profile %s: %w", notice.Profile, err)
+	}
+
+	if profile.ExpiryWebhookURL != "" {
+		if err := o.postExpiryNotice(profile, notice); err != nil {
+			return err
+		}
+	}
+
+	if o.events != nil {
+		o.events.Emit(events.CredentialExpiring, notice.Profile, &events.ExpiryData{
+			CredentialID: notice.CredentialID,
+			Types:        notice.Types,
+			Expires:      notice.Expires,
+		})
+	}
+
+	return nil
+}
+
+func (o *Operation) postExpiryNotice(profile *vcprofile.HolderProfile, notice *expirynotice.Notice) error {
+	noticeBytes, err := json.Marshal(notice)
+	if err != nil {
+		return fmt.Errorf("failed to marshal expiry notice: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, profile.ExpiryWebhookURL, bytes.NewBuffer(noticeBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create expiry notice request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.webhookClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post expiry notice: %w", err)
+	}
+
+	if errClose := resp.Body.Close(); errClose != nil {
+		logger.Warnf("failed to close response body")
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("expiry notice rejected with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// validateExpiryNotice checks the expiry notice configuration of the profile request
+func validateExpiryNotice(pr *HolderProfileRequest) error {
+	if pr.ExpiryNoticeDays < 0 {
+		return fmt.Errorf("invalid expiry notice days %d", pr.ExpiryNoticeDays)
+	}
+
+	if pr.ExpiryWebhookURL == "" {
+		return nil
+	}
+
+	if pr.ExpiryNoticeDays == 0 {
+		return fmt.Errorf("the expiry webhook requires expiry notice days")
+	}
+
+	u, err := url.Parse(pr.ExpiryWebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid expiry webhook URL %s", pr.ExpiryWebhookURL)
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/expirynotice"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/events"
+)
+
+type mockPublisher struct {
+	mutex  sync.Mutex
+	events []*events.Event
+}
+
+func (p *mockPublisher) Publish(_ context.Context, event *events.Event) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.events = append(p.events, event)
+
+	return nil
+}
+
+func (p *mockPublisher) Close() error {
+	return nil
+}
+
+func expiringVC(id string, expires time.Time) string {
+	if id != "" {
+		id = `"id":"` + id + `",`
+	}
+
+	return `{` + validContext + `,` + id + `"type":["VerifiableCredential","UniversityDegreeCredential"],` +
+		`"credentialSubject":{"id":"did:example:ebfeb1f712ebc6f1c276e12ec21"},"issuer":"did:example:76e12ec712ebc6f1",` +
+		`"issuanceDate":"2020-01-01T00:00:00Z","expirationDate":"` + expires.Format(time.RFC3339) + `"}`
+}
+
+func TestStoreCredential(t *testing.T) {
+	op, err := New(&Config{Crypto: &cryptomock.Crypto{}, StoreProvider: memstore.NewProvider(),
+		VDRI: &vdrimock.MockVDRIRegistry{}})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveHolderProfile(&vcprofile.HolderProfile{Name: "holder",
+		ExpiryNoticeDays: 30}))
+
+	handler := getHandler(t, op, storeCredentialEndpoint)
+
+	store := func(t *testing.T, profile, vc string) *httptest.ResponseRecorder {
+		reqBytes, err := json.Marshal(&StoreCredentialRequest{Credential: json.RawMessage(vc)})
+		require.NoError(t, err)
+
+		return serveHTTPMux(t, handler, "/"+profile+"/holder/credentials", reqBytes,
+			map[string]string{profileIDPathParam: profile})
+	}
+
+	t.Run("test credential stored", func(t *testing.T) {
+		expires := time.Now().UTC().AddDate(0, 3, 0)
+
+		rr := store(t, "holder", expiringVC("http://example.edu/credentials/1", expires))
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		resp := &StoreCredentialResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		require.Equal(t, &StoreCredentialResponse{CredentialID: "http://example.edu/credentials/1",
+			ExpiryNotice: expires.AddDate(0, 0, -30).Format(noticeDayLayout)}, resp)
+
+		rr = serveHTTPMux(t, getHandler(t, op, removeCredentialEndpoint),
+			"/holder/holder/credentials/"+base64.RawURLEncoding.EncodeToString([]byte(resp.CredentialID)), nil,
+			map[string]string{profileIDPathParam: "holder",
+				credentialIDPathParam: base64.RawURLEncoding.EncodeToString([]byte(resp.CredentialID))})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	})
+
+	t.Run("test credential without expiration date", func(t *testing.T) {
+		rr := store(t, "holder", `{`+validContext+`,"id":"http://example.edu/credentials/2",`+
+			`"type":"VerifiableCredential","credentialSubject":{"id":"did:example:ebfeb1f712ebc6f1c276e12ec21"},`+
+			`"issuer":"did:example:76e12ec712ebc6f1","issuanceDate":"2020-01-01T00:00:00Z"}`)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		require.JSONEq(t, `{"credentialID":"http://example.edu/credentials/2"}`, rr.Body.String())
+	})
+
+	t.Run("test error - invalid requests", func(t *testing.T) {
+		rr := store(t, "unknown", expiringVC("http://example.edu/credentials/1", time.Now()))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid holder profile")
+
+		rr = store(t, "holder", `{"id":"http://example.edu/credentials/1"}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to parse credential")
+
+		rr = store(t, "holder", expiringVC("", time.Now()))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "the credential has no ID")
+
+		rr = serveHTTPMux(t, handler, "/holder/holder/credentials", []byte("{"),
+			map[string]string{profileIDPathParam: "holder"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		remove := getHandler(t, op, removeCredentialEndpoint)
+
+		rr = serveHTTPMux(t, remove, "/holder/holder/credentials/invalid", nil,
+			map[string]string{profileIDPathParam: "holder", credentialIDPathParam: "!"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "expected base64url encoding")
+
+		rr = serveHTTPMux(t, remove, "/unknown/holder/credentials/dmMx", nil,
+			map[string]string{profileIDPathParam: "unknown", credentialIDPathParam: "dmMx"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid holder profile")
+	})
+}
+
+func TestNotifyExpiry(t *testing.T) {
+	var received []*expirynotice.Notice
+
+	status := http.StatusOK
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		notice := &expirynotice.Notice{}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(notice))
+
+		received = append(received, notice)
+
+		rw.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	publisher := &mockPublisher{}
+	emitter := events.NewEmitter(publisher, 0)
+
+	op, err := New(&Config{Crypto: &cryptomock.Crypto{}, StoreProvider: memstore.NewProvider(),
+		VDRI: &vdrimock.MockVDRIRegistry{}, Events: emitter, ExpiryWebhookHTTPClient: server.Client()})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveHolderProfile(&vcprofile.HolderProfile{Name: "holder",
+		ExpiryNoticeDays: 30, ExpiryWebhookURL: server.URL}))
+	require.NoError(t, op.profileStore.SaveHolderProfile(&vcprofile.HolderProfile{Name: "nowebhook",
+		ExpiryNoticeDays: 30}))
+
+	notice := &expirynotice.Notice{Profile: "holder", CredentialID: "http://example.edu/credentials/1",
+		Types: []string{"VerifiableCredential"}, Expires: time.Now().UTC().Truncate(time.Second)}
+
+	// the notice rejected by the webhook isn't emitted until it is accepted
+	status = http.StatusServiceUnavailable
+	require.EqualError(t, op.notifyExpiry(notice), "expiry notice rejected with status 503")
+
+	status = http.StatusOK
+	require.NoError(t, op.notifyExpiry(notice))
+	require.Equal(t, []*expirynotice.Notice{notice, notice}, received)
+
+	require.NoError(t, op.notifyExpiry(&expirynotice.Notice{Profile: "nowebhook",
+		CredentialID: "http://example.edu/credentials/2"}))
+
+	require.Error(t, op.notifyExpiry(&expirynotice.Notice{Profile: "unknown"}))
+
+	require.NoError(t, emitter.Close())
+	require.Len(t, publisher.events, 2)
+	require.Equal(t, events.CredentialExpiring, publisher.events[0].Type)
+	require.Equal(t, "holder", publisher.events[0].Profile)
+	require.Equal(t, &events.ExpiryData{CredentialID: notice.CredentialID, Types: notice.Types,
+		Expires: notice.Expires}, publisher.events[0].Data)
+	require.Equal(t, "nowebhook", publisher.events[1].Profile)
+}
+
+func TestValidateExpiryNotice(t *testing.T) {
+	newRequest := func(days int, webhookURL string) *HolderProfileRequest {
+		return &HolderProfileRequest{Name: "holder", DIDKeyType: vccrypto.Ed25519KeyType,
+			SignatureType: vccrypto.Ed25519Signature2018, ExpiryNoticeDays: days, ExpiryWebhookURL: webhookURL}
+	}
+
+	require.NoError(t, validateHolderProfileRequest(newRequest(0, "")))
+	require.NoError(t, validateHolderProfileRequest(newRequest(30, "https://wallet.example.com/notices")))
+
+	require.EqualError(t, validateHolderProfileRequest(newRequest(-1, "")), "invalid expiry notice days -1")
+	require.EqualError(t, validateHolderProfileRequest(newRequest(0, "https://wallet.example.com/notices")),
+		"the expiry webhook requires expiry notice days")
+	require.EqualError(t, validateHolderProfileRequest(newRequest(30, "wallet.example.com/notices")),
+		"invalid expiry webhook URL wallet.example.com/notices")
+}
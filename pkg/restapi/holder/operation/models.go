@@ -12,6 +12,8 @@ import (
 
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 
+	"github.com/trustbloc/edge-service/pkg/doc/vc/consent"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/redaction"
 	"github.com/trustbloc/edge-service/pkg/restapi/model"
 )
 
@@ -26,6 +28,57 @@ type HolderProfileRequest struct {
 	DIDKeyID                string                             `json:"didKeyID"`
 	UNIRegistrar            model.UNIRegistrar                 `json:"uniRegistrar,omitempty"`
 	OverwriteHolder         bool                               `json:"overwriteHolder,omitempty"`
+	ExpiryNoticeDays        int                                `json:"expiryNoticeDays,omitempty"`
+	ExpiryWebhookURL        string                             `json:"expiryWebhookURL,omitempty"`
+}
+
+// StoreCredentialRequest is the request storing a credential of the holder to be notified of its expiry
+type StoreCredentialRequest struct {
+	Credential json.RawMessage `json:"credential"`
+}
+
+// StoreCredentialResponse is the response of a stored credential
+type StoreCredentialResponse struct {
+	CredentialID string `json:"credentialID"`
+	// ExpiryNotice is the day the holder is notified of the expiry of the credential, empty if the credential has no
+	// expiration date or the profile no expiry notice days.
+	ExpiryNotice string `json:"expiryNotice,omitempty"`
+}
+
+// ConsentLogResponse is the presentations signed by a holder profile, latest first
+type ConsentLogResponse struct {
+	Entries []*consent.Entry `json:"entries"`
+}
+
+// DeriveCredentialRequest is the redactable credential the derived credential discloses claims of.
+type DeriveCredentialRequest struct {
+	// Credential issued with the redactable option, in JSON-LD or as a JWT string.
+	Credential json.RawMessage `json:"credential"`
+	// Claims the credential commits to, with their salts, as returned with the credential by its issuer.
+	Claims []*redaction.Claim `json:"claims"`
+	// Reveal are the JSON pointers of the claims disclosed, e.g. /credentialSubject/degree for all the claims of the
+	// degree, the other claims being redacted.
+	Reveal []string `json:"reveal"`
+}
+
+// PresentSDJWTRequest is the SD-JWT credential the presentation discloses claims of.
+type PresentSDJWTRequest struct {
+	// Credential is the SD-JWT issued to the holder, with the disclosures of all the claims of its subject.
+	Credential string `json:"credential"`
+	// Disclose are the names of the claims of the subject disclosed, e.g. given_name.
+	Disclose []string `json:"disclose,omitempty"`
+	// Audience is the verifier the SD-JWT is presented to, and Nonce its challenge, both signed in the key binding
+	// JWT of a SD-JWT bound to the holder key.
+	Audience string `json:"audience,omitempty"`
+	Nonce    string `json:"nonce,omitempty"`
+	// VerificationMethod signs the key binding JWT, the creator of the profile by default.
+	VerificationMethod string `json:"verificationMethod,omitempty"`
+}
+
+// PresentSDJWTResponse is the presentation of a SD-JWT credential: the issuer JWT, the disclosures of the disclosed
+// claims, and the key binding JWT, if bound to the holder key
+type PresentSDJWTResponse struct {
+	Presentation string `json:"presentation"`
 }
 
 // SignPresentationRequest request for signing a presentation.
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 import (
+	"github.com/trustbloc/edge-service/pkg/doc/vc/redaction"
 	"github.com/trustbloc/edge-service/pkg/restapi/model"
 )
 
@@ -65,3 +66,130 @@ type signPresentationReq struct { // nolint: unused,deadcode
 type signPresentationRes struct { // nolint: unused,deadcode
 	// in: body
 }
+
+// storeCredentialReq model
+//
+// swagger:parameters storeCredentialReq
+type storeCredentialReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"profileID"`
+
+	// in: body
+	Params StoreCredentialRequest
+}
+
+// storeCredentialRes model
+//
+// swagger:response storeCredentialRes
+type storeCredentialRes struct { // nolint: unused,deadcode
+	// in: body
+	StoreCredentialResponse
+}
+
+// removeCredentialReq model
+//
+// swagger:parameters removeCredentialReq
+type removeCredentialReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"profileID"`
+
+	// base64url encoded credential ID
+	//
+	// in: path
+	// required: true
+	CredentialID string `json:"credentialID"`
+}
+
+// deriveCredentialReq model
+//
+// swagger:parameters deriveCredentialReq
+type deriveCredentialReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"profileID"`
+
+	// in: body
+	Params DeriveCredentialRequest
+}
+
+// deriveCredentialRes model
+//
+// swagger:response deriveCredentialRes
+type deriveCredentialRes struct { // nolint: unused,deadcode
+	// in: body
+	redaction.Derived
+}
+
+// presentSDJWTReq model
+//
+// swagger:parameters presentSDJWTReq
+type presentSDJWTReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"profileID"`
+
+	// in: body
+	Params PresentSDJWTRequest
+}
+
+// presentSDJWTRes model
+//
+// swagger:response presentSDJWTRes
+type presentSDJWTRes struct { // nolint: unused,deadcode
+	// in: body
+	PresentSDJWTResponse
+}
+
+// consentLogReq model
+//
+// swagger:parameters consentLogReq
+type consentLogReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"profileID"`
+
+	// domain the presentations are presented to
+	//
+	// in: query
+	Domain string `json:"domain"`
+
+	// ID of a credential the presentations reveal
+	//
+	// in: query
+	CredentialID string `json:"credentialID"`
+
+	// RFC 3339 timestamp the presentations are created since
+	//
+	// in: query
+	Since string `json:"since"`
+
+	// RFC 3339 timestamp the presentations are created until
+	//
+	// in: query
+	Until string `json:"until"`
+
+	// maximum number of presentations
+	//
+	// in: query
+	Limit int `json:"limit"`
+}
+
+// consentLogRes model
+//
+// swagger:response consentLogRes
+type consentLogRes struct { // nolint: unused,deadcode
+	// in: body
+	ConsentLogResponse
+}
@@ -8,7 +8,6 @@ package operation
 
 import (
 	"crypto/tls"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -19,10 +18,16 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/trustbloc/edge-core/pkg/log"
 	"github.com/trustbloc/edge-core/pkg/storage"
 
+	"github.com/trustbloc/edge-service/pkg/cache"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/consent"
 	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/expirynotice"
 	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/events"
+	"github.com/trustbloc/edge-service/pkg/httpclient"
 	"github.com/trustbloc/edge-service/pkg/internal/common/support"
 	commondid "github.com/trustbloc/edge-service/pkg/restapi/internal/common/did"
 	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
@@ -30,16 +35,24 @@ import (
 )
 
 const (
-	profileIDPathParam = "profileID"
+	profileIDPathParam    = "profileID"
+	credentialIDPathParam = "credentialID"
 
 	// holder endpoints
 	holderProfileEndpoint    = "/holder/profile"
 	getHolderProfileEndpoint = holderProfileEndpoint + "/" + "{" + profileIDPathParam + "}"
 	signPresentationEndpoint = "/" + "{" + profileIDPathParam + "}" + "/prove/presentations"
+	storeCredentialEndpoint  = "/" + "{" + profileIDPathParam + "}" + "/holder/credentials"
+	removeCredentialEndpoint = storeCredentialEndpoint + "/{" + credentialIDPathParam + "}"
+	deriveCredentialEndpoint = storeCredentialEndpoint + "/derive"
+	presentSDJWTEndpoint     = storeCredentialEndpoint + "/presentSDJWT"
+	consentLogEndpoint       = "/" + "{" + profileIDPathParam + "}" + "/holder/consentLog"
 
 	invalidRequestErrMsg = "Invalid request"
 )
 
+var logger = log.New("edge-service-holder-restapi")
+
 // Handler http handler for each controller API endpoint
 type Handler interface {
 	Path() string
@@ -54,7 +67,7 @@ type commonDID interface {
 
 // New returns CreateCredential instance
 func New(config *Config) (*Operation, error) {
-	p, err := vcprofile.New(config.StoreProvider)
+	p, err := vcprofile.New(config.StoreProvider, vcprofile.WithCache(config.ProfileCache))
 	if err != nil {
 		return nil, err
 	}
@@ -62,8 +75,33 @@ func New(config *Config) (*Operation, error) {
 	svc := &Operation{
 		profileStore: p,
 		commonDID: commondid.New(&commondid.Config{VDRI: config.VDRI, KeyManager: config.KeyManager,
-			Domain: config.Domain, TLSConfig: config.TLSConfig}),
-		crypto: crypto.New(config.KeyManager, config.Crypto, config.VDRI),
+			Domain: config.Domain, TLSConfig: config.TLSConfig, HTTPClients: config.HTTPClients,
+			UniRegistrarHTTPClients: config.UniRegistrarHTTPClients}),
+		crypto:        crypto.New(config.KeyManager, config.Crypto, config.VDRI),
+		events:        config.Events,
+		webhookClient: config.ExpiryWebhookHTTPClient,
+	}
+
+	if svc.webhookClient == nil {
+		svc.webhookClient = &http.Client{Transport: &http.Transport{TLSClientConfig: config.TLSConfig}}
+
+		if config.HTTPClients != nil {
+			svc.webhookClient = config.HTTPClients.Client()
+		}
+	}
+
+	svc.consentLog, err = consent.New(config.StoreProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate consent log: %w", err)
+	}
+
+	svc.expiryNotices, err = expirynotice.New(config.StoreProvider, svc.notifyExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate expiry notice scheduler: %w", err)
+	}
+
+	if config.ExpiryNoticeInterval > 0 {
+		svc.expiryNotices.Start(config.ExpiryNoticeInterval)
 	}
 
 	return svc, nil
@@ -77,6 +115,20 @@ type Config struct {
 	Domain        string
 	TLSConfig     *tls.Config
 	Crypto        ariescrypto.Crypto
+	ProfileCache  cache.Cache
+	// HTTPClients creates the clients of the outbound calls (optional).
+	HTTPClients *httpclient.Factory
+	// UniRegistrarHTTPClients creates the clients of the uni-registrar requests, in their own trust domain
+	// (optional, HTTPClients by default).
+	UniRegistrarHTTPClients *httpclient.Factory
+	// ExpiryNoticeInterval is the interval at which the holders are notified of their stored credentials expiring,
+	// the holders aren't notified if not set.
+	ExpiryNoticeInterval time.Duration
+	// ExpiryWebhookHTTPClient posts the expiry notices to the webhooks of the profiles, when the webhooks are in
+	// their own trust domain (optional, the client of HTTPClients by default).
+	ExpiryWebhookHTTPClient *http.Client
+	// Events emits the expiry notices to a message broker (optional).
+	Events *events.Emitter
 }
 
 type keyManager interface {
@@ -85,9 +137,13 @@ type keyManager interface {
 
 // Operation defines handlers for Edge service
 type Operation struct {
-	commonDID    commonDID
-	profileStore *vcprofile.Profile
-	crypto       *crypto.Crypto
+	commonDID     commonDID
+	profileStore  *vcprofile.Profile
+	crypto        *crypto.Crypto
+	expiryNotices *expirynotice.Scheduler
+	consentLog    *consent.Log
+	events        *events.Emitter
+	webhookClient *http.Client
 }
 
 // GetRESTHandlers get all controller API handler available for this service
@@ -97,6 +153,11 @@ func (o *Operation) GetRESTHandlers() []Handler {
 		support.NewHTTPHandler(holderProfileEndpoint, http.MethodPost, o.createHolderProfileHandler),
 		support.NewHTTPHandler(getHolderProfileEndpoint, http.MethodGet, o.getHolderProfileHandler),
 		support.NewHTTPHandler(signPresentationEndpoint, http.MethodPost, o.signPresentationHandler),
+		support.NewHTTPHandler(storeCredentialEndpoint, http.MethodPost, o.storeCredentialHandler),
+		support.NewHTTPHandler(removeCredentialEndpoint, http.MethodDelete, o.removeCredentialHandler),
+		support.NewHTTPHandler(deriveCredentialEndpoint, http.MethodPost, o.deriveCredentialHandler),
+		support.NewHTTPHandler(presentSDJWTEndpoint, http.MethodPost, o.presentSDJWTHandler),
+		support.NewHTTPHandler(consentLogEndpoint, http.MethodGet, o.consentLogHandler),
 	}
 }
 
@@ -110,41 +171,42 @@ func (o *Operation) GetRESTHandlers() []Handler {
 func (o *Operation) createHolderProfileHandler(rw http.ResponseWriter, req *http.Request) {
 	request := &HolderProfileRequest{}
 
-	if err := json.NewDecoder(req.Body).Decode(request); err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidRequestErrMsg+": %s", err.Error()))
+	if err := commhttp.DecodeJSON(req, request); err != nil {
+		commhttp.WriteError(rw, err)
 
 		return
 	}
 
 	if err := validateHolderProfileRequest(request); err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
 
 		return
 	}
 
 	profile, err := o.profileStore.GetHolderProfile(request.Name)
 	if err != nil && !errors.Is(err, storage.ErrValueNotFound) {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.StorageError, err.Error())
 
 		return
 	}
 
 	if profile != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf("profile %s already exists", profile.Name))
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.AlreadyExists,
+			fmt.Sprintf("profile %s already exists", profile.Name))
 
 		return
 	}
 
 	profile, err = o.createHolderProfile(request)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.DIDError, err.Error())
 
 		return
 	}
 
 	err = o.profileStore.SaveHolderProfile(profile)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.StorageError, err.Error())
 
 		return
 	}
@@ -167,7 +229,7 @@ func (o *Operation) getHolderProfileHandler(rw http.ResponseWriter, req *http.Re
 
 	profile, err := o.profileStore.GetHolderProfile(profileID)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err), err.Error())
 
 		return
 	}
@@ -177,7 +239,7 @@ func (o *Operation) getHolderProfileHandler(rw http.ResponseWriter, req *http.Re
 
 // SignPresentation swagger:route POST /{id}/prove/presentations holder signPresentationReq
 //
-// Signs a presentation.
+// Signs a presentation, recorded in the consent log of the profile.
 //
 // Responses:
 //    default: genericError
@@ -188,8 +250,8 @@ func (o *Operation) signPresentationHandler(rw http.ResponseWriter, req *http.Re
 
 	profile, err := o.profileStore.GetHolderProfile(profileID)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf("invalid holder profile - id=%s: err=%s",
-			profileID, err.Error()))
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid holder profile - id=%s: err=%s", profileID, err.Error()))
 
 		return
 	}
@@ -197,9 +259,9 @@ func (o *Operation) signPresentationHandler(rw http.ResponseWriter, req *http.Re
 	// get the request
 	presReq := SignPresentationRequest{}
 
-	err = json.NewDecoder(req.Body).Decode(&presReq)
+	err = commhttp.DecodeJSON(req, &presReq)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidRequestErrMsg+": %s", err.Error()))
+		commhttp.WriteError(rw, err)
 
 		return
 	}
@@ -207,7 +269,7 @@ func (o *Operation) signPresentationHandler(rw http.ResponseWriter, req *http.Re
 	presentation, err := verifiable.ParsePresentation(presReq.Presentation,
 		verifiable.WithDisabledPresentationProofCheck())
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidCredential, err.Error())
 
 		return
 	}
@@ -218,8 +280,16 @@ func (o *Operation) signPresentationHandler(rw http.ResponseWriter, req *http.Re
 	// sign presentation
 	signedVP, err := o.crypto.SignPresentation(profile, presentation, getPresentationSigningOpts(presReq.Opts)...)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, fmt.Sprintf("failed to sign presentation:"+
-			" %s", err.Error()))
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.SigningError,
+			fmt.Sprintf("failed to sign presentation: %s", err.Error()))
+
+		return
+	}
+
+	// the presentation isn't returned unless it is recorded
+	if err = o.recordPresentation(profile.Name, signedVP, presReq.Opts); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to record presentation: %s", err.Error()))
 
 		return
 	}
@@ -277,6 +347,8 @@ func (o *Operation) createHolderProfile(pr *HolderProfileRequest) (*vcprofile.Ho
 		SignatureRepresentation: pr.SignatureRepresentation,
 		Creator:                 publicKeyID,
 		OverwriteHolder:         pr.OverwriteHolder,
+		ExpiryNoticeDays:        pr.ExpiryNoticeDays,
+		ExpiryWebhookURL:        pr.ExpiryWebhookURL,
 	}, nil
 }
 
@@ -285,5 +357,5 @@ func validateHolderProfileRequest(pr *HolderProfileRequest) error {
 		return fmt.Errorf("missing profile name")
 	}
 
-	return nil
+	return validateExpiryNotice(pr)
 }
@@ -0,0 +1,124 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/consent"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/sdjwt"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+// PresentSDJWT swagger:route POST /{profileID}/holder/credentials/presentSDJWT holder presentSDJWTReq
+//
+// Presents a SD-JWT credential disclosing the claims of its subject with the names, the other claims staying
+// undisclosed. The presentation of a SD-JWT bound to the holder key ends with a key binding JWT for the audience and
+// the nonce, signed by the key of the profile. The presentation is recorded in the consent log of the profile.
+//
+// Responses:
+//    default: genericError
+//        201: presentSDJWTRes
+func (o *Operation) presentSDJWTHandler(rw http.ResponseWriter, req *http.Request) {
+	profileID := mux.Vars(req)[profileIDPathParam]
+
+	profile, err := o.profileStore.GetHolderProfile(profileID)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid holder profile - id=%s: err=%s", profileID, err.Error()))
+
+		return
+	}
+
+	request := &PresentSDJWTRequest{}
+
+	if err = commhttp.DecodeJSON(req, request); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if err = validatePresentSDJWTRequest(request); err != nil {
+		commhttp.WriteError(rw, commhttp.NewValidationError(err))
+
+		return
+	}
+
+	presentation, err := o.crypto.PresentSDJWT(profile, request.Credential, request.Disclose, request.Audience,
+		request.Nonce, crypto.WithVerificationMethod(request.VerificationMethod))
+	if err != nil {
+		status, code := http.StatusInternalServerError, commhttp.SigningError
+		if errors.Is(err, crypto.ErrInvalidProofPurpose) {
+			status, code = http.StatusBadRequest, commhttp.InvalidRequest
+		}
+
+		commhttp.WriteErrorResponse(rw, status, code, fmt.Sprintf("failed to present SD-JWT: %s", err.Error()))
+
+		return
+	}
+
+	// the presentation isn't returned unless it is recorded
+	if err = o.recordSDJWTPresentation(profile.Name, presentation, request); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to record presentation: %s", err.Error()))
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, &PresentSDJWTResponse{Presentation: presentation})
+}
+
+func validatePresentSDJWTRequest(request *PresentSDJWTRequest) error {
+	validationErr := &commhttp.ValidationError{}
+
+	if request.Credential == "" {
+		validationErr.Add("/credential", "credential is required")
+
+		return validationErr
+	}
+
+	credential, err := sdjwt.Parse(request.Credential)
+	if err != nil {
+		validationErr.Add("/credential", fmt.Sprintf("invalid SD-JWT: %s", err.Error()))
+
+		return validationErr
+	}
+
+	disclosable := make(map[string]bool, len(credential.Disclosed))
+	for _, name := range credential.Disclosed {
+		disclosable[name] = true
+	}
+
+	for i, name := range request.Disclose {
+		if !disclosable[name] {
+			validationErr.Add(fmt.Sprintf("/disclose/%d", i), fmt.Sprintf("the SD-JWT has no disclosure of %s", name))
+		}
+	}
+
+	return validationErr.ErrorOrNil()
+}
+
+// recordSDJWTPresentation records the presentation of the SD-JWT in the consent log of the profile
+func (o *Operation) recordSDJWTPresentation(profile, presentation string, request *PresentSDJWTRequest) error {
+	credential, err := sdjwt.Parse(presentation)
+	if err != nil {
+		return err
+	}
+
+	entry, err := consent.NewSDJWTEntry(credential, request.Audience, request.Nonce)
+	if err != nil {
+		return err
+	}
+
+	return o.consentLog.Record(profile, entry)
+}
@@ -0,0 +1,180 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/consent"
+	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/sdjwt"
+)
+
+func TestPresentSDJWT(t *testing.T) {
+	const keyID = "key-1"
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	newOperation := func(t *testing.T, keyManager *mockkms.KeyManager) *Operation {
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(), KeyManager: keyManager,
+			Crypto: &cryptomock.Crypto{SignValue: []byte("signature")},
+			VDRI: &vdrimock.MockVDRIRegistry{
+				ResolveFunc: func(didID string, opts ...vdri.ResolveOpts) (*did.Doc, error) {
+					return createDIDDocWithKeyID(didID, keyID, pubKey), nil
+				},
+			}})
+		require.NoError(t, err)
+
+		require.NoError(t, op.profileStore.SaveHolderProfile(&vcprofile.HolderProfile{Name: "test",
+			DID: "did:test:holder", SignatureType: vccrypto.Ed25519Signature2018,
+			Creator: "did:test:holder#" + keyID}))
+
+		return op
+	}
+
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(`{` + validContext + `,
+		"id": "http://example.edu/credentials/1872",
+		"type": ["VerifiableCredential", "UniversityDegreeCredential"],
+		"issuer": "did:test:issuer",
+		"issuanceDate": "2010-01-01T19:23:24Z",
+		"credentialSubject": {"id": "did:test:holder", "name": "Jayden Doe",
+			"degree": {"type": "BachelorDegree", "name": "Bachelor of Science"}}}`))
+	require.NoError(t, err)
+
+	issue := func(t *testing.T, confirmation *sdjwt.Confirmation) string {
+		sdJWT, err := sdjwt.Issue(vc, &sdjwt.Issuance{VerificationMethod: "did:test:issuer#key-1",
+			Algorithm: sdjwt.AlgorithmEdDSA, Signer: signature.GetEd25519Signer(privKey, pubKey),
+			Confirmation: confirmation})
+		require.NoError(t, err)
+
+		return sdJWT
+	}
+
+	bound := issue(t, &sdjwt.Confirmation{KeyID: "did:test:holder#" + keyID})
+
+	urlVars := map[string]string{profileIDPathParam: "test"}
+
+	present := func(t *testing.T, op *Operation, req *PresentSDJWTRequest) (int, []byte) {
+		reqBytes, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, getHandler(t, op, presentSDJWTEndpoint), presentSDJWTEndpoint, reqBytes, urlVars)
+
+		return rr.Code, rr.Body.Bytes()
+	}
+
+	t.Run("test SD-JWT bound to the holder key", func(t *testing.T) {
+		op := newOperation(t, &mockkms.KeyManager{})
+
+		code, body := present(t, op, &PresentSDJWTRequest{Credential: bound, Disclose: []string{"degree"},
+			Audience: "https://verifier.example.com", Nonce: "nonce1"})
+		require.Equal(t, http.StatusCreated, code, string(body))
+		require.NotContains(t, string(body), "Jayden")
+
+		response := &PresentSDJWTResponse{}
+		require.NoError(t, json.Unmarshal(body, response))
+
+		credential, err := sdjwt.Parse(response.Presentation)
+		require.NoError(t, err)
+		require.True(t, credential.KeyBound)
+		require.Equal(t, []string{"degree"}, credential.Disclosed)
+
+		entries, err := op.consentLog.Query("test", &consent.Query{})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, "https://verifier.example.com", entries[0].Domain)
+		require.Equal(t, "nonce1", entries[0].Challenge)
+		require.Equal(t, []*consent.SharedCredential{{
+			ID:     "http://example.edu/credentials/1872",
+			Types:  []string{"VerifiableCredential", "UniversityDegreeCredential"},
+			Issuer: "did:test:issuer",
+			Claims: []string{"/credentialSubject/degree/name", "/credentialSubject/degree/type",
+				"/credentialSubject/id"},
+		}}, entries[0].Credentials)
+	})
+
+	t.Run("test SD-JWT not bound", func(t *testing.T) {
+		// the holder key doesn't sign the presentation
+		op := newOperation(t, &mockkms.KeyManager{GetKeyErr: errors.New("key not found")})
+
+		code, body := present(t, op, &PresentSDJWTRequest{Credential: issue(t, nil), Disclose: []string{"name"}})
+		require.Equal(t, http.StatusCreated, code, string(body))
+
+		response := &PresentSDJWTResponse{}
+		require.NoError(t, json.Unmarshal(body, response))
+		require.True(t, strings.HasSuffix(response.Presentation, "~"))
+
+		credential, err := sdjwt.Parse(response.Presentation)
+		require.NoError(t, err)
+		require.False(t, credential.KeyBound)
+		require.Equal(t, []string{"name"}, credential.Disclosed)
+	})
+
+	t.Run("test signing error", func(t *testing.T) {
+		op := newOperation(t, &mockkms.KeyManager{GetKeyErr: errors.New("key not found")})
+
+		code, body := present(t, op, &PresentSDJWTRequest{Credential: bound})
+		require.Equal(t, http.StatusInternalServerError, code)
+		require.Contains(t, string(body), "failed to present SD-JWT: key not found")
+	})
+
+	t.Run("test verification method not an authentication method", func(t *testing.T) {
+		op := newOperation(t, &mockkms.KeyManager{})
+
+		code, body := present(t, op, &PresentSDJWTRequest{Credential: bound,
+			VerificationMethod: "did:test:holder#key-2"})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "failed to present SD-JWT")
+	})
+
+	t.Run("test invalid request", func(t *testing.T) {
+		op := newOperation(t, &mockkms.KeyManager{})
+
+		code, body := present(t, op, &PresentSDJWTRequest{})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "credential is required")
+
+		code, body = present(t, op, &PresentSDJWTRequest{Credential: "abc"})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "invalid SD-JWT: not a SD-JWT")
+
+		code, body = present(t, op, &PresentSDJWTRequest{Credential: bound, Disclose: []string{"name", "id"}})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "/disclose/1")
+		require.Contains(t, string(body), "the SD-JWT has no disclosure of id")
+
+		rr := serveHTTPMux(t, getHandler(t, op, presentSDJWTEndpoint), presentSDJWTEndpoint, []byte("{"), urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("test invalid profile", func(t *testing.T) {
+		op := newOperation(t, &mockkms.KeyManager{})
+
+		rr := serveHTTPMux(t, getHandler(t, op, presentSDJWTEndpoint), presentSDJWTEndpoint, nil,
+			map[string]string{profileIDPathParam: "invalid"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid holder profile")
+	})
+}
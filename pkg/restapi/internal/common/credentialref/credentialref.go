@@ -0,0 +1,99 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package credentialref
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	"github.com/trustbloc/edge-service/pkg/httpclient"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+// MaxSize is the size limit of the credentials fetched by URL
+const MaxSize = 1 << 20
+
+var logger = log.New("edge-service-credentialref")
+
+// AllowedURL returns whether the URL matches one of the allowed URLs: same scheme and host, and a path under the
+// path of the allowed URL
+func AllowedURL(allowed []*url.URL, credentialURL string) bool {
+	u, err := url.Parse(credentialURL)
+	if err != nil || !u.IsAbs() || u.User != nil || strings.Contains("/"+u.Path+"/", "/../") {
+		return false
+	}
+
+	for _, a := range allowed {
+		prefix := strings.TrimSuffix(a.Path, "/")
+
+		if strings.EqualFold(u.Scheme, a.Scheme) && strings.EqualFold(u.Host, a.Host) &&
+			(u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewClient returns the client fetching the credentials by URL, which doesn't follow the redirects: the redirected
+// URLs could be outside of the allowed URLs
+func NewClient(httpClients *httpclient.Factory) *http.Client {
+	client := &http.Client{}
+	if httpClients != nil {
+		client = httpClients.Client()
+	}
+
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return client
+}
+
+// Fetch fetches the credential at the URL, which must be allowed
+func Fetch(client *http.Client, allowed []*url.URL, credentialURL string) ([]byte, error) {
+	if !AllowedURL(allowed, credentialURL) {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("referenced credential URL %s is not allowed", credentialURL))
+	}
+
+	resp, err := client.Get(credentialURL)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to fetch the referenced credential: %s", err.Error()))
+	}
+
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			logger.Warnf("failed to close response body")
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to fetch the referenced credential: status %d", resp.StatusCode))
+	}
+
+	vcBytes, err := ioutil.ReadAll(io.LimitReader(resp.Body, MaxSize+1))
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to read the referenced credential: %s", err.Error()))
+	}
+
+	if len(vcBytes) > MaxSize {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("referenced credential exceeds %d bytes", MaxSize))
+	}
+
+	return vcBytes, nil
+}
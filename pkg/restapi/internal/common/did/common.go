@@ -7,33 +7,45 @@ SPDX-License-Identifier: Apache-2.0
 package did
 
 import (
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcutil/base58"
 	ariesdid "github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
 	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/vdri/key"
 	didclient "github.com/trustbloc/trustbloc-did-method/pkg/did"
 	didmethodoperation "github.com/trustbloc/trustbloc-did-method/pkg/restapi/didmethod/operation"
 
 	"github.com/trustbloc/edge-service/pkg/client/uniregistrar"
 	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/httpclient"
+	"github.com/trustbloc/edge-service/pkg/kms/signingkms"
 	"github.com/trustbloc/edge-service/pkg/restapi/model"
+	"github.com/trustbloc/edge-service/pkg/vdri/web"
 )
 
 const (
 	recoveryKey = "recovery-key"
-)
 
-// nolint: gochecknoglobals
-var signatureKeyTypeMap = map[string]string{
-	crypto.Ed25519Signature2018: crypto.Ed25519VerificationKey2018,
-	crypto.JSONWebSignature2020: crypto.JwsVerificationKey2020,
-}
+	// MethodTrustBloc is the did:trustbloc method, the default method of the created DIDs
+	MethodTrustBloc = "trustbloc"
+	// MethodKey is the did:key method
+	MethodKey = "key"
+	// MethodWeb is the did:web method, the documents are served by the service
+	MethodWeb = "web"
+)
 
 // CommonDID common did operation
 type CommonDID struct {
@@ -42,6 +54,7 @@ type CommonDID struct {
 	keyManager         keyManager
 	vdri               vdriapi.Registry
 	domain             string
+	hostURL            string
 }
 
 // Config defines configuration for vcs operations
@@ -50,10 +63,18 @@ type Config struct {
 	VDRI       vdriapi.Registry
 	Domain     string
 	TLSConfig  *tls.Config
+	// HostURL is the external URL of the service, the base of the did:web DIDs
+	HostURL string
+	// HTTPClients creates the client of the uni-registrar requests (optional, a client with TLSConfig by default)
+	HTTPClients *httpclient.Factory
+	// UniRegistrarHTTPClients creates the client of the uni-registrar requests when the uni-registrar is in its own
+	// trust domain, e.g. with its CA certificates or a client certificate (optional, HTTPClients by default)
+	UniRegistrarHTTPClients *httpclient.Factory
 }
 
 type uniRegistrarClient interface {
 	CreateDID(driverURL string, opts ...uniregistrar.CreateDIDOption) (string, []didmethodoperation.Key, error)
+	UpdateDID(driverURL, did string, opts ...uniregistrar.CreateDIDOption) ([]didmethodoperation.Key, error)
 }
 
 type didBlocClient interface {
@@ -66,11 +87,22 @@ type keyManager interface {
 
 // New return new instance of common DID
 func New(config *Config) *CommonDID {
-	return &CommonDID{uniRegistrarClient: uniregistrar.New(uniregistrar.WithTLSConfig(config.TLSConfig)),
+	uniRegistrarOpts := []uniregistrar.Option{uniregistrar.WithTLSConfig(config.TLSConfig)}
+
+	switch {
+	case config.UniRegistrarHTTPClients != nil:
+		uniRegistrarOpts = append(uniRegistrarOpts,
+			uniregistrar.WithHTTPClient(config.UniRegistrarHTTPClients.Client()))
+	case config.HTTPClients != nil:
+		uniRegistrarOpts = append(uniRegistrarOpts, uniregistrar.WithHTTPClient(config.HTTPClients.Client()))
+	}
+
+	return &CommonDID{uniRegistrarClient: uniregistrar.New(uniRegistrarOpts...),
 		trustBlocDIDClient: didclient.New(didclient.WithTLSConfig(config.TLSConfig)),
 		keyManager:         config.KeyManager,
 		domain:             config.Domain,
 		vdri:               config.VDRI,
+		hostURL:            config.HostURL,
 	}
 }
 
@@ -118,6 +150,236 @@ func (o *CommonDID) CreateDID(keyType, signatureType, did, privateKey, keyID, pu
 	return didID, publicKeyID, nil
 }
 
+// CreateKeyDID creates a did:key and returns its public key ID. The did:key only supports Ed25519 keys and the
+// public key ID is the fingerprint of the key, so the key is generated here and imported in the kms with that ID.
+func (o *CommonDID) CreateKeyDID(keyType string) (string, string, error) {
+	if keyType != crypto.Ed25519KeyType {
+		return "", "", fmt.Errorf("did:key supports %s keys only", crypto.Ed25519KeyType)
+	}
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate did:key key: %v", err)
+	}
+
+	didDoc, err := key.New().Build(&vdriapi.PubKey{Type: crypto.Ed25519VerificationKey2018,
+		Value: base58.Encode(pubKey)})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create did:key: %v", err)
+	}
+
+	publicKeyID := didDoc.PublicKey[0].ID
+
+	if err := o.importKey(publicKeyID, kms.ED25519Type, privKey); err != nil {
+		return "", "", err
+	}
+
+	return didDoc.ID, publicKeyID, nil
+}
+
+// CreateWebDID creates a did:web with a single key, its document being served by the service under the given
+// path of its host URL, and returns its public key ID.
+func (o *CommonDID) CreateWebDID(keyType, signatureType string, path ...string) (string, string, error) {
+	didID, err := web.DID(o.hostURL, path...)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create did:web: %v", err)
+	}
+
+	publicKey, err := o.createPublicKey(keyType, signatureType)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create did public key: %v", err)
+	}
+
+	publicKeyID := didID + "#" + publicKey.ID
+
+	docPublicKey, err := newDocPublicKey(publicKeyID, didID, publicKey)
+	if err != nil {
+		return "", "", err
+	}
+
+	created := time.Now().UTC()
+
+	didDoc := &ariesdid.Doc{Context: []string{ariesdid.Context}, ID: didID, Created: &created}
+	addDocPublicKey(didDoc, docPublicKey)
+
+	err = o.vdri.Store(didDoc)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to store did:web document: %v", err)
+	}
+
+	return didID, publicKeyID, nil
+}
+
+// addKeyWeb adds a new key to the document of a did:web served by the service
+func (o *CommonDID) addKeyWeb(did, keyType, signatureType string) (string, error) {
+	didDoc, err := o.vdri.Resolve(did)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve did: %v", err)
+	}
+
+	publicKey, err := o.createPublicKey(keyType, signatureType)
+	if err != nil {
+		return "", fmt.Errorf("failed to create did public key: %v", err)
+	}
+
+	publicKeyID := did + "#" + publicKey.ID
+
+	docPublicKey, err := newDocPublicKey(publicKeyID, did, publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	addDocPublicKey(didDoc, docPublicKey)
+
+	err = o.vdri.Store(didDoc)
+	if err != nil {
+		return "", fmt.Errorf("failed to store did:web document: %v", err)
+	}
+
+	return publicKeyID, nil
+}
+
+// hostsWebDID returns if the did is a did:web served by the service
+func (o *CommonDID) hostsWebDID(did string) bool {
+	hostDID, err := web.DID(o.hostURL)
+	if err != nil {
+		return false
+	}
+
+	return did == hostDID || strings.HasPrefix(did, hostDID+":")
+}
+
+// addDocPublicKey adds the public key to the did document, for all the verification relationships
+func addDocPublicKey(didDoc *ariesdid.Doc, docPublicKey *ariesdid.PublicKey) {
+	updated := time.Now().UTC()
+
+	didDoc.PublicKey = append(didDoc.PublicKey, *docPublicKey)
+	didDoc.Authentication = append(didDoc.Authentication,
+		*ariesdid.NewReferencedVerificationMethod(docPublicKey, ariesdid.Authentication, false))
+	didDoc.AssertionMethod = append(didDoc.AssertionMethod,
+		*ariesdid.NewReferencedVerificationMethod(docPublicKey, ariesdid.AssertionMethod, false))
+	didDoc.CapabilityDelegation = append(didDoc.CapabilityDelegation,
+		*ariesdid.NewReferencedVerificationMethod(docPublicKey, ariesdid.CapabilityDelegation, false))
+	didDoc.CapabilityInvocation = append(didDoc.CapabilityInvocation,
+		*ariesdid.NewReferencedVerificationMethod(docPublicKey, ariesdid.CapabilityInvocation, false))
+	didDoc.Updated = &updated
+}
+
+// newDocPublicKey returns the did document public key of the created key, with its JWK for JwsVerificationKey2020
+// (Ed25519, EC and RSA keys) and EcdsaSecp256k1VerificationKey2019
+func newDocPublicKey(id, controller string, publicKey *didclient.PublicKey) (*ariesdid.PublicKey, error) {
+	if publicKey.Type != didclient.JWSVerificationKey2020 && publicKey.Type != crypto.EcdsaSecp256k1VerificationKey2019 {
+		return ariesdid.NewPublicKeyFromBytes(id, publicKey.Type, controller, publicKey.Value), nil
+	}
+
+	pubKey, err := jwkPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	jwk, err := jose.JWKFromPublicKey(pubKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create did public key JWK: %v", err)
+	}
+
+	return ariesdid.NewPublicKeyFromJWK(id, publicKey.Type, controller, jwk)
+}
+
+// jwkPublicKey returns the public key of the JWK of the created key
+func jwkPublicKey(publicKey *didclient.PublicKey) (interface{}, error) {
+	var curve elliptic.Curve
+
+	var curveName string
+
+	switch publicKey.KeyType {
+	case crypto.RSAKeyType:
+		rsaKey, err := x509.ParsePKCS1PublicKey(publicKey.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA public key: %w", err)
+		}
+
+		return rsaKey, nil
+	case didclient.P256KeyType:
+		curve, curveName = elliptic.P256(), "P-256"
+	case crypto.P384KeyType:
+		curve, curveName = elliptic.P384(), "P-384"
+	case crypto.P521KeyType:
+		curve, curveName = elliptic.P521(), "P-521"
+	case crypto.Secp256k1KeyType:
+		curve, curveName = btcec.S256(), "secp256k1"
+	default:
+		return ed25519.PublicKey(publicKey.Value), nil
+	}
+
+	x, y := elliptic.Unmarshal(curve, publicKey.Value)
+	if x == nil {
+		return nil, fmt.Errorf("invalid %s public key", curveName)
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// AddKey adds a new key to the did document and returns its public key ID. The existing keys are kept in
+// the did document and kms, so credentials signed with them can still be verified. The documents of the did:web
+// served by the service are updated in place.
+func (o *CommonDID) AddKey(did, keyType, signatureType, privateKey, keyID string,
+	registrar model.UNIRegistrar) (string, error) {
+	switch {
+	case registrar.DriverURL != "":
+		return o.addKeyUniRegistrar(did, keyType, signatureType, registrar)
+
+	case privateKey == "" && o.hostsWebDID(did):
+		return o.addKeyWeb(did, keyType, signatureType)
+
+	case privateKey != "":
+		// the key was already added to the did document by the caller
+		didDoc, err := o.vdri.Resolve(did)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve did: %v", err)
+		}
+
+		if !strings.Contains(keyID, "#") || !hasPublicKey(didDoc, keyID) {
+			return "", fmt.Errorf("key %s not found in did document", keyID)
+		}
+
+		if err := o.importKey(keyID, kms.ED25519Type, base58.Decode(privateKey)); err != nil {
+			return "", err
+		}
+
+		return keyID, nil
+
+	default:
+		return "", fmt.Errorf("add key requires uni-registrar or private key of a key added to did %s", did)
+	}
+}
+
+func (o *CommonDID) addKeyUniRegistrar(did, keyType, signatureType string,
+	registrar model.UNIRegistrar) (string, error) {
+	publicKey, err := o.createPublicKey(keyType, signatureType)
+	if err != nil {
+		return "", fmt.Errorf("failed to create did public key: %v", err)
+	}
+
+	keys, err := o.uniRegistrarClient.UpdateDID(registrar.DriverURL, did,
+		uniregistrar.WithPublicKey(&didmethodoperation.PublicKey{
+			ID: publicKey.ID, Type: publicKey.Type,
+			Value:    base64.StdEncoding.EncodeToString(publicKey.Value),
+			KeyType:  publicKey.KeyType,
+			Encoding: publicKey.Encoding, Usage: publicKey.Usage}),
+		uniregistrar.WithOptions(registrar.Options))
+	if err != nil {
+		return "", fmt.Errorf("failed to update did doc from uni-registrar: %v", err)
+	}
+
+	for _, v := range keys {
+		if strings.HasSuffix(v.ID, "#"+publicKey.ID) {
+			return v.ID, nil
+		}
+	}
+
+	return did + "#" + publicKey.ID, nil
+}
+
 // nolint: gocyclo,funlen
 func (o *CommonDID) createDIDUniRegistrar(keyType, signatureType, purpose string,
 	registrar model.UNIRegistrar) (string, string, error) {
@@ -258,17 +520,17 @@ func (o *CommonDID) createPublicKeys(keyType, signatureType string) ([]*didclien
 		Usage: []string{didclient.KeyUsageGeneral, didclient.KeyUsageAssertion, didclient.KeyUsageAuth}})
 
 	if keyType == crypto.Ed25519KeyType &&
-		didclient.Ed25519VerificationKey2018 == signatureKeyTypeMap[signatureType] {
+		didclient.Ed25519VerificationKey2018 == signatureKeyType(signatureType) {
 		return publicKeys, key1ID, nil
 	}
 
 	if keyType == crypto.Ed25519KeyType &&
-		didclient.JWSVerificationKey2020 == signatureKeyTypeMap[signatureType] {
+		didclient.JWSVerificationKey2020 == signatureKeyType(signatureType) {
 		return publicKeys, key2ID, nil
 	}
 
 	if keyType == crypto.P256KeyType &&
-		didclient.JWSVerificationKey2020 == signatureKeyTypeMap[signatureType] {
+		didclient.JWSVerificationKey2020 == signatureKeyType(signatureType) {
 		return publicKeys, key3ID, nil
 	}
 
@@ -276,6 +538,82 @@ func (o *CommonDID) createPublicKeys(keyType, signatureType string) ([]*didclien
 		fmt.Errorf("no key found to match key type:%s and signature type:%s", keyType, signatureType)
 }
 
+// publicKeyType is a key type with the type of its verification methods
+type publicKeyType struct {
+	keyType    string
+	pubKeyType string
+}
+
+// publicKeyTypes are the kms key types of the EC and RSA keys, by key and verification method type. The P-384 keys
+// are created by signingkms to sign the SHA-384 digest of ES384, the RSA keys (PS256) aren't supported by localkms.
+// nolint: gochecknoglobals
+var publicKeyTypes = map[publicKeyType]kms.KeyType{
+	{crypto.P256KeyType, crypto.JwsVerificationKey2020}:                 kms.ECDSAP256IEEEP1363,
+	{crypto.P384KeyType, crypto.JwsVerificationKey2020}:                 signingkms.P384KeyType,
+	{crypto.P521KeyType, crypto.JwsVerificationKey2020}:                 kms.ECDSAP521TypeIEEEP1363,
+	{crypto.RSAKeyType, crypto.JwsVerificationKey2020}:                  signingkms.RSAKeyType,
+	{crypto.Secp256k1KeyType, crypto.EcdsaSecp256k1VerificationKey2019}: signingkms.Secp256k1KeyType,
+}
+
+// ValidateKeyType returns an error if no key of the key type can be created for the signature type.
+func ValidateKeyType(keyType, signatureType string) error {
+	switch {
+	case keyType == crypto.Ed25519KeyType && signatureKeyType(signatureType) != "":
+		return nil
+
+	case keyType != crypto.Ed25519KeyType:
+		if _, ok := publicKeyTypes[publicKeyType{keyType, signatureKeyType(signatureType)}]; ok {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no key found to match key type:%s and signature type:%s", keyType, signatureType)
+}
+
+// signatureKeyType returns the type of the verification methods of the signature suite of the signature type, empty
+// if the signature type isn't registered
+func signatureKeyType(signatureType string) string {
+	if s, ok := crypto.GetSignatureSuite(signatureType); ok {
+		return s.KeyType()
+	}
+
+	return ""
+}
+
+func (o *CommonDID) createPublicKey(keyType, signatureType string) (*didclient.PublicKey, error) {
+	usage := []string{didclient.KeyUsageGeneral, didclient.KeyUsageAssertion, didclient.KeyUsageAuth}
+
+	switch {
+	case keyType == crypto.Ed25519KeyType &&
+		didclient.Ed25519VerificationKey2018 == signatureKeyType(signatureType):
+		return o.newPublicKey(kms.ED25519Type, didclient.Ed25519VerificationKey2018, didclient.Ed25519KeyType, usage)
+
+	case keyType == crypto.Ed25519KeyType &&
+		didclient.JWSVerificationKey2020 == signatureKeyType(signatureType):
+		return o.newPublicKey(kms.ED25519Type, didclient.JWSVerificationKey2020, didclient.Ed25519KeyType, usage)
+
+	case keyType != crypto.Ed25519KeyType:
+		pubKeyType := signatureKeyType(signatureType)
+
+		if kmsKeyType, ok := publicKeyTypes[publicKeyType{keyType, pubKeyType}]; ok {
+			return o.newPublicKey(kmsKeyType, pubKeyType, keyType, usage)
+		}
+	}
+
+	return nil, fmt.Errorf("no key found to match key type:%s and signature type:%s", keyType, signatureType)
+}
+
+func (o *CommonDID) newPublicKey(kmsKeyType kms.KeyType, pubKeyType, keyType string,
+	usage []string) (*didclient.PublicKey, error) {
+	keyID, pubKeyBytes, err := o.createKey(kmsKeyType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &didclient.PublicKey{ID: keyID, Type: pubKeyType, Value: pubKeyBytes,
+		Encoding: didclient.PublicKeyEncodingJwk, KeyType: keyType, Usage: usage}, nil
+}
+
 func (o *CommonDID) createKey(keyType kms.KeyType) (string, []byte, error) {
 	keyID, _, err := o.keyManager.Create(keyType)
 	if err != nil {
@@ -290,6 +628,16 @@ func (o *CommonDID) createKey(keyType kms.KeyType) (string, []byte, error) {
 	return keyID, pubKeyBytes, nil
 }
 
+func hasPublicKey(didDoc *ariesdid.Doc, keyID string) bool {
+	for _, pk := range didDoc.PublicKey {
+		if pk.ID == keyID || didDoc.ID+pk.ID == keyID {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (o *CommonDID) importKey(keyID string, keyType kms.KeyType, privateKeyBytes []byte) error {
 	split := strings.Split(keyID, "#")
 
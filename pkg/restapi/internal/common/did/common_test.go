@@ -7,11 +7,23 @@ SPDX-License-Identifier: Apache-2.0
 package did
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcutil/base58"
 	ariesdid "github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
 	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
 	"github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
 	"github.com/stretchr/testify/require"
@@ -20,6 +32,7 @@ import (
 
 	"github.com/trustbloc/edge-service/pkg/client/uniregistrar"
 	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/httpclient"
 	"github.com/trustbloc/edge-service/pkg/restapi/model"
 )
 
@@ -118,6 +131,38 @@ func TestCommonDID_CreateDIDUniRegistrar(t *testing.T) {
 		require.Equal(t, "did:trustbloc:123", did)
 	})
 
+	t.Run("test uni-registrar in its own trust domain", func(t *testing.T) {
+		var requests int
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			requests++
+
+			rw.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		rootCAs := x509.NewCertPool()
+		rootCAs.AddCert(srv.Certificate())
+
+		httpClients := httpclient.New()
+
+		c := New(&Config{KeyManager: &mockkms.KeyManager{CreateKeyID: "key-1"}, HTTPClients: httpClients})
+
+		_, _, err := c.CreateDID(crypto.P256KeyType, crypto.JSONWebSignature2020, "", "",
+			"", crypto.Authentication, model.UNIRegistrar{DriverURL: srv.URL})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "certificate")
+		require.Zero(t, requests)
+
+		c = New(&Config{KeyManager: &mockkms.KeyManager{CreateKeyID: "key-1"}, HTTPClients: httpClients,
+			UniRegistrarHTTPClients: httpClients.WithTLS(&tls.Config{RootCAs: rootCAs})})
+
+		_, _, err = c.CreateDID(crypto.P256KeyType, crypto.JSONWebSignature2020, "", "",
+			"", crypto.Authentication, model.UNIRegistrar{DriverURL: srv.URL})
+		require.Error(t, err)
+		require.Equal(t, 1, requests)
+	})
+
 	t.Run("test error - trustbloc method key not found", func(t *testing.T) {
 		c := New(&Config{KeyManager: &mockkms.KeyManager{CreateKeyID: "key-3"}})
 
@@ -206,6 +251,345 @@ func TestCommonDID_CreateDIDUniRegistrar(t *testing.T) {
 	})
 }
 
+func TestCommonDID_AddKey(t *testing.T) {
+	t.Run("test success - uni registrar", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{CreateKeyID: "key-2"}})
+
+		c.uniRegistrarClient = &mockUNIRegistrarClient{
+			UpdateDIDKeys: []didmethodoperation.Key{{ID: "did:test:123#key-2"}}}
+
+		keyID, err := c.AddKey("did:test:123", crypto.P256KeyType, crypto.JSONWebSignature2020, "", "",
+			model.UNIRegistrar{DriverURL: "url"})
+
+		require.NoError(t, err)
+		require.Equal(t, "did:test:123#key-2", keyID)
+	})
+
+	t.Run("test success - uni registrar without returned keys", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{CreateKeyID: "key-2"}})
+
+		c.uniRegistrarClient = &mockUNIRegistrarClient{}
+
+		keyID, err := c.AddKey("did:test:123", crypto.Ed25519KeyType, crypto.Ed25519Signature2018, "", "",
+			model.UNIRegistrar{DriverURL: "url"})
+
+		require.NoError(t, err)
+		require.Equal(t, "did:test:123#key-2", keyID)
+	})
+
+	t.Run("test error - key type not matching signature type", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{CreateKeyID: "key-2"}})
+
+		keyID, err := c.AddKey("did:test:123", crypto.P256KeyType, crypto.Ed25519Signature2018, "", "",
+			model.UNIRegistrar{DriverURL: "url"})
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no key found to match key type")
+		require.Empty(t, keyID)
+	})
+
+	t.Run("test error - update did through uni registrar failed", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{CreateKeyID: "key-2"}})
+
+		c.uniRegistrarClient = &mockUNIRegistrarClient{UpdateDIDErr: fmt.Errorf("failed update DID")}
+
+		keyID, err := c.AddKey("did:test:123", crypto.Ed25519KeyType, crypto.JSONWebSignature2020, "", "",
+			model.UNIRegistrar{DriverURL: "url"})
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed update DID")
+		require.Empty(t, keyID)
+	})
+
+	t.Run("test success - import private key", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{},
+			VDRI: &vdri.MockVDRIRegistry{ResolveValue: &ariesdid.Doc{ID: "did:test:123",
+				PublicKey: []ariesdid.PublicKey{{ID: "#key2"}}}}})
+
+		keyID, err := c.AddKey("did:test:123", "", "", base58.Encode([]byte("key")),
+			"did:test:123#key2", model.UNIRegistrar{})
+
+		require.NoError(t, err)
+		require.Equal(t, "did:test:123#key2", keyID)
+	})
+
+	t.Run("test error - key not in did document", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{},
+			VDRI: &vdri.MockVDRIRegistry{ResolveValue: &ariesdid.Doc{ID: "did:test:123",
+				PublicKey: []ariesdid.PublicKey{{ID: "did:test:123#key1"}}}}})
+
+		keyID, err := c.AddKey("did:test:123", "", "", base58.Encode([]byte("key")),
+			"did:test:123#key2", model.UNIRegistrar{})
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "key did:test:123#key2 not found in did document")
+		require.Empty(t, keyID)
+	})
+
+	t.Run("test error - resolve DID", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{},
+			VDRI: &vdri.MockVDRIRegistry{ResolveErr: fmt.Errorf("failed to resolve did")}})
+
+		keyID, err := c.AddKey("did:test:123", "", "", base58.Encode([]byte("key")),
+			"did:test:123#key2", model.UNIRegistrar{})
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to resolve did")
+		require.Empty(t, keyID)
+	})
+
+	t.Run("test error - missing uni registrar and private key", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{}})
+
+		keyID, err := c.AddKey("did:test:123", crypto.Ed25519KeyType, crypto.Ed25519Signature2018, "",
+			"", model.UNIRegistrar{})
+
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "add key requires uni-registrar or private key")
+		require.Empty(t, keyID)
+	})
+}
+
+func TestCommonDID_CreateKeyDID(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{}})
+
+		did, keyID, err := c.CreateKeyDID(crypto.Ed25519KeyType)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(did, "did:key:z"))
+		require.Equal(t, did+"#"+strings.TrimPrefix(did, "did:key:"), keyID)
+	})
+
+	t.Run("test error - key type not supported", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{}})
+
+		_, _, err := c.CreateKeyDID(crypto.P256KeyType)
+		require.EqualError(t, err, "did:key supports Ed25519 keys only")
+	})
+
+	t.Run("test error - import private key", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{ImportPrivateKeyErr: fmt.Errorf("import error")}})
+
+		_, _, err := c.CreateKeyDID(crypto.Ed25519KeyType)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "import error")
+	})
+}
+
+func TestCommonDID_CreateWebDID(t *testing.T) {
+	t.Run("test success - Ed25519Signature2018", func(t *testing.T) {
+		registry := &vdri.MockVDRIRegistry{}
+		c := New(&Config{KeyManager: &mockkms.KeyManager{CreateKeyID: "key-1",
+			ExportPubKeyBytesValue: make([]byte, ed25519.PublicKeySize)},
+			VDRI: registry, HostURL: "https://vcs.example.com"})
+
+		did, keyID, err := c.CreateWebDID(crypto.Ed25519KeyType, crypto.Ed25519Signature2018, "issuer", "profile1")
+		require.NoError(t, err)
+		require.Equal(t, "did:web:vcs.example.com:issuer:profile1", did)
+		require.Equal(t, did+"#key-1", keyID)
+
+		doc := registry.MemStore[did]
+		require.NotNil(t, doc)
+		require.Equal(t, keyID, doc.PublicKey[0].ID)
+		require.Equal(t, crypto.Ed25519VerificationKey2018, doc.PublicKey[0].Type)
+		require.Equal(t, keyID, doc.AssertionMethod[0].PublicKey.ID)
+	})
+
+	t.Run("test success - JsonWebSignature2020", func(t *testing.T) {
+		privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		registry := &vdri.MockVDRIRegistry{}
+		c := New(&Config{KeyManager: &mockkms.KeyManager{CreateKeyID: "key-1",
+			ExportPubKeyBytesValue: elliptic.Marshal(elliptic.P256(), privKey.X, privKey.Y)},
+			VDRI: registry, HostURL: "https://vcs.example.com"})
+
+		did, _, err := c.CreateWebDID(crypto.P256KeyType, crypto.JSONWebSignature2020, "issuer", "profile1")
+		require.NoError(t, err)
+		require.NotNil(t, registry.MemStore[did].PublicKey[0].JSONWebKey())
+	})
+
+	t.Run("test success - JsonWebSignature2020 P-384 and P-521", func(t *testing.T) {
+		for keyType, curve := range map[string]elliptic.Curve{
+			crypto.P384KeyType: elliptic.P384(),
+			crypto.P521KeyType: elliptic.P521(),
+		} {
+			privKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+			require.NoError(t, err)
+
+			registry := &vdri.MockVDRIRegistry{}
+			c := New(&Config{KeyManager: &mockkms.KeyManager{CreateKeyID: "key-1",
+				ExportPubKeyBytesValue: elliptic.Marshal(curve, privKey.X, privKey.Y)},
+				VDRI: registry, HostURL: "https://vcs.example.com"})
+
+			did, _, err := c.CreateWebDID(keyType, crypto.JSONWebSignature2020, "issuer")
+			require.NoError(t, err)
+
+			publicKey := registry.MemStore[did].PublicKey[0]
+			require.Equal(t, crypto.JwsVerificationKey2020, publicKey.Type)
+			require.NotNil(t, publicKey.JSONWebKey())
+			require.Equal(t, curve.Params().Name, publicKey.JSONWebKey().Crv)
+		}
+
+		_, _, err := New(&Config{KeyManager: &mockkms.KeyManager{ExportPubKeyBytesValue: []byte("key")},
+			VDRI: &vdri.MockVDRIRegistry{}, HostURL: "https://vcs.example.com"}).CreateWebDID(
+			crypto.P521KeyType, crypto.JSONWebSignature2020, "issuer")
+		require.EqualError(t, err, "invalid P-521 public key")
+	})
+
+	t.Run("test success - JsonWebSignature2020 RSA", func(t *testing.T) {
+		privKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		registry := &vdri.MockVDRIRegistry{}
+		c := New(&Config{KeyManager: &mockkms.KeyManager{CreateKeyID: "key-1",
+			ExportPubKeyBytesValue: x509.MarshalPKCS1PublicKey(&privKey.PublicKey)},
+			VDRI: registry, HostURL: "https://vcs.example.com"})
+
+		did, _, err := c.CreateWebDID(crypto.RSAKeyType, crypto.JSONWebSignature2020, "issuer")
+		require.NoError(t, err)
+
+		publicKey := registry.MemStore[did].PublicKey[0]
+		require.Equal(t, crypto.JwsVerificationKey2020, publicKey.Type)
+		require.NotNil(t, publicKey.JSONWebKey())
+		require.Equal(t, "RSA", publicKey.JSONWebKey().Kty)
+		require.Equal(t, x509.MarshalPKCS1PublicKey(&privKey.PublicKey), publicKey.Value)
+
+		_, _, err = New(&Config{KeyManager: &mockkms.KeyManager{ExportPubKeyBytesValue: []byte("key")},
+			VDRI: &vdri.MockVDRIRegistry{}, HostURL: "https://vcs.example.com"}).CreateWebDID(
+			crypto.RSAKeyType, crypto.JSONWebSignature2020, "issuer")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid RSA public key")
+	})
+
+	t.Run("test success - EcdsaSecp256k1Signature2019", func(t *testing.T) {
+		privKey, err := ecdsa.GenerateKey(btcec.S256(), rand.Reader)
+		require.NoError(t, err)
+
+		registry := &vdri.MockVDRIRegistry{}
+		c := New(&Config{KeyManager: &mockkms.KeyManager{CreateKeyID: "key-1",
+			ExportPubKeyBytesValue: elliptic.Marshal(btcec.S256(), privKey.X, privKey.Y)},
+			VDRI: registry, HostURL: "https://vcs.example.com"})
+
+		did, _, err := c.CreateWebDID(crypto.Secp256k1KeyType, crypto.EcdsaSecp256k1Signature2019, "issuer")
+		require.NoError(t, err)
+
+		publicKey := registry.MemStore[did].PublicKey[0]
+		require.Equal(t, crypto.EcdsaSecp256k1VerificationKey2019, publicKey.Type)
+		require.NotNil(t, publicKey.JSONWebKey())
+		require.Equal(t, "secp256k1", publicKey.JSONWebKey().Crv)
+
+		_, _, err = New(&Config{KeyManager: &mockkms.KeyManager{ExportPubKeyBytesValue: []byte("key")},
+			VDRI: &vdri.MockVDRIRegistry{}, HostURL: "https://vcs.example.com"}).CreateWebDID(
+			crypto.Secp256k1KeyType, crypto.EcdsaSecp256k1Signature2019, "issuer")
+		require.EqualError(t, err, "invalid secp256k1 public key")
+	})
+
+	t.Run("test error - invalid host url", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{}, VDRI: &vdri.MockVDRIRegistry{}})
+
+		_, _, err := c.CreateWebDID(crypto.Ed25519KeyType, crypto.Ed25519Signature2018, "issuer")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create did:web")
+	})
+
+	t.Run("test error - key type not matching signature type", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{}, VDRI: &vdri.MockVDRIRegistry{},
+			HostURL: "https://vcs.example.com"})
+
+		_, _, err := c.CreateWebDID(crypto.P256KeyType, crypto.Ed25519Signature2018, "issuer")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create did public key")
+	})
+
+	t.Run("test error - invalid P-256 key", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{ExportPubKeyBytesValue: []byte("key")},
+			VDRI: &vdri.MockVDRIRegistry{}, HostURL: "https://vcs.example.com"})
+
+		_, _, err := c.CreateWebDID(crypto.P256KeyType, crypto.JSONWebSignature2020, "issuer")
+		require.EqualError(t, err, "invalid P-256 public key")
+	})
+}
+
+func TestCommonDID_AddKeyWeb(t *testing.T) {
+	const hostURL = "https://vcs.example.com"
+
+	t.Run("test success", func(t *testing.T) {
+		registry := &vdri.MockVDRIRegistry{}
+		registry.ResolveFunc = func(didID string, _ ...vdriapi.ResolveOpts) (*ariesdid.Doc, error) {
+			return registry.MemStore[didID], nil
+		}
+
+		keyManager := &mockkms.KeyManager{CreateKeyID: "key-1",
+			ExportPubKeyBytesValue: make([]byte, ed25519.PublicKeySize)}
+
+		c := New(&Config{KeyManager: keyManager, VDRI: registry, HostURL: hostURL})
+
+		did, _, err := c.CreateWebDID(crypto.Ed25519KeyType, crypto.Ed25519Signature2018, "issuer", "profile1")
+		require.NoError(t, err)
+
+		keyManager.CreateKeyID = "key-2"
+
+		keyID, err := c.AddKey(did, crypto.Ed25519KeyType, crypto.Ed25519Signature2018, "", "", model.UNIRegistrar{})
+		require.NoError(t, err)
+		require.Equal(t, did+"#key-2", keyID)
+
+		doc := registry.MemStore[did]
+		require.Len(t, doc.PublicKey, 2)
+		require.Equal(t, keyID, doc.PublicKey[1].ID)
+		require.Equal(t, keyID, doc.AssertionMethod[1].PublicKey.ID)
+	})
+
+	t.Run("test error - did:web not served by the service", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{}, VDRI: &vdri.MockVDRIRegistry{}, HostURL: hostURL})
+
+		_, err := c.AddKey("did:web:other.example.com", crypto.Ed25519KeyType, crypto.Ed25519Signature2018,
+			"", "", model.UNIRegistrar{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "add key requires uni-registrar or private key")
+	})
+
+	t.Run("test error - failed to resolve did", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{},
+			VDRI: &vdri.MockVDRIRegistry{ResolveErr: fmt.Errorf("resolve error")}, HostURL: hostURL})
+
+		_, err := c.AddKey("did:web:vcs.example.com:issuer:profile1", crypto.Ed25519KeyType,
+			crypto.Ed25519Signature2018, "", "", model.UNIRegistrar{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "resolve error")
+	})
+
+	t.Run("test error - failed to store did document", func(t *testing.T) {
+		c := New(&Config{KeyManager: &mockkms.KeyManager{ExportPubKeyBytesValue: make([]byte, ed25519.PublicKeySize)},
+			VDRI: &vdri.MockVDRIRegistry{ResolveValue: &ariesdid.Doc{ID: "did:web:vcs.example.com"},
+				PutErr: fmt.Errorf("put error")}, HostURL: hostURL})
+
+		_, err := c.AddKey("did:web:vcs.example.com", crypto.Ed25519KeyType,
+			crypto.Ed25519Signature2018, "", "", model.UNIRegistrar{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "put error")
+	})
+}
+
+func TestValidateKeyType(t *testing.T) {
+	require.NoError(t, ValidateKeyType(crypto.Ed25519KeyType, crypto.Ed25519Signature2018))
+	require.NoError(t, ValidateKeyType(crypto.Ed25519KeyType, crypto.JSONWebSignature2020))
+	require.NoError(t, ValidateKeyType(crypto.P256KeyType, crypto.JSONWebSignature2020))
+	require.NoError(t, ValidateKeyType(crypto.P384KeyType, crypto.JSONWebSignature2020))
+	require.NoError(t, ValidateKeyType(crypto.P521KeyType, crypto.JSONWebSignature2020))
+	require.Error(t, ValidateKeyType(crypto.P384KeyType, crypto.Ed25519Signature2018))
+	require.NoError(t, ValidateKeyType(crypto.RSAKeyType, crypto.JSONWebSignature2020))
+	require.Error(t, ValidateKeyType(crypto.RSAKeyType, crypto.Ed25519Signature2018))
+	require.NoError(t, ValidateKeyType(crypto.Secp256k1KeyType, crypto.EcdsaSecp256k1Signature2019))
+	require.Error(t, ValidateKeyType(crypto.Secp256k1KeyType, crypto.JSONWebSignature2020))
+
+	err := ValidateKeyType(crypto.P256KeyType, crypto.Ed25519Signature2018)
+	require.EqualError(t, err, "no key found to match key type:P256 and signature type:Ed25519Signature2018")
+
+	require.Error(t, ValidateKeyType("", crypto.Ed25519Signature2018))
+	require.Error(t, ValidateKeyType(crypto.Ed25519KeyType, "unknown"))
+}
+
 func TestCommonDID_CreateKey(t *testing.T) {
 	t.Run("test error - export public key failed", func(t *testing.T) {
 		c := New(&Config{KeyManager: &mockkms.KeyManager{ExportPubKeyBytesErr: fmt.Errorf("failed export public key")}})
@@ -228,6 +612,9 @@ type mockUNIRegistrarClient struct {
 	CreateDIDValue string
 	CreateDIDKeys  []didmethodoperation.Key
 	CreateDIDErr   error
+	UpdateDIDKeys  []didmethodoperation.Key
+	UpdateDIDErr   error
+	UpdateDIDCalls int
 }
 
 func (m *mockUNIRegistrarClient) CreateDID(driverURL string,
@@ -235,6 +622,13 @@ func (m *mockUNIRegistrarClient) CreateDID(driverURL string,
 	return m.CreateDIDValue, m.CreateDIDKeys, m.CreateDIDErr
 }
 
+func (m *mockUNIRegistrarClient) UpdateDID(driverURL, did string,
+	opts ...uniregistrar.CreateDIDOption) ([]didmethodoperation.Key, error) {
+	m.UpdateDIDCalls++
+
+	return m.UpdateDIDKeys, m.UpdateDIDErr
+}
+
 type mockTrustBlocDIDClient struct {
 	CreateDIDValue *ariesdid.Doc
 	CreateDIDErr   error
@@ -0,0 +1,249 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	ariesdid "github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	didclient "github.com/trustbloc/trustbloc-did-method/pkg/did"
+	didmethodoperation "github.com/trustbloc/trustbloc-did-method/pkg/restapi/didmethod/operation"
+
+	"github.com/trustbloc/edge-service/pkg/client/uniregistrar"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/restapi/model"
+)
+
+// nolint: gochecknoglobals
+var (
+	// the sidetree key usages of the verification relationships
+	purposeKeyUsages = map[string]string{
+		crypto.Authentication:       didclient.KeyUsageAuth,
+		crypto.AssertionMethod:      didclient.KeyUsageAssertion,
+		crypto.CapabilityDelegation: didclient.KeyUsageDelegation,
+		crypto.CapabilityInvocation: didclient.KeyUsageInvocation,
+	}
+
+	defaultPurposes = []string{crypto.Authentication, crypto.AssertionMethod}
+)
+
+// UpdateDID applies the update to the did document, through the uni-registrar or directly for the did:web served
+// by the service, and returns the IDs of the added verification methods.
+func (o *CommonDID) UpdateDID(did string, update *model.DIDUpdate, registrar model.UNIRegistrar) ([]string, error) {
+	switch {
+	case registrar.DriverURL != "":
+		return o.updateDIDUniRegistrar(did, update, registrar)
+
+	case o.hostsWebDID(did):
+		return o.updateWebDID(did, update)
+
+	default:
+		return nil, fmt.Errorf("update of did %s requires uni-registrar", did)
+	}
+}
+
+func (o *CommonDID) updateDIDUniRegistrar(did string, update *model.DIDUpdate,
+	registrar model.UNIRegistrar) ([]string, error) {
+	var addOpts []uniregistrar.CreateDIDOption
+
+	for i := range update.AddServices {
+		service := update.AddServices[i]
+
+		addOpts = append(addOpts, uniregistrar.WithService(&didmethodoperation.Service{ID: service.ID,
+			Type: service.Type, Priority: service.Priority, RecipientKeys: service.RecipientKeys,
+			RoutingKeys: service.RoutingKeys, ServiceEndpoint: service.ServiceEndpoint}))
+	}
+
+	for i := range update.AddVerificationMethods {
+		vm := update.AddVerificationMethods[i]
+
+		usage := []string{didclient.KeyUsageGeneral}
+		for _, purpose := range verificationPurposes(&vm) {
+			usage = append(usage, purposeKeyUsages[purpose])
+		}
+
+		addOpts = append(addOpts, uniregistrar.WithPublicKey(&didmethodoperation.PublicKey{ID: vm.ID, Type: vm.Type,
+			Value:    base64.StdEncoding.EncodeToString(base58.Decode(vm.PublicKeyBase58)),
+			KeyType:  vm.KeyType,
+			Encoding: didclient.PublicKeyEncodingJwk, Usage: usage}))
+	}
+
+	var added []string
+
+	if len(addOpts) > 0 {
+		keys, err := o.uniRegistrarClient.UpdateDID(registrar.DriverURL, did,
+			append(addOpts, uniregistrar.WithOptions(registrar.Options))...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update did doc from uni-registrar: %v", err)
+		}
+
+		for i := range update.AddVerificationMethods {
+			added = append(added, keyIDFromRegistrar(did, update.AddVerificationMethods[i].ID, keys))
+		}
+	}
+
+	var removeOpts []uniregistrar.CreateDIDOption
+
+	for _, id := range update.RemoveServices {
+		removeOpts = append(removeOpts, uniregistrar.WithService(&didmethodoperation.Service{ID: id}))
+	}
+
+	for _, id := range update.RemoveVerificationMethods {
+		removeOpts = append(removeOpts, uniregistrar.WithPublicKey(&didmethodoperation.PublicKey{ID: id}))
+	}
+
+	if len(removeOpts) > 0 {
+		_, err := o.uniRegistrarClient.UpdateDID(registrar.DriverURL, did,
+			append(removeOpts, uniregistrar.WithDIDDocumentOperation(uniregistrar.RemoveFromDIDDocument),
+				uniregistrar.WithOptions(registrar.Options))...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update did doc from uni-registrar: %v", err)
+		}
+	}
+
+	return added, nil
+}
+
+// updateWebDID updates the document of a did:web served by the service
+func (o *CommonDID) updateWebDID(did string, update *model.DIDUpdate) ([]string, error) {
+	didDoc, err := o.vdri.Resolve(did)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve did: %v", err)
+	}
+
+	for _, id := range update.RemoveServices {
+		didDoc.Service = removeServices(didDoc.Service, did, id)
+	}
+
+	for _, id := range update.RemoveVerificationMethods {
+		removeDocPublicKey(didDoc, did, id)
+	}
+
+	for i := range update.AddServices {
+		service := update.AddServices[i]
+
+		didDoc.Service = append(didDoc.Service, ariesdid.Service{ID: did + "#" + service.ID, Type: service.Type,
+			Priority: service.Priority, RecipientKeys: service.RecipientKeys, RoutingKeys: service.RoutingKeys,
+			ServiceEndpoint: service.ServiceEndpoint})
+	}
+
+	added := make([]string, 0, len(update.AddVerificationMethods))
+
+	for i := range update.AddVerificationMethods {
+		vm := update.AddVerificationMethods[i]
+
+		docPublicKey, err := newDocPublicKey(did+"#"+vm.ID, did, &didclient.PublicKey{Type: vm.Type,
+			KeyType: vm.KeyType, Value: base58.Decode(vm.PublicKeyBase58)})
+		if err != nil {
+			return nil, err
+		}
+
+		didDoc.PublicKey = append(didDoc.PublicKey, *docPublicKey)
+
+		for _, purpose := range verificationPurposes(&vm) {
+			addVerificationMethod(didDoc, docPublicKey, purpose)
+		}
+
+		added = append(added, docPublicKey.ID)
+	}
+
+	updated := time.Now().UTC()
+	didDoc.Updated = &updated
+
+	err = o.vdri.Store(didDoc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store did:web document: %v", err)
+	}
+
+	return added, nil
+}
+
+func verificationPurposes(vm *model.DIDVerificationMethod) []string {
+	if len(vm.Purposes) == 0 {
+		return defaultPurposes
+	}
+
+	return vm.Purposes
+}
+
+func addVerificationMethod(didDoc *ariesdid.Doc, docPublicKey *ariesdid.PublicKey, purpose string) {
+	switch purpose {
+	case crypto.Authentication:
+		didDoc.Authentication = append(didDoc.Authentication,
+			*ariesdid.NewReferencedVerificationMethod(docPublicKey, ariesdid.Authentication, false))
+	case crypto.AssertionMethod:
+		didDoc.AssertionMethod = append(didDoc.AssertionMethod,
+			*ariesdid.NewReferencedVerificationMethod(docPublicKey, ariesdid.AssertionMethod, false))
+	case crypto.CapabilityDelegation:
+		didDoc.CapabilityDelegation = append(didDoc.CapabilityDelegation,
+			*ariesdid.NewReferencedVerificationMethod(docPublicKey, ariesdid.CapabilityDelegation, false))
+	case crypto.CapabilityInvocation:
+		didDoc.CapabilityInvocation = append(didDoc.CapabilityInvocation,
+			*ariesdid.NewReferencedVerificationMethod(docPublicKey, ariesdid.CapabilityInvocation, false))
+	}
+}
+
+// removeDocPublicKey removes the public key, identified by its ID or fragment, and its verification methods
+func removeDocPublicKey(didDoc *ariesdid.Doc, did, id string) {
+	publicKeys := didDoc.PublicKey[:0]
+
+	for _, pk := range didDoc.PublicKey {
+		if !matchesID(pk.ID, did, id) {
+			publicKeys = append(publicKeys, pk)
+		}
+	}
+
+	didDoc.PublicKey = publicKeys
+	didDoc.Authentication = removeVerificationMethods(didDoc.Authentication, did, id)
+	didDoc.AssertionMethod = removeVerificationMethods(didDoc.AssertionMethod, did, id)
+	didDoc.CapabilityDelegation = removeVerificationMethods(didDoc.CapabilityDelegation, did, id)
+	didDoc.CapabilityInvocation = removeVerificationMethods(didDoc.CapabilityInvocation, did, id)
+}
+
+func removeVerificationMethods(methods []ariesdid.VerificationMethod, did, id string) []ariesdid.VerificationMethod {
+	remaining := methods[:0]
+
+	for _, vm := range methods {
+		if !matchesID(vm.PublicKey.ID, did, id) {
+			remaining = append(remaining, vm)
+		}
+	}
+
+	return remaining
+}
+
+func removeServices(services []ariesdid.Service, did, id string) []ariesdid.Service {
+	remaining := services[:0]
+
+	for _, service := range services {
+		if !matchesID(service.ID, did, id) {
+			remaining = append(remaining, service)
+		}
+	}
+
+	return remaining
+}
+
+// matchesID returns if the document ID is the given ID or the did URL of the given fragment
+func matchesID(docID, did, id string) bool {
+	return docID == id || docID == did+"#"+strings.TrimPrefix(id, "#")
+}
+
+// keyIDFromRegistrar returns the ID of the added key, as returned by the uni-registrar if it does
+func keyIDFromRegistrar(did, id string, keys []didmethodoperation.Key) string {
+	for _, key := range keys {
+		if strings.HasSuffix(key.ID, "#"+id) {
+			return key.ID
+		}
+	}
+
+	return did + "#" + id
+}
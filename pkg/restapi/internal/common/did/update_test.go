@@ -0,0 +1,121 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package did
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcutil/base58"
+	ariesdid "github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	didmethodoperation "github.com/trustbloc/trustbloc-did-method/pkg/restapi/didmethod/operation"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/restapi/model"
+)
+
+func TestCommonDID_UpdateDID(t *testing.T) {
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	update := &model.DIDUpdate{
+		AddServices: []model.DIDService{{ID: "hub", Type: "DIDCommMessaging",
+			ServiceEndpoint: "https://hub.example.com"}},
+		AddVerificationMethods: []model.DIDVerificationMethod{{ID: "key-2",
+			Type: crypto.Ed25519VerificationKey2018, KeyType: crypto.Ed25519KeyType,
+			PublicKeyBase58: base58.Encode(pubKey), Purposes: []string{crypto.CapabilityInvocation}}},
+	}
+
+	t.Run("test success - uni-registrar", func(t *testing.T) {
+		registrarClient := &mockUNIRegistrarClient{
+			UpdateDIDKeys: []didmethodoperation.Key{{ID: "did:trustbloc:123#key-2"}}}
+
+		c := New(&Config{})
+		c.uniRegistrarClient = registrarClient
+
+		added, err := c.UpdateDID("did:trustbloc:123", &model.DIDUpdate{
+			AddVerificationMethods: update.AddVerificationMethods, RemoveServices: []string{"old"}},
+			model.UNIRegistrar{DriverURL: "https://registrar.example.com"})
+		require.NoError(t, err)
+		require.Equal(t, []string{"did:trustbloc:123#key-2"}, added)
+		require.Equal(t, 2, registrarClient.UpdateDIDCalls)
+	})
+
+	t.Run("test error - uni-registrar", func(t *testing.T) {
+		c := New(&Config{})
+		c.uniRegistrarClient = &mockUNIRegistrarClient{UpdateDIDErr: fmt.Errorf("update error")}
+
+		_, err := c.UpdateDID("did:trustbloc:123", &model.DIDUpdate{RemoveVerificationMethods: []string{"key-1"}},
+			model.UNIRegistrar{DriverURL: "https://registrar.example.com"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "update error")
+	})
+
+	t.Run("test success - did:web", func(t *testing.T) {
+		registry := &vdri.MockVDRIRegistry{}
+		registry.ResolveFunc = func(didID string, _ ...vdriapi.ResolveOpts) (*ariesdid.Doc, error) {
+			return registry.MemStore[didID], nil
+		}
+
+		c := New(&Config{KeyManager: &mockkms.KeyManager{CreateKeyID: "key-1",
+			ExportPubKeyBytesValue: make([]byte, ed25519.PublicKeySize)},
+			VDRI: registry, HostURL: "https://vcs.example.com"})
+
+		did, keyID, err := c.CreateWebDID(crypto.Ed25519KeyType, crypto.Ed25519Signature2018, "issuer", "profile1")
+		require.NoError(t, err)
+
+		added, err := c.UpdateDID(did, update, model.UNIRegistrar{})
+		require.NoError(t, err)
+		require.Equal(t, []string{did + "#key-2"}, added)
+
+		doc := registry.MemStore[did]
+		require.Len(t, doc.PublicKey, 2)
+		require.Len(t, doc.CapabilityInvocation, 2)
+		require.Len(t, doc.AssertionMethod, 1)
+		require.Equal(t, did+"#hub", doc.Service[0].ID)
+
+		_, err = c.UpdateDID(did, &model.DIDUpdate{RemoveServices: []string{"hub"},
+			RemoveVerificationMethods: []string{keyID}}, model.UNIRegistrar{})
+		require.NoError(t, err)
+
+		doc = registry.MemStore[did]
+		require.Len(t, doc.PublicKey, 1)
+		require.Equal(t, did+"#key-2", doc.PublicKey[0].ID)
+		require.Empty(t, doc.AssertionMethod)
+		require.Len(t, doc.CapabilityInvocation, 1)
+		require.Empty(t, doc.Service)
+	})
+
+	t.Run("test error - did:web invalid public key", func(t *testing.T) {
+		c := New(&Config{VDRI: &vdri.MockVDRIRegistry{ResolveValue: &ariesdid.Doc{ID: "did:web:vcs.example.com"}},
+			HostURL: "https://vcs.example.com"})
+
+		_, err := c.UpdateDID("did:web:vcs.example.com", &model.DIDUpdate{
+			AddVerificationMethods: []model.DIDVerificationMethod{{ID: "key-2", Type: crypto.JwsVerificationKey2020,
+				KeyType: crypto.P256KeyType, PublicKeyBase58: "abc"}}}, model.UNIRegistrar{})
+		require.EqualError(t, err, "invalid P-256 public key")
+	})
+
+	t.Run("test error - did:web not resolved", func(t *testing.T) {
+		c := New(&Config{VDRI: &vdri.MockVDRIRegistry{}, HostURL: "https://vcs.example.com"})
+
+		_, err := c.UpdateDID("did:web:vcs.example.com", update, model.UNIRegistrar{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to resolve did")
+	})
+
+	t.Run("test error - no uni-registrar", func(t *testing.T) {
+		_, err := New(&Config{}).UpdateDID("did:trustbloc:123", update, model.UNIRegistrar{})
+		require.EqualError(t, err, "update of did did:trustbloc:123 requires uni-registrar")
+	})
+}
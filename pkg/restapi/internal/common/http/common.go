@@ -7,28 +7,325 @@ SPDX-License-Identifier: Apache-2.0
 package http
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+	"github.com/trustbloc/edge-service/pkg/internal/common/support"
 )
 
 var logger = log.New("edge-service-restapi-common-http")
 
+const (
+	invalidRequestErrMsg = "Invalid request"
+
+	// MaxJSONDepth is the maximum nesting of the objects and arrays of a request body
+	MaxJSONDepth = 64
+
+	// bodyTooLargeErrMsg is the error of the body reader of http.MaxBytesReader once the limit is exceeded
+	bodyTooLargeErrMsg = "http: request body too large"
+)
+
+// ErrorCode is the stable, machine-readable code of an error response
+type ErrorCode string
+
+// error codes of the error responses
+const (
+	// InvalidRequest the request can't be decoded or fails validation
+	InvalidRequest ErrorCode = "INVALID_REQUEST"
+	// InvalidCredential the credential or presentation can't be parsed or verified
+	InvalidCredential ErrorCode = "INVALID_CREDENTIAL"
+	// ProfileNotFound the profile of the request doesn't exist
+	ProfileNotFound ErrorCode = "PROFILE_NOT_FOUND"
+	// NotFound the requested resource (credential, key, status list) doesn't exist
+	NotFound ErrorCode = "NOT_FOUND"
+	// AlreadyExists the resource to create already exists
+	AlreadyExists ErrorCode = "ALREADY_EXISTS"
+	// Conflict the stored resources are inconsistent (e.g. differing credentials stored under the same ID), or the
+	// request with the same idempotency key is in progress
+	Conflict ErrorCode = "CONFLICT"
+	// StorageError the store provider failed
+	StorageError ErrorCode = "STORAGE_ERROR"
+	// EDVError the encrypted data vault (or the encryption of its documents) failed
+	EDVError ErrorCode = "EDV_ERROR"
+	// KMSError the key management system failed
+	KMSError ErrorCode = "KMS_ERROR"
+	// SigningError the signing of the credential or presentation failed
+	SigningError ErrorCode = "SIGNING_ERROR"
+	// DIDError the creation or update of the DID failed
+	DIDError ErrorCode = "DID_ERROR"
+	// RateLimited the profile or API key exceeded its rate limit
+	RateLimited ErrorCode = "RATE_LIMITED"
+	// RequestTooLarge the request body exceeds the maximum request body size
+	RequestTooLarge ErrorCode = "REQUEST_TOO_LARGE"
+	// PolicyViolation the credential doesn't comply with the policy of the profile
+	PolicyViolation ErrorCode = "POLICY_VIOLATION"
+	// QuotaExceeded the profile issued all the credentials of its daily or monthly quota
+	QuotaExceeded ErrorCode = "QUOTA_EXCEEDED"
+	// DIDNotAnchored the DID of the profile isn't resolvable yet, the credentials can't be issued under the profile
+	DIDNotAnchored ErrorCode = "DID_NOT_ANCHORED"
+	// InternalError any other failure of the service
+	InternalError ErrorCode = "INTERNAL_ERROR"
+)
+
+// ErrorCodes returns the codes of the error responses
+func ErrorCodes() []ErrorCode {
+	return []ErrorCode{InvalidRequest, InvalidCredential, ProfileNotFound, NotFound, AlreadyExists, Conflict,
+		StorageError, EDVError, KMSError, SigningError, DIDError, RateLimited, RequestTooLarge, PolicyViolation,
+		QuotaExceeded, DIDNotAnchored, InternalError}
+}
+
 // ErrorResponse to send error message in the response
 type ErrorResponse struct {
-	Message string `json:"errMessage,omitempty"`
+	Code    ErrorCode    `json:"code,omitempty"`
+	Message string       `json:"errMessage,omitempty"`
+	Details string       `json:"details,omitempty"`
+	Fields  []FieldError `json:"fields,omitempty"`
+	// Violations are the rules of the profile policy violated by the credential
+	Violations    []policy.Violation `json:"violations,omitempty"`
+	CorrelationID string             `json:"correlationID,omitempty"`
+}
+
+// FieldError is the validation failure of a request field, identified by its JSON pointer (e.g. /options/domain)
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects the validation failures of all the invalid fields of a request
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Add adds the validation failure of the field
+func (e *ValidationError) Add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// ErrorOrNil returns the validation error if a field is invalid, nil otherwise
+func (e *ValidationError) ErrorOrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// Error returns the messages of the invalid fields
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+
+	for i, field := range e.Fields {
+		messages[i] = field.Message
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// Error is the failure of an operation, written as the error response of the REST API (see WriteError) or
+// converted into the status of the gRPC API.
+type Error struct {
+	Status     int
+	Code       ErrorCode
+	Message    string
+	Details    string
+	Fields     []FieldError
+	Violations []policy.Violation
+}
+
+// NewError returns the failure of an operation with the HTTP status and the code of its error response
+func NewError(status int, code ErrorCode, msg string) *Error {
+	return &Error{Status: status, Code: code, Message: msg}
+}
+
+// NewValidationError returns the bad request failure of a request failing the validation, listing its invalid
+// fields if the error is a ValidationError
+func NewValidationError(err error) *Error {
+	opErr := &Error{Status: http.StatusBadRequest, Code: InvalidRequest, Message: err.Error()}
+
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		opErr.Fields = validationErr.Fields
+	}
+
+	return opErr
+}
+
+// NewPolicyViolationError returns the forbidden failure of a credential violating the rules of the profile policy,
+// the violations being listed in the details of the error
+func NewPolicyViolationError(violations []policy.Violation) *Error {
+	return &Error{Status: http.StatusForbidden, Code: PolicyViolation,
+		Message: "credential violates the policy of the profile", Details: policy.Describe(violations),
+		Violations: violations}
+}
+
+// Error returns the message of the error, followed by its details if any
+func (e *Error) Error() string {
+	if e.Details == "" {
+		return e.Message
+	}
+
+	return e.Message + ": " + e.Details
+}
+
+// StatusCode returns the HTTP status of the error
+func (e *Error) StatusCode() int {
+	return e.Status
+}
+
+// ErrorCode returns the code of the error response
+func (e *Error) ErrorCode() string {
+	return string(e.Code)
+}
+
+// DecodeJSON decodes the JSON body of the request into v. It fails with status 413 if the body exceeds the maximum
+// request body size (the limit of http.MaxBytesReader set on the body), and with status 400 if the body isn't a
+// single JSON value or nests objects and arrays deeper than MaxJSONDepth.
+func DecodeJSON(req *http.Request, v interface{}) error {
+	body, err := readBody(req)
+	if err != nil {
+		return err
+	}
+
+	if len(body) == 0 {
+		return &Error{Status: http.StatusBadRequest, Code: InvalidRequest, Message: invalidRequestErrMsg,
+			Details: io.EOF.Error()}
+	}
+
+	if err := ValidateJSONDepth(body); err != nil {
+		return &Error{Status: http.StatusBadRequest, Code: InvalidRequest, Message: invalidRequestErrMsg,
+			Details: err.Error()}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+
+	if err := decoder.Decode(v); err != nil {
+		return &Error{Status: http.StatusBadRequest, Code: InvalidRequest, Message: invalidRequestErrMsg,
+			Details: err.Error()}
+	}
+
+	if decoder.More() {
+		return &Error{Status: http.StatusBadRequest, Code: InvalidRequest, Message: invalidRequestErrMsg,
+			Details: "unexpected data after the JSON value"}
+	}
+
+	return nil
+}
+
+// readBody reads the body of the request, failing with status 413 if it exceeds the maximum request body size
+func readBody(req *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		if err.Error() == bodyTooLargeErrMsg {
+			return nil, &Error{Status: http.StatusRequestEntityTooLarge, Code: RequestTooLarge,
+				Message: "Request body too large", Details: err.Error()}
+		}
+
+		return nil, &Error{Status: http.StatusBadRequest, Code: InvalidRequest, Message: invalidRequestErrMsg,
+			Details: err.Error()}
+	}
+
+	return body, nil
+}
+
+// IsEmptyBody returns true if the error of DecodeJSON is the failure to decode an empty body
+func IsEmptyBody(err error) bool {
+	var opErr *Error
+
+	return errors.As(err, &opErr) && opErr.Code == InvalidRequest && opErr.Details == io.EOF.Error()
+}
+
+// ValidateJSONDepth checks the JSON document doesn't nest objects and arrays deeper than MaxJSONDepth, for the
+// documents decoded without DecodeJSON (e.g. the lines of a NDJSON body)
+func ValidateJSONDepth(data []byte) error {
+	if jsonDepth(data) > MaxJSONDepth {
+		return fmt.Errorf("JSON nesting exceeds the maximum depth of %d", MaxJSONDepth)
+	}
+
+	return nil
+}
+
+// jsonDepth returns the maximum nesting of the objects and arrays of the JSON document, ignoring the brackets
+// within strings. The document is scanned before decoding, as the decoder recurses without limit.
+func jsonDepth(data []byte) int {
+	depth, maxDepth := 0, 0
+	inString, escaped := false, false
+
+	for _, b := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			escaped = b == '\\'
+			inString = b != '"'
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			depth++
+
+			if depth > maxDepth {
+				maxDepth = depth
+			}
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+
+	return maxDepth
+}
+
+// ProfileErrorCode returns the code of the failure to get a profile from the store
+func ProfileErrorCode(err error) ErrorCode {
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return ProfileNotFound
+	}
+
+	return StorageError
 }
 
 // WriteErrorResponse write error resp
-func WriteErrorResponse(rw http.ResponseWriter, status int, msg string) {
-	rw.WriteHeader(status)
+func WriteErrorResponse(rw http.ResponseWriter, status int, code ErrorCode, msg string) {
+	writeErrorResponse(rw, status, &ErrorResponse{Code: code, Message: msg})
+}
+
+// WriteValidationErrorResponse writes the bad request error resp of a request failing the validation, listing
+// its invalid fields if the error is a ValidationError
+func WriteValidationErrorResponse(rw http.ResponseWriter, err error) {
+	WriteError(rw, NewValidationError(err))
+}
 
-	err := json.NewEncoder(rw).Encode(ErrorResponse{
-		Message: msg,
-	})
+// WriteError writes the error resp of the failed operation, an error other than an Error is an internal error
+func WriteError(rw http.ResponseWriter, err error) {
+	var opErr *Error
+	if !errors.As(err, &opErr) {
+		opErr = NewError(http.StatusInternalServerError, InternalError, err.Error())
+	}
+
+	writeErrorResponse(rw, opErr.Status, &ErrorResponse{Code: opErr.Code, Message: opErr.Message,
+		Details: opErr.Details, Fields: opErr.Fields, Violations: opErr.Violations})
+}
+
+// WriteErrorResponseWithDetails write error resp with the details of the error (e.g. the cause of a decoding
+// failure). The correlation ID is the one set on the response by the HTTP handler.
+func WriteErrorResponseWithDetails(rw http.ResponseWriter, status int, code ErrorCode, msg, details string) {
+	writeErrorResponse(rw, status, &ErrorResponse{Code: code, Message: msg, Details: details})
+}
+
+func writeErrorResponse(rw http.ResponseWriter, status int, errResp *ErrorResponse) {
+	errResp.CorrelationID = rw.Header().Get(support.CorrelationIDHeader)
+
+	rw.WriteHeader(status)
 
+	err := json.NewEncoder(rw).Encode(errResp)
 	if err != nil {
 		logger.Errorf("Unable to send error message, %s", err)
 	}
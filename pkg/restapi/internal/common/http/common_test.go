@@ -0,0 +1,162 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+	"github.com/trustbloc/edge-service/pkg/internal/common/support"
+)
+
+func TestWriteErrorResponse(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rr.Header().Set(support.CorrelationIDHeader, "correlation-1")
+
+	WriteErrorResponseWithDetails(rr, http.StatusBadRequest, InvalidRequest, "Invalid request", "EOF")
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	errResp := &ErrorResponse{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), errResp))
+	require.Equal(t, &ErrorResponse{Code: InvalidRequest, Message: "Invalid request", Details: "EOF",
+		CorrelationID: "correlation-1"}, errResp)
+
+	rr = httptest.NewRecorder()
+
+	WriteErrorResponse(rr, http.StatusInternalServerError, EDVError, "vault not found")
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+	require.JSONEq(t, `{"code":"EDV_ERROR","errMessage":"vault not found"}`, rr.Body.String())
+}
+
+func TestWriteValidationErrorResponse(t *testing.T) {
+	validationErr := &ValidationError{}
+	require.NoError(t, validationErr.ErrorOrNil())
+
+	validationErr.Add("/name", "missing profile name")
+	validationErr.Add("/uri", "missing URI information")
+	require.EqualError(t, validationErr.ErrorOrNil(), "missing profile name; missing URI information")
+
+	rr := httptest.NewRecorder()
+
+	WriteValidationErrorResponse(rr, validationErr)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.JSONEq(t, `{"code":"INVALID_REQUEST","errMessage":"missing profile name; missing URI information",`+
+		`"fields":[{"field":"/name","message":"missing profile name"},`+
+		`{"field":"/uri","message":"missing URI information"}]}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+
+	WriteValidationErrorResponse(rr, errors.New("invalid request"))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.JSONEq(t, `{"code":"INVALID_REQUEST","errMessage":"invalid request"}`, rr.Body.String())
+}
+
+func TestWriteError(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	opErr := &Error{Status: http.StatusBadRequest, Code: InvalidRequest, Message: "Invalid request", Details: "EOF"}
+	require.EqualError(t, opErr, "Invalid request: EOF")
+	require.Equal(t, http.StatusBadRequest, opErr.StatusCode())
+	require.Equal(t, "INVALID_REQUEST", opErr.ErrorCode())
+
+	WriteError(rr, fmt.Errorf("issue credential: %w", opErr))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.JSONEq(t, `{"code":"INVALID_REQUEST","errMessage":"Invalid request","details":"EOF"}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+
+	WriteError(rr, errors.New("unexpected"))
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+	require.JSONEq(t, `{"code":"INTERNAL_ERROR","errMessage":"unexpected"}`, rr.Body.String())
+}
+
+func TestNewPolicyViolationError(t *testing.T) {
+	opErr := NewPolicyViolationError([]policy.Violation{{Rule: "subjectDID", Message: "subject id is not a did: 1"},
+		{Rule: "requiredTypes", Message: "missing credential types: DegreeCredential"}})
+	require.EqualError(t, opErr, "credential violates the policy of the profile: subjectDID: subject id is not a "+
+		"did: 1; requiredTypes: missing credential types: DegreeCredential")
+
+	rr := httptest.NewRecorder()
+
+	WriteError(rr, opErr)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	require.JSONEq(t, `{"code":"POLICY_VIOLATION","errMessage":"credential violates the policy of the profile",`+
+		`"details":"subjectDID: subject id is not a did: 1; requiredTypes: missing credential types: DegreeCredential",`+
+		`"violations":[{"rule":"subjectDID","message":"subject id is not a did: 1"},`+
+		`{"rule":"requiredTypes","message":"missing credential types: DegreeCredential"}]}`, rr.Body.String())
+}
+
+func TestDecodeJSON(t *testing.T) {
+	decode := func(body string) (map[string]interface{}, error) {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+
+		v := map[string]interface{}{}
+
+		return v, DecodeJSON(req, &v)
+	}
+
+	t.Run("success", func(t *testing.T) {
+		v, err := decode(`{"a":{"b":["[{\"", "c"]}}` + "\n")
+		require.NoError(t, err)
+		require.Equal(t, map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{`[{"`, "c"}}}, v)
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		_, err := decode("")
+		require.EqualError(t, err, "Invalid request: EOF")
+		require.True(t, IsEmptyBody(err))
+	})
+
+	t.Run("malformed body", func(t *testing.T) {
+		_, err := decode(`{"a":`)
+		require.EqualError(t, err, "Invalid request: unexpected EOF")
+		require.False(t, IsEmptyBody(err))
+
+		_, err = decode(`{"a":1} {"b":2}`)
+		require.EqualError(t, err, "Invalid request: unexpected data after the JSON value")
+	})
+
+	t.Run("body nested too deep", func(t *testing.T) {
+		_, err := decode(strings.Repeat("[", MaxJSONDepth) + strings.Repeat("]", MaxJSONDepth))
+		require.Contains(t, err.Error(), "cannot unmarshal array")
+
+		_, err = decode(strings.Repeat(`{"a":`, MaxJSONDepth+1) + "1" + strings.Repeat("}", MaxJSONDepth+1))
+		require.EqualError(t, err, "Invalid request: JSON nesting exceeds the maximum depth of 64")
+
+		var opErr *Error
+		require.True(t, errors.As(err, &opErr))
+		require.Equal(t, http.StatusBadRequest, opErr.Status)
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(`{"a":"bcdef"}`))
+		req.Body = http.MaxBytesReader(rr, req.Body, 5)
+
+		err := DecodeJSON(req, &map[string]interface{}{})
+
+		var opErr *Error
+		require.True(t, errors.As(err, &opErr))
+		require.Equal(t, http.StatusRequestEntityTooLarge, opErr.Status)
+		require.Equal(t, RequestTooLarge, opErr.Code)
+	})
+}
+
+func TestProfileErrorCode(t *testing.T) {
+	require.Equal(t, ProfileNotFound, ProfileErrorCode(fmt.Errorf("get profile: %w", storage.ErrValueNotFound)))
+	require.Equal(t, StorageError, ProfileErrorCode(errors.New("connection refused")))
+}
@@ -0,0 +1,277 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/apikey"
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+const (
+	// IdempotencyKeyHeader is the header of the key identifying the retries of a request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on the responses replayed for a retried request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	idempotencyStore        = "idempotency"
+	maxIdempotencyKeyLength = 255
+	// idempotencyKeyTTL is how long the response of a request is replayed for its retries
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyPendingTimeout is how long a request is in progress at most, its pending response not being
+	// replaced by its instance e.g. if it stopped
+	idempotencyPendingTimeout = 10 * time.Minute
+)
+
+// idempotentResponse is the stored response of a request with an idempotency key, pending while the request is
+// handled
+type idempotentResponse struct {
+	RequestHash string    `json:"requestHash"`
+	Pending     bool      `json:"pending,omitempty"`
+	Status      int       `json:"status,omitempty"`
+	ContentType string    `json:"contentType,omitempty"`
+	Body        []byte    `json:"body,omitempty"`
+	Created     time.Time `json:"created"`
+}
+
+// expired returns true if the response is no longer replayed, or the request is no longer considered in progress,
+// e.g. its instance stopped before storing its response
+func (r *idempotentResponse) expired() bool {
+	if r.Pending {
+		return time.Since(r.Created) > idempotencyPendingTimeout
+	}
+
+	return time.Since(r.Created) > idempotencyKeyTTL
+}
+
+// IdempotencyStore keeps the responses of the requests with an idempotency key, so their retries get the same
+// response instead of being handled again. A pending response is stored before a request is handled, so the retries
+// sent to any instance sharing the store while it is in progress are rejected.
+type IdempotencyStore struct {
+	store conditional.Store
+}
+
+// NewIdempotencyStore returns a new store of the responses of the requests with an idempotency key
+func NewIdempotencyStore(provider storage.Provider) (*IdempotencyStore, error) {
+	err := provider.CreateStore(idempotencyStore)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := provider.OpenStore(idempotencyStore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IdempotencyStore{store: conditional.New(store)}, nil
+}
+
+// Idempotent returns the handler replaying the stored response of a request with the same Idempotency-Key header,
+// path (e.g. of the profile) and client as a previous request, the retries of a request with a different body
+// failing with status 422 and the concurrent retries with status 409. The responses with a 5xx status are stored
+// too, as the request may have failed after its side effects, e.g. once a status list index was allocated. The
+// requests without the header are handled as is, as are all the requests if the store is nil.
+func Idempotent(store *IdempotencyStore, handle http.HandlerFunc) http.HandlerFunc {
+	if store == nil {
+		return handle
+	}
+
+	return func(rw http.ResponseWriter, req *http.Request) {
+		idempotencyKey := req.Header.Get(IdempotencyKeyHeader)
+		if idempotencyKey == "" {
+			handle(rw, req)
+
+			return
+		}
+
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			WriteError(rw, NewError(http.StatusBadRequest, InvalidRequest,
+				fmt.Sprintf("idempotency key exceeds %d characters", maxIdempotencyKeyLength)))
+
+			return
+		}
+
+		body, err := readBody(req)
+		if err != nil {
+			WriteError(rw, err)
+
+			return
+		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		if err := store.handle(rw, req, idempotencyKey, body, handle); err != nil {
+			WriteError(rw, err)
+		}
+	}
+}
+
+func (s *IdempotencyStore) handle(rw http.ResponseWriter, req *http.Request, idempotencyKey string, body []byte,
+	handle http.HandlerFunc) error {
+	key := hash(apikey.Client(req), req.URL.Path, idempotencyKey)
+	requestHash := hash(req.Method, string(body))
+
+	pending, err := json.Marshal(&idempotentResponse{RequestHash: requestHash, Pending: true, Created: time.Now()})
+	if err != nil {
+		return NewError(http.StatusInternalServerError, InternalError,
+			fmt.Sprintf("failed to marshal idempotent response: %s", err.Error()))
+	}
+
+	resp, err := s.start(key, pending)
+	if err != nil {
+		return err
+	}
+
+	if resp != nil {
+		return replay(rw, resp, idempotencyKey, requestHash)
+	}
+
+	rrw := &recordingResponseWriter{ResponseWriter: rw, status: http.StatusOK}
+
+	handle(rrw, req)
+
+	respBytes, err := json.Marshal(&idempotentResponse{RequestHash: requestHash, Status: rrw.status,
+		ContentType: rw.Header().Get("Content-Type"), Body: rrw.body.Bytes(), Created: time.Now()})
+	if err == nil {
+		err = s.store.Replace(key, pending, respBytes)
+	}
+
+	// the request is handled again once its pending response expires
+	if err != nil {
+		logger.Errorf("Failed to store the response of idempotency key %s: %s", idempotencyKey, err.Error())
+	}
+
+	return nil
+}
+
+// start stores the pending response of the key, unless the key has a response which hasn't expired: the request is
+// then either in progress or handled already, and its stored response is returned
+func (s *IdempotencyStore) start(key string, pending []byte) (*idempotentResponse, error) {
+	err := s.store.PutIfAbsent(key, pending)
+	if err == nil {
+		return nil, nil
+	}
+
+	if !errors.Is(err, conditional.ErrConflict) {
+		return nil, NewError(http.StatusInternalServerError, StorageError,
+			fmt.Sprintf("failed to store idempotent response: %s", err.Error()))
+	}
+
+	resp, stored, err := s.get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// the response removed in the meantime is handled as a concurrent request
+	if resp == nil {
+		return &idempotentResponse{Pending: true}, nil
+	}
+
+	if !resp.expired() {
+		return resp, nil
+	}
+
+	err = s.store.Replace(key, stored, pending)
+	if errors.Is(err, conditional.ErrConflict) {
+		return &idempotentResponse{Pending: true}, nil
+	}
+
+	if err != nil {
+		return nil, NewError(http.StatusInternalServerError, StorageError,
+			fmt.Sprintf("failed to store idempotent response: %s", err.Error()))
+	}
+
+	return nil, nil
+}
+
+// replay writes the stored response of the key, or the error of a request in progress or of a different request
+func replay(rw http.ResponseWriter, resp *idempotentResponse, idempotencyKey, requestHash string) error {
+	switch {
+	case resp.Pending:
+		return NewError(http.StatusConflict, Conflict,
+			fmt.Sprintf("request with idempotency key %s is in progress", idempotencyKey))
+	case resp.RequestHash != requestHash:
+		return NewError(http.StatusUnprocessableEntity, InvalidRequest,
+			fmt.Sprintf("idempotency key %s was used for a different request", idempotencyKey))
+	}
+
+	rw.Header().Set(IdempotentReplayedHeader, "true")
+
+	if resp.ContentType != "" {
+		rw.Header().Set("Content-Type", resp.ContentType)
+	}
+
+	rw.WriteHeader(resp.Status)
+
+	if _, err := rw.Write(resp.Body); err != nil {
+		logger.Errorf("Failed to write replayed response: %s", err.Error())
+	}
+
+	return nil
+}
+
+// get returns the stored response of the key and its stored bytes, nil if there is none
+func (s *IdempotencyStore) get(key string) (*idempotentResponse, []byte, error) {
+	respBytes, err := s.store.Get(key)
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return nil, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, NewError(http.StatusInternalServerError, StorageError,
+			fmt.Sprintf("failed to get idempotent response: %s", err.Error()))
+	}
+
+	resp := &idempotentResponse{}
+
+	if err := json.Unmarshal(respBytes, resp); err != nil {
+		return nil, nil, NewError(http.StatusInternalServerError, StorageError,
+			fmt.Sprintf("failed to unmarshal idempotent response: %s", err.Error()))
+	}
+
+	return resp, respBytes, nil
+}
+
+// hash returns the hash of the values, separated so that they can't be shifted into each other
+func hash(values ...string) string {
+	h := sha256.New()
+
+	for _, v := range values {
+		fmt.Fprintf(h, "%d:%s", len(v), v)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// recordingResponseWriter keeps the status code and the body written by the handler
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+
+	return w.ResponseWriter.Write(b)
+}
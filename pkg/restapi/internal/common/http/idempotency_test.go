@@ -0,0 +1,266 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+func TestNewIdempotencyStore(t *testing.T) {
+	t.Run("error - create store", func(t *testing.T) {
+		s, err := NewIdempotencyStore(&mockstore.Provider{ErrCreateStore: fmt.Errorf("error create")})
+		require.EqualError(t, err, "error create")
+		require.Nil(t, s)
+	})
+
+	t.Run("error - open store", func(t *testing.T) {
+		s, err := NewIdempotencyStore(&mockstore.Provider{ErrOpenStoreHandle: fmt.Errorf("error open")})
+		require.EqualError(t, err, "error open")
+		require.Nil(t, s)
+	})
+}
+
+func TestIdempotent(t *testing.T) {
+	calls := 0
+	handle := func(rw http.ResponseWriter, req *http.Request) {
+		calls++
+
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+
+		if string(body) == "fail" {
+			rw.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusCreated)
+		WriteResponse(rw, map[string]interface{}{"call": calls, "body": string(body)})
+	}
+
+	serve := func(handler http.HandlerFunc, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+
+		return rr
+	}
+
+	newStore := func() *IdempotencyStore {
+		s, err := NewIdempotencyStore(memstore.NewProvider())
+		require.NoError(t, err)
+
+		return s
+	}
+
+	t.Run("no store", func(t *testing.T) {
+		calls = 0
+
+		handler := Idempotent(nil, handle)
+		serve(handler, "/issuer1/credentials/issueCredential", "key1", "a")
+		serve(handler, "/issuer1/credentials/issueCredential", "key1", "a")
+		require.Equal(t, 2, calls)
+	})
+
+	t.Run("retries replayed", func(t *testing.T) {
+		calls = 0
+
+		handler := Idempotent(newStore(), handle)
+
+		rr := serve(handler, "/issuer1/credentials/issueCredential", "key1", "a")
+		require.Equal(t, http.StatusCreated, rr.Code)
+		require.Empty(t, rr.Header().Get(IdempotentReplayedHeader))
+
+		retry := serve(handler, "/issuer1/credentials/issueCredential", "key1", "a")
+		require.Equal(t, http.StatusCreated, retry.Code)
+		require.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+		require.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+		require.Equal(t, rr.Body.String(), retry.Body.String())
+		require.Equal(t, 1, calls)
+
+		// the keys are scoped by the path (of the profile), and the requests without key are always handled
+		serve(handler, "/issuer2/credentials/issueCredential", "key1", "a")
+		serve(handler, "/issuer1/credentials/issueCredential", "", "a")
+		require.Equal(t, 3, calls)
+	})
+
+	t.Run("key used for a different request", func(t *testing.T) {
+		handler := Idempotent(newStore(), handle)
+
+		serve(handler, "/issuer1/credentials/issueCredential", "key1", "a")
+
+		rr := serve(handler, "/issuer1/credentials/issueCredential", "key1", "b")
+		require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		require.Contains(t, rr.Body.String(), "idempotency key key1 was used for a different request")
+	})
+
+	t.Run("server errors replayed", func(t *testing.T) {
+		calls = 0
+
+		handler := Idempotent(newStore(), handle)
+
+		require.Equal(t, http.StatusInternalServerError,
+			serve(handler, "/updateStatus", "key1", "fail").Code)
+
+		// the request may have failed after its side effects, it isn't handled again
+		retry := serve(handler, "/updateStatus", "key1", "fail")
+		require.Equal(t, http.StatusInternalServerError, retry.Code)
+		require.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("request in progress on another instance", func(t *testing.T) {
+		calls = 0
+
+		provider := memstore.NewProvider()
+
+		instance1, err := NewIdempotencyStore(provider)
+		require.NoError(t, err)
+
+		instance2, err := NewIdempotencyStore(provider)
+		require.NoError(t, err)
+
+		started, release := make(chan struct{}), make(chan struct{})
+
+		done := make(chan *httptest.ResponseRecorder)
+
+		go func() {
+			done <- serve(Idempotent(instance1, func(rw http.ResponseWriter, req *http.Request) {
+				close(started)
+				<-release
+				handle(rw, req)
+			}), "/updateStatus", "key1", "a")
+		}()
+
+		<-started
+
+		rr := serve(Idempotent(instance2, handle), "/updateStatus", "key1", "a")
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Contains(t, rr.Body.String(), "request with idempotency key key1 is in progress")
+
+		close(release)
+		require.Equal(t, http.StatusCreated, (<-done).Code)
+
+		retry := serve(Idempotent(instance2, handle), "/updateStatus", "key1", "a")
+		require.Equal(t, http.StatusCreated, retry.Code)
+		require.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("pending response of a stopped instance", func(t *testing.T) {
+		calls = 0
+
+		s := newStore()
+
+		pending, err := json.Marshal(&idempotentResponse{RequestHash: hash(http.MethodPost, "a"), Pending: true,
+			Created: time.Now().Add(-idempotencyPendingTimeout - time.Minute)})
+		require.NoError(t, err)
+		require.NoError(t, s.store.Put(hash("", "/updateStatus", "key1"), pending))
+
+		require.Equal(t, http.StatusCreated, serve(Idempotent(s, handle), "/updateStatus", "key1", "a").Code)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("expired response", func(t *testing.T) {
+		calls = 0
+
+		s := newStore()
+		handler := Idempotent(s, handle)
+
+		expired, err := json.Marshal(&idempotentResponse{RequestHash: hash(http.MethodPost, "a"),
+			Status: http.StatusOK, Created: time.Now().Add(-idempotencyKeyTTL - time.Minute)})
+		require.NoError(t, err)
+		require.NoError(t, s.store.Put(hash("", "/updateStatus", "key1"), expired))
+
+		require.Equal(t, http.StatusCreated, serve(handler, "/updateStatus", "key1", "a").Code)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("error - key too long", func(t *testing.T) {
+		rr := serve(Idempotent(newStore(), handle), "/updateStatus", strings.Repeat("k", 256), "a")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "idempotency key exceeds 255 characters")
+	})
+
+	t.Run("error - body too large", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/updateStatus", bytes.NewBufferString("abcdef"))
+		req.Header.Set(IdempotencyKeyHeader, "key1")
+
+		rr := httptest.NewRecorder()
+		req.Body = http.MaxBytesReader(rr, req.Body, 5)
+
+		Idempotent(newStore(), handle)(rr, req)
+		require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+
+	t.Run("error - store pending response", func(t *testing.T) {
+		calls = 0
+
+		s := &IdempotencyStore{store: conditional.New(&mockstore.MockStore{Store: map[string][]byte{},
+			ErrPut: fmt.Errorf("put error")})}
+
+		rr := serve(Idempotent(s, handle), "/updateStatus", "key1", "a")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Zero(t, calls)
+
+		errResp := &ErrorResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), errResp))
+		require.Equal(t, StorageError, errResp.Code)
+		require.Equal(t, "failed to store idempotent response: put error", errResp.Message)
+	})
+
+	t.Run("error - get stored response", func(t *testing.T) {
+		s := &IdempotencyStore{store: &getFailingStore{Store: conditional.New(&mockstore.MockStore{
+			Store: map[string][]byte{hash("", "/updateStatus", "key1"): []byte("{}")}})}}
+
+		rr := serve(Idempotent(s, handle), "/updateStatus", "key1", "a")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+		errResp := &ErrorResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), errResp))
+		require.Equal(t, StorageError, errResp.Code)
+		require.Equal(t, "failed to get idempotent response: get error", errResp.Message)
+	})
+
+	t.Run("error - invalid stored response", func(t *testing.T) {
+		s := &IdempotencyStore{store: conditional.New(&mockstore.MockStore{Store: map[string][]byte{
+			hash("", "/updateStatus", "key1"): []byte("{")}})}
+
+		rr := serve(Idempotent(s, handle), "/updateStatus", "key1", "a")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to unmarshal idempotent response")
+	})
+}
+
+// getFailingStore fails to get the stored values, its conditional writes conflicting with the stored values
+type getFailingStore struct {
+	conditional.Store
+}
+
+func (s *getFailingStore) Get(string) ([]byte, error) {
+	return nil, errors.New("get error")
+}
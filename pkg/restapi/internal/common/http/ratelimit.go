@@ -0,0 +1,42 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package http
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/trustbloc/edge-service/pkg/apikey"
+	"github.com/trustbloc/edge-service/pkg/ratelimit"
+)
+
+// RateLimit returns the handler rejecting the requests over the rate limit with status 429 and the Retry-After
+// header (in seconds). The requests are limited per profile (the given path parameter) or per client authenticated
+// by its API key, as configured. The handler is returned as is if no rate limit is configured.
+func RateLimit(config *ratelimit.Config, profilePathParam string, handle http.HandlerFunc) http.HandlerFunc {
+	if config == nil || config.Limiter == nil {
+		return handle
+	}
+
+	return func(rw http.ResponseWriter, req *http.Request) {
+		allowed, retryAfter := config.Limiter.Allow(config.BucketKey(mux.Vars(req)[profilePathParam],
+			apikey.Client(req)))
+		if !allowed {
+			rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			WriteErrorResponse(rw, http.StatusTooManyRequests, RateLimited,
+				fmt.Sprintf("rate limit exceeded, retry after %s", retryAfter))
+
+			return
+		}
+
+		handle(rw, req)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/apikey"
+	"github.com/trustbloc/edge-service/pkg/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	handle := func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusCreated)
+	}
+
+	serve := func(handle http.HandlerFunc, apiKey string) *httptest.ResponseRecorder {
+		router := mux.NewRouter()
+		router.Use(apikey.New(map[string]string{"client1": "key1"}).Middleware)
+		router.HandleFunc("/{profileID}/credentials/issueCredential", handle)
+
+		req := httptest.NewRequest(http.MethodPost, "/issuer1/credentials/issueCredential", nil)
+		if apiKey != "" {
+			req.Header.Set(apikey.Header, apiKey)
+		}
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	t.Run("no rate limit", func(t *testing.T) {
+		require.Equal(t, http.StatusCreated, serve(RateLimit(nil, "profileID", handle), "").Code)
+		require.Equal(t, http.StatusCreated, serve(RateLimit(&ratelimit.Config{}, "profileID", handle), "").Code)
+	})
+
+	t.Run("limited per profile", func(t *testing.T) {
+		limiter := &mockLimiter{allowed: map[string]bool{"profile:issuer1": false}}
+		config := &ratelimit.Config{Limiter: limiter, Key: ratelimit.KeyProfile}
+
+		rr := serve(RateLimit(config, "profileID", handle), "key1")
+		require.Equal(t, http.StatusTooManyRequests, rr.Code)
+		require.Equal(t, "2", rr.Header().Get("Retry-After"))
+		require.Contains(t, rr.Body.String(), `"code":"RATE_LIMITED"`)
+		require.Equal(t, []string{"profile:issuer1"}, limiter.keys)
+	})
+
+	t.Run("limited per API key", func(t *testing.T) {
+		limiter := &mockLimiter{allowed: map[string]bool{"client:client1": true}}
+		config := &ratelimit.Config{Limiter: limiter, Key: ratelimit.KeyAPIKey}
+
+		require.Equal(t, http.StatusCreated, serve(RateLimit(config, "profileID", handle), "key1").Code)
+
+		// without API key the requests are limited per profile
+		require.Equal(t, http.StatusTooManyRequests, serve(RateLimit(config, "profileID", handle), "").Code)
+		require.Equal(t, []string{"client:client1", "profile:issuer1"}, limiter.keys)
+
+		// the requests with an unknown API key are rejected before being limited
+		require.Equal(t, http.StatusUnauthorized, serve(RateLimit(config, "profileID", handle), "key2").Code)
+		require.Equal(t, []string{"client:client1", "profile:issuer1"}, limiter.keys)
+	})
+}
+
+type mockLimiter struct {
+	allowed map[string]bool
+	keys    []string
+}
+
+func (m *mockLimiter) Allow(key string) (bool, time.Duration) {
+	m.keys = append(m.keys, key)
+
+	return m.allowed[key], 1500 * time.Millisecond
+}
@@ -22,8 +22,7 @@ import (
 )
 
 const (
-	defVCContext                = "https://www.w3.org/2018/credentials/v1"
-	jsonWebSignature2020Context = "https://trustbloc.github.io/context/vc/credentials-v1.jsonld"
+	defVCContext = "https://www.w3.org/2018/credentials/v1"
 )
 
 // GetContextsFromJSONRaw reads contexts from raw JSON
@@ -125,13 +124,31 @@ func UpdateIssuer(credential *verifiable.Credential, profile *vcprofile.DataProf
 	}
 }
 
-// UpdateSignatureTypeContext updates context for JSONWebSignature2020
+// UpdateSignatureTypeContext adds the context required by the signature suite of the profile, e.g. for
+// JSONWebSignature2020
 func UpdateSignatureTypeContext(credential *verifiable.Credential, profile *vcprofile.DataProfile) {
-	if profile.SignatureType == crypto.JSONWebSignature2020 {
-		credential.Context = append(credential.Context, jsonWebSignature2020Context)
+	if !HasSignatureTypeContext(credential, profile) {
+		credential.Context = append(credential.Context, crypto.SignatureContext(profile.SignatureType))
 	}
 }
 
+// HasSignatureTypeContext checks the credential has the context required by the signature type of the profile,
+// if any
+func HasSignatureTypeContext(credential *verifiable.Credential, profile *vcprofile.DataProfile) bool {
+	signatureContext := crypto.SignatureContext(profile.SignatureType)
+	if signatureContext == "" {
+		return true
+	}
+
+	for _, context := range credential.Context {
+		if context == signatureContext {
+			return true
+		}
+	}
+
+	return false
+}
+
 // GetDocIDFromURL Given an EDV document URL, returns just the document ID
 func GetDocIDFromURL(docURL string) string {
 	splitBySlashes := strings.Split(docURL, `/`)
@@ -219,8 +219,16 @@ func TestUpdateSignatureTypeContext(t *testing.T) {
 
 	UpdateSignatureTypeContext(vc, profile)
 	require.Len(t, vc.Context, 1)
+	require.True(t, HasSignatureTypeContext(vc, profile))
 
 	profile.SignatureType = crypto.JSONWebSignature2020
+	require.False(t, HasSignatureTypeContext(vc, profile))
+
+	UpdateSignatureTypeContext(vc, profile)
+	require.Len(t, vc.Context, 2)
+	require.True(t, HasSignatureTypeContext(vc, profile))
+
+	// added once
 	UpdateSignatureTypeContext(vc, profile)
 	require.Len(t, vc.Context, 2)
 }
@@ -37,6 +37,7 @@ func TestController_New(t *testing.T) {
 			VDRI: &vdrimock.MockVDRIRegistry{}, HostURL: ""})
 		require.NoError(t, err)
 		require.NotNil(t, controller)
+		require.NotNil(t, controller.Operation())
 	})
 
 	t.Run("test error", func(t *testing.T) {
@@ -66,5 +67,5 @@ func TestController_GetOperations(t *testing.T) {
 
 	ops := controller.GetOperations()
 
-	require.Equal(t, 9, len(ops))
+	require.Equal(t, 49, len(ops))
 }
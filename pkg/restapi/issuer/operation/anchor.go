@@ -0,0 +1,76 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+// anchorEvidenceType is the type of the evidence entry with the time-stamp token of an anchored credential
+const anchorEvidenceType = "TimestampAnchor"
+
+// anchorCredential time-stamps the credential, before it is signed, when the profile anchors its credentials and
+// adds the time-stamp token to its evidence. The token is the token of the hashlink of the credential as it was
+// before the entry was added: without the anchor entry, and without evidence if it is the only entry.
+func (o *Operation) anchorCredential(profile *vcprofile.DataProfile, credential *verifiable.Credential) error {
+	if !profile.AnchorCredentials {
+		return nil
+	}
+
+	if o.timestamper == nil {
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			"credential anchoring not supported: no time-stamping authority")
+	}
+
+	vcBytes, err := json.Marshal(credential)
+	if err != nil {
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to marshal credential to anchor: %s", err.Error()))
+	}
+
+	digest, err := canonicalDigest(vcBytes)
+	if err != nil {
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError, err.Error())
+	}
+
+	token, err := o.timestamper.Timestamp(digest)
+	if err != nil {
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to anchor credential: %s", err.Error()))
+	}
+
+	entry := map[string]interface{}{
+		"type":               anchorEvidenceType,
+		"hashlink":           hashlink(digest),
+		"timestampAuthority": token.Authority,
+		"anchoredAt":         token.Time.Format(time.RFC3339),
+		"timestampToken":     base64.StdEncoding.EncodeToString(token.Token),
+	}
+
+	var evidence []interface{}
+
+	switch existing := credential.Evidence.(type) {
+	case nil:
+	case []interface{}:
+		evidence = append(evidence, existing...)
+	default:
+		evidence = append(evidence, existing)
+	}
+
+	credential.Evidence = append(evidence, entry)
+
+	return nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/client/tsa"
+	"github.com/trustbloc/edge-service/pkg/internal/mock/edv"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+type mockTimestamper struct {
+	digest []byte
+	err    error
+}
+
+func (m *mockTimestamper) Timestamp(digest []byte) (*tsa.Token, error) {
+	m.digest = digest
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &tsa.Token{Authority: "https://tsa.example.com", Token: []byte("token"),
+		Time: time.Date(2020, 9, 1, 10, 20, 30, 0, time.UTC)}, nil
+}
+
+func TestAnchorCredential(t *testing.T) {
+	timestamper := &mockTimestamper{}
+	op := &Operation{timestamper: timestamper}
+
+	t.Run("test profile not anchoring its credentials", func(t *testing.T) {
+		vc, err := verifiable.ParseUnverifiedCredential([]byte(validVCWithoutStatus))
+		require.NoError(t, err)
+
+		require.NoError(t, op.anchorCredential(getTestProfile(), vc))
+		require.Nil(t, vc.Evidence)
+	})
+
+	t.Run("test anchored credential", func(t *testing.T) {
+		vc, err := verifiable.ParseUnverifiedCredential([]byte(validVCWithoutStatus))
+		require.NoError(t, err)
+
+		vcBytes, err := json.Marshal(vc)
+		require.NoError(t, err)
+
+		link, err := credentialHashlink(vcBytes)
+		require.NoError(t, err)
+
+		profile := getTestProfile()
+		profile.AnchorCredentials = true
+
+		require.NoError(t, op.anchorCredential(profile, vc))
+		require.Equal(t, link, hashlink(timestamper.digest))
+		require.Equal(t, []interface{}{map[string]interface{}{
+			"type":               anchorEvidenceType,
+			"hashlink":           link,
+			"timestampAuthority": "https://tsa.example.com",
+			"anchoredAt":         "2020-09-01T10:20:30Z",
+			"timestampToken":     base64.StdEncoding.EncodeToString([]byte("token")),
+		}}, vc.Evidence)
+
+		// the anchor entry is added to the existing evidence
+		vc.Evidence = map[string]interface{}{"type": "DocumentVerification"}
+		require.NoError(t, op.anchorCredential(profile, vc))
+		require.Len(t, vc.Evidence, 2)
+
+		require.NoError(t, op.anchorCredential(profile, vc))
+		require.Len(t, vc.Evidence, 3)
+	})
+
+	t.Run("test errors", func(t *testing.T) {
+		vc, err := verifiable.ParseUnverifiedCredential([]byte(validVCWithoutStatus))
+		require.NoError(t, err)
+
+		profile := getTestProfile()
+		profile.AnchorCredentials = true
+
+		err = (&Operation{timestamper: &mockTimestamper{err: errors.New("tsa unavailable")}}).
+			anchorCredential(profile, vc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to anchor credential: tsa unavailable")
+
+		err = (&Operation{}).anchorCredential(profile, vc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "no time-stamping authority")
+	})
+}
+
+func TestCreateProfile_AnchorCredentials(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	config := &Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		EDVClient:          edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		VDRI:               &vdrimock.MockVDRIRegistry{},
+		Crypto:             &cryptomock.Crypto{}}
+
+	op, err := New(config)
+	require.NoError(t, err)
+
+	op.commonDID = &mockCommonDID{}
+
+	pr := getProfileRequest()
+	pr.AnchorCredentials = true
+
+	_, err = op.CreateProfile(pr)
+	require.Error(t, err)
+
+	var opErr *commhttp.Error
+
+	require.True(t, errors.As(err, &opErr))
+	require.Equal(t, http.StatusBadRequest, opErr.Status)
+	require.Equal(t, "/anchorCredentials", opErr.Fields[0].Field)
+
+	config.Timestamper = &mockTimestamper{}
+
+	op, err = New(config)
+	require.NoError(t, err)
+
+	op.commonDID = &mockCommonDID{}
+
+	profile, err := op.CreateProfile(pr)
+	require.NoError(t, err)
+	require.True(t, profile.AnchorCredentials)
+}
@@ -0,0 +1,238 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/trustbloc/edv/pkg/restapi/messages"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	attachmentIDPathParam = "attachmentID"
+	evidencePath          = credentialsBasePath + "/evidence"
+	attachmentPath        = evidencePath + "/{" + attachmentIDPathParam + "}"
+
+	// attachmentEvidenceType is the type of the evidence entries of the attachments
+	attachmentEvidenceType = "DocumentAttachment"
+	defaultMediaType       = "application/octet-stream"
+
+	// the multihash prefix of a SHA-256 digest: the sha2-256 code and the digest length
+	multihashSHA256 = 0x12
+	sha256Length    = 32
+)
+
+// storedAttachment is the content of the EDV document of an attachment
+type storedAttachment struct {
+	Name      string `json:"name,omitempty"`
+	MediaType string `json:"mediaType"`
+	Data      []byte `json:"data"`
+}
+
+// hashlink returns the hashlink of the digest, the base58btc multibase encoding of its SHA-256 multihash
+func hashlink(digest []byte) string {
+	return "hl:z" + base58.Encode(append([]byte{multihashSHA256, sha256Length}, digest...))
+}
+
+// attachmentDocID returns the ID of the EDV document of the attachment with the digest: the attachments are stored
+// once per vault, EDV document IDs being base58 encoded 128-bit values
+func attachmentDocID(digest []byte) string {
+	return base58.Encode(digest[:16])
+}
+
+// addEvidenceAttachments stores the attachments of the compose request in the vault of the profile, and adds an
+// evidence entry with the URL and the hashlink of each attachment to the evidence of the credential
+func (o *Operation) addEvidenceAttachments(profile *vcprofile.DataProfile, credential *verifiable.Credential,
+	attachments []EvidenceAttachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	var evidence []interface{}
+
+	if credential.Evidence != nil {
+		evidence = append(evidence, credential.Evidence)
+	}
+
+	for i := range attachments {
+		entry, err := o.storeAttachment(profile, &attachments[i])
+		if err != nil {
+			return commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError,
+				fmt.Sprintf("failed to store the attachment at index %d: %s", i, err.Error()))
+		}
+
+		evidence = append(evidence, entry)
+	}
+
+	credential.Evidence = evidence
+
+	return nil
+}
+
+// storeAttachment stores the attachment, unless the vault already has it, and returns its evidence entry
+func (o *Operation) storeAttachment(profile *vcprofile.DataProfile,
+	attachment *EvidenceAttachment) (map[string]interface{}, error) {
+	data, err := base64.StdEncoding.DecodeString(attachment.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %w", err)
+	}
+
+	mediaType := attachment.MediaType
+	if mediaType == "" {
+		mediaType = defaultMediaType
+	}
+
+	digest := sha256.Sum256(data)
+	docID := attachmentDocID(digest[:])
+
+	docBytes, err := json.Marshal(&models.StructuredDocument{ID: docID, Content: map[string]interface{}{
+		"message": &storedAttachment{Name: attachment.Name, MediaType: mediaType, Data: data},
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = o.createAttachmentDocument(profile, docID, docBytes); err != nil {
+		return nil, err
+	}
+
+	entry := map[string]interface{}{
+		"id":        o.HostURL + "/" + url.PathEscape(profile.Name) + "/credentials/evidence/" + docID,
+		"type":      attachmentEvidenceType,
+		"mediaType": mediaType,
+		"hashlink":  hashlink(digest[:]),
+	}
+
+	if attachment.Name != "" {
+		entry["name"] = attachment.Name
+	}
+
+	return entry, nil
+}
+
+func (o *Operation) createAttachmentDocument(profile *vcprofile.DataProfile, docID string, docBytes []byte) error {
+	jweCrypto, err := o.profileJWECrypto(profile.Name)
+	if err != nil {
+		return err
+	}
+
+	jwe, err := jweCrypto.Encrypt(docBytes, nil)
+	if err != nil {
+		return err
+	}
+
+	serializedJWE, err := jwe.FullSerialize(json.Marshal)
+	if err != nil {
+		return err
+	}
+
+	edvClient, err := o.profileEDVClient(profile)
+	if err != nil {
+		return err
+	}
+
+	// the attachments have no index, they aren't listed nor re-encrypted with the credentials
+	document := &models.EncryptedDocument{ID: docID, JWE: []byte(serializedJWE)}
+
+	_, err = edvClient.CreateDocument(profile.Name, document)
+	if err != nil && strings.Contains(err.Error(), messages.ErrVaultNotFound.Error()) {
+		_, err = edvClient.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: profile.Name})
+		if err == nil {
+			_, err = edvClient.CreateDocument(profile.Name, document)
+		}
+	}
+
+	// the document IDs are derived from the digests, the vault already has the attachment
+	if err != nil && strings.Contains(err.Error(), messages.ErrDuplicateDocument.Error()) {
+		return nil
+	}
+
+	return err
+}
+
+// RetrieveEvidence swagger:route GET /{profileID}/credentials/evidence/{attachmentID} issuer evidenceAttachmentReq
+//
+// Retrieves an attachment of the evidence of the credentials issued by the profile, as referenced by the evidence.
+//
+// Responses:
+//    default: genericError
+//        200: evidenceAttachmentResp
+func (o *Operation) evidenceAttachmentHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.profileStore.GetProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid issuer profile: %s", err.Error()))
+
+		return
+	}
+
+	attachment, err := o.readAttachment(profile, mux.Vars(req)[attachmentIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", attachment.MediaType)
+
+	if _, err = rw.Write(attachment.Data); err != nil {
+		logger.Errorf("failed to write evidence attachment response: %s", err)
+	}
+}
+
+func (o *Operation) readAttachment(profile *vcprofile.DataProfile, docID string) (*storedAttachment, error) {
+	edvClient, err := o.profileEDVClient(profile)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError, err.Error())
+	}
+
+	document, err := edvClient.ReadDocument(profile.Name, docID)
+
+	// the attachments aren't migrated with the credentials, they stay in the vault keyed by the profile name
+	if c, ok := edvClient.(*profileVaultClient); ok && err != nil {
+		document, err = c.EDVClient.ReadDocument(profile.Name, docID)
+	}
+
+	if err != nil {
+		if strings.Contains(err.Error(), messages.ErrDocumentNotFound.Error()) ||
+			strings.Contains(err.Error(), messages.ErrVaultNotFound.Error()) {
+			return nil, commhttp.NewError(http.StatusNotFound, commhttp.NotFound,
+				fmt.Sprintf("evidence attachment %s not found", docID))
+		}
+
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError,
+			fmt.Sprintf("failed to read evidence attachment %s: %s", docID, err.Error()))
+	}
+
+	attachmentBytes, err := o.decryptVC(profile.Name, document, "retrieving evidence attachment")
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError, err.Error())
+	}
+
+	attachment := &storedAttachment{}
+
+	// the credentials are stored in the same vault, a credential document isn't an attachment
+	if err = json.Unmarshal(attachmentBytes, attachment); err != nil || attachment.Data == nil {
+		return nil, commhttp.NewError(http.StatusNotFound, commhttp.NotFound,
+			fmt.Sprintf("evidence attachment %s not found", docID))
+	}
+
+	return attachment, nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+)
+
+func TestHashlink(t *testing.T) {
+	digest := sha256.Sum256([]byte("Hello World!"))
+
+	// the example of the hashlink specification
+	require.Equal(t, "hl:zQmWvQxTqbG2Z9HPJgG57jjwR154cKhbtJenbyYTWkjgF3e", hashlink(digest[:]))
+}
+
+func TestEvidenceAttachments(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	newOperation := func(t *testing.T, credentialStorage string) *Operation {
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "https://issuer.example.com",
+			CredentialStorage:  credentialStorage})
+		require.NoError(t, err)
+
+		return op
+	}
+
+	profile := &vcprofile.DataProfile{Name: "issuer"}
+	document := base64.StdEncoding.EncodeToString([]byte("Hello World!"))
+
+	t.Run("test store and retrieve attachments", func(t *testing.T) {
+		op := newOperation(t, CredentialStorageLocal)
+		require.NoError(t, op.profileStore.SaveProfile(profile))
+
+		credential := &verifiable.Credential{Evidence: map[string]interface{}{"id": "https://example.edu/evidence/1"}}
+
+		require.NoError(t, op.addEvidenceAttachments(profile, credential, []EvidenceAttachment{
+			{Name: "hello.txt", MediaType: "text/plain", Data: document},
+			{Data: base64.StdEncoding.EncodeToString([]byte{0x01, 0x02})},
+		}))
+
+		evidence, ok := credential.Evidence.([]interface{})
+		require.True(t, ok)
+		require.Len(t, evidence, 3)
+		require.Equal(t, map[string]interface{}{"id": "https://example.edu/evidence/1"}, evidence[0])
+
+		entry, ok := evidence[1].(map[string]interface{})
+		require.True(t, ok)
+		require.Equal(t, attachmentEvidenceType, entry["type"])
+		require.Equal(t, "hello.txt", entry["name"])
+		require.Equal(t, "text/plain", entry["mediaType"])
+		require.Equal(t, "hl:zQmWvQxTqbG2Z9HPJgG57jjwR154cKhbtJenbyYTWkjgF3e", entry["hashlink"])
+
+		id, ok := entry["id"].(string)
+		require.True(t, ok)
+		require.Regexp(t, "^https://issuer.example.com/issuer/credentials/evidence/", id)
+
+		entry, ok = evidence[2].(map[string]interface{})
+		require.True(t, ok)
+		require.Equal(t, defaultMediaType, entry["mediaType"])
+		require.NotContains(t, entry, "name")
+
+		// the same document is stored once
+		credential = &verifiable.Credential{}
+
+		require.NoError(t, op.addEvidenceAttachments(profile, credential, []EvidenceAttachment{
+			{Name: "hello.txt", MediaType: "text/plain", Data: document},
+		}))
+		require.Equal(t, []interface{}{evidence[1]}, credential.Evidence)
+
+		handler := getHandler(t, op, attachmentPath, http.MethodGet)
+		attachmentID := id[len("https://issuer.example.com/issuer/credentials/evidence/"):]
+
+		rr := serveHTTPMux(t, handler, "/issuer/credentials/evidence/"+attachmentID, nil,
+			map[string]string{profileIDPathParam: "issuer", attachmentIDPathParam: attachmentID})
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "text/plain", rr.Header().Get("Content-Type"))
+		require.Equal(t, "Hello World!", rr.Body.String())
+
+		rr = serveHTTPMux(t, handler, "/issuer/credentials/evidence/unknown", nil,
+			map[string]string{profileIDPathParam: "issuer", attachmentIDPathParam: "unknown"})
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), "evidence attachment unknown not found")
+
+		rr = serveHTTPMux(t, handler, "/other/credentials/evidence/"+attachmentID, nil,
+			map[string]string{profileIDPathParam: "other", attachmentIDPathParam: attachmentID})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid issuer profile")
+	})
+
+	t.Run("test no attachments", func(t *testing.T) {
+		op := newOperation(t, "")
+
+		credential := &verifiable.Credential{Evidence: map[string]interface{}{"id": "https://example.edu/evidence/1"}}
+
+		require.NoError(t, op.addEvidenceAttachments(profile, credential, nil))
+		require.Equal(t, map[string]interface{}{"id": "https://example.edu/evidence/1"}, credential.Evidence)
+	})
+
+	t.Run("test error - EDV not configured", func(t *testing.T) {
+		op := newOperation(t, "")
+		require.NoError(t, op.profileStore.SaveProfile(profile))
+
+		err := op.addEvidenceAttachments(profile, &verifiable.Credential{},
+			[]EvidenceAttachment{{Data: document}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store the attachment at index 0: "+errEDVNotConfigured.Error())
+
+		handler := getHandler(t, op, attachmentPath, http.MethodGet)
+
+		rr := serveHTTPMux(t, handler, "/issuer/credentials/evidence/unknown", nil,
+			map[string]string{profileIDPathParam: "issuer", attachmentIDPathParam: "unknown"})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), errEDVNotConfigured.Error())
+	})
+}
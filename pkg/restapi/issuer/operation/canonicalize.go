@@ -0,0 +1,141 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/canonical"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+// CanonicalizeCredential swagger:route POST /credentials/canonicalize issuer canonicalizeCredentialReq
+//
+// Returns the URDNA2015 normalized form of the credential, the N-Quads its linked data proofs are signed over, and
+// flags the terms the normalization drops as they aren't defined by the contexts of the credential, and the
+// statements which aren't valid RDF. A credential whose terms are dropped is signed without them, so a verifier
+// resolving the contexts differently fails to verify it.
+//
+// Responses:
+//    default: genericError
+//        200: canonicalizeCredentialRes
+func (o *Operation) canonicalizeCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	data := CanonicalizeCredentialRequest{}
+
+	if err := commhttp.DecodeJSON(req, &data); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if len(data.Credential) == 0 {
+		validationErr := &commhttp.ValidationError{}
+		validationErr.Add("/credential", "credential is required")
+
+		commhttp.WriteError(rw, commhttp.NewValidationError(validationErr))
+
+		return
+	}
+
+	diagnosis, err := canonical.Diagnose(data.Credential, o.documentLoader)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, diagnosis)
+}
+
+// CompareCredentials swagger:route POST /credentials/compare issuer compareCredentialsReq
+//
+// Compares two credentials without their proofs: whether their URDNA2015 normalized forms are the same, i.e. the
+// same proofs are valid for both, and the fields which differ between them. Tells a tampered credential from a copy
+// of the credential signed again, e.g. the copies of a credential stored several times under the same ID.
+//
+// Responses:
+//    default: genericError
+//        200: compareCredentialsRes
+func (o *Operation) compareCredentialsHandler(rw http.ResponseWriter, req *http.Request) {
+	data := CompareCredentialsRequest{}
+
+	if err := commhttp.DecodeJSON(req, &data); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	validationErr := &commhttp.ValidationError{}
+
+	if len(data.Left) == 0 {
+		validationErr.Add("/left", "left credential is required")
+	}
+
+	if len(data.Right) == 0 {
+		validationErr.Add("/right", "right credential is required")
+	}
+
+	if err := validationErr.ErrorOrNil(); err != nil {
+		commhttp.WriteError(rw, commhttp.NewValidationError(err))
+
+		return
+	}
+
+	comparison, err := canonical.Compare(data.Left, data.Right, o.documentLoader)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, comparison)
+}
+
+// checkStrictJSONLD fails the issuance of a credential of a strict JSON-LD profile whose canonicalization is lossy,
+// naming the terms its contexts don't define
+func (o *Operation) checkStrictJSONLD(profile *vcprofile.DataProfile, credential *verifiable.Credential) error {
+	if !profile.StrictJSONLD {
+		return nil
+	}
+
+	credentialBytes, err := credential.MarshalJSON()
+	if err != nil {
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to marshal credential: %s", err.Error()))
+	}
+
+	diagnosis, err := canonical.Diagnose(credentialBytes, o.documentLoader)
+	if err != nil {
+		return commhttp.NewError(http.StatusBadRequest, commhttp.InvalidCredential, err.Error())
+	}
+
+	if len(diagnosis.DroppedTerms) > 0 {
+		terms := make([]string, len(diagnosis.DroppedTerms))
+
+		for i, term := range diagnosis.DroppedTerms {
+			terms[i] = fmt.Sprintf("%s at %s", term.Term, term.Path)
+		}
+
+		return &commhttp.Error{Status: http.StatusBadRequest, Code: commhttp.InvalidCredential,
+			Message: fmt.Sprintf("profile %s requires the terms of the credential to be defined by its contexts",
+				profile.Name),
+			Details: "undefined terms " + strings.Join(terms, ", ")}
+	}
+
+	if len(diagnosis.InvalidStatements) > 0 {
+		return &commhttp.Error{Status: http.StatusBadRequest, Code: commhttp.InvalidCredential,
+			Message: fmt.Sprintf("profile %s requires the credential to be valid RDF", profile.Name),
+			Details: "invalid statements " + strings.Join(diagnosis.InvalidStatements, ", ")}
+	}
+
+	return nil
+}
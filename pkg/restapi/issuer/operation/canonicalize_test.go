@@ -0,0 +1,256 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/canonical"
+	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const canonicalizedVC = `{
+  "@context": [
+    "https://www.w3.org/2018/credentials/v1",
+    {"name": "http://schema.org/name"}
+  ],
+  "id": "http://example.edu/credentials/1872",
+  "type": "VerifiableCredential",
+  "issuer": "did:example:76e12ec712ebc6f1c221ebfeb1f",
+  "issuanceDate": "2010-01-01T19:23:24Z",
+  "credentialSubject": {
+    "id": "did:example:ebfeb1f712ebc6f1c276e12ec21",
+    "name": "Jayden Doe",
+    "degree": "Bachelor of Science"
+  }
+}`
+
+func TestCanonicalizeCredentialHandler(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		StoreProvider:      memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		Crypto:             &cryptomock.Crypto{},
+	})
+	require.NoError(t, err)
+
+	op.documentLoader = verifiable.CachingJSONLDLoader()
+
+	handler := getHandler(t, op, canonicalizeCredentialEndpoint, http.MethodPost)
+
+	t.Run("test dropped terms", func(t *testing.T) {
+		reqBytes, err := json.Marshal(&CanonicalizeCredentialRequest{Credential: []byte(canonicalizedVC)})
+		require.NoError(t, err)
+
+		rr := serveHTTP(t, handler.Handle(), http.MethodPost, canonicalizeCredentialEndpoint, reqBytes)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		diagnosis := &canonical.Diagnosis{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), diagnosis))
+		require.Contains(t, diagnosis.Document, `<http://schema.org/name> "Jayden Doe"`)
+		require.Equal(t, []*canonical.DroppedTerm{{Path: "/credentialSubject/degree", Term: "degree"}},
+			diagnosis.DroppedTerms)
+		require.Empty(t, diagnosis.InvalidStatements)
+	})
+
+	t.Run("test invalid request", func(t *testing.T) {
+		rr := serveHTTP(t, handler.Handle(), http.MethodPost, canonicalizeCredentialEndpoint, []byte("{"))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("test missing credential", func(t *testing.T) {
+		rr := serveHTTP(t, handler.Handle(), http.MethodPost, canonicalizeCredentialEndpoint, []byte("{}"))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "credential is required")
+	})
+
+	t.Run("test credential not normalized", func(t *testing.T) {
+		rr := serveHTTP(t, handler.Handle(), http.MethodPost, canonicalizeCredentialEndpoint,
+			[]byte(`{"credential": {"@context": {"@version": 2}}}`))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to normalize credential")
+	})
+}
+
+func TestCompareCredentialsHandler(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		StoreProvider:      memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		Crypto:             &cryptomock.Crypto{},
+	})
+	require.NoError(t, err)
+
+	op.documentLoader = verifiable.CachingJSONLDLoader()
+
+	handler := getHandler(t, op, compareCredentialsEndpoint, http.MethodPost)
+
+	compare := func(t *testing.T, left, right string) *httptest.ResponseRecorder {
+		reqBytes, err := json.Marshal(&CompareCredentialsRequest{Left: []byte(left), Right: []byte(right)})
+		require.NoError(t, err)
+
+		return serveHTTP(t, handler.Handle(), http.MethodPost, compareCredentialsEndpoint, reqBytes)
+	}
+
+	t.Run("test tampered credential", func(t *testing.T) {
+		rr := compare(t, canonicalizedVC, strings.Replace(canonicalizedVC, "Jayden Doe", "John Doe", 1))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		comparison := &canonical.Comparison{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), comparison))
+		require.False(t, comparison.Equivalent)
+		require.Equal(t, []*canonical.Difference{{Path: "/credentialSubject/name", Kind: canonical.Changed,
+			Left: "Jayden Doe", Right: "John Doe"}}, comparison.Differences)
+	})
+
+	t.Run("test equivalent credentials", func(t *testing.T) {
+		rr := compare(t, canonicalizedVC, strings.Replace(canonicalizedVC, "Bachelor of Science", "BSc", 1))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), `"equivalent":true`)
+		require.Contains(t, rr.Body.String(), `"path":"/credentialSubject/degree"`)
+	})
+
+	t.Run("test invalid request", func(t *testing.T) {
+		rr := serveHTTP(t, handler.Handle(), http.MethodPost, compareCredentialsEndpoint, []byte("{"))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("test missing credentials", func(t *testing.T) {
+		rr := serveHTTP(t, handler.Handle(), http.MethodPost, compareCredentialsEndpoint, []byte("{}"))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "left credential is required")
+		require.Contains(t, rr.Body.String(), "right credential is required")
+	})
+
+	t.Run("test credential not normalized", func(t *testing.T) {
+		rr := compare(t, canonicalizedVC, `{"@context": {"@version": 2}}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "right credential: failed to normalize credential")
+	})
+}
+
+func TestInconsistentVCsDetails(t *testing.T) {
+	signed := strings.Replace(canonicalizedVC, `"issuanceDate"`, `"proof": {"jws": "abc"}, "issuanceDate"`, 1)
+	tampered := strings.Replace(signed, "Jayden Doe", "John Doe", 1)
+
+	require.Equal(t, "the VCs differ at /credentialSubject/name",
+		inconsistentVCsDetails([]byte(canonicalizedVC), []byte(tampered)))
+	require.Equal(t, "the VCs differ in their proofs only",
+		inconsistentVCsDetails([]byte(canonicalizedVC), []byte(signed)))
+	require.Empty(t, inconsistentVCsDetails([]byte(`"Hello World!"`), []byte(`"Howdy World!"`)))
+}
+
+func TestStrictJSONLD(t *testing.T) {
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		StoreProvider:      memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		Crypto:             &cryptomock.Crypto{},
+		VDRI: &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+				return createDIDDocWithKeyID(didID, "key1", pubKey), nil
+			}},
+	})
+	require.NoError(t, err)
+
+	// the status context added to the issued credentials isn't loaded remotely
+	loader := verifiable.CachingJSONLDLoader()
+	loader.AddDocument(cslstatus.Context, map[string]interface{}{"@context": map[string]interface{}{}})
+
+	op.documentLoader = loader
+	op.vcStatusManager = &mockVCStatusManager{createStatusIDErr: errors.New("status not allocated")}
+
+	profile := getTestProfile()
+	profile.OverwriteIssuer = true
+	profile.StrictJSONLD = true
+	require.NoError(t, op.profileStore.SaveProfile(profile))
+
+	t.Run("test undefined terms rejected before the status is allocated", func(t *testing.T) {
+		_, err := op.IssueCredential(profile.Name, &IssueCredentialRequest{Credential: []byte(canonicalizedVC)})
+		require.Error(t, err)
+
+		var opErr *commhttp.Error
+		require.True(t, errors.As(err, &opErr))
+		require.Equal(t, http.StatusBadRequest, opErr.Status)
+		require.Equal(t, commhttp.InvalidCredential, opErr.Code)
+		require.Equal(t, "profile test requires the terms of the credential to be defined by its contexts: "+
+			"undefined terms degree at /credentialSubject/degree", err.Error())
+	})
+
+	t.Run("test defined terms", func(t *testing.T) {
+		definedVC := strings.Replace(canonicalizedVC, `{"name": "http://schema.org/name"}`,
+			`{"name": "http://schema.org/name", "degree": "http://schema.org/degree"}`, 1)
+
+		_, err := op.IssueCredential(profile.Name, &IssueCredentialRequest{Credential: []byte(definedVC)})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status not allocated")
+	})
+
+	t.Run("test profile not strict", func(t *testing.T) {
+		lenient := *profile
+		lenient.Name = "lenient"
+		lenient.StrictJSONLD = false
+		require.NoError(t, op.profileStore.SaveProfile(&lenient))
+
+		_, err := op.IssueCredential(lenient.Name, &IssueCredentialRequest{Credential: []byte(canonicalizedVC)})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status not allocated")
+	})
+
+	t.Run("test dry run", func(t *testing.T) {
+		_, err := op.dryRunCredential(profile, &IssueCredentialRequest{Credential: []byte(canonicalizedVC)})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "undefined terms degree at /credentialSubject/degree")
+	})
+
+	t.Run("test invalid statements", func(t *testing.T) {
+		vc := &verifiable.Credential{
+			Context: []string{"https://www.w3.org/2018/credentials/v1"},
+			ID:      "http://example.edu/credentials/1872",
+			Types:   []string{"VerifiableCredential", "UniversityDegreeCredential"},
+			Subject: "did:example:ebfeb1f712ebc6f1c276e12ec21",
+			Issuer:  verifiable.Issuer{ID: "did:example:76e12ec712ebc6f1c221ebfeb1f"},
+		}
+
+		err := op.checkStrictJSONLD(profile, vc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "requires the credential to be valid RDF")
+		require.Contains(t, err.Error(), "<UniversityDegreeCredential>")
+	})
+}
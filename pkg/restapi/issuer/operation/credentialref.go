@@ -0,0 +1,70 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/trustbloc/edv/pkg/restapi/messages"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/restapi/internal/common/credentialref"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+	"github.com/trustbloc/edge-service/pkg/restapi/internal/common/vcutil"
+)
+
+// resolveCredential returns the credential of the request, or the credential it references
+func (o *Operation) resolveCredential(profile *vcprofile.DataProfile, cred *IssueCredentialRequest) ([]byte, error) {
+	switch {
+	case cred.CredentialRef == nil:
+		return cred.Credential, nil
+	case cred.CredentialRef.URL != "":
+		return credentialref.Fetch(o.credentialRefClient, o.credentialRefURLs, cred.CredentialRef.URL)
+	default:
+		return o.storedCredential(profile, cred.CredentialRef.ID)
+	}
+}
+
+// storedCredential returns the latest copy of the credential stored under the profile
+func (o *Operation) storedCredential(profile *vcprofile.DataProfile, id string) ([]byte, error) {
+	edvClient, err := o.profileEDVClient(profile)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError, err.Error())
+	}
+
+	docURLs, err := o.queryVault(edvClient, profile.Name, id)
+	if err != nil && err != errNoDocsMatchQuery && !strings.Contains(err.Error(), messages.ErrVaultNotFound.Error()) {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError,
+			fmt.Sprintf("failed to query the referenced credential: %s", err.Error()))
+	}
+
+	if len(docURLs) == 0 {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.NotFound,
+			fmt.Sprintf("referenced credential %s not found under profile %s", id, profile.Name))
+	}
+
+	docIDs := make([]string, len(docURLs))
+
+	for i, docURL := range docURLs {
+		docIDs[i] = vcutil.GetDocIDFromURL(docURL)
+	}
+
+	documents, err := edvClient.ReadDocuments(profile.Name, docIDs)
+	if err != nil || len(documents) == 0 {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError,
+			fmt.Sprintf("failed to read the referenced credential %s: %v", id, err))
+	}
+
+	vcBytes, err := o.decryptVC(profile.Name, latestDocument(documents), "retrieving referenced VC")
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError, err.Error())
+	}
+
+	return vcBytes, nil
+}
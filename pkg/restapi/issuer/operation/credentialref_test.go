@@ -0,0 +1,137 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/utils/retry"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/restapi/internal/common/credentialref"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+func TestResolveCredential(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	template := strings.Replace(validVCWithoutStatus, "vc without status", "template", 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/vc/degree.json":
+			_, err := rw.Write([]byte(template))
+			require.NoError(t, err)
+		case "/vc/redirect.json":
+			http.Redirect(rw, req, "/other/degree.json", http.StatusFound)
+		case "/vc/large.json":
+			_, err := rw.Write(bytes.Repeat([]byte(" "), credentialref.MaxSize+1))
+			require.NoError(t, err)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	allowedURL, err := url.Parse(server.URL + "/vc/")
+	require.NoError(t, err)
+
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		Crypto:             &plainMACCrypto{},
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		VDRI:               &vdrimock.MockVDRIRegistry{ResolveValue: createDIDDocWithKeyID("did:test:abc", "key1", nil)},
+		RetryParameters:    &retry.Params{},
+		CredentialStorage:  CredentialStorageLocal,
+		CredentialRefURLs:  []*url.URL{allowedURL}})
+	require.NoError(t, err)
+
+	profile := &vcprofile.DataProfile{Name: "issuer", DID: "did:test:abc", Creator: "did:test:abc#key1"}
+	require.NoError(t, op.profileStore.SaveProfile(profile))
+
+	resolve := func(t *testing.T, ref *CredentialReference) ([]byte, int, string) {
+		vcBytes, err := op.resolveCredential(profile, &IssueCredentialRequest{CredentialRef: ref})
+		if err == nil {
+			return vcBytes, 0, ""
+		}
+
+		httpErr, ok := err.(*commhttp.Error)
+		require.True(t, ok)
+
+		return nil, httpErr.Status, err.Error()
+	}
+
+	t.Run("test inline credential", func(t *testing.T) {
+		vcBytes, err := op.resolveCredential(profile, &IssueCredentialRequest{Credential: []byte(validVC)})
+		require.NoError(t, err)
+		require.Equal(t, validVC, string(vcBytes))
+	})
+
+	t.Run("test stored credential", func(t *testing.T) {
+		_, status, msg := resolve(t, &CredentialReference{ID: "http://example.edu/credentials/1872"})
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, msg, "referenced credential http://example.edu/credentials/1872 not found")
+
+		reqBytes, err := json.Marshal(&StoreVCRequest{Profile: "issuer", Credential: template})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint, bytes.NewBuffer(reqBytes))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		op.storeCredentialHandler(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		vcBytes, _, msg := resolve(t, &CredentialReference{ID: "http://example.edu/credentials/1872"})
+		require.Empty(t, msg)
+		require.Contains(t, string(vcBytes), `"name":"template"`)
+	})
+
+	t.Run("test credential URL", func(t *testing.T) {
+		vcBytes, _, msg := resolve(t, &CredentialReference{URL: server.URL + "/vc/degree.json"})
+		require.Empty(t, msg)
+		require.Equal(t, template, string(vcBytes))
+
+		_, status, msg := resolve(t, &CredentialReference{URL: server.URL + "/other/degree.json"})
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, msg, "is not allowed")
+
+		// the redirects aren't followed
+		_, status, msg = resolve(t, &CredentialReference{URL: server.URL + "/vc/redirect.json"})
+		require.Equal(t, http.StatusInternalServerError, status)
+		require.Contains(t, msg, "failed to fetch the referenced credential: status 302")
+
+		_, status, msg = resolve(t, &CredentialReference{URL: server.URL + "/vc/missing.json"})
+		require.Equal(t, http.StatusInternalServerError, status)
+		require.Contains(t, msg, "status 404")
+
+		_, status, msg = resolve(t, &CredentialReference{URL: server.URL + "/vc/large.json"})
+		require.Equal(t, http.StatusBadRequest, status)
+		require.Contains(t, msg, "referenced credential exceeds")
+	})
+
+	t.Run("test issue credential - credential not found", func(t *testing.T) {
+		_, err := op.IssueCredential("issuer", &IssueCredentialRequest{
+			CredentialRef: &CredentialReference{ID: "http://example.edu/credentials/unknown"}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "referenced credential http://example.edu/credentials/unknown not found")
+	})
+}
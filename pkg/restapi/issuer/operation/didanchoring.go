@@ -0,0 +1,128 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	commondid "github.com/trustbloc/edge-service/pkg/restapi/internal/common/did"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+// didAnchoringPollInterval is the interval of the resolutions of the DID of a new profile, while waiting for it to
+// be anchored
+const didAnchoringPollInterval = time.Second
+
+// DIDStatus swagger:route GET /profile/{id}/did issuer didStatusReq
+//
+// Retrieves the anchoring status of the DID of the profile. The credentials can't be issued under a profile whose
+// DID, created through the Sidetree node or the uni-registrar, isn't resolvable yet.
+//
+// Responses:
+//    default: genericError
+//        200: didStatusRes
+func (o *Operation) didStatusHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.GetProfile(mux.Vars(req)["id"])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	anchored, err := o.refreshDIDAnchoring(profile)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, &DIDStatusResponse{DID: profile.DID, Anchored: anchored})
+}
+
+// createsAnchoredDID returns whether the DID of the profile request is created through the Sidetree node or the
+// uni-registrar, a DID not resolvable until it is anchored. The did:key and did:web DIDs and the existing DIDs are
+// resolvable as soon as the profile is created.
+func createsAnchoredDID(pr *ProfileRequest) bool {
+	if pr.DIDMethod == commondid.MethodKey || pr.DIDMethod == commondid.MethodWeb {
+		return false
+	}
+
+	return pr.UNIRegistrar.DriverURL != "" || pr.DID == ""
+}
+
+// waitForDID resolves the DID until it is resolvable or the timeout elapses, returns whether the DID is resolvable.
+// The DID is resolved once if the timeout isn't set.
+func (o *Operation) waitForDID(didID string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		_, err := o.vdri.Resolve(didID)
+		if err == nil {
+			return true
+		}
+
+		if time.Now().Add(o.didPollInterval).After(deadline) {
+			logger.Infof("DID %s not resolvable yet: %s", didID, err)
+
+			return false
+		}
+
+		time.Sleep(o.didPollInterval)
+	}
+}
+
+// refreshDIDAnchoring resolves the DID of a profile whose DID is pending, the profile is updated once the DID is
+// resolvable. Returns whether the DID is anchored.
+func (o *Operation) refreshDIDAnchoring(profile *vcprofile.DataProfile) (bool, error) {
+	if !profile.DIDPending {
+		return true, nil
+	}
+
+	if !o.waitForDID(profile.DID, 0) {
+		return false, nil
+	}
+
+	profile.DIDPending = false
+
+	// the stored profile is updated rather than the profile of the request, e.g. with the signing key selected
+	stored, err := o.profileStore.GetProfile(profile.Name)
+	if err != nil {
+		return false, commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to get profile: %s", err.Error()))
+	}
+
+	stored.DIDPending = false
+
+	if err := o.profileStore.SaveProfile(stored); err != nil {
+		return false, commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to save profile: %s", err.Error()))
+	}
+
+	return true, nil
+}
+
+// checkDIDAnchored fails the issuance under a profile whose DID isn't resolvable yet, as the verifiers couldn't
+// resolve the key of the issued credentials
+func (o *Operation) checkDIDAnchored(profile *vcprofile.DataProfile) error {
+	anchored, err := o.refreshDIDAnchoring(profile)
+	if err != nil {
+		return err
+	}
+
+	if !anchored {
+		return commhttp.NewError(http.StatusConflict, commhttp.DIDNotAnchored,
+			fmt.Sprintf("the DID %s of profile %s isn't anchored yet, retry once it is resolvable", profile.DID,
+				profile.Name))
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/internal/mock/edv"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+	"github.com/trustbloc/edge-service/pkg/restapi/model"
+)
+
+// anchoringVDRI resolves the DIDs once resolutions attempts have failed, as a DID being anchored
+type anchoringVDRI struct {
+	vdrimock.MockVDRIRegistry
+	failures    int32
+	resolutions int32
+}
+
+func (v *anchoringVDRI) Resolve(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+	if atomic.AddInt32(&v.resolutions, 1) <= atomic.LoadInt32(&v.failures) {
+		return nil, vdriapi.ErrNotFound
+	}
+
+	return &did.Doc{ID: didID}, nil
+}
+
+func newAnchoringOperation(t *testing.T, vdri *anchoringVDRI, timeout time.Duration) *Operation {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider:  mem.NewProvider(),
+		EDVClient:           edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
+		KeyManager:          &mockkms.KeyManager{CreateKeyValue: kh},
+		VDRI:                vdri,
+		Crypto:              &cryptomock.Crypto{},
+		HostURL:             "localhost:8080",
+		DIDAnchoringTimeout: timeout})
+	require.NoError(t, err)
+
+	op.commonDID = &mockCommonDID{createDIDValue: "did:trustbloc:abc", createDIDKeyID: "did:trustbloc:abc#key1"}
+	op.didPollInterval = time.Millisecond
+
+	return op
+}
+
+func TestCreateProfileDIDAnchoring(t *testing.T) {
+	profileRequest := func(t *testing.T) *ProfileRequest {
+		pr := &ProfileRequest{}
+		require.NoError(t, json.Unmarshal([]byte(testIssuerProfile), pr))
+
+		return pr
+	}
+
+	t.Run("test DID resolvable within the timeout", func(t *testing.T) {
+		vdri := &anchoringVDRI{failures: 3}
+		op := newAnchoringOperation(t, vdri, time.Minute)
+
+		profile, err := op.CreateProfile(profileRequest(t))
+		require.NoError(t, err)
+		require.False(t, profile.DIDPending)
+		require.Equal(t, int32(4), atomic.LoadInt32(&vdri.resolutions))
+	})
+
+	t.Run("test DID not resolvable within the timeout", func(t *testing.T) {
+		vdri := &anchoringVDRI{failures: 1000}
+		op := newAnchoringOperation(t, vdri, 20*time.Millisecond)
+
+		profile, err := op.CreateProfile(profileRequest(t))
+		require.NoError(t, err)
+		require.True(t, profile.DIDPending)
+	})
+
+	t.Run("test DID resolved once without timeout", func(t *testing.T) {
+		vdri := &anchoringVDRI{failures: 1}
+		op := newAnchoringOperation(t, vdri, 0)
+
+		profile, err := op.CreateProfile(profileRequest(t))
+		require.NoError(t, err)
+		require.True(t, profile.DIDPending)
+		require.Equal(t, int32(1), atomic.LoadInt32(&vdri.resolutions))
+	})
+
+	t.Run("test DID not created through the Sidetree node", func(t *testing.T) {
+		vdri := &anchoringVDRI{failures: 1000}
+		op := newAnchoringOperation(t, vdri, 0)
+
+		pr := profileRequest(t)
+		pr.DIDMethod = "key"
+
+		profile, err := op.CreateProfile(pr)
+		require.NoError(t, err)
+		require.False(t, profile.DIDPending)
+		require.Equal(t, int32(0), atomic.LoadInt32(&vdri.resolutions))
+	})
+}
+
+func TestCreatesAnchoredDID(t *testing.T) {
+	require.True(t, createsAnchoredDID(&ProfileRequest{}))
+	require.True(t, createsAnchoredDID(&ProfileRequest{DID: "did:example:abc",
+		UNIRegistrar: model.UNIRegistrar{DriverURL: "https://uniregistrar.example.com"}}))
+	require.False(t, createsAnchoredDID(&ProfileRequest{DID: "did:example:abc"}))
+	require.False(t, createsAnchoredDID(&ProfileRequest{DIDMethod: "key"}))
+	require.False(t, createsAnchoredDID(&ProfileRequest{DIDMethod: "web"}))
+}
+
+func TestDIDAnchoringIssuance(t *testing.T) {
+	vdri := &anchoringVDRI{failures: 2}
+	op := newAnchoringOperation(t, vdri, 0)
+
+	profile := getTestProfile()
+	profile.DIDPending = true
+	require.NoError(t, op.profileStore.SaveProfile(profile))
+
+	didStatus := func(t *testing.T) *DIDStatusResponse {
+		handler := getHandler(t, op, updateDIDEndpoint, http.MethodGet)
+
+		rr := serveHTTPMux(t, handler, "/profile/"+profile.Name+"/did", nil, map[string]string{"id": profile.Name})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		resp := &DIDStatusResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+
+		return resp
+	}
+
+	t.Run("test issuance fails until the DID is anchored", func(t *testing.T) {
+		require.Equal(t, &DIDStatusResponse{DID: profile.DID}, didStatus(t))
+
+		_, err := op.IssueCredential(profile.Name, &IssueCredentialRequest{Credential: []byte(validVCWithoutStatus)})
+		require.Error(t, err)
+
+		var opErr *commhttp.Error
+		require.True(t, errors.As(err, &opErr))
+		require.Equal(t, http.StatusConflict, opErr.Status)
+		require.Equal(t, commhttp.DIDNotAnchored, opErr.Code)
+		require.Contains(t, err.Error(), "the DID did:test:abc of profile test isn't anchored yet")
+	})
+
+	t.Run("test profile updated once the DID is anchored", func(t *testing.T) {
+		require.Equal(t, &DIDStatusResponse{DID: profile.DID, Anchored: true}, didStatus(t))
+
+		stored, err := op.profileStore.GetProfile(profile.Name)
+		require.NoError(t, err)
+		require.False(t, stored.DIDPending)
+
+		// no longer resolved
+		resolutions := atomic.LoadInt32(&vdri.resolutions)
+		require.NoError(t, op.checkDIDAnchored(stored))
+		require.Equal(t, resolutions, atomic.LoadInt32(&vdri.resolutions))
+	})
+
+	t.Run("test error from profile store", func(t *testing.T) {
+		_, err := op.refreshDIDAnchoring(&vcprofile.DataProfile{Name: "missing", DID: "did:test:abc",
+			DIDPending: true})
+		require.Error(t, err)
+		require.True(t, strings.HasPrefix(err.Error(), "failed to get profile"))
+	})
+
+	t.Run("test status of a missing profile", func(t *testing.T) {
+		handler := getHandler(t, op, updateDIDEndpoint, http.MethodGet)
+
+		rr := serveHTTPMux(t, handler, "/profile/missing/did", nil, map[string]string{"id": "missing"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), string(commhttp.StorageError))
+	})
+}
@@ -0,0 +1,179 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/quota"
+	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+func TestIssueCredentialDryRun(t *testing.T) {
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		StoreProvider:      memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		Crypto:             &cryptomock.Crypto{SignErr: errors.New("not signed by a dry run")},
+		VDRI: &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+				return createDIDDocWithKeyID(didID, "key1", pubKey), nil
+			}},
+	})
+	require.NoError(t, err)
+
+	// no status is allocated by a dry run
+	op.vcStatusManager = &mockVCStatusManager{createStatusIDErr: errors.New("not allocated by a dry run")}
+
+	profile := getTestProfile()
+	profile.OverwriteIssuer = true
+	profile.Quota = &quota.Quota{Daily: 1, Monthly: 1}
+	require.NoError(t, op.profileStore.SaveProfile(profile))
+
+	handler := getHandler(t, op, issueCredentialPath, http.MethodPost)
+
+	dryRun := func(t *testing.T, profileName string, req *IssueCredentialRequest) (int, []byte) {
+		if req.Opts == nil {
+			req.Opts = &IssueCredentialOptions{}
+		}
+
+		req.Opts.DryRun = true
+
+		reqBytes, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, "/"+profileName+"/credentials/issueCredential", reqBytes,
+			map[string]string{profileIDPathParam: profileName})
+
+		return rr.Code, rr.Body.Bytes()
+	}
+
+	t.Run("test credential which would be issued", func(t *testing.T) {
+		// the quota isn't reserved by the dry runs
+		for i := 0; i < 2; i++ {
+			code, body := dryRun(t, profile.Name, &IssueCredentialRequest{Credential: []byte(validVCWithoutStatus)})
+			require.Equal(t, http.StatusOK, code, string(body))
+
+			resp, vc := parseDryRunResponse(t, body)
+			require.Equal(t, CredentialFormatLDP, resp.Format)
+			require.True(t, resp.CredentialStatus)
+			require.False(t, resp.Anchored)
+			require.Equal(t, profile.DID, vc.Issuer.ID)
+			require.Contains(t, vc.Context, cslstatus.Context)
+			require.Nil(t, vc.Status)
+			require.Empty(t, vc.Proofs)
+		}
+	})
+
+	t.Run("test jwt credential format", func(t *testing.T) {
+		jwtProfile := *profile
+		jwtProfile.Name = "jwt"
+		jwtProfile.CredentialFormat = CredentialFormatJWT
+		jwtProfile.DisableVCStatus = true
+		require.NoError(t, op.profileStore.SaveProfile(&jwtProfile))
+
+		code, body := dryRun(t, jwtProfile.Name, &IssueCredentialRequest{Credential: []byte(validVCWithoutStatus)})
+		require.Equal(t, http.StatusOK, code, string(body))
+
+		resp, vc := parseDryRunResponse(t, body)
+		require.Equal(t, CredentialFormatJWT, resp.Format)
+		require.False(t, resp.CredentialStatus)
+		require.NotContains(t, vc.Context, cslstatus.Context)
+	})
+
+	t.Run("test policy violation", func(t *testing.T) {
+		policyProfile := *profile
+		policyProfile.Name = "policy"
+		policyProfile.Policy = []policy.Rule{{Type: policy.RequiredTypes,
+			Values: []string{"UniversityDegreeCredential"}}}
+		require.NoError(t, op.profileStore.SaveProfile(&policyProfile))
+
+		code, body := dryRun(t, policyProfile.Name, &IssueCredentialRequest{Credential: []byte(validVCWithoutStatus)})
+		require.Equal(t, http.StatusForbidden, code)
+
+		errResp := &commhttp.ErrorResponse{}
+		require.NoError(t, json.Unmarshal(body, errResp))
+		require.Equal(t, commhttp.PolicyViolation, errResp.Code)
+	})
+
+	t.Run("test invalid credential", func(t *testing.T) {
+		code, body := dryRun(t, profile.Name, &IssueCredentialRequest{Credential: []byte(`{"id":"invalid"}`)})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "failed to validate credential")
+	})
+
+	t.Run("test missing holder binding", func(t *testing.T) {
+		bindingProfile := *profile
+		bindingProfile.Name = "binding"
+		bindingProfile.RequireHolderBinding = true
+		require.NoError(t, op.profileStore.SaveProfile(&bindingProfile))
+
+		code, body := dryRun(t, bindingProfile.Name, &IssueCredentialRequest{Credential: []byte(validVCWithoutStatus)})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "requires the holder binding of the credential subject")
+	})
+
+	t.Run("test anchoring not supported", func(t *testing.T) {
+		anchorProfile := *profile
+		anchorProfile.Name = "anchor"
+		anchorProfile.AnchorCredentials = true
+		require.NoError(t, op.profileStore.SaveProfile(&anchorProfile))
+
+		code, body := dryRun(t, anchorProfile.Name, &IssueCredentialRequest{Credential: []byte(validVCWithoutStatus)})
+		require.Equal(t, http.StatusInternalServerError, code)
+		require.Contains(t, string(body), "no time-stamping authority")
+	})
+
+	t.Run("test dry run only supported by issueCredential", func(t *testing.T) {
+		_, err := op.IssueCredential(profile.Name, &IssueCredentialRequest{Credential: []byte(validVCWithoutStatus),
+			Opts: &IssueCredentialOptions{DryRun: true}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "dry run only supported by issueCredential")
+	})
+}
+
+// parseDryRunResponse returns the dry run response and its credential, which is parsed apart as the credential isn't
+// unmarshaled from JSON
+func parseDryRunResponse(t *testing.T, body []byte) (*IssueCredentialDryRunResponse, *verifiable.Credential) {
+	resp := &IssueCredentialDryRunResponse{}
+	require.NoError(t, json.Unmarshal(body, resp))
+
+	raw := struct {
+		Credential json.RawMessage `json:"credential"`
+	}{}
+	require.NoError(t, json.Unmarshal(body, &raw))
+
+	vc, err := verifiable.ParseUnverifiedCredential(raw.Credential)
+	require.NoError(t, err)
+
+	return resp, vc
+}
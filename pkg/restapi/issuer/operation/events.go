@@ -0,0 +1,59 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/history"
+	"github.com/trustbloc/edge-service/pkg/events"
+)
+
+// publishedStatusHistory emits the status changes recorded in the status history, by the status endpoints as well
+// as by the revocation on expiry. The status is already changed when it is recorded, so the change is emitted even
+// if it fails to be recorded.
+type publishedStatusHistory struct {
+	statusHistory
+	events *events.Emitter
+}
+
+// publishStatusChanges returns the status history emitting its status changes, the status history itself if there
+// is no emitter
+func publishStatusChanges(h statusHistory, e *events.Emitter) statusHistory {
+	if e == nil {
+		return h
+	}
+
+	return &publishedStatusHistory{statusHistory: h, events: e}
+}
+
+func (h *publishedStatusHistory) Record(profile, vcID string, change *history.StatusChange) error {
+	err := h.statusHistory.Record(profile, vcID, change)
+
+	h.events.Emit(events.CredentialStatusChanged, profile, &events.StatusChangeData{
+		CredentialID: vcID,
+		Status:       change.Status,
+		StatusReason: change.StatusReason,
+		Actor:        change.Actor,
+	})
+
+	return err
+}
+
+// emitIssuance emits the issuance of the credential under the profile in the credential format
+func (o *Operation) emitIssuance(profile string, credential *verifiable.Credential, format string) {
+	if o.events == nil {
+		return
+	}
+
+	o.events.Emit(events.CredentialIssued, profile, &events.IssuanceData{
+		CredentialID: credential.ID,
+		Types:        credential.Types,
+		Issuer:       credential.Issuer.ID,
+		Format:       format,
+	})
+}
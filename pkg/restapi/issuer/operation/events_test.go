@@ -0,0 +1,130 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/history"
+	"github.com/trustbloc/edge-service/pkg/events"
+	"github.com/trustbloc/edge-service/pkg/internal/mock/edv"
+)
+
+type mockPublisher struct {
+	mutex  sync.Mutex
+	events []*events.Event
+}
+
+func (p *mockPublisher) Publish(_ context.Context, event *events.Event) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.events = append(p.events, event)
+
+	return nil
+}
+
+func (p *mockPublisher) Close() error {
+	return nil
+}
+
+type failingStatusHistory struct {
+	statusHistory
+}
+
+func (h *failingStatusHistory) Record(string, string, *history.StatusChange) error {
+	return errors.New("record error")
+}
+
+func TestStatusChangeEvents(t *testing.T) {
+	s := make(map[string][]byte)
+	s["profile_issuer_Example University"] = []byte(testIssuerProfile)
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	publisher := &mockPublisher{}
+	emitter := events.NewEmitter(publisher, 0)
+
+	op, err := New(&Config{StoreProvider: &mockstore.Provider{Store: &mockstore.MockStore{Store: s}},
+		KMSSecretsProvider: mem.NewProvider(), EDVClient: edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
+		KeyManager: &mockkms.KeyManager{CreateKeyValue: kh}, Crypto: &cryptomock.Crypto{},
+		VDRI: &vdrimock.MockVDRIRegistry{}, HostURL: "localhost:8080", Events: emitter})
+	require.NoError(t, err)
+
+	op.vcStatusManager = &mockVCStatusManager{}
+
+	reqBytes, err := json.Marshal(ChangeCredentialStatusRequest{Credential: validVC, StatusReason: "lost"})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, revokeCredentialEndpoint, bytes.NewBuffer(reqBytes))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	getHandler(t, op, revokeCredentialEndpoint, http.MethodPost).Handle().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// the status change is emitted even if it fails to be recorded
+	op.statusHistory.(*publishedStatusHistory).statusHistory = &failingStatusHistory{}
+
+	err = op.statusHistory.Record("issuer", "http://example.edu/credentials/1872",
+		&history.StatusChange{Status: cslstatus.StatusActive})
+	require.EqualError(t, err, "record error")
+
+	require.NoError(t, emitter.Close())
+	require.Len(t, publisher.events, 2)
+
+	event := publisher.events[0]
+	require.Equal(t, events.CredentialStatusChanged, event.Type)
+	require.Equal(t, "issuer", event.Profile)
+	require.Equal(t, &events.StatusChangeData{CredentialID: "http://example.edu/credentials/1872",
+		Status: cslstatus.StatusRevoked, StatusReason: "lost", Actor: anonymousActor}, event.Data)
+
+	require.Equal(t, cslstatus.StatusActive, publisher.events[1].Data.(*events.StatusChangeData).Status)
+}
+
+func TestEmitIssuance(t *testing.T) {
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(validVC))
+	require.NoError(t, err)
+
+	t.Run("test issuance emitted", func(t *testing.T) {
+		publisher := &mockPublisher{}
+		op := &Operation{events: events.NewEmitter(publisher, 0)}
+
+		op.emitIssuance("issuer", vc, CredentialFormatJWT)
+
+		require.NoError(t, op.events.Close())
+		require.Len(t, publisher.events, 1)
+		require.Equal(t, events.CredentialIssued, publisher.events[0].Type)
+		require.Equal(t, &events.IssuanceData{CredentialID: vc.ID, Types: vc.Types, Issuer: vc.Issuer.ID,
+			Format: CredentialFormatJWT}, publisher.events[0].Data)
+	})
+
+	t.Run("test no emitter", func(t *testing.T) {
+		require.NotPanics(t, func() {
+			(&Operation{}).emitIssuance("issuer", vc, CredentialFormatLDP)
+		})
+	})
+}
@@ -0,0 +1,384 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/binding"
+	vcchallenge "github.com/trustbloc/edge-service/pkg/doc/vc/challenge"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/exchange"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	exchangeIDPathParam = "exchangeID"
+	exchangesPath       = "/{" + profileIDPathParam + "}/exchanges"
+	exchangePath        = exchangesPath + "/{" + exchangeIDPathParam + "}"
+
+	didAuthQueryType       = "DIDAuthentication"
+	interactServiceType    = "UnmediatedHttpPresentationService2021"
+	credentialsV1Context   = "https://www.w3.org/2018/credentials/v1"
+	verifiablePresentation = "VerifiablePresentation"
+)
+
+type exchangeStore interface {
+	Create(profile, manifestID string, credential, options json.RawMessage) (*exchange.Exchange, error)
+	Get(profile, id string) (*exchange.Exchange, error)
+	Update(profile, id string, update func(ex *exchange.Exchange) error) (*exchange.Exchange, error)
+}
+
+// CreateExchange swagger:route POST /{profileID}/exchanges issuer createExchangeReq
+//
+// Creates an exchange issuing the credential under the profile once the holder authenticates its DID. The holder
+// interacts with the exchange at its interact URL to get the presentation request, then continues it with its
+// DIDAuth presentation to get the credential. The credential offered is described by the credential manifest of
+// the manifest ID, referenced by its URL.
+//
+// Responses:
+//    default: genericError
+//        201: exchangeRes
+func (o *Operation) createExchangeHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	request := CreateExchangeRequest{}
+
+	if err = commhttp.DecodeJSON(req, &request); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	ex, err := o.createExchange(profile, &request)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, o.exchangeResponse(ex))
+}
+
+// createExchange resolves the credential of the exchange, so a credential referenced by ID or URL is the one of
+// the exchange creation, and stores the exchange
+func (o *Operation) createExchange(profile *vcprofile.DataProfile,
+	request *CreateExchangeRequest) (*exchange.Exchange, error) {
+	if err := validateIssueCredentialRequest(&request.IssueCredentialRequest); err != nil {
+		return nil, commhttp.NewValidationError(err)
+	}
+
+	if err := o.validateExchangeManifest(profile, request.ManifestID); err != nil {
+		return nil, err
+	}
+
+	if request.Opts != nil && request.Opts.HolderBinding != nil {
+		validationErr := &commhttp.ValidationError{}
+		validationErr.Add("/options/holderBinding", "the holder binding of an exchange is its DIDAuth presentation")
+
+		return nil, commhttp.NewValidationError(validationErr)
+	}
+
+	credential, err := o.resolveCredential(profile, &request.IssueCredentialRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = verifiable.ParseCredential(credential, verifiable.WithDisabledProofCheck()); err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("failed to validate credential: %s", err.Error()))
+	}
+
+	var options json.RawMessage
+
+	if request.Opts != nil {
+		if options, err = json.Marshal(request.Opts); err != nil {
+			return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError, err.Error())
+		}
+	}
+
+	ex, err := o.exchanges.Create(profile.Name, request.ManifestID, credential, options)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError, err.Error())
+	}
+
+	return ex, nil
+}
+
+// validateExchangeManifest checks the profile has the manifest of the exchange, if set
+func (o *Operation) validateExchangeManifest(profile *vcprofile.DataProfile, manifestID string) error {
+	if manifestID == "" {
+		return nil
+	}
+
+	_, err := o.manifests.Get(profile.Name, manifestID)
+	if errors.Is(err, storage.ErrValueNotFound) {
+		validationErr := &commhttp.ValidationError{}
+		validationErr.Add("/manifestID", fmt.Sprintf("manifest not found: %s", manifestID))
+
+		return commhttp.NewValidationError(validationErr)
+	}
+
+	if err != nil {
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError, err.Error())
+	}
+
+	return nil
+}
+
+// GetExchange swagger:route GET /{profileID}/exchanges/{exchangeID} issuer getExchangeReq
+//
+// Returns the exchange, with the DID of the holder and the issued credential once complete.
+//
+// Responses:
+//    default: genericError
+//        200: exchangeRes
+func (o *Operation) getExchangeHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	ex, err := o.exchanges.Get(profile.Name, mux.Vars(req)[exchangeIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, exchangeError(mux.Vars(req)[exchangeIDPathParam], err))
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, o.exchangeResponse(ex))
+}
+
+// InteractExchange swagger:route POST /{profileID}/exchanges/{exchangeID} issuer interactExchangeReq
+//
+// Interacts with the exchange. Without presentation, returns the presentation request of the exchange: a DIDAuth
+// with a new challenge. With the DIDAuth presentation of the holder, signed with the challenge of the last
+// presentation request, completes the exchange: the credential is issued to the DID of the holder and returned in
+// a presentation.
+//
+// Responses:
+//    default: genericError
+//        200: interactExchangeRes
+func (o *Operation) interactExchangeHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	request := InteractExchangeRequest{}
+
+	if err = commhttp.DecodeJSON(req, &request); err != nil && !commhttp.IsEmptyBody(err) {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	id := mux.Vars(req)[exchangeIDPathParam]
+
+	var response *InteractExchangeResponse
+
+	if len(request.VerifiablePresentation) == 0 {
+		response, err = o.presentationRequest(profile, id)
+	} else {
+		response, err = o.completeExchange(profile, id, request.VerifiablePresentation)
+	}
+
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, response)
+}
+
+// presentationRequest returns the DIDAuth presentation request of the exchange, with a holder binding challenge of
+// the profile which becomes the challenge of the exchange
+func (o *Operation) presentationRequest(profile *vcprofile.DataProfile, id string) (*InteractExchangeResponse, error) {
+	var challenge *vcchallenge.Challenge
+
+	ex, err := o.exchanges.Update(profile.Name, id, func(ex *exchange.Exchange) error {
+		var err error
+
+		challenge, err = o.challenges.Create(profile.Name)
+		if err != nil {
+			return commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError, err.Error())
+		}
+
+		ex.State = exchange.StateActive
+		ex.Challenge = challenge.Challenge
+
+		return nil
+	})
+	if err != nil {
+		return nil, exchangeError(id, err)
+	}
+
+	return &InteractExchangeResponse{VerifiablePresentationRequest: &VerifiablePresentationRequest{
+		Query:     []PresentationQuery{{Type: didAuthQueryType}},
+		Challenge: challenge.Challenge,
+		Interact: &PresentationInteract{Service: []InteractService{{
+			Type:            interactServiceType,
+			ServiceEndpoint: o.interactURL(profile.Name, id),
+		}}},
+	}, ManifestURL: o.manifestURL(ex)}, nil
+}
+
+// completeExchange issues the credential of the exchange to the DID authenticated by the presentation, which is the
+// holder binding of the credential
+func (o *Operation) completeExchange(profile *vcprofile.DataProfile, id string,
+	vp json.RawMessage) (*InteractExchangeResponse, error) {
+	auth, err := binding.VerifyDIDAuth(vp, o.vdri)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("invalid DIDAuth presentation: %s", err.Error()))
+	}
+
+	var issued interface{}
+
+	_, err = o.exchanges.Update(profile.Name, id, func(ex *exchange.Exchange) error {
+		if ex.Challenge == "" || auth.Challenge != ex.Challenge {
+			return commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+				"the presentation isn't signed with the challenge of the exchange")
+		}
+
+		var err error
+
+		issued, err = o.issueExchangeCredential(profile, ex, auth.DID, vp)
+		if err != nil {
+			return err
+		}
+
+		if ex.IssuedCredential, err = json.Marshal(issued); err != nil {
+			return commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError, err.Error())
+		}
+
+		ex.State = exchange.StateComplete
+		ex.Holder = auth.DID
+
+		return nil
+	})
+	if err != nil {
+		return nil, exchangeError(id, err)
+	}
+
+	return &InteractExchangeResponse{VerifiablePresentation: map[string]interface{}{
+		"@context":             []string{credentialsV1Context},
+		"type":                 []string{verifiablePresentation},
+		"verifiableCredential": []interface{}{issued},
+	}}, nil
+}
+
+// issueExchangeCredential issues the credential of the exchange to the holder, with the presentation as its holder
+// binding. The JWT is returned for the jwt credential format.
+func (o *Operation) issueExchangeCredential(profile *vcprofile.DataProfile, ex *exchange.Exchange, holder string,
+	vp json.RawMessage) (interface{}, error) {
+	credential, err := bindCredential(ex.Credential, holder)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidCredential, err.Error())
+	}
+
+	opts := &IssueCredentialOptions{}
+
+	if len(ex.Options) > 0 {
+		if err = json.Unmarshal(ex.Options, opts); err != nil {
+			return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+				fmt.Sprintf("invalid exchange options: %s", err.Error()))
+		}
+	}
+
+	opts.HolderBinding = &HolderBinding{Presentation: vp}
+
+	signedVC, jwtVC, err := o.issueCredentialAs(profile,
+		&IssueCredentialRequest{Credential: credential, Opts: opts}, profile.CredentialFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	if jwtVC != "" {
+		return jwtVC, nil
+	}
+
+	return signedVC, nil
+}
+
+// bindCredential sets the ID of the credential subject to the holder DID and the ID of the credential to a UUID
+// URN, unless they are set, as required by the holder binding
+func bindCredential(credential json.RawMessage, holder string) (json.RawMessage, error) {
+	doc := map[string]interface{}{}
+
+	if err := json.Unmarshal(credential, &doc); err != nil {
+		return nil, fmt.Errorf("invalid credential of the exchange: %w", err)
+	}
+
+	if _, ok := doc["id"]; !ok {
+		doc["id"] = "urn:uuid:" + uuid.New().String()
+	}
+
+	// the holder binding fails for the credentials without a single subject
+	if subject, ok := doc["credentialSubject"].(map[string]interface{}); ok {
+		if _, ok := subject["id"]; !ok {
+			subject["id"] = holder
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+func (o *Operation) interactURL(profile, id string) string {
+	return o.HostURL + "/" + url.PathEscape(profile) + "/exchanges/" + url.PathEscape(id)
+}
+
+// manifestURL returns the URL the wallets get the manifest of the credential of the exchange at, if it has one
+func (o *Operation) manifestURL(ex *exchange.Exchange) string {
+	if ex.ManifestID == "" {
+		return ""
+	}
+
+	return o.HostURL + "/" + url.PathEscape(ex.Profile) + "/manifests/" + url.PathEscape(ex.ManifestID)
+}
+
+func (o *Operation) exchangeResponse(ex *exchange.Exchange) *ExchangeResponse {
+	return &ExchangeResponse{Exchange: ex, InteractURL: o.interactURL(ex.Profile, ex.ID), ManifestURL: o.manifestURL(ex)}
+}
+
+// exchangeError returns the error of the exchange store as an operation error
+func exchangeError(id string, err error) error {
+	switch {
+	case errors.Is(err, exchange.ErrNotFound):
+		return commhttp.NewError(http.StatusNotFound, commhttp.NotFound, fmt.Sprintf("exchange %s not found", id))
+	case errors.Is(err, exchange.ErrClosed):
+		return commhttp.NewError(http.StatusConflict, commhttp.Conflict,
+			fmt.Sprintf("exchange %s is complete or expired", id))
+	}
+
+	var opErr *commhttp.Error
+	if errors.As(err, &opErr) {
+		return opErr
+	}
+
+	return commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError, err.Error())
+}
@@ -0,0 +1,330 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/exchange"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/manifest"
+)
+
+const vcWithoutSubjectID = `{` + validContext + `,
+  "type": "VerifiableCredential",
+  "credentialSubject": {
+	"degree": "BachelorDegree"
+  },
+  "issuer": "did:example:76e12ec712ebc6f1c221ebfeb1f",
+  "issuanceDate": "2010-01-01T19:23:24Z"
+}`
+
+func TestExchange(t *testing.T) {
+	const holder = "did:example:holder"
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		StoreProvider:      memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		Crypto:             &cryptomock.Crypto{},
+		HostURL:            "https://issuer.example.com",
+		VDRI: &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+				return createDIDDocWithKeyID(didID, "key-1", pubKey), nil
+			}},
+	})
+	require.NoError(t, err)
+
+	profile := getTestProfile()
+	profile.Creator = "did:test:abc#key-1"
+	profile.DisableVCStatus = true
+	require.NoError(t, op.profileStore.SaveProfile(profile))
+
+	createExchange := func(t *testing.T, body string) *ExchangeResponse {
+		rr := serveHTTPMux(t, getHandler(t, op, exchangesPath, http.MethodPost), "/test/exchanges",
+			[]byte(body), map[string]string{profileIDPathParam: profile.Name})
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		response := &ExchangeResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), response))
+
+		return response
+	}
+
+	interact := func(t *testing.T, id, body string) (int, []byte) {
+		rr := serveHTTPMux(t, getHandler(t, op, exchangePath, http.MethodPost), "/test/exchanges/"+id,
+			[]byte(body), map[string]string{profileIDPathParam: profile.Name, exchangeIDPathParam: id})
+
+		return rr.Code, rr.Body.Bytes()
+	}
+
+	getExchange := func(t *testing.T, id string) *ExchangeResponse {
+		rr := serveHTTPMux(t, getHandler(t, op, exchangePath, http.MethodGet), "/test/exchanges/"+id, nil,
+			map[string]string{profileIDPathParam: profile.Name, exchangeIDPathParam: id})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		response := &ExchangeResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), response))
+
+		return response
+	}
+
+	presentationRequest := func(t *testing.T, id string) *VerifiablePresentationRequest {
+		code, body := interact(t, id, "")
+		require.Equal(t, http.StatusOK, code, string(body))
+
+		response := &InteractExchangeResponse{}
+		require.NoError(t, json.Unmarshal(body, response))
+		require.NotNil(t, response.VerifiablePresentationRequest)
+
+		return response.VerifiablePresentationRequest
+	}
+
+	signedPresentation := func(t *testing.T, challenge string) string {
+		vp := &verifiable.Presentation{Context: []string{"https://www.w3.org/2018/credentials/v1"},
+			Type: []string{"VerifiablePresentation"}, Holder: holder}
+
+		err := vp.AddLinkedDataProof(&verifiable.LinkedDataProofContext{
+			SignatureType: vccrypto.Ed25519Signature2018,
+			Suite: ed25519signature2018.New(
+				suite.WithSigner(signature.GetEd25519Signer(privKey, pubKey))),
+			SignatureRepresentation: verifiable.SignatureJWS,
+			VerificationMethod:      holder + "#key-1",
+			Purpose:                 vccrypto.Authentication,
+			Challenge:               challenge,
+		})
+		require.NoError(t, err)
+
+		vpBytes, err := vp.MarshalJSON()
+		require.NoError(t, err)
+
+		return `{"verifiablePresentation":` + string(vpBytes) + `}`
+	}
+
+	t.Run("test exchange created", func(t *testing.T) {
+		ex := createExchange(t, `{"credential":`+vcWithoutSubjectID+`,"options":{"hashlink":true}}`)
+		require.NotEmpty(t, ex.ID)
+		require.Equal(t, exchange.StatePending, ex.State)
+		require.Equal(t, "https://issuer.example.com/test/exchanges/"+ex.ID, ex.InteractURL)
+		require.JSONEq(t, `{"hashlink":true}`, string(ex.Options))
+
+		require.Equal(t, exchange.StatePending, getExchange(t, ex.ID).State)
+		require.Empty(t, ex.ManifestURL)
+	})
+
+	t.Run("test exchange offering the credential of a manifest", func(t *testing.T) {
+		require.NoError(t, op.manifests.Put(profile.Name, &manifest.CredentialManifest{ID: "degree",
+			OutputDescriptors: []manifest.OutputDescriptor{{ID: "bachelor", Schema: []byte(`"https://example.com/degree"`)}}}))
+
+		ex := createExchange(t, `{"credential":`+vcWithoutSubjectID+`,"manifestID":"degree"}`)
+		require.Equal(t, "degree", ex.ManifestID)
+		require.Equal(t, "https://issuer.example.com/test/manifests/degree", ex.ManifestURL)
+		require.Equal(t, ex.ManifestURL, getExchange(t, ex.ID).ManifestURL)
+
+		// the presentation request of the exchange references the manifest of the credential offered
+		code, body := interact(t, ex.ID, "")
+		require.Equal(t, http.StatusOK, code, string(body))
+
+		response := &InteractExchangeResponse{}
+		require.NoError(t, json.Unmarshal(body, response))
+		require.NotNil(t, response.VerifiablePresentationRequest)
+		require.Equal(t, ex.ManifestURL, response.ManifestURL)
+	})
+
+	t.Run("test presentation request", func(t *testing.T) {
+		ex := createExchange(t, `{"credential":`+vcWithoutSubjectID+`}`)
+
+		vpr := presentationRequest(t, ex.ID)
+		require.Equal(t, []PresentationQuery{{Type: didAuthQueryType}}, vpr.Query)
+		require.NotEmpty(t, vpr.Challenge)
+		require.Equal(t, []InteractService{{Type: interactServiceType, ServiceEndpoint: ex.InteractURL}},
+			vpr.Interact.Service)
+
+		ex = getExchange(t, ex.ID)
+		require.Equal(t, exchange.StateActive, ex.State)
+		require.Equal(t, vpr.Challenge, ex.Challenge)
+
+		// a new presentation request replaces the challenge of the exchange
+		code, body := interact(t, ex.ID, `{}`)
+		require.Equal(t, http.StatusOK, code, string(body))
+		require.NotEqual(t, vpr.Challenge, getExchange(t, ex.ID).Challenge)
+	})
+
+	t.Run("test exchange completed", func(t *testing.T) {
+		ex := createExchange(t, `{"credential":`+vcWithoutSubjectID+`}`)
+		vpr := presentationRequest(t, ex.ID)
+
+		code, body := interact(t, ex.ID, signedPresentation(t, vpr.Challenge))
+		require.Equal(t, http.StatusOK, code, string(body))
+
+		response := &InteractExchangeResponse{}
+		require.NoError(t, json.Unmarshal(body, response))
+
+		vpBytes, err := json.Marshal(response.VerifiablePresentation)
+		require.NoError(t, err)
+
+		vp, err := verifiable.ParsePresentation(vpBytes)
+		require.NoError(t, err)
+		require.Len(t, vp.Credentials(), 1)
+
+		ex = getExchange(t, ex.ID)
+		require.Equal(t, exchange.StateComplete, ex.State)
+		require.Equal(t, holder, ex.Holder)
+
+		vc, err := verifiable.ParseUnverifiedCredential(ex.IssuedCredential)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(vc.ID, "urn:uuid:"))
+		require.Equal(t, holder, subjectIDs(vc.Subject)[0])
+
+		// a complete exchange can't be continued
+		code, body = interact(t, ex.ID, "")
+		require.Equal(t, http.StatusConflict, code)
+		require.Contains(t, string(body), "is complete or expired")
+	})
+
+	t.Run("test presentation with another challenge", func(t *testing.T) {
+		ex := createExchange(t, `{"credential":`+vcWithoutSubjectID+`}`)
+
+		challenge, err := op.challenges.Create(profile.Name)
+		require.NoError(t, err)
+
+		code, body := interact(t, ex.ID, signedPresentation(t, challenge.Challenge))
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "the presentation isn't signed with the challenge of the exchange")
+	})
+
+	t.Run("test invalid presentation", func(t *testing.T) {
+		ex := createExchange(t, `{"credential":`+vcWithoutSubjectID+`}`)
+
+		code, body := interact(t, ex.ID, `{"verifiablePresentation":{"@context":`+
+			`["https://www.w3.org/2018/credentials/v1"],"type":"VerifiablePresentation"}}`)
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "invalid DIDAuth presentation")
+
+		code, body = interact(t, ex.ID, `{`)
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "Invalid request")
+
+		require.Equal(t, exchange.StatePending, getExchange(t, ex.ID).State)
+	})
+
+	t.Run("test expired exchange", func(t *testing.T) {
+		exchanges := op.exchanges
+
+		defer func() { op.exchanges = exchanges }()
+
+		store, err := exchange.New(memstore.NewProvider(), -time.Minute)
+		require.NoError(t, err)
+
+		op.exchanges = store
+
+		ex := createExchange(t, `{"credential":`+vcWithoutSubjectID+`}`)
+		require.Equal(t, exchange.StateExpired, getExchange(t, ex.ID).State)
+
+		code, body := interact(t, ex.ID, "")
+		require.Equal(t, http.StatusConflict, code)
+		require.Contains(t, string(body), "exchange "+ex.ID+" is complete or expired")
+	})
+
+	t.Run("test create exchange errors", func(t *testing.T) {
+		handler := getHandler(t, op, exchangesPath, http.MethodPost)
+		vars := map[string]string{profileIDPathParam: profile.Name}
+
+		rr := serveHTTPMux(t, handler, "/test/exchanges", []byte(`{}`), vars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "missing credential")
+
+		rr = serveHTTPMux(t, handler, "/test/exchanges", []byte(`{"credential":`+vcWithoutSubjectID+
+			`,"options":{"holderBinding":{"presentation":{}}}}`), vars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "/options/holderBinding")
+
+		rr = serveHTTPMux(t, handler, "/test/exchanges", []byte(`{"credential":{"id":"invalid"}}`), vars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to validate credential")
+
+		rr = serveHTTPMux(t, handler, "/test/exchanges", []byte(`{"credential":`+vcWithoutSubjectID+
+			`,"manifestID":"unknown"}`), vars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "manifest not found: unknown")
+
+		rr = serveHTTPMux(t, handler, "/test/exchanges", []byte(`{`), vars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = serveHTTPMux(t, handler, "/unknown/exchanges", []byte(`{"credential":`+vcWithoutSubjectID+`}`),
+			map[string]string{profileIDPathParam: "unknown"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid issuer profile")
+	})
+
+	t.Run("test exchange not found", func(t *testing.T) {
+		code, body := interact(t, "unknown", "")
+		require.Equal(t, http.StatusNotFound, code)
+		require.Contains(t, string(body), "exchange unknown not found")
+
+		rr := serveHTTPMux(t, getHandler(t, op, exchangePath, http.MethodGet), "/test/exchanges/unknown", nil,
+			map[string]string{profileIDPathParam: profile.Name, exchangeIDPathParam: "unknown"})
+		require.Equal(t, http.StatusNotFound, rr.Code)
+
+		for _, method := range []string{http.MethodGet, http.MethodPost} {
+			rr = serveHTTPMux(t, getHandler(t, op, exchangePath, method), "/unknown/exchanges/unknown", nil,
+				map[string]string{profileIDPathParam: "unknown", exchangeIDPathParam: "unknown"})
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			require.Contains(t, rr.Body.String(), "invalid issuer profile")
+		}
+	})
+}
+
+func TestBindCredential(t *testing.T) {
+	credential, err := bindCredential([]byte(vcWithoutSubjectID), "did:example:holder")
+	require.NoError(t, err)
+
+	vc, err := verifiable.ParseUnverifiedCredential(credential)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(vc.ID, "urn:uuid:"))
+	require.Equal(t, []string{"did:example:holder"}, subjectIDs(vc.Subject))
+
+	// the IDs set are kept
+	credential, err = bindCredential([]byte(validVCWithoutStatus), "did:example:holder")
+	require.NoError(t, err)
+
+	vc, err = verifiable.ParseUnverifiedCredential(credential)
+	require.NoError(t, err)
+	require.Equal(t, "http://example.edu/credentials/1872", vc.ID)
+	require.Equal(t, []string{"did:example:ebfeb1f712ebc6f1c276e12ec21"}, subjectIDs(vc.Subject))
+
+	_, err = bindCredential([]byte(`[]`), "did:example:holder")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid credential of the exchange")
+}
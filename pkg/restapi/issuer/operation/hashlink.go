@@ -0,0 +1,146 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	hashlinkPathParam = "hashlink"
+	hashlinkPath      = credentialsBasePath + "/hashlink/{" + hashlinkPathParam + "}"
+
+	// hashlinkHeader is the header of the issueCredential response with the hashlink of the issued credential
+	hashlinkHeader = "Hashlink"
+	hashlinkPrefix = "hl:"
+)
+
+// credentialHashlink returns the hashlink of the credential, computed over its JSON with the object keys sorted and
+// no insignificant whitespace: the credential read back from the vault has the same hashlink as the issued one
+func credentialHashlink(vcBytes []byte) (string, error) {
+	digest, err := canonicalDigest(vcBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return hashlink(digest), nil
+}
+
+// canonicalDigest returns the SHA-256 digest of the credential JSON with the object keys sorted and no
+// insignificant whitespace
+func canonicalDigest(vcBytes []byte) ([]byte, error) {
+	var doc interface{}
+
+	if err := json.Unmarshal(vcBytes, &doc); err != nil {
+		return nil, fmt.Errorf("invalid credential JSON: %w", err)
+	}
+
+	canonical, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(canonical)
+
+	return digest[:], nil
+}
+
+// issuedHashlink returns the hashlink of the issued credential, of the JWT for the jwt credential format
+func issuedHashlink(signedVC *verifiable.Credential, jwtVC string) (string, error) {
+	if jwtVC != "" {
+		digest := sha256.Sum256([]byte(jwtVC))
+
+		return hashlink(digest[:]), nil
+	}
+
+	vcBytes, err := json.Marshal(signedVC)
+	if err != nil {
+		return "", err
+	}
+
+	return credentialHashlink(vcBytes)
+}
+
+// CredentialByHashlink swagger:route GET /{profileID}/credentials/hashlink/{hashlink} issuer hashlinkCredentialReq
+//
+// Retrieves the credential stored under the profile having the hashlink, with or without its hl: prefix. The
+// hashlink is the one returned on issuance, computed over the credential JSON with sorted keys and no whitespace.
+//
+// Responses:
+//    default: genericError
+//        200: verifiableCredentialRes
+func (o *Operation) hashlinkCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	link := mux.Vars(req)[hashlinkPathParam]
+	if !strings.HasPrefix(link, hashlinkPrefix) {
+		link = hashlinkPrefix + link
+	}
+
+	vcBytes, err := o.credentialByHashlink(profile.Name, link)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	if _, err = rw.Write(vcBytes); err != nil {
+		logger.Errorf("failed to write credential by hashlink response: %s", err)
+	}
+}
+
+// credentialByHashlink returns the credential stored under the profile with the hashlink, checked against the
+// hashlink of the credential read from the vault
+func (o *Operation) credentialByHashlink(profileName, link string) ([]byte, error) {
+	edvClient, err := o.getEDVClient(profileName)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError, err.Error())
+	}
+
+	value, err := o.computeMACEncoded(link)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError, err.Error())
+	}
+
+	docIDs, err := queryDocIDs(edvClient, profileName,
+		&models.Query{Name: o.vcHashlinkIndexNameEncoded, Value: value}, "resolving hashlink")
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError, err.Error())
+	}
+
+	// the copies of a credential stored several times have the same hashlink
+	for _, docID := range docIDs {
+		vcBytes, err := o.retrieveVC(edvClient, profileName, docID, "resolving hashlink")
+		if err != nil {
+			return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError, err.Error())
+		}
+
+		if storedLink, err := credentialHashlink(vcBytes); err == nil && storedLink == link {
+			return vcBytes, nil
+		}
+	}
+
+	return nil, commhttp.NewError(http.StatusNotFound, commhttp.NotFound,
+		fmt.Sprintf("no credential stored with hashlink %s", link))
+}
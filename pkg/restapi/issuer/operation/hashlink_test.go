@@ -0,0 +1,127 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/utils/retry"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+)
+
+func TestCredentialHashlink(t *testing.T) {
+	link, err := credentialHashlink([]byte(`{"b": 1, "a": {"d": "x", "c": true}}`))
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte(`{"a":{"c":true,"d":"x"},"b":1}`))
+	require.Equal(t, hashlink(digest[:]), link)
+
+	_, err = credentialHashlink([]byte("not json"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid credential JSON")
+
+	link, err = issuedHashlink(nil, "eyJhbGciOiJQUzI1NiJ9.e30.c2ln")
+	require.NoError(t, err)
+
+	digest = sha256.Sum256([]byte("eyJhbGciOiJQUzI1NiJ9.e30.c2ln"))
+	require.Equal(t, hashlink(digest[:]), link)
+}
+
+func TestSetHashlinkHeader(t *testing.T) {
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(validVCWithoutStatus))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	require.NoError(t, setHashlinkHeader(rr, &IssueCredentialRequest{}, vc, ""))
+	require.Empty(t, rr.Header().Get(hashlinkHeader))
+
+	rr = httptest.NewRecorder()
+	require.NoError(t, setHashlinkHeader(rr, &IssueCredentialRequest{Opts: &IssueCredentialOptions{Hashlink: true}},
+		vc, ""))
+
+	vcBytes, err := json.Marshal(vc)
+	require.NoError(t, err)
+
+	expected, err := credentialHashlink(vcBytes)
+	require.NoError(t, err)
+	require.Equal(t, expected, rr.Header().Get(hashlinkHeader))
+}
+
+func TestCredentialByHashlink(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		Crypto:             &plainMACCrypto{},
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		VDRI:               &vdrimock.MockVDRIRegistry{},
+		RetryParameters:    &retry.Params{},
+		CredentialStorage:  CredentialStorageLocal})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "issuer"}))
+
+	handler := getHandler(t, op, hashlinkPath, http.MethodGet)
+
+	resolve := func(t *testing.T, profile, link string) *httptest.ResponseRecorder {
+		return serveHTTPMux(t, handler, "/"+profile+"/credentials/hashlink/"+link, nil,
+			map[string]string{profileIDPathParam: profile, hashlinkPathParam: link})
+	}
+
+	link, err := credentialHashlink([]byte(validVCWithoutStatus))
+	require.NoError(t, err)
+
+	t.Run("test credential not stored", func(t *testing.T) {
+		rr := resolve(t, "issuer", link)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), "no credential stored with hashlink "+link)
+	})
+
+	t.Run("test stored credential", func(t *testing.T) {
+		reqBytes, err := json.Marshal(&StoreVCRequest{Profile: "issuer", Credential: validVCWithoutStatus})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint, bytes.NewBuffer(reqBytes))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		op.storeCredentialHandler(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		rr = resolve(t, "issuer", link)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		require.JSONEq(t, validVCWithoutStatus, rr.Body.String())
+
+		// the hashlink is accepted without its prefix
+		rr = resolve(t, "issuer", strings.TrimPrefix(link, hashlinkPrefix))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.JSONEq(t, validVCWithoutStatus, rr.Body.String())
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		rr := resolve(t, "other", link)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid issuer profile")
+	})
+}
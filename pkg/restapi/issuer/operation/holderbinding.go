@@ -0,0 +1,185 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/binding"
+	vcchallenge "github.com/trustbloc/edge-service/pkg/doc/vc/challenge"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	holderBindingChallengePath = credentialsBasePath + "/holderBinding/challenge"
+	holderBindingPath          = credentialsBasePath + "/{" + credentialIDPathParam + "}/holderBinding"
+)
+
+type holderBindingStore interface {
+	Record(profile, vcID string, evidence *binding.Evidence) error
+	Get(profile, vcID string) (*binding.Evidence, error)
+}
+
+type challengeStore interface {
+	Create(scope string) (*vcchallenge.Challenge, error)
+	Consume(scope, challenge string) error
+	Check(scope, challenge string) error
+}
+
+// BindingChallenge swagger:route POST /{profileID}/credentials/holderBinding/challenge issuer bindingChallengeReq
+//
+// Returns a challenge for the holder binding of a credential issued by the profile, to be signed by its subject.
+//
+// Responses:
+//    default: genericError
+//        201: bindingChallengeRes
+func (o *Operation) holderBindingChallengeHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	challenge, err := o.challenges.Create(profile.Name)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, challenge)
+}
+
+// HolderBinding swagger:route GET /{profileID}/credentials/{credentialID}/holderBinding issuer holderBindingReq
+//
+// Returns the holder binding evidence of a credential issued by the profile. The credential ID is base64url encoded
+// without padding.
+//
+// Responses:
+//    default: genericError
+//        200: holderBindingRes
+func (o *Operation) holderBindingHandler(rw http.ResponseWriter, req *http.Request) {
+	vcID, err := base64.RawURLEncoding.DecodeString(mux.Vars(req)[credentialIDPathParam])
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("invalid credential ID, expected base64url encoding: %s", err.Error()))
+
+		return
+	}
+
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	evidence, err := o.holderBinding.Get(profile.Name, string(vcID))
+	if err != nil {
+		if errors.Is(err, storage.ErrValueNotFound) {
+			commhttp.WriteErrorResponse(rw, http.StatusNotFound, commhttp.NotFound,
+				fmt.Sprintf("no holder binding recorded for credential %s", vcID))
+
+			return
+		}
+
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, evidence)
+}
+
+// checkHolderBinding verifies the proof of possession of the subject DID of the credential, if required by the
+// profile or passed with the options, and returns its evidence. The challenge of the proof is used even if the
+// credential then fails to be issued.
+func (o *Operation) checkHolderBinding(profile *vcprofile.DataProfile, credential *verifiable.Credential,
+	opts *IssueCredentialOptions) (*binding.Evidence, error) {
+	if opts == nil || opts.HolderBinding == nil {
+		if profile.RequireHolderBinding {
+			return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+				fmt.Sprintf("profile %s requires the holder binding of the credential subject", profile.Name))
+		}
+
+		return nil, nil
+	}
+
+	subjects := subjectIDs(credential.Subject)
+	if credential.ID == "" || len(subjects) != 1 {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidCredential,
+			"holder binding requires a credential with an ID and a single subject ID")
+	}
+
+	// the challenge is left for the issuance by a dry run
+	evidence, err := o.verifyHolderBinding(profile, subjects[0], opts.HolderBinding, !opts.DryRun)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("invalid holder binding: %s", err.Error()))
+	}
+
+	return evidence, nil
+}
+
+// verifyHolderBinding verifies the presentation of the holder binding is a DIDAuth of the subject DID, with a
+// challenge issued by the profile, then uses the challenge unless only checked
+func (o *Operation) verifyHolderBinding(profile *vcprofile.DataProfile, subject string,
+	holderBinding *HolderBinding, consume bool) (*binding.Evidence, error) {
+	auth, err := binding.VerifyDIDAuth(holderBinding.Presentation, o.vdri)
+	if err != nil {
+		return nil, err
+	}
+
+	if auth.DID != subject {
+		return nil, fmt.Errorf("the presentation isn't signed by the subject %s", subject)
+	}
+
+	if consume {
+		err = o.challenges.Consume(profile.Name, auth.Challenge)
+	} else {
+		err = o.challenges.Check(profile.Name, auth.Challenge)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &binding.Evidence{
+		Subject:            subject,
+		VerificationMethod: auth.VerificationMethod,
+		Challenge:          auth.Challenge,
+		Domain:             auth.Domain,
+		Presentation:       holderBinding.Presentation,
+		Time:               time.Now().UTC(),
+	}, nil
+}
+
+// recordHolderBinding records the holder binding evidence of the issued credential, if any
+func (o *Operation) recordHolderBinding(profile *vcprofile.DataProfile, vc *verifiable.Credential,
+	evidence *binding.Evidence) error {
+	if evidence == nil {
+		return nil
+	}
+
+	if err := o.holderBinding.Record(profile.Name, vc.ID, evidence); err != nil {
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to record holder binding: %s", err.Error()))
+	}
+
+	return nil
+}
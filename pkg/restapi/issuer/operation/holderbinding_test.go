@@ -0,0 +1,250 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/binding"
+	vcchallenge "github.com/trustbloc/edge-service/pkg/doc/vc/challenge"
+	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+)
+
+func TestHolderBinding(t *testing.T) {
+	const (
+		subject = "did:example:holder"
+		vcID    = "http://example.edu/credentials/1872"
+	)
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		StoreProvider:      memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		Crypto:             &cryptomock.Crypto{},
+		VDRI: &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+				return createDIDDocWithKeyID(didID, "key-1", pubKey), nil
+			}},
+	})
+	require.NoError(t, err)
+
+	profile := getTestProfile()
+	profile.Creator = "did:test:abc#key-1"
+	profile.RequireHolderBinding = true
+	require.NoError(t, op.profileStore.SaveProfile(profile))
+
+	urlVars := map[string]string{profileIDPathParam: profile.Name}
+
+	newCredential := func(t *testing.T) *verifiable.Credential {
+		vc, err := verifiable.ParseUnverifiedCredential([]byte(validVCWithoutStatus))
+		require.NoError(t, err)
+
+		vc.Subject = subject
+
+		return vc
+	}
+
+	signedPresentation := func(t *testing.T, verificationMethod, purpose, challenge string) []byte {
+		vp := &verifiable.Presentation{Context: []string{"https://www.w3.org/2018/credentials/v1"},
+			Type: []string{"VerifiablePresentation"}, Holder: subject}
+
+		err := vp.AddLinkedDataProof(&verifiable.LinkedDataProofContext{
+			SignatureType: vccrypto.Ed25519Signature2018,
+			Suite: ed25519signature2018.New(
+				suite.WithSigner(signature.GetEd25519Signer(privKey, pubKey))),
+			SignatureRepresentation: verifiable.SignatureJWS,
+			VerificationMethod:      verificationMethod,
+			Purpose:                 purpose,
+			Challenge:               challenge,
+			Domain:                  "wallet.example.com",
+		})
+		require.NoError(t, err)
+
+		vpBytes, err := vp.MarshalJSON()
+		require.NoError(t, err)
+
+		return vpBytes
+	}
+
+	newChallenge := func(t *testing.T) string {
+		rr := serveHTTPMux(t, getHandler(t, op, holderBindingChallengePath, http.MethodPost),
+			"/test/credentials/holderBinding/challenge", nil, urlVars)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		challenge := &vcchallenge.Challenge{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), challenge))
+		require.NotEmpty(t, challenge.Challenge)
+
+		return challenge.Challenge
+	}
+
+	t.Run("test holder binding - success", func(t *testing.T) {
+		challenge := newChallenge(t)
+		vp := signedPresentation(t, subject+"#key-1", vccrypto.Authentication, challenge)
+		opts := &IssueCredentialOptions{HolderBinding: &HolderBinding{Presentation: vp}}
+
+		evidence, err := op.checkHolderBinding(profile, newCredential(t), opts)
+		require.NoError(t, err)
+		require.Equal(t, subject, evidence.Subject)
+		require.Equal(t, subject+"#key-1", evidence.VerificationMethod)
+		require.Equal(t, challenge, evidence.Challenge)
+		require.Equal(t, "wallet.example.com", evidence.Domain)
+
+		// the challenge can't be used twice
+		_, err = op.checkHolderBinding(profile, newCredential(t), opts)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), vcchallenge.ErrInvalidChallenge.Error())
+	})
+
+	t.Run("test holder binding - not signed by the subject", func(t *testing.T) {
+		vp := signedPresentation(t, "did:example:other#key-1", vccrypto.Authentication, newChallenge(t))
+
+		_, err := op.checkHolderBinding(profile, newCredential(t),
+			&IssueCredentialOptions{HolderBinding: &HolderBinding{Presentation: vp}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the presentation isn't signed by the subject did:example:holder")
+
+		vp = signedPresentation(t, subject+"#key-1", vccrypto.AssertionMethod, newChallenge(t))
+
+		_, err = op.checkHolderBinding(profile, newCredential(t),
+			&IssueCredentialOptions{HolderBinding: &HolderBinding{Presentation: vp}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "isn't signed by an authentication method of did:example:holder")
+	})
+
+	t.Run("test holder binding - required", func(t *testing.T) {
+		_, err := op.checkHolderBinding(profile, newCredential(t), &IssueCredentialOptions{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "profile test requires the holder binding of the credential subject")
+
+		evidence, err := op.checkHolderBinding(getTestProfile(), newCredential(t), nil)
+		require.NoError(t, err)
+		require.Nil(t, evidence)
+
+		_, err = op.IssueCredential(profile.Name, &IssueCredentialRequest{Credential: []byte(validVCWithoutStatus)})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "requires the holder binding")
+
+		rr := serveHTTPMux(t, getHandler(t, op, composeAndIssueCredentialPath, http.MethodPost),
+			"/test/credentials/composeAndIssueCredential", []byte(`{}`), urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "which can't be composed")
+	})
+
+	t.Run("test holder binding - invalid presentation", func(t *testing.T) {
+		credential := newCredential(t)
+		credential.ID = ""
+
+		_, err := op.checkHolderBinding(profile, credential,
+			&IssueCredentialOptions{HolderBinding: &HolderBinding{Presentation: []byte(`{}`)}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "holder binding requires a credential with an ID and a single subject ID")
+
+		_, err = op.checkHolderBinding(profile, newCredential(t),
+			&IssueCredentialOptions{HolderBinding: &HolderBinding{Presentation: []byte(`{`)}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid holder binding: failed to verify presentation")
+
+		unsigned := `{"@context":["https://www.w3.org/2018/credentials/v1"],"type":"VerifiablePresentation"}`
+
+		_, err = op.checkHolderBinding(profile, newCredential(t),
+			&IssueCredentialOptions{HolderBinding: &HolderBinding{Presentation: []byte(unsigned)}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "embedded proof is missing")
+	})
+
+	t.Run("test holder binding evidence", func(t *testing.T) {
+		handler := getHandler(t, op, holderBindingPath, http.MethodGet)
+		credentialID := base64.RawURLEncoding.EncodeToString([]byte(vcID))
+		vars := map[string]string{profileIDPathParam: profile.Name, credentialIDPathParam: credentialID}
+
+		rr := serveHTTPMux(t, handler, "/test/credentials/"+credentialID+"/holderBinding", nil, vars)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), "no holder binding recorded for credential "+vcID)
+
+		evidence := &binding.Evidence{Subject: subject, VerificationMethod: subject + "#key-1",
+			Challenge: "challenge", Presentation: []byte(`{}`)}
+
+		require.NoError(t, op.recordHolderBinding(profile, newCredential(t), evidence))
+		require.NoError(t, op.recordHolderBinding(profile, newCredential(t), nil))
+
+		rr = serveHTTPMux(t, handler, "/test/credentials/"+credentialID+"/holderBinding", nil, vars)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		recorded := &binding.Evidence{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), recorded))
+		require.Equal(t, evidence, recorded)
+
+		rr = serveHTTPMux(t, handler, "/test/credentials/invalid!/holderBinding", nil,
+			map[string]string{profileIDPathParam: profile.Name, credentialIDPathParam: "invalid!"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid credential ID")
+
+		rr = serveHTTPMux(t, handler, "/unknown/credentials/"+credentialID+"/holderBinding", nil,
+			map[string]string{profileIDPathParam: "unknown", credentialIDPathParam: credentialID})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid issuer profile")
+	})
+
+	t.Run("test holder binding - store errors", func(t *testing.T) {
+		provider := &mockstore.Provider{Store: &mockstore.MockStore{Store: map[string][]byte{},
+			ErrPut: errors.New("put error"), ErrGet: errors.New("get error")}}
+
+		store, err := binding.New(provider)
+		require.NoError(t, err)
+
+		challenges, err := vcchallenge.New(provider, vcchallenge.DefaultTTL)
+		require.NoError(t, err)
+
+		holderBinding, opChallenges := op.holderBinding, op.challenges
+		op.holderBinding, op.challenges = store, challenges
+
+		defer func() { op.holderBinding, op.challenges = holderBinding, opChallenges }()
+
+		rr := serveHTTPMux(t, getHandler(t, op, holderBindingChallengePath, http.MethodPost),
+			"/test/credentials/holderBinding/challenge", nil, urlVars)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "put error")
+
+		err = op.recordHolderBinding(profile, newCredential(t), &binding.Evidence{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to record holder binding")
+
+		rr = serveHTTPMux(t, getHandler(t, op, holderBindingChallengePath, http.MethodPost),
+			"/unknown/credentials/holderBinding/challenge", nil, map[string]string{profileIDPathParam: "unknown"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid issuer profile")
+	})
+}
@@ -0,0 +1,153 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	issueCredentialBatchPath = credentialsBasePath + "/issueCredentialBatch"
+
+	// maxIssueBatchSize is the maximum number of credentials issued by a batch request
+	maxIssueBatchSize = 100
+)
+
+// IssueCredentialBatch swagger:route POST /{id}/credentials/issueCredentialBatch issuer issueCredentialBatchReq
+//
+// Issues a batch of credentials, optionally wrapped into a presentation signed by the profile for their holder.
+//
+// Responses:
+//    default: genericError
+//        201: issueCredentialBatchRes
+func (o *Operation) issueCredentialBatchHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	batchReq := IssueCredentialBatchRequest{}
+
+	err = commhttp.DecodeJSON(req, &batchReq)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	resp, err := o.issueCredentialBatch(profile, &batchReq)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, resp)
+}
+
+// issueCredentialBatch issues the credentials of the batch in order. The batch fails at the first credential failing
+// to be issued, the credentials issued before it aren't returned.
+func (o *Operation) issueCredentialBatch(profile *vcprofile.DataProfile,
+	batchReq *IssueCredentialBatchRequest) (*IssueCredentialBatchResponse, error) {
+	if err := validateIssueCredentialBatchRequest(batchReq); err != nil {
+		return nil, commhttp.NewValidationError(err)
+	}
+
+	credentials := make([]*verifiable.Credential, len(batchReq.Credentials))
+
+	var hashlinks []string
+
+	for i := range batchReq.Credentials {
+		signedVC, err := o.issueCredential(profile, &batchReq.Credentials[i])
+		if err != nil {
+			return nil, batchCredentialError(i, err)
+		}
+
+		credentials[i] = signedVC
+
+		if opts := batchReq.Credentials[i].Opts; opts != nil && opts.Hashlink {
+			if hashlinks == nil {
+				hashlinks = make([]string, len(batchReq.Credentials))
+			}
+
+			hashlinks[i], err = issuedHashlink(signedVC, "")
+			if err != nil {
+				return nil, batchCredentialError(i, err)
+			}
+		}
+	}
+
+	if batchReq.Presentation == nil {
+		return &IssueCredentialBatchResponse{Credentials: credentials, Hashlinks: hashlinks}, nil
+	}
+
+	presentation, err := o.presentCredentials(profile, credentials, batchReq.Presentation)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.SigningError,
+			fmt.Sprintf("failed to sign presentation: %s", err.Error()))
+	}
+
+	return &IssueCredentialBatchResponse{Presentation: presentation, Hashlinks: hashlinks}, nil
+}
+
+// batchCredentialError returns the error of the credential at the index of the batch, with the status of the error
+func batchCredentialError(i int, err error) error {
+	var opErr *commhttp.Error
+	if !errors.As(err, &opErr) {
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("credential at index %d: %s", i, err.Error()))
+	}
+
+	batchErr := *opErr
+	batchErr.Message = fmt.Sprintf("credential at index %d: %s", i, opErr.Message)
+
+	return &batchErr
+}
+
+// presentCredentials wraps the credentials into a presentation for the holder, signed with the current key of the
+// profile for the authentication proof purpose
+func (o *Operation) presentCredentials(profile *vcprofile.DataProfile, credentials []*verifiable.Credential,
+	opts *BatchPresentationOptions) (*verifiable.Presentation, error) {
+	presentation, err := credentials[0].Presentation()
+	if err != nil {
+		return nil, err
+	}
+
+	creds := make([]interface{}, len(credentials))
+	for i, vc := range credentials {
+		creds[i] = vc
+	}
+
+	if err = presentation.SetCredentials(creds...); err != nil {
+		return nil, err
+	}
+
+	presentation.Holder = opts.Holder
+
+	presenter := &vcprofile.HolderProfile{
+		Name:                    profile.Name,
+		DID:                     profile.DID,
+		SignatureType:           profile.SignatureType,
+		SignatureRepresentation: profile.SignatureRepresentation,
+		Creator:                 profile.Creator,
+	}
+
+	return o.crypto.SignPresentation(presenter, presentation,
+		crypto.WithChallenge(opts.Challenge), crypto.WithDomain(opts.Domain))
+}
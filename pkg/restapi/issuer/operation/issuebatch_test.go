@@ -0,0 +1,161 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+func TestIssueCredentialBatch(t *testing.T) {
+	const keyID = "key-1"
+
+	endpoint := "/test/credentials/issueCredentialBatch"
+	profile := getTestProfile()
+	profile.Creator = profile.DID + "#" + keyID
+	profile.DisableVCStatus = true
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		StoreProvider:      memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		KeyManager:         &mockkms.KeyManager{CreateKeyID: keyID, CreateKeyValue: kh},
+		Crypto:             &cryptomock.Crypto{},
+		VDRI: &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+				return createDIDDocWithKeyID(didID, keyID, pubKey), nil
+			}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveProfile(profile))
+
+	urlVars := map[string]string{profileIDPathParam: profile.Name}
+	handler := getHandler(t, op, issueCredentialBatchPath, http.MethodPost)
+
+	t.Run("issue credential batch - success", func(t *testing.T) {
+		reqBytes, err := json.Marshal(&IssueCredentialBatchRequest{Credentials: []IssueCredentialRequest{
+			{Credential: []byte(validVCWithoutStatus)}, {Credential: []byte(validVCWithoutStatus)},
+		}})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		resp := make(map[string][]map[string]interface{})
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Len(t, resp["credentials"], 2)
+		require.NotContains(t, resp, "presentation")
+
+		for _, vc := range resp["credentials"] {
+			require.NotEmpty(t, vc["proof"])
+		}
+	})
+
+	t.Run("issue credential batch - presentation", func(t *testing.T) {
+		reqBytes, err := json.Marshal(&IssueCredentialBatchRequest{
+			Credentials:  []IssueCredentialRequest{{Credential: []byte(validVCWithoutStatus)}},
+			Presentation: &BatchPresentationOptions{Holder: "did:example:holder", Challenge: challenge, Domain: domain},
+		})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		resp := make(map[string]map[string]interface{})
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.NotContains(t, resp, "credentials")
+
+		vp := resp["presentation"]
+		require.Equal(t, "did:example:holder", vp["holder"])
+		require.Len(t, vp["verifiableCredential"], 1)
+
+		proof, ok := vp["proof"].(map[string]interface{})
+		require.True(t, ok)
+		require.Equal(t, challenge, proof["challenge"])
+		require.Equal(t, domain, proof["domain"])
+		require.Equal(t, "authentication", proof["proofPurpose"])
+		require.Equal(t, profile.Creator, proof["verificationMethod"])
+	})
+
+	t.Run("issue credential batch - invalid request", func(t *testing.T) {
+		credentials := make([]IssueCredentialRequest, maxIssueBatchSize+1)
+		for i := range credentials {
+			credentials[i].Credential = []byte(validVCWithoutStatus)
+		}
+
+		credentials[1] = IssueCredentialRequest{}
+
+		reqBytes, err := json.Marshal(&IssueCredentialBatchRequest{Credentials: credentials,
+			Presentation: &BatchPresentationOptions{Holder: "holder"}})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		errResp := &commhttp.ErrorResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), errResp))
+		require.Equal(t, []commhttp.FieldError{
+			{Field: "/credentials", Message: "batch exceeds 100 credentials"},
+			{Field: "/credentials/1/credential", Message: "missing credential"},
+			{Field: "/presentation/holder", Message: "the holder of the presentation must be a DID"},
+		}, errResp.Fields)
+
+		rr = serveHTTPMux(t, handler, endpoint, []byte(`{"credentials":[]}`), urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "missing credentials")
+	})
+
+	t.Run("issue credential batch - invalid credential", func(t *testing.T) {
+		reqBytes, err := json.Marshal(&IssueCredentialBatchRequest{Credentials: []IssueCredentialRequest{
+			{Credential: []byte(`{"id":"invalid"}`)}, {Credential: []byte(validVCWithoutStatus)},
+		}})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "credential at index 0: failed to validate credential")
+	})
+
+	t.Run("issue credential batch - invalid profile", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, "/unknown/credentials/issueCredentialBatch", []byte(`{}`),
+			map[string]string{profileIDPathParam: "unknown"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid issuer profile")
+	})
+
+	t.Run("issue credential batch - error", func(t *testing.T) {
+		err := batchCredentialError(2, errors.New("failure"))
+
+		var opErr *commhttp.Error
+		require.True(t, errors.As(err, &opErr))
+		require.Equal(t, http.StatusInternalServerError, opErr.Status)
+		require.Equal(t, "credential at index 2: failure", opErr.Message)
+	})
+}
@@ -0,0 +1,168 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/mdoc"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	issueMDocPath = credentialsBasePath + "/issueMDoc"
+
+	// mdocFormat is the format of the issuance events of the mdocs
+	mdocFormat = "mdoc"
+)
+
+// IssueMDoc swagger:route POST /{id}/credentials/issueMDoc issuer issueMDocReq
+//
+// Issues a credential as a mobile document (ISO/IEC 18013-5 mdoc), signed with the key of the profile.
+//
+// Responses:
+//    default: genericError
+//        201: issueMDocRes
+func (o *Operation) issueMDocHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	mdocReq := IssueMDocRequest{}
+
+	err = commhttp.DecodeJSON(req, &mdocReq)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	resp, err := o.issueMDoc(profile, &mdocReq)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, resp)
+}
+
+// issueMDoc issues the credential as a mdoc signed by the creator of the profile. The credential is validated and
+// checked against the policy of the profile as the other credentials, the mdocs having no status.
+func (o *Operation) issueMDoc(profile *vcprofile.DataProfile, mdocReq *IssueMDocRequest) (*IssueMDocResponse,
+	error) {
+	if err := validateIssueMDocRequest(mdocReq); err != nil {
+		return nil, commhttp.NewValidationError(err)
+	}
+
+	profile, _, credential, err := o.prepareCredential(profile, &IssueCredentialRequest{Credential: mdocReq.Credential})
+	if err != nil {
+		return nil, err
+	}
+
+	alg, err := o.mdocAlgorithm(profile.Creator)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("the key of the profile can't sign mdocs: %s", err.Error()))
+	}
+
+	release, err := o.reserveQuota(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	issuerSigned, err := mdoc.Issue(credential, &mdoc.Issuance{DocType: mdocReq.DocType, Namespace: mdocReq.Namespace,
+		DeviceKey: mdocReq.DeviceKey, VerificationMethod: profile.Creator, Algorithm: alg,
+		Signer: &mdocSigner{crypto: o.crypto, verificationMethod: profile.Creator}})
+	if err != nil {
+		release()
+
+		var signErr *mdocSigningError
+		if errors.As(err, &signErr) {
+			return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.SigningError,
+				fmt.Sprintf("failed to sign mdoc: %s", err.Error()))
+		}
+
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("failed to issue mdoc: %s", err.Error()))
+	}
+
+	o.emitIssuance(profile.Name, credential, mdocFormat)
+
+	docType := mdocReq.DocType
+	if docType == "" {
+		docType = mdoc.DocTypeMDL
+	}
+
+	return &IssueMDocResponse{DocType: docType, MDoc: base64.RawURLEncoding.EncodeToString(issuerSigned)}, nil
+}
+
+// mdocAlgorithm returns the COSE algorithm of the key of the verification method
+func (o *Operation) mdocAlgorithm(verificationMethod string) (int, error) {
+	split := strings.SplitN(verificationMethod, "#", 2)
+	if len(split) != 2 {
+		return 0, fmt.Errorf("invalid verification method %s", verificationMethod)
+	}
+
+	key, err := verifiable.NewDIDKeyResolver(o.vdri).PublicKeyFetcher()(split[0], "#"+split[1])
+	if err != nil {
+		return 0, err
+	}
+
+	return mdoc.Algorithm(key)
+}
+
+func validateIssueMDocRequest(mdocReq *IssueMDocRequest) error {
+	validationErr := &commhttp.ValidationError{}
+
+	if len(mdocReq.Credential) == 0 {
+		validationErr.Add("/credential", "missing credential")
+	}
+
+	if mdocReq.DeviceKey == nil {
+		validationErr.Add("/deviceKey", "missing device key")
+	}
+
+	return validationErr.ErrorOrNil()
+}
+
+// mdocSigner signs the mdocs with the kms key of the verification method
+type mdocSigner struct {
+	crypto             *crypto.Crypto
+	verificationMethod string
+}
+
+func (s *mdocSigner) Sign(data []byte) ([]byte, error) {
+	signature, err := s.crypto.Sign(s.verificationMethod, data)
+	if err != nil {
+		return nil, &mdocSigningError{err: err}
+	}
+
+	return signature, nil
+}
+
+// mdocSigningError is the error of the kms signing a mdoc, an internal error unlike the invalid mdoc requests
+type mdocSigningError struct {
+	err error
+}
+
+func (e *mdocSigningError) Error() string {
+	return e.err.Error()
+}
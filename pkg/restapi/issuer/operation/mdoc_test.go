@@ -0,0 +1,162 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/mdoc"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const validMDLCredential = `{` +
+	validContext + `,
+  "id": "http://example.edu/credentials/1872",
+  "type": "VerifiableCredential",
+  "credentialSubject": {
+	"id": "did:example:ebfeb1f712ebc6f1c276e12ec21",
+	"family_name": "Doe",
+	"given_name": "John",
+	"birth_date": "1990-01-01"
+  },
+  "issuer": "did:example:76e12ec712ebc6f1c221ebfeb1f",
+  "issuanceDate": "2010-01-01T19:23:24Z",
+  "expirationDate": "2099-01-01T19:23:24Z"
+}`
+
+func TestIssueMDoc(t *testing.T) {
+	const keyID = "key-1"
+
+	endpoint := "/test/credentials/issueMDoc"
+	profile := getTestProfile()
+	profile.Creator = profile.DID + "#" + keyID
+	profile.DisableVCStatus = true
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	devicePubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	deviceKey := &jose.JWK{}
+	deviceKey.Key = devicePubKey
+
+	mockCrypto := &cryptomock.Crypto{SignValue: []byte("signature")}
+	vdri := &vdrimock.MockVDRIRegistry{
+		ResolveFunc: func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+			return createDIDDocWithKeyID(didID, keyID, pubKey), nil
+		}}
+
+	op, err := New(&Config{
+		StoreProvider:      memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		KeyManager:         &mockkms.KeyManager{CreateKeyID: keyID, CreateKeyValue: kh},
+		Crypto:             mockCrypto,
+		VDRI:               vdri,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveProfile(profile))
+
+	urlVars := map[string]string{profileIDPathParam: profile.Name}
+	handler := getHandler(t, op, issueMDocPath, http.MethodPost)
+
+	t.Run("issue mdoc - success", func(t *testing.T) {
+		reqBytes, err := json.Marshal(&IssueMDocRequest{Credential: []byte(validMDLCredential), DeviceKey: deviceKey})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		resp := &IssueMDocResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		require.Equal(t, mdoc.DocTypeMDL, resp.DocType)
+
+		issuerSigned, err := base64.RawURLEncoding.DecodeString(resp.MDoc)
+		require.NoError(t, err)
+
+		// the mock crypto doesn't sign, the mdoc being signed by the key of the profile otherwise
+		_, err = mdoc.Verify(issuerSigned, verifiable.NewDIDKeyResolver(vdri).PublicKeyFetcher(), time.Now())
+		require.EqualError(t, err, "invalid issuer authentication: invalid signature")
+	})
+
+	t.Run("issue mdoc - invalid request", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, endpoint, []byte(`{}`), urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		errResp := &commhttp.ErrorResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), errResp))
+		require.Equal(t, []commhttp.FieldError{
+			{Field: "/credential", Message: "missing credential"},
+			{Field: "/deviceKey", Message: "missing device key"},
+		}, errResp.Fields)
+	})
+
+	t.Run("issue mdoc - credential not expiring", func(t *testing.T) {
+		reqBytes, err := json.Marshal(&IssueMDocRequest{Credential: []byte(validVCWithoutStatus), DeviceKey: deviceKey})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "mdocs require the issuance and expiration dates of the credential")
+	})
+
+	t.Run("issue mdoc - signing error", func(t *testing.T) {
+		mockCrypto.SignErr = errors.New("sign error")
+		defer func() { mockCrypto.SignErr = nil }()
+
+		reqBytes, err := json.Marshal(&IssueMDocRequest{Credential: []byte(validMDLCredential), DeviceKey: deviceKey})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "sign error")
+	})
+
+	t.Run("issue mdoc - profile key not supported", func(t *testing.T) {
+		vdri.ResolveFunc = func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+			doc := createDIDDocWithKeyID(didID, keyID, pubKey)
+			for _, methods := range doc.VerificationMethods() {
+				for i := range methods {
+					methods[i].PublicKey.Type = "X25519KeyAgreementKey2019"
+				}
+			}
+
+			return doc, nil
+		}
+
+		reqBytes, err := json.Marshal(&IssueMDocRequest{Credential: []byte(validMDLCredential), DeviceKey: deviceKey})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "the key of the profile can't sign mdocs")
+	})
+}
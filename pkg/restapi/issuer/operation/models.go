@@ -10,8 +10,16 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 
+	"github.com/trustbloc/edge-service/pkg/doc/vc/exchange"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/manifest"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/quota"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/redaction"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/history"
 	"github.com/trustbloc/edge-service/pkg/restapi/model"
 )
 
@@ -31,10 +39,17 @@ type UpdateCredentialStatusRequest struct {
 	StatusReason string `json:"statusReason"`
 }
 
-// StoreVCRequest stores the credential with profile name
+// ChangeCredentialStatusRequest request struct for revoking, suspending or reinstating a vc
+type ChangeCredentialStatusRequest struct {
+	Credential   string `json:"credential"`
+	StatusReason string `json:"statusReason,omitempty"`
+}
+
+// StoreVCRequest stores the credential with profile name, or the batch of credentials if Credentials is set
 type StoreVCRequest struct {
-	Profile    string `json:"profile"`
-	Credential string `json:"credential"`
+	Profile     string   `json:"profile"`
+	Credential  string   `json:"credential,omitempty"`
+	Credentials []string `json:"credentials,omitempty"`
 }
 
 // ProfileRequest struct the input for creating profile
@@ -50,10 +65,108 @@ type ProfileRequest struct {
 	UNIRegistrar            model.UNIRegistrar                 `json:"uniRegistrar,omitempty"`
 	DisableVCStatus         bool                               `json:"disableVCStatus"`
 	OverwriteIssuer         bool                               `json:"overwriteIssuer,omitempty"`
+	// DIDMethod is the method of the DID created for the profile: trustbloc (default), key or web. The did:web
+	// document is served by the service. Not supported with DID or UNIRegistrar.
+	DIDMethod string `json:"didMethod,omitempty"`
+	// DIDWebPath is the path of the did:web document on the host of the service, "issuer/<name>" by default.
+	// The "/" path is the host itself, its document being served at /.well-known/did.json.
+	DIDWebPath string `json:"didWebPath,omitempty"`
+	// CredentialStorage is where the credentials of the profile are stored: "edv" or "local" (the store
+	// provider of the service). Defaults to the storage of the deployment.
+	CredentialStorage string `json:"credentialStorage,omitempty"`
+	// RevokeOnExpiry revokes the issued credentials in their status list once their expiration date has passed.
+	RevokeOnExpiry bool `json:"revokeOnExpiry,omitempty"`
+	// Policy are the rules the credentials must comply with to be issued under the profile, evaluated before
+	// signing them.
+	Policy []policy.Rule `json:"policy,omitempty"`
+	// Quota caps the credentials issued under the profile per UTC day and month, once reached the issuance
+	// requests fail until the next period.
+	Quota *quota.Quota `json:"quota,omitempty"`
+	// RequireHolderBinding requires the proof of possession of the subject DID of the credentials issued under the
+	// profile, which can't compose credentials.
+	RequireHolderBinding bool `json:"requireHolderBinding,omitempty"`
+	// CredentialFormat is the format of the credentials issued by the issueCredential endpoint: "ldp" (default),
+	// signed with linked data proofs, "jwt", signed as PS256 JWT credentials, or "sd-jwt", signed as SD-JWT
+	// credentials with the selectively disclosable claims of their subject. The jwt format requires a RSA key.
+	CredentialFormat string `json:"credentialFormat,omitempty"`
+	// AnchorCredentials time-stamps the credentials issued under the profile with the time-stamping authority of the
+	// service before signing them, the time-stamp token is added to their evidence.
+	AnchorCredentials bool `json:"anchorCredentials,omitempty"`
+	// StrictJSONLD fails the issuance of the ldp credentials with terms not defined by their contexts, which would
+	// be dropped from the signed canonicalization of the credentials.
+	StrictJSONLD bool `json:"strictJSONLD,omitempty"`
 }
 
-// IssueCredentialRequest request for issuing credential.
+// ProfileKeyRequest struct the input for adding a key to the profile DID
+type ProfileKeyRequest struct {
+	// SignatureType of the new key. If omitted the signature type of the profile will be used.
+	SignatureType string `json:"signatureType,omitempty"`
+	// DIDKeyType of the new key (Ed25519, P256, or P384, P521, secp256k1 and RSA for did:web). If omitted an Ed25519
+	// key will be created.
+	DIDKeyType string `json:"didKeyType,omitempty"`
+	// DIDPrivateKey and DIDKeyID of a key already added to the profile DID (used when no uni-registrar is passed)
+	DIDPrivateKey string             `json:"didPrivateKey,omitempty"`
+	DIDKeyID      string             `json:"didKeyID,omitempty"`
+	UNIRegistrar  model.UNIRegistrar `json:"uniRegistrar,omitempty"`
+}
+
+// DIDUpdateRequest struct the input for updating the DID document of the profile. The IDs of the added services
+// and verification methods are fragments of the DID.
+type DIDUpdateRequest struct {
+	model.DIDUpdate
+	UNIRegistrar model.UNIRegistrar `json:"uniRegistrar,omitempty"`
+}
+
+// DIDUpdateResponse struct the output of a DID document update
+type DIDUpdateResponse struct {
+	// VerificationMethods are the IDs of the added verification methods
+	VerificationMethods []string `json:"verificationMethods"`
+}
+
+// IssueCredentialRequest request for issuing credential, either the credential of the request or the one referenced
+// by CredentialRef.
 type IssueCredentialRequest struct {
+	Credential    json.RawMessage         `json:"credential,omitempty"`
+	CredentialRef *CredentialReference    `json:"credentialRef,omitempty"`
+	Opts          *IssueCredentialOptions `json:"options,omitempty"`
+}
+
+// CredentialReference references an unsigned credential, e.g. a credential template, by either its ID or its URL.
+type CredentialReference struct {
+	// ID of a credential stored under the profile (POST /store), its latest copy being issued.
+	ID string `json:"id,omitempty"`
+	// URL of the credential, which must match one of the allowed credential URLs of the service.
+	URL string `json:"url,omitempty"`
+}
+
+// IssueCredentialBatchRequest request for issuing a batch of credentials with the same profile.
+type IssueCredentialBatchRequest struct {
+	Credentials []IssueCredentialRequest `json:"credentials"`
+	// Presentation wraps the issued credentials into a presentation signed by the profile, if set.
+	Presentation *BatchPresentationOptions `json:"presentation,omitempty"`
+}
+
+// BatchPresentationOptions options of the presentation of the issued credentials.
+type BatchPresentationOptions struct {
+	// Holder is the DID of the recipient of the credentials, set as the holder of the presentation.
+	Holder string `json:"holder"`
+	// Challenge and Domain of the proof of the presentation, e.g. the ones sent by the recipient.
+	Challenge string `json:"challenge,omitempty"`
+	Domain    string `json:"domain,omitempty"`
+}
+
+// IssueCredentialBatchResponse contains the issued credentials, or the presentation wrapping them if requested.
+type IssueCredentialBatchResponse struct {
+	Credentials  []*verifiable.Credential `json:"credentials,omitempty"`
+	Presentation *verifiable.Presentation `json:"presentation,omitempty"`
+	// Hashlinks are the hashlinks of the issued credentials, in the order of the batch, empty for the credentials
+	// whose options don't request it. Not set if no credential requests it.
+	Hashlinks []string `json:"hashlinks,omitempty"`
+}
+
+// EndorseCredentialRequest request for endorsing a credential signed by another party, the options are the ones of
+// the proof of the profile.
+type EndorseCredentialRequest struct {
 	Credential json.RawMessage         `json:"credential,omitempty"`
 	Opts       *IssueCredentialOptions `json:"options,omitempty"`
 }
@@ -62,6 +175,7 @@ type IssueCredentialRequest struct {
 type IssueCredentialOptions struct {
 	// VerificationMethod is the URI of the verificationMethod used for the proof.
 	// If omitted first ed25519 public key of DID (Issuer or Profile DID) will be used.
+	// For profiles with signing keys, it must be the profile creator or one of the signing keys.
 	VerificationMethod string `json:"verificationMethod,omitempty"`
 	// AssertionMethod is verification method to be used for credential proof.
 	// When provided along with 'VerificationMethod' property, 'VerificationMethod' takes precedence.
@@ -75,6 +189,114 @@ type IssueCredentialOptions struct {
 	Challenge string `json:"challenge,omitempty"`
 	// Domain is added to the proof
 	Domain string `json:"domain,omitempty"`
+	// Nonce is added to the proof, base64url encoded without padding.
+	Nonce string `json:"nonce,omitempty"`
+	// ProofProperties are additional properties of the proof (e.g. capabilityChain), added after the proof is
+	// created: they aren't signed, and can't override the properties set by the service (jws, nonce, etc).
+	ProofProperties map[string]interface{} `json:"proofProperties,omitempty"`
+	// Proofs are added to the credential after the proof above, making a proof set (e.g. Ed25519Signature2018 and
+	// JsonWebSignature2020 proofs, or proofs of two keys of the profile) for the verifiers accepting specific suites.
+	Proofs []ProofOptions `json:"proofs,omitempty"`
+	// HolderBinding proves the possession of the subject DID of the credential, required by the profiles with
+	// RequireHolderBinding. Not used by the endorsements.
+	HolderBinding *HolderBinding `json:"holderBinding,omitempty"`
+	// Hashlink returns the hashlink (hl:) of the issued credential, in the Hashlink header of the issueCredential
+	// response or in the hashlinks of the batch response. The credential stored under the profile is retrieved by
+	// its hashlink at GET /{profileID}/credentials/hashlink/{hashlink}.
+	Hashlink bool `json:"hashlink,omitempty"`
+	// DryRun validates the request and returns the credential which would be issued, unsigned, without allocating
+	// its status, reserving the quota, consuming the holder binding challenge, anchoring, signing or storing it. Only
+	// supported by issueCredential.
+	DryRun bool `json:"dryRun,omitempty"`
+	// Redactable replaces the claims of the subject with the digest of their salted digests before the credential is
+	// signed, so the holder can disclose them one by one. The claims are returned with their salts alongside the
+	// credential. Only supported by issueCredential, for the credentials with a single subject.
+	Redactable bool `json:"redactable,omitempty"`
+	// HolderKey is the public key of the holder, a JWK, the SD-JWT credential is bound to: its presentations are
+	// signed by the key. Only supported by the sd-jwt profiles.
+	HolderKey *jose.JWK `json:"holderKey,omitempty"`
+	// HolderKeyID is the verification method of the holder DID the SD-JWT credential is bound to, instead of
+	// HolderKey.
+	HolderKeyID string `json:"holderKeyID,omitempty"`
+}
+
+// RedactableCredentialResponse is the credential issued with the redactable option.
+type RedactableCredentialResponse struct {
+	// Credential committing to the claims, in JSON-LD or as a JWT string for the profiles with the jwt format.
+	Credential interface{} `json:"credential"`
+	// Claims of the subject of the credential with their salts, kept by the holder to derive redacted credentials.
+	Claims []*redaction.Claim `json:"claims"`
+}
+
+// IssueCredentialDryRunResponse is the credential which would be issued by a dry run of issueCredential.
+type IssueCredentialDryRunResponse struct {
+	// Credential which would be signed, with the issuer and the contexts of the proofs and the status set.
+	Credential *verifiable.Credential `json:"credential"`
+	// Format of the issued credential, ldp, jwt or sd-jwt.
+	Format string `json:"format"`
+	// CredentialStatus is set if a status would be allocated to the credential in a status list of the profile.
+	CredentialStatus bool `json:"credentialStatus"`
+	// Anchored is set if the credential would be time-stamped before it is signed.
+	Anchored bool `json:"anchored"`
+}
+
+// DIDStatusResponse is the anchoring status of the DID of a profile.
+type DIDStatusResponse struct {
+	DID string `json:"did"`
+	// Anchored is set once the DID is resolvable, the credentials can't be issued under the profile until then.
+	Anchored bool `json:"anchored"`
+}
+
+// CanonicalizeCredentialRequest is the credential whose canonicalization is diagnosed.
+type CanonicalizeCredentialRequest struct {
+	// Credential in JSON-LD, its proof being ignored.
+	Credential json.RawMessage `json:"credential"`
+}
+
+// CompareCredentialsRequest is the credentials compared.
+type CompareCredentialsRequest struct {
+	// Left and Right are the credentials in JSON-LD, their proofs being ignored.
+	Left  json.RawMessage `json:"left"`
+	Right json.RawMessage `json:"right"`
+}
+
+// IssueMDocRequest is the credential issued as a mobile document (ISO/IEC 18013-5 mdoc).
+type IssueMDocRequest struct {
+	// Credential in JSON-LD: the claims of its subject are the data elements of the mdoc, valid from its issuance
+	// date until its expiration date.
+	Credential json.RawMessage `json:"credential"`
+	// DocType of the mdoc, org.iso.18013.5.1.mDL (mobile driving licence) by default.
+	DocType string `json:"docType,omitempty"`
+	// Namespace of the data elements, org.iso.18013.5.1 by default.
+	Namespace string `json:"namespace,omitempty"`
+	// DeviceKey is the public key of the holder device, an Ed25519 or EC JWK.
+	DeviceKey *jose.JWK `json:"deviceKey"`
+}
+
+// IssueMDocResponse is the issued mobile document.
+type IssueMDocResponse struct {
+	DocType string `json:"docType"`
+	// MDoc is the IssuerSigned structure of the mdoc, CBOR encoded, base64url encoded without padding.
+	MDoc string `json:"mdoc"`
+}
+
+// HolderBinding is the proof of possession of the subject DID of the issued credential.
+type HolderBinding struct {
+	// Presentation signed by the subject DID for the authentication proof purpose, with a challenge issued by the
+	// profile (POST /{profileID}/credentials/holderBinding/challenge).
+	Presentation json.RawMessage `json:"presentation"`
+}
+
+// ProofOptions are the options of an additional proof of the credential, the created date, challenge, domain,
+// nonce and proof properties are the ones of the IssueCredentialOptions.
+type ProofOptions struct {
+	// VerificationMethod is the URI of the verificationMethod used for the proof, the profile creator if omitted.
+	// For profiles with signing keys, it must be the profile creator or one of the signing keys.
+	VerificationMethod string `json:"verificationMethod,omitempty"`
+	// SignatureType of the proof, the one of the verification method if omitted.
+	SignatureType string `json:"signatureType,omitempty"`
+	// ProofPurpose is purpose of the proof, the one of the IssueCredentialOptions if omitted.
+	ProofPurpose string `json:"proofPurpose,omitempty"`
 }
 
 // ComposeCredentialRequest for composing and issuing credential.
@@ -91,10 +313,178 @@ type ComposeCredentialRequest struct {
 	ProofFormat             string          `json:"proofFormat,omitempty"`
 	CredentialFormatOptions json.RawMessage `json:"credentialFormatOptions,omitempty"`
 	ProofFormatOptions      json.RawMessage `json:"proofFormatOptions,omitempty"`
+	// Attachments are stored in the vault of the profile, and added to the evidence of the credential with their
+	// URL and hashlink.
+	Attachments []EvidenceAttachment `json:"attachments,omitempty"`
+}
+
+// EvidenceAttachment is a document the credential is issued about, e.g. a scanned diploma.
+type EvidenceAttachment struct {
+	// Name of the document, e.g. its file name.
+	Name string `json:"name,omitempty"`
+	// MediaType of the document, application/octet-stream if omitted.
+	MediaType string `json:"mediaType,omitempty"`
+	// Data is the base64 encoded document.
+	Data string `json:"data"`
+}
+
+// ExportProfileRequest is request for exporting issuer profile.
+type ExportProfileRequest struct {
+	// KEK is the base64url encoded AES key (16 or 32 bytes) used to wrap the private keys of the profile.
+	KEK string `json:"kek"`
+}
+
+// ProfileExport contains issuer profile together with its wrapped private keys.
+type ProfileExport struct {
+	Profile *vcprofile.DataProfile `json:"profile"`
+	Keys    []WrappedKey           `json:"keys,omitempty"`
+}
+
+// WrappedKey contains KMS key wrapped with the key encryption key.
+type WrappedKey struct {
+	KeyID      string `json:"keyID"`
+	WrappedKey string `json:"wrappedKey"`
+}
+
+// ImportProfileRequest is request for importing issuer profile exported from another instance.
+type ImportProfileRequest struct {
+	// KEK is the base64url encoded AES key used to wrap the private keys on export.
+	KEK string `json:"kek"`
+	ProfileExport
+}
+
+// GenerateKeyPairRequest is request for KMS generate keypair API.
+type GenerateKeyPairRequest struct {
+	// KeyType of the keypair (Ed25519, P256, P384, P521, secp256k1 or RSA). If omitted Ed25519 will be used.
+	// BLS12381G2 is rejected, the kms of the service doesn't support BLS12-381 keys.
+	KeyType string `json:"keyType,omitempty"`
+}
+
+// generatedKey is the record of a key generated through the generate keypair API. The deleted keys stay recorded
+// as the store has no delete.
+type generatedKey struct {
+	KeyType string `json:"keyType"`
+	Deleted bool   `json:"deleted,omitempty"`
 }
 
 // GenerateKeyPairResponse contains response from KMS generate keypair API.
 type GenerateKeyPairResponse struct {
-	PublicKey string `json:"publicKey,omitempty"`
-	KeyID     string `json:"keyID,omitempty"`
+	PublicKey string    `json:"publicKey,omitempty"`
+	KeyID     string    `json:"keyID,omitempty"`
+	JWK       *jose.JWK `json:"jwk,omitempty"`
+}
+
+// StatusHistoryResponse contains the status changes of the credential, oldest first.
+type StatusHistoryResponse struct {
+	ID            string                 `json:"id"`
+	StatusChanges []history.StatusChange `json:"statusChanges"`
+}
+
+// ListManifestsResponse contains the credential manifests of the profile, in the order they were created.
+type ListManifestsResponse struct {
+	Manifests []manifest.CredentialManifest `json:"manifests"`
+}
+
+// ListCredentialsResponse contains a page of the credentials stored under the profile matching the filters, and the
+// cursor of the next page unless it is the last one.
+type ListCredentialsResponse struct {
+	Credentials []json.RawMessage `json:"credentials"`
+	NextCursor  string            `json:"nextCursor,omitempty"`
+}
+
+// ImportCredentialsResponse reports the import of the credentials of the request, each identified by its line
+type ImportCredentialsResponse struct {
+	Imported int                      `json:"imported"`
+	Failed   int                      `json:"failed"`
+	Results  []ImportCredentialResult `json:"results"`
+}
+
+// ImportCredentialResult is the import result of the credential of a line, with its error if it failed
+type ImportCredentialResult struct {
+	Line  int    `json:"line"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// ReencryptCredentialsRequest is the request to re-encrypt the credentials stored by a profile
+type ReencryptCredentialsRequest struct {
+	// NewKey creates a new document encryption key before the credentials are re-encrypted
+	NewKey bool `json:"newKey,omitempty"`
+}
+
+// ReencryptCredentialsResponse reports the re-encryption of the credentials stored by a profile
+type ReencryptCredentialsResponse struct {
+	// KeyID is the ID of the key the credentials are encrypted with
+	KeyID       string `json:"keyID"`
+	Reencrypted int    `json:"reencrypted"`
+	// Skipped is the number of the credentials encrypted with the key already
+	Skipped int `json:"skipped"`
+}
+
+// MigrateVaultResponse reports the migration of the credentials of a profile to the vault of its vault ID
+type MigrateVaultResponse struct {
+	VaultID string `json:"vaultID"`
+	// Migrated is the number of the credentials copied, the credentials already copied by an interrupted
+	// migration being skipped
+	Migrated int `json:"migrated"`
+}
+
+// ReconcileStatusPublicationsResponse reports the reconciliation of the publications of the status changes of a
+// profile to the revocation registries
+type ReconcileStatusPublicationsResponse struct {
+	// Queued is the number of publications queued to be published again
+	Queued int `json:"queued"`
+}
+
+// InteractExchangeRequest is the interaction of the holder with an exchange, without presentation to get the
+// presentation request of the exchange
+type InteractExchangeRequest struct {
+	// VerifiablePresentation is the DIDAuth presentation of the holder completing the exchange
+	VerifiablePresentation json.RawMessage `json:"verifiablePresentation,omitempty"`
+}
+
+// InteractExchangeResponse is either the presentation request of the exchange, with the URL of the manifest of the
+// credential offered, or the presentation of the issued credential once the exchange is complete
+type InteractExchangeResponse struct {
+	VerifiablePresentationRequest *VerifiablePresentationRequest `json:"verifiablePresentationRequest,omitempty"`
+	ManifestURL                   string                         `json:"manifestURL,omitempty"`
+	VerifiablePresentation        interface{}                    `json:"verifiablePresentation,omitempty"`
+}
+
+// VerifiablePresentationRequest is the presentation requested from the holder to continue an exchange
+type VerifiablePresentationRequest struct {
+	Query     []PresentationQuery   `json:"query"`
+	Challenge string                `json:"challenge"`
+	Interact  *PresentationInteract `json:"interact,omitempty"`
+}
+
+// PresentationQuery is a query of a presentation request, e.g. DIDAuthentication
+type PresentationQuery struct {
+	Type string `json:"type"`
+}
+
+// PresentationInteract is the services the holder interacts with to send its presentation
+type PresentationInteract struct {
+	Service []InteractService `json:"service"`
+}
+
+// InteractService is a service receiving the presentation of the holder
+type InteractService struct {
+	Type            string `json:"type"`
+	ServiceEndpoint string `json:"serviceEndpoint"`
+}
+
+// CreateExchangeRequest is the credential issued by an exchange and its issuance options, with the ID of the
+// credential manifest of the profile describing the credential
+type CreateExchangeRequest struct {
+	IssueCredentialRequest
+	ManifestID string `json:"manifestID,omitempty"`
+}
+
+// ExchangeResponse is an exchange, with the URL the holder interacts with and the URL of the manifest of the
+// credential offered
+type ExchangeResponse struct {
+	*exchange.Exchange
+	InteractURL string `json:"interactURL"`
+	ManifestURL string `json:"manifestURL,omitempty"`
 }
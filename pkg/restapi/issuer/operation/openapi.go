@@ -7,7 +7,13 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 import (
+	"github.com/trustbloc/edge-service/pkg/doc/vc/binding"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/canonical"
+	vcchallenge "github.com/trustbloc/edge-service/pkg/doc/vc/challenge"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/manifest"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/quota"
 	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/registry"
 	"github.com/trustbloc/edge-service/pkg/restapi/model"
 )
 
@@ -38,6 +44,148 @@ type retrieveProfileReq struct { // nolint: unused,deadcode
 	ID string `json:"id"`
 }
 
+// updateDIDReq model
+//
+// swagger:parameters updateDIDReq
+type updateDIDReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Params DIDUpdateRequest
+}
+
+// updateDIDRes model
+//
+// swagger:response updateDIDRes
+type updateDIDRes struct { // nolint: unused,deadcode
+	// in: body
+	DIDUpdateResponse
+}
+
+// webDIDDocumentReq model
+//
+// swagger:parameters webDIDDocumentReq
+type webDIDDocumentReq struct { // nolint: unused,deadcode
+	// did:web path of the profile
+	//
+	// in: path
+	// required: true
+	Path string `json:"path"`
+}
+
+// wellKnownDIDDocumentReq model
+//
+// swagger:parameters wellKnownDIDDocumentReq
+type wellKnownDIDDocumentReq struct{} // nolint: unused,deadcode
+
+// webDIDDocumentRes model
+//
+// swagger:response webDIDDocumentRes
+type webDIDDocumentRes struct { // nolint: unused,deadcode
+	// in: body
+	Document map[string]interface{}
+}
+
+// rotateKeyReq model
+//
+// swagger:parameters rotateKeyReq
+type rotateKeyReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Params ProfileKeyRequest
+}
+
+// addKeyReq model
+//
+// swagger:parameters addKeyReq
+type addKeyReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Params ProfileKeyRequest
+}
+
+// exportProfileReq model
+//
+// swagger:parameters exportProfileReq
+type exportProfileReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Params ExportProfileRequest
+}
+
+// exportProfileRes model
+//
+// swagger:response exportProfileRes
+type exportProfileRes struct { // nolint: unused,deadcode
+	// in: body
+	ProfileExport
+}
+
+// importProfileReq model
+//
+// swagger:parameters importProfileReq
+type importProfileReq struct { // nolint: unused,deadcode
+	// in: body
+	Params ImportProfileRequest
+}
+
+// issuanceUsageReq model
+//
+// swagger:parameters issuanceUsageReq
+type issuanceUsageReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// issuanceUsageRes model
+//
+// swagger:response issuanceUsageRes
+type issuanceUsageRes struct { // nolint: unused,deadcode
+	// in: body
+	quota.Usage
+}
+
+// statusListsReq model
+//
+// swagger:parameters statusListsReq
+type statusListsReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// statusListsRes model
+//
+// swagger:response statusListsRes
+type statusListsRes struct { // nolint: unused,deadcode
+	// in: body
+	cslstatus.Utilization
+}
+
 // issuerProfileRes model
 //
 // swagger:response issuerProfileRes
@@ -56,10 +204,56 @@ type issueCredentialReq struct { // nolint: unused,deadcode
 	// required: true
 	ID string `json:"id"`
 
+	// key identifying the retries of the request, which get the response of the first request
+	//
+	// in: header
+	IdempotencyKey string `json:"Idempotency-Key"`
+
 	// in: body
 	Params IssueCredentialRequest
 }
 
+// issueCredentialBatchReq model
+//
+// swagger:parameters issueCredentialBatchReq
+type issueCredentialBatchReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// key identifying the retries of the request, which get the response of the first request
+	//
+	// in: header
+	IdempotencyKey string `json:"Idempotency-Key"`
+
+	// in: body
+	Params IssueCredentialBatchRequest
+}
+
+// issueCredentialBatchRes model
+//
+// swagger:response issueCredentialBatchRes
+type issueCredentialBatchRes struct { // nolint: unused,deadcode
+	// in: body
+	IssueCredentialBatchResponse
+}
+
+// endorseCredentialReq model
+//
+// swagger:parameters endorseCredentialReq
+type endorseCredentialReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Params EndorseCredentialRequest
+}
+
 // issueCredentialReq model for OpenAPI annotation
 //
 // swagger:parameters composeCredentialReq
@@ -70,10 +264,104 @@ type composeCredentialReq struct { // nolint: unused,deadcode
 	// required: true
 	ID string `json:"id"`
 
+	// key identifying the retries of the request, which get the response of the first request
+	//
+	// in: header
+	IdempotencyKey string `json:"Idempotency-Key"`
+
 	// in: body
 	Params ComposeCredentialRequest
 }
 
+// issueCredentialDryRunRes model
+//
+// swagger:response issueCredentialDryRunRes
+type issueCredentialDryRunRes struct { // nolint: unused,deadcode
+	// in: body
+	IssueCredentialDryRunResponse
+}
+
+// redactableCredentialRes model
+//
+// swagger:response redactableCredentialRes
+type redactableCredentialRes struct { // nolint: unused,deadcode
+	// in: body
+	RedactableCredentialResponse
+}
+
+// didStatusReq model
+//
+// swagger:parameters didStatusReq
+type didStatusReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// didStatusRes model contains the anchoring status of the DID of the profile
+//
+// swagger:response didStatusRes
+type didStatusRes struct { // nolint: unused,deadcode
+	// in: body
+	DIDStatusResponse
+}
+
+// canonicalizeCredentialReq model
+//
+// swagger:parameters canonicalizeCredentialReq
+type canonicalizeCredentialReq struct { // nolint: unused,deadcode
+	// in: body
+	Params CanonicalizeCredentialRequest
+}
+
+// canonicalizeCredentialRes model contains the canonicalized credential and its dropped terms
+//
+// swagger:response canonicalizeCredentialRes
+type canonicalizeCredentialRes struct { // nolint: unused,deadcode
+	// in: body
+	canonical.Diagnosis
+}
+
+// compareCredentialsReq model
+//
+// swagger:parameters compareCredentialsReq
+type compareCredentialsReq struct { // nolint: unused,deadcode
+	// in: body
+	Params CompareCredentialsRequest
+}
+
+// compareCredentialsRes model contains the comparison of the credentials
+//
+// swagger:response compareCredentialsRes
+type compareCredentialsRes struct { // nolint: unused,deadcode
+	// in: body
+	canonical.Comparison
+}
+
+// issueMDocReq model
+//
+// swagger:parameters issueMDocReq
+type issueMDocReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Params IssueMDocRequest
+}
+
+// issueMDocRes model contains the issued mdoc
+//
+// swagger:response issueMDocRes
+type issueMDocRes struct { // nolint: unused,deadcode
+	// in: body
+	IssueMDocResponse
+}
+
 // verifiableCredentialRes model contains the verifiable credential
 //
 // swagger:response verifiableCredentialRes
@@ -81,6 +369,25 @@ type verifiableCredentialRes struct { // nolint: unused,deadcode
 	// in: body
 }
 
+// generateKeypairReq model
+//
+// swagger:parameters generateKeypairReq
+type generateKeypairReq struct { // nolint: unused,deadcode
+	// in: body
+	Params GenerateKeyPairRequest
+}
+
+// deleteKeyReq model
+//
+// swagger:parameters deleteKeyReq
+type deleteKeyReq struct { // nolint: unused,deadcode
+	// key ID
+	//
+	// in: path
+	// required: true
+	KeyID string `json:"keyID"`
+}
+
 // generateKeypairResp model
 //
 // swagger:response generateKeypairResp
@@ -120,14 +427,357 @@ type retrieveCredentialReq struct { // nolint: unused,deadcode
 	Profile string `json:"profile"`
 }
 
+// listCredentialsReq model
+//
+// swagger:parameters listCredentialsReq
+type listCredentialsReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// credential type
+	//
+	// in: query
+	Type string `json:"type"`
+
+	// credential subject ID
+	//
+	// in: query
+	Subject string `json:"subject"`
+
+	// earliest issuance date (RFC3339)
+	//
+	// in: query
+	IssuedAfter string `json:"issuedAfter"`
+
+	// latest issuance date (RFC3339)
+	//
+	// in: query
+	IssuedBefore string `json:"issuedBefore"`
+
+	// maximum number of credentials of the page (1 to 1000, defaults to 100)
+	//
+	// in: query
+	Limit int `json:"limit"`
+
+	// nextCursor of the previous page
+	//
+	// in: query
+	Cursor string `json:"cursor"`
+}
+
+// listCredentialsRes model
+//
+// swagger:response listCredentialsRes
+type listCredentialsRes struct { // nolint: unused,deadcode
+	// in: body
+	ListCredentialsResponse
+}
+
+// exportCredentialsReq model
+//
+// swagger:parameters exportCredentialsReq
+type exportCredentialsReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// format of the export: ndjson (default) or zip
+	//
+	// in: query
+	Format string `json:"format"`
+
+	// base64url encoded EC public JWK the exported credentials are encrypted to
+	//
+	// in: query
+	RecipientKey string `json:"recipientKey"`
+}
+
+// exportCredentialsResp model
+//
+// swagger:response exportCredentialsResp
+type exportCredentialsResp struct { // nolint: unused,deadcode
+	// the credentials (or their JWEs), one per line or file
+	//
+	// in: body
+	Body []byte
+}
+
+// importCredentialsReq model
+//
+// swagger:parameters importCredentialsReq
+type importCredentialsReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// the credentials, one JSON credential per line
+	//
+	// in: body
+	Body []byte
+}
+
+// importCredentialsResp model
+//
+// swagger:response importCredentialsResp
+type importCredentialsResp struct { // nolint: unused,deadcode
+	// in: body
+	ImportCredentialsResponse
+}
+
+// reencryptCredentialsReq model
+//
+// swagger:parameters reencryptCredentialsReq
+type reencryptCredentialsReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// in: body
+	Params ReencryptCredentialsRequest
+}
+
+// reencryptCredentialsResp model
+//
+// swagger:response reencryptCredentialsResp
+type reencryptCredentialsResp struct { // nolint: unused,deadcode
+	// in: body
+	ReencryptCredentialsResponse
+}
+
+// migrateVaultReq model
+//
+// swagger:parameters migrateVaultReq
+type migrateVaultReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// migrateVaultRes model
+//
+// swagger:response migrateVaultRes
+type migrateVaultRes struct { // nolint: unused,deadcode
+	// in: body
+	MigrateVaultResponse
+}
+
+// statusPublicationsReq model
+//
+// swagger:parameters statusPublicationsReq
+type statusPublicationsReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// statusPublicationsRes model
+//
+// swagger:response statusPublicationsRes
+type statusPublicationsRes struct { // nolint: unused,deadcode
+	// in: body
+	registry.Report
+}
+
+// reconcilePublicationsReq model
+//
+// swagger:parameters reconcilePublicationsReq
+type reconcilePublicationsReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
+// reconcilePublicationsRes model
+//
+// swagger:response reconcilePublicationsRes
+type reconcilePublicationsRes struct { // nolint: unused,deadcode
+	// in: body
+	ReconcileStatusPublicationsResponse
+}
+
 // updateCredentialStatusReq model
 //
 // swagger:parameters updateCredentialStatusReq
 type updateCredentialStatusReq struct { // nolint: unused,deadcode
+	// key identifying the retries of the request, which get the response of the first request
+	//
+	// in: header
+	IdempotencyKey string `json:"Idempotency-Key"`
+
 	// in: body
 	Params UpdateCredentialStatusRequest
 }
 
+// changeCredentialStatusReq model
+//
+// swagger:parameters changeCredentialStatusReq
+type changeCredentialStatusReq struct { // nolint: unused,deadcode
+	// in: body
+	Params ChangeCredentialStatusRequest
+}
+
+// statusHistoryReq model
+//
+// swagger:parameters statusHistoryReq
+type statusHistoryReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// credential ID, base64url encoded without padding
+	//
+	// in: path
+	// required: true
+	CredentialID string `json:"credentialID"`
+}
+
+// statusHistoryRes model
+//
+// swagger:response statusHistoryRes
+type statusHistoryRes struct { // nolint: unused,deadcode
+	// in: body
+	StatusHistoryResponse
+}
+
+// bindingChallengeReq model
+//
+// swagger:parameters bindingChallengeReq
+type bindingChallengeReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+}
+
+// bindingChallengeRes model
+//
+// swagger:response bindingChallengeRes
+type bindingChallengeRes struct { // nolint: unused,deadcode
+	// in: body
+	vcchallenge.Challenge
+}
+
+// holderBindingReq model
+//
+// swagger:parameters holderBindingReq
+type holderBindingReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// credential ID, base64url encoded without padding
+	//
+	// in: path
+	// required: true
+	CredentialID string `json:"credentialID"`
+}
+
+// holderBindingRes model
+//
+// swagger:response holderBindingRes
+type holderBindingRes struct { // nolint: unused,deadcode
+	// in: body
+	binding.Evidence
+}
+
+// createManifestReq model
+//
+// swagger:parameters createManifestReq
+type createManifestReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// in: body
+	Params manifest.CredentialManifest
+}
+
+// listManifestsReq model
+//
+// swagger:parameters listManifestsReq
+type listManifestsReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+}
+
+// listManifestsRes model
+//
+// swagger:response listManifestsRes
+type listManifestsRes struct { // nolint: unused,deadcode
+	// in: body
+	ListManifestsResponse
+}
+
+// manifestReq model
+//
+// swagger:parameters getManifestReq deleteManifestReq
+type manifestReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// manifest ID
+	//
+	// in: path
+	// required: true
+	ManifestID string `json:"manifestID"`
+}
+
+// updateManifestReq model
+//
+// swagger:parameters updateManifestReq
+type updateManifestReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// manifest ID
+	//
+	// in: path
+	// required: true
+	ManifestID string `json:"manifestID"`
+
+	// in: body
+	Params manifest.CredentialManifest
+}
+
+// manifestRes model
+//
+// swagger:response manifestRes
+type manifestRes struct { // nolint: unused,deadcode
+	// in: body
+	manifest.CredentialManifest
+}
+
 // retrieveCredentialStatusReq model
 //
 // swagger:parameters retrieveCredentialStatusReq
@@ -136,6 +786,12 @@ type retrieveCredentialStatusReq struct { // nolint: unused,deadcode
 	//
 	// in: path
 	// required: true
+	ProfileID string `json:"profileID"`
+
+	// status list
+	//
+	// in: path
+	// required: true
 	ID string `json:"id"`
 }
 
@@ -146,3 +802,121 @@ type retrieveCredentialStatusResp struct { // nolint: unused,deadcode
 	// in: body
 	cslstatus.CSL
 }
+
+// evidenceAttachmentReq model
+//
+// swagger:parameters evidenceAttachmentReq
+type evidenceAttachmentReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// attachment
+	//
+	// in: path
+	// required: true
+	AttachmentID string `json:"attachmentID"`
+}
+
+// hashlinkCredentialReq model
+//
+// swagger:parameters hashlinkCredentialReq
+type hashlinkCredentialReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// hashlink of the credential
+	//
+	// in: path
+	// required: true
+	Hashlink string `json:"hashlink"`
+}
+
+// evidenceAttachmentResp model
+//
+// swagger:response evidenceAttachmentResp
+type evidenceAttachmentResp struct { // nolint: unused,deadcode
+	// the attachment, with its media type as the content type
+	//
+	// in: body
+	Body []byte
+}
+
+// createExchangeReq model
+//
+// swagger:parameters createExchangeReq
+type createExchangeReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// key identifying the retries of the request, which get the response of the first request
+	//
+	// in: header
+	IdempotencyKey string `json:"Idempotency-Key"`
+
+	// the credential to issue, its issuance options and the ID of its credential manifest
+	//
+	// in: body
+	Params CreateExchangeRequest
+}
+
+// getExchangeReq model
+//
+// swagger:parameters getExchangeReq
+type getExchangeReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// exchange ID
+	//
+	// in: path
+	// required: true
+	ExchangeID string `json:"exchangeID"`
+}
+
+// exchangeRes model
+//
+// swagger:response exchangeRes
+type exchangeRes struct { // nolint: unused,deadcode
+	// in: body
+	ExchangeResponse
+}
+
+// interactExchangeReq model
+//
+// swagger:parameters interactExchangeReq
+type interactExchangeReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+
+	// exchange ID
+	//
+	// in: path
+	// required: true
+	ExchangeID string `json:"exchangeID"`
+
+	// in: body
+	Params InteractExchangeRequest
+}
+
+// interactExchangeRes model
+//
+// swagger:response interactExchangeRes
+type interactExchangeRes struct { // nolint: unused,deadcode
+	// in: body
+	InteractExchangeResponse
+}
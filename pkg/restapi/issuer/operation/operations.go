@@ -7,42 +7,108 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 import (
+	"archive/zip"
+	"bufio"
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/google/tink/go/keyset"
 	"github.com/gorilla/mux"
 	ariescrypto "github.com/hyperledger/aries-framework-go/pkg/crypto"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes/subtle"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
 	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
 	ariesstorage "github.com/hyperledger/aries-framework-go/pkg/storage"
+	"github.com/piprate/json-gold/ld"
 	"github.com/trustbloc/edge-core/pkg/log"
 	"github.com/trustbloc/edge-core/pkg/storage"
 	"github.com/trustbloc/edge-core/pkg/utils/retry"
 	"github.com/trustbloc/edv/pkg/restapi/messages"
 	"github.com/trustbloc/edv/pkg/restapi/models"
 
+	"github.com/trustbloc/edge-service/pkg/apikey"
+	"github.com/trustbloc/edge-service/pkg/cache"
+	"github.com/trustbloc/edge-service/pkg/cache/memcache"
+	"github.com/trustbloc/edge-service/pkg/client/localedv"
+	"github.com/trustbloc/edge-service/pkg/client/tsa"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/binding"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/canonical"
+	vcchallenge "github.com/trustbloc/edge-service/pkg/doc/vc/challenge"
 	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/exchange"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/manifest"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
 	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/quota"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/redaction"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/sdjwt"
 	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/expiry"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/history"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/registry"
+	"github.com/trustbloc/edge-service/pkg/events"
+	"github.com/trustbloc/edge-service/pkg/httpclient"
+	"github.com/trustbloc/edge-service/pkg/internal/common/diddoc"
 	"github.com/trustbloc/edge-service/pkg/internal/common/support"
 	"github.com/trustbloc/edge-service/pkg/internal/cryptosetup"
+	"github.com/trustbloc/edge-service/pkg/internal/keyexport"
+	"github.com/trustbloc/edge-service/pkg/kms/signingkms"
+	"github.com/trustbloc/edge-service/pkg/metrics"
+	"github.com/trustbloc/edge-service/pkg/ratelimit"
+	"github.com/trustbloc/edge-service/pkg/restapi/internal/common/credentialref"
 	commondid "github.com/trustbloc/edge-service/pkg/restapi/internal/common/did"
 	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
 	"github.com/trustbloc/edge-service/pkg/restapi/internal/common/vcutil"
 	"github.com/trustbloc/edge-service/pkg/restapi/model"
+	"github.com/trustbloc/edge-service/pkg/vdri/web"
+)
+
+const (
+	// CredentialStorageEDV stores the credentials in the EDV server
+	CredentialStorageEDV = "edv"
+	// CredentialStorageLocal stores the credentials in the store provider of the service
+	CredentialStorageLocal = "local"
+
+	// CredentialFormatLDP issues the credentials signed with linked data proofs (default)
+	CredentialFormatLDP = "ldp"
+	// CredentialFormatJWT issues the credentials as PS256 JWT credentials, signed with the RSA key of the profile
+	CredentialFormatJWT = "jwt"
+	// CredentialFormatSDJWT issues the credentials as SD-JWT credentials, the claims of their subject being
+	// selectively disclosable, signed with the key of the profile
+	CredentialFormatSDJWT = "sd-jwt"
+
+	// DuplicateCredentialsAllow stores a copy of the credential each time it is stored (default)
+	DuplicateCredentialsAllow = "allow"
+	// DuplicateCredentialsReject rejects the storage of a credential already stored under the profile
+	DuplicateCredentialsReject = "reject"
+	// DuplicateCredentialsOverwrite replaces the stored copy of the credential, deleted once the new copy is stored
+	DuplicateCredentialsOverwrite = "overwrite"
+	// DuplicateCredentialsVersion is DuplicateCredentialsOverwrite with the previous copies retrievable by version
+	DuplicateCredentialsVersion = "version"
 )
 
 const (
@@ -52,21 +118,88 @@ const (
 	// issuer endpoints
 	createProfileEndpoint          = "/profile"
 	getProfileEndpoint             = createProfileEndpoint + "/{id}"
+	rotateKeyEndpoint              = getProfileEndpoint + "/rotateKey"
+	addKeyEndpoint                 = getProfileEndpoint + "/keys"
+	updateDIDEndpoint              = getProfileEndpoint + "/did"
+	exportProfileEndpoint          = getProfileEndpoint + "/export"
+	issuanceUsageEndpoint          = getProfileEndpoint + "/usage"
+	statusListsEndpoint            = getProfileEndpoint + "/statusLists"
+	migrateVaultEndpoint           = getProfileEndpoint + "/vault/migrate"
+	statusPublicationsEndpoint     = getProfileEndpoint + "/statusPublications"
+	reconcilePublicationsEndpoint  = statusPublicationsEndpoint + "/reconcile"
+	importProfileEndpoint          = createProfileEndpoint + "/import"
 	storeCredentialEndpoint        = "/store"
 	retrieveCredentialEndpoint     = "/retrieve"
 	credentialStatus               = "/status"
 	updateCredentialStatusEndpoint = "/updateStatus"
 	credentialStatusEndpoint       = credentialStatus + "/{id}"
+	profileCredentialStatusPath    = credentialStatus + "/{" + profileIDPathParam + "}/{id}"
+	revokeCredentialEndpoint       = "/credentials/revoke"
+	suspendCredentialEndpoint      = "/credentials/suspend"
+	reinstateCredentialEndpoint    = "/credentials/reinstate"
+	canonicalizeCredentialEndpoint = "/credentials/canonicalize"
+	compareCredentialsEndpoint     = "/credentials/compare"
 	credentialsBasePath            = "/" + "{" + profileIDPathParam + "}" + "/credentials"
+	credentialIDPathParam          = "credentialID"
+	statusHistoryPath              = credentialsBasePath + "/{" + credentialIDPathParam + "}/statusHistory"
 	issueCredentialPath            = credentialsBasePath + "/issueCredential"
 	composeAndIssueCredentialPath  = credentialsBasePath + "/composeAndIssueCredential"
+	endorseCredentialPath          = credentialsBasePath + "/endorse"
+	exportCredentialsPath          = credentialsBasePath + "/export"
+	importCredentialsPath          = credentialsBasePath + "/import"
+	reencryptCredentialsPath       = credentialsBasePath + "/reencrypt"
+	manifestsPath                  = "/" + "{" + profileIDPathParam + "}" + "/manifests"
+	manifestIDPathParam            = "manifestID"
+	manifestPath                   = manifestsPath + "/{" + manifestIDPathParam + "}"
 	kmsBasePath                    = "/kms"
 	generateKeypairPath            = kmsBasePath + "/generatekeypair"
+	keyIDPathParam                 = "keyID"
+	deleteKeyPath                  = kmsBasePath + "/keys/{" + keyIDPathParam + "}"
+	webDIDPath                     = "issuer"
+	webDIDPathParam                = "path"
+	wellKnownDIDEndpoint           = "/.well-known/did.json"
+	webDIDDocumentEndpoint         = "/{" + webDIDPathParam + ":.+}/did.json"
+
+	// CSLSize is the number of credentials of the credential status lists of the issuer
+	CSLSize = 50
+
+	cslCacheSize = 1000
+
+	// the keys generated through the generate keypair API, the only keys the delete key API deletes
+	generatedKeysStoreName = "generatedkeys"
+	reencryptionStoreName  = "reencryption"
+
+	// bls12381G2KeyType is not supported by the aries-framework-go kms used by the service
+	bls12381G2KeyType = "BLS12381G2"
+
+	// names of the indexes used for listing the stored credentials
+	vcTypeEDVIndexName     = "vcType"
+	vcSubjectEDVIndexName  = "vcSubject"
+	vcIssuedEDVIndexName   = "vcIssued"
+	vcStoredEDVIndexName   = "vcStored"
+	vcHashlinkEDVIndexName = "vcHashlink"
 
-	cslSize = 50
+	// the issuance date index has the issuance day of the credentials, the ranges of at most maxIssuedIndexDays
+	// days are looked up in it a day at a time
+	issuedIndexDateFormat = "2006-01-02"
+	maxIssuedIndexDays    = 31
+
+	// the default and maximum numbers of credentials listed in a page
+	defaultListLimit = 100
+	maxListLimit     = 1000
+
+	// formats of the credentials export
+	exportFormatNDJSON = "ndjson"
+	exportFormatZIP    = "zip"
+
+	// importBatchSize is the number of imported credentials written to the vault at once
+	importBatchSize = 100
 
 	invalidRequestErrMsg = "Invalid request"
 
+	// anonymousActor is the actor of the status changes requested without API key
+	anonymousActor = "anonymous"
+
 	// supported proof purpose
 	assertionMethod      = "assertionMethod"
 	authentication       = "authentication"
@@ -76,7 +209,21 @@ const (
 
 var logger = log.New("edge-service-issuer-restapi")
 
+// nolint: gochecknoglobals
+var kmsKeyTypes = map[string]kms.KeyType{
+	crypto.Ed25519KeyType:   kms.ED25519Type,
+	crypto.P256KeyType:      kms.ECDSAP256TypeIEEEP1363,
+	crypto.P384KeyType:      kms.ECDSAP384TypeIEEEP1363,
+	crypto.P521KeyType:      kms.ECDSAP521TypeIEEEP1363,
+	crypto.Secp256k1KeyType: signingkms.Secp256k1KeyType,
+	crypto.RSAKeyType:       signingkms.RSAKeyType,
+}
+
 var errProfileNotFound = errors.New("specified profile ID does not exist")
+var errDuplicateVC = errors.New("a VC with the given ID is already stored under the profile")
+var errDuplicateVCInBatch = errors.New("several VCs of the batch have the same ID")
+var errConcurrentVCStorage = errors.New("a copy of the VC was stored concurrently, retry the storage")
+var errEDVNotConfigured = errors.New("the credentials can't be stored in an EDV as no EDV is configured")
 var errNoDocsMatchQuery = errors.New("no documents match the given query")
 
 var errMultipleInconsistentVCsFoundForOneID = errors.New("multiple VCs with " +
@@ -91,9 +238,29 @@ type Handler interface {
 }
 
 type vcStatusManager interface {
-	CreateStatusID() (*verifiable.TypedID, error)
+	CreateStatusID(profile *vcprofile.DataProfile, vcID string) (*verifiable.TypedID, error)
 	UpdateVCStatus(v *verifiable.Credential, profile *vcprofile.DataProfile, status, statusReason string) error
-	GetCSL(id string) (*cslstatus.CSL, error)
+	GetSignedCSL(id string) ([]byte, error)
+	RevokeVC(v *verifiable.Credential, profile *vcprofile.DataProfile, statusReason string) error
+	SuspendVC(v *verifiable.Credential, profile *vcprofile.DataProfile, statusReason string) error
+	ReinstateVC(v *verifiable.Credential, profile *vcprofile.DataProfile) error
+	Utilization(profileName string) (*cslstatus.Utilization, error)
+}
+
+type expiryLog interface {
+	Record(vc *verifiable.Credential, profile string) error
+}
+
+type statusHistory interface {
+	Record(profile, vcID string, change *history.StatusChange) error
+	Get(profile, vcID string) ([]history.StatusChange, error)
+}
+
+type manifestStore interface {
+	Put(profile string, manifest *manifest.CredentialManifest) error
+	Get(profile, id string) (*manifest.CredentialManifest, error)
+	List(profile string) ([]manifest.CredentialManifest, error)
+	Delete(profile, id string) error
 }
 
 // EDVClient interface to interact with edv client
@@ -102,60 +269,241 @@ type EDVClient interface {
 	CreateDocument(vaultID string, document *models.EncryptedDocument) (string, error)
 	ReadDocument(vaultID, docID string) (*models.EncryptedDocument, error)
 	QueryVault(vaultID string, query *models.Query) ([]string, error)
+	CreateDocuments(vaultID string, documents []*models.EncryptedDocument) ([]string, error)
+	ReadDocuments(vaultID string, docIDs []string) ([]*models.EncryptedDocument, error)
+	DeleteDocument(vaultID, docID string) error
 }
 
 type keyManager interface {
 	kms.KeyManager
 }
 
+// jweCrypto encrypts the documents of a profile stored in the EDV with the current key of the profile, which it
+// rotates
+type jweCrypto interface {
+	jose.Encrypter
+	jose.Decrypter
+	KeyID() string
+	RotateKey() (string, error)
+}
+
+type keyDeleter interface {
+	Delete(keyID string) error
+}
+
 type commonDID interface {
 	CreateDID(keyType, signatureType, did, privateKey, keyID, purpose string,
 		registrar model.UNIRegistrar) (string, string, error)
+	AddKey(did, keyType, signatureType, privateKey, keyID string,
+		registrar model.UNIRegistrar) (string, error)
+	CreateKeyDID(keyType string) (string, string, error)
+	CreateWebDID(keyType, signatureType string, path ...string) (string, string, error)
+	UpdateDID(did string, update *model.DIDUpdate, registrar model.UNIRegistrar) ([]string, error)
+}
+
+type webDIDDocs interface {
+	Get(did string) (*did.Doc, error)
+	Delete(did string) error
+}
+
+type claimsSource interface {
+	FetchClaims(subjectID string) (map[string]interface{}, error)
+}
+
+type timestamper interface {
+	Timestamp(digest []byte) (*tsa.Token, error)
 }
 
 // New returns CreateCredential instance
 func New(config *Config) (*Operation, error) {
 	c := crypto.New(config.KeyManager, config.Crypto, config.VDRI)
 
-	vcStatusManager, err := cslstatus.New(config.StoreProvider, config.HostURL+credentialStatus, cslSize, c)
+	cslOpts := []cslstatus.Option{cslstatus.WithShards(config.CSLShards)}
+	if config.CSLCacheTTL > 0 {
+		cslOpts = append(cslOpts, cslstatus.WithCache(memcache.New(cslCacheSize, config.CSLCacheTTL)))
+	}
+
+	statusStoreProvider := config.StatusStoreProvider
+	if statusStoreProvider == nil {
+		statusStoreProvider = config.StoreProvider
+	}
+
+	vcStatusManager, err := cslstatus.New(statusStoreProvider, config.HostURL+credentialStatus, CSLSize, c,
+		cslOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to instantiate new csl status: %w", err)
 	}
 
-	jweEncrypter, jweDecrypter, err := cryptosetup.PrepareJWECrypto(config.KeyManager, config.StoreProvider,
-		jose.A256GCM, kms.ECDHES256AES256GCMType)
+	edvKeyManager, edvCrypto := config.KeyManager, config.Crypto
+	if config.EDVKeyManager != nil {
+		edvKeyManager, edvCrypto = config.EDVKeyManager, config.EDVCrypto
+	}
+
+	serviceJWECrypto, err := cryptosetup.PrepareJWECrypto(edvKeyManager, config.StoreProvider,
+		edvJWEAlgorithm(config))
 	if err != nil {
 		return nil, err
 	}
 
 	kh, vcIDIndexNameMACEncoded, err :=
-		cryptosetup.PrepareMACCrypto(config.KeyManager, config.StoreProvider, config.Crypto, kms.HMACSHA256Tag256Type)
+		cryptosetup.PrepareMACCrypto(edvKeyManager, config.StoreProvider, edvCrypto, kms.HMACSHA256Tag256Type)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := vcprofile.New(config.StoreProvider, vcprofile.WithCache(config.ProfileCache))
 	if err != nil {
 		return nil, err
 	}
 
-	p, err := vcprofile.New(config.StoreProvider)
+	localEDVClient, err := localedv.New(config.StoreProvider)
 	if err != nil {
 		return nil, err
 	}
 
+	generatedKeys, err := openStore(config.StoreProvider, generatedKeysStoreName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open generated keys store: %w", err)
+	}
+
+	credentialStorage := config.CredentialStorage
+	if credentialStorage == "" {
+		credentialStorage = CredentialStorageEDV
+	}
+
+	duplicateCredentials := config.DuplicateCredentials
+	if duplicateCredentials == "" {
+		duplicateCredentials = DuplicateCredentialsAllow
+	}
+
+	vaultIDScheme := config.VaultIDScheme
+	if vaultIDScheme == "" {
+		vaultIDScheme = VaultIDSchemeName
+	}
+
 	svc := &Operation{
 		profileStore:         p,
 		edvClient:            config.EDVClient,
+		localEDVClient:       localEDVClient,
+		credentialStorage:    credentialStorage,
+		duplicateCredentials: duplicateCredentials,
+		cleanupDryRun:        config.DuplicateVCsCleanupDryRun,
+		vaultIDScheme:        vaultIDScheme,
+		vaultIDPrefix:        config.VaultIDPrefix,
 		kms:                  config.KeyManager,
+		kmsSecretsProvider:   config.KMSSecretsProvider,
+		generatedKeys:        generatedKeys,
+		storeProvider:        config.StoreProvider,
 		vdri:                 config.VDRI,
 		crypto:               c,
-		jweEncrypter:         jweEncrypter,
-		jweDecrypter:         jweDecrypter,
+		profileJWECrypto: func(profileName string) (jweCrypto, error) {
+			return serviceJWECrypto.ProfileCrypto(profileName)
+		},
 		vcStatusManager:      vcStatusManager,
 		domain:               config.Domain,
 		HostURL:              config.HostURL,
 		macKeyHandle:         kh,
-		macCrypto:            config.Crypto,
+		macCrypto:            edvCrypto,
 		vcIDIndexNameEncoded: vcIDIndexNameMACEncoded,
 		commonDID: commondid.New(&commondid.Config{VDRI: config.VDRI, KeyManager: config.KeyManager,
-			Domain: config.Domain, TLSConfig: config.TLSConfig}),
+			Domain: config.Domain, TLSConfig: config.TLSConfig, HostURL: config.HostURL,
+			HTTPClients: config.HTTPClients, UniRegistrarHTTPClients: config.UniRegistrarHTTPClients}),
+		webDIDDocs:      config.WebDIDDocs,
 		retryParameters: config.RetryParameters,
+		claimsSource:    config.ClaimsSource,
+		rateLimit:       config.RateLimit,
+	}
+
+	err = svc.prepareListIndexNames()
+	if err != nil {
+		return nil, err
+	}
+
+	historyStore, err := history.New(statusStoreProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate status history: %w", err)
+	}
+
+	statusHistory := publishToRegistries(publishStatusChanges(historyStore, config.Events), config.StatusPublisher,
+		vcStatusManager)
+
+	expiryScheduler, err := expiry.New(config.StoreProvider, vcStatusManager, p,
+		expiry.WithStatusHistory(statusHistory))
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate expiry scheduler: %w", err)
+	}
+
+	if config.ExpiryCheckInterval > 0 {
+		expiryScheduler.Start(config.ExpiryCheckInterval)
+	}
+
+	manifests, err := manifest.New(config.StoreProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate manifest store: %w", err)
+	}
+
+	idempotency, err := commhttp.NewIdempotencyStore(config.StoreProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate idempotency store: %w", err)
+	}
+
+	quotas, err := quota.New(config.StoreProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate issuance quota tracker: %w", err)
+	}
+
+	holderBinding, err := binding.New(config.StoreProvider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate holder binding store: %w", err)
+	}
+
+	challenges, err := vcchallenge.New(config.StoreProvider, vcchallenge.DefaultTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate challenge store: %w", err)
+	}
+
+	exchangeTTL := config.ExchangeTTL
+	if exchangeTTL == 0 {
+		exchangeTTL = exchange.DefaultTTL
+	}
+
+	exchanges, err := exchange.New(config.StoreProvider, exchangeTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate exchange store: %w", err)
+	}
+
+	reencryptionStore, err := openStore(config.StoreProvider, reencryptionStoreName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reencryption store: %w", err)
+	}
+
+	svc.expiryLog = expiryScheduler
+	svc.manifests = manifests
+	svc.idempotency = idempotency
+	svc.quotas = quotas
+	svc.holderBinding = holderBinding
+	svc.challenges = challenges
+	svc.exchanges = exchanges
+	svc.reencryptionStore = reencryptionStore
+	svc.reencrypting = map[string]struct{}{}
+	svc.creatingProfiles = map[string]struct{}{}
+	svc.statusHistory = statusHistory
+	svc.cslCacheTTL = config.CSLCacheTTL
+	svc.credentialRefURLs = config.CredentialRefURLs
+	svc.credentialRefClient = credentialref.NewClient(config.HTTPClients)
+	svc.timestamper = config.Timestamper
+	svc.events = config.Events
+	svc.statusPublisher = config.StatusPublisher
+	svc.didAnchoringTimeout = config.DIDAnchoringTimeout
+	svc.didPollInterval = didAnchoringPollInterval
+
+	if config.HTTPClients != nil {
+		svc.documentLoader = config.HTTPClients.DocumentLoader()
+	}
+
+	svc.policyEngine = config.PolicyEngine
+	if svc.policyEngine == nil {
+		svc.policyEngine = policy.New()
 	}
 
 	return svc, nil
@@ -173,25 +521,158 @@ type Config struct {
 	TLSConfig          *tls.Config
 	Crypto             ariescrypto.Crypto
 	RetryParameters    *retry.Params
+	ClaimsSource       claimsSource
+	// StatusStoreProvider stores the credential status lists and the status history of the credentials, apart from
+	// the profiles since the status updates are write heavy. StoreProvider is used if not set.
+	StatusStoreProvider storage.Provider
+	// EDVKeyManager and EDVCrypto are used for the encryption of the documents stored in the EDV and
+	// the MAC of their indexes. They default to KeyManager and Crypto, and must be Tink based (e.g. localkms)
+	// when KeyManager is a remote kms.
+	EDVKeyManager keyManager
+	EDVCrypto     ariescrypto.Crypto
+	// ProfileCache caches the profiles read by the credential operations (optional).
+	ProfileCache cache.Cache
+	// CredentialStorage is where the credentials of the profiles not setting it are stored:
+	// CredentialStorageEDV (default) or CredentialStorageLocal.
+	CredentialStorage string
+	// DuplicateCredentials is what happens when a credential already stored is stored again:
+	// DuplicateCredentialsAllow (default), DuplicateCredentialsReject, DuplicateCredentialsOverwrite or
+	// DuplicateCredentialsVersion.
+	DuplicateCredentials string
+	// VaultIDScheme is how the vaults of the new profiles are keyed: VaultIDSchemeName (default),
+	// VaultIDSchemePrefix or VaultIDSchemeUUID.
+	VaultIDScheme string
+	// VaultIDPrefix is the prefix of the vault IDs of the instance with VaultIDSchemePrefix.
+	VaultIDPrefix string
+	// DuplicateVCsCleanupDryRun only logs the extra copies of the identical VCs found on retrieval, instead of
+	// deleting them.
+	DuplicateVCsCleanupDryRun bool
+	// RateLimit limits the rate of the issuance requests (optional).
+	RateLimit *ratelimit.Config
+	// WebDIDDocs are the did:web documents of the profiles served by the service (e.g. the did:web vdri),
+	// required for the did:web profiles.
+	WebDIDDocs webDIDDocs
+	// ExpiryCheckInterval is the interval of the revocation of the expired credentials issued under the profiles
+	// with RevokeOnExpiry. Zero disables the scheduler, the credentials are still logged.
+	ExpiryCheckInterval time.Duration
+	// CSLCacheTTL is how long the credential status lists are cached, also advertised to the clients through the
+	// Cache-Control header. The lists are not cached if not set.
+	CSLCacheTTL time.Duration
+	// CSLShards is the number of active credential status lists of each profile, the status IDs being created in
+	// the lists in turn. A list filled rolls over to a new list. Defaults to one list.
+	CSLShards int
+	// PolicyEngine evaluates the policy rules of the profiles, with the built-in evaluators by default.
+	PolicyEngine *policy.Engine
+	// JWEEncAlg and JWEKeyWrapAlg are the content encryption and key wrapping algorithms of the documents stored
+	// in the EDV, A256GCM and ECDH-ES+A256KW by default. The documents encrypted with the algorithms configured
+	// before stay readable.
+	JWEEncAlg     string
+	JWEKeyWrapAlg string
+	// HTTPClients creates the clients of the outbound calls, e.g. to the uni-registrar, and the loader of the JSON-LD
+	// contexts of the canonicalized credentials (optional).
+	HTTPClients *httpclient.Factory
+	// UniRegistrarHTTPClients creates the clients of the uni-registrar requests, in their own trust domain
+	// (optional, HTTPClients by default).
+	UniRegistrarHTTPClients *httpclient.Factory
+	// CredentialRefURLs are the URLs under which the credentials issued by reference may be fetched, matched by
+	// scheme, host and path prefix. The credentials can't be referenced by URL if not set.
+	CredentialRefURLs []*url.URL
+	// Timestamper time-stamps the credentials issued under the profiles with AnchorCredentials, the profiles
+	// can't anchor their credentials if not set.
+	Timestamper timestamper
+	// ExchangeTTL is how long the issuance exchanges can be completed by the holders, exchange.DefaultTTL if not
+	// set.
+	ExchangeTTL time.Duration
+	// Events emits the issuance and status change events to a message broker (optional).
+	Events *events.Emitter
+	// StatusPublisher publishes the status changes to the external revocation registries, in addition to the status
+	// lists (optional).
+	StatusPublisher *registry.Publisher
+	// DIDAnchoringTimeout is how long the creation of a profile waits for its DID, created through the Sidetree node
+	// or the uni-registrar, to be resolvable. The DID is resolved once if not set. The credentials can't be issued
+	// under the profile until its DID is resolvable.
+	DIDAnchoringTimeout time.Duration
+}
+
+// edvJWEAlgorithm returns the configured JWE algorithm of the documents stored in the EDV
+func edvJWEAlgorithm(config *Config) cryptosetup.JWEAlgorithm {
+	algorithm := cryptosetup.DefaultJWEAlgorithm
+
+	if config.JWEEncAlg != "" {
+		algorithm.Enc = jose.EncAlg(config.JWEEncAlg)
+	}
+
+	if config.JWEKeyWrapAlg != "" {
+		algorithm.KeyWrap = config.JWEKeyWrapAlg
+	}
+
+	return algorithm
 }
 
 // Operation defines handlers for Edge service
 type Operation struct {
 	profileStore         *vcprofile.Profile
 	edvClient            EDVClient
+	localEDVClient       EDVClient
+	credentialStorage    string
+	duplicateCredentials string
+	cleanupDryRun        bool
+	vaultIDScheme        string
+	vaultIDPrefix        string
 	kms                  keyManager
+	kmsSecretsProvider   ariesstorage.Provider
+	generatedKeys        storage.Store
+	storeProvider        storage.Provider
 	vdri                 vdriapi.Registry
 	crypto               *crypto.Crypto
-	jweEncrypter         jose.Encrypter
-	jweDecrypter         jose.Decrypter
+	// returns the keys of the profile encrypting its documents stored in the EDV
+	profileJWECrypto     func(profileName string) (jweCrypto, error)
 	vcStatusManager      vcStatusManager
 	domain               string
 	HostURL              string
 	macKeyHandle         *keyset.Handle
 	macCrypto            ariescrypto.Crypto
 	vcIDIndexNameEncoded string
-	commonDID            commonDID
-	retryParameters      *retry.Params
+	// the indexes of the stored credentials used for listing them
+	vcTypeIndexNameEncoded     string
+	vcSubjectIndexNameEncoded  string
+	vcIssuedIndexNameEncoded   string
+	vcStoredIndexNameEncoded   string
+	vcHashlinkIndexNameEncoded string
+	commonDID                  commonDID
+	webDIDDocs                 webDIDDocs
+	retryParameters            *retry.Params
+	claimsSource               claimsSource
+	rateLimit                  *ratelimit.Config
+	expiryLog                  expiryLog
+	cslCacheTTL                time.Duration
+	statusHistory              statusHistory
+	policyEngine               *policy.Engine
+	manifests                  manifestStore
+	idempotency                *commhttp.IdempotencyStore
+	quotas                     *quota.Tracker
+	holderBinding              holderBindingStore
+	challenges                 challengeStore
+	exchanges                  exchangeStore
+	// the re-encrypted document of each profile, kept until it replaces the stored document
+	reencryptionStore storage.Store
+	// the allowed URLs of the credentials issued by reference, and the client fetching them
+	credentialRefURLs   []*url.URL
+	credentialRefClient *http.Client
+	timestamper         timestamper
+	events              *events.Emitter
+	statusPublisher     *registry.Publisher
+	// the wait for the DIDs of the new profiles to be anchored, and the interval of their resolutions
+	didAnchoringTimeout time.Duration
+	didPollInterval     time.Duration
+	// the loader of the JSON-LD contexts of the canonicalized credentials, the default loader if nil
+	documentLoader ld.DocumentLoader
+	// the profiles whose credentials are being re-encrypted
+	reencryptionMutex sync.Mutex
+	reencrypting      map[string]struct{}
+	// the profiles being created, whose names are reserved
+	profileCreationMutex sync.Mutex
+	creatingProfiles     map[string]struct{}
 }
 
 // GetRESTHandlers get all controller API handler available for this service
@@ -200,40 +681,164 @@ func (o *Operation) GetRESTHandlers() []Handler {
 		// issuer profile
 		support.NewHTTPHandler(createProfileEndpoint, http.MethodPost, o.createIssuerProfileHandler),
 		support.NewHTTPHandler(getProfileEndpoint, http.MethodGet, o.getIssuerProfileHandler),
+		support.NewHTTPHandler(rotateKeyEndpoint, http.MethodPost, o.rotateKeyHandler),
+		support.NewHTTPHandler(addKeyEndpoint, http.MethodPost, o.addKeyHandler),
+		support.NewHTTPHandler(updateDIDEndpoint, http.MethodPatch, o.updateDIDHandler),
+		support.NewHTTPHandler(updateDIDEndpoint, http.MethodGet, o.didStatusHandler),
+		support.NewHTTPHandler(exportProfileEndpoint, http.MethodPost, o.exportProfileHandler),
+		support.NewHTTPHandler(importProfileEndpoint, http.MethodPost, o.importProfileHandler),
+		support.NewHTTPHandler(issuanceUsageEndpoint, http.MethodGet, o.issuanceUsageHandler),
+		support.NewHTTPHandler(statusListsEndpoint, http.MethodGet, o.statusListsHandler),
+		support.NewHTTPHandler(migrateVaultEndpoint, http.MethodPost, o.migrateVaultHandler),
+		support.NewHTTPHandler(statusPublicationsEndpoint, http.MethodGet, o.statusPublicationsHandler),
+		support.NewHTTPHandler(reconcilePublicationsEndpoint, http.MethodPost, o.reconcileStatusPublicationsHandler),
+		support.NewHTTPHandler(manifestsPath, http.MethodPost, o.createManifestHandler),
+		support.NewHTTPHandler(manifestsPath, http.MethodGet, o.listManifestsHandler),
+		support.NewHTTPHandler(manifestPath, http.MethodGet, o.getManifestHandler),
+		support.NewHTTPHandler(manifestPath, http.MethodPut, o.updateManifestHandler),
+		support.NewHTTPHandler(manifestPath, http.MethodDelete, o.deleteManifestHandler),
+		// the well-known document is registered first, the path-based documents matching it too
+		support.NewHTTPHandler(wellKnownDIDEndpoint, http.MethodGet, o.webDIDDocumentHandler),
+		support.NewHTTPHandler(webDIDDocumentEndpoint, http.MethodGet, o.webDIDDocumentHandler),
 
 		// verifiable credential store
 		support.NewHTTPHandler(storeCredentialEndpoint, http.MethodPost, o.storeCredentialHandler),
 		support.NewHTTPHandler(retrieveCredentialEndpoint, http.MethodGet, o.retrieveCredentialHandler),
+		support.NewHTTPHandler(credentialsBasePath, http.MethodGet, o.listCredentialsHandler),
+		support.NewHTTPHandler(exportCredentialsPath, http.MethodGet, o.exportCredentialsHandler),
+		support.NewHTTPHandler(importCredentialsPath, http.MethodPost, o.importCredentialsHandler),
+		support.NewHTTPHandler(reencryptCredentialsPath, http.MethodPost, o.reencryptCredentialsHandler),
+		support.NewHTTPHandler(attachmentPath, http.MethodGet, o.evidenceAttachmentHandler),
+		support.NewHTTPHandler(hashlinkPath, http.MethodGet, o.hashlinkCredentialHandler),
 
 		// verifiable credential status
-		support.NewHTTPHandler(updateCredentialStatusEndpoint, http.MethodPost, o.updateCredentialStatusHandler),
+		support.NewHTTPHandler(updateCredentialStatusEndpoint, http.MethodPost,
+			commhttp.Idempotent(o.idempotency, o.updateCredentialStatusHandler)),
 		support.NewHTTPHandler(credentialStatusEndpoint, http.MethodGet, o.retrieveCredentialStatus),
+		support.NewHTTPHandler(profileCredentialStatusPath, http.MethodGet, o.retrieveCredentialStatus),
+		support.NewHTTPHandler(revokeCredentialEndpoint, http.MethodPost, o.revokeCredentialHandler),
+		support.NewHTTPHandler(suspendCredentialEndpoint, http.MethodPost, o.suspendCredentialHandler),
+		support.NewHTTPHandler(reinstateCredentialEndpoint, http.MethodPost, o.reinstateCredentialHandler),
+		support.NewHTTPHandler(statusHistoryPath, http.MethodGet, o.statusHistoryHandler),
+		support.NewHTTPHandler(holderBindingChallengePath, http.MethodPost, o.holderBindingChallengeHandler),
+		support.NewHTTPHandler(holderBindingPath, http.MethodGet, o.holderBindingHandler),
+		support.NewHTTPHandler(canonicalizeCredentialEndpoint, http.MethodPost, o.canonicalizeCredentialHandler),
+		support.NewHTTPHandler(compareCredentialsEndpoint, http.MethodPost, o.compareCredentialsHandler),
 
 		// issuer apis
-		support.NewHTTPHandler(generateKeypairPath, http.MethodGet, o.generateKeypairHandler),
-		support.NewHTTPHandler(issueCredentialPath, http.MethodPost, o.issueCredentialHandler),
-		support.NewHTTPHandler(composeAndIssueCredentialPath, http.MethodPost, o.composeAndIssueCredentialHandler),
+		support.NewHTTPHandler(generateKeypairPath, http.MethodPost, o.generateKeypairHandler),
+		support.NewHTTPHandler(deleteKeyPath, http.MethodDelete, o.deleteKeyHandler),
+		support.NewHTTPHandler(issueCredentialPath, http.MethodPost,
+			commhttp.RateLimit(o.rateLimit, profileIDPathParam,
+				commhttp.Idempotent(o.idempotency, o.issueCredentialHandler))),
+		support.NewHTTPHandler(issueCredentialBatchPath, http.MethodPost,
+			commhttp.RateLimit(o.rateLimit, profileIDPathParam,
+				commhttp.Idempotent(o.idempotency, o.issueCredentialBatchHandler))),
+		support.NewHTTPHandler(issueMDocPath, http.MethodPost,
+			commhttp.RateLimit(o.rateLimit, profileIDPathParam,
+				commhttp.Idempotent(o.idempotency, o.issueMDocHandler))),
+		support.NewHTTPHandler(composeAndIssueCredentialPath, http.MethodPost,
+			commhttp.RateLimit(o.rateLimit, profileIDPathParam,
+				commhttp.Idempotent(o.idempotency, o.composeAndIssueCredentialHandler))),
+		support.NewHTTPHandler(endorseCredentialPath, http.MethodPost,
+			commhttp.RateLimit(o.rateLimit, profileIDPathParam, o.endorseCredentialHandler)),
+		support.NewHTTPHandler(exchangesPath, http.MethodPost,
+			commhttp.RateLimit(o.rateLimit, profileIDPathParam,
+				commhttp.Idempotent(o.idempotency, o.createExchangeHandler))),
+		support.NewHTTPHandler(exchangePath, http.MethodGet, o.getExchangeHandler),
+		support.NewHTTPHandler(exchangePath, http.MethodPost,
+			commhttp.RateLimit(o.rateLimit, profileIDPathParam, o.interactExchangeHandler)),
 	}
 }
 
-// RetrieveCredentialStatus swagger:route GET /status/{id} issuer retrieveCredentialStatusReq
+// RetrieveCredentialStatus swagger:route GET /status/{profileID}/{id} issuer retrieveCredentialStatusReq
 //
-// Retrieves the credential status.
+// Retrieves the credential status list of the profile, signed by the profile as a status list credential. The list
+// is served with an ETag, a request with a matching If-None-Match header gets a 304 response. The lists shared by all
+// the profiles, created before the lists were scoped per profile, are served at /status/{id}.
 //
 // Responses:
 //    default: genericError
 //        200: retrieveCredentialStatusResp
 func (o *Operation) retrieveCredentialStatus(rw http.ResponseWriter, req *http.Request) {
-	csl, err := o.vcStatusManager.GetCSL(o.HostURL + req.RequestURI)
+	// the ID of the list is its URL under the external URL of the issuer, whatever the path of the request behind a
+	// reverse proxy or with the path prefix of the mode
+	segments := []string{mux.Vars(req)["id"]}
+	if profileID := mux.Vars(req)[profileIDPathParam]; profileID != "" {
+		segments = []string{profileID, segments[0]}
+	}
+
+	cslBytes, err := o.getSignedCSL(o.credentialStatusURL(segments...))
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest,
-			fmt.Sprintf("failed to get credential status list: %s", err.Error()))
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	hash := sha256.Sum256(cslBytes)
+	etag := `"` + base64.RawURLEncoding.EncodeToString(hash[:]) + `"`
+
+	rw.Header().Set("ETag", etag)
+	rw.Header().Set("Cache-Control", o.cslCacheControl())
+
+	if matchesETag(req.Header.Get("If-None-Match"), etag) {
+		rw.WriteHeader(http.StatusNotModified)
 
 		return
 	}
 
+	rw.Header().Set("Content-Type", "application/json")
 	rw.WriteHeader(http.StatusOK)
-	commhttp.WriteResponse(rw, csl)
+
+	if _, err := rw.Write(cslBytes); err != nil {
+		logger.Errorf("Failed to write credential status list: %s", err.Error())
+	}
+}
+
+// GetCredentialStatus returns the credential status list of the given ID (e.g. {profileID}/1), signed by its profile
+// as a status list credential.
+func (o *Operation) GetCredentialStatus(id string) ([]byte, error) {
+	return o.getSignedCSL(o.credentialStatusURL(strings.Split(id, "/")...))
+}
+
+// credentialStatusURL returns the URL of the credential status list of the path segments (e.g. {profileID}, 1)
+func (o *Operation) credentialStatusURL(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for i := range segments {
+		escaped[i] = url.PathEscape(segments[i])
+	}
+
+	return o.HostURL + credentialStatus + "/" + strings.Join(escaped, "/")
+}
+
+func (o *Operation) getSignedCSL(id string) ([]byte, error) {
+	cslBytes, err := o.vcStatusManager.GetSignedCSL(id)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.NotFound,
+			fmt.Sprintf("failed to get credential status list: %s", err.Error()))
+	}
+
+	return cslBytes, nil
+}
+
+func (o *Operation) cslCacheControl() string {
+	if o.cslCacheTTL <= 0 {
+		return "no-cache"
+	}
+
+	return fmt.Sprintf("public, max-age=%d", int(o.cslCacheTTL.Seconds()))
+}
+
+// matchesETag checks the If-None-Match header value, a list of ETags or "*", against the ETag
+func matchesETag(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+
+	return false
 }
 
 // UpdateCredentialStatus swagger:route POST /updateStatus issuer updateCredentialStatusReq
@@ -245,627 +850,4098 @@ func (o *Operation) retrieveCredentialStatus(rw http.ResponseWriter, req *http.R
 //        200: emptyRes
 func (o *Operation) updateCredentialStatusHandler(rw http.ResponseWriter, req *http.Request) {
 	data := UpdateCredentialStatusRequest{}
-	err := json.NewDecoder(req.Body).Decode(&data)
+	if err := commhttp.DecodeJSON(req, &data); err != nil {
+		commhttp.WriteError(rw, err)
+		return
+	}
 
+	err := o.UpdateCredentialStatus(&data, apikey.Client(req), rw.Header().Get(support.CorrelationIDHeader))
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest,
-			fmt.Sprintf("failed to decode request received: %s", err.Error()))
+		commhttp.WriteError(rw, err)
+
 		return
 	}
 
+	rw.WriteHeader(http.StatusOK)
+}
+
+// UpdateCredentialStatus updates the status of the credential, recorded in its status history as changed by the
+// client authenticated by its API key (empty if anonymous) in the request of the correlation ID.
+func (o *Operation) UpdateCredentialStatus(data *UpdateCredentialStatusRequest, client, correlationID string) error {
 	// TODO https://github.com/trustbloc/edge-service/issues/208 credential is bundled into string type - update
 	//  this to json.RawMessage
-	vc, err := o.parseAndVerifyVC([]byte(data.Credential))
+	vc, profile, err := o.getStatusCredentialAndProfile(data.Credential)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest,
-			fmt.Sprintf("unable to unmarshal the VC: %s", err.Error()))
-		return
+		return err
 	}
 
-	// get profile
-	profile, err := o.profileStore.GetProfile(vc.Issuer.CustomFields["name"].(string))
-	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest,
-			fmt.Sprintf("failed to get profile: %s", err.Error()))
-		return
-	}
+	if err := o.updateVCStatus(vc, profile, data.Status, data.StatusReason); err != nil {
+		if errors.Is(err, cslstatus.ErrInvalidStatusTransition) || errors.Is(err, cslstatus.ErrConcurrentUpdate) {
+			return commhttp.NewError(http.StatusConflict, commhttp.Conflict, err.Error())
+		}
 
-	if profile.DisableVCStatus {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest,
-			fmt.Sprintf("vc status is disabled for profile %s", profile.Name))
-		return
+		return commhttp.NewError(http.StatusBadRequest, commhttp.InternalError,
+			fmt.Sprintf("failed to update vc status: %s", err.Error()))
 	}
 
-	if err := o.vcStatusManager.UpdateVCStatus(vc, profile, data.Status, data.StatusReason); err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest,
-			fmt.Sprintf("failed to update vc status: %s", err.Error()))
-		return
+	return o.recordStatusChange(profile.Name, vc.ID, data.Status, data.StatusReason, client, correlationID)
+}
+
+// updateVCStatus applies the status of the legacy update endpoint, the revoked, suspended and active statuses go
+// through the same transitions as their dedicated endpoints
+func (o *Operation) updateVCStatus(vc *verifiable.Credential, profile *vcprofile.DataProfile,
+	status, statusReason string) error {
+	switch status {
+	case cslstatus.StatusRevoked:
+		return o.vcStatusManager.RevokeVC(vc, profile, statusReason)
+	case cslstatus.StatusSuspended:
+		return o.vcStatusManager.SuspendVC(vc, profile, statusReason)
+	case cslstatus.StatusActive:
+		return o.vcStatusManager.ReinstateVC(vc, profile)
+	default:
+		return o.vcStatusManager.UpdateVCStatus(vc, profile, status, statusReason)
 	}
+}
 
-	rw.WriteHeader(http.StatusOK)
+// RevokeCredential swagger:route POST /credentials/revoke issuer changeCredentialStatusReq
+//
+// Revokes an active or suspended credential. Revocation is permanent.
+//
+// Responses:
+//    default: genericError
+//        200: emptyRes
+func (o *Operation) revokeCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	o.changeCredentialStatus(rw, req, cslstatus.StatusRevoked, func(vc *verifiable.Credential,
+		profile *vcprofile.DataProfile, reason string) error {
+		return o.vcStatusManager.RevokeVC(vc, profile, reason)
+	})
 }
 
-// CreateIssuerProfile swagger:route POST /profile issuer issuerProfileReq
+// SuspendCredential swagger:route POST /credentials/suspend issuer changeCredentialStatusReq
 //
-// Creates issuer profile.
+// Suspends an active credential until it is reinstated.
 //
 // Responses:
 //    default: genericError
-//        201: issuerProfileRes
-func (o *Operation) createIssuerProfileHandler(rw http.ResponseWriter, req *http.Request) {
-	data := ProfileRequest{}
+//        200: emptyRes
+func (o *Operation) suspendCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	o.changeCredentialStatus(rw, req, cslstatus.StatusSuspended, func(vc *verifiable.Credential,
+		profile *vcprofile.DataProfile, reason string) error {
+		return o.vcStatusManager.SuspendVC(vc, profile, reason)
+	})
+}
+
+// ReinstateCredential swagger:route POST /credentials/reinstate issuer changeCredentialStatusReq
+//
+// Reinstates a suspended credential.
+//
+// Responses:
+//    default: genericError
+//        200: emptyRes
+func (o *Operation) reinstateCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	o.changeCredentialStatus(rw, req, cslstatus.StatusActive, func(vc *verifiable.Credential,
+		profile *vcprofile.DataProfile, _ string) error {
+		return o.vcStatusManager.ReinstateVC(vc, profile)
+	})
+}
 
-	if err := json.NewDecoder(req.Body).Decode(&data); err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidRequestErrMsg+": %s", err.Error()))
+// changeCredentialStatus applies the status change of the request, recorded in the status history of the
+// credential as the given status.
+func (o *Operation) changeCredentialStatus(rw http.ResponseWriter, req *http.Request, status string,
+	change func(*verifiable.Credential, *vcprofile.DataProfile, string) error) {
+	data := ChangeCredentialStatusRequest{}
 
+	if err := commhttp.DecodeJSON(req, &data); err != nil {
+		commhttp.WriteError(rw, err)
 		return
 	}
 
-	if err := validateProfileRequest(&data); err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+	vc, profile, err := o.getStatusCredentialAndProfile(data.Credential)
+	if err != nil {
+		commhttp.WriteError(rw, err)
 
 		return
 	}
 
-	profile, err := o.createIssuerProfile(&data)
-	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+	if err := change(vc, profile, data.StatusReason); err != nil {
+		if errors.Is(err, cslstatus.ErrInvalidStatusTransition) || errors.Is(err, cslstatus.ErrConcurrentUpdate) {
+			commhttp.WriteErrorResponse(rw, http.StatusConflict, commhttp.Conflict, err.Error())
+			return
+		}
+
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to update vc status: %s", err.Error()))
 
 		return
 	}
 
-	err = o.profileStore.SaveProfile(profile)
+	err = o.recordStatusChange(profile.Name, vc.ID, status, data.StatusReason, apikey.Client(req),
+		rw.Header().Get(support.CorrelationIDHeader))
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+		commhttp.WriteError(rw, err)
 
 		return
 	}
 
-	// create the vault associated with the profile
-	_, err = o.edvClient.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: profile.Name})
+	rw.WriteHeader(http.StatusOK)
+}
+
+// recordStatusChange records the status change in the status history of the credential. The status is already
+// changed, so the request fails when the change can't be recorded rather than silently losing the history entry.
+func (o *Operation) recordStatusChange(profile, vcID, status, statusReason, client, correlationID string) error {
+	err := o.statusHistory.Record(profile, vcID, &history.StatusChange{
+		Status:        status,
+		StatusReason:  statusReason,
+		Actor:         statusChangeActor(client),
+		CorrelationID: correlationID,
+		Time:          time.Now().UTC(),
+	})
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("credential status changed to %s but failed to record it in the status history: %s",
+				status, err.Error()))
+	}
 
-		return
+	return nil
+}
+
+// statusChangeActor identifies the client changing the status: the client authenticated by its API key, or
+// anonymous.
+func statusChangeActor(client string) string {
+	if client == "" {
+		return anonymousActor
 	}
 
-	rw.WriteHeader(http.StatusCreated)
-	commhttp.WriteResponse(rw, profile)
+	return "client:" + client
 }
 
-// RetrieveIssuerProfile swagger:route GET /profile/{id} issuer retrieveProfileReq
+// StatusHistory swagger:route GET /{profileID}/credentials/{credentialID}/statusHistory issuer statusHistoryReq
 //
-// Retrieves issuer profile.
+// Retrieves the status changes of a credential issued under the profile, oldest first, with who changed the
+// status, when and why. The credential ID is base64url encoded (without padding) in the path.
 //
 // Responses:
 //    default: genericError
-//        200: issuerProfileRes
-func (o *Operation) getIssuerProfileHandler(rw http.ResponseWriter, req *http.Request) {
-	profileID := mux.Vars(req)["id"]
+//        200: statusHistoryRes
+func (o *Operation) statusHistoryHandler(rw http.ResponseWriter, req *http.Request) {
+	profileID := mux.Vars(req)[profileIDPathParam]
 
-	profileResponseJSON, err := o.profileStore.GetProfile(profileID)
+	vcID, err := base64.RawURLEncoding.DecodeString(mux.Vars(req)[credentialIDPathParam])
 	if err != nil {
-		if errors.Is(err, errProfileNotFound) {
-			commhttp.WriteErrorResponse(rw, http.StatusNotFound, "Failed to find the profile")
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("invalid credential ID, expected base64url encoding: %s", err.Error()))
+
+		return
+	}
+
+	profile, err := o.profileStore.GetProfile(profileID)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid issuer profile - id=%s: err=%s", profileID, err.Error()))
+
+		return
+	}
+
+	changes, err := o.statusHistory.Get(profile.Name, string(vcID))
+	if err != nil {
+		if errors.Is(err, storage.ErrValueNotFound) {
+			commhttp.WriteErrorResponse(rw, http.StatusNotFound, commhttp.NotFound,
+				fmt.Sprintf("no status change recorded for credential %s", vcID))
 
 			return
 		}
 
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
 
 		return
 	}
 
-	commhttp.WriteResponse(rw, profileResponseJSON)
+	commhttp.WriteResponse(rw, &StatusHistoryResponse{ID: string(vcID), StatusChanges: changes})
 }
 
-// StoreVerifiableCredential swagger:route POST /store issuer storeCredentialReq
+// CreateManifest swagger:route POST /{profileID}/manifests issuer createManifestReq
 //
-// Stores a credential.
+// Creates a credential manifest of the profile, describing the credentials it issues and the inputs it requires. The
+// issuer of the manifest is the DID of the profile.
 //
 // Responses:
 //    default: genericError
-//        200: emptyRes
-func (o *Operation) storeCredentialHandler(rw http.ResponseWriter, req *http.Request) {
-	data := &StoreVCRequest{}
-
-	err := json.NewDecoder(req.Body).Decode(&data)
+//        201: manifestRes
+func (o *Operation) createManifestHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, m, err := o.decodeManifest(req)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidRequestErrMsg+": %s", err.Error()))
+		commhttp.WriteError(rw, err)
 
 		return
 	}
 
-	// TODO https://github.com/trustbloc/edge-service/issues/208 credential is bundled into string type - update
-	//  this to json.RawMessage
-	vc, err := o.parseAndVerifyVC([]byte(data.Credential))
-	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest,
-			fmt.Sprintf("unable to unmarshal the VC: %s", err.Error()))
+	_, err = o.manifests.Get(profile.Name, m.ID)
+	if err == nil {
+		commhttp.WriteErrorResponse(rw, http.StatusConflict, commhttp.AlreadyExists,
+			fmt.Sprintf("manifest %s already exists", m.ID))
+
 		return
 	}
 
-	// TODO https://github.com/trustbloc/edge-service/issues/417 add profileID to the path param rather than the body
-	if err = validateRequest(data.Profile, vc.ID); err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+	if !errors.Is(err, storage.ErrValueNotFound) {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
 
 		return
 	}
 
-	o.storeVC(data, vc, rw)
+	if err := o.manifests.Put(profile.Name, m); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, m)
 }
 
-// ToDo: data.Credential and vc seem to contain the same data... do they both need to be passed in?
-// https://github.com/trustbloc/edge-service/issues/265
-func (o *Operation) storeVC(data *StoreVCRequest, vc *verifiable.Credential, rw http.ResponseWriter) {
-	doc, err := vcutil.BuildStructuredDocForStorage([]byte(data.Credential))
+// ListManifests swagger:route GET /{profileID}/manifests issuer listManifestsReq
+//
+// Lists the credential manifests of the profile, in the order they were created.
+//
+// Responses:
+//    default: genericError
+//        200: listManifestsRes
+func (o *Operation) listManifestsHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+		commhttp.WriteError(rw, err)
 
 		return
 	}
 
-	encryptedDocument, err := o.buildEncryptedDoc(doc, vc.ID)
+	manifests, err := o.manifests.List(profile.Name)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, err.Error())
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
 
 		return
 	}
 
-	_, err = o.edvClient.CreateDocument(data.Profile, &encryptedDocument)
+	commhttp.WriteResponse(rw, &ListManifestsResponse{Manifests: manifests})
+}
 
-	if err != nil && strings.Contains(err.Error(), messages.ErrVaultNotFound.Error()) {
-		// create the new vault for this profile, if it doesn't exist
-		_, err = o.edvClient.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: data.Profile})
-		if err == nil {
-			_, err = o.edvClient.CreateDocument(data.Profile, &encryptedDocument)
-		}
+// GetManifest swagger:route GET /{profileID}/manifests/{manifestID} issuer getManifestReq
+//
+// Retrieves a credential manifest of the profile.
+//
+// Responses:
+//    default: genericError
+//        200: manifestRes
+func (o *Operation) getManifestHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
 	}
 
+	m, err := o.manifests.Get(profile.Name, mux.Vars(req)[manifestIDPathParam])
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, err.Error())
+		writeManifestError(rw, mux.Vars(req)[manifestIDPathParam], err)
 
 		return
 	}
+
+	commhttp.WriteResponse(rw, m)
 }
 
-func (o *Operation) buildEncryptedDoc(structuredDoc *models.StructuredDocument,
-	vcID string) (models.EncryptedDocument, error) {
-	marshalledStructuredDoc, err := json.Marshal(structuredDoc)
-	if err != nil {
+// UpdateManifest swagger:route PUT /{profileID}/manifests/{manifestID} issuer updateManifestReq
+//
+// Replaces a credential manifest of the profile, the ID of the manifest can't change.
+//
+// Responses:
+//    default: genericError
+//        200: manifestRes
+func (o *Operation) updateManifestHandler(rw http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)[manifestIDPathParam]
+
+	profile, m, err := o.decodeManifest(req)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if m.ID != id {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("manifest id %s doesn't match the path manifest id %s", m.ID, id))
+
+		return
+	}
+
+	if _, err := o.manifests.Get(profile.Name, id); err != nil {
+		writeManifestError(rw, id, err)
+
+		return
+	}
+
+	if err := o.manifests.Put(profile.Name, m); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, m)
+}
+
+// DeleteManifest swagger:route DELETE /{profileID}/manifests/{manifestID} issuer deleteManifestReq
+//
+// Deletes a credential manifest of the profile.
+//
+// Responses:
+//    default: genericError
+//        200: emptyRes
+func (o *Operation) deleteManifestHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if err := o.manifests.Delete(profile.Name, mux.Vars(req)[manifestIDPathParam]); err != nil {
+		writeManifestError(rw, mux.Vars(req)[manifestIDPathParam], err)
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+}
+
+// decodeManifest returns the profile of the request and the manifest of its body, issued by the DID of the profile
+// if the manifest has no issuer.
+func (o *Operation) decodeManifest(req *http.Request) (*vcprofile.DataProfile, *manifest.CredentialManifest, error) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m := &manifest.CredentialManifest{}
+
+	if err := commhttp.DecodeJSON(req, m); err != nil {
+		return nil, nil, err
+	}
+
+	if m.Issuer.ID == "" {
+		m.Issuer.ID = profile.DID
+	}
+
+	if m.Issuer.ID != profile.DID {
+		return nil, nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("manifest issuer %s is not the profile DID %s", m.Issuer.ID, profile.DID))
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+	}
+
+	return profile, m, nil
+}
+
+// writeManifestError writes the not found error of a missing manifest, or the storage error
+func writeManifestError(rw http.ResponseWriter, id string, err error) {
+	if errors.Is(err, storage.ErrValueNotFound) {
+		commhttp.WriteErrorResponse(rw, http.StatusNotFound, commhttp.NotFound, fmt.Sprintf("manifest not found: %s", id))
+
+		return
+	}
+
+	commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
+}
+
+// getStatusCredentialAndProfile parses the credential whose status is changed and loads its issuer profile.
+func (o *Operation) getStatusCredentialAndProfile(
+	credential string) (*verifiable.Credential, *vcprofile.DataProfile, error) {
+	vc, err := o.parseAndVerifyVC([]byte(credential))
+	if err != nil {
+		return nil, nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("unable to unmarshal the VC: %s", err.Error()))
+	}
+
+	// get profile
+	profile, err := o.profileStore.GetProfile(vc.Issuer.CustomFields["name"].(string))
+	if err != nil {
+		return nil, nil, commhttp.NewError(http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("failed to get profile: %s", err.Error()))
+	}
+
+	if profile.DisableVCStatus {
+		return nil, nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("vc status is disabled for profile %s", profile.Name))
+	}
+
+	return vc, profile, nil
+}
+
+// CreateIssuerProfile swagger:route POST /profile issuer issuerProfileReq
+//
+// Creates issuer profile.
+//
+// Responses:
+//    default: genericError
+//        201: issuerProfileRes
+func (o *Operation) createIssuerProfileHandler(rw http.ResponseWriter, req *http.Request) {
+	data := ProfileRequest{}
+
+	if err := commhttp.DecodeJSON(req, &data); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	profile, err := o.CreateProfile(&data)
+	if err != nil {
+		var opErr *commhttp.Error
+		if errors.As(err, &opErr) && opErr.Code == commhttp.AlreadyExists {
+			rw.Header().Set("Location", o.profileURL(data.Name))
+		}
+
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, profile)
+}
+
+// CreateProfile validates the profile request, then creates the profile with its DID and its vault. The name of the
+// profile is reserved until it is created, and the resources created for the profile are removed if it can't be.
+func (o *Operation) CreateProfile(data *ProfileRequest) (*vcprofile.DataProfile, error) {
+	if err := validateProfileRequest(data, o.policyEngine); err != nil {
+		return nil, commhttp.NewValidationError(err)
+	}
+
+	if data.AnchorCredentials && o.timestamper == nil {
+		validationErr := &commhttp.ValidationError{}
+		validationErr.Add("/anchorCredentials", "credential anchoring requires a time-stamping authority")
+
+		return nil, commhttp.NewValidationError(validationErr)
+	}
+
+	if err := o.reserveProfileName(data.Name); err != nil {
+		return nil, err
+	}
+
+	defer o.releaseProfileName(data.Name)
+
+	profile, err := o.createIssuerProfile(data)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.DIDError, err.Error())
+	}
+
+	profile.VaultID = o.newVaultID(profile)
+
+	// the issuance is enabled once the DID is resolvable
+	if createsAnchoredDID(data) && !o.waitForDID(profile.DID, o.didAnchoringTimeout) {
+		profile.DIDPending = true
+	}
+
+	err = o.profileStore.SaveProfile(profile)
+	if err != nil {
+		o.rollbackProfile(data, profile, false)
+
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.StorageError, err.Error())
+	}
+
+	edvClient, err := o.profileEDVClient(profile)
+	if err != nil {
+		o.rollbackProfile(data, profile, true)
+
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.EDVError, err.Error())
+	}
+
+	// create the vault associated with the profile
+	_, err = edvClient.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: profile.Name})
+	if err != nil {
+		o.rollbackProfile(data, profile, true)
+
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.EDVError, err.Error())
+	}
+
+	return profile, nil
+}
+
+// IssuanceUsage swagger:route GET /profile/{id}/usage issuer issuanceUsageReq
+//
+// Retrieves the credentials issued under the profile during the current UTC day and month, with its quota.
+//
+// Responses:
+//    default: genericError
+//        200: issuanceUsageRes
+func (o *Operation) issuanceUsageHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.GetProfile(mux.Vars(req)["id"])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	usage, err := o.quotas.Usage(profile.Name, profile.Quota)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to get issuance usage: %s", err.Error()))
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, usage)
+}
+
+// StatusLists swagger:route GET /profile/{id}/statusLists issuer statusListsReq
+//
+// Retrieves the utilization of the credential status lists of the profile: the status IDs created in each list over
+// its size, and the status IDs the active lists can still hold before they roll over to new lists.
+//
+// Responses:
+//    default: genericError
+//        200: statusListsRes
+func (o *Operation) statusListsHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.GetProfile(mux.Vars(req)["id"])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	utilization, err := o.vcStatusManager.Utilization(profile.Name)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to get status lists utilization: %s", err.Error()))
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, utilization)
+}
+
+// RetrieveIssuerProfile swagger:route GET /profile/{id} issuer retrieveProfileReq
+//
+// Retrieves issuer profile.
+//
+// Responses:
+//    default: genericError
+//        200: issuerProfileRes
+func (o *Operation) getIssuerProfileHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.GetProfile(mux.Vars(req)["id"])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, profile)
+}
+
+// GetProfile returns the issuer profile.
+func (o *Operation) GetProfile(id string) (*vcprofile.DataProfile, error) {
+	profile, err := o.profileStore.GetProfile(id)
+	if err != nil {
+		if errors.Is(err, errProfileNotFound) {
+			return nil, commhttp.NewError(http.StatusNotFound, commhttp.ProfileNotFound, "Failed to find the profile")
+		}
+
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.StorageError, err.Error())
+	}
+
+	return profile, nil
+}
+
+// WellKnownDIDDocument swagger:route GET /.well-known/did.json issuer wellKnownDIDDocumentReq
+//
+// Retrieves the DID document of the did:web of the host, created by an issuer profile with the "/" did:web path.
+//
+// Responses:
+//    default: genericError
+//        200: webDIDDocumentRes
+
+// WebDIDDocument swagger:route GET /{path}/did.json issuer webDIDDocumentReq
+//
+// Retrieves the DID document of an issuer profile created with the did:web method, served under the did:web path
+// of the profile (issuer/{profileID} by default). The document lists all the keys of the profile, including the
+// keys added by key rotation.
+//
+// Responses:
+//    default: genericError
+//        200: webDIDDocumentRes
+func (o *Operation) webDIDDocumentHandler(rw http.ResponseWriter, req *http.Request) {
+	if o.webDIDDocs == nil {
+		commhttp.WriteErrorResponse(rw, http.StatusNotFound, commhttp.NotFound, "did:web not supported")
+
+		return
+	}
+
+	var path []string
+
+	if p := mux.Vars(req)[webDIDPathParam]; p != "" {
+		path = strings.Split(p, "/")
+	}
+
+	didID, err := web.DID(o.HostURL, path...)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.InternalError, err.Error())
+
+		return
+	}
+
+	didDoc, err := o.webDIDDocs.Get(didID)
+	if err != nil {
+		if errors.Is(err, web.ErrDocumentNotFound) {
+			commhttp.WriteErrorResponse(rw, http.StatusNotFound, commhttp.NotFound,
+				fmt.Sprintf("did document of %s not found", didID))
+
+			return
+		}
+
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
+
+		return
+	}
+
+	docBytes, err := didDoc.JSONBytes()
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to marshal did document: %s", err.Error()))
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/did+json")
+
+	if _, err := rw.Write(docBytes); err != nil {
+		logger.Errorf("Failed to write did document: %s", err.Error())
+	}
+}
+
+// RotateIssuerProfileKey swagger:route POST /profile/{id}/rotateKey issuer rotateKeyReq
+//
+// Adds a new key to the issuer profile DID and uses it for signing new credentials. The previous keys stay in
+// the DID document so that credentials issued before the rotation can still be verified.
+//
+// Responses:
+//    default: genericError
+//        200: issuerProfileRes
+func (o *Operation) rotateKeyHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, key, ok := o.addProfileKey(rw, req)
+	if !ok {
+		return
+	}
+
+	profile.PreviousCreators = append(profile.PreviousCreators, profile.Creator)
+	profile.Creator = key.ID
+	profile.SignatureType = key.SignatureType
+
+	o.saveProfile(rw, profile)
+}
+
+// AddIssuerProfileKey swagger:route POST /profile/{id}/keys issuer addKeyReq
+//
+// Adds a signing key to the issuer profile DID. The key can be selected for signing through the verification
+// method of the issue credential options.
+//
+// Responses:
+//    default: genericError
+//        200: issuerProfileRes
+func (o *Operation) addKeyHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, key, ok := o.addProfileKey(rw, req)
+	if !ok {
+		return
+	}
+
+	profile.SigningKeys = append(profile.SigningKeys, *key)
+
+	o.saveProfile(rw, profile)
+}
+
+// addProfileKey adds the requested key to the profile DID. The error response is written if it fails.
+func (o *Operation) addProfileKey(rw http.ResponseWriter,
+	req *http.Request) (*vcprofile.DataProfile, *vcprofile.SigningKey, bool) {
+	profileID := mux.Vars(req)["id"]
+
+	data := ProfileKeyRequest{}
+
+	if err := commhttp.DecodeJSON(req, &data); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return nil, nil, false
+	}
+
+	if data.DIDPrivateKey != "" && data.DIDKeyID == "" {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, "missing did key id")
+
+		return nil, nil, false
+	}
+
+	profile, err := o.profileStore.GetProfile(profileID)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid issuer profile: %s", err.Error()))
+
+		return nil, nil, false
+	}
+
+	if data.SignatureType == "" {
+		data.SignatureType = profile.SignatureType
+	}
+
+	if data.DIDKeyType == "" {
+		data.DIDKeyType = crypto.Ed25519KeyType
+	}
+
+	if err = commondid.ValidateKeyType(data.DIDKeyType, data.SignatureType); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+
+		return nil, nil, false
+	}
+
+	publicKeyID, err := o.commonDID.AddKey(profile.DID, data.DIDKeyType, data.SignatureType,
+		data.DIDPrivateKey, data.DIDKeyID, data.UNIRegistrar)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.DIDError,
+			fmt.Sprintf("failed to add key: %s", err.Error()))
+
+		return nil, nil, false
+	}
+
+	return profile, &vcprofile.SigningKey{ID: publicKeyID, SignatureType: data.SignatureType}, true
+}
+
+// UpdateIssuerProfileDID swagger:route PATCH /profile/{id}/did issuer updateDIDReq
+//
+// Adds and removes service endpoints and verification methods of the issuer profile DID, through the uni-registrar
+// or directly for the did:web served by the service. The signing keys of the profile can't be removed.
+//
+// Responses:
+//    default: genericError
+//        200: updateDIDRes
+func (o *Operation) updateDIDHandler(rw http.ResponseWriter, req *http.Request) {
+	data := DIDUpdateRequest{}
+
+	if err := commhttp.DecodeJSON(req, &data); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if err := validateDIDUpdateRequest(&data); err != nil {
+		commhttp.WriteValidationErrorResponse(rw, err)
+
+		return
+	}
+
+	profile, err := o.profileStore.GetProfile(mux.Vars(req)["id"])
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid issuer profile: %s", err.Error()))
+
+		return
+	}
+
+	for _, id := range data.RemoveVerificationMethods {
+		if isProfileSigningKey(profile, id) {
+			commhttp.WriteErrorResponse(rw, http.StatusConflict, commhttp.Conflict,
+				fmt.Sprintf("verification method %s is a signing key of the profile", id))
+
+			return
+		}
+	}
+
+	added, err := o.commonDID.UpdateDID(profile.DID, &data.DIDUpdate, data.UNIRegistrar)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.DIDError,
+			fmt.Sprintf("failed to update did: %s", err.Error()))
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, &DIDUpdateResponse{VerificationMethods: added})
+}
+
+// isProfileSigningKey returns if the key, identified by its ID or fragment, is the creator or a signing key of
+// the profile
+func isProfileSigningKey(profile *vcprofile.DataProfile, id string) bool {
+	keyIDs := []string{profile.Creator}
+
+	for _, key := range profile.SigningKeys {
+		keyIDs = append(keyIDs, key.ID)
+	}
+
+	for _, keyID := range keyIDs {
+		if keyID == id || keyID == profile.DID+"#"+strings.TrimPrefix(id, "#") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o *Operation) saveProfile(rw http.ResponseWriter, profile *vcprofile.DataProfile) {
+	err := o.profileStore.SaveProfile(profile)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, profile)
+}
+
+// ExportIssuerProfile swagger:route POST /profile/{id}/export issuer exportProfileReq
+//
+// Exports issuer profile together with its private keys wrapped with the given key encryption key.
+//
+// Responses:
+//    default: genericError
+//        200: exportProfileRes
+func (o *Operation) exportProfileHandler(rw http.ResponseWriter, req *http.Request) {
+	profileID := mux.Vars(req)["id"]
+
+	data := ExportProfileRequest{}
+
+	if err := commhttp.DecodeJSON(req, &data); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	kek, err := base64.URLEncoding.DecodeString(data.KEK)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("invalid kek: %s", err.Error()))
+
+		return
+	}
+
+	profile, err := o.profileStore.GetProfile(profileID)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid issuer profile: %s", err.Error()))
+
+		return
+	}
+
+	keyIDs, err := profileKeyIDs(profile)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.InternalError, err.Error())
+
+		return
+	}
+
+	var keys []WrappedKey
+
+	for _, keyID := range keyIDs {
+		wrappedKey, err := keyexport.ExportKey(o.kms, keyID, kek)
+		if err != nil {
+			commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.KMSError,
+				fmt.Sprintf("failed to export key: %s", err.Error()))
+
+			return
+		}
+
+		keys = append(keys, WrappedKey{KeyID: keyID, WrappedKey: wrappedKey})
+	}
+
+	commhttp.WriteResponse(rw, &ProfileExport{Profile: profile, Keys: keys})
+}
+
+// ImportIssuerProfile swagger:route POST /profile/import issuer importProfileReq
+//
+// Imports issuer profile exported from another instance. The private keys are unwrapped with the given
+// key encryption key and stored in the KMS, keys already present in the KMS are left untouched. The vault of the
+// profile is created if it doesn't exist yet, and the profile is saved last: a failed import can be retried.
+//
+// Responses:
+//    default: genericError
+//        201: issuerProfileRes
+func (o *Operation) importProfileHandler(rw http.ResponseWriter, req *http.Request) {
+	data := ImportProfileRequest{}
+
+	if err := commhttp.DecodeJSON(req, &data); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if data.Profile == nil || data.Profile.Name == "" {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, "missing profile")
+
+		return
+	}
+
+	kek, err := base64.URLEncoding.DecodeString(data.KEK)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("invalid kek: %s", err.Error()))
+
+		return
+	}
+
+	if _, err = o.profileStore.GetProfile(data.Profile.Name); err == nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.AlreadyExists,
+			fmt.Sprintf("profile %s already exists", data.Profile.Name))
+
+		return
+	}
+
+	for _, key := range data.Keys {
+		if _, err = o.kms.Get(key.KeyID); err == nil {
+			continue
+		}
+
+		if err = keyexport.ImportKey(o.kms, key.KeyID, key.WrappedKey, kek); err != nil {
+			commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.KMSError,
+				fmt.Sprintf("failed to import key: %s", err.Error()))
+
+			return
+		}
+	}
+
+	edvClient, err := o.profileEDVClient(data.Profile)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	// the profile is saved before its vault is created, the EDV requests being signed with the key of the profile
+	// when the EDV server enforces authorization
+	if err = o.profileStore.SaveProfile(data.Profile); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
+
+		return
+	}
+
+	// the vault may already exist: the credentials of the profile are stored in the EDV shared with the
+	// exporting instance, or a previous import failed after creating it
+	_, err = edvClient.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: data.Profile.Name})
+	if err != nil && !strings.Contains(err.Error(), messages.ErrDuplicateVault.Error()) {
+		// the profile is deleted for the import to be retried
+		if errDelete := o.profileStore.DeleteProfile(data.Profile.Name); errDelete != nil {
+			logger.Errorf("failed to delete profile %s: %s", data.Profile.Name, errDelete)
+		}
+
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, data.Profile)
+}
+
+// profileKeyIDs returns the KMS key IDs of all the keys used by the profile.
+func profileKeyIDs(profile *vcprofile.DataProfile) ([]string, error) {
+	verificationMethods := append([]string{profile.Creator}, profile.PreviousCreators...)
+
+	for _, key := range profile.SigningKeys {
+		verificationMethods = append(verificationMethods, key.ID)
+	}
+
+	var keyIDs []string
+
+	seen := make(map[string]bool)
+
+	for _, verificationMethod := range verificationMethods {
+		keyID, err := diddoc.GetKeyIDFromVerificationMethod(verificationMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[keyID] {
+			seen[keyID] = true
+
+			keyIDs = append(keyIDs, keyID)
+		}
+	}
+
+	return keyIDs, nil
+}
+
+// StoreVerifiableCredential swagger:route POST /store issuer storeCredentialReq
+//
+// Stores a credential, or a batch of credentials of the same profile.
+//
+// Responses:
+//    default: genericError
+//        200: emptyRes
+func (o *Operation) storeCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	data := &StoreVCRequest{}
+
+	err := commhttp.DecodeJSON(req, &data)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if len(data.Credentials) > 0 {
+		o.storeVCs(rw, data.Profile, data.Credentials)
+
+		return
+	}
+
+	// TODO https://github.com/trustbloc/edge-service/issues/208 credential is bundled into string type - update
+	//  this to json.RawMessage
+	vc, err := o.parseAndVerifyVC([]byte(data.Credential))
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("unable to unmarshal the VC: %s", err.Error()))
+		return
+	}
+
+	// TODO https://github.com/trustbloc/edge-service/issues/417 add profileID to the path param rather than the body
+	if err = validateRequest(data.Profile, vc.ID); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+
+		return
+	}
+
+	o.storeVC(data, vc, rw)
+}
+
+// ToDo: data.Credential and vc seem to contain the same data... do they both need to be passed in?
+// https://github.com/trustbloc/edge-service/issues/265
+func (o *Operation) storeVC(data *StoreVCRequest, vc *verifiable.Credential, rw http.ResponseWriter) {
+	doc, err := vcutil.BuildStructuredDocForStorage([]byte(data.Credential))
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidCredential, err.Error())
+
+		return
+	}
+
+	encryptedDocument, err := o.buildEncryptedDoc(data.Profile, doc, vc)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	edvClient, err := o.getEDVClient(data.Profile)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	copies, err := o.checkDuplicates(edvClient, data.Profile, &encryptedDocument)
+	if err != nil {
+		writeDuplicatesError(rw, err)
+
+		return
+	}
+
+	_, err = edvClient.CreateDocument(data.Profile, &encryptedDocument)
+
+	if err != nil && strings.Contains(err.Error(), messages.ErrVaultNotFound.Error()) {
+		// create the new vault for this profile, if it doesn't exist
+		_, err = edvClient.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: data.Profile})
+		if err == nil {
+			_, err = edvClient.CreateDocument(data.Profile, &encryptedDocument)
+		}
+	}
+
+	if err != nil {
+		writeDuplicatesError(rw, o.duplicateDocumentError(err))
+
+		return
+	}
+
+	o.deleteReplacedCopies(edvClient, data.Profile, copies)
+}
+
+func (o *Operation) storeVCs(rw http.ResponseWriter, profile string, credentials []string) {
+	edvClient, err := o.getEDVClient(profile)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	var (
+		documents []*models.EncryptedDocument
+		copies    []string
+	)
+
+	// the index in documents of the last VC of the batch with the given ID
+	batchIndex := make(map[string]int)
+
+	for i, credential := range credentials {
+		vc, err := o.parseAndVerifyVC([]byte(credential))
+		if err != nil {
+			commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidCredential,
+				fmt.Sprintf("unable to unmarshal the VC at index %d: %s", i, err.Error()))
+
+			return
+		}
+
+		if err = validateRequest(profile, vc.ID); err != nil {
+			commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+
+			return
+		}
+
+		doc, err := vcutil.BuildStructuredDocForStorage([]byte(credential))
+		if err != nil {
+			commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidCredential, err.Error())
+
+			return
+		}
+
+		encryptedDocument, err := o.buildEncryptedDoc(profile, doc, vc)
+		if err != nil {
+			commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+			return
+		}
+
+		vcCopies, err := o.checkDuplicates(edvClient, profile, &encryptedDocument)
+		if err != nil {
+			writeDuplicatesError(rw, err)
+
+			return
+		}
+
+		if j, ok := batchIndex[vc.ID]; ok {
+			if err = o.batchDuplicate(documents, j, &encryptedDocument); err != nil {
+				writeDuplicatesError(rw, fmt.Errorf("%w: the VC at index %d", err, i))
+
+				return
+			}
+		} else {
+			copies = append(copies, vcCopies...)
+		}
+
+		batchIndex[vc.ID] = len(documents)
+		documents = append(documents, &encryptedDocument)
+	}
+
+	documents = removeReplacedDocuments(documents)
+
+	_, err = edvClient.CreateDocuments(profile, documents)
+
+	if err != nil && strings.Contains(err.Error(), messages.ErrVaultNotFound.Error()) {
+		// create the new vault for this profile, if it doesn't exist
+		_, err = edvClient.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: profile})
+		if err == nil {
+			_, err = edvClient.CreateDocuments(profile, documents)
+		}
+	}
+
+	if err != nil {
+		writeDuplicatesError(rw, o.duplicateDocumentError(err))
+
+		return
+	}
+
+	o.deleteReplacedCopies(edvClient, profile, copies)
+}
+
+// ImportCredentials swagger:route POST /{profileID}/credentials/import issuer importCredentialsReq
+//
+// Imports the credentials of the request body, one JSON credential per line (NDJSON), into the vault of the profile.
+// Each credential is validated and stored as by POST /store, including the duplicate credentials policy, and the
+// credentials are written importBatchSize at a time. The response reports the result of each line: the invalid
+// credentials, and the ones of a batch failed to be written, are reported with their error without failing the
+// others.
+//
+// Responses:
+//    default: genericError
+//        200: importCredentialsResp
+func (o *Operation) importCredentialsHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	edvClient, err := o.profileEDVClient(profile)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	resp := &ImportCredentialsResponse{Results: []ImportCredentialResult{}}
+	batch := newImportBatch()
+	reader := bufio.NewReader(req.Body)
+
+	for line := 1; ; line++ {
+		lineBytes, errRead := reader.ReadBytes('\n')
+		if errRead != nil && !errors.Is(errRead, io.EOF) {
+			// the line is cut off, so it isn't imported
+			resp.add(ImportCredentialResult{Line: line,
+				Error: fmt.Sprintf("failed to read the request body: %s", errRead.Error())})
+
+			break
+		}
+
+		if vcBytes := bytes.TrimSpace(lineBytes); len(vcBytes) > 0 {
+			if errImport := o.addToImportBatch(edvClient, profile.Name, batch, line, vcBytes); errImport != nil {
+				resp.add(ImportCredentialResult{Line: line, Error: errImport.Error()})
+			}
+		}
+
+		if len(batch.documents) == importBatchSize {
+			o.storeImportBatch(edvClient, profile.Name, batch, resp)
+			batch = newImportBatch()
+		}
+
+		if errRead != nil {
+			break
+		}
+	}
+
+	o.storeImportBatch(edvClient, profile.Name, batch, resp)
+
+	sort.SliceStable(resp.Results, func(i, j int) bool { return resp.Results[i].Line < resp.Results[j].Line })
+
+	commhttp.WriteResponse(rw, resp)
+}
+
+// importBatch holds the imported credentials to be written to the vault at once
+type importBatch struct {
+	documents []*models.EncryptedDocument
+	results   []ImportCredentialResult
+	// the copies of the VCs already stored, deleted once the batch is written if they are overwritten
+	copies []string
+	// the index in documents of the last VC of the batch with the given ID
+	index map[string]int
+}
+
+func newImportBatch() *importBatch {
+	return &importBatch{index: make(map[string]int)}
+}
+
+// addToImportBatch validates and encrypts the credential of the line, and adds it to the batch
+func (o *Operation) addToImportBatch(edvClient EDVClient, profile string, batch *importBatch, line int,
+	vcBytes []byte) error {
+	if err := commhttp.ValidateJSONDepth(vcBytes); err != nil {
+		return err
+	}
+
+	vc, err := o.parseAndVerifyVC(vcBytes)
+	if err != nil {
+		return fmt.Errorf("unable to unmarshal the VC: %w", err)
+	}
+
+	if err = validateRequest(profile, vc.ID); err != nil {
+		return err
+	}
+
+	doc, err := vcutil.BuildStructuredDocForStorage(vcBytes)
+	if err != nil {
+		return err
+	}
+
+	encryptedDocument, err := o.buildEncryptedDoc(profile, doc, vc)
+	if err != nil {
+		return err
+	}
+
+	vcCopies, err := o.checkDuplicates(edvClient, profile, &encryptedDocument)
+	if err != nil {
+		return err
+	}
+
+	if j, ok := batch.index[vc.ID]; ok {
+		if err = o.batchDuplicate(batch.documents, j, &encryptedDocument); err != nil {
+			return err
+		}
+	} else {
+		batch.copies = append(batch.copies, vcCopies...)
+	}
+
+	batch.index[vc.ID] = len(batch.documents)
+	batch.documents = append(batch.documents, &encryptedDocument)
+	batch.results = append(batch.results, ImportCredentialResult{Line: line, ID: vc.ID})
+
+	return nil
+}
+
+// storeImportBatch writes the documents of the batch to the vault of the profile, and reports their results
+func (o *Operation) storeImportBatch(edvClient EDVClient, profile string, batch *importBatch,
+	resp *ImportCredentialsResponse) {
+	if len(batch.documents) == 0 {
+		return
+	}
+
+	documents := removeReplacedDocuments(batch.documents)
+
+	_, err := edvClient.CreateDocuments(profile, documents)
+
+	if err != nil && strings.Contains(err.Error(), messages.ErrVaultNotFound.Error()) {
+		// create the new vault for this profile, if it doesn't exist
+		_, err = edvClient.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: profile})
+		if err == nil {
+			_, err = edvClient.CreateDocuments(profile, documents)
+		}
+	}
+
+	if err != nil {
+		err = o.duplicateDocumentError(err)
+	} else {
+		o.deleteReplacedCopies(edvClient, profile, batch.copies)
+	}
+
+	for _, result := range batch.results {
+		if err != nil {
+			result.Error = fmt.Sprintf("failed to store the VC: %s", err.Error())
+		}
+
+		resp.add(result)
+	}
+}
+
+// add adds the import result of a credential to the report
+func (r *ImportCredentialsResponse) add(result ImportCredentialResult) {
+	if result.Error != "" {
+		r.Failed++
+	} else {
+		r.Imported++
+	}
+
+	r.Results = append(r.Results, result)
+}
+
+// checkDuplicates looks up the copies of the VC already stored under the profile, using the VC ID index of the
+// document. Depending on the duplicate credentials policy, the storage is rejected or the sequence of the document
+// is set after the ones of the copies, and the IDs of the copies are returned.
+//
+// Unless the copies are allowed, the ID of the document is derived from the VC ID and the sequence: the EDV then
+// rejects the document if a copy with the same sequence was stored concurrently.
+func (o *Operation) checkDuplicates(edvClient EDVClient, profile string,
+	document *models.EncryptedDocument) ([]string, error) {
+	if o.duplicateCredentials == DuplicateCredentialsAllow {
+		return nil, nil
+	}
+
+	indexedAttribute := document.IndexedAttributeCollections[0].IndexedAttributes[0]
+
+	docURLs, err := edvClient.QueryVault(profile, &models.Query{
+		Name:  indexedAttribute.Name,
+		Value: indexedAttribute.Value,
+	})
+	if err != nil && !strings.Contains(err.Error(), messages.ErrVaultNotFound.Error()) {
+		return nil, fmt.Errorf("failed to query the stored copies of the VC: %w", err)
+	}
+
+	if len(docURLs) == 0 {
+		setDocumentSequence(document, 0)
+
+		return nil, nil
+	}
+
+	if o.duplicateCredentials == DuplicateCredentialsReject {
+		return nil, errDuplicateVC
+	}
+
+	docIDs := make([]string, len(docURLs))
+
+	for i, docURL := range docURLs {
+		docIDs[i] = vcutil.GetDocIDFromURL(docURL)
+	}
+
+	copies, err := edvClient.ReadDocuments(profile, docIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the stored copies of the VC: %w", err)
+	}
+
+	setDocumentSequence(document, latestDocument(copies).Sequence+1)
+
+	return docIDs, nil
+}
+
+// batchDuplicate handles the VC of the batch having the ID of the VC of documents[j]: rejected, or stored after it.
+// The overwritten VC of the batch is not stored.
+func (o *Operation) batchDuplicate(documents []*models.EncryptedDocument, j int,
+	document *models.EncryptedDocument) error {
+	switch o.duplicateCredentials {
+	case DuplicateCredentialsAllow:
+		return nil
+	case DuplicateCredentialsReject:
+		return errDuplicateVCInBatch
+	}
+
+	setDocumentSequence(document, documents[j].Sequence+1)
+
+	if o.duplicateCredentials == DuplicateCredentialsOverwrite {
+		documents[j] = nil
+	}
+
+	return nil
+}
+
+// deleteReplacedCopies deletes the copies of the overwritten VCs once the new copies are stored. The copy with the
+// latest sequence is retrieved, so the copies failed to be deleted are only logged.
+func (o *Operation) deleteReplacedCopies(edvClient EDVClient, profile string, docIDs []string) {
+	if o.duplicateCredentials != DuplicateCredentialsOverwrite {
+		return
+	}
+
+	for _, docID := range docIDs {
+		if err := edvClient.DeleteDocument(profile, docID); err != nil {
+			logger.Warnf("failed to delete the overwritten copy %s of a VC of profile %s: %s", docID, profile, err)
+		}
+	}
+}
+
+// duplicateDocumentError returns the error of the storage of a document whose ID is already used, i.e. a copy
+// with the same sequence was stored since the check of the duplicates.
+func (o *Operation) duplicateDocumentError(err error) error {
+	if o.duplicateCredentials == DuplicateCredentialsAllow ||
+		!strings.Contains(err.Error(), messages.ErrDuplicateDocument.Error()) {
+		return err
+	}
+
+	if o.duplicateCredentials == DuplicateCredentialsReject {
+		return errDuplicateVC
+	}
+
+	return errConcurrentVCStorage
+}
+
+// setDocumentSequence sets the sequence of the document and the ID derived from it and the VC ID index
+func setDocumentSequence(document *models.EncryptedDocument, sequence int) {
+	vcIDIndexValue := document.IndexedAttributeCollections[0].IndexedAttributes[0].Value
+	hash := sha256.Sum256([]byte(vcIDIndexValue + "/" + strconv.Itoa(sequence)))
+
+	document.Sequence = sequence
+	// EDV document IDs are base58 encoded 128-bit values
+	document.ID = base58.Encode(hash[:16])
+}
+
+func removeReplacedDocuments(documents []*models.EncryptedDocument) []*models.EncryptedDocument {
+	var stored []*models.EncryptedDocument
+
+	for _, document := range documents {
+		if document != nil {
+			stored = append(stored, document)
+		}
+	}
+
+	return stored
+}
+
+func writeDuplicatesError(rw http.ResponseWriter, err error) {
+	if errors.Is(err, errDuplicateVC) || errors.Is(err, errDuplicateVCInBatch) {
+		commhttp.WriteErrorResponse(rw, http.StatusConflict, commhttp.AlreadyExists, err.Error())
+
+		return
+	}
+
+	if errors.Is(err, errConcurrentVCStorage) {
+		commhttp.WriteErrorResponse(rw, http.StatusConflict, commhttp.Conflict, err.Error())
+
+		return
+	}
+
+	commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+}
+
+// versionedCredentials returns true if the copies of a VC are distinguished by the sequence of their document
+func (o *Operation) versionedCredentials() bool {
+	return o.duplicateCredentials == DuplicateCredentialsOverwrite ||
+		o.duplicateCredentials == DuplicateCredentialsVersion
+}
+
+func latestDocument(documents []*models.EncryptedDocument) *models.EncryptedDocument {
+	latest := documents[0]
+
+	for _, document := range documents[1:] {
+		if document.Sequence > latest.Sequence {
+			latest = document
+		}
+	}
+
+	return latest
+}
+
+// getEDVClient returns the client storing the credentials of the profile, the profile doesn't have to exist
+func (o *Operation) getEDVClient(profileName string) (EDVClient, error) {
+	profile, err := o.profileStore.GetProfile(profileName)
+	if err != nil {
+		if !errors.Is(err, storage.ErrValueNotFound) {
+			return nil, fmt.Errorf("failed to get profile %s: %w", profileName, err)
+		}
+
+		profile = &vcprofile.DataProfile{Name: profileName}
+
+		// the vault of a profile which doesn't exist can't be keyed by a UUID
+		if o.vaultIDScheme == VaultIDSchemePrefix {
+			profile.VaultID = o.newVaultID(profile)
+		}
+	}
+
+	return o.profileEDVClient(profile)
+}
+
+func (o *Operation) profileEDVClient(profile *vcprofile.DataProfile) (EDVClient, error) {
+	credentialStorage := profile.CredentialStorage
+	if credentialStorage == "" {
+		credentialStorage = o.credentialStorage
+	}
+
+	if credentialStorage == CredentialStorageLocal {
+		return newProfileVaultClient(o.localEDVClient, profile), nil
+	}
+
+	if o.edvClient == nil {
+		return nil, errEDVNotConfigured
+	}
+
+	return newProfileVaultClient(o.edvClient, profile), nil
+}
+
+func (o *Operation) buildEncryptedDoc(profileName string, structuredDoc *models.StructuredDocument,
+	vc *verifiable.Credential) (models.EncryptedDocument, error) {
+	marshalledStructuredDoc, err := json.Marshal(structuredDoc)
+	if err != nil {
+		return models.EncryptedDocument{}, err
+	}
+
+	jweEncrypter, err := o.profileJWECrypto(profileName)
+	if err != nil {
+		return models.EncryptedDocument{}, err
+	}
+
+	jwe, err := jweEncrypter.Encrypt(marshalledStructuredDoc, nil)
+	if err != nil {
+		return models.EncryptedDocument{}, err
+	}
+
+	encryptedStructuredDoc, err := jwe.FullSerialize(json.Marshal)
+	if err != nil {
+		return models.EncryptedDocument{}, err
+	}
+
+	vcIDIndexValueEncoded, err := o.computeMACEncoded(vc.ID)
+	if err != nil {
+		return models.EncryptedDocument{}, err
+	}
+
+	indexedAttribute := models.IndexedAttribute{
+		Name:  o.vcIDIndexNameEncoded,
+		Value: vcIDIndexValueEncoded,
+		// the copies of a versioned VC share the index
+		Unique: !o.versionedCredentials(),
+	}
+
+	vcBytes, err := json.Marshal(structuredDoc.Content["message"])
+	if err != nil {
+		return models.EncryptedDocument{}, err
+	}
+
+	// the VC ID index stays the first one, the duplicate credentials are looked up with it
+	listIndexedAttributes, err := o.buildListIndexedAttributes(vc, vcBytes)
+	if err != nil {
 		return models.EncryptedDocument{}, err
 	}
 
-	jwe, err := o.jweEncrypter.Encrypt(marshalledStructuredDoc, nil)
+	indexedAttributeCollection := models.IndexedAttributeCollection{
+		Sequence:          0,
+		HMAC:              models.IDTypePair{},
+		IndexedAttributes: append([]models.IndexedAttribute{indexedAttribute}, listIndexedAttributes...),
+	}
+
+	indexedAttributeCollections := []models.IndexedAttributeCollection{indexedAttributeCollection}
+
+	encryptedDocument := models.EncryptedDocument{
+		ID:                          structuredDoc.ID,
+		Sequence:                    0,
+		JWE:                         []byte(encryptedStructuredDoc),
+		IndexedAttributeCollections: indexedAttributeCollections,
+	}
+
+	return encryptedDocument, nil
+}
+
+// buildListIndexedAttributes returns the indexed attributes of the VC types, subject IDs, hashlink of the VC bytes
+// and issuance day (empty if the VC has no issuance date), and the attribute shared by all the stored VCs
+func (o *Operation) buildListIndexedAttributes(vc *verifiable.Credential,
+	vcBytes []byte) ([]models.IndexedAttribute, error) {
+	attributes := []models.IndexedAttribute{{Name: o.vcStoredIndexNameEncoded, Value: o.vcStoredIndexNameEncoded}}
+
+	for _, vcType := range vc.Types {
+		value, err := o.computeMACEncoded(vcType)
+		if err != nil {
+			return nil, err
+		}
+
+		attributes = append(attributes, models.IndexedAttribute{Name: o.vcTypeIndexNameEncoded, Value: value})
+	}
+
+	for _, subjectID := range subjectIDs(vc.Subject) {
+		value, err := o.computeMACEncoded(subjectID)
+		if err != nil {
+			return nil, err
+		}
+
+		attributes = append(attributes, models.IndexedAttribute{Name: o.vcSubjectIndexNameEncoded, Value: value})
+	}
+
+	link, err := credentialHashlink(vcBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := o.computeMACEncoded(link)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes = append(attributes, models.IndexedAttribute{Name: o.vcHashlinkIndexNameEncoded, Value: value})
+
+	issued := ""
+	if vc.Issued != nil {
+		issued = vc.Issued.Time.UTC().Format(issuedIndexDateFormat)
+	}
+
+	value, err = o.computeMACEncoded(issued)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(attributes, models.IndexedAttribute{Name: o.vcIssuedIndexNameEncoded, Value: value}), nil
+}
+
+func (o *Operation) prepareListIndexNames() error {
+	var err error
+
+	o.vcTypeIndexNameEncoded, err = o.computeMACEncoded(vcTypeEDVIndexName)
+	if err != nil {
+		return err
+	}
+
+	o.vcSubjectIndexNameEncoded, err = o.computeMACEncoded(vcSubjectEDVIndexName)
+	if err != nil {
+		return err
+	}
+
+	o.vcIssuedIndexNameEncoded, err = o.computeMACEncoded(vcIssuedEDVIndexName)
+	if err != nil {
+		return err
+	}
+
+	o.vcStoredIndexNameEncoded, err = o.computeMACEncoded(vcStoredEDVIndexName)
+	if err != nil {
+		return err
+	}
+
+	o.vcHashlinkIndexNameEncoded, err = o.computeMACEncoded(vcHashlinkEDVIndexName)
+
+	return err
+}
+
+func (o *Operation) computeMACEncoded(value string) (string, error) {
+	mac, err := o.macCrypto.ComputeMAC([]byte(value), o.macKeyHandle)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.URLEncoding.EncodeToString(mac), nil
+}
+
+// subjectIDs returns the IDs of the credential subject, which can be a string, a map, a struct or a list of them
+func subjectIDs(subject interface{}) []string {
+	subjectBytes, err := json.Marshal(subject)
+	if err != nil {
+		return nil
+	}
+
+	var subjects interface{}
+
+	if err = json.Unmarshal(subjectBytes, &subjects); err != nil {
+		return nil
+	}
+
+	if id, ok := subjects.(string); ok {
+		return []string{id}
+	}
+
+	list, ok := subjects.([]interface{})
+	if !ok {
+		list = []interface{}{subjects}
+	}
+
+	var ids []string
+
+	for _, s := range list {
+		if m, ok := s.(map[string]interface{}); ok {
+			if id, ok := m["id"].(string); ok && id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids
+}
+
+// ListCredentials swagger:route GET /{profileID}/credentials issuer listCredentialsReq
+//
+// Lists the credentials stored under the profile, filtered by type, subject and issuance date, a page of at most
+// limit credentials at a time. The credentials stored before the listing indexes were added aren't listed until they
+// are stored again, or re-encrypted for the issuance date index.
+//
+// Responses:
+//    default: genericError
+//        200: listCredentialsRes
+func (o *Operation) listCredentialsHandler(rw http.ResponseWriter, req *http.Request) {
+	profileID := mux.Vars(req)[profileIDPathParam]
+
+	profile, err := o.profileStore.GetProfile(profileID)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid issuer profile - id=%s: err=%s", profileID, err.Error()))
+
+		return
+	}
+
+	filter, err := parseCredentialsFilter(req.URL.Query())
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+
+		return
+	}
+
+	edvClient, err := o.profileEDVClient(profile)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	credentials, nextCursor, err := o.listCredentials(edvClient, profile.Name, filter)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, &ListCredentialsResponse{Credentials: credentials, NextCursor: nextCursor})
+}
+
+// listCredentials returns the page of credentials matching the filter after its cursor, and the cursor of the next
+// page, empty if it is the last page.
+func (o *Operation) listCredentials(edvClient EDVClient, profileName string,
+	filter *credentialsFilter) ([]json.RawMessage, string, error) {
+	criteria, err := o.listCredentialsQueries(filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var docIDs []string
+
+	// the documents matching all the criteria
+	for i, queries := range criteria {
+		criterionDocIDs, errQuery := queryAnyDocIDs(edvClient, profileName, queries)
+		if errQuery != nil {
+			return nil, "", errQuery
+		}
+
+		if i == 0 {
+			docIDs = criterionDocIDs
+		} else {
+			docIDs = intersectSortedStrings(docIDs, criterionDocIDs)
+		}
+	}
+
+	return o.readCredentialsPage(edvClient, profileName, docIDs, filter)
+}
+
+// queryAnyDocIDs returns the sorted IDs of the documents matching any of the queries
+func queryAnyDocIDs(edvClient EDVClient, profileName string, queries []*models.Query) ([]string, error) {
+	if len(queries) == 1 {
+		return queryDocIDs(edvClient, profileName, queries[0], "listing VCs")
+	}
+
+	docIDs := []string{}
+	seen := make(map[string]bool)
+
+	for _, query := range queries {
+		matchingDocIDs, err := queryDocIDs(edvClient, profileName, query, "listing VCs")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, docID := range matchingDocIDs {
+			if !seen[docID] {
+				seen[docID] = true
+				docIDs = append(docIDs, docID)
+			}
+		}
+	}
+
+	sort.Strings(docIDs)
+
+	return docIDs, nil
+}
+
+func intersectSortedStrings(a, b []string) []string {
+	intersection := []string{}
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			intersection = append(intersection, a[i])
+			i++
+			j++
+		}
+	}
+
+	return intersection
+}
+
+// queryDocIDs returns the sorted IDs of the documents of the profile vault matching the query, none if nothing was
+// stored under the profile yet
+func queryDocIDs(edvClient EDVClient, profileName string, query *models.Query,
+	contextErrText string) ([]string, error) {
+	docURLs, err := edvClient.QueryVault(profileName, query)
+	if err != nil {
+		if strings.Contains(err.Error(), messages.ErrVaultNotFound.Error()) {
+			return []string{}, nil
+		}
+
+		return nil, fmt.Errorf("failed to query vault while "+contextErrText+": %w", err)
+	}
+
+	docIDs := make([]string, len(docURLs))
+
+	for i, docURL := range docURLs {
+		docIDs[i] = vcutil.GetDocIDFromURL(docURL)
+	}
+
+	sort.Strings(docIDs)
+
+	return docIDs, nil
+}
+
+// ExportCredentials swagger:route GET /{profileID}/credentials/export issuer exportCredentialsReq
+//
+// Exports all the credentials stored by the profile, decrypted from its vault, for backup and migration. The
+// credentials are streamed as newline delimited JSON (format ndjson, default) or as a zip of JSON files (format
+// zip). With the recipientKey parameter (a base64url encoded EC public JWK), each credential is exported as a JWE
+// encrypted to that key. A failure once the export started ends the response early, the zip being then invalid.
+//
+// Responses:
+//    default: genericError
+//        200: exportCredentialsResp
+func (o *Operation) exportCredentialsHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	exporter, err := newCredentialsExporter(req.URL.Query())
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+
+		return
+	}
+
+	edvClient, err := o.profileEDVClient(profile)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	docIDs, err := queryDocIDs(edvClient, profile.Name,
+		&models.Query{Name: o.vcStoredIndexNameEncoded, Value: o.vcStoredIndexNameEncoded}, "exporting VCs")
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	exporter.start(rw, profile.Name)
+
+	if err := o.exportCredentials(edvClient, profile.Name, docIDs, exporter); err != nil {
+		logger.Errorf("Failed to export the credentials of profile %s: %s", profile.Name, err.Error())
+
+		return
+	}
+
+	if err := exporter.close(); err != nil {
+		logger.Errorf("Failed to complete the export of the credentials of profile %s: %s", profile.Name,
+			err.Error())
+	}
+}
+
+// ReencryptCredentials swagger:route POST /{profileID}/credentials/reencrypt issuer reencryptCredentialsReq
+//
+// Re-encrypts the credentials stored by the profile with the current key encrypting the stored documents, after
+// creating a new key if newKey is set. The previous keys are kept to decrypt the documents not re-encrypted yet,
+// e.g. of the other profiles. The EDV can't update a document, so each document is deleted and stored again: the
+// re-encrypted document is kept until it is stored, and an interrupted re-encryption is resumed by the next request.
+//
+// Responses:
+//    default: genericError
+//        200: reencryptCredentialsResp
+func (o *Operation) reencryptCredentialsHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	request := ReencryptCredentialsRequest{}
+
+	if err = commhttp.DecodeJSON(req, &request); err != nil && !commhttp.IsEmptyBody(err) {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	edvClient, err := o.profileEDVClient(profile)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	profileJWECrypto, err := o.profileJWECrypto(profile.Name)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.KMSError, err.Error())
+
+		return
+	}
+
+	if !o.startReencryption(profile.Name) {
+		commhttp.WriteErrorResponse(rw, http.StatusConflict, commhttp.Conflict,
+			fmt.Sprintf("the credentials of profile %s are already being re-encrypted", profile.Name))
+
+		return
+	}
+
+	defer o.doneReencryption(profile.Name)
+
+	if request.NewKey {
+		if _, err = profileJWECrypto.RotateKey(); err != nil {
+			commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.KMSError,
+				fmt.Sprintf("failed to create the document encryption key: %s", err.Error()))
+
+			return
+		}
+	}
+
+	resp, err := o.reencryptCredentials(edvClient, profile.Name, profileJWECrypto)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, resp)
+}
+
+// startReencryption marks the re-encryption of the credentials of the profile in progress, returns false if it
+// already is
+func (o *Operation) startReencryption(profileName string) bool {
+	o.reencryptionMutex.Lock()
+	defer o.reencryptionMutex.Unlock()
+
+	if _, ok := o.reencrypting[profileName]; ok {
+		return false
+	}
+
+	o.reencrypting[profileName] = struct{}{}
+
+	return true
+}
+
+func (o *Operation) doneReencryption(profileName string) {
+	o.reencryptionMutex.Lock()
+	defer o.reencryptionMutex.Unlock()
+
+	delete(o.reencrypting, profileName)
+}
+
+// reencryptCredentials replaces the documents of the profile not encrypted with the current key, read maxListLimit
+// documents at a time, once the document whose replacement was interrupted is stored
+func (o *Operation) reencryptCredentials(edvClient EDVClient, profileName string,
+	profileJWECrypto jweCrypto) (*ReencryptCredentialsResponse, error) {
+	if err := o.resumeReencryption(edvClient, profileName); err != nil {
+		return nil, err
+	}
+
+	keyID := profileJWECrypto.KeyID()
+	resp := &ReencryptCredentialsResponse{KeyID: keyID}
+
+	docIDs, err := queryDocIDs(edvClient, profileName,
+		&models.Query{Name: o.vcStoredIndexNameEncoded, Value: o.vcStoredIndexNameEncoded}, "re-encrypting VCs")
+	if err != nil {
+		return nil, err
+	}
+
+	for start := 0; start < len(docIDs); start += maxListLimit {
+		end := start + maxListLimit
+		if end > len(docIDs) {
+			end = len(docIDs)
+		}
+
+		documents, errRead := edvClient.ReadDocuments(profileName, docIDs[start:end])
+		if errRead != nil {
+			return nil, fmt.Errorf("failed to read documents while re-encrypting VCs: %w", errRead)
+		}
+
+		for _, document := range documents {
+			reencrypted, errReencrypt := o.reencryptDocument(edvClient, profileName, document, profileJWECrypto, keyID)
+			if errReencrypt != nil {
+				return nil, errReencrypt
+			}
+
+			if reencrypted {
+				resp.Reencrypted++
+			} else {
+				resp.Skipped++
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// reencryptDocument replaces the document by a copy encrypted with the current key and having all the listing
+// indexes, unless it is encrypted with the key and indexed already
+func (o *Operation) reencryptDocument(edvClient EDVClient, profileName string, document *models.EncryptedDocument,
+	profileJWECrypto jweCrypto, keyID string) (bool, error) {
+	jwe, err := jose.Deserialize(string(document.JWE))
+	if err != nil {
+		return false, fmt.Errorf("failed to deserialize document %s while re-encrypting VCs: %w", document.ID, err)
+	}
+
+	if cryptosetup.JWEKeyID(jwe) == keyID && hasIndexedAttribute(document, o.vcIssuedIndexNameEncoded) &&
+		hasIndexedAttribute(document, o.vcHashlinkIndexNameEncoded) {
+		return false, nil
+	}
+
+	structuredDocBytes, err := profileJWECrypto.Decrypt(jwe)
+	if err != nil {
+		return false, fmt.Errorf("failed to decrypt document %s while re-encrypting VCs: %w", document.ID, err)
+	}
+
+	jwe, err = profileJWECrypto.Encrypt(structuredDocBytes, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to encrypt document %s while re-encrypting VCs: %w", document.ID, err)
+	}
+
+	serializedJWE, err := jwe.FullSerialize(json.Marshal)
+	if err != nil {
+		return false, fmt.Errorf("failed to serialize document %s while re-encrypting VCs: %w", document.ID, err)
+	}
+
+	reencrypted := *document
+	reencrypted.JWE = []byte(serializedJWE)
+
+	// the documents stored before an index was added get it
+	if err = o.rebuildListIndexes(&reencrypted, structuredDocBytes); err != nil {
+		return false, fmt.Errorf("failed to index document %s while re-encrypting VCs: %w", document.ID, err)
+	}
+
+	return true, o.replaceDocument(edvClient, profileName, &reencrypted)
+}
+
+// rebuildListIndexes replaces the listing indexes of the document by the ones of its VC, the VC ID index staying the
+// first one
+func (o *Operation) rebuildListIndexes(document *models.EncryptedDocument, structuredDocBytes []byte) error {
+	if len(document.IndexedAttributeCollections) == 0 ||
+		len(document.IndexedAttributeCollections[0].IndexedAttributes) == 0 {
+		return errors.New("missing VC ID index")
+	}
+
+	structuredDoc := models.StructuredDocument{}
+
+	if err := json.Unmarshal(structuredDocBytes, &structuredDoc); err != nil {
+		return err
+	}
+
+	vcBytes, err := json.Marshal(structuredDoc.Content["message"])
+	if err != nil {
+		return err
+	}
+
+	vc, err := verifiable.ParseUnverifiedCredential(vcBytes)
+	if err != nil {
+		return err
+	}
+
+	listIndexedAttributes, err := o.buildListIndexedAttributes(vc, vcBytes)
+	if err != nil {
+		return err
+	}
+
+	collection := document.IndexedAttributeCollections[0]
+	collection.IndexedAttributes = append([]models.IndexedAttribute{collection.IndexedAttributes[0]},
+		listIndexedAttributes...)
+	document.IndexedAttributeCollections = []models.IndexedAttributeCollection{collection}
+
+	return nil
+}
+
+func hasIndexedAttribute(document *models.EncryptedDocument, name string) bool {
+	for _, collection := range document.IndexedAttributeCollections {
+		for _, attribute := range collection.IndexedAttributes {
+			if attribute.Name == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// replaceDocument replaces the stored document having the ID of the re-encrypted document, which is kept in the
+// reencryption store until it is stored so it isn't lost if the replacement is interrupted
+func (o *Operation) replaceDocument(edvClient EDVClient, profileName string,
+	document *models.EncryptedDocument) error {
+	documentBytes, err := json.Marshal(document)
+	if err != nil {
+		return fmt.Errorf("failed to marshal re-encrypted document %s: %w", document.ID, err)
+	}
+
+	if err = o.reencryptionStore.Put(profileName, documentBytes); err != nil {
+		return fmt.Errorf("failed to keep re-encrypted document %s: %w", document.ID, err)
+	}
+
+	if err = edvClient.DeleteDocument(profileName, document.ID); err != nil {
+		return fmt.Errorf("failed to delete document %s while re-encrypting VCs: %w", document.ID, err)
+	}
+
+	return o.storeReencryptedDocument(edvClient, profileName, document)
+}
+
+// storeReencryptedDocument stores the re-encrypted document in place of the deleted one. The document still
+// stored (its deletion failed) or stored again in the meantime is kept.
+func (o *Operation) storeReencryptedDocument(edvClient EDVClient, profileName string,
+	document *models.EncryptedDocument) error {
+	if _, err := edvClient.CreateDocument(profileName, document); err != nil {
+		if !strings.Contains(err.Error(), messages.ErrDuplicateDocument.Error()) {
+			return fmt.Errorf("failed to store document %s while re-encrypting VCs: %w", document.ID, err)
+		}
+
+		logger.Warnf("Document %s of profile %s is stored, its re-encrypted copy is dropped", document.ID,
+			profileName)
+	}
+
+	// the store can't delete values
+	if err := o.reencryptionStore.Put(profileName, []byte{}); err != nil {
+		return fmt.Errorf("failed to remove re-encrypted document %s: %w", document.ID, err)
+	}
+
+	return nil
+}
+
+// resumeReencryption stores the re-encrypted document of the profile whose replacement was interrupted, if any
+func (o *Operation) resumeReencryption(edvClient EDVClient, profileName string) error {
+	documentBytes, err := o.reencryptionStore.Get(profileName)
+	if errors.Is(err, storage.ErrValueNotFound) || (err == nil && len(documentBytes) == 0) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to get re-encrypted document: %w", err)
+	}
+
+	document := &models.EncryptedDocument{}
+
+	if err = json.Unmarshal(documentBytes, document); err != nil {
+		return fmt.Errorf("failed to unmarshal re-encrypted document: %w", err)
+	}
+
+	return o.storeReencryptedDocument(edvClient, profileName, document)
+}
+
+// exportCredentials decrypts and exports the credentials of the documents, read maxListLimit documents at a time
+func (o *Operation) exportCredentials(edvClient EDVClient, profileName string, docIDs []string,
+	exporter *credentialsExporter) error {
+	for start := 0; start < len(docIDs); start += maxListLimit {
+		end := start + maxListLimit
+		if end > len(docIDs) {
+			end = len(docIDs)
+		}
+
+		documents, err := edvClient.ReadDocuments(profileName, docIDs[start:end])
+		if err != nil {
+			return fmt.Errorf("failed to read documents while exporting VCs: %w", err)
+		}
+
+		for _, document := range documents {
+			vcBytes, errDecrypt := o.decryptVC(profileName, document, "exporting VCs")
+			if errDecrypt != nil {
+				return errDecrypt
+			}
+
+			if errWrite := exporter.write(document.ID, vcBytes); errWrite != nil {
+				return errWrite
+			}
+		}
+
+		exporter.flush()
+	}
+
+	return nil
+}
+
+// credentialsExporter writes the exported credentials in the requested format, encrypted to the recipient key if
+// one is given
+type credentialsExporter struct {
+	format    string
+	encrypter jose.Encrypter
+	rw        http.ResponseWriter
+	zw        *zip.Writer
+}
+
+func newCredentialsExporter(query url.Values) (*credentialsExporter, error) {
+	exporter := &credentialsExporter{format: query.Get("format")}
+
+	switch exporter.format {
+	case "":
+		exporter.format = exportFormatNDJSON
+	case exportFormatNDJSON, exportFormatZIP:
+	default:
+		return nil, fmt.Errorf("unsupported export format %s, expected %s or %s", exporter.format,
+			exportFormatNDJSON, exportFormatZIP)
+	}
+
+	if recipientKey := query.Get("recipientKey"); recipientKey != "" {
+		pubKey, err := parseRecipientKey(recipientKey)
+		if err != nil {
+			return nil, err
+		}
+
+		exporter.encrypter, err = jose.NewJWEEncrypt(jose.A256GCM, []subtle.PublicKey{*pubKey})
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient key: %w", err)
+		}
+	}
+
+	return exporter, nil
+}
+
+// parseRecipientKey parses the base64url encoded EC public JWK the credentials are encrypted to
+func parseRecipientKey(recipientKey string) (*subtle.PublicKey, error) {
+	keyBytes, err := base64.RawURLEncoding.DecodeString(recipientKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient key, expected a base64url encoded JWK: %w", err)
+	}
+
+	jwk := &jose.JWK{}
+
+	if err := jwk.UnmarshalJSON(keyBytes); err != nil {
+		return nil, fmt.Errorf("invalid recipient key, expected a base64url encoded JWK: %w", err)
+	}
+
+	pubKey, ok := jwk.Key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("invalid recipient key, expected an EC public key")
+	}
+
+	return &subtle.PublicKey{KID: jwk.KeyID, X: pubKey.X.Bytes(), Y: pubKey.Y.Bytes(),
+		Curve: pubKey.Curve.Params().Name, Type: "EC"}, nil
+}
+
+func (e *credentialsExporter) start(rw http.ResponseWriter, profileName string) {
+	e.rw = rw
+
+	contentType := "application/x-ndjson"
+	if e.format == exportFormatZIP {
+		contentType = "application/zip"
+		e.zw = zip.NewWriter(rw)
+	}
+
+	rw.Header().Set("Content-Type", contentType)
+	rw.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="%s-credentials.%s"`, url.PathEscape(profileName), e.format))
+	rw.WriteHeader(http.StatusOK)
+}
+
+// write exports the credential of the document, as a line of the ndjson export or a file of the zip export
+func (e *credentialsExporter) write(docID string, vcBytes []byte) error {
+	content, ext := vcBytes, ".json"
+
+	if e.encrypter != nil {
+		jwe, err := e.encrypter.Encrypt(vcBytes, nil)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt VC while exporting VCs: %w", err)
+		}
+
+		serializedJWE, err := jwe.FullSerialize(json.Marshal)
+		if err != nil {
+			return fmt.Errorf("failed to serialize JWE while exporting VCs: %w", err)
+		}
+
+		content, ext = []byte(serializedJWE), ".jwe"
+	}
+
+	if e.zw == nil {
+		_, err := e.rw.Write(append(content, '\n'))
+
+		return err
+	}
+
+	f, err := e.zw.Create(docID + ext)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(content)
+
+	return err
+}
+
+// flush sends the credentials exported so far
+func (e *credentialsExporter) flush() {
+	if e.zw != nil {
+		if err := e.zw.Flush(); err != nil {
+			logger.Warnf("Failed to flush zip export: %s", err.Error())
+		}
+	}
+
+	if flusher, ok := e.rw.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (e *credentialsExporter) close() error {
+	if e.zw != nil {
+		return e.zw.Close()
+	}
+
+	return nil
+}
+
+// readCredentialsPage reads the page of credentials matching the filter after its cursor. The documents are paged in
+// the order of their IDs, the cursor is the ID of the last document read, and are read limit documents at a time
+// until the page is full.
+func (o *Operation) readCredentialsPage(edvClient EDVClient, profileName string, docIDs []string,
+	filter *credentialsFilter) ([]json.RawMessage, string, error) {
+	next := sort.Search(len(docIDs), func(i int) bool { return docIDs[i] > filter.cursor })
+	credentials := []json.RawMessage{}
+
+	for next < len(docIDs) && len(credentials) < filter.limit {
+		end := next + filter.limit
+		if end > len(docIDs) {
+			end = len(docIDs)
+		}
+
+		documents, errRead := edvClient.ReadDocuments(profileName, docIDs[next:end])
+		if errRead != nil {
+			return nil, "", fmt.Errorf("failed to read documents while listing VCs: %w", errRead)
+		}
+
+		for _, document := range documents {
+			next++
+
+			vcBytes, matches, errMatch := o.matchStoredVC(profileName, document, filter)
+			if errMatch != nil {
+				return nil, "", errMatch
+			}
+
+			if matches {
+				credentials = append(credentials, vcBytes)
+			}
+
+			if len(credentials) == filter.limit {
+				break
+			}
+		}
+	}
+
+	if next < len(docIDs) {
+		return credentials, docIDs[next-1], nil
+	}
+
+	return credentials, "", nil
+}
+
+// listCredentialsQueries returns the queries of the indexed criteria of the filter, the documents matching any
+// query of each criterion. The criteria are checked again on the decrypted VCs, the issuance date ones being only
+// looked up in the index when the range is bounded and short enough.
+func (o *Operation) listCredentialsQueries(filter *credentialsFilter) ([][]*models.Query, error) {
+	var criteria [][]*models.Query
+
+	for _, criterion := range []struct{ indexName, value string }{
+		{o.vcTypeIndexNameEncoded, filter.vcType},
+		{o.vcSubjectIndexNameEncoded, filter.subject},
+	} {
+		if criterion.value == "" {
+			continue
+		}
+
+		value, err := o.computeMACEncoded(criterion.value)
+		if err != nil {
+			return nil, err
+		}
+
+		criteria = append(criteria, []*models.Query{{Name: criterion.indexName, Value: value}})
+	}
+
+	issuedQueries, err := o.issuedQueries(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if issuedQueries != nil {
+		criteria = append(criteria, issuedQueries)
+	}
+
+	if len(criteria) == 0 {
+		criteria = append(criteria, []*models.Query{{Name: o.vcStoredIndexNameEncoded,
+			Value: o.vcStoredIndexNameEncoded}})
+	}
+
+	return criteria, nil
+}
+
+// issuedQueries returns the queries of each day of the issuance date range of the filter, nil if the range isn't
+// bounded or is longer than maxIssuedIndexDays days
+func (o *Operation) issuedQueries(filter *credentialsFilter) ([]*models.Query, error) {
+	if filter.issuedAfter == nil || filter.issuedBefore == nil {
+		return nil, nil
+	}
+
+	day := func(t *time.Time) time.Time {
+		utc := t.UTC()
+
+		return time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	first, last := day(filter.issuedAfter), day(filter.issuedBefore)
+	if last.Sub(first) >= maxIssuedIndexDays*24*time.Hour {
+		return nil, nil
+	}
+
+	queries := []*models.Query{}
+
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		value, err := o.computeMACEncoded(d.Format(issuedIndexDateFormat))
+		if err != nil {
+			return nil, err
+		}
+
+		queries = append(queries, &models.Query{Name: o.vcIssuedIndexNameEncoded, Value: value})
+	}
+
+	return queries, nil
+}
+
+// matchStoredVC decrypts the stored VC and returns it if it matches the filter
+func (o *Operation) matchStoredVC(profileName string, document *models.EncryptedDocument,
+	filter *credentialsFilter) (json.RawMessage, bool, error) {
+	vcBytes, err := o.decryptVC(profileName, document, "listing VCs")
+	if err != nil {
+		return nil, false, err
+	}
+
+	vc, err := verifiable.ParseUnverifiedCredential(vcBytes)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse VC while listing VCs: %w", err)
+	}
+
+	return vcBytes, filter.matches(vc), nil
+}
+
+type credentialsFilter struct {
+	vcType       string
+	subject      string
+	issuedAfter  *time.Time
+	issuedBefore *time.Time
+
+	// the page of credentials after the cursor, at most limit of them
+	limit  int
+	cursor string
+}
+
+func parseCredentialsFilter(query url.Values) (*credentialsFilter, error) {
+	filter := &credentialsFilter{vcType: query.Get("type"), subject: query.Get("subject"),
+		limit: defaultListLimit, cursor: query.Get("cursor")}
+
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+
+		filter.limit, err = strconv.Atoi(limit)
+		if err != nil || filter.limit < 1 || filter.limit > maxListLimit {
+			return nil, fmt.Errorf("invalid limit, expected an integer between 1 and %d: %s", maxListLimit, limit)
+		}
+	}
+
+	for param, date := range map[string]**time.Time{
+		"issuedAfter":  &filter.issuedAfter,
+		"issuedBefore": &filter.issuedBefore,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s date, RFC3339 expected: %s", param, value)
+		}
+
+		*date = &t
+	}
+
+	return filter, nil
+}
+
+func (f *credentialsFilter) matches(vc *verifiable.Credential) bool {
+	if f.vcType != "" && !containsString(vc.Types, f.vcType) {
+		return false
+	}
+
+	if f.subject != "" && !containsString(subjectIDs(vc.Subject), f.subject) {
+		return false
+	}
+
+	if f.issuedAfter == nil && f.issuedBefore == nil {
+		return true
+	}
+
+	if vc.Issued == nil {
+		return false
+	}
+
+	if f.issuedAfter != nil && vc.Issued.Time.Before(*f.issuedAfter) {
+		return false
+	}
+
+	return f.issuedBefore == nil || !vc.Issued.Time.After(*f.issuedBefore)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StoreVerifiableCredential swagger:route POST /retrieve issuer retrieveCredentialReq
+//
+// Retrieves a stored credential.
+//
+// Responses:
+//    default: genericError
+//        200: emptyRes
+func (o *Operation) retrieveCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	id := req.URL.Query().Get("id")
+	profile := req.URL.Query().Get("profile")
+
+	if err := validateRequest(profile, id); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+
+		return
+	}
+
+	edvClient, err := o.getEDVClient(profile)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	version := req.URL.Query().Get("version")
+	if version != "" && o.duplicateCredentials != DuplicateCredentialsVersion {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, "the VCs are not versioned")
+
+		return
+	}
+
+	docURLs, err := o.queryVault(edvClient, profile, id)
+
+	if err != nil {
+		// The case where no docs match the given query is handled in o.retrieveCredential.
+		// Any other error is unexpected and is handled here.
+		if err != errNoDocsMatchQuery {
+			commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+			return
+		}
+	}
+
+	if o.versionedCredentials() && len(docURLs) > 0 {
+		o.retrieveVersionedCredential(rw, edvClient, profile, docURLs, version)
+
+		return
+	}
+
+	o.retrieveCredential(rw, edvClient, profile, docURLs)
+}
+
+// retrieveVersionedCredential writes the latest copy of the VC, or the copy of the given version
+func (o *Operation) retrieveVersionedCredential(rw http.ResponseWriter, edvClient EDVClient, profileName string,
+	docURLs []string, version string) {
+	docIDs := make([]string, len(docURLs))
+
+	for i, docURL := range docURLs {
+		docIDs[i] = vcutil.GetDocIDFromURL(docURL)
+	}
+
+	documents, err := edvClient.ReadDocuments(profileName, docIDs)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError,
+			fmt.Sprintf("failed to read documents while retrieving versioned VC: %s", err))
+
+		return
+	}
+
+	document := latestDocument(documents)
+
+	if version != "" {
+		document = nil
+
+		sequence, errParse := strconv.Atoi(version)
+		if errParse != nil {
+			commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+				fmt.Sprintf("invalid version: %s", version))
+
+			return
+		}
+
+		for _, d := range documents {
+			if d.Sequence == sequence {
+				document = d
+
+				break
+			}
+		}
+
+		if document == nil {
+			commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.NotFound,
+				fmt.Sprintf(`no VC under profile "%s" was found with the given id and version`, profileName))
+
+			return
+		}
+	}
+
+	retrievedVC, err := o.decryptVC(profileName, document, "retrieving versioned VC")
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	_, err = rw.Write(retrievedVC)
+	if err != nil {
+		logger.Errorf("Failed to write response for document retrieval success: %s", err.Error())
+	}
+}
+
+func (o *Operation) createIssuerProfile(pr *ProfileRequest) (*vcprofile.DataProfile, error) {
+	var didID, publicKeyID string
+
+	var err error
+
+	switch pr.DIDMethod {
+	case commondid.MethodKey:
+		didID, publicKeyID, err = o.commonDID.CreateKeyDID(pr.DIDKeyType)
+	case commondid.MethodWeb:
+		didID, publicKeyID, err = o.createWebDID(pr)
+	default:
+		didID, publicKeyID, err = o.commonDID.CreateDID(pr.DIDKeyType, pr.SignatureType,
+			pr.DID, pr.DIDPrivateKey, pr.DIDKeyID, crypto.AssertionMethod, pr.UNIRegistrar)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	created := time.Now().UTC()
+
+	return &vcprofile.DataProfile{Name: pr.Name, URI: pr.URI, Created: &created, DID: didID,
+		SignatureType: pr.SignatureType, SignatureRepresentation: pr.SignatureRepresentation, Creator: publicKeyID,
+		DisableVCStatus: pr.DisableVCStatus, OverwriteIssuer: pr.OverwriteIssuer,
+		CredentialStorage: pr.CredentialStorage, RevokeOnExpiry: pr.RevokeOnExpiry, Policy: pr.Policy,
+		Quota: pr.Quota, RequireHolderBinding: pr.RequireHolderBinding, CredentialFormat: pr.CredentialFormat,
+		AnchorCredentials: pr.AnchorCredentials, StrictJSONLD: pr.StrictJSONLD,
+	}, nil
+}
+
+// createWebDID creates the did:web of the profile, served under its did:web path
+func (o *Operation) createWebDID(pr *ProfileRequest) (string, string, error) {
+	if o.webDIDDocs == nil {
+		return "", "", errors.New("did:web not supported")
+	}
+
+	path := webDIDPathSegments(pr)
+
+	didID, err := web.DID(o.HostURL, path...)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create did:web: %w", err)
+	}
+
+	// the document of another profile can't be replaced
+	_, err = o.webDIDDocs.Get(didID)
+	if err == nil {
+		return "", "", fmt.Errorf("did document of %s already exists", didID)
+	}
+
+	if !errors.Is(err, web.ErrDocumentNotFound) {
+		return "", "", err
+	}
+
+	return o.commonDID.CreateWebDID(pr.DIDKeyType, pr.SignatureType, path...)
+}
+
+// webDIDPathSegments returns the path segments of the did:web of the profile, "/" being the host itself
+func webDIDPathSegments(pr *ProfileRequest) []string {
+	switch pr.DIDWebPath {
+	case "":
+		return []string{webDIDPath, pr.Name}
+	case "/":
+		return nil
+	default:
+		return strings.Split(strings.Trim(pr.DIDWebPath, "/"), "/")
+	}
+}
+
+func validateRequest(profileName, vcID string) error {
+	if profileName == "" {
+		return fmt.Errorf("missing profile name")
+	}
+
+	if vcID == "" {
+		return fmt.Errorf("missing verifiable credential ID")
+	}
+
+	return nil
+}
+
+// IssueCredential swagger:route POST /{id}/credentials/issueCredential issuer issueCredentialReq
+//
+// Issues a credential, returned as a JWT string for the profiles with the jwt credential format. The hashlink of the
+// issued credential is returned in the Hashlink header if requested by the options. With the dryRun option, the
+// credential which would be issued is returned unsigned. With the redactable option, the credential is returned with
+// the salted claims it commits to (redactableCredentialRes).
+//
+// Responses:
+//    default: genericError
+//        200: issueCredentialDryRunRes
+//        201: verifiableCredentialRes
+func (o *Operation) issueCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	// get the issuer profile
+	profile, err := o.getIssuerProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	// get the request
+	cred := IssueCredentialRequest{}
+
+	err = commhttp.DecodeJSON(req, &cred)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	var claims []*redaction.Claim
+
+	if cred.Opts != nil && cred.Opts.Redactable {
+		if claims, err = commitCredential(&cred); err != nil {
+			commhttp.WriteError(rw, err)
+
+			return
+		}
+	}
+
+	if cred.Opts != nil && cred.Opts.DryRun {
+		resp, err := o.dryRunCredential(profile, &cred)
+		if err != nil {
+			commhttp.WriteError(rw, err)
+
+			return
+		}
+
+		commhttp.WriteResponse(rw, resp)
+
+		return
+	}
+
+	// the credentials of the jwt and sd-jwt profiles are returned as the JWT string
+	if isJWTFormat(profile.CredentialFormat) {
+		_, jwtVC, err := o.issueCredentialAs(profile, &cred, profile.CredentialFormat)
+		if err != nil {
+			commhttp.WriteError(rw, err)
+
+			return
+		}
+
+		if err = setHashlinkHeader(rw, &cred, nil, jwtVC); err != nil {
+			commhttp.WriteError(rw, err)
+
+			return
+		}
+
+		writeIssuedCredential(rw, jwtVC, claims)
+
+		return
+	}
+
+	signedVC, err := o.issueCredential(profile, &cred)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if err = setHashlinkHeader(rw, &cred, signedVC, ""); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	writeIssuedCredential(rw, signedVC, claims)
+}
+
+// setHashlinkHeader sets the hashlink of the issued credential in the response header, if requested
+func setHashlinkHeader(rw http.ResponseWriter, cred *IssueCredentialRequest, signedVC *verifiable.Credential,
+	jwtVC string) error {
+	if cred.Opts == nil || !cred.Opts.Hashlink {
+		return nil
+	}
+
+	link, err := issuedHashlink(signedVC, jwtVC)
+	if err != nil {
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to compute the hashlink of the credential: %s", err.Error()))
+	}
+
+	rw.Header().Set(hashlinkHeader, link)
+
+	return nil
+}
+
+// IssueCredential validates the request, then issues the credential signed by the issuer profile.
+func (o *Operation) IssueCredential(profileID string, cred *IssueCredentialRequest) (*verifiable.Credential, error) {
+	profile, err := o.getIssuerProfile(profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.issueCredential(profile, cred)
+}
+
+func (o *Operation) getIssuerProfile(profileID string) (*vcprofile.DataProfile, error) {
+	profile, err := o.profileStore.GetProfile(profileID)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid issuer profile - id=%s: err=%s", profileID, err.Error()))
+	}
+
+	return profile, nil
+}
+
+func (o *Operation) issueCredential(profile *vcprofile.DataProfile,
+	cred *IssueCredentialRequest) (*verifiable.Credential, error) {
+	signedVC, _, err := o.issueCredentialAs(profile, cred, CredentialFormatLDP)
+
+	return signedVC, err
+}
+
+// issueCredentialAs issues the credential in the credential format, the JWT of the jwt and sd-jwt formats being
+// returned with the credential
+func (o *Operation) issueCredentialAs(profile *vcprofile.DataProfile, cred *IssueCredentialRequest,
+	format string) (*verifiable.Credential, string, error) {
+	// the dry run is only handled by issueCredential, e.g. not by the batches
+	if cred.Opts != nil && cred.Opts.DryRun {
+		validationErr := &commhttp.ValidationError{}
+		validationErr.Add("/options/dryRun", "dry run only supported by issueCredential")
+
+		return nil, "", commhttp.NewValidationError(validationErr)
+	}
+
+	if cred.Opts != nil && cred.Opts.Redactable {
+		validationErr := &commhttp.ValidationError{}
+		validationErr.Add("/options/redactable", "redactable credentials only supported by issueCredential")
+
+		return nil, "", commhttp.NewValidationError(validationErr)
+	}
+
+	if cred.Opts != nil && (cred.Opts.HolderKey != nil || cred.Opts.HolderKeyID != "") &&
+		format != CredentialFormatSDJWT {
+		validationErr := &commhttp.ValidationError{}
+		validationErr.Add("/options/holderKey", fmt.Sprintf("holder keys only supported by the %s credential format",
+			CredentialFormatSDJWT))
+
+		return nil, "", commhttp.NewValidationError(validationErr)
+	}
+
+	profile, proofProfiles, credential, err := o.prepareCredential(profile, cred)
+	if err != nil {
+		return nil, "", err
+	}
+
+	holderBinding, err := o.checkHolderBinding(profile, credential, cred.Opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	release, err := o.reserveQuota(profile)
+	if err != nil {
+		return nil, "", err
+	}
+
+	signedVC, jwtVC, err := o.issue(profile, proofProfiles, credential, cred.Opts, format)
+	if err != nil {
+		release()
+
+		return nil, "", err
+	}
+
+	if err = o.recordHolderBinding(profile, signedVC, holderBinding); err != nil {
+		release()
+
+		return nil, "", err
+	}
+
+	o.emitIssuance(profile.Name, signedVC, format)
+
+	return signedVC, jwtVC, nil
+}
+
+// prepareCredential validates the request, selects the signing profiles and resolves the credential to issue, which
+// is validated and checked against the policy of the profile
+func (o *Operation) prepareCredential(profile *vcprofile.DataProfile, cred *IssueCredentialRequest) (
+	*vcprofile.DataProfile, []*vcprofile.DataProfile, *verifiable.Credential, error) {
+	// validate the request
+	if err := validateIssueCredentialRequest(cred); err != nil {
+		return nil, nil, nil, commhttp.NewValidationError(err)
+	}
+
+	profile, proofProfiles, err := o.selectSigningProfiles(profile, cred.Opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	credentialBytes, err := o.resolveCredential(profile, cred)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// validate the VC (ignore the proof)
+	credential, err := verifiable.ParseCredential(credentialBytes, verifiable.WithDisabledProofCheck())
+	if err != nil {
+		return nil, nil, nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("failed to validate credential: %s", err.Error()))
+	}
+
+	if err := o.checkPolicy(profile, credential); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return profile, proofProfiles, credential, nil
+}
+
+// dryRunCredential runs the checks of the issuance of the credential and returns the credential which would be
+// issued, unsigned. Nothing is allocated or consumed: the quota and the holder binding challenge are left for the
+// issuance, so a dry run passing doesn't guarantee the issuance does.
+func (o *Operation) dryRunCredential(profile *vcprofile.DataProfile,
+	cred *IssueCredentialRequest) (*IssueCredentialDryRunResponse, error) {
+	format := CredentialFormatLDP
+	if isJWTFormat(profile.CredentialFormat) {
+		format = profile.CredentialFormat
+	}
+
+	profile, proofProfiles, credential, err := o.prepareCredential(profile, cred)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = o.checkHolderBinding(profile, credential, cred.Opts); err != nil {
+		return nil, err
+	}
+
+	if err = checkProofFormat(proofProfiles, format); err != nil {
+		return nil, err
+	}
+
+	if profile.AnchorCredentials && o.timestamper == nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			"credential anchoring not supported: no time-stamping authority")
+	}
+
+	if !profile.DisableVCStatus {
+		credential.Context = append(credential.Context, cslstatus.Context)
+	}
+
+	setIssuance(profile, proofProfiles, credential)
+
+	// the JWT credentials aren't canonicalized
+	if !isJWTFormat(format) {
+		if err = o.checkStrictJSONLD(profile, credential); err != nil {
+			return nil, err
+		}
+	}
+
+	return &IssueCredentialDryRunResponse{
+		Credential:       credential,
+		Format:           format,
+		CredentialStatus: !profile.DisableVCStatus,
+		Anchored:         profile.AnchorCredentials,
+	}, nil
+}
+
+// reserveQuota counts the credential to issue under the profile, the returned function uncounts it if the
+// credential isn't issued
+func (o *Operation) reserveQuota(profile *vcprofile.DataProfile) (func(), error) {
+	release, err := o.quotas.Reserve(profile.Name, profile.Quota)
+	if err != nil {
+		var exceeded *quota.ExceededError
+		if errors.As(err, &exceeded) {
+			return nil, commhttp.NewError(http.StatusTooManyRequests, commhttp.QuotaExceeded, err.Error())
+		}
+
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to reserve issuance quota: %s", err.Error()))
+	}
+
+	return release, nil
+}
+
+// checkPolicy evaluates the policy of the profile on the credential to issue, the violations of its rules are
+// returned as a policy violation error
+func (o *Operation) checkPolicy(profile *vcprofile.DataProfile, credential *verifiable.Credential) error {
+	if violations := o.policyEngine.Evaluate(profile.Policy, credential); len(violations) > 0 {
+		return commhttp.NewPolicyViolationError(violations)
+	}
+
+	return nil
+}
+
+// issue sets the status and the issuer of the credential, then signs it with the profile and the proof profiles,
+// or as a JWT for the jwt and sd-jwt credential formats
+func (o *Operation) issue(profile *vcprofile.DataProfile, proofProfiles []*vcprofile.DataProfile,
+	credential *verifiable.Credential, opts *IssueCredentialOptions, format string) (*verifiable.Credential, string,
+	error) {
+	var err error
+
+	if !profile.DisableVCStatus {
+		credential.Context = append(credential.Context, cslstatus.Context)
+	}
+
+	setIssuance(profile, proofProfiles, credential)
+
+	// checked with the contexts the credential is signed with, before its status is allocated. The JWT credentials
+	// aren't canonicalized.
+	if !isJWTFormat(format) {
+		if err = o.checkStrictJSONLD(profile, credential); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if !profile.DisableVCStatus {
+		// set credential status
+		credential.Status, err = o.vcStatusManager.CreateStatusID(profile, credential.ID)
+		if err != nil {
+			return nil, "", commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError,
+				fmt.Sprintf("failed to add credential status: %s", err.Error()))
+		}
+	}
+
+	if err = o.anchorCredential(profile, credential); err != nil {
+		return nil, "", err
+	}
+
+	signedVC, jwtVC, err := o.sign(profile, proofProfiles, credential, opts, format)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if profile.RevokeOnExpiry {
+		if err := o.expiryLog.Record(signedVC, profile.Name); err != nil {
+			return nil, "", commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError,
+				fmt.Sprintf("failed to log credential for revocation on expiry: %s", err.Error()))
+		}
+	}
+
+	return signedVC, jwtVC, nil
+}
+
+// setIssuance sets the contexts of the signature types of the profile and the proof profiles, and the issuer of the
+// credential
+func setIssuance(profile *vcprofile.DataProfile, proofProfiles []*vcprofile.DataProfile,
+	credential *verifiable.Credential) {
+	// update context
+	vcutil.UpdateSignatureTypeContext(credential, profile)
+
+	for _, proofProfile := range proofProfiles {
+		vcutil.UpdateSignatureTypeContext(credential, proofProfile)
+	}
+
+	// update credential issuer
+	vcutil.UpdateIssuer(credential, profile)
+}
+
+// checkProofFormat checks the credential format supports the proof profiles
+func checkProofFormat(proofProfiles []*vcprofile.DataProfile, format string) error {
+	// a JWT has a single signature
+	if isJWTFormat(format) && len(proofProfiles) > 0 {
+		return commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("credential format %s doesn't support proof sets", format))
+	}
+
+	return nil
+}
+
+// isJWTFormat returns true for the credential formats issuing the credentials as JWTs
+func isJWTFormat(format string) bool {
+	return format == CredentialFormatJWT || format == CredentialFormatSDJWT
+}
+
+// sign signs the credential with the profile and the proof profiles, or as a JWT signed by the profile for the jwt
+// and sd-jwt credential formats
+func (o *Operation) sign(profile *vcprofile.DataProfile, proofProfiles []*vcprofile.DataProfile,
+	credential *verifiable.Credential, opts *IssueCredentialOptions, format string) (*verifiable.Credential, string,
+	error) {
+	if err := checkProofFormat(proofProfiles, format); err != nil {
+		return nil, "", err
+	}
+
+	if format == CredentialFormatJWT {
+		jwtVC, err := o.crypto.SignCredentialJWT(profile, credential, getIssuerSigningOpts(opts)...)
+		if err != nil {
+			return nil, "", commhttp.NewError(http.StatusInternalServerError, commhttp.SigningError,
+				fmt.Sprintf("failed to sign credential: %s", err.Error()))
+		}
+
+		return credential, jwtVC, nil
+	}
+
+	if format == CredentialFormatSDJWT {
+		sdJWT, err := o.crypto.SignCredentialSDJWT(profile, credential, holderConfirmation(opts),
+			getIssuerSigningOpts(opts)...)
+		if err != nil {
+			return nil, "", commhttp.NewError(http.StatusInternalServerError, commhttp.SigningError,
+				fmt.Sprintf("failed to sign credential: %s", err.Error()))
+		}
+
+		return credential, sdJWT, nil
+	}
+
+	signedVC, err := o.crypto.SignCredential(profile, credential, getIssuerSigningOpts(opts)...)
+	if err != nil {
+		return nil, "", commhttp.NewError(http.StatusInternalServerError, commhttp.SigningError,
+			fmt.Sprintf("failed to sign credential: %s", err.Error()))
+	}
+
+	signedVC, err = o.addProofs(signedVC, proofProfiles, opts)
+	if err != nil {
+		return nil, "", commhttp.NewError(http.StatusInternalServerError, commhttp.SigningError, err.Error())
+	}
+
+	return signedVC, "", nil
+}
+
+// nolint funlen
+// composeAndIssueCredential swagger:route POST /{id}/credentials/composeAndIssueCredential issuer composeCredentialReq
+//
+// Composes and Issues a credential.
+//
+// Responses:
+//    default: genericError
+//        201: verifiableCredentialRes
+func (o *Operation) composeAndIssueCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)[profileIDPathParam]
+
+	profile, err := o.profileStore.GetProfile(id)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid issuer profile: %s", err.Error()))
+
+		return
+	}
+
+	// get the request
+	composeCredReq := ComposeCredentialRequest{}
+
+	err = commhttp.DecodeJSON(req, &composeCredReq)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if err = validateComposeCredentialRequest(&composeCredReq); err != nil {
+		commhttp.WriteValidationErrorResponse(rw, err)
+
+		return
+	}
+
+	// the composed credentials have no holder binding
+	if profile.RequireHolderBinding {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("profile %s requires the holder binding of the credential subject, which can't be composed",
+				profile.Name))
+
+		return
+	}
+
+	// merge the subject claims from the configured claims source (if any)
+	if o.claimsSource != nil {
+		if err = o.mergeSourceClaims(&composeCredReq); err != nil {
+			commhttp.WriteError(rw, err)
+
+			return
+		}
+	}
+
+	// create the verifiable credential
+	credential, err := buildCredential(&composeCredReq)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("failed to build credential: %s", err.Error()))
+
+		return
+	}
+
+	if err = o.checkPolicy(profile, credential); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if err = o.checkDIDAnchored(profile); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	// prepare signing options from request options, and select the signing key of multi-key profiles
+	profile, opts, err := getComposeSigningProfile(profile, &composeCredReq)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("failed to prepare signing options: %s", err.Error()))
+
+		return
+	}
+
+	if err = signingKeyError(o.crypto.ValidateSigningKey(profile, opts...), "the signing key"); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	release, err := o.reserveQuota(profile)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	issued := false
+
+	// the credential isn't counted if it fails to be issued
+	defer func() {
+		if !issued {
+			release()
+		}
+	}()
+
+	if err = o.addEvidenceAttachments(profile, credential, composeCredReq.Attachments); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if !profile.DisableVCStatus {
+		credential.Context = append(credential.Context, cslstatus.Context)
+	}
+
+	// update context
+	vcutil.UpdateSignatureTypeContext(credential, profile)
+
+	// update credential issuer
+	vcutil.UpdateIssuer(credential, profile)
+
+	if err = o.checkStrictJSONLD(profile, credential); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if !profile.DisableVCStatus {
+		// set credential status
+		credential.Status, err = o.vcStatusManager.CreateStatusID(profile, credential.ID)
+		if err != nil {
+			commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+				fmt.Sprintf("failed to add credential status: %s", err.Error()))
+
+			return
+		}
+	}
+
+	if err = o.anchorCredential(profile, credential); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	// sign the credential
+	signedVC, err := o.crypto.SignCredential(profile, credential, opts...)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.SigningError,
+			fmt.Sprintf("failed to sign credential: %s", err.Error()))
+
+		return
+	}
+
+	if profile.RevokeOnExpiry {
+		if err := o.expiryLog.Record(signedVC, profile.Name); err != nil {
+			commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+				fmt.Sprintf("failed to log credential for revocation on expiry: %s", err.Error()))
+
+			return
+		}
+	}
+
+	issued = true
+
+	o.emitIssuance(profile.Name, signedVC, CredentialFormatLDP)
+
+	// response
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, signedVC)
+}
+
+// mergeSourceClaims fetches the claims of the subject from the claims source and merges them with the
+// request claims. Claims present in the request take precedence over the fetched ones.
+func (o *Operation) mergeSourceClaims(composeCredReq *ComposeCredentialRequest) error {
+	if composeCredReq.Subject == "" {
+		return nil
+	}
+
+	requestClaims := make(map[string]interface{})
+
+	if composeCredReq.Claims != nil {
+		if err := json.Unmarshal(composeCredReq.Claims, &requestClaims); err != nil {
+			return commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+				fmt.Sprintf("invalid claims: %s", err.Error()))
+		}
+	}
+
+	claims, err := o.claimsSource.FetchClaims(composeCredReq.Subject)
 	if err != nil {
-		return models.EncryptedDocument{}, err
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to fetch claims: %s", err.Error()))
 	}
 
-	encryptedStructuredDoc, err := jwe.FullSerialize(json.Marshal)
+	if claims == nil {
+		claims = make(map[string]interface{})
+	}
+
+	for k, v := range requestClaims {
+		claims[k] = v
+	}
+
+	composeCredReq.Claims, err = json.Marshal(claims)
 	if err != nil {
-		return models.EncryptedDocument{}, err
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to merge claims: %s", err.Error()))
 	}
 
-	vcIDMAC, err := o.macCrypto.ComputeMAC([]byte(vcID), o.macKeyHandle)
+	return nil
+}
+
+// nolint: funlen
+func buildCredential(composeCredReq *ComposeCredentialRequest) (*verifiable.Credential, error) {
+	// create the verifiable credential
+	credential := &verifiable.Credential{}
+
+	var err error
+
+	// set credential data
+	credential.Context, err = vcutil.GetContextsFromJSONRaw(composeCredReq.CredentialFormatOptions)
 	if err != nil {
-		return models.EncryptedDocument{}, err
+		return nil, err
 	}
 
-	vcIDIndexValueEncoded := base64.URLEncoding.EncodeToString(vcIDMAC)
+	if composeCredReq.IssuanceDate != nil {
+		credential.Issued = util.NewTime(*composeCredReq.IssuanceDate)
+	}
 
-	indexedAttribute := models.IndexedAttribute{
-		Name:   o.vcIDIndexNameEncoded,
-		Value:  vcIDIndexValueEncoded,
-		Unique: true,
+	if composeCredReq.ExpirationDate != nil {
+		credential.Expired = util.NewTime(*composeCredReq.ExpirationDate)
 	}
 
-	indexedAttributeCollection := models.IndexedAttributeCollection{
-		Sequence:          0,
-		HMAC:              models.IDTypePair{},
-		IndexedAttributes: []models.IndexedAttribute{indexedAttribute},
+	// set default type, if request doesn't contain the type
+	credential.Types = []string{"VerifiableCredential"}
+	if len(composeCredReq.Types) != 0 {
+		credential.Types = composeCredReq.Types
 	}
 
-	indexedAttributeCollections := []models.IndexedAttributeCollection{indexedAttributeCollection}
+	// set subject
+	credentialSubject := make(map[string]interface{})
 
-	encryptedDocument := models.EncryptedDocument{
-		ID:                          structuredDoc.ID,
-		Sequence:                    0,
-		JWE:                         []byte(encryptedStructuredDoc),
-		IndexedAttributeCollections: indexedAttributeCollections,
+	if composeCredReq.Claims != nil {
+		err = json.Unmarshal(composeCredReq.Claims, &credentialSubject)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return encryptedDocument, nil
+	credentialSubject["id"] = composeCredReq.Subject
+	credential.Subject = credentialSubject
+
+	// set issuer
+	credential.Issuer = verifiable.Issuer{
+		ID: composeCredReq.Issuer,
+	}
+
+	// set terms of use
+	termsOfUse, err := vcutil.DecodeTypedIDFromJSONRaw(composeCredReq.TermsOfUse)
+	if err != nil {
+		return nil, err
+	}
+
+	credential.TermsOfUse = termsOfUse
+
+	// set evidence
+	if composeCredReq.Evidence != nil {
+		evidence := make(map[string]interface{})
+
+		err := json.Unmarshal(composeCredReq.Evidence, &evidence)
+		if err != nil {
+			return nil, err
+		}
+
+		credential.Evidence = evidence
+	}
+
+	return credential, nil
 }
 
-// StoreVerifiableCredential swagger:route POST /retrieve issuer retrieveCredentialReq
+// getComposeSigningProfile returns the profile to sign the composed credential with for the key ID of the proof
+// format options, and the signing options of the request.
+func getComposeSigningProfile(profile *vcprofile.DataProfile,
+	composeCredReq *ComposeCredentialRequest) (*vcprofile.DataProfile, []crypto.SigningOpts, error) {
+	proofOptions, err := parseProofFormatOptions(composeCredReq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profile, err = selectSigningKey(profile, &IssueCredentialOptions{VerificationMethod: proofOptions.KeyID})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opts, err := getComposeSigningOpts(composeCredReq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return profile, opts, nil
+}
+
+// composeProofOptions are the proof format options of the compose credential request.
+type composeProofOptions struct {
+	KeyID   string     `json:"kid,omitempty"`
+	Purpose string     `json:"proofPurpose,omitempty"`
+	Created *time.Time `json:"created,omitempty"`
+}
+
+func parseProofFormatOptions(composeCredReq *ComposeCredentialRequest) (*composeProofOptions, error) {
+	options := &composeProofOptions{}
+
+	if composeCredReq.ProofFormatOptions != nil {
+		err := json.Unmarshal(composeCredReq.ProofFormatOptions, options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare signing opts: %w", err)
+		}
+	}
+
+	return options, nil
+}
+
+func getComposeSigningOpts(composeCredReq *ComposeCredentialRequest) ([]crypto.SigningOpts, error) {
+	proofFormatOptions, err := parseProofFormatOptions(composeCredReq)
+	if err != nil {
+		return nil, err
+	}
+
+	representation := "jws"
+	if composeCredReq.ProofFormat != "" {
+		representation = composeCredReq.ProofFormat
+	}
+
+	return []crypto.SigningOpts{
+		crypto.WithPurpose(proofFormatOptions.Purpose),
+		crypto.WithVerificationMethod(proofFormatOptions.KeyID),
+		crypto.WithSigningRepresentation(representation),
+		crypto.WithCreated(proofFormatOptions.Created),
+	}, nil
+}
+
+// selectSigningKey returns the profile to sign with for the verification method of the options. When signing
+// keys are registered on the profile, the verification method must be one of the profile keys.
+func selectSigningKey(profile *vcprofile.DataProfile, opts *IssueCredentialOptions) (*vcprofile.DataProfile, error) {
+	if len(profile.SigningKeys) == 0 || opts == nil {
+		return profile, nil
+	}
+
+	verificationMethod := opts.VerificationMethod
+	if verificationMethod == "" {
+		verificationMethod = opts.AssertionMethod
+	}
+
+	if verificationMethod == "" || verificationMethod == profile.Creator {
+		return profile, nil
+	}
+
+	didID, err := diddoc.GetDIDFromVerificationMethod(verificationMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	if didID != profile.DID {
+		return nil, fmt.Errorf("verification method %s does not belong to profile did %s",
+			verificationMethod, profile.DID)
+	}
+
+	for _, key := range profile.SigningKeys {
+		if key.ID == verificationMethod {
+			signingProfile := *profile
+			signingProfile.Creator = key.ID
+			signingProfile.SignatureType = key.SignatureType
+
+			return &signingProfile, nil
+		}
+	}
+
+	return nil, fmt.Errorf("verification method %s is not a signing key of the profile", verificationMethod)
+}
+
+// EndorseCredential swagger:route POST /{id}/credentials/endorse issuer endorseCredentialReq
 //
-// Retrieves a stored credential.
+// Endorses a credential signed by another party, e.g. to notarize it. Once the proofs of the credential are
+// verified, the proofs of the profile are added to them, the credential being otherwise unchanged.
 //
 // Responses:
 //    default: genericError
-//        200: emptyRes
-func (o *Operation) retrieveCredentialHandler(rw http.ResponseWriter, req *http.Request) {
-	id := req.URL.Query().Get("id")
-	profile := req.URL.Query().Get("profile")
+//        200: verifiableCredentialRes
+func (o *Operation) endorseCredentialHandler(rw http.ResponseWriter, req *http.Request) {
+	profileID := mux.Vars(req)[profileIDPathParam]
 
-	if err := validateRequest(profile, id); err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+	profile, err := o.profileStore.GetProfile(profileID)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid issuer profile - id=%s: err=%s", profileID, err.Error()))
+
+		return
+	}
+
+	endorseReq := EndorseCredentialRequest{}
+
+	if err = commhttp.DecodeJSON(req, &endorseReq); err != nil {
+		commhttp.WriteError(rw, err)
 
 		return
 	}
 
-	docURLs, err := o.queryVault(profile, id)
+	if err = validateEndorseCredentialRequest(&endorseReq); err != nil {
+		commhttp.WriteValidationErrorResponse(rw, err)
 
+		return
+	}
+
+	profile, proofProfiles, err := o.selectSigningProfiles(profile, endorseReq.Opts)
 	if err != nil {
-		// The case where no docs match the given query is handled in o.retrieveCredential.
-		// Any other error is unexpected and is handled here.
-		if err != errNoDocsMatchQuery {
-			commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, err.Error())
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	credential, err := o.parseAndVerifyVC(endorseReq.Credential)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("failed to verify credential: %s", err.Error()))
+
+		return
+	}
+
+	if len(credential.Proofs) == 0 {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidCredential,
+			"the credential has no proof to endorse")
+
+		return
+	}
+
+	// the context can't be updated without breaking the existing proofs
+	for _, signingProfile := range append([]*vcprofile.DataProfile{profile}, proofProfiles...) {
+		if !vcutil.HasSignatureTypeContext(credential, signingProfile) {
+			commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidCredential,
+				fmt.Sprintf("the credential is missing the context of signature type %s",
+					signingProfile.SignatureType))
+
 			return
 		}
 	}
 
-	o.retrieveCredential(rw, profile, docURLs)
+	endorsedVC, err := o.crypto.SignCredential(profile, credential, getIssuerSigningOpts(endorseReq.Opts)...)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.SigningError,
+			fmt.Sprintf("failed to endorse credential: %s", err.Error()))
+
+		return
+	}
+
+	endorsedVC, err = o.addProofs(endorsedVC, proofProfiles, endorseReq.Opts)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.SigningError, err.Error())
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, endorsedVC)
 }
 
-func (o *Operation) createIssuerProfile(pr *ProfileRequest) (*vcprofile.DataProfile, error) {
-	var didID, publicKeyID string
+// addProofs adds the additional proofs of the options to the signed credential
+func (o *Operation) addProofs(vc *verifiable.Credential, proofProfiles []*vcprofile.DataProfile,
+	opts *IssueCredentialOptions) (*verifiable.Credential, error) {
+	for i, proofProfile := range proofProfiles {
+		signedVC, err := o.crypto.SignCredential(proofProfile, vc, getProofSigningOpts(opts, &opts.Proofs[i])...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add proof %d to credential: %w", i, err)
+		}
+
+		vc = signedVC
+	}
+
+	return vc, nil
+}
+
+// selectProofSigningKeys returns the signing profiles of the additional proofs of the options, signing with their
+// verification method and signature type.
+func selectProofSigningKeys(profile *vcprofile.DataProfile,
+	opts *IssueCredentialOptions) ([]*vcprofile.DataProfile, error) {
+	if opts == nil {
+		return nil, nil
+	}
+
+	proofProfiles := make([]*vcprofile.DataProfile, len(opts.Proofs))
+
+	for i, proof := range opts.Proofs {
+		signingProfile, err := selectSigningKey(profile,
+			&IssueCredentialOptions{VerificationMethod: proof.VerificationMethod})
+		if err != nil {
+			return nil, fmt.Errorf("proof %d: %w", i, err)
+		}
+
+		proofProfile := *signingProfile
+		if proof.SignatureType != "" {
+			proofProfile.SignatureType = proof.SignatureType
+		}
+
+		proofProfiles[i] = &proofProfile
+	}
+
+	return proofProfiles, nil
+}
+
+// selectSigningProfiles selects the signing keys of the additional proofs, then the one of the main proof for
+// multi-key profiles, and validates them
+func (o *Operation) selectSigningProfiles(profile *vcprofile.DataProfile,
+	opts *IssueCredentialOptions) (*vcprofile.DataProfile, []*vcprofile.DataProfile, error) {
+	// the keys of the proofs are keys of the DID of the profile
+	if err := o.checkDIDAnchored(profile); err != nil {
+		return nil, nil, err
+	}
 
-	didID, publicKeyID, err := o.commonDID.CreateDID(pr.DIDKeyType, pr.SignatureType,
-		pr.DID, pr.DIDPrivateKey, pr.DIDKeyID, crypto.AssertionMethod, pr.UNIRegistrar)
+	proofProfiles, err := selectProofSigningKeys(profile, opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
 	}
 
-	created := time.Now().UTC()
+	profile, err = selectSigningKey(profile, opts)
+	if err != nil {
+		return nil, nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+	}
 
-	return &vcprofile.DataProfile{Name: pr.Name, URI: pr.URI, Created: &created, DID: didID,
-		SignatureType: pr.SignatureType, SignatureRepresentation: pr.SignatureRepresentation, Creator: publicKeyID,
-		DisableVCStatus: pr.DisableVCStatus, OverwriteIssuer: pr.OverwriteIssuer,
-	}, nil
+	if err = o.validateSigningKeys(profile, proofProfiles, opts); err != nil {
+		return nil, nil, err
+	}
+
+	return profile, proofProfiles, nil
 }
 
-func validateProfileRequest(pr *ProfileRequest) error {
-	if pr.Name == "" {
-		return fmt.Errorf("missing profile name")
+// validateSigningKeys validates the verification methods of the main proof and of the additional proofs are listed
+// under their proof purpose in the DID document of the profile, before the credential is issued
+func (o *Operation) validateSigningKeys(profile *vcprofile.DataProfile, proofProfiles []*vcprofile.DataProfile,
+	opts *IssueCredentialOptions) error {
+	err := o.crypto.ValidateSigningKey(profile, getIssuerSigningOpts(opts)...)
+	if err = signingKeyError(err, "the signing key"); err != nil {
+		return err
 	}
 
-	if pr.URI == "" {
-		return fmt.Errorf("missing URI information")
+	for i, proofProfile := range proofProfiles {
+		err = o.crypto.ValidateSigningKey(proofProfile, getProofSigningOpts(opts, &opts.Proofs[i])...)
+		if err = signingKeyError(err, fmt.Sprintf("the signing key of proof %d", i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// signingKeyError returns the error of the validation of the signing key: a bad request if the verification method
+// isn't listed under the proof purpose, else a signing error
+func signingKeyError(err error, key string) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, crypto.ErrInvalidProofPurpose) {
+		return commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("invalid proof purpose for %s: %s", key, err.Error()))
+	}
+
+	return commhttp.NewError(http.StatusInternalServerError, commhttp.SigningError,
+		fmt.Sprintf("failed to validate %s: %s", key, err.Error()))
+}
+
+// getProofSigningOpts returns the signing options of an additional proof, the proof purpose defaults to the one of
+// the issue credential options.
+func getProofSigningOpts(opts *IssueCredentialOptions, proof *ProofOptions) []crypto.SigningOpts {
+	purpose := proof.ProofPurpose
+	if purpose == "" {
+		purpose = opts.ProofPurpose
+	}
+
+	return []crypto.SigningOpts{
+		crypto.WithVerificationMethod(proof.VerificationMethod),
+		crypto.WithPurpose(purpose),
+		crypto.WithCreated(opts.Created),
+		crypto.WithChallenge(opts.Challenge),
+		crypto.WithDomain(opts.Domain),
+		crypto.WithNonce(decodeNonce(opts.Nonce)),
+		crypto.WithProofProperties(opts.ProofProperties),
+	}
+}
+
+func getIssuerSigningOpts(opts *IssueCredentialOptions) []crypto.SigningOpts {
+	var signingOpts []crypto.SigningOpts
+
+	if opts != nil {
+		// verification method takes priority
+		verificationMethod := opts.VerificationMethod
+
+		if verificationMethod == "" {
+			verificationMethod = opts.AssertionMethod
+		}
+
+		signingOpts = []crypto.SigningOpts{
+			crypto.WithVerificationMethod(verificationMethod),
+			crypto.WithPurpose(opts.ProofPurpose),
+			crypto.WithCreated(opts.Created),
+			crypto.WithChallenge(opts.Challenge),
+			crypto.WithDomain(opts.Domain),
+			crypto.WithNonce(decodeNonce(opts.Nonce)),
+			crypto.WithProofProperties(opts.ProofProperties),
+		}
 	}
 
-	if pr.SignatureType == "" {
-		return fmt.Errorf("missing signature type")
-	}
+	return signingOpts
+}
 
-	_, err := url.Parse(pr.URI)
-	if err != nil {
-		return fmt.Errorf("invalid uri: %s", err.Error())
+// holderConfirmation returns the holder key the SD-JWT credentials are bound to, nil if not set
+func holderConfirmation(opts *IssueCredentialOptions) *sdjwt.Confirmation {
+	if opts == nil || (opts.HolderKey == nil && opts.HolderKeyID == "") {
+		return nil
 	}
 
-	return nil
+	return &sdjwt.Confirmation{JWK: opts.HolderKey, KeyID: opts.HolderKeyID}
 }
 
-func validateRequest(profileName, vcID string) error {
-	if profileName == "" {
-		return fmt.Errorf("missing profile name")
+// decodeNonce decodes the base64url nonce of the issue credential options, validated with the request
+func decodeNonce(nonce string) []byte {
+	if nonce == "" {
+		return nil
 	}
 
-	if vcID == "" {
-		return fmt.Errorf("missing verifiable credential ID")
+	decoded, err := base64.RawURLEncoding.DecodeString(nonce)
+	if err != nil {
+		return nil
 	}
 
-	return nil
+	return decoded
 }
 
-// IssueCredential swagger:route POST /{id}/credentials/issueCredential issuer issueCredentialReq
+// GenerateKeypair swagger:route POST /kms/generatekeypair issuer generateKeypairReq
 //
-// Issues a credential.
+// Generates a keypair, stores it in the KMS and returns the public key.
 //
 // Responses:
 //    default: genericError
-//        201: verifiableCredentialRes
-// nolint: funlen
-func (o *Operation) issueCredentialHandler(rw http.ResponseWriter, req *http.Request) {
-	// get the issuer profile
-	profileID := mux.Vars(req)[profileIDPathParam]
+//        200: generateKeypairResp
+func (o *Operation) generateKeypairHandler(rw http.ResponseWriter, req *http.Request) {
+	data := GenerateKeyPairRequest{}
 
-	profile, err := o.profileStore.GetProfile(profileID)
-	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf("invalid issuer profile - id=%s: err=%s",
-			profileID, err.Error()))
+	if err := commhttp.DecodeJSON(req, &data); err != nil && !commhttp.IsEmptyBody(err) {
+		commhttp.WriteError(rw, err)
 
 		return
 	}
 
-	// get the request
-	cred := IssueCredentialRequest{}
+	if data.KeyType == "" {
+		data.KeyType = crypto.Ed25519KeyType
+	}
 
-	err = json.NewDecoder(req.Body).Decode(&cred)
-	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidRequestErrMsg+": %s", err.Error()))
+	if data.KeyType == bls12381G2KeyType {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("key type not supported: %s keys are not supported by the kms of the service",
+				data.KeyType))
 
 		return
 	}
 
-	// validate options
-	if err = validateIssueCredOptions(cred.Opts); err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+	kmsKeyType, ok := kmsKeyTypes[data.KeyType]
+	if !ok {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("key type not supported: %s", data.KeyType))
 
 		return
 	}
 
-	// validate the VC (ignore the proof)
-	credential, err := verifiable.ParseCredential(cred.Credential, verifiable.WithDisabledProofCheck())
+	keyID, signKey, err := o.createKey(kmsKeyType)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf("failed to validate credential: %s", err.Error()))
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.KMSError,
+			fmt.Sprintf("failed to create key pair: %s", err.Error()))
 
 		return
 	}
 
-	if !profile.DisableVCStatus {
-		// set credential status
-		credential.Status, err = o.vcStatusManager.CreateStatusID()
-		if err != nil {
-			commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, fmt.Sprintf("failed to add credential status:"+
-				" %s", err.Error()))
-
-			return
-		}
+	jwk, err := publicKeyToJWK(data.KeyType, signKey)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.KMSError,
+			fmt.Sprintf("failed to create jwk: %s", err.Error()))
 
-		credential.Context = append(credential.Context, cslstatus.Context)
+		return
 	}
 
-	// update context
-	vcutil.UpdateSignatureTypeContext(credential, profile)
-
-	// update credential issuer
-	vcutil.UpdateIssuer(credential, profile)
+	jwk.KeyID = keyID
 
-	// sign the credential
-	signedVC, err := o.crypto.SignCredential(profile, credential, getIssuerSigningOpts(cred.Opts)...)
-	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, fmt.Sprintf("failed to sign credential:"+
-			" %s", err.Error()))
+	if err := o.putGeneratedKey(keyID, &generatedKey{KeyType: data.KeyType}); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to record generated key: %s", err.Error()))
 
 		return
 	}
 
-	rw.WriteHeader(http.StatusCreated)
-	commhttp.WriteResponse(rw, signedVC)
+	rw.WriteHeader(http.StatusOK)
+	commhttp.WriteResponse(rw, &GenerateKeyPairResponse{
+		PublicKey: base58.Encode(signKey),
+		KeyID:     keyID,
+		JWK:       jwk,
+	})
 }
 
-// nolint funlen
-// composeAndIssueCredential swagger:route POST /{id}/credentials/composeAndIssueCredential issuer composeCredentialReq
+// DeleteKey swagger:route DELETE /kms/keys/{keyID} issuer deleteKeyReq
 //
-// Composes and Issues a credential.
+// Deletes a key generated through the generate keypair API from the KMS. The keys used by profiles or by the
+// encryption of the stored credentials are not deleted.
 //
 // Responses:
 //    default: genericError
-//        201: verifiableCredentialRes
-func (o *Operation) composeAndIssueCredentialHandler(rw http.ResponseWriter, req *http.Request) {
-	id := mux.Vars(req)[profileIDPathParam]
+//        200: emptyRes
+func (o *Operation) deleteKeyHandler(rw http.ResponseWriter, req *http.Request) {
+	keyID := mux.Vars(req)[keyIDPathParam]
 
-	profile, err := o.profileStore.GetProfile(id)
+	key, err := o.getGeneratedKey(keyID)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf("invalid issuer profile: %s", err.Error()))
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to get generated key: %s", err.Error()))
 
 		return
 	}
 
-	// get the request
-	composeCredReq := ComposeCredentialRequest{}
-
-	err = json.NewDecoder(req.Body).Decode(&composeCredReq)
-	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidRequestErrMsg+": %s", err.Error()))
+	if key == nil || key.Deleted {
+		commhttp.WriteErrorResponse(rw, http.StatusNotFound, commhttp.NotFound,
+			fmt.Sprintf("key not found: %s", keyID))
 
 		return
 	}
 
-	// create the verifiable credential
-	credential, err := buildCredential(&composeCredReq)
+	user, err := o.keyUser(keyID)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf("failed to build credential:"+
-			" %s", err.Error()))
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to check the use of the key: %s", err.Error()))
 
 		return
 	}
 
-	if !profile.DisableVCStatus {
-		// set credential status
-		credential.Status, err = o.vcStatusManager.CreateStatusID()
-		if err != nil {
-			commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, fmt.Sprintf("failed to add credential status:"+
-				" %s", err.Error()))
-
-			return
-		}
+	if user != "" {
+		commhttp.WriteErrorResponse(rw, http.StatusConflict, commhttp.Conflict,
+			fmt.Sprintf("key %s is used by %s", keyID, user))
 
-		credential.Context = append(credential.Context, cslstatus.Context)
+		return
 	}
 
-	// update context
-	vcutil.UpdateSignatureTypeContext(credential, profile)
-
-	// update credential issuer
-	vcutil.UpdateIssuer(credential, profile)
-
-	// prepare signing options from request options
-	opts, err := getComposeSigningOpts(&composeCredReq)
-	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf("failed to prepare signing options:"+
-			" %s", err.Error()))
+	if err := o.deleteKey(keyID); err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.KMSError,
+			fmt.Sprintf("failed to delete key: %s", err.Error()))
 
 		return
 	}
 
-	// sign the credential
-	signedVC, err := o.crypto.SignCredential(profile, credential, opts...)
-	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, fmt.Sprintf("failed to sign credential:"+
-			" %s", err.Error()))
+	key.Deleted = true
 
-		return
+	if err := o.putGeneratedKey(keyID, key); err != nil {
+		logger.Warnf("failed to mark key %s as deleted: %s", keyID, err)
 	}
 
-	// response
-	rw.WriteHeader(http.StatusCreated)
-	commhttp.WriteResponse(rw, signedVC)
+	rw.WriteHeader(http.StatusOK)
 }
 
-// nolint: funlen
-func buildCredential(composeCredReq *ComposeCredentialRequest) (*verifiable.Credential, error) {
-	// create the verifiable credential
-	credential := &verifiable.Credential{}
-
-	var err error
-
-	// set credential data
-	credential.Context, err = vcutil.GetContextsFromJSONRaw(composeCredReq.CredentialFormatOptions)
+// getGeneratedKey returns the key generated through the generate keypair API, nil if the key was not generated
+// through it.
+func (o *Operation) getGeneratedKey(keyID string) (*generatedKey, error) {
+	keyBytes, err := o.generatedKeys.Get(keyID)
 	if err != nil {
+		if errors.Is(err, storage.ErrValueNotFound) {
+			return nil, nil
+		}
+
 		return nil, err
 	}
 
-	if composeCredReq.IssuanceDate != nil {
-		credential.Issued = util.NewTime(*composeCredReq.IssuanceDate)
+	key := &generatedKey{}
+	if err := json.Unmarshal(keyBytes, key); err != nil {
+		return nil, err
 	}
 
-	if composeCredReq.ExpirationDate != nil {
-		credential.Expired = util.NewTime(*composeCredReq.ExpirationDate)
-	}
+	return key, nil
+}
 
-	// set default type, if request doesn't contain the type
-	credential.Types = []string{"VerifiableCredential"}
-	if len(composeCredReq.Types) != 0 {
-		credential.Types = composeCredReq.Types
+func (o *Operation) putGeneratedKey(keyID string, key *generatedKey) error {
+	keyBytes, err := json.Marshal(key)
+	if err != nil {
+		return err
 	}
 
-	// set subject
-	credentialSubject := make(map[string]interface{})
+	return o.generatedKeys.Put(keyID, keyBytes)
+}
 
-	if composeCredReq.Claims != nil {
-		err = json.Unmarshal(composeCredReq.Claims, &credentialSubject)
-		if err != nil {
-			return nil, err
-		}
+// keyUser returns what uses the key, an empty string if the key is not used by a profile nor by the encryption
+// of the stored credentials.
+func (o *Operation) keyUser(keyID string) (string, error) {
+	cryptoKey, err := cryptosetup.IsCryptoKey(o.storeProvider, keyID)
+	if err != nil {
+		return "", err
 	}
 
-	credentialSubject["id"] = composeCredReq.Subject
-	credential.Subject = credentialSubject
-
-	// set issuer
-	credential.Issuer = verifiable.Issuer{
-		ID: composeCredReq.Issuer,
+	if cryptoKey {
+		return "the encryption of the stored credentials", nil
 	}
 
-	// set terms of use
-	termsOfUse, err := vcutil.DecodeTypedIDFromJSONRaw(composeCredReq.TermsOfUse)
+	profile, err := o.profileStore.GetKeyReference(keyID)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	credential.TermsOfUse = termsOfUse
-
-	// set evidence
-	if composeCredReq.Evidence != nil {
-		evidence := make(map[string]interface{})
-
-		err := json.Unmarshal(composeCredReq.Evidence, &evidence)
-		if err != nil {
-			return nil, err
-		}
-
-		credential.Evidence = evidence
+	if profile != "" {
+		return "profile " + profile, nil
 	}
 
-	return credential, nil
+	return "", nil
 }
 
-func getComposeSigningOpts(composeCredReq *ComposeCredentialRequest) ([]crypto.SigningOpts, error) {
-	var proofFormatOptions struct {
-		KeyID   string     `json:"kid,omitempty"`
-		Purpose string     `json:"proofPurpose,omitempty"`
-		Created *time.Time `json:"created,omitempty"`
+// deleteKey deletes the key from the kms. Key managers without delete support (e.g. localkms) store their
+// keys in the kms secrets store, so the key is removed from there.
+func (o *Operation) deleteKey(keyID string) error {
+	if d, ok := o.kms.(keyDeleter); ok {
+		return d.Delete(keyID)
 	}
 
-	if composeCredReq.ProofFormatOptions != nil {
-		err := json.Unmarshal(composeCredReq.ProofFormatOptions, &proofFormatOptions)
-		if err != nil {
-			return nil, fmt.Errorf("failed to prepare signing opts: %w", err)
-		}
+	store, err := o.kmsSecretsProvider.OpenStore(localkms.Namespace)
+	if err != nil {
+		return err
 	}
 
-	representation := "jws"
-	if composeCredReq.ProofFormat != "" {
-		representation = composeCredReq.ProofFormat
+	return store.Delete(keyID)
+}
+
+func openStore(provider storage.Provider, name string) (storage.Store, error) {
+	err := provider.CreateStore(name)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
 	}
 
-	return []crypto.SigningOpts{
-		crypto.WithPurpose(proofFormatOptions.Purpose),
-		crypto.WithVerificationMethod(proofFormatOptions.KeyID),
-		crypto.WithSigningRepresentation(representation),
-		crypto.WithCreated(proofFormatOptions.Created),
-	}, nil
+	return provider.OpenStore(name)
 }
 
-func getIssuerSigningOpts(opts *IssueCredentialOptions) []crypto.SigningOpts {
-	var signingOpts []crypto.SigningOpts
-
-	if opts != nil {
-		// verification method takes priority
-		verificationMethod := opts.VerificationMethod
+func publicKeyToJWK(keyType string, pubKeyBytes []byte) (*jose.JWK, error) {
+	var pubKey interface{}
 
-		if verificationMethod == "" {
-			verificationMethod = opts.AssertionMethod
+	switch keyType {
+	case crypto.Ed25519KeyType:
+		pubKey = ed25519.PublicKey(pubKeyBytes)
+	case crypto.RSAKeyType:
+		rsaKey, err := x509.ParsePKCS1PublicKey(pubKeyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rsa public key: %w", err)
 		}
 
-		signingOpts = []crypto.SigningOpts{
-			crypto.WithVerificationMethod(verificationMethod),
-			crypto.WithPurpose(opts.ProofPurpose),
-			crypto.WithCreated(opts.Created),
-			crypto.WithChallenge(opts.Challenge),
-			crypto.WithDomain(opts.Domain),
+		pubKey = rsaKey
+	default:
+		var curve elliptic.Curve
+
+		switch keyType {
+		case crypto.P384KeyType:
+			curve = elliptic.P384()
+		case crypto.P521KeyType:
+			curve = elliptic.P521()
+		case crypto.Secp256k1KeyType:
+			curve = btcec.S256()
+		default:
+			curve = elliptic.P256()
 		}
-	}
-
-	return signingOpts
-}
 
-// GenerateKeypair swagger:route GET /kms/generatekeypair issuer req
-//
-// Generates a keypair, stores it in the KMS and returns the public key.
-//
-// Responses:
-//    default: genericError
-//        200: generateKeypairResp
-func (o *Operation) generateKeypairHandler(rw http.ResponseWriter, req *http.Request) {
-	keyID, signKey, err := o.createKey(kms.ED25519Type)
-	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError,
-			fmt.Sprintf("failed to create key pair: %s", err.Error()))
+		x, y := elliptic.Unmarshal(curve, pubKeyBytes)
+		if x == nil {
+			return nil, errors.New("invalid ecdsa public key")
+		}
 
-		return
+		pubKey = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
 	}
 
-	rw.WriteHeader(http.StatusOK)
-	commhttp.WriteResponse(rw, &GenerateKeyPairResponse{
-		PublicKey: base58.Encode(signKey),
-		KeyID:     keyID,
-	})
+	return jose.JWKFromPublicKey(pubKey)
 }
 
 func (o *Operation) createKey(keyType kms.KeyType) (string, []byte, error) {
+	start := time.Now()
+
 	keyID, _, err := o.kms.Create(keyType)
 	if err != nil {
 		return "", nil, err
 	}
 
+	metrics.ObserveKMSOperation("createKey", start)
+
+	start = time.Now()
+
 	pubKeyBytes, err := o.kms.ExportPubKeyBytes(keyID)
 	if err != nil {
 		return "", nil, err
 	}
 
+	metrics.ObserveKMSOperation("exportPubKey", start)
+
 	return keyID, pubKeyBytes, nil
 }
 
@@ -875,6 +4951,7 @@ func (o *Operation) parseAndVerifyVC(vcBytes []byte) (*verifiable.Credential, er
 		verifiable.WithPublicKeyFetcher(
 			verifiable.NewDIDKeyResolver(o.vdri).PublicKeyFetcher(),
 		),
+		verifiable.WithEmbeddedSignatureSuites(crypto.VerifierSuites()...),
 	)
 
 	if err != nil {
@@ -884,7 +4961,7 @@ func (o *Operation) parseAndVerifyVC(vcBytes []byte) (*verifiable.Credential, er
 	return vc, nil
 }
 
-func (o *Operation) queryVault(vaultID, vcID string) ([]string, error) {
+func (o *Operation) queryVault(edvClient EDVClient, vaultID, vcID string) ([]string, error) {
 	vcIDMAC, err := o.macCrypto.ComputeMAC([]byte(vcID), o.macKeyHandle)
 	if err != nil {
 		return nil, err
@@ -897,7 +4974,7 @@ func (o *Operation) queryVault(vaultID, vcID string) ([]string, error) {
 	err = retry.Retry(func() error {
 		var errQueryVault error
 
-		docURLs, errQueryVault = o.edvClient.QueryVault(vaultID, &models.Query{
+		docURLs, errQueryVault = edvClient.QueryVault(vaultID, &models.Query{
 			Name:  o.vcIDIndexNameEncoded,
 			Value: vcIDIndexValueEncoded,
 		})
@@ -915,40 +4992,49 @@ func (o *Operation) queryVault(vaultID, vcID string) ([]string, error) {
 	return docURLs, err
 }
 
-func (o *Operation) retrieveCredential(rw http.ResponseWriter, profileName string, docURLs []string) {
+func (o *Operation) retrieveCredential(rw http.ResponseWriter, edvClient EDVClient, profileName string,
+	docURLs []string) {
 	var retrievedVC []byte
 
 	switch len(docURLs) {
 	case 0:
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest,
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.NotFound,
 			fmt.Sprintf(`no VC under profile "%s" was found with the given id`, profileName))
 	case 1:
 		docID := vcutil.GetDocIDFromURL(docURLs[0])
 
 		var err error
 
-		retrievedVC, err = o.retrieveVC(profileName, docID, "retrieving VC")
+		retrievedVC, err = o.retrieveVC(edvClient, profileName, docID, "retrieving VC")
 		if err != nil {
-			commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, err.Error())
+			commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
 
 			return
 		}
 	default:
 		// Multiple VCs were found with the same id. This is technically possible under the right circumstances
 		// when storing the same VC multiples times in a store provider that follows an "eventually consistent"
-		// consistency model. If they are all the same, then just return the first one arbitrarily.
-		// ToDo: If the multiple VCs with the same ID all are identical then delete the extras and leave only one.
-		// https://github.com/trustbloc/edge-service/issues/262
+		// consistency model. If they are all the same, then just return the first one arbitrarily
+		// and delete the extras.
 		var err error
 
 		var statusCode int
 
-		retrievedVC, statusCode, err = o.verifyMultipleMatchingVCsAreIdentical(profileName, docURLs)
+		retrievedVC, statusCode, err = o.verifyMultipleMatchingVCsAreIdentical(edvClient, profileName, docURLs)
 		if err != nil {
-			commhttp.WriteErrorResponse(rw, statusCode, err.Error())
+			var opErr *commhttp.Error
+			if errors.As(err, &opErr) {
+				commhttp.WriteError(rw, opErr)
+
+				return
+			}
+
+			commhttp.WriteErrorResponse(rw, statusCode, commhttp.EDVError, err.Error())
 
 			return
 		}
+
+		o.deleteDuplicateVCs(edvClient, profileName, docURLs[1:])
 	}
 
 	_, err := rw.Write(retrievedVC)
@@ -960,42 +5046,107 @@ func (o *Operation) retrieveCredential(rw http.ResponseWriter, profileName strin
 	}
 }
 
-func (o *Operation) verifyMultipleMatchingVCsAreIdentical(profileName string, docURLs []string) ([]byte, int, error) {
-	var retrievedVCs [][]byte
-
+// deleteDuplicateVCs deletes the extra copies of an identical VC. The VC has already been retrieved, so
+// the failures are only logged.
+func (o *Operation) deleteDuplicateVCs(edvClient EDVClient, profileName string, docURLs []string) {
 	for _, docURL := range docURLs {
 		docID := vcutil.GetDocIDFromURL(docURL)
 
-		retrievedVC, err := o.retrieveVC(profileName, docID, "determining if the multiple VCs "+
-			"matching the given ID are the same")
+		if o.cleanupDryRun {
+			logger.Infof("dry run: duplicate VC document %s of vault %s would be deleted", docID, profileName)
+
+			continue
+		}
+
+		if err := edvClient.DeleteDocument(profileName, docID); err != nil {
+			logger.Warnf("failed to delete duplicate VC document %s of vault %s: %s", docID, profileName, err)
+
+			continue
+		}
+
+		logger.Infof("deleted duplicate VC document %s of vault %s", docID, profileName)
+	}
+}
+
+func (o *Operation) verifyMultipleMatchingVCsAreIdentical(edvClient EDVClient, profileName string,
+	docURLs []string) ([]byte, int, error) {
+	const contextErrText = "determining if the multiple VCs matching the given ID are the same"
+
+	docIDs := make([]string, len(docURLs))
+
+	for i, docURL := range docURLs {
+		docIDs[i] = vcutil.GetDocIDFromURL(docURL)
+	}
+
+	documents, err := edvClient.ReadDocuments(profileName, docIDs)
+	if err != nil {
+		return nil, http.StatusInternalServerError,
+			fmt.Errorf("failed to read documents while %s: %s", contextErrText, err)
+	}
+
+	retrievedVCs := make([][]byte, len(documents))
+
+	for i, document := range documents {
+		retrievedVCs[i], err = o.decryptVC(profileName, document, contextErrText)
 		if err != nil {
 			return nil, http.StatusInternalServerError, err
 		}
-
-		retrievedVCs = append(retrievedVCs, retrievedVC)
 	}
 
 	for i := 1; i < len(retrievedVCs); i++ {
 		if !bytes.Equal(retrievedVCs[0], retrievedVCs[i]) {
-			return nil, http.StatusConflict, errMultipleInconsistentVCsFoundForOneID
+			return nil, http.StatusConflict, &commhttp.Error{Status: http.StatusConflict, Code: commhttp.Conflict,
+				Message: errMultipleInconsistentVCsFoundForOneID.Error(),
+				Details: inconsistentVCsDetails(retrievedVCs[0], retrievedVCs[i])}
 		}
 	}
 
 	return retrievedVCs[0], http.StatusOK, nil
 }
 
-func (o *Operation) retrieveVC(profileName, docID, contextErrText string) ([]byte, error) {
-	document, err := o.edvClient.ReadDocument(profileName, docID)
+// inconsistentVCsDetails describes the fields which differ between two VCs stored under the same ID, to reconcile
+// them (see POST /credentials/compare)
+func inconsistentVCsDetails(vc, otherVC []byte) string {
+	differences, err := canonical.Diff(vc, otherVC)
+	if err != nil {
+		return ""
+	}
+
+	if len(differences) == 0 {
+		return "the VCs differ in their proofs only"
+	}
+
+	paths := make([]string, len(differences))
+
+	for i, difference := range differences {
+		paths[i] = difference.Path
+	}
+
+	return "the VCs differ at " + strings.Join(paths, ", ")
+}
+
+func (o *Operation) retrieveVC(edvClient EDVClient, profileName, docID, contextErrText string) ([]byte, error) {
+	document, err := edvClient.ReadDocument(profileName, docID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read document while %s: %s", contextErrText, err)
 	}
 
+	return o.decryptVC(profileName, document, contextErrText)
+}
+
+func (o *Operation) decryptVC(profileName string, document *models.EncryptedDocument,
+	contextErrText string) ([]byte, error) {
 	encryptedJWE, err := jose.Deserialize(string(document.JWE))
 	if err != nil {
 		return nil, err
 	}
 
-	decryptedDocBytes, err := o.jweDecrypter.Decrypt(encryptedJWE)
+	jweDecrypter, err := o.profileJWECrypto(profileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the document encryption keys while "+contextErrText+": %w", err)
+	}
+
+	decryptedDocBytes, err := jweDecrypter.Decrypt(encryptedJWE)
 	if err != nil {
 		return nil, fmt.Errorf("decrypting document failed while "+contextErrText+": %s", err)
 	}
@@ -1016,23 +5167,3 @@ func (o *Operation) retrieveVC(profileName, docID, contextErrText string) ([]byt
 
 	return retrievedVC, nil
 }
-
-func validateIssueCredOptions(options *IssueCredentialOptions) error {
-	if options != nil {
-		switch {
-		case options.ProofPurpose != "":
-			switch options.ProofPurpose {
-			case assertionMethod, authentication, capabilityDelegation, capabilityInvocation:
-			default:
-				return fmt.Errorf("invalid proof option : %s", options.ProofPurpose)
-			}
-		case options.AssertionMethod != "":
-			idSplit := strings.Split(options.AssertionMethod, "#")
-			if len(idSplit) != 2 {
-				return fmt.Errorf("invalid assertion method : %s", idSplit)
-			}
-		}
-	}
-
-	return nil
-}
@@ -7,44 +7,78 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 import (
+	"archive/zip"
 	"bytes"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/google/tink/go/keyset"
+	"github.com/google/tink/go/mac"
 	"github.com/gorilla/mux"
 	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes/subtle"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/hyperledger/aries-framework-go/pkg/kms/localkms"
 	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
 	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
 	mocklegacykms "github.com/hyperledger/aries-framework-go/pkg/mock/kms/legacykms"
 	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock"
+	"github.com/hyperledger/aries-framework-go/pkg/secretlock/noop"
+	ariesstorage "github.com/hyperledger/aries-framework-go/pkg/storage"
 	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/edge-core/pkg/storage"
 	"github.com/trustbloc/edge-core/pkg/storage/memstore"
 	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
 	"github.com/trustbloc/edge-core/pkg/utils/retry"
+	"github.com/trustbloc/edv/pkg/restapi/messages"
 	"github.com/trustbloc/edv/pkg/restapi/models"
 
+	"github.com/trustbloc/edge-service/pkg/apikey"
 	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/manifest"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
 	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/quota"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/sdjwt"
 	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/history"
+	"github.com/trustbloc/edge-service/pkg/internal/common/support"
+	"github.com/trustbloc/edge-service/pkg/internal/cryptosetup"
 	"github.com/trustbloc/edge-service/pkg/internal/mock/edv"
+	"github.com/trustbloc/edge-service/pkg/kms/signingkms"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
 	"github.com/trustbloc/edge-service/pkg/restapi/model"
+	"github.com/trustbloc/edge-service/pkg/vdri/web"
 )
 
 const (
@@ -226,12 +260,390 @@ func TestNew(t *testing.T) {
 		require.Contains(t, err.Error(), "failed to instantiate new csl status")
 		require.Nil(t, op)
 	})
+	t.Run("test error from csl of the status store provider", func(t *testing.T) {
+		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			StatusStoreProvider: &mockstore.Provider{FailNameSpace: "credentialstatus"},
+			EDVClient:           client, VDRI: &vdrimock.MockVDRIRegistry{}, HostURL: "localhost:8080"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to instantiate new csl status")
+		require.Nil(t, op)
+	})
+	t.Run("test edv key manager", func(t *testing.T) {
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		remoteKMS := &mockkms.KeyManager{CreateKeyErr: errors.New("remote kms error")}
+		config := &Config{StoreProvider: memstore.NewProvider(), VDRI: &vdrimock.MockVDRIRegistry{},
+			EDVClient:  edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
+			KeyManager: remoteKMS, Crypto: &cryptomock.Crypto{}, HostURL: "localhost:8080"}
+
+		op, err := New(config)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "remote kms error")
+		require.Nil(t, op)
+
+		config.EDVKeyManager = &mockkms.KeyManager{CreateKeyValue: kh}
+		config.EDVCrypto = &cryptomock.Crypto{}
+
+		op, err = New(config)
+		require.NoError(t, err)
+		require.Equal(t, remoteKMS, op.kms)
+		require.Equal(t, config.EDVCrypto, op.macCrypto)
+	})
+	t.Run("test unsupported JWE algorithm", func(t *testing.T) {
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(), VDRI: &vdrimock.MockVDRIRegistry{},
+			KeyManager: &mockkms.KeyManager{}, HostURL: "localhost:8080", JWEEncAlg: "XC20P"})
+		require.EqualError(t, err, "unsupported JWE algorithm ECDH-ES+A256KW/XC20P, "+
+			"supported algorithms: ECDH-ES+A256KW/A256GCM")
+		require.Nil(t, op)
+	})
 }
 
 func TestUpdateCredentialStatusHandler(t *testing.T) {
 	testUpdateCredentialStatusHandler(t)
 }
 
+func TestChangeCredentialStatusHandlers(t *testing.T) {
+	s := make(map[string][]byte)
+	s["profile_issuer_Example University"] = []byte(testIssuerProfile)
+	s["profile_issuer_vc without status"] = []byte(testIssuerProfileWithDisableVCStatus)
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{StoreProvider: &mockstore.Provider{Store: &mockstore.MockStore{Store: s}},
+		KMSSecretsProvider: mem.NewProvider(), EDVClient: edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
+		KeyManager: &mockkms.KeyManager{CreateKeyValue: kh}, Crypto: &cryptomock.Crypto{},
+		VDRI: &vdrimock.MockVDRIRegistry{}, HostURL: "localhost:8080"})
+	require.NoError(t, err)
+
+	statusManager := &mockVCStatusManager{}
+	op.vcStatusManager = statusManager
+
+	changeStatus := func(t *testing.T, endpoint, credential string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		reqBytes, err := json.Marshal(ChangeCredentialStatusRequest{Credential: credential, StatusReason: "reason"})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer(reqBytes))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		getHandler(t, op, endpoint, http.MethodPost).Handle().ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	t.Run("success", func(t *testing.T) {
+		for _, endpoint := range []string{revokeCredentialEndpoint, suspendCredentialEndpoint,
+			reinstateCredentialEndpoint} {
+			rr := changeStatus(t, endpoint, validVC)
+			require.Equal(t, http.StatusOK, rr.Code, endpoint)
+		}
+	})
+
+	t.Run("invalid status transition", func(t *testing.T) {
+		statusManager.revokeVCErr = fmt.Errorf("%w: credential is already revoked",
+			cslstatus.ErrInvalidStatusTransition)
+		defer func() { statusManager.revokeVCErr = nil }()
+
+		rr := changeStatus(t, revokeCredentialEndpoint, validVC)
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Contains(t, rr.Body.String(), "already revoked")
+	})
+
+	t.Run("status list updated concurrently", func(t *testing.T) {
+		statusManager.suspendVCErr = fmt.Errorf("%w: csl 1", cslstatus.ErrConcurrentUpdate)
+		defer func() { statusManager.suspendVCErr = nil }()
+
+		rr := changeStatus(t, suspendCredentialEndpoint, validVC)
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Contains(t, rr.Body.String(), "csl updated concurrently")
+	})
+
+	t.Run("error from status manager", func(t *testing.T) {
+		statusManager.suspendVCErr = fmt.Errorf("error suspend vc")
+		statusManager.reinstateVCErr = fmt.Errorf("error reinstate vc")
+		defer func() { statusManager.suspendVCErr, statusManager.reinstateVCErr = nil, nil }()
+
+		rr := changeStatus(t, suspendCredentialEndpoint, validVC)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to update vc status: error suspend vc")
+
+		rr = changeStatus(t, reinstateCredentialEndpoint, validVC)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to update vc status: error reinstate vc")
+	})
+
+	t.Run("vc status disabled", func(t *testing.T) {
+		rr := changeStatus(t, suspendCredentialEndpoint, validVCWithoutStatus)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "vc status is disabled for profile")
+	})
+
+	t.Run("invalid credential", func(t *testing.T) {
+		rr := changeStatus(t, revokeCredentialEndpoint, invalidVC)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "unable to unmarshal the VC")
+	})
+
+	t.Run("error decode request", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, reinstateCredentialEndpoint, bytes.NewBuffer([]byte("w")))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		getHandler(t, op, reinstateCredentialEndpoint, http.MethodPost).Handle().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "INVALID_REQUEST")
+	})
+}
+
+func TestStatusHistoryHandler(t *testing.T) {
+	s := make(map[string][]byte)
+	s["profile_issuer_Example University"] = []byte(testIssuerProfile)
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{StoreProvider: &mockstore.Provider{Store: &mockstore.MockStore{Store: s}},
+		KMSSecretsProvider: mem.NewProvider(), EDVClient: edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
+		KeyManager: &mockkms.KeyManager{CreateKeyValue: kh}, Crypto: &cryptomock.Crypto{},
+		VDRI: &vdrimock.MockVDRIRegistry{}, HostURL: "localhost:8080"})
+	require.NoError(t, err)
+
+	op.vcStatusManager = &mockVCStatusManager{}
+
+	authenticator := apikey.New(map[string]string{"client1": "key1"})
+
+	changeStatus := func(t *testing.T, endpoint string, header map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		reqBytes, err := json.Marshal(ChangeCredentialStatusRequest{Credential: validVC, StatusReason: "lost"})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer(reqBytes))
+		require.NoError(t, err)
+
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+
+		rr := httptest.NewRecorder()
+		authenticator.Middleware(getHandler(t, op, endpoint, http.MethodPost).Handle()).ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	getHistory := func(t *testing.T, profile, credentialID string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		return serveHTTPMux(t, getHandler(t, op, statusHistoryPath, http.MethodGet), statusHistoryPath, nil,
+			map[string]string{profileIDPathParam: profile, credentialIDPathParam: credentialID})
+	}
+
+	vcID := base64.RawURLEncoding.EncodeToString([]byte("http://example.edu/credentials/1872"))
+
+	t.Run("success", func(t *testing.T) {
+		rr := changeStatus(t, suspendCredentialEndpoint, map[string]string{
+			apikey.Header:               "key1",
+			support.CorrelationIDHeader: "correlation1",
+		})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = changeStatus(t, reinstateCredentialEndpoint, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		// the status change of an unknown API key is rejected before being applied
+		rr = changeStatus(t, revokeCredentialEndpoint, map[string]string{apikey.Header: "key2"})
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		rr = getHistory(t, "Example University", vcID)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp StatusHistoryResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		require.Equal(t, "http://example.edu/credentials/1872", resp.ID)
+		require.Len(t, resp.StatusChanges, 2)
+
+		require.Equal(t, cslstatus.StatusSuspended, resp.StatusChanges[0].Status)
+		require.Equal(t, "lost", resp.StatusChanges[0].StatusReason)
+		require.Equal(t, "client:client1", resp.StatusChanges[0].Actor)
+		require.Equal(t, "correlation1", resp.StatusChanges[0].CorrelationID)
+
+		require.Equal(t, cslstatus.StatusActive, resp.StatusChanges[1].Status)
+		require.Equal(t, "anonymous", resp.StatusChanges[1].Actor)
+		require.NotEmpty(t, resp.StatusChanges[1].CorrelationID)
+		require.False(t, resp.StatusChanges[1].Time.Before(resp.StatusChanges[0].Time))
+	})
+
+	t.Run("no status change recorded", func(t *testing.T) {
+		rr := getHistory(t, "Example University",
+			base64.RawURLEncoding.EncodeToString([]byte("http://example.edu/credentials/1")))
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), "no status change recorded for credential")
+	})
+
+	t.Run("invalid credential ID", func(t *testing.T) {
+		rr := getHistory(t, "Example University", "http://example.edu/credentials/1872")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid credential ID, expected base64url encoding")
+	})
+
+	t.Run("profile not found", func(t *testing.T) {
+		rr := getHistory(t, "unknown", vcID)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid issuer profile")
+	})
+
+	t.Run("error from status history", func(t *testing.T) {
+		op.statusHistory = &mockStatusHistory{err: fmt.Errorf("error history")}
+
+		// the status change is applied, but the request fails as it isn't recorded
+		rr := changeStatus(t, revokeCredentialEndpoint, nil)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(),
+			"credential status changed to Revoked but failed to record it in the status history: error history")
+
+		rr = getHistory(t, "Example University", vcID)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "error history")
+	})
+}
+
+func TestManifestHandlers(t *testing.T) {
+	op, err := New(&Config{
+		Crypto:             &cryptomock.Crypto{},
+		StoreProvider:      memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: getTestKeyHandle(t)},
+	})
+	require.NoError(t, err)
+
+	profile := getTestProfile()
+	require.NoError(t, op.profileStore.SaveProfile(profile))
+
+	manifestBytes := []byte(`{
+		"id": "degree",
+		"output_descriptors": [{"id": "bachelor", "schema": "https://example.com/schemas/degree"}]
+	}`)
+
+	serveManifest := func(t *testing.T, path, method string, reqBytes []byte,
+		urlVars map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		return serveHTTPMux(t, getHandler(t, op, path, method), path, reqBytes, urlVars)
+	}
+
+	profileVars := map[string]string{profileIDPathParam: profile.Name}
+	manifestVars := map[string]string{profileIDPathParam: profile.Name, manifestIDPathParam: "degree"}
+
+	t.Run("success", func(t *testing.T) {
+		rr := serveManifest(t, manifestsPath, http.MethodPost, manifestBytes, profileVars)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		created := &manifest.CredentialManifest{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), created))
+		require.Equal(t, "degree", created.ID)
+		require.Equal(t, profile.DID, created.Issuer.ID)
+
+		rr = serveManifest(t, manifestsPath, http.MethodPost, manifestBytes, profileVars)
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Contains(t, rr.Body.String(), "manifest degree already exists")
+
+		created.Issuer.Name = "Example University"
+
+		updateBytes, err := json.Marshal(created)
+		require.NoError(t, err)
+
+		rr = serveManifest(t, manifestPath, http.MethodPut, updateBytes, manifestVars)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = serveManifest(t, manifestPath, http.MethodGet, nil, manifestVars)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		m := &manifest.CredentialManifest{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), m))
+		require.Equal(t, created, m)
+
+		rr = serveManifest(t, manifestsPath, http.MethodGet, nil, profileVars)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		list := &ListManifestsResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), list))
+		require.Equal(t, []manifest.CredentialManifest{*created}, list.Manifests)
+
+		rr = serveManifest(t, manifestPath, http.MethodDelete, nil, manifestVars)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = serveManifest(t, manifestPath, http.MethodGet, nil, manifestVars)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), "manifest not found: degree")
+
+		rr = serveManifest(t, manifestPath, http.MethodDelete, nil, manifestVars)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+
+		rr = serveManifest(t, manifestPath, http.MethodPut, updateBytes, manifestVars)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		rr := serveManifest(t, manifestsPath, http.MethodPost, []byte("{"), profileVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), invalidRequestErrMsg)
+
+		rr = serveManifest(t, manifestsPath, http.MethodPost, []byte(`{"id":"degree"}`), profileVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "missing manifest output descriptors")
+
+		rr = serveManifest(t, manifestsPath, http.MethodPost,
+			[]byte(`{"id":"degree","issuer":{"id":"did:example:other"}}`), profileVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "manifest issuer did:example:other is not the profile DID did:test:abc")
+
+		rr = serveManifest(t, manifestPath, http.MethodPut, manifestBytes,
+			map[string]string{profileIDPathParam: profile.Name, manifestIDPathParam: "other"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "manifest id degree doesn't match the path manifest id other")
+	})
+
+	t.Run("profile not found", func(t *testing.T) {
+		vars := map[string]string{profileIDPathParam: "other", manifestIDPathParam: "degree"}
+
+		for _, h := range []struct{ path, method string }{{manifestsPath, http.MethodPost},
+			{manifestsPath, http.MethodGet}, {manifestPath, http.MethodGet}, {manifestPath, http.MethodPut},
+			{manifestPath, http.MethodDelete}} {
+			rr := serveManifest(t, h.path, h.method, manifestBytes, vars)
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			require.Contains(t, rr.Body.String(), string(commhttp.ProfileNotFound))
+		}
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		manifests := op.manifests
+		defer func() { op.manifests = manifests }()
+
+		op.manifests = &mockManifestStore{err: errors.New("store error")}
+
+		rr := serveManifest(t, manifestsPath, http.MethodPost, manifestBytes, profileVars)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "store error")
+
+		rr = serveManifest(t, manifestsPath, http.MethodGet, nil, profileVars)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+		rr = serveManifest(t, manifestPath, http.MethodGet, nil, manifestVars)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+		op.manifests = &mockManifestStore{putErr: errors.New("put error")}
+
+		rr = serveManifest(t, manifestsPath, http.MethodPost, manifestBytes, profileVars)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "put error")
+	})
+}
+
 func testUpdateCredentialStatusHandler(t *testing.T) {
 	client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
 	s := make(map[string][]byte)
@@ -264,6 +676,36 @@ func testUpdateCredentialStatusHandler(t *testing.T) {
 		require.Equal(t, http.StatusOK, rr.Code)
 	})
 
+	t.Run("update credential status - invalid status transition", func(t *testing.T) {
+		tests := []struct {
+			status  string
+			manager *mockVCStatusManager
+		}{
+			{cslstatus.StatusRevoked, &mockVCStatusManager{revokeVCErr: cslstatus.ErrInvalidStatusTransition}},
+			{cslstatus.StatusSuspended, &mockVCStatusManager{suspendVCErr: cslstatus.ErrInvalidStatusTransition}},
+			{cslstatus.StatusActive, &mockVCStatusManager{reinstateVCErr: cslstatus.ErrInvalidStatusTransition}},
+			{"Expired", &mockVCStatusManager{updateVCStatusErr: cslstatus.ErrInvalidStatusTransition}},
+			{cslstatus.StatusRevoked, &mockVCStatusManager{revokeVCErr: cslstatus.ErrConcurrentUpdate}},
+		}
+
+		for _, tc := range tests {
+			op.vcStatusManager = tc.manager
+
+			ucsReq := UpdateCredentialStatusRequest{Credential: validVC, Status: tc.status}
+			ucsReqBytes, err := json.Marshal(ucsReq)
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, updateCredentialStatusEndpoint, bytes.NewBuffer(ucsReqBytes))
+			require.NoError(t, err)
+			rr := httptest.NewRecorder()
+
+			updateCredentialStatusHandler.Handle().ServeHTTP(rr, req)
+			require.Equal(t, http.StatusConflict, rr.Code, tc.status)
+		}
+
+		op.vcStatusManager = &mockVCStatusManager{getCSLValue: &cslstatus.CSL{}}
+	})
+
 	t.Run("test disable vc status", func(t *testing.T) {
 		ucsReq := UpdateCredentialStatusRequest{Credential: validVCWithoutStatus, Status: "revoked"}
 		ucsReqBytes, err := json.Marshal(ucsReq)
@@ -287,7 +729,7 @@ func testUpdateCredentialStatusHandler(t *testing.T) {
 		updateCredentialStatusHandler.Handle().ServeHTTP(rr, req)
 		require.Equal(t, http.StatusBadRequest, rr.Code)
 
-		require.Contains(t, rr.Body.String(), "failed to decode request received")
+		require.Contains(t, rr.Body.String(), "INVALID_REQUEST")
 	})
 
 	t.Run("test error from parse credential", func(t *testing.T) {
@@ -372,6 +814,12 @@ type mockCommonDID struct {
 	createDIDValue string
 	createDIDKeyID string
 	createDIDErr   error
+	addKeyValue    string
+	addKeyErr      error
+	addKeyType     string
+	webDIDPath     []string
+	updateDIDValue []string
+	updateDIDErr   error
 }
 
 func (m *mockCommonDID) CreateDID(keyType, signatureType, didID, privateKey, keyID, purpose string,
@@ -379,6 +827,28 @@ func (m *mockCommonDID) CreateDID(keyType, signatureType, didID, privateKey, key
 	return m.createDIDValue, m.createDIDKeyID, m.createDIDErr
 }
 
+func (m *mockCommonDID) AddKey(didID, keyType, signatureType, privateKey, keyID string,
+	registrar model.UNIRegistrar) (string, error) {
+	m.addKeyType = keyType
+
+	return m.addKeyValue, m.addKeyErr
+}
+
+func (m *mockCommonDID) CreateKeyDID(keyType string) (string, string, error) {
+	return m.createDIDValue, m.createDIDKeyID, m.createDIDErr
+}
+
+func (m *mockCommonDID) CreateWebDID(keyType, signatureType string, path ...string) (string, string, error) {
+	m.webDIDPath = path
+
+	return m.createDIDValue, m.createDIDKeyID, m.createDIDErr
+}
+
+func (m *mockCommonDID) UpdateDID(didID string, update *model.DIDUpdate,
+	registrar model.UNIRegistrar) ([]string, error) {
+	return m.updateDIDValue, m.updateDIDErr
+}
+
 func testCreateProfileHandler(t *testing.T) {
 	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
 	require.NoError(t, err)
@@ -477,8 +947,8 @@ func testCreateProfileHandler(t *testing.T) {
 		require.Contains(t, rr.Body.String(), "failed to resolve did")
 	})
 
-	t.Run("missing profile name", func(t *testing.T) {
-		prBytes, err := json.Marshal(ProfileRequest{})
+	t.Run("missing profile fields", func(t *testing.T) {
+		prBytes, err := json.Marshal(ProfileRequest{CredentialStorage: "s3"})
 		require.NoError(t, err)
 
 		req, err := http.NewRequest(http.MethodPost, createProfileEndpoint, bytes.NewBuffer(prBytes))
@@ -492,7 +962,37 @@ func testCreateProfileHandler(t *testing.T) {
 		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
 		require.NoError(t, err)
 
-		require.Equal(t, errResp.Message, "missing profile name")
+		require.Equal(t, "INVALID_REQUEST", errResp.Code)
+		require.Equal(t, "missing profile name; missing URI information; missing signature type; "+
+			"invalid credential storage: s3", errResp.Message)
+		require.Equal(t, []model.FieldError{
+			{Field: "/name", Message: "missing profile name"},
+			{Field: "/uri", Message: "missing URI information"},
+			{Field: "/signatureType", Message: "missing signature type"},
+			{Field: "/credentialStorage", Message: "invalid credential storage: s3"},
+		}, errResp.Fields)
+	})
+	t.Run("invalid profile quota", func(t *testing.T) {
+		for q, fields := range map[quota.Quota][]model.FieldError{
+			{Daily: -1, Monthly: -1}: {
+				{Field: "/quota/daily", Message: "negative daily quota"},
+				{Field: "/quota/monthly", Message: "negative monthly quota"},
+			},
+			{Daily: 20, Monthly: 10}: {{Field: "/quota/daily", Message: "daily quota exceeds the monthly quota"}},
+		} {
+			q := q
+
+			prBytes, err := json.Marshal(ProfileRequest{Name: "quota", URI: "https://example.com/credentials",
+				SignatureType: vccrypto.Ed25519Signature2018, Quota: &q})
+			require.NoError(t, err)
+
+			rr := serveHTTP(t, createProfileHandler.Handle(), http.MethodPost, createProfileEndpoint, prBytes)
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+
+			errResp := &model.ErrorResponse{}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &errResp))
+			require.Equal(t, fields, errResp.Fields)
+		}
 	})
 	t.Run("create profile error by passing invalid request", func(t *testing.T) {
 		req, err := http.NewRequest(http.MethodPost, createProfileEndpoint, bytes.NewBuffer([]byte("")))
@@ -506,7 +1006,11 @@ func testCreateProfileHandler(t *testing.T) {
 		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
 		require.NoError(t, err)
 
-		require.Equal(t, invalidRequestErrMsg+": EOF", errResp.Message)
+		require.Equal(t, "INVALID_REQUEST", errResp.Code)
+		require.Equal(t, invalidRequestErrMsg, errResp.Message)
+		require.Equal(t, "EOF", errResp.Details)
+		require.Equal(t, rr.Header().Get(support.CorrelationIDHeader), errResp.CorrelationID)
+		require.NotEmpty(t, errResp.CorrelationID)
 	})
 	t.Run("create profile error unable to write a response while reading the request", func(t *testing.T) {
 		req, err := http.NewRequest(http.MethodPost, createProfileEndpoint, bytes.NewBuffer([]byte("")))
@@ -583,592 +1087,2580 @@ func TestGetProfileHandler(t *testing.T) {
 	})
 }
 
-func createProfileSuccess(t *testing.T, op *Operation) *vcprofile.DataProfile {
-	req, err := http.NewRequest(http.MethodPost, createProfileEndpoint, bytes.NewBuffer([]byte(testIssuerProfile)))
+func TestIssuanceUsageHandler(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
 	require.NoError(t, err)
 
-	rr := httptest.NewRecorder()
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		Crypto:             &cryptomock.Crypto{},
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		VDRI:               &vdrimock.MockVDRIRegistry{},
+		HostURL:            "localhost:8080"})
+	require.NoError(t, err)
 
-	createProfileEndpoint := getHandler(t, op, createProfileEndpoint, http.MethodPost)
-	createProfileEndpoint.Handle().ServeHTTP(rr, req)
+	profile := getTestProfile()
+	profile.Quota = &quota.Quota{Daily: 10, Monthly: 100}
 
-	profile := &vcprofile.DataProfile{}
+	require.NoError(t, op.profileStore.SaveProfile(profile))
 
-	err = json.Unmarshal(rr.Body.Bytes(), &profile)
-	require.NoError(t, err)
+	handler := getHandler(t, op, issuanceUsageEndpoint, http.MethodGet)
 
-	require.Equal(t, http.StatusCreated, rr.Code)
-	require.NotEmpty(t, profile.Name)
+	t.Run("test usage", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			_, err = op.quotas.Reserve(profile.Name, profile.Quota)
+			require.NoError(t, err)
+		}
 
-	return profile
-}
+		rr := serveHTTPMux(t, handler, "/profile/"+profile.Name+"/usage", nil, map[string]string{"id": profile.Name})
+		require.Equal(t, http.StatusOK, rr.Code)
 
-type failingCrypto struct {
-}
+		usage := &quota.Usage{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), usage))
+		require.Equal(t, 3, usage.Daily.Issued)
+		require.Equal(t, 10, usage.Daily.Limit)
+		require.Equal(t, 3, usage.Monthly.Issued)
+		require.Equal(t, 100, usage.Monthly.Limit)
+	})
 
-func (m failingCrypto) Encrypt(msg, aad []byte, kh interface{}) ([]byte, []byte, error) {
-	panic("implement me")
+	t.Run("test profile not found", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, "/profile/notfound/usage", nil, map[string]string{"id": "notfound"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
 }
 
-func (m failingCrypto) Decrypt(cipher, aad, nonce []byte, kh interface{}) ([]byte, error) {
-	panic("implement me")
-}
+func TestStatusListsHandler(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
 
-func (m failingCrypto) Sign(msg []byte, kh interface{}) ([]byte, error) {
-	panic("implement me")
-}
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		Crypto:             &cryptomock.Crypto{},
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		VDRI:               &vdrimock.MockVDRIRegistry{},
+		HostURL:            "localhost:8080",
+		CSLShards:          2})
+	require.NoError(t, err)
 
-func (m failingCrypto) Verify(signature, msg []byte, kh interface{}) error {
-	panic("implement me")
-}
+	profile := getTestProfile()
+	require.NoError(t, op.profileStore.SaveProfile(profile))
 
-func (m failingCrypto) ComputeMAC(data []byte, kh interface{}) ([]byte, error) {
-	return nil, errors.New("i always fail")
-}
+	handler := getHandler(t, op, statusListsEndpoint, http.MethodGet)
 
-func (m failingCrypto) VerifyMAC(_, data []byte, kh interface{}) error {
-	panic("implement me")
-}
+	t.Run("test utilization", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, "/profile/"+profile.Name+"/statusLists", nil,
+			map[string]string{"id": profile.Name})
+		require.Equal(t, http.StatusOK, rr.Code)
 
-type failingJWEEncrypt struct {
-	encryptReturnValue *jose.JSONWebEncryption
-	errEncrypt         error
-}
+		utilization := &cslstatus.Utilization{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), utilization))
+		require.Equal(t, profile.Name, utilization.Profile)
+		require.Len(t, utilization.Lists, 2)
+		require.Equal(t, 2*CSLSize, utilization.Available)
+	})
 
-func (f *failingJWEEncrypt) Encrypt(_, _ []byte) (*jose.JSONWebEncryption, error) {
-	return f.encryptReturnValue, f.errEncrypt
+	t.Run("test profile not found", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, "/profile/notfound/statusLists", nil, map[string]string{"id": "notfound"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("test error from status manager", func(t *testing.T) {
+		op.vcStatusManager = &mockVCStatusManager{utilizationErr: errors.New("get error")}
+
+		rr := serveHTTPMux(t, handler, "/profile/"+profile.Name+"/statusLists", nil,
+			map[string]string{"id": profile.Name})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to get status lists utilization: get error")
+	})
 }
 
-func TestStoreVCHandler(t *testing.T) {
-	t.Run("store vc success", func(t *testing.T) {
-		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+func TestWebDID(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
-		require.NoError(t, err)
+	webDIDDocs, err := web.New(memstore.NewProvider())
+	require.NoError(t, err)
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+	newConfig := func() *Config {
+		return &Config{StoreProvider: memstore.NewProvider(),
 			KMSSecretsProvider: mem.NewProvider(),
 			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
+			EDVClient:          edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
 			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
 			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080"})
+			HostURL:            "https://vcs.example.com",
+			WebDIDDocs:         webDIDDocs}
+	}
+
+	profileReq := getProfileRequest()
+	profileReq.DIDMethod = "web"
+	profileReq.DIDKeyType = vccrypto.Ed25519KeyType
+
+	reqBytes, err := json.Marshal(profileReq)
+	require.NoError(t, err)
+
+	t.Run("test success", func(t *testing.T) {
+		op, err := New(newConfig())
 		require.NoError(t, err)
-		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
-			bytes.NewBuffer([]byte(testStoreCredentialRequest)))
+
+		didID := "did:web:vcs.example.com:issuer:issuer"
+		commonDID := &mockCommonDID{createDIDValue: didID, createDIDKeyID: didID + "#key1"}
+		op.commonDID = commonDID
+
+		rr := serveHTTP(t, getHandler(t, op, createProfileEndpoint, http.MethodPost).Handle(),
+			http.MethodPost, createProfileEndpoint, reqBytes)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		require.Equal(t, []string{"issuer", "issuer"}, commonDID.webDIDPath)
+
+		pubKey, _, err := ed25519.GenerateKey(rand.Reader)
 		require.NoError(t, err)
-		rr := httptest.NewRecorder()
-		op.storeCredentialHandler(rr, req)
+
+		publicKey := did.NewPublicKeyFromBytes(didID+"#key1", vccrypto.Ed25519VerificationKey2018, didID, pubKey)
+		require.NoError(t, webDIDDocs.Store(&did.Doc{Context: []string{did.Context}, ID: didID,
+			PublicKey: []did.PublicKey{*publicKey}}, nil))
+
+		documentHandler := getHandler(t, op, webDIDDocumentEndpoint, http.MethodGet)
+
+		rr = serveHTTPMux(t, documentHandler, "/issuer/issuer/did.json", nil,
+			map[string]string{webDIDPathParam: "issuer/issuer"})
 		require.Equal(t, http.StatusOK, rr.Code)
-	})
-	t.Run("store vc err while creating the document - vault not found", func(t *testing.T) {
-		client := NewMockEDVClient("test")
+		require.Equal(t, "application/did+json", rr.Header().Get("Content-Type"))
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		doc, err := did.ParseDocument(rr.Body.Bytes())
 		require.NoError(t, err)
+		require.Equal(t, didID+"#key1", doc.PublicKey[0].ID)
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080"})
-		require.NoError(t, err)
-		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
-			bytes.NewBuffer([]byte(testStoreCredentialRequest)))
-		require.NoError(t, err)
-		rr := httptest.NewRecorder()
-		op.storeCredentialHandler(rr, req)
-		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		rr = serveHTTPMux(t, documentHandler, "/issuer/unknown/did.json", nil,
+			map[string]string{webDIDPathParam: "issuer/unknown"})
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), "did document of did:web:vcs.example.com:issuer:unknown not found")
 
-		errResp := &model.ErrorResponse{}
-		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		profileReq := getProfileRequest()
+		profileReq.Name = "issuer2"
+		profileReq.DIDMethod = "web"
+		profileReq.DIDKeyType = vccrypto.Ed25519KeyType
+		profileReq.DIDWebPath = "issuer/issuer"
+
+		reqBytes, err := json.Marshal(profileReq)
 		require.NoError(t, err)
 
-		require.Equal(t, errResp.Message, errVaultNotFound.Error())
+		rr = serveHTTP(t, getHandler(t, op, createProfileEndpoint, http.MethodPost).Handle(),
+			http.MethodPost, createProfileEndpoint, reqBytes)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "did document of "+didID+" already exists")
 	})
-	t.Run("store vc err missing profile name", func(t *testing.T) {
-		client := NewMockEDVClient("test")
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	t.Run("test success - well-known did document", func(t *testing.T) {
+		op, err := New(newConfig())
 		require.NoError(t, err)
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080"})
+		didID := "did:web:vcs.example.com"
+		commonDID := &mockCommonDID{createDIDValue: didID, createDIDKeyID: didID + "#key1"}
+		op.commonDID = commonDID
+
+		profileReq := getProfileRequest()
+		profileReq.Name = "host-issuer"
+		profileReq.DIDMethod = "web"
+		profileReq.DIDKeyType = vccrypto.Ed25519KeyType
+		profileReq.DIDWebPath = "/"
+
+		reqBytes, err := json.Marshal(profileReq)
 		require.NoError(t, err)
-		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
-			bytes.NewBuffer([]byte(testStoreIncorrectCredentialRequest)))
+
+		rr := serveHTTP(t, getHandler(t, op, createProfileEndpoint, http.MethodPost).Handle(),
+			http.MethodPost, createProfileEndpoint, reqBytes)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		require.Empty(t, commonDID.webDIDPath)
+
+		require.NoError(t, webDIDDocs.Store(&did.Doc{Context: []string{did.Context}, ID: didID}, nil))
+
+		rr = serveHTTP(t, getHandler(t, op, wellKnownDIDEndpoint, http.MethodGet).Handle(),
+			http.MethodGet, wellKnownDIDEndpoint, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		doc, err := did.ParseDocument(rr.Body.Bytes())
 		require.NoError(t, err)
-		rr := httptest.NewRecorder()
-		op.storeCredentialHandler(rr, req)
-		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Equal(t, didID, doc.ID)
+	})
 
-		errResp := &model.ErrorResponse{}
-		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+	t.Run("test error - did:web not supported", func(t *testing.T) {
+		config := newConfig()
+		config.WebDIDDocs = nil
+
+		op, err := New(config)
 		require.NoError(t, err)
 
-		require.Equal(t, errResp.Message, "missing profile name")
+		op.commonDID = &mockCommonDID{}
+
+		rr := serveHTTP(t, getHandler(t, op, createProfileEndpoint, http.MethodPost).Handle(),
+			http.MethodPost, createProfileEndpoint, reqBytes)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "did:web not supported")
+
+		rr = serveHTTPMux(t, getHandler(t, op, webDIDDocumentEndpoint, http.MethodGet),
+			"/issuer/issuer/did.json", nil, map[string]string{webDIDPathParam: "issuer/issuer"})
+		require.Equal(t, http.StatusNotFound, rr.Code)
 	})
-	t.Run("store vc err unable to unmarshal vc", func(t *testing.T) {
-		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	t.Run("test did:key", func(t *testing.T) {
+		op, err := New(newConfig())
 		require.NoError(t, err)
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080"})
+		op.commonDID = &mockCommonDID{createDIDValue: "did:key:z6Mk", createDIDKeyID: "did:key:z6Mk#z6Mk"}
+
+		profileReq := getProfileRequest()
+		profileReq.Name = "key-issuer"
+		profileReq.DIDMethod = "key"
+		profileReq.DIDKeyType = vccrypto.Ed25519KeyType
+
+		reqBytes, err := json.Marshal(profileReq)
 		require.NoError(t, err)
-		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
-			bytes.NewBuffer([]byte(testStoreCredentialRequestBadVC)))
+
+		rr := serveHTTP(t, getHandler(t, op, createProfileEndpoint, http.MethodPost).Handle(),
+			http.MethodPost, createProfileEndpoint, reqBytes)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		profile := &vcprofile.DataProfile{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), profile))
+		require.Equal(t, "did:key:z6Mk", profile.DID)
+		require.Equal(t, "did:key:z6Mk#z6Mk", profile.Creator)
+	})
+}
+
+func TestRotateKeyHandler(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		Crypto:             &cryptomock.Crypto{},
+		EDVClient:          edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		VDRI:               &vdrimock.MockVDRIRegistry{},
+		HostURL:            "localhost:8080"})
+	require.NoError(t, err)
+
+	err = op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "issuer", DID: "did:test:123",
+		Creator: "did:test:123#key1", SignatureType: vccrypto.Ed25519Signature2018})
+	require.NoError(t, err)
+
+	rotateKeyHandler := getHandler(t, op, rotateKeyEndpoint, http.MethodPost)
+
+	rotateKey := func(profileID, body string) *httptest.ResponseRecorder {
+		return serveHTTPMux(t, rotateKeyHandler, "/profile/"+profileID+"/rotateKey", []byte(body),
+			map[string]string{"id": profileID})
+	}
+
+	t.Run("rotate key success", func(t *testing.T) {
+		op.commonDID = &mockCommonDID{addKeyValue: "did:test:123#key2"}
+
+		rr := rotateKey("issuer", `{"didKeyType":"P256","signatureType":"JsonWebSignature2020",
+			"uniRegistrar":{"driverURL":"https://registrar/update"}}`)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		profile := &vcprofile.DataProfile{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), profile))
+		require.Equal(t, "did:test:123#key2", profile.Creator)
+		require.Equal(t, vccrypto.JSONWebSignature2020, profile.SignatureType)
+		require.Equal(t, []string{"did:test:123#key1"}, profile.PreviousCreators)
+
+		stored, err := op.profileStore.GetProfile("issuer")
 		require.NoError(t, err)
-		rr := httptest.NewRecorder()
-		op.storeCredentialHandler(rr, req)
+		require.Equal(t, profile, stored)
+	})
+
+	t.Run("rotate key success - default key type", func(t *testing.T) {
+		commonDID := &mockCommonDID{addKeyValue: "did:test:123#key3"}
+		op.commonDID = commonDID
+
+		rr := rotateKey("issuer", `{"uniRegistrar":{"driverURL":"https://registrar/update"}}`)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, vccrypto.Ed25519KeyType, commonDID.addKeyType)
+	})
+
+	t.Run("rotate key error - unsupported key type", func(t *testing.T) {
+		rr := rotateKey("issuer", `{"didKeyType":"P256","signatureType":"Ed25519Signature2018"}`)
 		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(),
+			"no key found to match key type:P256 and signature type:Ed25519Signature2018")
 
-		errResp := &model.ErrorResponse{}
-		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
-		require.NoError(t, err)
+		rr = rotateKey("issuer", `{"didKeyType":"X25519"}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "no key found to match key type:X25519")
+	})
 
-		require.Equal(t, "unable to unmarshal the VC: decode new credential: "+
-			"embedded proof is not JSON: unexpected end of JSON input", errResp.Message)
+	t.Run("rotate key error - invalid request", func(t *testing.T) {
+		rr := rotateKey("issuer", "invalid")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), invalidRequestErrMsg)
 	})
-	t.Run("store vc err while computing MAC", func(t *testing.T) {
-		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
-		require.NoError(t, err)
+	t.Run("rotate key error - missing did key id", func(t *testing.T) {
+		rr := rotateKey("issuer", `{"didPrivateKey":"key"}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "missing did key id")
+	})
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080"})
+	t.Run("rotate key error - profile not found", func(t *testing.T) {
+		rr := rotateKey("unknown", `{}`)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid issuer profile")
+	})
 
-		op.macCrypto = failingCrypto{}
-		require.NoError(t, err)
-		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
-			bytes.NewBuffer([]byte(testStoreCredentialRequest)))
-		require.NoError(t, err)
-		rr := httptest.NewRecorder()
-		op.storeCredentialHandler(rr, req)
+	t.Run("rotate key error - rotate key failed", func(t *testing.T) {
+		op.commonDID = &mockCommonDID{addKeyErr: errors.New("registrar error")}
 
-		errResp := &model.ErrorResponse{}
-		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
-		require.NoError(t, err)
-		require.Equal(t, "i always fail", errResp.Message)
+		rr := rotateKey("issuer", `{"didKeyType":"Ed25519"}`)
 		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to add key: registrar error")
 	})
-	t.Run("store vc err while encrypting structured doc", func(t *testing.T) {
-		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+}
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
-		require.NoError(t, err)
+func TestAddKeyHandler(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080"})
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		Crypto:             &cryptomock.Crypto{},
+		EDVClient:          edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		VDRI:               &vdrimock.MockVDRIRegistry{},
+		HostURL:            "localhost:8080"})
+	require.NoError(t, err)
 
-		testError := errors.New("test encryption failure")
+	err = op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "issuer", DID: "did:test:123",
+		Creator: "did:test:123#key1", SignatureType: vccrypto.Ed25519Signature2018})
+	require.NoError(t, err)
 
-		op.jweEncrypter = &failingJWEEncrypt{errEncrypt: testError}
+	addKeyHandler := getHandler(t, op, addKeyEndpoint, http.MethodPost)
 
-		require.NoError(t, err)
-		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
-			bytes.NewBuffer([]byte(testStoreCredentialRequest)))
-		require.NoError(t, err)
-		rr := httptest.NewRecorder()
-		op.storeCredentialHandler(rr, req)
+	t.Run("add key success", func(t *testing.T) {
+		op.commonDID = &mockCommonDID{addKeyValue: "did:test:123#key2"}
 
-		errResp := &model.ErrorResponse{}
-		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		rr := serveHTTPMux(t, addKeyHandler, "/profile/issuer/keys",
+			[]byte(`{"didKeyType":"P256","signatureType":"JsonWebSignature2020",
+				"uniRegistrar":{"driverURL":"https://registrar/update"}}`), map[string]string{"id": "issuer"})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		profile := &vcprofile.DataProfile{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), profile))
+		require.Equal(t, "did:test:123#key1", profile.Creator)
+		require.Equal(t, vccrypto.Ed25519Signature2018, profile.SignatureType)
+		require.Equal(t, []vcprofile.SigningKey{{ID: "did:test:123#key2",
+			SignatureType: vccrypto.JSONWebSignature2020}}, profile.SigningKeys)
+
+		stored, err := op.profileStore.GetProfile("issuer")
 		require.NoError(t, err)
-		require.Equal(t, testError.Error(), errResp.Message)
-		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Equal(t, profile, stored)
 	})
-	t.Run("store vc err while serializing JWE", func(t *testing.T) {
-		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
-		require.NoError(t, err)
+	t.Run("add key error - unsupported key type", func(t *testing.T) {
+		rr := serveHTTPMux(t, addKeyHandler, "/profile/issuer/keys", []byte(`{"didKeyType":"P256"}`),
+			map[string]string{"id": "issuer"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(),
+			"no key found to match key type:P256 and signature type:Ed25519Signature2018")
+	})
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080"})
+	t.Run("add key error", func(t *testing.T) {
+		op.commonDID = &mockCommonDID{addKeyErr: errors.New("registrar error")}
 
-		op.jweEncrypter = &failingJWEEncrypt{encryptReturnValue: &jose.JSONWebEncryption{}}
+		rr := serveHTTPMux(t, addKeyHandler, "/profile/issuer/keys", []byte(`{}`),
+			map[string]string{"id": "issuer"})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to add key: registrar error")
+	})
+}
 
+func TestUpdateDIDHandler(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		Crypto:             &cryptomock.Crypto{},
+		EDVClient:          edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		VDRI:               &vdrimock.MockVDRIRegistry{},
+		HostURL:            "localhost:8080"})
+	require.NoError(t, err)
+
+	err = op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "issuer", DID: "did:test:123",
+		Creator: "did:test:123#key1", SignatureType: vccrypto.Ed25519Signature2018,
+		SigningKeys: []vcprofile.SigningKey{{ID: "did:test:123#key2"}}})
+	require.NoError(t, err)
+
+	updateDIDHandler := getHandler(t, op, updateDIDEndpoint, http.MethodPatch)
+
+	t.Run("update did success", func(t *testing.T) {
+		op.commonDID = &mockCommonDID{updateDIDValue: []string{"did:test:123#key3"}}
+
+		rr := serveHTTPMux(t, updateDIDHandler, "/profile/issuer/did",
+			[]byte(`{"addServices":[{"id":"hub","type":"DIDCommMessaging","serviceEndpoint":"https://hub.example.com"}],
+				"addVerificationMethods":[{"id":"key3","type":"Ed25519VerificationKey2018","keyType":"Ed25519",
+				"publicKeyBase58":"GUXiqNHCdirb6NKpH6wYG4px3YfMjiCh6dQhU3zxQVQ7"}],"removeServices":["old"],
+				"uniRegistrar":{"driverURL":"https://registrar/update"}}`), map[string]string{"id": "issuer"})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		resp := &DIDUpdateResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		require.Equal(t, []string{"did:test:123#key3"}, resp.VerificationMethods)
+	})
+
+	t.Run("update did error - invalid request", func(t *testing.T) {
+		rr := serveHTTPMux(t, updateDIDHandler, "/profile/issuer/did", []byte(`{"addServices":[{}]}`),
+			map[string]string{"id": "issuer"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "/addServices/0/serviceEndpoint")
+
+		rr = serveHTTPMux(t, updateDIDHandler, "/profile/issuer/did", []byte(`[]`),
+			map[string]string{"id": "issuer"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("update did error - profile not found", func(t *testing.T) {
+		rr := serveHTTPMux(t, updateDIDHandler, "/profile/unknown/did", []byte(`{"removeServices":["old"]}`),
+			map[string]string{"id": "unknown"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid issuer profile")
+	})
+
+	t.Run("update did error - signing key removed", func(t *testing.T) {
+		for _, id := range []string{"key1", "did:test:123#key2"} {
+			rr := serveHTTPMux(t, updateDIDHandler, "/profile/issuer/did",
+				[]byte(`{"removeVerificationMethods":["`+id+`"]}`), map[string]string{"id": "issuer"})
+			require.Equal(t, http.StatusConflict, rr.Code)
+			require.Contains(t, rr.Body.String(), "is a signing key of the profile")
+		}
+	})
+
+	t.Run("update did error", func(t *testing.T) {
+		op.commonDID = &mockCommonDID{updateDIDErr: errors.New("registrar error")}
+
+		rr := serveHTTPMux(t, updateDIDHandler, "/profile/issuer/did", []byte(`{"removeServices":["old"]}`),
+			map[string]string{"id": "issuer"})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to update did: registrar error")
+	})
+}
+
+func TestExportImportProfile(t *testing.T) {
+	kek := base64.URLEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+	sourceKMS := newTestLocalKMS(t)
+	targetKMS := newTestLocalKMS(t)
+
+	keyID1, _, err := sourceKMS.Create(kms.ED25519Type)
+	require.NoError(t, err)
+
+	keyID2, _, err := sourceKMS.Create(kms.ECDSAP256TypeIEEEP1363)
+	require.NoError(t, err)
+
+	source := newTestOperation(t, sourceKMS)
+	target := newTestOperation(t, targetKMS)
+
+	profile := &vcprofile.DataProfile{Name: "issuer", DID: "did:test:123",
+		Creator: "did:test:123#" + keyID1, SignatureType: vccrypto.Ed25519Signature2018,
+		PreviousCreators: []string{"did:test:123#" + keyID1},
+		SigningKeys:      []vcprofile.SigningKey{{ID: "did:test:123#" + keyID2, SignatureType: "JsonWebSignature2020"}}}
+	require.NoError(t, source.profileStore.SaveProfile(profile))
+
+	exportHandler := getHandler(t, source, exportProfileEndpoint, http.MethodPost)
+	importHandler := getHandler(t, target, importProfileEndpoint, http.MethodPost)
+
+	t.Run("export and import profile - success", func(t *testing.T) {
+		rr := serveHTTPMux(t, exportHandler, "/profile/issuer/export", []byte(`{"kek":"`+kek+`"}`),
+			map[string]string{"id": "issuer"})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		export := &ProfileExport{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), export))
+		require.Equal(t, profile, export.Profile)
+		require.Len(t, export.Keys, 2)
+
+		reqBytes, err := json.Marshal(&ImportProfileRequest{KEK: kek, ProfileExport: *export})
+		require.NoError(t, err)
+
+		rr = serveHTTP(t, importHandler.Handle(), http.MethodPost, importProfileEndpoint, reqBytes)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		imported, err := target.profileStore.GetProfile("issuer")
+		require.NoError(t, err)
+		require.Equal(t, profile, imported)
+
+		for _, keyID := range []string{keyID1, keyID2} {
+			sourcePubKey, err := sourceKMS.ExportPubKeyBytes(keyID)
+			require.NoError(t, err)
+
+			targetPubKey, err := targetKMS.ExportPubKeyBytes(keyID)
+			require.NoError(t, err)
+			require.Equal(t, sourcePubKey, targetPubKey)
+		}
+
+		rr = serveHTTP(t, importHandler.Handle(), http.MethodPost, importProfileEndpoint, reqBytes)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "profile issuer already exists")
+	})
+
+	t.Run("export profile - errors", func(t *testing.T) {
+		rr := serveHTTPMux(t, exportHandler, "/profile/issuer/export", []byte("invalid-json"),
+			map[string]string{"id": "issuer"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), invalidRequestErrMsg)
+
+		rr = serveHTTPMux(t, exportHandler, "/profile/issuer/export", []byte(`{"kek":"!"}`),
+			map[string]string{"id": "issuer"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid kek")
+
+		rr = serveHTTPMux(t, exportHandler, "/profile/other/export", []byte(`{"kek":"`+kek+`"}`),
+			map[string]string{"id": "other"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid issuer profile")
+
+		require.NoError(t, source.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "invalid",
+			Creator: "did:test:123"}))
+
+		rr = serveHTTPMux(t, exportHandler, "/profile/invalid/export", []byte(`{"kek":"`+kek+`"}`),
+			map[string]string{"id": "invalid"})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "verificationMethod value did:test:123 should be in did#keyID format")
+
+		require.NoError(t, source.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "missing",
+			Creator: "did:test:123#missing"}))
+
+		rr = serveHTTPMux(t, exportHandler, "/profile/missing/export", []byte(`{"kek":"`+kek+`"}`),
+			map[string]string{"id": "missing"})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to export key")
+	})
+
+	t.Run("import profile - errors", func(t *testing.T) {
+		rr := serveHTTP(t, importHandler.Handle(), http.MethodPost, importProfileEndpoint, []byte("invalid-json"))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), invalidRequestErrMsg)
+
+		rr = serveHTTP(t, importHandler.Handle(), http.MethodPost, importProfileEndpoint, []byte(`{}`))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "missing profile")
+
+		rr = serveHTTP(t, importHandler.Handle(), http.MethodPost, importProfileEndpoint,
+			[]byte(`{"kek":"!","profile":{"name":"issuer2"}}`))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid kek")
+
+		rr = serveHTTP(t, importHandler.Handle(), http.MethodPost, importProfileEndpoint,
+			[]byte(`{"kek":"`+kek+`","profile":{"name":"issuer2"},"keys":[{"keyID":"k1","wrappedKey":"e30="}]}`))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to import key")
+	})
+
+	t.Run("import profile - vault", func(t *testing.T) {
+		reqBytes := []byte(`{"kek":"` + kek + `","profile":{"name":"issuer3"}}`)
+
+		target.edvClient = &createVaultEDVClient{Client: edv.NewMockEDVClient("test", nil, nil, nil),
+			err: errors.New("failed to create vault: connection refused")}
+
+		rr := serveHTTP(t, importHandler.Handle(), http.MethodPost, importProfileEndpoint, reqBytes)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "connection refused")
+
+		// the profile is only saved once its vault exists, so the import can be retried
+		_, err := target.profileStore.GetProfile("issuer3")
+		require.Error(t, err)
+
+		target.edvClient = &createVaultEDVClient{Client: edv.NewMockEDVClient("test", nil, nil, nil),
+			err: fmt.Errorf("failed to create vault: %s", messages.ErrDuplicateVault.Error())}
+
+		rr = serveHTTP(t, importHandler.Handle(), http.MethodPost, importProfileEndpoint, reqBytes)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		_, err = target.profileStore.GetProfile("issuer3")
+		require.NoError(t, err)
+	})
+}
+
+func TestExportImportProfileSigningKMSKeys(t *testing.T) {
+	kek := base64.URLEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+	sourceKMS := newTestSigningKMS(t)
+	targetKMS := newTestSigningKMS(t)
+
+	keyID1, _, err := sourceKMS.Create(signingkms.Secp256k1KeyType)
+	require.NoError(t, err)
+
+	keyID2, _, err := sourceKMS.Create(signingkms.RSAKeyType)
+	require.NoError(t, err)
+
+	source := newTestOperation(t, sourceKMS)
+	target := newTestOperation(t, targetKMS)
+
+	profile := &vcprofile.DataProfile{Name: "issuer", DID: "did:test:123",
+		Creator: "did:test:123#" + keyID1, SignatureType: vccrypto.EcdsaSecp256k1Signature2019,
+		SigningKeys: []vcprofile.SigningKey{{ID: "did:test:123#" + keyID2, SignatureType: "JsonWebSignature2020"}}}
+	require.NoError(t, source.profileStore.SaveProfile(profile))
+
+	rr := serveHTTPMux(t, getHandler(t, source, exportProfileEndpoint, http.MethodPost), "/profile/issuer/export",
+		[]byte(`{"kek":"`+kek+`"}`), map[string]string{"id": "issuer"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	export := &ProfileExport{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), export))
+	require.Len(t, export.Keys, 2)
+
+	reqBytes, err := json.Marshal(&ImportProfileRequest{KEK: kek, ProfileExport: *export})
+	require.NoError(t, err)
+
+	rr = serveHTTP(t, getHandler(t, target, importProfileEndpoint, http.MethodPost).Handle(), http.MethodPost,
+		importProfileEndpoint, reqBytes)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	for _, keyID := range []string{keyID1, keyID2} {
+		sourcePubKey, err := sourceKMS.ExportPubKeyBytes(keyID)
+		require.NoError(t, err)
+
+		targetPubKey, err := targetKMS.ExportPubKeyBytes(keyID)
+		require.NoError(t, err)
+		require.Equal(t, sourcePubKey, targetPubKey)
+	}
+}
+
+func newTestOperation(t *testing.T, keyManager keyManager) *Operation {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		Crypto:             &cryptomock.Crypto{},
+		EDVClient:          edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		VDRI:               &vdrimock.MockVDRIRegistry{},
+		HostURL:            "localhost:8080"})
+	require.NoError(t, err)
+
+	op.kms = keyManager
+
+	return op
+}
+
+type testKMSProvider struct {
+	storageProvider ariesstorage.Provider
+}
+
+func (k testKMSProvider) StorageProvider() ariesstorage.Provider {
+	return k.storageProvider
+}
+
+func (k testKMSProvider) SecretLock() secretlock.Service {
+	return &noop.NoLock{}
+}
+
+func newTestLocalKMS(t *testing.T) *localkms.LocalKMS {
+	km, err := localkms.New("local-lock://test/key-uri/", testKMSProvider{storageProvider: mem.NewProvider()})
+	require.NoError(t, err)
+
+	return km
+}
+
+func newTestSigningKMS(t *testing.T) *signingkms.KeyManager {
+	provider := mem.NewProvider()
+
+	localKMS, err := localkms.New("local-lock://test/key-uri/", testKMSProvider{storageProvider: provider})
+	require.NoError(t, err)
+
+	km, err := signingkms.New(localKMS, provider)
+	require.NoError(t, err)
+
+	return km
+}
+
+func TestSelectSigningKey(t *testing.T) {
+	profile := &vcprofile.DataProfile{Name: "issuer", DID: "did:test:123", Creator: "did:test:123#key1",
+		SignatureType: vccrypto.Ed25519Signature2018,
+		SigningKeys:   []vcprofile.SigningKey{{ID: "did:test:123#key2", SignatureType: vccrypto.JSONWebSignature2020}}}
+
+	t.Run("test profile without signing keys", func(t *testing.T) {
+		p := &vcprofile.DataProfile{DID: "did:test:123", Creator: "did:test:123#key1"}
+
+		signingProfile, err := selectSigningKey(p, &IssueCredentialOptions{VerificationMethod: "did:test:456#key1"})
+		require.NoError(t, err)
+		require.Equal(t, p, signingProfile)
+	})
+
+	t.Run("test profile creator", func(t *testing.T) {
+		signingProfile, err := selectSigningKey(profile, nil)
+		require.NoError(t, err)
+		require.Equal(t, profile, signingProfile)
+
+		signingProfile, err = selectSigningKey(profile, &IssueCredentialOptions{AssertionMethod: profile.Creator})
+		require.NoError(t, err)
+		require.Equal(t, profile, signingProfile)
+	})
+
+	t.Run("test signing key", func(t *testing.T) {
+		signingProfile, err := selectSigningKey(profile,
+			&IssueCredentialOptions{VerificationMethod: "did:test:123#key2"})
+		require.NoError(t, err)
+		require.Equal(t, "did:test:123#key2", signingProfile.Creator)
+		require.Equal(t, vccrypto.JSONWebSignature2020, signingProfile.SignatureType)
+
+		// stored profile is not changed
+		require.Equal(t, "did:test:123#key1", profile.Creator)
+	})
+
+	t.Run("test error - verification method of another did", func(t *testing.T) {
+		signingProfile, err := selectSigningKey(profile,
+			&IssueCredentialOptions{VerificationMethod: "did:test:456#key2"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not belong to profile did did:test:123")
+		require.Nil(t, signingProfile)
+	})
+
+	t.Run("test error - key not registered", func(t *testing.T) {
+		signingProfile, err := selectSigningKey(profile,
+			&IssueCredentialOptions{VerificationMethod: "did:test:123#key3"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is not a signing key of the profile")
+		require.Nil(t, signingProfile)
+	})
+
+	t.Run("test error - invalid verification method", func(t *testing.T) {
+		signingProfile, err := selectSigningKey(profile,
+			&IssueCredentialOptions{VerificationMethod: "did:test:123"})
+		require.Error(t, err)
+		require.Nil(t, signingProfile)
+	})
+}
+
+func TestSelectProofSigningKeys(t *testing.T) {
+	profile := &vcprofile.DataProfile{Name: "issuer", DID: "did:test:123", Creator: "did:test:123#key1",
+		SignatureType: vccrypto.Ed25519Signature2018,
+		SigningKeys:   []vcprofile.SigningKey{{ID: "did:test:123#key2", SignatureType: vccrypto.JSONWebSignature2020}}}
+
+	t.Run("test no additional proofs", func(t *testing.T) {
+		proofProfiles, err := selectProofSigningKeys(profile, nil)
+		require.NoError(t, err)
+		require.Empty(t, proofProfiles)
+
+		proofProfiles, err = selectProofSigningKeys(profile, &IssueCredentialOptions{})
+		require.NoError(t, err)
+		require.Empty(t, proofProfiles)
+	})
+
+	t.Run("test additional proofs", func(t *testing.T) {
+		proofProfiles, err := selectProofSigningKeys(profile, &IssueCredentialOptions{Proofs: []ProofOptions{
+			{SignatureType: vccrypto.JSONWebSignature2020},
+			{VerificationMethod: "did:test:123#key2"},
+			{VerificationMethod: "did:test:123#key2", SignatureType: vccrypto.Ed25519Signature2018},
+		}})
+		require.NoError(t, err)
+		require.Len(t, proofProfiles, 3)
+
+		require.Equal(t, "did:test:123#key1", proofProfiles[0].Creator)
+		require.Equal(t, vccrypto.JSONWebSignature2020, proofProfiles[0].SignatureType)
+		require.Equal(t, "did:test:123#key2", proofProfiles[1].Creator)
+		require.Equal(t, vccrypto.JSONWebSignature2020, proofProfiles[1].SignatureType)
+		require.Equal(t, "did:test:123#key2", proofProfiles[2].Creator)
+		require.Equal(t, vccrypto.Ed25519Signature2018, proofProfiles[2].SignatureType)
+
+		// stored profile is not changed
+		require.Equal(t, vccrypto.Ed25519Signature2018, profile.SignatureType)
+	})
+
+	t.Run("test error - key not registered", func(t *testing.T) {
+		proofProfiles, err := selectProofSigningKeys(profile, &IssueCredentialOptions{Proofs: []ProofOptions{
+			{VerificationMethod: "did:test:123#key3"},
+		}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "proof 0: verification method did:test:123#key3 is not a signing key")
+		require.Nil(t, proofProfiles)
+	})
+}
+
+func createProfileSuccess(t *testing.T, op *Operation) *vcprofile.DataProfile {
+	req, err := http.NewRequest(http.MethodPost, createProfileEndpoint, bytes.NewBuffer([]byte(testIssuerProfile)))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+
+	createProfileEndpoint := getHandler(t, op, createProfileEndpoint, http.MethodPost)
+	createProfileEndpoint.Handle().ServeHTTP(rr, req)
+
+	profile := &vcprofile.DataProfile{}
+
+	err = json.Unmarshal(rr.Body.Bytes(), &profile)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	require.NotEmpty(t, profile.Name)
+
+	return profile
+}
+
+type failingCrypto struct {
+}
+
+func (m failingCrypto) Encrypt(msg, aad []byte, kh interface{}) ([]byte, []byte, error) {
+	panic("implement me")
+}
+
+func (m failingCrypto) Decrypt(cipher, aad, nonce []byte, kh interface{}) ([]byte, error) {
+	panic("implement me")
+}
+
+func (m failingCrypto) Sign(msg []byte, kh interface{}) ([]byte, error) {
+	panic("implement me")
+}
+
+func (m failingCrypto) Verify(signature, msg []byte, kh interface{}) error {
+	panic("implement me")
+}
+
+func (m failingCrypto) ComputeMAC(data []byte, kh interface{}) ([]byte, error) {
+	return nil, errors.New("i always fail")
+}
+
+func (m failingCrypto) VerifyMAC(_, data []byte, kh interface{}) error {
+	panic("implement me")
+}
+
+type failingJWECrypto struct {
+	encryptReturnValue *jose.JSONWebEncryption
+	errEncrypt         error
+	errRotate          error
+}
+
+func (f *failingJWECrypto) Encrypt(_, _ []byte) (*jose.JSONWebEncryption, error) {
+	return f.encryptReturnValue, f.errEncrypt
+}
+
+func (f *failingJWECrypto) Decrypt(*jose.JSONWebEncryption) ([]byte, error) {
+	return nil, errors.New("decrypt not supported")
+}
+
+func (f *failingJWECrypto) KeyID() string {
+	return ""
+}
+
+func (f *failingJWECrypto) RotateKey() (string, error) {
+	return "", f.errRotate
+}
+
+// profileJWECrypto returns the JWE crypto for the keys of all the profiles
+func profileJWECrypto(c jweCrypto) func(string) (jweCrypto, error) {
+	return func(string) (jweCrypto, error) {
+		return c, nil
+	}
+}
+
+func TestStoreVCHandler(t *testing.T) {
+	t.Run("store vc success", func(t *testing.T) {
+		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080"})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
+			bytes.NewBuffer([]byte(testStoreCredentialRequest)))
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		op.storeCredentialHandler(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+	t.Run("store vc batch", func(t *testing.T) {
+		storeReq := &StoreVCRequest{}
+		require.NoError(t, json.Unmarshal([]byte(testStoreCredentialRequest), storeReq))
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		newOperation := func(client EDVClient) *Operation {
+			op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+				KMSSecretsProvider: mem.NewProvider(),
+				Crypto:             &cryptomock.Crypto{},
+				EDVClient:          client,
+				KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+				VDRI:               &vdrimock.MockVDRIRegistry{},
+				HostURL:            "localhost:8080"})
+			require.NoError(t, err)
+
+			return op
+		}
+
+		storeVCs := func(op *Operation, credentials []string) *httptest.ResponseRecorder {
+			reqBytes, err := json.Marshal(&StoreVCRequest{Profile: storeReq.Profile, Credentials: credentials})
+			require.NoError(t, err)
+
+			req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint, bytes.NewBuffer(reqBytes))
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			op.storeCredentialHandler(rr, req)
+
+			return rr
+		}
+
+		rr := storeVCs(newOperation(edv.NewMockEDVClient("test", nil, nil, nil)),
+			[]string{storeReq.Credential, storeReq.Credential})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = storeVCs(newOperation(NewMockEDVClient("test")), []string{storeReq.Credential})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), errVaultNotFound.Error())
+
+		rr = storeVCs(newOperation(edv.NewMockEDVClient("test", nil, nil, nil)),
+			[]string{storeReq.Credential, "{"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "unable to unmarshal the VC at index 1")
+
+		op := newOperation(edv.NewMockEDVClient("test", nil, nil, nil))
+		op.profileJWECrypto = profileJWECrypto(&failingJWECrypto{errEncrypt: errors.New("test encryption failure")})
+
+		rr = storeVCs(op, []string{storeReq.Credential})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "test encryption failure")
+	})
+	t.Run("store vc err while creating the document - vault not found", func(t *testing.T) {
+		client := NewMockEDVClient("test")
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080"})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
+			bytes.NewBuffer([]byte(testStoreCredentialRequest)))
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		op.storeCredentialHandler(rr, req)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+		errResp := &model.ErrorResponse{}
+		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		require.NoError(t, err)
+
+		require.Equal(t, errResp.Message, errVaultNotFound.Error())
+	})
+	t.Run("store vc err missing profile name", func(t *testing.T) {
+		client := NewMockEDVClient("test")
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080"})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
+			bytes.NewBuffer([]byte(testStoreIncorrectCredentialRequest)))
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		op.storeCredentialHandler(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		errResp := &model.ErrorResponse{}
+		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		require.NoError(t, err)
+
+		require.Equal(t, errResp.Message, "missing profile name")
+	})
+	t.Run("store vc err unable to unmarshal vc", func(t *testing.T) {
+		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080"})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
+			bytes.NewBuffer([]byte(testStoreCredentialRequestBadVC)))
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		op.storeCredentialHandler(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		errResp := &model.ErrorResponse{}
+		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		require.NoError(t, err)
+
+		require.Equal(t, "unable to unmarshal the VC: decode new credential: "+
+			"embedded proof is not JSON: unexpected end of JSON input", errResp.Message)
+	})
+	t.Run("store vc err while computing MAC", func(t *testing.T) {
+		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080"})
+
+		op.macCrypto = failingCrypto{}
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
+			bytes.NewBuffer([]byte(testStoreCredentialRequest)))
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		op.storeCredentialHandler(rr, req)
+
+		errResp := &model.ErrorResponse{}
+		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		require.NoError(t, err)
+		require.Equal(t, "i always fail", errResp.Message)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+	t.Run("store vc err while encrypting structured doc", func(t *testing.T) {
+		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080"})
+
+		testError := errors.New("test encryption failure")
+
+		op.profileJWECrypto = profileJWECrypto(&failingJWECrypto{errEncrypt: testError})
+
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
+			bytes.NewBuffer([]byte(testStoreCredentialRequest)))
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		op.storeCredentialHandler(rr, req)
+
+		errResp := &model.ErrorResponse{}
+		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		require.NoError(t, err)
+		require.Equal(t, testError.Error(), errResp.Message)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+	t.Run("store vc err while serializing JWE", func(t *testing.T) {
+		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080"})
+
+		op.profileJWECrypto = profileJWECrypto(&failingJWECrypto{encryptReturnValue: &jose.JSONWebEncryption{}})
+
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
+			bytes.NewBuffer([]byte(testStoreCredentialRequest)))
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		op.storeCredentialHandler(rr, req)
+
+		errResp := &model.ErrorResponse{}
+		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		require.NoError(t, err)
+		require.Equal(t, "ciphertext cannot be empty", errResp.Message)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestRetrieveVCHandler(t *testing.T) {
+	t.Run("retrieve vc success", func(t *testing.T) {
+		// The mock client needs to be passed into operation.New, but we need the packer and key from the
+		// operation object in order to create a decryptable EncryptedDocument to be returned from the mock EDV client.
+		// It's set to nil here but later in this test it gets set to a valid object.
+		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080",
+			RetryParameters:    &retry.Params{}})
+		require.NoError(t, err)
+
+		setMockEDVClientReadDocumentReturnValue(t, client, op, testStructuredDocument1)
+
+		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
+			bytes.NewBuffer([]byte(nil)))
+		require.NoError(t, err)
+
+		q := r.URL.Query()
+		q.Add("id", testURLQueryID)
+		q.Add("profile", getTestProfile().Name)
+		r.URL.RawQuery = q.Encode()
+		rr := httptest.NewRecorder()
+
+		op.retrieveCredentialHandler(rr, r)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, testStructuredDocMessage1, rr.Body.String())
+	})
+	t.Run("retrieve vc success - multiple VCs "+
+		"found under the same ID but they have identical contents", func(t *testing.T) {
+		// The mock client needs to be passed into operation.New, but we need the packer and key from the
+		// operation object in order to create a decryptable EncryptedDocument to be returned from the mock EDV client.
+		// It's set to nil here but later in this test it gets set to a valid object.
+		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID1", "testID2"})
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080",
+			RetryParameters:    &retry.Params{}})
+		require.NoError(t, err)
+
+		setMockEDVClientReadDocumentReturnValue(t, client, op, testStructuredDocument1)
+
+		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
+			bytes.NewBuffer([]byte(nil)))
+		require.NoError(t, err)
+
+		q := r.URL.Query()
+		q.Add("id", testURLQueryID)
+		q.Add("profile", getTestProfile().Name)
+		r.URL.RawQuery = q.Encode()
+		rr := httptest.NewRecorder()
+
+		op.retrieveCredentialHandler(rr, r)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, testStructuredDocMessage1, rr.Body.String())
+		require.Equal(t, []string{"testID2"}, client.DeletedDocIDs)
+	})
+	t.Run("retrieve vc success - duplicate identical VCs not deleted", func(t *testing.T) {
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		for _, tc := range []struct {
+			name      string
+			dryRun    bool
+			deleteErr error
+		}{
+			{name: "dry run", dryRun: true},
+			{name: "delete error", deleteErr: errors.New("delete error")},
+		} {
+			client := edv.NewMockEDVClient("test", nil, nil, []string{"testID1", "testID2"})
+			client.DeleteDocumentErr = tc.deleteErr
+
+			op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+				KMSSecretsProvider:        mem.NewProvider(),
+				Crypto:                    &cryptomock.Crypto{},
+				EDVClient:                 client,
+				KeyManager:                &mockkms.KeyManager{CreateKeyValue: kh},
+				VDRI:                      &vdrimock.MockVDRIRegistry{},
+				HostURL:                   "localhost:8080",
+				RetryParameters:           &retry.Params{},
+				DuplicateVCsCleanupDryRun: tc.dryRun})
+			require.NoError(t, err, tc.name)
+
+			setMockEDVClientReadDocumentReturnValue(t, client, op, testStructuredDocument1)
+
+			r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint, nil)
+			require.NoError(t, err, tc.name)
+
+			q := r.URL.Query()
+			q.Add("id", testURLQueryID)
+			q.Add("profile", getTestProfile().Name)
+			r.URL.RawQuery = q.Encode()
+			rr := httptest.NewRecorder()
+
+			op.retrieveCredentialHandler(rr, r)
+			require.Equal(t, http.StatusOK, rr.Code, tc.name)
+			require.Equal(t, testStructuredDocMessage1, rr.Body.String(), tc.name)
+			require.Empty(t, client.DeletedDocIDs, tc.name)
+		}
+	})
+	t.Run("retrieve vc error - multiple VCs "+
+		"found under the same ID and they have differing contents", func(t *testing.T) {
+		// The mock client needs to be passed into operation.New, but we need the packer and key from the
+		// operation object in order to create a decryptable EncryptedDocument to be returned from the mock EDV client.
+		// It's set to nil here but later in this test it gets set to a valid object.
+		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID1", "testID2"})
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080",
+			RetryParameters:    &retry.Params{}})
+		require.NoError(t, err)
+
+		setMockEDVClientReadDocumentReturnValue(t, client, op, testStructuredDocument2)
+
+		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
+			bytes.NewBuffer([]byte(nil)))
+		require.NoError(t, err)
+
+		q := r.URL.Query()
+		q.Add("id", testURLQueryID)
+		q.Add("profile", getTestProfile().Name)
+		r.URL.RawQuery = q.Encode()
+		rr := httptest.NewRecorder()
+
+		op.retrieveCredentialHandler(rr, r)
+
+		errResp := &model.ErrorResponse{}
+		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		require.NoError(t, err)
+
+		require.Equal(t, errMultipleInconsistentVCsFoundForOneID.Error(), errResp.Message)
+
+		require.Equal(t, http.StatusConflict, rr.Code)
+	})
+	t.Run("retrieve vc error - multiple VCs found under the same ID but they can't be read", func(t *testing.T) {
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient: &failingReadDocumentsEDVClient{
+				Client: edv.NewMockEDVClient("test", nil, nil, []string{"testID1", "testID2"}),
+			},
+			KeyManager:      &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:            &vdrimock.MockVDRIRegistry{},
+			HostURL:         "localhost:8080",
+			RetryParameters: &retry.Params{}})
+		require.NoError(t, err)
+
+		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
+			bytes.NewBuffer([]byte(nil)))
+		require.NoError(t, err)
+
+		q := r.URL.Query()
+		q.Add("id", testURLQueryID)
+		q.Add("profile", getTestProfile().Name)
+		r.URL.RawQuery = q.Encode()
+		rr := httptest.NewRecorder()
+
+		op.retrieveCredentialHandler(rr, r)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to read documents while determining if the multiple VCs")
+		require.Contains(t, rr.Body.String(), errDocumentNotFound.Error())
+	})
+	t.Run("retrieve vc fail - no VC found under the given ID", func(t *testing.T) {
+		// The mock client needs to be passed into operation.New, but we need the packer and key from the
+		// operation object in order to create a decryptable EncryptedDocument to be returned from the mock EDV client.
+		// It's set to nil here but later in this test it gets set to a valid object.
+		client := edv.NewMockEDVClient("test", nil, nil, nil)
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080",
+			RetryParameters:    &retry.Params{}})
+		require.NoError(t, err)
+
+		setMockEDVClientReadDocumentReturnValue(t, client, op, testStructuredDocument1)
+
+		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
+			bytes.NewBuffer([]byte(nil)))
+		require.NoError(t, err)
+
+		q := r.URL.Query()
+		q.Add("id", testURLQueryID)
+		q.Add("profile", getTestProfile().Name)
+		r.URL.RawQuery = q.Encode()
+		rr := httptest.NewRecorder()
+
+		op.retrieveCredentialHandler(rr, r)
+		errResp := &model.ErrorResponse{}
+		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		require.NoError(t, err)
+
+		require.Equal(t, `no VC under profile "test" was found with the given id`, errResp.Message)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+	t.Run("retrieve vc error when missing profile name", func(t *testing.T) {
+		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080"})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
+			bytes.NewBuffer([]byte(nil)))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+
+		op.retrieveCredentialHandler(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "missing profile name")
+	})
+	t.Run("retrieve vc error when missing vc ID", func(t *testing.T) {
+		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080"})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
+			bytes.NewBuffer([]byte(nil)))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		q := req.URL.Query()
+		q.Add("profile", getTestProfile().Name)
+		req.URL.RawQuery = q.Encode()
+		op.retrieveCredentialHandler(rr, req)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "missing verifiable credential ID")
+	})
+	t.Run("retrieve vc error when no document is found", func(t *testing.T) {
+		client := NewMockEDVClient("test")
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			EDVClient:          client,
+			Crypto:             &cryptomock.Crypto{},
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080",
+			RetryParameters:    &retry.Params{}})
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
+			bytes.NewBuffer([]byte(nil)))
+		require.NoError(t, err)
+
+		q := req.URL.Query()
+		q.Add("id", testUUID)
+		q.Add("profile", getTestProfile().Name)
+		req.URL.RawQuery = q.Encode()
+
+		rr := httptest.NewRecorder()
+
+		op.retrieveCredentialHandler(rr, req)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), errDocumentNotFound.Error())
+	})
+	t.Run("retrieve vc fail when writing document retrieval success", func(t *testing.T) {
+		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080",
+			RetryParameters:    &retry.Params{}})
+		require.NoError(t, err)
+
+		setMockEDVClientReadDocumentReturnValue(t, client, op, testStructuredDocument1)
+
+		retrieveVCHandler := getHandler(t, op, retrieveCredentialEndpoint, http.MethodGet)
+
+		req, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
+			bytes.NewBuffer([]byte(nil)))
+		require.NoError(t, err)
+
+		q := req.URL.Query()
+		q.Add("id", testURLQueryID)
+		q.Add("profile", getTestProfile().Name)
+		req.URL.RawQuery = q.Encode()
+
+		rw := mockResponseWriter{}
+		retrieveVCHandler.Handle().ServeHTTP(rw, req)
+		require.Contains(t, testLoggerProvider.logContents.String(),
+			"Failed to write response for document retrieval success: response writer failed")
+	})
+	t.Run("fail to compute MAC when querying vault", func(t *testing.T) {
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          NewMockEDVClient("test"),
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080"})
+		require.NoError(t, err)
+
+		op.macCrypto = failingCrypto{}
+
+		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
+			bytes.NewBuffer([]byte(nil)))
+		require.NoError(t, err)
+
+		q := r.URL.Query()
+		q.Add("id", testURLQueryID)
+		q.Add("profile", getTestProfile().Name)
+		r.URL.RawQuery = q.Encode()
+		rr := httptest.NewRecorder()
+
+		op.retrieveCredentialHandler(rr, r)
+		errResp := &model.ErrorResponse{}
+		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		require.NoError(t, err)
+
+		require.Equal(t, `i always fail`,
+			errResp.Message)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+	t.Run("fail to deserialize JWE", func(t *testing.T) {
+		client := edv.NewMockEDVClient("test",
+			&models.EncryptedDocument{JWE: []byte("{ not valid JWE }")},
+			nil, []string{"testID"})
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080",
+			RetryParameters:    &retry.Params{}})
+		require.NoError(t, err)
+
+		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
+			bytes.NewBuffer([]byte(nil)))
+		require.NoError(t, err)
+
+		q := r.URL.Query()
+		q.Add("id", testURLQueryID)
+		q.Add("profile", getTestProfile().Name)
+		r.URL.RawQuery = q.Encode()
+		rr := httptest.NewRecorder()
+
+		op.retrieveCredentialHandler(rr, r)
+		errResp := &model.ErrorResponse{}
+		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		require.NoError(t, err)
+
+		require.Equal(t, "invalid character 'n' looking for beginning of object key string", errResp.Message)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
+
+func TestLocalCredentialStorage(t *testing.T) {
+	storeReq := &StoreVCRequest{}
+	require.NoError(t, json.Unmarshal([]byte(testStoreCredentialRequest), storeReq))
+
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(storeReq.Credential))
+	require.NoError(t, err)
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	newOperation := func(t *testing.T, client EDVClient, credentialStorage string) *Operation {
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			EDVClient:          client,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080",
+			RetryParameters:    &retry.Params{},
+			CredentialStorage:  credentialStorage})
+		require.NoError(t, err)
+
+		return op
+	}
+
+	storeAndRetrieve := func(t *testing.T, op *Operation) *httptest.ResponseRecorder {
+		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
+			bytes.NewBuffer([]byte(testStoreCredentialRequest)))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		op.storeCredentialHandler(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint, nil)
+		require.NoError(t, err)
+
+		q := r.URL.Query()
+		q.Add("id", vc.ID)
+		q.Add("profile", storeReq.Profile)
+		r.URL.RawQuery = q.Encode()
+
+		rr = httptest.NewRecorder()
+		op.retrieveCredentialHandler(rr, r)
+
+		return rr
+	}
+
+	t.Run("test deployment storage", func(t *testing.T) {
+		op := newOperation(t, nil, CredentialStorageLocal)
+
+		rr := storeAndRetrieve(t, op)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), vc.ID)
+	})
+
+	t.Run("test profile storage", func(t *testing.T) {
+		// the EDV client fails to store the credentials
+		op := newOperation(t, NewMockEDVClient("test"), "")
+
+		require.NoError(t, op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: storeReq.Profile,
+			CredentialStorage: CredentialStorageLocal}))
+
+		rr := storeAndRetrieve(t, op)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), vc.ID)
+	})
+
+	t.Run("test error - EDV not configured", func(t *testing.T) {
+		op := newOperation(t, nil, "")
+
+		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint, nil)
+		require.NoError(t, err)
+
+		q := r.URL.Query()
+		q.Add("id", vc.ID)
+		q.Add("profile", storeReq.Profile)
+		r.URL.RawQuery = q.Encode()
+
+		rr := httptest.NewRecorder()
+		op.retrieveCredentialHandler(rr, r)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), errEDVNotConfigured.Error())
+	})
+}
+
+func TestListCredentials(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		Crypto:             &plainMACCrypto{},
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		VDRI:               &vdrimock.MockVDRIRegistry{},
+		HostURL:            "localhost:8080",
+		RetryParameters:    &retry.Params{},
+		CredentialStorage:  CredentialStorageLocal})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "issuer"}))
+
+	credential := func(id, vcType, subject, issued string) string {
+		return `{"@context":"https://www.w3.org/2018/credentials/v1","id":"http://example.edu/credentials/` + id +
+			`","type":["VerifiableCredential","` + vcType + `"],"credentialSubject":{"id":"` + subject + `"},` +
+			`"issuer":"did:example:76e12ec712ebc6f1c221ebfeb1f","issuanceDate":"` + issued + `"}`
+	}
+
+	list := func(t *testing.T, profile string, query map[string]string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodGet, "/"+profile+"/credentials", nil)
+		require.NoError(t, err)
+
+		q := r.URL.Query()
+		for k, v := range query {
+			q.Add(k, v)
+		}
+
+		r.URL.RawQuery = q.Encode()
+
+		rr := httptest.NewRecorder()
+		op.listCredentialsHandler(rr, mux.SetURLVars(r, map[string]string{profileIDPathParam: profile}))
+
+		return rr
+	}
+
+	listIDs := func(t *testing.T, query map[string]string) []string {
+		rr := list(t, "issuer", query)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		resp := &ListCredentialsResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+
+		ids := make([]string, 0, len(resp.Credentials))
+
+		for _, c := range resp.Credentials {
+			vc, err := verifiable.ParseUnverifiedCredential(c)
+			require.NoError(t, err)
+
+			ids = append(ids, strings.TrimPrefix(vc.ID, "http://example.edu/credentials/"))
+		}
+
+		return ids
+	}
+
+	t.Run("test empty profile", func(t *testing.T) {
+		require.Empty(t, listIDs(t, nil))
+	})
+
+	reqBytes, err := json.Marshal(&StoreVCRequest{Profile: "issuer", Credentials: []string{
+		credential("1", "UniversityDegreeCredential", "did:example:alice", "2019-01-01T00:00:00Z"),
+		credential("2", "UniversityDegreeCredential", "did:example:bob", "2020-01-01T00:00:00Z"),
+		credential("3", "DriversLicenseCredential", "did:example:alice", "2020-06-01T00:00:00Z"),
+	}})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint, bytes.NewBuffer(reqBytes))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	op.storeCredentialHandler(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	t.Run("test filters", func(t *testing.T) {
+		require.ElementsMatch(t, []string{"1", "2", "3"}, listIDs(t, nil))
+		require.ElementsMatch(t, []string{"1", "2"}, listIDs(t, map[string]string{
+			"type": "UniversityDegreeCredential"}))
+		require.ElementsMatch(t, []string{"1", "3"}, listIDs(t, map[string]string{"subject": "did:example:alice"}))
+		require.ElementsMatch(t, []string{"1"}, listIDs(t, map[string]string{
+			"type": "UniversityDegreeCredential", "subject": "did:example:alice"}))
+		require.ElementsMatch(t, []string{"2", "3"}, listIDs(t, map[string]string{
+			"issuedAfter": "2019-06-01T00:00:00Z"}))
+		require.ElementsMatch(t, []string{"2"}, listIDs(t, map[string]string{
+			"issuedAfter": "2019-06-01T00:00:00Z", "issuedBefore": "2020-01-01T00:00:00Z"}))
+		require.Empty(t, listIDs(t, map[string]string{"type": "PermanentResidentCard"}))
+
+		// the short issuance date ranges are looked up in the index
+		require.ElementsMatch(t, []string{"2"}, listIDs(t, map[string]string{
+			"issuedAfter": "2019-12-31T00:00:00Z", "issuedBefore": "2020-01-01T12:00:00Z"}))
+		require.ElementsMatch(t, []string{"3"}, listIDs(t, map[string]string{"subject": "did:example:alice",
+			"issuedAfter": "2020-05-15T00:00:00+02:00", "issuedBefore": "2020-06-14T00:00:00Z"}))
+		require.Empty(t, listIDs(t, map[string]string{
+			"issuedAfter": "2020-01-01T00:00:01Z", "issuedBefore": "2020-01-01T12:00:00Z"}))
+		require.Empty(t, listIDs(t, map[string]string{
+			"issuedAfter": "2020-01-02T00:00:00Z", "issuedBefore": "2020-01-01T00:00:00Z"}))
+	})
+
+	t.Run("test paging", func(t *testing.T) {
+		page := func(t *testing.T, query map[string]string) *ListCredentialsResponse {
+			rr := list(t, "issuer", query)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+			resp := &ListCredentialsResponse{}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+
+			return resp
+		}
+
+		first := page(t, map[string]string{"limit": "2"})
+		require.Len(t, first.Credentials, 2)
+		require.NotEmpty(t, first.NextCursor)
+
+		second := page(t, map[string]string{"limit": "2", "cursor": first.NextCursor})
+		require.Len(t, second.Credentials, 1)
+		require.Empty(t, second.NextCursor)
+
+		filtered := page(t, map[string]string{"limit": "1", "subject": "did:example:alice"})
+		require.Len(t, filtered.Credentials, 1)
+		require.NotEmpty(t, filtered.NextCursor)
+
+		filtered = page(t, map[string]string{"limit": "1", "subject": "did:example:alice",
+			"cursor": filtered.NextCursor})
+		require.Len(t, filtered.Credentials, 1)
+	})
+
+	t.Run("test error - invalid limit", func(t *testing.T) {
+		for _, limit := range []string{"0", "1001", "x"} {
+			rr := list(t, "issuer", map[string]string{"limit": limit})
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			require.Contains(t, rr.Body.String(), "invalid limit, expected an integer between 1 and 1000: "+limit)
+		}
+	})
+
+	t.Run("test error - invalid profile", func(t *testing.T) {
+		rr := list(t, "unknown", nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid issuer profile - id=unknown")
+		require.Contains(t, rr.Body.String(), `"code":"PROFILE_NOT_FOUND"`)
+	})
+
+	t.Run("test error - invalid date", func(t *testing.T) {
+		rr := list(t, "issuer", map[string]string{"issuedBefore": "2020-01-01"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid issuedBefore date, RFC3339 expected: 2020-01-01")
+	})
+
+	t.Run("test error - EDV not configured", func(t *testing.T) {
+		require.NoError(t, op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "edv",
+			CredentialStorage: CredentialStorageEDV}))
+
+		rr := list(t, "edv", nil)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), errEDVNotConfigured.Error())
+		require.Contains(t, rr.Body.String(), `"code":"EDV_ERROR"`)
+	})
+
+	t.Run("test error - documents can't be read", func(t *testing.T) {
+		op.edvClient = NewMockEDVClient("test")
+		defer func() { op.edvClient = nil }()
+
+		rr := list(t, "edv", nil)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to read documents while listing VCs")
+	})
+}
+
+func TestExportCredentials(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		Crypto:             &plainMACCrypto{},
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		VDRI:               &vdrimock.MockVDRIRegistry{},
+		HostURL:            "localhost:8080",
+		RetryParameters:    &retry.Params{},
+		CredentialStorage:  CredentialStorageLocal})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "issuer"}))
+
+	export := func(t *testing.T, profile string, query map[string]string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodGet, "/"+profile+"/credentials/export", nil)
+		require.NoError(t, err)
+
+		q := r.URL.Query()
+		for k, v := range query {
+			q.Add(k, v)
+		}
+
+		r.URL.RawQuery = q.Encode()
+
+		rr := httptest.NewRecorder()
+		op.exportCredentialsHandler(rr, mux.SetURLVars(r, map[string]string{profileIDPathParam: profile}))
+
+		return rr
+	}
+
+	credentialID := func(t *testing.T, vcBytes []byte) string {
+		vc, err := verifiable.ParseUnverifiedCredential(vcBytes)
+		require.NoError(t, err)
+
+		return strings.TrimPrefix(vc.ID, "http://example.edu/credentials/")
+	}
+
+	t.Run("test empty profile", func(t *testing.T) {
+		rr := export(t, "issuer", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+		require.Empty(t, rr.Body.String())
+	})
+
+	var credentials []string
+
+	for _, id := range []string{"1", "2"} {
+		credentials = append(credentials, `{"@context":"https://www.w3.org/2018/credentials/v1",`+
+			`"id":"http://example.edu/credentials/`+id+`","type":"VerifiableCredential",`+
+			`"credentialSubject":{"id":"did:example:alice"},"issuer":"did:example:76e12ec712ebc6f1c221ebfeb1f",`+
+			`"issuanceDate":"2020-01-01T00:00:00Z"}`)
+	}
+
+	reqBytes, err := json.Marshal(&StoreVCRequest{Profile: "issuer", Credentials: credentials})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint, bytes.NewBuffer(reqBytes))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	op.storeCredentialHandler(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	t.Run("test ndjson export", func(t *testing.T) {
+		rr := export(t, "issuer", map[string]string{"format": "ndjson"})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, `attachment; filename="issuer-credentials.ndjson"`, rr.Header().Get("Content-Disposition"))
+
+		lines := strings.Split(strings.TrimSuffix(rr.Body.String(), "\n"), "\n")
+		require.Len(t, lines, 2)
+		require.ElementsMatch(t, []string{"1", "2"},
+			[]string{credentialID(t, []byte(lines[0])), credentialID(t, []byte(lines[1]))})
+	})
+
+	t.Run("test zip export", func(t *testing.T) {
+		rr := export(t, "issuer", map[string]string{"format": "zip"})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+
+		zr, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+		require.NoError(t, err)
+		require.Len(t, zr.File, 2)
+
+		var ids []string
+
+		for _, f := range zr.File {
+			require.True(t, strings.HasSuffix(f.Name, ".json"))
+
+			r, err := f.Open()
+			require.NoError(t, err)
+
+			vcBytes, err := ioutil.ReadAll(r)
+			require.NoError(t, err)
+
+			ids = append(ids, credentialID(t, vcBytes))
+		}
+
+		require.ElementsMatch(t, []string{"1", "2"}, ids)
+	})
+
+	t.Run("test export encrypted to the recipient key", func(t *testing.T) {
+		recipientKH, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		pubKH, err := recipientKH.Public()
+		require.NoError(t, err)
+
+		buf := new(bytes.Buffer)
+		require.NoError(t, pubKH.WriteWithNoSecrets(ecdhes.NewWriter(buf)))
+
+		pubKey := &subtle.PublicKey{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), pubKey))
+
+		jwk, err := jose.JWKFromPublicKey(&ecdsa.PublicKey{Curve: elliptic.P256(),
+			X: new(big.Int).SetBytes(pubKey.X), Y: new(big.Int).SetBytes(pubKey.Y)})
+		require.NoError(t, err)
+
+		jwkBytes, err := jwk.MarshalJSON()
+		require.NoError(t, err)
+
+		rr := export(t, "issuer", map[string]string{
+			"recipientKey": base64.RawURLEncoding.EncodeToString(jwkBytes)})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		lines := strings.Split(strings.TrimSuffix(rr.Body.String(), "\n"), "\n")
+		require.Len(t, lines, 2)
+
+		for _, line := range lines {
+			jwe, err := jose.Deserialize(line)
+			require.NoError(t, err)
+
+			vcBytes, err := jose.NewJWEDecrypt(recipientKH).Decrypt(jwe)
+			require.NoError(t, err)
+			require.NotEmpty(t, credentialID(t, vcBytes))
+		}
+	})
+
+	t.Run("test error - invalid format", func(t *testing.T) {
+		rr := export(t, "issuer", map[string]string{"format": "csv"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "unsupported export format csv, expected ndjson or zip")
+	})
+
+	t.Run("test error - invalid recipient key", func(t *testing.T) {
+		rr := export(t, "issuer", map[string]string{"recipientKey": "!"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid recipient key, expected a base64url encoded JWK")
+
+		rr = export(t, "issuer", map[string]string{"recipientKey": base64.RawURLEncoding.EncodeToString([]byte("{}"))})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid recipient key")
+
+		pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		jwk, err := jose.JWKFromPublicKey(pubKey)
+		require.NoError(t, err)
+
+		jwkBytes, err := jwk.MarshalJSON()
+		require.NoError(t, err)
+
+		rr = export(t, "issuer", map[string]string{"recipientKey": base64.RawURLEncoding.EncodeToString(jwkBytes)})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid recipient key, expected an EC public key")
+	})
+
+	t.Run("test error - invalid profile", func(t *testing.T) {
+		rr := export(t, "unknown", nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), `"code":"PROFILE_NOT_FOUND"`)
+	})
+
+	t.Run("test error - EDV not configured", func(t *testing.T) {
+		require.NoError(t, op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "edv",
+			CredentialStorage: CredentialStorageEDV}))
+
+		rr := export(t, "edv", nil)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), errEDVNotConfigured.Error())
+	})
+
+	t.Run("test error - documents can't be read once the export started", func(t *testing.T) {
+		op.edvClient = NewMockEDVClient("test")
+		defer func() { op.edvClient = nil }()
+
+		rr := export(t, "edv", map[string]string{"format": "zip"})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		_, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+		require.Error(t, err)
+	})
+}
+
+func TestImportCredentials(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	newOperation := func(t *testing.T, duplicateCredentials string) *Operation {
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider:   mem.NewProvider(),
+			Crypto:               &plainMACCrypto{},
+			KeyManager:           &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:                 &vdrimock.MockVDRIRegistry{},
+			HostURL:              "localhost:8080",
+			RetryParameters:      &retry.Params{},
+			CredentialStorage:    CredentialStorageLocal,
+			DuplicateCredentials: duplicateCredentials})
+		require.NoError(t, err)
+
+		require.NoError(t, op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "issuer"}))
+
+		return op
+	}
+
+	importCredentials := func(t *testing.T, op *Operation, profile, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodPost, "/"+profile+"/credentials/import", strings.NewReader(body))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		op.importCredentialsHandler(rr, mux.SetURLVars(r, map[string]string{profileIDPathParam: profile}))
+
+		return rr
+	}
+
+	report := func(t *testing.T, rr *httptest.ResponseRecorder) *ImportCredentialsResponse {
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		resp := &ImportCredentialsResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+
+		return resp
+	}
+
+	t.Run("test import", func(t *testing.T) {
+		op := newOperation(t, DuplicateCredentialsAllow)
+
+		resp := report(t, importCredentials(t, op, "issuer", testCredential("1")+"\n\n"+"{\n"+
+			strings.Replace(testCredential("2"), `"id":"http://example.edu/credentials/2",`, "", 1)+"\n"+
+			testCredential("3")))
+		require.Equal(t, 2, resp.Imported)
+		require.Equal(t, 2, resp.Failed)
+		require.Len(t, resp.Results, 4)
+
+		require.Equal(t, ImportCredentialResult{Line: 1, ID: "http://example.edu/credentials/1"}, resp.Results[0])
+		require.Equal(t, 3, resp.Results[1].Line)
+		require.Contains(t, resp.Results[1].Error, "unable to unmarshal the VC")
+		require.Equal(t, ImportCredentialResult{Line: 4, Error: "missing verifiable credential ID"},
+			resp.Results[2])
+		require.Equal(t, ImportCredentialResult{Line: 5, ID: "http://example.edu/credentials/3"}, resp.Results[3])
+
+		docIDs, err := queryDocIDs(op.localEDVClient, "issuer",
+			&models.Query{Name: op.vcStoredIndexNameEncoded, Value: op.vcStoredIndexNameEncoded}, "test")
+		require.NoError(t, err)
+		require.Len(t, docIDs, 2)
+	})
+
+	t.Run("test import in batches", func(t *testing.T) {
+		op := newOperation(t, DuplicateCredentialsAllow)
+
+		lines := make([]string, importBatchSize+1)
+		for i := range lines {
+			lines[i] = testCredential(strconv.Itoa(i))
+		}
+
+		resp := report(t, importCredentials(t, op, "issuer", strings.Join(lines, "\n")+"\n"))
+		require.Equal(t, importBatchSize+1, resp.Imported)
+		require.Zero(t, resp.Failed)
+		require.Equal(t, importBatchSize+1, resp.Results[importBatchSize].Line)
+	})
+
+	t.Run("test duplicates rejected", func(t *testing.T) {
+		op := newOperation(t, DuplicateCredentialsReject)
+
+		resp := report(t, importCredentials(t, op, "issuer", testCredential("1")+"\n"+testCredential("1")))
+		require.Equal(t, 1, resp.Imported)
+		require.Equal(t, errDuplicateVCInBatch.Error(), resp.Results[1].Error)
+
+		resp = report(t, importCredentials(t, op, "issuer", testCredential("1")))
+		require.Equal(t, 1, resp.Failed)
+		require.Equal(t, errDuplicateVC.Error(), resp.Results[0].Error)
+	})
+
+	t.Run("test error - nesting too deep", func(t *testing.T) {
+		op := newOperation(t, DuplicateCredentialsAllow)
+
+		resp := report(t, importCredentials(t, op, "issuer", strings.Repeat("[", 65)+strings.Repeat("]", 65)))
+		require.Equal(t, "JSON nesting exceeds the maximum depth of 64", resp.Results[0].Error)
+	})
+
+	t.Run("test error - VCs can't be stored", func(t *testing.T) {
+		op := newOperation(t, DuplicateCredentialsAllow)
+		op.localEDVClient = NewMockEDVClient("test")
+
+		resp := report(t, importCredentials(t, op, "issuer", testCredential("1")+"\n"+testCredential("2")))
+		require.Equal(t, 2, resp.Failed)
+		require.Contains(t, resp.Results[0].Error, "failed to store the VC")
+		require.Equal(t, "http://example.edu/credentials/2", resp.Results[1].ID)
+	})
+
+	t.Run("test error - body can't be read", func(t *testing.T) {
+		op := newOperation(t, DuplicateCredentialsAllow)
+
+		r, err := http.NewRequest(http.MethodPost, "/issuer/credentials/import",
+			strings.NewReader(testCredential("1")+"\n"+testCredential("2")))
 		require.NoError(t, err)
-		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint,
-			bytes.NewBuffer([]byte(testStoreCredentialRequest)))
-		require.NoError(t, err)
+
 		rr := httptest.NewRecorder()
-		op.storeCredentialHandler(rr, req)
+		r.Body = http.MaxBytesReader(rr, r.Body, int64(len(testCredential("1"))+10))
 
-		errResp := &model.ErrorResponse{}
-		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
-		require.NoError(t, err)
-		require.Equal(t, "ciphertext cannot be empty", errResp.Message)
-		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		op.importCredentialsHandler(rr, mux.SetURLVars(r, map[string]string{profileIDPathParam: "issuer"}))
+
+		resp := report(t, rr)
+		require.Equal(t, 1, resp.Imported)
+		require.Equal(t, ImportCredentialResult{Line: 2,
+			Error: "failed to read the request body: http: request body too large"}, resp.Results[1])
 	})
-}
 
-func TestRetrieveVCHandler(t *testing.T) {
-	t.Run("retrieve vc success", func(t *testing.T) {
-		// The mock client needs to be passed into operation.New, but we need the packer and key from the
-		// operation object in order to create a decryptable EncryptedDocument to be returned from the mock EDV client.
-		// It's set to nil here but later in this test it gets set to a valid object.
-		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+	t.Run("test error - invalid profile", func(t *testing.T) {
+		rr := importCredentials(t, newOperation(t, DuplicateCredentialsAllow), "unknown", testCredential("1"))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), `"code":"PROFILE_NOT_FOUND"`)
+	})
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
-		require.NoError(t, err)
+	t.Run("test error - EDV not configured", func(t *testing.T) {
+		op := newOperation(t, DuplicateCredentialsAllow)
+		require.NoError(t, op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "edv",
+			CredentialStorage: CredentialStorageEDV}))
+
+		rr := importCredentials(t, op, "edv", testCredential("1"))
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), errEDVNotConfigured.Error())
+	})
+}
 
+func TestReencryptCredentials(t *testing.T) {
+	newOperation := func(t *testing.T) *Operation {
 		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
 			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			Crypto:             &plainMACCrypto{},
+			KeyManager:         newJWEKeyManager(),
 			VDRI:               &vdrimock.MockVDRIRegistry{},
 			HostURL:            "localhost:8080",
-			RetryParameters:    &retry.Params{}})
+			RetryParameters:    &retry.Params{},
+			CredentialStorage:  CredentialStorageLocal})
 		require.NoError(t, err)
 
-		setMockEDVClientReadDocumentReturnValue(t, client, op, testStructuredDocument1)
+		require.NoError(t, op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "issuer"}))
 
-		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
-			bytes.NewBuffer([]byte(nil)))
+		r, err := http.NewRequest(http.MethodPost, "/issuer/credentials/import", strings.NewReader(
+			testCredential("1")+"\n"+testCredential("2")+"\n"+testCredential("3")))
 		require.NoError(t, err)
 
-		q := r.URL.Query()
-		q.Add("id", testURLQueryID)
-		q.Add("profile", getTestProfile().Name)
-		r.URL.RawQuery = q.Encode()
 		rr := httptest.NewRecorder()
-
-		op.retrieveCredentialHandler(rr, r)
+		op.importCredentialsHandler(rr, mux.SetURLVars(r, map[string]string{profileIDPathParam: "issuer"}))
 		require.Equal(t, http.StatusOK, rr.Code)
-		require.Equal(t, testStructuredDocMessage1, rr.Body.String())
-	})
-	t.Run("retrieve vc success - multiple VCs "+
-		"found under the same ID but they have identical contents", func(t *testing.T) {
-		// The mock client needs to be passed into operation.New, but we need the packer and key from the
-		// operation object in order to create a decryptable EncryptedDocument to be returned from the mock EDV client.
-		// It's set to nil here but later in this test it gets set to a valid object.
-		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID1", "testID2"})
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
-		require.NoError(t, err)
+		return op
+	}
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080",
-			RetryParameters:    &retry.Params{}})
+	reencrypt := func(t *testing.T, op *Operation, profile, body string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodPost, "/"+profile+"/credentials/reencrypt", strings.NewReader(body))
 		require.NoError(t, err)
 
-		setMockEDVClientReadDocumentReturnValue(t, client, op, testStructuredDocument1)
+		rr := httptest.NewRecorder()
+		op.reencryptCredentialsHandler(rr, mux.SetURLVars(r, map[string]string{profileIDPathParam: profile}))
 
-		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
-			bytes.NewBuffer([]byte(nil)))
+		return rr
+	}
+
+	report := func(t *testing.T, rr *httptest.ResponseRecorder) *ReencryptCredentialsResponse {
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		resp := &ReencryptCredentialsResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+
+		return resp
+	}
+
+	// storedKeyIDs returns the IDs of the keys of the stored documents, which are all decrypted
+	storedKeyIDs := func(t *testing.T, op *Operation) []string {
+		docIDs, err := queryDocIDs(op.localEDVClient, "issuer",
+			&models.Query{Name: op.vcStoredIndexNameEncoded, Value: op.vcStoredIndexNameEncoded}, "test")
 		require.NoError(t, err)
 
-		q := r.URL.Query()
-		q.Add("id", testURLQueryID)
-		q.Add("profile", getTestProfile().Name)
-		r.URL.RawQuery = q.Encode()
-		rr := httptest.NewRecorder()
+		documents, err := op.localEDVClient.ReadDocuments("issuer", docIDs)
+		require.NoError(t, err)
 
-		op.retrieveCredentialHandler(rr, r)
-		require.Equal(t, http.StatusOK, rr.Code)
-		require.Equal(t, testStructuredDocMessage1, rr.Body.String())
+		keyIDs := make([]string, len(documents))
+
+		for i, document := range documents {
+			_, err = op.decryptVC("issuer", document, "test")
+			require.NoError(t, err)
+
+			jwe, err := jose.Deserialize(string(document.JWE))
+			require.NoError(t, err)
+
+			keyIDs[i] = cryptosetup.JWEKeyID(jwe)
+		}
+
+		return keyIDs
+	}
+
+	t.Run("test re-encryption with a new key", func(t *testing.T) {
+		op := newOperation(t)
+		require.Equal(t, []string{"key2", "key2", "key2"}, storedKeyIDs(t, op))
+
+		resp := report(t, reencrypt(t, op, "issuer", `{"newKey":true}`))
+		require.Equal(t, ReencryptCredentialsResponse{KeyID: "key3", Reencrypted: 3}, *resp)
+		require.Equal(t, []string{"key3", "key3", "key3"}, storedKeyIDs(t, op))
+
+		resp = report(t, reencrypt(t, op, "issuer", ""))
+		require.Equal(t, ReencryptCredentialsResponse{KeyID: "key3", Skipped: 3}, *resp)
+
+		// the previous key can't be deleted, other profiles may still use it
+		cryptoKey, err := cryptosetup.IsCryptoKey(op.storeProvider, "key2")
+		require.NoError(t, err)
+		require.True(t, cryptoKey)
 	})
-	t.Run("retrieve vc error - multiple VCs "+
-		"found under the same ID and they have differing contents", func(t *testing.T) {
-		// The mock client needs to be passed into operation.New, but we need the packer and key from the
-		// operation object in order to create a decryptable EncryptedDocument to be returned from the mock EDV client.
-		// It's set to nil here but later in this test it gets set to a valid object.
-		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID1", "testID2"})
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	t.Run("test re-encryption of a document without issuance date index", func(t *testing.T) {
+		op := newOperation(t)
+
+		docIDs, err := queryDocIDs(op.localEDVClient, "issuer",
+			&models.Query{Name: op.vcStoredIndexNameEncoded, Value: op.vcStoredIndexNameEncoded}, "test")
 		require.NoError(t, err)
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080",
-			RetryParameters:    &retry.Params{}})
+		// a document stored before the issuance date index was added
+		document, err := op.localEDVClient.ReadDocument("issuer", docIDs[0])
 		require.NoError(t, err)
+		require.NoError(t, op.localEDVClient.DeleteDocument("issuer", document.ID))
 
-		setMockEDVClientReadDocumentReturnValue(t, client, op, testStructuredDocument2)
+		attributes := document.IndexedAttributeCollections[0].IndexedAttributes
+		document.IndexedAttributeCollections[0].IndexedAttributes = attributes[:len(attributes)-1]
 
-		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
-			bytes.NewBuffer([]byte(nil)))
+		_, err = op.localEDVClient.CreateDocument("issuer", document)
 		require.NoError(t, err)
 
-		q := r.URL.Query()
-		q.Add("id", testURLQueryID)
-		q.Add("profile", getTestProfile().Name)
-		r.URL.RawQuery = q.Encode()
-		rr := httptest.NewRecorder()
+		filter := &credentialsFilter{issuedAfter: &time.Time{}, issuedBefore: &time.Time{}, limit: maxListLimit}
+		*filter.issuedAfter = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		*filter.issuedBefore = *filter.issuedAfter
 
-		op.retrieveCredentialHandler(rr, r)
+		credentials, _, err := op.listCredentials(op.localEDVClient, "issuer", filter)
+		require.NoError(t, err)
+		require.Len(t, credentials, 2)
 
-		errResp := &model.ErrorResponse{}
-		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
+		resp := report(t, reencrypt(t, op, "issuer", ""))
+		require.Equal(t, ReencryptCredentialsResponse{KeyID: "key2", Reencrypted: 1, Skipped: 2}, *resp)
+
+		credentials, _, err = op.listCredentials(op.localEDVClient, "issuer", filter)
 		require.NoError(t, err)
+		require.Len(t, credentials, 3)
+	})
+	t.Run("test interrupted re-encryption resumed", func(t *testing.T) {
+		op := newOperation(t)
+		localEDVClient := op.localEDVClient
 
-		require.Equal(t, errMultipleInconsistentVCsFoundForOneID.Error(), errResp.Message)
+		op.localEDVClient = &failingEDVClient{EDVClient: localEDVClient, createErr: errors.New("create error")}
+
+		rr := reencrypt(t, op, "issuer", `{"newKey":true}`)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "create error")
+		require.Len(t, storedKeyIDs(t, op), 2)
+
+		op.localEDVClient = localEDVClient
+
+		resp := report(t, reencrypt(t, op, "issuer", ""))
+		require.Equal(t, ReencryptCredentialsResponse{KeyID: "key3", Reencrypted: 2, Skipped: 1}, *resp)
+		require.Equal(t, []string{"key3", "key3", "key3"}, storedKeyIDs(t, op))
+	})
+
+	t.Run("test interrupted re-encryption of a document stored again", func(t *testing.T) {
+		op := newOperation(t)
+
+		op.localEDVClient = &failingEDVClient{EDVClient: op.localEDVClient, deleteErr: errors.New("delete error")}
+
+		rr := reencrypt(t, op, "issuer", `{"newKey":true}`)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "delete error")
+
+		op.localEDVClient = op.localEDVClient.(*failingEDVClient).EDVClient
+
+		// the re-encrypted copy is dropped, the document is re-encrypted again
+		resp := report(t, reencrypt(t, op, "issuer", ""))
+		require.Equal(t, ReencryptCredentialsResponse{KeyID: "key3", Reencrypted: 3}, *resp)
+	})
+
+	t.Run("test error - re-encryption in progress", func(t *testing.T) {
+		op := newOperation(t)
+		require.True(t, op.startReencryption("issuer"))
 
+		rr := reencrypt(t, op, "issuer", "")
 		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Contains(t, rr.Body.String(), "the credentials of profile issuer are already being re-encrypted")
+
+		op.doneReencryption("issuer")
+		require.Equal(t, http.StatusOK, reencrypt(t, op, "issuer", "").Code)
 	})
-	t.Run("retrieve vc fail - no VC found under the given ID", func(t *testing.T) {
-		// The mock client needs to be passed into operation.New, but we need the packer and key from the
-		// operation object in order to create a decryptable EncryptedDocument to be returned from the mock EDV client.
-		// It's set to nil here but later in this test it gets set to a valid object.
-		client := edv.NewMockEDVClient("test", nil, nil, nil)
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
-		require.NoError(t, err)
+	t.Run("test error - keys of the profile can't be prepared", func(t *testing.T) {
+		op := newOperation(t)
+		op.profileJWECrypto = func(string) (jweCrypto, error) {
+			return nil, errors.New("kms error")
+		}
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080",
-			RetryParameters:    &retry.Params{}})
-		require.NoError(t, err)
+		rr := reencrypt(t, op, "issuer", "")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), `"code":"KMS_ERROR"`)
+		require.Contains(t, rr.Body.String(), "kms error")
+	})
+	t.Run("test error - key creation failed", func(t *testing.T) {
+		op := newOperation(t)
+		op.profileJWECrypto = profileJWECrypto(&failingJWECrypto{errRotate: errors.New("kms error")})
 
-		setMockEDVClientReadDocumentReturnValue(t, client, op, testStructuredDocument1)
+		rr := reencrypt(t, op, "issuer", `{"newKey":true}`)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), `"code":"KMS_ERROR"`)
+		require.Contains(t, rr.Body.String(), "failed to create the document encryption key: kms error")
+	})
 
-		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
-			bytes.NewBuffer([]byte(nil)))
-		require.NoError(t, err)
+	t.Run("test error - documents can't be read", func(t *testing.T) {
+		op := newOperation(t)
+		op.localEDVClient = &failingEDVClient{EDVClient: op.localEDVClient, readErr: errors.New("read error")}
 
-		q := r.URL.Query()
-		q.Add("id", testURLQueryID)
-		q.Add("profile", getTestProfile().Name)
-		r.URL.RawQuery = q.Encode()
-		rr := httptest.NewRecorder()
+		rr := reencrypt(t, op, "issuer", "")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to read documents while re-encrypting VCs: read error")
+	})
 
-		op.retrieveCredentialHandler(rr, r)
-		errResp := &model.ErrorResponse{}
-		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
-		require.NoError(t, err)
+	t.Run("test error - invalid re-encrypted document", func(t *testing.T) {
+		op := newOperation(t)
+		require.NoError(t, op.reencryptionStore.Put("issuer", []byte("{")))
 
-		require.Equal(t, `no VC under profile "test" was found with the given id`, errResp.Message)
+		rr := reencrypt(t, op, "issuer", "")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to unmarshal re-encrypted document")
+	})
+
+	t.Run("test error - invalid request", func(t *testing.T) {
+		rr := reencrypt(t, newOperation(t), "issuer", `{"newKey":1}`)
 		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), `"code":"INVALID_REQUEST"`)
 	})
-	t.Run("retrieve vc error when missing profile name", func(t *testing.T) {
-		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
-		require.NoError(t, err)
+	t.Run("test error - invalid profile", func(t *testing.T) {
+		rr := reencrypt(t, newOperation(t), "unknown", "")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), `"code":"PROFILE_NOT_FOUND"`)
+	})
+
+	t.Run("test error - EDV not configured", func(t *testing.T) {
+		op := newOperation(t)
+		require.NoError(t, op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "edv",
+			CredentialStorage: CredentialStorageEDV}))
+
+		rr := reencrypt(t, op, "edv", "")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), errEDVNotConfigured.Error())
+	})
+}
+func TestSubjectIDs(t *testing.T) {
+	require.Equal(t, []string{"did:example:1"}, subjectIDs("did:example:1"))
+	require.Equal(t, []string{"did:example:1"}, subjectIDs(map[string]interface{}{"id": "did:example:1"}))
+	require.Equal(t, []string{"did:example:1", "did:example:2"}, subjectIDs([]map[string]interface{}{
+		{"id": "did:example:1"}, {"id": "did:example:2"}}))
+	require.Empty(t, subjectIDs(map[string]interface{}{"name": "Jayden Doe"}))
+	require.Empty(t, subjectIDs(make(chan int)))
+}
+
+// plainMACCrypto returns the data as its MAC, so that the indexes of the stored VCs are distinct
+type plainMACCrypto struct {
+	cryptomock.Crypto
+}
+
+func (c *plainMACCrypto) ComputeMAC(data []byte, _ interface{}) ([]byte, error) {
+	return data, nil
+}
+
+func TestDuplicateCredentials(t *testing.T) {
+	storeReq := &StoreVCRequest{}
+	require.NoError(t, json.Unmarshal([]byte(testStoreCredentialRequest), storeReq))
+
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(storeReq.Credential))
+	require.NoError(t, err)
+
+	updatedCredential := strings.Replace(storeReq.Credential, "Example University", "Updated University", 1)
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
 
+	newOperation := func(t *testing.T, client EDVClient, duplicateCredentials string) *Operation {
 		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080"})
+			KMSSecretsProvider:   mem.NewProvider(),
+			Crypto:               &cryptomock.Crypto{},
+			EDVClient:            client,
+			KeyManager:           &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:                 &vdrimock.MockVDRIRegistry{},
+			HostURL:              "localhost:8080",
+			RetryParameters:      &retry.Params{},
+			CredentialStorage:    CredentialStorageLocal,
+			DuplicateCredentials: duplicateCredentials})
+		require.NoError(t, err)
+
+		return op
+	}
+
+	storeBatch := func(t *testing.T, op *Operation, credentials ...string) *httptest.ResponseRecorder {
+		reqBytes, err := json.Marshal(&StoreVCRequest{Profile: storeReq.Profile, Credentials: credentials})
 		require.NoError(t, err)
-		req, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
-			bytes.NewBuffer([]byte(nil)))
+
+		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint, bytes.NewBuffer(reqBytes))
 		require.NoError(t, err)
 
 		rr := httptest.NewRecorder()
+		op.storeCredentialHandler(rr, req)
 
-		op.retrieveCredentialHandler(rr, req)
-		require.Equal(t, http.StatusBadRequest, rr.Code)
-		require.Contains(t, rr.Body.String(), "missing profile name")
-	})
-	t.Run("retrieve vc error when missing vc ID", func(t *testing.T) {
-		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
+		return rr
+	}
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	storedCopies := func(t *testing.T, op *Operation) int {
+		vcIDIndexValueEncoded, err := op.computeMACEncoded(vc.ID)
 		require.NoError(t, err)
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080"})
-		require.NoError(t, err)
-		req, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
-			bytes.NewBuffer([]byte(nil)))
+		docURLs, err := op.localEDVClient.QueryVault(storeReq.Profile,
+			&models.Query{Name: op.vcIDIndexNameEncoded, Value: vcIDIndexValueEncoded})
+		if err != nil && strings.Contains(err.Error(), messages.ErrVaultNotFound.Error()) {
+			return 0
+		}
+
 		require.NoError(t, err)
 
-		rr := httptest.NewRecorder()
-		q := req.URL.Query()
-		q.Add("profile", getTestProfile().Name)
-		req.URL.RawQuery = q.Encode()
-		op.retrieveCredentialHandler(rr, req)
-		require.Equal(t, http.StatusBadRequest, rr.Code)
-		require.Contains(t, rr.Body.String(), "missing verifiable credential ID")
-	})
-	t.Run("retrieve vc error when no document is found", func(t *testing.T) {
-		client := NewMockEDVClient("test")
+		return len(docURLs)
+	}
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	store := func(t *testing.T, op *Operation, credential string) *httptest.ResponseRecorder {
+		reqBytes, err := json.Marshal(&StoreVCRequest{Profile: storeReq.Profile, Credential: credential})
 		require.NoError(t, err)
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			EDVClient:          client,
-			Crypto:             &cryptomock.Crypto{},
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080",
-			RetryParameters:    &retry.Params{}})
+		req, err := http.NewRequest(http.MethodPost, storeCredentialEndpoint, bytes.NewBuffer(reqBytes))
 		require.NoError(t, err)
 
-		req, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
-			bytes.NewBuffer([]byte(nil)))
+		rr := httptest.NewRecorder()
+		op.storeCredentialHandler(rr, req)
+
+		return rr
+	}
+
+	retrieve := func(t *testing.T, op *Operation, version string) *httptest.ResponseRecorder {
+		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint, nil)
 		require.NoError(t, err)
 
-		q := req.URL.Query()
-		q.Add("id", testUUID)
-		q.Add("profile", getTestProfile().Name)
-		req.URL.RawQuery = q.Encode()
+		q := r.URL.Query()
+		q.Add("id", vc.ID)
+		q.Add("profile", storeReq.Profile)
+
+		if version != "" {
+			q.Add("version", version)
+		}
+
+		r.URL.RawQuery = q.Encode()
 
 		rr := httptest.NewRecorder()
+		op.retrieveCredentialHandler(rr, r)
 
-		op.retrieveCredentialHandler(rr, req)
-		require.Equal(t, http.StatusInternalServerError, rr.Code)
-		require.Contains(t, rr.Body.String(), errDocumentNotFound.Error())
+		return rr
+	}
+
+	t.Run("test reject", func(t *testing.T) {
+		op := newOperation(t, nil, DuplicateCredentialsReject)
+
+		require.Equal(t, http.StatusOK, store(t, op, storeReq.Credential).Code)
+
+		rr := store(t, op, updatedCredential)
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Contains(t, rr.Body.String(), errDuplicateVC.Error())
+
+		rr = retrieve(t, op, "")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), "Example University")
+
+		rr = retrieve(t, op, "0")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "the VCs are not versioned")
 	})
-	t.Run("retrieve vc fail when writing document retrieval success", func(t *testing.T) {
-		client := edv.NewMockEDVClient("test", nil, nil, []string{"testID"})
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
-		require.NoError(t, err)
+	t.Run("test overwrite", func(t *testing.T) {
+		op := newOperation(t, nil, DuplicateCredentialsOverwrite)
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080",
-			RetryParameters:    &retry.Params{}})
-		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, store(t, op, storeReq.Credential).Code)
+		require.Equal(t, http.StatusOK, store(t, op, updatedCredential).Code)
 
-		setMockEDVClientReadDocumentReturnValue(t, client, op, testStructuredDocument1)
+		rr := retrieve(t, op, "")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), "Updated University")
+		require.Equal(t, 1, storedCopies(t, op))
+	})
 
-		retrieveVCHandler := getHandler(t, op, retrieveCredentialEndpoint, http.MethodGet)
+	t.Run("test reject - batch", func(t *testing.T) {
+		op := newOperation(t, nil, DuplicateCredentialsReject)
 
-		req, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
-			bytes.NewBuffer([]byte(nil)))
-		require.NoError(t, err)
+		rr := storeBatch(t, op, storeReq.Credential, updatedCredential)
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Contains(t, rr.Body.String(), errDuplicateVCInBatch.Error()+": the VC at index 1")
+		require.Equal(t, 0, storedCopies(t, op))
+	})
 
-		q := req.URL.Query()
-		q.Add("id", testURLQueryID)
-		q.Add("profile", getTestProfile().Name)
-		req.URL.RawQuery = q.Encode()
+	t.Run("test overwrite - batch", func(t *testing.T) {
+		op := newOperation(t, nil, DuplicateCredentialsOverwrite)
 
-		rw := mockResponseWriter{}
-		retrieveVCHandler.Handle().ServeHTTP(rw, req)
-		require.Contains(t, testLoggerProvider.logContents.String(),
-			"Failed to write response for document retrieval success: response writer failed")
+		require.Equal(t, http.StatusOK, store(t, op, storeReq.Credential).Code)
+		require.Equal(t, http.StatusOK, storeBatch(t, op, storeReq.Credential, updatedCredential).Code)
+
+		rr := retrieve(t, op, "")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), "Updated University")
+		require.Equal(t, 1, storedCopies(t, op))
 	})
-	t.Run("fail to compute MAC when querying vault", func(t *testing.T) {
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
-		require.NoError(t, err)
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080"})
-		require.NoError(t, err)
+	t.Run("test version - batch", func(t *testing.T) {
+		op := newOperation(t, nil, DuplicateCredentialsVersion)
 
-		op.macCrypto = failingCrypto{}
+		require.Equal(t, http.StatusOK, storeBatch(t, op, storeReq.Credential, updatedCredential).Code)
 
-		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
-			bytes.NewBuffer([]byte(nil)))
-		require.NoError(t, err)
+		rr := retrieve(t, op, "0")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), "Example University")
 
-		q := r.URL.Query()
-		q.Add("id", testURLQueryID)
-		q.Add("profile", getTestProfile().Name)
-		r.URL.RawQuery = q.Encode()
-		rr := httptest.NewRecorder()
+		rr = retrieve(t, op, "1")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), "Updated University")
+	})
 
-		op.retrieveCredentialHandler(rr, r)
-		errResp := &model.ErrorResponse{}
-		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
-		require.NoError(t, err)
+	t.Run("test error - copy stored concurrently", func(t *testing.T) {
+		for policy, errMsg := range map[string]string{
+			DuplicateCredentialsReject:  errDuplicateVC.Error(),
+			DuplicateCredentialsVersion: errConcurrentVCStorage.Error(),
+		} {
+			op := newOperation(t, nil, policy)
 
-		require.Equal(t, `i always fail`,
-			errResp.Message)
-		require.Equal(t, http.StatusInternalServerError, rr.Code)
+			require.Equal(t, http.StatusOK, store(t, op, storeReq.Credential).Code)
+
+			// the stored copy is not found by the check of the duplicates
+			op.localEDVClient = &noQueryEDVClient{EDVClient: op.localEDVClient}
+
+			rr := store(t, op, storeReq.Credential)
+			require.Equal(t, http.StatusConflict, rr.Code, policy)
+			require.Contains(t, rr.Body.String(), errMsg)
+		}
 	})
-	t.Run("fail to deserialize JWE", func(t *testing.T) {
-		client := edv.NewMockEDVClient("test",
-			&models.EncryptedDocument{JWE: []byte("{ not valid JWE }")},
-			nil, []string{"testID"})
 
-		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
-		require.NoError(t, err)
+	t.Run("test version", func(t *testing.T) {
+		op := newOperation(t, nil, DuplicateCredentialsVersion)
 
-		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
-			KMSSecretsProvider: mem.NewProvider(),
-			Crypto:             &cryptomock.Crypto{},
-			EDVClient:          client,
-			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-			VDRI:               &vdrimock.MockVDRIRegistry{},
-			HostURL:            "localhost:8080",
-			RetryParameters:    &retry.Params{}})
-		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, store(t, op, storeReq.Credential).Code)
+		require.Equal(t, http.StatusOK, store(t, op, updatedCredential).Code)
 
-		r, err := http.NewRequest(http.MethodGet, retrieveCredentialEndpoint,
-			bytes.NewBuffer([]byte(nil)))
-		require.NoError(t, err)
+		rr := retrieve(t, op, "")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), "Updated University")
 
-		q := r.URL.Query()
-		q.Add("id", testURLQueryID)
-		q.Add("profile", getTestProfile().Name)
-		r.URL.RawQuery = q.Encode()
-		rr := httptest.NewRecorder()
+		rr = retrieve(t, op, "0")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), "Example University")
 
-		op.retrieveCredentialHandler(rr, r)
-		errResp := &model.ErrorResponse{}
-		err = json.Unmarshal(rr.Body.Bytes(), &errResp)
-		require.NoError(t, err)
+		rr = retrieve(t, op, "1")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Contains(t, rr.Body.String(), "Updated University")
 
-		require.Equal(t, "invalid character 'n' looking for beginning of object key string", errResp.Message)
+		rr = retrieve(t, op, "2")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "was found with the given id and version")
+
+		rr = retrieve(t, op, "latest")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid version: latest")
+	})
+
+	t.Run("test error - failed to read the stored copies", func(t *testing.T) {
+		op := newOperation(t, NewMockEDVClient("test"), DuplicateCredentialsVersion)
+		op.credentialStorage = CredentialStorageEDV
+
+		rr := store(t, op, storeReq.Credential)
 		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to read the stored copies of the VC")
+
+		rr = retrieve(t, op, "")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to read documents while retrieving versioned VC")
 	})
 }
 
@@ -1227,46 +3719,85 @@ func TestVCStatus(t *testing.T) {
 
 		vcStatusHandler.Handle().ServeHTTP(rr, req)
 		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
 
 		var csl cslstatus.CSL
 		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &csl))
 		require.Equal(t, "https://example.gov/status/24", csl.ID)
+
+		etag := rr.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		req.Header.Set("If-None-Match", `"other", `+etag)
+		rr = httptest.NewRecorder()
+
+		vcStatusHandler.Handle().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusNotModified, rr.Code)
+		require.Empty(t, rr.Body.Bytes())
+
+		req.Header.Set("If-None-Match", `"other"`)
+		rr = httptest.NewRecorder()
+
+		vcStatusHandler.Handle().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
 	})
-}
 
-func TestOperation_validateProfileRequest(t *testing.T) {
-	t.Run("valid profile ", func(t *testing.T) {
-		profile := getProfileRequest()
-		err := validateProfileRequest(profile)
+	t.Run("test cached csl", func(t *testing.T) {
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
 		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "localhost:8080",
+			CSLCacheTTL:        time.Minute})
+		require.NoError(t, err)
+
+		op.vcStatusManager = &mockVCStatusManager{getCSLValue: &cslstatus.CSL{ID: "https://example.gov/status/1"}}
+
+		req, err := http.NewRequest(http.MethodGet, credentialStatus+"/1", nil)
+		require.NoError(t, err)
+		req.Header.Set("If-None-Match", "*")
+
+		rr := httptest.NewRecorder()
+
+		getHandler(t, op, credentialStatusEndpoint, http.MethodGet).Handle().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusNotModified, rr.Code)
+		require.Equal(t, "public, max-age=60", rr.Header().Get("Cache-Control"))
 	})
-	t.Run("missing profile name", func(t *testing.T) {
-		profile := getProfileRequest()
-		profile.Name = ""
-		err := validateProfileRequest(profile)
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "missing profile name")
-	})
-	t.Run("missing URI ", func(t *testing.T) {
-		profile := getProfileRequest()
-		profile.URI = ""
-		err := validateProfileRequest(profile)
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "missing URI information")
-	})
-	t.Run("missing signature type ", func(t *testing.T) {
-		profile := getProfileRequest()
-		profile.SignatureType = ""
-		err := validateProfileRequest(profile)
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "missing signature type")
-	})
-	t.Run("parse uri failed", func(t *testing.T) {
-		profile := getProfileRequest()
-		profile.URI = "//not-valid.&&%^)$"
-		err := validateProfileRequest(profile)
-		require.Error(t, err)
-		require.Contains(t, err.Error(), "invalid uri")
+
+	t.Run("test list URL under the external URL", func(t *testing.T) {
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			Crypto:             &cryptomock.Crypto{},
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{},
+			HostURL:            "https://example.com/vcs"})
+		require.NoError(t, err)
+
+		statusManager := &mockVCStatusManager{getCSLValue: &cslstatus.CSL{ID: "https://example.com/vcs/status/p1/1"}}
+		op.vcStatusManager = statusManager
+
+		// the path of the request as forwarded by a reverse proxy
+		req, err := http.NewRequest(http.MethodGet, "/issuer"+credentialStatus+"/p%201/1", nil)
+		require.NoError(t, err)
+
+		req = mux.SetURLVars(req, map[string]string{profileIDPathParam: "p 1", "id": "1"})
+
+		rr := httptest.NewRecorder()
+
+		getHandler(t, op, profileCredentialStatusPath, http.MethodGet).Handle().ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "https://example.com/vcs/status/p%201/1", statusManager.getCSLID)
+
+		_, err = op.GetCredentialStatus("p 1/2")
+		require.NoError(t, err)
+		require.Equal(t, "https://example.com/vcs/status/p%201/2", statusManager.getCSLID)
 	})
 }
 
@@ -1441,6 +3972,71 @@ func TestIssueCredential(t *testing.T) {
 		require.Equal(t, "assertionMethod", proof["proofPurpose"])
 	})
 
+	t.Run("issue credential - jwt credential format", func(t *testing.T) {
+		jwtProfile := getTestProfile()
+		jwtProfile.Name = "jwt"
+		jwtProfile.Creator = issuerProfileDIDKey
+		jwtProfile.CredentialFormat = CredentialFormatJWT
+		jwtProfile.DisableVCStatus = true
+
+		err = op.profileStore.SaveProfile(jwtProfile)
+		require.NoError(t, err)
+
+		reqBytes, err := json.Marshal(&IssueCredentialRequest{Credential: []byte(validVC)})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, map[string]string{profileIDPathParam: jwtProfile.Name})
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		var jwtVC string
+
+		err = json.Unmarshal(rr.Body.Bytes(), &jwtVC)
+		require.NoError(t, err)
+		require.Len(t, strings.Split(jwtVC, "."), 3)
+
+		// a JWT has a single signature
+		reqBytes, err = json.Marshal(&IssueCredentialRequest{Credential: []byte(validVC),
+			Opts: &IssueCredentialOptions{Proofs: []ProofOptions{{}}}})
+		require.NoError(t, err)
+
+		rr = serveHTTPMux(t, handler, endpoint, reqBytes, map[string]string{profileIDPathParam: jwtProfile.Name})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "credential format jwt doesn't support proof sets")
+	})
+
+	t.Run("issue credential - sd-jwt credential format", func(t *testing.T) {
+		sdJWTProfile := getTestProfile()
+		sdJWTProfile.Name = "sd-jwt"
+		sdJWTProfile.Creator = issuerProfileDIDKey
+		sdJWTProfile.CredentialFormat = CredentialFormatSDJWT
+		sdJWTProfile.DisableVCStatus = true
+
+		err = op.profileStore.SaveProfile(sdJWTProfile)
+		require.NoError(t, err)
+
+		reqBytes, err := json.Marshal(&IssueCredentialRequest{Credential: []byte(validMDLCredential),
+			Opts: &IssueCredentialOptions{HolderKeyID: "did:example:ebfeb1f712ebc6f1c276e12ec21#key-1"}})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, map[string]string{profileIDPathParam: sdJWTProfile.Name})
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		var sdJWT string
+
+		err = json.Unmarshal(rr.Body.Bytes(), &sdJWT)
+		require.NoError(t, err)
+
+		credential, err := sdjwt.Parse(sdJWT)
+		require.NoError(t, err)
+		require.Equal(t, []string{"birth_date", "family_name", "given_name"}, credential.Disclosed)
+		require.Equal(t, strings.Split(issuerProfileDIDKey, "#")[0], credential.Issuer)
+
+		// the holder keys only bind the SD-JWTs
+		rr = serveHTTPMux(t, handler, endpoint, reqBytes, map[string]string{profileIDPathParam: "jwt"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "holder keys only supported by the sd-jwt credential format")
+	})
+
 	t.Run("issue credential with opts - success", func(t *testing.T) {
 		customVerificationMethod := "did:test:zzz#" + keyID
 
@@ -1644,7 +4240,119 @@ func TestIssueCredential(t *testing.T) {
 
 		rr = serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
 
-		require.Equal(t, http.StatusCreated, rr.Code)
+		require.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("issue credential - revoke on expiry", func(t *testing.T) {
+		expiringProfile := *profile
+		expiringProfile.Name = "expiring"
+		expiringProfile.RevokeOnExpiry = true
+
+		require.NoError(t, op.profileStore.SaveProfile(&expiringProfile))
+
+		expiryLog := &mockExpiryLog{}
+		op.expiryLog = expiryLog
+
+		reqBytes, err := json.Marshal(&IssueCredentialRequest{Credential: []byte(validVC)})
+		require.NoError(t, err)
+
+		vars := map[string]string{profileIDPathParam: expiringProfile.Name}
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, vars)
+		require.Equal(t, http.StatusCreated, rr.Code)
+		require.Equal(t, []string{expiringProfile.Name}, expiryLog.profiles)
+
+		expiryLog.err = errors.New("log error")
+
+		rr = serveHTTPMux(t, handler, endpoint, reqBytes, vars)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to log credential for revocation on expiry: log error")
+	})
+
+	t.Run("issue credential - proof set", func(t *testing.T) {
+		reqBytes, err := json.Marshal(&IssueCredentialRequest{
+			Credential: []byte(validVC),
+			Opts: &IssueCredentialOptions{
+				Proofs: []ProofOptions{{SignatureType: vccrypto.JSONWebSignature2020}},
+			},
+		})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		signedVCResp := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &signedVCResp))
+
+		proofs, ok := signedVCResp["proof"].([]interface{})
+		require.True(t, ok)
+		require.Len(t, proofs, 2)
+		require.Equal(t, vccrypto.JSONWebSignature2020, proofs[1].(map[string]interface{})["type"])
+	})
+
+	t.Run("issue credential - policy violation", func(t *testing.T) {
+		policyProfile := *profile
+		policyProfile.Name = "policy"
+		policyProfile.Policy = []policy.Rule{{Type: policy.SubjectDID},
+			{Type: policy.RequiredTypes, Values: []string{"UniversityDegreeCredential"}}}
+
+		require.NoError(t, op.profileStore.SaveProfile(&policyProfile))
+
+		reqBytes, err := json.Marshal(&IssueCredentialRequest{Credential: []byte(validVC)})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, map[string]string{profileIDPathParam: "policy"})
+		require.Equal(t, http.StatusForbidden, rr.Code)
+
+		errResp := &commhttp.ErrorResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), errResp))
+		require.Equal(t, commhttp.PolicyViolation, errResp.Code)
+		require.Equal(t, []policy.Violation{{Rule: policy.RequiredTypes,
+			Message: "missing credential types: UniversityDegreeCredential"}}, errResp.Violations)
+	})
+
+	t.Run("issue credential - quota exceeded", func(t *testing.T) {
+		quotaProfile := *profile
+		quotaProfile.Name = "quota"
+		quotaProfile.Quota = &quota.Quota{Daily: 1, Monthly: 10}
+
+		require.NoError(t, op.profileStore.SaveProfile(&quotaProfile))
+
+		_, err := op.quotas.Reserve(quotaProfile.Name, quotaProfile.Quota)
+		require.NoError(t, err)
+
+		reqBytes, err := json.Marshal(&IssueCredentialRequest{Credential: []byte(validVC)})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, map[string]string{profileIDPathParam: "quota"})
+		require.Equal(t, http.StatusTooManyRequests, rr.Code)
+
+		errResp := &commhttp.ErrorResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), errResp))
+		require.Equal(t, commhttp.QuotaExceeded, errResp.Code)
+		require.Contains(t, errResp.Message, "daily issuance quota of 1 credentials exceeded until")
+	})
+
+	t.Run("issue credential - invalid proof signing key", func(t *testing.T) {
+		multiKeyProfile := *profile
+		multiKeyProfile.Name = "multikey"
+		multiKeyProfile.SigningKeys = []vcprofile.SigningKey{
+			{ID: multiKeyProfile.DID + "#key-2", SignatureType: vccrypto.JSONWebSignature2020}}
+
+		require.NoError(t, op.profileStore.SaveProfile(&multiKeyProfile))
+
+		reqBytes, err := json.Marshal(&IssueCredentialRequest{
+			Credential: []byte(validVC),
+			Opts: &IssueCredentialOptions{
+				Proofs: []ProofOptions{{}, {VerificationMethod: multiKeyProfile.DID + "#key-3"}},
+			},
+		})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, map[string]string{profileIDPathParam: "multikey"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "proof 1: verification method "+multiKeyProfile.DID+
+			"#key-3 is not a signing key of the profile")
 	})
 
 	t.Run("issue credential - DID not resolvable", func(t *testing.T) {
@@ -1684,7 +4392,7 @@ func TestIssueCredential(t *testing.T) {
 		_, signingKey, err := closeableKMS.CreateKeySet()
 		require.NoError(t, err)
 
-		didDoc := createDIDDoc("did:test:hd9712akdsaishda7", base58.Decode(signingKey))
+		didDoc := createDIDDoc("did:test:abc", base58.Decode(signingKey))
 
 		op, err := New(&Config{
 			Crypto:             &cryptomock.Crypto{},
@@ -1750,12 +4458,54 @@ func TestIssueCredential(t *testing.T) {
 		require.Contains(t, rr.Body.String(), "invalid assertion method : [did:test:urosdjwas7823y]")
 	})
 
+	t.Run("issue credential - verification method not listed under the proof purpose", func(t *testing.T) {
+		req := &IssueCredentialRequest{
+			Credential: []byte(validVC),
+			Opts:       &IssueCredentialOptions{VerificationMethod: "did:test:abc#key-2"},
+		}
+
+		reqBytes, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid proof purpose for the signing key")
+		require.Contains(t, rr.Body.String(), "did:test:abc#key-2")
+
+		req.Opts = &IssueCredentialOptions{Proofs: []ProofOptions{{VerificationMethod: "did:test:abc#key-2",
+			ProofPurpose: vccrypto.CapabilityInvocation}}}
+
+		reqBytes, err = json.Marshal(req)
+		require.NoError(t, err)
+
+		rr = serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid proof purpose for the signing key of proof 0")
+	})
+
+	t.Run("issue credential - DID resolution error", func(t *testing.T) {
+		ops, err := New(&Config{
+			Crypto:             &cryptomock.Crypto{},
+			StoreProvider:      memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI:               &vdrimock.MockVDRIRegistry{ResolveErr: errors.New("resolve error")},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, ops.profileStore.SaveProfile(profile))
+
+		_, err = ops.IssueCredential(profile.Name, &IssueCredentialRequest{Credential: []byte(validVC)})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to validate the signing key: failed to resolve DID did:test:abc")
+	})
+
 	t.Run("issue credential - signing error", func(t *testing.T) {
 		closeableKMS := &mocklegacykms.CloseableKMS{}
 		_, signingKey, err := closeableKMS.CreateKeySet()
 		require.NoError(t, err)
 
-		didDoc := createDIDDoc("did:test:hd9712akdsaishda7", base58.Decode(signingKey))
+		didDoc := createDIDDoc("did:test:urosdjwas7823y", base58.Decode(signingKey))
 
 		op, err := New(&Config{
 			Crypto:             &cryptomock.Crypto{SignErr: fmt.Errorf("failed to sign credential")},
@@ -1769,6 +4519,9 @@ func TestIssueCredential(t *testing.T) {
 		err = op.profileStore.SaveProfile(profile)
 		require.NoError(t, err)
 
+		// the status list would be signed first by the profile
+		op.vcStatusManager = &mockVCStatusManager{}
+
 		issueCredentialHandler := getHandler(t, op, issueCredentialPath, http.MethodPost)
 
 		req := &IssueCredentialRequest{
@@ -1783,6 +4536,124 @@ func TestIssueCredential(t *testing.T) {
 
 		require.Equal(t, http.StatusInternalServerError, rr.Code)
 		require.Contains(t, rr.Body.String(), "failed to sign credential")
+
+		// the credential that failed to be issued isn't counted
+		usage, err := op.quotas.Usage(profile.Name, nil)
+		require.NoError(t, err)
+		require.Zero(t, usage.Daily.Issued)
+	})
+}
+
+func TestEndorseCredential(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		StoreProvider:      memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		Crypto:             &cryptomock.Crypto{},
+		VDRI: &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, opts ...vdri.ResolveOpts) (*did.Doc, error) {
+				return createDIDDocWithKeyID(didID, "key1", pubKey), nil
+			}},
+	})
+	require.NoError(t, err)
+
+	profile := getTestProfile()
+	profile.SigningKeys = []vcprofile.SigningKey{{ID: "did:test:abc#key2", SignatureType: vccrypto.JSONWebSignature2020}}
+	require.NoError(t, op.profileStore.SaveProfile(profile))
+
+	handler := getHandler(t, op, endorseCredentialPath, http.MethodPost)
+	urlVars := map[string]string{profileIDPathParam: profile.Name}
+
+	endorse := func(t *testing.T, endorseReq *EndorseCredentialRequest) *httptest.ResponseRecorder {
+		t.Helper()
+
+		reqBytes, err := json.Marshal(endorseReq)
+		require.NoError(t, err)
+
+		return serveHTTPMux(t, handler, endorseCredentialPath, reqBytes, urlVars)
+	}
+
+	t.Run("endorse credential - success", func(t *testing.T) {
+		vc, err := verifiable.ParseUnverifiedCredential([]byte(validVC))
+		require.NoError(t, err)
+
+		vc.Issuer.ID = "did:example:issuer"
+
+		err = vc.AddLinkedDataProof(&verifiable.LinkedDataProofContext{
+			SignatureType: vccrypto.Ed25519Signature2018,
+			Suite: ed25519signature2018.New(
+				suite.WithSigner(signature.GetEd25519Signer(privKey, pubKey))),
+			SignatureRepresentation: verifiable.SignatureJWS,
+			VerificationMethod:      "did:example:issuer#key1",
+		})
+		require.NoError(t, err)
+
+		vcBytes, err := vc.MarshalJSON()
+		require.NoError(t, err)
+
+		rr := endorse(t, &EndorseCredentialRequest{Credential: vcBytes})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		endorsedVC, err := verifiable.ParseUnverifiedCredential(rr.Body.Bytes())
+		require.NoError(t, err)
+		require.Len(t, endorsedVC.Proofs, 2)
+		require.Equal(t, "did:example:issuer", endorsedVC.Issuer.ID)
+		require.Equal(t, profile.Creator, endorsedVC.Proofs[1]["verificationMethod"])
+
+		// the JsonWebSignature2020 context can't be added to the signed credential
+		rr = endorse(t, &EndorseCredentialRequest{Credential: vcBytes,
+			Opts: &IssueCredentialOptions{VerificationMethod: "did:test:abc#key2"}})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "the credential is missing the context of signature type "+
+			vccrypto.JSONWebSignature2020)
+	})
+
+	t.Run("endorse credential - credential without proof", func(t *testing.T) {
+		rr := endorse(t, &EndorseCredentialRequest{Credential: []byte(validVC)})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "the credential has no proof to endorse")
+	})
+
+	t.Run("endorse credential - invalid credential", func(t *testing.T) {
+		rr := endorse(t, &EndorseCredentialRequest{Credential: []byte(invalidVC)})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to verify credential")
+	})
+
+	t.Run("endorse credential - invalid signing key", func(t *testing.T) {
+		rr := endorse(t, &EndorseCredentialRequest{Credential: []byte(validVC),
+			Opts: &IssueCredentialOptions{VerificationMethod: "did:test:abc#key3"}})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "is not a signing key of the profile")
+
+		rr = endorse(t, &EndorseCredentialRequest{Credential: []byte(validVC),
+			Opts: &IssueCredentialOptions{Proofs: []ProofOptions{{VerificationMethod: "did:test:abc#key3"}}}})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "proof 0: verification method did:test:abc#key3")
+	})
+
+	t.Run("endorse credential - invalid request", func(t *testing.T) {
+		rr := endorse(t, &EndorseCredentialRequest{Opts: &IssueCredentialOptions{ProofPurpose: "other"}})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "missing credential")
+		require.Contains(t, rr.Body.String(), "invalid proof option : other")
+
+		rr = serveHTTPMux(t, handler, endorseCredentialPath, []byte("{"), urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), invalidRequestErrMsg)
+	})
+
+	t.Run("endorse credential - profile not found", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, endorseCredentialPath, []byte("{}"),
+			map[string]string{profileIDPathParam: "unknown"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid issuer profile")
 	})
 }
 
@@ -1830,8 +4701,11 @@ func TestComposeAndIssueCredential(t *testing.T) {
 		StoreProvider:      memstore.NewProvider(),
 		KMSSecretsProvider: mem.NewProvider(),
 		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
-		VDRI:               &vdrimock.MockVDRIRegistry{},
-		Crypto:             &cryptomock.Crypto{SignErr: fmt.Errorf("failed to sign credential")},
+		VDRI: &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, opts ...vdri.ResolveOpts) (*did.Doc, error) {
+				return createDIDDocWithKeyID(didID, key1ID, nil), nil
+			}},
+		Crypto: &cryptomock.Crypto{SignErr: fmt.Errorf("failed to sign credential")},
 	})
 	require.NoError(t, err)
 
@@ -2022,6 +4896,10 @@ func TestComposeAndIssueCredential(t *testing.T) {
 			StoreProvider:      memstore.NewProvider(),
 			KMSSecretsProvider: mem.NewProvider(),
 			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI: &vdrimock.MockVDRIRegistry{
+				ResolveFunc: func(didID string, opts ...vdri.ResolveOpts) (*did.Doc, error) {
+					return createDIDDocWithKeyID(didID, key1ID, nil), nil
+				}},
 		})
 		require.NoError(t, err)
 
@@ -2044,7 +4922,26 @@ func TestComposeAndIssueCredential(t *testing.T) {
 		require.Contains(t, rr.Body.String(), "failed to add credential status: csl error")
 	})
 
+	t.Run("compose and issue credential - attachment storage error", func(t *testing.T) {
+		// the EDV isn't configured to store the attachments
+		req := &ComposeCredentialRequest{Attachments: []EvidenceAttachment{{Data: "AQI="}}}
+
+		reqBytes, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
+
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to store the attachment at index 0")
+	})
+
 	t.Run("compose and issue credential - signing failure", func(t *testing.T) {
+		// the status list would be signed first by the profile
+		statusManager := op.vcStatusManager
+		op.vcStatusManager = &mockVCStatusManager{}
+
+		defer func() { op.vcStatusManager = statusManager }()
+
 		req := &ComposeCredentialRequest{}
 
 		reqBytes, err := json.Marshal(req)
@@ -2057,7 +4954,72 @@ func TestComposeAndIssueCredential(t *testing.T) {
 		require.Contains(t, rr.Body.String(), "failed to sign credential")
 	})
 
-	t.Run("compose and issue credential - build credential error (termsOfUse)", func(t *testing.T) {
+	t.Run("compose and issue credential - claims from claims source", func(t *testing.T) {
+		pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		claimsSource := &mockClaimsSource{claims: map[string]interface{}{
+			"name": "Jane Doe", "department": "Engineering"}}
+
+		op, err := New(&Config{
+			StoreProvider:      memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+			VDRI: &vdrimock.MockVDRIRegistry{ResolveFunc: func(didID string, opts ...vdri.ResolveOpts) (doc *did.Doc, e error) {
+				return createDIDDocWithKeyID(didID, key1ID, pubKey), nil
+			}},
+			Crypto:       &cryptomock.Crypto{},
+			ClaimsSource: claimsSource,
+		})
+		require.NoError(t, err)
+
+		err = op.profileStore.SaveProfile(profile)
+		require.NoError(t, err)
+
+		restHandler := getHandler(t, op, composeAndIssueCredentialPath, http.MethodPost)
+
+		claimJSON, err := json.Marshal(claim)
+		require.NoError(t, err)
+
+		reqBytes, err := json.Marshal(&ComposeCredentialRequest{Subject: subject, Claims: claimJSON})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, restHandler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusCreated, rr.Code)
+		require.Equal(t, subject, claimsSource.subjectID)
+
+		vcResp, err := verifiable.ParseUnverifiedCredential(rr.Body.Bytes())
+		require.NoError(t, err)
+
+		// request claims take precedence over the fetched claims
+		credSubject, ok := vcResp.Subject.(map[string]interface{})
+		require.True(t, ok)
+		require.Equal(t, subject, credSubject["id"])
+		require.Equal(t, name, credSubject["name"])
+		require.Equal(t, customFieldVal, credSubject[customField])
+		require.Equal(t, "Engineering", credSubject["department"])
+
+		// invalid request claims
+		reqBytes, err = json.Marshal(&ComposeCredentialRequest{Subject: subject,
+			Claims: []byte(`"invalid"`)})
+		require.NoError(t, err)
+
+		rr = serveHTTPMux(t, restHandler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "claims must be a JSON object")
+
+		// claims source error
+		claimsSource.err = errors.New("claims api error")
+
+		reqBytes, err = json.Marshal(&ComposeCredentialRequest{Subject: subject})
+		require.NoError(t, err)
+
+		rr = serveHTTPMux(t, restHandler, endpoint, reqBytes, urlVars)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to fetch claims: claims api error")
+	})
+
+	t.Run("compose and issue credential - invalid termsOfUse", func(t *testing.T) {
 		req := `{
 			"termsOfUse":"should be object or array"
 		}`
@@ -2066,10 +5028,10 @@ func TestComposeAndIssueCredential(t *testing.T) {
 		rr := serveHTTPMux(t, handler, endpoint, []byte(req), urlVars)
 
 		require.Equal(t, http.StatusBadRequest, rr.Code)
-		require.Contains(t, rr.Body.String(), "failed to build credential")
+		require.Contains(t, rr.Body.String(), `{"field":"/termsOfUse","message":"invalid terms of use`)
 	})
 
-	t.Run("compose and issue credential - build credential error (claims)", func(t *testing.T) {
+	t.Run("compose and issue credential - invalid claims", func(t *testing.T) {
 		req := `{
 			"claims":"invalid"
 		}`
@@ -2078,10 +5040,10 @@ func TestComposeAndIssueCredential(t *testing.T) {
 		rr := serveHTTPMux(t, handler, endpoint, []byte(req), urlVars)
 
 		require.Equal(t, http.StatusBadRequest, rr.Code)
-		require.Contains(t, rr.Body.String(), "failed to build credential")
+		require.Contains(t, rr.Body.String(), `{"field":"/claims","message":"claims must be a JSON object"`)
 	})
 
-	t.Run("compose and issue credential - build credential error (evidence)", func(t *testing.T) {
+	t.Run("compose and issue credential - invalid evidence", func(t *testing.T) {
 		req := `{
 			"evidence":"invalid"
 		}`
@@ -2090,7 +5052,7 @@ func TestComposeAndIssueCredential(t *testing.T) {
 		rr := serveHTTPMux(t, handler, endpoint, []byte(req), urlVars)
 
 		require.Equal(t, http.StatusBadRequest, rr.Code)
-		require.Contains(t, rr.Body.String(), "failed to build credential")
+		require.Contains(t, rr.Body.String(), `{"field":"/evidence","message":"evidence must be a JSON object"`)
 	})
 
 	t.Run("compose and issue credential - invalid proof format option", func(t *testing.T) {
@@ -2128,11 +5090,12 @@ func TestComposeAndIssueCredential(t *testing.T) {
 		// invoke the endpoint
 		rr := serveHTTPMux(t, handler1, endpoint, reqBytes, urlVars)
 
-		require.Equal(t, http.StatusInternalServerError, rr.Code)
-		require.Contains(t, rr.Body.String(), "invalid proof format : invalid-proof-format-value")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), `{"field":"/proofFormat","message":"invalid proof format : `+
+			`invalid-proof-format-value"}`)
 	})
 
-	t.Run("compose and issue credential - get signing DID error - invalid kid type", func(t *testing.T) {
+	t.Run("compose and issue credential - invalid proof format options", func(t *testing.T) {
 		proofFormatOptions := 33
 
 		proofFormatOptionsJSON, err := json.Marshal(proofFormatOptions)
@@ -2149,10 +5112,10 @@ func TestComposeAndIssueCredential(t *testing.T) {
 		rr := serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
 
 		require.Equal(t, http.StatusBadRequest, rr.Code)
-		require.Contains(t, rr.Body.String(), "failed to prepare signing options")
+		require.Contains(t, rr.Body.String(), `{"field":"/proofFormatOptions","message":"invalid proof format options`)
 	})
 
-	t.Run("compose and issue credential - get signing DID error - invalid kid type", func(t *testing.T) {
+	t.Run("compose and issue credential - invalid proof format options", func(t *testing.T) {
 		proofFormatOptions := make(map[string]interface{})
 		proofFormatOptions[keyID] = 23
 
@@ -2170,7 +5133,87 @@ func TestComposeAndIssueCredential(t *testing.T) {
 		rr := serveHTTPMux(t, handler, endpoint, reqBytes, urlVars)
 
 		require.Equal(t, http.StatusBadRequest, rr.Code)
-		require.Contains(t, rr.Body.String(), "failed to prepare signing options: failed to prepare signing opts:")
+		require.Contains(t, rr.Body.String(), `{"field":"/proofFormatOptions","message":"invalid proof format options: `+
+			`json: cannot unmarshal number into Go struct field .kid of type string"}`)
+	})
+
+	t.Run("compose and issue credential - policy violation", func(t *testing.T) {
+		policyProfile := *profile
+		policyProfile.Name = "policy"
+		policyProfile.Policy = []policy.Rule{{Type: policy.SubjectDID},
+			{Type: policy.RequiredClaims, Values: []string{"name"}}}
+
+		require.NoError(t, op.profileStore.SaveProfile(&policyProfile))
+
+		reqBytes, err := json.Marshal(&ComposeCredentialRequest{Subject: "urn:uuid:1", Types: types})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, map[string]string{profileIDPathParam: "policy"})
+		require.Equal(t, http.StatusForbidden, rr.Code)
+		require.Contains(t, rr.Body.String(), `"violations":[{"rule":"subjectDID","message":"subject id is not `+
+			`a did: urn:uuid:1"},{"rule":"requiredClaims","message":"missing subject claims: name"}]`)
+	})
+
+	t.Run("compose and issue credential - invalid signing key", func(t *testing.T) {
+		multiKeyProfile := *profile
+		multiKeyProfile.Name = "multikey"
+		multiKeyProfile.SigningKeys = []vcprofile.SigningKey{
+			{ID: multiKeyProfile.DID + "#key-2", SignatureType: vccrypto.JSONWebSignature2020}}
+
+		require.NoError(t, op.profileStore.SaveProfile(&multiKeyProfile))
+
+		reqBytes, err := json.Marshal(&ComposeCredentialRequest{
+			ProofFormatOptions: []byte(`{"kid":"` + multiKeyProfile.DID + `#key-3"}`),
+		})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, reqBytes, map[string]string{profileIDPathParam: "multikey"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "verification method "+multiKeyProfile.DID+
+			"#key-3 is not a signing key of the profile")
+	})
+}
+
+func TestMergeSourceClaims(t *testing.T) {
+	claimsSource := &mockClaimsSource{claims: map[string]interface{}{"name": "Jane Doe", "department": "Engineering"}}
+	op := &Operation{claimsSource: claimsSource}
+
+	t.Run("merge claims", func(t *testing.T) {
+		req := &ComposeCredentialRequest{Subject: "did:example:123", Claims: []byte(`{"name":"John Doe"}`)}
+
+		require.NoError(t, op.mergeSourceClaims(req))
+		require.Equal(t, "did:example:123", claimsSource.subjectID)
+		require.JSONEq(t, `{"name":"John Doe","department":"Engineering"}`, string(req.Claims))
+	})
+
+	t.Run("no subject", func(t *testing.T) {
+		req := &ComposeCredentialRequest{Claims: []byte(`{"name":"John Doe"}`)}
+
+		require.NoError(t, op.mergeSourceClaims(req))
+		require.JSONEq(t, `{"name":"John Doe"}`, string(req.Claims))
+	})
+
+	t.Run("error - invalid request claims", func(t *testing.T) {
+		err := op.mergeSourceClaims(&ComposeCredentialRequest{Subject: "did:example:123", Claims: []byte(`"invalid"`)})
+
+		var opErr *commhttp.Error
+
+		require.True(t, errors.As(err, &opErr))
+		require.Equal(t, http.StatusBadRequest, opErr.StatusCode())
+		require.Equal(t, string(commhttp.InvalidRequest), opErr.ErrorCode())
+		require.Contains(t, err.Error(), "invalid claims")
+	})
+
+	t.Run("error - claims source error", func(t *testing.T) {
+		failingOp := &Operation{claimsSource: &mockClaimsSource{err: errors.New("claims api error")}}
+
+		err := failingOp.mergeSourceClaims(&ComposeCredentialRequest{Subject: "did:example:123"})
+
+		var opErr *commhttp.Error
+
+		require.True(t, errors.As(err, &opErr))
+		require.Equal(t, http.StatusInternalServerError, opErr.StatusCode())
+		require.EqualError(t, err, "failed to fetch claims: claims api error")
 	})
 }
 
@@ -2223,30 +5266,143 @@ func TestGetComposeSigningOpts(t *testing.T) {
 
 		t.Parallel()
 
-		for _, test := range tests {
-			tc := test
-			t.Run(tc.name, func(t *testing.T) {
-				opts, err := getComposeSigningOpts(&ComposeCredentialRequest{
-					ProofFormatOptions: json.RawMessage([]byte(tc.ProofFormatOptions)),
-					ProofFormat:        tc.ProofFormat,
-				})
+		for _, test := range tests {
+			tc := test
+			t.Run(tc.name, func(t *testing.T) {
+				opts, err := getComposeSigningOpts(&ComposeCredentialRequest{
+					ProofFormatOptions: json.RawMessage([]byte(tc.ProofFormatOptions)),
+					ProofFormat:        tc.ProofFormat,
+				})
+
+				if tc.err != "" {
+					require.Error(t, err)
+					require.Contains(t, err.Error(), tc.err)
+					return
+				}
+
+				require.NoError(t, err)
+				require.NotEmpty(t, opts)
+			})
+		}
+	})
+}
+
+func TestGetComposeSigningProfile(t *testing.T) {
+	profile := &vcprofile.DataProfile{Name: "issuer", DID: "did:test:123",
+		Creator: "did:test:123#key1", SignatureType: vccrypto.Ed25519Signature2018,
+		SigningKeys: []vcprofile.SigningKey{{ID: "did:test:123#key2", SignatureType: vccrypto.JSONWebSignature2020}}}
+
+	t.Run("default signing key", func(t *testing.T) {
+		signingProfile, opts, err := getComposeSigningProfile(profile, &ComposeCredentialRequest{})
+		require.NoError(t, err)
+		require.Equal(t, profile, signingProfile)
+		require.NotEmpty(t, opts)
+	})
+
+	t.Run("signing key of the proof format options", func(t *testing.T) {
+		signingProfile, opts, err := getComposeSigningProfile(profile, &ComposeCredentialRequest{
+			ProofFormatOptions: []byte(`{"kid":"did:test:123#key2"}`),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "did:test:123#key2", signingProfile.Creator)
+		require.Equal(t, vccrypto.JSONWebSignature2020, signingProfile.SignatureType)
+		require.NotEmpty(t, opts)
+	})
+
+	t.Run("error - invalid signing key", func(t *testing.T) {
+		_, _, err := getComposeSigningProfile(profile, &ComposeCredentialRequest{
+			ProofFormatOptions: []byte(`{"kid":"did:test:456#key2"}`),
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "does not belong to profile did did:test:123")
+	})
+
+	t.Run("error - invalid proof format options", func(t *testing.T) {
+		_, _, err := getComposeSigningProfile(profile, &ComposeCredentialRequest{
+			ProofFormatOptions: []byte(`{"kid":1}`),
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to prepare signing opts")
+	})
+}
+
+func TestGenerateKeypair(t *testing.T) {
+	t.Run("generate key pair - success", func(t *testing.T) {
+		pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+		require.NoError(t, err)
+
+		op, err := New(&Config{
+			Crypto:             &cryptomock.Crypto{},
+			StoreProvider:      memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			KeyManager: &mockkms.KeyManager{CreateKeyID: "key-1", CreateKeyValue: kh,
+				ExportPubKeyBytesValue: pubKey},
+		})
+		require.NoError(t, err)
+
+		generateKeypairHandler := getHandler(t, op, generateKeypairPath, http.MethodPost)
+
+		rr := serveHTTP(t, generateKeypairHandler.Handle(), http.MethodPost, generateKeypairPath, nil)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		generateKeypairResp := &GenerateKeyPairResponse{}
 
-				if tc.err != "" {
-					require.Error(t, err)
-					require.Contains(t, err.Error(), tc.err)
-					return
-				}
+		err = json.Unmarshal(rr.Body.Bytes(), generateKeypairResp)
+		require.NoError(t, err)
+		require.Equal(t, base58.Encode(pubKey), generateKeypairResp.PublicKey)
+		require.Equal(t, "key-1", generateKeypairResp.KeyID)
+		require.NotNil(t, generateKeypairResp.JWK)
+		require.Equal(t, "key-1", generateKeypairResp.JWK.KeyID)
+	})
 
-				require.NoError(t, err)
-				require.NotEmpty(t, opts)
+	t.Run("generate key pair - success ecdsa key types", func(t *testing.T) {
+		for keyType, curve := range map[string]struct {
+			elliptic.Curve
+			crv string
+		}{
+			vccrypto.P256KeyType:      {Curve: elliptic.P256(), crv: "P-256"},
+			vccrypto.P384KeyType:      {Curve: elliptic.P384(), crv: "P-384"},
+			vccrypto.P521KeyType:      {Curve: elliptic.P521(), crv: "P-521"},
+			vccrypto.Secp256k1KeyType: {Curve: btcec.S256(), crv: "secp256k1"},
+		} {
+			privKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+			require.NoError(t, err)
+
+			kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+			require.NoError(t, err)
+
+			op, err := New(&Config{
+				Crypto:             &cryptomock.Crypto{},
+				StoreProvider:      memstore.NewProvider(),
+				KMSSecretsProvider: mem.NewProvider(),
+				KeyManager: &mockkms.KeyManager{CreateKeyID: "key-1", CreateKeyValue: kh,
+					ExportPubKeyBytesValue: elliptic.Marshal(curve, privKey.X, privKey.Y)},
 			})
+			require.NoError(t, err)
+
+			generateKeypairHandler := getHandler(t, op, generateKeypairPath, http.MethodPost)
+
+			rr := serveHTTP(t, generateKeypairHandler.Handle(), http.MethodPost, generateKeypairPath,
+				[]byte(`{"keyType":"`+keyType+`"}`))
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			generateKeypairResp := &GenerateKeyPairResponse{}
+
+			err = json.Unmarshal(rr.Body.Bytes(), generateKeypairResp)
+			require.NoError(t, err)
+			require.NotEmpty(t, generateKeypairResp.PublicKey)
+			require.NotNil(t, generateKeypairResp.JWK)
+			require.Equal(t, curve.crv, generateKeypairResp.JWK.Crv)
 		}
 	})
-}
 
-func TestGenerateKeypair(t *testing.T) {
-	t.Run("generate key pair - success", func(t *testing.T) {
-		pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	t.Run("generate key pair - success rsa key type", func(t *testing.T) {
+		privKey, err := rsa.GenerateKey(rand.Reader, 2048)
 		require.NoError(t, err)
 
 		kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
@@ -2257,21 +5413,65 @@ func TestGenerateKeypair(t *testing.T) {
 			StoreProvider:      memstore.NewProvider(),
 			KMSSecretsProvider: mem.NewProvider(),
 			KeyManager: &mockkms.KeyManager{CreateKeyID: "key-1", CreateKeyValue: kh,
-				ExportPubKeyBytesValue: pubKey},
+				ExportPubKeyBytesValue: x509.MarshalPKCS1PublicKey(&privKey.PublicKey)},
 		})
 		require.NoError(t, err)
 
-		generateKeypairHandler := getHandler(t, op, generateKeypairPath, http.MethodGet)
+		generateKeypairHandler := getHandler(t, op, generateKeypairPath, http.MethodPost)
 
-		rr := serveHTTP(t, generateKeypairHandler.Handle(), http.MethodGet, generateKeypairPath, nil)
+		rr := serveHTTP(t, generateKeypairHandler.Handle(), http.MethodPost, generateKeypairPath,
+			[]byte(`{"keyType":"RSA"}`))
 
 		require.Equal(t, http.StatusOK, rr.Code)
 
-		generateKeypairResp := make(map[string]interface{})
+		generateKeypairResp := &GenerateKeyPairResponse{}
+
+		err = json.Unmarshal(rr.Body.Bytes(), generateKeypairResp)
+		require.NoError(t, err)
+		require.NotEmpty(t, generateKeypairResp.PublicKey)
+		require.NotNil(t, generateKeypairResp.JWK)
+		require.Equal(t, "RSA", generateKeypairResp.JWK.Kty)
+	})
+
+	t.Run("generate key pair - invalid request", func(t *testing.T) {
+		op, err := New(&Config{
+			Crypto:             &cryptomock.Crypto{},
+			StoreProvider:      memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: getTestKeyHandle(t)},
+		})
+		require.NoError(t, err)
+
+		generateKeypairHandler := getHandler(t, op, generateKeypairPath, http.MethodPost)
+
+		rr := serveHTTP(t, generateKeypairHandler.Handle(), http.MethodPost, generateKeypairPath,
+			[]byte("invalid-json"))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), invalidRequestErrMsg)
+
+		rr = serveHTTP(t, generateKeypairHandler.Handle(), http.MethodPost, generateKeypairPath,
+			[]byte(`{"keyType":"BLS12381G2"}`))
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(),
+			"key type not supported: BLS12381G2 keys are not supported by the kms of the service")
+	})
 
-		err = json.Unmarshal(rr.Body.Bytes(), &generateKeypairResp)
+	t.Run("generate key pair - invalid ecdsa public key", func(t *testing.T) {
+		op, err := New(&Config{
+			Crypto:             &cryptomock.Crypto{},
+			StoreProvider:      memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			KeyManager: &mockkms.KeyManager{CreateKeyValue: getTestKeyHandle(t),
+				ExportPubKeyBytesValue: []byte("invalid")},
+		})
 		require.NoError(t, err)
-		require.NotEmpty(t, generateKeypairResp["publicKey"])
+
+		generateKeypairHandler := getHandler(t, op, generateKeypairPath, http.MethodPost)
+
+		rr := serveHTTP(t, generateKeypairHandler.Handle(), http.MethodPost, generateKeypairPath,
+			[]byte(`{"keyType":"P256"}`))
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to create jwk: invalid ecdsa public key")
 	})
 
 	t.Run("generate key pair - failure", func(t *testing.T) {
@@ -2287,15 +5487,167 @@ func TestGenerateKeypair(t *testing.T) {
 		require.NoError(t, err)
 		op.kms = &mockkms.KeyManager{CreateKeyErr: errors.New("kms - create keyset error")}
 
-		generateKeypairHandler := getHandler(t, op, generateKeypairPath, http.MethodGet)
+		generateKeypairHandler := getHandler(t, op, generateKeypairPath, http.MethodPost)
 
-		rr := serveHTTP(t, generateKeypairHandler.Handle(), http.MethodGet, generateKeypairPath, nil)
+		rr := serveHTTP(t, generateKeypairHandler.Handle(), http.MethodPost, generateKeypairPath, nil)
 
 		require.Equal(t, http.StatusInternalServerError, rr.Code)
 		require.Contains(t, rr.Body.String(), "failed to create key pair")
 	})
 }
 
+func TestDeleteKey(t *testing.T) {
+	t.Run("delete key - success", func(t *testing.T) {
+		kmsSecretsProvider := mem.NewProvider()
+
+		kmsStore, err := kmsSecretsProvider.OpenStore(localkms.Namespace)
+		require.NoError(t, err)
+		require.NoError(t, kmsStore.Put("key-1", []byte("keyset")))
+
+		pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		op, err := New(&Config{
+			Crypto:             &cryptomock.Crypto{},
+			StoreProvider:      memstore.NewProvider(),
+			KMSSecretsProvider: kmsSecretsProvider,
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: getTestKeyHandle(t)},
+		})
+		require.NoError(t, err)
+
+		op.kms = &mockkms.KeyManager{CreateKeyID: "key-1", CreateKeyValue: getTestKeyHandle(t),
+			ExportPubKeyBytesValue: pubKey}
+
+		rr := serveHTTP(t, getHandler(t, op, generateKeypairPath, http.MethodPost).Handle(), http.MethodPost,
+			generateKeypairPath, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = serveHTTPMux(t, getHandler(t, op, deleteKeyPath, http.MethodDelete), kmsBasePath+"/keys/key-1",
+			nil, map[string]string{keyIDPathParam: "key-1"})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		_, err = kmsStore.Get("key-1")
+		require.Error(t, err)
+
+		rr = serveHTTPMux(t, getHandler(t, op, deleteKeyPath, http.MethodDelete), kmsBasePath+"/keys/key-1",
+			nil, map[string]string{keyIDPathParam: "key-1"})
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), "key not found: key-1")
+	})
+
+	t.Run("delete key - key not generated through the api", func(t *testing.T) {
+		op, err := New(&Config{
+			Crypto:             &cryptomock.Crypto{},
+			StoreProvider:      memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: getTestKeyHandle(t)},
+		})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, getHandler(t, op, deleteKeyPath, http.MethodDelete), kmsBasePath+"/keys/key-1",
+			nil, map[string]string{keyIDPathParam: "key-1"})
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), "key not found: key-1")
+	})
+
+	t.Run("delete key - key used by a profile", func(t *testing.T) {
+		op, err := New(&Config{
+			Crypto:             &cryptomock.Crypto{},
+			StoreProvider:      memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: getTestKeyHandle(t)},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, op.putGeneratedKey("key-1", &generatedKey{KeyType: vccrypto.Ed25519KeyType}))
+		require.NoError(t, op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "issuer",
+			Creator: "did:example:123#key-1"}))
+
+		rr := serveHTTPMux(t, getHandler(t, op, deleteKeyPath, http.MethodDelete), kmsBasePath+"/keys/key-1",
+			nil, map[string]string{keyIDPathParam: "key-1"})
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Contains(t, rr.Body.String(), "key key-1 is used by profile issuer/issuer")
+	})
+
+	t.Run("delete key - key used by the encryption of the stored credentials", func(t *testing.T) {
+		op, err := New(&Config{
+			Crypto:             &cryptomock.Crypto{},
+			StoreProvider:      memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			KeyManager:         &mockkms.KeyManager{CreateKeyID: "key-1", CreateKeyValue: getTestKeyHandle(t)},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, op.putGeneratedKey("key-1", &generatedKey{KeyType: vccrypto.Ed25519KeyType}))
+
+		rr := serveHTTPMux(t, getHandler(t, op, deleteKeyPath, http.MethodDelete), kmsBasePath+"/keys/key-1",
+			nil, map[string]string{keyIDPathParam: "key-1"})
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Contains(t, rr.Body.String(), "key key-1 is used by the encryption of the stored credentials")
+	})
+
+	t.Run("delete key - generated keys store error", func(t *testing.T) {
+		op, err := New(&Config{
+			Crypto:             &cryptomock.Crypto{},
+			StoreProvider:      memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: getTestKeyHandle(t)},
+		})
+		require.NoError(t, err)
+
+		op.generatedKeys = &mockstore.MockStore{Store: map[string][]byte{"key-1": []byte("{}")},
+			ErrGet: errors.New("get error")}
+
+		rr := serveHTTPMux(t, getHandler(t, op, deleteKeyPath, http.MethodDelete), kmsBasePath+"/keys/key-1",
+			nil, map[string]string{keyIDPathParam: "key-1"})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to get generated key: get error")
+	})
+
+	t.Run("delete key - key manager delete error", func(t *testing.T) {
+		op, err := New(&Config{
+			Crypto:             &cryptomock.Crypto{},
+			StoreProvider:      memstore.NewProvider(),
+			KMSSecretsProvider: mem.NewProvider(),
+			KeyManager:         &mockkms.KeyManager{CreateKeyValue: getTestKeyHandle(t)},
+		})
+		require.NoError(t, err)
+
+		op.kms = &mockKeyDeleter{deleteErr: errors.New("delete error")}
+
+		require.NoError(t, op.putGeneratedKey("key-1", &generatedKey{KeyType: vccrypto.Ed25519KeyType}))
+
+		rr := serveHTTPMux(t, getHandler(t, op, deleteKeyPath, http.MethodDelete), kmsBasePath+"/keys/key-1",
+			nil, map[string]string{keyIDPathParam: "key-1"})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to delete key: delete error")
+	})
+}
+
+type noQueryEDVClient struct {
+	EDVClient
+}
+
+func (c *noQueryEDVClient) QueryVault(string, *models.Query) ([]string, error) {
+	return nil, nil
+}
+
+type mockKeyDeleter struct {
+	mockkms.KeyManager
+	deleteErr error
+}
+
+func (m *mockKeyDeleter) Delete(keyID string) error {
+	return m.deleteErr
+}
+
+func getTestKeyHandle(t *testing.T) *keyset.Handle {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	return kh
+}
+
 func serveHTTP(t *testing.T, handler http.HandlerFunc, method, path string, req []byte) *httptest.ResponseRecorder {
 	httpReq, err := http.NewRequest(
 		method,
@@ -2450,7 +5802,10 @@ func setMockEDVClientReadDocumentReturnValue(t *testing.T, client *edv.Client, o
 }
 
 func prepareEncryptedDocument(t *testing.T, op *Operation, structuredDoc string) models.EncryptedDocument {
-	jwe, err := op.jweEncrypter.Encrypt([]byte(structuredDoc), nil)
+	jweEncrypter, err := op.profileJWECrypto(getTestProfile().Name)
+	require.NoError(t, err)
+
+	jwe, err := jweEncrypter.Encrypt([]byte(structuredDoc), nil)
 	require.NoError(t, err)
 
 	serializedJWE, err := jwe.FullSerialize(json.Marshal)
@@ -2469,7 +5824,7 @@ type mockResponseWriter struct {
 }
 
 func (b mockResponseWriter) Header() http.Header {
-	panic("implement me")
+	return http.Header{}
 }
 
 func (b mockResponseWriter) Write([]byte) (int, error) {
@@ -2507,15 +5862,62 @@ func (c *TestClient) QueryVault(vaultID string, query *models.Query) ([]string,
 	return []string{"dummyID"}, nil
 }
 
+func (c *TestClient) CreateDocuments(vaultID string, documents []*models.EncryptedDocument) ([]string, error) {
+	return nil, errVaultNotFound
+}
+
+func (c *TestClient) ReadDocuments(vaultID string, docIDs []string) ([]*models.EncryptedDocument, error) {
+	return nil, errDocumentNotFound
+}
+
+func (c *TestClient) DeleteDocument(vaultID, docID string) error {
+	return errDocumentNotFound
+}
+
+type createVaultEDVClient struct {
+	*edv.Client
+	err error
+}
+
+func (c *createVaultEDVClient) CreateDataVault(*models.DataVaultConfiguration) (string, error) {
+	return "", c.err
+}
+
+type failingReadDocumentsEDVClient struct {
+	*edv.Client
+}
+
+func (c *failingReadDocumentsEDVClient) ReadDocuments(string, []string) ([]*models.EncryptedDocument, error) {
+	return nil, errDocumentNotFound
+}
+
+type mockStatusHistory struct {
+	err error
+}
+
+func (m *mockStatusHistory) Record(profile, vcID string, change *history.StatusChange) error {
+	return m.err
+}
+
+func (m *mockStatusHistory) Get(profile, vcID string) ([]history.StatusChange, error) {
+	return nil, m.err
+}
+
 type mockVCStatusManager struct {
 	createStatusIDValue *verifiable.TypedID
 	createStatusIDErr   error
 	updateVCStatusErr   error
 	getCSLValue         *cslstatus.CSL
 	getCSLErr           error
+	getCSLID            string
+	revokeVCErr         error
+	suspendVCErr        error
+	reinstateVCErr      error
+	utilizationErr      error
 }
 
-func (m *mockVCStatusManager) CreateStatusID() (*verifiable.TypedID, error) {
+func (m *mockVCStatusManager) CreateStatusID(profile *vcprofile.DataProfile,
+	vcID string) (*verifiable.TypedID, error) {
 	return m.createStatusIDValue, m.createStatusIDErr
 }
 
@@ -2524,15 +5926,55 @@ func (m *mockVCStatusManager) UpdateVCStatus(v *verifiable.Credential, profile *
 	return m.updateVCStatusErr
 }
 
-func (m *mockVCStatusManager) GetCSL(id string) (*cslstatus.CSL, error) {
-	return m.getCSLValue, m.getCSLErr
+func (m *mockVCStatusManager) GetSignedCSL(id string) ([]byte, error) {
+	m.getCSLID = id
+
+	if m.getCSLErr != nil {
+		return nil, m.getCSLErr
+	}
+
+	return json.Marshal(m.getCSLValue)
+}
+
+func (m *mockVCStatusManager) RevokeVC(v *verifiable.Credential, profile *vcprofile.DataProfile,
+	statusReason string) error {
+	return m.revokeVCErr
+}
+
+func (m *mockVCStatusManager) SuspendVC(v *verifiable.Credential, profile *vcprofile.DataProfile,
+	statusReason string) error {
+	return m.suspendVCErr
+}
+
+func (m *mockVCStatusManager) ReinstateVC(v *verifiable.Credential, profile *vcprofile.DataProfile) error {
+	return m.reinstateVCErr
+}
+
+func (m *mockVCStatusManager) Utilization(profileName string) (*cslstatus.Utilization, error) {
+	return nil, m.utilizationErr
+}
+
+type mockExpiryLog struct {
+	profiles []string
+	err      error
+}
+
+func (m *mockExpiryLog) Record(vc *verifiable.Credential, profile string) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.profiles = append(m.profiles, profile)
+
+	return nil
 }
 
 type mockCredentialStatusManager struct {
 	CreateErr error
 }
 
-func (m *mockCredentialStatusManager) CreateStatusID() (*verifiable.TypedID, error) {
+func (m *mockCredentialStatusManager) CreateStatusID(profile *vcprofile.DataProfile,
+	vcID string) (*verifiable.TypedID, error) {
 	if m.CreateErr != nil {
 		return nil, m.CreateErr
 	}
@@ -2545,6 +5987,154 @@ func (m *mockCredentialStatusManager) UpdateVCStatus(v *verifiable.Credential,
 	return nil
 }
 
-func (m *mockCredentialStatusManager) GetCSL(id string) (*cslstatus.CSL, error) {
+func (m *mockCredentialStatusManager) GetSignedCSL(id string) ([]byte, error) {
+	return nil, nil
+}
+
+func (m *mockCredentialStatusManager) RevokeVC(v *verifiable.Credential, profile *vcprofile.DataProfile,
+	statusReason string) error {
+	return nil
+}
+
+func (m *mockCredentialStatusManager) SuspendVC(v *verifiable.Credential, profile *vcprofile.DataProfile,
+	statusReason string) error {
+	return nil
+}
+
+func (m *mockCredentialStatusManager) ReinstateVC(v *verifiable.Credential,
+	profile *vcprofile.DataProfile) error {
+	return nil
+}
+
+func (m *mockCredentialStatusManager) Utilization(profileName string) (*cslstatus.Utilization, error) {
 	return nil, nil
 }
+
+type mockClaimsSource struct {
+	claims    map[string]interface{}
+	subjectID string
+	err       error
+}
+
+func (m *mockClaimsSource) FetchClaims(subjectID string) (map[string]interface{}, error) {
+	m.subjectID = subjectID
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	claims := make(map[string]interface{})
+
+	for k, v := range m.claims {
+		claims[k] = v
+	}
+
+	return claims, nil
+}
+
+type mockManifestStore struct {
+	err    error
+	putErr error
+}
+
+func (m *mockManifestStore) Put(string, *manifest.CredentialManifest) error {
+	return m.putErr
+}
+
+func (m *mockManifestStore) Get(string, string) (*manifest.CredentialManifest, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return nil, storage.ErrValueNotFound
+}
+
+func (m *mockManifestStore) List(string) ([]manifest.CredentialManifest, error) {
+	return nil, m.err
+}
+
+func (m *mockManifestStore) Delete(string, string) error {
+	return m.err
+}
+
+func testCredential(id string) string {
+	return `{"@context":"https://www.w3.org/2018/credentials/v1","id":"http://example.edu/credentials/` + id +
+		`","type":"VerifiableCredential","credentialSubject":{"id":"did:example:alice"},` +
+		`"issuer":"did:example:76e12ec712ebc6f1c221ebfeb1f","issuanceDate":"2020-01-01T00:00:00Z"}`
+}
+
+// jweKeyManager creates the JWE keys and returns them by ID
+type jweKeyManager struct {
+	mockkms.KeyManager
+	keys    map[string]*keyset.Handle
+	jweKeys int
+}
+
+func newJWEKeyManager() *jweKeyManager {
+	return &jweKeyManager{keys: map[string]*keyset.Handle{}}
+}
+
+func (m *jweKeyManager) Create(kt kms.KeyType) (string, interface{}, error) {
+	if kt == kms.HMACSHA256Tag256Type {
+		kh, err := keyset.NewHandle(mac.HMACSHA256Tag256KeyTemplate())
+		if err != nil {
+			return "", nil, err
+		}
+
+		m.keys["hmac"] = kh
+
+		return "hmac", kh, nil
+	}
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	if err != nil {
+		return "", nil, err
+	}
+
+	m.jweKeys++
+	keyID := "key" + strconv.Itoa(m.jweKeys)
+	m.keys[keyID] = kh
+
+	return keyID, kh, nil
+}
+
+func (m *jweKeyManager) Get(keyID string) (interface{}, error) {
+	kh, ok := m.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found", keyID)
+	}
+
+	return kh, nil
+}
+
+// failingEDVClient fails the requests of the set errors
+type failingEDVClient struct {
+	EDVClient
+	createErr error
+	deleteErr error
+	readErr   error
+}
+
+func (c *failingEDVClient) CreateDocument(vaultID string, document *models.EncryptedDocument) (string, error) {
+	if c.createErr != nil {
+		return "", c.createErr
+	}
+
+	return c.EDVClient.CreateDocument(vaultID, document)
+}
+
+func (c *failingEDVClient) DeleteDocument(vaultID, docID string) error {
+	if c.deleteErr != nil {
+		return c.deleteErr
+	}
+
+	return c.EDVClient.DeleteDocument(vaultID, docID)
+}
+
+func (c *failingEDVClient) ReadDocuments(vaultID string, docIDs []string) ([]*models.EncryptedDocument, error) {
+	if c.readErr != nil {
+		return nil, c.readErr
+	}
+
+	return c.EDVClient.ReadDocuments(vaultID, docIDs)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	commondid "github.com/trustbloc/edge-service/pkg/restapi/internal/common/did"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+// reserveProfileName reserves the name of a profile being created, which fails if a profile of that name exists or
+// is being created. The name is checked before the DID and the vault of the profile are created, as they would
+// otherwise be left behind.
+func (o *Operation) reserveProfileName(name string) error {
+	o.profileCreationMutex.Lock()
+	defer o.profileCreationMutex.Unlock()
+
+	if _, ok := o.creatingProfiles[name]; ok {
+		return commhttp.NewError(http.StatusConflict, commhttp.AlreadyExists,
+			fmt.Sprintf("profile %s is being created", name))
+	}
+
+	profile, err := o.profileStore.GetProfile(name)
+	if err != nil && !errors.Is(err, storage.ErrValueNotFound) {
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to get profile: %s", err.Error()))
+	}
+
+	if profile != nil {
+		return &commhttp.Error{Status: http.StatusConflict, Code: commhttp.AlreadyExists,
+			Message: fmt.Sprintf("profile %s already exists", name),
+			Details: fmt.Sprintf("profile %s of DID %s", o.profileURL(name), profile.DID)}
+	}
+
+	o.creatingProfiles[name] = struct{}{}
+
+	return nil
+}
+
+func (o *Operation) releaseProfileName(name string) {
+	o.profileCreationMutex.Lock()
+	defer o.profileCreationMutex.Unlock()
+
+	delete(o.creatingProfiles, name)
+}
+
+// profileURL returns the URL the profile is retrieved from
+func (o *Operation) profileURL(name string) string {
+	return o.HostURL + "/profile/" + url.PathEscape(name)
+}
+
+// rollbackProfile removes the resources created for a profile which can't be created, so the creation can be
+// retried: the stored profile if saved, the served did:web document and the key of the DID created by the service.
+// The DIDs registered through the Sidetree node or the uni-registrar can't be removed.
+func (o *Operation) rollbackProfile(pr *ProfileRequest, profile *vcprofile.DataProfile, saved bool) {
+	if saved {
+		if err := o.profileStore.DeleteProfile(profile.Name); err != nil {
+			logger.Errorf("failed to delete profile %s: %s", profile.Name, err)
+		}
+	}
+
+	switch {
+	case pr.DIDMethod == commondid.MethodWeb:
+		if err := o.webDIDDocs.Delete(profile.DID); err != nil {
+			logger.Errorf("failed to delete did document of profile %s: %s", profile.Name, err)
+		}
+	case pr.DIDMethod == commondid.MethodKey:
+		// the did:key is derived from its key
+	case pr.DID != "" && pr.UNIRegistrar.DriverURL == "":
+		// the key of an existing DID is only created by the service when its private key is imported
+		if pr.DIDPrivateKey == "" {
+			return
+		}
+	default:
+		logger.Warnf("DID %s of profile %s stays registered", profile.DID, profile.Name)
+	}
+
+	keyIDs, err := profileKeyIDs(profile)
+	if err != nil {
+		logger.Errorf("failed to get keys of profile %s: %s", profile.Name, err)
+
+		return
+	}
+
+	for _, keyID := range keyIDs {
+		if err := o.deleteKey(keyID); err != nil {
+			logger.Errorf("failed to delete key %s of profile %s: %s", keyID, profile.Name, err)
+		}
+	}
+}
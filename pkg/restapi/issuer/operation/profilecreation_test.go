@@ -0,0 +1,198 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/internal/mock/edv"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+	"github.com/trustbloc/edge-service/pkg/vdri/web"
+)
+
+// deletingKeyManager records the deleted keys
+type deletingKeyManager struct {
+	*mockkms.KeyManager
+	deleted []string
+}
+
+func (m *deletingKeyManager) Delete(keyID string) error {
+	m.deleted = append(m.deleted, keyID)
+
+	return nil
+}
+
+// webDIDCommonDID stores the document of the created did:web, as the service does
+type webDIDCommonDID struct {
+	*mockCommonDID
+	docs *web.VDRI
+}
+
+func (m *webDIDCommonDID) CreateWebDID(keyType, signatureType string, path ...string) (string, string, error) {
+	didID, keyID, err := m.mockCommonDID.CreateWebDID(keyType, signatureType, path...)
+	if err != nil {
+		return "", "", err
+	}
+
+	return didID, keyID, m.docs.Store(&did.Doc{Context: []string{did.Context}, ID: didID}, nil)
+}
+
+func TestCreateProfileConflict(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		EDVClient:          edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
+		KeyManager:         &mockkms.KeyManager{CreateKeyValue: kh},
+		VDRI:               &vdrimock.MockVDRIRegistry{},
+		Crypto:             &cryptomock.Crypto{},
+		HostURL:            "https://vcs.example.com/issuer"})
+	require.NoError(t, err)
+
+	op.commonDID = &mockCommonDID{createDIDValue: "did:test:abc", createDIDKeyID: "did:test:abc#key1"}
+
+	reqBytes, err := json.Marshal(getProfileRequest())
+	require.NoError(t, err)
+
+	handler := getHandler(t, op, createProfileEndpoint, http.MethodPost)
+
+	t.Run("test profile name taken", func(t *testing.T) {
+		rr := serveHTTP(t, handler.Handle(), http.MethodPost, createProfileEndpoint, reqBytes)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		// the DID of the second request isn't created
+		op.commonDID = &mockCommonDID{createDIDErr: errors.New("DID created")}
+
+		rr = serveHTTP(t, handler.Handle(), http.MethodPost, createProfileEndpoint, reqBytes)
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Equal(t, "https://vcs.example.com/issuer/profile/issuer", rr.Header().Get("Location"))
+
+		errResp := &commhttp.ErrorResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), errResp))
+		require.Equal(t, commhttp.AlreadyExists, errResp.Code)
+		require.Equal(t, "profile issuer already exists", errResp.Message)
+		require.Equal(t, "profile https://vcs.example.com/issuer/profile/issuer of DID did:test:abc", errResp.Details)
+	})
+
+	t.Run("test profile being created", func(t *testing.T) {
+		require.NoError(t, op.reserveProfileName("reserved"))
+
+		pr := getProfileRequest()
+		pr.Name = "reserved"
+
+		_, err := op.CreateProfile(pr)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "profile reserved is being created")
+
+		op.releaseProfileName("reserved")
+		require.NoError(t, op.reserveProfileName("reserved"))
+	})
+
+	t.Run("test error from profile store", func(t *testing.T) {
+		store := &mockstore.MockStore{Store: make(map[string][]byte)}
+
+		profileStore, err := vcprofile.New(&mockstore.Provider{Store: store})
+		require.NoError(t, err)
+		require.NoError(t, profileStore.SaveProfile(&vcprofile.DataProfile{Name: "other"}))
+
+		store.ErrGet = errors.New("get error")
+		op.profileStore = profileStore
+
+		err = op.reserveProfileName("other")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get profile: get error")
+	})
+}
+
+func TestCreateProfileRollback(t *testing.T) {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	webDIDDocs, err := web.New(memstore.NewProvider())
+	require.NoError(t, err)
+
+	keyManager := &deletingKeyManager{KeyManager: &mockkms.KeyManager{CreateKeyValue: kh}}
+
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		EDVClient:          edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
+		KeyManager:         keyManager,
+		VDRI:               &vdrimock.MockVDRIRegistry{},
+		Crypto:             &cryptomock.Crypto{},
+		HostURL:            "https://vcs.example.com",
+		WebDIDDocs:         webDIDDocs})
+	require.NoError(t, err)
+
+	didID := "did:web:vcs.example.com:issuer:issuer"
+	op.commonDID = &webDIDCommonDID{
+		mockCommonDID: &mockCommonDID{createDIDValue: didID, createDIDKeyID: didID + "#key1"},
+		docs:          webDIDDocs,
+	}
+
+	pr := getProfileRequest()
+	pr.DIDMethod = "web"
+
+	t.Run("test resources removed when the vault can't be created", func(t *testing.T) {
+		op.edvClient = &createVaultEDVClient{Client: edv.NewMockEDVClient("test", nil, nil, nil),
+			err: errors.New("vault error")}
+
+		_, err := op.CreateProfile(pr)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "vault error")
+
+		_, err = op.profileStore.GetProfile(pr.Name)
+		require.Error(t, err)
+
+		_, err = webDIDDocs.Get(didID)
+		require.True(t, errors.Is(err, web.ErrDocumentNotFound))
+
+		require.Equal(t, []string{"key1"}, keyManager.deleted)
+	})
+
+	t.Run("test creation retried", func(t *testing.T) {
+		op.edvClient = edv.NewMockEDVClient("test", nil, nil, nil)
+
+		profile, err := op.CreateProfile(pr)
+		require.NoError(t, err)
+		require.Equal(t, didID, profile.DID)
+
+		_, err = webDIDDocs.Get(didID)
+		require.NoError(t, err)
+	})
+
+	t.Run("test key of an existing DID kept", func(t *testing.T) {
+		keyManager.deleted = nil
+
+		existing := getProfileRequest()
+		existing.DID = "did:test:abc"
+		existing.DIDKeyID = "did:test:abc#key1"
+
+		op.rollbackProfile(existing, getTestProfile(), false)
+		require.Empty(t, keyManager.deleted)
+
+		existing.DIDPrivateKey = "private key"
+
+		op.rollbackProfile(existing, getTestProfile(), false)
+		require.Equal(t, []string{"key1"}, keyManager.deleted)
+	})
+}
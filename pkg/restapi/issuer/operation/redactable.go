@@ -0,0 +1,67 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/redaction"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+// commitCredential replaces the claims of the subject of the credential of the request with their commitment, and
+// returns the claims with their salts. The redactable option of the request is cleared once the credential is
+// committed, the request being issued as any other.
+func commitCredential(cred *IssueCredentialRequest) ([]*redaction.Claim, error) {
+	if len(cred.Credential) == 0 {
+		validationErr := &commhttp.ValidationError{}
+		validationErr.Add("/options/redactable", "redactable credentials must be issued from the credential "+
+			"of the request")
+
+		return nil, commhttp.NewValidationError(validationErr)
+	}
+
+	vc, err := verifiable.ParseUnverifiedCredential(cred.Credential)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("failed to validate credential: %s", err.Error()))
+	}
+
+	claims, err := redaction.Commit(vc)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("failed to commit to the claims of the credential: %s", err.Error()))
+	}
+
+	cred.Credential, err = vc.MarshalJSON()
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to marshal committed credential: %s", err.Error()))
+	}
+
+	opts := *cred.Opts
+	opts.Redactable = false
+	cred.Opts = &opts
+
+	return claims, nil
+}
+
+// writeIssuedCredential writes the issued credential, with the claims it commits to if redactable
+func writeIssuedCredential(rw http.ResponseWriter, credential interface{}, claims []*redaction.Claim) {
+	rw.WriteHeader(http.StatusCreated)
+
+	if claims == nil {
+		commhttp.WriteResponse(rw, credential)
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, &RedactableCredentialResponse{Credential: credential, Claims: claims})
+}
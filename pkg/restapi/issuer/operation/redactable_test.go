@@ -0,0 +1,148 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/redaction"
+)
+
+func TestIssueRedactableCredential(t *testing.T) {
+	keyID := "key-1"
+
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		StoreProvider:      memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		KeyManager:         &mockkms.KeyManager{CreateKeyID: keyID, CreateKeyValue: kh},
+		Crypto:             &cryptomock.Crypto{SignValue: []byte("signature")},
+		VDRI: &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+				return createDIDDocWithKeyID(didID, keyID, pubKey), nil
+			}},
+	})
+	require.NoError(t, err)
+
+	profile := getTestProfile()
+	profile.Name = "jwt"
+	profile.Creator = "did:test:abc#" + keyID
+	profile.CredentialFormat = CredentialFormatJWT
+	profile.DisableVCStatus = true
+	require.NoError(t, op.profileStore.SaveProfile(profile))
+
+	credential := strings.Replace(validVCWithoutStatus, `"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"`,
+		`"id": "did:example:ebfeb1f712ebc6f1c276e12ec21", "name": "Jayden Doe", "birthDate": "1958-07-17"`, 1)
+
+	handler := getHandler(t, op, issueCredentialPath, http.MethodPost)
+
+	issue := func(t *testing.T, req *IssueCredentialRequest) (int, []byte) {
+		reqBytes, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, "/"+profile.Name+"/credentials/issueCredential", reqBytes,
+			map[string]string{profileIDPathParam: profile.Name})
+
+		return rr.Code, rr.Body.Bytes()
+	}
+
+	t.Run("test redactable credential", func(t *testing.T) {
+		code, body := issue(t, &IssueCredentialRequest{Credential: []byte(credential),
+			Opts: &IssueCredentialOptions{Redactable: true}})
+		require.Equal(t, http.StatusCreated, code, string(body))
+
+		resp := struct {
+			Credential json.RawMessage    `json:"credential"`
+			Claims     []*redaction.Claim `json:"claims"`
+		}{}
+		require.NoError(t, json.Unmarshal(body, &resp))
+		require.Len(t, resp.Claims, 2)
+		require.Equal(t, "/credentialSubject/birthDate", resp.Claims[0].Path)
+		require.Equal(t, "/credentialSubject/name", resp.Claims[1].Path)
+
+		var jwtVC string
+		require.NoError(t, json.Unmarshal(resp.Credential, &jwtVC))
+		require.Len(t, strings.Split(jwtVC, "."), 3)
+
+		vc, err := verifiable.ParseUnverifiedCredential([]byte(jwtVC))
+		require.NoError(t, err)
+
+		vcBytes, err := vc.MarshalJSON()
+		require.NoError(t, err)
+		require.NotContains(t, string(vcBytes), "Jayden Doe")
+
+		derived, err := redaction.Derive(resp.Credential, resp.Claims, []string{"/credentialSubject/name"})
+		require.NoError(t, err)
+
+		disclosed, err := redaction.Verify(vc, derived.Disclosures)
+		require.NoError(t, err)
+		require.Equal(t, []*redaction.Claim{resp.Claims[1]}, disclosed)
+	})
+
+	t.Run("test dry run of a redactable credential", func(t *testing.T) {
+		code, body := issue(t, &IssueCredentialRequest{Credential: []byte(credential),
+			Opts: &IssueCredentialOptions{Redactable: true, DryRun: true}})
+		require.Equal(t, http.StatusOK, code, string(body))
+
+		_, vc := parseDryRunResponse(t, body)
+
+		vcBytes, err := vc.MarshalJSON()
+		require.NoError(t, err)
+		require.Contains(t, string(vcBytes), "claimsDigest")
+		require.NotContains(t, string(vcBytes), "Jayden Doe")
+	})
+
+	t.Run("test credential without claims", func(t *testing.T) {
+		code, body := issue(t, &IssueCredentialRequest{Credential: []byte(validVCWithoutStatus),
+			Opts: &IssueCredentialOptions{Redactable: true}})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "failed to commit to the claims of the credential: no claims to commit to")
+	})
+
+	t.Run("test invalid credential", func(t *testing.T) {
+		code, body := issue(t, &IssueCredentialRequest{Credential: []byte(`{"id":"invalid"}`),
+			Opts: &IssueCredentialOptions{Redactable: true}})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "failed to validate credential")
+	})
+
+	t.Run("test credential reference", func(t *testing.T) {
+		code, body := issue(t, &IssueCredentialRequest{CredentialRef: &CredentialReference{ID: "vc1"},
+			Opts: &IssueCredentialOptions{Redactable: true}})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "redactable credentials must be issued from the credential of the request")
+	})
+
+	t.Run("test redactable only supported by issueCredential", func(t *testing.T) {
+		_, err := op.IssueCredential(profile.Name, &IssueCredentialRequest{Credential: []byte(credential),
+			Opts: &IssueCredentialOptions{Redactable: true}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "redactable credentials only supported by issueCredential")
+	})
+}
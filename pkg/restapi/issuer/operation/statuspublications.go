@@ -0,0 +1,136 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/history"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/registry"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+type statusEntries interface {
+	GetStatusEntry(vcID string) (*cslstatus.StatusEntry, error)
+}
+
+// registryStatusHistory publishes the status changes recorded in the status history to the revocation registries,
+// by the status endpoints as well as by the revocation on expiry. As for the events, the status is already changed
+// when it is recorded, so the change is published even if it fails to be recorded.
+type registryStatusHistory struct {
+	statusHistory
+	publisher *registry.Publisher
+	entries   statusEntries
+}
+
+// publishToRegistries returns the status history publishing its status changes to the revocation registries, the
+// status history itself if there is no publisher
+func publishToRegistries(h statusHistory, p *registry.Publisher, entries statusEntries) statusHistory {
+	if p == nil {
+		return h
+	}
+
+	return &registryStatusHistory{statusHistory: h, publisher: p, entries: entries}
+}
+
+func (h *registryStatusHistory) Record(profile, vcID string, change *history.StatusChange) error {
+	err := h.statusHistory.Record(profile, vcID, change)
+
+	statusChange := &registry.StatusChange{
+		Profile:      profile,
+		CredentialID: vcID,
+		Status:       change.Status,
+		StatusReason: change.StatusReason,
+		Time:         change.Time,
+	}
+
+	// the credentials issued before the status lists were indexed are published without their status list
+	if entry, errEntry := h.entries.GetStatusEntry(vcID); errEntry == nil {
+		statusChange.StatusListID = entry.ListID
+	}
+
+	if errPublish := h.publisher.Publish(statusChange); errPublish != nil {
+		logger.Errorf("failed to publish the status change of credential %s to the revocation registries: %s",
+			vcID, errPublish)
+	}
+
+	return err
+}
+
+// StatusPublications swagger:route GET /profile/{id}/statusPublications issuer statusPublicationsReq
+//
+// Reports the publications of the status changes of the credentials of the profile to each revocation registry: the
+// number of publications published, pending and failed, with the failed publications.
+//
+// Responses:
+//    default: genericError
+//        200: statusPublicationsRes
+func (o *Operation) statusPublicationsHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.registryProfile(mux.Vars(req)["id"])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	report, err := o.statusPublisher.Report(profile)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to get status publications: %s", err.Error()))
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, report)
+}
+
+// ReconcileStatusPublications swagger:route POST /profile/{id}/statusPublications/reconcile issuer reconcilePublicationsReq
+//
+// Publishes again the failed publications of the status changes of the credentials of the profile to the revocation
+// registries, with the pending publications left by a stopped instance.
+//
+// Responses:
+//    default: genericError
+//        200: reconcilePublicationsRes
+func (o *Operation) reconcileStatusPublicationsHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.registryProfile(mux.Vars(req)["id"])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	queued, err := o.statusPublisher.Reconcile(profile)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to reconcile status publications: %s", err.Error()))
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, &ReconcileStatusPublicationsResponse{Queued: queued})
+}
+
+// registryProfile returns the name of the profile whose status changes are published, an error if there are no
+// revocation registries
+func (o *Operation) registryProfile(id string) (string, error) {
+	if o.statusPublisher == nil {
+		return "", commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			"no revocation registries are configured")
+	}
+
+	profile, err := o.GetProfile(id)
+	if err != nil {
+		return "", err
+	}
+
+	return profile.Name, nil
+}
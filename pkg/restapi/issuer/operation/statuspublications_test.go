@@ -0,0 +1,199 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/history"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/status/registry"
+	"github.com/trustbloc/edge-service/pkg/internal/mock/edv"
+)
+
+type mockRegistry struct {
+	mutex     sync.Mutex
+	published []*registry.StatusChange
+	err       error
+}
+
+func (r *mockRegistry) Name() string {
+	return "ebsi"
+}
+
+func (r *mockRegistry) Publish(_ context.Context, change *registry.StatusChange) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+
+	r.published = append(r.published, change)
+
+	return nil
+}
+
+type mockStatusEntries struct {
+	entry *cslstatus.StatusEntry
+}
+
+func (e *mockStatusEntries) GetStatusEntry(string) (*cslstatus.StatusEntry, error) {
+	if e.entry == nil {
+		return nil, errors.New("entry not found")
+	}
+
+	return e.entry, nil
+}
+
+func newStatusPublicationsOperation(t *testing.T, publisher *registry.Publisher) *Operation {
+	s := make(map[string][]byte)
+	s["profile_issuer_Example University"] = []byte(testIssuerProfile)
+
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	op, err := New(&Config{StoreProvider: &mockstore.Provider{Store: &mockstore.MockStore{Store: s}},
+		KMSSecretsProvider: mem.NewProvider(), EDVClient: edv.NewMockEDVClient("test", nil, nil, []string{"testID"}),
+		KeyManager: &mockkms.KeyManager{CreateKeyValue: kh}, Crypto: &cryptomock.Crypto{},
+		VDRI: &vdrimock.MockVDRIRegistry{}, HostURL: "localhost:8080", StatusPublisher: publisher})
+	require.NoError(t, err)
+
+	op.vcStatusManager = &mockVCStatusManager{}
+
+	return op
+}
+
+func TestStatusPublications(t *testing.T) {
+	r := &mockRegistry{err: errors.New("registry unavailable")}
+
+	publisher, err := registry.New(memstore.NewProvider(), []registry.Registry{r}, registry.WithRetry(1, 0))
+	require.NoError(t, err)
+
+	op := newStatusPublicationsOperation(t, publisher)
+
+	reqBytes, err := json.Marshal(ChangeCredentialStatusRequest{Credential: validVC, StatusReason: "lost"})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, revokeCredentialEndpoint, bytes.NewBuffer(reqBytes))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	getHandler(t, op, revokeCredentialEndpoint, http.MethodPost).Handle().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	report := func(t *testing.T) *registry.Report {
+		rr := serveHTTPMux(t, getHandler(t, op, statusPublicationsEndpoint, http.MethodGet),
+			"/profile/Example University/statusPublications", nil,
+			map[string]string{"id": "Example University"})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		report := &registry.Report{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), report))
+
+		return report
+	}
+
+	require.Eventually(t, func() bool {
+		return report(t).Registries[0].Failed == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	failed := report(t).Registries[0].FailedPublications[0]
+	require.Equal(t, "registry unavailable", failed.LastError)
+	require.Equal(t, "http://example.edu/credentials/1872", failed.Change.CredentialID)
+	require.Equal(t, cslstatus.StatusRevoked, failed.Change.Status)
+	require.Equal(t, "lost", failed.Change.StatusReason)
+
+	r.mutex.Lock()
+	r.err = nil
+	r.mutex.Unlock()
+
+	rr = serveHTTPMux(t, getHandler(t, op, reconcilePublicationsEndpoint, http.MethodPost),
+		"/profile/Example University/statusPublications/reconcile", nil,
+		map[string]string{"id": "Example University"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.JSONEq(t, `{"queued":1}`, rr.Body.String())
+
+	publisher.Close()
+
+	require.Len(t, r.published, 1)
+	require.Equal(t, 1, report(t).Registries[0].Published)
+
+	t.Run("test error - profile not found", func(t *testing.T) {
+		rr := serveHTTPMux(t, getHandler(t, op, statusPublicationsEndpoint, http.MethodGet),
+			"/profile/unknown/statusPublications", nil, map[string]string{"id": "unknown"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("test error - no revocation registries", func(t *testing.T) {
+		op := newStatusPublicationsOperation(t, nil)
+
+		rr := serveHTTPMux(t, getHandler(t, op, statusPublicationsEndpoint, http.MethodGet),
+			"/profile/Example University/statusPublications", nil,
+			map[string]string{"id": "Example University"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "no revocation registries are configured")
+
+		rr = serveHTTPMux(t, getHandler(t, op, reconcilePublicationsEndpoint, http.MethodPost),
+			"/profile/Example University/statusPublications/reconcile", nil,
+			map[string]string{"id": "Example University"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestPublishToRegistries(t *testing.T) {
+	r := &mockRegistry{}
+
+	publisher, err := registry.New(memstore.NewProvider(), []registry.Registry{r})
+	require.NoError(t, err)
+
+	require.Equal(t, &failingStatusHistory{}, publishToRegistries(&failingStatusHistory{}, nil, nil))
+
+	h := publishToRegistries(&failingStatusHistory{}, publisher,
+		&mockStatusEntries{entry: &cslstatus.StatusEntry{ListID: "localhost:8080/status/issuer/1"}})
+
+	// the status change is published even if it fails to be recorded
+	now := time.Now().UTC()
+
+	err = h.Record("issuer", "http://example.edu/credentials/1", &history.StatusChange{
+		Status: cslstatus.StatusSuspended, StatusReason: "lost", Time: now})
+	require.EqualError(t, err, "record error")
+
+	h.(*registryStatusHistory).entries = &mockStatusEntries{}
+
+	err = h.Record("issuer", "http://example.edu/credentials/2", &history.StatusChange{
+		Status: cslstatus.StatusRevoked, Time: now})
+	require.EqualError(t, err, "record error")
+
+	publisher.Close()
+
+	require.Equal(t, []*registry.StatusChange{
+		{Profile: "issuer", CredentialID: "http://example.edu/credentials/1",
+			StatusListID: "localhost:8080/status/issuer/1", Status: cslstatus.StatusSuspended, StatusReason: "lost",
+			Time: now},
+		{Profile: "issuer", CredentialID: "http://example.edu/credentials/2", Status: cslstatus.StatusRevoked,
+			Time: now},
+	}, r.published)
+}
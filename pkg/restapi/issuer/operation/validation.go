@@ -0,0 +1,433 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+
+	"github.com/btcsuite/btcutil/base58"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/quota"
+	commondid "github.com/trustbloc/edge-service/pkg/restapi/internal/common/did"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+	"github.com/trustbloc/edge-service/pkg/restapi/internal/common/vcutil"
+	"github.com/trustbloc/edge-service/pkg/restapi/model"
+)
+
+// The validators below check all the fields of a request and report every invalid field, identified by its
+// JSON pointer, in a single commhttp.ValidationError.
+
+func validateProfileRequest(pr *ProfileRequest, policyEngine *policy.Engine) error {
+	validationErr := &commhttp.ValidationError{}
+
+	if pr.Name == "" {
+		validationErr.Add("/name", "missing profile name")
+	}
+
+	if pr.URI == "" {
+		validationErr.Add("/uri", "missing URI information")
+	} else if _, err := url.Parse(pr.URI); err != nil {
+		validationErr.Add("/uri", fmt.Sprintf("invalid uri: %s", err.Error()))
+	}
+
+	if pr.SignatureType == "" {
+		validationErr.Add("/signatureType", "missing signature type")
+	}
+
+	validateDIDMethod(validationErr, pr)
+
+	if pr.DIDWebPath != "" && pr.DIDMethod != commondid.MethodWeb {
+		validationErr.Add("/didWebPath", "did web path requires did method web")
+	} else {
+		validateDIDWebPath(validationErr, pr)
+	}
+
+	if pr.CredentialStorage != "" && pr.CredentialStorage != CredentialStorageEDV &&
+		pr.CredentialStorage != CredentialStorageLocal {
+		validationErr.Add("/credentialStorage", fmt.Sprintf("invalid credential storage: %s", pr.CredentialStorage))
+	}
+
+	if pr.RevokeOnExpiry && pr.DisableVCStatus {
+		validationErr.Add("/revokeOnExpiry", "revocation on expiry requires the vc status")
+	}
+
+	for i := range pr.Policy {
+		if err := policyEngine.ValidateRule(&pr.Policy[i]); err != nil {
+			validationErr.Add(fmt.Sprintf("/policy/%d", i), err.Error())
+		}
+	}
+
+	validateQuota(validationErr, pr.Quota)
+	validateCredentialFormat(validationErr, pr)
+
+	return validationErr.ErrorOrNil()
+}
+
+func validateCredentialFormat(validationErr *commhttp.ValidationError, pr *ProfileRequest) {
+	switch pr.CredentialFormat {
+	case "", CredentialFormatLDP:
+	case CredentialFormatJWT:
+		// the JWT credentials are signed with PS256
+		if pr.DIDKeyType != crypto.RSAKeyType {
+			validationErr.Add("/didKeyType", fmt.Sprintf("credential format %s requires %s keys",
+				CredentialFormatJWT, crypto.RSAKeyType))
+		}
+	case CredentialFormatSDJWT:
+	default:
+		validationErr.Add("/credentialFormat", fmt.Sprintf("invalid credential format: %s", pr.CredentialFormat))
+	}
+}
+
+func validateQuota(validationErr *commhttp.ValidationError, q *quota.Quota) {
+	if q == nil {
+		return
+	}
+
+	if q.Daily < 0 {
+		validationErr.Add("/quota/daily", "negative daily quota")
+	}
+
+	if q.Monthly < 0 {
+		validationErr.Add("/quota/monthly", "negative monthly quota")
+	}
+
+	if q.Daily > 0 && q.Monthly > 0 && q.Daily > q.Monthly {
+		validationErr.Add("/quota/daily", "daily quota exceeds the monthly quota")
+	}
+}
+
+func validateDIDMethod(validationErr *commhttp.ValidationError, pr *ProfileRequest) {
+	switch pr.DIDMethod {
+	case "", commondid.MethodTrustBloc:
+		return
+	case commondid.MethodKey, commondid.MethodWeb:
+	default:
+		validationErr.Add("/didMethod", fmt.Sprintf("unsupported did method: %s", pr.DIDMethod))
+
+		return
+	}
+
+	if pr.DID != "" || pr.UNIRegistrar.DriverURL != "" {
+		validationErr.Add("/didMethod", fmt.Sprintf("did method %s can't be used with a did or uni-registrar",
+			pr.DIDMethod))
+	}
+
+	if pr.DIDMethod == commondid.MethodKey && pr.DIDKeyType != crypto.Ed25519KeyType {
+		validationErr.Add("/didKeyType", fmt.Sprintf("did:key supports %s keys only", crypto.Ed25519KeyType))
+	}
+}
+
+func validateDIDWebPath(validationErr *commhttp.ValidationError, pr *ProfileRequest) {
+	if pr.DIDWebPath == "" || pr.DIDWebPath == "/" {
+		return
+	}
+
+	segments := strings.Split(strings.Trim(pr.DIDWebPath, "/"), "/")
+
+	for _, segment := range segments {
+		if segment == "" {
+			validationErr.Add("/didWebPath", fmt.Sprintf("invalid did web path: %s", pr.DIDWebPath))
+
+			return
+		}
+	}
+
+	// the well-known path is the document of the host
+	if segments[0] == ".well-known" {
+		validationErr.Add("/didWebPath", fmt.Sprintf("reserved did web path: %s", pr.DIDWebPath))
+	}
+}
+
+func validateDIDUpdateRequest(update *DIDUpdateRequest) error {
+	validationErr := &commhttp.ValidationError{}
+
+	if len(update.AddServices) == 0 && len(update.RemoveServices) == 0 &&
+		len(update.AddVerificationMethods) == 0 && len(update.RemoveVerificationMethods) == 0 {
+		validationErr.Add("", "empty did update")
+	}
+
+	for i, service := range update.AddServices {
+		field := fmt.Sprintf("/addServices/%d", i)
+
+		validateDIDFragment(validationErr, field+"/id", service.ID)
+
+		if service.Type == "" {
+			validationErr.Add(field+"/type", "missing service type")
+		}
+
+		if _, err := url.ParseRequestURI(service.ServiceEndpoint); err != nil {
+			validationErr.Add(field+"/serviceEndpoint", fmt.Sprintf("invalid service endpoint: %s",
+				service.ServiceEndpoint))
+		}
+	}
+
+	for i, vm := range update.AddVerificationMethods {
+		validateVerificationMethod(validationErr, fmt.Sprintf("/addVerificationMethods/%d", i), &vm)
+	}
+
+	for i, id := range update.RemoveServices {
+		if id == "" {
+			validationErr.Add(fmt.Sprintf("/removeServices/%d", i), "empty service id")
+		}
+	}
+
+	for i, id := range update.RemoveVerificationMethods {
+		if id == "" {
+			validationErr.Add(fmt.Sprintf("/removeVerificationMethods/%d", i), "empty verification method id")
+		}
+	}
+
+	return validationErr.ErrorOrNil()
+}
+
+func validateVerificationMethod(validationErr *commhttp.ValidationError, field string,
+	vm *model.DIDVerificationMethod) {
+	validateDIDFragment(validationErr, field+"/id", vm.ID)
+
+	switch {
+	case vm.Type == crypto.Ed25519VerificationKey2018 && vm.KeyType == crypto.Ed25519KeyType,
+		vm.Type == crypto.JwsVerificationKey2020 &&
+			(vm.KeyType == crypto.Ed25519KeyType || vm.KeyType == crypto.P256KeyType):
+	default:
+		validationErr.Add(field+"/type", fmt.Sprintf("unsupported verification method type %s with key type %s",
+			vm.Type, vm.KeyType))
+	}
+
+	if len(base58.Decode(vm.PublicKeyBase58)) == 0 {
+		validationErr.Add(field+"/publicKeyBase58", "missing or invalid base58 public key")
+	}
+
+	for i, purpose := range vm.Purposes {
+		switch purpose {
+		case authentication, assertionMethod, capabilityDelegation, capabilityInvocation:
+		default:
+			validationErr.Add(fmt.Sprintf("%s/purposes/%d", field, i), fmt.Sprintf("invalid purpose: %s", purpose))
+		}
+	}
+}
+
+// validateDIDFragment checks the id is a fragment of the did URLs, the did being the one of the profile
+func validateDIDFragment(validationErr *commhttp.ValidationError, field, id string) {
+	if id == "" || strings.ContainsAny(id, "#:/?") {
+		validationErr.Add(field, fmt.Sprintf("invalid id: %s", id))
+	}
+}
+
+func validateIssueCredentialRequest(cred *IssueCredentialRequest) error {
+	validationErr := &commhttp.ValidationError{}
+
+	switch {
+	case cred.CredentialRef != nil:
+		validateCredentialRef(validationErr, cred)
+	case len(cred.Credential) == 0:
+		validationErr.Add("/credential", "missing credential")
+	}
+
+	if cred.Opts != nil {
+		validateProofPurpose(validationErr, "/options/proofPurpose", cred.Opts.ProofPurpose)
+
+		if cred.Opts.AssertionMethod != "" {
+			idSplit := strings.Split(cred.Opts.AssertionMethod, "#")
+			if len(idSplit) != 2 {
+				validationErr.Add("/options/assertionMethod", fmt.Sprintf("invalid assertion method : %s", idSplit))
+			}
+		}
+
+		validateProofValues(validationErr, cred.Opts)
+
+		if cred.Opts.HolderBinding != nil && len(cred.Opts.HolderBinding.Presentation) == 0 {
+			validationErr.Add("/options/holderBinding/presentation", "missing holder binding presentation")
+		}
+
+		if cred.Opts.HolderKey != nil && cred.Opts.HolderKeyID != "" {
+			validationErr.Add("/options/holderKeyID", "holderKey and holderKeyID are mutually exclusive")
+		}
+
+		if cred.Opts.HolderKeyID != "" && len(strings.Split(cred.Opts.HolderKeyID, "#")) != 2 {
+			validationErr.Add("/options/holderKeyID", fmt.Sprintf("invalid verification method: %s",
+				cred.Opts.HolderKeyID))
+		}
+
+		for i := range cred.Opts.Proofs {
+			validateProofOptions(validationErr, fmt.Sprintf("/options/proofs/%d", i), &cred.Opts.Proofs[i])
+		}
+	}
+
+	return validationErr.ErrorOrNil()
+}
+
+// validateCredentialRef checks the request references the credential by either its ID or its URL
+func validateCredentialRef(validationErr *commhttp.ValidationError, cred *IssueCredentialRequest) {
+	if len(cred.Credential) != 0 {
+		validationErr.Add("/credentialRef", "credential and credentialRef are mutually exclusive")
+	}
+
+	if (cred.CredentialRef.ID == "") == (cred.CredentialRef.URL == "") {
+		validationErr.Add("/credentialRef", "either the id or the url of the credential must be set")
+	}
+}
+
+func validateIssueCredentialBatchRequest(batchReq *IssueCredentialBatchRequest) error {
+	validationErr := &commhttp.ValidationError{}
+
+	switch {
+	case len(batchReq.Credentials) == 0:
+		validationErr.Add("/credentials", "missing credentials")
+	case len(batchReq.Credentials) > maxIssueBatchSize:
+		validationErr.Add("/credentials", fmt.Sprintf("batch exceeds %d credentials", maxIssueBatchSize))
+	}
+
+	for i := range batchReq.Credentials {
+		var credErr *commhttp.ValidationError
+		if errors.As(validateIssueCredentialRequest(&batchReq.Credentials[i]), &credErr) {
+			for _, field := range credErr.Fields {
+				validationErr.Add(fmt.Sprintf("/credentials/%d%s", i, field.Field), field.Message)
+			}
+		}
+	}
+
+	if batchReq.Presentation != nil && !strings.HasPrefix(batchReq.Presentation.Holder, "did:") {
+		validationErr.Add("/presentation/holder", "the holder of the presentation must be a DID")
+	}
+
+	return validationErr.ErrorOrNil()
+}
+
+func validateEndorseCredentialRequest(endorseReq *EndorseCredentialRequest) error {
+	validationErr := &commhttp.ValidationError{}
+
+	if len(endorseReq.Credential) == 0 {
+		validationErr.Add("/credential", "missing credential")
+	}
+
+	if endorseReq.Opts != nil {
+		validateProofPurpose(validationErr, "/options/proofPurpose", endorseReq.Opts.ProofPurpose)
+		validateProofValues(validationErr, endorseReq.Opts)
+
+		for i := range endorseReq.Opts.Proofs {
+			validateProofOptions(validationErr, fmt.Sprintf("/options/proofs/%d", i), &endorseReq.Opts.Proofs[i])
+		}
+	}
+
+	return validationErr.ErrorOrNil()
+}
+
+// validateProofValues validates the nonce and the proof properties added to the proofs
+func validateProofValues(validationErr *commhttp.ValidationError, opts *IssueCredentialOptions) {
+	if opts.Nonce != "" {
+		if _, err := base64.RawURLEncoding.DecodeString(opts.Nonce); err != nil {
+			validationErr.Add("/options/nonce", "nonce must be base64url encoded without padding")
+		}
+	}
+
+	if err := crypto.ValidateProofProperties(opts.ProofProperties); err != nil {
+		validationErr.Add("/options/proofProperties", err.Error())
+	}
+}
+
+func validateProofOptions(validationErr *commhttp.ValidationError, field string, proof *ProofOptions) {
+	validateProofPurpose(validationErr, field+"/proofPurpose", proof.ProofPurpose)
+
+	if _, ok := crypto.GetSignatureSuite(proof.SignatureType); proof.SignatureType != "" && !ok {
+		validationErr.Add(field+"/signatureType", fmt.Sprintf("unsupported signature type: %s", proof.SignatureType))
+	}
+}
+
+func validateAttachments(validationErr *commhttp.ValidationError, attachments []EvidenceAttachment) {
+	for i := range attachments {
+		field := fmt.Sprintf("/attachments/%d", i)
+
+		data, err := base64.StdEncoding.DecodeString(attachments[i].Data)
+		if err != nil || len(data) == 0 {
+			validationErr.Add(field+"/data", "data must be a non-empty base64 encoded document")
+		}
+
+		if attachments[i].MediaType != "" {
+			if _, _, err = mime.ParseMediaType(attachments[i].MediaType); err != nil {
+				validationErr.Add(field+"/mediaType", fmt.Sprintf("invalid media type: %s", attachments[i].MediaType))
+			}
+		}
+	}
+}
+
+func validateComposeCredentialRequest(composeCredReq *ComposeCredentialRequest) error {
+	validationErr := &commhttp.ValidationError{}
+
+	for i, vcType := range composeCredReq.Types {
+		if vcType == "" {
+			validationErr.Add(fmt.Sprintf("/types/%d", i), "empty credential type")
+		}
+	}
+
+	if composeCredReq.IssuanceDate != nil && composeCredReq.ExpirationDate != nil &&
+		composeCredReq.ExpirationDate.Before(*composeCredReq.IssuanceDate) {
+		validationErr.Add("/expirationDate", "expiration date before issuance date")
+	}
+
+	validateJSONObject(validationErr, "/claims", composeCredReq.Claims)
+	validateJSONObject(validationErr, "/evidence", composeCredReq.Evidence)
+	validateAttachments(validationErr, composeCredReq.Attachments)
+
+	if _, err := vcutil.DecodeTypedIDFromJSONRaw(composeCredReq.TermsOfUse); err != nil {
+		validationErr.Add("/termsOfUse", fmt.Sprintf("invalid terms of use: %s", err.Error()))
+	}
+
+	if _, err := vcutil.GetContextsFromJSONRaw(composeCredReq.CredentialFormatOptions); err != nil {
+		validationErr.Add("/credentialFormatOptions", fmt.Sprintf("invalid credential format options: %s",
+			err.Error()))
+	}
+
+	switch composeCredReq.ProofFormat {
+	case "", "jws", "proofValue":
+	default:
+		validationErr.Add("/proofFormat", fmt.Sprintf("invalid proof format : %s", composeCredReq.ProofFormat))
+	}
+
+	if composeCredReq.ProofFormatOptions != nil {
+		var proofFormatOptions struct {
+			KeyID   string `json:"kid,omitempty"`
+			Purpose string `json:"proofPurpose,omitempty"`
+		}
+
+		err := json.Unmarshal(composeCredReq.ProofFormatOptions, &proofFormatOptions)
+		if err != nil {
+			validationErr.Add("/proofFormatOptions", fmt.Sprintf("invalid proof format options: %s", err.Error()))
+		} else {
+			validateProofPurpose(validationErr, "/proofFormatOptions/proofPurpose", proofFormatOptions.Purpose)
+		}
+	}
+
+	return validationErr.ErrorOrNil()
+}
+
+func validateProofPurpose(validationErr *commhttp.ValidationError, field, proofPurpose string) {
+	switch proofPurpose {
+	case "", assertionMethod, authentication, capabilityDelegation, capabilityInvocation:
+	default:
+		validationErr.Add(field, fmt.Sprintf("invalid proof option : %s", proofPurpose))
+	}
+}
+
+// validateJSONObject checks the optional field is a JSON object
+func validateJSONObject(validationErr *commhttp.ValidationError, field string, raw json.RawMessage) {
+	if raw == nil {
+		return
+	}
+
+	var object map[string]interface{}
+
+	if err := json.Unmarshal(raw, &object); err != nil {
+		validationErr.Add(field, fmt.Sprintf("%s must be a JSON object", strings.TrimPrefix(field, "/")))
+	}
+}
@@ -0,0 +1,286 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+	"github.com/trustbloc/edge-service/pkg/restapi/model"
+)
+
+func TestOperation_validateProfileRequest(t *testing.T) {
+	t.Run("valid profile ", func(t *testing.T) {
+		profile := getProfileRequest()
+		err := validateProfileRequest(profile, policy.New())
+		require.NoError(t, err)
+	})
+	t.Run("missing profile name", func(t *testing.T) {
+		profile := getProfileRequest()
+		profile.Name = ""
+		err := validateProfileRequest(profile, policy.New())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing profile name")
+	})
+	t.Run("missing URI ", func(t *testing.T) {
+		profile := getProfileRequest()
+		profile.URI = ""
+		err := validateProfileRequest(profile, policy.New())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing URI information")
+	})
+	t.Run("missing signature type ", func(t *testing.T) {
+		profile := getProfileRequest()
+		profile.SignatureType = ""
+		err := validateProfileRequest(profile, policy.New())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "missing signature type")
+	})
+	t.Run("parse uri failed", func(t *testing.T) {
+		profile := getProfileRequest()
+		profile.URI = "//not-valid.&&%^)$"
+		err := validateProfileRequest(profile, policy.New())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid uri")
+	})
+	t.Run("invalid credential storage", func(t *testing.T) {
+		profile := getProfileRequest()
+		profile.CredentialStorage = "s3"
+		err := validateProfileRequest(profile, policy.New())
+		require.EqualError(t, err, "invalid credential storage: s3")
+	})
+	t.Run("revoke on expiry", func(t *testing.T) {
+		profile := getProfileRequest()
+		profile.RevokeOnExpiry = true
+		require.NoError(t, validateProfileRequest(profile, policy.New()))
+
+		profile.DisableVCStatus = true
+		require.EqualError(t, validateProfileRequest(profile, policy.New()),
+			"revocation on expiry requires the vc status")
+	})
+
+	t.Run("credential format", func(t *testing.T) {
+		profile := getProfileRequest()
+		profile.CredentialFormat = CredentialFormatLDP
+		require.NoError(t, validateProfileRequest(profile, policy.New()))
+
+		profile.CredentialFormat = CredentialFormatJWT
+		require.EqualError(t, validateProfileRequest(profile, policy.New()), "credential format jwt requires RSA keys")
+
+		profile.DIDKeyType = vccrypto.RSAKeyType
+		require.NoError(t, validateProfileRequest(profile, policy.New()))
+
+		profile.CredentialFormat = CredentialFormatSDJWT
+		require.NoError(t, validateProfileRequest(profile, policy.New()))
+
+		profile.CredentialFormat = "cbor"
+		require.EqualError(t, validateProfileRequest(profile, policy.New()), "invalid credential format: cbor")
+	})
+
+	t.Run("policy", func(t *testing.T) {
+		profile := getProfileRequest()
+		profile.Policy = []policy.Rule{{Type: policy.SubjectDID},
+			{Type: policy.RequiredTypes, Values: []string{"UniversityDegreeCredential"}}}
+		require.NoError(t, validateProfileRequest(profile, policy.New()))
+
+		profile.Policy = []policy.Rule{{Type: policy.RequiredClaims}, {Type: "unknown"}}
+
+		err := validateProfileRequest(profile, policy.New())
+		require.EqualError(t, err, "rule requiredClaims requires values; unsupported rule type: unknown")
+
+		validationErr := &commhttp.ValidationError{}
+		require.True(t, errors.As(err, &validationErr))
+		require.Equal(t, "/policy/1", validationErr.Fields[1].Field)
+	})
+	t.Run("did method", func(t *testing.T) {
+		profile := getProfileRequest()
+		profile.DIDMethod = "web"
+		require.NoError(t, validateProfileRequest(profile, policy.New()))
+
+		profile.DIDMethod = "peer"
+		require.EqualError(t, validateProfileRequest(profile, policy.New()), "unsupported did method: peer")
+
+		profile.DIDMethod = "key"
+		profile.DID = "did:example:123"
+		require.EqualError(t, validateProfileRequest(profile, policy.New()),
+			"did method key can't be used with a did or uni-registrar; did:key supports Ed25519 keys only")
+
+		profile.DID = ""
+		profile.DIDKeyType = "Ed25519"
+		require.NoError(t, validateProfileRequest(profile, policy.New()))
+	})
+	t.Run("did web path", func(t *testing.T) {
+		profile := getProfileRequest()
+		profile.DIDWebPath = "/"
+		require.EqualError(t, validateProfileRequest(profile, policy.New()), "did web path requires did method web")
+
+		profile.DIDMethod = "web"
+		require.NoError(t, validateProfileRequest(profile, policy.New()))
+
+		profile.DIDWebPath = "/issuers/acme/"
+		require.NoError(t, validateProfileRequest(profile, policy.New()))
+
+		profile.DIDWebPath = "issuers//acme"
+		require.EqualError(t, validateProfileRequest(profile, policy.New()), "invalid did web path: issuers//acme")
+
+		profile.DIDWebPath = ".well-known"
+		require.EqualError(t, validateProfileRequest(profile, policy.New()), "reserved did web path: .well-known")
+	})
+	t.Run("multiple invalid fields", func(t *testing.T) {
+		err := validateProfileRequest(&ProfileRequest{URI: "//not-valid.&&%^)$", CredentialStorage: "s3"},
+			policy.New())
+		require.Error(t, err)
+
+		validationErr, ok := err.(*commhttp.ValidationError)
+		require.True(t, ok)
+		require.Len(t, validationErr.Fields, 4)
+		require.Equal(t, "/name", validationErr.Fields[0].Field)
+		require.Equal(t, "/uri", validationErr.Fields[1].Field)
+		require.Contains(t, validationErr.Fields[1].Message, "invalid uri")
+		require.Equal(t, "/signatureType", validationErr.Fields[2].Field)
+		require.Equal(t, "/credentialStorage", validationErr.Fields[3].Field)
+	})
+}
+
+func TestOperation_validateDIDUpdateRequest(t *testing.T) {
+	t.Run("valid request", func(t *testing.T) {
+		err := validateDIDUpdateRequest(&DIDUpdateRequest{DIDUpdate: model.DIDUpdate{
+			AddServices: []model.DIDService{{ID: "hub", Type: "DIDCommMessaging",
+				ServiceEndpoint: "https://hub.example.com"}},
+			AddVerificationMethods: []model.DIDVerificationMethod{{ID: "key-2", Type: "JwsVerificationKey2020",
+				KeyType: "P256", PublicKeyBase58: "GUXiqNHCdirb6NKpH6wYG4px3YfMjiCh6dQhU3zxQVQ7",
+				Purposes: []string{capabilityInvocation}}},
+			RemoveVerificationMethods: []string{"key-1"},
+		}})
+		require.NoError(t, err)
+	})
+	t.Run("empty update", func(t *testing.T) {
+		require.EqualError(t, validateDIDUpdateRequest(&DIDUpdateRequest{}), "empty did update")
+	})
+	t.Run("invalid fields", func(t *testing.T) {
+		err := validateDIDUpdateRequest(&DIDUpdateRequest{DIDUpdate: model.DIDUpdate{
+			AddServices: []model.DIDService{{ID: "did:example:123#hub", ServiceEndpoint: "hub"}},
+			AddVerificationMethods: []model.DIDVerificationMethod{{ID: "key-2", Type: "Ed25519VerificationKey2018",
+				KeyType: "P256", Purposes: []string{"signing"}}},
+			RemoveServices: []string{""},
+		}})
+		require.Error(t, err)
+
+		validationErr, ok := err.(*commhttp.ValidationError)
+		require.True(t, ok)
+
+		var fields []string
+		for _, field := range validationErr.Fields {
+			fields = append(fields, field.Field)
+		}
+
+		require.Equal(t, []string{"/addServices/0/id", "/addServices/0/type", "/addServices/0/serviceEndpoint",
+			"/addVerificationMethods/0/type", "/addVerificationMethods/0/publicKeyBase58",
+			"/addVerificationMethods/0/purposes/0", "/removeServices/0"}, fields)
+	})
+}
+
+func TestOperation_validateIssueCredentialRequest(t *testing.T) {
+	t.Run("valid request", func(t *testing.T) {
+		err := validateIssueCredentialRequest(&IssueCredentialRequest{
+			Credential: []byte(validVC),
+			Opts: &IssueCredentialOptions{ProofPurpose: authentication, AssertionMethod: "did:test:abc#key1",
+				Nonce: "bm9uY2U", ProofProperties: map[string]interface{}{"capabilityChain": []string{"urn:zcap:1"}},
+				Proofs: []ProofOptions{{SignatureType: vccrypto.JSONWebSignature2020, ProofPurpose: assertionMethod}}},
+		})
+		require.NoError(t, err)
+	})
+	t.Run("invalid fields", func(t *testing.T) {
+		err := validateIssueCredentialRequest(&IssueCredentialRequest{
+			Opts: &IssueCredentialOptions{ProofPurpose: "customPurpose", AssertionMethod: "did:test:abc",
+				Nonce: "not+base64url=", ProofProperties: map[string]interface{}{"jws": "forged"},
+				Proofs: []ProofOptions{{}, {SignatureType: "RsaSignature2018", ProofPurpose: "other"}}},
+		})
+		require.EqualError(t, err, "missing credential; invalid proof option : customPurpose; "+
+			"invalid assertion method : [did:test:abc]; nonce must be base64url encoded without padding; "+
+			"proof property jws is reserved; invalid proof option : other; "+
+			"unsupported signature type: RsaSignature2018")
+
+		validationErr, ok := err.(*commhttp.ValidationError)
+		require.True(t, ok)
+		require.Equal(t, []commhttp.FieldError{
+			{Field: "/credential", Message: "missing credential"},
+			{Field: "/options/proofPurpose", Message: "invalid proof option : customPurpose"},
+			{Field: "/options/assertionMethod", Message: "invalid assertion method : [did:test:abc]"},
+			{Field: "/options/nonce", Message: "nonce must be base64url encoded without padding"},
+			{Field: "/options/proofProperties", Message: "proof property jws is reserved"},
+			{Field: "/options/proofs/1/proofPurpose", Message: "invalid proof option : other"},
+			{Field: "/options/proofs/1/signatureType", Message: "unsupported signature type: RsaSignature2018"},
+		}, validationErr.Fields)
+	})
+	t.Run("credential reference", func(t *testing.T) {
+		require.NoError(t, validateIssueCredentialRequest(&IssueCredentialRequest{
+			CredentialRef: &CredentialReference{ID: "http://example.edu/credentials/1872"}}))
+
+		err := validateIssueCredentialRequest(&IssueCredentialRequest{Credential: []byte(validVC),
+			CredentialRef: &CredentialReference{ID: "http://example.edu/credentials/1872",
+				URL: "https://example.edu/credentials/1872.json"}})
+		require.EqualError(t, err, "credential and credentialRef are mutually exclusive; "+
+			"either the id or the url of the credential must be set")
+
+		err = validateIssueCredentialRequest(&IssueCredentialRequest{CredentialRef: &CredentialReference{}})
+		require.EqualError(t, err, "either the id or the url of the credential must be set")
+	})
+}
+
+func TestOperation_validateComposeCredentialRequest(t *testing.T) {
+	issued := time.Now()
+	expired := issued.Add(-time.Hour)
+
+	t.Run("valid request", func(t *testing.T) {
+		err := validateComposeCredentialRequest(&ComposeCredentialRequest{
+			Types:              []string{"VerifiableCredential", "UniversityDegreeCredential"},
+			IssuanceDate:       &issued,
+			Claims:             json.RawMessage(`{"name":"John Smith"}`),
+			Evidence:           json.RawMessage(`{"id":"https://example.com/evidence/1"}`),
+			TermsOfUse:         json.RawMessage(`[{"type":"IssuerPolicy"}]`),
+			ProofFormat:        "proofValue",
+			ProofFormatOptions: json.RawMessage(`{"kid":"did:test:abc#key1","proofPurpose":"assertionMethod"}`),
+			Attachments: []EvidenceAttachment{{Name: "diploma.pdf", MediaType: "application/pdf", Data: "JVBERi0="},
+				{Data: "AQI="}},
+		})
+		require.NoError(t, err)
+	})
+	t.Run("invalid fields", func(t *testing.T) {
+		err := validateComposeCredentialRequest(&ComposeCredentialRequest{
+			Types:                   []string{"VerifiableCredential", ""},
+			IssuanceDate:            &issued,
+			ExpirationDate:          &expired,
+			Claims:                  json.RawMessage(`"claims"`),
+			Evidence:                json.RawMessage(`[]`),
+			TermsOfUse:              json.RawMessage(`"terms"`),
+			CredentialFormatOptions: json.RawMessage(`{"@context":1}`),
+			ProofFormat:             "jwt",
+			ProofFormatOptions:      json.RawMessage(`{"proofPurpose":"customPurpose"}`),
+			Attachments:             []EvidenceAttachment{{Data: "AQI="}, {Data: "not base64"}, {MediaType: "/pdf"}},
+		})
+		require.Error(t, err)
+
+		validationErr, ok := err.(*commhttp.ValidationError)
+		require.True(t, ok)
+
+		fields := make([]string, len(validationErr.Fields))
+		for i, field := range validationErr.Fields {
+			fields[i] = field.Field
+		}
+
+		require.Equal(t, []string{"/types/1", "/expirationDate", "/claims", "/evidence", "/attachments/1/data",
+			"/attachments/2/data", "/attachments/2/mediaType", "/termsOfUse", "/credentialFormatOptions",
+			"/proofFormat", "/proofFormatOptions/proofPurpose"}, fields)
+	})
+}
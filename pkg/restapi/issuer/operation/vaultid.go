@@ -0,0 +1,245 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/trustbloc/edv/pkg/restapi/messages"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	// VaultIDSchemeName keys the vault of a profile by the profile name (default), colliding with the profiles of
+	// the same name of the other instances sharing the EDV server
+	VaultIDSchemeName = "name"
+	// VaultIDSchemePrefix keys the vault of a profile by the vault ID prefix of the instance followed by the
+	// profile name
+	VaultIDSchemePrefix = "prefix"
+	// VaultIDSchemeUUID keys the vault of a profile by a UUID generated on creation
+	VaultIDSchemeUUID = "uuid"
+)
+
+// newVaultID returns the ID of the vault of a new profile, stored on the profile unless it is the profile name
+func (o *Operation) newVaultID(profile *vcprofile.DataProfile) string {
+	switch o.vaultIDScheme {
+	case VaultIDSchemePrefix:
+		return o.vaultIDPrefix + profile.Name
+	case VaultIDSchemeUUID:
+		return uuid.New().String()
+	default:
+		return ""
+	}
+}
+
+// migratedVaultID returns the ID of the vault a profile keyed by its name is migrated to. The UUID is derived from
+// the DID and the name of the profile, so an interrupted migration is resumed into the same vault.
+func (o *Operation) migratedVaultID(profile *vcprofile.DataProfile) string {
+	if o.vaultIDScheme == VaultIDSchemeUUID {
+		return uuid.NewSHA1(uuid.NameSpaceURL, []byte(profile.DID+"#"+profile.Name)).String()
+	}
+
+	return o.newVaultID(profile)
+}
+
+// profileVaultClient keeps the credentials of a profile in the vault of its vault ID: the vault ID of the requests
+// is the profile name, as for the profiles keyed by their name.
+type profileVaultClient struct {
+	EDVClient
+	profileName string
+	vaultID     string
+}
+
+func newProfileVaultClient(client EDVClient, profile *vcprofile.DataProfile) EDVClient {
+	if profile.VaultID == "" || profile.VaultID == profile.Name {
+		return client
+	}
+
+	return &profileVaultClient{EDVClient: client, profileName: profile.Name, vaultID: profile.VaultID}
+}
+
+func (c *profileVaultClient) vault(vaultID string) string {
+	if vaultID == c.profileName {
+		return c.vaultID
+	}
+
+	return vaultID
+}
+
+func (c *profileVaultClient) CreateDataVault(config *models.DataVaultConfiguration) (string, error) {
+	vaultConfig := *config
+	vaultConfig.ReferenceID = c.vault(config.ReferenceID)
+
+	return c.EDVClient.CreateDataVault(&vaultConfig)
+}
+
+func (c *profileVaultClient) CreateDocument(vaultID string, document *models.EncryptedDocument) (string, error) {
+	return c.EDVClient.CreateDocument(c.vault(vaultID), document)
+}
+
+func (c *profileVaultClient) ReadDocument(vaultID, docID string) (*models.EncryptedDocument, error) {
+	return c.EDVClient.ReadDocument(c.vault(vaultID), docID)
+}
+
+func (c *profileVaultClient) QueryVault(vaultID string, query *models.Query) ([]string, error) {
+	return c.EDVClient.QueryVault(c.vault(vaultID), query)
+}
+
+func (c *profileVaultClient) CreateDocuments(vaultID string, documents []*models.EncryptedDocument) ([]string, error) {
+	return c.EDVClient.CreateDocuments(c.vault(vaultID), documents)
+}
+
+func (c *profileVaultClient) ReadDocuments(vaultID string, docIDs []string) ([]*models.EncryptedDocument, error) {
+	return c.EDVClient.ReadDocuments(c.vault(vaultID), docIDs)
+}
+
+func (c *profileVaultClient) DeleteDocument(vaultID, docID string) error {
+	return c.EDVClient.DeleteDocument(c.vault(vaultID), docID)
+}
+
+// MigrateVault swagger:route POST /profile/{id}/vault/migrate issuer migrateVaultReq
+//
+// Migrates the credentials of a profile keyed by its name to the vault of the vault ID scheme of the service. The
+// documents are copied as stored, still encrypted, and the profile uses the new vault once they are copied. A
+// migration interrupted is resumed by the next request.
+//
+// Responses:
+//    default: genericError
+//        200: migrateVaultRes
+func (o *Operation) migrateVaultHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getIssuerProfile(mux.Vars(req)["id"])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if o.vaultIDScheme == VaultIDSchemeName {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+			"the vaults are keyed by the profile names, no vault ID scheme to migrate to")
+
+		return
+	}
+
+	if profile.VaultID != "" {
+		commhttp.WriteErrorResponse(rw, http.StatusConflict, commhttp.Conflict,
+			fmt.Sprintf("the vault of profile %s is already keyed by %s", profile.Name, profile.VaultID))
+
+		return
+	}
+
+	edvClient, err := o.profileEDVClient(&vcprofile.DataProfile{Name: profile.Name,
+		CredentialStorage: profile.CredentialStorage})
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.EDVError, err.Error())
+
+		return
+	}
+
+	// the documents are neither re-encrypted nor migrated concurrently
+	if !o.startReencryption(profile.Name) {
+		commhttp.WriteErrorResponse(rw, http.StatusConflict, commhttp.Conflict,
+			fmt.Sprintf("the credentials of profile %s are being re-encrypted or migrated", profile.Name))
+
+		return
+	}
+
+	defer o.doneReencryption(profile.Name)
+
+	resp, err := o.migrateVault(edvClient, profile)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, resp)
+}
+
+// migrateVault copies the documents of the profile to its new vault, then saves the profile with the new vault ID.
+// The documents stored while the first copy runs are copied once the profile is saved.
+func (o *Operation) migrateVault(edvClient EDVClient, profile *vcprofile.DataProfile) (*MigrateVaultResponse, error) {
+	vaultID := o.migratedVaultID(profile)
+
+	_, err := edvClient.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: vaultID})
+	if err != nil && !strings.Contains(err.Error(), messages.ErrDuplicateVault.Error()) {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError,
+			fmt.Sprintf("failed to create vault %s: %s", vaultID, err.Error()))
+	}
+
+	resp := &MigrateVaultResponse{VaultID: vaultID}
+
+	if err = o.copyDocuments(edvClient, profile.Name, vaultID, resp); err != nil {
+		return nil, err
+	}
+
+	stored, err := o.profileStore.GetProfile(profile.Name)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to get profile: %s", err.Error()))
+	}
+
+	stored.VaultID = vaultID
+
+	if err = o.profileStore.SaveProfile(stored); err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError,
+			fmt.Sprintf("failed to save profile: %s", err.Error()))
+	}
+
+	if err = o.copyDocuments(edvClient, profile.Name, vaultID, resp); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// copyDocuments copies the stored credentials of the vault not in the other vault yet, read maxListLimit documents
+// at a time
+func (o *Operation) copyDocuments(edvClient EDVClient, fromVaultID, toVaultID string,
+	resp *MigrateVaultResponse) error {
+	docIDs, err := queryDocIDs(edvClient, fromVaultID,
+		&models.Query{Name: o.vcStoredIndexNameEncoded, Value: o.vcStoredIndexNameEncoded}, "migrating VCs")
+	if err != nil {
+		return commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError, err.Error())
+	}
+
+	for start := 0; start < len(docIDs); start += maxListLimit {
+		end := start + maxListLimit
+		if end > len(docIDs) {
+			end = len(docIDs)
+		}
+
+		documents, err := edvClient.ReadDocuments(fromVaultID, docIDs[start:end])
+		if err != nil {
+			return commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError,
+				fmt.Sprintf("failed to read documents while migrating VCs: %s", err.Error()))
+		}
+
+		for _, document := range documents {
+			_, err = edvClient.CreateDocument(toVaultID, document)
+			if err != nil && strings.Contains(err.Error(), messages.ErrDuplicateDocument.Error()) {
+				continue
+			}
+
+			if err != nil {
+				return commhttp.NewError(http.StatusInternalServerError, commhttp.EDVError,
+					fmt.Sprintf("failed to copy document %s while migrating VCs: %s", document.ID, err.Error()))
+			}
+
+			resp.Migrated++
+		}
+	}
+
+	return nil
+}
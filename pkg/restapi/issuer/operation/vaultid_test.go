@@ -0,0 +1,261 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/storage/mem"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/utils/retry"
+	"github.com/trustbloc/edv/pkg/restapi/models"
+
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+)
+
+func newVaultIDOperation(t *testing.T, scheme string) *Operation {
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		KMSSecretsProvider: mem.NewProvider(),
+		Crypto:             &plainMACCrypto{},
+		KeyManager:         newJWEKeyManager(),
+		VDRI:               &vdrimock.MockVDRIRegistry{},
+		HostURL:            "localhost:8080",
+		RetryParameters:    &retry.Params{},
+		CredentialStorage:  CredentialStorageLocal,
+		VaultIDScheme:      scheme,
+		VaultIDPrefix:      "instance1-"})
+	require.NoError(t, err)
+
+	return op
+}
+
+func TestNewVaultID(t *testing.T) {
+	profile := &vcprofile.DataProfile{Name: "issuer", DID: "did:test:abc"}
+
+	require.Empty(t, newVaultIDOperation(t, "").newVaultID(profile))
+	require.Equal(t, "instance1-issuer", newVaultIDOperation(t, VaultIDSchemePrefix).newVaultID(profile))
+
+	op := newVaultIDOperation(t, VaultIDSchemeUUID)
+
+	vaultID, err := uuid.Parse(op.newVaultID(profile))
+	require.NoError(t, err)
+	require.NotEqual(t, vaultID.String(), op.newVaultID(profile))
+
+	// the vault a profile is migrated to doesn't change
+	require.Equal(t, op.migratedVaultID(profile), op.migratedVaultID(profile))
+	require.NotEqual(t, op.migratedVaultID(profile), op.migratedVaultID(&vcprofile.DataProfile{Name: "issuer",
+		DID: "did:test:def"}))
+}
+
+func TestCreateProfileVaultID(t *testing.T) {
+	op := newVaultIDOperation(t, VaultIDSchemePrefix)
+	op.commonDID = &mockCommonDID{createDIDValue: "did:test:abc", createDIDKeyID: "did:test:abc#key1"}
+
+	pr := getProfileRequest()
+	pr.CredentialStorage = CredentialStorageLocal
+
+	profile, err := op.CreateProfile(pr)
+	require.NoError(t, err)
+	require.Equal(t, "instance1-issuer", profile.VaultID)
+
+	// the vault is keyed by the vault ID
+	_, err = op.localEDVClient.QueryVault("instance1-issuer", &models.Query{Name: "name", Value: "value"})
+	require.NoError(t, err)
+
+	_, err = op.localEDVClient.QueryVault("issuer", &models.Query{Name: "name", Value: "value"})
+	require.Error(t, err)
+
+	t.Run("test vault of a profile which doesn't exist", func(t *testing.T) {
+		client, err := op.getEDVClient("unknown")
+		require.NoError(t, err)
+		require.Equal(t, "instance1-unknown", client.(*profileVaultClient).vaultID)
+	})
+}
+
+func TestProfileVaultClient(t *testing.T) {
+	op := newVaultIDOperation(t, "")
+
+	require.Equal(t, op.localEDVClient, newProfileVaultClient(op.localEDVClient,
+		&vcprofile.DataProfile{Name: "issuer"}))
+	require.Equal(t, op.localEDVClient, newProfileVaultClient(op.localEDVClient,
+		&vcprofile.DataProfile{Name: "issuer", VaultID: "issuer"}))
+
+	client := newProfileVaultClient(op.localEDVClient, &vcprofile.DataProfile{Name: "issuer", VaultID: "vault1"})
+
+	_, err := client.CreateDataVault(&models.DataVaultConfiguration{ReferenceID: "issuer"})
+	require.NoError(t, err)
+
+	document := &models.EncryptedDocument{ID: "doc1", JWE: []byte("{}")}
+
+	_, err = client.CreateDocument("issuer", document)
+	require.NoError(t, err)
+
+	_, err = client.CreateDocuments("issuer", []*models.EncryptedDocument{{ID: "doc2", JWE: []byte("{}")}})
+	require.NoError(t, err)
+
+	stored, err := op.localEDVClient.ReadDocument("vault1", "doc1")
+	require.NoError(t, err)
+	require.Equal(t, "doc1", stored.ID)
+
+	stored, err = client.ReadDocument("issuer", "doc1")
+	require.NoError(t, err)
+	require.Equal(t, "doc1", stored.ID)
+
+	documents, err := client.ReadDocuments("issuer", []string{"doc1", "doc2"})
+	require.NoError(t, err)
+	require.Len(t, documents, 2)
+
+	_, err = client.QueryVault("issuer", &models.Query{Name: "name", Value: "value"})
+	require.NoError(t, err)
+
+	require.NoError(t, client.DeleteDocument("issuer", "doc1"))
+
+	// the other vaults are kept
+	_, err = client.ReadDocument("other", "doc2")
+	require.Error(t, err)
+}
+
+func TestMigrateVault(t *testing.T) {
+	newOperation := func(t *testing.T, scheme string) *Operation {
+		op := newVaultIDOperation(t, scheme)
+
+		require.NoError(t, op.profileStore.SaveProfile(&vcprofile.DataProfile{Name: "issuer", DID: "did:test:abc"}))
+
+		r, err := http.NewRequest(http.MethodPost, "/issuer/credentials/import", strings.NewReader(
+			testCredential("1")+"\n"+testCredential("2")+"\n"+testCredential("3")))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		op.importCredentialsHandler(rr, mux.SetURLVars(r, map[string]string{profileIDPathParam: "issuer"}))
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		return op
+	}
+
+	migrate := func(t *testing.T, op *Operation, profile string) *httptest.ResponseRecorder {
+		rr := serveHTTPMux(t, getHandler(t, op, migrateVaultEndpoint, http.MethodPost),
+			"/profile/"+profile+"/vault/migrate", nil, map[string]string{"id": profile})
+
+		return rr
+	}
+
+	storedDocIDs := func(t *testing.T, op *Operation) []string {
+		profile, err := op.profileStore.GetProfile("issuer")
+		require.NoError(t, err)
+
+		edvClient, err := op.profileEDVClient(profile)
+		require.NoError(t, err)
+
+		docIDs, err := queryDocIDs(edvClient, "issuer",
+			&models.Query{Name: op.vcStoredIndexNameEncoded, Value: op.vcStoredIndexNameEncoded}, "test")
+		require.NoError(t, err)
+
+		return docIDs
+	}
+
+	t.Run("test credentials migrated", func(t *testing.T) {
+		op := newOperation(t, VaultIDSchemeUUID)
+		docIDs := storedDocIDs(t, op)
+		require.Len(t, docIDs, 3)
+
+		rr := migrate(t, op, "issuer")
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		resp := &MigrateVaultResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		require.Equal(t, 3, resp.Migrated)
+
+		profile, err := op.profileStore.GetProfile("issuer")
+		require.NoError(t, err)
+		require.Equal(t, op.migratedVaultID(profile), profile.VaultID)
+		require.Equal(t, profile.VaultID, resp.VaultID)
+		require.Equal(t, docIDs, storedDocIDs(t, op))
+
+		rr = migrate(t, op, "issuer")
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Contains(t, rr.Body.String(), "the vault of profile issuer is already keyed by "+profile.VaultID)
+	})
+
+	t.Run("test interrupted migration resumed", func(t *testing.T) {
+		op := newOperation(t, VaultIDSchemePrefix)
+
+		profile, err := op.profileStore.GetProfile("issuer")
+		require.NoError(t, err)
+
+		resp, err := op.migrateVault(op.localEDVClient, profile)
+		require.NoError(t, err)
+		require.Equal(t, 3, resp.Migrated)
+
+		profile.VaultID = ""
+		require.NoError(t, op.profileStore.SaveProfile(profile))
+
+		rr := migrate(t, op, "issuer")
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), `"migrated":0`)
+		require.Contains(t, rr.Body.String(), `"vaultID":"instance1-issuer"`)
+	})
+
+	t.Run("test error - no vault ID scheme", func(t *testing.T) {
+		rr := migrate(t, newOperation(t, VaultIDSchemeName), "issuer")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "no vault ID scheme to migrate to")
+	})
+
+	t.Run("test error - profile not found", func(t *testing.T) {
+		rr := migrate(t, newOperation(t, VaultIDSchemeUUID), "unknown")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("test error - credentials being re-encrypted", func(t *testing.T) {
+		op := newOperation(t, VaultIDSchemeUUID)
+		require.True(t, op.startReencryption("issuer"))
+
+		rr := migrate(t, op, "issuer")
+		require.Equal(t, http.StatusConflict, rr.Code)
+		require.Contains(t, rr.Body.String(), "are being re-encrypted or migrated")
+	})
+
+	t.Run("test error - documents not copied", func(t *testing.T) {
+		op := newOperation(t, VaultIDSchemeUUID)
+		op.localEDVClient = &failingEDVClient{EDVClient: op.localEDVClient, createErr: errors.New("create error")}
+
+		rr := migrate(t, op, "issuer")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "create error")
+
+		profile, err := op.profileStore.GetProfile("issuer")
+		require.NoError(t, err)
+		require.Empty(t, profile.VaultID)
+	})
+
+	t.Run("test error - documents not read", func(t *testing.T) {
+		op := newOperation(t, VaultIDSchemeUUID)
+		op.localEDVClient = &failingEDVClient{EDVClient: op.localEDVClient, readErr: errors.New("read error")}
+
+		rr := migrate(t, op, "issuer")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "read error")
+	})
+
+	t.Run("test error - vault not created", func(t *testing.T) {
+		op := newOperation(t, VaultIDSchemeUUID)
+		op.localEDVClient = &createVaultEDVClient{err: errors.New("vault error")}
+
+		rr := migrate(t, op, "issuer")
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "vault error")
+	})
+}
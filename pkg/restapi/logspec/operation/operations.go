@@ -7,7 +7,6 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
@@ -92,9 +91,8 @@ func logSpecGetHandler(rw http.ResponseWriter, _ *http.Request) {
 func changeLogSpec(rw http.ResponseWriter, req *http.Request) {
 	var incomingLogSpec logSpec
 
-	err := json.NewDecoder(req.Body).Decode(&incomingLogSpec)
-	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidLogSpec, err))
+	if err := commhttp.DecodeJSON(req, &incomingLogSpec); err != nil {
+		commhttp.WriteError(rw, err)
 		return
 	}
 
@@ -110,7 +108,8 @@ func changeLogSpec(rw http.ResponseWriter, req *http.Request) {
 
 			logLevel, errParse := log.ParseLevel(moduleAndLevelPair[1])
 			if errParse != nil {
-				commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidLogSpec, errParse))
+				commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+					fmt.Sprintf(invalidLogSpec, errParse))
 				return
 			}
 
@@ -119,7 +118,7 @@ func changeLogSpec(rw http.ResponseWriter, req *http.Request) {
 		} else {
 			if defaultLogLevel != -1 {
 				// The given log spec is formatted incorrectly; it contains multiple default values.
-				commhttp.WriteErrorResponse(rw, http.StatusBadRequest,
+				commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
 					fmt.Sprintf(invalidLogSpec, multipleDefaultValues))
 				return
 			}
@@ -127,7 +126,8 @@ func changeLogSpec(rw http.ResponseWriter, req *http.Request) {
 
 			defaultLogLevel, errParse = log.ParseLevel(logLevelByModulePart)
 			if errParse != nil {
-				commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidLogSpec, errParse))
+				commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+					fmt.Sprintf(invalidLogSpec, errParse))
 				return
 			}
 		}
@@ -153,7 +153,8 @@ func getLogSpec(rw http.ResponseWriter, response StringBuilder) {
 		} else {
 			_, err := response.Write([]byte(module + "=" + log.ParseString(level) + ":"))
 			if err != nil {
-				commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, fmt.Sprintf(getLogSpecPrepareErrMsg, err))
+				commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.InternalError,
+					fmt.Sprintf(getLogSpecPrepareErrMsg, err))
 				return
 			}
 		}
@@ -161,7 +162,8 @@ func getLogSpec(rw http.ResponseWriter, response StringBuilder) {
 
 	_, err := response.Write([]byte(defaultDebugLevel))
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, fmt.Sprintf(getLogSpecPrepareErrMsg, err))
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf(getLogSpecPrepareErrMsg, err))
 		return
 	}
 
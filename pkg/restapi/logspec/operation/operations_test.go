@@ -77,7 +77,7 @@ func TestLogSpecPut(t *testing.T) {
 		err = json.Unmarshal(rr.Body.Bytes(), &response)
 		require.NoError(t, err)
 
-		require.Equal(t, fmt.Sprintf(invalidLogSpec, "EOF"), response.Message)
+		require.Equal(t, "Invalid request", response.Message)
 
 		// Log levels should remain at the default setting of "info"
 		require.Equal(t, log.INFO, log.GetLevel("restapi"))
@@ -14,19 +14,70 @@ type UNIRegistrar struct {
 	Options   map[string]string `json:"options,omitempty"`
 }
 
+// DIDUpdate changes of a DID document
+type DIDUpdate struct {
+	AddServices               []DIDService            `json:"addServices,omitempty"`
+	RemoveServices            []string                `json:"removeServices,omitempty"`
+	AddVerificationMethods    []DIDVerificationMethod `json:"addVerificationMethods,omitempty"`
+	RemoveVerificationMethods []string                `json:"removeVerificationMethods,omitempty"`
+}
+
+// DIDService service endpoint of a DID document (e.g. DIDComm messaging, credential status)
+type DIDService struct {
+	ID              string   `json:"id"`
+	Type            string   `json:"type"`
+	ServiceEndpoint string   `json:"serviceEndpoint"`
+	Priority        uint     `json:"priority,omitempty"`
+	RecipientKeys   []string `json:"recipientKeys,omitempty"`
+	RoutingKeys     []string `json:"routingKeys,omitempty"`
+}
+
+// DIDVerificationMethod public key of a DID document
+type DIDVerificationMethod struct {
+	ID string `json:"id"`
+	// Type is Ed25519VerificationKey2018 or JwsVerificationKey2020
+	Type string `json:"type"`
+	// KeyType is Ed25519 or P256
+	KeyType         string `json:"keyType"`
+	PublicKeyBase58 string `json:"publicKeyBase58"`
+	// Purposes are the verification relationships of the key: authentication, assertionMethod,
+	// capabilityDelegation and capabilityInvocation. Defaults to authentication and assertionMethod.
+	Purposes []string `json:"purposes,omitempty"`
+}
+
 // ErrorResponse to send error message in the response
 type ErrorResponse struct {
+	// Code is the machine-readable code of the error (e.g. INVALID_REQUEST, EDV_ERROR)
+	Code    string `json:"code,omitempty"`
 	Message string `json:"errMessage,omitempty"`
+	Details string `json:"details,omitempty"`
+	// Fields are the invalid fields of the request
+	Fields        []FieldError `json:"fields,omitempty"`
+	CorrelationID string       `json:"correlationID,omitempty"`
+}
+
+// FieldError is the validation failure of a request field, identified by its JSON pointer
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // DataProfile struct for profile
 type DataProfile struct { // nolint: unused
-	Name                    string     `json:"name,omitempty"`
-	DID                     string     `json:"did,omitempty"`
-	URI                     string     `json:"uri,omitempty"`
-	SignatureType           string     `json:"signatureType,omitempty"`
-	SignatureRepresentation int        `json:"signatureRepresentation,omitempty"`
-	Creator                 string     `json:"creator,omitempty"`
-	Created                 *time.Time `json:"created,omitempty"`
-	DIDPrivateKey           string     `json:"didPrivateKey,omitempty"`
+	Name                    string       `json:"name,omitempty"`
+	DID                     string       `json:"did,omitempty"`
+	URI                     string       `json:"uri,omitempty"`
+	SignatureType           string       `json:"signatureType,omitempty"`
+	SignatureRepresentation int          `json:"signatureRepresentation,omitempty"`
+	Creator                 string       `json:"creator,omitempty"`
+	Created                 *time.Time   `json:"created,omitempty"`
+	DIDPrivateKey           string       `json:"didPrivateKey,omitempty"`
+	PreviousCreators        []string     `json:"previousCreators,omitempty"`
+	SigningKeys             []SigningKey `json:"signingKeys,omitempty"`
+}
+
+// SigningKey additional signing key of the profile
+type SigningKey struct {
+	ID            string `json:"id,omitempty"`
+	SignatureType string `json:"signatureType,omitempty"`
 }
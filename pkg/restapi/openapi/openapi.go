@@ -0,0 +1,316 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	// Endpoint is the path of the OpenAPI document
+	Endpoint = "/openapi.json"
+
+	openAPIVersion = "3.0.3"
+
+	errorResponseSchema = "ErrorResponse"
+	jsonContentType     = "application/json"
+
+	bearerAuthScheme = "bearerAuth"
+	apiKeyAuthScheme = "apiKeyAuth"
+)
+
+var logger = log.New("edge-service-openapi-restapi")
+
+// the path parameters of the mux path templates, with their optional pattern ({name} or {name:pattern})
+var pathParamRegex = regexp.MustCompile(`{([^{}:]+)(:[^{}]*)?}`)
+
+// Handler is a REST handler documented by the generator
+type Handler interface {
+	Path() string
+	Method() string
+}
+
+// Document is an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+	Tags       []Tag                 `json:"tags,omitempty"`
+}
+
+// Info is the title and version of the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Server is the URL the paths of the API are relative to
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Tag groups the operations of a mode of the service
+type Tag struct {
+	Name string `json:"name"`
+}
+
+// PathItem are the operations of a path, by lowercase method
+type PathItem map[string]*Operation
+
+// Operation is an operation of the API
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a parameter of an operation
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is the body of the request of an operation
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response is a response of an operation
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of a request or response body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components are the schemas and security schemes referenced by the operations
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is an authentication of the requests
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+	In     string `json:"in,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+// Option configures the generator
+type Option func(g *Generator)
+
+// WithBearerAuth option documents the requests as authenticated with the API token of the service
+func WithBearerAuth() Option {
+	return func(g *Generator) {
+		g.addSecurityScheme(bearerAuthScheme, &SecurityScheme{Type: "http", Scheme: "bearer"})
+	}
+}
+
+// WithAPIKeyAuth option documents the requests as authenticated with the API key of the client in the header
+func WithAPIKeyAuth(header string) Option {
+	return func(g *Generator) {
+		g.addSecurityScheme(apiKeyAuthScheme, &SecurityScheme{Type: "apiKey", In: "header", Name: header})
+	}
+}
+
+// WithServerURL option sets the URL the paths are relative to, e.g. the external URL of the service behind a reverse
+// proxy
+func WithServerURL(u string) Option {
+	return func(g *Generator) {
+		g.doc.Servers = append(g.doc.Servers, Server{URL: u})
+	}
+}
+
+// Generator builds the OpenAPI document of the REST handlers registered by the service, each failed operation
+// returning the error response of the REST API
+type Generator struct {
+	doc   *Document
+	mutex sync.RWMutex
+}
+
+// New returns the generator of the OpenAPI document of the API
+func New(title, version string, opts ...Option) *Generator {
+	g := &Generator{doc: &Document{
+		OpenAPI: openAPIVersion,
+		Info:    Info{Title: title, Version: version},
+		Paths:   map[string]PathItem{},
+		Components: Components{Schemas: map[string]*Schema{
+			errorResponseSchema: errorSchema(),
+		}},
+	}}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// Add documents the handler registered by the service, grouped under the tag (e.g. the mode of the handler)
+func (g *Generator) Add(tag string, handler Handler) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	path, params := openAPIPath(handler.Path())
+	method := strings.ToLower(handler.Method())
+
+	pathItem, ok := g.doc.Paths[path]
+	if !ok {
+		pathItem = PathItem{}
+		g.doc.Paths[path] = pathItem
+	}
+
+	op := &Operation{
+		OperationID: operationID(method, path),
+		Responses: map[string]*Response{
+			"2XX": {Description: "Success"},
+			"default": {Description: "Error", Content: map[string]*MediaType{
+				jsonContentType: {Schema: &Schema{Ref: "#/components/schemas/" + errorResponseSchema}},
+			}},
+		},
+	}
+
+	if tag != "" {
+		op.Tags = []string{tag}
+		g.addTag(tag)
+	}
+
+	for _, param := range params {
+		op.Parameters = append(op.Parameters, &Parameter{Name: param, In: "path", Required: true,
+			Schema: &Schema{Type: "string"}})
+	}
+
+	switch handler.Method() {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		op.RequestBody = &RequestBody{Required: true, Content: map[string]*MediaType{
+			jsonContentType: {Schema: &Schema{Type: "object"}},
+		}}
+	}
+
+	pathItem[method] = op
+}
+
+// Document returns the OpenAPI document of the handlers added so far
+func (g *Generator) Document() ([]byte, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	return json.Marshal(g.doc)
+}
+
+// ServeHTTP serves the OpenAPI document
+func (g *Generator) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	docBytes, err := g.Document()
+	if err != nil {
+		commhttp.WriteErrorResponseWithDetails(rw, http.StatusInternalServerError, commhttp.InternalError,
+			"failed to marshal OpenAPI document", err.Error())
+
+		return
+	}
+
+	rw.Header().Set("Content-Type", jsonContentType)
+
+	if _, err = rw.Write(docBytes); err != nil {
+		logger.Errorf("failed to write OpenAPI document: %s", err)
+	}
+}
+
+func (g *Generator) addSecurityScheme(name string, scheme *SecurityScheme) {
+	if g.doc.Components.SecuritySchemes == nil {
+		g.doc.Components.SecuritySchemes = map[string]*SecurityScheme{}
+	}
+
+	g.doc.Components.SecuritySchemes[name] = scheme
+
+	// the requests are authenticated with all the schemes
+	if len(g.doc.Security) == 0 {
+		g.doc.Security = []map[string][]string{{}}
+	}
+
+	g.doc.Security[0][name] = []string{}
+}
+
+func (g *Generator) addTag(tag string) {
+	for _, t := range g.doc.Tags {
+		if t.Name == tag {
+			return
+		}
+	}
+
+	g.doc.Tags = append(g.doc.Tags, Tag{Name: tag})
+}
+
+// openAPIPath returns the OpenAPI path of the mux path template, without the patterns of its parameters, and the
+// names of its parameters
+func openAPIPath(template string) (string, []string) {
+	var params []string
+
+	path := pathParamRegex.ReplaceAllStringFunc(template, func(param string) string {
+		name := pathParamRegex.FindStringSubmatch(param)[1]
+		params = append(params, name)
+
+		return "{" + name + "}"
+	})
+
+	return path, params
+}
+
+// operationID returns the ID of the operation, the camel-cased method and path segments
+// (e.g. postProfileIDCredentialsIssueCredential for POST /{profileID}/credentials/issueCredential)
+func operationID(method, path string) string {
+	id := method
+
+	segments := strings.FieldsFunc(path, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, segment := range segments {
+		id += strings.ToUpper(segment[:1]) + segment[1:]
+	}
+
+	return id
+}
+
+// errorSchema returns the schema of the error responses, with the stable codes of the errors
+func errorSchema() *Schema {
+	schema := schemaOf(commhttp.ErrorResponse{})
+
+	codes := commhttp.ErrorCodes()
+	enum := make([]string, len(codes))
+
+	for i, code := range codes {
+		enum[i] = string(code)
+	}
+
+	sort.Strings(enum)
+
+	schema.Properties["code"].Enum = enum
+
+	return schema
+}
@@ -0,0 +1,130 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator(t *testing.T) {
+	g := New("VC Service", "v1")
+
+	g.Add("issuer", &mockHandler{path: "/{profileID}/credentials/issueCredential", method: http.MethodPost})
+	g.Add("issuer", &mockHandler{path: "/profile/{id}", method: http.MethodGet})
+	g.Add("issuer", &mockHandler{path: "/profile/{id}", method: http.MethodDelete})
+	g.Add("verifier", &mockHandler{path: "/verifier/{id:[a-z]+}/credentials", method: http.MethodPost})
+	g.Add("", &mockHandler{path: "/healthcheck", method: http.MethodGet})
+
+	doc := document(t, g)
+
+	t.Run("test paths", func(t *testing.T) {
+		require.Equal(t, "3.0.3", doc.OpenAPI)
+		require.Equal(t, Info{Title: "VC Service", Version: "v1"}, doc.Info)
+		require.Len(t, doc.Paths, 4)
+		require.Equal(t, []Tag{{Name: "issuer"}, {Name: "verifier"}}, doc.Tags)
+		require.Empty(t, doc.Security)
+		require.Empty(t, doc.Servers)
+
+		profile := doc.Paths["/profile/{id}"]
+		require.Len(t, profile, 2)
+		require.Equal(t, "getProfileId", profile["get"].OperationID)
+		require.Equal(t, "deleteProfileId", profile["delete"].OperationID)
+		require.Nil(t, profile["get"].RequestBody)
+		require.Equal(t, []string{"issuer"}, profile["get"].Tags)
+
+		require.Empty(t, doc.Paths["/healthcheck"]["get"].Tags)
+	})
+
+	t.Run("test path parameters", func(t *testing.T) {
+		op := doc.Paths["/{profileID}/credentials/issueCredential"]["post"]
+		require.Equal(t, "postProfileIDCredentialsIssueCredential", op.OperationID)
+		require.Equal(t, []*Parameter{{Name: "profileID", In: "path", Required: true,
+			Schema: &Schema{Type: "string"}}}, op.Parameters)
+		require.NotNil(t, op.RequestBody)
+
+		// the pattern of the parameter is not part of the path
+		op = doc.Paths["/verifier/{id}/credentials"]["post"]
+		require.NotNil(t, op)
+		require.Equal(t, "id", op.Parameters[0].Name)
+	})
+
+	t.Run("test error responses", func(t *testing.T) {
+		op := doc.Paths["/profile/{id}"]["get"]
+		require.Equal(t, "#/components/schemas/ErrorResponse",
+			op.Responses["default"].Content[jsonContentType].Schema.Ref)
+		require.Equal(t, "Success", op.Responses["2XX"].Description)
+
+		errSchema := doc.Components.Schemas["ErrorResponse"]
+		require.Equal(t, "object", errSchema.Type)
+		require.Contains(t, errSchema.Properties["code"].Enum, "PROFILE_NOT_FOUND")
+		require.Equal(t, "string", errSchema.Properties["errMessage"].Type)
+		require.Equal(t, "array", errSchema.Properties["fields"].Type)
+		require.Equal(t, []string{"field", "message"}, errSchema.Properties["fields"].Items.Required)
+		require.Equal(t, "string", errSchema.Properties["violations"].Items.Properties["rule"].Type)
+		require.Empty(t, errSchema.Required)
+	})
+}
+
+func TestGenerator_Security(t *testing.T) {
+	doc := document(t, New("VC Service", "v1", WithBearerAuth(), WithAPIKeyAuth("X-API-Key")))
+
+	require.Equal(t, &SecurityScheme{Type: "http", Scheme: "bearer"}, doc.Components.SecuritySchemes["bearerAuth"])
+	require.Equal(t, &SecurityScheme{Type: "apiKey", In: "header", Name: "X-API-Key"},
+		doc.Components.SecuritySchemes["apiKeyAuth"])
+
+	// both the token and the API key are required
+	require.Equal(t, []map[string][]string{{"bearerAuth": {}, "apiKeyAuth": {}}}, doc.Security)
+}
+
+func TestGenerator_ServerURL(t *testing.T) {
+	doc := document(t, New("VC Service", "v1", WithServerURL("https://example.com/vcs")))
+
+	require.Equal(t, []Server{{URL: "https://example.com/vcs"}}, doc.Servers)
+}
+
+func TestGenerator_ServeHTTP(t *testing.T) {
+	g := New("VC Service", "v1")
+	g.Add("holder", &mockHandler{path: "/holder/{id}/prove/presentations", method: http.MethodPost})
+
+	rr := httptest.NewRecorder()
+	g.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, Endpoint, nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	doc := &Document{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), doc))
+	require.Contains(t, doc.Paths, "/holder/{id}/prove/presentations")
+}
+
+func document(t *testing.T, g *Generator) *Document {
+	docBytes, err := g.Document()
+	require.NoError(t, err)
+
+	doc := &Document{}
+	require.NoError(t, json.Unmarshal(docBytes, doc))
+
+	return doc
+}
+
+type mockHandler struct {
+	path   string
+	method string
+}
+
+func (m *mockHandler) Path() string {
+	return m.path
+}
+
+func (m *mockHandler) Method() string {
+	return m.method
+}
@@ -0,0 +1,56 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type embedded struct {
+	ID string `json:"id"`
+}
+
+type example struct {
+	embedded
+	Name      string                 `json:"name"`
+	Count     int                    `json:"count,omitempty"`
+	Ratio     float64                `json:"ratio,omitempty"`
+	Enabled   *bool                  `json:"enabled,omitempty"`
+	Created   time.Time              `json:"created"`
+	Tags      []string               `json:"tags,omitempty"`
+	Data      []byte                 `json:"data,omitempty"`
+	Claims    map[string]interface{} `json:"claims,omitempty"`
+	Raw       json.RawMessage        `json:"raw,omitempty"`
+	Untagged  string
+	Ignored   string `json:"-"`
+	unexposed string
+}
+
+func TestSchemaOf(t *testing.T) {
+	schema := schemaOf(&example{unexposed: "unexposed"})
+
+	require.Equal(t, "object", schema.Type)
+	require.Equal(t, []string{"id", "name", "created", "Untagged"}, schema.Required)
+	require.Len(t, schema.Properties, 11)
+
+	require.Equal(t, &Schema{Type: "string"}, schema.Properties["id"])
+	require.Equal(t, &Schema{Type: "integer"}, schema.Properties["count"])
+	require.Equal(t, &Schema{Type: "number"}, schema.Properties["ratio"])
+	require.Equal(t, &Schema{Type: "boolean"}, schema.Properties["enabled"])
+	require.Equal(t, &Schema{Type: "string", Format: "date-time"}, schema.Properties["created"])
+	require.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}}, schema.Properties["tags"])
+	require.Equal(t, &Schema{Type: "string", Format: "byte"}, schema.Properties["data"])
+	require.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{}}, schema.Properties["claims"])
+	require.Equal(t, &Schema{}, schema.Properties["raw"])
+	require.Equal(t, &Schema{Type: "string"}, schema.Properties["Untagged"])
+	require.NotContains(t, schema.Properties, "Ignored")
+	require.NotContains(t, schema.Properties, "unexposed")
+}
@@ -0,0 +1,23 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package resolver
+
+import (
+	"testing"
+
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/edge-service/pkg/restapi/resolver/operation"
+)
+
+func TestController_GetOperations(t *testing.T) {
+	controller := New(&operation.Config{VDRI: &vdrimock.MockVDRIRegistry{}})
+	require.NotNil(t, controller)
+
+	require.Equal(t, 1, len(controller.GetOperations()))
+}
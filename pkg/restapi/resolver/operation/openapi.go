@@ -0,0 +1,38 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"github.com/trustbloc/edge-service/pkg/restapi/model"
+)
+
+// genericError model
+//
+// swagger:response genericError
+type genericError struct { // nolint: unused,deadcode
+	// in: body
+	model.ErrorResponse
+}
+
+// resolveDIDReq model
+//
+// swagger:parameters resolveDIDReq
+type resolveDIDReq struct { // nolint: unused,deadcode
+	// DID to resolve
+	//
+	// in: query
+	// required: true
+	DID string `json:"did"`
+}
+
+// resolveDIDRes model
+//
+// swagger:response resolveDIDRes
+type resolveDIDRes struct { // nolint: unused,deadcode
+	// in: body
+	Document map[string]interface{}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	"github.com/trustbloc/edge-core/pkg/log"
+
+	"github.com/trustbloc/edge-service/pkg/cache"
+	"github.com/trustbloc/edge-service/pkg/cache/memcache"
+	"github.com/trustbloc/edge-service/pkg/internal/common/support"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	resolveDIDEndpoint = "/resolveDID"
+	didQueryParam      = "did"
+
+	didDocumentContentType = "application/did+json"
+
+	// DefaultCacheSize is the number of DID documents cached if the size is not configured
+	DefaultCacheSize = 1000
+)
+
+var logger = log.New("edge-service-resolver-restapi")
+
+// Handler represents an HTTP handler for each controller API endpoint
+type Handler interface {
+	Path() string
+	Method() string
+	Handle() http.HandlerFunc
+}
+
+// Config defines configuration for the DID resolution operations
+type Config struct {
+	VDRI vdriapi.Registry
+	// CacheTTL is how long the resolved documents are cached, also advertised to the clients through the
+	// Cache-Control header. The documents are not cached if not set.
+	CacheTTL time.Duration
+	// CacheSize is the maximum number of cached documents, DefaultCacheSize if not set
+	CacheSize int
+}
+
+// Operation defines handlers for the DID resolution
+type Operation struct {
+	vdri     vdriapi.Registry
+	cache    cache.Cache
+	cacheTTL time.Duration
+}
+
+// New returns the DID resolution operations
+func New(config *Config) *Operation {
+	o := &Operation{vdri: config.VDRI, cacheTTL: config.CacheTTL}
+
+	if config.CacheTTL > 0 {
+		size := config.CacheSize
+		if size <= 0 {
+			size = DefaultCacheSize
+		}
+
+		o.cache = memcache.New(size, config.CacheTTL)
+	}
+
+	return o
+}
+
+// GetRESTHandlers get all controller API handler available for this service
+func (o *Operation) GetRESTHandlers() []Handler {
+	return []Handler{
+		support.NewHTTPHandler(resolveDIDEndpoint, http.MethodGet, o.resolveDIDHandler),
+	}
+}
+
+// ResolveDID swagger:route GET /resolveDID resolver resolveDIDReq
+//
+// Resolves a DID with the DID methods supported by the service. The resolved documents are cached.
+//
+// Responses:
+//    default: genericError
+//        200: resolveDIDRes
+func (o *Operation) resolveDIDHandler(rw http.ResponseWriter, req *http.Request) {
+	didID := req.URL.Query().Get(didQueryParam)
+	if didID == "" {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest, "missing did")
+
+		return
+	}
+
+	docBytes, ok := o.getCached(didID)
+	if !ok {
+		var err error
+
+		docBytes, err = o.resolve(didID)
+		if err != nil {
+			if errors.Is(err, vdriapi.ErrNotFound) {
+				commhttp.WriteErrorResponse(rw, http.StatusNotFound, commhttp.NotFound,
+					fmt.Sprintf("did %s not found", didID))
+
+				return
+			}
+
+			commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.DIDError,
+				fmt.Sprintf("failed to resolve did %s: %s", didID, err.Error()))
+
+			return
+		}
+	}
+
+	rw.Header().Set("Content-Type", didDocumentContentType)
+	rw.Header().Set("Cache-Control", o.cacheControl())
+
+	if _, err := rw.Write(docBytes); err != nil {
+		logger.Errorf("Failed to write did document: %s", err.Error())
+	}
+}
+
+// resolve resolves the did and caches its document, the failed resolutions are not cached
+func (o *Operation) resolve(didID string) ([]byte, error) {
+	didDoc, err := o.vdri.Resolve(didID)
+	if err != nil {
+		return nil, err
+	}
+
+	docBytes, err := didDoc.JSONBytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal did document: %w", err)
+	}
+
+	if o.cache != nil {
+		o.cache.Set(didID, docBytes)
+	}
+
+	return docBytes, nil
+}
+
+func (o *Operation) getCached(didID string) ([]byte, bool) {
+	if o.cache == nil {
+		return nil, false
+	}
+
+	return o.cache.Get(didID)
+}
+
+func (o *Operation) cacheControl() string {
+	if o.cache == nil {
+		return "no-cache"
+	}
+
+	return fmt.Sprintf("public, max-age=%d", int(o.cacheTTL.Seconds()))
+}
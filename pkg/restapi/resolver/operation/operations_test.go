@@ -0,0 +1,97 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+)
+
+const testDID = "did:example:123"
+
+func TestResolveDID(t *testing.T) {
+	t.Run("test success - cached", func(t *testing.T) {
+		resolved := 0
+
+		registry := &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, _ ...vdriapi.ResolveOpts) (*did.Doc, error) {
+				resolved++
+
+				return &did.Doc{Context: []string{did.Context}, ID: didID}, nil
+			},
+		}
+
+		op := New(&Config{VDRI: registry, CacheTTL: time.Minute})
+
+		for i := 0; i < 2; i++ {
+			rr := resolveDID(op, testDID)
+			require.Equal(t, http.StatusOK, rr.Code)
+			require.Equal(t, "application/did+json", rr.Header().Get("Content-Type"))
+			require.Equal(t, "public, max-age=60", rr.Header().Get("Cache-Control"))
+
+			doc, err := did.ParseDocument(rr.Body.Bytes())
+			require.NoError(t, err)
+			require.Equal(t, testDID, doc.ID)
+		}
+
+		require.Equal(t, 1, resolved)
+	})
+
+	t.Run("test success - not cached", func(t *testing.T) {
+		op := New(&Config{VDRI: &vdrimock.MockVDRIRegistry{
+			ResolveValue: &did.Doc{Context: []string{did.Context}, ID: testDID}}})
+
+		rr := resolveDID(op, testDID)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, "no-cache", rr.Header().Get("Cache-Control"))
+	})
+
+	t.Run("test error - missing did", func(t *testing.T) {
+		op := New(&Config{VDRI: &vdrimock.MockVDRIRegistry{}})
+
+		rr := resolveDID(op, "")
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "missing did")
+	})
+
+	t.Run("test error - did not found", func(t *testing.T) {
+		op := New(&Config{VDRI: &vdrimock.MockVDRIRegistry{}, CacheTTL: time.Minute})
+
+		rr := resolveDID(op, testDID)
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		require.Contains(t, rr.Body.String(), "did did:example:123 not found")
+	})
+
+	t.Run("test error - failed to resolve did", func(t *testing.T) {
+		op := New(&Config{VDRI: &vdrimock.MockVDRIRegistry{ResolveErr: errors.New("resolve error")},
+			CacheTTL: time.Minute})
+
+		rr := resolveDID(op, testDID)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "failed to resolve did did:example:123: resolve error")
+
+		_, cached := op.cache.Get(testDID)
+		require.False(t, cached)
+	})
+}
+
+func resolveDID(op *Operation, didID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, resolveDIDEndpoint+"?did="+didID, nil)
+	rr := httptest.NewRecorder()
+
+	op.GetRESTHandlers()[0].Handle().ServeHTTP(rr, req)
+
+	return rr
+}
@@ -23,15 +23,21 @@ func New(config *operation.Config) (*Controller, error) {
 
 	allHandlers = append(allHandlers, handlers...)
 
-	return &Controller{handlers: allHandlers}, nil
+	return &Controller{handlers: allHandlers, operation: holderService}, nil
 }
 
 // Controller contains handlers for controller
 type Controller struct {
-	handlers []operation.Handler
+	handlers  []operation.Handler
+	operation *operation.Operation
 }
 
 // GetOperations returns all controller endpoints
 func (c *Controller) GetOperations() []operation.Handler {
 	return c.handlers
 }
+
+// Operation returns the operation of the controller endpoints, also served by the gRPC API
+func (c *Controller) Operation() *operation.Operation {
+	return c.operation
+}
@@ -26,6 +26,7 @@ func TestController_New(t *testing.T) {
 		})
 		require.NoError(t, err)
 		require.NotNil(t, controller)
+		require.NotNil(t, controller.Operation())
 	})
 
 	t.Run("test failure", func(t *testing.T) {
@@ -49,5 +50,5 @@ func TestController_GetOperations(t *testing.T) {
 
 	ops := controller.GetOperations()
 
-	require.Equal(t, 4, len(ops))
+	require.Equal(t, 13, len(ops))
 }
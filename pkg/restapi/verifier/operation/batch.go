@@ -0,0 +1,274 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	batchVerificationEndpoint = credentialsVerificationEndpoint + "/verifyBatch"
+
+	// DefaultMaxBatchSize is the maximum number of credentials of a batch verification, unless configured
+	DefaultMaxBatchSize = 100
+
+	// batchConcurrency is the number of credentials of a batch verified concurrently
+	batchConcurrency = 10
+
+	didCacheKeyPrefix    = "did/"
+	statusCacheKeyPrefix = "status/"
+)
+
+// batchCacheKey is the context key of the cache shared by the verifications of the credentials of a batch
+type batchCacheKey struct{}
+
+// batchCache caches the DID documents and status lists fetched by the verifications of a batch, each fetched once
+// however many credentials of the batch share it. The cache lives as long as the batch verification.
+type batchCache struct {
+	mutex   sync.Mutex
+	entries map[string]*batchCacheEntry
+}
+
+// batchCacheEntry is a fetched value, or the error fetching it, available once done is closed
+type batchCacheEntry struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// cachedStatusList is a status list fetched for a batch, with its version
+type cachedStatusList struct {
+	list    []byte
+	version string
+}
+
+func newBatchCache() *batchCache {
+	return &batchCache{entries: make(map[string]*batchCacheEntry)}
+}
+
+// batchCacheFrom returns the cache of the batch verified with the context, nil if the context isn't of a batch
+func batchCacheFrom(ctx context.Context) *batchCache {
+	cache, ok := ctx.Value(batchCacheKey{}).(*batchCache)
+	if !ok {
+		return nil
+	}
+
+	return cache
+}
+
+// get returns the value of the key, fetched on the first get of the key, the concurrent gets waiting for it
+func (c *batchCache) get(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	c.mutex.Lock()
+	entry, ok := c.entries[key]
+
+	if !ok {
+		entry = &batchCacheEntry{done: make(chan struct{})}
+		c.entries[key] = entry
+	}
+	c.mutex.Unlock()
+
+	if ok {
+		<-entry.done
+
+		return entry.value, entry.err
+	}
+
+	defer close(entry.done)
+
+	entry.value, entry.err = fetch()
+
+	return entry.value, entry.err
+}
+
+// batchResolver resolves the DIDs through the cache of a batch
+type batchResolver struct {
+	vdriapi.Registry
+	cache *batchCache
+}
+
+// Resolve resolves the DID once for the batch, the resolutions with options not being cached
+func (r *batchResolver) Resolve(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+	if len(opts) > 0 {
+		return r.Registry.Resolve(didID, opts...)
+	}
+
+	doc, err := r.cache.get(didCacheKeyPrefix+didID, func() (interface{}, error) {
+		return r.Registry.Resolve(didID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.(*did.Doc), nil
+}
+
+// resolver returns the registry resolving the DIDs of the verification, through the cache of the batch if the
+// verification is of a batch
+func (o *Operation) resolver(ctx context.Context) vdriapi.Registry {
+	if cache := batchCacheFrom(ctx); cache != nil {
+		return &batchResolver{Registry: o.vdri, cache: cache}
+	}
+
+	return o.vdri
+}
+
+// fetchBatchStatusList fetches the status list once for the batch, the list being unchanged for the batch if its
+// version is given
+func (o *Operation) fetchBatchStatusList(ctx context.Context, cache *batchCache, url,
+	version string) ([]byte, string, error) {
+	fetched, err := cache.get(statusCacheKeyPrefix+url, func() (interface{}, error) {
+		list, listVersion, err := o.requestStatusList(ctx, url, "")
+		if err != nil {
+			return nil, err
+		}
+
+		return &cachedStatusList{list: list, version: listVersion}, nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	statusList := fetched.(*cachedStatusList)
+
+	if version != "" && version == statusList.version {
+		return nil, version, nil
+	}
+
+	return statusList.list, statusList.version, nil
+}
+
+// VerifyCredentialBatch swagger:route POST /{id}/verifier/credentials/verifyBatch verifier verifyBatchReq
+//
+// Verifies a batch of credentials concurrently, the DID documents and status lists shared by the credentials being
+// fetched once for the batch. The results of the credentials are returned in the order of the batch, the batch
+// being verified if all its credentials are.
+//
+// Responses:
+//    default: genericError
+//        200: verifyBatchResp
+func (o *Operation) verifyBatchHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getVerifierProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	batchReq := BatchVerificationRequest{}
+
+	err = commhttp.DecodeJSON(req, &batchReq)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if err = o.validateBatch(profile, &batchReq); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	resp := &BatchVerificationResponse{Verified: true, Results: o.verifyBatch(req.Context(), profile, &batchReq)}
+
+	for i := range resp.Results {
+		if !resp.Results[i].Verified {
+			resp.Verified = false
+		}
+	}
+
+	rw.WriteHeader(http.StatusOK)
+	commhttp.WriteResponse(rw, resp)
+}
+
+// validateBatch checks the batch has credentials, no more than the maximum batch size
+func (o *Operation) validateBatch(profile *verifier.ProfileData, batchReq *BatchVerificationRequest) error {
+	if len(batchReq.Credentials) == 0 {
+		return commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest, "no credentials to verify")
+	}
+
+	if len(batchReq.Credentials) > o.maxBatchSize {
+		return commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("the batch has %d credentials, more than the maximum of %d", len(batchReq.Credentials),
+				o.maxBatchSize))
+	}
+
+	return checkReceiptKey(profile, batchReq.Opts != nil && batchReq.Opts.Receipt)
+}
+
+// verifyBatch verifies the credentials of the batch, batchConcurrency at a time, with a cache shared by their
+// verifications
+func (o *Operation) verifyBatch(ctx context.Context, profile *verifier.ProfileData,
+	batchReq *BatchVerificationRequest) []BatchVerificationResult {
+	ctx = context.WithValue(ctx, batchCacheKey{}, newBatchCache())
+
+	results := make([]BatchVerificationResult, len(batchReq.Credentials))
+
+	// the buffered channel bounds the number of credentials verified concurrently
+	slots := make(chan struct{}, batchConcurrency)
+
+	var wg sync.WaitGroup
+
+	for i, credential := range batchReq.Credentials {
+		wg.Add(1)
+
+		slots <- struct{}{}
+
+		go func(i int, credential json.RawMessage) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			results[i] = o.verifyBatchCredential(ctx, profile, credential, batchReq.Opts)
+		}(i, credential)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// verifyBatchCredential verifies a credential of the batch, the credential failing its verification if it can't
+// be verified
+func (o *Operation) verifyBatchCredential(ctx context.Context, profile *verifier.ProfileData,
+	credential json.RawMessage, opts *CredentialsVerificationOptions) BatchVerificationResult {
+	result := BatchVerificationResult{ID: documentID(credential)}
+
+	checks, failed, err := o.verifyCredential(ctx, profile,
+		&CredentialsVerificationRequest{Credential: credential, Opts: opts})
+	if err != nil {
+		result.Error = err.Error()
+
+		return result
+	}
+
+	result.Checks = checks
+	result.FailedChecks = failed
+	result.Verified = len(failed) == 0
+
+	if opts != nil && opts.Receipt {
+		result.Receipt, err = o.newReceipt(profile, verifiableCredential, credential, checks,
+			failedCredentialChecks(failed))
+		if err != nil {
+			result.Error = err.Error()
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,205 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+)
+
+func TestVerifyBatch(t *testing.T) {
+	list := &statusListServer{etag: `"v1"`}
+
+	srv := httptest.NewServer(list)
+	defer srv.Close()
+
+	credential := func(t *testing.T, id string) json.RawMessage {
+		vc, err := verifiable.ParseUnverifiedCredential([]byte(prCardVC))
+		require.NoError(t, err)
+
+		vc.ID = id
+		vc.Status = &verifiable.TypedID{ID: srv.URL + "/status/1", Type: "CredentialStatusList2017"}
+
+		vcBytes, err := vc.MarshalJSON()
+		require.NoError(t, err)
+
+		return vcBytes
+	}
+
+	list.update(`"v1"`, &cslstatus.CSL{ID: "list1", VC: []string{`{"id":"http://example.com/credentials/3"}`}})
+
+	newOperation := func(t *testing.T, maxBatchSize int) *Operation {
+		op, err := New(&Config{VDRI: &vdrimock.MockVDRIRegistry{}, StoreProvider: memstore.NewProvider(),
+			MaxBatchSize: maxBatchSize})
+		require.NoError(t, err)
+
+		require.NoError(t, op.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test verifier",
+			CredentialChecks: []string{statusCheck}}))
+
+		return op
+	}
+
+	verify := func(t *testing.T, op *Operation, profile string,
+		batchReq *BatchVerificationRequest) *httptest.ResponseRecorder {
+		reqBytes, err := json.Marshal(batchReq)
+		require.NoError(t, err)
+
+		return serveHTTPMux(t, getHandler(t, op, batchVerificationEndpoint, http.MethodPost),
+			"/"+profile+"/verifier/credentials/verifyBatch", reqBytes, map[string]string{profileIDPathParam: profile})
+	}
+
+	t.Run("test batch verified", func(t *testing.T) {
+		var credentials []json.RawMessage
+
+		for i := 0; i < 20; i++ {
+			credentials = append(credentials, credential(t, fmt.Sprintf("http://example.com/credentials/%d", i)))
+		}
+
+		credentials = append(credentials, []byte(`{"id":"invalid"}`))
+
+		rr := verify(t, newOperation(t, 0), "test", &BatchVerificationRequest{Credentials: credentials})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		resp := &BatchVerificationResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		require.False(t, resp.Verified)
+		require.Len(t, resp.Results, 21)
+
+		for i, result := range resp.Results[:20] {
+			require.Equal(t, fmt.Sprintf("http://example.com/credentials/%d", i), result.ID)
+			require.Equal(t, []string{statusCheck}, result.Checks)
+			require.Empty(t, result.Error)
+
+			if i == 3 {
+				require.False(t, result.Verified)
+				require.Len(t, result.FailedChecks, 1)
+				require.Equal(t, statusCheck, result.FailedChecks[0].Check)
+			} else {
+				require.True(t, result.Verified)
+				require.Empty(t, result.FailedChecks)
+			}
+		}
+
+		require.False(t, resp.Results[20].Verified)
+		require.Equal(t, "invalid", resp.Results[20].ID)
+		require.Contains(t, resp.Results[20].Error, invalidRequestErrMsg)
+
+		// the status list shared by the credentials is fetched once for the batch
+		require.Equal(t, 1, list.fetches())
+	})
+
+	t.Run("test all credentials verified", func(t *testing.T) {
+		rr := verify(t, newOperation(t, 0), "test", &BatchVerificationRequest{
+			Credentials: []json.RawMessage{credential(t, "http://example.com/credentials/1")}})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), `"verified":true`)
+	})
+
+	t.Run("test error - no credentials", func(t *testing.T) {
+		rr := verify(t, newOperation(t, 0), "test", &BatchVerificationRequest{})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "no credentials to verify")
+	})
+
+	t.Run("test error - batch too large", func(t *testing.T) {
+		rr := verify(t, newOperation(t, 2), "test", &BatchVerificationRequest{Credentials: []json.RawMessage{
+			credential(t, "1"), credential(t, "2"), credential(t, "3")}})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "the batch has 3 credentials, more than the maximum of 2")
+	})
+
+	t.Run("test error - profile without receipt key", func(t *testing.T) {
+		rr := verify(t, newOperation(t, 0), "test", &BatchVerificationRequest{
+			Credentials: []json.RawMessage{credential(t, "1")},
+			Opts:        &CredentialsVerificationOptions{Receipt: true}})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "verifier profile test has no receipt key")
+	})
+
+	t.Run("test error - invalid profile", func(t *testing.T) {
+		rr := verify(t, newOperation(t, 0), "unknown", &BatchVerificationRequest{
+			Credentials: []json.RawMessage{credential(t, "1")}})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid verifier profile")
+	})
+
+	t.Run("test error - invalid request", func(t *testing.T) {
+		rr := serveHTTPMux(t, getHandler(t, newOperation(t, 0), batchVerificationEndpoint, http.MethodPost),
+			"/test/verifier/credentials/verifyBatch", []byte("{"), map[string]string{profileIDPathParam: "test"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestBatchResolver(t *testing.T) {
+	var (
+		resolutions int
+		mutex       sync.Mutex
+	)
+
+	registry := &vdrimock.MockVDRIRegistry{ResolveFunc: func(didID string,
+		opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+		mutex.Lock()
+		resolutions++
+		mutex.Unlock()
+
+		if didID == "did:test:unknown" {
+			return nil, errors.New("DID not found")
+		}
+
+		return &did.Doc{ID: didID}, nil
+	}}
+
+	op, err := New(&Config{VDRI: registry, StoreProvider: memstore.NewProvider()})
+	require.NoError(t, err)
+
+	// the DIDs aren't cached outside of a batch
+	require.Equal(t, registry, op.resolver(context.Background()))
+
+	resolver := op.resolver(context.WithValue(context.Background(), batchCacheKey{}, newBatchCache()))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			doc, errResolve := resolver.Resolve("did:test:abc")
+			require.NoError(t, errResolve)
+			require.Equal(t, "did:test:abc", doc.ID)
+
+			_, errResolve = resolver.Resolve("did:test:unknown")
+			require.EqualError(t, errResolve, "DID not found")
+		}()
+	}
+
+	wg.Wait()
+
+	require.Equal(t, 2, resolutions)
+
+	// the resolutions with options aren't cached
+	_, err = resolver.Resolve("did:test:abc", vdriapi.WithNoCache(true))
+	require.NoError(t, err)
+	require.Equal(t, 3, resolutions)
+}
@@ -0,0 +1,81 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	vcchallenge "github.com/trustbloc/edge-service/pkg/doc/vc/challenge"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	challengesEndpoint = "/" + "{" + profileIDPathParam + "}" + verifierBasePath + "/challenges"
+)
+
+type challengeStore interface {
+	Create(scope string) (*vcchallenge.Challenge, error)
+	Consume(scope, challenge string) error
+}
+
+// CreateChallenge swagger:route POST /{profileID}/verifier/challenges verifier createChallengeReq
+//
+// Returns a challenge for a presentation verified by the profile. The challenge can be used once, before it expires.
+//
+// Responses:
+//    default: genericError
+//        201: challengeRes
+func (o *Operation) createChallengeHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getVerifierProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	challenge, err := o.challenges.Create(presentationChallengeScope(profile.ID))
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, challenge)
+}
+
+// consumePresentationChallenge consumes the challenge of the verified proof of a presentation, which must have been
+// created for the profile
+func (o *Operation) consumePresentationChallenge(profile *verifier.ProfileData, proof verifiable.Proof) error {
+	proofChallenge, ok := proof[challenge].(string)
+	if !ok || proofChallenge == "" {
+		return fmt.Errorf("profile %s requires a presentation signed with a challenge of the profile", profile.ID)
+	}
+
+	err := o.challenges.Consume(presentationChallengeScope(profile.ID), proofChallenge)
+	if errors.Is(err, vcchallenge.ErrInvalidChallenge) {
+		return fmt.Errorf("presentation challenge: %w", err)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to consume the presentation challenge : %w", err)
+	}
+
+	return nil
+}
+
+// presentationChallengeScope is the scope of the challenges of the profile in the challenge store, the path of its
+// presentations being distinct from the DIDAuth challenges and from the issuer profiles
+func presentationChallengeScope(profileID string) string {
+	return "/" + profileID + verifierBasePath + "/presentations"
+}
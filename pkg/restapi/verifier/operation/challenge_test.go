@@ -0,0 +1,115 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	mockstorage "github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	vcchallenge "github.com/trustbloc/edge-service/pkg/doc/vc/challenge"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+)
+
+func TestCreateChallenge(t *testing.T) {
+	op, err := New(&Config{StoreProvider: memstore.NewProvider()})
+	require.NoError(t, err)
+
+	profile := &verifier.ProfileData{ID: "test", Name: "test", RequireChallenge: true}
+	require.NoError(t, op.profileStore.SaveProfile(profile))
+
+	handler := getHandler(t, op, challengesEndpoint, http.MethodPost)
+	urlVars := map[string]string{profileIDPathParam: profile.ID}
+
+	newChallenge := func(t *testing.T) string {
+		rr := serveHTTPMux(t, handler, "/test/verifier/challenges", nil, urlVars)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		challenge := &vcchallenge.Challenge{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), challenge))
+		require.NotEmpty(t, challenge.Challenge)
+
+		return challenge.Challenge
+	}
+
+	proof := func(challenge string) verifiable.Proof {
+		return verifiable.Proof{"type": "Ed25519Signature2018", "challenge": challenge}
+	}
+
+	t.Run("test challenge consumed once", func(t *testing.T) {
+		challenge := newChallenge(t)
+
+		require.NoError(t, op.consumePresentationChallenge(profile, proof(challenge)))
+
+		err := op.consumePresentationChallenge(profile, proof(challenge))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), vcchallenge.ErrInvalidChallenge.Error())
+	})
+
+	t.Run("test challenge of another scope", func(t *testing.T) {
+		rr := serveHTTPMux(t, getHandler(t, op, didAuthEndpoint, http.MethodPost), didAuthEndpoint, []byte(`{}`), nil)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		didAuthChallenge := &vcchallenge.Challenge{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), didAuthChallenge))
+
+		err := op.consumePresentationChallenge(profile, proof(didAuthChallenge.Challenge))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), vcchallenge.ErrInvalidChallenge.Error())
+
+		err = op.consumePresentationChallenge(&verifier.ProfileData{ID: "other"}, proof(newChallenge(t)))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), vcchallenge.ErrInvalidChallenge.Error())
+	})
+
+	t.Run("test presentation without challenge", func(t *testing.T) {
+		err := op.consumePresentationChallenge(profile, proof(""))
+		require.EqualError(t, err, "profile test requires a presentation signed with a challenge of the profile")
+
+		err = op.consumePresentationChallenge(profile, verifiable.Proof{})
+		require.EqualError(t, err, "profile test requires a presentation signed with a challenge of the profile")
+	})
+
+	t.Run("test checks of a profile requiring challenges", func(t *testing.T) {
+		require.Equal(t, []string{proofCheck}, getPresentationChecks(profile, nil))
+		require.Equal(t, []string{proofCheck, policyCheck},
+			getPresentationChecks(profile, &VerifyPresentationOptions{Checks: []string{policyCheck}}))
+		require.Equal(t, []string{policyCheck},
+			getPresentationChecks(&verifier.ProfileData{}, &VerifyPresentationOptions{Checks: []string{policyCheck}}))
+	})
+
+	t.Run("test unknown profile", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, "/unknown/verifier/challenges", nil,
+			map[string]string{profileIDPathParam: "unknown"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid verifier profile")
+	})
+
+	t.Run("test store errors", func(t *testing.T) {
+		store, err := vcchallenge.New(&mockstorage.Provider{Store: &mockstorage.MockStore{
+			Store: map[string][]byte{"challenge": []byte("{}")}, ErrPut: errors.New("put error"), ErrGet: errors.New("get error")}}, vcchallenge.DefaultTTL)
+		require.NoError(t, err)
+
+		challenges := op.challenges
+		op.challenges = store
+
+		defer func() { op.challenges = challenges }()
+
+		rr := serveHTTPMux(t, handler, "/test/verifier/challenges", nil, urlVars)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "put error")
+
+		err = op.consumePresentationChallenge(profile, proof("challenge"))
+		require.EqualError(t, err, "failed to consume the presentation challenge : failed to get challenge: get error")
+	})
+}
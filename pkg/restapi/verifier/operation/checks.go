@@ -0,0 +1,82 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+)
+
+// checkOutcome is the outcome of a verification check, failed if the failure message is set
+type checkOutcome struct {
+	failure    string
+	violations []policy.Violation
+	// statusVersion is the version of the status list of the credential, set by the status check
+	statusVersion string
+	// reason is the reason of the failure, if it isn't the default reason of the check
+	reason string
+}
+
+type indexedOutcome struct {
+	index   int
+	outcome checkOutcome
+}
+
+// runChecks runs the checks concurrently, so that a verification takes as long as its slowest check rather than
+// the sum of the network calls of its checks (DID resolution, status list, governance credential). The checks not
+// completed once the context is done fail, the outcomes being in the order of the checks.
+func runChecks(ctx context.Context, checks []string,
+	run func(ctx context.Context, check string) checkOutcome) []checkOutcome {
+	// buffered for the checks completing after the context is done not to block
+	done := make(chan indexedOutcome, len(checks))
+
+	for i, check := range checks {
+		go func(i int, check string) {
+			defer func() {
+				if r := recover(); r != nil {
+					done <- indexedOutcome{index: i, outcome: checkOutcome{failure: fmt.Sprintf("check failed: %v", r),
+						reason: reasonCheckFailed}}
+				}
+			}()
+
+			done <- indexedOutcome{index: i, outcome: run(ctx, check)}
+		}(i, check)
+	}
+
+	outcomes := make([]checkOutcome, len(checks))
+	completed := make([]bool, len(checks))
+
+	for range checks {
+		select {
+		case o := <-done:
+			outcomes[o.index] = o.outcome
+			completed[o.index] = true
+		case <-ctx.Done():
+			for i := range outcomes {
+				if !completed[i] {
+					outcomes[i] = checkOutcome{failure: fmt.Sprintf("check not completed: %s", ctx.Err()),
+						reason: reasonTimeout}
+				}
+			}
+
+			return outcomes
+		}
+	}
+
+	return outcomes
+}
+
+// checksContext returns the context of the checks of a verification, bounded by the check timeout if set
+func (o *Operation) checksContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.checkTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, o.checkTimeout)
+}
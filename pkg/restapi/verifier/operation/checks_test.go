@@ -0,0 +1,122 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+)
+
+func TestRunChecks(t *testing.T) {
+	t.Run("test checks run concurrently", func(t *testing.T) {
+		var started sync.WaitGroup
+
+		started.Add(3)
+
+		// each check waits for all the checks to be started
+		outcomes := runChecks(context.Background(), []string{"a", "b", "c"},
+			func(_ context.Context, check string) checkOutcome {
+				started.Done()
+				started.Wait()
+
+				if check == "b" {
+					return checkOutcome{failure: "b failed"}
+				}
+
+				return checkOutcome{statusVersion: check}
+			})
+
+		require.Equal(t, []checkOutcome{{statusVersion: "a"}, {failure: "b failed"}, {statusVersion: "c"}}, outcomes)
+	})
+
+	t.Run("test checks not completed", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		outcomes := runChecks(ctx, []string{"fast", "slow"}, func(ctx context.Context, check string) checkOutcome {
+			if check == "slow" {
+				<-ctx.Done()
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			return checkOutcome{}
+		})
+
+		require.Equal(t, []checkOutcome{{}, {failure: "check not completed: context deadline exceeded",
+			reason: reasonTimeout}}, outcomes)
+	})
+
+	t.Run("test check panics", func(t *testing.T) {
+		outcomes := runChecks(context.Background(), []string{"a"}, func(context.Context, string) checkOutcome {
+			panic("invalid check")
+		})
+
+		require.Equal(t, []checkOutcome{{failure: "check failed: invalid check", reason: reasonCheckFailed}}, outcomes)
+	})
+
+	t.Run("test no checks", func(t *testing.T) {
+		require.Empty(t, runChecks(context.Background(), nil, nil))
+	})
+}
+
+func TestVerifyCredential_CheckTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(prCardVC))
+	require.NoError(t, err)
+
+	vc.Status = &verifiable.TypedID{ID: srv.URL + "/status/1", Type: "CredentialStatusList2017"}
+
+	vcBytes, err := vc.MarshalJSON()
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		VDRI:          &vdrimock.MockVDRIRegistry{},
+		StoreProvider: memstore.NewProvider(),
+		CheckTimeout:  50 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test verifier",
+		CredentialChecks: []string{statusCheck, policyCheck},
+		Policy:           []policy.Rule{{Type: policy.DisallowedTypes, Values: []string{"PermanentResidentCard"}}}}))
+
+	start := time.Now()
+
+	checks, result, err := op.VerifyCredential("test", &CredentialsVerificationRequest{Credential: vcBytes})
+	require.NoError(t, err)
+	require.Equal(t, []string{statusCheck, policyCheck}, checks)
+	require.Less(t, int64(time.Since(start)), int64(time.Second))
+
+	// the failed checks are in the order of the checks
+	require.Len(t, result, 2)
+	require.Equal(t, statusCheck, result[0].Check)
+	require.Contains(t, result[0].Error, "context deadline exceeded")
+	require.Equal(t, policyCheck, result[1].Check)
+	require.Len(t, result[1].Violations, 1)
+}
@@ -0,0 +1,42 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/trustbloc/edge-service/pkg/restapi/internal/common/credentialref"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+// fetchCredential sets the credential of the request to the credential fetched from its credential URL, if any
+func (o *Operation) fetchCredential(verificationReq *CredentialsVerificationRequest) error {
+	if verificationReq.CredentialURL == "" {
+		return nil
+	}
+
+	if len(verificationReq.Credential) > 0 {
+		return commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			"verifiableCredential and credentialURL are mutually exclusive")
+	}
+
+	vcBytes, err := credentialref.Fetch(o.credentialRefClient, o.credentialURLs, verificationReq.CredentialURL)
+	if err != nil {
+		return err
+	}
+
+	// the fetched credential is returned in the JSON response
+	if !json.Valid(vcBytes) {
+		return commhttp.NewError(http.StatusBadRequest, commhttp.InvalidCredential,
+			"referenced credential is not a JSON document")
+	}
+
+	verificationReq.Credential = vcBytes
+
+	return nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+)
+
+func TestVerifyCredentialByURL(t *testing.T) {
+	vcWithStatus := `{
+	  "@context": ["https://www.w3.org/2018/credentials/v1"],
+	  "id": "http://example.edu/credentials/1872",
+	  "type": "VerifiableCredential",
+	  "credentialSubject": {"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"},
+	  "issuer": "did:example:76e12ec712ebc6f1c221ebfeb1f",
+	  "issuanceDate": "2010-01-01T19:23:24Z",
+	  "credentialStatus": {"id": "https://example.gov/status/24", "type": "CredentialStatusList2017"}
+	}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		var err error
+
+		switch req.URL.Path {
+		case "/vc/prcard.json":
+			_, err = rw.Write([]byte(prCardVC))
+		case "/vc/status.json":
+			_, err = rw.Write([]byte(vcWithStatus))
+		case "/vc/jwt":
+			_, err = rw.Write([]byte("eyJhbGciOiJQUzI1NiJ9.e30.c2ln"))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	allowedURL, err := url.Parse(server.URL + "/vc/")
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		VDRI:           &vdrimock.MockVDRIRegistry{},
+		StoreProvider:  memstore.NewProvider(),
+		CredentialURLs: []*url.URL{allowedURL},
+	})
+	require.NoError(t, err)
+
+	err = op.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test verifier",
+		CredentialChecks: []string{statusCheck}})
+	require.NoError(t, err)
+
+	handler := getHandler(t, op, credentialsVerificationEndpoint, http.MethodPost)
+
+	verify := func(t *testing.T, req *CredentialsVerificationRequest) *httptest.ResponseRecorder {
+		reqBytes, err := json.Marshal(req)
+		require.NoError(t, err)
+
+		return serveHTTPMux(t, handler, "/test/verifier/credentials", reqBytes,
+			map[string]string{profileIDPathParam: "test"})
+	}
+
+	t.Run("test success", func(t *testing.T) {
+		rr := verify(t, &CredentialsVerificationRequest{CredentialURL: server.URL + "/vc/prcard.json"})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		resp := &CredentialsVerificationSuccessResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		require.Equal(t, []string{statusCheck}, resp.Checks)
+		require.JSONEq(t, prCardVC, string(resp.Credential))
+	})
+
+	t.Run("test failed check", func(t *testing.T) {
+		op.httpClient = &mockHTTPClient{doErr: errors.New("status list unavailable")}
+
+		rr := verify(t, &CredentialsVerificationRequest{CredentialURL: server.URL + "/vc/status.json"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		resp := &CredentialsVerificationFailResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		require.Len(t, resp.Checks, 1)
+		require.Equal(t, statusCheck, resp.Checks[0].Check)
+		require.JSONEq(t, vcWithStatus, string(resp.Credential))
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		rr := verify(t, &CredentialsVerificationRequest{Credential: []byte(prCardVC),
+			CredentialURL: server.URL + "/vc/prcard.json"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "verifiableCredential and credentialURL are mutually exclusive")
+
+		rr = verify(t, &CredentialsVerificationRequest{CredentialURL: server.URL + "/other/prcard.json"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "is not allowed")
+
+		rr = verify(t, &CredentialsVerificationRequest{CredentialURL: server.URL + "/vc/missing.json"})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "status 404")
+
+		rr = verify(t, &CredentialsVerificationRequest{CredentialURL: server.URL + "/vc/jwt"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "referenced credential is not a JSON document")
+	})
+}
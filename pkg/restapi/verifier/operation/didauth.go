@@ -0,0 +1,109 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/binding"
+	vcchallenge "github.com/trustbloc/edge-service/pkg/doc/vc/challenge"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	didAuthEndpoint = verifierBasePath + "/did-auth"
+
+	// didAuthScope is the scope of the DIDAuth challenges in the challenge store, distinct from the issuer profiles
+	// whose names can't have a slash, and from the verifier profiles
+	didAuthScope = didAuthEndpoint
+)
+
+// DIDAuth swagger:route POST /verifier/did-auth verifier didAuthReq
+//
+// Returns a challenge when the request has no presentation, else verifies the DIDAuth presentation signing the
+// challenge proves the control of a DID.
+//
+// Responses:
+//    default: genericError
+//        200: didAuthRes
+//        201: didAuthChallengeRes
+func (o *Operation) didAuthHandler(rw http.ResponseWriter, req *http.Request) {
+	request := &DIDAuthRequest{}
+
+	if err := commhttp.DecodeJSON(req, request); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if len(request.Presentation) == 0 {
+		challenge, err := o.challenges.Create(didAuthScope)
+		if err != nil {
+			commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
+
+			return
+		}
+
+		rw.WriteHeader(http.StatusCreated)
+		commhttp.WriteResponse(rw, challenge)
+
+		return
+	}
+
+	auth, err := o.VerifyDIDAuth(request)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, auth)
+}
+
+// VerifyDIDAuth verifies the DIDAuth presentation of the request is signed for the authentication proof purpose,
+// with a challenge returned by the DIDAuth endpoint, by the DID and for the domain of the request if set, then uses
+// the challenge.
+func (o *Operation) VerifyDIDAuth(request *DIDAuthRequest) (*binding.DIDAuth, error) {
+	var opts []verifiable.PresentationOpt
+
+	if o.documentLoader != nil {
+		opts = append(opts, verifiable.WithPresJSONLDDocumentLoader(o.documentLoader))
+	}
+
+	auth, err := binding.VerifyDIDAuth(request.Presentation, o.vdri, opts...)
+	if err != nil {
+		return nil, didAuthError(err)
+	}
+
+	if request.DID != "" && auth.DID != request.DID {
+		return nil, didAuthError(fmt.Errorf("the presentation isn't signed by %s", request.DID))
+	}
+
+	if request.Domain != "" && auth.Domain != request.Domain {
+		return nil, didAuthError(fmt.Errorf("the domain of the presentation isn't %s", request.Domain))
+	}
+
+	err = o.challenges.Consume(didAuthScope, auth.Challenge)
+	if errors.Is(err, vcchallenge.ErrInvalidChallenge) {
+		return nil, didAuthError(err)
+	}
+
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError, err.Error())
+	}
+
+	return auth, nil
+}
+
+func didAuthError(err error) error {
+	return commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+		fmt.Sprintf("invalid DIDAuth presentation: %s", err.Error()))
+}
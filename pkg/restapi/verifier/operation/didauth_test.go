@@ -0,0 +1,157 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	mockstorage "github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/binding"
+	vcchallenge "github.com/trustbloc/edge-service/pkg/doc/vc/challenge"
+	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+)
+
+func TestDIDAuth(t *testing.T) {
+	const didID = "did:example:holder"
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		StoreProvider: memstore.NewProvider(),
+		VDRI: &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+				return createDIDDoc(didID, pubKey), nil
+			}},
+	})
+	require.NoError(t, err)
+
+	handler := getHandler(t, op, didAuthEndpoint, http.MethodPost)
+
+	newChallenge := func(t *testing.T) string {
+		rr := serveHTTPMux(t, handler, didAuthEndpoint, []byte(`{}`), nil)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		challenge := &vcchallenge.Challenge{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), challenge))
+		require.NotEmpty(t, challenge.Challenge)
+
+		return challenge.Challenge
+	}
+
+	didAuthRequest := func(t *testing.T, challenge string) []byte {
+		vp := &verifiable.Presentation{Context: []string{"https://www.w3.org/2018/credentials/v1"},
+			Type: []string{"VerifiablePresentation"}, Holder: didID}
+
+		err := vp.AddLinkedDataProof(&verifiable.LinkedDataProofContext{
+			SignatureType: "Ed25519Signature2018",
+			Suite: ed25519signature2018.New(
+				suite.WithSigner(getEd25519TestSigner(privKey))),
+			SignatureRepresentation: verifiable.SignatureJWS,
+			VerificationMethod:      didID + "#key-1",
+			Purpose:                 vccrypto.Authentication,
+			Challenge:               challenge,
+			Domain:                  "example.com",
+		})
+		require.NoError(t, err)
+
+		vpBytes, err := vp.MarshalJSON()
+		require.NoError(t, err)
+
+		reqBytes, err := json.Marshal(&DIDAuthRequest{Presentation: vpBytes})
+		require.NoError(t, err)
+
+		return reqBytes
+	}
+
+	t.Run("test DIDAuth - success", func(t *testing.T) {
+		challenge := newChallenge(t)
+		reqBytes := didAuthRequest(t, challenge)
+
+		rr := serveHTTPMux(t, handler, didAuthEndpoint, reqBytes, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		auth := &binding.DIDAuth{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), auth))
+		require.Equal(t, &binding.DIDAuth{DID: didID, VerificationMethod: didID + "#key-1", Challenge: challenge,
+			Domain: "example.com"}, auth)
+
+		// the challenge can't be used twice
+		rr = serveHTTPMux(t, handler, didAuthEndpoint, reqBytes, nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), vcchallenge.ErrInvalidChallenge.Error())
+	})
+
+	t.Run("test DIDAuth - DID and domain of the request", func(t *testing.T) {
+		request := &DIDAuthRequest{}
+		require.NoError(t, json.Unmarshal(didAuthRequest(t, newChallenge(t)), request))
+
+		request.DID = "did:example:other"
+
+		_, err := op.VerifyDIDAuth(request)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the presentation isn't signed by did:example:other")
+
+		request.DID = didID
+		request.Domain = "other.com"
+
+		_, err = op.VerifyDIDAuth(request)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the domain of the presentation isn't other.com")
+
+		request.Domain = "example.com"
+
+		auth, err := op.VerifyDIDAuth(request)
+		require.NoError(t, err)
+		require.Equal(t, didID, auth.DID)
+	})
+
+	t.Run("test DIDAuth - unknown challenge", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, didAuthEndpoint, didAuthRequest(t, "unknown"), nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), vcchallenge.ErrInvalidChallenge.Error())
+	})
+
+	t.Run("test DIDAuth - invalid request", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, didAuthEndpoint, []byte(`{`), nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "Invalid request")
+
+		rr = serveHTTPMux(t, handler, didAuthEndpoint, []byte(`{"presentation":{}}`), nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid DIDAuth presentation: failed to verify presentation")
+	})
+
+	t.Run("test DIDAuth - store errors", func(t *testing.T) {
+		store, err := vcchallenge.New(&mockstorage.Provider{Store: &mockstorage.MockStore{Store: map[string][]byte{},
+			ErrPut: errors.New("put error")}}, vcchallenge.DefaultTTL)
+		require.NoError(t, err)
+
+		challenges := op.challenges
+		op.challenges = store
+
+		defer func() { op.challenges = challenges }()
+
+		rr := serveHTTPMux(t, handler, didAuthEndpoint, []byte(`{}`), nil)
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "put error")
+	})
+}
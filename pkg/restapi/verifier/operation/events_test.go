@@ -0,0 +1,87 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	"github.com/trustbloc/edge-service/pkg/events"
+)
+
+type mockPublisher struct {
+	mutex  sync.Mutex
+	events []*events.Event
+}
+
+func (p *mockPublisher) Publish(_ context.Context, event *events.Event) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.events = append(p.events, event)
+
+	return nil
+}
+
+func (p *mockPublisher) Close() error {
+	return nil
+}
+
+func TestVerificationEvents(t *testing.T) {
+	publisher := &mockPublisher{}
+	emitter := events.NewEmitter(publisher, 0)
+
+	op, err := New(&Config{
+		VDRI:          &vdrimock.MockVDRIRegistry{},
+		StoreProvider: memstore.NewProvider(),
+		Events:        emitter,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test verifier",
+		CredentialChecks: []string{policyCheck}, PresentationChecks: []string{policyCheck},
+		Policy: []policy.Rule{{Type: policy.ClaimValues, Values: []string{"lprCategory=C09"}}}}))
+
+	_, result, err := op.VerifyCredential("test", &CredentialsVerificationRequest{Credential: []byte(prCardVC)})
+	require.NoError(t, err)
+	require.Empty(t, result)
+
+	_, result, err = op.VerifyCredential("test", &CredentialsVerificationRequest{Credential: []byte(validVCWithProof)})
+	require.NoError(t, err)
+	require.NotEmpty(t, result)
+
+	_, presentationResult, err := op.VerifyPresentation("test",
+		&VerifyPresentationRequest{Presentation: []byte(vpWithoutProof)})
+	require.NoError(t, err)
+	require.NotEmpty(t, presentationResult)
+
+	require.NoError(t, emitter.Close())
+	require.Len(t, publisher.events, 3)
+
+	for _, event := range publisher.events {
+		require.Equal(t, "test", event.Profile)
+	}
+
+	require.Equal(t, events.CredentialVerified, publisher.events[0].Type)
+	require.Equal(t, &events.VerificationData{CredentialID: "https://issuer.oidp.uscis.gov/credentials/83627465",
+		Verified: true, Checks: []string{policyCheck}}, publisher.events[0].Data)
+
+	require.Equal(t, events.CredentialVerified, publisher.events[1].Type)
+	require.Equal(t, &events.VerificationData{CredentialID: "http://example.edu/credentials/1872",
+		Checks: []string{policyCheck}, FailureReasons: []string{reasonPolicyViolation}}, publisher.events[1].Data)
+
+	require.Equal(t, events.PresentationVerified, publisher.events[2].Type)
+	require.Equal(t, &events.VerificationData{Checks: []string{policyCheck},
+		FailureReasons: []string{reasonPolicyViolation}}, publisher.events[2].Data)
+}
@@ -0,0 +1,313 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/trustbloc/edge-service/pkg/events"
+	"github.com/trustbloc/edge-service/pkg/metrics"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	failuresEndpoint = "/" + "{" + profileIDPathParam + "}" + verifierBasePath + "/failures"
+	windowQueryParam = "window"
+
+	defaultFailureWindow = time.Hour
+	// failureRetention is how long the verification outcomes are kept, the longest window of the failure stats
+	failureRetention = 24 * time.Hour
+	// failureBucketSize is the resolution of the windows of the failure stats
+	failureBucketSize = time.Minute
+
+	// reasons of the verification failures
+	reasonBadProof          = "badProof"
+	reasonRevoked           = "revoked"
+	reasonStatusUnavailable = "statusUnavailable"
+	reasonUntrustedIssuer   = "untrustedIssuer"
+	reasonPolicyViolation   = "policyViolation"
+	reasonTimeout           = "timeout"
+	reasonCheckFailed       = "checkFailed"
+
+	failureRateAlertType = "verificationFailureRate"
+)
+
+// FailureAlertConfig configures the webhook alerted when the failure rate of the verifications of a profile spikes
+type FailureAlertConfig struct {
+	// WebhookURL receives the alerts as POST requests with a VerificationFailureAlert body.
+	WebhookURL string
+	// FailureRate is the ratio of failed verifications over the window triggering an alert, e.g. 0.5.
+	FailureRate float64
+	// Window is the period the failure rate is computed over, a profile being alerted at most once per window.
+	Window time.Duration
+	// MinVerifications is the number of verifications over the window required to alert, so that a few failures
+	// of a quiet profile don't trigger an alert.
+	MinVerifications int
+	// HTTPClient posts the alerts when the webhook is in its own trust domain, e.g. with the CA certificates or a
+	// client certificate of the webhook (optional, the client of the status list requests by default).
+	HTTPClient *http.Client
+}
+
+// failureBucket counts the verifications of a profile started during a failureBucketSize period
+type failureBucket struct {
+	start         time.Time
+	verifications int
+	failures      int
+	reasons       map[string]int
+}
+
+// failureTracker aggregates the outcomes of the verifications of each profile over the last failureRetention,
+// in memory: each instance reports its own verifications
+type failureTracker struct {
+	mutex     sync.Mutex
+	buckets   map[string][]*failureBucket
+	lastAlert map[string]time.Time
+	now       func() time.Time
+}
+
+func newFailureTracker() *failureTracker {
+	return &failureTracker{
+		buckets:   make(map[string][]*failureBucket),
+		lastAlert: make(map[string]time.Time),
+		now:       time.Now,
+	}
+}
+
+// record counts a verification of the profile, failed for the given reasons if any
+func (t *failureTracker) record(profile string, reasons []string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	start := now.Truncate(failureBucketSize)
+
+	buckets := t.buckets[profile]
+
+	// drop the buckets older than the retention
+	expired := 0
+	for expired < len(buckets) && now.Sub(buckets[expired].start) > failureRetention {
+		expired++
+	}
+
+	buckets = buckets[expired:]
+
+	if len(buckets) == 0 || !buckets[len(buckets)-1].start.Equal(start) {
+		buckets = append(buckets, &failureBucket{start: start, reasons: make(map[string]int)})
+	}
+
+	bucket := buckets[len(buckets)-1]
+	bucket.verifications++
+
+	if len(reasons) > 0 {
+		bucket.failures++
+
+		for _, reason := range reasons {
+			bucket.reasons[reason]++
+		}
+	}
+
+	t.buckets[profile] = buckets
+}
+
+// stats returns the verifications of the profile started during the window
+func (t *failureTracker) stats(profile string, window time.Duration) *VerificationFailureStats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.windowStats(profile, window)
+}
+
+func (t *failureTracker) windowStats(profile string, window time.Duration) *VerificationFailureStats {
+	stats := &VerificationFailureStats{Profile: profile, Window: window.String(), Reasons: map[string]int{}}
+
+	// the bucket of the start of the window is included
+	since := t.now().Add(-window).Truncate(failureBucketSize)
+
+	for _, bucket := range t.buckets[profile] {
+		if bucket.start.Before(since) {
+			continue
+		}
+
+		stats.Verifications += bucket.verifications
+		stats.Failures += bucket.failures
+
+		for reason, count := range bucket.reasons {
+			stats.Reasons[reason] += count
+		}
+	}
+
+	if stats.Verifications > 0 {
+		stats.FailureRate = float64(stats.Failures) / float64(stats.Verifications)
+	}
+
+	return stats
+}
+
+// alertDue returns the stats of the alert window of the profile if its failure rate reached the threshold of the
+// alert, and the profile wasn't alerted during the window
+func (t *failureTracker) alertDue(profile string, alert *FailureAlertConfig) (*VerificationFailureStats, bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	stats := t.windowStats(profile, alert.Window)
+	if stats.Verifications == 0 || stats.Verifications < alert.MinVerifications ||
+		stats.FailureRate < alert.FailureRate {
+		return nil, false
+	}
+
+	now := t.now()
+
+	if last, ok := t.lastAlert[profile]; ok && now.Sub(last) < alert.Window {
+		return nil, false
+	}
+
+	t.lastAlert[profile] = now
+
+	return stats, true
+}
+
+// recordVerification records the outcome of a verification of the profile in the metrics and the failure stats,
+// emits it as an event of the type, and alerts the webhook if the failure rate of the profile spiked. The
+// verification failed if there are failure reasons.
+func (o *Operation) recordVerification(eventType, profile, credentialID string, checks, reasons []string) {
+	metrics.ObserveVerification(profile, reasons)
+	o.failures.record(profile, reasons)
+
+	o.events.Emit(eventType, profile, &events.VerificationData{
+		CredentialID:   credentialID,
+		Verified:       len(reasons) == 0,
+		Checks:         checks,
+		FailureReasons: reasons,
+	})
+
+	if o.failureAlert == nil || len(reasons) == 0 {
+		return
+	}
+
+	if stats, due := o.failures.alertDue(profile, o.failureAlert); due {
+		go o.sendFailureAlert(&VerificationFailureAlert{
+			Type:      failureRateAlertType,
+			Threshold: o.failureAlert.FailureRate,
+			Time:      o.failures.now().UTC(),
+			Stats:     stats,
+		})
+	}
+}
+
+// sendFailureAlert posts the alert to the webhook, a failed alert is logged and not retried
+func (o *Operation) sendFailureAlert(alert *VerificationFailureAlert) {
+	alertBytes, err := json.Marshal(alert)
+	if err != nil {
+		logger.Errorf("failed to marshal verification failure alert: %s", err)
+
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodPost, o.failureAlert.WebhookURL, bytes.NewBuffer(alertBytes))
+	if err != nil {
+		logger.Errorf("failed to create verification failure alert request: %s", err)
+
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := o.httpClient
+	if o.failureAlert.HTTPClient != nil {
+		client = o.failureAlert.HTTPClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.Warnf("failed to send verification failure alert of profile %s: %s", alert.Stats.Profile, err)
+
+		return
+	}
+
+	if errClose := resp.Body.Close(); errClose != nil {
+		logger.Warnf("failed to close response body")
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		logger.Warnf("verification failure alert of profile %s rejected with status %d", alert.Stats.Profile,
+			resp.StatusCode)
+	}
+}
+
+// failureReason returns the reason of the failed check, the reason set by the check if any
+func failureReason(check string, outcome *checkOutcome) string {
+	if outcome.reason != "" {
+		return outcome.reason
+	}
+
+	switch check {
+	case proofCheck:
+		return reasonBadProof
+	case statusCheck:
+		return reasonRevoked
+	case governanceCheck:
+		return reasonUntrustedIssuer
+	case policyCheck:
+		return reasonPolicyViolation
+	default:
+		return reasonCheckFailed
+	}
+}
+
+// failureReasons returns the distinct reasons of the failed checks
+func failureReasons(checks []string, outcomes []checkOutcome) []string {
+	var reasons []string
+
+	for i := range outcomes {
+		if outcomes[i].failure == "" {
+			continue
+		}
+
+		if reason := failureReason(checks[i], &outcomes[i]); !contains(reasons, reason) {
+			reasons = append(reasons, reason)
+		}
+	}
+
+	return reasons
+}
+
+// VerificationFailures swagger:route GET /{id}/verifier/failures verifier verificationFailuresReq
+//
+// Retrieves the verifications of the profile during the window, with the reasons of the failed verifications.
+//
+// Responses:
+//    default: genericError
+//        200: verificationFailuresResp
+func (o *Operation) verificationFailuresHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getVerifierProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	window := defaultFailureWindow
+
+	if windowParam := req.URL.Query().Get(windowQueryParam); windowParam != "" {
+		window, err = time.ParseDuration(windowParam)
+		if err != nil || window <= 0 || window > failureRetention {
+			commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+				fmt.Sprintf("invalid window %s: must be a duration up to %s, e.g. 15m", windowParam, failureRetention))
+
+			return
+		}
+	}
+
+	commhttp.WriteResponse(rw, o.failures.stats(profile.ID, window))
+}
@@ -0,0 +1,215 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+)
+
+func TestFailureTracker(t *testing.T) {
+	now := time.Date(2020, time.October, 16, 10, 0, 30, 0, time.UTC)
+
+	t.Run("test stats", func(t *testing.T) {
+		tracker := newFailureTracker()
+		tracker.now = func() time.Time { return now }
+
+		tracker.record("test", nil)
+		tracker.record("test", []string{reasonBadProof})
+		tracker.record("test", []string{reasonRevoked, reasonUntrustedIssuer})
+		tracker.record("other", []string{reasonRevoked})
+
+		require.Equal(t, &VerificationFailureStats{Profile: "test", Window: "1h0m0s", Verifications: 3, Failures: 2,
+			FailureRate: 2.0 / 3, Reasons: map[string]int{reasonBadProof: 1, reasonRevoked: 1,
+				reasonUntrustedIssuer: 1}}, tracker.stats("test", time.Hour))
+
+		require.Equal(t, &VerificationFailureStats{Profile: "unknown", Window: "1h0m0s", Reasons: map[string]int{}},
+			tracker.stats("unknown", time.Hour))
+	})
+
+	t.Run("test window", func(t *testing.T) {
+		tracker := newFailureTracker()
+		tracker.now = func() time.Time { return now }
+
+		tracker.record("test", []string{reasonBadProof})
+
+		tracker.now = func() time.Time { return now.Add(10 * time.Minute) }
+
+		tracker.record("test", nil)
+
+		require.Equal(t, 1, tracker.stats("test", 5*time.Minute).Verifications)
+		require.Equal(t, 2, tracker.stats("test", 15*time.Minute).Verifications)
+
+		// the outcomes older than the retention are dropped
+		tracker.now = func() time.Time { return now.Add(failureRetention + 5*time.Minute) }
+
+		tracker.record("test", nil)
+
+		require.Len(t, tracker.buckets["test"], 2)
+		require.Equal(t, 2, tracker.stats("test", failureRetention).Verifications)
+	})
+
+	t.Run("test alert due", func(t *testing.T) {
+		tracker := newFailureTracker()
+		tracker.now = func() time.Time { return now }
+
+		alert := &FailureAlertConfig{FailureRate: 0.5, Window: 5 * time.Minute, MinVerifications: 3}
+
+		tracker.record("test", []string{reasonBadProof})
+		tracker.record("test", []string{reasonBadProof})
+
+		// not enough verifications
+		_, due := tracker.alertDue("test", alert)
+		require.False(t, due)
+
+		tracker.record("test", nil)
+
+		stats, due := tracker.alertDue("test", alert)
+		require.True(t, due)
+		require.Equal(t, 2, stats.Failures)
+
+		// alerted once per window
+		_, due = tracker.alertDue("test", alert)
+		require.False(t, due)
+
+		tracker.now = func() time.Time { return now.Add(5 * time.Minute) }
+
+		tracker.record("test", []string{reasonBadProof})
+
+		_, due = tracker.alertDue("test", alert)
+		require.True(t, due)
+
+		// below the threshold
+		for i := 0; i < 5; i++ {
+			tracker.record("quiet", nil)
+		}
+
+		tracker.record("quiet", []string{reasonRevoked})
+
+		_, due = tracker.alertDue("quiet", alert)
+		require.False(t, due)
+	})
+}
+
+func TestFailureReasons(t *testing.T) {
+	checks := []string{proofCheck, statusCheck, governanceCheck, policyCheck, "other", statusCheck}
+	outcomes := []checkOutcome{{failure: "invalid proof"}, {failure: "revoked"}, {}, {failure: "violation"},
+		{failure: "not supported"}, {failure: "check not completed", reason: reasonTimeout}}
+
+	require.Equal(t, []string{reasonBadProof, reasonRevoked, reasonPolicyViolation, reasonCheckFailed, reasonTimeout},
+		failureReasons(checks, outcomes))
+
+	require.Empty(t, failureReasons(checks[:3], []checkOutcome{{}, {}, {}}))
+}
+
+func TestVerificationFailuresHandler(t *testing.T) {
+	alerts := make(chan *VerificationFailureAlert, 1)
+
+	webhook := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		alert := &VerificationFailureAlert{}
+		require.NoError(t, json.NewDecoder(req.Body).Decode(alert))
+
+		alerts <- alert
+	}))
+	defer webhook.Close()
+
+	op, err := New(&Config{
+		VDRI:          &vdrimock.MockVDRIRegistry{},
+		StoreProvider: memstore.NewProvider(),
+		FailureAlert: &FailureAlertConfig{WebhookURL: webhook.URL, FailureRate: 0.5, Window: time.Minute,
+			MinVerifications: 2},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test verifier",
+		CredentialChecks: []string{policyCheck}, PresentationChecks: []string{policyCheck},
+		Policy: []policy.Rule{{Type: policy.ClaimValues, Values: []string{"lprCategory=C09"}}}}))
+
+	handler := getHandler(t, op, failuresEndpoint, http.MethodGet)
+
+	t.Run("test verification failures", func(t *testing.T) {
+		_, result, err := op.VerifyCredential("test", &CredentialsVerificationRequest{Credential: []byte(prCardVC)})
+		require.NoError(t, err)
+		require.Empty(t, result)
+
+		_, result, err = op.VerifyCredential("test",
+			&CredentialsVerificationRequest{Credential: []byte(validVCWithProof)})
+		require.NoError(t, err)
+		require.NotEmpty(t, result)
+
+		select {
+		case alert := <-alerts:
+			require.Equal(t, failureRateAlertType, alert.Type)
+			require.Equal(t, 0.5, alert.Threshold)
+			require.Equal(t, &VerificationFailureStats{Profile: "test", Window: "1m0s", Verifications: 2,
+				Failures: 1, FailureRate: 0.5, Reasons: map[string]int{reasonPolicyViolation: 1}}, alert.Stats)
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "verification failure alert not sent")
+		}
+
+		_, presentationResult, err := op.VerifyPresentation("test",
+			&VerifyPresentationRequest{Presentation: []byte(vpWithoutProof)})
+		require.NoError(t, err)
+		require.NotEmpty(t, presentationResult)
+
+		rr := serveHTTPMux(t, handler, "/test/verifier/failures?window=15m", nil,
+			map[string]string{profileIDPathParam: "test"})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		stats := &VerificationFailureStats{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), stats))
+		require.Equal(t, &VerificationFailureStats{Profile: "test", Window: "15m0s", Verifications: 3, Failures: 2,
+			FailureRate: 2.0 / 3, Reasons: map[string]int{reasonPolicyViolation: 2}}, stats)
+	})
+
+	t.Run("test invalid window", func(t *testing.T) {
+		for _, window := range []string{"invalid", "-1m", "48h"} {
+			rr := serveHTTPMux(t, handler, "/test/verifier/failures?window="+window, nil,
+				map[string]string{profileIDPathParam: "test"})
+			require.Equal(t, http.StatusBadRequest, rr.Code, window)
+			require.Contains(t, rr.Body.String(), "invalid window "+window)
+		}
+	})
+
+	t.Run("test invalid profile", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, "/invalid/verifier/failures", nil,
+			map[string]string{profileIDPathParam: "invalid"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid verifier profile")
+	})
+}
+
+func TestSendFailureAlert(t *testing.T) {
+	var alerts int
+
+	webhook := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		alerts++
+	}))
+	defer webhook.Close()
+
+	alert := &VerificationFailureAlert{Type: failureRateAlertType, Stats: &VerificationFailureStats{Profile: "test"}}
+
+	op := &Operation{httpClient: &http.Client{}, failureAlert: &FailureAlertConfig{WebhookURL: webhook.URL}}
+
+	op.sendFailureAlert(alert)
+	require.Zero(t, alerts)
+
+	op.failureAlert.HTTPClient = webhook.Client()
+
+	op.sendFailureAlert(alert)
+	require.Equal(t, 1, alerts)
+}
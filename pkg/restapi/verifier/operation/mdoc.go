@@ -0,0 +1,79 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/mdoc"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const mdocsVerificationEndpoint = "/" + "{" + profileIDPathParam + "}" + verifierBasePath + "/mdocs"
+
+// VerifyMDoc swagger:route POST /{id}/verifier/mdocs verifier verifyMDocReq
+//
+// Verifies a mobile document (ISO/IEC 18013-5 mdoc): the issuer authentication, the validity and the digests of its
+// data elements.
+//
+// Responses:
+//    default: genericError
+//        200: verifyMDocRes
+func (o *Operation) verifyMDocHandler(rw http.ResponseWriter, req *http.Request) {
+	if _, err := o.getVerifierProfile(mux.Vars(req)[profileIDPathParam]); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	request := &VerifyMDocRequest{}
+
+	if err := commhttp.DecodeJSON(req, request); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	doc, err := o.verifyMDoc(request)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, doc)
+}
+
+// verifyMDoc verifies the mdoc of the request, the key of its issuer being fetched as the keys of the issuers of the
+// credentials
+func (o *Operation) verifyMDoc(request *VerifyMDocRequest) (*mdoc.Document, error) {
+	if request.MDoc == "" {
+		validationErr := &commhttp.ValidationError{}
+		validationErr.Add("/mdoc", "missing mdoc")
+
+		return nil, commhttp.NewValidationError(validationErr)
+	}
+
+	issuerSigned, err := base64.RawURLEncoding.DecodeString(request.MDoc)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("the mdoc isn't base64url encoded: %s", err.Error()))
+	}
+
+	doc, err := mdoc.Verify(issuerSigned, o.publicKeyFetcher(o.vdri), time.Now())
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("invalid mdoc: %s", err.Error()))
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/mdoc"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+func TestVerifyMDoc(t *testing.T) {
+	const issuerDID = "did:example:issuer"
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	devicePubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	deviceKey := &jose.JWK{}
+	deviceKey.Key = devicePubKey
+
+	op, err := New(&Config{
+		StoreProvider: memstore.NewProvider(),
+		VDRI: &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+				return createDIDDoc(didID, pubKey), nil
+			}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test verifier"}))
+
+	handler := getHandler(t, op, mdocsVerificationEndpoint, http.MethodPost)
+
+	issue := func(t *testing.T, validUntil time.Time) string {
+		issued := time.Now().Add(-time.Hour)
+
+		vc := &verifiable.Credential{
+			Context: []string{"https://www.w3.org/2018/credentials/v1"},
+			Types:   []string{"VerifiableCredential"},
+			Issuer:  verifiable.Issuer{ID: issuerDID},
+			Subject: map[string]interface{}{"id": "did:example:holder", "family_name": "Doe",
+				"birth_date": "1990-01-01"},
+			Issued:  &util.TimeWithTrailingZeroMsec{Time: issued},
+			Expired: &util.TimeWithTrailingZeroMsec{Time: validUntil},
+		}
+
+		issuerSigned, err := mdoc.Issue(vc, &mdoc.Issuance{DeviceKey: deviceKey,
+			VerificationMethod: issuerDID + "#key-1", Algorithm: mdoc.AlgorithmEdDSA,
+			Signer: getEd25519TestSigner(privKey)})
+		require.NoError(t, err)
+
+		return base64.RawURLEncoding.EncodeToString(issuerSigned)
+	}
+
+	verify := func(t *testing.T, profile string, request *VerifyMDocRequest) (int, []byte) {
+		reqBytes, err := json.Marshal(request)
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, "/"+profile+"/verifier/mdocs", reqBytes,
+			map[string]string{profileIDPathParam: profile})
+
+		return rr.Code, rr.Body.Bytes()
+	}
+
+	t.Run("verify mdoc - success", func(t *testing.T) {
+		code, body := verify(t, "test", &VerifyMDocRequest{MDoc: issue(t, time.Now().Add(time.Hour))})
+		require.Equal(t, http.StatusOK, code, string(body))
+
+		doc := &mdoc.Document{}
+		require.NoError(t, json.Unmarshal(body, doc))
+		require.Equal(t, mdoc.DocTypeMDL, doc.DocType)
+		require.Equal(t, issuerDID, doc.Issuer)
+		require.Equal(t, map[string]map[string]interface{}{
+			mdoc.NamespaceMDL: {"family_name": "Doe", "birth_date": "1990-01-01"},
+		}, doc.Claims)
+	})
+
+	t.Run("verify mdoc - expired", func(t *testing.T) {
+		code, body := verify(t, "test", &VerifyMDocRequest{MDoc: issue(t, time.Now().Add(-time.Minute))})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "invalid mdoc: the mdoc is only valid from")
+	})
+
+	t.Run("verify mdoc - missing mdoc", func(t *testing.T) {
+		code, body := verify(t, "test", &VerifyMDocRequest{})
+		require.Equal(t, http.StatusBadRequest, code)
+
+		errResp := &commhttp.ErrorResponse{}
+		require.NoError(t, json.Unmarshal(body, errResp))
+		require.Equal(t, []commhttp.FieldError{{Field: "/mdoc", Message: "missing mdoc"}}, errResp.Fields)
+	})
+
+	t.Run("verify mdoc - not base64url encoded", func(t *testing.T) {
+		code, body := verify(t, "test", &VerifyMDocRequest{MDoc: "!"})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "the mdoc isn't base64url encoded")
+	})
+
+	t.Run("verify mdoc - invalid profile", func(t *testing.T) {
+		code, body := verify(t, "unknown", &VerifyMDocRequest{MDoc: issue(t, time.Now().Add(time.Hour))})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "invalid verifier profile")
+	})
+}
@@ -6,29 +6,55 @@ SPDX-License-Identifier: Apache-2.0
 
 package operation
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/presentationrequest"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/redaction"
+)
 
 // CredentialsVerificationRequest request for verifying credential.
 type CredentialsVerificationRequest struct {
-	Credential json.RawMessage                 `json:"verifiableCredential,omitempty"`
-	Opts       *CredentialsVerificationOptions `json:"options,omitempty"`
+	Credential json.RawMessage `json:"verifiableCredential,omitempty"`
+	// CredentialURL is the URL of the credential to verify instead of verifiableCredential, fetched by the service.
+	// It must be under one of the URLs the service may fetch the credentials from.
+	CredentialURL string                          `json:"credentialURL,omitempty"`
+	Opts          *CredentialsVerificationOptions `json:"options,omitempty"`
 }
 
 // CredentialsVerificationOptions options for credential verifications.
 type CredentialsVerificationOptions struct {
-	Domain    string   `json:"domain,omitempty"`
-	Challenge string   `json:"challenge,omitempty"`
-	Checks    []string `json:"checks,omitempty"`
+	Domain       string   `json:"domain,omitempty"`
+	Challenge    string   `json:"challenge,omitempty"`
+	ProofPurpose string   `json:"proofPurpose,omitempty"`
+	Checks       []string `json:"checks,omitempty"`
+	// MaxProofAge is the maximum difference in seconds between the creation time of the proof and now (optional).
+	MaxProofAge int64 `json:"maxProofAge,omitempty"`
+	// Receipt requests a verification receipt signed by the receipt key of the profile.
+	Receipt bool `json:"receipt,omitempty"`
+	// Disclosures are the disclosures of a credential derived from a redactable credential, checked against the
+	// claims digest of the credential by the proof check.
+	Disclosures []*redaction.Disclosure `json:"disclosures,omitempty"`
 }
 
 // CredentialsVerificationSuccessResponse resp when credential verification is success.
 type CredentialsVerificationSuccessResponse struct {
 	Checks []string `json:"checks,omitempty"`
+	// Credential is the credential fetched from the credentialURL of the request.
+	Credential json.RawMessage `json:"verifiableCredential,omitempty"`
+	// Receipt is the verification receipt, when requested.
+	Receipt json.RawMessage `json:"receipt,omitempty"`
 }
 
 // CredentialsVerificationFailResponse resp when credential verification is failed.
 type CredentialsVerificationFailResponse struct {
 	Checks []CredentialsVerificationCheckResult `json:"checks,omitempty"`
+	// Credential is the credential fetched from the credentialURL of the request.
+	Credential json.RawMessage `json:"verifiableCredential,omitempty"`
+	// Receipt is the verification receipt, when requested.
+	Receipt json.RawMessage `json:"receipt,omitempty"`
 }
 
 // CredentialsVerificationCheckResult resp containing failure check details.
@@ -36,6 +62,37 @@ type CredentialsVerificationCheckResult struct {
 	Check              string `json:"check,omitempty"`
 	Error              string `json:"error,omitempty"`
 	VerificationMethod string `json:"verificationMethod,omitempty"`
+	// Violations are the violated rules of the profile policy, when the policy check failed
+	Violations []policy.Violation `json:"violations,omitempty"`
+}
+
+// BatchVerificationRequest request for verifying a batch of credentials.
+type BatchVerificationRequest struct {
+	Credentials []json.RawMessage `json:"verifiableCredentials,omitempty"`
+	// Opts are the options of the verifications of all the credentials of the batch.
+	Opts *CredentialsVerificationOptions `json:"options,omitempty"`
+}
+
+// BatchVerificationResponse resp of a batch verification, with the results of the credentials in the order of
+// the batch.
+type BatchVerificationResponse struct {
+	// Verified is true if all the credentials of the batch are verified.
+	Verified bool                      `json:"verified"`
+	Results  []BatchVerificationResult `json:"results"`
+}
+
+// BatchVerificationResult is the result of the verification of a credential of a batch.
+type BatchVerificationResult struct {
+	// ID is the ID of the credential, if any
+	ID       string   `json:"id,omitempty"`
+	Verified bool     `json:"verified"`
+	Checks   []string `json:"checks,omitempty"`
+	// FailedChecks are the failed checks, with their failure details
+	FailedChecks []CredentialsVerificationCheckResult `json:"failedChecks,omitempty"`
+	// Error is the error of a credential which couldn't be verified, or the error signing its receipt
+	Error string `json:"error,omitempty"`
+	// Receipt is the verification receipt, when requested.
+	Receipt json.RawMessage `json:"receipt,omitempty"`
 }
 
 // VerifyPresentationRequest request for verifying presentation.
@@ -46,19 +103,28 @@ type VerifyPresentationRequest struct {
 
 // VerifyPresentationOptions options for presentation verifications.
 type VerifyPresentationOptions struct {
-	Domain    string   `json:"domain,omitempty"`
-	Challenge string   `json:"challenge,omitempty"`
-	Checks    []string `json:"checks,omitempty"`
+	Domain       string   `json:"domain,omitempty"`
+	Challenge    string   `json:"challenge,omitempty"`
+	ProofPurpose string   `json:"proofPurpose,omitempty"`
+	Checks       []string `json:"checks,omitempty"`
+	// MaxProofAge is the maximum difference in seconds between the creation time of the proof and now (optional).
+	MaxProofAge int64 `json:"maxProofAge,omitempty"`
+	// Receipt requests a verification receipt signed by the receipt key of the profile.
+	Receipt bool `json:"receipt,omitempty"`
 }
 
 // VerifyPresentationSuccessResponse resp when presentation verification is success.
 type VerifyPresentationSuccessResponse struct {
 	Checks []string `json:"checks,omitempty"`
+	// Receipt is the verification receipt, when requested.
+	Receipt json.RawMessage `json:"receipt,omitempty"`
 }
 
 // VerifyPresentationFailureResponse resp when presentation verification is failed.
 type VerifyPresentationFailureResponse struct {
 	Checks []VerifyPresentationCheckResult `json:"checks,omitempty"`
+	// Receipt is the verification receipt, when requested.
+	Receipt json.RawMessage `json:"receipt,omitempty"`
 }
 
 // VerifyPresentationCheckResult resp containing failure check details.
@@ -66,6 +132,151 @@ type VerifyPresentationCheckResult struct {
 	Check              string `json:"check,omitempty"`
 	Error              string `json:"error,omitempty"`
 	VerificationMethod string `json:"verificationMethod,omitempty"`
+	// Violations are the violated rules of the profile policy, when the policy check failed
+	Violations []policy.Violation `json:"violations,omitempty"`
+}
+
+// VerificationReceiptSubject is the subject of a verification receipt: which credential or presentation a verifier
+// profile verified, when, and with which result.
+type VerificationReceiptSubject struct {
+	// ID is the ID of the verified credential or presentation, if any
+	ID string `json:"id,omitempty"`
+	// VerifiedType is VerifiableCredential or VerifiablePresentation
+	VerifiedType string `json:"verifiedType"`
+	// Digest is the hex encoded SHA-256 digest of the verified document as submitted, prefixed with sha256:
+	Digest string `json:"digest"`
+	// Verifier is the ID of the verifier profile
+	Verifier     string   `json:"verifier"`
+	Checks       []string `json:"checks"`
+	FailedChecks []string `json:"failedChecks,omitempty"`
+	Verified     bool     `json:"verified"`
+	// VerificationTime is the time the document was verified
+	VerificationTime time.Time `json:"verificationTime"`
+}
+
+// VerificationFailureStats are the verifications of a verifier profile during a window, with the number of failed
+// verifications per failure reason (a verification failing several checks counts for each of their reasons).
+type VerificationFailureStats struct {
+	Profile       string         `json:"profile"`
+	Window        string         `json:"window"`
+	Verifications int            `json:"verifications"`
+	Failures      int            `json:"failures"`
+	FailureRate   float64        `json:"failureRate"`
+	Reasons       map[string]int `json:"reasons"`
+}
+
+// VerificationFailureAlert is posted to the alert webhook when the failure rate of a profile reaches the threshold.
+type VerificationFailureAlert struct {
+	Type      string                    `json:"type"`
+	Threshold float64                   `json:"threshold"`
+	Time      time.Time                 `json:"time"`
+	Stats     *VerificationFailureStats `json:"stats"`
+}
+
+// DIDAuthRequest request for the DIDAuth of a DID. A request without presentation returns a challenge, to be signed
+// by the DIDAuth presentation of the next request.
+type DIDAuthRequest struct {
+	Presentation json.RawMessage `json:"presentation,omitempty"`
+	// DID is the DID the presentation must prove the control of (optional).
+	DID string `json:"did,omitempty"`
+	// Domain is the domain the proof of the presentation must have (optional).
+	Domain string `json:"domain,omitempty"`
+}
+
+// VerifyMDocRequest is the mobile document (ISO/IEC 18013-5 mdoc) to verify.
+type VerifyMDocRequest struct {
+	// MDoc is the IssuerSigned structure of the mdoc, CBOR encoded, base64url encoded without padding.
+	MDoc string `json:"mdoc"`
+}
+
+// VerifySDJWTRequest is the presented SD-JWT credential to verify.
+type VerifySDJWTRequest struct {
+	// SDJWT is the presentation: the issuer JWT, the disclosures of the disclosed claims, and the key binding JWT.
+	SDJWT string `json:"sdjwt"`
+	// Audience and Nonce the key binding JWT must have, if set, e.g. the domain and the challenge of the verifier.
+	Audience string `json:"audience,omitempty"`
+	Nonce    string `json:"nonce,omitempty"`
+	// RequireKeyBinding fails the presentations without key binding JWT, also required if Audience or Nonce is set.
+	RequireKeyBinding bool `json:"requireKeyBinding,omitempty"`
+}
+
+// CreatePresentationRequest is the presentation request to create, of the vpr or the pe format.
+type CreatePresentationRequest struct {
+	// Format is vpr, a verifiable presentation request with queries (default), or pe, a presentation definition.
+	Format string `json:"format,omitempty"`
+	// Query is the array of queries of the vpr format, DIDAuthentication or QueryByExample.
+	Query json.RawMessage `json:"query,omitempty"`
+	// PresentationDefinition is the presentation definition of the pe format.
+	PresentationDefinition json.RawMessage `json:"presentationDefinition,omitempty"`
+	// AnonCredsProofRequest is an AnonCreds proof request of the pe format, translated to its presentation
+	// definition, instead of the presentation definition.
+	AnonCredsProofRequest json.RawMessage `json:"anonCredsProofRequest,omitempty"`
+	// AnonCredsCredentialDefinitions are the credential definitions of the credentials the AnonCreds presentations
+	// responding to the request of the pe format are verified with.
+	AnonCredsCredentialDefinitions []json.RawMessage `json:"anonCredsCredentialDefinitions,omitempty"`
+	// Domain is the domain the proof of the presentation must have (optional).
+	Domain string `json:"domain,omitempty"`
+	// ExpiresIn is the number of seconds the holder can respond to the request, 15 minutes if not set and at most
+	// 24 hours, or the time the transient records are kept in the database.
+	ExpiresIn int `json:"expiresIn,omitempty"`
+}
+
+// PresentationRequestResponse is a presentation request, with the request URI shared with the holder.
+type PresentationRequestResponse struct {
+	*presentationrequest.Request
+	// RequestURI is the URL the holder gets the request from and responds to, e.g. the payload of a QR code.
+	RequestURI string `json:"requestURI"`
+}
+
+// InteractPresentationRequest is the response of the holder to a presentation request, empty to get the request.
+type InteractPresentationRequest struct {
+	VerifiablePresentation json.RawMessage `json:"verifiablePresentation,omitempty"`
+	// PresentationSubmission maps the input descriptors of the pe format to the credentials of the presentation,
+	// unless set as the presentation_submission of the presentation.
+	PresentationSubmission *presentationrequest.Submission `json:"presentation_submission,omitempty"`
+	// AnonCredsPresentation is an AnonCreds presentation answering the AnonCreds proof request of the pe format,
+	// instead of the verifiable presentation: the DIDComm present-proof presentation message received by the agent
+	// of the verifier, or the presentation attached to it.
+	AnonCredsPresentation json.RawMessage `json:"anonCredsPresentation,omitempty"`
+}
+
+// InteractPresentationRequestResponse is the request in its format, or the outcome of the response to the request.
+type InteractPresentationRequestResponse struct {
+	VerifiablePresentationRequest *VerifiablePresentationRequest `json:"verifiablePresentationRequest,omitempty"`
+	*PresentationDefinitionRequest
+	State  string   `json:"state,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// VerifiablePresentationRequest is a presentation request of the vpr format.
+type VerifiablePresentationRequest struct {
+	Query     json.RawMessage       `json:"query"`
+	Challenge string                `json:"challenge"`
+	Domain    string                `json:"domain,omitempty"`
+	Interact  *PresentationInteract `json:"interact,omitempty"`
+}
+
+// PresentationInteract is the services the holder interacts with to send its presentation.
+type PresentationInteract struct {
+	Service []InteractService `json:"service"`
+}
+
+// InteractService is a service receiving the presentation of the holder.
+type InteractService struct {
+	Type            string `json:"type"`
+	ServiceEndpoint string `json:"serviceEndpoint"`
+}
+
+// PresentationDefinitionRequest is a presentation request of the pe format.
+type PresentationDefinitionRequest struct {
+	PresentationDefinition json.RawMessage `json:"presentation_definition"`
+	Challenge              string          `json:"challenge"`
+	Domain                 string          `json:"domain,omitempty"`
+	// ResponseURI is the URL the holder posts its presentation to.
+	ResponseURI string `json:"response_uri"`
+	// AnonCredsProofRequest is the proof request the holder can answer with an AnonCreds presentation instead, if
+	// the presentation definition can be translated to it.
+	AnonCredsProofRequest json.RawMessage `json:"anoncreds_proof_request,omitempty"`
 }
 
 // VerifyCredentialResponse describes verify credential response
@@ -73,3 +284,31 @@ type VerifyCredentialResponse struct {
 	Verified bool   `json:"verified"`
 	Message  string `json:"message"`
 }
+
+// TrustedIssuerList is the subject of a governance credential listing the trusted issuers.
+type TrustedIssuerList struct {
+	TrustedIssuers []TrustedIssuer `json:"trustedIssuers,omitempty"`
+}
+
+// TrustedIssuer is an issuer trusted by a governance framework, for the given credential types if set.
+type TrustedIssuer struct {
+	ID              string   `json:"id"`
+	CredentialTypes []string `json:"credentialTypes,omitempty"`
+}
+
+// trusts returns if the issuer is trusted for a credential of the types
+func (i *TrustedIssuer) trusts(types []string) bool {
+	if len(i.CredentialTypes) == 0 {
+		return true
+	}
+
+	for _, credentialType := range i.CredentialTypes {
+		for _, t := range types {
+			if t == credentialType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -7,7 +7,11 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 import (
+	"github.com/trustbloc/edge-service/pkg/doc/vc/binding"
+	vcchallenge "github.com/trustbloc/edge-service/pkg/doc/vc/challenge"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/mdoc"
 	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/sdjwt"
 	"github.com/trustbloc/edge-service/pkg/restapi/model"
 )
 
@@ -65,7 +69,29 @@ type verifyCredentialSuccessResp struct { // nolint: unused,deadcode
 // swagger:response verifyCredentialFailureResp
 type verifyCredentialFailureResp struct { // nolint: unused,deadcode
 	// in: body
-	Checks []*CredentialsVerificationCheckResult `json:"checks,omitempty"`
+	CredentialsVerificationFailResponse
+}
+
+// verifyBatchReq model
+//
+// swagger:parameters verifyBatchReq
+type verifyBatchReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Params BatchVerificationRequest
+}
+
+// verifyBatchResp model
+//
+// swagger:response verifyBatchResp
+type verifyBatchResp struct { // nolint: unused,deadcode
+	// in: body
+	BatchVerificationResponse
 }
 
 // verifyPresentationReq model
@@ -97,3 +123,181 @@ type verifyPresentationFailureResp struct { // nolint: unused,deadcode
 	// in: body
 	Checks []*VerifyPresentationCheckResult `json:"checks,omitempty"`
 }
+
+// verificationFailuresReq model
+//
+// swagger:parameters verificationFailuresReq
+type verificationFailuresReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// the period of the stats, e.g. 15m (1h by default, 24h at most)
+	//
+	// in: query
+	Window string `json:"window"`
+}
+
+// verificationFailuresResp model
+//
+// swagger:response verificationFailuresResp
+type verificationFailuresResp struct { // nolint: unused,deadcode
+	// in: body
+	VerificationFailureStats
+}
+
+// verifyMDocReq model
+//
+// swagger:parameters verifyMDocReq
+type verifyMDocReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Params VerifyMDocRequest
+}
+
+// verifyMDocRes model contains the verified mdoc
+//
+// swagger:response verifyMDocRes
+type verifyMDocRes struct { // nolint: unused,deadcode
+	// in: body
+	mdoc.Document
+}
+
+// verifySDJWTReq model
+//
+// swagger:parameters verifySDJWTReq
+type verifySDJWTReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Params VerifySDJWTRequest
+}
+
+// verifySDJWTRes model contains the credential of the verified SD-JWT with its disclosed claims
+//
+// swagger:response verifySDJWTRes
+type verifySDJWTRes struct { // nolint: unused,deadcode
+	// in: body
+	sdjwt.Credential
+}
+
+// createPresentationRequestReq model
+//
+// swagger:parameters createPresentationRequestReq
+type createPresentationRequestReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// in: body
+	Params CreatePresentationRequest
+}
+
+// getPresentationRequestReq model
+//
+// swagger:parameters getPresentationRequestReq
+type getPresentationRequestReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// presentation request
+	//
+	// in: path
+	// required: true
+	RequestID string `json:"requestID"`
+}
+
+// presentationRequestRes model
+//
+// swagger:response presentationRequestRes
+type presentationRequestRes struct { // nolint: unused,deadcode
+	// in: body
+	PresentationRequestResponse
+}
+
+// interactRequestReq model
+//
+// swagger:parameters interactRequestReq
+type interactRequestReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ID string `json:"id"`
+
+	// presentation request
+	//
+	// in: path
+	// required: true
+	RequestID string `json:"requestID"`
+
+	// in: body
+	Params InteractPresentationRequest
+}
+
+// interactPresentationRequestRes model
+//
+// swagger:response interactPresentationRequestRes
+type interactPresentationRequestRes struct { // nolint: unused,deadcode
+	// in: body
+	InteractPresentationRequestResponse
+}
+
+// didAuthReq model
+//
+// swagger:parameters didAuthReq
+type didAuthReq struct { // nolint: unused,deadcode
+	// in: body
+	Params DIDAuthRequest
+}
+
+// didAuthChallengeRes model
+//
+// swagger:response didAuthChallengeRes
+type didAuthChallengeRes struct { // nolint: unused,deadcode
+	// in: body
+	vcchallenge.Challenge
+}
+
+// didAuthRes model
+//
+// swagger:response didAuthRes
+type didAuthRes struct { // nolint: unused,deadcode
+	// in: body
+	binding.DIDAuth
+}
+
+// createChallengeReq model
+//
+// swagger:parameters createChallengeReq
+type createChallengeReq struct { // nolint: unused,deadcode
+	// profile
+	//
+	// in: path
+	// required: true
+	ProfileID string `json:"profileID"`
+}
+
+// challengeRes model
+//
+// swagger:response challengeRes
+type challengeRes struct { // nolint: unused,deadcode
+	// in: body
+	vcchallenge.Challenge
+}
@@ -7,26 +7,46 @@ SPDX-License-Identifier: Apache-2.0
 package operation
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
+	ariescrypto "github.com/hyperledger/aries-framework-go/pkg/crypto"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jwt"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	"github.com/hyperledger/aries-framework-go/pkg/kms"
+	"github.com/piprate/json-gold/ld"
 	"github.com/trustbloc/edge-core/pkg/log"
 	"github.com/trustbloc/edge-core/pkg/storage"
 
+	"github.com/trustbloc/edge-service/pkg/cache"
+	"github.com/trustbloc/edge-service/pkg/cache/memcache"
+	vcchallenge "github.com/trustbloc/edge-service/pkg/doc/vc/challenge"
 	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/keyfetcher"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/presentationrequest"
 	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/redaction"
 	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	"github.com/trustbloc/edge-service/pkg/events"
+	"github.com/trustbloc/edge-service/pkg/httpclient"
 	"github.com/trustbloc/edge-service/pkg/internal/common/diddoc"
 	"github.com/trustbloc/edge-service/pkg/internal/common/support"
+	"github.com/trustbloc/edge-service/pkg/ratelimit"
+	"github.com/trustbloc/edge-service/pkg/restapi/internal/common/credentialref"
+	commondid "github.com/trustbloc/edge-service/pkg/restapi/internal/common/did"
 	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
 )
 
@@ -45,16 +65,22 @@ const (
 	successMsg = "success"
 
 	// credential verification checks
-	proofCheck  = "proof"
-	statusCheck = "status"
+	proofCheck      = "proof"
+	statusCheck     = "status"
+	governanceCheck = "governance"
+	policyCheck     = "policy"
 
 	// proof data keys
 	challenge          = "challenge"
+	created            = "created"
 	domain             = "domain"
 	proofPurpose       = "proofPurpose"
 	verificationMethod = "verificationMethod"
 
 	cslRequestTokenName = "csl"
+
+	// governanceVCCacheTTL is how long a fetched governance credential is reused before it is fetched again
+	governanceVCCacheTTL = 5 * time.Minute
 )
 
 var logger = log.New("edge-service-verifier-restapi")
@@ -77,11 +103,66 @@ func New(config *Config) (*Operation, error) {
 		return nil, err
 	}
 
+	challenges, err := vcchallenge.New(config.StoreProvider, vcchallenge.DefaultTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate challenge store: %w", err)
+	}
+
+	presentationRequests, err := presentationrequest.New(config.StoreProvider, presentationrequest.DefaultTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate presentation request store: %w", err)
+	}
+
 	svc := &Operation{
 		profileStore:  p,
 		vdri:          config.VDRI,
 		httpClient:    &http.Client{Transport: &http.Transport{TLSClientConfig: config.TLSConfig}},
 		requestTokens: config.RequestTokens,
+		rateLimit:     config.RateLimit,
+		governanceVCs: make(map[string]*cachedGovernanceVC),
+		policyEngine:  config.PolicyEngine,
+		checkTimeout:  config.CheckTimeout,
+		failures:      newFailureTracker(),
+		failureAlert:  config.FailureAlert,
+		challenges:    challenges,
+		maxBatchSize:  config.MaxBatchSize,
+		keyFetchers:   config.KeyFetchers,
+		hostURL:       config.HostURL,
+	}
+
+	svc.presentationRequests = presentationRequests
+
+	svc.maxRequestTTL = config.MaxPresentationRequestTTL
+	if svc.maxRequestTTL <= 0 {
+		svc.maxRequestTTL = presentationrequest.DefaultMaxTTL
+	}
+
+	svc.credentialURLs = config.CredentialURLs
+	svc.events = config.Events
+	svc.credentialRefClient = credentialref.NewClient(config.HTTPClients)
+
+	// the verification receipts are signed with the keys of the key manager
+	if config.KeyManager != nil {
+		svc.commonDID = commondid.New(&commondid.Config{VDRI: config.VDRI, KeyManager: config.KeyManager,
+			Domain: config.Domain, TLSConfig: config.TLSConfig, HTTPClients: config.HTTPClients})
+		svc.crypto = crypto.New(config.KeyManager, config.Crypto, config.VDRI)
+	}
+
+	if svc.maxBatchSize <= 0 {
+		svc.maxBatchSize = DefaultMaxBatchSize
+	}
+
+	if svc.policyEngine == nil {
+		svc.policyEngine = policy.New()
+	}
+
+	if config.HTTPClients != nil {
+		svc.httpClient = config.HTTPClients.Client()
+		svc.documentLoader = config.HTTPClients.DocumentLoader()
+	}
+
+	if config.ResultCacheTTL > 0 {
+		svc.resultCache = memcache.New(resultCacheSize, config.ResultCacheTTL)
 	}
 
 	return svc, nil
@@ -93,6 +174,42 @@ type Config struct {
 	VDRI          vdriapi.Registry
 	TLSConfig     *tls.Config
 	RequestTokens map[string]string
+	// RateLimit limits the rate of the verification requests (optional).
+	RateLimit *ratelimit.Config
+	// PolicyEngine evaluates the policy rules of the profiles, with the built-in evaluators by default.
+	PolicyEngine *policy.Engine
+	// ResultCacheTTL is how long the successful verification of a credential is reused for the same verification
+	// request, as long as the status list of the credential is unchanged. The results are not cached if not set.
+	ResultCacheTTL time.Duration
+	// CheckTimeout bounds the time the checks of a verification run concurrently, the checks not completed by then
+	// failing. The checks are not bounded if not set.
+	CheckTimeout time.Duration
+	// HTTPClients creates the client of the status list and governance requests, and the loader of the JSON-LD
+	// contexts of the verified credentials (optional, a client with TLSConfig and the default loader by default).
+	HTTPClients *httpclient.Factory
+	// FailureAlert alerts a webhook when the failure rate of the verifications of a profile spikes (optional).
+	FailureAlert *FailureAlertConfig
+	// CredentialURLs are the URLs under which the credentials verified by URL may be fetched, matched by scheme,
+	// host and path prefix. The credentials can't be verified by URL if not set.
+	CredentialURLs []*url.URL
+	// Events emits the verification events to a message broker (optional).
+	Events *events.Emitter
+	// KeyManager creates the keys signing the verification receipts of the profiles, signed by Crypto. The
+	// profiles can't return receipts if not set.
+	KeyManager kms.KeyManager
+	Crypto     ariescrypto.Crypto
+	// Domain is the domain of the DIDs signing the receipts, unless created as a did:key.
+	Domain string
+	// MaxBatchSize is the maximum number of credentials of a batch verification, DefaultMaxBatchSize if not set.
+	MaxBatchSize int
+	// KeyFetchers fetch the keys of the issuers without DIDs, e.g. from the key sets of trusted issuers, tried in
+	// order after the DIDs are resolved (optional).
+	KeyFetchers []keyfetcher.Fetcher
+	// HostURL is the external URL of the service, the base of the request URIs of the presentation requests.
+	HostURL string
+	// MaxPresentationRequestTTL is the longest expiry of the presentation requests, at most the time the transient
+	// records are kept in the database (presentationrequest.DefaultMaxTTL if not set).
+	MaxPresentationRequestTTL time.Duration
 }
 
 // Operation defines handlers for Edge service
@@ -101,6 +218,40 @@ type Operation struct {
 	vdri          vdriapi.Registry
 	httpClient    httpClient
 	requestTokens map[string]string
+	rateLimit     *ratelimit.Config
+	policyEngine  *policy.Engine
+	resultCache   cache.Cache
+	checkTimeout  time.Duration
+	maxBatchSize  int
+	keyFetchers   []keyfetcher.Fetcher
+	// the loader of the JSON-LD contexts, the default loader if nil
+	documentLoader ld.DocumentLoader
+
+	governanceVCs   map[string]*cachedGovernanceVC
+	governanceMutex sync.Mutex
+
+	failures     *failureTracker
+	failureAlert *FailureAlertConfig
+
+	challenges challengeStore
+
+	presentationRequests presentationRequestStore
+	maxRequestTTL        time.Duration
+	hostURL              string
+
+	credentialURLs      []*url.URL
+	credentialRefClient *http.Client
+
+	events *events.Emitter
+
+	commonDID commonDID
+	crypto    *crypto.Crypto
+}
+
+// cachedGovernanceVC is a verified governance credential, reused until expiresAt
+type cachedGovernanceVC struct {
+	vc        *verifiable.Credential
+	expiresAt time.Time
 }
 
 // GetRESTHandlers get all controller API handler available for this service
@@ -111,8 +262,31 @@ func (o *Operation) GetRESTHandlers() []Handler {
 		support.NewHTTPHandler(getProfileEndpoint, http.MethodGet, o.getProfileHandler),
 
 		// verification
-		support.NewHTTPHandler(credentialsVerificationEndpoint, http.MethodPost, o.verifyCredentialHandler),
-		support.NewHTTPHandler(presentationsVerificationEndpoint, http.MethodPost, o.verifyPresentationHandler),
+		support.NewHTTPHandler(credentialsVerificationEndpoint, http.MethodPost,
+			commhttp.RateLimit(o.rateLimit, profileIDPathParam, o.verifyCredentialHandler)),
+		support.NewHTTPHandler(batchVerificationEndpoint, http.MethodPost,
+			commhttp.RateLimit(o.rateLimit, profileIDPathParam, o.verifyBatchHandler)),
+		support.NewHTTPHandler(presentationsVerificationEndpoint, http.MethodPost,
+			commhttp.RateLimit(o.rateLimit, profileIDPathParam, o.verifyPresentationHandler)),
+		support.NewHTTPHandler(mdocsVerificationEndpoint, http.MethodPost,
+			commhttp.RateLimit(o.rateLimit, profileIDPathParam, o.verifyMDocHandler)),
+		support.NewHTTPHandler(sdJWTsVerificationEndpoint, http.MethodPost,
+			commhttp.RateLimit(o.rateLimit, profileIDPathParam, o.verifySDJWTHandler)),
+
+		// verification failures
+		support.NewHTTPHandler(failuresEndpoint, http.MethodGet, o.verificationFailuresHandler),
+
+		// challenges
+		support.NewHTTPHandler(challengesEndpoint, http.MethodPost, o.createChallengeHandler),
+
+		// presentation requests
+		support.NewHTTPHandler(presentationRequestsEndpoint, http.MethodPost, o.createPresentationRequestHandler),
+		support.NewHTTPHandler(presentationRequestEndpoint, http.MethodGet, o.getPresentationRequestHandler),
+		support.NewHTTPHandler(presentationRequestEndpoint, http.MethodPost,
+			commhttp.RateLimit(o.rateLimit, profileIDPathParam, o.interactPresentationRequestHandler)),
+
+		// DIDAuth
+		support.NewHTTPHandler(didAuthEndpoint, http.MethodPost, o.didAuthHandler),
 	}
 }
 
@@ -126,40 +300,48 @@ func (o *Operation) GetRESTHandlers() []Handler {
 func (o *Operation) createProfileHandler(rw http.ResponseWriter, req *http.Request) {
 	request := &verifier.ProfileData{}
 
-	if err := json.NewDecoder(req.Body).Decode(request); err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidRequestErrMsg+": %s", err.Error()))
+	if err := commhttp.DecodeJSON(req, request); err != nil {
+		commhttp.WriteError(rw, err)
 
 		return
 	}
 
-	if err := validateProfileRequest(request); err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+	if err := o.CreateProfile(request); err != nil {
+		commhttp.WriteError(rw, err)
 
 		return
 	}
 
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, request)
+}
+
+// CreateProfile validates and saves the verifier profile.
+func (o *Operation) CreateProfile(request *verifier.ProfileData) error {
+	if err := validateProfileRequest(request, o.policyEngine); err != nil {
+		return commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest, err.Error())
+	}
+
 	profile, err := o.profileStore.GetProfile(request.ID)
 	if err != nil && !errors.Is(err, storage.ErrValueNotFound) {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
-
-		return
+		return commhttp.NewError(http.StatusBadRequest, commhttp.StorageError, err.Error())
 	}
 
 	if profile != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf("profile %s already exists", profile.ID))
+		return commhttp.NewError(http.StatusBadRequest, commhttp.AlreadyExists,
+			fmt.Sprintf("profile %s already exists", profile.ID))
+	}
 
-		return
+	if err = o.createReceiptKey(request); err != nil {
+		return err
 	}
 
 	err = o.profileStore.SaveProfile(request)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
-
-		return
+		return commhttp.NewError(http.StatusBadRequest, commhttp.StorageError, err.Error())
 	}
 
-	rw.WriteHeader(http.StatusCreated)
-	commhttp.WriteResponse(rw, request)
+	return nil
 }
 
 // RetrieveProfile swagger:route GET /verifier/profile/{id} verifier getProfileReq
@@ -170,11 +352,9 @@ func (o *Operation) createProfileHandler(rw http.ResponseWriter, req *http.Reque
 //    default: genericError
 //        200: profileData
 func (o *Operation) getProfileHandler(rw http.ResponseWriter, req *http.Request) {
-	profileID := mux.Vars(req)[profileIDPathParam]
-
-	profile, err := o.profileStore.GetProfile(profileID)
+	profile, err := o.GetProfile(mux.Vars(req)[profileIDPathParam])
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, err.Error())
+		commhttp.WriteError(rw, err)
 
 		return
 	}
@@ -182,10 +362,21 @@ func (o *Operation) getProfileHandler(rw http.ResponseWriter, req *http.Request)
 	commhttp.WriteResponse(rw, profile)
 }
 
+// GetProfile returns the verifier profile.
+func (o *Operation) GetProfile(id string) (*verifier.ProfileData, error) {
+	profile, err := o.profileStore.GetProfile(id)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.ProfileErrorCode(err), err.Error())
+	}
+
+	return profile, nil
+}
+
 // nolint dupl
 // VerifyCredential swagger:route POST /{id}/verifier/credentials verifier verifyCredentialReq
 //
-// Verifies a credential.
+// Verifies a credential, or the credential fetched from the credential URL of the request, returned with the
+// checks. A verification receipt signed by the receipt key of the profile is returned if requested.
 //
 // Responses:
 //    default: genericError
@@ -193,12 +384,9 @@ func (o *Operation) getProfileHandler(rw http.ResponseWriter, req *http.Request)
 //        400: verifyCredentialFailureResp
 func (o *Operation) verifyCredentialHandler(rw http.ResponseWriter, req *http.Request) {
 	// get the profile
-	profileID := mux.Vars(req)[profileIDPathParam]
-
-	profile, err := o.profileStore.GetProfile(profileID)
+	profile, err := o.getVerifierProfile(mux.Vars(req)[profileIDPathParam])
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf("invalid verifier profile - id=%s: err=%s",
-			profileID, err.Error()))
+		commhttp.WriteError(rw, err)
 
 		return
 	}
@@ -206,76 +394,195 @@ func (o *Operation) verifyCredentialHandler(rw http.ResponseWriter, req *http.Re
 	// get the request
 	verificationReq := CredentialsVerificationRequest{}
 
-	err = json.NewDecoder(req.Body).Decode(&verificationReq)
+	err = commhttp.DecodeJSON(req, &verificationReq)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidRequestErrMsg+": %s", err.Error()))
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	receiptRequested := verificationReq.Opts != nil && verificationReq.Opts.Receipt
+
+	if err = checkReceiptKey(profile, receiptRequested); err != nil {
+		commhttp.WriteError(rw, err)
 
 		return
 	}
 
-	vc, err := verifiable.ParseUnverifiedCredential(verificationReq.Credential)
+	checks, result, err := o.verifyCredential(req.Context(), profile, &verificationReq)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidRequestErrMsg+": %s", err.Error()))
+		commhttp.WriteError(rw, err)
 
 		return
 	}
 
-	checks := getCredentialChecks(profile, verificationReq.Opts)
+	// the fetched credential is returned with the result of its verification
+	var fetched json.RawMessage
+	if verificationReq.CredentialURL != "" {
+		fetched = verificationReq.Credential
+	}
 
-	var result []CredentialsVerificationCheckResult
-
-	for _, val := range checks {
-		switch val {
-		case proofCheck:
-			err := o.validateCredentialProof(verificationReq.Credential, verificationReq.Opts, false)
-			if err != nil {
-				result = append(result, CredentialsVerificationCheckResult{
-					Check: val,
-					Error: err.Error(),
-				})
-			}
-		case statusCheck:
-			failureMessage := ""
-			if vc.Status != nil && vc.Status.ID != "" {
-				ver, err := o.checkVCStatus(vc.Status.ID, vc.ID)
-
-				if err != nil {
-					failureMessage = fmt.Sprintf("failed to fetch the status : %s", err.Error())
-				} else if !ver.Verified {
-					failureMessage = ver.Message
-				}
-			}
+	var receipt json.RawMessage
 
-			if failureMessage != "" {
-				result = append(result, CredentialsVerificationCheckResult{
-					Check: val,
-					Error: failureMessage,
-				})
-			}
-		default:
-			result = append(result, CredentialsVerificationCheckResult{
-				Check: val,
-				Error: "check not supported",
-			})
+	if receiptRequested {
+		receipt, err = o.newReceipt(profile, verifiableCredential, verificationReq.Credential, checks,
+			failedCredentialChecks(result))
+		if err != nil {
+			commhttp.WriteError(rw, err)
+
+			return
 		}
 	}
 
 	if len(result) == 0 {
 		rw.WriteHeader(http.StatusOK)
 		commhttp.WriteResponse(rw, &CredentialsVerificationSuccessResponse{
-			Checks: checks,
+			Checks:     checks,
+			Credential: fetched,
+			Receipt:    receipt,
 		})
 	} else {
 		rw.WriteHeader(http.StatusBadRequest)
 		commhttp.WriteResponse(rw, &CredentialsVerificationFailResponse{
-			Checks: result,
+			Checks:     result,
+			Credential: fetched,
+			Receipt:    receipt,
 		})
 	}
 }
 
+// VerifyCredential runs the checks of the request (or of the verifier profile) on the credential, and returns
+// the checks with the failed ones. The credential is verified if no check failed. The credential fetched from the
+// credential URL of the request is set as its credential.
+func (o *Operation) VerifyCredential(profileID string,
+	verificationReq *CredentialsVerificationRequest) ([]string, []CredentialsVerificationCheckResult, error) {
+	profile, err := o.getVerifierProfile(profileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return o.verifyCredential(context.Background(), profile, verificationReq)
+}
+
+func (o *Operation) getVerifierProfile(profileID string) (*verifier.ProfileData, error) {
+	profile, err := o.profileStore.GetProfile(profileID)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.ProfileErrorCode(err),
+			fmt.Sprintf("invalid verifier profile - id=%s: err=%s", profileID, err.Error()))
+	}
+
+	return profile, nil
+}
+
+func (o *Operation) verifyCredential(ctx context.Context, profile *verifier.ProfileData,
+	verificationReq *CredentialsVerificationRequest) ([]string, []CredentialsVerificationCheckResult, error) {
+	if err := o.fetchCredential(verificationReq); err != nil {
+		return nil, nil, err
+	}
+
+	vc, err := verifiable.ParseUnverifiedCredential(credentialBytes(verificationReq.Credential))
+	if err != nil {
+		return nil, nil, &commhttp.Error{Status: http.StatusBadRequest, Code: commhttp.InvalidRequest,
+			Message: invalidRequestErrMsg, Details: err.Error()}
+	}
+
+	checks := getCredentialChecks(profile, verificationReq.Opts)
+
+	ctx, cancel := o.checksContext(ctx)
+	defer cancel()
+
+	cacheKey := o.resultCacheKey(profile, verificationReq)
+	if o.cachedResultValid(ctx, cacheKey, vc) {
+		o.recordVerification(events.CredentialVerified, profile.ID, vc.ID, checks, nil)
+
+		return checks, nil, nil
+	}
+
+	outcomes := runChecks(ctx, checks, func(ctx context.Context, check string) checkOutcome {
+		return o.runCredentialCheck(ctx, check, profile, vc, verificationReq)
+	})
+
+	var (
+		result        []CredentialsVerificationCheckResult
+		statusVersion string
+	)
+
+	for i, outcome := range outcomes {
+		if outcome.statusVersion != "" {
+			statusVersion = outcome.statusVersion
+		}
+
+		if outcome.failure != "" {
+			result = append(result, CredentialsVerificationCheckResult{
+				Check:      checks[i],
+				Error:      outcome.failure,
+				Violations: outcome.violations,
+			})
+		}
+	}
+
+	// only the successful verifications are cached, a failed check may be transient
+	if len(result) == 0 {
+		o.cacheResult(cacheKey, vc, statusVersion)
+	}
+
+	o.recordVerification(events.CredentialVerified, profile.ID, vc.ID, checks, failureReasons(checks, outcomes))
+
+	return checks, result, nil
+}
+
+// runStatusCheck checks the credential is not revoked, the outcome having the version of the status list of the
+// credential
+func (o *Operation) runStatusCheck(ctx context.Context, vc *verifiable.Credential) checkOutcome {
+	if vc.Status == nil || vc.Status.ID == "" {
+		return checkOutcome{}
+	}
+
+	ver, version, err := o.checkVCStatus(ctx, vc.Status.ID, vc.ID)
+	if err != nil {
+		return checkOutcome{failure: fmt.Sprintf("failed to fetch the status : %s", err.Error()),
+			reason: reasonStatusUnavailable}
+	} else if !ver.Verified {
+		return checkOutcome{failure: ver.Message, statusVersion: version}
+	}
+
+	return checkOutcome{statusVersion: version}
+}
+
+// runCredentialCheck runs the check on the credential
+func (o *Operation) runCredentialCheck(ctx context.Context, check string, profile *verifier.ProfileData,
+	vc *verifiable.Credential, verificationReq *CredentialsVerificationRequest) checkOutcome {
+	switch check {
+	case proofCheck:
+		if err := o.validateCredentialProof(ctx, credentialBytes(verificationReq.Credential), verificationReq.Opts,
+			false); err != nil {
+			return checkOutcome{failure: err.Error()}
+		}
+
+		if err := checkDisclosures(vc, verificationReq.Opts); err != nil {
+			return checkOutcome{failure: err.Error()}
+		}
+	case statusCheck:
+		return o.runStatusCheck(ctx, vc)
+	case governanceCheck:
+		if err := o.checkGovernance(ctx, profile, vc); err != nil {
+			return checkOutcome{failure: err.Error()}
+		}
+	case policyCheck:
+		if violations := o.policyEngine.Evaluate(profile.Policy, vc); len(violations) > 0 {
+			return checkOutcome{failure: policy.Describe(violations), violations: violations}
+		}
+	default:
+		return checkOutcome{failure: "check not supported"}
+	}
+
+	return checkOutcome{}
+}
+
 // VerifyPresentation swagger:route POST /{id}/verifier/presentations verifier verifyPresentationReq
 //
-// Verifies a presentation.
+// Verifies a presentation. A verification receipt signed by the receipt key of the profile is returned if
+// requested.
 //
 // Responses:
 //    default: genericError
@@ -283,12 +590,9 @@ func (o *Operation) verifyCredentialHandler(rw http.ResponseWriter, req *http.Re
 //        400: verifyPresentationFailureResp
 func (o *Operation) verifyPresentationHandler(rw http.ResponseWriter, req *http.Request) {
 	// get the profile
-	profileID := mux.Vars(req)[profileIDPathParam]
-
-	profile, err := o.profileStore.GetProfile(profileID)
+	profile, err := o.getVerifierProfile(mux.Vars(req)[profileIDPathParam])
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf("invalid verifier profile - id=%s: err=%s",
-			profileID, err.Error()))
+		commhttp.WriteError(rw, err)
 
 		return
 	}
@@ -296,55 +600,173 @@ func (o *Operation) verifyPresentationHandler(rw http.ResponseWriter, req *http.
 	// get the request
 	verificationReq := VerifyPresentationRequest{}
 
-	err = json.NewDecoder(req.Body).Decode(&verificationReq)
+	err = commhttp.DecodeJSON(req, &verificationReq)
 	if err != nil {
-		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidRequestErrMsg+": %s", err.Error()))
+		commhttp.WriteError(rw, err)
 
 		return
 	}
 
-	checks := getPresentationChecks(profile, verificationReq.Opts)
+	receiptRequested := verificationReq.Opts != nil && verificationReq.Opts.Receipt
 
-	var result []VerifyPresentationCheckResult
+	if err = checkReceiptKey(profile, receiptRequested); err != nil {
+		commhttp.WriteError(rw, err)
 
-	for _, val := range checks {
-		switch val {
-		case proofCheck:
-			err := o.validatePresentationProof(verificationReq.Presentation, verificationReq.Opts)
-			if err != nil {
-				result = append(result, VerifyPresentationCheckResult{
-					Check: val,
-					Error: err.Error(),
-				})
-			}
-		default:
-			result = append(result, VerifyPresentationCheckResult{
-				Check: val,
-				Error: "check not supported",
-			})
+		return
+	}
+
+	checks, result := o.verifyPresentation(req.Context(), profile, &verificationReq)
+
+	var receipt json.RawMessage
+
+	if receiptRequested {
+		receipt, err = o.newReceipt(profile, verifiablePresentation, verificationReq.Presentation, checks,
+			failedPresentationChecks(result))
+		if err != nil {
+			commhttp.WriteError(rw, err)
+
+			return
 		}
 	}
 
 	if len(result) == 0 {
 		rw.WriteHeader(http.StatusOK)
 		commhttp.WriteResponse(rw, &VerifyPresentationSuccessResponse{
-			Checks: checks,
+			Checks:  checks,
+			Receipt: receipt,
 		})
 	} else {
 		rw.WriteHeader(http.StatusBadRequest)
 		commhttp.WriteResponse(rw, &VerifyPresentationFailureResponse{
-			Checks: result,
+			Checks:  result,
+			Receipt: receipt,
 		})
 	}
 }
 
-func (o *Operation) validateCredentialProof(vcByte []byte, opts *CredentialsVerificationOptions, vcInVPValidation bool) error { // nolint: lll,gocyclo
-	vc, err := o.parseAndVerifyVCStrictMode(vcByte)
+// VerifyPresentation runs the checks of the request (or of the verifier profile) on the presentation, and returns
+// the checks with the failed ones. The presentation is verified if no check failed.
+func (o *Operation) VerifyPresentation(profileID string,
+	verificationReq *VerifyPresentationRequest) ([]string, []VerifyPresentationCheckResult, error) {
+	profile, err := o.getVerifierProfile(profileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	checks, result := o.verifyPresentation(context.Background(), profile, verificationReq)
+
+	return checks, result, nil
+}
+
+func (o *Operation) verifyPresentation(ctx context.Context, profile *verifier.ProfileData,
+	verificationReq *VerifyPresentationRequest) ([]string, []VerifyPresentationCheckResult) {
+	checks := getPresentationChecks(profile, verificationReq.Opts)
+
+	ctx, cancel := o.checksContext(ctx)
+	defer cancel()
+
+	outcomes := runChecks(ctx, checks, func(_ context.Context, check string) checkOutcome {
+		return o.runPresentationCheck(check, profile, verificationReq)
+	})
+
+	var result []VerifyPresentationCheckResult
+
+	for i, outcome := range outcomes {
+		if outcome.failure != "" {
+			result = append(result, VerifyPresentationCheckResult{
+				Check:      checks[i],
+				Error:      outcome.failure,
+				Violations: outcome.violations,
+			})
+		}
+	}
+
+	o.recordVerification(events.PresentationVerified, profile.ID, "", checks, failureReasons(checks, outcomes))
+
+	return checks, result
+}
+
+// runPresentationCheck runs the check on the presentation
+func (o *Operation) runPresentationCheck(check string, profile *verifier.ProfileData,
+	verificationReq *VerifyPresentationRequest) checkOutcome {
+	switch check {
+	case proofCheck:
+		if err := o.validatePresentationProof(profile, verificationReq.Presentation, verificationReq.Opts); err != nil {
+			return checkOutcome{failure: err.Error()}
+		}
+	case policyCheck:
+		if violations, err := o.evaluatePresentationPolicy(profile, verificationReq.Presentation); err != nil {
+			return checkOutcome{failure: err.Error()}
+		} else if len(violations) > 0 {
+			return checkOutcome{failure: policy.Describe(violations), violations: violations}
+		}
+	default:
+		return checkOutcome{failure: "check not supported"}
+	}
+
+	return checkOutcome{}
+}
+
+// evaluatePresentationPolicy evaluates the policy of the profile on each credential of the presentation, the
+// messages of the violations being prefixed with the ID of the credential
+func (o *Operation) evaluatePresentationPolicy(profile *verifier.ProfileData,
+	vpBytes []byte) ([]policy.Violation, error) {
+	vp, err := verifiable.ParseUnverifiedPresentation(vpBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the presentation : %w", err)
+	}
+
+	var violations []policy.Violation
+
+	for _, cred := range vp.Credentials() {
+		vcBytes, marshalErr := json.Marshal(cred)
+		if marshalErr != nil {
+			return nil, marshalErr
+		}
+
+		vc, parseErr := verifiable.ParseUnverifiedCredential(vcBytes)
+		if parseErr != nil {
+			return nil, fmt.Errorf("failed to parse the presentation credential : %w", parseErr)
+		}
+
+		for _, violation := range o.policyEngine.Evaluate(profile.Policy, vc) {
+			violation.Message = fmt.Sprintf("credential %s: %s", vc.ID, violation.Message)
+			violations = append(violations, violation)
+		}
+	}
+
+	return violations, nil
+}
+
+// checkDisclosures checks the disclosures of the options, if any, are claims the credential commits to
+func checkDisclosures(vc *verifiable.Credential, opts *CredentialsVerificationOptions) error {
+	if opts == nil || len(opts.Disclosures) == 0 {
+		return nil
+	}
+
+	if _, err := redaction.Verify(vc, opts.Disclosures); err != nil {
+		return fmt.Errorf("invalid disclosures : %w", err)
+	}
+
+	return nil
+}
+
+func (o *Operation) validateCredentialProof(ctx context.Context, vcByte []byte, opts *CredentialsVerificationOptions, vcInVPValidation bool) error { // nolint: lll,gocyclo
+	vc, err := o.parseAndVerifyVCStrictMode(ctx, vcByte)
 
 	if err != nil {
 		return fmt.Errorf("verifiable credential proof validation error : %w", err)
 	}
 
+	// the proof of a JWT credential, its signature, is checked by the parsing with the key of its issuer
+	if jwt.IsJWS(string(vcByte)) {
+		if opts != nil && (opts.Challenge != "" || opts.Domain != "") {
+			return errors.New("the challenge and domain of the proof are not supported by JWT credentials")
+		}
+
+		return nil
+	}
+
 	if len(vc.Proofs) == 0 {
 		return errors.New("verifiable credential doesn't contains proof")
 	}
@@ -367,6 +789,10 @@ func (o *Operation) validateCredentialProof(vcByte []byte, opts *CredentialsVeri
 		if validateErr := validateProofData(proof, domain, opts.Domain); validateErr != nil {
 			return validateErr
 		}
+
+		if validateErr := validateProofOptions(proof, opts.ProofPurpose, opts.MaxProofAge); validateErr != nil {
+			return validateErr
+		}
 	}
 
 	// get the verification method
@@ -376,7 +802,7 @@ func (o *Operation) validateCredentialProof(vcByte []byte, opts *CredentialsVeri
 	}
 
 	// get the did doc from verification method
-	didDoc, err := getDIDDocFromProof(verificationMethod, o.vdri)
+	didDoc, err := getDIDDocFromProof(verificationMethod, o.resolver(ctx))
 	if err != nil {
 		return err
 	}
@@ -394,7 +820,10 @@ func (o *Operation) validateCredentialProof(vcByte []byte, opts *CredentialsVeri
 	return nil
 }
 
-func (o *Operation) validatePresentationProof(vpByte []byte, opts *VerifyPresentationOptions) error { // nolint: gocyclo
+// validatePresentationProof validates the proof of the presentation. The challenge of the proof is consumed if the
+// profile requires challenges, the challenge of the options being optional.
+func (o *Operation) validatePresentationProof(profile *verifier.ProfileData, vpByte []byte, // nolint: gocyclo
+	opts *VerifyPresentationOptions) error {
 	vp, err := o.parseAndVerifyVP(vpByte)
 
 	if err != nil {
@@ -414,8 +843,10 @@ func (o *Operation) validatePresentationProof(vpByte []byte, opts *VerifyPresent
 	}
 
 	// validate challenge
-	if validateErr := validateProofData(proof, challenge, opts.Challenge); validateErr != nil {
-		return validateErr
+	if !profile.RequireChallenge || opts.Challenge != "" {
+		if validateErr := validateProofData(proof, challenge, opts.Challenge); validateErr != nil {
+			return validateErr
+		}
 	}
 
 	// validate domain
@@ -423,6 +854,10 @@ func (o *Operation) validatePresentationProof(vpByte []byte, opts *VerifyPresent
 		return validateErr
 	}
 
+	if validateErr := validateProofOptions(proof, opts.ProofPurpose, opts.MaxProofAge); validateErr != nil {
+		return validateErr
+	}
+
 	// get the verification method
 	verificationMethod, err := getVerificationMethodFromProof(proof)
 	if err != nil {
@@ -445,26 +880,27 @@ func (o *Operation) validatePresentationProof(vpByte []byte, opts *VerifyPresent
 		return fmt.Errorf("verifiable presentation proof purpose validation error : %w", err)
 	}
 
+	if profile.RequireChallenge {
+		return o.consumePresentationChallenge(profile, proof)
+	}
+
 	return nil
 }
 
-func (o *Operation) checkVCStatus(vclID, vcID string) (*VerifyCredentialResponse, error) {
+// checkVCStatus checks the credential is not listed in the status list, and returns the version of the status list
+func (o *Operation) checkVCStatus(ctx context.Context, vclID, vcID string) (*VerifyCredentialResponse, string,
+	error) {
 	vcResp := &VerifyCredentialResponse{
 		Verified: false}
 
-	req, err := http.NewRequest(http.MethodGet, vclID, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := o.sendHTTPRequest(req, http.StatusOK, o.requestTokens[cslRequestTokenName])
+	resp, version, err := o.fetchStatusList(ctx, vclID, "")
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var csl cslstatus.CSL
 	if err := json.Unmarshal(resp, &csl); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal resp to csl: %w", err)
+		return nil, "", fmt.Errorf("failed to unmarshal resp to csl: %w", err)
 	}
 
 	for _, vcStatus := range csl.VC {
@@ -472,35 +908,119 @@ func (o *Operation) checkVCStatus(vclID, vcID string) (*VerifyCredentialResponse
 			continue
 		}
 
-		statusVc, err := o.parseAndVerifyVC([]byte(vcStatus))
+		statusVc, err := o.parseAndVerifyVC(ctx, []byte(vcStatus))
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse and verify status vc: %s", err.Error())
+			return nil, "", fmt.Errorf("failed to parse and verify status vc: %s", err.Error())
 		}
 
 		subjectBytes, err := json.Marshal(statusVc.Subject)
 		if err != nil {
-			return nil, fmt.Errorf(fmt.Sprintf("failed to marshal status vc subject: %s", err.Error()))
+			return nil, "", fmt.Errorf(fmt.Sprintf("failed to marshal status vc subject: %s", err.Error()))
 		}
 
 		vcResp.Message = string(subjectBytes)
 
-		return vcResp, nil
+		return vcResp, version, nil
 	}
 
 	vcResp.Verified = true
 	vcResp.Message = successMsg
 
-	return vcResp, nil
+	return vcResp, version, nil
 }
 
-func (o *Operation) parseAndVerifyVCStrictMode(vcBytes []byte) (*verifiable.Credential, error) {
-	vc, err := verifiable.ParseCredential(
-		vcBytes,
-		verifiable.WithPublicKeyFetcher(
-			verifiable.NewDIDKeyResolver(o.vdri).PublicKeyFetcher(),
-		),
-		verifiable.WithStrictValidation(),
-	)
+// checkGovernance checks the issuer of the credential is trusted by the governance credential of the profile
+func (o *Operation) checkGovernance(ctx context.Context, profile *verifier.ProfileData,
+	vc *verifiable.Credential) error {
+	if profile.GovernanceVC == "" {
+		return errors.New("governance vc not configured for the profile")
+	}
+
+	governanceVC, err := o.getGovernanceVC(ctx, profile.GovernanceVC)
+	if err != nil {
+		return err
+	}
+
+	if err = validateGovernanceVC(profile, governanceVC, time.Now()); err != nil {
+		return err
+	}
+
+	trustedIssuers, err := getTrustedIssuers(governanceVC)
+	if err != nil {
+		return err
+	}
+
+	for _, trustedIssuer := range trustedIssuers {
+		if trustedIssuer.ID == vc.Issuer.ID && trustedIssuer.trusts(vc.Types) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("issuer %s is not trusted by the governance vc %s", vc.Issuer.ID, governanceVC.ID)
+}
+
+// getGovernanceVC returns the verified governance credential at the given URL, fetching it only when it is not
+// cached or its cache entry is stale
+func (o *Operation) getGovernanceVC(ctx context.Context, url string) (*verifiable.Credential, error) {
+	now := time.Now()
+
+	o.governanceMutex.Lock()
+	cached, ok := o.governanceVCs[url]
+	o.governanceMutex.Unlock()
+
+	if ok && now.Before(cached.expiresAt) {
+		return cached.vc, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.sendHTTPRequest(req, http.StatusOK, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the governance vc : %w", err)
+	}
+
+	governanceVC, err := o.parseAndVerifyVC(ctx, resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the governance vc : %w", err)
+	}
+
+	if len(governanceVC.Proofs) == 0 {
+		return nil, errors.New("governance vc doesn't contain proof")
+	}
+
+	expiresAt := now.Add(governanceVCCacheTTL)
+	if governanceVC.Expired != nil && governanceVC.Expired.Time.Before(expiresAt) {
+		expiresAt = governanceVC.Expired.Time
+	}
+
+	o.governanceMutex.Lock()
+	o.governanceVCs[url] = &cachedGovernanceVC{vc: governanceVC, expiresAt: expiresAt}
+	o.governanceMutex.Unlock()
+
+	return governanceVC, nil
+}
+
+// validateGovernanceVC checks the governance credential is issued by the governance authority of the profile
+// and has not expired
+func validateGovernanceVC(profile *verifier.ProfileData, governanceVC *verifiable.Credential, now time.Time) error {
+	if governanceVC.Issuer.ID != profile.GovernanceAuthority {
+		return fmt.Errorf("governance vc issuer %s is not the governance authority %s of the profile",
+			governanceVC.Issuer.ID, profile.GovernanceAuthority)
+	}
+
+	if governanceVC.Expired != nil && !now.Before(governanceVC.Expired.Time) {
+		return fmt.Errorf("governance vc %s expired at %s", governanceVC.ID,
+			governanceVC.Expired.Time.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func (o *Operation) parseAndVerifyVCStrictMode(ctx context.Context, vcBytes []byte) (*verifiable.Credential, error) {
+	vc, err := verifiable.ParseCredential(vcBytes, o.credentialOpts(ctx, verifiable.WithStrictValidation())...)
 
 	if err != nil {
 		return nil, err
@@ -510,12 +1030,16 @@ func (o *Operation) parseAndVerifyVCStrictMode(vcBytes []byte) (*verifiable.Cred
 }
 
 func (o *Operation) parseAndVerifyVP(vpBytes []byte) (*verifiable.Presentation, error) {
-	vp, err := verifiable.ParsePresentation(
-		vpBytes,
-		verifiable.WithPresPublicKeyFetcher(
-			verifiable.NewDIDKeyResolver(o.vdri).PublicKeyFetcher(),
-		),
-	)
+	vpOpts := []verifiable.PresentationOpt{
+		verifiable.WithPresPublicKeyFetcher(o.publicKeyFetcher(o.vdri)),
+		verifiable.WithPresEmbeddedSignatureSuites(crypto.VerifierSuites()...),
+	}
+
+	if o.documentLoader != nil {
+		vpOpts = append(vpOpts, verifiable.WithPresJSONLDDocumentLoader(o.documentLoader))
+	}
+
+	vp, err := verifiable.ParsePresentation(vpBytes, vpOpts...)
 
 	if err != nil {
 		return nil, err
@@ -529,7 +1053,7 @@ func (o *Operation) parseAndVerifyVP(vpBytes []byte) (*verifiable.Presentation,
 			return nil, err
 		}
 		// verify if the credential in vp is valid
-		err = o.validateCredentialProof(vcBytes, nil, true)
+		err = o.validateCredentialProof(context.Background(), vcBytes, nil, true)
 		if err != nil {
 			return nil, err
 		}
@@ -538,13 +1062,8 @@ func (o *Operation) parseAndVerifyVP(vpBytes []byte) (*verifiable.Presentation,
 	return vp, nil
 }
 
-func (o *Operation) parseAndVerifyVC(vcBytes []byte) (*verifiable.Credential, error) {
-	vc, err := verifiable.ParseCredential(
-		vcBytes,
-		verifiable.WithPublicKeyFetcher(
-			verifiable.NewDIDKeyResolver(o.vdri).PublicKeyFetcher(),
-		),
-	)
+func (o *Operation) parseAndVerifyVC(ctx context.Context, vcBytes []byte) (*verifiable.Credential, error) {
+	vc, err := verifiable.ParseCredential(vcBytes, o.credentialOpts(ctx)...)
 
 	if err != nil {
 		return nil, err
@@ -553,6 +1072,36 @@ func (o *Operation) parseAndVerifyVC(vcBytes []byte) (*verifiable.Credential, er
 	return vc, nil
 }
 
+// credentialOpts returns the options verifying the proofs of the credentials with the keys of their issuers and the
+// registered signature suites, and loading their contexts with the configured loader
+func (o *Operation) credentialOpts(ctx context.Context, opts ...verifiable.CredentialOpt) []verifiable.CredentialOpt {
+	opts = append(opts,
+		verifiable.WithPublicKeyFetcher(o.publicKeyFetcher(o.resolver(ctx))),
+		verifiable.WithEmbeddedSignatureSuites(crypto.VerifierSuites()...))
+
+	if o.documentLoader != nil {
+		opts = append(opts, verifiable.WithJSONLDDocumentLoader(o.documentLoader))
+	}
+
+	return opts
+}
+
+// publicKeyFetcher returns the fetcher of the keys of the issuers, the DIDs being resolved with the registry and the
+// keys of the other issuers fetched by the configured fetchers
+func (o *Operation) publicKeyFetcher(registry vdriapi.Registry) verifiable.PublicKeyFetcher {
+	return keyfetcher.Chain(append([]keyfetcher.Fetcher{keyfetcher.NewDIDFetcher(registry)}, o.keyFetchers...)...)
+}
+
+// credentialBytes returns the credential of a request, the JWT of a credential sent as a JSON string
+func credentialBytes(credential json.RawMessage) []byte {
+	var jwtVC string
+	if err := json.Unmarshal(credential, &jwtVC); err == nil {
+		return []byte(jwtVC)
+	}
+
+	return credential
+}
+
 func (o *Operation) sendHTTPRequest(req *http.Request, status int, token string) ([]byte, error) {
 	if token != "" {
 		req.Header.Add("Authorization", "Bearer "+token)
@@ -582,6 +1131,35 @@ func (o *Operation) sendHTTPRequest(req *http.Request, status int, token string)
 	return body, nil
 }
 
+// getTrustedIssuers returns the trusted issuers listed in the subject of the governance credential
+func getTrustedIssuers(governanceVC *verifiable.Credential) ([]TrustedIssuer, error) {
+	subjectBytes, err := json.Marshal(governanceVC.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal governance vc subject: %w", err)
+	}
+
+	var subjects []TrustedIssuerList
+
+	// the subject is either a single object or a list of objects
+	if err := json.Unmarshal(subjectBytes, &subjects); err != nil {
+		subject := TrustedIssuerList{}
+
+		if err := json.Unmarshal(subjectBytes, &subject); err != nil {
+			return nil, fmt.Errorf("invalid governance vc subject: %w", err)
+		}
+
+		subjects = []TrustedIssuerList{subject}
+	}
+
+	var trustedIssuers []TrustedIssuer
+
+	for _, subject := range subjects {
+		trustedIssuers = append(trustedIssuers, subject.TrustedIssuers...)
+	}
+
+	return trustedIssuers, nil
+}
+
 func getCredentialChecks(profile *verifier.ProfileData, opts *CredentialsVerificationOptions) []string {
 	switch {
 	case opts != nil && len(opts.Checks) != 0:
@@ -593,15 +1171,23 @@ func getCredentialChecks(profile *verifier.ProfileData, opts *CredentialsVerific
 	return []string{proofCheck}
 }
 
+// getPresentationChecks returns the checks of the request, else of the profile. The proof check, which consumes
+// the challenge, always runs for a profile requiring challenges.
 func getPresentationChecks(profile *verifier.ProfileData, opts *VerifyPresentationOptions) []string {
+	checks := []string{proofCheck}
+
 	switch {
 	case opts != nil && len(opts.Checks) != 0:
-		return opts.Checks
+		checks = opts.Checks
 	case len(profile.PresentationChecks) != 0:
-		return profile.PresentationChecks
+		checks = profile.PresentationChecks
 	}
 
-	return []string{proofCheck}
+	if profile.RequireChallenge && !contains(checks, proofCheck) {
+		checks = append([]string{proofCheck}, checks...)
+	}
+
+	return checks
 }
 
 func validateProofData(proof verifiable.Proof, key, expectedValue string) error {
@@ -619,6 +1205,49 @@ func validateProofData(proof verifiable.Proof, key, expectedValue string) error
 	return nil
 }
 
+// validateProofOptions validates the proof against the expected purpose and maximum age, if requested
+func validateProofOptions(proof verifiable.Proof, expectedPurpose string, maxAge int64) error {
+	if expectedPurpose != "" {
+		if err := validateProofData(proof, proofPurpose, expectedPurpose); err != nil {
+			return err
+		}
+	}
+
+	if maxAge > 0 {
+		if err := validateProofAge(proof, time.Duration(maxAge)*time.Second); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateProofAge validates the creation time of the proof is within the max age (both ways, to allow clock skew)
+func validateProofAge(proof verifiable.Proof, maxAge time.Duration) error {
+	createdVal, ok := proof[created]
+	if !ok {
+		return errors.New("proof doesn't have created")
+	}
+
+	createdStr, ok := createdVal.(string)
+	if !ok {
+		return errors.New("proof created is not a string")
+	}
+
+	createdTime, err := time.Parse(time.RFC3339, createdStr)
+	if err != nil {
+		return fmt.Errorf("invalid created in the proof : %w", err)
+	}
+
+	age := time.Since(createdTime)
+	if age > maxAge || age < -maxAge {
+		return fmt.Errorf("invalid created in the proof : %s is not within %s of the current time",
+			createdStr, maxAge)
+	}
+
+	return nil
+}
+
 func validateProofPurpose(proof verifiable.Proof, verificationMethod string, didDoc *did.Doc) error {
 	purposeVal, ok := proof[proofPurpose]
 	if !ok {
@@ -661,7 +1290,15 @@ func getDIDDocFromProof(verificationMethod string, vdri vdriapi.Registry) (*did.
 	return didDoc, nil
 }
 
-func validateProfileRequest(pr *verifier.ProfileData) error { // nolint: gocyclo
+func validateProfileRequest(pr *verifier.ProfileData, policyEngine *policy.Engine) error { // nolint: gocyclo
+	if err := validatePolicy(pr, policyEngine); err != nil {
+		return err
+	}
+
+	if err := validateReceiptKey(pr); err != nil {
+		return err
+	}
+
 	switch {
 	case pr.ID == "":
 		return errors.New("missing profile id")
@@ -670,7 +1307,15 @@ func validateProfileRequest(pr *verifier.ProfileData) error { // nolint: gocyclo
 	case len(pr.CredentialChecks) != 0:
 		for _, val := range pr.CredentialChecks {
 			switch val {
-			case proofCheck, statusCheck:
+			case proofCheck, statusCheck, policyCheck:
+			case governanceCheck:
+				if pr.GovernanceVC == "" {
+					return errors.New("governance check requires a governance vc")
+				}
+
+				if pr.GovernanceAuthority == "" {
+					return errors.New("governance check requires a governance authority")
+				}
 			default:
 				return fmt.Errorf("invalid credential check option - %s", val)
 			}
@@ -678,7 +1323,7 @@ func validateProfileRequest(pr *verifier.ProfileData) error { // nolint: gocyclo
 	case len(pr.PresentationChecks) != 0:
 		for _, val := range pr.PresentationChecks {
 			switch val {
-			case proofCheck:
+			case proofCheck, policyCheck:
 			default:
 				return fmt.Errorf("invalid presentation check option - %s", val)
 			}
@@ -687,3 +1332,29 @@ func validateProfileRequest(pr *verifier.ProfileData) error { // nolint: gocyclo
 
 	return nil
 }
+
+// validatePolicy checks the rules of the profile policy can be evaluated, and the policy check has rules to evaluate
+func validatePolicy(pr *verifier.ProfileData, policyEngine *policy.Engine) error {
+	for i := range pr.Policy {
+		if err := policyEngine.ValidateRule(&pr.Policy[i]); err != nil {
+			return fmt.Errorf("invalid policy rule %d: %w", i, err)
+		}
+	}
+
+	policyChecked := contains(pr.CredentialChecks, policyCheck) || contains(pr.PresentationChecks, policyCheck)
+	if len(pr.Policy) == 0 && policyChecked {
+		return errors.New("policy check requires a policy")
+	}
+
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -8,8 +8,11 @@ package operation
 
 import (
 	"bytes"
+	"context"
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,6 +28,9 @@ import (
 	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/signature/suite/ed25519signature2018"
+	sigverifier "github.com/hyperledger/aries-framework-go/pkg/doc/signature/verifier"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util/signature"
 	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
 	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
 	"github.com/stretchr/testify/require"
@@ -32,8 +38,14 @@ import (
 	mockstorage "github.com/trustbloc/edge-core/pkg/storage/mockstore"
 
 	vccrypto "github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/keyfetcher"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
 	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/redaction"
 	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+	"github.com/trustbloc/edge-service/pkg/httpclient"
+	"github.com/trustbloc/edge-service/pkg/ratelimit"
+	"github.com/trustbloc/edge-service/pkg/ratelimit/memlimiter"
 )
 
 const (
@@ -50,6 +62,29 @@ func Test_New(t *testing.T) {
 		require.NotNil(t, controller)
 	})
 
+	t.Run("test HTTP clients", func(t *testing.T) {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			_, errWrite := rw.Write([]byte(`{"id":"list1"}`))
+			require.NoError(t, errWrite)
+		}))
+		defer srv.Close()
+
+		rootCAs := x509.NewCertPool()
+		rootCAs.AddCert(srv.Certificate())
+
+		controller, err := New(&Config{
+			StoreProvider: memstore.NewProvider(),
+			VDRI:          &vdrimock.MockVDRIRegistry{},
+			HTTPClients:   httpclient.New(httpclient.WithTLSConfig(&tls.Config{RootCAs: rootCAs})),
+		})
+		require.NoError(t, err)
+		require.NotNil(t, controller.documentLoader)
+
+		resp, _, err := controller.checkVCStatus(context.Background(), srv.URL, "vc1")
+		require.NoError(t, err)
+		require.True(t, resp.Verified)
+	})
+
 	t.Run("test failure", func(t *testing.T) {
 		controller, err := New(&Config{
 			StoreProvider: &mockstorage.Provider{ErrCreateStore: errors.New("error creating the store")},
@@ -59,6 +94,16 @@ func Test_New(t *testing.T) {
 		require.Contains(t, err.Error(), "error creating the store")
 		require.Nil(t, controller)
 	})
+	t.Run("test challenge store failure", func(t *testing.T) {
+		controller, err := New(&Config{
+			StoreProvider: &mockstorage.Provider{Store: &mockstorage.MockStore{Store: map[string][]byte{}},
+				FailNameSpace: "challenge"},
+			VDRI: &vdrimock.MockVDRIRegistry{},
+		})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to instantiate challenge store")
+		require.Nil(t, controller)
+	})
 }
 
 func TestCreateProfile(t *testing.T) {
@@ -141,6 +186,64 @@ func TestCreateProfile(t *testing.T) {
 		require.Contains(t, rr.Body.String(), "invalid credential check option - invalidCheck")
 	})
 
+	t.Run("create profile - governance check without governance vc", func(t *testing.T) {
+		vReqBytes, err := json.Marshal(&verifier.ProfileData{
+			ID:               "test1",
+			Name:             "test 1",
+			CredentialChecks: []string{proofCheck, governanceCheck},
+		})
+		require.NoError(t, err)
+
+		rr := serveHTTP(t, handler.Handle(), http.MethodPost, endpoint, vReqBytes)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "governance check requires a governance vc")
+	})
+
+	t.Run("create profile - governance check without governance authority", func(t *testing.T) {
+		vReqBytes, err := json.Marshal(&verifier.ProfileData{
+			ID:               "test1",
+			Name:             "test 1",
+			CredentialChecks: []string{proofCheck, governanceCheck},
+			GovernanceVC:     "https://example.com/governance/.well-known/governance/trusted-issuers.json",
+		})
+		require.NoError(t, err)
+
+		rr := serveHTTP(t, handler.Handle(), http.MethodPost, endpoint, vReqBytes)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "governance check requires a governance authority")
+	})
+
+	t.Run("create profile - policy check without policy", func(t *testing.T) {
+		vReqBytes, err := json.Marshal(&verifier.ProfileData{
+			ID:               "test1",
+			Name:             "test 1",
+			CredentialChecks: []string{proofCheck, policyCheck},
+		})
+		require.NoError(t, err)
+
+		rr := serveHTTP(t, handler.Handle(), http.MethodPost, endpoint, vReqBytes)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "policy check requires a policy")
+	})
+
+	t.Run("create profile - invalid policy rule", func(t *testing.T) {
+		vReqBytes, err := json.Marshal(&verifier.ProfileData{
+			ID:               "test1",
+			Name:             "test 1",
+			CredentialChecks: []string{proofCheck, policyCheck},
+			Policy:           []policy.Rule{{Type: policy.NotExpired}, {Type: policy.MaxAge, Values: []string{"1y"}}},
+		})
+		require.NoError(t, err)
+
+		rr := serveHTTP(t, handler.Handle(), http.MethodPost, endpoint, vReqBytes)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid policy rule 1: rule maxAge: invalid duration")
+	})
+
 	t.Run("create profile - invalid presentation checks", func(t *testing.T) {
 		vReq := &verifier.ProfileData{
 			ID:                 "test1",
@@ -349,6 +452,25 @@ func TestVerifyCredential(t *testing.T) {
 		require.Contains(t, rr.Body.String(), "invalid verifier profile")
 	})
 
+	t.Run("credential verification - rate limit exceeded", func(t *testing.T) {
+		ops, err := New(&Config{
+			VDRI:          &vdrimock.MockVDRIRegistry{},
+			StoreProvider: memstore.NewProvider(),
+			RateLimit:     &ratelimit.Config{Limiter: memlimiter.New(1, 1)},
+		})
+		require.NoError(t, err)
+
+		handler := getHandler(t, ops, credentialsVerificationEndpoint, http.MethodPost)
+
+		rr := serveHTTPMux(t, handler, endpoint, nil, urlVars)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = serveHTTPMux(t, handler, endpoint, nil, urlVars)
+		require.Equal(t, http.StatusTooManyRequests, rr.Code)
+		require.Equal(t, "1", rr.Header().Get("Retry-After"))
+		require.Contains(t, rr.Body.String(), "rate limit exceeded")
+	})
+
 	t.Run("credential verification - request doesn't contain checks", func(t *testing.T) {
 		req := &CredentialsVerificationRequest{
 			Credential: []byte(prCardVC),
@@ -381,7 +503,7 @@ func TestVerifyCredential(t *testing.T) {
 		rr := serveHTTPMux(t, verificationsHandler, endpoint, reqBytes, urlVars)
 
 		require.Equal(t, http.StatusBadRequest, rr.Code)
-		require.Contains(t, rr.Body.String(), "Invalid request: build new credential")
+		require.Contains(t, rr.Body.String(), `"details":"build new credential`)
 	})
 
 	t.Run("credential verification - proof check failure", func(t *testing.T) {
@@ -984,6 +1106,58 @@ func TestVerifyPresentation(t *testing.T) {
 		require.Contains(t, rr.Body.String(), "invalid domain in the proof")
 	})
 
+	t.Run("presentation verification - invalid proof purpose and proof age", func(t *testing.T) {
+		pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		didID := "did:test:xyz"
+
+		didDoc := createDIDDoc(didID, pubKey)
+		verificationMethod := didDoc.PublicKey[0].ID
+
+		op, err := New(&Config{
+			VDRI:          &vdrimock.MockVDRIRegistry{ResolveValue: didDoc},
+			StoreProvider: memstore.NewProvider(),
+		})
+		require.NoError(t, err)
+
+		err = op.profileStore.SaveProfile(vReq)
+		require.NoError(t, err)
+
+		handler := getHandler(t, op, presentationsVerificationEndpoint, http.MethodPost)
+
+		vReq := &VerifyPresentationRequest{
+			Presentation: getSignedVP(t, privKey, prCardVC, didID, verificationMethod,
+				didID, verificationMethod, domain, challenge),
+			Opts: &VerifyPresentationOptions{
+				Checks:       []string{proofCheck},
+				Domain:       domain,
+				Challenge:    challenge,
+				ProofPurpose: assertionMethod,
+			},
+		}
+
+		vReqBytes, err := json.Marshal(vReq)
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, endpoint, vReqBytes, urlVars)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid proofPurpose in the proof")
+
+		// the proof was created in 2018
+		vReq.Opts.ProofPurpose = vccrypto.Authentication
+		vReq.Opts.MaxProofAge = 300
+
+		vReqBytes, err = json.Marshal(vReq)
+		require.NoError(t, err)
+
+		rr = serveHTTPMux(t, handler, endpoint, vReqBytes, urlVars)
+
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "invalid created in the proof")
+	})
+
 	t.Run("presentation verification - invalid vp proof purpose", func(t *testing.T) {
 		pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
 		require.NoError(t, err)
@@ -1133,6 +1307,54 @@ func TestValidateProof(t *testing.T) {
 	require.Contains(t, err.Error(), "invalid challenge in the proof")
 }
 
+func TestValidateProofOptions(t *testing.T) {
+	proof := map[string]interface{}{
+		proofPurpose: assertionMethod,
+		created:      time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// success - nothing requested
+	require.NoError(t, validateProofOptions(proof, "", 0))
+
+	// success
+	require.NoError(t, validateProofOptions(proof, assertionMethod, 60))
+
+	// fail - purpose
+	err := validateProofOptions(proof, vccrypto.Authentication, 0)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid proofPurpose in the proof")
+
+	// fail - too old
+	proof[created] = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	err = validateProofOptions(proof, "", 60)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid created in the proof")
+
+	// fail - too far in the future
+	proof[created] = time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	err = validateProofOptions(proof, "", 60)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid created in the proof")
+
+	// fail - invalid created
+	proof[created] = "invalid-time"
+	err = validateProofOptions(proof, "", 60)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid created in the proof")
+
+	// fail - not a string
+	proof[created] = 123
+	err = validateProofOptions(proof, "", 60)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "proof created is not a string")
+
+	// fail - missing
+	delete(proof, created)
+	err = validateProofOptions(proof, "", 60)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "proof doesn't have created")
+}
+
 func TestValidateProofPurpose(t *testing.T) {
 	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
 	require.NoError(t, err)
@@ -1233,6 +1455,212 @@ func TestGetDIDDocFromProof(t *testing.T) {
 	require.Nil(t, doc)
 }
 
+func TestCheckGovernance(t *testing.T) {
+	op, err := New(&Config{
+		VDRI:          &vdrimock.MockVDRIRegistry{},
+		StoreProvider: memstore.NewProvider(),
+	})
+	require.NoError(t, err)
+
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(prCardVC))
+	require.NoError(t, err)
+
+	profile := &verifier.ProfileData{ID: "test", Name: "test verifier",
+		GovernanceVC:        "https://example.com/governance/.well-known/governance/trusted-issuers.json",
+		GovernanceAuthority: "did:example:governance"}
+
+	t.Run("governance check - not configured", func(t *testing.T) {
+		require.NoError(t, op.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test verifier"}))
+
+		reqBytes, err := json.Marshal(&CredentialsVerificationRequest{Credential: []byte(prCardVC),
+			Opts: &CredentialsVerificationOptions{Checks: []string{governanceCheck}}})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, getHandler(t, op, credentialsVerificationEndpoint, http.MethodPost),
+			"/test/verifier/credentials", reqBytes, map[string]string{profileIDPathParam: "test"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		verificationResp := &CredentialsVerificationFailResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), verificationResp))
+		require.Equal(t, []CredentialsVerificationCheckResult{{Check: governanceCheck,
+			Error: "governance vc not configured for the profile"}}, verificationResp.Checks)
+	})
+
+	t.Run("governance check - fetch error", func(t *testing.T) {
+		op.httpClient = &mockHTTPClient{doErr: errors.New("connection refused")}
+
+		err := op.checkGovernance(context.Background(), profile, vc)
+		require.EqualError(t, err, "failed to fetch the governance vc : connection refused")
+
+		op.httpClient = &mockHTTPClient{doValue: &http.Response{StatusCode: http.StatusNotFound,
+			Body: ioutil.NopCloser(strings.NewReader("not found"))}}
+
+		err = op.checkGovernance(context.Background(), profile, vc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to fetch the governance vc")
+	})
+
+	t.Run("governance check - invalid governance vc", func(t *testing.T) {
+		op.httpClient = &mockHTTPClient{doValue: &http.Response{StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader("{}"))}}
+
+		err := op.checkGovernance(context.Background(), profile, vc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to verify the governance vc")
+	})
+
+	t.Run("governance check - governance vc without proof", func(t *testing.T) {
+		op.httpClient = &mockHTTPClient{doValue: &http.Response{StatusCode: http.StatusOK,
+			Body: ioutil.NopCloser(strings.NewReader(`{
+				"@context": ["https://www.w3.org/2018/credentials/v1"],
+				"type": "VerifiableCredential",
+				"credentialSubject": {"id": "https://example.com/governance/v1"},
+				"issuer": "did:example:governance",
+				"issuanceDate": "2020-06-01T00:00:00Z"
+			}`))}}
+
+		err := op.checkGovernance(context.Background(), profile, vc)
+		require.EqualError(t, err, "governance vc doesn't contain proof")
+	})
+
+	governanceVC, err := verifiable.ParseUnverifiedCredential([]byte(`{
+		"@context": ["https://www.w3.org/2018/credentials/v1"],
+		"id": "https://example.com/governance/v1",
+		"type": "VerifiableCredential",
+		"credentialSubject": {"trustedIssuers": [{"id": "did:example:other"}]},
+		"issuer": "did:example:governance",
+		"issuanceDate": "2020-06-01T00:00:00Z"
+	}`))
+	require.NoError(t, err)
+
+	t.Run("governance check - cached governance vc", func(t *testing.T) {
+		op.httpClient = &mockHTTPClient{doErr: errors.New("connection refused")}
+		op.governanceVCs[profile.GovernanceVC] = &cachedGovernanceVC{vc: governanceVC,
+			expiresAt: time.Now().Add(time.Minute)}
+
+		err := op.checkGovernance(context.Background(), profile, vc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is not trusted by the governance vc https://example.com/governance/v1")
+
+		op.governanceVCs[profile.GovernanceVC].expiresAt = time.Now().Add(-time.Minute)
+
+		err = op.checkGovernance(context.Background(), profile, vc)
+		require.EqualError(t, err, "failed to fetch the governance vc : connection refused")
+	})
+
+	t.Run("governance check - governance vc not issued by the governance authority", func(t *testing.T) {
+		err := validateGovernanceVC(&verifier.ProfileData{GovernanceAuthority: "did:example:authority"},
+			governanceVC, time.Now())
+		require.EqualError(t, err,
+			"governance vc issuer did:example:governance is not the governance authority did:example:authority "+
+				"of the profile")
+	})
+
+	t.Run("governance check - expired governance vc", func(t *testing.T) {
+		require.NoError(t, validateGovernanceVC(profile, governanceVC, time.Now()))
+
+		expired := *governanceVC
+		expired.Expired = util.NewTime(time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC))
+
+		require.NoError(t, validateGovernanceVC(profile, &expired, expired.Expired.Time.Add(-time.Second)))
+
+		err := validateGovernanceVC(profile, &expired, expired.Expired.Time)
+		require.EqualError(t, err, "governance vc https://example.com/governance/v1 expired at 2020-07-01T00:00:00Z")
+	})
+}
+
+func TestCheckPolicy(t *testing.T) {
+	op, err := New(&Config{
+		VDRI:          &vdrimock.MockVDRIRegistry{},
+		StoreProvider: memstore.NewProvider(),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test verifier",
+		CredentialChecks: []string{policyCheck}, PresentationChecks: []string{policyCheck},
+		Policy: []policy.Rule{
+			{Type: policy.NotExpired},
+			{Type: policy.DisallowedTypes, Values: []string{"UniversityDegreeCredential"}},
+			{Type: policy.ClaimValues, Values: []string{"lprCategory=C09"}},
+		}}))
+
+	t.Run("policy check - credential complies", func(t *testing.T) {
+		checks, result, err := op.VerifyCredential("test", &CredentialsVerificationRequest{
+			Credential: []byte(prCardVC)})
+		require.NoError(t, err)
+		require.Equal(t, []string{policyCheck}, checks)
+		require.Empty(t, result)
+	})
+
+	t.Run("policy check - credential violates the policy", func(t *testing.T) {
+		reqBytes, err := json.Marshal(&CredentialsVerificationRequest{Credential: []byte(validVCWithProof)})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, getHandler(t, op, credentialsVerificationEndpoint, http.MethodPost),
+			"/test/verifier/credentials", reqBytes, map[string]string{profileIDPathParam: "test"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+
+		verificationResp := &CredentialsVerificationFailResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), verificationResp))
+		require.Equal(t, []CredentialsVerificationCheckResult{{Check: policyCheck,
+			Error: "claimValues: subject claim lprCategory is not C09: <nil>",
+			Violations: []policy.Violation{{Rule: policy.ClaimValues,
+				Message: "subject claim lprCategory is not C09: <nil>"}}}}, verificationResp.Checks)
+	})
+
+	t.Run("policy check - presentation credential violates the policy", func(t *testing.T) {
+		checks, result, err := op.VerifyPresentation("test", &VerifyPresentationRequest{
+			Presentation: []byte(vpWithoutProof)})
+		require.NoError(t, err)
+		require.Equal(t, []string{policyCheck}, checks)
+		require.Equal(t, []VerifyPresentationCheckResult{{Check: policyCheck,
+			Error: "claimValues: credential http://example.edu/credentials/1872: subject claim lprCategory is not " +
+				"C09: <nil>",
+			Violations: []policy.Violation{{Rule: policy.ClaimValues,
+				Message: "credential http://example.edu/credentials/1872: subject claim lprCategory is not C09: <nil>"}},
+		}}, result)
+	})
+
+	t.Run("policy check - invalid presentation", func(t *testing.T) {
+		_, result, err := op.VerifyPresentation("test", &VerifyPresentationRequest{
+			Presentation: []byte(invalidVC)})
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		require.Contains(t, result[0].Error, "failed to parse the presentation")
+	})
+}
+
+func TestGetTrustedIssuers(t *testing.T) {
+	governanceVC, err := verifiable.ParseUnverifiedCredential([]byte(`{
+		"@context": ["https://www.w3.org/2018/credentials/v1"],
+		"type": ["VerifiableCredential", "TrustedIssuerListCredential"],
+		"credentialSubject": {
+			"id": "https://example.com/governance/v1",
+			"trustedIssuers": [
+				{"id": "did:example:issuer1", "credentialTypes": ["UniversityDegreeCredential"]},
+				{"id": "did:example:issuer2"}
+			]
+		},
+		"issuer": "did:example:governance",
+		"issuanceDate": "2020-06-01T00:00:00Z"
+	}`))
+	require.NoError(t, err)
+
+	trustedIssuers, err := getTrustedIssuers(governanceVC)
+	require.NoError(t, err)
+	require.Len(t, trustedIssuers, 2)
+
+	require.True(t, trustedIssuers[0].trusts([]string{"VerifiableCredential", "UniversityDegreeCredential"}))
+	require.False(t, trustedIssuers[0].trusts([]string{"VerifiableCredential", "PermanentResidentCard"}))
+	require.True(t, trustedIssuers[1].trusts([]string{"VerifiableCredential", "PermanentResidentCard"}))
+
+	governanceVC.Subject = "did:example:governance"
+
+	_, err = getTrustedIssuers(governanceVC)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid governance vc subject")
+}
+
 type mockHTTPClient struct {
 	doValue *http.Response
 	doErr   error
@@ -1537,3 +1965,127 @@ const (
 		}	
 	}`
 )
+
+func TestCheckDisclosures(t *testing.T) {
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(`{
+		"@context": ["https://www.w3.org/2018/credentials/v1"],
+		"type": ["VerifiableCredential"],
+		"issuer": "did:example:76e12ec712ebc6f1c221ebfeb1f",
+		"issuanceDate": "2010-01-01T19:23:24Z",
+		"credentialSubject": {"id": "did:example:ebfeb1f712ebc6f1c276e12ec21", "name": "Jayden Doe", "age": 62}
+	}`))
+	require.NoError(t, err)
+
+	claims, err := redaction.Commit(vc)
+	require.NoError(t, err)
+
+	vcBytes, err := vc.MarshalJSON()
+	require.NoError(t, err)
+
+	derived, err := redaction.Derive(vcBytes, claims, []string{"/credentialSubject/name"})
+	require.NoError(t, err)
+
+	require.NoError(t, checkDisclosures(vc, nil))
+	require.NoError(t, checkDisclosures(vc, &CredentialsVerificationOptions{}))
+	require.NoError(t, checkDisclosures(vc, &CredentialsVerificationOptions{Disclosures: derived.Disclosures}))
+
+	derived.Disclosures[1].Claim.Value = "John Doe"
+
+	err = checkDisclosures(vc, &CredentialsVerificationOptions{Disclosures: derived.Disclosures})
+	require.EqualError(t, err, "invalid disclosures : the claims don't match the claims digest of the credential")
+}
+
+func TestVerifyJWTCredential(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	issuer := "https://issuer.example.com"
+
+	op, err := New(&Config{
+		VDRI:          &vdrimock.MockVDRIRegistry{},
+		StoreProvider: memstore.NewProvider(),
+		KeyFetchers: []keyfetcher.Fetcher{&mockKeyFetcher{issuer: issuer,
+			key: &sigverifier.PublicKey{Type: "JwsVerificationKey2020", Value: pubKey}}},
+	})
+	require.NoError(t, err)
+
+	err = op.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test verifier",
+		CredentialChecks: []string{proofCheck}})
+	require.NoError(t, err)
+
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(`{
+		"@context": ["https://www.w3.org/2018/credentials/v1"],
+		"type": ["VerifiableCredential"],
+		"issuer": "` + issuer + `",
+		"issuanceDate": "2010-01-01T19:23:24Z",
+		"credentialSubject": {"id": "did:example:ebfeb1f712ebc6f1c276e12ec21"}
+	}`))
+	require.NoError(t, err)
+
+	claims, err := vc.JWTClaims(false)
+	require.NoError(t, err)
+
+	jwtVC, err := claims.MarshalJWS(verifiable.EdDSA, signature.GetEd25519Signer(privKey, pubKey), "key-1")
+	require.NoError(t, err)
+
+	handler := getHandler(t, op, credentialsVerificationEndpoint, http.MethodPost)
+
+	verify := func(t *testing.T, credential string, opts *CredentialsVerificationOptions) *httptest.ResponseRecorder {
+		credentialBytes, err := json.Marshal(credential)
+		require.NoError(t, err)
+
+		reqBytes, err := json.Marshal(&CredentialsVerificationRequest{Credential: credentialBytes, Opts: opts})
+		require.NoError(t, err)
+
+		return serveHTTPMux(t, handler, "/test/verifier/credentials", reqBytes,
+			map[string]string{profileIDPathParam: "test"})
+	}
+
+	t.Run("test credential of an issuer without DID", func(t *testing.T) {
+		rr := verify(t, jwtVC, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	})
+
+	t.Run("test credential signed with another key", func(t *testing.T) {
+		_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		otherVC, err := claims.MarshalJWS(verifiable.EdDSA, signature.GetEd25519Signer(otherKey, pubKey), "key-1")
+		require.NoError(t, err)
+
+		rr := verify(t, otherVC, nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "verifiable credential proof validation error")
+	})
+
+	t.Run("test challenge of a JWT credential", func(t *testing.T) {
+		rr := verify(t, jwtVC, &CredentialsVerificationOptions{Challenge: uuid.New().String()})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(),
+			"the challenge and domain of the proof are not supported by JWT credentials")
+	})
+
+	t.Run("test issuer without key fetcher", func(t *testing.T) {
+		claims.Issuer = "https://other.example.com"
+
+		otherVC, err := claims.MarshalJWS(verifiable.EdDSA, signature.GetEd25519Signer(privKey, pubKey), "key-1")
+		require.NoError(t, err)
+
+		rr := verify(t, otherVC, nil)
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "no key fetcher for issuer https://other.example.com")
+	})
+}
+
+type mockKeyFetcher struct {
+	issuer string
+	key    *sigverifier.PublicKey
+}
+
+func (f *mockKeyFetcher) Fetch(issuerID, _ string) (*sigverifier.PublicKey, error) {
+	if issuerID != f.issuer {
+		return nil, keyfetcher.ErrNotSupported
+	}
+
+	return f.key, nil
+}
@@ -0,0 +1,500 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/anoncreds"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/presentationrequest"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const (
+	presentationRequestIDPathParam = "requestID"
+	presentationRequestsEndpoint   = "/" + "{" + profileIDPathParam + "}" + verifierBasePath + "/presentationRequests"
+	presentationRequestEndpoint    = presentationRequestsEndpoint + "/{" + presentationRequestIDPathParam + "}"
+
+	interactServiceType = "UnmediatedHttpPresentationService2021"
+)
+
+type presentationRequestStore interface {
+	Create(request *presentationrequest.Request, ttl time.Duration) (*presentationrequest.Request, error)
+	Get(profile, id string) (*presentationrequest.Request, error)
+	Respond(profile, id string, presentation json.RawMessage,
+		verify func(request *presentationrequest.Request) (string, []string, error)) (*presentationrequest.Request,
+		error)
+}
+
+// CreateRequest swagger:route POST /{id}/verifier/presentationRequests verifier createPresentationRequestReq
+//
+// Creates a presentation request of the profile, a verifiable presentation request (vpr) or a presentation
+// definition (pe), with a new challenge. The request is shared with the holder as its request URI, e.g. in a QR code:
+// the holder gets the request from its request URI, then responds to it once with its presentation, before it
+// expires.
+//
+// Responses:
+//    default: genericError
+//        201: presentationRequestRes
+func (o *Operation) createPresentationRequestHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getVerifierProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	request := &CreatePresentationRequest{}
+
+	if err = commhttp.DecodeJSON(req, request); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	if request.Format == "" {
+		request.Format = presentationrequest.FormatVPR
+	}
+
+	if err = validateCreatePresentationRequest(request, o.maxRequestTTL); err != nil {
+		commhttp.WriteError(rw, commhttp.NewValidationError(err))
+
+		return
+	}
+
+	proofRequest, err := anonCredsProofRequest(request)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.InternalError, err.Error())
+
+		return
+	}
+
+	created, err := o.presentationRequests.Create(&presentationrequest.Request{Profile: profile.ID,
+		Format: request.Format, Query: request.Query, PresentationDefinition: request.PresentationDefinition,
+		AnonCredsProofRequest: proofRequest, AnonCredsCredentialDefinitions: request.AnonCredsCredentialDefinitions,
+		Domain: request.Domain}, time.Duration(request.ExpiresIn)*time.Second)
+	if err != nil {
+		commhttp.WriteErrorResponse(rw, http.StatusInternalServerError, commhttp.StorageError, err.Error())
+
+		return
+	}
+
+	rw.WriteHeader(http.StatusCreated)
+	commhttp.WriteResponse(rw, o.presentationRequestResponse(created))
+}
+
+// GetRequest swagger:route GET /{id}/verifier/presentationRequests/{requestID} verifier getPresentationRequestReq
+//
+// Returns the presentation request, with the presentation of the holder and the errors of its verification once
+// responded to.
+//
+// Responses:
+//    default: genericError
+//        200: presentationRequestRes
+func (o *Operation) getPresentationRequestHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getVerifierProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	id := mux.Vars(req)[presentationRequestIDPathParam]
+
+	request, err := o.presentationRequests.Get(profile.ID, id)
+	if err != nil {
+		commhttp.WriteError(rw, presentationRequestError(id, err))
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, o.presentationRequestResponse(request))
+}
+
+// Interact swagger:route POST /{id}/verifier/presentationRequests/{requestID} verifier interactRequestReq
+//
+// Interacts with the presentation request at its request URI. Without presentation, returns the request in its
+// format, to the holder. With the presentation of the holder, signed with the challenge and the domain of the
+// request, responds to the request: the presentation is verified with the checks of the profile, and must answer
+// the queries or the presentation definition of the request. A request of the pe format can be responded to with an
+// AnonCreds presentation instead, received over DIDComm by the agent of the verifier: its proof is verified with the
+// credential definitions of the request, and it must answer the AnonCreds proof request of the request. A request is
+// responded to once, the response failing with status 400 if the presentation isn't verified.
+//
+// Responses:
+//    default: genericError
+//        200: interactPresentationRequestRes
+func (o *Operation) interactPresentationRequestHandler(rw http.ResponseWriter, req *http.Request) {
+	profile, err := o.getVerifierProfile(mux.Vars(req)[profileIDPathParam])
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	interaction := &InteractPresentationRequest{}
+
+	if err = commhttp.DecodeJSON(req, interaction); err != nil && !commhttp.IsEmptyBody(err) {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	id := mux.Vars(req)[presentationRequestIDPathParam]
+
+	if len(interaction.VerifiablePresentation) > 0 && len(interaction.AnonCredsPresentation) > 0 {
+		commhttp.WriteErrorResponse(rw, http.StatusBadRequest, commhttp.InvalidRequest,
+			"only one of verifiablePresentation and anonCredsPresentation must be set")
+
+		return
+	}
+
+	if len(interaction.VerifiablePresentation) == 0 && len(interaction.AnonCredsPresentation) == 0 {
+		request, getErr := o.presentationRequests.Get(profile.ID, id)
+		if getErr == nil && request.State != presentationrequest.StatePending {
+			getErr = presentationrequest.ErrClosed
+		}
+
+		if getErr != nil {
+			commhttp.WriteError(rw, presentationRequestError(id, getErr))
+
+			return
+		}
+
+		commhttp.WriteResponse(rw, o.interactResponse(request))
+
+		return
+	}
+
+	request, err := o.respondPresentationRequest(req.Context(), profile, id, interaction)
+	if err != nil {
+		commhttp.WriteError(rw, presentationRequestError(id, err))
+
+		return
+	}
+
+	if request.State != presentationrequest.StateVerified {
+		rw.WriteHeader(http.StatusBadRequest)
+	}
+
+	commhttp.WriteResponse(rw, &InteractPresentationRequestResponse{State: request.State,
+		Errors: request.Response.Errors})
+}
+
+// respondPresentationRequest verifies the presentation responding to the request with the checks of the profile,
+// the proof being checked with the challenge and the domain of the request, and checks it answers the request
+func (o *Operation) respondPresentationRequest(ctx context.Context, profile *verifier.ProfileData, id string,
+	interaction *InteractPresentationRequest) (*presentationrequest.Request, error) {
+	if len(interaction.AnonCredsPresentation) > 0 {
+		return o.respondAnonCredsPresentation(profile, id, interaction.AnonCredsPresentation)
+	}
+
+	// the challenge of the request is consumed by the response, rather than a challenge of the profile
+	requestProfile := *profile
+	requestProfile.RequireChallenge = false
+
+	checks := getPresentationChecks(profile, nil)
+	if !contains(checks, proofCheck) {
+		checks = append([]string{proofCheck}, checks...)
+	}
+
+	vp := interaction.VerifiablePresentation
+
+	return o.presentationRequests.Respond(profile.ID, id, vp,
+		func(request *presentationrequest.Request) (string, []string, error) {
+			_, result := o.verifyPresentation(ctx, &requestProfile, &VerifyPresentationRequest{Presentation: vp,
+				Opts: &VerifyPresentationOptions{Challenge: request.Challenge, Domain: request.Domain, Checks: checks}})
+
+			var errs []string
+
+			for _, failed := range result {
+				errs = append(errs, fmt.Sprintf("%s: %s", failed.Check, failed.Error))
+			}
+
+			answer, err := presentationAnswer(vp, interaction.PresentationSubmission)
+			if err != nil {
+				return "", append(errs, err.Error()), nil
+			}
+
+			if err = request.Check(answer); err != nil {
+				errs = append(errs, fmt.Sprintf("the presentation doesn't answer the request: %s", err.Error()))
+			}
+
+			return answer.Holder, errs, nil
+		})
+}
+
+// respondAnonCredsPresentation verifies the AnonCreds presentation responding to the request with the credential
+// definitions of the request, and checks it answers the AnonCreds proof request of the request. The holders of the
+// AnonCreds presentations are anonymous.
+func (o *Operation) respondAnonCredsPresentation(profile *verifier.ProfileData, id string,
+	presentation json.RawMessage) (*presentationrequest.Request, error) {
+	return o.presentationRequests.Respond(profile.ID, id, presentation,
+		func(request *presentationrequest.Request) (string, []string, error) {
+			if err := verifyAnonCredsPresentation(request, presentation); err != nil {
+				return "", []string{err.Error()}, nil
+			}
+
+			return "", nil, nil
+		})
+}
+
+// verifyAnonCredsPresentation verifies the AnonCreds presentation, the DIDComm message or the presentation attached
+// to it, with the AnonCreds proof request and the credential definitions of the request
+func verifyAnonCredsPresentation(request *presentationrequest.Request, raw json.RawMessage) error {
+	if len(request.AnonCredsProofRequest) == 0 {
+		return errors.New("the request can't be answered with an AnonCreds presentation")
+	}
+
+	proofRequest, err := anoncreds.ParseProofRequest(request.AnonCredsProofRequest)
+	if err != nil {
+		return err
+	}
+
+	credDefs := make(map[string]*anoncreds.CredentialDefinition)
+
+	for _, rawCredDef := range request.AnonCredsCredentialDefinitions {
+		credDef, parseErr := anoncreds.ParseCredentialDefinition(rawCredDef)
+		if parseErr != nil {
+			return parseErr
+		}
+
+		credDefs[credDef.ID] = credDef
+	}
+
+	presentation, err := anoncreds.ParsePresentation(raw)
+	if err != nil {
+		return err
+	}
+
+	if err = anoncreds.Verify(proofRequest, presentation, credDefs); err != nil {
+		return fmt.Errorf("the AnonCreds presentation isn't verified: %w", err)
+	}
+
+	return nil
+}
+
+// presentationAnswer returns the answer of the presentation, the presentation submission being the submission of the
+// request or the presentation_submission of the presentation
+func presentationAnswer(vpBytes json.RawMessage,
+	submission *presentationrequest.Submission) (*presentationrequest.Answer, error) {
+	vp, err := verifiable.ParseUnverifiedPresentation(vpBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the presentation : %w", err)
+	}
+
+	answer := &presentationrequest.Answer{Holder: vp.Holder, Submission: submission}
+
+	if answer.Submission == nil {
+		// the presentation submission of a JSON-LD presentation, not a field of the parsed presentation
+		embedded := &struct {
+			Submission *presentationrequest.Submission `json:"presentation_submission"`
+		}{}
+
+		if json.Unmarshal(vpBytes, embedded) == nil {
+			answer.Submission = embedded.Submission
+		}
+	}
+
+	for _, cred := range vp.Credentials() {
+		vcBytes, err := json.Marshal(cred)
+		if err != nil {
+			return nil, err
+		}
+
+		vc, err := verifiable.ParseUnverifiedCredential(vcBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the presentation credential : %w", err)
+		}
+
+		answer.CredentialTypes = append(answer.CredentialTypes, vc.Types)
+	}
+
+	return answer, nil
+}
+
+// validateCreatePresentationRequest validates the request, the AnonCreds proof request of the pe format being
+// translated to its presentation definition. The request must expire within the max TTL.
+func validateCreatePresentationRequest(request *CreatePresentationRequest, maxTTL time.Duration) error {
+	validationErr := &commhttp.ValidationError{}
+
+	switch request.Format {
+	case presentationrequest.FormatVPR:
+		if len(request.PresentationDefinition) > 0 {
+			validationErr.Add("/presentationDefinition", "presentation definition only supported by the pe format")
+		}
+
+		if len(request.AnonCredsProofRequest) > 0 {
+			validationErr.Add("/anonCredsProofRequest", "AnonCreds proof request only supported by the pe format")
+		}
+
+		if len(request.AnonCredsCredentialDefinitions) > 0 {
+			validationErr.Add("/anonCredsCredentialDefinitions",
+				"AnonCreds credential definitions only supported by the pe format")
+		}
+
+		if len(request.Query) == 0 {
+			validationErr.Add("/query", "missing query")
+		} else if _, err := presentationrequest.ParseQuery(request.Query); err != nil {
+			validationErr.Add("/query", err.Error())
+		}
+	case presentationrequest.FormatPE:
+		validatePresentationDefinitionRequest(request, validationErr)
+	default:
+		validationErr.Add("/format", fmt.Sprintf("invalid format: %s", request.Format))
+	}
+
+	// compared in seconds, as a duration of the larger values would overflow
+	maxExpiresIn := int64(maxTTL / time.Second)
+
+	switch {
+	case request.ExpiresIn < 0:
+		validationErr.Add("/expiresIn", "expiresIn must be a positive number of seconds")
+	case int64(request.ExpiresIn) > maxExpiresIn:
+		validationErr.Add("/expiresIn", fmt.Sprintf("expiresIn must be at most %d seconds", maxExpiresIn))
+	}
+
+	return validationErr.ErrorOrNil()
+}
+
+// validatePresentationDefinitionRequest validates the request of the pe format, translating its AnonCreds proof
+// request to its presentation definition
+func validatePresentationDefinitionRequest(request *CreatePresentationRequest,
+	validationErr *commhttp.ValidationError) {
+	if len(request.Query) > 0 {
+		validationErr.Add("/query", "query only supported by the vpr format")
+	}
+
+	for i, credDef := range request.AnonCredsCredentialDefinitions {
+		if _, err := anoncreds.ParseCredentialDefinition(credDef); err != nil {
+			validationErr.Add(fmt.Sprintf("/anonCredsCredentialDefinitions/%d", i), err.Error())
+		}
+	}
+
+	if len(request.AnonCredsProofRequest) > 0 {
+		if len(request.PresentationDefinition) > 0 {
+			validationErr.Add("/anonCredsProofRequest",
+				"only one of presentationDefinition and anonCredsProofRequest must be set")
+
+			return
+		}
+
+		definition, err := presentationDefinition(request.AnonCredsProofRequest)
+		if err != nil {
+			validationErr.Add("/anonCredsProofRequest", err.Error())
+
+			return
+		}
+
+		request.PresentationDefinition = definition
+	}
+
+	if len(request.PresentationDefinition) == 0 {
+		validationErr.Add("/presentationDefinition", "missing presentation definition or AnonCreds proof request")
+	} else if _, err := presentationrequest.ParseDefinition(request.PresentationDefinition); err != nil {
+		validationErr.Add("/presentationDefinition", err.Error())
+	}
+}
+
+// presentationDefinition translates the AnonCreds proof request to its presentation definition
+func presentationDefinition(raw json.RawMessage) (json.RawMessage, error) {
+	proofRequest, err := anoncreds.ParseProofRequest(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	definition, err := anoncreds.ToPresentationDefinition(proofRequest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proof request: %w", err)
+	}
+
+	return json.Marshal(definition)
+}
+
+// anonCredsProofRequest returns the AnonCreds proof request of the request of the pe format: the proof request of
+// the verifier, else the presentation definition translated to a proof request with a new nonce, if it can be.
+func anonCredsProofRequest(request *CreatePresentationRequest) (json.RawMessage, error) {
+	if request.Format != presentationrequest.FormatPE || len(request.AnonCredsProofRequest) > 0 {
+		return request.AnonCredsProofRequest, nil
+	}
+
+	definition, err := anoncreds.ParsePresentationDefinition(request.PresentationDefinition)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := anoncreds.NewNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	proofRequest, err := anoncreds.ToProofRequest(definition, nonce)
+	if err != nil {
+		// the definition requests credentials which can't be AnonCreds credentials
+		return nil, nil
+	}
+
+	return json.Marshal(proofRequest)
+}
+
+// interactResponse returns the request in its format, with the request URI as the endpoint of the response
+func (o *Operation) interactResponse(request *presentationrequest.Request) *InteractPresentationRequestResponse {
+	requestURI := o.requestURI(request.Profile, request.ID)
+
+	if request.Format == presentationrequest.FormatPE {
+		return &InteractPresentationRequestResponse{PresentationDefinitionRequest: &PresentationDefinitionRequest{
+			PresentationDefinition: request.PresentationDefinition,
+			Challenge:              request.Challenge,
+			Domain:                 request.Domain,
+			ResponseURI:            requestURI,
+			AnonCredsProofRequest:  request.AnonCredsProofRequest,
+		}}
+	}
+
+	return &InteractPresentationRequestResponse{VerifiablePresentationRequest: &VerifiablePresentationRequest{
+		Query:     request.Query,
+		Challenge: request.Challenge,
+		Domain:    request.Domain,
+		Interact: &PresentationInteract{Service: []InteractService{{
+			Type:            interactServiceType,
+			ServiceEndpoint: requestURI,
+		}}},
+	}}
+}
+
+func (o *Operation) requestURI(profile, id string) string {
+	return o.hostURL + "/" + url.PathEscape(profile) + verifierBasePath + "/presentationRequests/" + url.PathEscape(id)
+}
+
+func (o *Operation) presentationRequestResponse(
+	request *presentationrequest.Request) *PresentationRequestResponse {
+	return &PresentationRequestResponse{Request: request, RequestURI: o.requestURI(request.Profile, request.ID)}
+}
+
+// presentationRequestError returns the error of the presentation request store as an operation error
+func presentationRequestError(id string, err error) error {
+	switch {
+	case errors.Is(err, presentationrequest.ErrNotFound):
+		return commhttp.NewError(http.StatusNotFound, commhttp.NotFound,
+			fmt.Sprintf("presentation request %s not found", id))
+	case errors.Is(err, presentationrequest.ErrClosed):
+		return commhttp.NewError(http.StatusConflict, commhttp.Conflict,
+			fmt.Sprintf("presentation request %s is responded to or expired", id))
+	}
+
+	return commhttp.NewError(http.StatusInternalServerError, commhttp.StorageError, err.Error())
+}
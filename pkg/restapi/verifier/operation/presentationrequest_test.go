@@ -0,0 +1,424 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/anoncreds"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/presentationrequest"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+)
+
+func TestPresentationRequests(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	didID := "did:test:EiBNfNRaz1Ll8BjVsbNv-fWc7K_KIoPuW8GFCh1_Tz_Iuw=="
+	didDoc := createDIDDoc(didID, pubKey)
+	verificationMethod := didDoc.PublicKey[0].ID
+
+	op, err := New(&Config{
+		VDRI:          &vdrimock.MockVDRIRegistry{ResolveValue: didDoc},
+		StoreProvider: memstore.NewProvider(),
+		HostURL:       "https://verifier.example.com",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test verifier",
+		CredentialChecks: []string{proofCheck}, PresentationChecks: []string{proofCheck}}))
+
+	createHandler := getHandler(t, op, presentationRequestsEndpoint, http.MethodPost)
+	getRequestHandler := getHandler(t, op, presentationRequestEndpoint, http.MethodGet)
+	interactHandler := getHandler(t, op, presentationRequestEndpoint, http.MethodPost)
+
+	create := func(t *testing.T, request *CreatePresentationRequest) *PresentationRequestResponse {
+		reqBytes, err := json.Marshal(request)
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, createHandler, "/test/verifier/presentationRequests", reqBytes,
+			map[string]string{profileIDPathParam: "test"})
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		created := &PresentationRequestResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), created))
+
+		return created
+	}
+
+	interact := func(t *testing.T, id string, reqBytes []byte) (int, *InteractPresentationRequestResponse) {
+		rr := serveHTTPMux(t, interactHandler, "/test/verifier/presentationRequests/"+id, reqBytes,
+			map[string]string{profileIDPathParam: "test", presentationRequestIDPathParam: id})
+
+		response := &InteractPresentationRequestResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), response), rr.Body.String())
+
+		return rr.Code, response
+	}
+
+	vprQuery := json.RawMessage(`[{"type":"QueryByExample","credentialQuery":{"example":{
+		"type":["VerifiableCredential","PermanentResidentCard"]}}}]`)
+
+	t.Run("create and get a vpr request", func(t *testing.T) {
+		created := create(t, &CreatePresentationRequest{Query: vprQuery, Domain: "example.com", ExpiresIn: 60})
+		require.Equal(t, presentationrequest.FormatVPR, created.Format)
+		require.Equal(t, presentationrequest.StatePending, created.State)
+		require.NotEmpty(t, created.Challenge)
+		require.Equal(t, "example.com", created.Domain)
+		require.Equal(t, "https://verifier.example.com/test/verifier/presentationRequests/"+created.ID,
+			created.RequestURI)
+		require.WithinDuration(t, time.Now().Add(time.Minute), created.Expires, 5*time.Second)
+
+		rr := serveHTTPMux(t, getRequestHandler, "/test/verifier/presentationRequests/"+created.ID, nil,
+			map[string]string{profileIDPathParam: "test", presentationRequestIDPathParam: created.ID})
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		request := &PresentationRequestResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), request))
+		require.Equal(t, created.Challenge, request.Challenge)
+		require.Equal(t, created.RequestURI, request.RequestURI)
+
+		code, response := interact(t, created.ID, nil)
+		require.Equal(t, http.StatusOK, code)
+		require.NotNil(t, response.VerifiablePresentationRequest)
+		require.Nil(t, response.PresentationDefinitionRequest)
+		require.JSONEq(t, string(vprQuery), string(response.VerifiablePresentationRequest.Query))
+		require.Equal(t, created.Challenge, response.VerifiablePresentationRequest.Challenge)
+		require.Equal(t, "example.com", response.VerifiablePresentationRequest.Domain)
+		require.Equal(t, []InteractService{{Type: interactServiceType, ServiceEndpoint: created.RequestURI}},
+			response.VerifiablePresentationRequest.Interact.Service)
+	})
+
+	t.Run("create a pe request", func(t *testing.T) {
+		definition := json.RawMessage(`{"id":"pd1","input_descriptors":[{"id":"prc"}]}`)
+
+		created := create(t, &CreatePresentationRequest{Format: presentationrequest.FormatPE,
+			PresentationDefinition: definition})
+		require.Equal(t, presentationrequest.FormatPE, created.Format)
+		require.WithinDuration(t, time.Now().Add(presentationrequest.DefaultTTL), created.Expires, 5*time.Second)
+
+		code, response := interact(t, created.ID, []byte("{}"))
+		require.Equal(t, http.StatusOK, code)
+		require.Nil(t, response.VerifiablePresentationRequest)
+		require.NotNil(t, response.PresentationDefinitionRequest)
+		require.JSONEq(t, string(definition), string(response.PresentationDefinition))
+		require.Equal(t, created.Challenge, response.PresentationDefinitionRequest.Challenge)
+		require.Equal(t, created.RequestURI, response.ResponseURI)
+
+		// the input descriptor requests no field of the credential subject, which has no AnonCreds equivalent
+		require.Empty(t, created.AnonCredsProofRequest)
+		require.Empty(t, response.PresentationDefinitionRequest.AnonCredsProofRequest)
+	})
+
+	t.Run("create a pe request translated to an AnonCreds proof request", func(t *testing.T) {
+		created := create(t, &CreatePresentationRequest{Format: presentationrequest.FormatPE,
+			PresentationDefinition: json.RawMessage(`{"id":"pd1","input_descriptors":[{"id":"prc",
+				"constraints":{"fields":[{"path":["$.credentialSubject.givenName"]}]}}]}`)})
+
+		proofRequest, err := anoncreds.ParseProofRequest(created.AnonCredsProofRequest)
+		require.NoError(t, err)
+		require.NotEmpty(t, proofRequest.Nonce)
+		require.Equal(t, "givenName", proofRequest.RequestedAttributes["prc"].Name)
+
+		code, response := interact(t, created.ID, nil)
+		require.Equal(t, http.StatusOK, code)
+		require.JSONEq(t, string(created.AnonCredsProofRequest),
+			string(response.PresentationDefinitionRequest.AnonCredsProofRequest))
+	})
+
+	t.Run("create a pe request of an AnonCreds proof request", func(t *testing.T) {
+		created := create(t, &CreatePresentationRequest{Format: presentationrequest.FormatPE,
+			AnonCredsProofRequest: json.RawMessage(`{"name":"proof of residence","version":"1.0","nonce":"1234",
+				"requested_attributes":{"attr1_referent":{"name":"givenName",
+				"restrictions":[{"issuer_did":"did:example:76e12ec712ebc6f1c221ebfeb1f"}]}}}`)})
+		require.Equal(t, presentationrequest.FormatPE, created.Format)
+
+		definition, err := presentationrequest.ParseDefinition(created.PresentationDefinition)
+		require.NoError(t, err)
+		require.Equal(t, "1234", definition.ID)
+		require.Len(t, definition.InputDescriptors, 1)
+		require.Equal(t, "attr1_referent", definition.InputDescriptors[0].ID)
+		require.Contains(t, string(created.PresentationDefinition), `"$.credentialSubject.givenName"`)
+		require.Contains(t, string(created.AnonCredsProofRequest), `"nonce":"1234"`)
+	})
+
+	t.Run("respond with an AnonCreds presentation", func(t *testing.T) {
+		created := create(t, &CreatePresentationRequest{Format: presentationrequest.FormatPE,
+			AnonCredsProofRequest: json.RawMessage(`{"nonce":"1234",
+				"requested_attributes":{"attr1_referent":{"name":"givenName"}}}`),
+			AnonCredsCredentialDefinitions: []json.RawMessage{json.RawMessage(anonCredsCredDef)}})
+		require.Len(t, created.AnonCredsCredentialDefinitions, 1)
+
+		// the presentation of the DIDComm message doesn't reveal the requested attribute
+		reqBytes, err := json.Marshal(&InteractPresentationRequest{AnonCredsPresentation: json.RawMessage(`{
+			"@type": "https://didcomm.org/present-proof/2.0/presentation",
+			"formats": [{"attach_id": "indy", "format": "hlindy/proof@v2.0"}],
+			"presentations~attach": [{"@id": "indy", "data": {"json": {
+				"proof": {"proofs": [{"primary_proof": {"eq_proof": {}}}], "aggregated_proof": {}},
+				"requested_proof": {"revealed_attrs": {}},
+				"identifiers": [{"schema_id": "Th7MpTaRZVRYnPiabds81Y:2:Passport:1.0",
+					"cred_def_id": "Th7MpTaRZVRYnPiabds81Y:3:CL:12:tag"}]
+			}}}]
+		}`)})
+		require.NoError(t, err)
+
+		code, response := interact(t, created.ID, reqBytes)
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Equal(t, presentationrequest.StateFailed, response.State)
+		require.Equal(t, []string{"the AnonCreds presentation isn't verified: requested attribute attr1_referent: " +
+			"not revealed"}, response.Errors)
+
+		request, err := op.presentationRequests.Get("test", created.ID)
+		require.NoError(t, err)
+		require.Equal(t, presentationrequest.StateFailed, request.State)
+		require.Empty(t, request.Response.Holder)
+	})
+
+	t.Run("invalid AnonCreds responses", func(t *testing.T) {
+		created := create(t, &CreatePresentationRequest{Query: vprQuery})
+
+		code, response := interact(t, created.ID, []byte(`{"anonCredsPresentation":{"proof":{}}}`))
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Equal(t, []string{"the request can't be answered with an AnonCreds presentation"}, response.Errors)
+
+		created = create(t, &CreatePresentationRequest{Query: vprQuery})
+
+		code, _ = interact(t, created.ID, []byte(`{"verifiablePresentation":{},"anonCredsPresentation":{}}`))
+		require.Equal(t, http.StatusBadRequest, code)
+
+		request, err := op.presentationRequests.Get("test", created.ID)
+		require.NoError(t, err)
+		require.Equal(t, presentationrequest.StatePending, request.State)
+	})
+
+	t.Run("respond to a request", func(t *testing.T) {
+		created := create(t, &CreatePresentationRequest{Query: vprQuery, Domain: domain})
+
+		vp := getSignedVP(t, privKey, prCardVC, didID, verificationMethod, didID, verificationMethod, domain,
+			created.Challenge)
+
+		reqBytes, err := json.Marshal(&InteractPresentationRequest{VerifiablePresentation: vp})
+		require.NoError(t, err)
+
+		code, response := interact(t, created.ID, reqBytes)
+		require.Equal(t, http.StatusOK, code, response.Errors)
+		require.Equal(t, presentationrequest.StateVerified, response.State)
+
+		request, err := op.presentationRequests.Get("test", created.ID)
+		require.NoError(t, err)
+		require.Equal(t, presentationrequest.StateVerified, request.State)
+		require.Equal(t, didID, request.Response.Holder)
+	})
+
+	t.Run("failed response", func(t *testing.T) {
+		created := create(t, &CreatePresentationRequest{Query: vprQuery})
+
+		// the presentation isn't signed
+		vp := json.RawMessage(`{"@context":["https://www.w3.org/2018/credentials/v1"],
+			"type":["VerifiablePresentation"],"holder":"did:example:holder"}`)
+
+		reqBytes, err := json.Marshal(&InteractPresentationRequest{VerifiablePresentation: vp})
+		require.NoError(t, err)
+
+		code, response := interact(t, created.ID, reqBytes)
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Equal(t, presentationrequest.StateFailed, response.State)
+		require.NotEmpty(t, response.Errors)
+
+		request, err := op.presentationRequests.Get("test", created.ID)
+		require.NoError(t, err)
+		require.Equal(t, presentationrequest.StateFailed, request.State)
+		require.Equal(t, response.Errors, request.Response.Errors)
+
+		// a request is responded to once
+		code, _ = interact(t, created.ID, reqBytes)
+		require.Equal(t, http.StatusConflict, code)
+
+		code, _ = interact(t, created.ID, nil)
+		require.Equal(t, http.StatusConflict, code)
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		for _, tc := range []struct {
+			name    string
+			request string
+			err     string
+		}{
+			{name: "missing query", request: `{}`, err: "missing query"},
+			{name: "invalid query", request: `{"query":[{"type":"unknown"}]}`, err: "unsupported query type"},
+			{name: "vpr with definition", request: `{"query":[{"type":"DIDAuthentication"}],
+				"presentationDefinition":{"id":"pd1"}}`, err: "only supported by the pe format"},
+			{name: "missing definition", request: `{"format":"pe"}`, err: "missing presentation definition"},
+			{name: "invalid definition", request: `{"format":"pe","presentationDefinition":{"id":"pd1"}}`,
+				err: "must have input descriptors"},
+			{name: "vpr with proof request", request: `{"query":[{"type":"DIDAuthentication"}],
+				"anonCredsProofRequest":{"nonce":"1"}}`, err: "AnonCreds proof request only supported by the pe format"},
+			{name: "definition and proof request", request: `{"format":"pe",
+				"presentationDefinition":{"id":"pd1","input_descriptors":[{"id":"d1"}]},
+				"anonCredsProofRequest":{"nonce":"1"}}`,
+				err: "only one of presentationDefinition and anonCredsProofRequest must be set"},
+			{name: "invalid proof request", request: `{"format":"pe","anonCredsProofRequest":{"nonce":"1"}}`,
+				err: "invalid proof request: the proof request has no requested attributes nor predicates"},
+			{name: "vpr with credential definitions", request: `{"query":[{"type":"DIDAuthentication"}],
+				"anonCredsCredentialDefinitions":[{"id":"1"}]}`,
+				err: "AnonCreds credential definitions only supported by the pe format"},
+			{name: "invalid credential definition", request: `{"format":"pe",
+				"presentationDefinition":{"id":"pd1","input_descriptors":[{"id":"d1"}]},
+				"anonCredsCredentialDefinitions":[{"id":"1"}]}`,
+				err: "invalid credential definition 1: missing primary public key"},
+			{name: "pe with query", request: `{"format":"pe","query":[{"type":"DIDAuthentication"}],
+				"presentationDefinition":{"id":"pd1","input_descriptors":[{"id":"d1"}]}}`,
+				err: "only supported by the vpr format"},
+			{name: "invalid format", request: `{"format":"oidc"}`, err: "invalid format: oidc"},
+			{name: "invalid expiry", request: `{"query":[{"type":"DIDAuthentication"}],"expiresIn":-1}`,
+				err: "expiresIn must be a positive number of seconds"},
+			{name: "expiry beyond the max TTL", request: `{"query":[{"type":"DIDAuthentication"}],"expiresIn":86401}`,
+				err: "expiresIn must be at most 86400 seconds"},
+			{name: "expiry overflowing a duration", request: `{"query":[{"type":"DIDAuthentication"}],
+				"expiresIn":9223372036854775807}`, err: "expiresIn must be at most 86400 seconds"},
+			{name: "invalid json", request: `{`, err: "Invalid request"},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				rr := serveHTTPMux(t, createHandler, "/test/verifier/presentationRequests", []byte(tc.request),
+					map[string]string{profileIDPathParam: "test"})
+				require.Equal(t, http.StatusBadRequest, rr.Code)
+				require.Contains(t, rr.Body.String(), tc.err)
+			})
+		}
+	})
+
+	t.Run("max expiry of the config", func(t *testing.T) {
+		op, err := New(&Config{StoreProvider: memstore.NewProvider(), MaxPresentationRequestTTL: 48 * time.Hour})
+		require.NoError(t, err)
+
+		require.NoError(t, op.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test verifier"}))
+
+		handler := getHandler(t, op, presentationRequestsEndpoint, http.MethodPost)
+
+		rr := serveHTTPMux(t, handler, "/test/verifier/presentationRequests",
+			[]byte(`{"query":[{"type":"DIDAuthentication"}],"expiresIn":172800}`),
+			map[string]string{profileIDPathParam: "test"})
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		created := &PresentationRequestResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), created))
+		require.Equal(t, 48*time.Hour, created.Expires.Sub(created.Created))
+
+		rr = serveHTTPMux(t, handler, "/test/verifier/presentationRequests",
+			[]byte(`{"query":[{"type":"DIDAuthentication"}],"expiresIn":172801}`),
+			map[string]string{profileIDPathParam: "test"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "expiresIn must be at most 172800 seconds")
+	})
+
+	t.Run("invalid profile", func(t *testing.T) {
+		for _, h := range []Handler{createHandler, getRequestHandler, interactHandler} {
+			rr := serveHTTPMux(t, h, "/unknown/verifier/presentationRequests", nil,
+				map[string]string{profileIDPathParam: "unknown", presentationRequestIDPathParam: "id"})
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			require.Contains(t, rr.Body.String(), "invalid verifier profile")
+		}
+	})
+
+	t.Run("request not found", func(t *testing.T) {
+		for _, h := range []Handler{getRequestHandler, interactHandler} {
+			rr := serveHTTPMux(t, h, "/test/verifier/presentationRequests/unknown", nil,
+				map[string]string{profileIDPathParam: "test", presentationRequestIDPathParam: "unknown"})
+			require.Equal(t, http.StatusNotFound, rr.Code)
+			require.Contains(t, rr.Body.String(), "presentation request unknown not found")
+		}
+	})
+
+	t.Run("invalid response", func(t *testing.T) {
+		created := create(t, &CreatePresentationRequest{Query: vprQuery})
+
+		code, _ := interact(t, created.ID, []byte(`{"verifiablePresentation":`))
+		require.Equal(t, http.StatusBadRequest, code)
+
+		request, err := op.presentationRequests.Get("test", created.ID)
+		require.NoError(t, err)
+		require.Equal(t, presentationrequest.StatePending, request.State)
+	})
+
+	t.Run("store errors", func(t *testing.T) {
+		store := &mockPresentationRequestStore{err: errors.New("store error")}
+
+		storeOp, err := New(&Config{VDRI: &vdrimock.MockVDRIRegistry{}, StoreProvider: memstore.NewProvider()})
+		require.NoError(t, err)
+
+		storeOp.presentationRequests = store
+
+		require.NoError(t, storeOp.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test"}))
+
+		rr := serveHTTPMux(t, getHandler(t, storeOp, presentationRequestsEndpoint, http.MethodPost),
+			"/test/verifier/presentationRequests", []byte(`{"query":[{"type":"DIDAuthentication"}]}`),
+			map[string]string{profileIDPathParam: "test"})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "store error")
+
+		rr = serveHTTPMux(t, getHandler(t, storeOp, presentationRequestEndpoint, http.MethodPost),
+			"/test/verifier/presentationRequests/id", []byte(`{"verifiablePresentation":{}}`),
+			map[string]string{profileIDPathParam: "test", presentationRequestIDPathParam: "id"})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), "store error")
+	})
+}
+
+const anonCredsCredDef = `{
+	"id": "Th7MpTaRZVRYnPiabds81Y:3:CL:12:tag",
+	"schemaId": "12",
+	"type": "CL",
+	"tag": "tag",
+	"value": {"primary": {"n": "77", "s": "4", "r": {"givenname": "9", "master_secret": "16"}, "rctxt": "25",
+		"z": "36"}}
+}`
+
+func TestPresentationAnswer(t *testing.T) {
+	t.Run("embedded submission", func(t *testing.T) {
+		answer, err := presentationAnswer([]byte(`{"@context":["https://www.w3.org/2018/credentials/v1"],
+			"type":["VerifiablePresentation"],"holder":"did:example:holder",
+			"presentation_submission":{"id":"s1","definition_id":"pd1","descriptor_map":[{"id":"d1"}]}}`), nil)
+		require.NoError(t, err)
+		require.Equal(t, "did:example:holder", answer.Holder)
+		require.Equal(t, "pd1", answer.Submission.DefinitionID)
+		require.Empty(t, answer.CredentialTypes)
+	})
+
+	t.Run("invalid presentation", func(t *testing.T) {
+		_, err := presentationAnswer([]byte(`{}`), nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to parse the presentation")
+	})
+}
+
+type mockPresentationRequestStore struct {
+	err error
+}
+
+func (m *mockPresentationRequestStore) Create(*presentationrequest.Request,
+	time.Duration) (*presentationrequest.Request, error) {
+	return nil, m.err
+}
+
+func (m *mockPresentationRequestStore) Get(string, string) (*presentationrequest.Request, error) {
+	return nil, m.err
+}
+
+func (m *mockPresentationRequestStore) Respond(string, string, json.RawMessage,
+	func(*presentationrequest.Request) (string, []string, error)) (*presentationrequest.Request, error) {
+	return nil, m.err
+}
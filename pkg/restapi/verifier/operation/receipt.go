@@ -0,0 +1,208 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/crypto"
+	vcprofile "github.com/trustbloc/edge-service/pkg/doc/vc/profile"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	commondid "github.com/trustbloc/edge-service/pkg/restapi/internal/common/did"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+	"github.com/trustbloc/edge-service/pkg/restapi/model"
+)
+
+const (
+	// VerificationReceiptType is the type of the verification receipts
+	VerificationReceiptType = "VerificationReceipt"
+
+	credentialsContext     = "https://www.w3.org/2018/credentials/v1"
+	verifiableCredential   = "VerifiableCredential"
+	verifiablePresentation = "VerifiablePresentation"
+
+	// receiptVocabulary defines the terms of the receipts, which would otherwise be left out of their signature
+	receiptVocabulary = "https://trustbloc.github.io/context/vc/verification-receipt#"
+
+	receiptDigestPrefix = "sha256:"
+)
+
+type commonDID interface {
+	CreateDID(keyType, signatureType, did, privateKey, keyID, purpose string,
+		registrar model.UNIRegistrar) (string, string, error)
+	CreateKeyDID(keyType string) (string, string, error)
+}
+
+// validateReceiptKey checks the key signing the receipts of the profile can be created
+func validateReceiptKey(pr *verifier.ProfileData) error {
+	if pr.ReceiptSignatureType == "" {
+		if pr.ReceiptKeyType != "" || pr.ReceiptDIDMethod != "" {
+			return errors.New("the receipt key requires a receipt signature type")
+		}
+
+		return nil
+	}
+
+	keyType := pr.ReceiptKeyType
+	if keyType == "" {
+		keyType = crypto.Ed25519KeyType
+	}
+
+	switch pr.ReceiptDIDMethod {
+	case "", commondid.MethodKey:
+	default:
+		return fmt.Errorf("unsupported receipt DID method %s", pr.ReceiptDIDMethod)
+	}
+
+	if pr.ReceiptDIDMethod == commondid.MethodKey && keyType != crypto.Ed25519KeyType {
+		return fmt.Errorf("did:key supports %s keys only", crypto.Ed25519KeyType)
+	}
+
+	if err := commondid.ValidateKeyType(keyType, pr.ReceiptSignatureType); err != nil {
+		return fmt.Errorf("invalid receipt key: %w", err)
+	}
+
+	return nil
+}
+
+// createReceiptKey creates the DID signing the receipts of the profile, the service only signing the receipts with
+// the keys it created for them
+func (o *Operation) createReceiptKey(pr *verifier.ProfileData) error {
+	pr.ReceiptDID, pr.ReceiptCreator = "", ""
+
+	if pr.ReceiptSignatureType == "" {
+		return nil
+	}
+
+	if o.commonDID == nil {
+		return commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			"the service has no key manager to sign verification receipts")
+	}
+
+	if pr.ReceiptKeyType == "" {
+		pr.ReceiptKeyType = crypto.Ed25519KeyType
+	}
+
+	var err error
+
+	if pr.ReceiptDIDMethod == commondid.MethodKey {
+		pr.ReceiptDID, pr.ReceiptCreator, err = o.commonDID.CreateKeyDID(pr.ReceiptKeyType)
+	} else {
+		pr.ReceiptDID, pr.ReceiptCreator, err = o.commonDID.CreateDID(pr.ReceiptKeyType, pr.ReceiptSignatureType,
+			"", "", "", crypto.AssertionMethod, model.UNIRegistrar{})
+	}
+
+	if err != nil {
+		return commhttp.NewError(http.StatusBadRequest, commhttp.DIDError,
+			fmt.Sprintf("failed to create receipt DID: %s", err.Error()))
+	}
+
+	return nil
+}
+
+// checkReceiptKey returns an error if a receipt is requested from a profile without receipt key, so the document
+// isn't verified for nothing
+func checkReceiptKey(profile *verifier.ProfileData, requested bool) error {
+	if requested && profile.ReceiptCreator == "" {
+		return commhttp.NewError(http.StatusBadRequest, commhttp.InvalidRequest,
+			fmt.Sprintf("verifier profile %s has no receipt key", profile.ID))
+	}
+
+	return nil
+}
+
+// newReceipt returns the verification receipt of the verified document, a credential issued by the receipt DID of
+// the profile
+func (o *Operation) newReceipt(profile *verifier.ProfileData, verifiedType string, doc json.RawMessage,
+	checks, failedChecks []string) (json.RawMessage, error) {
+	now := time.Now().UTC()
+	digest := sha256.Sum256(doc)
+
+	subject := &VerificationReceiptSubject{
+		ID:               documentID(doc),
+		VerifiedType:     verifiedType,
+		Digest:           receiptDigestPrefix + hex.EncodeToString(digest[:]),
+		Verifier:         profile.ID,
+		Checks:           checks,
+		FailedChecks:     failedChecks,
+		Verified:         len(failedChecks) == 0,
+		VerificationTime: now,
+	}
+
+	receipt := &verifiable.Credential{
+		Context:       []string{credentialsContext},
+		CustomContext: []interface{}{map[string]interface{}{"@vocab": receiptVocabulary}},
+		ID:            "urn:uuid:" + uuid.New().String(),
+		Types:         []string{verifiableCredential, VerificationReceiptType},
+		Issuer:        verifiable.Issuer{ID: profile.ReceiptDID},
+		Issued:        util.NewTime(now),
+		Subject:       subject,
+	}
+
+	if signatureContext := crypto.SignatureContext(profile.ReceiptSignatureType); signatureContext != "" {
+		receipt.Context = append(receipt.Context, signatureContext)
+	}
+
+	signed, err := o.crypto.SignCredential(&vcprofile.DataProfile{Name: profile.ID, DID: profile.ReceiptDID,
+		Creator: profile.ReceiptCreator, SignatureType: profile.ReceiptSignatureType,
+		SignatureRepresentation: verifiable.SignatureJWS}, receipt)
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.SigningError,
+			fmt.Sprintf("failed to sign verification receipt: %s", err.Error()))
+	}
+
+	receiptBytes, err := signed.MarshalJSON()
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusInternalServerError, commhttp.InternalError,
+			fmt.Sprintf("failed to marshal verification receipt: %s", err.Error()))
+	}
+
+	return receiptBytes, nil
+}
+
+// documentID returns the ID of the JSON-LD credential or presentation, empty for a JWT or a document without ID
+func documentID(doc json.RawMessage) string {
+	identified := struct {
+		ID string `json:"id"`
+	}{}
+
+	if err := json.Unmarshal(doc, &identified); err != nil {
+		return ""
+	}
+
+	return identified.ID
+}
+
+func failedCredentialChecks(result []CredentialsVerificationCheckResult) []string {
+	var failed []string
+
+	for _, r := range result {
+		failed = append(failed, r.Check)
+	}
+
+	return failed
+}
+
+func failedPresentationChecks(result []VerifyPresentationCheckResult) []string {
+	var failed []string
+
+	for _, r := range result {
+		failed = append(failed, r.Check)
+	}
+
+	return failed
+}
@@ -0,0 +1,272 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/tink/go/keyset"
+	"github.com/hyperledger/aries-framework-go/pkg/crypto/tinkcrypto/primitive/composite/ecdhes"
+	cryptomock "github.com/hyperledger/aries-framework-go/pkg/mock/crypto"
+	mockkms "github.com/hyperledger/aries-framework-go/pkg/mock/kms"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/policy"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+	"github.com/trustbloc/edge-service/pkg/restapi/model"
+)
+
+type mockCommonDID struct {
+	createDIDValue string
+	createDIDKeyID string
+	createDIDErr   error
+	keyDID         bool
+}
+
+func (m *mockCommonDID) CreateDID(keyType, signatureType, didID, privateKey, keyID, purpose string,
+	registrar model.UNIRegistrar) (string, string, error) {
+	return m.createDIDValue, m.createDIDKeyID, m.createDIDErr
+}
+
+func (m *mockCommonDID) CreateKeyDID(keyType string) (string, string, error) {
+	m.keyDID = true
+
+	return m.createDIDValue, m.createDIDKeyID, m.createDIDErr
+}
+
+func newReceiptOperation(t *testing.T, signErr error) *Operation {
+	kh, err := keyset.NewHandle(ecdhes.ECDHES256KWAES256GCMKeyTemplate())
+	require.NoError(t, err)
+
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	// the DID signing the receipts is resolved to check its key may sign them
+	op, err := New(&Config{StoreProvider: memstore.NewProvider(),
+		VDRI:       &vdrimock.MockVDRIRegistry{ResolveValue: createDIDDoc("did:test:verifier", pubKey)},
+		KeyManager: &mockkms.KeyManager{CreateKeyValue: kh},
+		Crypto:     &cryptomock.Crypto{SignErr: signErr}})
+	require.NoError(t, err)
+
+	op.commonDID = &mockCommonDID{createDIDValue: "did:test:verifier", createDIDKeyID: "did:test:verifier#key-1"}
+
+	return op
+}
+
+func TestCreateProfileReceiptKey(t *testing.T) {
+	t.Run("test receipt DID created", func(t *testing.T) {
+		op := newReceiptOperation(t, nil)
+
+		profile := &verifier.ProfileData{ID: "test", Name: "test", ReceiptSignatureType: "Ed25519Signature2018"}
+		require.NoError(t, op.CreateProfile(profile))
+
+		stored, err := op.profileStore.GetProfile("test")
+		require.NoError(t, err)
+		require.Equal(t, "did:test:verifier", stored.ReceiptDID)
+		require.Equal(t, "did:test:verifier#key-1", stored.ReceiptCreator)
+		require.Equal(t, "Ed25519", stored.ReceiptKeyType)
+		require.False(t, op.commonDID.(*mockCommonDID).keyDID)
+	})
+
+	t.Run("test receipt did:key created", func(t *testing.T) {
+		op := newReceiptOperation(t, nil)
+
+		require.NoError(t, op.CreateProfile(&verifier.ProfileData{ID: "test", Name: "test",
+			ReceiptSignatureType: "Ed25519Signature2018", ReceiptDIDMethod: "key"}))
+		require.True(t, op.commonDID.(*mockCommonDID).keyDID)
+	})
+
+	t.Run("test receipt key of the request ignored", func(t *testing.T) {
+		op := newReceiptOperation(t, nil)
+
+		require.NoError(t, op.CreateProfile(&verifier.ProfileData{ID: "test", Name: "test",
+			ReceiptDID: "did:test:other", ReceiptCreator: "did:test:other#key1"}))
+
+		stored, err := op.profileStore.GetProfile("test")
+		require.NoError(t, err)
+		require.Empty(t, stored.ReceiptDID)
+		require.Empty(t, stored.ReceiptCreator)
+	})
+
+	t.Run("test invalid receipt key", func(t *testing.T) {
+		op := newReceiptOperation(t, nil)
+
+		for profile, msg := range map[*verifier.ProfileData]string{
+			{ReceiptKeyType: "Ed25519"}: "the receipt key requires a receipt signature type",
+			{ReceiptSignatureType: "Ed25519Signature2018", ReceiptDIDMethod: "web"}: "unsupported receipt DID " +
+				"method web",
+			{ReceiptSignatureType: "JsonWebSignature2020", ReceiptKeyType: "P256",
+				ReceiptDIDMethod: "key"}: "did:key supports Ed25519 keys only",
+			{ReceiptSignatureType: "unknown"}: "invalid receipt key",
+		} {
+			profile.ID, profile.Name = "test", "test"
+
+			err := op.CreateProfile(profile)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), msg)
+		}
+	})
+
+	t.Run("test error - no key manager", func(t *testing.T) {
+		op, err := New(&Config{StoreProvider: memstore.NewProvider()})
+		require.NoError(t, err)
+
+		err = op.CreateProfile(&verifier.ProfileData{ID: "test", Name: "test",
+			ReceiptSignatureType: "Ed25519Signature2018"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "the service has no key manager to sign verification receipts")
+	})
+
+	t.Run("test error - DID not created", func(t *testing.T) {
+		op := newReceiptOperation(t, nil)
+		op.commonDID = &mockCommonDID{createDIDErr: errors.New("DID error")}
+
+		err := op.CreateProfile(&verifier.ProfileData{ID: "test", Name: "test",
+			ReceiptSignatureType: "Ed25519Signature2018"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create receipt DID: DID error")
+
+		_, err = op.profileStore.GetProfile("test")
+		require.Error(t, err)
+	})
+}
+
+func TestVerificationReceipt(t *testing.T) {
+	newOperation := func(t *testing.T, signErr error) *Operation {
+		op := newReceiptOperation(t, signErr)
+
+		require.NoError(t, op.CreateProfile(&verifier.ProfileData{ID: "test", Name: "test",
+			CredentialChecks: []string{statusCheck}, PresentationChecks: []string{policyCheck},
+			Policy:               []policy.Rule{{Type: policy.NotExpired}},
+			ReceiptSignatureType: "Ed25519Signature2018"}))
+		require.NoError(t, op.profileStore.SaveProfile(&verifier.ProfileData{ID: "nokey", Name: "nokey"}))
+
+		return op
+	}
+
+	receiptSubject := func(t *testing.T, receipt json.RawMessage) *VerificationReceiptSubject {
+		vc := struct {
+			Types   []string                    `json:"type"`
+			Issuer  string                      `json:"issuer"`
+			Subject *VerificationReceiptSubject `json:"credentialSubject"`
+			Proof   map[string]interface{}      `json:"proof"`
+		}{}
+
+		require.NoError(t, json.Unmarshal(receipt, &vc))
+		require.Equal(t, []string{"VerifiableCredential", VerificationReceiptType}, vc.Types)
+		require.Equal(t, "did:test:verifier", vc.Issuer)
+		require.Equal(t, "did:test:verifier#key-1", vc.Proof["verificationMethod"])
+		require.NotEmpty(t, vc.Proof["jws"])
+
+		return vc.Subject
+	}
+
+	digest := func(doc string) string {
+		sum := sha256.Sum256([]byte(doc))
+
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	verifyCredential := func(t *testing.T, op *Operation, profileID string,
+		opts *CredentialsVerificationOptions) *httptest.ResponseRecorder {
+		reqBytes, err := json.Marshal(&CredentialsVerificationRequest{Credential: []byte(validVCStatus),
+			Opts: opts})
+		require.NoError(t, err)
+
+		return serveHTTPMux(t, getHandler(t, op, credentialsVerificationEndpoint, http.MethodPost),
+			"/"+profileID+"/verifier/credentials", reqBytes, map[string]string{profileIDPathParam: profileID})
+	}
+
+	t.Run("test receipt of a verified credential", func(t *testing.T) {
+		rr := verifyCredential(t, newOperation(t, nil), "test", &CredentialsVerificationOptions{Receipt: true})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		resp := &CredentialsVerificationSuccessResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+
+		subject := receiptSubject(t, resp.Receipt)
+		require.True(t, subject.Verified)
+		require.Equal(t, "#ID", subject.ID)
+		require.Equal(t, "VerifiableCredential", subject.VerifiedType)
+		require.Equal(t, "test", subject.Verifier)
+		require.Equal(t, digest(validVCStatus), subject.Digest)
+		require.False(t, subject.VerificationTime.IsZero())
+	})
+
+	t.Run("test receipt of a credential failing a check", func(t *testing.T) {
+		rr := verifyCredential(t, newOperation(t, nil), "test", &CredentialsVerificationOptions{Receipt: true,
+			Checks: []string{proofCheck}})
+		require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+
+		resp := &CredentialsVerificationFailResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+
+		subject := receiptSubject(t, resp.Receipt)
+		require.False(t, subject.Verified)
+		require.Equal(t, []string{proofCheck}, subject.Checks)
+		require.Equal(t, []string{proofCheck}, subject.FailedChecks)
+	})
+
+	t.Run("test no receipt unless requested", func(t *testing.T) {
+		rr := verifyCredential(t, newOperation(t, nil), "test", nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.NotContains(t, rr.Body.String(), "receipt")
+	})
+
+	t.Run("test receipt of a verified presentation", func(t *testing.T) {
+		reqBytes, err := json.Marshal(&VerifyPresentationRequest{Presentation: []byte(vpWithoutProof),
+			Opts: &VerifyPresentationOptions{Receipt: true}})
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, getHandler(t, newOperation(t, nil), presentationsVerificationEndpoint,
+			http.MethodPost), "/test/verifier/presentations", reqBytes, map[string]string{profileIDPathParam: "test"})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		resp := &VerifyPresentationSuccessResponse{}
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+
+		subject := receiptSubject(t, resp.Receipt)
+		require.True(t, subject.Verified)
+		require.Equal(t, "urn:uuid:3978344f-8596-4c3a-a978-8fcaba3903c5", subject.ID)
+		require.Equal(t, "VerifiablePresentation", subject.VerifiedType)
+		require.Equal(t, digest(vpWithoutProof), subject.Digest)
+	})
+
+	t.Run("test error - profile without receipt key", func(t *testing.T) {
+		rr := verifyCredential(t, newOperation(t, nil), "nokey", &CredentialsVerificationOptions{Receipt: true})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "verifier profile nokey has no receipt key")
+
+		reqBytes, err := json.Marshal(&VerifyPresentationRequest{Presentation: []byte(vpWithoutProof),
+			Opts: &VerifyPresentationOptions{Receipt: true}})
+		require.NoError(t, err)
+
+		rr = serveHTTPMux(t, getHandler(t, newOperation(t, nil), presentationsVerificationEndpoint,
+			http.MethodPost), "/nokey/verifier/presentations", reqBytes, map[string]string{profileIDPathParam: "nokey"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+		require.Contains(t, rr.Body.String(), "verifier profile nokey has no receipt key")
+	})
+
+	t.Run("test error - receipt not signed", func(t *testing.T) {
+		rr := verifyCredential(t, newOperation(t, errors.New("sign error")), "test",
+			&CredentialsVerificationOptions{Receipt: true})
+		require.Equal(t, http.StatusInternalServerError, rr.Code)
+		require.Contains(t, rr.Body.String(), string(commhttp.SigningError))
+		require.Contains(t, rr.Body.String(), "failed to sign verification receipt")
+	})
+}
@@ -0,0 +1,192 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+)
+
+const (
+	// resultCacheSize is the maximum number of verification results cached
+	resultCacheSize = 10000
+
+	resultCacheKeyPrefix = "vcresult/"
+)
+
+// cachedResult is a successful verification of a credential, reused while the status list of the credential is
+// unchanged and the credential has not expired
+type cachedResult struct {
+	StatusList    string     `json:"statusList,omitempty"`
+	StatusVersion string     `json:"statusVersion,omitempty"`
+	Expires       *time.Time `json:"expires,omitempty"`
+}
+
+// resultCacheKey returns the key of the verification result of the credential, the hash of the profile and of the
+// verification request, an empty string if the result isn't cached
+func (o *Operation) resultCacheKey(profile *verifier.ProfileData,
+	verificationReq *CredentialsVerificationRequest) string {
+	if o.resultCache == nil {
+		return ""
+	}
+
+	// the age of the proof changes over time
+	if verificationReq.Opts != nil && verificationReq.Opts.MaxProofAge > 0 {
+		return ""
+	}
+
+	keyBytes, err := json.Marshal(struct {
+		Profile *verifier.ProfileData           `json:"profile"`
+		Request *CredentialsVerificationRequest `json:"request"`
+	}{Profile: profile, Request: verificationReq})
+	if err != nil {
+		logger.Warnf("failed to marshal verification result cache key: %s", err)
+
+		return ""
+	}
+
+	hash := sha256.Sum256(keyBytes)
+
+	return resultCacheKeyPrefix + base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// cachedResultValid checks the credential has a cached successful verification, and its status list hasn't changed
+// since
+func (o *Operation) cachedResultValid(ctx context.Context, key string, vc *verifiable.Credential) bool {
+	if key == "" {
+		return false
+	}
+
+	resultBytes, ok := o.resultCache.Get(key)
+	if !ok {
+		return false
+	}
+
+	result := &cachedResult{}
+
+	if err := json.Unmarshal(resultBytes, result); err != nil {
+		logger.Warnf("invalid cached verification result %s: %s", key, err)
+
+		return false
+	}
+
+	if result.Expires != nil && !time.Now().Before(*result.Expires) {
+		o.resultCache.Delete(key)
+
+		return false
+	}
+
+	if result.StatusList == "" {
+		return true
+	}
+
+	_, version, err := o.fetchStatusList(ctx, result.StatusList, result.StatusVersion)
+	if err != nil || version != result.StatusVersion {
+		logger.Debugf("status list %s of credential %s changed, verifying it again", result.StatusList, vc.ID)
+
+		o.resultCache.Delete(key)
+
+		return false
+	}
+
+	return true
+}
+
+// cacheResult caches the successful verification of the credential, with the version of its status list if its
+// status was checked
+func (o *Operation) cacheResult(key string, vc *verifiable.Credential, statusVersion string) {
+	if key == "" {
+		return
+	}
+
+	result := &cachedResult{StatusVersion: statusVersion}
+
+	if statusVersion != "" {
+		result.StatusList = vc.Status.ID
+	}
+
+	if vc.Expired != nil {
+		result.Expires = &vc.Expired.Time
+	}
+
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		logger.Warnf("failed to marshal verification result: %s", err)
+
+		return
+	}
+
+	o.resultCache.Set(key, resultBytes)
+}
+
+// fetchStatusList fetches the status list, and returns it with its version: its ETag, or the hash of the list if
+// the list is served without ETag. If the version of the list is given and the list is unchanged, the list isn't
+// returned. The list is fetched once for a batch of credentials.
+func (o *Operation) fetchStatusList(ctx context.Context, url, version string) ([]byte, string, error) {
+	if cache := batchCacheFrom(ctx); cache != nil {
+		return o.fetchBatchStatusList(ctx, cache, url, version)
+	}
+
+	return o.requestStatusList(ctx, url, version)
+}
+
+// requestStatusList requests the status list, conditionally if its version is given
+func (o *Operation) requestStatusList(ctx context.Context, url, version string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if token := o.requestTokens[cslRequestTokenName]; token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
+
+	if version != "" {
+		req.Header.Set("If-None-Match", version)
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer func() {
+		if errClose := resp.Body.Close(); errClose != nil {
+			logger.Warnf("failed to close response body")
+		}
+	}()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		logger.Warnf("failed to read response body for status %d: %s", resp.StatusCode, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && version != "":
+		return nil, version, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, "", fmt.Errorf("failed to read response body for status %d: %s", resp.StatusCode, string(body))
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		return body, etag, nil
+	}
+
+	hash := sha256.Sum256(body)
+
+	return body, base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
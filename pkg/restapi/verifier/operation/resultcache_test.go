@@ -0,0 +1,233 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	cslstatus "github.com/trustbloc/edge-service/pkg/doc/vc/status/csl"
+)
+
+func TestVerifyCredential_ResultCache(t *testing.T) {
+	list := &statusListServer{etag: `"v1"`, csl: &cslstatus.CSL{ID: "list1"}}
+
+	srv := httptest.NewServer(list)
+	defer srv.Close()
+
+	vc, err := verifiable.ParseUnverifiedCredential([]byte(prCardVC))
+	require.NoError(t, err)
+
+	vc.Status = &verifiable.TypedID{ID: srv.URL + "/status/1", Type: "CredentialStatusList2017"}
+
+	vcBytes, err := vc.MarshalJSON()
+	require.NoError(t, err)
+
+	op, err := New(&Config{
+		VDRI:           &vdrimock.MockVDRIRegistry{},
+		StoreProvider:  memstore.NewProvider(),
+		ResultCacheTTL: time.Minute,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test verifier",
+		CredentialChecks: []string{statusCheck}}))
+
+	verify := func(t *testing.T) []CredentialsVerificationCheckResult {
+		checks, result, errVerify := op.VerifyCredential("test", &CredentialsVerificationRequest{Credential: vcBytes})
+		require.NoError(t, errVerify)
+		require.Equal(t, []string{statusCheck}, checks)
+
+		return result
+	}
+
+	t.Run("test result cached", func(t *testing.T) {
+		require.Empty(t, verify(t))
+		require.Equal(t, 1, list.fetches())
+
+		// the cached result is reused, the status list being unchanged
+		require.Empty(t, verify(t))
+		require.Equal(t, 1, list.fetches())
+		require.Equal(t, 2, list.requests())
+	})
+
+	t.Run("test result invalidated when the status list changes", func(t *testing.T) {
+		list.update(`"v2"`, &cslstatus.CSL{ID: "list1", Description: "updated"})
+
+		// the changed list is fetched when revalidating the cached result, then when verifying the credential
+		require.Empty(t, verify(t))
+		require.Equal(t, 3, list.fetches())
+
+		require.Empty(t, verify(t))
+		require.Equal(t, 3, list.fetches())
+	})
+
+	t.Run("test revoked credential not cached", func(t *testing.T) {
+		list.update(`"v3"`, &cslstatus.CSL{ID: "list1", VC: []string{`{"id":"` + vc.ID + `"}`}})
+
+		require.Len(t, verify(t), 1)
+		require.Equal(t, 5, list.fetches())
+
+		require.Len(t, verify(t), 1)
+		require.Equal(t, 6, list.fetches())
+	})
+
+	t.Run("test status list without ETag", func(t *testing.T) {
+		list.update("", &cslstatus.CSL{ID: "list1"})
+
+		require.Empty(t, verify(t))
+		require.Equal(t, 7, list.fetches())
+
+		// the list is fetched again to compare its hash
+		require.Empty(t, verify(t))
+		require.Equal(t, 8, list.fetches())
+
+		list.update("", &cslstatus.CSL{ID: "list1", VC: []string{`{"id":"` + vc.ID + `"}`}})
+
+		require.Len(t, verify(t), 1)
+	})
+
+	t.Run("test status list unavailable", func(t *testing.T) {
+		list.update(`"v4"`, &cslstatus.CSL{ID: "list1"})
+
+		require.Empty(t, verify(t))
+
+		srv.Close()
+
+		result := verify(t)
+		require.Len(t, result, 1)
+		require.Contains(t, result[0].Error, "failed to fetch the status")
+	})
+}
+
+func TestResultCacheKey(t *testing.T) {
+	profile := &verifier.ProfileData{ID: "test", CredentialChecks: []string{statusCheck}}
+	req := &CredentialsVerificationRequest{Credential: []byte(prCardVC)}
+
+	t.Run("test results not cached", func(t *testing.T) {
+		op, err := New(&Config{VDRI: &vdrimock.MockVDRIRegistry{}, StoreProvider: memstore.NewProvider()})
+		require.NoError(t, err)
+
+		require.Empty(t, op.resultCacheKey(profile, req))
+	})
+
+	op, err := New(&Config{VDRI: &vdrimock.MockVDRIRegistry{}, StoreProvider: memstore.NewProvider(),
+		ResultCacheTTL: time.Minute})
+	require.NoError(t, err)
+
+	t.Run("test key of the profile and request", func(t *testing.T) {
+		key := op.resultCacheKey(profile, req)
+		require.NotEmpty(t, key)
+		require.Equal(t, key, op.resultCacheKey(profile, req))
+
+		require.NotEqual(t, key, op.resultCacheKey(&verifier.ProfileData{ID: "test",
+			CredentialChecks: []string{proofCheck, statusCheck}}, req))
+		require.NotEqual(t, key, op.resultCacheKey(profile, &CredentialsVerificationRequest{
+			Credential: []byte(prCardVC), Opts: &CredentialsVerificationOptions{Challenge: "challenge"}}))
+	})
+
+	t.Run("test proof age not cached", func(t *testing.T) {
+		require.Empty(t, op.resultCacheKey(profile, &CredentialsVerificationRequest{
+			Credential: []byte(prCardVC), Opts: &CredentialsVerificationOptions{MaxProofAge: 60}}))
+	})
+
+	t.Run("test expired credential", func(t *testing.T) {
+		vc, err := verifiable.ParseUnverifiedCredential([]byte(prCardVC))
+		require.NoError(t, err)
+
+		vc.Expired.Time = time.Now().Add(-time.Minute)
+
+		op.cacheResult("key", vc, "")
+		_, ok := op.resultCache.Get("key")
+		require.True(t, ok)
+
+		require.False(t, op.cachedResultValid(context.Background(), "key", vc))
+
+		_, ok = op.resultCache.Get("key")
+		require.False(t, ok)
+	})
+
+	t.Run("test invalid cached result", func(t *testing.T) {
+		op.resultCache.Set("key", []byte("{"))
+
+		require.False(t, op.cachedResultValid(context.Background(), "key", &verifiable.Credential{}))
+	})
+}
+
+// statusListServer serves a credential status list with its ETag, counting the requests and the requests for which
+// the list is sent
+type statusListServer struct {
+	etag       string
+	csl        *cslstatus.CSL
+	nRequests  int
+	nFetches   int
+	statusLock sync.Mutex
+}
+
+func (s *statusListServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+
+	s.nRequests++
+
+	if s.etag != "" {
+		rw.Header().Set("ETag", s.etag)
+
+		if req.Header.Get("If-None-Match") == s.etag {
+			rw.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+	}
+
+	s.nFetches++
+
+	cslBytes, err := json.Marshal(s.csl)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	_, err = rw.Write(cslBytes)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (s *statusListServer) update(etag string, csl *cslstatus.CSL) {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+
+	s.etag = etag
+	s.csl = csl
+}
+
+func (s *statusListServer) requests() int {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+
+	return s.nRequests
+}
+
+func (s *statusListServer) fetches() int {
+	s.statusLock.Lock()
+	defer s.statusLock.Unlock()
+
+	return s.nFetches
+}
@@ -0,0 +1,74 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/sdjwt"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+const sdJWTsVerificationEndpoint = "/" + "{" + profileIDPathParam + "}" + verifierBasePath + "/sdjwts"
+
+// VerifySDJWT swagger:route POST /{id}/verifier/sdjwts verifier verifySDJWTReq
+//
+// Verifies a presented SD-JWT credential: the signature and the expiration of its issuer JWT, the digests of its
+// disclosures, and its key binding JWT signed by the holder key, for the audience and with the nonce if set.
+//
+// Responses:
+//    default: genericError
+//        200: verifySDJWTRes
+func (o *Operation) verifySDJWTHandler(rw http.ResponseWriter, req *http.Request) {
+	if _, err := o.getVerifierProfile(mux.Vars(req)[profileIDPathParam]); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	request := &VerifySDJWTRequest{}
+
+	if err := commhttp.DecodeJSON(req, request); err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	credential, err := o.verifySDJWT(request)
+	if err != nil {
+		commhttp.WriteError(rw, err)
+
+		return
+	}
+
+	commhttp.WriteResponse(rw, credential)
+}
+
+// verifySDJWT verifies the SD-JWT of the request, the keys of its issuer and holder being fetched as the keys of the
+// issuers of the credentials
+func (o *Operation) verifySDJWT(request *VerifySDJWTRequest) (*sdjwt.Credential, error) {
+	if request.SDJWT == "" {
+		validationErr := &commhttp.ValidationError{}
+		validationErr.Add("/sdjwt", "missing sdjwt")
+
+		return nil, commhttp.NewValidationError(validationErr)
+	}
+
+	credential, err := sdjwt.Verify(request.SDJWT, o.publicKeyFetcher(o.vdri), &sdjwt.Verification{
+		Audience: request.Audience, Nonce: request.Nonce, RequireKeyBinding: request.RequireKeyBinding,
+		Now: time.Now()})
+	if err != nil {
+		return nil, commhttp.NewError(http.StatusBadRequest, commhttp.InvalidCredential,
+			fmt.Sprintf("invalid SD-JWT: %s", err.Error()))
+	}
+
+	return credential, nil
+}
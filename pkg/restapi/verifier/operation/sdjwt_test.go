@@ -0,0 +1,148 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/util"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/verifiable"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	vdrimock "github.com/hyperledger/aries-framework-go/pkg/mock/vdri"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+
+	"github.com/trustbloc/edge-service/pkg/doc/vc/profile/verifier"
+	"github.com/trustbloc/edge-service/pkg/doc/vc/sdjwt"
+	commhttp "github.com/trustbloc/edge-service/pkg/restapi/internal/common/http"
+)
+
+func TestVerifySDJWT(t *testing.T) {
+	const issuerDID = "did:example:issuer"
+
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	holderPubKey, holderPrivKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	holderKey := &jose.JWK{}
+	holderKey.Key = holderPubKey
+
+	op, err := New(&Config{
+		StoreProvider: memstore.NewProvider(),
+		VDRI: &vdrimock.MockVDRIRegistry{
+			ResolveFunc: func(didID string, opts ...vdriapi.ResolveOpts) (*did.Doc, error) {
+				return createDIDDoc(didID, pubKey), nil
+			}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, op.profileStore.SaveProfile(&verifier.ProfileData{ID: "test", Name: "test verifier"}))
+
+	handler := getHandler(t, op, sdJWTsVerificationEndpoint, http.MethodPost)
+
+	issue := func(t *testing.T, expired time.Time) string {
+		vc := &verifiable.Credential{
+			Context: []string{"https://www.w3.org/2018/credentials/v1"},
+			Types:   []string{"VerifiableCredential"},
+			Issuer:  verifiable.Issuer{ID: issuerDID},
+			Subject: map[string]interface{}{"id": "did:example:holder", "family_name": "Doe",
+				"birth_date": "1990-01-01"},
+			Issued:  &util.TimeWithTrailingZeroMsec{Time: time.Now().Add(-time.Hour)},
+			Expired: &util.TimeWithTrailingZeroMsec{Time: expired},
+		}
+
+		sdJWT, err := sdjwt.Issue(vc, &sdjwt.Issuance{VerificationMethod: issuerDID + "#key-1",
+			Algorithm: sdjwt.AlgorithmEdDSA, Signer: getEd25519TestSigner(privKey),
+			Confirmation: &sdjwt.Confirmation{JWK: holderKey}})
+		require.NoError(t, err)
+
+		return sdJWT
+	}
+
+	sdJWT := issue(t, time.Now().Add(time.Hour))
+
+	presentation, err := sdjwt.Present(sdJWT, []string{"birth_date"}, &sdjwt.KeyBinding{
+		Audience: "https://verifier.example.com", Nonce: "nonce1", Algorithm: sdjwt.AlgorithmEdDSA,
+		Signer: getEd25519TestSigner(holderPrivKey)})
+	require.NoError(t, err)
+
+	verify := func(t *testing.T, profile string, request *VerifySDJWTRequest) (int, []byte) {
+		reqBytes, err := json.Marshal(request)
+		require.NoError(t, err)
+
+		rr := serveHTTPMux(t, handler, "/"+profile+"/verifier/sdjwts", reqBytes,
+			map[string]string{profileIDPathParam: profile})
+
+		return rr.Code, rr.Body.Bytes()
+	}
+
+	t.Run("verify SD-JWT - success", func(t *testing.T) {
+		code, body := verify(t, "test", &VerifySDJWTRequest{SDJWT: presentation,
+			Audience: "https://verifier.example.com", Nonce: "nonce1"})
+		require.Equal(t, http.StatusOK, code, string(body))
+		require.NotContains(t, string(body), "Doe")
+
+		credential := &sdjwt.Credential{}
+		require.NoError(t, json.Unmarshal(body, credential))
+		require.Equal(t, issuerDID, credential.Issuer)
+		require.True(t, credential.KeyBound)
+		require.Equal(t, []string{"birth_date"}, credential.Disclosed)
+		require.Equal(t, map[string]interface{}{"id": "did:example:holder", "birth_date": "1990-01-01"},
+			credential.VC["credentialSubject"])
+	})
+
+	t.Run("verify SD-JWT - wrong nonce", func(t *testing.T) {
+		code, body := verify(t, "test", &VerifySDJWTRequest{SDJWT: presentation, Nonce: "nonce2"})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "invalid SD-JWT: invalid key binding JWT: the nonce doesn't match")
+	})
+
+	t.Run("verify SD-JWT - key binding required", func(t *testing.T) {
+		code, body := verify(t, "test", &VerifySDJWTRequest{SDJWT: sdJWT})
+		require.Equal(t, http.StatusOK, code, string(body))
+
+		code, body = verify(t, "test", &VerifySDJWTRequest{SDJWT: sdJWT, RequireKeyBinding: true})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "invalid SD-JWT: missing key binding JWT")
+	})
+
+	t.Run("verify SD-JWT - expired", func(t *testing.T) {
+		code, body := verify(t, "test", &VerifySDJWTRequest{SDJWT: issue(t, time.Now().Add(-time.Minute))})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "invalid SD-JWT")
+	})
+
+	t.Run("verify SD-JWT - missing SD-JWT", func(t *testing.T) {
+		code, body := verify(t, "test", &VerifySDJWTRequest{})
+		require.Equal(t, http.StatusBadRequest, code)
+
+		errResp := &commhttp.ErrorResponse{}
+		require.NoError(t, json.Unmarshal(body, errResp))
+		require.Equal(t, []commhttp.FieldError{{Field: "/sdjwt", Message: "missing sdjwt"}}, errResp.Fields)
+	})
+
+	t.Run("verify SD-JWT - invalid request", func(t *testing.T) {
+		rr := serveHTTPMux(t, handler, "/test/verifier/sdjwts", []byte("{"),
+			map[string]string{profileIDPathParam: "test"})
+		require.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("verify SD-JWT - invalid profile", func(t *testing.T) {
+		code, body := verify(t, "unknown", &VerifySDJWTRequest{SDJWT: presentation})
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, string(body), "invalid verifier profile")
+	})
+}
@@ -0,0 +1,88 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package conditional
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+)
+
+// ErrConflict is returned by a conditional write when the stored value isn't the expected one, e.g. it was written
+// concurrently by another instance
+var ErrConflict = errors.New("stored value changed concurrently")
+
+// lock serializes the conditional writes of the stores without conditional writes
+// nolint: gochecknoglobals
+var lock sync.Mutex
+
+// Store is a store with conditional writes, checked and written atomically by the database so that they are safe
+// across the instances sharing it
+type Store interface {
+	storage.Store
+	// PutIfAbsent stores the value of the key, or returns ErrConflict if the key already has a value.
+	PutIfAbsent(k string, v []byte) error
+	// Replace replaces the value of the key if it is still the old value, as read with Get, or returns ErrConflict.
+	Replace(k string, old, v []byte) error
+}
+
+// New returns the conditional writes of the store: its own if it has them, else writes checked under a lock of this
+// instance, e.g. for the in-memory store. The stores without conditional writes mustn't be shared by instances.
+func New(store storage.Store) Store {
+	if s, ok := store.(Store); ok {
+		return s
+	}
+
+	return &lockedStore{Store: store}
+}
+
+// Supported returns true if the store has its own conditional writes
+func Supported(store storage.Store) bool {
+	_, ok := store.(Store)
+
+	return ok
+}
+
+type lockedStore struct {
+	storage.Store
+}
+
+func (s *lockedStore) PutIfAbsent(k string, v []byte) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	_, err := s.Get(k)
+
+	switch {
+	case err == nil:
+		return ErrConflict
+	case !errors.Is(err, storage.ErrValueNotFound):
+		return fmt.Errorf("failed to get data: %w", err)
+	}
+
+	return s.Put(k, v)
+}
+
+func (s *lockedStore) Replace(k string, old, v []byte) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	stored, err := s.Get(k)
+
+	switch {
+	case errors.Is(err, storage.ErrValueNotFound):
+		return ErrConflict
+	case err != nil:
+		return fmt.Errorf("failed to get data: %w", err)
+	case !bytes.Equal(stored, old):
+		return ErrConflict
+	}
+
+	return s.Put(k, v)
+}
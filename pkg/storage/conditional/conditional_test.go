@@ -0,0 +1,59 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package conditional
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+)
+
+func TestNew(t *testing.T) {
+	store := New(&mockstore.MockStore{Store: make(map[string][]byte)})
+	require.False(t, Supported(store.(*lockedStore).Store))
+	require.True(t, Supported(store))
+	require.Equal(t, store, New(store))
+}
+
+func TestLockedStore(t *testing.T) {
+	p := memstore.NewProvider()
+	require.NoError(t, p.CreateStore("test"))
+
+	memStore, err := p.OpenStore("test")
+	require.NoError(t, err)
+
+	store := New(memStore)
+
+	t.Run("test put if absent", func(t *testing.T) {
+		require.NoError(t, store.PutIfAbsent("k1", []byte("v1")))
+		require.True(t, errors.Is(store.PutIfAbsent("k1", []byte("v2")), ErrConflict))
+
+		v, err := store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v1"), v)
+	})
+
+	t.Run("test replace", func(t *testing.T) {
+		require.NoError(t, store.Replace("k1", []byte("v1"), []byte("v2")))
+		require.True(t, errors.Is(store.Replace("k1", []byte("v1"), []byte("v3")), ErrConflict))
+		require.True(t, errors.Is(store.Replace("k2", []byte("v1"), []byte("v3")), ErrConflict))
+
+		v, err := store.Get("k1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("v2"), v)
+	})
+
+	t.Run("test get errors", func(t *testing.T) {
+		store := New(&mockstore.MockStore{Store: map[string][]byte{"k1": []byte("v1")},
+			ErrGet: errors.New("get error")})
+
+		require.EqualError(t, store.PutIfAbsent("k1", []byte("v1")), "failed to get data: get error")
+		require.EqualError(t, store.Replace("k1", []byte("v1"), []byte("v2")), "failed to get data: get error")
+	})
+}
@@ -0,0 +1,196 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package couchdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	_ "github.com/go-kivik/couchdb" // The CouchDB driver
+	"github.com/go-kivik/kivik"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	couchdbstore "github.com/trustbloc/edge-core/pkg/storage/couchdb"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+const (
+	revField         = "_rev"
+	idField          = "_id"
+	attachmentsField = "_attachments"
+	// the values which aren't JSON objects are stored as the data attachment of their document
+	attachmentName = "data"
+)
+
+// Option configures the CouchDB provider
+type Option func(p *Provider)
+
+// WithDBPrefix option is for adding prefix to the database names
+func WithDBPrefix(dbPrefix string) Option {
+	return func(p *Provider) {
+		p.dbPrefix = dbPrefix
+	}
+}
+
+// Provider is the CouchDB provider of edge-core, its stores having conditional writes checked by CouchDB with the
+// revisions of the documents
+type Provider struct {
+	*couchdbstore.Provider
+	client   *kivik.Client
+	dbPrefix string
+}
+
+// NewProvider instantiates Provider
+func NewProvider(hostURL string, opts ...Option) (*Provider, error) {
+	p := &Provider{}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	provider, err := couchdbstore.NewProvider(hostURL, couchdbstore.WithDBPrefix(p.dbPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := kivik.New("couch", hostURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CouchDB client: %w", err)
+	}
+
+	p.Provider = provider
+	p.client = client
+
+	return p, nil
+}
+
+// OpenStore opens an existing store with the given name and returns it.
+func (p *Provider) OpenStore(name string) (storage.Store, error) {
+	store, err := p.Provider.OpenStore(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.dbPrefix != "" {
+		name = p.dbPrefix + "_" + name
+	}
+
+	return &Store{Store: store, db: p.client.DB(context.Background(), name)}, nil
+}
+
+// Store is an edge-core CouchDB store with conditional writes
+type Store struct {
+	storage.Store
+	db *kivik.DB
+}
+
+// PutIfAbsent stores the given key-value pair unless the key has a value, CouchDB rejecting the creation of a
+// document which exists.
+func (s *Store) PutIfAbsent(k string, v []byte) error {
+	_, err := s.db.Put(context.Background(), k, document(v, ""))
+	if err != nil {
+		if kivik.StatusCode(err) == http.StatusConflict {
+			return conditional.ErrConflict
+		}
+
+		return fmt.Errorf("failed to store data: %w", err)
+	}
+
+	return nil
+}
+
+// Replace replaces the value of the key if it is still the old value, the document being updated with the revision
+// the old value was compared in so that CouchDB rejects the update if the document changed in the meantime.
+func (s *Store) Replace(k string, old, v []byte) error {
+	ctx := context.Background()
+
+	rawDoc := make(map[string]interface{})
+
+	err := s.db.Get(ctx, k).ScanDoc(&rawDoc)
+	if err != nil {
+		if kivik.StatusCode(err) == http.StatusNotFound {
+			return conditional.ErrConflict
+		}
+
+		return fmt.Errorf("failed to get data: %w", err)
+	}
+
+	rev, ok := rawDoc[revField].(string)
+	if !ok {
+		return errors.New("failed to get data: document without revision")
+	}
+
+	stored, err := s.storedValue(ctx, k, rev, rawDoc)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(stored, old) {
+		return conditional.ErrConflict
+	}
+
+	_, err = s.db.Put(ctx, k, document(v, rev))
+	if err != nil {
+		if kivik.StatusCode(err) == http.StatusConflict {
+			return conditional.ErrConflict
+		}
+
+		return fmt.Errorf("failed to store data: %w", err)
+	}
+
+	return nil
+}
+
+// storedValue returns the value of the document at the revision, as returned by Get
+func (s *Store) storedValue(ctx context.Context, k, rev string, rawDoc map[string]interface{}) ([]byte, error) {
+	if _, ok := rawDoc[attachmentsField]; ok {
+		attachment, err := s.db.GetAttachment(ctx, k, attachmentName, kivik.Options{"rev": rev})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get data: %w", err)
+		}
+
+		defer attachment.Content.Close() // nolint: errcheck
+
+		data, err := ioutil.ReadAll(attachment.Content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get data: %w", err)
+		}
+
+		return data, nil
+	}
+
+	delete(rawDoc, idField)
+	delete(rawDoc, revField)
+
+	return json.Marshal(rawDoc)
+}
+
+// document returns the document of the value at the revision, the values which aren't JSON objects being attached to
+// their document as edge-core does
+func document(v []byte, rev string) map[string]interface{} {
+	var doc map[string]interface{}
+
+	if json.Unmarshal(v, &doc) != nil || doc == nil {
+		doc = map[string]interface{}{attachmentsField: map[string]interface{}{
+			attachmentName: map[string]interface{}{
+				"data":         base64.StdEncoding.EncodeToString(v),
+				"content_type": "text/plain",
+			},
+		}}
+	}
+
+	if rev != "" {
+		doc[revField] = rev
+	}
+
+	return doc
+}
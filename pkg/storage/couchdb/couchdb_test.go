@@ -0,0 +1,237 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package couchdb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+func TestStore(t *testing.T) {
+	couchDB := newFakeCouchDB()
+
+	server := httptest.NewServer(couchDB)
+	defer server.Close()
+
+	p, err := NewProvider(server.URL, WithDBPrefix("test"))
+	require.NoError(t, err)
+
+	require.NoError(t, p.CreateStore("store1"))
+
+	store, err := p.OpenStore("store1")
+	require.NoError(t, err)
+	require.True(t, conditional.Supported(store))
+
+	conditionalStore := conditional.New(store)
+
+	t.Run("test put if absent", func(t *testing.T) {
+		require.NoError(t, conditionalStore.PutIfAbsent("key1", []byte(`{"consumed":false}`)))
+		require.Equal(t, conditional.ErrConflict, conditionalStore.PutIfAbsent("key1", []byte(`{"consumed":true}`)))
+
+		v, err := store.Get("key1")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"consumed":false}`, string(v))
+	})
+
+	t.Run("test replace", func(t *testing.T) {
+		old, err := store.Get("key1")
+		require.NoError(t, err)
+
+		require.NoError(t, conditionalStore.Replace("key1", old, []byte(`{"consumed":true}`)))
+		require.Equal(t, conditional.ErrConflict, conditionalStore.Replace("key1", old, []byte(`{"consumed":true}`)))
+		require.Equal(t, conditional.ErrConflict, conditionalStore.Replace("key2", old, []byte(`{"consumed":true}`)))
+
+		v, err := store.Get("key1")
+		require.NoError(t, err)
+		require.JSONEq(t, `{"consumed":true}`, string(v))
+	})
+
+	t.Run("test replace attached value", func(t *testing.T) {
+		require.NoError(t, conditionalStore.PutIfAbsent("count", []byte("1")))
+		require.NoError(t, conditionalStore.Replace("count", []byte("1"), []byte("2")))
+		require.Equal(t, conditional.ErrConflict, conditionalStore.Replace("count", []byte("1"), []byte("3")))
+
+		v, err := store.Get("count")
+		require.NoError(t, err)
+		require.Equal(t, []byte("2"), v)
+	})
+
+	t.Run("test replace of a concurrent update", func(t *testing.T) {
+		// the document is updated by another instance between the comparison and the update
+		couchDB.beforePut = func() {
+			couchDB.beforePut = nil
+			require.NoError(t, store.Put("count", []byte("5")))
+		}
+
+		require.Equal(t, conditional.ErrConflict, conditionalStore.Replace("count", []byte("2"), []byte("3")))
+
+		v, err := store.Get("count")
+		require.NoError(t, err)
+		require.Equal(t, []byte("5"), v)
+	})
+
+	t.Run("test errors", func(t *testing.T) {
+		couchDB.fail = true
+		defer func() { couchDB.fail = false }()
+
+		err := conditionalStore.PutIfAbsent("key3", []byte("1"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store data")
+
+		err = conditionalStore.Replace("key1", []byte("1"), []byte("2"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get data")
+	})
+
+	t.Run("test open store error", func(t *testing.T) {
+		_, err := p.OpenStore("store2")
+		require.Equal(t, storage.ErrStoreNotFound, err)
+	})
+}
+
+func TestNewProvider(t *testing.T) {
+	_, err := NewProvider("")
+	require.Error(t, err)
+}
+
+func TestDocument(t *testing.T) {
+	require.Equal(t, map[string]interface{}{"consumed": true, "_rev": "1-a"}, document([]byte(`{"consumed":true}`), "1-a"))
+
+	doc := document([]byte("null"), "")
+	require.Equal(t, base64.StdEncoding.EncodeToString([]byte("null")),
+		doc[attachmentsField].(map[string]interface{})[attachmentName].(map[string]interface{})["data"])
+}
+
+// fakeCouchDB serves the documents of the databases and their revisions, as CouchDB does for the requests of the
+// stores
+type fakeCouchDB struct {
+	mutex     sync.Mutex
+	dbs       map[string]map[string]map[string]interface{}
+	beforePut func()
+	fail      bool
+}
+
+func newFakeCouchDB() *fakeCouchDB {
+	return &fakeCouchDB{dbs: make(map[string]map[string]map[string]interface{})}
+}
+
+func (c *fakeCouchDB) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if c.fail {
+		writeJSON(rw, http.StatusInternalServerError, map[string]string{"error": "unknown", "reason": "failure"})
+
+		return
+	}
+
+	if beforePut := c.beforePut; beforePut != nil && req.Method == http.MethodPut {
+		beforePut()
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+
+	db, ok := c.dbs[parts[0]]
+
+	switch {
+	case len(parts) == 1 && req.Method == http.MethodPut:
+		c.dbs[parts[0]] = make(map[string]map[string]interface{})
+
+		writeJSON(rw, http.StatusCreated, map[string]bool{"ok": true})
+	case len(parts) == 1 && ok:
+		writeJSON(rw, http.StatusOK, map[string]string{"db_name": parts[0]})
+	case !ok:
+		writeJSON(rw, http.StatusNotFound, map[string]string{"error": "not_found", "reason": "missing"})
+	case len(parts) == 2 && req.Method == http.MethodPut:
+		c.putDocument(rw, req, db, parts[1])
+	case len(parts) == 2:
+		c.getDocument(rw, db[parts[1]])
+	default:
+		c.getAttachment(rw, db[parts[1]])
+	}
+}
+
+func (c *fakeCouchDB) putDocument(rw http.ResponseWriter, req *http.Request, db map[string]map[string]interface{},
+	id string) {
+	doc := make(map[string]interface{})
+
+	if err := json.NewDecoder(req.Body).Decode(&doc); err != nil {
+		writeJSON(rw, http.StatusBadRequest, map[string]string{"error": "bad_request", "reason": err.Error()})
+
+		return
+	}
+
+	stored, exists := db[id]
+
+	rev, _ := doc[revField].(string) // nolint: errcheck
+	if exists && rev != stored[revField] || !exists && rev != "" {
+		writeJSON(rw, http.StatusConflict, map[string]string{"error": "conflict", "reason": "Document update conflict."})
+
+		return
+	}
+
+	generation := 1
+	if exists {
+		fmt.Sscanf(rev, "%d-", &generation) // nolint: errcheck,gosec
+		generation++
+	}
+
+	doc[idField] = id
+	doc[revField] = fmt.Sprintf("%d-%s", generation, id)
+	db[id] = doc
+
+	writeJSON(rw, http.StatusCreated, map[string]interface{}{"ok": true, "id": id, "rev": doc[revField]})
+}
+
+func (c *fakeCouchDB) getDocument(rw http.ResponseWriter, doc map[string]interface{}) {
+	if doc == nil {
+		writeJSON(rw, http.StatusNotFound, map[string]string{"error": "not_found", "reason": "missing"})
+
+		return
+	}
+
+	rw.Header().Set("ETag", fmt.Sprintf("%q", doc[revField]))
+	writeJSON(rw, http.StatusOK, doc)
+}
+
+func (c *fakeCouchDB) getAttachment(rw http.ResponseWriter, doc map[string]interface{}) {
+	attachments, ok := doc[attachmentsField].(map[string]interface{})
+	if !ok {
+		writeJSON(rw, http.StatusNotFound, map[string]string{"error": "not_found", "reason": "missing"})
+
+		return
+	}
+
+	data, err := base64.StdEncoding.DecodeString(
+		attachments[attachmentName].(map[string]interface{})["data"].(string))
+	if err != nil {
+		panic(err)
+	}
+
+	rw.Header().Set("Content-Type", "text/plain")
+	rw.Header().Set("ETag", `"md5-data"`)
+	rw.WriteHeader(http.StatusOK)
+
+	rw.Write(data) // nolint: errcheck,gosec
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+
+	json.NewEncoder(rw).Encode(v) // nolint: errcheck,gosec
+}
@@ -0,0 +1,477 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package eventlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/edge-core/pkg/storage"
+)
+
+const (
+	logStore = "eventlog"
+
+	headKey           = "head"
+	snapshotsKey      = "snapshots"
+	eventKeyPrefix    = "event_"
+	snapshotKeyPrefix = "snapshot_"
+
+	// DefaultSnapshotInterval is the number of events between the snapshots of the recorded stores
+	DefaultSnapshotInterval = 10000
+
+	snapshotChunkSize  = 1000
+	maxAppendAttempts  = 10
+	sequenceKeyPattern = "%020d"
+)
+
+var logger = log.New("edge-service-eventlog")
+
+// Event is a mutation of a recorded store: the value put under the key of the store. The events are numbered in
+// the order they are appended to the log, from 1.
+type Event struct {
+	Sequence uint64    `json:"sequence"`
+	ID       string    `json:"id"`
+	Time     time.Time `json:"time"`
+	Store    string    `json:"store"`
+	Key      string    `json:"key"`
+	Value    []byte    `json:"value"`
+}
+
+// Point is a point in time of the log: the replay stops after the event of the sequence and before the first
+// event appended after the time. The zero value is the head of the log.
+type Point struct {
+	Sequence uint64
+	Time     time.Time
+}
+
+// Snapshot is the state of the recorded stores after the event of the sequence, by store and key
+type Snapshot struct {
+	Sequence uint64
+	Time     time.Time
+	Stores   map[string]map[string][]byte
+}
+
+// snapshotInfo is the stored header of a snapshot, its entries being stored in chunks
+type snapshotInfo struct {
+	Sequence uint64    `json:"sequence"`
+	Time     time.Time `json:"time"`
+	Chunks   int       `json:"chunks"`
+}
+
+type snapshotEntry struct {
+	Store string `json:"store"`
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// Option configures the event log
+type Option func(l *Log)
+
+// WithSnapshotInterval option sets the number of events between the snapshots, taken in the background after the
+// event completing the interval is appended. Snapshots aren't taken if the interval is 0.
+func WithSnapshotInterval(interval uint64) Option {
+	return func(l *Log) {
+		l.snapshotInterval = interval
+	}
+}
+
+// Log is the ordered log of the mutations of the recorded stores, from which the state of the stores at a point in
+// time can be rebuilt. The snapshots of the state taken at intervals spare the replay of the whole log.
+//
+// An event is stored under its sequence, before the head of the log. The log mutex serializes the sequencing, so
+// the events appended by an instance are ordered. The instances sharing the storage provider skip the sequences
+// taken by another instance, and detect a concurrent append of the same sequence by reading back the event ID
+// they wrote, the last write winning. A storage without read-your-writes consistency could still lose an event
+// appended at the same time by two instances.
+type Log struct {
+	store            storage.Store
+	snapshotInterval uint64
+	mutex            sync.Mutex
+	snapshots        sync.WaitGroup
+	now              func() time.Time
+}
+
+// New returns the event log stored by the provider
+func New(provider storage.Provider, opts ...Option) (*Log, error) {
+	err := provider.CreateStore(logStore)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, err
+	}
+
+	store, err := provider.OpenStore(logStore)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{store: store, snapshotInterval: DefaultSnapshotInterval, now: time.Now}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l, nil
+}
+
+// Append appends the value put under the key of the store to the log
+func (l *Log) Append(store, key string, value []byte) (*Event, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	head, err := l.head()
+	if err != nil {
+		return nil, err
+	}
+
+	event := &Event{ID: uuid.New().String(), Store: store, Key: key, Value: value}
+
+	for attempt := 0; attempt < maxAppendAttempts; attempt++ {
+		event.Sequence = head + 1
+		event.Time = l.now().UTC()
+
+		appended, err := l.tryAppend(event)
+		if err != nil {
+			return nil, err
+		}
+
+		if !appended {
+			head++
+
+			continue
+		}
+
+		if err := l.store.Put(headKey, []byte(strconv.FormatUint(event.Sequence, 10))); err != nil {
+			return nil, fmt.Errorf("failed to store event log head: %w", err)
+		}
+
+		if l.snapshotInterval > 0 && event.Sequence%l.snapshotInterval == 0 {
+			l.snapshots.Add(1)
+
+			go func(sequence uint64) {
+				defer l.snapshots.Done()
+
+				if err := l.Snapshot(sequence); err != nil {
+					logger.Errorf("failed to take snapshot of event %d: %s", sequence, err)
+				}
+			}(event.Sequence)
+		}
+
+		return event, nil
+	}
+
+	return nil, fmt.Errorf("failed to append event: sequence %d taken by other instances", event.Sequence)
+}
+
+// tryAppend stores the event under its sequence, false if the sequence is taken by another instance
+func (l *Log) tryAppend(event *Event) (bool, error) {
+	stored, err := l.get(event.Sequence)
+	if err != nil && !errors.Is(err, storage.ErrValueNotFound) {
+		return false, err
+	}
+
+	if stored != nil {
+		return false, nil
+	}
+
+	if err := l.put(event); err != nil {
+		return false, err
+	}
+
+	// another instance appending the same sequence at the same time overwrites the event
+	stored, err = l.get(event.Sequence)
+	if err != nil {
+		return false, err
+	}
+
+	return stored.ID == event.ID, nil
+}
+
+// Replay calls fn with the events of the log from the sequence up to the point, in order
+func (l *Log) Replay(from uint64, to Point, fn func(event *Event) error) error {
+	if from == 0 {
+		from = 1
+	}
+
+	for sequence := from; to.Sequence == 0 || sequence <= to.Sequence; sequence++ {
+		event, err := l.get(sequence)
+		if errors.Is(err, storage.ErrValueNotFound) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !to.Time.IsZero() && event.Time.After(to.Time) {
+			return nil
+		}
+
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// State returns the state of the recorded stores at the point, replaying the events from the latest snapshot
+// taken before the point
+func (l *Log) State(to Point) (*Snapshot, error) {
+	infos, err := l.snapshotInfos()
+	if err != nil {
+		return nil, err
+	}
+
+	state := &Snapshot{Stores: make(map[string]map[string][]byte)}
+
+	for i := len(infos) - 1; i >= 0; i-- {
+		info := infos[i]
+
+		if (to.Sequence == 0 || info.Sequence <= to.Sequence) && (to.Time.IsZero() || !info.Time.After(to.Time)) {
+			if state, err = l.getSnapshot(info); err != nil {
+				return nil, err
+			}
+
+			break
+		}
+	}
+
+	err = l.Replay(state.Sequence+1, to, func(event *Event) error {
+		state.apply(event)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// Snapshot takes the snapshot of the state of the recorded stores after the event of the sequence
+func (l *Log) Snapshot(sequence uint64) error {
+	state, err := l.State(Point{Sequence: sequence})
+	if err != nil {
+		return err
+	}
+
+	if state.Sequence != sequence {
+		return fmt.Errorf("event %d not found", sequence)
+	}
+
+	if err := l.putSnapshot(state); err != nil {
+		return err
+	}
+
+	logger.Infof("took snapshot of event %d", sequence)
+
+	return nil
+}
+
+// Close waits for the snapshots being taken
+func (l *Log) Close() {
+	l.snapshots.Wait()
+}
+
+func (l *Log) head() (uint64, error) {
+	headBytes, err := l.store.Get(headKey)
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to get event log head: %w", err)
+	}
+
+	head, err := strconv.ParseUint(string(headBytes), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid event log head: %w", err)
+	}
+
+	return head, nil
+}
+
+func (l *Log) get(sequence uint64) (*Event, error) {
+	eventBytes, err := l.store.Get(eventKey(sequence))
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get event %d: %w", sequence, err)
+	}
+
+	event := &Event{}
+
+	if err := json.Unmarshal(eventBytes, event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal event %d: %w", sequence, err)
+	}
+
+	return event, nil
+}
+
+func (l *Log) put(event *Event) error {
+	eventBytes, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	if err := l.store.Put(eventKey(event.Sequence), eventBytes); err != nil {
+		return fmt.Errorf("failed to store event %d: %w", event.Sequence, err)
+	}
+
+	return nil
+}
+
+func (l *Log) snapshotInfos() ([]snapshotInfo, error) {
+	infosBytes, err := l.store.Get(snapshotsKey)
+	if errors.Is(err, storage.ErrValueNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshots: %w", err)
+	}
+
+	var infos []snapshotInfo
+
+	if err := json.Unmarshal(infosBytes, &infos); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal snapshots: %w", err)
+	}
+
+	return infos, nil
+}
+
+func (l *Log) getSnapshot(info snapshotInfo) (*Snapshot, error) {
+	state := &Snapshot{Sequence: info.Sequence, Time: info.Time, Stores: make(map[string]map[string][]byte)}
+
+	for i := 0; i < info.Chunks; i++ {
+		chunkBytes, err := l.store.Get(snapshotChunkKey(info.Sequence, i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get snapshot %d: %w", info.Sequence, err)
+		}
+
+		var entries []snapshotEntry
+
+		if err := json.Unmarshal(chunkBytes, &entries); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal snapshot %d: %w", info.Sequence, err)
+		}
+
+		for _, entry := range entries {
+			state.put(entry.Store, entry.Key, entry.Value)
+		}
+	}
+
+	return state, nil
+}
+
+// putSnapshot stores the entries of the snapshot in chunks, then adds the snapshot to the snapshots. The snapshots
+// taken of the same event by several instances are the same.
+func (l *Log) putSnapshot(state *Snapshot) error {
+	entries := state.entries()
+	info := snapshotInfo{Sequence: state.Sequence, Time: state.Time}
+
+	for start := 0; start < len(entries); start += snapshotChunkSize {
+		end := start + snapshotChunkSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		chunkBytes, err := json.Marshal(entries[start:end])
+		if err != nil {
+			return fmt.Errorf("failed to marshal snapshot: %w", err)
+		}
+
+		if err := l.store.Put(snapshotChunkKey(state.Sequence, info.Chunks), chunkBytes); err != nil {
+			return fmt.Errorf("failed to store snapshot %d: %w", state.Sequence, err)
+		}
+
+		info.Chunks++
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	infos, err := l.snapshotInfos()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range infos {
+		if existing.Sequence == info.Sequence {
+			return nil
+		}
+	}
+
+	infos = append(infos, info)
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Sequence < infos[j].Sequence
+	})
+
+	infosBytes, err := json.Marshal(infos)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshots: %w", err)
+	}
+
+	if err := l.store.Put(snapshotsKey, infosBytes); err != nil {
+		return fmt.Errorf("failed to store snapshots: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Snapshot) apply(event *Event) {
+	s.put(event.Store, event.Key, event.Value)
+	s.Sequence = event.Sequence
+	s.Time = event.Time
+}
+
+func (s *Snapshot) put(store, key string, value []byte) {
+	values, ok := s.Stores[store]
+	if !ok {
+		values = make(map[string][]byte)
+		s.Stores[store] = values
+	}
+
+	values[key] = value
+}
+
+// entries returns the entries of the snapshot sorted by store and key
+func (s *Snapshot) entries() []snapshotEntry {
+	var entries []snapshotEntry
+
+	for store, values := range s.Stores {
+		for key, value := range values {
+			entries = append(entries, snapshotEntry{Store: store, Key: key, Value: value})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Store != entries[j].Store {
+			return entries[i].Store < entries[j].Store
+		}
+
+		return entries[i].Key < entries[j].Key
+	})
+
+	return entries
+}
+
+func eventKey(sequence uint64) string {
+	return eventKeyPrefix + fmt.Sprintf(sequenceKeyPattern, sequence)
+}
+
+func snapshotChunkKey(sequence uint64, chunk int) string {
+	return fmt.Sprintf(snapshotKeyPrefix+sequenceKeyPattern+"_%d", sequence, chunk)
+}
@@ -0,0 +1,229 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package eventlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+)
+
+func newLog(t *testing.T, provider storage.Provider, opts ...Option) *Log {
+	l, err := New(provider, opts...)
+	require.NoError(t, err)
+
+	now := time.Date(2020, time.October, 16, 10, 0, 0, 0, time.UTC)
+
+	l.now = func() time.Time {
+		now = now.Add(time.Minute)
+
+		return now
+	}
+
+	return l
+}
+
+func TestNew(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		l, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+		require.Equal(t, uint64(DefaultSnapshotInterval), l.snapshotInterval)
+	})
+
+	t.Run("test error from create store", func(t *testing.T) {
+		l, err := New(&mockstore.Provider{ErrCreateStore: fmt.Errorf("error create")})
+		require.EqualError(t, err, "error create")
+		require.Nil(t, l)
+	})
+
+	t.Run("test error from open store", func(t *testing.T) {
+		l, err := New(&mockstore.Provider{ErrOpenStoreHandle: fmt.Errorf("error open")})
+		require.EqualError(t, err, "error open")
+		require.Nil(t, l)
+	})
+}
+
+func TestLog_Append(t *testing.T) {
+	t.Run("test events sequenced", func(t *testing.T) {
+		l := newLog(t, memstore.NewProvider(), WithSnapshotInterval(0))
+
+		for i := 1; i <= 3; i++ {
+			event, err := l.Append("credential", fmt.Sprintf("key%d", i), []byte("value"))
+			require.NoError(t, err)
+			require.Equal(t, uint64(i), event.Sequence)
+		}
+
+		var events []*Event
+
+		require.NoError(t, l.Replay(0, Point{}, func(event *Event) error {
+			events = append(events, event)
+
+			return nil
+		}))
+
+		require.Len(t, events, 3)
+		require.Equal(t, "credential", events[2].Store)
+		require.Equal(t, "key3", events[2].Key)
+		require.Equal(t, []byte("value"), events[2].Value)
+	})
+
+	t.Run("test sequence taken by another instance skipped", func(t *testing.T) {
+		provider := memstore.NewProvider()
+		l := newLog(t, provider)
+		other := newLog(t, provider)
+
+		_, err := l.Append("credential", "key1", []byte("value"))
+		require.NoError(t, err)
+
+		// the other instance appended without updating the head yet
+		require.NoError(t, other.put(&Event{Sequence: 2, ID: "other"}))
+
+		event, err := l.Append("credential", "key2", []byte("value"))
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), event.Sequence)
+	})
+
+	t.Run("test concurrent append of the sequence detected", func(t *testing.T) {
+		s := &overwritingStore{MockStore: &mockstore.MockStore{Store: make(map[string][]byte)}}
+		l := newLog(t, &mockstore.Provider{Store: s.MockStore})
+		l.store = s
+
+		event, err := l.Append("credential", "key1", []byte("value"))
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), event.Sequence)
+	})
+
+	t.Run("test error from get head", func(t *testing.T) {
+		l := newLog(t, &mockstore.Provider{Store: &mockstore.MockStore{
+			Store: map[string][]byte{headKey: []byte("1")}, ErrGet: errors.New("get error")}})
+
+		event, err := l.Append("credential", "key1", []byte("value"))
+		require.EqualError(t, err, "failed to get event log head: get error")
+		require.Nil(t, event)
+	})
+
+	t.Run("test invalid head", func(t *testing.T) {
+		l := newLog(t, &mockstore.Provider{Store: &mockstore.MockStore{
+			Store: map[string][]byte{headKey: []byte("head")}}})
+
+		_, err := l.Append("credential", "key1", []byte("value"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid event log head")
+	})
+
+	t.Run("test error from put", func(t *testing.T) {
+		l := newLog(t, &mockstore.Provider{Store: &mockstore.MockStore{Store: make(map[string][]byte),
+			ErrPut: errors.New("put error")}})
+
+		_, err := l.Append("credential", "key1", []byte("value"))
+		require.EqualError(t, err, "failed to store event 1: put error")
+	})
+}
+
+// overwritingStore overwrites the first event put, as another instance appending the same sequence
+type overwritingStore struct {
+	*mockstore.MockStore
+	overwritten bool
+}
+
+func (s *overwritingStore) Put(k string, v []byte) error {
+	if err := s.MockStore.Put(k, v); err != nil {
+		return err
+	}
+
+	if k == eventKey(1) && !s.overwritten {
+		s.overwritten = true
+
+		eventBytes, err := json.Marshal(&Event{Sequence: 1, ID: "other"})
+		if err != nil {
+			return err
+		}
+
+		return s.MockStore.Put(k, eventBytes)
+	}
+
+	return nil
+}
+
+func TestLog_State(t *testing.T) {
+	l := newLog(t, memstore.NewProvider(), WithSnapshotInterval(2))
+
+	for i := 1; i <= 5; i++ {
+		_, err := l.Append("credentialstatus", "csl", []byte(fmt.Sprintf("v%d", i)))
+		require.NoError(t, err)
+
+		_, err = l.Append("credential", fmt.Sprintf("profile%d", i), []byte("profile"))
+		require.NoError(t, err)
+	}
+
+	l.Close()
+
+	infos, err := l.snapshotInfos()
+	require.NoError(t, err)
+	require.Len(t, infos, 5)
+
+	t.Run("test state at the head", func(t *testing.T) {
+		state, err := l.State(Point{})
+		require.NoError(t, err)
+		require.Equal(t, uint64(10), state.Sequence)
+		require.Equal(t, []byte("v5"), state.Stores["credentialstatus"]["csl"])
+		require.Len(t, state.Stores["credential"], 5)
+	})
+
+	t.Run("test state at a sequence", func(t *testing.T) {
+		state, err := l.State(Point{Sequence: 5})
+		require.NoError(t, err)
+		require.Equal(t, uint64(5), state.Sequence)
+		require.Equal(t, []byte("v3"), state.Stores["credentialstatus"]["csl"])
+		require.Len(t, state.Stores["credential"], 2)
+	})
+
+	t.Run("test state at a time", func(t *testing.T) {
+		state, err := l.State(Point{Time: infos[1].Time.Add(30 * time.Second)})
+		require.NoError(t, err)
+		require.Equal(t, uint64(4), state.Sequence)
+		require.Equal(t, []byte("v2"), state.Stores["credentialstatus"]["csl"])
+	})
+
+	t.Run("test state before the first event", func(t *testing.T) {
+		state, err := l.State(Point{Time: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)})
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), state.Sequence)
+		require.Empty(t, state.Stores)
+	})
+
+	t.Run("test snapshot of a missing event", func(t *testing.T) {
+		require.EqualError(t, l.Snapshot(11), "event 11 not found")
+	})
+}
+
+func TestSnapshotChunks(t *testing.T) {
+	l := newLog(t, memstore.NewProvider(), WithSnapshotInterval(0))
+
+	for i := 0; i < snapshotChunkSize+1; i++ {
+		_, err := l.Append("statushistory", fmt.Sprintf("key%d", i), []byte("value"))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, l.Snapshot(snapshotChunkSize+1))
+	// taken again by another instance
+	require.NoError(t, l.Snapshot(snapshotChunkSize+1))
+
+	infos, err := l.snapshotInfos()
+	require.NoError(t, err)
+	require.Equal(t, []snapshotInfo{{Sequence: snapshotChunkSize + 1, Time: infos[0].Time, Chunks: 2}}, infos)
+
+	state, err := l.getSnapshot(infos[0])
+	require.NoError(t, err)
+	require.Len(t, state.Stores["statushistory"], snapshotChunkSize+1)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package eventlog
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+// Provider is a storage.Provider recording the mutations of the named stores in the event log
+type Provider struct {
+	storage.Provider
+	log    *Log
+	stores map[string]bool
+}
+
+// NewProvider returns a provider recording the mutations of the named stores of the provider in the log
+func NewProvider(provider storage.Provider, l *Log, stores ...string) *Provider {
+	p := &Provider{Provider: provider, log: l, stores: make(map[string]bool)}
+
+	for _, store := range stores {
+		p.stores[store] = true
+	}
+
+	return p
+}
+
+// OpenStore opens the store, recording its mutations if it is one of the named stores
+func (p *Provider) OpenStore(name string) (storage.Store, error) {
+	store, err := p.Provider.OpenStore(name)
+	if err != nil || !p.stores[name] {
+		return store, err
+	}
+
+	return &recordedStore{Store: store, name: name, log: p.log}, nil
+}
+
+// recordedStore appends the values put in the store to the log before storing them. A value that fails to be
+// stored stays in the log, the callers retrying the failed mutations.
+type recordedStore struct {
+	storage.Store
+	name string
+	log  *Log
+}
+
+func (s *recordedStore) Put(k string, v []byte) error {
+	if err := s.record(k, v); err != nil {
+		return err
+	}
+
+	return s.Store.Put(k, v)
+}
+
+// PutIfAbsent stores the value with the conditional write of the store, then appends it to the log: the values of
+// the conditional writes that fail aren't recorded, as they would be replayed over the values that were stored.
+func (s *recordedStore) PutIfAbsent(k string, v []byte) error {
+	if err := conditional.New(s.Store).PutIfAbsent(k, v); err != nil {
+		return err
+	}
+
+	return s.record(k, v)
+}
+
+// Replace replaces the value with the conditional write of the store, then appends it to the log. A value that fails
+// to be recorded stays stored, the next value stored for the key being recorded.
+func (s *recordedStore) Replace(k string, old, v []byte) error {
+	if err := conditional.New(s.Store).Replace(k, old, v); err != nil {
+		return err
+	}
+
+	return s.record(k, v)
+}
+
+// record appends the value of the key to the log
+func (s *recordedStore) record(k string, v []byte) error {
+	if _, err := s.log.Append(s.name, k, v); err != nil {
+		return fmt.Errorf("failed to record mutation of %s: %w", s.name, err)
+	}
+
+	return nil
+}
+
+// Rebuild stores the state of the recorded stores at the point in the stores of the target provider, along with
+// the events up to the point and the snapshot of the state. The target provider is expected to be empty, e.g. a new
+// database prefix the service is switched to once rebuilt.
+func Rebuild(l *Log, to Point, target storage.Provider) (*Snapshot, error) {
+	state, err := l.State(to)
+	if err != nil {
+		return nil, err
+	}
+
+	targetLog, err := New(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open target event log: %w", err)
+	}
+
+	err = l.Replay(1, Point{Sequence: state.Sequence}, targetLog.put)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Sequence > 0 {
+		if err := targetLog.putSnapshot(state); err != nil {
+			return nil, err
+		}
+
+		if err := targetLog.store.Put(headKey, []byte(strconv.FormatUint(state.Sequence, 10))); err != nil {
+			return nil, fmt.Errorf("failed to store event log head: %w", err)
+		}
+	}
+
+	for name, values := range state.Stores {
+		if err := target.CreateStore(name); err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+			return nil, fmt.Errorf("failed to create store %s: %w", name, err)
+		}
+
+		store, err := target.OpenStore(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open store %s: %w", name, err)
+		}
+
+		for k, v := range values {
+			if err := store.Put(k, v); err != nil {
+				return nil, fmt.Errorf("failed to store %s of store %s: %w", k, name, err)
+			}
+		}
+	}
+
+	return state, nil
+}
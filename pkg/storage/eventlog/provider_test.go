@@ -0,0 +1,177 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package eventlog
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+func TestProvider(t *testing.T) {
+	t.Run("test recorded stores", func(t *testing.T) {
+		l := newLog(t, memstore.NewProvider())
+		p := NewProvider(memstore.NewProvider(), l, "credential")
+
+		require.NoError(t, p.CreateStore("credential"))
+		require.NoError(t, p.CreateStore("generatedkeys"))
+
+		recorded, err := p.OpenStore("credential")
+		require.NoError(t, err)
+
+		other, err := p.OpenStore("generatedkeys")
+		require.NoError(t, err)
+
+		require.NoError(t, recorded.Put("profile_issuer_test", []byte("profile")))
+		require.NoError(t, other.Put("key", []byte("value")))
+
+		v, err := recorded.Get("profile_issuer_test")
+		require.NoError(t, err)
+		require.Equal(t, []byte("profile"), v)
+
+		state, err := l.State(Point{})
+		require.NoError(t, err)
+		require.Equal(t, map[string]map[string][]byte{"credential": {"profile_issuer_test": []byte("profile")}},
+			state.Stores)
+	})
+
+	t.Run("test recorded conditional writes", func(t *testing.T) {
+		l := newLog(t, memstore.NewProvider())
+		p := NewProvider(memstore.NewProvider(), l, "credentialstatus")
+
+		require.NoError(t, p.CreateStore("credentialstatus"))
+
+		store, err := p.OpenStore("credentialstatus")
+		require.NoError(t, err)
+
+		recorded := conditional.New(store)
+
+		require.NoError(t, recorded.PutIfAbsent("csl", []byte("active")))
+		require.True(t, errors.Is(recorded.PutIfAbsent("csl", []byte("suspended")), conditional.ErrConflict))
+		require.NoError(t, recorded.Replace("csl", []byte("active"), []byte("revoked")))
+		require.True(t, errors.Is(recorded.Replace("csl", []byte("active"), []byte("suspended")),
+			conditional.ErrConflict))
+
+		// the conditional writes that failed aren't recorded
+		state, err := l.State(Point{})
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), state.Sequence)
+		require.Equal(t, map[string]map[string][]byte{"credentialstatus": {"csl": []byte("revoked")}}, state.Stores)
+	})
+
+	t.Run("test error from open store", func(t *testing.T) {
+		p := NewProvider(&mockstore.Provider{ErrOpenStoreHandle: errors.New("open error")}, nil, "credential")
+
+		store, err := p.OpenStore("credential")
+		require.EqualError(t, err, "open error")
+		require.Nil(t, store)
+	})
+
+	t.Run("test error from append", func(t *testing.T) {
+		l := newLog(t, &mockstore.Provider{Store: &mockstore.MockStore{Store: make(map[string][]byte),
+			ErrPut: errors.New("put error")}})
+		p := NewProvider(memstore.NewProvider(), l, "credential")
+
+		require.NoError(t, p.CreateStore("credential"))
+
+		store, err := p.OpenStore("credential")
+		require.NoError(t, err)
+
+		err = store.Put("profile_issuer_test", []byte("profile"))
+		require.EqualError(t, err, "failed to record mutation of credential: failed to store event 1: put error")
+
+		_, err = store.Get("profile_issuer_test")
+		require.True(t, errors.Is(err, storage.ErrValueNotFound))
+	})
+}
+
+func TestRebuild(t *testing.T) {
+	l := newLog(t, memstore.NewProvider(), WithSnapshotInterval(2))
+	p := NewProvider(memstore.NewProvider(), l, "credential", "credentialstatus")
+
+	for _, name := range []string{"credential", "credentialstatus"} {
+		require.NoError(t, p.CreateStore(name))
+	}
+
+	profiles, err := p.OpenStore("credential")
+	require.NoError(t, err)
+
+	status, err := p.OpenStore("credentialstatus")
+	require.NoError(t, err)
+
+	require.NoError(t, profiles.Put("profile_issuer_test", []byte("profile")))
+	require.NoError(t, status.Put("csl", []byte("active")))
+	require.NoError(t, status.Put("csl", []byte("revoked")))
+	require.NoError(t, profiles.Put("profile_issuer_test", []byte{}))
+
+	l.Close()
+
+	t.Run("test rebuild at a sequence", func(t *testing.T) {
+		target := memstore.NewProvider()
+
+		state, err := Rebuild(l, Point{Sequence: 3}, target)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3), state.Sequence)
+
+		store, err := target.OpenStore("credential")
+		require.NoError(t, err)
+
+		v, err := store.Get("profile_issuer_test")
+		require.NoError(t, err)
+		require.Equal(t, []byte("profile"), v)
+
+		store, err = target.OpenStore("credentialstatus")
+		require.NoError(t, err)
+
+		v, err = store.Get("csl")
+		require.NoError(t, err)
+		require.Equal(t, []byte("revoked"), v)
+
+		// the rebuilt log continues after the point
+		targetLog := newLog(t, target)
+
+		event, err := targetLog.Append("credential", "profile_issuer_other", []byte("profile"))
+		require.NoError(t, err)
+		require.Equal(t, uint64(4), event.Sequence)
+
+		targetState, err := targetLog.State(Point{Sequence: 3})
+		require.NoError(t, err)
+		require.Equal(t, state.Stores, targetState.Stores)
+	})
+
+	t.Run("test rebuild of an empty log", func(t *testing.T) {
+		state, err := Rebuild(newLog(t, memstore.NewProvider()), Point{}, memstore.NewProvider())
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), state.Sequence)
+	})
+
+	t.Run("test error from target", func(t *testing.T) {
+		state, err := Rebuild(l, Point{}, &mockstore.Provider{ErrCreateStore: errors.New("create error")})
+		require.EqualError(t, err, "failed to open target event log: create error")
+		require.Nil(t, state)
+	})
+
+	t.Run("test error from the store of the target", func(t *testing.T) {
+		target := &mockstore.Provider{Store: &mockstore.MockStore{Store: make(map[string][]byte)}}
+
+		// the target log is stored, the store put fails
+		_, err := Rebuild(l, Point{}, target)
+		require.NoError(t, err)
+
+		target.Store.ErrPut = errors.New("put error")
+
+		state, err := Rebuild(l, Point{}, target)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "put error")
+		require.Nil(t, state)
+	})
+}
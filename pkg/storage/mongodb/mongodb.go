@@ -0,0 +1,494 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mongodb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+const (
+	defaultDatabaseName = "edgeservice"
+	defaultTimeout      = 10 * time.Second
+
+	// mongodb error codes
+	namespaceExistsErrCode = 48
+	duplicateKeyErrCode    = 11000
+
+	docField       = "doc"
+	createdAtField = "createdAt"
+
+	// profiles are looked up by name
+	nameIndexName  = "name"
+	nameIndexField = docField + ".name"
+	ttlIndexName   = "ttl"
+)
+
+var errBlankConnString = errors.New("connection string for new MongoDB provider can't be blank")
+
+// Option configures the MongoDB provider
+type Option func(p *Provider)
+
+// WithDBPrefix option is for adding prefix to the collection names
+func WithDBPrefix(dbPrefix string) Option {
+	return func(p *Provider) {
+		p.dbPrefix = dbPrefix
+	}
+}
+
+// WithDatabaseName option sets the name of the database holding the stores (collections)
+func WithDatabaseName(databaseName string) Option {
+	return func(p *Provider) {
+		p.databaseName = databaseName
+	}
+}
+
+// WithTimeout option sets the timeout of the MongoDB operations
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Provider) {
+		p.timeout = timeout
+	}
+}
+
+// WithNameIndex option indexes the name of the records of the given stores, e.g. the profile stores.
+func WithNameIndex(storeNames ...string) Option {
+	return func(p *Provider) {
+		for _, name := range storeNames {
+			p.nameIndexes[name] = true
+		}
+	}
+}
+
+// WithTTL option enables the expiry of the records of the given store, they are removed by MongoDB once
+// they weren't written for the ttl. To be used for transient records.
+func WithTTL(storeName string, ttl time.Duration) Option {
+	return func(p *Provider) {
+		p.ttls[storeName] = ttl
+	}
+}
+
+// Provider represents a MongoDB implementation of the storage.Provider interface. Each store is a collection.
+type Provider struct {
+	client       *mongo.Client
+	databaseName string
+	dbPrefix     string
+	timeout      time.Duration
+	nameIndexes  map[string]bool
+	ttls         map[string]time.Duration
+	dbs          map[string]*Store
+	mux          sync.RWMutex
+}
+
+// NewProvider instantiates Provider
+func NewProvider(connString string, opts ...Option) (*Provider, error) {
+	if connString == "" {
+		return nil, errBlankConnString
+	}
+
+	p := &Provider{
+		databaseName: defaultDatabaseName,
+		timeout:      defaultTimeout,
+		nameIndexes:  map[string]bool{},
+		ttls:         map[string]time.Duration{},
+		dbs:          map[string]*Store{},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	client, err := mongo.NewClient(options.Client().ApplyURI(connString).SetConnectTimeout(p.timeout).
+		SetServerSelectionTimeout(p.timeout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MongoDB client: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	err = client.Connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+
+	err = client.Ping(ctx, readpref.Primary())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+
+	p.client = client
+
+	return p, nil
+}
+
+// CreateStore creates a new store with the given name, along with its indexes.
+func (p *Provider) CreateStore(name string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	db := p.client.Database(p.databaseName)
+
+	var createErr error
+
+	err := db.RunCommand(ctx, bson.D{{Key: "create", Value: p.collectionName(name)}}).Err()
+	if err != nil {
+		var cmdErr mongo.CommandError
+		if !errors.As(err, &cmdErr) || cmdErr.Code != namespaceExistsErrCode {
+			return fmt.Errorf("failed to create store %s: %w", name, err)
+		}
+
+		createErr = storage.ErrDuplicateStore
+	}
+
+	// the indexes are also created if the store exists, e.g. created by a previous version
+	indexModels := p.indexModels(name)
+	if len(indexModels) == 0 {
+		return createErr
+	}
+
+	_, err = db.Collection(p.collectionName(name)).Indexes().CreateMany(ctx, indexModels)
+	if err != nil {
+		return fmt.Errorf("failed to create indexes of store %s: %w", name, err)
+	}
+
+	return createErr
+}
+
+// OpenStore opens an existing store with the given name and returns it.
+func (p *Provider) OpenStore(name string) (storage.Store, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	collectionName := p.collectionName(name)
+
+	if store, ok := p.dbs[collectionName]; ok {
+		return store, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	db := p.client.Database(p.databaseName)
+
+	names, err := db.ListCollectionNames(ctx, bson.D{{Key: "name", Value: collectionName}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if store %s exists: %w", name, err)
+	}
+
+	if len(names) == 0 {
+		return nil, storage.ErrStoreNotFound
+	}
+
+	_, withTTL := p.ttls[name]
+
+	store := &Store{collection: db.Collection(collectionName), timeout: p.timeout, withTTL: withTTL}
+
+	p.dbs[collectionName] = store
+
+	return store, nil
+}
+
+// CloseStore closes a previously opened store.
+func (p *Provider) CloseStore(name string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	collectionName := p.collectionName(name)
+
+	if _, ok := p.dbs[collectionName]; !ok {
+		return storage.ErrStoreNotFound
+	}
+
+	delete(p.dbs, collectionName)
+
+	return nil
+}
+
+// Close closes the provider and disconnects from MongoDB.
+func (p *Provider) Close() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.dbs = map[string]*Store{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	return p.client.Disconnect(ctx)
+}
+
+func (p *Provider) collectionName(name string) string {
+	if p.dbPrefix != "" {
+		return p.dbPrefix + "_" + name
+	}
+
+	return name
+}
+
+func (p *Provider) indexModels(name string) []mongo.IndexModel {
+	var indexModels []mongo.IndexModel
+
+	if p.nameIndexes[name] {
+		indexModels = append(indexModels, mongo.IndexModel{
+			Keys:    bson.D{{Key: nameIndexField, Value: 1}},
+			Options: options.Index().SetName(nameIndexName).SetSparse(true),
+		})
+	}
+
+	if ttl, ok := p.ttls[name]; ok {
+		indexModels = append(indexModels, mongo.IndexModel{
+			Keys:    bson.D{{Key: createdAtField, Value: 1}},
+			Options: options.Index().SetName(ttlIndexName).SetExpireAfterSeconds(int32(ttl.Seconds())),
+		})
+	}
+
+	return indexModels
+}
+
+// Store represents a MongoDB collection backed store. Values which are JSON objects are also stored as
+// documents so that they can be indexed and queried.
+type Store struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+	withTTL    bool
+}
+
+type document struct {
+	Key   string                 `bson:"_id"`
+	Value []byte                 `bson:"value"`
+	Doc   map[string]interface{} `bson:"doc,omitempty"`
+	// CreatedAt is the time the record was written, the records of the stores with a TTL expire from it
+	CreatedAt *time.Time `bson:"createdAt,omitempty"`
+}
+
+// Put stores the given key-value pair in the store.
+func (s *Store) Put(k string, v []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	_, err := s.collection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: k}}, s.newDocument(k, v),
+		options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to store data: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves the value in the store associated with the given key.
+func (s *Store) Get(k string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var doc document
+
+	err := s.collection.FindOne(ctx, bson.D{{Key: "_id", Value: k}}).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, storage.ErrValueNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+
+	return doc.Value, nil
+}
+
+// PutIfAbsent stores the given key-value pair unless the key has a value, the insert of the document failing on its
+// duplicate key.
+func (s *Store) PutIfAbsent(k string, v []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	_, err := s.collection.InsertOne(ctx, s.newDocument(k, v))
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return conditional.ErrConflict
+		}
+
+		return fmt.Errorf("failed to store data: %w", err)
+	}
+
+	return nil
+}
+
+// Replace replaces the value of the key if it is still the old value, the document being matched on its value.
+func (s *Store) Replace(k string, old, v []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	result, err := s.collection.ReplaceOne(ctx, replaceFilter(k, old), s.newDocument(k, v))
+	if err != nil {
+		return fmt.Errorf("failed to store data: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return conditional.ErrConflict
+	}
+
+	return nil
+}
+
+// CreateIndex creates an index based on the provided CreateIndexRequest.
+// createIndexRequest.IndexName is the name of the index.
+// createIndexRequest.WhatToIndex is the field of the stored JSON values to index, e.g. name.
+func (s *Store) CreateIndex(createIndexRequest storage.CreateIndexRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	_, err := s.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: docField + "." + createIndexRequest.WhatToIndex, Value: 1}},
+		Options: options.Index().SetName(createIndexRequest.IndexName).SetSparse(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create index %s: %w", createIndexRequest.IndexName, err)
+	}
+
+	return nil
+}
+
+// Query queries the stored JSON values. The query is a MongoDB filter (extended JSON) on the fields of the
+// values, e.g. {"name": "profile1"}.
+func (s *Store) Query(query string) (storage.ResultsIterator, error) {
+	filter, err := docFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	cursor, err := s.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query data: %w", err)
+	}
+
+	return &resultsIterator{cursor: cursor, timeout: s.timeout}, nil
+}
+
+func (s *Store) newDocument(k string, v []byte) *document {
+	doc := &document{Key: k, Value: v}
+
+	var jsonDoc map[string]interface{}
+	if json.Unmarshal(v, &jsonDoc) == nil {
+		doc.Doc = jsonDoc
+	}
+
+	if s.withTTL {
+		now := time.Now()
+		doc.CreatedAt = &now
+	}
+
+	return doc
+}
+
+// replaceFilter matches the document of the key with the old value
+func replaceFilter(k string, old []byte) bson.D {
+	return bson.D{{Key: "_id", Value: k}, {Key: "value", Value: old}}
+}
+
+func isDuplicateKeyError(err error) bool {
+	var writeErr mongo.WriteException
+	if !errors.As(err, &writeErr) {
+		return false
+	}
+
+	for _, e := range writeErr.WriteErrors {
+		if e.Code == duplicateKeyErrCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// docFilter parses the query and prefixes its fields with the doc field.
+func docFilter(query string) (bson.D, error) {
+	var filter bson.D
+
+	err := bson.UnmarshalExtJSON([]byte(query), false, &filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+
+	prefixFields(filter)
+
+	return filter, nil
+}
+
+// prefixFields prefixes the fields of the filter with the doc field, including the fields of the filters
+// combined by logical operators ($and, $or, $nor).
+func prefixFields(filter bson.D) {
+	for i := range filter {
+		if !strings.HasPrefix(filter[i].Key, "$") {
+			filter[i].Key = docField + "." + filter[i].Key
+
+			continue
+		}
+
+		if subFilters, ok := filter[i].Value.(bson.A); ok {
+			for _, subFilter := range subFilters {
+				if d, ok := subFilter.(bson.D); ok {
+					prefixFields(d)
+				}
+			}
+		}
+	}
+}
+
+type resultsIterator struct {
+	cursor  *mongo.Cursor
+	timeout time.Duration
+	current document
+}
+
+// Next moves the pointer to the next value in the iterator. It returns false if the iterator is exhausted.
+func (i *resultsIterator) Next() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), i.timeout)
+	defer cancel()
+
+	if !i.cursor.Next(ctx) {
+		return false, i.cursor.Err()
+	}
+
+	i.current = document{}
+
+	return true, i.cursor.Decode(&i.current)
+}
+
+// Release releases associated resources.
+func (i *resultsIterator) Release() error {
+	ctx, cancel := context.WithTimeout(context.Background(), i.timeout)
+	defer cancel()
+
+	return i.cursor.Close(ctx)
+}
+
+// Key returns the key of the current key-value pair.
+func (i *resultsIterator) Key() (string, error) {
+	return i.current.Key, nil
+}
+
+// Value returns the value of the current key-value pair.
+func (i *resultsIterator) Value() ([]byte, error) {
+	return i.current.Value, nil
+}
@@ -0,0 +1,143 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mongodb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestNewProvider(t *testing.T) {
+	t.Run("test error - blank connection string", func(t *testing.T) {
+		p, err := NewProvider("")
+		require.Equal(t, errBlankConnString, err)
+		require.Nil(t, p)
+	})
+
+	t.Run("test error - invalid connection string", func(t *testing.T) {
+		p, err := NewProvider("invalid://localhost")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create MongoDB client")
+		require.Nil(t, p)
+	})
+
+	t.Run("test error - server not reachable", func(t *testing.T) {
+		p, err := NewProvider("mongodb://localhost:1", WithTimeout(100*time.Millisecond))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to connect to MongoDB")
+		require.Nil(t, p)
+	})
+}
+
+func TestProvider_Options(t *testing.T) {
+	p := &Provider{nameIndexes: map[string]bool{}, ttls: map[string]time.Duration{}}
+
+	for _, opt := range []Option{WithDBPrefix("prefix"), WithDatabaseName("db"), WithTimeout(time.Second),
+		WithNameIndex("profile", "verifier"), WithTTL("challenge", time.Minute)} {
+		opt(p)
+	}
+
+	require.Equal(t, "prefix_profile", p.collectionName("profile"))
+	require.Equal(t, "db", p.databaseName)
+	require.Equal(t, time.Second, p.timeout)
+	require.Equal(t, map[string]bool{"profile": true, "verifier": true}, p.nameIndexes)
+	require.Equal(t, map[string]time.Duration{"challenge": time.Minute}, p.ttls)
+
+	require.Equal(t, storage.ErrStoreNotFound, p.CloseStore("profile"))
+}
+
+func TestProvider_IndexModels(t *testing.T) {
+	p := &Provider{nameIndexes: map[string]bool{}, ttls: map[string]time.Duration{}}
+
+	for _, opt := range []Option{WithNameIndex("profile"), WithTTL("challenge", time.Minute),
+		WithTTL("profile", time.Hour)} {
+		opt(p)
+	}
+
+	t.Run("name index", func(t *testing.T) {
+		indexModels := p.indexModels("profile")
+		require.Len(t, indexModels, 2)
+		require.Equal(t, nameIndexName, *indexModels[0].Options.Name)
+		require.Equal(t, bson.D{{Key: "doc.name", Value: 1}}, indexModels[0].Keys)
+		require.True(t, *indexModels[0].Options.Sparse)
+		require.Nil(t, indexModels[0].Options.ExpireAfterSeconds)
+	})
+
+	t.Run("ttl index", func(t *testing.T) {
+		indexModels := p.indexModels("challenge")
+		require.Len(t, indexModels, 1)
+		require.Equal(t, ttlIndexName, *indexModels[0].Options.Name)
+		require.Equal(t, bson.D{{Key: createdAtField, Value: 1}}, indexModels[0].Keys)
+		require.Equal(t, int32(60), *indexModels[0].Options.ExpireAfterSeconds)
+
+		require.Equal(t, int32(3600), *p.indexModels("profile")[1].Options.ExpireAfterSeconds)
+	})
+
+	t.Run("no index", func(t *testing.T) {
+		require.Empty(t, p.indexModels("credentialstatus"))
+	})
+}
+
+func TestStore_NewDocument(t *testing.T) {
+	doc := (&Store{}).newDocument("key1", []byte(`{"name":"profile1"}`))
+	require.Equal(t, "key1", doc.Key)
+	require.Equal(t, []byte(`{"name":"profile1"}`), doc.Value)
+	require.Equal(t, map[string]interface{}{"name": "profile1"}, doc.Doc)
+	require.Nil(t, doc.CreatedAt)
+
+	before := time.Now()
+
+	doc = (&Store{withTTL: true}).newDocument("key1", []byte("value1"))
+	require.Equal(t, []byte("value1"), doc.Value)
+	require.Nil(t, doc.Doc)
+	require.NotNil(t, doc.CreatedAt)
+	require.False(t, doc.CreatedAt.Before(before))
+
+	raw, err := bson.Marshal(doc)
+	require.NoError(t, err)
+	require.Equal(t, bsontype.DateTime, bson.Raw(raw).Lookup(createdAtField).Type)
+}
+
+func TestStore_ReplaceFilter(t *testing.T) {
+	filter := replaceFilter("key1", []byte("value1"))
+	require.Equal(t, bson.D{{Key: "_id", Value: "key1"}, {Key: "value", Value: []byte("value1")}}, filter)
+
+	// the filter matches the value as stored, a binary
+	raw, err := bson.Marshal(filter)
+	require.NoError(t, err)
+	require.Equal(t, bsontype.Binary, bson.Raw(raw).Lookup("value").Type)
+}
+
+func TestIsDuplicateKeyError(t *testing.T) {
+	require.True(t, isDuplicateKeyError(fmt.Errorf("insert: %w",
+		mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: duplicateKeyErrCode}}})))
+	require.False(t, isDuplicateKeyError(mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 2}}}))
+	require.False(t, isDuplicateKeyError(errors.New("insert error")))
+}
+
+func TestDocFilter(t *testing.T) {
+	filter, err := docFilter(`{"name": "profile1", "$or": [{"did": "did:example:1"}]}`)
+	require.NoError(t, err)
+	require.Equal(t, "doc.name", filter[0].Key)
+	require.Equal(t, "profile1", filter[0].Value)
+	require.Equal(t, "$or", filter[1].Key)
+	require.Equal(t, "doc.did", filter[1].Value.(bson.A)[0].(bson.D)[0].Key)
+
+	_, err = docFilter("{")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid query")
+
+	_, err = (&Store{}).Query("{")
+	require.Error(t, err)
+}
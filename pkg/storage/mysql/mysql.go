@@ -0,0 +1,390 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mysql
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/utils/retry"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+const (
+	driverName = "mysql"
+
+	// mysql error numbers
+	tableExistsErrNumber    = 1050
+	duplicateEntryErrNumber = 1062
+
+	createTableStmt = "CREATE TABLE `%s` (`key` VARCHAR(255) NOT NULL PRIMARY KEY, `value` MEDIUMBLOB)"
+	tableExistsStmt = "SELECT COUNT(*) FROM information_schema.tables " +
+		"WHERE table_schema = DATABASE() AND table_name = ?"
+	putStmt = "INSERT INTO `%s` (`key`, `value`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `value` = VALUES(`value`)"
+	getStmt = "SELECT `value` FROM `%s` WHERE `key` = ?"
+	// the conditional writes fail on the duplicate key, or match no row
+	insertStmt  = "INSERT INTO `%s` (`key`, `value`) VALUES (?, ?)"
+	replaceStmt = "UPDATE `%s` SET `value` = ? WHERE `key` = ? AND `value` = ?"
+	// the values matched by the query are JSON documents, the fields being compared as JSON values
+	queryStmt      = "SELECT `key`, `value` FROM `%s` WHERE JSON_VALID(`value`)%s ORDER BY `key`"
+	queryFieldCond = " AND JSON_EXTRACT(`value`, ?) = CAST(? AS JSON)"
+
+	defaultMaxRetries     = 5
+	defaultInitialBackoff = time.Second
+	defaultBackoffFactor  = 1.5
+)
+
+var (
+	errBlankDBURL        = errors.New("DB URL for new MySQL provider can't be blank")
+	errIndexNotSupported = errors.New("indexes are not supported by the MySQL store")
+)
+
+// Option configures the MySQL provider
+type Option func(p *Provider)
+
+// WithDBPrefix option is for adding prefix to the table names
+func WithDBPrefix(dbPrefix string) Option {
+	return func(p *Provider) {
+		p.dbPrefix = dbPrefix
+	}
+}
+
+// WithMaxOpenConns option sets the maximum number of open connections of the connection pool (0 is unlimited)
+func WithMaxOpenConns(maxOpenConns int) Option {
+	return func(p *Provider) {
+		p.db.SetMaxOpenConns(maxOpenConns)
+	}
+}
+
+// WithMaxIdleConns option sets the maximum number of idle connections kept in the connection pool
+func WithMaxIdleConns(maxIdleConns int) Option {
+	return func(p *Provider) {
+		p.db.SetMaxIdleConns(maxIdleConns)
+	}
+}
+
+// WithConnMaxLifetime option sets the maximum amount of time a pooled connection may be reused
+func WithConnMaxLifetime(connMaxLifetime time.Duration) Option {
+	return func(p *Provider) {
+		p.db.SetConnMaxLifetime(connMaxLifetime)
+	}
+}
+
+// WithRetryParams option sets how connecting to the database is retried when the provider is created
+func WithRetryParams(retryParams *retry.Params) Option {
+	return func(p *Provider) {
+		p.retryParams = retryParams
+	}
+}
+
+// Provider represents a MySQL implementation of the storage.Provider interface. Each store is a table
+// of the database referenced by the DB URL.
+type Provider struct {
+	db          *sql.DB
+	dbPrefix    string
+	retryParams *retry.Params
+	dbs         map[string]*Store
+	mux         sync.RWMutex
+}
+
+// NewProvider instantiates Provider. The DB URL is a MySQL data source name,
+// e.g. user:password@tcp(mysql:3306)/edgeservice.
+func NewProvider(dbURL string, opts ...Option) (*Provider, error) {
+	if dbURL == "" {
+		return nil, errBlankDBURL
+	}
+
+	db, err := sql.Open(driverName, dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection to MySQL: %w", err)
+	}
+
+	return newProvider(db, opts...)
+}
+
+func newProvider(db *sql.DB, opts ...Option) (*Provider, error) {
+	p := &Provider{
+		db:  db,
+		dbs: map[string]*Store{},
+		retryParams: &retry.Params{
+			MaxRetries:     defaultMaxRetries,
+			InitialBackoff: defaultInitialBackoff,
+			BackoffFactor:  defaultBackoffFactor,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	err := retry.Retry(db.Ping, p.retryParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MySQL: %w", err)
+	}
+
+	return p, nil
+}
+
+// CreateStore creates a new store with the given name.
+func (p *Provider) CreateStore(name string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	_, err := p.db.Exec(fmt.Sprintf(createTableStmt, p.tableName(name)))
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == tableExistsErrNumber {
+			return storage.ErrDuplicateStore
+		}
+
+		return fmt.Errorf("failed to create store %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// OpenStore opens an existing store with the given name and returns it.
+func (p *Provider) OpenStore(name string) (storage.Store, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	tableName := p.tableName(name)
+
+	if store, ok := p.dbs[tableName]; ok {
+		return store, nil
+	}
+
+	var count int
+
+	err := p.db.QueryRow(tableExistsStmt, tableName).Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if store %s exists: %w", name, err)
+	}
+
+	if count == 0 {
+		return nil, storage.ErrStoreNotFound
+	}
+
+	store := &Store{db: p.db, tableName: tableName}
+
+	p.dbs[tableName] = store
+
+	return store, nil
+}
+
+// CloseStore closes a previously opened store.
+func (p *Provider) CloseStore(name string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	tableName := p.tableName(name)
+
+	if _, ok := p.dbs[tableName]; !ok {
+		return storage.ErrStoreNotFound
+	}
+
+	delete(p.dbs, tableName)
+
+	return nil
+}
+
+// Close closes the provider and its connection pool.
+func (p *Provider) Close() error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.dbs = map[string]*Store{}
+
+	return p.db.Close()
+}
+
+func (p *Provider) tableName(name string) string {
+	if p.dbPrefix != "" {
+		name = p.dbPrefix + "_" + name
+	}
+
+	// backticks would allow to escape the quoted table name
+	return strings.ReplaceAll(name, "`", "")
+}
+
+// Store represents a MySQL table backed store.
+type Store struct {
+	db        *sql.DB
+	tableName string
+}
+
+// Put stores the given key-value pair in the store.
+func (s *Store) Put(k string, v []byte) error {
+	_, err := s.db.Exec(fmt.Sprintf(putStmt, s.tableName), k, v)
+	if err != nil {
+		return fmt.Errorf("failed to store data: %w", err)
+	}
+
+	return nil
+}
+
+// Get retrieves the value in the store associated with the given key.
+func (s *Store) Get(k string) ([]byte, error) {
+	var v []byte
+
+	err := s.db.QueryRow(fmt.Sprintf(getStmt, s.tableName), k).Scan(&v)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrValueNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get data: %w", err)
+	}
+
+	return v, nil
+}
+
+// PutIfAbsent stores the given key-value pair unless the key has a value, the insert failing on the duplicate key.
+func (s *Store) PutIfAbsent(k string, v []byte) error {
+	_, err := s.db.Exec(fmt.Sprintf(insertStmt, s.tableName), k, v)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == duplicateEntryErrNumber {
+			return conditional.ErrConflict
+		}
+
+		return fmt.Errorf("failed to store data: %w", err)
+	}
+
+	return nil
+}
+
+// Replace replaces the value of the key if it is still the old value, the row being updated where it has the old
+// value.
+func (s *Store) Replace(k string, old, v []byte) error {
+	// an update to the same value affects no row, the value is only compared
+	if bytes.Equal(old, v) {
+		stored, err := s.Get(k)
+
+		switch {
+		case errors.Is(err, storage.ErrValueNotFound):
+			return conditional.ErrConflict
+		case err != nil:
+			return err
+		case !bytes.Equal(stored, old):
+			return conditional.ErrConflict
+		}
+
+		return nil
+	}
+
+	result, err := s.db.Exec(fmt.Sprintf(replaceStmt, s.tableName), v, k, old)
+	if err != nil {
+		return fmt.Errorf("failed to store data: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to store data: %w", err)
+	}
+
+	if rows == 0 {
+		return conditional.ErrConflict
+	}
+
+	return nil
+}
+
+// CreateIndex is not supported by the MySQL store, the queries scan the table of the store.
+func (s *Store) CreateIndex(storage.CreateIndexRequest) error {
+	return errIndexNotSupported
+}
+
+// Query queries the stored JSON values. The query is a JSON object of the top-level fields of the values and
+// the values they must be equal to, e.g. {"name": "profile1"}. The matching key-value pairs are ordered by key.
+func (s *Store) Query(query string) (storage.ResultsIterator, error) {
+	conditions, args, err := queryConditions(query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(queryStmt, s.tableName, conditions), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query data: %w", err)
+	}
+
+	return &resultsIterator{rows: rows}, nil
+}
+
+// queryConditions returns the conditions of the query fields, and their arguments (JSON path and value).
+func queryConditions(query string) (string, []interface{}, error) {
+	var fields map[string]json.RawMessage
+
+	if err := json.Unmarshal([]byte(query), &fields); err != nil {
+		return "", nil, fmt.Errorf("invalid query: %w", err)
+	}
+
+	names := make([]string, 0, len(fields))
+
+	for name := range fields {
+		if name == "" || strings.ContainsAny(name, `"\`) {
+			return "", nil, fmt.Errorf("invalid query field: %s", name)
+		}
+
+		names = append(names, name)
+	}
+
+	// the conditions are in a stable order, the fields being unordered in the query
+	sort.Strings(names)
+
+	conditions := strings.Repeat(queryFieldCond, len(names))
+	args := make([]interface{}, 0, 2*len(names))
+
+	for _, name := range names {
+		args = append(args, `$."`+name+`"`, string(fields[name]))
+	}
+
+	return conditions, args, nil
+}
+
+type resultsIterator struct {
+	rows  *sql.Rows
+	key   string
+	value []byte
+}
+
+// Next moves the pointer to the next value in the iterator. It returns false if the iterator is exhausted.
+func (i *resultsIterator) Next() (bool, error) {
+	if !i.rows.Next() {
+		return false, i.rows.Err()
+	}
+
+	if err := i.rows.Scan(&i.key, &i.value); err != nil {
+		return false, fmt.Errorf("failed to read query result: %w", err)
+	}
+
+	return true, nil
+}
+
+// Release releases associated resources.
+func (i *resultsIterator) Release() error {
+	return i.rows.Close()
+}
+
+// Key returns the key of the current key-value pair.
+func (i *resultsIterator) Key() (string, error) {
+	return i.key, nil
+}
+
+// Value returns the value of the current key-value pair.
+func (i *resultsIterator) Value() ([]byte, error) {
+	return i.value, nil
+}
@@ -0,0 +1,334 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage"
+	"github.com/trustbloc/edge-core/pkg/utils/retry"
+
+	"github.com/trustbloc/edge-service/pkg/storage/conditional"
+)
+
+func TestNewProvider(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		p, mock := newTestProvider(t, WithDBPrefix("prefix"), WithMaxOpenConns(10), WithMaxIdleConns(2),
+			WithConnMaxLifetime(time.Minute))
+		require.Equal(t, "prefix", p.dbPrefix)
+		require.Equal(t, 10, p.db.Stats().MaxOpenConnections)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("test error - blank db url", func(t *testing.T) {
+		p, err := NewProvider("")
+		require.Equal(t, errBlankDBURL, err)
+		require.Nil(t, p)
+	})
+
+	t.Run("test error - connect", func(t *testing.T) {
+		p, err := NewProvider("user:pass@tcp(localhost:0)/db", WithRetryParams(&retry.Params{}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to connect to MySQL")
+		require.Nil(t, p)
+	})
+
+	t.Run("test connect retried", func(t *testing.T) {
+		db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		require.NoError(t, err)
+
+		mock.ExpectPing().WillReturnError(errors.New("ping error"))
+		mock.ExpectPing()
+
+		_, err = newProvider(db, WithRetryParams(&retry.Params{MaxRetries: 1}))
+		require.NoError(t, err)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestProvider_CreateStore(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		p, mock := newTestProvider(t, WithDBPrefix("prefix"))
+
+		mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE `prefix_store1`")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		require.NoError(t, p.CreateStore("store1"))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("test duplicate store", func(t *testing.T) {
+		p, mock := newTestProvider(t)
+
+		mock.ExpectExec("CREATE TABLE").WillReturnError(&mysql.MySQLError{Number: tableExistsErrNumber})
+
+		require.Equal(t, storage.ErrDuplicateStore, p.CreateStore("store1"))
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		p, mock := newTestProvider(t)
+
+		mock.ExpectExec("CREATE TABLE").WillReturnError(errors.New("create error"))
+
+		err := p.CreateStore("store1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to create store store1: create error")
+	})
+}
+
+func TestProvider_OpenStore(t *testing.T) {
+	t.Run("test success", func(t *testing.T) {
+		p, mock := newTestProvider(t)
+
+		mock.ExpectQuery("information_schema.tables").WithArgs("store1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+		store, err := p.OpenStore("store1")
+		require.NoError(t, err)
+		require.NotNil(t, store)
+
+		cachedStore, err := p.OpenStore("store1")
+		require.NoError(t, err)
+		require.Equal(t, store, cachedStore)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("test store not found", func(t *testing.T) {
+		p, mock := newTestProvider(t)
+
+		mock.ExpectQuery("information_schema.tables").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		store, err := p.OpenStore("store1")
+		require.Equal(t, storage.ErrStoreNotFound, err)
+		require.Nil(t, store)
+	})
+
+	t.Run("test error", func(t *testing.T) {
+		p, mock := newTestProvider(t)
+
+		mock.ExpectQuery("information_schema.tables").WillReturnError(errors.New("query error"))
+
+		store, err := p.OpenStore("store1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to check if store store1 exists: query error")
+		require.Nil(t, store)
+	})
+}
+
+func TestProvider_CloseStore(t *testing.T) {
+	p, mock := newTestProvider(t)
+
+	mock.ExpectQuery("information_schema.tables").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	_, err := p.OpenStore("store1")
+	require.NoError(t, err)
+
+	require.NoError(t, p.CloseStore("store1"))
+	require.Equal(t, storage.ErrStoreNotFound, p.CloseStore("store1"))
+
+	mock.ExpectClose()
+
+	require.NoError(t, p.Close())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStore(t *testing.T) {
+	p, mock := newTestProvider(t)
+
+	mock.ExpectQuery("information_schema.tables").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	store, err := p.OpenStore("store1")
+	require.NoError(t, err)
+
+	t.Run("test put", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `store1`")).WithArgs("key1", []byte("value1")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, store.Put("key1", []byte("value1")))
+
+		mock.ExpectExec("INSERT INTO").WillReturnError(errors.New("insert error"))
+
+		err := store.Put("key1", []byte("value1"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store data: insert error")
+	})
+
+	t.Run("test get", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `value` FROM `store1`")).WithArgs("key1").
+			WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow([]byte("value1")))
+
+		v, err := store.Get("key1")
+		require.NoError(t, err)
+		require.Equal(t, []byte("value1"), v)
+
+		mock.ExpectQuery("SELECT").WillReturnError(sql.ErrNoRows)
+
+		v, err = store.Get("key2")
+		require.Equal(t, storage.ErrValueNotFound, err)
+		require.Nil(t, v)
+
+		mock.ExpectQuery("SELECT").WillReturnError(errors.New("select error"))
+
+		_, err = store.Get("key2")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to get data: select error")
+	})
+
+	t.Run("test put if absent", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `store1` (`key`, `value`) VALUES (?, ?)")).
+			WithArgs("key1", []byte("value1")).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, store.(*Store).PutIfAbsent("key1", []byte("value1")))
+
+		mock.ExpectExec("INSERT INTO").WillReturnError(&mysql.MySQLError{Number: duplicateEntryErrNumber})
+
+		require.Equal(t, conditional.ErrConflict, store.(*Store).PutIfAbsent("key1", []byte("value1")))
+
+		mock.ExpectExec("INSERT INTO").WillReturnError(errors.New("insert error"))
+
+		err := store.(*Store).PutIfAbsent("key1", []byte("value1"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store data: insert error")
+	})
+
+	t.Run("test replace", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE `store1` SET `value` = ? WHERE `key` = ? AND `value` = ?")).
+			WithArgs([]byte("value2"), "key1", []byte("value1")).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, store.(*Store).Replace("key1", []byte("value1"), []byte("value2")))
+
+		mock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		require.Equal(t, conditional.ErrConflict, store.(*Store).Replace("key1", []byte("value1"), []byte("value2")))
+
+		mock.ExpectExec("UPDATE").WillReturnError(errors.New("update error"))
+
+		err := store.(*Store).Replace("key1", []byte("value1"), []byte("value2"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store data: update error")
+
+		mock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewErrorResult(errors.New("result error")))
+
+		err = store.(*Store).Replace("key1", []byte("value1"), []byte("value2"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to store data: result error")
+	})
+
+	t.Run("test replace with the same value", func(t *testing.T) {
+		mock.ExpectQuery("SELECT").WithArgs("key1").
+			WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow([]byte("value1")))
+
+		require.NoError(t, store.(*Store).Replace("key1", []byte("value1"), []byte("value1")))
+
+		mock.ExpectQuery("SELECT").WithArgs("key1").
+			WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow([]byte("value2")))
+
+		require.Equal(t, conditional.ErrConflict, store.(*Store).Replace("key1", []byte("value1"), []byte("value1")))
+
+		mock.ExpectQuery("SELECT").WillReturnError(sql.ErrNoRows)
+
+		require.Equal(t, conditional.ErrConflict, store.(*Store).Replace("key1", []byte("value1"), []byte("value1")))
+	})
+
+	t.Run("test index not supported", func(t *testing.T) {
+		require.Equal(t, errIndexNotSupported, store.CreateIndex(storage.CreateIndexRequest{}))
+	})
+
+	t.Run("test query", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `key`, `value` FROM `store1` WHERE JSON_VALID(`value`) "+
+			"AND JSON_EXTRACT(`value`, ?) = CAST(? AS JSON) AND JSON_EXTRACT(`value`, ?) = CAST(? AS JSON) "+
+			"ORDER BY `key`")).
+			WithArgs(`$."name"`, `"profile1"`, `$."version"`, `2`).
+			WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).
+				AddRow("key1", []byte(`{"name":"profile1","version":2}`)).
+				AddRow("key2", []byte(`{"name":"profile1","version":2,"id":"2"}`)))
+
+		iter, err := store.Query(`{"version":2,"name":"profile1"}`)
+		require.NoError(t, err)
+
+		var keys []string
+
+		for {
+			ok, err := iter.Next()
+			require.NoError(t, err)
+
+			if !ok {
+				break
+			}
+
+			key, err := iter.Key()
+			require.NoError(t, err)
+
+			value, err := iter.Value()
+			require.NoError(t, err)
+			require.Contains(t, string(value), `"name":"profile1"`)
+
+			keys = append(keys, key)
+		}
+
+		require.Equal(t, []string{"key1", "key2"}, keys)
+		require.NoError(t, iter.Release())
+	})
+
+	t.Run("test query all", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT `key`, `value` FROM `store1` WHERE JSON_VALID(`value`) " +
+			"ORDER BY `key`")).WillReturnRows(sqlmock.NewRows([]string{"key", "value"}))
+
+		iter, err := store.Query(`{}`)
+		require.NoError(t, err)
+
+		ok, err := iter.Next()
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("test query error", func(t *testing.T) {
+		_, err := store.Query("")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid query")
+
+		_, err = store.Query(`{"na\"me":"profile1"}`)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid query field")
+
+		mock.ExpectQuery("SELECT").WillReturnError(errors.New("select error"))
+
+		_, err = store.Query(`{"name":"profile1"}`)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to query data: select error")
+
+		mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).
+			AddRow("key1", []byte("value1")).RowError(0, errors.New("row error")))
+
+		iter, err := store.Query(`{"name":"profile1"}`)
+		require.NoError(t, err)
+
+		_, err = iter.Next()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "row error")
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func newTestProvider(t *testing.T, opts ...Option) (*Provider, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	p, err := newProvider(db, opts...)
+	require.NoError(t, err)
+
+	return p, mock
+}
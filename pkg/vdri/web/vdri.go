@@ -0,0 +1,250 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package web
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	vdriapi "github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdri"
+	"github.com/trustbloc/edge-core/pkg/log"
+	"github.com/trustbloc/edge-core/pkg/storage"
+)
+
+const (
+	didMethod = "web"
+	didPrefix = "did:" + didMethod + ":"
+	storeName = "didweb"
+
+	wellKnownPath = "/.well-known"
+	documentName  = "/did.json"
+)
+
+var logger = log.New("didweb-vdri")
+
+// ErrDocumentNotFound is returned when the document of a did:web is not stored by the service
+var ErrDocumentNotFound = errors.New("did document not found")
+
+// VDRI implements the did:web method. The documents of the DIDs created by the service are stored locally, to be
+// served by the service and resolved without a request to itself, the other documents are fetched over HTTPS.
+type VDRI struct {
+	store      storage.Store
+	httpClient *http.Client
+}
+
+// Option configures the VDRI
+type Option func(v *VDRI)
+
+// WithTLSConfig sets the TLS configuration of the requests fetching the documents
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(v *VDRI) {
+		v.httpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	}
+}
+
+// WithHTTPClient sets the HTTP client of the requests fetching the documents
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(v *VDRI) {
+		v.httpClient = httpClient
+	}
+}
+
+// New returns the did:web VDRI storing the documents in the given provider
+func New(provider storage.Provider, opts ...Option) (*VDRI, error) {
+	err := provider.CreateStore(storeName)
+	if err != nil && !errors.Is(err, storage.ErrDuplicateStore) {
+		return nil, fmt.Errorf("failed to create did:web store: %w", err)
+	}
+
+	store, err := provider.OpenStore(storeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open did:web store: %w", err)
+	}
+
+	v := &VDRI{store: store, httpClient: &http.Client{}}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v, nil
+}
+
+// Accept accepts the did:web method
+func (v *VDRI) Accept(method string) bool {
+	return method == didMethod
+}
+
+// Read resolves the did, from the stored documents or from its web server
+func (v *VDRI) Read(didID string, _ ...vdriapi.ResolveOpts) (*did.Doc, error) {
+	doc, err := v.Get(didID)
+	if err == nil || !errors.Is(err, ErrDocumentNotFound) {
+		return doc, err
+	}
+
+	documentURL, err := DocumentURL(didID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := v.httpClient.Get(documentURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch did document from %s: %w", documentURL, err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Warnf("failed to close response body: %s", err.Error())
+		}
+	}()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read did document from %s: %w", documentURL, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch did document from %s: status %d", documentURL, resp.StatusCode)
+	}
+
+	doc, err = did.ParseDocument(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse did document of %s: %w", didID, err)
+	}
+
+	if doc.ID != didID {
+		return nil, fmt.Errorf("did document of %s has id %s", didID, doc.ID)
+	}
+
+	return doc, nil
+}
+
+// Get returns the stored document of the did, or ErrDocumentNotFound
+func (v *VDRI) Get(didID string) (*did.Doc, error) {
+	docBytes, err := v.store.Get(didID)
+	if err != nil {
+		if errors.Is(err, storage.ErrValueNotFound) {
+			return nil, ErrDocumentNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get did document of %s: %w", didID, err)
+	}
+
+	// the deleted documents are stored empty, the store doesn't support deletion
+	if len(docBytes) == 0 {
+		return nil, ErrDocumentNotFound
+	}
+
+	doc, err := did.ParseDocument(docBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse did document of %s: %w", didID, err)
+	}
+
+	return doc, nil
+}
+
+// Store stores the document of a did created by the service
+func (v *VDRI) Store(doc *did.Doc, _ *[]vdriapi.ModifiedBy) error {
+	docBytes, err := doc.JSONBytes()
+	if err != nil {
+		return fmt.Errorf("failed to marshal did document of %s: %w", doc.ID, err)
+	}
+
+	err = v.store.Put(doc.ID, docBytes)
+	if err != nil {
+		return fmt.Errorf("failed to store did document of %s: %w", doc.ID, err)
+	}
+
+	return nil
+}
+
+// Delete deletes the stored document of a did created by the service, e.g. when its profile can't be created
+func (v *VDRI) Delete(didID string) error {
+	err := v.store.Put(didID, []byte{})
+	if err != nil {
+		return fmt.Errorf("failed to delete did document of %s: %w", didID, err)
+	}
+
+	return nil
+}
+
+// Build is not supported, the documents are built by their creator and stored
+func (v *VDRI) Build(_ *vdriapi.PubKey, _ ...vdriapi.DocOpts) (*did.Doc, error) {
+	return nil, errors.New("build not supported by did:web vdri")
+}
+
+// Close frees the resources of the VDRI
+func (v *VDRI) Close() error {
+	return nil
+}
+
+// DID returns the did:web of the document served under the path of the host URL
+func DID(hostURL string, path ...string) (string, error) {
+	u, err := url.Parse(hostURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid host url: %w", err)
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid host url %s: did:web requires the external URL of the service with its "+
+			"scheme, e.g. https://issuer.example.com", hostURL)
+	}
+
+	segments := []string{escape(u.Host)}
+
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments = append(segments, escape(segment))
+		}
+	}
+
+	for _, segment := range path {
+		segments = append(segments, escape(segment))
+	}
+
+	return didPrefix + strings.Join(segments, ":"), nil
+}
+
+// DocumentURL returns the URL of the document of the did:web
+func DocumentURL(didID string) (string, error) {
+	if !strings.HasPrefix(didID, didPrefix) {
+		return "", fmt.Errorf("invalid did:web %s", didID)
+	}
+
+	segments := strings.Split(strings.TrimPrefix(didID, didPrefix), ":")
+
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil || unescaped == "" {
+			return "", fmt.Errorf("invalid did:web %s", didID)
+		}
+
+		segments[i] = unescaped
+	}
+
+	if len(segments) == 1 {
+		return "https://" + segments[0] + wellKnownPath + documentName, nil
+	}
+
+	path := make([]string, len(segments)-1)
+
+	for i, segment := range segments[1:] {
+		path[i] = url.PathEscape(segment)
+	}
+
+	return "https://" + segments[0] + "/" + strings.Join(path, "/") + documentName, nil
+}
+
+// escape escapes the did segment, the colons being the separators of the segments
+func escape(segment string) string {
+	return strings.ReplaceAll(url.PathEscape(segment), ":", "%3A")
+}
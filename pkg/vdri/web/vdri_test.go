@@ -0,0 +1,172 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package web
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/stretchr/testify/require"
+	"github.com/trustbloc/edge-core/pkg/storage/memstore"
+	"github.com/trustbloc/edge-core/pkg/storage/mockstore"
+)
+
+func TestDID(t *testing.T) {
+	didID, err := DID("https://vcs.example.com", "issuer", "profile1")
+	require.NoError(t, err)
+	require.Equal(t, "did:web:vcs.example.com:issuer:profile1", didID)
+
+	didID, err = DID("https://vcs.example.com:8443/vcs/", "issuer", "profile:1")
+	require.NoError(t, err)
+	require.Equal(t, "did:web:vcs.example.com%3A8443:vcs:issuer:profile%3A1", didID)
+
+	documentURL, err := DocumentURL(didID)
+	require.NoError(t, err)
+	require.Equal(t, "https://vcs.example.com:8443/vcs/issuer/profile:1/did.json", documentURL)
+
+	documentURL, err = DocumentURL("did:web:example.com")
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/.well-known/did.json", documentURL)
+
+	_, err = DID("vcs.example.com")
+	require.EqualError(t, err, "invalid host url vcs.example.com: did:web requires the external URL of the "+
+		"service with its scheme, e.g. https://issuer.example.com")
+
+	// a host URL without scheme is parsed as an opaque URL
+	_, err = DID("localhost:8080")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "did:web requires the external URL of the service with its scheme")
+
+	_, err = DID(":")
+	require.Contains(t, err.Error(), "invalid host url")
+
+	_, err = DocumentURL("did:key:z6Mk")
+	require.EqualError(t, err, "invalid did:web did:key:z6Mk")
+
+	_, err = DocumentURL("did:web:example.com::issuer")
+	require.EqualError(t, err, "invalid did:web did:web:example.com::issuer")
+}
+
+func TestVDRI(t *testing.T) {
+	t.Run("test stored document", func(t *testing.T) {
+		v, err := New(memstore.NewProvider())
+		require.NoError(t, err)
+		require.True(t, v.Accept("web"))
+		require.False(t, v.Accept("key"))
+
+		_, err = v.Get("did:web:example.com:issuer")
+		require.True(t, errors.Is(err, ErrDocumentNotFound))
+
+		require.NoError(t, v.Store(newDoc(t, "did:web:example.com:issuer"), nil))
+
+		doc, err := v.Read("did:web:example.com:issuer")
+		require.NoError(t, err)
+		require.Equal(t, "did:web:example.com:issuer#key1", doc.PublicKey[0].ID)
+
+		require.NoError(t, v.Delete("did:web:example.com:issuer"))
+
+		_, err = v.Get("did:web:example.com:issuer")
+		require.True(t, errors.Is(err, ErrDocumentNotFound))
+
+		_, err = v.Build(nil)
+		require.Error(t, err)
+		require.NoError(t, v.Close())
+	})
+
+	t.Run("test fetched document", func(t *testing.T) {
+		var didID string
+
+		serv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			if req.URL.Path != "/issuer/did.json" {
+				rw.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			docBytes, err := newDoc(t, didID).JSONBytes()
+			require.NoError(t, err)
+
+			_, err = rw.Write(docBytes)
+			require.NoError(t, err)
+		}))
+		defer serv.Close()
+
+		didID, err := DID(serv.URL, "issuer")
+		require.NoError(t, err)
+
+		v, err := New(memstore.NewProvider(), WithTLSConfig(&tls.Config{InsecureSkipVerify: true})) // nolint: gosec
+		require.NoError(t, err)
+
+		doc, err := v.Read(didID)
+		require.NoError(t, err)
+		require.Equal(t, didID, doc.ID)
+
+		_, err = v.Read(strings.TrimSuffix(didID, ":issuer") + ":unknown")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "status 404")
+
+		_, err = v.Read("did:web:localhost%3A1")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "failed to fetch did document")
+
+		v, err = New(memstore.NewProvider(), WithHTTPClient(serv.Client()))
+		require.NoError(t, err)
+
+		doc, err = v.Read(didID)
+		require.NoError(t, err)
+		require.Equal(t, didID, doc.ID)
+	})
+
+	t.Run("test error - store failures", func(t *testing.T) {
+		_, err := New(&mockstore.Provider{ErrCreateStore: errors.New("create error")})
+		require.EqualError(t, err, "failed to create did:web store: create error")
+
+		_, err = New(&mockstore.Provider{ErrOpenStoreHandle: errors.New("open error"),
+			Store: &mockstore.MockStore{}})
+		require.EqualError(t, err, "failed to open did:web store: open error")
+
+		store := &mockstore.MockStore{Store: map[string][]byte{}, ErrPut: errors.New("put error")}
+
+		v, err := New(&mockstore.Provider{Store: store})
+		require.NoError(t, err)
+
+		err = v.Store(newDoc(t, "did:web:example.com"), nil)
+		require.EqualError(t, err, "failed to store did document of did:web:example.com: put error")
+
+		err = v.Delete("did:web:example.com")
+		require.EqualError(t, err, "failed to delete did document of did:web:example.com: put error")
+
+		store.Store["did:web:example.com"] = []byte("{")
+
+		_, err = v.Read("did:web:example.com")
+		require.Contains(t, err.Error(), "failed to parse did document")
+
+		store.ErrGet = errors.New("get error")
+
+		_, err = v.Read("did:web:example.com")
+		require.EqualError(t, err, "failed to get did document of did:web:example.com: get error")
+	})
+}
+
+func newDoc(t *testing.T, didID string) *did.Doc {
+	t.Helper()
+
+	pubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	publicKey := did.NewPublicKeyFromBytes(didID+"#key1", "Ed25519VerificationKey2018", didID, pubKey)
+
+	return &did.Doc{Context: []string{did.Context}, ID: didID, PublicKey: []did.PublicKey{*publicKey},
+		AssertionMethod: []did.VerificationMethod{*did.NewReferencedVerificationMethod(publicKey,
+			did.AssertionMethod, false)}}
+}
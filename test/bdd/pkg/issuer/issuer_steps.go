@@ -112,8 +112,13 @@ func (e *Steps) createDID(user string) error {
 }
 
 func (e *Steps) generateKeypair() (string, string, error) {
-	resp, err := bddutil.HTTPDo(http.MethodGet, issuerURL+"/kms/generatekeypair", //nolint: bodyclose
-		"", "rw_token", nil)
+	reqBytes, err := json.Marshal(operation.GenerateKeyPairRequest{KeyType: "Ed25519"})
+	if err != nil {
+		return "", "", err
+	}
+
+	resp, err := bddutil.HTTPDo(http.MethodPost, issuerURL+"/kms/generatekeypair", //nolint: bodyclose
+		"application/json", "rw_token", bytes.NewBuffer(reqBytes))
 	if err != nil {
 		return "", "", err
 	}